@@ -0,0 +1,317 @@
+//go:build !nompc
+
+// Command cb-mpc-bench measures end-to-end key generation and signing latency
+// of the N-party protocols over different transports, to help size
+// deployments.
+//
+// For every selected transport it connects all parties inside this process,
+// runs one key generation and then -signatures signatures one after another,
+// and reports signing latency percentiles, throughput and per-round
+// statistics: how long parties waited in each protocol round and how many
+// bytes they exchanged.
+//
+// Usage:
+//
+//	cb-mpc-bench [-transports mocknet,tcp,ws] [-parties 3] [-curve ed25519] \
+//	    [-signatures 100] [-concurrency 1] [-latency 20ms] [-jitter 5ms] [-json]
+//
+// Transports:
+//
+//   - mocknet – in-process queues, the lower bound set by computation alone
+//   - tcp     – the mtls transport over loopback TCP
+//   - ws      – the websocket transport over loopback TCP
+//
+// -latency and -jitter delay every message on the tcp and ws links to
+// emulate a WAN between parties.  With -concurrency above 1, that many
+// signatures run at a time, each on its own stream of the mux package over
+// the same connections, the way a signer daemon serves many sessions.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mux"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/netsim"
+	"golang.org/x/sync/errgroup"
+
+	"solana-threshold-wallet/cmd/internal/mpcnet"
+)
+
+var benchMessage = []byte("cb-mpc-bench message digest 32b!")
+
+func main() {
+	transports := flag.String("transports", "mocknet,tcp,ws", "comma-separated transports: mocknet, tcp, ws")
+	parties := flag.Int("parties", 3, "number of parties (at least 3)")
+	curveName := flag.String("curve", "ed25519", "curve: ed25519 or secp256k1")
+	signatures := flag.Int("signatures", 100, "number of signatures to measure")
+	concurrency := flag.Int("concurrency", 1, "signatures run at a time, multiplexed over one connection per pair of parties")
+	latency := flag.Duration("latency", 0, "one-way latency added to every tcp and ws message")
+	jitter := flag.Duration("jitter", 0, "random extra latency in [0, jitter) added to every tcp and ws message")
+	asJSON := flag.Bool("json", false, "print the reports as JSON")
+	flag.Parse()
+
+	if *parties < 3 {
+		log.Fatalf("at least 3 parties are required")
+	}
+	if *signatures < 1 {
+		log.Fatalf("at least one signature must be measured")
+	}
+
+	var reports []*report
+	for _, name := range splitList(*transports) {
+		delay := netsim.LatencyConfig{Latency: *latency, Jitter: *jitter}
+		if name == "mocknet" {
+			delay = netsim.LatencyConfig{}
+		}
+		r, err := run(name, *curveName, *parties, *signatures, *concurrency, delay)
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		reports = append(reports, r)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			log.Fatalf("encoding reports: %v", err)
+		}
+		return
+	}
+	for _, r := range reports {
+		r.print(os.Stdout)
+	}
+}
+
+// report holds the results for one transport.  Durations are in
+// milliseconds.
+type report struct {
+	Transport   string       `json:"transport"`
+	Curve       string       `json:"curve"`
+	Parties     int          `json:"parties"`
+	Concurrency int          `json:"concurrency"`
+	LatencyMs   float64      `json:"latency_ms"`
+	JitterMs    float64      `json:"jitter_ms"`
+	KeygenMs    float64      `json:"keygen_ms"`
+	Signatures  int          `json:"signatures"`
+	Sign        percentiles  `json:"sign_ms"`
+	SignsPerSec float64      `json:"signatures_per_second"`
+	Rounds      []roundStats `json:"rounds"`
+}
+
+// roundStats aggregates one signing round over all parties and signatures.
+type roundStats struct {
+	Round         int         `json:"round"`
+	Wait          percentiles `json:"wait_ms"`
+	BytesSent     int         `json:"bytes_sent"`     // Per party and signature, mean
+	BytesReceived int         `json:"bytes_received"` // Per party and signature, mean
+}
+
+type percentiles struct {
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+func summarize(ds []time.Duration) percentiles {
+	if len(ds) == 0 {
+		return percentiles{}
+	}
+	sorted := slices.Clone(ds)
+	slices.Sort(sorted)
+	at := func(q float64) float64 { return ms(sorted[int(q*float64(len(sorted)-1))]) }
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return percentiles{P50: at(0.5), P90: at(0.9), P99: at(0.99), Max: ms(sorted[len(sorted)-1]), Mean: ms(sum / time.Duration(len(sorted)))}
+}
+
+func ms(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+
+func (r *report) print(w io.Writer) {
+	fmt.Fprintf(w, "== %s: %d parties, %s, concurrency %d, latency %vms jitter %vms ==\n", r.Transport, r.Parties, r.Curve, r.Concurrency, r.LatencyMs, r.JitterMs)
+	fmt.Fprintf(w, "keygen        %.2f ms\n", r.KeygenMs)
+	fmt.Fprintf(w, "sign          p50 %.2f  p90 %.2f  p99 %.2f  max %.2f ms over %d signatures\n", r.Sign.P50, r.Sign.P90, r.Sign.P99, r.Sign.Max, r.Signatures)
+	fmt.Fprintf(w, "throughput    %.1f signatures/s\n", r.SignsPerSec)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "round\twait p50 ms\twait p99 ms\tsent B\treceived B\t")
+	for _, rs := range r.Rounds {
+		fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t%d\t%d\t\n", rs.Round, rs.Wait.P50, rs.Wait.P99, rs.BytesSent, rs.BytesReceived)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// run benchmarks one transport.  With concurrency above 1 the signatures run
+// that many at a time, each on its own mux stream over the same connections.
+func run(name, curveName string, n, signatures, concurrency int, delay netsim.LatencyConfig) (*report, error) {
+	cv, err := mpcnet.NewCurve(curveName)
+	if err != nil {
+		return nil, err
+	}
+	defer cv.Free()
+
+	messengers, closeNet, err := mpcnet.Connect(name, n)
+	if err != nil {
+		return nil, fmt.Errorf("connecting parties: %v", err)
+	}
+	defer closeNet()
+
+	links := make([]transport.Messenger, n)
+	for i := range messengers {
+		links[i] = messengers[i]
+		if delay.Latency > 0 || delay.Jitter > 0 {
+			l, err := netsim.NewLatency(links[i], delay)
+			if err != nil {
+				return nil, err
+			}
+			defer l.Close()
+			links[i] = l
+		}
+	}
+	var muxes []*mux.Mux
+	if concurrency > 1 {
+		for i := range links {
+			var peers []int
+			for j := 0; j < n; j++ {
+				if j != i {
+					peers = append(peers, j)
+				}
+			}
+			m, err := mux.New(links[i], mux.Config{Peers: peers})
+			if err != nil {
+				return nil, err
+			}
+			defer m.Close()
+			muxes = append(muxes, m)
+		}
+	}
+	pnames := mocknet.GeneratePartyNames(n)
+	p := mpcnet.NewProtocol(cv)
+	defer p.Free()
+
+	// session runs fn for all parties, on its own stream when multiplexing,
+	// and returns the parties' recorders.
+	session := func(id string, fn func(job *mpc.JobMP) error) ([]*netsim.Recorder, error) {
+		recorders := make([]*netsim.Recorder, n)
+		for i := range links {
+			m := links[i]
+			if muxes != nil {
+				s, err := muxes[i].Open(id)
+				if err != nil {
+					return nil, err
+				}
+				defer s.Close()
+				m = s
+			}
+			recorders[i] = netsim.NewRecorder(m)
+		}
+		return recorders, mpcnet.RunParties(recorders, pnames, fn)
+	}
+
+	start := time.Now()
+	if _, err := session("keygen", func(job *mpc.JobMP) error { return p.Keygen(job, cv) }); err != nil {
+		return nil, fmt.Errorf("key generation: %v", err)
+	}
+	keygen := time.Since(start)
+
+	sign := func(job *mpc.JobMP) error { return p.Sign(job, benchMessage) }
+	var (
+		mu        sync.Mutex
+		durations = make([]time.Duration, 0, signatures)
+		waits     = map[int][]time.Duration{}
+		sent      = map[int]int{}
+		received  = map[int]int{}
+	)
+	next := make(chan int)
+	eg, ctx := errgroup.WithContext(context.Background())
+	total := time.Now()
+	for w := 0; w < max(concurrency, 1); w++ {
+		eg.Go(func() error {
+			for s := range next {
+				start := time.Now()
+				recorders, err := session(fmt.Sprintf("sign-%d", s), sign)
+				if err != nil {
+					return fmt.Errorf("signature %d: %v", s+1, err)
+				}
+				elapsed := time.Since(start)
+				mu.Lock()
+				durations = append(durations, elapsed)
+				for _, r := range recorders {
+					for _, round := range r.Rounds() {
+						waits[round.Index] = append(waits[round.Index], round.Wait)
+						sent[round.Index] += round.BytesSent
+						received[round.Index] += round.BytesReceived
+					}
+				}
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	eg.Go(func() error {
+		defer close(next)
+		for s := 0; s < signatures; s++ {
+			select {
+			case next <- s:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(total)
+
+	rep := &report{
+		Transport:   name,
+		Curve:       cv.String(),
+		Parties:     n,
+		Concurrency: max(concurrency, 1),
+		LatencyMs:   ms(delay.Latency),
+		JitterMs:    ms(delay.Jitter),
+		KeygenMs:    ms(keygen),
+		Signatures:  signatures,
+		Sign:        summarize(durations),
+		SignsPerSec: float64(signatures) / elapsed.Seconds(),
+	}
+	samples := n * signatures
+	for round := 1; round <= len(waits); round++ {
+		rep.Rounds = append(rep.Rounds, roundStats{
+			Round:         round,
+			Wait:          summarize(waits[round]),
+			BytesSent:     sent[round] / samples,
+			BytesReceived: received[round] / samples,
+		})
+	}
+	return rep, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,404 @@
+//go:build !nompc
+
+// Command cb-mpc-ceremony creates a production threshold key through a guided
+// key ceremony with dual-operator control, RNG health checks, verified
+// backups and a signed ceremony report.
+//
+// It replaces solana-wallet-generator.go for production use.  All parties run
+// inside this process, so the ceremony host must be trusted and air-gapped;
+// shares are written encrypted to the backup directory and never printed.
+//
+// Usage:
+//
+//	cb-mpc-ceremony -key-id treasury -parties server,kms,pin -threshold 2 \
+//	    -operators alice,bob -backup-dir ./backup -backup-key backup.key \
+//	    -report-key host.key -report report.json [-script confirmations.txt] \
+//	    [-dice] [-hsm-rng /dev/hwrng] [-print-phrase] [-canary-log canary.log] \
+//	    [-pin-party pin [-pinentry pinentry-curses]]
+//
+// With -canary-log the new shares sign a canary message once the ceremony
+// completes, which is verified against the public key and recorded, so a
+// share set that cannot sign is caught before the key receives funds.
+//
+// With -pin-party the backup of that party's share is additionally sealed
+// under a PIN, which the holder chooses at the terminal (masked, confirmed
+// and checked for strength) or through a pinentry program.
+//
+// The backup key file holds either the hex key or its 24-word BIP-39
+// phrase.  With -print-phrase the phrase is shown once the ceremony
+// completes so that it can be written down as the recovery factor.
+//
+// Without -script every operator is prompted on the terminal and confirms a
+// step by typing their own name.  With -script, confirmations are replayed
+// from a file with one "step operator" pair per line, in order.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+
+	"solana-threshold-wallet/cmd/internal/mpcnet"
+	"solana-threshold-wallet/cmd/internal/pinlock"
+	"solana-threshold-wallet/wallet/canary"
+	"solana-threshold-wallet/wallet/ceremony"
+	"solana-threshold-wallet/wallet/client"
+	"solana-threshold-wallet/wallet/fingerprint"
+	"solana-threshold-wallet/wallet/prompt"
+	"solana-threshold-wallet/wallet/recoveryphrase"
+)
+
+func main() {
+	keyID := flag.String("key-id", "", "identifier of the key to create")
+	curveName := flag.String("curve", "ed25519", "curve: ed25519 or secp256k1")
+	parties := flag.String("parties", "server,kms,pin", "comma-separated party names")
+	threshold := flag.Int("threshold", 2, "number of parties required to sign")
+	operators := flag.String("operators", "", "comma-separated names of at least two operators")
+	backupDir := flag.String("backup-dir", "", "directory receiving encrypted share backups")
+	backupKey := flag.String("backup-key", "", "file holding the 32-byte backup encryption key as hex or a BIP-39 phrase")
+	reportKey := flag.String("report-key", "", "file holding the hex-encoded Ed25519 seed that signs the report")
+	reportPath := flag.String("report", "ceremony-report.json", "where to write the signed report")
+	script := flag.String("script", "", "file with scripted confirmations")
+	dice := flag.Bool("dice", false, "ask an operator for dice rolls to mix into the RNG")
+	hsmRNG := flag.String("hsm-rng", "", "device or file whose output is mixed into the RNG, e.g. an HSM RNG")
+	printPhrase := flag.Bool("print-phrase", false, "print the backup key as a BIP-39 phrase after the ceremony")
+	canaryLog := flag.String("canary-log", "", "file recording a canary signed with the new shares; empty skips it")
+	pinParty := flag.String("pin-party", "", "party whose backup is also sealed under a PIN chosen at the prompt")
+	pinentry := flag.String("pinentry", "", "pinentry program asking for the PIN instead of the terminal")
+	flag.Parse()
+
+	backupKeyBytes, err := recoveryphrase.ReadKeyFile(*backupKey)
+	if err != nil {
+		log.Fatalf("backup key: %v", err)
+	}
+	seed, err := readHexFile(*reportKey, ed25519.SeedSize)
+	if err != nil {
+		log.Fatalf("report key: %v", err)
+	}
+	var backups keystore.Medium
+	if backups, err = keystore.NewFileMedium(keystore.FileMediumConfig{Dir: *backupDir, Key: backupKeyBytes}); err != nil {
+		log.Fatalf("backup medium: %v", err)
+	}
+	if *pinParty != "" {
+		if !slices.Contains(splitList(*parties), *pinParty) {
+			log.Fatalf("PIN party %q is not one of the parties", *pinParty)
+		}
+		prompter, err := prompt.New(prompt.Config{Pinentry: *pinentry})
+		if err != nil {
+			log.Fatalf("PIN prompt: %v", err)
+		}
+		locked := pinlock.New(backups, *pinParty, prompter)
+		defer locked.Forget()
+		backups = locked
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	var contributors []ceremony.EntropyContributor
+	if *dice {
+		contributors = append(contributors, ceremony.DiceContributor("dice", func(context.Context) (string, error) {
+			fmt.Print("Enter at least 100 dice rolls (1-6) on one line: ")
+			return stdin.ReadString('\n')
+		}))
+	}
+	if *hsmRNG != "" {
+		f, err := os.Open(*hsmRNG)
+		if err != nil {
+			log.Fatalf("opening RNG device: %v", err)
+		}
+		defer f.Close()
+		contributors = append(contributors, ceremony.ReaderContributor(*hsmRNG, f, 64))
+	}
+
+	var confirmer ceremony.Confirmer = terminalConfirmer{in: stdin, out: os.Stdout}
+	if *script != "" {
+		f, err := os.Open(*script)
+		if err != nil {
+			log.Fatalf("opening script: %v", err)
+		}
+		defer f.Close()
+		confirmer = &scriptConfirmer{lines: bufio.NewScanner(f)}
+	}
+
+	c, err := ceremony.New(ceremony.Config{
+		Params: ceremony.Params{
+			KeyID:     *keyID,
+			Curve:     *curveName,
+			Parties:   splitList(*parties),
+			Threshold: *threshold,
+		},
+		Operators:    splitList(*operators),
+		Confirmer:    confirmer,
+		Generator:    ceremony.KeyGeneratorFunc(generate),
+		Backups:      backups,
+		Contributors: contributors,
+		ReportKey:    ed25519.NewKeyFromSeed(seed),
+		Guide: func(step, instructions string) {
+			fmt.Printf("\n== %s ==\n%s\n", step, instructions)
+		},
+	})
+	if err != nil {
+		log.Fatalf("preparing ceremony: %v", err)
+	}
+
+	report, km, err := c.Run(context.Background())
+	if err != nil {
+		log.Fatalf("ceremony aborted: %v", err)
+	}
+	if *canaryLog != "" {
+		if err := signCanary(&report.Report, km, *canaryLog); err != nil {
+			log.Fatalf("canary: %v", err)
+		}
+	}
+	shareFingerprints := make(map[string]fingerprint.Fingerprint, len(km.Shares))
+	for party, share := range km.Shares {
+		shareFingerprints[party] = fingerprint.Share(share)
+		clear(share)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalf("encoding report: %v", err)
+	}
+	if err := os.WriteFile(*reportPath, data, 0o644); err != nil {
+		log.Fatalf("writing report: %v", err)
+	}
+	fmt.Printf("\nCeremony %s completed. Public key %x. Report written to %s\n", report.Report.ID, report.Report.PublicKey, *reportPath)
+	fmt.Printf("\nKey fingerprint  %s\n", fingerprint.Key(report.Report.PublicKey))
+	for _, s := range report.Report.Shares {
+		fmt.Printf("  %-14s %s\n", s.Party, shareFingerprints[s.Party])
+	}
+	if *printPhrase {
+		phrase, err := recoveryphrase.Encode(backupKeyBytes)
+		if err != nil {
+			log.Fatalf("encoding backup key: %v", err)
+		}
+		fmt.Printf("\nBackup key recovery phrase (write it down; it decrypts every backup):\n\n  %s\n", phrase)
+	}
+}
+
+// signCanary signs a canary message with the new shares of every party,
+// verifies it against the public key and records it in path.  Only Ed25519
+// keys are checked.
+func signCanary(r *ceremony.Report, km *ceremony.KeyMaterial, path string) error {
+	if r.Params.Curve != "ed25519" {
+		fmt.Println("\nCanary skipped: only ed25519 keys are checked.")
+		return nil
+	}
+	res, err := canary.Run(context.Background(), canary.Config{
+		KeyID:    r.Params.KeyID,
+		Event:    canary.EventKeygen,
+		Verifier: client.Ed25519(km.PublicKey),
+		Sign: func(_ context.Context, message []byte) ([]byte, error) {
+			var sig []byte
+			err := mpcnet.RunParties(mocknet.NewMockNetwork(len(r.Params.Parties)), r.Params.Parties, func(job *mpc.JobMP) error {
+				var k mpc.EDDSAMPCKey
+				if err := k.UnmarshalBinary(km.Shares[r.Params.Parties[job.GetPartyIndex()]]); err != nil {
+					return err
+				}
+				defer k.Free()
+				resp, err := mpc.EDDSAMPCSign(job, &mpc.EDDSAMPCSignRequest{KeyShare: k, Message: message})
+				if err != nil {
+					return err
+				}
+				if job.GetPartyIndex() == 0 {
+					sig = resp.Signature
+				}
+				return nil
+			})
+			return sig, err
+		},
+		Log: &canary.FileLog{Path: path},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nCanary %s signed with the new shares and verified, recorded in %s\n", res.ID, path)
+	return nil
+}
+
+// generate runs the threshold DKG for all parties inside this process.  The
+// native library draws from its own RNG, so a sample of the ceremony RNG is
+// first mixed into it with mpc.SeedRandom.
+func generate(_ context.Context, p ceremony.Params, rand io.Reader) (*ceremony.KeyMaterial, error) {
+	if err := seedNativeRNG(rand); err != nil {
+		return nil, fmt.Errorf("mixing ceremony entropy into the native RNG: %w", err)
+	}
+	var (
+		cv  curve.Curve
+		err error
+	)
+	switch p.Curve {
+	case "ed25519":
+		cv, err = curve.NewEd25519()
+	case "secp256k1":
+		cv, err = curve.NewSecp256k1()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", p.Curve)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer cv.Free()
+
+	leaves := make([]*mpc.AccessNode, len(p.Parties))
+	for i, name := range p.Parties {
+		leaves[i] = mpc.Leaf(name)
+	}
+	ac := &mpc.AccessStructure{Root: mpc.Threshold("", p.Threshold, leaves...), Curve: cv}
+
+	n := len(p.Parties)
+	messengers := mocknet.NewMockNetwork(n)
+	shares := make([][]byte, n)
+	publicKeys := make([][]byte, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job, err := mpc.NewJobMP(messengers[i], n, i, p.Parties)
+			if err != nil {
+				errs[i] = fmt.Errorf("party %s: %w", p.Parties[i], err)
+				return
+			}
+			defer job.Free()
+			if shares[i], publicKeys[i], err = dkg(job, cv, ac); err != nil {
+				errs[i] = fmt.Errorf("party %s: %w", p.Parties[i], err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	km := &ceremony.KeyMaterial{PublicKey: publicKeys[0], Shares: make(map[string][]byte, n)}
+	for i, name := range p.Parties {
+		if !bytes.Equal(publicKeys[i], km.PublicKey) {
+			return nil, fmt.Errorf("party %s derived a different public key", name)
+		}
+		km.Shares[name] = shares[i]
+	}
+	return km, nil
+}
+
+// dkg runs the curve-appropriate threshold DKG and returns the serialized
+// share and the group public key.
+func dkg(job *mpc.JobMP, cv curve.Curve, ac *mpc.AccessStructure) ([]byte, []byte, error) {
+	if cv.String() == "secp256k1" {
+		resp, err := mpc.ECDSAMPCThresholdDKG(job, &mpc.ECDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: ac})
+		if err != nil {
+			return nil, nil, err
+		}
+		return marshalWithQ(resp.KeyShare.MarshalBinary, resp.KeyShare.Q)
+	}
+	resp, err := mpc.EDDSAMPCThresholdDKG(job, &mpc.EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: ac})
+	if err != nil {
+		return nil, nil, err
+	}
+	return marshalWithQ(resp.KeyShare.MarshalBinary, resp.KeyShare.Q)
+}
+
+func marshalWithQ(marshal func() ([]byte, error), q func() (*curve.Point, error)) ([]byte, []byte, error) {
+	share, err := marshal()
+	if err != nil {
+		return nil, nil, err
+	}
+	point, err := q()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer point.Free()
+	return share, point.Bytes(), nil
+}
+
+// seedNativeRNG mixes 64 bytes of rand into the RNG of the native library.
+func seedNativeRNG(rand io.Reader) error {
+	buf := make([]byte, 64)
+	defer clear(buf)
+	if _, err := io.ReadFull(rand, buf); err != nil {
+		return err
+	}
+	return mpc.SeedRandom(buf)
+}
+
+// terminalConfirmer asks each operator to type their name.
+type terminalConfirmer struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (t terminalConfirmer) Confirm(_ context.Context, p ceremony.Prompt) error {
+	fmt.Fprintf(t.out, "[%s] %s\n%s, type your name to confirm: ", p.Step, p.Summary, p.Operator)
+	line, err := t.in.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(line) != p.Operator {
+		return fmt.Errorf("confirmation declined")
+	}
+	return nil
+}
+
+// scriptConfirmer replays "step operator" lines.
+type scriptConfirmer struct {
+	lines *bufio.Scanner
+}
+
+func (s *scriptConfirmer) Confirm(_ context.Context, p ceremony.Prompt) error {
+	if !s.lines.Scan() {
+		return fmt.Errorf("script has no confirmation for %s/%s", p.Step, p.Operator)
+	}
+	fields := strings.Fields(s.lines.Text())
+	if len(fields) != 2 || fields[0] != p.Step || fields[1] != p.Operator {
+		return fmt.Errorf("script line %q does not confirm %s/%s", s.lines.Text(), p.Step, p.Operator)
+	}
+	fmt.Printf("[%s] %s: confirmed by %s (scripted)\n", p.Step, p.Summary, p.Operator)
+	return nil
+}
+
+func readHexFile(path string, size int) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file must be provided")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", size, len(b))
+	}
+	return b, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
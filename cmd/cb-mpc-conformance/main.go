@@ -0,0 +1,111 @@
+// Command cb-mpc-conformance checks a party written in another language
+// against the wire protocol of the Go parties (see SPEC.md in the
+// transport/conformance package).
+//
+// run listens as party 0 over the websocket transport, waits for the party
+// under test to dial in as party 1 and runs the conformance suite against it.
+// It exits with status 1 if any case fails, so it can gate CI:
+//
+//	$ cb-mpc-conformance run -listen :7000 [-json] [-timeout 30s]
+//
+// respond is the Go reference of the party under test.  It dials a runner
+// and answers the suite, e.g. to check a runner or a proxy in between:
+//
+//	$ cb-mpc-conformance respond -connect host:7000
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/conformance"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/websocket"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("usage: cb-mpc-conformance run|respond [flags]")
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "run":
+		err = run(args)
+	case "respond":
+		err = respond(args)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	listen := fs.String("listen", ":7000", "address to listen on for the party under test")
+	path := fs.String("path", "", "HTTP path of the upgrade request (default /cbmpc)")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of each case")
+	connect := fs.Duration("connect-timeout", 5*time.Minute, "how long to wait for the party under test")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	log.Printf("waiting for the party under test on %s", *listen)
+	link, err := websocket.NewMessenger(websocket.Config{
+		SelfIndex: 0,
+		// The party under test dials in; its address is never used.
+		Addresses:      map[int]string{0: *listen, 1: "party under test"},
+		Path:           *path,
+		ConnectTimeout: *connect,
+	})
+	if err != nil {
+		return err
+	}
+	defer link.Close()
+
+	report, err := conformance.Run(context.Background(), link, conformance.Config{Self: 0, Peer: 1, Timeout: *timeout})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Print(report)
+	}
+	if !report.Passed() {
+		link.Close()
+		os.Exit(1)
+	}
+	return nil
+}
+
+func respond(args []string) error {
+	fs := flag.NewFlagSet("respond", flag.ExitOnError)
+	connect := fs.String("connect", "127.0.0.1:7000", "address of the runner")
+	path := fs.String("path", "", "HTTP path of the upgrade request (default /cbmpc)")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of each case")
+	fs.Parse(args)
+
+	link, err := websocket.NewMessenger(websocket.Config{
+		SelfIndex: 1,
+		Addresses: map[int]string{0: *connect, 1: ""},
+		Path:      *path,
+	})
+	if err != nil {
+		return err
+	}
+	defer link.Close()
+	if err := conformance.Respond(context.Background(), link, conformance.Config{Self: 1, Peer: 0, Timeout: *timeout}); err != nil {
+		return err
+	}
+	log.Print("suite answered")
+	return nil
+}
@@ -0,0 +1,284 @@
+//go:build !nompc
+
+// Command cb-mpc-difftest runs the MPC protocols through keygen, derive and
+// sign over random inputs and cross-checks every public key, address and
+// signature against single-key reference implementations (see package
+// difftest).  It exits with status 1 on any divergence, so CI can run it on
+// every change to the native library or the bindings.
+//
+// All parties run inside this process over mocknet.  Derived Ed25519 keys
+// are signed for with the child shares of mpc.DeriveChild, through
+// delegate.SignChildShare.
+//
+// Usage:
+//
+//	cb-mpc-difftest [-curves ed25519,secp256k1] [-parties 3] [-keys 4] \
+//	    [-signatures 16] [-paths cbmpc/1/0,cbmpc/1/1] [-seed 0] \
+//	    [-report difftest.json]
+//
+// A failing run prints its seed; pass it back with -seed to replay the same
+// messages.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+
+	"solana-threshold-wallet/cmd/internal/mpcnet"
+	"solana-threshold-wallet/wallet/chain/evm"
+	"solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/delegate"
+	"solana-threshold-wallet/wallet/difftest"
+)
+
+func main() {
+	curves := flag.String("curves", "ed25519,secp256k1", "comma-separated curves: ed25519, secp256k1")
+	parties := flag.Int("parties", 3, "number of parties (at least 2)")
+	keys := flag.Int("keys", 4, "keys generated per curve")
+	signatures := flag.Int("signatures", 16, "random messages signed per key")
+	paths := flag.String("paths", "cbmpc/1/0,cbmpc/1/1", "comma-separated Ed25519 derivation paths")
+	seed := flag.Uint64("seed", 0, "message seed; 0 picks a random one")
+	reportPath := flag.String("report", "", "write the JSON report to this file")
+	flag.Parse()
+
+	if *parties < 2 {
+		log.Fatalf("at least 2 parties are required")
+	}
+	// The chains only derive addresses here; they never contact the node.
+	sol, err := solana.New(solana.Config{ID: "solana-difftest", RPCEndpoint: "http://127.0.0.1:0"})
+	if err != nil {
+		log.Fatalf("solana chain: %v", err)
+	}
+	eth, err := evm.New(evm.Config{ID: "evm-difftest", RPCEndpoint: "http://127.0.0.1:0", ChainID: big.NewInt(1)})
+	if err != nil {
+		log.Fatalf("evm chain: %v", err)
+	}
+	var cs []difftest.Curve
+	for _, name := range splitList(*curves) {
+		switch name {
+		case "ed25519":
+			cs = append(cs, difftest.Curve{Name: name, Reference: difftest.Ed25519Reference{}, Addresses: sol})
+		case "secp256k1":
+			cs = append(cs, difftest.Curve{Name: name, Reference: difftest.Secp256k1Reference{}, Addresses: eth})
+		default:
+			log.Fatalf("unsupported curve %q", name)
+		}
+	}
+
+	report, err := difftest.Run(context.Background(), difftest.Config{
+		Subject:    subject{parties: *parties},
+		Curves:     cs,
+		Keys:       *keys,
+		Signatures: *signatures,
+		Paths:      splitList(*paths),
+		Seed:       *seed,
+	})
+	if err != nil {
+		log.Fatalf("difftest: %v", err)
+	}
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("encoding report: %v", err)
+		}
+		if err := os.WriteFile(*reportPath, append(data, '\n'), 0o644); err != nil {
+			log.Fatalf("writing report: %v", err)
+		}
+	}
+	for _, d := range report.Divergences {
+		log.Printf("DIVERGENCE %s", d)
+	}
+	if !report.OK() {
+		log.Printf("FAIL: %d of %d checks diverged (seed %d)", len(report.Divergences), report.Checks, report.Seed)
+		os.Exit(1)
+	}
+	log.Printf("PASS: %d checks, %d skipped (seed %d)", report.Checks, report.Skipped, report.Seed)
+}
+
+// subject runs the MPC protocols with all parties in this process.
+type subject struct {
+	parties int
+}
+
+func (s subject) Keygen(_ context.Context, curveName string) (difftest.Key, error) {
+	var cv curve.Curve
+	var err error
+	switch curveName {
+	case "ed25519":
+		cv, err = curve.NewEd25519()
+	case "secp256k1":
+		cv, err = curve.NewSecp256k1()
+	default:
+		err = fmt.Errorf("unsupported curve %q", curveName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	k := &mpcKey{
+		cv:         cv,
+		ecdsa:      curveName == "secp256k1",
+		messengers: mocknet.NewMockNetwork(s.parties),
+		pnames:     mocknet.GeneratePartyNames(s.parties),
+		ecKeys:     make([]mpc.ECDSAMPCKey, s.parties),
+		edKeys:     make([]mpc.EDDSAMPCKey, s.parties),
+	}
+	if err := k.run(k.keygen); err != nil {
+		k.Close()
+		return nil, fmt.Errorf("key generation: %v", err)
+	}
+	q, err := k.q()
+	if err != nil {
+		k.Close()
+		return nil, err
+	}
+	k.pub = q
+	return k, nil
+}
+
+// mpcKey holds the shares of every party of one generated key.
+type mpcKey struct {
+	cv         curve.Curve
+	ecdsa      bool
+	messengers []*mocknet.MockMessenger
+	pnames     []string
+	pub        []byte
+
+	mu     sync.Mutex
+	ecKeys []mpc.ECDSAMPCKey
+	edKeys []mpc.EDDSAMPCKey
+	sig    []byte
+}
+
+func (k *mpcKey) run(fn func(job *mpc.JobMP) error) error {
+	return mpcnet.RunParties(k.messengers, k.pnames, fn)
+}
+
+func (k *mpcKey) keygen(job *mpc.JobMP) error {
+	i := job.GetPartyIndex()
+	if k.ecdsa {
+		resp, err := mpc.ECDSAMPCKeyGen(job, &mpc.ECDSAMPCKeyGenRequest{Curve: k.cv})
+		if err != nil {
+			return err
+		}
+		k.mu.Lock()
+		k.ecKeys[i] = resp.KeyShare
+		k.mu.Unlock()
+		return nil
+	}
+	resp, err := mpc.EDDSAMPCKeyGen(job, &mpc.EDDSAMPCKeyGenRequest{Curve: k.cv})
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	k.edKeys[i] = resp.KeyShare
+	k.mu.Unlock()
+	return nil
+}
+
+// q returns the group public key held by party 0.
+func (k *mpcKey) q() ([]byte, error) {
+	var q *curve.Point
+	var err error
+	if k.ecdsa {
+		q, err = k.ecKeys[0].Q()
+	} else {
+		q, err = k.edKeys[0].Q()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer q.Free()
+	return q.Bytes(), nil
+}
+
+func (k *mpcKey) PublicKey() []byte { return k.pub }
+
+// Sign runs the signing protocol; party 0 receives the signature.
+func (k *mpcKey) Sign(_ context.Context, message []byte) ([]byte, error) {
+	k.sig = nil
+	err := k.run(func(job *mpc.JobMP) error {
+		i := job.GetPartyIndex()
+		var sig []byte
+		if k.ecdsa {
+			resp, err := mpc.ECDSAMPCSign(job, &mpc.ECDSAMPCSignRequest{KeyShare: k.ecKeys[i], Message: message})
+			if err != nil {
+				return err
+			}
+			sig = resp.Signature
+		} else {
+			resp, err := mpc.EDDSAMPCSign(job, &mpc.EDDSAMPCSignRequest{KeyShare: k.edKeys[i], Message: message})
+			if err != nil {
+				return err
+			}
+			sig = resp.Signature
+		}
+		if i == 0 {
+			k.mu.Lock()
+			k.sig = sig
+			k.mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return k.sig, nil
+}
+
+// SignChild runs the signing protocol on every party's share of child;
+// party 0 receives the signature.
+func (k *mpcKey) SignChild(_ context.Context, child *delegate.Key, message []byte) ([]byte, error) {
+	if k.ecdsa {
+		return nil, fmt.Errorf("child keys are derived for Ed25519 only")
+	}
+	k.sig = nil
+	err := k.run(func(job *mpc.JobMP) error {
+		i := job.GetPartyIndex()
+		sig, err := delegate.SignChildShare(job, k.edKeys[i], child, message)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			k.mu.Lock()
+			k.sig = sig
+			k.mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return k.sig, nil
+}
+
+func (k *mpcKey) Close() error {
+	for _, s := range k.ecKeys {
+		s.Free()
+	}
+	for _, s := range k.edKeys {
+		s.Free()
+	}
+	k.cv.Free()
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
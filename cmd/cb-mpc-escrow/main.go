@@ -0,0 +1,355 @@
+//go:build !nompc
+
+// Command cb-mpc-escrow deposits a key with a regulated escrow agent and lets
+// the agent verify the deposit without decrypting it.
+//
+// The custodian exports every party's share from the backups, encrypted with
+// PVE (publicly verifiable encryption) to the agent's key, together with the
+// escrow policy and the key's signed public bundle:
+//
+//	custodian$ cb-mpc-escrow export -scheme eddsa -backup-dir ./backup -backup-key backup.key \
+//	               -ids <id>,<id>,<id> -bundle bundle.json -bundle-signer <hex ed25519 key> \
+//	               -agent-name "Escrow Trust" -agent-key agent.pub -policy policy.json \
+//	               -signing-key custodian.key -out escrow.json
+//
+// The agent checks it against its own key and the custodian's signing key:
+//
+//	agent$ cb-mpc-escrow verify -package escrow.json -agent-key agent.pub \
+//	           -custodian <hex ed25519 key> [-bundle-signer <hex ed25519 key>]
+//
+// verify prints a JSON report and exits with status 1 if the package is not
+// valid.  policy.json holds an escrow.Policy; agent.pub the agent's hex
+// mpc.BaseEncPublicKey.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+
+	"solana-threshold-wallet/wallet/escrow"
+)
+
+// agentLeaf names the agent in the single-leaf PVE access structure.
+const agentLeaf = "escrow-agent"
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("usage: cb-mpc-escrow export|verify [flags]")
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "export":
+		err = export(args)
+	case "verify":
+		err = verify(args)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	scheme := fs.String("scheme", "", "key share type: ecdsa or eddsa")
+	backupDir := fs.String("backup-dir", "", "directory holding the encrypted backups")
+	backupKey := fs.String("backup-key", "", "file holding the hex-encoded backup encryption key")
+	ids := fs.String("ids", "", "comma-separated backup identifiers, one per party")
+	bundlePath := fs.String("bundle", "", "signed public bundle of the key")
+	bundleSigner := fs.String("bundle-signer", "", "hex Ed25519 key expected to have signed the bundle")
+	agentName := fs.String("agent-name", "", "name of the escrow agent")
+	agentKeyPath := fs.String("agent-key", "", "file holding the agent's hex PVE public key")
+	policyPath := fs.String("policy", "", "JSON escrow policy")
+	signingKey := fs.String("signing-key", "", "file holding the custodian's hex Ed25519 private key")
+	out := fs.String("out", "escrow.json", "where to write the package")
+	fs.Parse(args)
+
+	agentKey, err := readHex(*agentKeyPath)
+	if err != nil {
+		return fmt.Errorf("agent key: %w", err)
+	}
+	signer, err := readHex(*signingKey)
+	if err != nil {
+		return fmt.Errorf("signing key: %w", err)
+	}
+	if len(signer) != ed25519.PrivateKeySize {
+		return fmt.Errorf("signing key must be %d bytes", ed25519.PrivateKeySize)
+	}
+	var policy escrow.Policy
+	if err := readJSON(*policyPath, &policy); err != nil {
+		return fmt.Errorf("policy: %w", err)
+	}
+	bundleData, err := os.ReadFile(*bundlePath)
+	if err != nil {
+		return err
+	}
+	bundle, err := parseBundle(bundleData, *bundleSigner)
+	if err != nil {
+		return err
+	}
+	cv, err := curveByName(bundle.Curve)
+	if err != nil {
+		return err
+	}
+	defer cv.Free()
+
+	key, err := readHex(*backupKey)
+	if err != nil {
+		return fmt.Errorf("backup key: %w", err)
+	}
+	medium, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: *backupDir, Key: key})
+	if err != nil {
+		return err
+	}
+	secrets := make(map[string]*curve.Scalar)
+	defer func() {
+		for _, x := range secrets {
+			clear(x.Bytes)
+		}
+	}()
+	for _, id := range strings.Split(*ids, ",") {
+		share, err := medium.Load(context.Background(), id)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", id, err)
+		}
+		party, x, err := secretShare(*scheme, share, bundle)
+		clear(share)
+		if err != nil {
+			return fmt.Errorf("share %s: %w", id, err)
+		}
+		secrets[party] = x
+	}
+
+	data, err := escrow.Export(escrow.ExportConfig{
+		Agent:        escrow.Agent{Name: *agentName, EncryptionKey: agentKey},
+		Policy:       policy,
+		Bundle:       bundleData,
+		PublicKey:    bundle.PublicKey,
+		PublicShares: bundle.PublicShares,
+		Encrypt: func(party string, _, agentKey []byte, label string) ([]byte, error) {
+			x, ok := secrets[party]
+			if !ok {
+				return nil, fmt.Errorf("no backup given for %s", party)
+			}
+			res, err := mpc.PVEEncrypt(&mpc.PVEEncryptRequest{
+				AccessStructure: agentAccessStructure(cv),
+				PublicKeys:      map[string]mpc.BaseEncPublicKey{agentLeaf: agentKey},
+				PrivateValues:   []*curve.Scalar{x},
+				Label:           label,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return res.EncryptedBundle, nil
+		},
+		Signer: ed25519.PrivateKey(signer),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	packagePath := fs.String("package", "escrow.json", "escrow package to verify")
+	agentKeyPath := fs.String("agent-key", "", "file holding the agent's hex PVE public key")
+	custodian := fs.String("custodian", "", "hex Ed25519 key expected to have signed the package")
+	bundleSigner := fs.String("bundle-signer", "", "hex Ed25519 key expected to have signed the bundle")
+	fs.Parse(args)
+
+	data, err := os.ReadFile(*packagePath)
+	if err != nil {
+		return err
+	}
+	agentKey, err := readHex(*agentKeyPath)
+	if err != nil {
+		return fmt.Errorf("agent key: %w", err)
+	}
+	config := escrow.VerifyConfig{AgentKey: agentKey}
+	if *custodian != "" {
+		if config.Custodian, err = hex.DecodeString(*custodian); err != nil {
+			return fmt.Errorf("custodian: %w", err)
+		}
+	} else {
+		log.Printf("warning: custodian key not pinned")
+	}
+	var cv curve.Curve
+	defer func() {
+		if cv != nil {
+			cv.Free()
+		}
+	}()
+	config.Bundle = func(data []byte) ([]byte, map[string][]byte, error) {
+		bundle, err := parseBundle(data, *bundleSigner)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cv, err = curveByName(bundle.Curve); err != nil {
+			return nil, nil, err
+		}
+		return bundle.PublicKey, bundle.PublicShares, nil
+	}
+	config.VerifyShare = func(s *escrow.Share, agentKey []byte) error {
+		if cv == nil {
+			return fmt.Errorf("curve unknown")
+		}
+		qi, err := curve.NewPointFromBytes(s.PublicShare)
+		if err != nil {
+			return err
+		}
+		defer qi.Free()
+		out, err := mpc.PVEVerify(&mpc.PVEVerifyRequest{
+			AccessStructure: agentAccessStructure(cv),
+			PublicKeys:      map[string]mpc.BaseEncPublicKey{agentLeaf: agentKey},
+			EncryptedBundle: s.Ciphertext,
+			PublicShares:    []*curve.Point{qi},
+			Label:           s.Label,
+		})
+		if err != nil {
+			return err
+		}
+		if !out.Valid {
+			return fmt.Errorf("ciphertext rejected")
+		}
+		return nil
+	}
+
+	report, err := escrow.Verify(data, config)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	if !report.Valid {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// agentAccessStructure lets the agent alone decrypt.
+func agentAccessStructure(cv curve.Curve) *mpc.AccessStructure {
+	return &mpc.AccessStructure{Root: mpc.Or("", mpc.Leaf(agentLeaf)), Curve: cv}
+}
+
+// secretShare returns the party and secret share x_i held in a serialized key
+// share, after checking that x_i·G is the party's public share in bundle.
+func secretShare(scheme string, data []byte, bundle *mpc.PublicBundle) (string, *curve.Scalar, error) {
+	type share interface {
+		PartyName() (string, error)
+		XShare() (*curve.Scalar, error)
+		Curve() (curve.Curve, error)
+	}
+	var s share
+	switch scheme {
+	case "ecdsa":
+		var k mpc.ECDSAMPCKey
+		if err := k.UnmarshalBinary(data); err != nil {
+			return "", nil, err
+		}
+		defer k.Free()
+		s = k
+	case "eddsa":
+		var k mpc.EDDSAMPCKey
+		if err := k.UnmarshalBinary(data); err != nil {
+			return "", nil, err
+		}
+		defer k.Free()
+		s = k
+	default:
+		return "", nil, fmt.Errorf("unknown scheme %q", scheme)
+	}
+
+	party, err := s.PartyName()
+	if err != nil {
+		return "", nil, err
+	}
+	qi, ok := bundle.PublicShares[party]
+	if !ok {
+		return "", nil, fmt.Errorf("bundle has no public share for %q", party)
+	}
+	x, err := s.XShare()
+	if err != nil {
+		return "", nil, err
+	}
+	cv, err := s.Curve()
+	if err != nil {
+		return "", nil, err
+	}
+	defer cv.Free()
+	xG, err := cv.MultiplyGenerator(x)
+	if err != nil {
+		return "", nil, err
+	}
+	defer xG.Free()
+	if !bytes.Equal(xG.Bytes(), qi) {
+		clear(x.Bytes)
+		return "", nil, fmt.Errorf("secret share of %s does not match the bundle", party)
+	}
+	return party, x, nil
+}
+
+// parseBundle parses and verifies a public bundle, optionally pinning the key
+// that signed it.
+func parseBundle(data []byte, signer string) (*mpc.PublicBundle, error) {
+	bundle, signed, err := mpc.ParsePublicBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	if signer != "" {
+		want, err := hex.DecodeString(signer)
+		if err != nil {
+			return nil, fmt.Errorf("bundle signer: %w", err)
+		}
+		if !bytes.Equal(want, signed.PublicKey) {
+			return nil, fmt.Errorf("bundle signed by %x, expected %s", signed.PublicKey, signer)
+		}
+	} else {
+		log.Printf("warning: bundle signer %x not pinned", signed.PublicKey)
+	}
+	return bundle, nil
+}
+
+func curveByName(name string) (curve.Curve, error) {
+	switch name {
+	case "secp256k1":
+		return curve.NewSecp256k1()
+	case "P-256":
+		return curve.NewP256()
+	case "Ed25519":
+		return curve.NewEd25519()
+	}
+	return nil, fmt.Errorf("unsupported curve %q", name)
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func readHex(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(data)))
+}
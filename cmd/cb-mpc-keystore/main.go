@@ -0,0 +1,172 @@
+// Command cb-mpc-keystore works with stored key shares.
+//
+// inspect reports what a share file is without decrypting or unmarshalling
+// the secret share, to identify share files of unknown origin:
+//
+//	$ cb-mpc-keystore inspect [-json] kms.backup server.share
+//
+// For every file it prints the size, modification time and fingerprint, the
+// keystore layers (versioned envelope with share ID and version, KMS
+// envelope) and, when the share itself is not encrypted, its format, curve,
+// parties and access structure.  Encrypted shares report their KDF, key ID
+// and label.  None of the formats records a creation time; the modification
+// time of the file is the best available hint.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+
+	"solana-threshold-wallet/wallet/fingerprint"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("usage: cb-mpc-keystore inspect [flags] <blob>...")
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "inspect":
+		err = inspect(args)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// report is the metadata of one share file.
+type report struct {
+	File        string               `json:"file"`
+	Size        int                  `json:"size"`
+	Modified    time.Time            `json:"modified"`
+	SHA256      string               `json:"sha256"`
+	Fingerprint string               `json:"fingerprint"`
+	Stored      *keystore.StoredInfo `json:"stored,omitempty"`
+	Share       *mpc.ShareInfo       `json:"share,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+func inspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no share file given")
+	}
+
+	var reports []report
+	for _, path := range fs.Args() {
+		r, err := inspectFile(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, r)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	for i, r := range reports {
+		if i > 0 {
+			fmt.Println()
+		}
+		printReport(os.Stdout, &r)
+	}
+	return nil
+}
+
+// inspectFile reads the metadata of the file at path.  Data it cannot
+// recognize is reported in the Error field rather than failing the command.
+func inspectFile(path string) (report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report{}, err
+	}
+	defer clear(data)
+	st, err := os.Stat(path)
+	if err != nil {
+		return report{}, err
+	}
+	digest := sha256.Sum256(data)
+	r := report{
+		File:        path,
+		Size:        len(data),
+		Modified:    st.ModTime().UTC(),
+		SHA256:      hex.EncodeToString(digest[:]),
+		Fingerprint: fingerprint.Share(data).String(),
+	}
+	stored, inner, err := keystore.Inspect(data)
+	if err != nil {
+		r.Error = err.Error()
+		return r, nil
+	}
+	if len(stored.Layers) > 0 {
+		r.Stored = stored
+	}
+	if inner == nil {
+		return r, nil
+	}
+	if r.Share, err = mpc.InspectShare(inner); err != nil {
+		r.Error = fmt.Sprintf("%v (data stored by an encrypting file medium looks like this too)", err)
+	}
+	return r, nil
+}
+
+func printReport(w io.Writer, r *report) {
+	fmt.Fprintf(w, "file:         %s\n", r.File)
+	fmt.Fprintf(w, "size:         %d bytes\n", r.Size)
+	fmt.Fprintf(w, "modified:     %s\n", r.Modified.Format(time.RFC3339))
+	fmt.Fprintf(w, "sha256:       %s\n", r.SHA256)
+	fmt.Fprintf(w, "fingerprint:  %s\n", r.Fingerprint)
+	if s := r.Stored; s != nil {
+		fmt.Fprintf(w, "layers:       %s\n", strings.Join(s.Layers, ", "))
+		if s.ID != "" {
+			fmt.Fprintf(w, "share ID:     %s\n", s.ID)
+			fmt.Fprintf(w, "version:      %d\n", s.Version)
+			fmt.Fprintf(w, "key version:  %x, epoch %d\n", s.KeyID, s.Epoch)
+			fmt.Fprintf(w, "encryption:   AES-256-GCM under the store key\n")
+		}
+		if s.WrappedKeySize > 0 {
+			fmt.Fprintf(w, "encryption:   data key wrapped by a KMS or HSM (%d bytes)\n", s.WrappedKeySize)
+		}
+	}
+	if s := r.Share; s != nil {
+		fmt.Fprintf(w, "format:       %s\n", s.Format)
+		switch s.Format {
+		case mpc.ShareFormatEncrypted:
+			fmt.Fprintf(w, "kdf:          %s\n", s.KDF)
+			if s.KEKID != "" {
+				fmt.Fprintf(w, "key ID:       %s\n", s.KEKID)
+			}
+			if s.Label != "" {
+				fmt.Fprintf(w, "label:        %s\n", s.Label)
+			}
+		case mpc.ShareFormatEnvelope:
+			fmt.Fprintf(w, "curve:        %s\n", s.Curve)
+			fmt.Fprintf(w, "parties:      %s\n", strings.Join(s.PartyNames, ", "))
+			fmt.Fprintf(w, "access structure:\n")
+			for _, line := range strings.Split(strings.TrimSuffix(s.AccessStructure.String(), "\n"), "\n") {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		case mpc.ShareFormatLegacy:
+			fmt.Fprintf(w, "              no access structure; curve and party need the native library\n")
+		}
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, "unrecognized: %s\n", r.Error)
+	}
+}
@@ -0,0 +1,81 @@
+// Command cb-mpc-reconcile replays a key's signing history against the chain
+// to detect use of its shares outside the coordinator.
+//
+// Usage:
+//
+//	cb-mpc-reconcile -sessions ./sessions -chain solana-mainnet \
+//	    -rpc https://api.mainnet-beta.solana.com -address <base58> [-limit 1000]
+//
+// It re-verifies every signature recorded in the coordinator's session store
+// for the address, matches the address's recent Solana transactions to the
+// sessions that signed them and prints a JSON reconcile.Report.  It exits with
+// status 1 if the report has findings, e.g. a transaction signed by the key
+// that no session produced.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/client"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/reconcile"
+)
+
+func main() {
+	sessions := flag.String("sessions", "", "directory of the coordinator's session store")
+	chainID := flag.String("chain", "", "identifier of the chain in the sessions, e.g. solana-devnet")
+	endpoint := flag.String("rpc", "https://api.devnet.solana.com", "Solana RPC endpoint")
+	address := flag.String("address", "", "base58 address of the key")
+	limit := flag.Int("limit", 1000, "number of most recent transactions to check")
+	flag.Parse()
+
+	if err := run(*sessions, *chainID, *endpoint, *address, *limit); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(sessions, chainID, endpoint, address string, limit int) error {
+	if sessions == "" || chainID == "" || address == "" {
+		return fmt.Errorf("-sessions, -chain and -address must be provided")
+	}
+	store, err := coordinator.NewFileStore(sessions)
+	if err != nil {
+		return err
+	}
+	ch, err := solana.New(solana.Config{ID: chainID, RPCEndpoint: endpoint, Commitment: rpc.CommitmentFinalized})
+	if err != nil {
+		return err
+	}
+	verifier, err := client.SolanaAddress(address)
+	if err != nil {
+		return err
+	}
+	report, err := reconcile.Run(context.Background(), reconcile.Config{
+		Store:    store,
+		History:  ch,
+		Chain:    chainID,
+		Address:  address,
+		Verifier: verifier,
+		Limit:    limit,
+	})
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	if !report.Clean() {
+		os.Exit(1)
+	}
+	return nil
+}
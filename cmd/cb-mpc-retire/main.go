@@ -0,0 +1,292 @@
+// Command cb-mpc-retire retires a threshold key: a quorum of approvers
+// authorizes the deletion, every party wipes its share and signs a
+// destruction statement, and the statements are aggregated into a signed
+// destruction certificate for compliance archives.
+//
+// Usage:
+//
+//	coordinator$ cb-mpc-retire request -key-id treasury -public-key <hex> \
+//	                 -parties server,kms,pin -reason "key rotated" -out request.json
+//	approver$    cb-mpc-retire approve -request request.json -approver alice \
+//	                 -key alice.key -approvals approvals.json
+//	party$       cb-mpc-retire destroy -request request.json -approvals approvals.json \
+//	                 -policy policy.json -party kms -key kms.key -dir ./shares -id kms \
+//	                 -out kms-statement.json
+//	coordinator$ cb-mpc-retire certify -request request.json -approvals approvals.json \
+//	                 -policy policy.json -statements server.json,kms.json,pin.json \
+//	                 -key host.key -out certificate.json
+//	auditor$     cb-mpc-retire verify -certificate certificate.json -policy policy.json \
+//	                 [-signer <hex ed25519 key>]
+//
+// Keys are files holding a hex-encoded Ed25519 seed.  The policy file is the
+// JSON encoding of retire.Policy: the approvers' and parties' public keys and
+// the number of approvals required.  approve appends to the approvals file,
+// creating it if needed.  destroy accepts several comma-separated -dir values
+// when a share is kept in more than one place.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"solana-threshold-wallet/wallet/fingerprint"
+	"solana-threshold-wallet/wallet/retire"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("usage: cb-mpc-retire request|approve|destroy|certify|verify [flags]")
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "request":
+		err = request(args)
+	case "approve":
+		err = approve(args)
+	case "destroy":
+		err = destroy(args)
+	case "certify":
+		err = certify(args)
+	case "verify":
+		err = verify(args)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func request(args []string) error {
+	fs := flag.NewFlagSet("request", flag.ExitOnError)
+	keyID := fs.String("key-id", "", "identifier of the key to retire")
+	publicKey := fs.String("public-key", "", "hex-encoded public key of the key to retire")
+	parties := fs.String("parties", "", "comma-separated names of the parties holding shares")
+	reason := fs.String("reason", "", "why the key is retired")
+	out := fs.String("out", "request.json", "where to write the request")
+	fs.Parse(args)
+
+	pub, err := hex.DecodeString(*publicKey)
+	if err != nil {
+		return fmt.Errorf("public key: %v", err)
+	}
+	req, err := retire.NewRequest(*keyID, pub, splitList(*parties), *reason, time.Now())
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, req)
+}
+
+func approve(args []string) error {
+	fs := flag.NewFlagSet("approve", flag.ExitOnError)
+	reqPath := fs.String("request", "request.json", "retirement request")
+	approver := fs.String("approver", "", "name of the approver, as in the policy")
+	keyPath := fs.String("key", "", "file holding the approver's hex-encoded Ed25519 seed")
+	approvalsPath := fs.String("approvals", "approvals.json", "approvals file to append to")
+	fs.Parse(args)
+
+	var req retire.Request
+	if err := readJSON(*reqPath, &req); err != nil {
+		return fmt.Errorf("reading request: %v", err)
+	}
+	key, err := readKey(*keyPath)
+	if err != nil {
+		return fmt.Errorf("approver key: %v", err)
+	}
+	fmt.Printf("Approving retirement of key %s held by %s\nFingerprint: %s\nReason: %s\n",
+		req.KeyID, strings.Join(req.Parties, ", "), fingerprint.Key(req.PublicKey), req.Reason)
+
+	var approvals []retire.Approval
+	if err := readJSON(*approvalsPath, &approvals); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading approvals: %v", err)
+	}
+	a, err := retire.Approve(&req, *approver, key, time.Now())
+	if err != nil {
+		return err
+	}
+	return writeJSON(*approvalsPath, append(approvals, *a))
+}
+
+func destroy(args []string) error {
+	fs := flag.NewFlagSet("destroy", flag.ExitOnError)
+	reqPath := fs.String("request", "request.json", "retirement request")
+	approvalsPath := fs.String("approvals", "approvals.json", "approvals of the request")
+	policyPath := fs.String("policy", "policy.json", "retirement policy")
+	party := fs.String("party", "", "name of this party")
+	keyPath := fs.String("key", "", "file holding the party's hex-encoded Ed25519 seed")
+	dirs := fs.String("dir", "", "comma-separated directories holding the share")
+	id := fs.String("id", "", "share ID within the directories")
+	out := fs.String("out", "statement.json", "where to write the destruction statement")
+	fs.Parse(args)
+
+	var (
+		req       retire.Request
+		approvals []retire.Approval
+		policy    retire.Policy
+	)
+	if err := readJSON(*reqPath, &req); err != nil {
+		return fmt.Errorf("reading request: %v", err)
+	}
+	if err := readJSON(*approvalsPath, &approvals); err != nil {
+		return fmt.Errorf("reading approvals: %v", err)
+	}
+	if err := readJSON(*policyPath, &policy); err != nil {
+		return fmt.Errorf("reading policy: %v", err)
+	}
+	key, err := readKey(*keyPath)
+	if err != nil {
+		return fmt.Errorf("party key: %v", err)
+	}
+	var shares []retire.Share
+	for _, dir := range splitList(*dirs) {
+		shares = append(shares, retire.Share{Wiper: retire.NewFileWiper(dir), ID: *id})
+	}
+
+	s, err := retire.Destroy(context.Background(), retire.DestroyConfig{
+		Party:  *party,
+		Key:    key,
+		Policy: policy,
+		Shares: shares,
+	}, &req, approvals)
+	if err != nil {
+		return err
+	}
+	for _, w := range s.Wiped {
+		fmt.Printf("wiped %s on %s\n", w.ID, w.Location)
+	}
+	return writeJSON(*out, s)
+}
+
+func certify(args []string) error {
+	fs := flag.NewFlagSet("certify", flag.ExitOnError)
+	reqPath := fs.String("request", "request.json", "retirement request")
+	approvalsPath := fs.String("approvals", "approvals.json", "approvals of the request")
+	policyPath := fs.String("policy", "policy.json", "retirement policy")
+	statementPaths := fs.String("statements", "", "comma-separated destruction statements, one per party")
+	keyPath := fs.String("key", "", "file holding the hex-encoded Ed25519 seed that signs the certificate")
+	out := fs.String("out", "certificate.json", "where to write the destruction certificate")
+	fs.Parse(args)
+
+	var (
+		req       retire.Request
+		approvals []retire.Approval
+		policy    retire.Policy
+	)
+	if err := readJSON(*reqPath, &req); err != nil {
+		return fmt.Errorf("reading request: %v", err)
+	}
+	if err := readJSON(*approvalsPath, &approvals); err != nil {
+		return fmt.Errorf("reading approvals: %v", err)
+	}
+	if err := readJSON(*policyPath, &policy); err != nil {
+		return fmt.Errorf("reading policy: %v", err)
+	}
+	var statements []retire.Statement
+	for _, path := range splitList(*statementPaths) {
+		var s retire.Statement
+		if err := readJSON(path, &s); err != nil {
+			return fmt.Errorf("reading statement %s: %v", path, err)
+		}
+		statements = append(statements, s)
+	}
+	key, err := readKey(*keyPath)
+	if err != nil {
+		return fmt.Errorf("certificate key: %v", err)
+	}
+
+	cert, err := retire.Certify(&req, approvals, statements, policy, key, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("certified destruction of key %s by %d parties\n", req.KeyID, len(statements))
+	return writeJSON(*out, cert)
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	certPath := fs.String("certificate", "certificate.json", "destruction certificate")
+	policyPath := fs.String("policy", "policy.json", "retirement policy")
+	signer := fs.String("signer", "", "hex-encoded Ed25519 key the certificate must be signed with")
+	fs.Parse(args)
+
+	var (
+		cert   retire.SignedCertificate
+		policy retire.Policy
+	)
+	if err := readJSON(*certPath, &cert); err != nil {
+		return fmt.Errorf("reading certificate: %v", err)
+	}
+	if err := readJSON(*policyPath, &policy); err != nil {
+		return fmt.Errorf("reading policy: %v", err)
+	}
+	if *signer != "" {
+		want, err := hex.DecodeString(*signer)
+		if err != nil {
+			return fmt.Errorf("signer: %v", err)
+		}
+		if !bytes.Equal(want, cert.PublicKey) {
+			return fmt.Errorf("certificate is signed by %x, not the expected signer", []byte(cert.PublicKey))
+		}
+	}
+	if err := cert.Verify(policy); err != nil {
+		return err
+	}
+	fmt.Printf("certificate valid: key %s destroyed by %d parties, issued %s\nfingerprint: %s\n",
+		cert.Certificate.Request.KeyID, len(cert.Certificate.Statements), cert.Certificate.IssuedAt.Format(time.RFC3339),
+		fingerprint.Key(cert.Certificate.Request.PublicKey))
+	return nil
+}
+
+func readKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file must be provided")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,412 @@
+//go:build !nompc
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	solanago "github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/cmd/internal/mpcnet"
+	"solana-threshold-wallet/wallet"
+	"solana-threshold-wallet/wallet/beacon"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/devtools"
+	"solana-threshold-wallet/wallet/scenario"
+)
+
+// chainID identifies the local validator in sessions.
+const chainID = "solana-localnet"
+
+type envConfig struct {
+	Endpoint  string
+	Dir       string
+	Transport string
+	Asset     scenario.Asset
+	Beacon    *beacon.Source // Mixed into the native RNG before signing, if set
+}
+
+// env implements scenario.Env on a local validator, with every party in
+// this process.
+type env struct {
+	config envConfig
+	chain  *solana.Chain
+	funder *devtools.RPCFunder
+	asset  wallet.Asset
+
+	// Set by Keygen.
+	cv       curve.Curve
+	pnames   []string
+	pub      []byte
+	address  string
+	shares   []keystore.Medium // One per party, the party's own store
+	backups  keystore.Medium
+	wallet   *wallet.Wallet
+	restores int
+}
+
+// Ensure env implements the scenario.Env and coordinator.Signer interfaces
+var (
+	_ scenario.Env       = (*env)(nil)
+	_ coordinator.Signer = (*env)(nil)
+)
+
+func newEnv(config envConfig) (*env, error) {
+	ch, err := solana.New(solana.Config{ID: chainID, RPCEndpoint: config.Endpoint, Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return nil, err
+	}
+	return &env{
+		config: config,
+		chain:  ch,
+		funder: devtools.NewRPCFunder(config.Endpoint),
+		asset:  wallet.Asset{Chain: chainID, Symbol: config.Asset.Symbol, Decimals: config.Asset.Decimals},
+	}, nil
+}
+
+// Close frees the curve.
+func (e *env) Close() {
+	if e.cv != nil {
+		e.cv.Free()
+	}
+}
+
+// medium returns a new file medium in dir below the scenario's directory,
+// encrypting every stored share under a fresh data key so that a refresh
+// also rotates the key.
+func (e *env) medium(dir string) (keystore.Medium, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	files, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: filepath.Join(e.config.Dir, dir)})
+	if err != nil {
+		return nil, err
+	}
+	wrapper, err := keystore.NewAESKeyWrapper(key)
+	if err != nil {
+		return nil, err
+	}
+	return keystore.NewEnvelopeMedium(keystore.EnvelopeMediumConfig{Medium: files, Wrapper: wrapper})
+}
+
+func shareID(party string) string { return party + ".share" }
+
+func (e *env) Keygen(ctx context.Context, parties int, curveName string) (string, error) {
+	if curveName != "ed25519" {
+		return "", fmt.Errorf("Solana keys are Ed25519; curve %q is not supported", curveName)
+	}
+	cv, err := mpcnet.NewCurve(curveName)
+	if err != nil {
+		return "", err
+	}
+	e.cv = cv
+	e.pnames = mocknet.GeneratePartyNames(parties)
+
+	shares := make([][]byte, parties)
+	err = e.run(nil, func(job *mpc.JobMP) error {
+		resp, err := mpc.EDDSAMPCKeyGen(job, &mpc.EDDSAMPCKeyGenRequest{Curve: cv})
+		if err != nil {
+			return err
+		}
+		defer resp.KeyShare.Free()
+		shares[job.GetPartyIndex()], err = resp.KeyShare.MarshalBinary()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("key generation: %w", err)
+	}
+	if e.pub, err = publicKey(shares[0]); err != nil {
+		return "", err
+	}
+	if e.backups, err = e.medium("backup"); err != nil {
+		return "", err
+	}
+	e.shares = make([]keystore.Medium, parties)
+	for i, party := range e.pnames {
+		if e.shares[i], err = e.medium(party); err != nil {
+			return "", err
+		}
+	}
+	if err := e.store(ctx, shares); err != nil {
+		return "", err
+	}
+
+	if e.address, err = e.chain.DeriveAddress(e.pub); err != nil {
+		return "", err
+	}
+	var signer coordinator.Signer = e
+	if e.config.Beacon != nil {
+		signer = e.config.Beacon.Signer(mpc.SeedRandom, signer)
+	}
+	coord, err := coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{e.chain},
+		Signer:          coordinator.RequireContext(signer),
+		ConfirmInterval: 500 * time.Millisecond,
+	})
+	if err != nil {
+		return "", err
+	}
+	e.wallet, err = wallet.New(wallet.Config{Coordinator: coord, Addresses: map[string]string{chainID: e.address}})
+	if err != nil {
+		return "", err
+	}
+	return e.address, nil
+}
+
+// store writes every party's share to its own medium and to the backups.
+func (e *env) store(ctx context.Context, shares [][]byte) error {
+	for i, party := range e.pnames {
+		if err := e.shares[i].Store(ctx, shareID(party), shares[i]); err != nil {
+			return fmt.Errorf("storing share of %s: %w", party, err)
+		}
+		if err := e.backups.Store(ctx, shareID(party), shares[i]); err != nil {
+			return fmt.Errorf("backing up share of %s: %w", party, err)
+		}
+	}
+	return nil
+}
+
+// load reads every party's share from its own medium.
+func (e *env) load(ctx context.Context) ([][]byte, error) {
+	shares := make([][]byte, len(e.pnames))
+	for i, party := range e.pnames {
+		var err error
+		if shares[i], err = e.shares[i].Load(ctx, shareID(party)); err != nil {
+			return nil, fmt.Errorf("loading share of %s: %w", party, err)
+		}
+	}
+	return shares, nil
+}
+
+// run runs fn for every party over a fresh connection on the scenario's
+// transport, once all parties passed the preflight check on intent, the
+// hash of what they sign (nil for a DKG).
+func (e *env) run(intent []byte, fn func(job *mpc.JobMP) error) error {
+	messengers, closeNet, err := mpcnet.Connect(e.config.Transport, len(e.pnames))
+	if err != nil {
+		return fmt.Errorf("connecting parties: %w", err)
+	}
+	defer closeNet()
+	if err := mpcnet.Preflight(context.Background(), messengers, nil, intent); err != nil {
+		return err
+	}
+	return mpcnet.RunParties(messengers, e.pnames, fn)
+}
+
+// Sign implements coordinator.Signer.  The coordinator reaches it through
+// coordinator.RequireContext, which checks the signing context; like a party
+// host, every party then checks the intent before contributing its share.
+func (e *env) Sign(ctx context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	intent, err := coordinator.VerifyIntent(req, e.chain, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	shares, err := e.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Progress(1); err != nil {
+		return nil, err
+	}
+	var (
+		mu  sync.Mutex
+		sig []byte
+	)
+	err = e.run(intent, func(job *mpc.JobMP) error {
+		var k mpc.EDDSAMPCKey
+		if err := k.UnmarshalBinary(shares[job.GetPartyIndex()]); err != nil {
+			return err
+		}
+		defer k.Free()
+		resp, err := mpc.EDDSAMPCSign(job, &mpc.EDDSAMPCSignRequest{KeyShare: k, Message: req.Payload})
+		if err != nil {
+			return err
+		}
+		if job.GetPartyIndex() == 0 {
+			mu.Lock()
+			sig = resp.Signature
+			mu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func (e *env) NewAccount(context.Context) (string, error) {
+	return solanago.NewWallet().PublicKey().String(), nil
+}
+
+func (e *env) Fund(ctx context.Context, address string, amount *big.Int) error {
+	to, err := solanago.PublicKeyFromBase58(address)
+	if err != nil {
+		return err
+	}
+	if !amount.IsUint64() {
+		return fmt.Errorf("amount %s is too large", amount)
+	}
+	return e.funder.Airdrop(ctx, to, amount.Uint64())
+}
+
+func (e *env) Transfer(ctx context.Context, to string, amount *big.Int) error {
+	_, err := e.wallet.Send(ctx, e.asset, wholeUnits(amount, e.asset.Decimals), to)
+	return err
+}
+
+func (e *env) Refresh(ctx context.Context) error {
+	shares, err := e.load(ctx)
+	if err != nil {
+		return err
+	}
+	sid := make([]byte, 32)
+	if _, err := rand.Read(sid); err != nil {
+		return err
+	}
+	fresh := make([][]byte, len(shares))
+	err = e.run(nil, func(job *mpc.JobMP) error {
+		i := job.GetPartyIndex()
+		var k mpc.EDDSAMPCKey
+		if err := k.UnmarshalBinary(shares[i]); err != nil {
+			return err
+		}
+		defer k.Free()
+		resp, err := mpc.EDDSAMPCRefresh(job, &mpc.EDDSAMPCRefreshRequest{KeyShare: k, SessionID: sid})
+		if err != nil {
+			return err
+		}
+		defer resp.NewKeyShare.Free()
+		fresh[i], err = resp.NewKeyShare.MarshalBinary()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("refresh: %w", err)
+	}
+	for i, share := range fresh {
+		if bytes.Equal(share, shares[i]) {
+			return fmt.Errorf("refresh left the share of %s unchanged", e.pnames[i])
+		}
+		pub, err := publicKey(share)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(pub, e.pub) {
+			return fmt.Errorf("refreshed share of %s is for a different public key", e.pnames[i])
+		}
+	}
+	return e.store(ctx, fresh)
+}
+
+// Recover replaces the party's medium by an empty one, as on a replacement
+// host, and restores the share from its backup after checking it.
+func (e *env) Recover(ctx context.Context, party string) error {
+	i := -1
+	for j, name := range e.pnames {
+		if name == party {
+			i = j
+		}
+	}
+	if i < 0 {
+		return fmt.Errorf("unknown party %q; the parties are %s", party, strings.Join(e.pnames, ", "))
+	}
+	share, err := e.backups.Load(ctx, shareID(party))
+	if err != nil {
+		return fmt.Errorf("loading backup of %s: %w", party, err)
+	}
+	var k mpc.EDDSAMPCKey
+	if err := k.UnmarshalBinary(share); err != nil {
+		return fmt.Errorf("decoding backup of %s: %w", party, err)
+	}
+	defer k.Free()
+	if name, err := k.PartyName(); err != nil || name != party {
+		return fmt.Errorf("backup of %s belongs to party %q", party, name)
+	}
+	if pub, err := publicKey(share); err != nil || !bytes.Equal(pub, e.pub) {
+		return fmt.Errorf("backup of %s is for a different public key", party)
+	}
+
+	e.restores++
+	target, err := e.medium(fmt.Sprintf("%s-restored-%d", party, e.restores))
+	if err != nil {
+		return err
+	}
+	if err := target.Store(ctx, shareID(party), share); err != nil {
+		return fmt.Errorf("restoring share of %s: %w", party, err)
+	}
+	e.shares[i] = target
+	return nil
+}
+
+func (e *env) Sweep(ctx context.Context, to string) (*big.Int, error) {
+	balance, err := e.Balance(ctx, e.address)
+	if err != nil {
+		return nil, err
+	}
+	// The fee does not depend on the amount, so quote half the balance.
+	quote, err := e.chain.Quote(ctx, &chain.Transfer{From: e.address, To: to, Amount: new(big.Int).Rsh(balance, 1)})
+	if err != nil {
+		return nil, err
+	}
+	amount := new(big.Int).Sub(balance, quote.Fee)
+	amount.Sub(amount, quote.Deposit)
+	if amount.Sign() <= 0 {
+		return nil, fmt.Errorf("balance %s does not cover the fee", e.asset.Format(balance))
+	}
+	return amount, e.Transfer(ctx, to, amount)
+}
+
+func (e *env) Balance(ctx context.Context, address string) (*big.Int, error) {
+	pk, err := solanago.PublicKeyFromBase58(address)
+	if err != nil {
+		return nil, err
+	}
+	lamports, err := e.funder.Balance(ctx, pk)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(lamports), nil
+}
+
+// publicKey returns the group public key of a serialized share.
+func publicKey(share []byte) ([]byte, error) {
+	var k mpc.EDDSAMPCKey
+	if err := k.UnmarshalBinary(share); err != nil {
+		return nil, err
+	}
+	defer k.Free()
+	q, err := k.Q()
+	if err != nil {
+		return nil, err
+	}
+	defer q.Free()
+	return q.Bytes(), nil
+}
+
+// wholeUnits writes amount, in smallest units, as the decimal in whole units
+// that Wallet.Send takes.
+func wholeUnits(amount *big.Int, decimals int) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	s := new(big.Rat).SetFrac(amount, unit).FloatString(decimals)
+	if decimals > 0 {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
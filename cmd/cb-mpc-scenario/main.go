@@ -0,0 +1,142 @@
+//go:build !nompc
+
+// Command cb-mpc-scenario runs scripted scenarios (see package scenario)
+// end to end against a local validator and reports their outcome.  It exits
+// with status 1 when any scenario fails, so CI can run the scenarios as
+// regression tests of the whole stack.
+//
+// All parties run inside this process and talk over the scenario's
+// transport: mocknet, tcp (mtls over loopback) or ws (websocket over
+// loopback).  Their shares and backups live in encrypted file media under
+// -dir.  Transfers go through a wallet and coordinator exactly as in
+// production, with funds coming from the validator's faucet.
+//
+// Usage:
+//
+//	solana-test-validator --reset --quiet &
+//	cb-mpc-scenario [-rpc http://127.0.0.1:8899] [-transport tcp] \
+//	    [-dir ./scenario-state] [-report scenarios.json] \
+//	    [-drand https://api.drand.sh -drand-chain HASH -drand-key KEY] \
+//	    wallet/scenario/testdata/*.yaml
+//
+// -transport overrides the transport of every scenario.  Without -dir the
+// state is kept in a temporary directory and removed at exit.
+//
+// With -drand, every signature is preceded by a drand round mixed into the
+// native RNG (see package beacon), and the rounds are recorded in
+// beacon.jsonl under -dir.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"solana-threshold-wallet/wallet/beacon"
+	"solana-threshold-wallet/wallet/scenario"
+)
+
+func main() {
+	endpoint := flag.String("rpc", "http://127.0.0.1:8899", "JSON-RPC endpoint of the local validator")
+	transport := flag.String("transport", "", "override the scenarios' transport: mocknet, tcp or ws")
+	dir := flag.String("dir", "", "directory for shares and session state; a temporary one by default")
+	reportPath := flag.String("report", "", "write the JSON reports to this file")
+	drandURL := flag.String("drand", "", "mix rounds of the drand network at this URL into every signature")
+	drandChain := flag.String("drand-chain", "", "hex hash of the drand chain")
+	drandKey := flag.String("drand-key", "", "hex public key of the drand chain")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatalf("usage: cb-mpc-scenario [flags] scenario.yaml...")
+	}
+	var scenarios []*scenario.Scenario
+	for _, path := range flag.Args() {
+		s, err := scenario.Load(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *transport != "" {
+			s.Transport = *transport
+		}
+		scenarios = append(scenarios, s)
+	}
+	root := *dir
+	if root == "" {
+		tmp, err := os.MkdirTemp("", "cb-mpc-scenario")
+		if err != nil {
+			log.Fatal(err)
+		}
+		root = tmp
+	}
+
+	var source *beacon.Source
+	if *drandURL != "" {
+		if err := os.MkdirAll(root, 0o700); err != nil {
+			log.Fatal(err)
+		}
+		var err error
+		source, err = beacon.New(beacon.Config{
+			Beacon: beacon.NewDrand(beacon.DrandConfig{URL: *drandURL, ChainHash: *drandChain, PublicKey: *drandKey}),
+			Audit:  &beacon.FileLog{Path: filepath.Join(root, "beacon.jsonl")},
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	var (
+		reports []*scenario.Report
+		failed  int
+	)
+	for i, s := range scenarios {
+		log.Printf("scenario %s (%d parties over %s)", s.Name, s.Parties, s.Transport)
+		env, err := newEnv(envConfig{
+			Endpoint:  *endpoint,
+			Dir:       filepath.Join(root, fmt.Sprintf("%02d-%s", i+1, s.Name)),
+			Transport: s.Transport,
+			Asset:     *s.Asset,
+			Beacon:    source,
+		})
+		if err != nil {
+			log.Fatalf("scenario %s: %v", s.Name, err)
+		}
+		report, err := scenario.Run(ctx, scenario.Config{
+			Scenario: s,
+			Env:      env,
+			OnStep:   func(r scenario.StepResult) { log.Printf("  %s", r) },
+		})
+		env.Close()
+		if report != nil {
+			reports = append(reports, report)
+		}
+		if err != nil {
+			log.Printf("FAIL %s: %v", s.Name, err)
+			failed++
+			continue
+		}
+		log.Printf("PASS %s", s.Name)
+	}
+
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			log.Fatalf("encoding reports: %v", err)
+		}
+		if err := os.WriteFile(*reportPath, append(data, '\n'), 0o644); err != nil {
+			log.Fatalf("writing reports: %v", err)
+		}
+	}
+	if *dir == "" {
+		os.RemoveAll(root)
+	}
+	if failed > 0 {
+		log.Printf("FAIL: %d of %d scenarios failed", failed, len(scenarios))
+		os.Exit(1)
+	}
+	log.Printf("PASS: %d scenarios", len(scenarios))
+}
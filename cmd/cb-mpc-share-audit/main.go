@@ -0,0 +1,410 @@
+//go:build !nompc
+
+// Command cb-mpc-share-audit lets auditors confirm that an encrypted share
+// backup is a valid share of a published key without the share ever being
+// revealed.
+//
+// Interactive audit of a keystore backup (the party decrypts locally and
+// proves knowledge of its share in zero knowledge):
+//
+//	auditor$ cb-mpc-share-audit challenge -party kms -blob kms.backup -out challenge.json
+//	party$   cb-mpc-share-audit prove -challenge challenge.json -scheme eddsa \
+//	             -backup-dir ./backup -backup-key backup.key -id <backup id> -out response.json
+//	auditor$ cb-mpc-share-audit verify -challenge challenge.json -response response.json \
+//	             -bundle bundle.json [-bundle-signer <hex ed25519 key>]
+//
+// Non-interactive audit of a PVE backup (publicly verifiable encryption):
+//
+//	auditor$ cb-mpc-share-audit verify-pve -party kms -blob kms.pve -bundle bundle.json \
+//	             -pve-keys pve-keys.json -label <label>
+//
+// verify and verify-pve print a JSON audit result and exit with status 1 if
+// the share is not valid.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/zk"
+
+	"solana-threshold-wallet/wallet/audit"
+	"solana-threshold-wallet/wallet/fingerprint"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("usage: cb-mpc-share-audit challenge|prove|verify|verify-pve [flags]")
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "challenge":
+		err = challenge(args)
+	case "prove":
+		err = prove(args)
+	case "verify":
+		err = verify(args)
+	case "verify-pve":
+		err = verifyPVE(args)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func challenge(args []string) error {
+	fs := flag.NewFlagSet("challenge", flag.ExitOnError)
+	party := fs.String("party", "", "party whose backup is audited")
+	blobPath := fs.String("blob", "", "encrypted backup blob")
+	out := fs.String("out", "challenge.json", "where to write the challenge")
+	fs.Parse(args)
+
+	blob, err := os.ReadFile(*blobPath)
+	if err != nil {
+		return err
+	}
+	c, err := audit.NewChallenge(*party, blob)
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, c)
+}
+
+func prove(args []string) error {
+	fs := flag.NewFlagSet("prove", flag.ExitOnError)
+	challengePath := fs.String("challenge", "challenge.json", "challenge issued by the auditor")
+	scheme := fs.String("scheme", "", "key share type: ecdsa or eddsa")
+	backupDir := fs.String("backup-dir", "", "directory holding the encrypted backup")
+	backupKey := fs.String("backup-key", "", "file holding the hex-encoded backup encryption key")
+	id := fs.String("id", "", "backup identifier")
+	out := fs.String("out", "response.json", "where to write the response")
+	fs.Parse(args)
+
+	var c audit.Challenge
+	if err := readJSON(*challengePath, &c); err != nil {
+		return err
+	}
+	key, err := readHex(*backupKey)
+	if err != nil {
+		return fmt.Errorf("backup key: %w", err)
+	}
+	medium, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: *backupDir, Key: key})
+	if err != nil {
+		return err
+	}
+	blob, err := os.ReadFile(filepath.Join(*backupDir, *id))
+	if err != nil {
+		return err
+	}
+
+	resp, err := audit.Respond(&c, blob, func(sessionID []byte) ([]byte, error) {
+		share, err := medium.Load(context.Background(), *id)
+		if err != nil {
+			return nil, err
+		}
+		defer clear(share)
+		qi, x, err := decodeShare(*scheme, share, c.Party)
+		if err != nil {
+			return nil, err
+		}
+		defer qi.Free()
+		defer clear(x.Bytes)
+		res, err := zk.ZKUCDLProve(&zk.ZKUCDLProveRequest{PublicKey: qi, Witness: x, SessionID: sessionID})
+		if err != nil {
+			return nil, err
+		}
+		return res.Proof, nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, resp)
+}
+
+// decodeShare returns the public share Qi of party and the secret share x_i
+// held in a serialized key share, after checking that x_i·G = Qi.
+func decodeShare(scheme string, data []byte, party string) (*curve.Point, *curve.Scalar, error) {
+	type share interface {
+		PartyName() (string, error)
+		XShare() (*curve.Scalar, error)
+		Qis() (map[string]*curve.Point, error)
+		Curve() (curve.Curve, error)
+	}
+	var s share
+	switch scheme {
+	case "ecdsa":
+		var k mpc.ECDSAMPCKey
+		if err := k.UnmarshalBinary(data); err != nil {
+			return nil, nil, err
+		}
+		defer k.Free()
+		s = k
+	case "eddsa":
+		var k mpc.EDDSAMPCKey
+		if err := k.UnmarshalBinary(data); err != nil {
+			return nil, nil, err
+		}
+		defer k.Free()
+		s = k
+	default:
+		return nil, nil, fmt.Errorf("unknown scheme %q", scheme)
+	}
+
+	name, err := s.PartyName()
+	if err != nil {
+		return nil, nil, err
+	}
+	if name != party {
+		return nil, nil, fmt.Errorf("backup holds the share of %q, challenge is for %q", name, party)
+	}
+	x, err := s.XShare()
+	if err != nil {
+		return nil, nil, err
+	}
+	qis, err := s.Qis()
+	if err != nil {
+		return nil, nil, err
+	}
+	var qi *curve.Point
+	for n, pt := range qis {
+		if n == party {
+			qi = pt
+		} else {
+			pt.Free()
+		}
+	}
+	if qi == nil {
+		return nil, nil, fmt.Errorf("key share has no public share for %q", party)
+	}
+	cv, err := s.Curve()
+	if err != nil {
+		qi.Free()
+		return nil, nil, err
+	}
+	defer cv.Free()
+	xG, err := cv.MultiplyGenerator(x)
+	if err != nil {
+		qi.Free()
+		return nil, nil, err
+	}
+	defer xG.Free()
+	if !bytes.Equal(xG.Bytes(), qi.Bytes()) {
+		qi.Free()
+		return nil, nil, fmt.Errorf("secret share does not match public share")
+	}
+	return qi, x, nil
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	challengePath := fs.String("challenge", "challenge.json", "challenge issued for this audit")
+	responsePath := fs.String("response", "response.json", "response returned by the party")
+	bundlePath := fs.String("bundle", "", "signed public bundle of the key")
+	signer := fs.String("bundle-signer", "", "hex Ed25519 key expected to have signed the bundle")
+	fs.Parse(args)
+
+	var c audit.Challenge
+	if err := readJSON(*challengePath, &c); err != nil {
+		return err
+	}
+	var resp audit.Response
+	if err := readJSON(*responsePath, &resp); err != nil {
+		return err
+	}
+	qi, err := publicShare(*bundlePath, *signer, c.Party)
+	if err != nil {
+		return err
+	}
+
+	res, err := audit.Verify(&c, &resp, qi, func(publicShare, proof, sessionID []byte) error {
+		pt, err := curve.NewPointFromBytes(publicShare)
+		if err != nil {
+			return err
+		}
+		defer pt.Free()
+		out, err := zk.ZKUCDLVerify(&zk.ZKUCDLVerifyRequest{PublicKey: pt, Proof: proof, SessionID: sessionID})
+		if err != nil {
+			return err
+		}
+		if !out.Valid {
+			return fmt.Errorf("proof rejected")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return report(res)
+}
+
+func verifyPVE(args []string) error {
+	fs := flag.NewFlagSet("verify-pve", flag.ExitOnError)
+	party := fs.String("party", "", "party whose backup is audited")
+	blobPath := fs.String("blob", "", "PVE ciphertext of the share")
+	bundlePath := fs.String("bundle", "", "signed public bundle of the key")
+	signer := fs.String("bundle-signer", "", "hex Ed25519 key expected to have signed the bundle")
+	keysPath := fs.String("pve-keys", "", `JSON file {"access_structure": ..., "public_keys": {"<leaf>": "<hex>"}}`)
+	label := fs.String("label", "", "label bound to the PVE backup")
+	fs.Parse(args)
+
+	blob, err := os.ReadFile(*blobPath)
+	if err != nil {
+		return err
+	}
+	var keys struct {
+		AccessStructure *mpc.AccessNode   `json:"access_structure"`
+		PublicKeys      map[string]string `json:"public_keys"`
+	}
+	if err := readJSON(*keysPath, &keys); err != nil {
+		return err
+	}
+	pubKeys := make(map[string]mpc.BaseEncPublicKey, len(keys.PublicKeys))
+	for name, h := range keys.PublicKeys {
+		if pubKeys[name], err = hex.DecodeString(h); err != nil {
+			return fmt.Errorf("public key of %s: %w", name, err)
+		}
+	}
+	bundle, err := loadBundle(*bundlePath, *signer)
+	if err != nil {
+		return err
+	}
+	qiBytes, ok := bundle.PublicShares[*party]
+	if !ok {
+		return fmt.Errorf("bundle has no public share for %q", *party)
+	}
+	cv, err := curveByName(bundle.Curve)
+	if err != nil {
+		return err
+	}
+	defer cv.Free()
+	qi, err := curve.NewPointFromBytes(qiBytes)
+	if err != nil {
+		return err
+	}
+	defer qi.Free()
+
+	sum := sha256.Sum256(blob)
+	res := &audit.Result{Party: *party, BlobSHA256: sum[:], PublicShare: qiBytes, Method: "pve"}
+	out, err := mpc.PVEVerify(&mpc.PVEVerifyRequest{
+		AccessStructure: &mpc.AccessStructure{Root: keys.AccessStructure, Curve: cv},
+		PublicKeys:      pubKeys,
+		EncryptedBundle: blob,
+		PublicShares:    []*curve.Point{qi},
+		Label:           *label,
+	})
+	switch {
+	case err != nil:
+		res.Err = err.Error()
+	case !out.Valid:
+		res.Err = "ciphertext rejected"
+	default:
+		res.Valid = true
+	}
+	res.CheckedAt = time.Now().UTC()
+	return report(res)
+}
+
+// publicShare returns the public share of party from a verified bundle.
+func publicShare(path, signer, party string) ([]byte, error) {
+	bundle, err := loadBundle(path, signer)
+	if err != nil {
+		return nil, err
+	}
+	qi, ok := bundle.PublicShares[party]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no public share for %q", party)
+	}
+	return qi, nil
+}
+
+// loadBundle parses and verifies a public bundle, optionally pinning the key
+// that signed it.
+func loadBundle(path, signer string) (*mpc.PublicBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	bundle, signed, err := mpc.ParsePublicBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	if signer != "" {
+		want, err := hex.DecodeString(signer)
+		if err != nil {
+			return nil, fmt.Errorf("bundle signer: %w", err)
+		}
+		if !bytes.Equal(want, signed.PublicKey) {
+			return nil, fmt.Errorf("bundle signed by %x, expected %s", signed.PublicKey, signer)
+		}
+	} else {
+		log.Printf("warning: bundle signer %x not pinned", signed.PublicKey)
+	}
+	return bundle, nil
+}
+
+func curveByName(name string) (curve.Curve, error) {
+	switch name {
+	case "secp256k1":
+		return curve.NewSecp256k1()
+	case "P-256":
+		return curve.NewP256()
+	case "Ed25519":
+		return curve.NewEd25519()
+	}
+	return nil, fmt.Errorf("unsupported curve %q", name)
+}
+
+func report(res *audit.Result) error {
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	if len(res.BlobSHA256) > 0 {
+		// On stderr, so that stdout stays JSON.
+		fmt.Fprintf(os.Stderr, "share fingerprint of %s: %s\n", res.Party, fingerprint.ShareDigest(res.BlobSHA256))
+	}
+	fmt.Println(string(data))
+	if !res.Valid {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func readHex(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,268 @@
+//go:build !nompc
+
+// Command cb-mpc-soak runs key generation and signing cycles for hours and
+// fails when the process's resource usage drifts, to catch leaks in the cgo
+// layer before they reach long-running signer daemons.
+//
+// Every cycle connects all parties inside this process over the next
+// transport in -transports, generates a key, signs -signatures messages, and
+// frees the keys, curve and connections again.  A steady-state process
+// returns to the same footprint after each cycle.
+//
+// Every -interval the command forces a garbage collection and samples the
+// resident set size, the bytes allocated by the native malloc, the live
+// native objects counted by memstats, the Go heap, the number of goroutines
+// and the number of open file descriptors.  The
+// first sample after -warmup is the baseline; once a later sample exceeds it
+// by more than the allowed growth, the command reports the drift and exits
+// with status 1.
+//
+// Usage:
+//
+//	cb-mpc-soak [-duration 4h] [-transports mocknet,tcp,ws] [-parties 3] \
+//	    [-curve ed25519] [-signatures 10] [-interval 1m] [-warmup 10m] \
+//	    [-max-rss-growth 64] [-max-native-growth 32] [-max-object-growth 0] \
+//	    [-max-goroutine-growth 8] [-max-fd-growth 8] [-samples soak.jsonl]
+//
+// Growth limits for memory are in MiB.  Resident set size and open file
+// descriptors are read from /proc and native allocations from glibc's
+// mallinfo2; where these are unavailable the metric is reported as -1 and
+// not checked.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/memstats"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+
+	"solana-threshold-wallet/cmd/internal/mpcnet"
+)
+
+const mib = 1 << 20
+
+var soakMessage = []byte("cb-mpc-soak message digest 32 b!")
+
+func main() {
+	duration := flag.Duration("duration", 4*time.Hour, "how long to run")
+	transports := flag.String("transports", "mocknet,tcp,ws", "comma-separated transports to cycle through: mocknet, tcp, ws")
+	parties := flag.Int("parties", 3, "number of parties (at least 3)")
+	curveName := flag.String("curve", "ed25519", "curve: ed25519 or secp256k1")
+	signatures := flag.Int("signatures", 10, "signatures per cycle")
+	interval := flag.Duration("interval", time.Minute, "time between samples")
+	warmup := flag.Duration("warmup", 10*time.Minute, "time before the baseline sample")
+	maxRSS := flag.Int64("max-rss-growth", 64, "allowed resident set growth over the baseline, in MiB")
+	maxNative := flag.Int64("max-native-growth", 32, "allowed native heap growth over the baseline, in MiB")
+	maxObjects := flag.Int("max-object-growth", 0, "allowed growth of live native keys, jobs, points and curves over the baseline")
+	maxGoroutines := flag.Int("max-goroutine-growth", 8, "allowed goroutine count growth over the baseline")
+	maxFDs := flag.Int("max-fd-growth", 8, "allowed open file descriptor growth over the baseline")
+	samplesPath := flag.String("samples", "", "append every sample as a JSON line to this file")
+	flag.Parse()
+
+	names := splitList(*transports)
+	if len(names) == 0 {
+		log.Fatalf("at least one transport is required")
+	}
+	if *parties < 3 {
+		log.Fatalf("at least 3 parties are required")
+	}
+	if *signatures < 1 {
+		log.Fatalf("at least one signature per cycle is required")
+	}
+	if *warmup >= *duration {
+		log.Fatalf("-warmup must be shorter than -duration")
+	}
+	limits := limits{
+		RSS:        *maxRSS * mib,
+		Native:     *maxNative * mib,
+		Objects:    *maxObjects,
+		Goroutines: *maxGoroutines,
+		FDs:        *maxFDs,
+	}
+
+	var out io.Writer = io.Discard
+	if *samplesPath != "" {
+		f, err := os.OpenFile(*samplesPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatalf("opening samples file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+
+	start := time.Now()
+	next := start.Add(*interval)
+	var (
+		baseline *sample
+		cycles   int
+		signed   int
+	)
+	for time.Since(start) < *duration {
+		name := names[cycles%len(names)]
+		if err := cycle(name, *curveName, *parties, *signatures); err != nil {
+			log.Fatalf("cycle %d (%s): %v", cycles+1, name, err)
+		}
+		cycles++
+		signed += *signatures
+		if time.Now().Before(next) {
+			continue
+		}
+		next = time.Now().Add(*interval)
+
+		s := take(time.Since(start), cycles, signed)
+		if err := enc.Encode(s); err != nil {
+			log.Fatalf("writing sample: %v", err)
+		}
+		log.Printf("%s", s)
+		if s.Elapsed < *warmup {
+			continue
+		}
+		if baseline == nil {
+			baseline = s
+			log.Printf("baseline taken after %d cycles", cycles)
+			continue
+		}
+		if drift := limits.check(baseline, s); len(drift) > 0 {
+			log.Printf("FAIL: resource usage drifted after %d cycles:", cycles)
+			for _, d := range drift {
+				log.Printf("  %s", d)
+			}
+			os.Exit(1)
+		}
+	}
+	if baseline == nil {
+		log.Fatalf("no baseline taken: -duration too short for -warmup and -interval")
+	}
+	log.Printf("PASS: %d cycles, %d signatures in %s without drift", cycles, signed, time.Since(start).Round(time.Second))
+}
+
+// cycle connects the parties over transport name, generates a key, signs
+// with it and releases everything again.
+func cycle(name, curveName string, n, signatures int) error {
+	cv, err := mpcnet.NewCurve(curveName)
+	if err != nil {
+		return err
+	}
+	defer cv.Free()
+
+	messengers, closeNet, err := mpcnet.Connect(name, n)
+	if err != nil {
+		return fmt.Errorf("connecting parties: %v", err)
+	}
+	defer closeNet()
+
+	pnames := mocknet.GeneratePartyNames(n)
+	p := mpcnet.NewProtocol(cv)
+	defer p.Free()
+	if err := mpcnet.RunParties(messengers, pnames, func(job *mpc.JobMP) error { return p.Keygen(job, cv) }); err != nil {
+		return fmt.Errorf("key generation: %v", err)
+	}
+	sign := func(job *mpc.JobMP) error { return p.Sign(job, soakMessage) }
+	for s := 0; s < signatures; s++ {
+		if err := mpcnet.RunParties(messengers, pnames, sign); err != nil {
+			return fmt.Errorf("signature %d: %v", s+1, err)
+		}
+	}
+	return nil
+}
+
+// sample is one measurement of the process's resource usage.  Metrics that
+// cannot be read on this platform are -1.
+type sample struct {
+	Time        time.Time     `json:"time"`
+	Elapsed     time.Duration `json:"elapsed_ns"`
+	Cycles      int           `json:"cycles"`
+	Signatures  int           `json:"signatures"`
+	RSSBytes    int64         `json:"rss_bytes"`
+	NativeBytes int64         `json:"native_bytes"`
+	Objects     int           `json:"native_objects"`
+	GoHeapBytes int64         `json:"go_heap_bytes"`
+	Goroutines  int           `json:"goroutines"`
+	FDs         int           `json:"fds"`
+}
+
+// take samples resource usage after returning freed memory to the OS, so
+// that garbage awaiting collection is not mistaken for a leak.
+func take(elapsed time.Duration, cycles, signatures int) *sample {
+	debug.FreeOSMemory()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	ns := memstats.Read()
+	return &sample{
+		Time:        time.Now().UTC(),
+		Elapsed:     elapsed,
+		Cycles:      cycles,
+		Signatures:  signatures,
+		RSSBytes:    residentBytes(),
+		NativeBytes: nativeBytes(),
+		Objects:     int(ns.Keys.Live + ns.Jobs.Live + ns.Points.Live + ns.Curves.Live),
+		GoHeapBytes: int64(ms.HeapInuse),
+		Goroutines:  runtime.NumGoroutine(),
+		FDs:         openFDs(),
+	}
+}
+
+func (s *sample) String() string {
+	return fmt.Sprintf("%s cycles=%d rss=%s native=%s objects=%d goheap=%s goroutines=%d fds=%d",
+		s.Elapsed.Round(time.Second), s.Cycles, fmtBytes(s.RSSBytes), fmtBytes(s.NativeBytes),
+		s.Objects, fmtBytes(s.GoHeapBytes), s.Goroutines, s.FDs)
+}
+
+// limits is the allowed growth of each metric over the baseline.
+type limits struct {
+	RSS        int64
+	Native     int64
+	Objects    int
+	Goroutines int
+	FDs        int
+}
+
+// check returns a description of every metric of s that grew beyond its
+// limit since base.
+func (l limits) check(base, s *sample) []string {
+	var drift []string
+	bytes := func(metric string, from, to, limit int64) {
+		if from >= 0 && to >= 0 && to-from > limit {
+			drift = append(drift, fmt.Sprintf("%s grew from %s to %s, more than %s", metric, fmtBytes(from), fmtBytes(to), fmtBytes(limit)))
+		}
+	}
+	count := func(metric string, from, to, limit int) {
+		if from >= 0 && to >= 0 && to-from > limit {
+			drift = append(drift, fmt.Sprintf("%s grew from %d to %d, more than %d", metric, from, to, limit))
+		}
+	}
+	bytes("resident set", base.RSSBytes, s.RSSBytes, l.RSS)
+	bytes("native heap", base.NativeBytes, s.NativeBytes, l.Native)
+	count("live native objects", base.Objects, s.Objects, l.Objects)
+	count("goroutines", base.Goroutines, s.Goroutines, l.Goroutines)
+	count("open file descriptors", base.FDs, s.FDs, l.FDs)
+	return drift
+}
+
+func fmtBytes(n int64) string {
+	if n < 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1fMiB", float64(n)/mib)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+//go:build linux && !nompc
+
+package main
+
+/*
+#include <malloc.h>
+
+static long long native_in_use(void) {
+#if defined(__GLIBC__) && (__GLIBC__ > 2 || (__GLIBC__ == 2 && __GLIBC_MINOR__ >= 33))
+	struct mallinfo2 mi = mallinfo2();
+	return (long long)(mi.uordblks + mi.hblkhd);
+#else
+	return -1;
+#endif
+}
+*/
+import "C"
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// residentBytes returns VmRSS from /proc/self/status.
+func residentBytes() int64 {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		rest, ok := strings.CutPrefix(sc.Text(), "VmRSS:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rest), "kB")), 10, 64)
+		if err != nil {
+			return -1
+		}
+		return kb * 1024
+	}
+	return -1
+}
+
+// nativeBytes returns the bytes handed out by malloc, in small blocks and
+// mmapped large ones, which covers the allocations of the native library.
+func nativeBytes() int64 {
+	return int64(C.native_in_use())
+}
+
+// openFDs counts the entries of /proc/self/fd.
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	// One entry is the descriptor ReadDir itself had open.
+	return len(entries) - 1
+}
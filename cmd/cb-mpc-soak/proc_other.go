@@ -0,0 +1,9 @@
+//go:build !linux && !nompc
+
+package main
+
+func residentBytes() int64 { return -1 }
+
+func nativeBytes() int64 { return -1 }
+
+func openFDs() int { return -1 }
@@ -0,0 +1,342 @@
+//go:build !nompc
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+
+	"solana-threshold-wallet/cmd/internal/mpcnet"
+	"solana-threshold-wallet/wallet/canary"
+	"solana-threshold-wallet/wallet/ceremony"
+	"solana-threshold-wallet/wallet/client"
+	"solana-threshold-wallet/wallet/fingerprint"
+	"solana-threshold-wallet/wallet/recoveryphrase"
+)
+
+// errDeclined aborts a ceremony when the operator does not confirm a step.
+var errDeclined = errors.New("ceremony aborted by operator")
+
+// step announces a ceremony step and asks the operator to start it.
+func (c *console) step(n, total int, name, description string) error {
+	fmt.Fprintf(c.out, "\n== Step %d/%d: %s ==\n%s\n", n, total, name, description)
+	if !c.confirm("Continue?") {
+		return errDeclined
+	}
+	return nil
+}
+
+// backupID is where the ceremony stored a party's share; see ceremony.Ceremony.
+func backupID(r *ceremony.Report, party string) string {
+	return r.ID + "." + party
+}
+
+// matchesReport reports whether share is the share of party recorded in r.
+func matchesReport(r *ceremony.Report, party string, share []byte) bool {
+	for _, s := range r.Shares {
+		if s.Party == party {
+			return s.Matches(share)
+		}
+	}
+	return false
+}
+
+// refresh re-shares a key among its parties and replaces the backups.  The
+// public key is unchanged, but shares taken before the refresh stop working
+// together with the new ones.
+func (c *console) refresh(ctx context.Context, keyID string) error {
+	if c.backups == nil {
+		return fmt.Errorf("no backups; start the shell with -backup-dir")
+	}
+	signed, err := c.report(keyID)
+	if err != nil {
+		return err
+	}
+	r := &signed.Report
+	parties := r.Params.Parties
+
+	if err := c.step(1, 3, "Load shares", fmt.Sprintf("Read the backups of %v from %s.", parties, c.backups.Name())); err != nil {
+		return err
+	}
+	shares := make([][]byte, len(parties))
+	defer func() {
+		for _, s := range shares {
+			clear(s)
+		}
+	}()
+	for i, party := range parties {
+		if shares[i], err = c.backups.Load(ctx, backupID(r, party)); err != nil {
+			return fmt.Errorf("loading share of %s: %w", party, err)
+		}
+		status := "matches the ceremony report"
+		if !matchesReport(r, party, shares[i]) {
+			status = "differs from the ceremony report (refreshed before)"
+		}
+		fmt.Fprintf(c.out, "  %-12s loaded, %s\n", party, status)
+	}
+
+	if err := c.step(2, 3, "Refresh", "Run the refresh protocol for all parties and check the public key."); err != nil {
+		return err
+	}
+	fresh, err := refreshShares(r.Params.Curve, parties, shares)
+	defer func() {
+		for _, s := range fresh {
+			clear(s)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	for i, party := range parties {
+		if err := checkShare(r, party, fresh[i]); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "  %-12s new share, public key unchanged\n", party)
+	}
+	if err := c.canary(ctx, r, canary.EventRefresh, fresh); err != nil {
+		return fmt.Errorf("%w; backups left unchanged", err)
+	}
+
+	if err := c.step(3, 3, "Replace backups", "Overwrite every backup with its refreshed share.  Copies of the old shares must be destroyed afterwards."); err != nil {
+		return err
+	}
+	for i, party := range parties {
+		id := backupID(r, party)
+		if err := c.backups.Store(ctx, id, fresh[i]); err != nil {
+			return fmt.Errorf("storing share of %s: %w", party, err)
+		}
+		restored, err := c.backups.Load(ctx, id)
+		if err != nil {
+			return fmt.Errorf("reading back share of %s: %w", party, err)
+		}
+		if !bytes.Equal(restored, fresh[i]) {
+			return fmt.Errorf("backup of %s does not match the refreshed share", party)
+		}
+		fmt.Fprintf(c.out, "  %-12s stored and verified, fingerprint %s\n", party, fingerprint.Share(fresh[i]))
+	}
+	fmt.Fprintf(c.out, "\nKey %s refreshed.  Deliver the new shares to the party hosts.\n", keyID)
+	return nil
+}
+
+// recover restores a party's share from its backup into a new medium, e.g.
+// the store of a replacement party host.
+func (c *console) recover(ctx context.Context, keyID, party, dir, keyFile string) error {
+	if c.backups == nil {
+		return fmt.Errorf("no backups; start the shell with -backup-dir")
+	}
+	signed, err := c.report(keyID)
+	if err != nil {
+		return err
+	}
+	r := &signed.Report
+	known := false
+	for _, p := range r.Params.Parties {
+		known = known || p == party
+	}
+	if !known {
+		return fmt.Errorf("%s is not a party of key %s", party, keyID)
+	}
+	var key []byte
+	if keyFile != "" {
+		if key, err = recoveryphrase.ReadKeyFile(keyFile); err != nil {
+			return fmt.Errorf("target key: %v", err)
+		}
+	}
+
+	if err := c.step(1, 2, "Load and check share", fmt.Sprintf("Read the backup of %s from %s and check it against the key.", party, c.backups.Name())); err != nil {
+		return err
+	}
+	share, err := c.backups.Load(ctx, backupID(r, party))
+	if err != nil {
+		return fmt.Errorf("loading share of %s: %w", party, err)
+	}
+	defer clear(share)
+	if err := checkShare(r, party, share); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "  share of %s is valid for public key %x\n", party, r.PublicKey)
+	fmt.Fprintf(c.out, "  key fingerprint %s\n", fingerprint.Key(r.PublicKey))
+
+	target, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: dir, Key: key})
+	if err != nil {
+		return err
+	}
+	if err := c.step(2, 2, "Restore share", fmt.Sprintf("Write the share to %s.", target.Name())); err != nil {
+		return err
+	}
+	id := backupID(r, party)
+	if err := target.Store(ctx, id, share); err != nil {
+		return fmt.Errorf("storing share: %w", err)
+	}
+	restored, err := target.Load(ctx, id)
+	if err != nil {
+		return fmt.Errorf("reading back share: %w", err)
+	}
+	if !bytes.Equal(restored, share) {
+		return fmt.Errorf("restored share does not match the backup")
+	}
+	if c.canaryLog != "" {
+		// Sign with the restored share and the backups of the other parties.
+		shares := make([][]byte, len(r.Params.Parties))
+		defer func() {
+			for _, s := range shares {
+				clear(s)
+			}
+		}()
+		for i, p := range r.Params.Parties {
+			if p == party {
+				shares[i] = restored
+				continue
+			}
+			if shares[i], err = c.backups.Load(ctx, backupID(r, p)); err != nil {
+				return fmt.Errorf("loading share of %s: %w", p, err)
+			}
+		}
+		if err := c.canary(ctx, r, canary.EventRecovery, shares); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.out, "\nShare of %s restored to %s as %s.\n", party, target.Name(), id)
+	return nil
+}
+
+// canary signs a canary message with shares, one per party of r in order,
+// verifies it against the key and records it in the canary log.  Only Ed25519
+// keys are checked.
+func (c *console) canary(ctx context.Context, r *ceremony.Report, event canary.Event, shares [][]byte) error {
+	if c.canaryLog == "" {
+		return nil
+	}
+	if r.Params.Curve != "ed25519" {
+		fmt.Fprintf(c.out, "  canary skipped: only ed25519 keys are checked\n")
+		return nil
+	}
+	res, err := canary.Run(ctx, canary.Config{
+		KeyID:    r.Params.KeyID,
+		Event:    event,
+		Verifier: client.Ed25519(r.PublicKey),
+		Sign: func(_ context.Context, message []byte) ([]byte, error) {
+			return signShares(r.Params.Parties, shares, message)
+		},
+		Log: &canary.FileLog{Path: c.canaryLog},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "  canary %s signed and verified, recorded in %s\n", res.ID, c.canaryLog)
+	return nil
+}
+
+// signShares runs the EdDSA signing protocol for all parties inside this
+// process and returns the signature of message.
+func signShares(parties []string, shares [][]byte, message []byte) ([]byte, error) {
+	var sig []byte
+	err := mpcnet.RunParties(mocknet.NewMockNetwork(len(parties)), parties, func(job *mpc.JobMP) error {
+		var k mpc.EDDSAMPCKey
+		if err := k.UnmarshalBinary(shares[job.GetPartyIndex()]); err != nil {
+			return err
+		}
+		defer k.Free()
+		resp, err := mpc.EDDSAMPCSign(job, &mpc.EDDSAMPCSignRequest{KeyShare: k, Message: message})
+		if err != nil {
+			return err
+		}
+		if job.GetPartyIndex() == 0 {
+			sig = resp.Signature
+		}
+		return nil
+	})
+	return sig, err
+}
+
+// mpKey is the part of ECDSAMPCKey and EDDSAMPCKey the ceremonies use.
+type mpKey interface {
+	MarshalBinary() ([]byte, error)
+	PartyName() (string, error)
+	Q() (*curve.Point, error)
+	Free()
+}
+
+// unmarshalShare decodes a share of a key on the named curve.
+func unmarshalShare(curveName string, data []byte) (mpKey, error) {
+	switch curveName {
+	case "secp256k1":
+		k := new(mpc.ECDSAMPCKey)
+		return k, k.UnmarshalBinary(data)
+	case "ed25519":
+		k := new(mpc.EDDSAMPCKey)
+		return k, k.UnmarshalBinary(data)
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", curveName)
+	}
+}
+
+// checkShare checks that share belongs to party and to the report's key.
+func checkShare(r *ceremony.Report, party string, share []byte) error {
+	k, err := unmarshalShare(r.Params.Curve, share)
+	if err != nil {
+		return fmt.Errorf("decoding share of %s: %w", party, err)
+	}
+	defer k.Free()
+	name, err := k.PartyName()
+	if err != nil {
+		return err
+	}
+	if name != party {
+		return fmt.Errorf("share of %s belongs to party %q", party, name)
+	}
+	q, err := k.Q()
+	if err != nil {
+		return err
+	}
+	defer q.Free()
+	if !bytes.Equal(q.Bytes(), r.PublicKey) {
+		return fmt.Errorf("share of %s is for a different public key", party)
+	}
+	return nil
+}
+
+// refreshShares runs the refresh protocol for all parties inside this process
+// and returns their new serialized shares.
+func refreshShares(curveName string, parties []string, shares [][]byte) ([][]byte, error) {
+	sid := make([]byte, 32)
+	if _, err := rand.Read(sid); err != nil {
+		return nil, err
+	}
+	fresh := make([][]byte, len(parties))
+	err := mpcnet.RunParties(mocknet.NewMockNetwork(len(parties)), parties, func(job *mpc.JobMP) error {
+		i := job.GetPartyIndex()
+		k, err := unmarshalShare(curveName, shares[i])
+		if err != nil {
+			return err
+		}
+		defer k.Free()
+		var next mpKey
+		switch k := k.(type) {
+		case *mpc.ECDSAMPCKey:
+			resp, err := mpc.ECDSAMPCRefresh(job, &mpc.ECDSAMPCRefreshRequest{KeyShare: *k, SessionID: sid})
+			if err != nil {
+				return err
+			}
+			next = &resp.NewKeyShare
+		case *mpc.EDDSAMPCKey:
+			resp, err := mpc.EDDSAMPCRefresh(job, &mpc.EDDSAMPCRefreshRequest{KeyShare: *k, SessionID: sid})
+			if err != nil {
+				return err
+			}
+			next = &resp.NewKeyShare
+		}
+		defer next.Free()
+		fresh[i], err = next.MarshalBinary()
+		return err
+	})
+	return fresh, err
+}
@@ -0,0 +1,487 @@
+//go:build !nompc
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+
+	"solana-threshold-wallet/wallet/ceremony"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/display"
+	"solana-threshold-wallet/wallet/fingerprint"
+)
+
+// errQuit ends the shell.
+var errQuit = errors.New("quit")
+
+// screenWidth is the width transactions are decoded at; wider than a device
+// screen so that most addresses fit on one line.
+const screenWidth = 48
+
+const help = `Commands:
+  keys                               list keys from the signed ceremony reports
+  sessions [all|<state>]             list pending sessions, all sessions or those in a state
+  show <session>                     decode a session's transaction and approval status
+  history <session>                  print a session's event log
+  approve <session>                  approve a session after confirming its decoded transaction
+  reject <session> <reason...>       cancel a session
+  hold <session>                     place a session under legal hold
+  release <session>                  release a session's legal hold
+  purge                              delete finished sessions past -retain-finalized
+                                     or -retain-failed, sparing held ones
+  refresh <key>                      re-share a key without changing it, step by step;
+                                     with -canary-log the new shares sign a canary
+                                     before the backups are replaced
+  recover <key> <party> <dir> [key]  restore a party's share from its backup into dir,
+                                     encrypted with the key in file [key] (hex or
+                                     BIP-39 phrase) if given, then sign a canary
+                                     with it if -canary-log is set
+  help                               show this text
+  quit                               leave the shell`
+
+// console is an interactive operator session.
+type console struct {
+	operator string
+	in       *bufio.Scanner
+	out      io.Writer
+	reports  string
+	assets   map[string]display.Asset
+	// formatter words the transaction summary shown above the screens.
+	formatter *display.Formatter
+	coord     *coordinator.Coordinator
+	store     coordinator.PurgeStore
+	retention coordinator.Retention
+	backups   keystore.Medium
+	// canaryLog records the canaries signed after refresh and recovery;
+	// empty disables them.
+	canaryLog string
+}
+
+// Run reads and executes commands until quit or end of input.
+func (c *console) Run() error {
+	fmt.Fprintln(c.out, `cb-mpc-wallet shell; type "help" for commands`)
+	ctx := context.Background()
+	for {
+		line, ok := c.prompt("> ")
+		if !ok {
+			fmt.Fprintln(c.out)
+			return c.in.Err()
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		err := c.exec(ctx, fields[0], fields[1:])
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(c.out, "error: %v\n", err)
+		}
+	}
+}
+
+// prompt prints p and reads a line.  It returns false at end of input.
+func (c *console) prompt(p string) (string, bool) {
+	fmt.Fprint(c.out, p)
+	if !c.in.Scan() {
+		return "", false
+	}
+	return strings.TrimSpace(c.in.Text()), true
+}
+
+// confirm asks a yes/no question; anything but "y" or "yes" declines.
+func (c *console) confirm(question string) bool {
+	answer, _ := c.prompt(question + " [y/N] ")
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+func (c *console) exec(ctx context.Context, cmd string, args []string) error {
+	switch cmd {
+	case "help", "?":
+		fmt.Fprintln(c.out, help)
+		return nil
+	case "quit", "exit":
+		return errQuit
+	case "keys":
+		return c.keys()
+	case "sessions":
+		return c.sessions(ctx, args)
+	case "show":
+		return c.withSession(args, 1, func(id string) error { return c.show(ctx, id) })
+	case "history":
+		return c.withSession(args, 1, func(id string) error { return c.history(ctx, id) })
+	case "approve":
+		return c.withSession(args, 1, func(id string) error { return c.approve(ctx, id) })
+	case "reject":
+		return c.withSession(args, 2, func(id string) error { return c.reject(ctx, id, strings.Join(args[1:], " ")) })
+	case "hold":
+		return c.withSession(args, 1, func(id string) error { return c.hold(ctx, id, true) })
+	case "release":
+		return c.withSession(args, 1, func(id string) error { return c.hold(ctx, id, false) })
+	case "purge":
+		if c.coord == nil {
+			return fmt.Errorf("no session store; start the shell with -sessions")
+		}
+		return c.purge(ctx)
+	case "refresh":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: refresh <key>")
+		}
+		return c.refresh(ctx, args[0])
+	case "recover":
+		if len(args) != 3 && len(args) != 4 {
+			return fmt.Errorf("usage: recover <key> <party> <dir> [key file]")
+		}
+		keyFile := ""
+		if len(args) == 4 {
+			keyFile = args[3]
+		}
+		return c.recover(ctx, args[0], args[1], args[2], keyFile)
+	default:
+		return fmt.Errorf("unknown command %q; type \"help\"", cmd)
+	}
+}
+
+// withSession checks that the session store is configured and that at least
+// n arguments, the first being the session ID, were given.
+func (c *console) withSession(args []string, n int, fn func(id string) error) error {
+	if c.coord == nil {
+		return fmt.Errorf("no session store; start the shell with -sessions")
+	}
+	if len(args) < n {
+		return fmt.Errorf("missing arguments; type \"help\"")
+	}
+	return fn(args[0])
+}
+
+// loadReports reads and verifies every signed ceremony report, by key ID.
+func (c *console) loadReports() (map[string]*ceremony.SignedReport, error) {
+	if c.reports == "" {
+		return nil, fmt.Errorf("no report directory; start the shell with -reports")
+	}
+	paths, err := filepath.Glob(filepath.Join(c.reports, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	reports := make(map[string]*ceremony.SignedReport, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r := new(ceremony.SignedReport)
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if err := r.Verify(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, dup := reports[r.Report.Params.KeyID]; dup {
+			return nil, fmt.Errorf("%s: duplicate report for key %q", path, r.Report.Params.KeyID)
+		}
+		reports[r.Report.Params.KeyID] = r
+	}
+	return reports, nil
+}
+
+func (c *console) report(keyID string) (*ceremony.SignedReport, error) {
+	reports, err := c.loadReports()
+	if err != nil {
+		return nil, err
+	}
+	r, ok := reports[keyID]
+	if !ok {
+		return nil, fmt.Errorf("no ceremony report for key %q", keyID)
+	}
+	return r, nil
+}
+
+func (c *console) keys() error {
+	reports, err := c.loadReports()
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		fmt.Fprintln(c.out, "no keys")
+		return nil
+	}
+	ids := make([]string, 0, len(reports))
+	for id := range reports {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		r := reports[id].Report
+		fmt.Fprintf(c.out, "%-16s %-9s %d-of-%d %-24s %x\n", id, r.Params.Curve, r.Params.Threshold,
+			len(r.Params.Parties), strings.Join(r.Params.Parties, ","), r.PublicKey)
+		fmt.Fprintf(c.out, "%16s ceremony %s on %s, report verified\n", "", r.ID, r.FinishedAt.Format(time.DateOnly))
+		fmt.Fprintf(c.out, "%16s fingerprint %s\n", "", fingerprint.Key(r.PublicKey))
+	}
+	return nil
+}
+
+func (c *console) sessions(ctx context.Context, args []string) error {
+	if c.coord == nil {
+		return fmt.Errorf("no session store; start the shell with -sessions")
+	}
+	filter := coordinator.Filter{NonTerminal: true}
+	if len(args) > 0 {
+		switch args[0] {
+		case "all":
+			filter = coordinator.Filter{}
+		default:
+			state, err := parseState(args[0])
+			if err != nil {
+				return err
+			}
+			filter = coordinator.Filter{States: []coordinator.State{state}}
+		}
+	}
+	sessions, err := c.coord.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Fprintln(c.out, "no sessions")
+		return nil
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	for _, s := range sessions {
+		fmt.Fprintf(c.out, "%-34s %-18s %-16s %s\n", s.ID, s.State, s.Request.Chain, c.amount(s))
+		if s.State == coordinator.StatePolicyEvaluated && s.Decision != nil && s.Decision.RequiredApprovals > 0 {
+			fmt.Fprintf(c.out, "%34s approvals %d/%d, due %s\n", "", len(s.Approvals),
+				s.Decision.RequiredApprovals, s.ApprovalDeadline.Local().Format(time.DateTime))
+		}
+	}
+	return nil
+}
+
+func parseState(name string) (coordinator.State, error) {
+	for s := coordinator.StateCreated; s <= coordinator.StateFailed; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown state %q", name)
+}
+
+// asset returns the display asset of a summary's transferred asset and of
+// its fee.  Unknown assets are shown in smallest units.
+func (c *console) asset(summary *chain.Summary) (display.Asset, display.Asset) {
+	units := display.Asset{Ticker: "units", Decimals: 0}
+	fee, ok := c.assets[summary.Chain]
+	if !ok {
+		fee = units
+	}
+	if summary.Token == "" {
+		return fee, fee
+	}
+	asset, ok := c.assets[summary.Token]
+	if !ok {
+		asset = units
+	}
+	return asset, fee
+}
+
+// amount renders a session's transferred amount, once it is known.
+func (c *console) amount(s *coordinator.Session) string {
+	if s.Summary == nil || s.Summary.Amount == nil {
+		return "-"
+	}
+	asset, _ := c.asset(s.Summary)
+	return display.FormatAmount(s.Summary.Amount, asset)
+}
+
+// confirmationCode is what an operator types to approve a transaction: the
+// start of the digest the approval screens are built from.
+func confirmationCode(digest [32]byte) string {
+	return fmt.Sprintf("%X", digest[:3])
+}
+
+func (c *console) show(ctx context.Context, id string) error {
+	s, err := c.coord.Session(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = c.describe(ctx, s)
+	return err
+}
+
+// describe prints a session with its decoded transaction and returns the
+// approval screens, which are nil until the transaction is built.
+func (c *console) describe(ctx context.Context, s *coordinator.Session) (*display.Payload, error) {
+	fmt.Fprintf(c.out, "Session   %s\n", s.ID)
+	fmt.Fprintf(c.out, "State     %s (updated %s)\n", s.State, s.UpdatedAt.Local().Format(time.DateTime))
+	fmt.Fprintf(c.out, "Chain     %s, priority %s\n", s.Request.Chain, s.Request.Priority)
+	if s.Request.Reference != "" {
+		fmt.Fprintf(c.out, "Reference %s\n", s.Request.Reference)
+	}
+	if s.Err != "" {
+		fmt.Fprintf(c.out, "Error     %s\n", s.Err)
+	}
+	var payload *display.Payload
+	if s.Summary != nil {
+		asset, fee := c.asset(s.Summary)
+		var err error
+		payload, err = display.Build(s.Summary, asset, display.Options{Width: screenWidth, FeeAsset: fee, FullAddresses: true})
+		if err != nil {
+			return nil, fmt.Errorf("decoding transaction: %v", err)
+		}
+		prompt, err := c.formatter.Prompt(ctx, s.Summary, asset, fee)
+		if err != nil {
+			return nil, fmt.Errorf("decoding transaction: %v", err)
+		}
+		fmt.Fprintf(c.out, "Summary   %s\n", prompt.Headline)
+		for _, q := range prompt.Quotes {
+			fmt.Fprintf(c.out, "          1 %s = %s %s (%s)\n", q.Asset, q.Price.FloatString(2), q.Currency, q.Source)
+		}
+		fmt.Fprintln(c.out, "Transaction:")
+		for _, screen := range payload.Screens {
+			fmt.Fprintf(c.out, "  %-14s %s\n", screen.Title, strings.Join(screen.Lines, " "))
+		}
+		fmt.Fprintf(c.out, "Confirmation code %s\n", confirmationCode(payload.Digest))
+	}
+	if d := s.Decision; d != nil {
+		fmt.Fprintf(c.out, "Policy    %s\n", d.Reason)
+		if d.RequiredApprovals > 0 {
+			fmt.Fprintf(c.out, "Approvals %d of %d: %s\n", len(s.Approvals), d.RequiredApprovals, strings.Join(s.Approvals, ", "))
+			if pending := s.PendingApprovers(); len(pending) > 0 {
+				fmt.Fprintf(c.out, "Waiting   %s\n", strings.Join(pending, ", "))
+			}
+			if !s.ApprovalDeadline.IsZero() {
+				fmt.Fprintf(c.out, "Due       %s\n", s.ApprovalDeadline.Local().Format(time.DateTime))
+			}
+		}
+	}
+	for _, r := range s.Reviews {
+		verdict := "passed"
+		if r.Veto {
+			verdict = "vetoed: " + r.Reason
+		}
+		fmt.Fprintf(c.out, "Review    %s %s\n", r.Reviewer, verdict)
+	}
+	if s.TxID != "" {
+		fmt.Fprintf(c.out, "TxID      %s\n", s.TxID)
+	}
+	return payload, nil
+}
+
+func (c *console) history(ctx context.Context, id string) error {
+	events, err := c.coord.History(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		detail := ""
+		switch {
+		case e.Approver != "":
+			detail = e.Approver
+		case e.Review != nil:
+			detail = e.Review.Reviewer
+		case e.Round > 0:
+			detail = fmt.Sprintf("round %d", e.Round)
+		case e.TxID != "":
+			detail = e.TxID
+		case e.Err != "":
+			detail = e.Err
+		}
+		fmt.Fprintf(c.out, "%3d %s %-18s %s\n", e.Seq, e.Time.Local().Format(time.DateTime), e.Type, detail)
+	}
+	return nil
+}
+
+// approve shows the decoded transaction and records the operator's approval
+// once they type its confirmation code.
+func (c *console) approve(ctx context.Context, id string) error {
+	if c.operator == "" {
+		return fmt.Errorf("no operator; start the shell with -operator")
+	}
+	s, err := c.coord.Session(ctx, id)
+	if err != nil {
+		return err
+	}
+	if s.State != coordinator.StatePolicyEvaluated {
+		return fmt.Errorf("session is %s, not waiting for approval", s.State)
+	}
+	payload, err := c.describe(ctx, s)
+	if err != nil {
+		return err
+	}
+	code, _ := c.prompt(fmt.Sprintf("%s, type the confirmation code to approve: ", c.operator))
+	if !strings.EqualFold(code, confirmationCode(payload.Digest)) {
+		fmt.Fprintln(c.out, "not approved")
+		return nil
+	}
+	if s, err = c.coord.Approve(ctx, id, c.operator); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "approved; %d of %d approvals\n", len(s.Approvals), s.Decision.RequiredApprovals)
+	return nil
+}
+
+func (c *console) reject(ctx context.Context, id, reason string) error {
+	if c.operator == "" {
+		return fmt.Errorf("no operator; start the shell with -operator")
+	}
+	if !c.confirm(fmt.Sprintf("Cancel session %s?", id)) {
+		return nil
+	}
+	s, err := c.coord.Fail(ctx, id, fmt.Sprintf("rejected by %s: %s", c.operator, reason))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "session %s\n", s.State)
+	return nil
+}
+
+// hold places a session under legal hold or releases it.
+func (c *console) hold(ctx context.Context, id string, hold bool) error {
+	if err := c.store.SetHold(ctx, id, hold); err != nil {
+		return err
+	}
+	if hold {
+		fmt.Fprintf(c.out, "session %s is under legal hold\n", id)
+	} else {
+		fmt.Fprintf(c.out, "legal hold on session %s released\n", id)
+	}
+	return nil
+}
+
+// purge deletes the finished sessions whose retention period is over.
+func (c *console) purge(ctx context.Context) error {
+	if c.retention == (coordinator.Retention{}) {
+		return fmt.Errorf("no retention; start the shell with -retain-finalized or -retain-failed")
+	}
+	p, err := coordinator.NewPurger(coordinator.PurgerConfig{Store: c.store, Retention: c.retention})
+	if err != nil {
+		return err
+	}
+	if !c.confirm("Delete finished sessions past their retention period?") {
+		return nil
+	}
+	r, err := p.Purge(ctx)
+	fmt.Fprintf(c.out, "%d sessions deleted, %d kept under legal hold\n", len(r.Purged), len(r.Held))
+	return err
+}
+
+// noSigner refuses to sign; the shell's coordinator never runs sessions.
+type noSigner struct{}
+
+func (noSigner) Sign(context.Context, *coordinator.SignRequest) ([]byte, error) {
+	return nil, fmt.Errorf("the wallet shell does not sign")
+}
@@ -0,0 +1,189 @@
+//go:build !nompc
+
+// Command cb-mpc-wallet is the operator tool for a threshold wallet.
+//
+// The shell subcommand opens an interactive session for manual operations:
+// listing keys, inspecting pending signing sessions, approving or rejecting
+// them after reviewing the decoded transaction, and running refresh and
+// recovery ceremonies one confirmed step at a time.
+//
+//	cb-mpc-wallet shell -operator alice -sessions ./sessions -reports ./reports \
+//	    -backup-dir ./backup -backup-key backup.key \
+//	    [-assets solana-mainnet=SOL:9,<usdc mint>=USDC:6] \
+//	    [-locale de-DE] [-currency EUR -prices SOL=160.5,USDC=0.92] \
+//	    [-retain-finalized 43800h -retain-failed 8760h] [-canary-log canary.log] \
+//	    [-pin-party pin] [-pinentry pinentry-curses]
+//
+// Type "help" at the prompt for the list of commands.  Like cb-mpc-ceremony,
+// refresh and recovery run every party inside this process, so they must be
+// run on the trusted, air-gapped ceremony host.  With -canary-log, the shares
+// produced by refresh and recovery first sign a canary message, which is
+// verified against the key and recorded, so a broken share set is caught
+// before funds depend on it.
+//
+// Without -backup-key, the backup key's recovery phrase is asked for at the
+// terminal with masked input, or through the -pinentry program, so that it
+// need not be kept on disk.  With -pin-party, the backup of that party is
+// PIN-sealed as by cb-mpc-ceremony -pin-party, and its PIN is asked for the
+// first time refresh or recovery reads it.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+
+	"solana-threshold-wallet/cmd/internal/pinlock"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/display"
+	"solana-threshold-wallet/wallet/price"
+	"solana-threshold-wallet/wallet/prompt"
+	"solana-threshold-wallet/wallet/recoveryphrase"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("usage: cb-mpc-wallet shell [flags]")
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "shell":
+		err = shell(args)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func shell(args []string) error {
+	fs := flag.NewFlagSet("shell", flag.ExitOnError)
+	operator := fs.String("operator", "", "name recorded on approvals and rejections")
+	sessionsDir := fs.String("sessions", "", "coordinator session store directory")
+	reportsDir := fs.String("reports", "", "directory of signed ceremony reports, one per key")
+	backupDir := fs.String("backup-dir", "", "directory holding the encrypted share backups")
+	backupKey := fs.String("backup-key", "", "file holding the 32-byte backup encryption key as hex or a BIP-39 phrase; empty asks for the phrase")
+	assets := fs.String("assets", "", "comma-separated chain=TICKER:DECIMALS entries, keyed by chain or token")
+	locale := fs.String("locale", "en-US", "locale of transaction summaries: en-US, de-DE or fr-FR")
+	currency := fs.String("currency", "", "fiat currency transaction summaries show values in, e.g. USD")
+	prices := fs.String("prices", "", "comma-separated TICKER=PRICE entries in -currency")
+	retainFinalized := fs.Duration("retain-finalized", 0, "how long purge keeps finalized sessions; 0 keeps them forever")
+	retainFailed := fs.Duration("retain-failed", 0, "how long purge keeps failed sessions; 0 keeps them forever")
+	canaryLog := fs.String("canary-log", "", "file recording canary signatures after refresh and recovery; empty disables them")
+	pinParty := fs.String("pin-party", "", "party whose backup is PIN-sealed")
+	pinentry := fs.String("pinentry", "", "pinentry program asking for secrets instead of the terminal")
+	fs.Parse(args)
+
+	sh := &console{
+		operator:  *operator,
+		in:        bufio.NewScanner(os.Stdin),
+		out:       os.Stdout,
+		reports:   *reportsDir,
+		canaryLog: *canaryLog,
+		retention: coordinator.Retention{
+			Finalized: *retainFinalized,
+			Failed:    *retainFailed,
+		},
+	}
+	var err error
+	if sh.assets, err = parseAssets(*assets); err != nil {
+		return fmt.Errorf("assets: %v", err)
+	}
+	sh.formatter = &display.Formatter{Currency: *currency}
+	if sh.formatter.Locale, err = display.LookupLocale(*locale); err != nil {
+		return err
+	}
+	if *prices != "" {
+		if sh.formatter.Oracle, err = price.ParseStatic(*currency, splitList(*prices)); err != nil {
+			return fmt.Errorf("prices: %v", err)
+		}
+	}
+	if *sessionsDir != "" {
+		store, err := coordinator.NewFileStore(*sessionsDir)
+		if err != nil {
+			return fmt.Errorf("session store: %v", err)
+		}
+		sh.store = store
+		// The shell only approves and cancels sessions; signing is left to
+		// the coordinator service.
+		sh.coord, err = coordinator.New(coordinator.Config{Store: store, Signer: noSigner{}})
+		if err != nil {
+			return err
+		}
+	}
+	if *backupDir != "" {
+		prompter, err := prompt.New(prompt.Config{Pinentry: *pinentry})
+		if err != nil {
+			return fmt.Errorf("prompt: %v", err)
+		}
+		key, err := backupKeyOf(*backupKey, prompter)
+		if err != nil {
+			return fmt.Errorf("backup key: %v", err)
+		}
+		files, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: *backupDir, Key: key})
+		clear(key)
+		if err != nil {
+			return fmt.Errorf("backup medium: %v", err)
+		}
+		sh.backups = files
+		if *pinParty != "" {
+			locked := pinlock.New(files, *pinParty, prompter)
+			defer locked.Forget()
+			sh.backups = locked
+		}
+	}
+	return sh.Run()
+}
+
+// backupKeyOf reads the backup key from path or, if path is empty, asks for
+// its recovery phrase.
+func backupKeyOf(path string, prompter *prompt.Prompter) ([]byte, error) {
+	if path != "" {
+		return recoveryphrase.ReadKeyFile(path)
+	}
+	phrase, err := prompter.Secret(context.Background(), prompt.Request{
+		Prompt:      "Recovery phrase",
+		Description: "Enter the 24-word recovery phrase of the backup key.",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer clear(phrase)
+	return recoveryphrase.ParseKey(string(phrase))
+}
+
+// parseAssets parses "id=TICKER:DECIMALS" entries.
+func parseAssets(s string) (map[string]display.Asset, error) {
+	assets := make(map[string]display.Asset)
+	for _, entry := range splitList(s) {
+		id, spec, ok := strings.Cut(entry, "=")
+		ticker, decimals, ok2 := strings.Cut(spec, ":")
+		if !ok || !ok2 || id == "" || ticker == "" {
+			return nil, fmt.Errorf("malformed entry %q", entry)
+		}
+		d, err := strconv.Atoi(decimals)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("malformed decimals in %q", entry)
+		}
+		assets[id] = display.Asset{Ticker: ticker, Decimals: d}
+	}
+	return assets, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
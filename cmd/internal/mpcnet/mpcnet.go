@@ -0,0 +1,330 @@
+//go:build !nompc
+
+// Package mpcnet runs all parties of an N-party protocol inside one process
+// over a choice of transports.  It is shared by the benchmark and soak
+// commands.
+package mpcnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mtls"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/preflight"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/websocket"
+)
+
+// NewCurve returns the curve called name, ed25519 or secp256k1.
+func NewCurve(name string) (curve.Curve, error) {
+	switch name {
+	case "ed25519":
+		return curve.NewEd25519()
+	case "secp256k1":
+		return curve.NewSecp256k1()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", name)
+	}
+}
+
+// Protocol holds the key shares of all parties between keygen and signing.
+type Protocol struct {
+	ecdsa  bool
+	mu     sync.Mutex
+	ecKeys map[int]mpc.ECDSAMPCKey
+	edKeys map[int]mpc.EDDSAMPCKey
+}
+
+// NewProtocol returns a Protocol for curve cv: ECDSA on secp256k1, EdDSA
+// otherwise.
+func NewProtocol(cv curve.Curve) *Protocol {
+	return &Protocol{
+		ecdsa:  cv.String() == "secp256k1",
+		ecKeys: map[int]mpc.ECDSAMPCKey{},
+		edKeys: map[int]mpc.EDDSAMPCKey{},
+	}
+}
+
+// Keygen generates the key share of job's party.
+func (p *Protocol) Keygen(job *mpc.JobMP, cv curve.Curve) error {
+	if p.ecdsa {
+		resp, err := mpc.ECDSAMPCKeyGen(job, &mpc.ECDSAMPCKeyGenRequest{Curve: cv})
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.ecKeys[job.GetPartyIndex()] = resp.KeyShare
+		p.mu.Unlock()
+		return nil
+	}
+	resp, err := mpc.EDDSAMPCKeyGen(job, &mpc.EDDSAMPCKeyGenRequest{Curve: cv})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.edKeys[job.GetPartyIndex()] = resp.KeyShare
+	p.mu.Unlock()
+	return nil
+}
+
+// Sign signs message with the key share of job's party.
+func (p *Protocol) Sign(job *mpc.JobMP, message []byte) error {
+	p.mu.Lock()
+	ecKey, edKey := p.ecKeys[job.GetPartyIndex()], p.edKeys[job.GetPartyIndex()]
+	p.mu.Unlock()
+	if p.ecdsa {
+		_, err := mpc.ECDSAMPCSign(job, &mpc.ECDSAMPCSignRequest{KeyShare: ecKey, Message: message})
+		return err
+	}
+	_, err := mpc.EDDSAMPCSign(job, &mpc.EDDSAMPCSignRequest{KeyShare: edKey, Message: message})
+	return err
+}
+
+// Free releases the native key shares.
+func (p *Protocol) Free() {
+	for _, k := range p.ecKeys {
+		k.Free()
+	}
+	for _, k := range p.edKeys {
+		k.Free()
+	}
+	clear(p.ecKeys)
+	clear(p.edKeys)
+}
+
+// RunParties runs fn for every party concurrently, each with its own job.
+func RunParties[M transport.Messenger](messengers []M, pnames []string, fn func(job *mpc.JobMP) error) error {
+	n := len(messengers)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job, err := mpc.NewJobMP(messengers[i], n, i, pnames)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer job.Free()
+			if err := fn(job); err != nil {
+				errs[i] = fmt.Errorf("party %d: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Preflight runs the preflight check for every party concurrently, so that
+// a missing party or stale share fails before the protocol starts.
+// keyVersion returns the key version party i holds and intent is the hash of
+// what the parties sign; both may be nil for a DKG.
+func Preflight[M transport.Messenger](ctx context.Context, messengers []M, keyVersion func(i int) keystore.KeyVersion, intent []byte) error {
+	n := len(messengers)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		config := preflight.Config{Self: i, Intent: intent}
+		for j := 0; j < n; j++ {
+			if j != i {
+				config.Peers = append(config.Peers, j)
+			}
+		}
+		if keyVersion != nil {
+			config.KeyVersion = keyVersion(i)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := preflight.Check(ctx, messengers[i], config); err != nil {
+				errs[i] = fmt.Errorf("party %d: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect sets up one Messenger per party over the named transport: mocknet
+// (in-process queues), tcp (mtls over loopback) or ws (websocket over
+// loopback).  The returned function closes them.
+func Connect(name string, n int) ([]transport.Messenger, func(), error) {
+	switch name {
+	case "mocknet":
+		var out []transport.Messenger
+		for _, m := range mocknet.NewMockNetwork(n) {
+			out = append(out, m)
+		}
+		return out, func() {}, nil
+	case "tcp":
+		return connectMTLS(n)
+	case "ws":
+		addrs, err := loopbackAddresses(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		ms, err := connectAll(n, func(i int) (*websocket.Messenger, error) {
+			return websocket.NewMessenger(websocket.Config{SelfIndex: i, Addresses: addrs})
+		})
+		return ms, closeAll(ms), err
+	default:
+		return nil, nil, fmt.Errorf("unknown transport %q", name)
+	}
+}
+
+// connectMTLS connects n parties with the mtls transport over loopback, using
+// a throwaway self-signed certificate per party.
+func connectMTLS(n int) ([]transport.Messenger, func(), error) {
+	addrs, err := loopbackAddresses(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := x509.NewCertPool()
+	certs := make([]tls.Certificate, n)
+	parties := make(map[int]mtls.PartyConfig, n)
+	nameToIndex := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		cert, err := selfSignedCert(fmt.Sprintf("party-%d", i))
+		if err != nil {
+			return nil, nil, err
+		}
+		certs[i] = cert
+		pool.AddCert(cert.Leaf)
+		parties[i] = mtls.PartyConfig{Address: addrs[i], Cert: cert.Leaf}
+		name, err := mtls.PartyNameFromCertificate(cert.Leaf)
+		if err != nil {
+			return nil, nil, err
+		}
+		nameToIndex[name] = i
+	}
+
+	// The mtls transport logs connection setup on stdout; keep stdout for
+	// the report.
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	ms, err := connectAll(n, func(i int) (*mtls.MTLSMessenger, error) {
+		return mtls.NewMTLSMessenger(mtls.Config{
+			Parties:     parties,
+			CertPool:    pool,
+			TLSCert:     certs[i],
+			NameToIndex: nameToIndex,
+			SelfIndex:   i,
+		})
+	})
+	return ms, func() {
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+		closeAll(ms)()
+	}, err
+}
+
+// connectAll creates the Messengers of all parties concurrently, since each
+// one blocks until its peers are connected.
+func connectAll[M interface {
+	transport.Messenger
+	Close() error
+}](n int, create func(i int) (M, error)) ([]transport.Messenger, error) {
+	out := make([]transport.Messenger, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := create(i)
+			if err != nil {
+				errs[i] = fmt.Errorf("party %d: %v", i, err)
+				return
+			}
+			out[i] = m
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			closeAll(out)()
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func closeAll(ms []transport.Messenger) func() {
+	return func() {
+		for _, m := range ms {
+			if c, ok := m.(io.Closer); ok && c != nil {
+				c.Close()
+			}
+		}
+	}
+}
+
+// loopbackAddresses reserves n free loopback ports.
+func loopbackAddresses(n int) (map[int]string, error) {
+	out := make(map[int]string, n)
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		out[i] = ln.Addr().String()
+		ln.Close()
+	}
+	return out, nil
+}
+
+func selfSignedCert(name string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
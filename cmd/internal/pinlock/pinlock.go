@@ -0,0 +1,118 @@
+// Package pinlock seals the backup of one party's share under a PIN asked
+// for on the terminal, so that the share of a PIN party is never stored
+// under the backup key alone.  It is shared by the ceremony and wallet
+// commands.
+package pinlock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+
+	"solana-threshold-wallet/wallet/prompt"
+)
+
+// MinPINDigits is the shortest PIN accepted when one is chosen.
+const MinPINDigits = 6
+
+// Medium stores the backups of one party, those whose ID ends in
+// "."+party, as mpc.EncryptedKeyShares under a PIN, and passes every other
+// ID through.  The PIN is asked for once, when first needed, and kept until
+// Forget.
+type Medium struct {
+	inner    keystore.Medium
+	party    string
+	prompter *prompt.Prompter
+	pin      []byte
+}
+
+// Ensure Medium implements the Medium interface
+var _ keystore.Medium = (*Medium)(nil)
+
+// New creates a Medium sealing the backups of party in inner.
+func New(inner keystore.Medium, party string, prompter *prompt.Prompter) *Medium {
+	return &Medium{inner: inner, party: party, prompter: prompter}
+}
+
+// Name implements keystore.Medium.
+func (m *Medium) Name() string { return fmt.Sprintf("%s, PIN-sealed for %s", m.inner.Name(), m.party) }
+
+// Store implements keystore.Medium.  Storing the first share of the party
+// asks for a new PIN, twice, and checks its strength.
+func (m *Medium) Store(ctx context.Context, id string, data []byte) error {
+	if !m.sealed(id) {
+		return m.inner.Store(ctx, id, data)
+	}
+	pin, err := m.unlock(ctx, true)
+	if err != nil {
+		return err
+	}
+	e := mpc.EncryptedKeyShare{Label: id}
+	if err := e.Seal(data, mpc.Passphrase(pin)); err != nil {
+		return fmt.Errorf("sealing share of %s: %w", m.party, err)
+	}
+	out, err := json.Marshal(&e)
+	if err != nil {
+		return err
+	}
+	return m.inner.Store(ctx, id, out)
+}
+
+// Load implements keystore.Medium.  A wrong PIN is forgotten, so the next
+// Load asks again.
+func (m *Medium) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := m.inner.Load(ctx, id)
+	if err != nil || !m.sealed(id) {
+		return data, err
+	}
+	var e mpc.EncryptedKeyShare
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("share of %s is not PIN-sealed: %v", m.party, err)
+	}
+	if e.Label != id {
+		return nil, fmt.Errorf("share of %s is sealed for %q", m.party, e.Label)
+	}
+	pin, err := m.unlock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	share, err := e.Open(mpc.Passphrase(pin))
+	if errors.Is(err, mpc.ErrDecryptShare) {
+		m.Forget()
+		return nil, fmt.Errorf("unlocking share of %s: wrong PIN: %w", m.party, err)
+	}
+	return share, err
+}
+
+// Forget clears the PIN.
+func (m *Medium) Forget() {
+	clear(m.pin)
+	m.pin = nil
+}
+
+func (m *Medium) sealed(id string) bool { return strings.HasSuffix(id, "."+m.party) }
+
+// unlock returns the PIN, asking for it if needed; a chosen PIN is confirmed
+// and checked for strength.
+func (m *Medium) unlock(ctx context.Context, choose bool) ([]byte, error) {
+	if m.pin != nil {
+		return m.pin, nil
+	}
+	req := prompt.Request{Prompt: "PIN", Description: fmt.Sprintf("Enter the PIN of the %s share.", m.party)}
+	if choose {
+		req.Description = fmt.Sprintf("Choose the PIN that will protect the %s share.", m.party)
+		req.Confirm = true
+		req.Policy = prompt.PIN(MinPINDigits)
+	}
+	pin, err := m.prompter.Secret(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("PIN of %s: %w", m.party, err)
+	}
+	m.pin = pin
+	return pin, nil
+}
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package curve
 
 import (
@@ -34,8 +34,14 @@
 //   - Creation of named curves (secp256k1 for now)
 //   - Arithmetic on immutable `Point` values: Add, Sub, Neg, Mul
 //   - Constant-time, allocation-free serialization (compressed & uncompressed)
+//   - Standard wire formats via Point.Encode and DecodePoint: SEC1
+//     uncompressed/compressed, BIP-340 x-only and RFC 8032 Ed25519
 //   - Helper utilities for random scalar / point generation (in tests)
 //
+// Built with the nompc tag the package needs no cgo: curves keep their name
+// and order and points are opaque encodings that can be parsed and compared,
+// while arithmetic returns ErrNoNative.
+//
 // All heavy arithmetic is executed in constant time inside C++, guaranteeing that
 // the Go bindings themselves never become a side-channel.
 package curve
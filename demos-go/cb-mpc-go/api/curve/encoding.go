@@ -0,0 +1,328 @@
+package curve
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+)
+
+// PointFormat selects a standard wire encoding for a curve point.
+//
+// The native library's Bytes() serialization is only understood by cb-mpc
+// itself; chains and external verifiers expect one of the formats below.
+type PointFormat int
+
+const (
+	// FormatSEC1Uncompressed is 0x04 || X || Y (SEC1 §2.3.3), 65 bytes.
+	FormatSEC1Uncompressed PointFormat = iota + 1
+	// FormatSEC1Compressed is 0x02/0x03 || X, the prefix carrying the
+	// parity of Y (SEC1 §2.3.3), 33 bytes.
+	FormatSEC1Compressed
+	// FormatXOnly is the 32-byte X coordinate of a secp256k1 point whose Y
+	// is implicitly even (BIP-340).
+	FormatXOnly
+	// FormatEd25519 is the 32-byte little-endian Y coordinate with the sign
+	// of X in the top bit (RFC 8032 §5.1.2). Solana addresses use it.
+	FormatEd25519
+)
+
+// fieldSize is the coordinate length of every supported curve.
+const fieldSize = 32
+
+// ErrInvalidEncoding is returned when bytes do not decode to a point on the
+// requested curve, or a format is not defined for that curve.
+var ErrInvalidEncoding = errors.New("curve: invalid point encoding")
+
+func (f PointFormat) String() string {
+	switch f {
+	case FormatSEC1Uncompressed:
+		return "sec1-uncompressed"
+	case FormatSEC1Compressed:
+		return "sec1-compressed"
+	case FormatXOnly:
+		return "x-only"
+	case FormatEd25519:
+		return "ed25519"
+	default:
+		return fmt.Sprintf("PointFormat(%d)", int(f))
+	}
+}
+
+// Size returns the encoded length in bytes, or 0 for an unknown format.
+func (f PointFormat) Size() int {
+	switch f {
+	case FormatSEC1Uncompressed:
+		return 1 + 2*fieldSize
+	case FormatSEC1Compressed:
+		return 1 + fieldSize
+	case FormatXOnly, FormatEd25519:
+		return fieldSize
+	default:
+		return 0
+	}
+}
+
+// Encode serializes p in format f after checking that f is defined for c
+// and that p lies on c.
+func (p *Point) Encode(c Curve, f PointFormat) ([]byte, error) {
+	if p == nil {
+		return nil, fmt.Errorf("nil point")
+	}
+	x, y, err := p.coordinates()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeCoordinates(c, f, x, y)
+}
+
+// EncodeCoordinates serializes the affine point (x, y), given as big-endian
+// byte strings, in format f.  The point must lie on c.
+func EncodeCoordinates(c Curve, f PointFormat, x, y []byte) ([]byte, error) {
+	if len(x) > fieldSize || len(y) > fieldSize {
+		return nil, fmt.Errorf("%w: coordinate longer than %d bytes", ErrInvalidEncoding, fieldSize)
+	}
+	if err := checkFormat(c, f); err != nil {
+		return nil, err
+	}
+	X, Y := new(big.Int).SetBytes(x), new(big.Int).SetBytes(y)
+	code := curveCode(c)
+	if code == ed25519Code {
+		if !edwardsOnCurve(X, Y) {
+			return nil, fmt.Errorf("%w: point not on %s", ErrInvalidEncoding, c)
+		}
+		out := make([]byte, fieldSize)
+		Y.FillBytes(out)
+		reverse(out)
+		if X.Bit(0) == 1 {
+			out[fieldSize-1] |= 0x80
+		}
+		return out, nil
+	}
+	if !weierstrassOnCurve(code, X, Y) {
+		return nil, fmt.Errorf("%w: point not on %s", ErrInvalidEncoding, c)
+	}
+	switch f {
+	case FormatSEC1Uncompressed:
+		out := make([]byte, f.Size())
+		out[0] = 0x04
+		X.FillBytes(out[1 : 1+fieldSize])
+		Y.FillBytes(out[1+fieldSize:])
+		return out, nil
+	case FormatSEC1Compressed:
+		out := make([]byte, f.Size())
+		out[0] = 0x02 | byte(Y.Bit(0))
+		X.FillBytes(out[1:])
+		return out, nil
+	default: // FormatXOnly
+		return X.FillBytes(make([]byte, fieldSize)), nil
+	}
+}
+
+// DecodePoint parses data in format f and returns the affine coordinates as
+// fixed-length big-endian byte strings.  It rejects non-canonical encodings,
+// the SEC1 point at infinity and points that are not on c.
+func DecodePoint(c Curve, f PointFormat, data []byte) (x, y []byte, err error) {
+	if err := checkFormat(c, f); err != nil {
+		return nil, nil, err
+	}
+	if len(data) != f.Size() {
+		return nil, nil, fmt.Errorf("%w: %s needs %d bytes, got %d", ErrInvalidEncoding, f, f.Size(), len(data))
+	}
+	code := curveCode(c)
+	if code == ed25519Code {
+		return decodeEd25519(data)
+	}
+	var X, Y *big.Int
+	switch f {
+	case FormatSEC1Uncompressed:
+		if data[0] != 0x04 {
+			return nil, nil, fmt.Errorf("%w: bad SEC1 prefix 0x%02x", ErrInvalidEncoding, data[0])
+		}
+		X = new(big.Int).SetBytes(data[1 : 1+fieldSize])
+		Y = new(big.Int).SetBytes(data[1+fieldSize:])
+		if !weierstrassOnCurve(code, X, Y) {
+			return nil, nil, fmt.Errorf("%w: point not on %s", ErrInvalidEncoding, c)
+		}
+	case FormatSEC1Compressed:
+		if data[0] != 0x02 && data[0] != 0x03 {
+			return nil, nil, fmt.Errorf("%w: bad SEC1 prefix 0x%02x", ErrInvalidEncoding, data[0])
+		}
+		X = new(big.Int).SetBytes(data[1:])
+		if Y, err = weierstrassLift(code, X, uint(data[0]&1)); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEncoding, err)
+		}
+	default: // FormatXOnly
+		X = new(big.Int).SetBytes(data)
+		if Y, err = weierstrassLift(code, X, 0); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEncoding, err)
+		}
+	}
+	return X.FillBytes(make([]byte, fieldSize)), Y.FillBytes(make([]byte, fieldSize)), nil
+}
+
+// checkFormat reports whether f is defined for c: SEC1 for the Weierstrass
+// curves, x-only for secp256k1 only and the RFC 8032 form for Ed25519 only.
+func checkFormat(c Curve, f PointFormat) error {
+	if c == nil {
+		return fmt.Errorf("nil curve")
+	}
+	if f.Size() == 0 {
+		return fmt.Errorf("%w: unknown format %s", ErrInvalidEncoding, f)
+	}
+	code := curveCode(c)
+	ok := false
+	switch code {
+	case secp256k1Code:
+		ok = f != FormatEd25519
+	case p256Code:
+		ok = f == FormatSEC1Uncompressed || f == FormatSEC1Compressed
+	case ed25519Code:
+		ok = f == FormatEd25519
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s is not defined for %s", ErrInvalidEncoding, f, c)
+	}
+	return nil
+}
+
+// weierstrassOnCurve reports whether (x, y) lies on secp256k1 or P-256.
+// secp256k1 points are checked by the verify package, which holds the one
+// pure-Go implementation of that curve.
+func weierstrassOnCurve(code int, x, y *big.Int) bool {
+	if code == p256Code {
+		return p256Params.onCurve(x, y)
+	}
+	if x.Sign() < 0 || y.Sign() < 0 || x.BitLen() > 8*fieldSize || y.BitLen() > 8*fieldSize {
+		return false
+	}
+	pub := make([]byte, 1+2*fieldSize)
+	pub[0] = 0x04
+	x.FillBytes(pub[1 : 1+fieldSize])
+	y.FillBytes(pub[1+fieldSize:])
+	_, err := verify.UncompressSecp256k1(pub)
+	return err == nil
+}
+
+// weierstrassLift returns the Y coordinate for x whose low bit equals parity
+// on secp256k1 or P-256.
+func weierstrassLift(code int, x *big.Int, parity uint) (*big.Int, error) {
+	if code == p256Code {
+		return p256Params.lift(x, parity)
+	}
+	if x.Sign() < 0 || x.BitLen() > 8*fieldSize {
+		return nil, errors.New("x coordinate out of range")
+	}
+	pub := make([]byte, 1+fieldSize)
+	pub[0] = 0x02 | byte(parity)
+	x.FillBytes(pub[1:])
+	uncompressed, err := verify.UncompressSecp256k1(pub)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(uncompressed[1+fieldSize:]), nil
+}
+
+// weierstrass holds the parameters of y² = x³ + ax + b over GF(p).  The P-256
+// prime is 3 mod 4, so square roots are a single exponentiation.
+type weierstrass struct {
+	p, a, b *big.Int
+}
+
+var p256Params = weierstrass{
+	p: mustBig("ffffffff00000001000000000000000000000000ffffffffffffffffffffffff"),
+	a: big.NewInt(-3),
+	b: mustBig("5ac635d8aa3a93e7b3ebbd55769886bc651d06b0cc53b0f63bce3c3e27d2604b"),
+}
+
+// rhs returns x³ + ax + b mod p.
+func (w *weierstrass) rhs(x *big.Int) *big.Int {
+	r := new(big.Int).Exp(x, big.NewInt(3), w.p)
+	r.Add(r, new(big.Int).Mul(w.a, x))
+	r.Add(r, w.b)
+	return r.Mod(r, w.p)
+}
+
+func (w *weierstrass) onCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || y.Sign() < 0 || x.Cmp(w.p) >= 0 || y.Cmp(w.p) >= 0 {
+		return false
+	}
+	y2 := new(big.Int).Mul(y, y)
+	return y2.Mod(y2, w.p).Cmp(w.rhs(x)) == 0
+}
+
+// lift returns the Y coordinate for x whose low bit equals parity.
+func (w *weierstrass) lift(x *big.Int, parity uint) (*big.Int, error) {
+	if x.Cmp(w.p) >= 0 {
+		return nil, errors.New("x coordinate out of range")
+	}
+	r := w.rhs(x)
+	e := new(big.Int).Add(w.p, big.NewInt(1))
+	e.Rsh(e, 2)
+	y := new(big.Int).Exp(r, e, w.p)
+	if new(big.Int).Exp(y, big.NewInt(2), w.p).Cmp(r) != 0 {
+		return nil, errors.New("x is not on the curve")
+	}
+	if y.Bit(0) != parity {
+		y.Sub(w.p, y)
+	}
+	return y, nil
+}
+
+var (
+	edwardsP = mustBig("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed")
+	edwardsD = mustBig("52036cee2b6ffe738cc740797779e89800700a4d4141d8ab75eb4dca135978a3")
+)
+
+// edwardsOnCurve checks -x² + y² = 1 + d·x²·y² mod p.
+func edwardsOnCurve(x, y *big.Int) bool {
+	if x.Cmp(edwardsP) >= 0 || y.Cmp(edwardsP) >= 0 {
+		return false
+	}
+	x2 := new(big.Int).Mul(x, x)
+	y2 := new(big.Int).Mul(y, y)
+	lhs := new(big.Int).Sub(y2, x2)
+	lhs.Mod(lhs, edwardsP)
+	rhs := new(big.Int).Mul(x2, y2)
+	rhs.Mul(rhs, edwardsD)
+	rhs.Add(rhs, big.NewInt(1))
+	rhs.Mod(rhs, edwardsP)
+	return lhs.Cmp(rhs) == 0
+}
+
+func decodeEd25519(data []byte) (x, y []byte, err error) {
+	pt, err := new(edwards25519.Point).SetBytes(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEncoding, err)
+	}
+	// SetBytes tolerates y >= p; only the canonical form round-trips.
+	if !bytes.Equal(pt.Bytes(), data) {
+		return nil, nil, fmt.Errorf("%w: non-canonical Ed25519 encoding", ErrInvalidEncoding)
+	}
+	X, Y, Z, _ := pt.ExtendedCoordinates()
+	zInv := new(field.Element).Invert(Z)
+	x = new(field.Element).Multiply(X, zInv).Bytes()
+	y = new(field.Element).Multiply(Y, zInv).Bytes()
+	reverse(x)
+	reverse(y)
+	return x, y, nil
+}
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+func mustBig(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("curve: bad constant " + s)
+	}
+	return v
+}
@@ -0,0 +1,118 @@
+package curve
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestEncodeDecodeWeierstrass(t *testing.T) {
+	cases := []struct {
+		name string
+		ctor func() (Curve, error)
+		x, y string
+	}{
+		{"secp256k1", NewSecp256k1,
+			"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"},
+		{"P-256", NewP256,
+			"6b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296",
+			"4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := tc.ctor()
+			require.NoError(t, err)
+			defer c.Free()
+			x, y := unhex(t, tc.x), unhex(t, tc.y)
+
+			for _, f := range []PointFormat{FormatSEC1Uncompressed, FormatSEC1Compressed} {
+				enc, err := EncodeCoordinates(c, f, x, y)
+				require.NoError(t, err)
+				assert.Len(t, enc, f.Size())
+				gx, gy, err := DecodePoint(c, f, enc)
+				require.NoError(t, err, f)
+				assert.Equal(t, x, gx)
+				assert.Equal(t, y, gy)
+			}
+
+			enc, err := EncodeCoordinates(c, FormatSEC1Compressed, x, y)
+			require.NoError(t, err)
+			enc[0] ^= 1
+			_, gy, err := DecodePoint(c, FormatSEC1Compressed, enc)
+			require.NoError(t, err)
+			assert.NotEqual(t, y, gy, "flipped prefix must select the other root")
+
+			bad := append([]byte(nil), y...)
+			bad[fieldSize-1] ^= 1
+			_, err = EncodeCoordinates(c, FormatSEC1Uncompressed, x, bad)
+			assert.ErrorIs(t, err, ErrInvalidEncoding)
+
+			_, err = EncodeCoordinates(c, FormatEd25519, x, y)
+			assert.ErrorIs(t, err, ErrInvalidEncoding)
+		})
+	}
+}
+
+func TestXOnly(t *testing.T) {
+	k1, err := NewSecp256k1()
+	require.NoError(t, err)
+	defer k1.Free()
+	x := unhex(t, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+
+	gx, gy, err := DecodePoint(k1, FormatXOnly, x)
+	require.NoError(t, err)
+	assert.Equal(t, x, gx)
+	assert.Zero(t, gy[fieldSize-1]&1, "BIP-340 lifts to the even Y")
+
+	enc, err := EncodeCoordinates(k1, FormatXOnly, gx, gy)
+	require.NoError(t, err)
+	assert.Equal(t, x, enc)
+
+	// x = 5 has no secp256k1 point: 5³ + 7 = 132 is a non-residue.
+	five := make([]byte, fieldSize)
+	five[fieldSize-1] = 5
+	_, _, err = DecodePoint(k1, FormatXOnly, five)
+	assert.ErrorIs(t, err, ErrInvalidEncoding)
+
+	p256, err := NewP256()
+	require.NoError(t, err)
+	defer p256.Free()
+	_, _, err = DecodePoint(p256, FormatXOnly, x)
+	assert.ErrorIs(t, err, ErrInvalidEncoding)
+}
+
+func TestEd25519Encoding(t *testing.T) {
+	ed, err := NewEd25519()
+	require.NoError(t, err)
+	defer ed.Free()
+
+	base := edwards25519.NewGeneratorPoint().Bytes()
+	x, y, err := DecodePoint(ed, FormatEd25519, base)
+	require.NoError(t, err)
+	assert.Equal(t, "216936d3cd6e53fec0a4e231fdd6dc5c692cc7609525a7b2c9562d608f25d51a", hex.EncodeToString(x))
+
+	enc, err := EncodeCoordinates(ed, FormatEd25519, x, y)
+	require.NoError(t, err)
+	assert.Equal(t, base, enc)
+
+	// y = p is the non-canonical encoding of y = 0.
+	nonCanonical := unhex(t, "edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")
+	_, _, err = DecodePoint(ed, FormatEd25519, nonCanonical)
+	assert.ErrorIs(t, err, ErrInvalidEncoding)
+
+	_, _, err = DecodePoint(ed, FormatSEC1Compressed, append([]byte{0x02}, base...))
+	assert.ErrorIs(t, err, ErrInvalidEncoding)
+	_, _, err = DecodePoint(ed, FormatEd25519, base[:31])
+	assert.ErrorIs(t, err, ErrInvalidEncoding)
+}
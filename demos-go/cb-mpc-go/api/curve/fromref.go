@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package curve
 
 import "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
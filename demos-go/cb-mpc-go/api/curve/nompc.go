@@ -0,0 +1,122 @@
+//go:build nompc
+
+package curve
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// In nompc builds curves only carry their identity: name, code and order.
+// Anything that needs group arithmetic returns ErrNoNative.
+
+var curveOrders = map[int][]byte{
+	secp256k1Code: mustHex("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+	p256Code:      mustHex("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551"),
+	ed25519Code:   mustHex("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed"),
+}
+
+type identityCurve struct{ code int }
+
+// NewSecp256k1 returns the secp256k1 curve identity.
+func NewSecp256k1() (Curve, error) { return &identityCurve{code: secp256k1Code}, nil }
+
+// NewP256 returns the P-256 curve identity.
+func NewP256() (Curve, error) { return &identityCurve{code: p256Code}, nil }
+
+// NewEd25519 returns the Ed25519 curve identity.
+func NewEd25519() (Curve, error) { return &identityCurve{code: ed25519Code}, nil }
+
+func (c *identityCurve) Generator() *Point { return nil }
+
+func (c *identityCurve) Order() []byte { return append([]byte(nil), curveOrders[c.code]...) }
+
+func (c *identityCurve) Free() {}
+
+func (c *identityCurve) RandomScalar() (*Scalar, error) { return nil, ErrNoNative }
+
+func (c *identityCurve) MultiplyGenerator(*Scalar) (*Point, error) { return nil, ErrNoNative }
+
+func (c *identityCurve) RandomKeyPair() (*Scalar, *Point, error) { return nil, nil, ErrNoNative }
+
+func (c *identityCurve) Add(a, b *Scalar) (*Scalar, error) { return nil, ErrNoNative }
+
+func (c *identityCurve) String() string {
+	switch c.code {
+	case secp256k1Code:
+		return "secp256k1"
+	case p256Code:
+		return "P-256"
+	case ed25519Code:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("unknown curve (%d)", c.code)
+	}
+}
+
+var _ Curve = (*identityCurve)(nil)
+
+// curveCode returns the OpenSSL NID of c, or 0 for a foreign implementation.
+func curveCode(c Curve) int {
+	if ic, ok := c.(*identityCurve); ok {
+		return ic.code
+	}
+	return 0
+}
+
+// Point is a serialized curve point.  In nompc builds it is an opaque byte
+// string: points can be parsed, compared and re-encoded but not operated on.
+type Point struct {
+	b []byte
+}
+
+// NewPointFromBytes wraps serialized point bytes.  The encoding is not
+// validated without the native library.
+func NewPointFromBytes(pointBytes []byte) (*Point, error) {
+	if len(pointBytes) == 0 {
+		return nil, fmt.Errorf("empty point bytes")
+	}
+	return &Point{b: append([]byte(nil), pointBytes...)}, nil
+}
+
+// Free is a no-op; nompc points hold no native resources.
+func (p *Point) Free() {}
+
+// Bytes returns the serialized point.
+func (p *Point) Bytes() []byte {
+	if p == nil {
+		return nil
+	}
+	return append([]byte(nil), p.b...)
+}
+
+// Equals reports whether both points have the same encoding.
+func (p *Point) Equals(other *Point) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	return bytes.Equal(p.b, other.b)
+}
+
+// coordinates returns the affine X and Y coordinates for Encode.  Without
+// the native library only SEC1 uncompressed bytes can be split into them.
+func (p *Point) coordinates() (x, y []byte, err error) {
+	if len(p.b) != 1+2*fieldSize || p.b[0] != 0x04 {
+		return nil, nil, ErrNoNative
+	}
+	return p.b[1 : 1+fieldSize], p.b[1+fieldSize:], nil
+}
+
+// String returns a string representation of the point
+func (p *Point) String() string {
+	return fmt.Sprintf("Point(%x)", p.Bytes())
+}
+
+func mustHex(s string) []byte {
+	out, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
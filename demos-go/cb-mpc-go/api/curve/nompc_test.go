@@ -0,0 +1,53 @@
+//go:build nompc
+
+package curve
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityCurves(t *testing.T) {
+	for name, ctor := range map[string]func() (Curve, error){
+		"secp256k1": NewSecp256k1,
+		"P-256":     NewP256,
+		"Ed25519":   NewEd25519,
+	} {
+		c, err := ctor()
+		require.NoError(t, err)
+		assert.Equal(t, name, c.String())
+		assert.Len(t, c.Order(), 32)
+		_, err = c.RandomScalar()
+		assert.ErrorIs(t, err, ErrNoNative)
+	}
+}
+
+func TestPointBytes(t *testing.T) {
+	p, err := NewPointFromBytes([]byte{0x02, 0x01})
+	require.NoError(t, err)
+	q, err := NewPointFromBytes(p.Bytes())
+	require.NoError(t, err)
+	assert.True(t, p.Equals(q))
+	_, err = NewPointFromBytes(nil)
+	assert.Error(t, err)
+}
+
+func TestPointEncodeFromSEC1(t *testing.T) {
+	c, err := NewSecp256k1()
+	require.NoError(t, err)
+	sec1, err := hex.DecodeString("0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8")
+	require.NoError(t, err)
+	p, err := NewPointFromBytes(sec1)
+	require.NoError(t, err)
+	enc, err := p.Encode(c, FormatSEC1Compressed)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0x02}, sec1[1:33]...), enc)
+
+	opaque, err := NewPointFromBytes([]byte{0x02, 0x01})
+	require.NoError(t, err)
+	_, err = opaque.Encode(c, FormatSEC1Compressed)
+	assert.ErrorIs(t, err, ErrNoNative)
+}
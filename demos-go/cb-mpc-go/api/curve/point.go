@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package curve
 
 import (
@@ -113,3 +115,11 @@ func (p *Point) Bytes() []byte {
 func newPointFromCRef(ref cgobinding.ECCPointRef) *Point {
 	return &Point{cPoint: ref}
 }
+
+// coordinates returns the affine X and Y coordinates for Encode.
+func (p *Point) coordinates() (x, y []byte, err error) {
+	if p.IsZero() {
+		return nil, nil, fmt.Errorf("%w: point at infinity", ErrInvalidEncoding)
+	}
+	return p.GetX(), p.GetY(), nil
+}
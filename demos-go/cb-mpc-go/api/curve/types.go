@@ -0,0 +1,83 @@
+package curve
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Curve is the public interface that represents an elliptic curve supported by the cb-mpc library.
+//
+// Concrete curves – secp256k1, P-256 and Ed25519 – implement this interface.
+// Users should obtain a curve via the constructor helpers NewSecp256k1, NewP256 or NewEd25519
+// rather than dealing with numeric curve codes directly.
+//
+// All implementations wrap native (C++) resources. Therefore each Curve must be released
+// with a call to Free once it is no longer needed to avoid memory leaks.
+// Alternatively, the caller can rely on the Go GC finalizer to invoke Free automatically
+// (not implemented here to avoid hidden costs).
+//
+// The methods mirror the functionality that was previously available on the ECurve struct.
+// They remain unchanged so existing call-sites require only minimal migration.
+type Curve interface {
+	// Generator returns the generator point of the curve.
+	Generator() *Point
+	// Order returns the (big-endian) order of the curve group.
+	Order() []byte
+	// Free releases the native resources associated with the curve.
+	Free()
+	// RandomScalar returns a uniformly random non-zero scalar in the interval
+	// [1, Order()-1]. The random sampling is delegated to the native C++ layer.
+	RandomScalar() (*Scalar, error)
+	// MultiplyGenerator multiplies the curve generator by the given scalar
+	// and returns the resulting point (k * G).
+	MultiplyGenerator(k *Scalar) (*Point, error)
+	// RandomKeyPair returns a uniformly random non-zero scalar in the interval
+	// [1, Order()-1] and the corresponding point (k * G).
+	RandomKeyPair() (*Scalar, *Point, error)
+	// Add returns (a + b) mod Order() as a new Scalar.
+	Add(a, b *Scalar) (*Scalar, error)
+	// String returns a human friendly identifier (implements fmt.Stringer).
+	fmt.Stringer
+}
+
+// Internal numeric identifiers – matching OpenSSL NIDs – used by the native library.
+const (
+	secp256k1Code = 714  // OpenSSL NID_secp256k1
+	p256Code      = 415  // OpenSSL NID_X9_62_prime256v1
+	ed25519Code   = 1087 // OpenSSL NID_ED25519
+)
+
+// ErrNoNative is returned by curve arithmetic in builds without the native
+// library (the nompc build tag).
+var ErrNoNative = errors.New("curve: native library not linked (built with nompc)")
+
+// Scalar represents a field element (mod the curve order).
+//
+// Bytes is a fixed-length, big-endian encoding with the same byte length as
+// the curve order.
+//
+// For now the type only supports random generation, but it lays the
+// foundation for future arithmetic helpers.
+type Scalar struct {
+	// Bytes holds the big-endian representation of the scalar. The length of
+	// the slice matches the order of the underlying curve (but the scalar
+	// itself no longer embeds that information).
+	Bytes []byte
+}
+
+// Equal returns true if s and other represent the same scalar value.
+// Returns false if either scalar is nil.
+func (s *Scalar) Equal(other *Scalar) bool {
+	if s == nil || other == nil {
+		return false
+	}
+	return bytes.Equal(s.Bytes, other.Bytes)
+}
+
+func (s *Scalar) String() string {
+	if s == nil {
+		return "<nil scalar>"
+	}
+	return fmt.Sprintf("Scalar(%x)", s.Bytes)
+}
@@ -0,0 +1,160 @@
+package awskms
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+)
+
+// ShareIDContextKey is the encryption context key under which every data key
+// is bound to the ID of the share it encrypts.
+const ShareIDContextKey = "cbmpc:share-id"
+
+// wrappedVersion prefixes every wrapped data key.
+const wrappedVersion = 1
+
+// ErrKeyNotAllowed is returned by Unwrap for a data key wrapped under a KMS key
+// that is not in Config.AllowedKeyIDs.
+var ErrKeyNotAllowed = errors.New("awskms: KMS key not allowed")
+
+// Client is the subset of the AWS KMS API a Wrapper uses.  *kms.Client
+// implements it.
+type Client interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// Ensure kms.Client implements the Client interface
+var _ Client = (*kms.Client)(nil)
+
+// Config contains the configuration for a Wrapper.
+type Config struct {
+	// Client calls AWS KMS, typically kms.NewFromConfig(awsConfig).
+	Client Client
+	// KeyID is the symmetric KMS key new data keys are wrapped under: a key
+	// ID, key ARN, alias name or alias ARN.
+	KeyID string
+	// AllowedKeyIDs, if set, lists the key ARNs Unwrap accepts.  It should
+	// contain the ARN KeyID resolves to, plus the ARNs of keys that still
+	// wrap stored shares after KeyID was moved to a new key.  If empty,
+	// Unwrap accepts any key the client may decrypt with.
+	AllowedKeyIDs []string
+	// EncryptionContext is added to the encryption context of every data
+	// key, e.g. to name the wallet.  It must not contain ShareIDContextKey.
+	EncryptionContext map[string]string
+	// GrantTokens are passed on every KMS call.
+	GrantTokens []string
+}
+
+// Wrapper wraps the data keys of a keystore.EnvelopeMedium with a symmetric
+// AWS KMS key.  The wrapped key records the ARN of the KMS key, and every
+// data key is bound to its share ID through the KMS encryption context.
+//
+// Wrapper does not implement keystore.KeyRetirer: a KMS key wraps the data
+// keys of many shares and cannot destroy one of them.
+type Wrapper struct {
+	client  Client
+	keyID   string
+	allowed []string
+	context map[string]string
+	grants  []string
+}
+
+// Ensure Wrapper implements the KeyWrapper interface
+var _ keystore.KeyWrapper = (*Wrapper)(nil)
+
+// New creates a Wrapper from the given configuration.
+func New(config Config) (*Wrapper, error) {
+	if config.Client == nil {
+		return nil, fmt.Errorf("KMS client must be provided")
+	}
+	if config.KeyID == "" {
+		return nil, fmt.Errorf("KMS key ID must be provided")
+	}
+	if _, ok := config.EncryptionContext[ShareIDContextKey]; ok {
+		return nil, fmt.Errorf("encryption context must not contain %q", ShareIDContextKey)
+	}
+	return &Wrapper{
+		client:  config.Client,
+		keyID:   config.KeyID,
+		allowed: slices.Clone(config.AllowedKeyIDs),
+		context: maps.Clone(config.EncryptionContext),
+		grants:  slices.Clone(config.GrantTokens),
+	}, nil
+}
+
+// Wrap implements keystore.KeyWrapper.
+func (w *Wrapper) Wrap(ctx context.Context, id string, dataKey []byte) ([]byte, error) {
+	out, err := w.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(w.keyID),
+		Plaintext:         dataKey,
+		EncryptionContext: w.encryptionContext(id),
+		GrantTokens:       w.grants,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KMS encrypt: %w", err)
+	}
+	keyARN := aws.ToString(out.KeyId)
+	if keyARN == "" || len(keyARN) > 0xffff {
+		return nil, fmt.Errorf("KMS encrypt returned invalid key ID %q", keyARN)
+	}
+	wrapped := make([]byte, 0, 3+len(keyARN)+len(out.CiphertextBlob))
+	wrapped = append(wrapped, wrappedVersion)
+	wrapped = binary.BigEndian.AppendUint16(wrapped, uint16(len(keyARN)))
+	wrapped = append(wrapped, keyARN...)
+	return append(wrapped, out.CiphertextBlob...), nil
+}
+
+// Unwrap implements keystore.KeyWrapper.  The KMS key recorded by Wrap is
+// passed to KMS, which refuses a ciphertext produced under another key.
+func (w *Wrapper) Unwrap(ctx context.Context, id string, wrapped []byte) ([]byte, error) {
+	keyARN, blob, err := KeyID(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if len(w.allowed) > 0 && !slices.Contains(w.allowed, keyARN) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotAllowed, keyARN)
+	}
+	out, err := w.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(keyARN),
+		CiphertextBlob:    blob,
+		EncryptionContext: w.encryptionContext(id),
+		GrantTokens:       w.grants,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KMS decrypt: %w", err)
+	}
+	return out.Plaintext, nil
+}
+
+// KeyID returns the ARN of the KMS key a data key returned by Wrap is wrapped
+// under, and the KMS ciphertext.  It can be used to find the shares that
+// still depend on a key before the key is disabled.
+func KeyID(wrapped []byte) (keyARN string, ciphertext []byte, err error) {
+	if len(wrapped) < 3 || wrapped[0] != wrappedVersion {
+		return "", nil, fmt.Errorf("not a KMS-wrapped data key")
+	}
+	n := int(binary.BigEndian.Uint16(wrapped[1:3]))
+	if n == 0 || len(wrapped) <= 3+n {
+		return "", nil, fmt.Errorf("wrapped data key truncated")
+	}
+	return string(wrapped[3 : 3+n]), wrapped[3+n:], nil
+}
+
+// encryptionContext returns the configured encryption context with the share
+// ID added.
+func (w *Wrapper) encryptionContext(id string) map[string]string {
+	ec := make(map[string]string, len(w.context)+1)
+	for k, v := range w.context {
+		ec[k] = v
+	}
+	ec[ShareIDContextKey] = id
+	return ec
+}
@@ -0,0 +1,200 @@
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS encrypts with one AES-256-GCM key per key ARN and authenticates the
+// encryption context, as KMS does.  The ciphertext blob starts with the index
+// of the key, standing in for the key reference KMS embeds.
+type fakeKMS struct {
+	aliases map[string]string // Alias to key ARN
+	arns    []string
+	keys    []cipher.AEAD
+}
+
+func newFakeKMS(t *testing.T, arns ...string) *fakeKMS {
+	k := &fakeKMS{aliases: make(map[string]string), arns: arns}
+	for range arns {
+		block, err := aes.NewCipher(bytes.Repeat([]byte{byte(len(k.keys) + 1)}, 32))
+		require.NoError(t, err)
+		aead, err := cipher.NewGCM(block)
+		require.NoError(t, err)
+		k.keys = append(k.keys, aead)
+	}
+	return k
+}
+
+func (k *fakeKMS) resolve(keyID string) (int, error) {
+	if arn, ok := k.aliases[keyID]; ok {
+		keyID = arn
+	}
+	i := slices.Index(k.arns, keyID)
+	if i < 0 {
+		return 0, fmt.Errorf("NotFoundException: key %s", keyID)
+	}
+	return i, nil
+}
+
+func encodeContext(ec map[string]string) []byte {
+	var b []byte
+	for _, k := range slices.Sorted(maps.Keys(ec)) {
+		b = fmt.Appendf(b, "%q=%q;", k, ec[k])
+	}
+	return b
+}
+
+func (k *fakeKMS) Encrypt(_ context.Context, in *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	i, err := k.resolve(aws.ToString(in.KeyId))
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, k.keys[i].NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	blob := append([]byte{byte(i)}, nonce...)
+	blob = k.keys[i].Seal(blob, nonce, in.Plaintext, encodeContext(in.EncryptionContext))
+	return &kms.EncryptOutput{CiphertextBlob: blob, KeyId: aws.String(k.arns[i])}, nil
+}
+
+func (k *fakeKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	i, err := k.resolve(aws.ToString(in.KeyId))
+	if err != nil {
+		return nil, err
+	}
+	blob := in.CiphertextBlob
+	if len(blob) < 1+k.keys[i].NonceSize() || int(blob[0]) != i {
+		return nil, fmt.Errorf("IncorrectKeyException")
+	}
+	nonce, ciphertext := blob[1:1+k.keys[i].NonceSize()], blob[1+k.keys[i].NonceSize():]
+	plaintext, err := k.keys[i].Open(nil, nonce, ciphertext, encodeContext(in.EncryptionContext))
+	if err != nil {
+		return nil, fmt.Errorf("InvalidCiphertextException")
+	}
+	return &kms.DecryptOutput{Plaintext: plaintext, KeyId: aws.String(k.arns[i])}, nil
+}
+
+const (
+	arn1 = "arn:aws:kms:us-east-1:111122223333:key/1"
+	arn2 = "arn:aws:kms:us-east-1:111122223333:key/2"
+)
+
+func newMedium(t *testing.T, files keystore.Medium, config Config) *keystore.EnvelopeMedium {
+	t.Helper()
+	wrapper, err := New(config)
+	require.NoError(t, err)
+	medium, err := keystore.NewEnvelopeMedium(keystore.EnvelopeMediumConfig{Medium: files, Wrapper: wrapper})
+	require.NoError(t, err)
+	return medium
+}
+
+func TestWrapperEnvelopeMedium(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeKMS(t, arn1)
+	client.aliases["alias/wallet-s2"] = arn1
+	files, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	medium := newMedium(t, files, Config{
+		Client:            client,
+		KeyID:             "alias/wallet-s2",
+		EncryptionContext: map[string]string{"wallet": "treasury"},
+	})
+
+	share := bytes.Repeat([]byte("eddsa share "), 400) // Larger than KMS Encrypt accepts
+	require.NoError(t, medium.Store(ctx, "kms", share))
+	loaded, err := medium.Load(ctx, "kms")
+	require.NoError(t, err)
+	assert.Equal(t, share, loaded)
+
+	stored, err := files.Load(ctx, "kms")
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(stored, []byte("eddsa share")))
+	assert.True(t, bytes.Contains(stored, []byte(arn1)), "the key ARN is recorded")
+
+	// The data key is bound to the share ID and the configured context.
+	require.NoError(t, files.Store(ctx, "server", stored))
+	_, err = medium.Load(ctx, "server")
+	assert.ErrorContains(t, err, "InvalidCiphertextException")
+
+	other := newMedium(t, files, Config{
+		Client:            client,
+		KeyID:             arn1,
+		EncryptionContext: map[string]string{"wallet": "payroll"},
+	})
+	_, err = other.Load(ctx, "kms")
+	assert.ErrorContains(t, err, "InvalidCiphertextException")
+}
+
+func TestWrapperKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeKMS(t, arn1, arn2)
+	files, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, newMedium(t, files, Config{Client: client, KeyID: arn1}).Store(ctx, "kms", []byte("old")))
+
+	// After moving to a new key, shares wrapped under the old one stay
+	// readable while it is allowed.
+	rotated := newMedium(t, files, Config{Client: client, KeyID: arn2, AllowedKeyIDs: []string{arn1, arn2}})
+	share, err := rotated.Load(ctx, "kms")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("old"), share)
+	require.NoError(t, rotated.Store(ctx, "kms", []byte("new")))
+
+	stored, err := files.Load(ctx, "kms")
+	require.NoError(t, err)
+	require.True(t, bytes.Contains(stored, []byte(arn2)))
+
+	restricted := newMedium(t, files, Config{Client: client, KeyID: arn1, AllowedKeyIDs: []string{arn1}})
+	_, err = restricted.Load(ctx, "kms")
+	assert.ErrorIs(t, err, ErrKeyNotAllowed)
+}
+
+func TestWrapperRejectsForgedKeyID(t *testing.T) {
+	ctx := context.Background()
+	wrapper, err := New(Config{Client: newFakeKMS(t, arn1, arn2), KeyID: arn1})
+	require.NoError(t, err)
+	wrapped, err := wrapper.Wrap(ctx, "kms", make([]byte, 32))
+	require.NoError(t, err)
+
+	keyARN, _, err := KeyID(wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, arn1, keyARN)
+
+	forged := bytes.Replace(wrapped, []byte(arn1), []byte(arn2), 1)
+	_, err = wrapper.Unwrap(ctx, "kms", forged)
+	assert.ErrorContains(t, err, "IncorrectKeyException")
+
+	for _, bad := range [][]byte{nil, {2, 0, 1, 'k', 0}, wrapped[:3+len(arn1)]} {
+		_, err = wrapper.Unwrap(ctx, "kms", bad)
+		assert.Error(t, err)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	client := newFakeKMS(t, arn1)
+	for _, config := range []Config{
+		{KeyID: arn1},
+		{Client: client},
+		{Client: client, KeyID: arn1, EncryptionContext: map[string]string{ShareIDContextKey: "x"}},
+	} {
+		_, err := New(config)
+		assert.Error(t, err)
+	}
+	_, err := New(Config{Client: client, KeyID: arn1})
+	assert.NoError(t, err)
+}
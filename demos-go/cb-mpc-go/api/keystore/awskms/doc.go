@@ -0,0 +1,31 @@
+// Package awskms keeps key shares under an AWS KMS key, for example the S2
+// share of a server/KMS/PIN wallet.
+//
+// A Wrapper is a keystore.KeyWrapper: plugged into a keystore.EnvelopeMedium,
+// every stored share is encrypted with AES-256-GCM under a fresh data key, and
+// only that data key is sent to KMS to be wrapped.  The share itself never
+// leaves the host, and the 4 KiB limit of KMS Encrypt does not apply:
+//
+//	cfg, _ := config.LoadDefaultConfig(ctx)
+//	wrapper, _ := awskms.New(awskms.Config{
+//		Client: kms.NewFromConfig(cfg),
+//		KeyID:  "alias/wallet-s2",
+//	})
+//	files, _ := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: dir})
+//	medium, _ := keystore.NewEnvelopeMedium(keystore.EnvelopeMediumConfig{Medium: files, Wrapper: wrapper})
+//
+//	shareBytes, _ := key.MarshalBinary() // an mpc.EDDSAMPCKey
+//	_ = medium.Store(ctx, "kms", shareBytes)
+//	shareBytes, err := medium.Load(ctx, "kms")
+//
+// Each data key is bound to its share ID through the KMS encryption context
+// (ShareIDContextKey), so an envelope copied to another ID fails to decrypt,
+// and CloudTrail records which share every Decrypt call was for.  Key
+// policies can restrict a role to certain shares with the
+// kms:EncryptionContext condition keys.
+//
+// The wrapped data key records the ARN of the KMS key it was wrapped under
+// (see KeyID).  Unwrap passes it to KMS, which refuses ciphertexts of other
+// keys, and checks it against AllowedKeyIDs, so moving KeyID to a new key
+// leaves existing shares readable until they are stored again.
+package awskms
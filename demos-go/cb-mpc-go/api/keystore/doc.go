@@ -0,0 +1,73 @@
+// Package keystore persists serialized MPC key shares.
+//
+// The package works on the opaque byte slices produced by the key types'
+// MarshalBinary methods and therefore has no dependency on the native library.
+//
+// # Split shares
+//
+// A single logical party can spread its share over several storage media –
+// for example an encrypted file on the server and a hardware token – so that
+// compromising one medium reveals nothing about the share.  `Split` turns a
+// share into N parts that are individually indistinguishable from random and
+// `Combine` requires all N of them:
+//
+//	store, _ := keystore.NewSplitStore(fileMedium, tokenMedium)
+//	_ = store.Put(ctx, "server", shareBytes)
+//
+//	err := store.Use(ctx, "server", func(share []byte) error {
+//	    var key mpc.EDDSAMPCKey
+//	    if err := key.UnmarshalBinary(share); err != nil {
+//	        return err
+//	    }
+//	    defer key.Free()
+//	    _, err := mpc.EDDSAMPCSign(job, &mpc.EDDSAMPCSignRequest{KeyShare: key, Message: msg})
+//	    return err
+//	})
+//
+// The recombined share only exists in memory for the duration of the callback
+// and is zeroed afterwards.
+//
+// # Rollback protection
+//
+// A refresh replaces every party's share while keeping the public key, so an
+// old backup restored after a refresh would quietly resurrect a share that was
+// meant to be gone.  `VersionedStore` encrypts every share under its own key
+// with the share ID, a monotonic local version and the share's `KeyVersion`
+// as additional data, so none of them can be edited, and keeps the latest
+// local version in a separate `Counter`:
+//
+//	store, _ := keystore.NewVersionedStore(keystore.VersionedStoreConfig{Medium: fileMedium, Counter: tpmCounter, Key: shareKey})
+//	kv := keystore.KeyVersion{KeyID: publicKey, Epoch: epoch + 1} // after a refresh
+//	version, _ := store.Put(ctx, "server", kv, refreshedShare)
+//	stored, err := store.Load(ctx, "server") // *RollbackError if older than the counter
+//
+// Because a local counter can be rolled back together with the disk, parties
+// also run `CheckPeerVersions` over the protocol transport before using a
+// share.  They compare key versions – the key ID and the refresh epoch that
+// every party advances with each refresh – not their local versions, so a
+// party presenting an older epoch than its peers is named in a
+// *VersionMismatchError and every party aborts.
+//
+// # Envelope keys
+//
+// `EnvelopeMedium` encrypts every stored share under a fresh data key, which
+// is kept next to it wrapped by the party's KMS or HSM through a `KeyWrapper`.
+// Since every refresh stores a new share, it also rotates the data key; a
+// wrapper that implements `KeyRetirer`, for example by destroying the KMS key
+// version, retires the old data key once the new share is stored:
+//
+//	medium, _ := keystore.NewEnvelopeMedium(keystore.EnvelopeMediumConfig{Medium: fileMedium, Wrapper: kms})
+//	store, _ := keystore.NewVersionedStore(keystore.VersionedStoreConfig{Medium: medium, Counter: tpmCounter, Key: shareKey})
+//
+// A backup stolen before a refresh then both holds a stale share and can no
+// longer be decrypted.  The awskms sub-package provides a KeyWrapper for AWS
+// KMS keys.
+//
+// # Inspecting stored data
+//
+// `Inspect` reports the share ID and versions of a versioned envelope and
+// whether the data is a KMS envelope, without unwrapping or decrypting
+// anything, and returns unencrypted shares for mpc.InspectShare.  The
+// cb-mpc-keystore inspect command prints both for share files of unknown
+// origin.
+package keystore
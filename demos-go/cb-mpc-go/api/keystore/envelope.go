@@ -0,0 +1,232 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// envelopeMagic prefixes every envelope written by EnvelopeMedium.
+var envelopeMagic = []byte("CBSE")
+
+// KeyWrapper encrypts data keys under a key-encryption key held by the
+// party's KMS or HSM, which never releases it.
+type KeyWrapper interface {
+	// Wrap encrypts dataKey for the data stored under id.
+	Wrap(ctx context.Context, id string, dataKey []byte) ([]byte, error)
+	// Unwrap returns the data key wrapped for id.
+	Unwrap(ctx context.Context, id string, wrapped []byte) ([]byte, error)
+}
+
+// KeyRetirer is implemented by KeyWrappers that can make a wrapped data key
+// permanently unusable, for example by destroying the KMS key version it was
+// wrapped under.
+type KeyRetirer interface {
+	// Retire makes wrapped, previously returned by Wrap for id, impossible
+	// to unwrap.
+	Retire(ctx context.Context, id string, wrapped []byte) error
+}
+
+// RetireError reports that EnvelopeMedium.Store stored the new data but could
+// not retire the data key of the data it replaced.
+type RetireError struct {
+	ID  string
+	Err error
+}
+
+func (e *RetireError) Error() string {
+	return fmt.Sprintf("retiring previous data key of %s: %v", e.ID, e.Err)
+}
+
+func (e *RetireError) Unwrap() error { return e.Err }
+
+// EnvelopeMediumConfig contains the configuration for an EnvelopeMedium.
+type EnvelopeMediumConfig struct {
+	// Medium stores the envelopes.
+	Medium Medium
+	// Wrapper wraps the data keys.  If it implements KeyRetirer, the data
+	// key of replaced data is retired after every Store.
+	Wrapper KeyWrapper
+}
+
+// EnvelopeMedium encrypts data under a fresh AES-256-GCM data key on every
+// Store and keeps the data key, wrapped by a KeyWrapper, next to it.
+type EnvelopeMedium struct {
+	medium  Medium
+	wrapper KeyWrapper
+}
+
+// Ensure EnvelopeMedium implements the Medium interface
+var _ Medium = (*EnvelopeMedium)(nil)
+
+// NewEnvelopeMedium creates an EnvelopeMedium from the given configuration.
+func NewEnvelopeMedium(config EnvelopeMediumConfig) (*EnvelopeMedium, error) {
+	if config.Medium == nil || config.Wrapper == nil {
+		return nil, fmt.Errorf("medium and wrapper must be provided")
+	}
+	return &EnvelopeMedium{medium: config.Medium, wrapper: config.Wrapper}, nil
+}
+
+// Name implements Medium.
+func (m *EnvelopeMedium) Name() string { return "envelope:" + m.medium.Name() }
+
+// Store implements Medium.  The data replaced by this call has its data key
+// retired once the new envelope is stored; if that fails Store returns a
+// *RetireError, but the new data is in place.
+func (m *EnvelopeMedium) Store(ctx context.Context, id string, data []byte) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	var previous []byte
+	if _, ok := m.wrapper.(KeyRetirer); ok {
+		old, err := m.medium.Load(ctx, id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return fmt.Errorf("loading previous envelope: %w", err)
+		default:
+			if previous, _, err = parseEnvelope(id, old); err != nil {
+				return err
+			}
+		}
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("generating data key: %v", err)
+	}
+	defer zero(dataKey)
+	wrapped, err := m.wrapper.Wrap(ctx, id, dataKey)
+	if err != nil {
+		return fmt.Errorf("wrapping data key: %w", err)
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generating nonce: %v", err)
+	}
+	out := make([]byte, 0, len(envelopeMagic)+2+len(wrapped)+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, envelopeMagic...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, data, envelopeAD(id, wrapped))
+	if err := m.medium.Store(ctx, id, out); err != nil {
+		return err
+	}
+
+	if previous != nil && !bytes.Equal(previous, wrapped) {
+		if err := m.wrapper.(KeyRetirer).Retire(ctx, id, previous); err != nil {
+			return &RetireError{ID: id, Err: err}
+		}
+	}
+	return nil
+}
+
+// Load implements Medium.
+func (m *EnvelopeMedium) Load(ctx context.Context, id string) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	data, err := m.medium.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, sealed, err := parseEnvelope(id, data)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := m.wrapper.Unwrap(ctx, id, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key of %s: %w", id, err)
+	}
+	defer zero(dataKey)
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("envelope of %s is truncated", id)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, envelopeAD(id, wrapped))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %v", id, err)
+	}
+	return plain, nil
+}
+
+// parseEnvelope splits "CBSE" | len(wrapped) | wrapped | nonce | ciphertext.
+func parseEnvelope(id string, data []byte) (wrapped, sealed []byte, err error) {
+	head := len(envelopeMagic) + 2
+	if len(data) < head || !bytes.Equal(data[:len(envelopeMagic)], envelopeMagic) {
+		return nil, nil, fmt.Errorf("%s is not an envelope", id)
+	}
+	n := int(binary.BigEndian.Uint16(data[len(envelopeMagic):]))
+	if len(data) < head+n {
+		return nil, nil, fmt.Errorf("envelope of %s is truncated", id)
+	}
+	return data[head : head+n], data[head+n:], nil
+}
+
+// envelopeAD binds the ciphertext to its ID and wrapped data key.
+func envelopeAD(id string, wrapped []byte) []byte {
+	ad := binary.BigEndian.AppendUint16(nil, uint16(len(id)))
+	ad = append(ad, id...)
+	return append(ad, wrapped...)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// AESKeyWrapper wraps data keys with a local AES-256-GCM key.  It stands in
+// for a KMS in development and tests; it cannot retire data keys.
+type AESKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+// Ensure AESKeyWrapper implements the KeyWrapper interface
+var _ KeyWrapper = (*AESKeyWrapper)(nil)
+
+// NewAESKeyWrapper creates an AESKeyWrapper from a 32-byte key.
+func NewAESKeyWrapper(key []byte) (*AESKeyWrapper, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("wrapping key must be 32 bytes, got %d", len(key))
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESKeyWrapper{aead: aead}, nil
+}
+
+// Wrap implements KeyWrapper.
+func (w *AESKeyWrapper) Wrap(_ context.Context, id string, dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %v", err)
+	}
+	return w.aead.Seal(nonce, nonce, dataKey, []byte(id)), nil
+}
+
+// Unwrap implements KeyWrapper.
+func (w *AESKeyWrapper) Unwrap(_ context.Context, id string, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < w.aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+	nonce, ciphertext := wrapped[:w.aead.NonceSize()], wrapped[w.aead.NonceSize():]
+	return w.aead.Open(nil, nonce, ciphertext, []byte(id))
+}
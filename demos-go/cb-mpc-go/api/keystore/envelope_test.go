@@ -0,0 +1,133 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS wraps every data key under a new key version, as a KMS with one
+// key version per share generation would, and destroys versions on Retire.
+type fakeKMS struct {
+	mu        sync.Mutex
+	versions  map[uint32][]byte // Version to the data key it wraps
+	next      uint32
+	retireErr error
+}
+
+func (k *fakeKMS) Wrap(_ context.Context, _ string, dataKey []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.versions == nil {
+		k.versions = make(map[uint32][]byte)
+	}
+	k.next++
+	k.versions[k.next] = append([]byte(nil), dataKey...)
+	return binary.BigEndian.AppendUint32(nil, k.next), nil
+}
+
+func (k *fakeKMS) Unwrap(_ context.Context, _ string, wrapped []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.versions[binary.BigEndian.Uint32(wrapped)]
+	if !ok {
+		return nil, fmt.Errorf("key version destroyed")
+	}
+	return append([]byte(nil), key...), nil
+}
+
+func (k *fakeKMS) Retire(_ context.Context, _ string, wrapped []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.retireErr != nil {
+		return k.retireErr
+	}
+	delete(k.versions, binary.BigEndian.Uint32(wrapped))
+	return nil
+}
+
+func TestEnvelopeMediumRotatesDataKeys(t *testing.T) {
+	ctx := context.Background()
+	inner := &memoryMedium{}
+	kms := &fakeKMS{}
+	medium, err := NewEnvelopeMedium(EnvelopeMediumConfig{Medium: inner, Wrapper: kms})
+	require.NoError(t, err)
+
+	require.NoError(t, medium.Store(ctx, "p1", []byte("share-v1")))
+	backup, err := inner.Load(ctx, "p1")
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(backup, []byte("share-v1")))
+
+	// A refresh stores the new share under a fresh data key and retires the
+	// old one.
+	require.NoError(t, medium.Store(ctx, "p1", []byte("share-v2")))
+	share, err := medium.Load(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("share-v2"), share)
+	assert.Len(t, kms.versions, 1)
+
+	require.NoError(t, inner.Store(ctx, "p1", backup))
+	_, err = medium.Load(ctx, "p1")
+	assert.ErrorContains(t, err, "key version destroyed")
+}
+
+func TestEnvelopeMediumBindsID(t *testing.T) {
+	ctx := context.Background()
+	inner := &memoryMedium{}
+	wrapper, err := NewAESKeyWrapper(make([]byte, 32))
+	require.NoError(t, err)
+	medium, err := NewEnvelopeMedium(EnvelopeMediumConfig{Medium: inner, Wrapper: wrapper})
+	require.NoError(t, err)
+
+	require.NoError(t, medium.Store(ctx, "p1", []byte("share")))
+	share, err := medium.Load(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("share"), share)
+
+	data, err := inner.Load(ctx, "p1")
+	require.NoError(t, err)
+	require.NoError(t, inner.Store(ctx, "p2", data))
+	_, err = medium.Load(ctx, "p2")
+	assert.Error(t, err)
+
+	require.NoError(t, inner.Store(ctx, "p3", []byte("plain")))
+	_, err = medium.Load(ctx, "p3")
+	assert.ErrorContains(t, err, "not an envelope")
+
+	_, err = NewAESKeyWrapper(make([]byte, 16))
+	assert.Error(t, err)
+}
+
+func TestVersionedStoreReportsRetireError(t *testing.T) {
+	ctx := context.Background()
+	kms := &fakeKMS{}
+	medium, err := NewEnvelopeMedium(EnvelopeMediumConfig{Medium: &memoryMedium{}, Wrapper: kms})
+	require.NoError(t, err)
+	counter, err := NewFileCounter(FileCounterConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	store, err := NewVersionedStore(VersionedStoreConfig{Medium: medium, Counter: counter, Key: testKey})
+	require.NoError(t, err)
+
+	_, err = store.Put(ctx, "p1", epoch1, []byte("share-v1"))
+	require.NoError(t, err)
+	kms.retireErr = errors.New("kms unavailable")
+	v, err := store.Put(ctx, "p1", epoch2, []byte("share-v2"))
+	var retire *RetireError
+	require.ErrorAs(t, err, &retire)
+	assert.Equal(t, "p1", retire.ID)
+	assert.Equal(t, uint64(2), v)
+
+	current, err := counter.Current(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), current)
+	stored, err := store.Load(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("share-v2"), stored.Share)
+}
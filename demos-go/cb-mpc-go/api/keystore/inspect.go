@@ -0,0 +1,65 @@
+package keystore
+
+import "bytes"
+
+// Layers reported by Inspect.
+const (
+	LayerVersioned = "versioned" // VersionedStore envelope
+	LayerEnvelope  = "envelope"  // EnvelopeMedium ciphertext
+)
+
+// StoredInfo is the non-secret metadata of data written by this package.
+type StoredInfo struct {
+	// Layers lists the layers found, outermost first.
+	Layers []string `json:"layers,omitempty"`
+	// ID, Version and the key version, KeyID and Epoch, are bound into a
+	// versioned envelope.
+	ID      string `json:"id,omitempty"`
+	Version uint64 `json:"version,omitempty"`
+	KeyID   []byte `json:"key_id,omitempty"`
+	Epoch   uint64 `json:"epoch,omitempty"`
+	// WrappedKeySize is the size of the wrapped data key of an envelope.
+	// Everything after it is ciphertext.
+	WrappedKeySize int `json:"wrapped_key_size,omitempty"`
+}
+
+// Encrypted reports whether the data ends in a layer that Inspect did not
+// look into.  Both envelopes and versioned envelopes are encrypted.
+func (i *StoredInfo) Encrypted() bool {
+	return len(i.Layers) > 0
+}
+
+// Inspect reports the metadata of the layer this package wraps around a share
+// without unwrapping any key or decrypting anything.  It returns the data
+// inside the layer, typically a serialized or encrypted share to be inspected
+// with mpc.InspectShare, or nil if the data is encrypted, as it is in a
+// VersionedStore or an EnvelopeMedium.  Data with no known layer is returned
+// as is; data stored by an encrypting FileMedium cannot be told apart from
+// random bytes.
+func Inspect(data []byte) (*StoredInfo, []byte, error) {
+	info := &StoredInfo{}
+	switch {
+	case bytes.HasPrefix(data, versionMagic):
+		h, _, _, err := parseVersionHeader(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := checkID(h.ID); err != nil {
+			return nil, nil, err
+		}
+		info.ID, info.Version = h.ID, h.Version
+		info.KeyID, info.Epoch = h.KeyVersion.KeyID, h.KeyVersion.Epoch
+		info.Layers = append(info.Layers, LayerVersioned)
+		return info, nil, nil
+	case bytes.HasPrefix(data, envelopeMagic):
+		wrapped, _, err := parseEnvelope("data", data)
+		if err != nil {
+			return nil, nil, err
+		}
+		info.WrappedKeySize = len(wrapped)
+		info.Layers = append(info.Layers, LayerEnvelope)
+		return info, nil, nil
+	default:
+		return info, data, nil
+	}
+}
@@ -0,0 +1,56 @@
+package keystore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	ctx := context.Background()
+
+	aead, err := newGCM(testKey)
+	require.NoError(t, err)
+	seal := func(id string, version uint64) []byte {
+		data, err := sealVersion(aead, id, version, epoch2, []byte("share"))
+		require.NoError(t, err)
+		return data
+	}
+
+	info, inner, err := Inspect(seal("server", 3))
+	require.NoError(t, err)
+	assert.Equal(t, &StoredInfo{Layers: []string{LayerVersioned}, ID: "server", Version: 3, KeyID: []byte("key-1"), Epoch: 2}, info)
+	assert.True(t, info.Encrypted())
+	assert.Nil(t, inner)
+
+	files, err := NewFileMedium(FileMediumConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	wrapper, err := NewAESKeyWrapper(make([]byte, 32))
+	require.NoError(t, err)
+	medium, err := NewEnvelopeMedium(EnvelopeMediumConfig{Medium: files, Wrapper: wrapper})
+	require.NoError(t, err)
+	require.NoError(t, medium.Store(ctx, "server", seal("server", 1)))
+	data, err := files.Load(ctx, "server")
+	require.NoError(t, err)
+	info, inner, err = Inspect(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{LayerEnvelope}, info.Layers)
+	assert.True(t, info.Encrypted())
+	assert.Equal(t, 60, info.WrappedKeySize)
+	assert.Nil(t, inner)
+
+	info, inner, err = Inspect([]byte("share"))
+	require.NoError(t, err)
+	assert.Empty(t, info.Layers)
+	assert.False(t, info.Encrypted())
+	assert.Equal(t, []byte("share"), inner)
+
+	_, _, err = Inspect(seal("server", 3)[:12])
+	assert.Error(t, err)
+	_, _, err = Inspect(seal("../etc", 3))
+	assert.Error(t, err)
+	_, _, err = Inspect(envelopeMagic)
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package keystore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrNotFound is returned by a Medium that holds no data for an ID.
+var ErrNotFound = errors.New("keystore: not found")
+
+// Medium is a storage location for one part of a split share, such as an
+// encrypted file, a hardware token or a cloud secret manager.
+type Medium interface {
+	// Name identifies the medium in error messages.
+	Name() string
+	// Store saves data under id, replacing any previous value.
+	Store(ctx context.Context, id string, data []byte) error
+	// Load returns the data stored under id, or ErrNotFound.
+	Load(ctx context.Context, id string) ([]byte, error)
+}
+
+// validID restricts share IDs to names that are safe file names.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+func checkID(id string) error {
+	if !validID.MatchString(id) || id == "." || id == ".." {
+		return fmt.Errorf("invalid share ID %q", id)
+	}
+	return nil
+}
+
+// FileMediumConfig contains the configuration for a FileMedium.
+type FileMediumConfig struct {
+	// Dir is the directory holding the files.  It is created if missing.
+	Dir string
+	// Key is an optional 32-byte AES-256-GCM key.  When set, data is
+	// encrypted at rest and bound to its ID.
+	Key []byte
+}
+
+// FileMedium stores data in one file per ID, optionally encrypted.
+type FileMedium struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// Ensure FileMedium implements the Medium interface
+var _ Medium = (*FileMedium)(nil)
+
+// NewFileMedium creates a FileMedium from the given configuration.
+func NewFileMedium(config FileMediumConfig) (*FileMedium, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("directory must be provided")
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating directory: %v", err)
+	}
+	m := &FileMedium{dir: config.Dir}
+	if config.Key != nil {
+		if len(config.Key) != 32 {
+			return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(config.Key))
+		}
+		block, err := aes.NewCipher(config.Key)
+		if err != nil {
+			return nil, err
+		}
+		if m.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Name implements Medium.
+func (m *FileMedium) Name() string { return "file:" + m.dir }
+
+// Store implements Medium.  The file is written atomically.
+func (m *FileMedium) Store(_ context.Context, id string, data []byte) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	if m.aead != nil {
+		nonce := make([]byte, m.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("generating nonce: %v", err)
+		}
+		data = m.aead.Seal(nonce, nonce, data, []byte(id))
+	}
+
+	tmp, err := os.CreateTemp(m.dir, "."+id+".*")
+	if err != nil {
+		return fmt.Errorf("creating file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(m.dir, id))
+}
+
+// Load implements Medium.
+func (m *FileMedium) Load(_ context.Context, id string) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(m.dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %v", err)
+	}
+	if m.aead == nil {
+		return data, nil
+	}
+	if len(data) < m.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:m.aead.NonceSize()], data[m.aead.NonceSize():]
+	plain, err := m.aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %v", id, err)
+	}
+	return plain, nil
+}
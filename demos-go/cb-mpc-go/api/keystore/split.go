@@ -0,0 +1,184 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// ErrPartMismatch is returned by Combine when the parts do not belong to the
+// same split or the recombined share fails its integrity check.
+var ErrPartMismatch = errors.New("keystore: share parts do not match")
+
+const (
+	partMagic = "CBSP"
+	// partVersion 2 splits the checksum together with the share.  Version 1
+	// parts carried a SHA-256 of the share in every header, which let anyone
+	// holding a single part confirm a guess of the share; Combine still
+	// reads them so that stored shares can be split again.
+	partVersion   = 2
+	partVersionV1 = 1
+	setIDSize     = 16
+	// partHeaderSize is magic, version, index, count and set ID.
+	partHeaderSize = len(partMagic) + 3 + setIDSize
+	// partHeaderSizeV1 adds the digest of the share.
+	partHeaderSizeV1 = partHeaderSize + sha256.Size
+	maxParts         = 16
+)
+
+// Split divides share into n parts, all of which are needed to recover it.
+// Every part is the share and its checksum XOR-ed with independent random
+// pads, so any n-1 parts are statistically independent of the share.
+//
+// Each part carries a header with a random split identifier, so that parts
+// from different splits (e.g. before and after a key refresh) are detected
+// by Combine.  The checksum, a SHA-256 of the split identifier and the
+// share, is only recovered together with the share and catches corrupted
+// parts.
+func Split(share []byte, n int) ([][]byte, error) {
+	if len(share) == 0 {
+		return nil, fmt.Errorf("share cannot be empty")
+	}
+	if n < 2 || n > maxParts {
+		return nil, fmt.Errorf("number of parts must be between 2 and %d, got %d", maxParts, n)
+	}
+
+	var setID [setIDSize]byte
+	if _, err := rand.Read(setID[:]); err != nil {
+		return nil, fmt.Errorf("generating split ID: %v", err)
+	}
+	sum := checksum(setID, share)
+	last := append(append([]byte(nil), share...), sum[:]...)
+
+	parts := make([][]byte, n)
+	for i := range parts {
+		var header bytes.Buffer
+		header.WriteString(partMagic)
+		header.WriteByte(partVersion)
+		header.WriteByte(byte(i))
+		header.WriteByte(byte(n))
+		header.Write(setID[:])
+
+		part := make([]byte, partHeaderSize+len(last))
+		copy(part, header.Bytes())
+		payload := part[partHeaderSize:]
+		if i == n-1 {
+			copy(payload, last)
+		} else {
+			if _, err := rand.Read(payload); err != nil {
+				return nil, fmt.Errorf("generating pad: %v", err)
+			}
+			subtle.XORBytes(last, last, payload)
+		}
+		parts[i] = part
+	}
+	zero(last)
+	return parts, nil
+}
+
+// Combine recovers the share from all parts produced by a single Split call.
+// The parts may be passed in any order.  Callers should zero the returned
+// slice once they are done with it.
+func Combine(parts [][]byte) ([]byte, error) {
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("at least two parts are required, got %d", len(parts))
+	}
+	first, err := parsePart(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if first.count != len(parts) {
+		return nil, fmt.Errorf("%w: split has %d parts, got %d", ErrPartMismatch, first.count, len(parts))
+	}
+
+	payload := make([]byte, len(first.payload))
+	seen := make([]bool, first.count)
+	for i, raw := range parts {
+		p, err := parsePart(raw)
+		if err != nil {
+			zero(payload)
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		if p.version != first.version || p.setID != first.setID || p.count != first.count || p.digest != first.digest || len(p.payload) != len(payload) {
+			zero(payload)
+			return nil, fmt.Errorf("%w: part %d belongs to a different split", ErrPartMismatch, i)
+		}
+		if p.index >= p.count || seen[p.index] {
+			zero(payload)
+			return nil, fmt.Errorf("%w: duplicate or out-of-range part index %d", ErrPartMismatch, p.index)
+		}
+		seen[p.index] = true
+		subtle.XORBytes(payload, payload, p.payload)
+	}
+
+	share, want := payload, first.digest[:]
+	var got [sha256.Size]byte
+	if first.version == partVersionV1 {
+		got = sha256.Sum256(share)
+	} else {
+		share, want = payload[:len(payload)-sha256.Size], payload[len(payload)-sha256.Size:]
+		got = checksum(first.setID, share)
+	}
+	if subtle.ConstantTimeCompare(got[:], want) != 1 {
+		zero(payload)
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrPartMismatch)
+	}
+	zero(want)
+	return share, nil
+}
+
+// checksum binds the share to its split.
+func checksum(setID [setIDSize]byte, share []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(partMagic))
+	h.Write(setID[:])
+	h.Write(share)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// part is a parsed share part.
+type part struct {
+	version byte
+	index   int
+	count   int
+	setID   [setIDSize]byte
+	digest  [sha256.Size]byte // Digest of the share in version 1 headers
+	payload []byte
+}
+
+func parsePart(raw []byte) (*part, error) {
+	if len(raw) <= partHeaderSize || string(raw[:len(partMagic)]) != partMagic {
+		return nil, fmt.Errorf("not a share part")
+	}
+	rest := raw[len(partMagic):]
+	p := &part{version: rest[0], index: int(rest[1]), count: int(rest[2])}
+	copy(p.setID[:], rest[3:])
+	switch p.version {
+	case partVersion:
+		if len(raw) <= partHeaderSize+sha256.Size {
+			return nil, fmt.Errorf("share part is truncated")
+		}
+		p.payload = raw[partHeaderSize:]
+	case partVersionV1:
+		if len(raw) <= partHeaderSizeV1 {
+			return nil, fmt.Errorf("share part is truncated")
+		}
+		copy(p.digest[:], rest[3+setIDSize:])
+		p.payload = raw[partHeaderSizeV1:]
+	default:
+		return nil, fmt.Errorf("unsupported share part version %d", p.version)
+	}
+	return p, nil
+}
+
+// zero overwrites b with zeros.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
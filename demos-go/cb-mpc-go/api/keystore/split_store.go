@@ -0,0 +1,71 @@
+package keystore
+
+import (
+	"context"
+	"fmt"
+)
+
+// SplitStore stores every share split across a fixed list of media.
+type SplitStore struct {
+	media []Medium
+}
+
+// NewSplitStore creates a SplitStore that splits shares into one part per
+// medium.  The order of media does not matter when loading.
+func NewSplitStore(media ...Medium) (*SplitStore, error) {
+	if len(media) < 2 {
+		return nil, fmt.Errorf("at least two media are required, got %d", len(media))
+	}
+	if len(media) > maxParts {
+		return nil, fmt.Errorf("at most %d media are supported, got %d", maxParts, len(media))
+	}
+	for i, m := range media {
+		if m == nil {
+			return nil, fmt.Errorf("medium %d is nil", i)
+		}
+	}
+	return &SplitStore{media: media}, nil
+}
+
+// Put splits share and stores one part on each medium.  A failure part-way
+// leaves the media holding parts of different splits, which Use reports as
+// ErrPartMismatch; callers should retry Put until it succeeds.
+func (s *SplitStore) Put(ctx context.Context, id string, share []byte) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	parts, err := Split(share, len(s.media))
+	if err != nil {
+		return err
+	}
+	for i, m := range s.media {
+		if err := m.Store(ctx, id, parts[i]); err != nil {
+			return fmt.Errorf("storing part on %s: %v", m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Use recombines the share stored under id, passes it to fn and zeroes it
+// once fn returns.  fn must not retain the slice.
+func (s *SplitStore) Use(ctx context.Context, id string, fn func(share []byte) error) error {
+	parts := make([][]byte, len(s.media))
+	defer func() {
+		for _, p := range parts {
+			zero(p)
+		}
+	}()
+	for i, m := range s.media {
+		p, err := m.Load(ctx, id)
+		if err != nil {
+			return fmt.Errorf("loading part from %s: %w", m.Name(), err)
+		}
+		parts[i] = p
+	}
+	share, err := Combine(parts)
+	if err != nil {
+		return fmt.Errorf("recombining %s: %w", id, err)
+	}
+	defer zero(share)
+	return fn(share)
+}
@@ -0,0 +1,151 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryMedium is an in-memory Medium standing in for a hardware token.
+type memoryMedium struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (m *memoryMedium) Name() string { return "memory" }
+
+func (m *memoryMedium) Store(_ context.Context, id string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		m.data = make(map[string][]byte)
+	}
+	m.data[id] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memoryMedium) Load(_ context.Context, id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.data[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func TestSplitCombine(t *testing.T) {
+	share := []byte("serialized key share")
+	parts, err := Split(share, 3)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	digest := sha256.Sum256(share)
+	for _, p := range parts {
+		assert.False(t, bytes.Contains(p, share))
+		assert.False(t, bytes.Contains(p, digest[:]), "a single part must not confirm a guess of the share")
+	}
+
+	got, err := Combine([][]byte{parts[2], parts[0], parts[1]})
+	require.NoError(t, err)
+	assert.Equal(t, share, got)
+
+	_, err = Combine(parts[:2])
+	assert.ErrorIs(t, err, ErrPartMismatch)
+	_, err = Combine([][]byte{parts[0], parts[0], parts[1]})
+	assert.ErrorIs(t, err, ErrPartMismatch)
+
+	other, err := Split(share, 3)
+	require.NoError(t, err)
+	_, err = Combine([][]byte{parts[0], parts[1], other[2]})
+	assert.ErrorIs(t, err, ErrPartMismatch)
+
+	corrupted := append([]byte(nil), parts[1]...)
+	corrupted[len(corrupted)-1] ^= 1
+	_, err = Combine([][]byte{parts[0], corrupted, parts[2]})
+	assert.ErrorIs(t, err, ErrPartMismatch)
+}
+
+func TestCombineVersion1(t *testing.T) {
+	share := []byte("serialized key share")
+	digest := sha256.Sum256(share)
+	pad := bytes.Repeat([]byte{0x5a}, len(share))
+	header := func(i byte) []byte {
+		h := append([]byte(partMagic), partVersionV1, i, 2)
+		h = append(h, make([]byte, setIDSize)...)
+		return append(h, digest[:]...)
+	}
+	masked := make([]byte, len(share))
+	subtle.XORBytes(masked, share, pad)
+	parts := [][]byte{append(header(0), pad...), append(header(1), masked...)}
+
+	got, err := Combine(parts)
+	require.NoError(t, err)
+	assert.Equal(t, share, got)
+
+	parts[1][len(parts[1])-1] ^= 1
+	_, err = Combine(parts)
+	assert.ErrorIs(t, err, ErrPartMismatch)
+}
+
+func TestSplitValidation(t *testing.T) {
+	_, err := Split(nil, 2)
+	assert.Error(t, err)
+	_, err = Split([]byte{1}, 1)
+	assert.Error(t, err)
+	_, err = Split([]byte{1}, maxParts+1)
+	assert.Error(t, err)
+}
+
+func TestSplitStore(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{0x42}, 32)
+	file, err := NewFileMedium(FileMediumConfig{Dir: t.TempDir(), Key: key})
+	require.NoError(t, err)
+	token := &memoryMedium{}
+	store, err := NewSplitStore(file, token)
+	require.NoError(t, err)
+
+	share := []byte("server share")
+	require.NoError(t, store.Put(ctx, "server", share))
+
+	var seen []byte
+	err = store.Use(ctx, "server", func(s []byte) error {
+		seen = s
+		assert.Equal(t, share, s)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, len(share)), seen, "share must be zeroed after use")
+
+	err = store.Use(ctx, "kms", func([]byte) error { return nil })
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Losing the token part makes the share unrecoverable.
+	other := &memoryMedium{}
+	store2, err := NewSplitStore(file, other)
+	require.NoError(t, err)
+	assert.Error(t, store2.Use(ctx, "server", func([]byte) error { return nil }))
+}
+
+func TestFileMediumEncryption(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	m, err := NewFileMedium(FileMediumConfig{Dir: dir, Key: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+	require.NoError(t, m.Store(ctx, "a", []byte("secret")))
+
+	wrong, err := NewFileMedium(FileMediumConfig{Dir: dir, Key: bytes.Repeat([]byte{2}, 32)})
+	require.NoError(t, err)
+	_, err = wrong.Load(ctx, "a")
+	assert.Error(t, err)
+
+	assert.Error(t, m.Store(ctx, "../escape", []byte("x")))
+	_, err = NewFileMedium(FileMediumConfig{Dir: dir, Key: []byte("short")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,445 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+var (
+	// ErrRollback is returned when a share older than the latest known
+	// version is loaded or presented by a peer.
+	ErrRollback = errors.New("keystore: share rolled back")
+	// ErrKeyMismatch is returned when a peer presents a share of another
+	// key.
+	ErrKeyMismatch = errors.New("keystore: share of another key")
+)
+
+// versionMagic prefixes every versioned share envelope.
+var versionMagic = []byte("CBSV")
+
+// RollbackError reports a stored share older than the counter.
+type RollbackError struct {
+	ID       string
+	Version  uint64 // Version found in storage
+	Expected uint64 // Version recorded by the counter
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("share %s has version %d, expected %d", e.ID, e.Version, e.Expected)
+}
+
+// Unwrap returns ErrRollback.
+func (e *RollbackError) Unwrap() error { return ErrRollback }
+
+// Counter is a monotonic per-share version counter.  To be useful it must
+// live somewhere a share backup cannot restore: a TPM NV index, an HSM, a
+// separate host or at least a different volume than the shares.
+type Counter interface {
+	// Current returns the latest version recorded for id, or zero.
+	Current(ctx context.Context, id string) (uint64, error)
+	// Advance records version for id.  It fails if version is lower than
+	// the current one.
+	Advance(ctx context.Context, id string, version uint64) error
+}
+
+// FileCounterConfig contains the configuration for a FileCounter.
+type FileCounterConfig struct {
+	// Dir is the directory holding one counter file per share.  It is
+	// created if missing.
+	Dir string
+}
+
+// FileCounter keeps counters in plain files.
+type FileCounter struct {
+	dir string
+}
+
+// Ensure FileCounter implements the Counter interface
+var _ Counter = (*FileCounter)(nil)
+
+// NewFileCounter creates a FileCounter from the given configuration.
+func NewFileCounter(config FileCounterConfig) (*FileCounter, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("directory must be provided")
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating directory: %v", err)
+	}
+	return &FileCounter{dir: config.Dir}, nil
+}
+
+// Current implements Counter.
+func (c *FileCounter) Current(_ context.Context, id string) (uint64, error) {
+	if err := checkID(id); err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading counter: %v", err)
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing counter %s: %v", id, err)
+	}
+	return v, nil
+}
+
+// Advance implements Counter.  The file is written atomically.
+func (c *FileCounter) Advance(ctx context.Context, id string, version uint64) error {
+	current, err := c.Current(ctx, id)
+	if err != nil {
+		return err
+	}
+	if version < current {
+		return fmt.Errorf("counter %s cannot move back from %d to %d", id, current, version)
+	}
+	tmp, err := os.CreateTemp(c.dir, "."+id+".*")
+	if err != nil {
+		return fmt.Errorf("creating file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(strconv.FormatUint(version, 10) + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, id))
+}
+
+// KeyVersion identifies the shares a party holds in a way every party of the
+// key agrees on: KeyID names the key, for example by its public key or
+// fingerprint, and Epoch counts the refreshes the shares went through.  All
+// parties advance Epoch together, with every refresh.
+type KeyVersion struct {
+	KeyID []byte
+	Epoch uint64
+}
+
+// IsZero reports whether v is the zero KeyVersion, as used before a DKG.
+func (v KeyVersion) IsZero() bool { return len(v.KeyID) == 0 && v.Epoch == 0 }
+
+// Equal reports whether v and o name the same key and epoch.
+func (v KeyVersion) Equal(o KeyVersion) bool {
+	return v.Epoch == o.Epoch && bytes.Equal(v.KeyID, o.KeyID)
+}
+
+// Bytes encodes v as epoch (uint64) | key ID, or as nothing if v is zero.
+func (v KeyVersion) Bytes() []byte {
+	if v.IsZero() {
+		return nil
+	}
+	return append(binary.BigEndian.AppendUint64(nil, v.Epoch), v.KeyID...)
+}
+
+// ParseKeyVersion parses the output of KeyVersion.Bytes.
+func ParseKeyVersion(data []byte) (KeyVersion, error) {
+	if len(data) == 0 {
+		return KeyVersion{}, nil
+	}
+	if len(data) < 8 {
+		return KeyVersion{}, fmt.Errorf("key version too short")
+	}
+	return KeyVersion{Epoch: binary.BigEndian.Uint64(data), KeyID: bytes.Clone(data[8:])}, nil
+}
+
+// VersionedStoreConfig contains the configuration for a VersionedStore.
+type VersionedStoreConfig struct {
+	// Medium stores the versioned envelopes.
+	Medium Medium
+	// Counter records the latest version of every share.
+	Counter Counter
+	// Key is the 32-byte AES-256-GCM key the shares are encrypted with.
+	// The envelope header with the share ID, version and KeyVersion is
+	// authenticated as additional data, so it cannot be edited.
+	Key []byte
+}
+
+// VersionedStore stores shares together with a monotonic version and rejects
+// loading any share older than the latest one stored.
+type VersionedStore struct {
+	medium  Medium
+	counter Counter
+	aead    cipher.AEAD
+}
+
+// VersionedShare is a share loaded from a VersionedStore.
+type VersionedShare struct {
+	Share      []byte
+	Version    uint64     // Local version, advanced by every Put
+	KeyVersion KeyVersion // Key version the share was stored with
+}
+
+// NewVersionedStore creates a VersionedStore from the given configuration.
+func NewVersionedStore(config VersionedStoreConfig) (*VersionedStore, error) {
+	if config.Medium == nil || config.Counter == nil {
+		return nil, fmt.Errorf("medium and counter must be provided")
+	}
+	if len(config.Key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(config.Key))
+	}
+	aead, err := newGCM(config.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionedStore{medium: config.Medium, counter: config.Counter, aead: aead}, nil
+}
+
+// Put stores share with its key version under the next local version and
+// returns that version.  Call it with the new share and the next epoch after
+// every refresh.
+//
+// The envelope is written before the counter is advanced, so a crash in
+// between leaves a share newer than the counter, which Load accepts and rolls
+// the counter forward to.  If the medium is an EnvelopeMedium that could not
+// retire the previous data key, Put returns the new version together with the
+// *RetireError.
+func (s *VersionedStore) Put(ctx context.Context, id string, keyVersion KeyVersion, share []byte) (uint64, error) {
+	if err := checkID(id); err != nil {
+		return 0, err
+	}
+	current, err := s.counter.Current(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("reading counter: %w", err)
+	}
+	version := current + 1
+	data, err := sealVersion(s.aead, id, version, keyVersion, share)
+	if err != nil {
+		return 0, err
+	}
+	var retire *RetireError
+	if err := s.medium.Store(ctx, id, data); errors.As(err, &retire) {
+		// The share is stored; only the old data key outlived it.
+	} else if err != nil {
+		return 0, fmt.Errorf("storing share on %s: %v", s.medium.Name(), err)
+	}
+	if err := s.counter.Advance(ctx, id, version); err != nil {
+		return 0, fmt.Errorf("advancing counter: %w", err)
+	}
+	if retire != nil {
+		return version, retire
+	}
+	return version, nil
+}
+
+// Load returns the share stored under id with its versions.  It returns a
+// *RollbackError if the stored share is older than the counter.
+func (s *VersionedStore) Load(ctx context.Context, id string) (*VersionedShare, error) {
+	data, err := s.medium.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := openVersion(s.aead, id, data)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.counter.Current(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("reading counter: %w", err)
+	}
+	if stored.Version < current {
+		return nil, &RollbackError{ID: id, Version: stored.Version, Expected: current}
+	}
+	if stored.Version > current {
+		if err := s.counter.Advance(ctx, id, stored.Version); err != nil {
+			return nil, fmt.Errorf("advancing counter: %w", err)
+		}
+	}
+	return stored, nil
+}
+
+// versionHeader is the authenticated, unencrypted part of a versioned
+// envelope.
+type versionHeader struct {
+	ID         string
+	Version    uint64
+	KeyVersion KeyVersion
+}
+
+// sealVersion encrypts share into "CBSV" | version | len(id) | id |
+// len(key version) | key version | nonce | ciphertext, with everything before
+// the nonce as additional data.  Binding the ID prevents an envelope from
+// being replayed under another share's name, binding the versions prevents
+// them from being edited.
+func sealVersion(aead cipher.AEAD, id string, version uint64, keyVersion KeyVersion, share []byte) ([]byte, error) {
+	kv := keyVersion.Bytes()
+	out := make([]byte, 0, len(versionMagic)+8+2+len(id)+2+len(kv)+aead.NonceSize()+len(share)+aead.Overhead())
+	out = append(out, versionMagic...)
+	out = binary.BigEndian.AppendUint64(out, version)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(id)))
+	out = append(out, id...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(kv)))
+	out = append(out, kv...)
+	header := len(out)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %v", err)
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, share, out[:header]), nil
+}
+
+// parseVersionHeader splits a versioned envelope into its header, the
+// additional data it was sealed with and the sealed rest.
+func parseVersionHeader(data []byte) (*versionHeader, []byte, []byte, error) {
+	head := len(versionMagic) + 8 + 2
+	if len(data) < head || !bytes.Equal(data[:len(versionMagic)], versionMagic) {
+		return nil, nil, nil, fmt.Errorf("data is not versioned")
+	}
+	h := &versionHeader{Version: binary.BigEndian.Uint64(data[len(versionMagic):])}
+	n := int(binary.BigEndian.Uint16(data[len(versionMagic)+8:]))
+	if len(data) < head+n+2 {
+		return nil, nil, nil, fmt.Errorf("versioned envelope is truncated")
+	}
+	h.ID = string(data[head : head+n])
+	head += n
+	n = int(binary.BigEndian.Uint16(data[head:]))
+	head += 2
+	if len(data) < head+n {
+		return nil, nil, nil, fmt.Errorf("versioned envelope is truncated")
+	}
+	var err error
+	if h.KeyVersion, err = ParseKeyVersion(data[head : head+n]); err != nil {
+		return nil, nil, nil, err
+	}
+	return h, data[:head+n], data[head+n:], nil
+}
+
+// openVersion decrypts the output of sealVersion and checks the bound ID.
+func openVersion(aead cipher.AEAD, id string, data []byte) (*VersionedShare, error) {
+	h, ad, sealed, err := parseVersionHeader(data)
+	if err != nil {
+		return nil, fmt.Errorf("share %s: %v", id, err)
+	}
+	if h.ID != id {
+		return nil, fmt.Errorf("share %s is bound to a different ID", id)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("share %s: ciphertext too short", id)
+	}
+	share, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], ad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting share %s: %v", id, err)
+	}
+	return &VersionedShare{Share: share, Version: h.Version, KeyVersion: h.KeyVersion}, nil
+}
+
+// VersionMismatchError reports parties whose key versions disagree during
+// CheckPeerVersions.
+type VersionMismatchError struct {
+	Versions map[int]KeyVersion // Key version presented by every party, including self
+	Stale    []int              // Parties of the same key below the highest epoch, in order
+	Foreign  []int              // Parties holding another key than self, in order
+}
+
+func (e *VersionMismatchError) Error() string {
+	var parts []string
+	if len(e.Stale) > 0 {
+		var b strings.Builder
+		var max uint64
+		for i, p := range e.Stale {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "party %d at epoch %d", p, e.Versions[p].Epoch)
+		}
+		for _, v := range e.Versions {
+			if bytes.Equal(v.KeyID, e.Versions[e.Stale[0]].KeyID) && v.Epoch > max {
+				max = v.Epoch
+			}
+		}
+		parts = append(parts, fmt.Sprintf("stale shares (%s), latest epoch is %d", b.String(), max))
+	}
+	if len(e.Foreign) > 0 {
+		parts = append(parts, fmt.Sprintf("parties %v hold shares of another key", e.Foreign))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Unwrap returns ErrRollback if a party is stale and ErrKeyMismatch
+// otherwise.
+func (e *VersionMismatchError) Unwrap() error {
+	if len(e.Stale) > 0 {
+		return ErrRollback
+	}
+	return ErrKeyMismatch
+}
+
+// CompareKeyVersions checks the key versions presented by every party,
+// including self.  Parties holding another key than self are foreign, and
+// parties of the key below its highest epoch are stale; either is reported
+// as a *VersionMismatchError.  Every party holding the same versions reaches
+// the same verdict.
+func CompareKeyVersions(self int, versions map[int]KeyVersion) error {
+	own := versions[self]
+	var max uint64
+	for _, v := range versions {
+		if bytes.Equal(v.KeyID, own.KeyID) && v.Epoch > max {
+			max = v.Epoch
+		}
+	}
+	e := &VersionMismatchError{Versions: versions}
+	for p, v := range versions {
+		switch {
+		case !bytes.Equal(v.KeyID, own.KeyID):
+			e.Foreign = append(e.Foreign, p)
+		case v.Epoch < max:
+			e.Stale = append(e.Stale, p)
+		}
+	}
+	if len(e.Stale)+len(e.Foreign) == 0 {
+		return nil
+	}
+	sort.Ints(e.Stale)
+	sort.Ints(e.Foreign)
+	return e
+}
+
+// CheckPeerVersions is a setup round run over m before a protocol that uses
+// a stored share.  Every party sends its key version to every peer and all
+// must agree; since a refresh advances the epoch of every party, a party
+// that restored an old backup is detected by the others.  A party whose own
+// epoch is behind learns it here too.  Parties compare key versions rather
+// than the local versions of their stores, which differ between parties.
+//
+// A mismatch is reported as a *VersionMismatchError; all parties see the same
+// set of versions and so abort together.
+func CheckPeerVersions(ctx context.Context, m transport.Messenger, self int, peers []int, version KeyVersion) error {
+	msg := version.Bytes()
+	for _, p := range peers {
+		if err := m.MessageSend(ctx, p, msg); err != nil {
+			return fmt.Errorf("sending version to party %d: %w", p, err)
+		}
+	}
+	versions := map[int]KeyVersion{self: version}
+	for _, p := range peers {
+		data, err := m.MessageReceive(ctx, p)
+		if err != nil {
+			return fmt.Errorf("receiving version from party %d: %w", p, err)
+		}
+		if versions[p], err = ParseKeyVersion(data); err != nil {
+			return fmt.Errorf("version of party %d: %v", p, err)
+		}
+	}
+	return CompareKeyVersions(self, versions)
+}
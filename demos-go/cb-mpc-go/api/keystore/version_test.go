@@ -0,0 +1,242 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVersionedStore(t *testing.T) (*VersionedStore, *FileMedium) {
+	t.Helper()
+	medium, err := NewFileMedium(FileMediumConfig{Dir: t.TempDir(), Key: make([]byte, 32)})
+	require.NoError(t, err)
+	counter, err := NewFileCounter(FileCounterConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	store, err := NewVersionedStore(VersionedStoreConfig{Medium: medium, Counter: counter, Key: testKey})
+	require.NoError(t, err)
+	return store, medium
+}
+
+var (
+	testKey = make([]byte, 32)
+	epoch1  = KeyVersion{KeyID: []byte("key-1"), Epoch: 1}
+	epoch2  = KeyVersion{KeyID: []byte("key-1"), Epoch: 2}
+)
+
+func TestVersionedStoreRejectsRollback(t *testing.T) {
+	ctx := context.Background()
+	store, medium := newVersionedStore(t)
+
+	v, err := store.Put(ctx, "p1", epoch1, []byte("share-v1"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), v)
+	backup, err := medium.Load(ctx, "p1")
+	require.NoError(t, err)
+
+	v, err = store.Put(ctx, "p1", epoch2, []byte("share-v2"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), v)
+
+	stored, err := store.Load(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, &VersionedShare{Share: []byte("share-v2"), Version: 2, KeyVersion: epoch2}, stored)
+
+	// Restore the backup taken before the refresh.
+	require.NoError(t, medium.Store(ctx, "p1", backup))
+	_, err = store.Load(ctx, "p1")
+	var rerr *RollbackError
+	require.ErrorAs(t, err, &rerr)
+	assert.True(t, errors.Is(err, ErrRollback))
+	assert.Equal(t, uint64(1), rerr.Version)
+	assert.Equal(t, uint64(2), rerr.Expected)
+}
+
+func TestVersionedStoreBindsID(t *testing.T) {
+	ctx := context.Background()
+	mem := &memoryMedium{}
+	counter, err := NewFileCounter(FileCounterConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	store, err := NewVersionedStore(VersionedStoreConfig{Medium: mem, Counter: counter, Key: testKey})
+	require.NoError(t, err)
+
+	_, err = store.Put(ctx, "p1", epoch1, []byte("share"))
+	require.NoError(t, err)
+	data, err := mem.Load(ctx, "p1")
+	require.NoError(t, err)
+	require.NoError(t, mem.Store(ctx, "p2", data))
+	_, err = store.Load(ctx, "p2")
+	assert.ErrorContains(t, err, "different ID")
+}
+
+func TestVersionedStoreAuthenticatesVersions(t *testing.T) {
+	ctx := context.Background()
+	mem := &memoryMedium{}
+	counter, err := NewFileCounter(FileCounterConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	store, err := NewVersionedStore(VersionedStoreConfig{Medium: mem, Counter: counter, Key: testKey})
+	require.NoError(t, err)
+
+	_, err = store.Put(ctx, "p1", epoch1, []byte("share"))
+	require.NoError(t, err)
+	data, err := mem.Load(ctx, "p1")
+	require.NoError(t, err)
+
+	// Raise the version of the stored envelope, e.g. to pass off an old
+	// backup as current.
+	edited := bytes.Clone(data)
+	edited[len(versionMagic)+7]++
+	require.NoError(t, mem.Store(ctx, "p1", edited))
+	_, err = store.Load(ctx, "p1")
+	assert.ErrorContains(t, err, "decrypting")
+
+	// Or its epoch.
+	h, ad, _, err := parseVersionHeader(data)
+	require.NoError(t, err)
+	edited = bytes.Clone(data)
+	edited[len(ad)-len(h.KeyVersion.KeyID)-1]++
+	require.NoError(t, mem.Store(ctx, "p1", edited))
+	_, err = store.Load(ctx, "p1")
+	assert.ErrorContains(t, err, "decrypting")
+
+	_, err = NewVersionedStore(VersionedStoreConfig{Medium: mem, Counter: counter})
+	assert.Error(t, err, "the key is required")
+}
+
+func TestVersionedStoreRollsCounterForward(t *testing.T) {
+	ctx := context.Background()
+	store, medium := newVersionedStore(t)
+
+	// Simulate a crash between storing the envelope and advancing the counter.
+	data, err := sealVersion(store.aead, "p1", 5, epoch1, []byte("share"))
+	require.NoError(t, err)
+	require.NoError(t, medium.Store(ctx, "p1", data))
+	stored, err := store.Load(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), stored.Version)
+	current, err := store.counter.Current(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), current)
+}
+
+func TestFileCounterIsMonotonic(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFileCounter(FileCounterConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, c.Advance(ctx, "p1", 3))
+	require.NoError(t, c.Advance(ctx, "p1", 3))
+	assert.Error(t, c.Advance(ctx, "p1", 2))
+}
+
+// chanMessenger is an in-memory transport.Messenger; links[i][j] carries
+// i -> j.
+type chanMessenger struct {
+	self  int
+	links map[int]map[int]chan []byte
+}
+
+func newChanNetwork(n int) []*chanMessenger {
+	links := make(map[int]map[int]chan []byte)
+	for i := 0; i < n; i++ {
+		links[i] = make(map[int]chan []byte)
+		for j := 0; j < n; j++ {
+			links[i][j] = make(chan []byte, 1)
+		}
+	}
+	out := make([]*chanMessenger, n)
+	for i := range out {
+		out[i] = &chanMessenger{self: i, links: links}
+	}
+	return out
+}
+
+func (m *chanMessenger) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	m.links[m.self][receiver] <- buffer
+	return nil
+}
+
+func (m *chanMessenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	select {
+	case msg := <-m.links[sender][m.self]:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *chanMessenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	out := make([][]byte, len(senders))
+	for i, s := range senders {
+		msg, err := m.MessageReceive(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = msg
+	}
+	return out, nil
+}
+
+func checkVersions(t *testing.T, versions []KeyVersion) []error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	net := newChanNetwork(len(versions))
+	errs := make([]error, len(versions))
+	var wg sync.WaitGroup
+	for i := range versions {
+		var peers []int
+		for j := range versions {
+			if j != i {
+				peers = append(peers, j)
+			}
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = CheckPeerVersions(ctx, net[i], i, peers, versions[i])
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestCheckPeerVersions(t *testing.T) {
+	for _, err := range checkVersions(t, []KeyVersion{epoch2, epoch2, epoch2}) {
+		assert.NoError(t, err)
+	}
+
+	for _, err := range checkVersions(t, []KeyVersion{epoch2, epoch1, epoch2}) {
+		var merr *VersionMismatchError
+		require.ErrorAs(t, err, &merr)
+		assert.True(t, errors.Is(err, ErrRollback))
+		assert.Equal(t, []int{1}, merr.Stale)
+		assert.Equal(t, "stale shares (party 1 at epoch 1), latest epoch is 2", err.Error())
+	}
+
+	other := KeyVersion{KeyID: []byte("key-2"), Epoch: 2}
+	errs := checkVersions(t, []KeyVersion{epoch2, epoch2, other})
+	var merr *VersionMismatchError
+	require.ErrorAs(t, errs[0], &merr)
+	assert.ErrorIs(t, errs[0], ErrKeyMismatch)
+	assert.Equal(t, []int{2}, merr.Foreign)
+	assert.Empty(t, merr.Stale)
+	require.ErrorAs(t, errs[2], &merr)
+	assert.Equal(t, []int{0, 1}, merr.Foreign)
+}
+
+func TestKeyVersionBytes(t *testing.T) {
+	v, err := ParseKeyVersion(epoch2.Bytes())
+	require.NoError(t, err)
+	assert.True(t, v.Equal(epoch2))
+	assert.Nil(t, KeyVersion{}.Bytes())
+	v, err = ParseKeyVersion(nil)
+	require.NoError(t, err)
+	assert.True(t, v.IsZero())
+	_, err = ParseKeyVersion([]byte{1, 2})
+	assert.Error(t, err)
+}
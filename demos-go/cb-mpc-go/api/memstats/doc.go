@@ -0,0 +1,22 @@
+// Package memstats reports the native objects – key shares, jobs, points and
+// curves – alive in the process, with an estimate of the memory they hold.
+//
+// Native objects live outside the Go heap, so runtime.MemStats does not see
+// them and a forgotten Free leaks silently.  The counters are updated as
+// objects are created and freed, at the cost of one atomic add each:
+//
+//	s := memstats.Read()
+//	if s.Jobs.Live > 2*maxSessions*parties {
+//	    log.Printf("native jobs piling up: %d live", s.Jobs.Live)
+//	}
+//
+// For monitoring, Publish exports the counters through expvar as
+// "cbmpc_native", and Handler serves them in the Prometheus text format:
+//
+//	http.Handle("/metrics/native", memstats.Handler())
+//
+// Byte counts are estimates from typical object sizes, not measurements: a
+// key share grows with the number of parties and a job with the messages in
+// flight.  Watch the live counts to find leaks and the bytes for trends.  In
+// nompc builds no native objects exist and all counters stay zero.
+package memstats
@@ -0,0 +1,130 @@
+package memstats
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/nativestats"
+)
+
+// ExpvarName is the name under which Publish exports the statistics.
+const ExpvarName = "cbmpc_native"
+
+// Typical sizes of native objects in bytes, for a three-party ed25519 or
+// secp256k1 setup.
+const (
+	keyBytes   = 2048 // Secret share, public key and per-party public shares
+	jobBytes   = 4096 // Party names, network state and message buffers
+	pointBytes = 160  // Coordinates as big numbers
+	curveBytes = 32   // Handle to a shared, static curve
+)
+
+var kinds = []struct {
+	kind  nativestats.Kind
+	name  string
+	bytes uint64
+}{
+	{nativestats.Key, "key", keyBytes},
+	{nativestats.Job, "job", jobBytes},
+	{nativestats.Point, "point", pointBytes},
+	{nativestats.Curve, "curve", curveBytes},
+}
+
+// Objects are the statistics of one kind of native object.
+type Objects struct {
+	Live        uint64 `json:"live"`
+	Created     uint64 `json:"created"`
+	Freed       uint64 `json:"freed"`
+	ApproxBytes uint64 `json:"approx_bytes"` // Estimated memory of the live objects
+}
+
+// Stats are the statistics of all native objects.
+type Stats struct {
+	Keys   Objects `json:"keys"`
+	Jobs   Objects `json:"jobs"`
+	Points Objects `json:"points"`
+	Curves Objects `json:"curves"`
+	// ApproxBytes is the estimated memory of all live objects.
+	ApproxBytes uint64 `json:"approx_bytes"`
+}
+
+// Read returns the current statistics.
+func Read() Stats {
+	var s Stats
+	for _, k := range kinds {
+		o := read(k.kind, k.bytes)
+		*s.objects(k.kind) = o
+		s.ApproxBytes += o.ApproxBytes
+	}
+	return s
+}
+
+func read(kind nativestats.Kind, bytes uint64) Objects {
+	created, freed := nativestats.Counts(kind)
+	o := Objects{Created: created, Freed: freed}
+	if created > freed {
+		o.Live = created - freed
+	}
+	o.ApproxBytes = o.Live * bytes
+	return o
+}
+
+func (s *Stats) objects(kind nativestats.Kind) *Objects {
+	switch kind {
+	case nativestats.Key:
+		return &s.Keys
+	case nativestats.Job:
+		return &s.Jobs
+	case nativestats.Point:
+		return &s.Points
+	default:
+		return &s.Curves
+	}
+}
+
+var publish sync.Once
+
+// Publish exports the statistics through expvar as ExpvarName.  It may be
+// called more than once.
+func Publish() {
+	publish.Do(func() {
+		expvar.Publish(ExpvarName, expvar.Func(func() any { return Read() }))
+	})
+}
+
+// WritePrometheus writes the statistics to w in the Prometheus text
+// exposition format.
+func WritePrometheus(w io.Writer) error {
+	s := Read()
+	metrics := []struct {
+		name, help, typ string
+		value           func(o *Objects) uint64
+	}{
+		{"cbmpc_native_objects", "Live native objects.", "gauge", func(o *Objects) uint64 { return o.Live }},
+		{"cbmpc_native_objects_created_total", "Native objects created.", "counter", func(o *Objects) uint64 { return o.Created }},
+		{"cbmpc_native_objects_freed_total", "Native objects freed.", "counter", func(o *Objects) uint64 { return o.Freed }},
+		{"cbmpc_native_approx_bytes", "Estimated memory of live native objects.", "gauge", func(o *Objects) uint64 { return o.ApproxBytes }},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ); err != nil {
+			return err
+		}
+		for _, k := range kinds {
+			if _, err := fmt.Fprintf(w, "%s{kind=%q} %d\n", m.name, k.name, m.value(s.objects(k.kind))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Handler serves the statistics in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WritePrometheus(w)
+	})
+}
@@ -0,0 +1,58 @@
+package memstats
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/nativestats"
+)
+
+func TestReadTracksLiveObjects(t *testing.T) {
+	before := Read()
+	nativestats.Alloc(nativestats.Key)
+	nativestats.Alloc(nativestats.Key)
+	nativestats.Alloc(nativestats.Job)
+	nativestats.Free(nativestats.Key)
+
+	s := Read()
+	assert.Equal(t, before.Keys.Live+1, s.Keys.Live)
+	assert.Equal(t, before.Keys.Created+2, s.Keys.Created)
+	assert.Equal(t, before.Keys.Freed+1, s.Keys.Freed)
+	assert.Equal(t, before.Jobs.Live+1, s.Jobs.Live)
+	assert.Equal(t, before.ApproxBytes+keyBytes+jobBytes, s.ApproxBytes)
+
+	nativestats.Free(nativestats.Key)
+	nativestats.Free(nativestats.Job)
+	assert.Equal(t, before.ApproxBytes, Read().ApproxBytes)
+}
+
+func TestPublish(t *testing.T) {
+	Publish()
+	Publish()
+	v := expvar.Get(ExpvarName)
+	require.NotNil(t, v)
+	var s Stats
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &s))
+	assert.Equal(t, Read(), s)
+}
+
+func TestHandlerServesPrometheusText(t *testing.T) {
+	nativestats.Alloc(nativestats.Point)
+	defer nativestats.Free(nativestats.Point)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE cbmpc_native_objects gauge\n")
+	assert.Contains(t, body, "# TYPE cbmpc_native_objects_created_total counter\n")
+	for _, kind := range []string{"key", "job", "point", "curve"} {
+		assert.Contains(t, body, `cbmpc_native_approx_bytes{kind="`+kind+`"} `)
+	}
+	assert.Contains(t, body, fmt.Sprintf("cbmpc_native_objects{kind=\"point\"} %d\n", Read().Points.Live))
+}
@@ -6,12 +6,9 @@ package mpc
 
 import (
 	"fmt"
-	"runtime"
 	"strings"
 
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
-	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/internal/curveref"
-	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
 )
 
 // NodeKind tells which logical operator a node represents.
@@ -86,69 +83,6 @@ func (as *AccessStructure) String() string {
 	return sb.String()
 }
 
-// toCryptoAC converts the AccessStructure into the native secret-sharing
-// representation expected by the MPC engine and returns an opaque handle that
-// must eventually be released via cgobinding.FreeAccessStructure.
-//
-// The method panics if the AccessStructure is malformed (nil fields, unknown
-// node kinds, …). Such errors typically indicate a misuse by calling code.
-func (as *AccessStructure) toCryptoAC() cgobinding.C_AcPtr {
-	if as == nil {
-		panic("AccessStructure.toCryptoAC: receiver is nil")
-	}
-	if as.Root == nil {
-		panic("AccessStructure.toCryptoAC: Root is nil")
-	}
-	if as.Curve == nil {
-		panic("AccessStructure.toCryptoAC: Curve is nil")
-	}
-
-	// Local helper mapping Go enum to C enum (identical to the previous
-	// implementation that lived on AccessNode).
-	kindToC := func(k NodeKind) cgobinding.NodeType {
-		switch k {
-		case KindLeaf:
-			return cgobinding.NodeType_LEAF
-		case KindAnd:
-			return cgobinding.NodeType_AND
-		case KindOr:
-			return cgobinding.NodeType_OR
-		case KindThreshold:
-			return cgobinding.NodeType_THRESHOLD
-		default:
-			panic(fmt.Sprintf("AccessStructure.toCryptoAC: unknown NodeKind %d", k))
-		}
-	}
-
-	// Recursively clone the Go tree into the C representation.
-	var build func(n *AccessNode) cgobinding.C_NodePtr
-	build = func(n *AccessNode) cgobinding.C_NodePtr {
-		cNode := cgobinding.NewNode(kindToC(n.Kind), n.Name, n.K)
-		for _, child := range n.Children {
-			if child == nil {
-				continue
-			}
-			childPtr := build(child)
-			cgobinding.AddChild(cNode, childPtr)
-		}
-		return cNode
-	}
-
-	rootPtr := build(as.Root)
-
-	// Resolve the underlying native curve reference via the internal helper.
-	curveRef := curveref.Ref(as.Curve)
-
-	ac := cgobinding.NewAccessStructure(rootPtr, curveRef)
-
-	// Ensure native resources are released when the Go value becomes
-	// unreachable by attaching a finalizer.
-	runtime.SetFinalizer(&ac, func(p *cgobinding.C_AcPtr) {
-		cgobinding.FreeAccessStructure(*p)
-	})
-	return ac
-}
-
 // ---- helpers (section 3) ----
 
 // Leaf returns a pointer to a leaf node.
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package mpc
 
 import (
@@ -6,6 +8,9 @@ import (
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
 )
 
+// maxRandomBits bounds AgreeRandomRequest.BitLen.
+const maxRandomBits = 1 << 16
+
 // AgreeRandomRequest represents the input parameters for agree random protocol
 type AgreeRandomRequest struct {
 	BitLen int // Number of bits for the random value
@@ -19,8 +24,14 @@ type AgreeRandomResponse struct {
 // AgreeRandom executes the agree random protocol between two parties.
 // Both parties will agree on the same random value of the specified bit length.
 func AgreeRandom(job2p *Job2P, req *AgreeRandomRequest) (*AgreeRandomResponse, error) {
-	if req.BitLen <= 0 {
-		return nil, fmt.Errorf("bit length must be positive, got %d", req.BitLen)
+	if job2p == nil {
+		return nil, invalid("job", "must be provided")
+	}
+	if req == nil {
+		return nil, invalid("request", "cannot be nil")
+	}
+	if req.BitLen <= 0 || req.BitLen > maxRandomBits {
+		return nil, invalid("bit length", "must be between 1 and %d, got %d", maxRandomBits, req.BitLen)
 	}
 
 	// Execute the agree random protocol using the provided Job2P
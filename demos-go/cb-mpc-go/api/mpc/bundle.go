@@ -0,0 +1,184 @@
+package mpc
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// publicBundleVersion is the format version written into every bundle.
+const publicBundleVersion = 1
+
+// publicBundleDomain prefixes the signed digest so that a bundle signature
+// cannot be replayed as a signature over anything else.
+const publicBundleDomain = "cb-mpc public bundle v1\x00"
+
+// ErrBadBundleSignature is returned when a public bundle does not verify.
+var ErrBadBundleSignature = errors.New("mpc: invalid public bundle signature")
+
+// PublicBundle is the public description of a distributed key.  It contains
+// everything a third party needs to verify signatures and attestations made
+// with the key, and no secret material.
+type PublicBundle struct {
+	Version         int               `json:"version"`
+	Curve           string            `json:"curve"`
+	PublicKey       []byte            `json:"public_key"`              // Group public key Q
+	PublicShares    map[string][]byte `json:"public_shares"`           // Per-party public shares Qi
+	AccessStructure *AccessNode       `json:"access_structure"`        // Root of the access structure
+	Identities      map[string][]byte `json:"identities,omitempty"`    // Party identity keys, e.g. DER certificates
+	CeremonyHash    []byte            `json:"ceremony_hash,omitempty"` // Hash of the key creation ceremony report
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+// SignedPublicBundle is a PublicBundle signed with an Ed25519 key.  The bundle
+// is kept as the exact JSON that was signed.
+type SignedPublicBundle struct {
+	Bundle    json.RawMessage   `json:"bundle"`
+	PublicKey ed25519.PublicKey `json:"public_key"`
+	Signature []byte            `json:"signature"`
+}
+
+// PublicBundleOptions supplies the parts of a bundle that are not stored in a
+// key share.
+type PublicBundleOptions struct {
+	// AccessStructure the key was generated for.  Every leaf must name a
+	// party holding a share.
+	AccessStructure *AccessStructure
+	// Identities maps party names to identity keys.  Optional.
+	Identities map[string][]byte
+	// CeremonyHash identifies the ceremony that created the key.  Optional.
+	CeremonyHash []byte
+	// Signer signs the bundle.
+	Signer ed25519.PrivateKey
+}
+
+// newPublicBundle assembles and validates a bundle from its public parts.
+func newPublicBundle(curveName string, q []byte, shares map[string][]byte, opts *PublicBundleOptions) (*PublicBundle, error) {
+	if opts == nil || opts.AccessStructure == nil || opts.AccessStructure.Root == nil {
+		return nil, fmt.Errorf("access structure must be provided")
+	}
+	if err := opts.AccessStructure.Validate(nil); err != nil {
+		return nil, err
+	}
+	leaves, err := opts.AccessStructure.Root.leaves()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range leaves {
+		if _, ok := shares[name]; !ok {
+			return nil, fmt.Errorf("access structure names party %q without a public share", name)
+		}
+	}
+	for name := range opts.Identities {
+		if _, ok := shares[name]; !ok {
+			return nil, fmt.Errorf("identity given for unknown party %q", name)
+		}
+	}
+	return &PublicBundle{
+		Version:         publicBundleVersion,
+		Curve:           curveName,
+		PublicKey:       q,
+		PublicShares:    shares,
+		AccessStructure: opts.AccessStructure.Root,
+		Identities:      opts.Identities,
+		CeremonyHash:    opts.CeremonyHash,
+		CreatedAt:       time.Now().UTC(),
+	}, nil
+}
+
+// Sign encodes the bundle and signs it with key.
+func (b *PublicBundle) Sign(key ed25519.PrivateKey) (*SignedPublicBundle, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid bundle signing key")
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("encoding public bundle: %v", err)
+	}
+	return &SignedPublicBundle{
+		Bundle:    data,
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, bundleDigest(data)),
+	}, nil
+}
+
+// Verify checks the signature against the embedded public key and returns the
+// decoded bundle.  Callers must additionally check that PublicKey is trusted.
+func (s *SignedPublicBundle) Verify() (*PublicBundle, error) {
+	if len(s.PublicKey) != ed25519.PublicKeySize || !ed25519.Verify(s.PublicKey, bundleDigest(s.Bundle), s.Signature) {
+		return nil, ErrBadBundleSignature
+	}
+	var b PublicBundle
+	if err := json.Unmarshal(s.Bundle, &b); err != nil {
+		return nil, fmt.Errorf("decoding public bundle: %v", err)
+	}
+	if b.Version != publicBundleVersion {
+		return nil, fmt.Errorf("unsupported public bundle version %d", b.Version)
+	}
+	return &b, nil
+}
+
+// ParsePublicBundle decodes and verifies a bundle produced by
+// ExportPublicBundle.
+func ParsePublicBundle(data []byte) (*PublicBundle, *SignedPublicBundle, error) {
+	var s SignedPublicBundle
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, nil, fmt.Errorf("decoding signed public bundle: %v", err)
+	}
+	b, err := s.Verify()
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, &s, nil
+}
+
+func bundleDigest(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return append([]byte(publicBundleDomain), sum[:]...)
+}
+
+// accessNodeJSON is the wire form of an AccessNode; Parent is implied by
+// nesting.
+type accessNodeJSON struct {
+	Name     string        `json:"name,omitempty"`
+	Kind     string        `json:"kind"`
+	K        int           `json:"k,omitempty"`
+	Children []*AccessNode `json:"children,omitempty"`
+}
+
+// MarshalJSON encodes the subtree rooted at n.
+func (n *AccessNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(accessNodeJSON{Name: n.Name, Kind: n.Kind.String(), K: n.K, Children: n.Children})
+}
+
+// UnmarshalJSON decodes a subtree and wires the Parent pointers of its
+// children.
+func (n *AccessNode) UnmarshalJSON(data []byte) error {
+	var v accessNodeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var kind NodeKind
+	switch v.Kind {
+	case "LEAF":
+		kind = KindLeaf
+	case "AND":
+		kind = KindAnd
+	case "OR":
+		kind = KindOr
+	case "THRESHOLD":
+		kind = KindThreshold
+	default:
+		return fmt.Errorf("unknown access node kind %q", v.Kind)
+	}
+	*n = AccessNode{Name: v.Name, Kind: kind, K: v.K, Children: v.Children}
+	for _, c := range n.Children {
+		if c != nil {
+			c.Parent = n
+		}
+	}
+	return nil
+}
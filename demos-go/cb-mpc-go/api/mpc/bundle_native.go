@@ -0,0 +1,56 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+)
+
+// ExportPublicBundle returns the signed public bundle of the key as JSON.
+func (k ECDSAMPCKey) ExportPublicBundle(opts *PublicBundleOptions) ([]byte, error) {
+	return exportPublicBundle(k.Curve, k.Q, k.Qis, opts)
+}
+
+// ExportPublicBundle returns the signed public bundle of the key as JSON.
+func (k EDDSAMPCKey) ExportPublicBundle(opts *PublicBundleOptions) ([]byte, error) {
+	return exportPublicBundle(k.Curve, k.Q, k.Qis, opts)
+}
+
+func exportPublicBundle(
+	curveFn func() (curve.Curve, error),
+	qFn func() (*curve.Point, error),
+	qisFn func() (map[string]*curve.Point, error),
+	opts *PublicBundleOptions,
+) ([]byte, error) {
+	cv, err := curveFn()
+	if err != nil {
+		return nil, fmt.Errorf("reading curve: %v", err)
+	}
+	defer cv.Free()
+	q, err := qFn()
+	if err != nil {
+		return nil, fmt.Errorf("reading public key: %v", err)
+	}
+	defer q.Free()
+	qis, err := qisFn()
+	if err != nil {
+		return nil, fmt.Errorf("reading public shares: %v", err)
+	}
+	shares := make(map[string][]byte, len(qis))
+	for name, pt := range qis {
+		shares[name] = pt.Bytes()
+		pt.Free()
+	}
+	bundle, err := newPublicBundle(cv.String(), q.Bytes(), shares, opts)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := bundle.Sign(opts.Signer)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(signed)
+}
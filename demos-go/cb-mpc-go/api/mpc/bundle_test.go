@@ -0,0 +1,87 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicBundleRoundTrip(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	shares := map[string][]byte{"a": {1}, "b": {2}, "admin": {3}}
+	root := And("", Leaf("admin"), Threshold("sig", 1, Leaf("a"), Leaf("b")))
+	bundle, err := newPublicBundle("secp256k1", []byte{9}, shares, &PublicBundleOptions{
+		AccessStructure: &AccessStructure{Root: root},
+		Identities:      map[string][]byte{"admin": []byte("cert")},
+		CeremonyHash:    []byte{0xCE},
+	})
+	require.NoError(t, err)
+	signed, err := bundle.Sign(key)
+	require.NoError(t, err)
+	data, err := json.Marshal(signed)
+	require.NoError(t, err)
+
+	got, _, err := ParsePublicBundle(data)
+	require.NoError(t, err)
+	assert.Equal(t, shares, got.PublicShares)
+	assert.Equal(t, []byte{0xCE}, got.CeremonyHash)
+	assert.Equal(t, root.String(), got.AccessStructure.String())
+	assert.Same(t, got.AccessStructure, got.AccessStructure.Children[1].Parent)
+
+	signed.Bundle = []byte(`{"version":1,"curve":"P-256"}`)
+	_, err = signed.Verify()
+	assert.ErrorIs(t, err, ErrBadBundleSignature)
+}
+
+func TestPublicBundleRejectsUnknownParties(t *testing.T) {
+	shares := map[string][]byte{"a": {1}, "b": {2}}
+	_, err := newPublicBundle("Ed25519", []byte{9}, shares, &PublicBundleOptions{
+		AccessStructure: &AccessStructure{Root: Threshold("", 2, Leaf("a"), Leaf("c"))},
+	})
+	assert.Error(t, err)
+	_, err = newPublicBundle("Ed25519", []byte{9}, shares, &PublicBundleOptions{
+		AccessStructure: &AccessStructure{Root: Threshold("", 2, Leaf("a"), Leaf("b"))},
+		Identities:      map[string][]byte{"c": nil},
+	})
+	assert.Error(t, err)
+}
+
+func TestEDDSAMPCKeyExportPublicBundle(t *testing.T) {
+	ed, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer ed.Free()
+
+	keys, _, err := EDDSAMPCWithMockNet(3, ed, []byte("bundle"))
+	require.NoError(t, err)
+	names := mocknet.GeneratePartyNames(3)
+	leaves := make([]*AccessNode, len(names))
+	for i, name := range names {
+		leaves[i] = Leaf(name)
+	}
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	data, err := keys[0].KeyShare.ExportPublicBundle(&PublicBundleOptions{
+		AccessStructure: &AccessStructure{Root: Threshold("", 2, leaves...), Curve: ed},
+		Signer:          signer,
+	})
+	require.NoError(t, err)
+	bundle, _, err := ParsePublicBundle(data)
+	require.NoError(t, err)
+
+	q, err := keys[1].KeyShare.Q()
+	require.NoError(t, err)
+	defer q.Free()
+	assert.Equal(t, q.Bytes(), bundle.PublicKey)
+	assert.Equal(t, ed.String(), bundle.Curve)
+	assert.Len(t, bundle.PublicShares, 3)
+}
@@ -0,0 +1,180 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"fmt"
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// committees are the large-committee configurations exercised by the tests
+// and benchmarks below.
+var committees = []struct{ threshold, parties int }{
+	{2, 3},
+	{3, 5},
+	{5, 9},
+	{7, 15},
+}
+
+// runThresholdDKG runs the ECDSA threshold DKG for a t-of-n committee over the
+// mock network and returns every party's key share.
+func runThresholdDKG(cv curvepkg.Curve, threshold, nParties int) ([]ECDSAMPCKey, error) {
+	pnames := mocknet.GeneratePartyNames(nParties)
+	messengers := mocknet.NewMockNetwork(nParties)
+	shares := make([]ECDSAMPCKey, nParties)
+	errs := make(chan error, nParties)
+	for i := 0; i < nParties; i++ {
+		go func(idx int) {
+			job, err := NewJobMP(messengers[idx], nParties, idx, pnames)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer job.Free()
+			resp, err := ECDSAMPCThresholdDKG(job, &ECDSAMPCThresholdDKGRequest{
+				Curve:           cv,
+				AccessStructure: createThresholdAccessStructure(pnames, threshold, cv),
+			})
+			if err == nil {
+				shares[idx] = resp.KeyShare
+			}
+			errs <- err
+		}(i)
+	}
+	var firstErr error
+	for i := 0; i < nParties; i++ {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return shares, firstErr
+}
+
+func TestThresholdDKGLargeCommittees(t *testing.T) {
+	cv, err := curvepkg.NewSecp256k1()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	for _, c := range committees {
+		if testing.Short() && c.parties > 5 {
+			continue
+		}
+		t.Run(fmt.Sprintf("%d-of-%d", c.threshold, c.parties), func(t *testing.T) {
+			shares, err := runThresholdDKG(cv, c.threshold, c.parties)
+			require.NoError(t, err)
+			q0, err := shares[0].Q()
+			require.NoError(t, err)
+			defer q0.Free()
+			for i, share := range shares {
+				q, err := share.Q()
+				require.NoError(t, err)
+				assert.Equal(t, q0.Bytes(), q.Bytes(), "party %d public key", i)
+				q.Free()
+				share.Free()
+			}
+		})
+	}
+}
+
+func TestNewJobMPRejectsOversizedCommittee(t *testing.T) {
+	n := MaxParties + 1
+	_, err := NewJobMP(mocknet.NewMockNetwork(1)[0], n, 0, mocknet.GeneratePartyNames(n))
+	assert.ErrorContains(t, err, "exceeds the maximum")
+}
+
+func TestAccessStructureValidate(t *testing.T) {
+	pnames := []string{"a", "b", "c"}
+	cases := map[string]*AccessNode{
+		"threshold too high": Threshold("", 4, Leaf("a"), Leaf("b"), Leaf("c")),
+		"unknown party":      Threshold("", 2, Leaf("a"), Leaf("d")),
+		"duplicate leaf":     Or("", Leaf("a"), Leaf("a")),
+		"empty and":          And("", And("x")),
+		"named root":         Threshold("root", 1, Leaf("a")),
+	}
+	for name, root := range cases {
+		assert.Error(t, (&AccessStructure{Root: root}).Validate(pnames), name)
+	}
+	ok := And("", Leaf("a"), Threshold("t", 1, Leaf("b"), Leaf("c")))
+	assert.NoError(t, (&AccessStructure{Root: ok}).Validate(pnames))
+}
+
+func BenchmarkECDSAThresholdDKG(b *testing.B) {
+	cv, err := curvepkg.NewSecp256k1()
+	require.NoError(b, err)
+	defer cv.Free()
+
+	for _, c := range committees {
+		b.Run(fmt.Sprintf("%d-of-%d", c.threshold, c.parties), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				shares, err := runThresholdDKG(cv, c.threshold, c.parties)
+				require.NoError(b, err)
+				for _, share := range shares {
+					share.Free()
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkECDSAMPCSign(b *testing.B) {
+	cv, err := curvepkg.NewSecp256k1()
+	require.NoError(b, err)
+	defer cv.Free()
+
+	for _, c := range committees {
+		b.Run(fmt.Sprintf("%d-parties", c.parties), func(b *testing.B) {
+			pnames := mocknet.GeneratePartyNames(c.parties)
+			keys := make([]ECDSAMPCKey, c.parties)
+			runParties(b, c.parties, pnames, func(job *JobMP) error {
+				resp, err := ECDSAMPCKeyGen(job, &ECDSAMPCKeyGenRequest{Curve: cv})
+				if err == nil {
+					keys[job.GetPartyIndex()] = resp.KeyShare
+				}
+				return err
+			})
+			defer func() {
+				for i := range keys {
+					keys[i].Free()
+				}
+			}()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runParties(b, c.parties, pnames, func(job *JobMP) error {
+					_, err := ECDSAMPCSign(job, &ECDSAMPCSignRequest{
+						KeyShare: keys[job.GetPartyIndex()],
+						Message:  []byte("benchmark message digest 32bytes"),
+					})
+					return err
+				})
+			}
+		})
+	}
+}
+
+// runParties runs fn for every party of a fresh mock network and fails b on
+// any error.
+func runParties(b *testing.B, nParties int, pnames []string, fn func(job *JobMP) error) {
+	b.Helper()
+	messengers := mocknet.NewMockNetwork(nParties)
+	errs := make(chan error, nParties)
+	for i := 0; i < nParties; i++ {
+		go func(idx int) {
+			job, err := NewJobMP(messengers[idx], nParties, idx, pnames)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer job.Free()
+			errs <- fn(job)
+		}(i)
+	}
+	for i := 0; i < nParties; i++ {
+		require.NoError(b, <-errs)
+	}
+}
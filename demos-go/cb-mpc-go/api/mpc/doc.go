@@ -34,6 +34,154 @@
 //	job, _ := mpc.NewJob2P(messenger, selfIndex, []string{"alice", "bob"})
 //	resp, err := mpc.AgreeRandom(job, &mpc.AgreeRandomRequest{BitLen: 256})
 //
+// # Building
+//
+// The package links the native library through cgo: run `make build-no-test`
+// and `make install` first, which build libcbmpc and the static OpenSSL it
+// links against.  Code that only verifies signatures can use the pure-Go
+// verify package instead and build with CGO_ENABLED=0.
+//
+// Services that never run a protocol – verifiers, coordinators, explorers –
+// can build the whole module with `-tags nompc`.  That mode needs no cgo and
+// keeps only the pure-Go parts: access structures and quorum selection,
+// public bundles (ParsePublicBundle and Verify), committee and input limits,
+// the transport packages and curve identities and point encodings.  Protocol
+// entry points such as NewJobMP are compiled out, and curve arithmetic
+// returns curve.ErrNoNative.
+//
+// # Committee size
+//
+// N-party jobs support up to MaxParties (64) parties.  Key generation sends
+// O(n²) messages per round, so cost grows quickly: see the committee
+// benchmarks (go test -bench . -run ^$) for 3-of-5 up to 7-of-15.  For large
+// committees, generate a threshold key once and sign with a quorum only – the
+// signing cost then depends on the quorum size rather than on n.  NewJobMP and
+// the threshold DKG entry points validate committee and access structure up
+// front and return descriptive errors instead of native failures.
+//
+// NewQuorumJob runs such a quorum over the network of the whole committee.
+// It orders the quorum, translates its job indices to committee indices so
+// that absent parties are never addressed, and converts threshold shares:
+//
+//	job, _ := mpc.NewQuorumJob(messenger, ac, []string{"server", "pin"}, "pin")
+//	defer job.Free()
+//	additive, _ := job.EDDSAAdditiveShare(thresholdShare)
+//	defer additive.Free()
+//	resp, err := mpc.EDDSAMPCSign(job.JobMP, &mpc.EDDSAMPCSignRequest{KeyShare: additive, Message: msg})
+//
+// When one process holds the shares of the whole quorum, as in tests, demos
+// and recovery tools, SignWithQuorum does all of this in one call.  The
+// shares must be marshalled with MarshalBinaryWithAccessStructure, so that
+// the access structure and the committee come with them:
+//
+//	resp, err := mpc.SignWithQuorum(map[string][]byte{"server": s1, "pin": s3}, []string{"server", "pin"}, msg)
+//
+// # Key refresh
+//
+// Refresh replaces every share with a new random share of the same key, so
+// that shares leaked before the refresh are useless afterwards; old and new
+// shares do not combine.  All parties of the committee take part.  Additive
+// shares refresh with EDDSAMPCRefresh, threshold shares with
+// EDDSAMPCThresholdRefresh under their access structure, and Refresh on the
+// key share picks one of the two:
+//
+//	fresh, err := share.Refresh(job, ac, sessionID) // nil ac for additive shares
+//
+// Both check that the public key did not change.  Store the new share and
+// erase the old one only once every party has its new share.
+//
+// Reshare moves an Ed25519 key to another access structure and party set,
+// e.g. from 2-of-3 to 3-of-5 after adding devices, under the same public
+// key.  The job spans the old parties that deal and the new parties; old
+// parties pass their stored shares, new parties nil:
+//
+//	share, err := mpc.Reshare(job, stored, newAC) // stored is nil on new devices
+//	data, _ := share.MarshalBinaryWithAccessStructure(newAC, job.PartyNames())
+//
+// Every party checks each deal against the dealer's public shares, which
+// commit it to a sharing of the degrees of the new structure.  New shares
+// never combine with old ones, but old shares keep signing under the old
+// structure: once all parties confirm their new shares, Reshare zeroes the
+// stored share it was passed, and other copies must be deleted.
+//
+// # Derived keys
+//
+// One Ed25519 key can back many accounts.  DeriveEd25519 derives a child
+// public key and its tweak from the master public key along a non-hardened
+// path such as "cbmpc/0/3"; hardened paths need the private key and fail
+// with ErrHardenedDerivation.  The scheme is specific to cb-mpc: other
+// wallets do not derive the same children from the same key, whatever the
+// path.  DeriveChild turns an additive share of the master key into the
+// share of the child, without any messages, and the child shares sign with
+// EDDSAMPCSign:
+//
+//	resp, _ := mpc.DeriveChild(&mpc.DeriveChildRequest{KeyShare: additive, Path: "cbmpc/0/3"})
+//	defer resp.KeyShare.Free()
+//	sig, err := mpc.EDDSAMPCSign(job.JobMP, &mpc.EDDSAMPCSignRequest{KeyShare: resp.KeyShare, Message: msg})
+//
+// # ECDSA on secp256k1
+//
+// ECDSAMPCKeyGen and ECDSAMPCSign run on any JobMP, quorum jobs included.
+// The message is the digest to sign, e.g. keccak256 of an Ethereum
+// transaction.  The signature receiver gets the DER signature and, for
+// secp256k1 keys, RecoverableSignature: r || s || v with a low S and the
+// recovery id v, ready for Ethereum transactions and Bitcoin signed
+// messages.  RecoveryID returns v alone.
+//
+// # Input validation
+//
+// Every entry point checks its input in Go before any native code runs: nil
+// jobs, requests and curves, empty key shares, party indices out of range,
+// party names outside letters, digits and . _ - : @, and messages, session
+// IDs and PVE labels longer than the process-wide Limits (see SetLimits).
+// Such input is rejected with an *InputError, which wraps ErrInvalidInput.
+// Signing and refresh also check the job's party names against the parties
+// recorded in the key share: a share of another party, a job in a different
+// order or a threshold share not yet converted for the quorum fails with a
+// *PartyMismatchError listing the discrepancy, instead of deep in the
+// protocol.
+//
+// # Versions
+//
+// The package is written against one libcbmpc release, WrapperVersion.  When
+// the package is initialized it compares it with the version the linked
+// library reports, so a host whose headers, archive and Go code were upgraded
+// only in part fails with a descriptive *VersionError (see CheckVersion)
+// instead of running subtly different protocols than its peers; NewJob2P and
+// NewJobMP return that error.  Version reports both versions, e.g. for health
+// checks.
+//
+// Once a key exists, ExportPublicBundle on the key share produces a signed
+// JSON bundle – group public key, curve, access structure, party identity keys
+// and the hash of the creation ceremony – that third parties can use to verify
+// later signatures and attestations.  ParsePublicBundle verifies it.
+//
+// Threshold key shares can be stored with MarshalBinaryWithAccessStructure,
+// which embeds the access structure and the party names by job index under a
+// checksum.  ECDSAMPCAdditiveShare and EDDSAMPCAdditiveShare then convert such
+// a share for a quorum without the caller rebuilding the structure.  Sealing
+// and restoring both check that the public shares of the key reconstruct its
+// public key under the embedded structure, so a blob whose structure was
+// edited fails with ErrShareIntegrity instead of producing shares for the
+// wrong quorum.  The checksum is unkeyed and only catches corruption; keep
+// shares that need protection from tampering in an EncryptedKeyShare.
+// UnmarshalBinary reads both formats.
+//
+// EncryptedKeyShare encrypts a marshalled share for storage at rest with
+// AES-256-GCM, under a passphrase or PIN stretched with Argon2id or under a
+// caller-supplied key-encryption key:
+//
+//	var sealed mpc.EncryptedKeyShare
+//	_ = sealed.Seal(shareBytes, mpc.Passphrase(pin))
+//	shareBytes, err := sealed.Open(mpc.Passphrase(pin))
+//
+// InspectShare reports the non-secret metadata of a stored share – curve,
+// parties and access structure of an envelope, KDF, key ID and label of an
+// encrypted share – without decrypting or unmarshalling the secret.
+//
+// For keys held in a KMS or HSM, or shares spread over several media, see the
+// keystore package.
+//
 // Every exported helper returns rich, declarative request and response structs
 // making it straightforward to marshal results into JSON or protobuf.
 package mpc
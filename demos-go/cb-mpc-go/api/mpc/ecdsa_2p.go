@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package mpc
 
 import (
@@ -29,6 +31,9 @@ func (k ECDSA2PCKey) cgobindingRef() cgobinding.Mpc_ecdsa2pc_key_ref {
 	return cgobinding.Mpc_ecdsa2pc_key_ref(k)
 }
 
+// empty reports whether k is the zero value.
+func (k ECDSA2PCKey) empty() bool { return k.cgobindingRef().Empty() }
+
 // RoleIndex returns which party (e.g., 0 or 1) owns this key share.
 // It delegates to the underlying cgobinding implementation.
 func (k ECDSA2PCKey) RoleIndex() (int, error) {
@@ -78,8 +83,11 @@ type ECDSA2PCKeyGenResponse struct {
 // ECDSA2PCKeyGen executes the distributed key generation protocol between two parties.
 // Both parties will generate complementary key shares that can be used together for signing.
 func ECDSA2PCKeyGen(job2p *Job2P, req *ECDSA2PCKeyGenRequest) (*ECDSA2PCKeyGenResponse, error) {
+	if job2p == nil {
+		return nil, invalid("job", "must be provided")
+	}
 	if req == nil || req.Curve == nil {
-		return nil, fmt.Errorf("curve must be provided")
+		return nil, invalid("curve", "must be provided")
 	}
 
 	// Execute the distributed key generation using the provided Job2P
@@ -106,8 +114,20 @@ type ECDSA2PCSignResponse struct {
 // ECDSA2PCSign executes the collaborative signing protocol between two parties.
 // Both parties use their key shares to jointly create a signature for the given message.
 func ECDSA2PCSign(job2p *Job2P, req *ECDSA2PCSignRequest) (*ECDSA2PCSignResponse, error) {
-	if len(req.Message) == 0 {
-		return nil, fmt.Errorf("message cannot be empty")
+	if job2p == nil {
+		return nil, invalid("job", "must be provided")
+	}
+	if req == nil {
+		return nil, invalid("request", "cannot be nil")
+	}
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
+	}
+	if err := checkMessage(req.Message); err != nil {
+		return nil, err
+	}
+	if err := checkSessionID(req.SessionID); err != nil {
+		return nil, err
 	}
 
 	// Prepare message array (cgobinding.Sign expects a slice)
@@ -153,8 +173,14 @@ type ECDSA2PCRefreshResponse struct {
 // a new, independent share such that the public key and the combined secret
 // remain unchanged.
 func ECDSA2PCRefresh(job2p *Job2P, req *ECDSA2PCRefreshRequest) (*ECDSA2PCRefreshResponse, error) {
+	if job2p == nil {
+		return nil, invalid("job", "must be provided")
+	}
 	if req == nil {
-		return nil, fmt.Errorf("request must be provided")
+		return nil, invalid("request", "cannot be nil")
+	}
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
 	}
 
 	newKeyRef, err := cgobinding.Refresh(job2p.cgo(), req.KeyShare.cgobindingRef())
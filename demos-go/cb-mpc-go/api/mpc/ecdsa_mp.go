@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package mpc
 
 import (
@@ -9,6 +11,7 @@ import (
 
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
 	curveref "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/internal/curveref"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
 )
 
@@ -89,15 +92,24 @@ func (k ECDSAMPCKey) MarshalBinary() ([]byte, error) {
 // reclaim any resources referenced by the previous value should invoke
 // (*ECDSAMPCKey).Free before calling this method.
 func (k *ECDSAMPCKey) UnmarshalBinary(data []byte) error {
+	share, e, err := openShare(data)
+	if err != nil {
+		return err
+	}
 	var parts [][]byte
-	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&parts); err != nil {
+	if err := gob.NewDecoder(bytes.NewReader(share)).Decode(&parts); err != nil {
 		return err
 	}
 	keyRef, err := cgobinding.DeserializeECDSAShare(parts)
 	if err != nil {
 		return err
 	}
-	*k = newECDSAMPCKey(keyRef)
+	key := newECDSAMPCKey(keyRef)
+	if err := e.checkKey(key.cgobindingRef(), key.Curve, key.PartyName); err != nil {
+		key.Free()
+		return err
+	}
+	*k = key
 	return nil
 }
 
@@ -165,6 +177,9 @@ func (k ECDSAMPCKey) cgobindingRef() cgobinding.Mpc_eckey_mp_ref {
 	return cgobinding.Mpc_eckey_mp_ref(k)
 }
 
+// empty reports whether k is the zero value, e.g. a share that was freed.
+func (k ECDSAMPCKey) empty() bool { return k.cgobindingRef().Empty() }
+
 // ECDSAMPCKeyGenRequest represents a request for N-party ECDSA key generation.
 // The caller specifies the Curve instance instead of a raw numeric identifier
 // to align the API with other MPC primitives (e.g. ECDSA2PC).
@@ -185,16 +200,16 @@ type ECDSAMPCKeyGenResponse struct {
 // All parties must call this function simultaneously with the same parameters
 func ECDSAMPCKeyGen(jobmp *JobMP, req *ECDSAMPCKeyGenRequest) (*ECDSAMPCKeyGenResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if req.Curve == nil {
-		return nil, fmt.Errorf("curve must be provided")
+		return nil, invalid("curve", "must be provided")
 	}
 	if jobmp.NParties() < 3 {
-		return nil, fmt.Errorf("n-party ECDSA requires at least 3 parties (use ECDSA2PC for 2-party)")
+		return nil, invalid("job", "n-party ECDSA requires at least 3 parties (use ECDSA2PC for 2-party)")
 	}
 
 	// Perform distributed key generation using the provided JobMP and curve
@@ -209,29 +224,53 @@ func ECDSAMPCKeyGen(jobmp *JobMP, req *ECDSAMPCKeyGenRequest) (*ECDSAMPCKeyGenRe
 // ECDSAMPCSignRequest represents a request for N-party ECDSA signing
 type ECDSAMPCSignRequest struct {
 	KeyShare          ECDSAMPCKey // The key share from key generation
-	Message           []byte      // The message to sign
+	Message           []byte      // The digest to sign, e.g. keccak256 of an Ethereum transaction
 	SignatureReceiver int         // Which party should receive the final signature (typically 0)
 }
 
 // ECDSAMPCSignResponse represents the response from N-party ECDSA signing
 type ECDSAMPCSignResponse struct {
-	Signature []byte // The ECDSA signature (only populated for the designated receiver)
+	Signature []byte // The DER-encoded ECDSA signature (only populated for the designated receiver)
+	// RecoverableSignature is Signature as 65-byte r || s || v, with S in the
+	// lower half of the group order and v the recovery id, as Ethereum and
+	// Bitcoin expect.  It is only populated for the designated receiver of a
+	// secp256k1 signature.
+	RecoverableSignature []byte
+}
+
+// RecoveryID returns the recovery id v, 0 or 1, of a secp256k1 signature,
+// and false when RecoverableSignature is not populated.
+func (r *ECDSAMPCSignResponse) RecoveryID() (byte, bool) {
+	if r == nil || len(r.RecoverableSignature) != verify.RecoverableSize {
+		return 0, false
+	}
+	return r.RecoverableSignature[verify.RecoverableSize-1], true
 }
 
 // ECDSAMPCSign performs N-party ECDSA signing
-// All parties must call this function simultaneously with their respective key shares
+// All parties must call this function simultaneously with their respective key shares.
+// A quorum of a threshold key (see NewQuorumJob) may consist of two parties.
 func ECDSAMPCSign(jobmp *JobMP, req *ECDSAMPCSignRequest) (*ECDSAMPCSignResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
-	if jobmp.NParties() < 3 {
-		return nil, fmt.Errorf("n-party signing requires at least 3 parties")
+	if jobmp.NParties() < 2 {
+		return nil, invalid("job", "n-party signing requires at least 2 parties")
 	}
-	if len(req.Message) == 0 {
-		return nil, fmt.Errorf("message cannot be empty")
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
+	}
+	if err := jobmp.checkKeyShare(req.KeyShare.PartyName, req.KeyShare.Qis); err != nil {
+		return nil, err
+	}
+	if err := checkMessage(req.Message); err != nil {
+		return nil, err
+	}
+	if err := checkPartyIndex("signature receiver", req.SignatureReceiver, jobmp.NParties()); err != nil {
+		return nil, err
 	}
 
 	// Perform distributed signing using the provided JobMP
@@ -244,12 +283,37 @@ func ECDSAMPCSign(jobmp *JobMP, req *ECDSAMPCSignRequest) (*ECDSAMPCSignResponse
 	roleIndex := jobmp.GetPartyIndex()
 
 	// Only the designated receiver gets the signature
-	var sigBytes []byte
-	if roleIndex == req.SignatureReceiver {
-		sigBytes = signature
+	if roleIndex != req.SignatureReceiver {
+		return &ECDSAMPCSignResponse{}, nil
 	}
+	recoverable, err := recoverableSignature(req.KeyShare, req.Message, signature)
+	if err != nil {
+		return nil, fmt.Errorf("ECDSA N-party signing: %w", err)
+	}
+	return &ECDSAMPCSignResponse{Signature: signature, RecoverableSignature: recoverable}, nil
+}
 
-	return &ECDSAMPCSignResponse{Signature: sigBytes}, nil
+// recoverableSignature converts a DER signature by key into r || s || v
+// when key is on secp256k1, and returns nil on other curves.
+func recoverableSignature(key ECDSAMPCKey, hash, signature []byte) ([]byte, error) {
+	c, err := key.Curve()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Free()
+	if c.String() != "secp256k1" {
+		return nil, nil
+	}
+	q, err := key.Q()
+	if err != nil {
+		return nil, err
+	}
+	defer q.Free()
+	pub, err := q.Encode(c, curve.FormatSEC1Uncompressed)
+	if err != nil {
+		return nil, err
+	}
+	return verify.RecoverableSecp256k1(pub, hash, signature)
 }
 
 // ECDSAMPCRefreshRequest represents the parameters required to refresh (re-share)
@@ -281,13 +345,22 @@ type ECDSAMPCRefreshResponse struct {
 // current key shares and an identical SessionID.
 func ECDSAMPCRefresh(jobmp *JobMP, req *ECDSAMPCRefreshRequest) (*ECDSAMPCRefreshResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if jobmp.NParties() < 3 {
-		return nil, fmt.Errorf("n-party refresh requires at least 3 parties")
+		return nil, invalid("job", "n-party refresh requires at least 3 parties")
+	}
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
+	}
+	if err := jobmp.checkKeyShare(req.KeyShare.PartyName, req.KeyShare.Qis); err != nil {
+		return nil, err
+	}
+	if err := checkSessionID(req.SessionID); err != nil {
+		return nil, err
 	}
 	// Ensure a session ID is always provided to the native layer. If the caller
 	// did not supply one, fall back to an empty slice (the binding will handle
@@ -338,13 +411,13 @@ type ECDSAMPCThresholdDKGResponse struct {
 //     by (a subset of) the parties represented by jobmp.
 func ECDSAMPCThresholdDKG(jobmp *JobMP, req *ECDSAMPCThresholdDKGRequest) (*ECDSAMPCThresholdDKGResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if req.Curve == nil {
-		return nil, fmt.Errorf("curve must be provided")
+		return nil, invalid("curve", "must be provided")
 	}
 
 	// Ensure a session ID is always passed to the binding. An empty slice is
@@ -353,7 +426,18 @@ func ECDSAMPCThresholdDKG(jobmp *JobMP, req *ECDSAMPCThresholdDKGRequest) (*ECDS
 
 	// Ensure we have a valid access-structure description.
 	if req.AccessStructure == nil {
-		return nil, fmt.Errorf("access structure must be provided")
+		return nil, invalid("access structure", "must be provided")
+	}
+
+	// Catch malformed trees and unknown parties before the native layer does.
+	if err := req.AccessStructure.Validate(jobmp.pnames); err != nil {
+		return nil, err
+	}
+	if err := checkSessionID(sid); err != nil {
+		return nil, err
+	}
+	if err := checkQuorum(req.QuorumRIDs, jobmp.NParties()); err != nil {
+		return nil, err
 	}
 
 	// Translate the high-level Go representation into the native C handle.
@@ -387,10 +471,16 @@ func ECDSAMPCThresholdDKG(jobmp *JobMP, req *ECDSAMPCThresholdDKGRequest) (*ECDS
 func (k ECDSAMPCKey) ToAdditiveShare(ac *AccessStructure, quorumPartyNames []string) (ECDSAMPCKey, error) {
 	// Validate inputs
 	if ac == nil {
-		return ECDSAMPCKey{}, fmt.Errorf("access structure must be provided")
+		return ECDSAMPCKey{}, invalid("access structure", "must be provided")
 	}
 	if len(quorumPartyNames) == 0 {
-		return ECDSAMPCKey{}, fmt.Errorf("quorumPartyNames cannot be empty")
+		return ECDSAMPCKey{}, invalid("quorum", "party names cannot be empty")
+	}
+	if err := ac.Validate(nil); err != nil {
+		return ECDSAMPCKey{}, err
+	}
+	if err := validatePartyNames(quorumPartyNames); err != nil {
+		return ECDSAMPCKey{}, err
 	}
 
 	// Translate the high-level AccessStructure into the native representation.
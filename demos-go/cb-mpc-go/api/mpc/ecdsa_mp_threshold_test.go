@@ -1,3 +1,5 @@
+//go:build !nompc
+
 // Replace placeholder with test implementations
 package mpc
 
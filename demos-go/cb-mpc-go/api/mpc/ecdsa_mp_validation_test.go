@@ -1,6 +1,9 @@
+//go:build !nompc
+
 package mpc
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"testing"
 
@@ -10,6 +13,7 @@ import (
 	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
 	curveref "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/internal/curveref"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
 )
 
@@ -323,6 +327,59 @@ func TestECDSAMPC_Sign_Refresh_Sign(t *testing.T) {
 	assert.NotEqual(t, sigRes1[0].Signature, sigRes2[0].Signature)
 }
 
+func TestECDSAMPC_SignRecoverable(t *testing.T) {
+	const nParties = 3
+	digest := sha256.Sum256([]byte("transfer 1 ETH"))
+
+	secp, err := curvepkg.NewSecp256k1()
+	require.NoError(t, err)
+	defer secp.Free()
+	keyGenRes, err := keyGenWithMockNet(nParties, secp)
+	require.NoError(t, err)
+	keyShares := make([]ECDSAMPCKey, nParties)
+	for i, r := range keyGenRes {
+		keyShares[i] = r.KeyShare
+	}
+
+	sigRes, err := signWithMockNet(keyShares, digest[:], 1)
+	require.NoError(t, err)
+	require.NoError(t, verify.Secp256k1(encodedQ(t, keyShares[0], secp), digest[:], sigRes[1].Signature))
+	v, ok := sigRes[1].RecoveryID()
+	require.True(t, ok)
+	assert.LessOrEqual(t, v, byte(1))
+	pub, err := verify.RecoverSecp256k1(digest[:], sigRes[1].RecoverableSignature)
+	require.NoError(t, err)
+	assert.Equal(t, encodedQ(t, keyShares[0], secp), pub)
+
+	_, ok = sigRes[0].RecoveryID()
+	assert.False(t, ok, "only the receiver gets the signature")
+
+	// Other curves have no recovery id.
+	p256, err := curvepkg.NewP256()
+	require.NoError(t, err)
+	defer p256.Free()
+	keyGenRes, err = keyGenWithMockNet(nParties, p256)
+	require.NoError(t, err)
+	for i, r := range keyGenRes {
+		keyShares[i] = r.KeyShare
+	}
+	sigRes, err = signWithMockNet(keyShares, digest[:], 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, sigRes[0].Signature)
+	assert.Nil(t, sigRes[0].RecoverableSignature)
+}
+
+// encodedQ returns the uncompressed SEC1 group public key of key.
+func encodedQ(t *testing.T, key ECDSAMPCKey, c curvepkg.Curve) []byte {
+	t.Helper()
+	q, err := key.Q()
+	require.NoError(t, err)
+	defer q.Free()
+	pub, err := q.Encode(c, curvepkg.FormatSEC1Uncompressed)
+	require.NoError(t, err)
+	return pub
+}
+
 func TestECDSAMPC_SerializeDeserialize(t *testing.T) {
 	const nParties = 3
 
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package mpc
 
 import (
@@ -53,6 +55,9 @@ func (k EDDSAMPCKey) cgobindingRef() cgobinding.Mpc_eckey_mp_ref {
 	return cgobinding.Mpc_eckey_mp_ref(k)
 }
 
+// empty reports whether k is the zero value, e.g. a share that was freed.
+func (k EDDSAMPCKey) empty() bool { return k.cgobindingRef().Empty() }
+
 // MarshalBinary serialises the key share into a portable wire format.
 func (k EDDSAMPCKey) MarshalBinary() ([]byte, error) {
 	parts, err := cgobinding.SerializeKeyShare(k.cgobindingRef())
@@ -68,15 +73,24 @@ func (k EDDSAMPCKey) MarshalBinary() ([]byte, error) {
 
 // UnmarshalBinary restores a key share previously produced by MarshalBinary.
 func (k *EDDSAMPCKey) UnmarshalBinary(data []byte) error {
+	share, e, err := openShare(data)
+	if err != nil {
+		return err
+	}
 	var parts [][]byte
-	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&parts); err != nil {
+	if err := gob.NewDecoder(bytes.NewReader(share)).Decode(&parts); err != nil {
 		return err
 	}
 	ref, err := cgobinding.DeserializeKeyShare(parts)
 	if err != nil {
 		return err
 	}
-	*k = newEDDSAMPCKey(ref)
+	key := newEDDSAMPCKey(ref)
+	if err := e.checkKey(key.cgobindingRef(), key.Curve, key.PartyName); err != nil {
+		key.Free()
+		return err
+	}
+	*k = key
 	return nil
 }
 
@@ -167,16 +181,16 @@ type EDDSAMPCRefreshResponse struct {
 // EDDSAMPCKeyGen performs algorithm-agnostic distributed key generation.
 func EDDSAMPCKeyGen(jobmp *JobMP, req *EDDSAMPCKeyGenRequest) (*EDDSAMPCKeyGenResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if req.Curve == nil {
-		return nil, fmt.Errorf("curve must be provided")
+		return nil, invalid("curve", "must be provided")
 	}
 	if jobmp.NParties() < 3 {
-		return nil, fmt.Errorf("n-party EdDSA requires at least 3 parties")
+		return nil, invalid("job", "n-party EdDSA requires at least 3 parties")
 	}
 
 	key, err := cgobinding.KeyShareDKG(jobmp.cgo(), curveref.Ref(req.Curve))
@@ -186,19 +200,29 @@ func EDDSAMPCKeyGen(jobmp *JobMP, req *EDDSAMPCKeyGenRequest) (*EDDSAMPCKeyGenRe
 	return &EDDSAMPCKeyGenResponse{KeyShare: newEDDSAMPCKey(key)}, nil
 }
 
-// EDDSAMPCSign performs N-party EdDSA signing.
+// EDDSAMPCSign performs N-party EdDSA signing.  A quorum of a threshold key
+// (see NewQuorumJob) may consist of two parties.
 func EDDSAMPCSign(jobmp *JobMP, req *EDDSAMPCSignRequest) (*EDDSAMPCSignResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
-	if jobmp.NParties() < 3 {
-		return nil, fmt.Errorf("n-party signing requires at least 3 parties")
+	if jobmp.NParties() < 2 {
+		return nil, invalid("job", "n-party signing requires at least 2 parties")
 	}
-	if len(req.Message) == 0 {
-		return nil, fmt.Errorf("message cannot be empty")
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
+	}
+	if err := jobmp.checkKeyShare(req.KeyShare.PartyName, req.KeyShare.Qis); err != nil {
+		return nil, err
+	}
+	if err := checkMessage(req.Message); err != nil {
+		return nil, err
+	}
+	if err := checkPartyIndex("signature receiver", req.SignatureReceiver, jobmp.NParties()); err != nil {
+		return nil, err
 	}
 
 	sig, err := cgobinding.MPC_eddsampc_sign(jobmp.cgo(), req.KeyShare.cgobindingRef(), req.Message, req.SignatureReceiver)
@@ -217,13 +241,22 @@ func EDDSAMPCSign(jobmp *JobMP, req *EDDSAMPCSignRequest) (*EDDSAMPCSignResponse
 // EDDSAMPCRefresh re-shares secret without changing public key.
 func EDDSAMPCRefresh(jobmp *JobMP, req *EDDSAMPCRefreshRequest) (*EDDSAMPCRefreshResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if jobmp.NParties() < 3 {
-		return nil, fmt.Errorf("n-party refresh requires at least 3 parties")
+		return nil, invalid("job", "n-party refresh requires at least 3 parties")
+	}
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
+	}
+	if err := jobmp.checkKeyShare(req.KeyShare.PartyName, req.KeyShare.Qis); err != nil {
+		return nil, err
+	}
+	if err := checkSessionID(req.SessionID); err != nil {
+		return nil, err
 	}
 	sid := req.SessionID
 	newKey, err := cgobinding.KeyShareRefresh(jobmp.cgo(), sid, req.KeyShare.cgobindingRef())
@@ -255,19 +288,29 @@ type EDDSAMPCThresholdDKGResponse struct {
 // returns the caller's key share.
 func EDDSAMPCThresholdDKG(jobmp *JobMP, req *EDDSAMPCThresholdDKGRequest) (*EDDSAMPCThresholdDKGResponse, error) {
 	if jobmp == nil {
-		return nil, fmt.Errorf("job must be provided")
+		return nil, invalid("job", "must be provided")
 	}
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if req.Curve == nil {
-		return nil, fmt.Errorf("curve must be provided")
+		return nil, invalid("curve", "must be provided")
 	}
 
 	sid := req.SessionID
 
 	if req.AccessStructure == nil {
-		return nil, fmt.Errorf("access structure must be provided")
+		return nil, invalid("access structure", "must be provided")
+	}
+
+	if err := req.AccessStructure.Validate(jobmp.pnames); err != nil {
+		return nil, err
+	}
+	if err := checkSessionID(sid); err != nil {
+		return nil, err
+	}
+	if err := checkQuorum(req.QuorumRIDs, jobmp.NParties()); err != nil {
+		return nil, err
 	}
 
 	acPtr := req.AccessStructure.toCryptoAC()
@@ -288,12 +331,122 @@ func EDDSAMPCThresholdDKG(jobmp *JobMP, req *EDDSAMPCThresholdDKGRequest) (*EDDS
 	return &EDDSAMPCThresholdDKGResponse{KeyShare: newEDDSAMPCKey(keyShareRef)}, nil
 }
 
+// EDDSAMPCThresholdRefreshRequest holds the parameters for refreshing the
+// shares of a key generated by EDDSAMPCThresholdDKG.  AccessStructure and
+// QuorumRIDs must be the ones the key was generated with.
+type EDDSAMPCThresholdRefreshRequest struct {
+	KeyShare        EDDSAMPCKey      // Threshold key share to refresh
+	SessionID       []byte           // Optional caller-supplied session identifier
+	AccessStructure *AccessStructure // Quorum access-structure description
+	QuorumRIDs      []int            // (Optional) indices of the quorum parties; defaults to all parties if nil/empty
+}
+
+// EDDSAMPCThresholdRefreshResponse contains the refreshed key share of the
+// calling party.
+type EDDSAMPCThresholdRefreshResponse struct {
+	NewKeyShare EDDSAMPCKey
+}
+
+// EDDSAMPCThresholdRefresh re-randomizes the threshold shares of every party
+// while keeping the public key, and with it the wallet address.  All parties
+// of the key run it concurrently over a job of the whole committee.  The old
+// shares cannot be combined with the new ones, so a share that leaked before
+// the refresh becomes useless once every party has replaced its share.
+func EDDSAMPCThresholdRefresh(jobmp *JobMP, req *EDDSAMPCThresholdRefreshRequest) (*EDDSAMPCThresholdRefreshResponse, error) {
+	if jobmp == nil {
+		return nil, invalid("job", "must be provided")
+	}
+	if req == nil {
+		return nil, invalid("request", "cannot be nil")
+	}
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
+	}
+	if req.AccessStructure == nil {
+		return nil, invalid("access structure", "must be provided")
+	}
+	if err := req.AccessStructure.Validate(jobmp.pnames); err != nil {
+		return nil, err
+	}
+	if err := jobmp.checkKeyShare(req.KeyShare.PartyName, req.KeyShare.Qis); err != nil {
+		return nil, err
+	}
+	if err := checkSessionID(req.SessionID); err != nil {
+		return nil, err
+	}
+	if err := checkQuorum(req.QuorumRIDs, jobmp.NParties()); err != nil {
+		return nil, err
+	}
+
+	cv, err := req.KeyShare.Curve()
+	if err != nil {
+		return nil, fmt.Errorf("reading curve: %v", err)
+	}
+	defer cv.Free()
+	acPtr := req.AccessStructure.toCryptoAC()
+	roleIndices := req.QuorumRIDs
+	if len(roleIndices) == 0 {
+		roleIndices = make([]int, jobmp.NParties())
+		for i := 0; i < jobmp.NParties(); i++ {
+			roleIndices[i] = i
+		}
+	}
+
+	newKey, err := cgobinding.ThresholdRefresh(jobmp.cgo(), curveref.Ref(cv), req.SessionID, acPtr, roleIndices, req.KeyShare.cgobindingRef())
+	if err != nil {
+		return nil, fmt.Errorf("EdDSA threshold refresh failed: %v", err)
+	}
+	share := newEDDSAMPCKey(newKey)
+	before, err := encodedKey(req.KeyShare.Q, cv)
+	if err != nil {
+		share.Free()
+		return nil, err
+	}
+	after, err := encodedKey(share.Q, cv)
+	if err != nil {
+		share.Free()
+		return nil, err
+	}
+	if !bytes.Equal(before, after) {
+		share.Free()
+		return nil, fmt.Errorf("EdDSA threshold refresh changed the public key from %x to %x", before, after)
+	}
+	return &EDDSAMPCThresholdRefreshResponse{NewKeyShare: share}, nil
+}
+
+// Refresh runs the key-refresh protocol on k over jobmp and returns the new
+// share of this party; the public key stays the same.  A share from
+// EDDSAMPCKeyGen is refreshed with EDDSAMPCRefresh and ac is nil; a share
+// from EDDSAMPCThresholdDKG is refreshed with EDDSAMPCThresholdRefresh under
+// ac, the access structure it was generated with.  Every party of the key
+// calls Refresh concurrently with the same sessionID, which may be nil.
+func (k EDDSAMPCKey) Refresh(jobmp *JobMP, ac *AccessStructure, sessionID []byte) (EDDSAMPCKey, error) {
+	if ac == nil {
+		resp, err := EDDSAMPCRefresh(jobmp, &EDDSAMPCRefreshRequest{KeyShare: k, SessionID: sessionID})
+		if err != nil {
+			return EDDSAMPCKey{}, err
+		}
+		return resp.NewKeyShare, nil
+	}
+	resp, err := EDDSAMPCThresholdRefresh(jobmp, &EDDSAMPCThresholdRefreshRequest{KeyShare: k, SessionID: sessionID, AccessStructure: ac})
+	if err != nil {
+		return EDDSAMPCKey{}, err
+	}
+	return resp.NewKeyShare, nil
+}
+
 func (k EDDSAMPCKey) ToAdditiveShare(ac *AccessStructure, quorumPartyNames []string) (EDDSAMPCKey, error) {
 	if ac == nil {
-		return EDDSAMPCKey{}, fmt.Errorf("access structure must be provided")
+		return EDDSAMPCKey{}, invalid("access structure", "must be provided")
 	}
 	if len(quorumPartyNames) == 0 {
-		return EDDSAMPCKey{}, fmt.Errorf("quorumPartyNames cannot be empty")
+		return EDDSAMPCKey{}, invalid("quorum", "party names cannot be empty")
+	}
+	if err := ac.Validate(nil); err != nil {
+		return EDDSAMPCKey{}, err
+	}
+	if err := validatePartyNames(quorumPartyNames); err != nil {
+		return EDDSAMPCKey{}, err
 	}
 
 	acPtr := ac.toCryptoAC()
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package mpc
 
 import (
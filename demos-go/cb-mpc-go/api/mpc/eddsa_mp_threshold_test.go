@@ -0,0 +1,118 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"bytes"
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// TestEDDSAMPC_ThresholdRefresh refreshes a 2-of-3 key and signs with the new
+// shares under the same public key.
+func TestEDDSAMPC_ThresholdRefresh(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	pnames := []string{"server", "kms", "pin"}
+	ac := createThresholdAccessStructure(pnames, 2, cv)
+	messengers := mocknet.NewMockNetwork(len(pnames))
+
+	// run runs fn for every party over a job of the whole committee.
+	run := func(fn func(i int, job *JobMP) error) {
+		var eg errgroup.Group
+		for i := range pnames {
+			eg.Go(func() error {
+				job, err := NewJobMP(messengers[i], len(pnames), i, pnames)
+				if err != nil {
+					return err
+				}
+				defer job.Free()
+				return fn(i, job)
+			})
+		}
+		require.NoError(t, eg.Wait())
+	}
+
+	shares := make([]EDDSAMPCKey, len(pnames))
+	run(func(i int, job *JobMP) error {
+		resp, err := EDDSAMPCThresholdDKG(job, &EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: ac})
+		if err != nil {
+			return err
+		}
+		shares[i] = resp.KeyShare
+		return nil
+	})
+	refreshed := make([]EDDSAMPCKey, len(pnames))
+	run(func(i int, job *JobMP) error {
+		var err error
+		refreshed[i], err = shares[i].Refresh(job, ac, []byte("refresh-1"))
+		return err
+	})
+	defer func() {
+		for i := range pnames {
+			shares[i].Free()
+			refreshed[i].Free()
+		}
+	}()
+
+	pubKey, err := encodedKey(shares[0].Q, cv)
+	require.NoError(t, err)
+	marshal := func(keys []EDDSAMPCKey) map[string][]byte {
+		out := make(map[string][]byte, len(keys))
+		for i, k := range keys {
+			data, err := k.MarshalBinaryWithAccessStructure(ac, pnames)
+			require.NoError(t, err)
+			out[pnames[i]] = data
+		}
+		return out
+	}
+	for i := range pnames {
+		q, err := encodedKey(refreshed[i].Q, cv)
+		require.NoError(t, err)
+		assert.Equal(t, pubKey, q, "public key of %s", pnames[i])
+		before, err := shares[i].XShare()
+		require.NoError(t, err)
+		after, err := refreshed[i].XShare()
+		require.NoError(t, err)
+		assert.False(t, bytes.Equal(before.Bytes, after.Bytes), "share of %s", pnames[i])
+	}
+
+	message := []byte("after refresh")
+	resp, err := SignWithQuorum(marshal(refreshed), []string{"server", "pin"}, message)
+	require.NoError(t, err)
+	assert.NoError(t, verify.Ed25519(pubKey, message, resp.Signature))
+
+	// An old share does not combine with a refreshed one.
+	mixed := marshal(refreshed)
+	mixed["pin"] = marshal(shares)["pin"]
+	resp, err = SignWithQuorum(mixed, []string{"server", "pin"}, message)
+	if err == nil {
+		assert.Error(t, verify.Ed25519(pubKey, message, resp.Signature))
+	}
+}
+
+func TestEDDSAMPC_ThresholdRefreshValidation(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+	pnames := []string{"server", "kms", "pin"}
+	ac := createThresholdAccessStructure(pnames, 2, cv)
+	job, err := NewJobMP(mocknet.NewMockNetwork(3)[0], 3, 0, pnames)
+	require.NoError(t, err)
+	defer job.Free()
+
+	_, err = EDDSAMPCThresholdRefresh(nil, &EDDSAMPCThresholdRefreshRequest{AccessStructure: ac})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = EDDSAMPCThresholdRefresh(job, nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = EDDSAMPCThresholdRefresh(job, &EDDSAMPCThresholdRefreshRequest{AccessStructure: ac})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,184 @@
+package mpc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// encryptedShareVersion is the format version written into every
+// EncryptedKeyShare.
+const encryptedShareVersion = 1
+
+// encryptedShareDomain starts the additional data of every encrypted share.
+const encryptedShareDomain = "cb-mpc encrypted key share v1\x00"
+
+// KDFs of an EncryptedKeyShare.
+const (
+	KDFArgon2id = "argon2id" // Key derived from a passphrase
+	KDFNone     = "none"     // Caller-supplied key-encryption key
+)
+
+// Argon2id parameters: RFC 9106's second recommended option, and the largest
+// cost Open accepts, so that a crafted blob cannot exhaust memory.
+const (
+	argon2Time      = 3
+	argon2Memory    = 64 * 1024 // KiB
+	argon2Threads   = 4
+	argon2MaxTime   = 16
+	argon2MaxMemory = 1024 * 1024 // KiB
+	saltSize        = 16
+)
+
+// ErrDecryptShare is returned by Open when the key or passphrase is wrong or
+// the encrypted share was modified.
+var ErrDecryptShare = errors.New("mpc: cannot decrypt key share")
+
+// ShareKey is what an EncryptedKeyShare is sealed under: a passphrase, or a
+// 32-byte key-encryption key (KEK) held by the caller, e.g. in a KMS.
+type ShareKey struct {
+	passphrase []byte
+	kek        []byte
+	kekID      string
+}
+
+// Passphrase returns a ShareKey that derives the encryption key from a
+// passphrase or PIN with Argon2id.
+func Passphrase(passphrase []byte) ShareKey { return ShareKey{passphrase: passphrase} }
+
+// KEK returns a ShareKey for a 32-byte key-encryption key.  id names the key
+// and is recorded in the encrypted share, so that the holder can tell which
+// key to fetch; it is authenticated but not secret.
+func KEK(id string, key []byte) ShareKey { return ShareKey{kek: key, kekID: id} }
+
+// EncryptedKeyShare is a serialized key share encrypted with AES-256-GCM for
+// storage at rest.  It is JSON-encodable; Open needs nothing but the
+// encrypted share and its ShareKey.
+type EncryptedKeyShare struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	// Argon2id parameters; KDFArgon2id only.
+	Salt    []byte `json:"salt,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"` // KiB
+	Threads uint8  `json:"threads,omitempty"`
+	// KEKID names the key-encryption key; KDFNone only.
+	KEKID string `json:"kek_id,omitempty"`
+	// Label is free-form, authenticated metadata such as the party name.
+	Label      string `json:"label,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Seal encrypts share, the output of a key share's MarshalBinary, under key
+// and replaces the contents of e.  e.Label, if set, is kept and
+// authenticated.
+func (e *EncryptedKeyShare) Seal(share []byte, key ShareKey) error {
+	if len(share) == 0 {
+		return invalid("key share", "cannot be empty")
+	}
+	out := EncryptedKeyShare{Version: encryptedShareVersion, Label: e.Label}
+	switch {
+	case key.kek != nil:
+		if len(key.kek) != 32 {
+			return invalid("key-encryption key", "must be 32 bytes, got %d", len(key.kek))
+		}
+		out.KDF, out.KEKID = KDFNone, key.kekID
+	case len(key.passphrase) > 0:
+		out.KDF = KDFArgon2id
+		out.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(out.Salt); err != nil {
+			return fmt.Errorf("generating salt: %v", err)
+		}
+		out.Time, out.Memory, out.Threads = argon2Time, argon2Memory, argon2Threads
+	default:
+		return invalid("share key", "passphrase or key-encryption key must be provided")
+	}
+	aead, err := out.aead(key)
+	if err != nil {
+		return err
+	}
+	out.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(out.Nonce); err != nil {
+		return fmt.Errorf("generating nonce: %v", err)
+	}
+	out.Ciphertext = aead.Seal(nil, out.Nonce, share, out.additionalData())
+	*e = out
+	return nil
+}
+
+// Open decrypts the key share.  A wrong key or passphrase, or a modified
+// encrypted share, returns ErrDecryptShare.  The caller should zero the
+// returned share once it is unmarshalled.
+func (e *EncryptedKeyShare) Open(key ShareKey) ([]byte, error) {
+	if e.Version != encryptedShareVersion {
+		return nil, fmt.Errorf("unsupported encrypted key share version %d", e.Version)
+	}
+	switch e.KDF {
+	case KDFNone:
+		if key.kek == nil {
+			return nil, invalid("share key", "share is sealed under key-encryption key %q", e.KEKID)
+		}
+	case KDFArgon2id:
+		if key.kek != nil {
+			return nil, invalid("share key", "share is sealed under a passphrase")
+		}
+		if len(e.Salt) < saltSize || e.Time == 0 || e.Time > argon2MaxTime ||
+			e.Memory == 0 || e.Memory > argon2MaxMemory || e.Threads == 0 {
+			return nil, fmt.Errorf("%w: invalid Argon2id parameters", ErrDecryptShare)
+		}
+	default:
+		return nil, fmt.Errorf("unknown KDF %q", e.KDF)
+	}
+	aead, err := e.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, ErrDecryptShare
+	}
+	share, err := aead.Open(nil, e.Nonce, e.Ciphertext, e.additionalData())
+	if err != nil {
+		return nil, ErrDecryptShare
+	}
+	return share, nil
+}
+
+// aead returns the cipher for key under the KDF and parameters of e.
+func (e *EncryptedKeyShare) aead(key ShareKey) (cipher.AEAD, error) {
+	k := key.kek
+	if e.KDF == KDFArgon2id {
+		if len(key.passphrase) == 0 {
+			return nil, invalid("share key", "passphrase must be provided")
+		}
+		k = argon2.IDKey(key.passphrase, e.Salt, e.Time, e.Memory, e.Threads, 32)
+		defer clear(k)
+	}
+	if len(k) != 32 {
+		return nil, invalid("key-encryption key", "must be 32 bytes, got %d", len(k))
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the ciphertext to every other field, so that neither
+// the KDF parameters nor the key ID or label can be swapped.
+func (e *EncryptedKeyShare) additionalData() []byte {
+	ad := []byte(encryptedShareDomain)
+	ad = binary.BigEndian.AppendUint32(ad, uint32(e.Version))
+	for _, s := range []string{e.KDF, string(e.Salt), e.KEKID, e.Label} {
+		ad = binary.BigEndian.AppendUint32(ad, uint32(len(s)))
+		ad = append(ad, s...)
+	}
+	ad = binary.BigEndian.AppendUint32(ad, e.Time)
+	ad = binary.BigEndian.AppendUint32(ad, e.Memory)
+	return append(ad, e.Threads)
+}
@@ -0,0 +1,74 @@
+package mpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedKeySharePassphrase(t *testing.T) {
+	e := EncryptedKeyShare{Label: "pin"}
+	require.NoError(t, e.Seal([]byte("share"), Passphrase([]byte("123456"))))
+	assert.Equal(t, KDFArgon2id, e.KDF)
+	assert.Equal(t, "pin", e.Label)
+	assert.False(t, bytes.Contains(e.Ciphertext, []byte("share")))
+
+	data, err := json.Marshal(&e)
+	require.NoError(t, err)
+	var stored EncryptedKeyShare
+	require.NoError(t, json.Unmarshal(data, &stored))
+	share, err := stored.Open(Passphrase([]byte("123456")))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("share"), share)
+
+	_, err = stored.Open(Passphrase([]byte("654321")))
+	assert.ErrorIs(t, err, ErrDecryptShare)
+	_, err = stored.Open(KEK("kms", make([]byte, 32)))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	// Lowering the cost to ease brute force breaks authentication.
+	stored.Time = 1
+	_, err = stored.Open(Passphrase([]byte("123456")))
+	assert.ErrorIs(t, err, ErrDecryptShare)
+
+	stored.Memory = 1 << 30
+	_, err = stored.Open(Passphrase([]byte("123456")))
+	assert.ErrorIs(t, err, ErrDecryptShare, "refuses to allocate a terabyte")
+}
+
+func TestEncryptedKeyShareKEK(t *testing.T) {
+	kek := bytes.Repeat([]byte{7}, 32)
+	var e EncryptedKeyShare
+	require.NoError(t, e.Seal([]byte("share"), KEK("kms/server/1", kek)))
+	assert.Equal(t, KDFNone, e.KDF)
+	assert.Equal(t, "kms/server/1", e.KEKID)
+	assert.Empty(t, e.Salt)
+
+	share, err := e.Open(KEK("kms/server/1", kek))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("share"), share)
+
+	_, err = e.Open(KEK("kms/server/1", bytes.Repeat([]byte{8}, 32)))
+	assert.ErrorIs(t, err, ErrDecryptShare)
+
+	relabelled := e
+	relabelled.KEKID = "kms/server/2"
+	_, err = relabelled.Open(KEK("kms/server/2", kek))
+	assert.ErrorIs(t, err, ErrDecryptShare)
+
+	tampered := e
+	tampered.Ciphertext = bytes.Clone(e.Ciphertext)
+	tampered.Ciphertext[0] ^= 1
+	_, err = tampered.Open(KEK("kms/server/1", kek))
+	assert.ErrorIs(t, err, ErrDecryptShare)
+}
+
+func TestEncryptedKeyShareSealValidation(t *testing.T) {
+	var e EncryptedKeyShare
+	assert.ErrorIs(t, e.Seal(nil, Passphrase([]byte("pin"))), ErrInvalidInput)
+	assert.ErrorIs(t, e.Seal([]byte("share"), ShareKey{}), ErrInvalidInput)
+	assert.ErrorIs(t, e.Seal([]byte("share"), KEK("short", make([]byte, 16))), ErrInvalidInput)
+}
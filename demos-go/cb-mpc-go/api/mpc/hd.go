@@ -0,0 +1,139 @@
+package mpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"filippo.io/edwards25519"
+)
+
+// hdChainCodeDomain keys the HMAC that derives the default chain code from
+// the master public key.
+const hdChainCodeDomain = "cb-mpc hd chain code v1"
+
+// HDPathRoot starts every path of DeriveEd25519, e.g. "cbmpc/0/3".  It stands
+// in for BIP32's "m" so that paths of this scheme are not mistaken for BIP44
+// or SLIP-0010 paths, whose keys other wallets derive differently.
+const HDPathRoot = "cbmpc"
+
+// ErrHardenedDerivation is returned for derivation paths with hardened
+// indices.  A hardened child depends on the private key, which no party of an
+// MPC key holds, and the signing protocols cannot compute it jointly.
+var ErrHardenedDerivation = errors.New("mpc: hardened derivation is not supported for MPC keys")
+
+// ChildKey is an Ed25519 key derived from a master public key with
+// DeriveEd25519.
+type ChildKey struct {
+	Path      string // Derivation path, e.g. "cbmpc/0/3"
+	PublicKey []byte // 32-byte Ed25519 public key
+	ChainCode []byte // 32-byte chain code to derive further children from
+	// Tweak is the big-endian scalar added to the master secret to obtain
+	// the child secret.  It is derived from public data only.
+	Tweak []byte
+}
+
+// DeriveEd25519 derives the child of master, a 32-byte Ed25519 public key, at
+// path.  Every step is the non-hardened derivation of BIP32 carried over to
+// Ed25519 scalars: with chain code c, parent key A and index i,
+//
+//	Z = HMAC-SHA512(c, 0x02 || A || LE32(i)),  child = A + (Z mod ℓ)·B
+//	c' = HMAC-SHA512(c, 0x03 || A || LE32(i))[32:]
+//
+// Anyone who knows master and chainCode derives the same children, without
+// any secret; a nil chainCode stands for the default chain code of master
+// (see MasterChainCode).  Paths start with HDPathRoot, e.g. "cbmpc/0/3";
+// hardened indices ("3'") fail with ErrHardenedDerivation.
+//
+// The scheme is specific to cb-mpc and the derived keys are not compatible
+// with other wallets.  It is neither BIP32, which is defined for secp256k1,
+// nor SLIP-0010, which derives Ed25519 keys through hardened steps only, so
+// a wallet that imports the master key and derives m/44'/501'/n'/0' or any
+// other path does not arrive at the same keys or addresses.  Keep the path
+// and the chain code of every funded child to find it again.
+func DeriveEd25519(master, chainCode []byte, path string) (*ChildKey, error) {
+	indices, err := parseHDPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(master) != 32 {
+		return nil, invalid("master key", "must be 32 bytes, got %d", len(master))
+	}
+	a, err := new(edwards25519.Point).SetBytes(master)
+	if err != nil {
+		return nil, invalid("master key", "is not an Ed25519 point: %v", err)
+	}
+	if chainCode == nil {
+		chainCode = MasterChainCode(master)
+	}
+	if len(chainCode) != 32 {
+		return nil, invalid("chain code", "must be 32 bytes, got %d", len(chainCode))
+	}
+
+	tweak := edwards25519.NewScalar()
+	c := slices.Clone(chainCode)
+	for _, i := range indices {
+		parent := a.Bytes()
+		z := hdHMAC(c, 0x02, parent, i)
+		t, err := edwards25519.NewScalar().SetUniformBytes(z)
+		if err != nil {
+			return nil, err
+		}
+		a = new(edwards25519.Point).Add(a, new(edwards25519.Point).ScalarBaseMult(t))
+		if a.Equal(edwards25519.NewIdentityPoint()) == 1 {
+			return nil, fmt.Errorf("derivation path %s reaches the identity at index %d", path, i)
+		}
+		tweak.Add(tweak, t)
+		c = hdHMAC(c, 0x03, parent, i)[32:]
+	}
+	le := tweak.Bytes()
+	slices.Reverse(le)
+	return &ChildKey{Path: path, PublicKey: a.Bytes(), ChainCode: c, Tweak: le}, nil
+}
+
+// MasterChainCode returns the chain code DeriveEd25519 uses for master when
+// none is given.  It is public: whoever knows the master public key can
+// derive, and therefore link, all its children.  Parties that want child
+// keys unlinkable to outsiders agree on a random chain code instead, e.g.
+// with AgreeRandom after key generation.
+func MasterChainCode(master []byte) []byte {
+	h := hmac.New(sha512.New, []byte(hdChainCodeDomain))
+	h.Write(master)
+	return h.Sum(nil)[32:]
+}
+
+func hdHMAC(chainCode []byte, prefix byte, parent []byte, index uint32) []byte {
+	h := hmac.New(sha512.New, chainCode)
+	h.Write([]byte{prefix})
+	h.Write(parent)
+	h.Write(binary.LittleEndian.AppendUint32(nil, index))
+	return h.Sum(nil)
+}
+
+// parseHDPath parses "cbmpc/i/j/..." into its non-hardened indices.
+func parseHDPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] == "m" {
+		return nil, invalid("derivation path", "%q is a BIP32 path; paths of this non-standard scheme start with %s", path, HDPathRoot)
+	}
+	if parts[0] != HDPathRoot {
+		return nil, invalid("derivation path", "%q must start with %s", path, HDPathRoot)
+	}
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") || strings.HasSuffix(p, "H") {
+			return nil, fmt.Errorf("%w: %s", ErrHardenedDerivation, path)
+		}
+		i, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || i >= 1<<31 {
+			return nil, invalid("derivation path", "%q has an invalid index %q", path, p)
+		}
+		indices = append(indices, uint32(i))
+	}
+	return indices, nil
+}
@@ -0,0 +1,105 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+)
+
+// DeriveChildRequest asks for the share of a child key.
+type DeriveChildRequest struct {
+	// KeyShare is an additive Ed25519 share: the output of EDDSAMPCKeyGen,
+	// or a threshold share converted with ToAdditiveShare for the quorum
+	// that will sign.
+	KeyShare EDDSAMPCKey
+	Path     string // e.g. "cbmpc/0/3"; see DeriveEd25519
+	// ChainCode is the master chain code, or nil for MasterChainCode.
+	ChainCode []byte
+}
+
+// DeriveChildResponse holds the share of the child key.
+type DeriveChildResponse struct {
+	KeyShare EDDSAMPCKey // The caller must Free it
+	Child    *ChildKey
+}
+
+// DeriveChild derives the share of the child key at req.Path from a share of
+// the master key, so that one key generation backs any number of accounts.
+// Every party of the signing job calls it with the same path and chain code;
+// the child is derived from public data only, so no messages are exchanged.
+// The child shares then sign with EDDSAMPCSign like any other key.
+//
+// The secret tweak of the child is added by exactly one party, the one whose
+// name sorts first among the parties of the share, and every party shifts
+// the public key and that party's public share to match.  A threshold share
+// must therefore be converted with ToAdditiveShare before DeriveChild, not
+// after.
+func DeriveChild(req *DeriveChildRequest) (*DeriveChildResponse, error) {
+	if req == nil {
+		return nil, invalid("request", "cannot be nil")
+	}
+	if req.KeyShare.empty() {
+		return nil, invalid("key share", "must be provided")
+	}
+	cv, err := req.KeyShare.Curve()
+	if err != nil {
+		return nil, fmt.Errorf("reading curve: %v", err)
+	}
+	defer cv.Free()
+	if cv.String() != "Ed25519" {
+		return nil, invalid("key share", "is on %s, derivation needs Ed25519", cv)
+	}
+	master, err := encodedKey(req.KeyShare.Q, cv)
+	if err != nil {
+		return nil, err
+	}
+	child, err := DeriveEd25519(master, req.ChainCode, req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	qis, err := req.KeyShare.Qis()
+	if err != nil {
+		return nil, fmt.Errorf("reading public shares: %v", err)
+	}
+	names := make([]string, 0, len(qis))
+	for name, pt := range qis {
+		names = append(names, name)
+		pt.Free()
+	}
+	if len(names) == 0 {
+		return nil, invalid("key share", "has no public shares")
+	}
+	sort.Strings(names)
+
+	ref := req.KeyShare.cgobindingRef()
+	tweaked, err := (&ref).Tweak(child.Tweak, names[0])
+	if err != nil {
+		return nil, fmt.Errorf("deriving child share: %v", err)
+	}
+	share := newEDDSAMPCKey(tweaked)
+	got, err := encodedKey(share.Q, cv)
+	if err != nil {
+		share.Free()
+		return nil, err
+	}
+	if !bytes.Equal(got, child.PublicKey) {
+		share.Free()
+		return nil, fmt.Errorf("derived share has public key %x, expected %x", got, child.PublicKey)
+	}
+	return &DeriveChildResponse{KeyShare: share, Child: child}, nil
+}
+
+// encodedKey returns the Ed25519 encoding of the public key of a share.
+func encodedKey(q func() (*curve.Point, error), cv curve.Curve) ([]byte, error) {
+	pt, err := q()
+	if err != nil {
+		return nil, fmt.Errorf("reading public key: %v", err)
+	}
+	defer pt.Free()
+	return pt.Encode(cv, curve.FormatEd25519)
+}
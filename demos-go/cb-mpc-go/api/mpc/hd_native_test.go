@@ -0,0 +1,85 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// TestDeriveChild signs with the shares of two child keys of one 3-party
+// Ed25519 key.
+func TestDeriveChild(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	pnames := []string{"server", "kms", "pin"}
+	messengers := mocknet.NewMockNetwork(len(pnames))
+	shares := make([]EDDSAMPCKey, len(pnames))
+	var eg errgroup.Group
+	for i := range pnames {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(pnames), i, pnames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			resp, err := EDDSAMPCKeyGen(job, &EDDSAMPCKeyGenRequest{Curve: cv})
+			if err != nil {
+				return err
+			}
+			shares[i] = resp.KeyShare
+			return nil
+		})
+	}
+	require.NoError(t, eg.Wait())
+	defer func() {
+		for i := range shares {
+			shares[i].Free()
+		}
+	}()
+
+	for _, path := range []string{"cbmpc/0", "cbmpc/1"} {
+		children := make([]*DeriveChildResponse, len(pnames))
+		for i := range pnames {
+			children[i], err = DeriveChild(&DeriveChildRequest{KeyShare: shares[i], Path: path})
+			require.NoError(t, err)
+			defer children[i].KeyShare.Free()
+			assert.Equal(t, children[0].Child, children[i].Child)
+		}
+
+		message := []byte("derived " + path)
+		var signature []byte
+		for i := range pnames {
+			eg.Go(func() error {
+				job, err := NewJobMP(messengers[i], len(pnames), i, pnames)
+				if err != nil {
+					return err
+				}
+				defer job.Free()
+				resp, err := EDDSAMPCSign(job, &EDDSAMPCSignRequest{KeyShare: children[i].KeyShare, Message: message})
+				if err != nil {
+					return err
+				}
+				if i == 0 {
+					signature = resp.Signature
+				}
+				return nil
+			})
+		}
+		require.NoError(t, eg.Wait())
+		assert.NoError(t, verify.Ed25519(children[0].Child.PublicKey, message, signature), path)
+	}
+
+	_, err = DeriveChild(&DeriveChildRequest{KeyShare: shares[0], Path: "cbmpc/0'"})
+	assert.ErrorIs(t, err, ErrHardenedDerivation)
+	_, err = DeriveChild(&DeriveChildRequest{Path: "cbmpc/0"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
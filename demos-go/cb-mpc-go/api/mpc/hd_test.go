@@ -0,0 +1,73 @@
+package mpc
+
+import (
+	"crypto/rand"
+	"slices"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveEd25519(t *testing.T) {
+	seed := make([]byte, 64)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+	x, err := edwards25519.NewScalar().SetUniformBytes(seed)
+	require.NoError(t, err)
+	master := new(edwards25519.Point).ScalarBaseMult(x).Bytes()
+
+	child, err := DeriveEd25519(master, nil, "cbmpc/0/3")
+	require.NoError(t, err)
+	assert.Equal(t, "cbmpc/0/3", child.Path)
+	assert.Len(t, child.ChainCode, 32)
+
+	// The child secret is the master secret plus the tweak.
+	le := slices.Clone(child.Tweak)
+	slices.Reverse(le)
+	tweak, err := edwards25519.NewScalar().SetCanonicalBytes(le)
+	require.NoError(t, err)
+	childSecret := edwards25519.NewScalar().Add(x, tweak)
+	assert.Equal(t, new(edwards25519.Point).ScalarBaseMult(childSecret).Bytes(), child.PublicKey)
+
+	// Derivation is deterministic, composes and depends on every input.
+	again, err := DeriveEd25519(master, MasterChainCode(master), "cbmpc/0/3")
+	require.NoError(t, err)
+	assert.Equal(t, child, again)
+	parent, err := DeriveEd25519(master, nil, "cbmpc/0")
+	require.NoError(t, err)
+	fromParent, err := DeriveEd25519(parent.PublicKey, parent.ChainCode, "cbmpc/3")
+	require.NoError(t, err)
+	assert.Equal(t, child.PublicKey, fromParent.PublicKey)
+	for _, path := range []string{"cbmpc/1/3", "cbmpc/0"} {
+		other, err := DeriveEd25519(master, nil, path)
+		require.NoError(t, err)
+		assert.NotEqual(t, child.PublicKey, other.PublicKey, path)
+	}
+	other, err := DeriveEd25519(master, make([]byte, 32), "cbmpc/0/3")
+	require.NoError(t, err)
+	assert.NotEqual(t, child.PublicKey, other.PublicKey)
+
+	root, err := DeriveEd25519(master, nil, "cbmpc")
+	require.NoError(t, err)
+	assert.Equal(t, master, root.PublicKey)
+	assert.Equal(t, make([]byte, 32), root.Tweak)
+}
+
+func TestDeriveEd25519Invalid(t *testing.T) {
+	master := edwards25519.NewGeneratorPoint().Bytes()
+
+	_, err := DeriveEd25519(master, nil, "cbmpc/0'/3'")
+	assert.ErrorIs(t, err, ErrHardenedDerivation)
+	_, err = DeriveEd25519(master, nil, "cbmpc/0h")
+	assert.ErrorIs(t, err, ErrHardenedDerivation)
+	for _, path := range []string{"", "0/3", "cbmpc/", "cbmpc/x", "cbmpc/2147483648", "cbmpc/-1", "m", "m/44/501/0/0"} {
+		_, err = DeriveEd25519(master, nil, path)
+		assert.ErrorIs(t, err, ErrInvalidInput, path)
+	}
+	_, err = DeriveEd25519(master[:31], nil, "cbmpc/0")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = DeriveEd25519(master, make([]byte, 16), "cbmpc/0")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package mpc
 
 import (
@@ -19,6 +21,21 @@ type Job2P struct {
 // roleIndex – 0 or 1 for the local party.
 // pnames    – names of the two parties (len == 2).
 func NewJob2P(messenger transport.Messenger, roleIndex int, pnames []string) (*Job2P, error) {
+	if versionErr != nil {
+		return nil, versionErr
+	}
+	if messenger == nil {
+		return nil, invalid("messenger", "must be provided")
+	}
+	if len(pnames) != 2 {
+		return nil, invalid("party names", "a two-party job needs 2 names, got %d", len(pnames))
+	}
+	if err := validatePartyNames(pnames); err != nil {
+		return nil, err
+	}
+	if err := checkPartyIndex("role index", roleIndex, 2); err != nil {
+		return nil, err
+	}
 	inner, err := cgobinding.NewJob2P(messenger, roleIndex, pnames)
 	if err != nil {
 		return nil, err
@@ -1,22 +1,46 @@
+//go:build !nompc
+
 package mpc
 
 import (
+	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
 )
 
 // JobMP is an opaque handle for an N-party MPC job (N>2).
 type JobMP struct {
-	inner cgobinding.JobMP
+	inner     cgobinding.JobMP
+	pnames    []string
+	messenger transport.Messenger // For the rounds run in Go, such as Reshare
 }
 
-// NewJobMP constructs a multi-party job.
+// NewJobMP constructs a multi-party job.  Committees of up to MaxParties
+// parties with distinct names of letters, digits and . _ - : @ are
+// supported.
 func NewJobMP(messenger transport.Messenger, partyCount, roleIndex int, pnames []string) (*JobMP, error) {
+	if versionErr != nil {
+		return nil, versionErr
+	}
+	if messenger == nil {
+		return nil, invalid("messenger", "must be provided")
+	}
+	if err := validatePartyNames(pnames); err != nil {
+		return nil, err
+	}
+	if partyCount != len(pnames) {
+		return nil, invalid("party count", "%d does not match %d party names", partyCount, len(pnames))
+	}
+	if err := checkPartyIndex("role index", roleIndex, partyCount); err != nil {
+		return nil, err
+	}
 	inner, err := cgobinding.NewJobMP(messenger, partyCount, roleIndex, pnames)
 	if err != nil {
 		return nil, err
 	}
-	return &JobMP{inner: inner}, nil
+	return &JobMP{inner: inner, pnames: append([]string(nil), pnames...), messenger: messenger}, nil
 }
 
 // Free releases resources.
@@ -36,3 +60,25 @@ func (j *JobMP) cgo() cgobinding.JobMP { return j.inner }
 
 // NParties returns the total number of parties in this MPC job.
 func (j *JobMP) NParties() int { return j.inner.GetNParties() }
+
+// PartyNames returns the names of all parties in the job, indexed by role.
+func (j *JobMP) PartyNames() []string { return append([]string(nil), j.pnames...) }
+
+// checkKeyShare checks that the job's party names match the parties
+// recorded in a key share, given its accessors.
+func (j *JobMP) checkKeyShare(partyNameFn func() (string, error), qisFn func() (map[string]*curve.Point, error)) error {
+	owner, err := partyNameFn()
+	if err != nil {
+		return fmt.Errorf("reading party name of key share: %v", err)
+	}
+	qis, err := qisFn()
+	if err != nil {
+		return fmt.Errorf("reading parties of key share: %v", err)
+	}
+	parties := make([]string, 0, len(qis))
+	for name, pt := range qis {
+		parties = append(parties, name)
+		pt.Free()
+	}
+	return checkKeyParties(j.pnames, j.GetPartyIndex(), owner, parties)
+}
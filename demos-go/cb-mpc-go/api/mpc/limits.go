@@ -0,0 +1,259 @@
+package mpc
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// MaxParties is the largest committee the native engine supports: party sets
+// are represented as 64-bit masks.
+const MaxParties = 64
+
+// ErrInvalidInput is wrapped by every error the protocol entry points return
+// for malformed input.  Such input is rejected in Go, before any native code
+// runs.
+var ErrInvalidInput = errors.New("mpc: invalid input")
+
+// InputError describes malformed input to an entry point.
+type InputError struct {
+	Field  string // Input that was rejected, e.g. "message" or "party names"
+	Reason string
+}
+
+func (e *InputError) Error() string { return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason) }
+
+// Unwrap returns ErrInvalidInput.
+func (e *InputError) Unwrap() error { return ErrInvalidInput }
+
+func invalid(field, format string, args ...any) error {
+	return &InputError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Limits bounds the size of the input the entry points accept, so that
+// network-facing services cannot be made to hand arbitrarily large buffers to
+// the native engine.
+type Limits struct {
+	MaxMessageSize   int // Longest message to sign, in bytes
+	MaxSessionIDSize int // Longest caller-supplied session ID, in bytes
+	MaxLabelSize     int // Longest PVE label, in bytes
+	MaxPartyNameSize int // Longest party name, in bytes
+}
+
+// DefaultLimits are the limits in effect until SetLimits is called.  They
+// admit any digest and any Solana transaction message.
+var DefaultLimits = Limits{
+	MaxMessageSize:   64 << 10,
+	MaxSessionIDSize: 256,
+	MaxLabelSize:     1024,
+	MaxPartyNameSize: 128,
+}
+
+var limits atomic.Pointer[Limits]
+
+// SetLimits replaces the limits of the whole process.  Zero fields take
+// their value from DefaultLimits.
+func SetLimits(l Limits) error {
+	fields := []struct {
+		name  string
+		value *int
+		def   int
+	}{
+		{"message size", &l.MaxMessageSize, DefaultLimits.MaxMessageSize},
+		{"session ID size", &l.MaxSessionIDSize, DefaultLimits.MaxSessionIDSize},
+		{"label size", &l.MaxLabelSize, DefaultLimits.MaxLabelSize},
+		{"party name size", &l.MaxPartyNameSize, DefaultLimits.MaxPartyNameSize},
+	}
+	for _, f := range fields {
+		switch {
+		case *f.value < 0:
+			return fmt.Errorf("maximum %s cannot be negative", f.name)
+		case *f.value == 0:
+			*f.value = f.def
+		}
+	}
+	limits.Store(&l)
+	return nil
+}
+
+// CurrentLimits returns the limits in effect.
+func CurrentLimits() Limits {
+	if l := limits.Load(); l != nil {
+		return *l
+	}
+	return DefaultLimits
+}
+
+// checkMessage checks a message to sign.
+func checkMessage(msg []byte) error {
+	if len(msg) == 0 {
+		return invalid("message", "cannot be empty")
+	}
+	if max := CurrentLimits().MaxMessageSize; len(msg) > max {
+		return invalid("message", "%d bytes exceeds the maximum of %d", len(msg), max)
+	}
+	return nil
+}
+
+// checkSessionID checks an optional caller-supplied session ID.
+func checkSessionID(sid []byte) error {
+	if max := CurrentLimits().MaxSessionIDSize; len(sid) > max {
+		return invalid("session ID", "%d bytes exceeds the maximum of %d", len(sid), max)
+	}
+	return nil
+}
+
+// checkLabel checks a PVE label.
+func checkLabel(label string) error {
+	if label == "" {
+		return invalid("label", "cannot be empty")
+	}
+	if max := CurrentLimits().MaxLabelSize; len(label) > max {
+		return invalid("label", "%d bytes exceeds the maximum of %d", len(label), max)
+	}
+	return nil
+}
+
+// checkPartyIndex checks that index designates one of n parties.
+func checkPartyIndex(field string, index, n int) error {
+	if index < 0 || index >= n {
+		return invalid(field, "party index %d is out of range for %d parties", index, n)
+	}
+	return nil
+}
+
+// checkQuorum checks that indices designate distinct parties among n.
+func checkQuorum(indices []int, n int) error {
+	seen := make(map[int]bool, len(indices))
+	for _, i := range indices {
+		if err := checkPartyIndex("quorum", i, n); err != nil {
+			return err
+		}
+		if seen[i] {
+			return invalid("quorum", "party index %d is listed more than once", i)
+		}
+		seen[i] = true
+	}
+	return nil
+}
+
+// validPartyName reports whether name consists only of letters, digits and
+// the punctuation found in host names and e-mail addresses: . _ - : @
+func validPartyName(name string) bool {
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-', c == ':', c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validatePartyNames checks a committee before it is handed to the native
+// engine, which reports such problems far less helpfully.
+func validatePartyNames(pnames []string) error {
+	if len(pnames) > MaxParties {
+		return invalid("party names", "committee of %d parties exceeds the maximum of %d", len(pnames), MaxParties)
+	}
+	max := CurrentLimits().MaxPartyNameSize
+	seen := make(map[string]bool, len(pnames))
+	for i, name := range pnames {
+		switch {
+		case name == "":
+			return invalid("party names", "party %d has an empty name", i)
+		case len(name) > max:
+			return invalid("party names", "name of party %d exceeds the maximum of %d bytes", i, max)
+		case !validPartyName(name):
+			return invalid("party names", "name %q may only contain letters, digits and . _ - : @", name)
+		case seen[name]:
+			return invalid("party names", "party name %q is used more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Validate checks that the access structure is well formed and that every
+// leaf names one of pnames, the parties of the job it will be used with.  A
+// nil pnames skips the membership check.  Errors are *InputErrors.
+//
+// The native engine panics or fails with opaque errors on malformed trees, so
+// the protocol entry points call Validate before translating the tree.
+func (as *AccessStructure) Validate(pnames []string) error {
+	if as == nil || as.Root == nil {
+		return invalid("access structure", "has no root")
+	}
+	if as.Root.Name != "" || as.Root.Parent != nil {
+		return invalid("access structure", "root must be unnamed and have no parent")
+	}
+	leaves, err := as.Root.leaves()
+	if err != nil {
+		return &InputError{Field: "access structure", Reason: err.Error()}
+	}
+	if len(leaves) > MaxParties {
+		return invalid("access structure", "has %d leaves, the maximum is %d", len(leaves), MaxParties)
+	}
+	if pnames == nil {
+		return nil
+	}
+	members := make(map[string]bool, len(pnames))
+	for _, name := range pnames {
+		members[name] = true
+	}
+	for _, leaf := range leaves {
+		if !members[leaf] {
+			return invalid("access structure", "names party %q which is not in the job", leaf)
+		}
+	}
+	return nil
+}
+
+// leaves validates the subtree rooted at n and returns its leaf names in
+// tree order.
+func (n *AccessNode) leaves() ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	var walk func(n *AccessNode) error
+	walk = func(n *AccessNode) error {
+		if n == nil {
+			return fmt.Errorf("access structure contains a nil node")
+		}
+		switch n.Kind {
+		case KindLeaf:
+			if len(n.Children) != 0 {
+				return fmt.Errorf("leaf %q has children", n.Name)
+			}
+			if n.Name == "" {
+				return fmt.Errorf("access structure contains an unnamed leaf")
+			}
+			if seen[n.Name] {
+				return fmt.Errorf("party %q appears more than once in the access structure", n.Name)
+			}
+			seen[n.Name] = true
+			out = append(out, n.Name)
+			return nil
+		case KindAnd, KindOr:
+		case KindThreshold:
+			if n.K < 1 || n.K > len(n.Children) {
+				return fmt.Errorf("threshold node %q requires %d of %d children", n.Name, n.K, len(n.Children))
+			}
+		default:
+			return fmt.Errorf("node %q has unknown kind %v", n.Name, n.Kind)
+		}
+		if len(n.Children) == 0 {
+			return fmt.Errorf("%v node %q has no children", n.Kind, n.Name)
+		}
+		for _, c := range n.Children {
+			if err := walk(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(n); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
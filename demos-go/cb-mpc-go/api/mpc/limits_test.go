@@ -0,0 +1,54 @@
+package mpc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLimits(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, SetLimits(DefaultLimits)) })
+
+	assert.NoError(t, checkMessage(bytes.Repeat([]byte{1}, DefaultLimits.MaxMessageSize)))
+	require.NoError(t, SetLimits(Limits{MaxMessageSize: 32}))
+	assert.Equal(t, 32, CurrentLimits().MaxMessageSize)
+	assert.Equal(t, DefaultLimits.MaxSessionIDSize, CurrentLimits().MaxSessionIDSize)
+
+	assert.NoError(t, checkMessage(make([]byte, 32)))
+	err := checkMessage(make([]byte, 33))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	var ierr *InputError
+	require.ErrorAs(t, err, &ierr)
+	assert.Equal(t, "message", ierr.Field)
+	assert.ErrorIs(t, checkMessage(nil), ErrInvalidInput)
+
+	assert.Error(t, SetLimits(Limits{MaxLabelSize: -1}))
+	assert.Equal(t, 32, CurrentLimits().MaxMessageSize)
+}
+
+func TestInputValidation(t *testing.T) {
+	assert.NoError(t, validatePartyNames([]string{"server", "pin-device", "kms.example.com:443", "ops@example.com", "party_0"}))
+	for name, pnames := range map[string][]string{
+		"empty":     {"a", ""},
+		"duplicate": {"a", "a"},
+		"space":     {"a", "b c"},
+		"control":   {"a", "b\x00"},
+		"slash":     {"a", "../b"},
+		"too long":  {"a", string(bytes.Repeat([]byte{'b'}, DefaultLimits.MaxPartyNameSize+1))},
+	} {
+		assert.ErrorIs(t, validatePartyNames(pnames), ErrInvalidInput, name)
+	}
+
+	assert.NoError(t, checkQuorum([]int{0, 2}, 3))
+	assert.ErrorIs(t, checkQuorum([]int{0, 3}, 3), ErrInvalidInput)
+	assert.ErrorIs(t, checkQuorum([]int{1, 1}, 3), ErrInvalidInput)
+	assert.ErrorIs(t, checkPartyIndex("signature receiver", -1, 3), ErrInvalidInput)
+	assert.ErrorIs(t, checkSessionID(make([]byte, DefaultLimits.MaxSessionIDSize+1)), ErrInvalidInput)
+	assert.ErrorIs(t, checkLabel(""), ErrInvalidInput)
+
+	err := (&AccessStructure{Root: Threshold("", 3, Leaf("a"), Leaf("b"))}).Validate(nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.EqualError(t, err, `invalid access structure: threshold node "" requires 3 of 2 children`)
+}
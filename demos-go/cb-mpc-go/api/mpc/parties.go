@@ -0,0 +1,65 @@
+package mpc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PartyMismatchError reports that the party names of a job do not match the
+// parties recorded in a key share.  It unwraps to ErrInvalidInput.
+type PartyMismatchError struct {
+	// Self is the job's name for this party; Owner is the party the key
+	// share belongs to.  They differ if the job's order does not match.
+	Self, Owner string
+	// Missing lists parties of the key share that are not in the job, and
+	// Unknown parties of the job that the key share does not know.
+	Missing, Unknown []string
+}
+
+func (e *PartyMismatchError) Error() string {
+	var parts []string
+	if e.Self != e.Owner {
+		parts = append(parts, fmt.Sprintf("this party is %q in the job but the key share belongs to %q", e.Self, e.Owner))
+	}
+	if len(e.Missing) > 0 {
+		msg := fmt.Sprintf("key share parties missing from the job: %s", strings.Join(e.Missing, ", "))
+		if len(e.Unknown) == 0 {
+			msg += " (convert threshold shares with ToAdditiveShare for the quorum)"
+		}
+		parts = append(parts, msg)
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, fmt.Sprintf("job parties unknown to the key share: %s", strings.Join(e.Unknown, ", ")))
+	}
+	return "party names do not match the key share: " + strings.Join(parts, "; ")
+}
+
+// Unwrap returns ErrInvalidInput.
+func (e *PartyMismatchError) Unwrap() error { return ErrInvalidInput }
+
+// checkKeyParties checks that a job whose parties are pnames, with this party
+// at index self, runs with a key share owned by owner whose parties are
+// keyParties.  Threshold shares must be converted with ToAdditiveShare for the
+// job's quorum first.
+func checkKeyParties(pnames []string, self int, owner string, keyParties []string) error {
+	e := &PartyMismatchError{Owner: owner}
+	if self >= 0 && self < len(pnames) {
+		e.Self = pnames[self]
+	}
+	for _, name := range keyParties {
+		if !slices.Contains(pnames, name) {
+			e.Missing = append(e.Missing, name)
+		}
+	}
+	for _, name := range pnames {
+		if !slices.Contains(keyParties, name) {
+			e.Unknown = append(e.Unknown, name)
+		}
+	}
+	if e.Self == e.Owner && len(e.Missing) == 0 && len(e.Unknown) == 0 {
+		return nil
+	}
+	slices.Sort(e.Missing)
+	return e
+}
@@ -0,0 +1,33 @@
+package mpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckKeyParties(t *testing.T) {
+	job := []string{"server", "kms", "pin"}
+	assert.NoError(t, checkKeyParties(job, 1, "kms", []string{"pin", "server", "kms"}))
+
+	var mismatch *PartyMismatchError
+	err := checkKeyParties(job, 1, "server", []string{"server", "kms", "pin"})
+	require.ErrorAs(t, err, &mismatch)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.Equal(t, "kms", mismatch.Self)
+	assert.Equal(t, "server", mismatch.Owner)
+	assert.EqualError(t, err, `party names do not match the key share: this party is "kms" in the job but the key share belongs to "server"`)
+
+	err = checkKeyParties([]string{"server", "pin"}, 0, "server", []string{"server", "kms", "pin"})
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, []string{"kms"}, mismatch.Missing)
+	assert.Empty(t, mismatch.Unknown)
+	assert.ErrorContains(t, err, "ToAdditiveShare")
+
+	err = checkKeyParties([]string{"server", "hsm", "pin"}, 0, "server", []string{"server", "kms", "pin"})
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, []string{"kms"}, mismatch.Missing)
+	assert.Equal(t, []string{"hsm"}, mismatch.Unknown)
+	assert.EqualError(t, err, "party names do not match the key share: key share parties missing from the job: kms; job parties unknown to the key share: hsm")
+}
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package mpc
 
 import (
@@ -69,20 +71,20 @@ type PVEEncryptResponse struct {
 // PVEEncrypt performs publicly verifiable encryption of private shares for backup
 func PVEEncrypt(req *PVEEncryptRequest) (*PVEEncryptResponse, error) {
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if len(req.PrivateValues) == 0 {
-		return nil, fmt.Errorf("private shares cannot be empty")
+		return nil, invalid("private values", "cannot be empty")
 	}
 	if len(req.PublicKeys) == 0 {
-		return nil, fmt.Errorf("public keys map cannot be empty")
+		return nil, invalid("public keys", "cannot be empty")
 	}
-	if req.Label == "" {
-		return nil, fmt.Errorf("label cannot be empty")
+	if err := checkLabel(req.Label); err != nil {
+		return nil, err
 	}
 
 	if req.AccessStructure == nil {
-		return nil, fmt.Errorf("access structure cannot be nil")
+		return nil, invalid("access structure", "must be provided")
 	}
 
 	// Determine the elliptic curve.
@@ -113,11 +115,15 @@ func PVEEncrypt(req *PVEEncryptRequest) (*PVEEncryptResponse, error) {
 	xs := make([][]byte, len(req.PrivateValues))
 	for i, s := range req.PrivateValues {
 		if s == nil {
-			return nil, fmt.Errorf("private share %d is nil", i)
+			return nil, invalid("private values", "value %d is nil", i)
 		}
 		xs[i] = s.Bytes
 	}
 
+	if err := req.AccessStructure.Validate(nil); err != nil {
+		return nil, err
+	}
+
 	// Convert the Go-level access-structure to its native representation.
 	acPtr := req.AccessStructure.toCryptoAC()
 
@@ -159,19 +165,26 @@ type PVEDecryptResponse struct {
 // PVEDecrypt performs publicly verifiable decryption to recover private shares
 func PVEDecrypt(req *PVEDecryptRequest) (*PVEDecryptResponse, error) {
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if req.AccessStructure == nil {
-		return nil, fmt.Errorf("access structure cannot be nil")
+		return nil, invalid("access structure", "must be provided")
 	}
 	if len(req.PrivateKeys) == 0 {
-		return nil, fmt.Errorf("private keys cannot be empty")
+		return nil, invalid("private keys", "cannot be empty")
 	}
 	if len(req.PublicKeys) == 0 {
-		return nil, fmt.Errorf("public keys cannot be empty")
+		return nil, invalid("public keys", "cannot be empty")
 	}
-	if req.Label == "" {
-		return nil, fmt.Errorf("label cannot be empty")
+	if err := checkLabel(req.Label); err != nil {
+		return nil, err
+	}
+	if req.AccessStructure.Curve == nil {
+		return nil, invalid("access structure", "curve must be set")
+	}
+
+	if err := req.AccessStructure.Validate(nil); err != nil {
+		return nil, err
 	}
 
 	// Convert the Go-level access-structure to its native representation.
@@ -186,7 +199,7 @@ func PVEDecrypt(req *PVEDecryptRequest) (*PVEDecryptResponse, error) {
 		prv, ok1 := req.PrivateKeys[name]
 		pub, ok2 := req.PublicKeys[name]
 		if !ok1 || !ok2 {
-			return nil, fmt.Errorf("missing keys for leaf %s", name)
+			return nil, invalid("private keys", "missing keys for leaf %s", name)
 		}
 		privBytes[i] = []byte(prv)
 		pubBytes[i] = []byte(pub)
@@ -196,7 +209,7 @@ func PVEDecrypt(req *PVEDecryptRequest) (*PVEDecryptResponse, error) {
 	xsBytes := make([][]byte, len(req.PublicShares))
 	for i, pt := range req.PublicShares {
 		if pt == nil {
-			return nil, fmt.Errorf("public share %d is nil", i)
+			return nil, invalid("public shares", "share %d is nil", i)
 		}
 		xsBytes[i] = pt.Bytes()
 	}
@@ -221,9 +234,6 @@ func PVEDecrypt(req *PVEDecryptRequest) (*PVEDecryptResponse, error) {
 	// Trim any excess leading bytes so that each share has exactly the byte
 	// length of the curve order. This ensures round-trip consistency with the
 	// input format expected by the Go layer.
-	if req.AccessStructure == nil || req.AccessStructure.Curve == nil {
-		return nil, fmt.Errorf("access structure or curve not set")
-	}
 	orderLen := len(req.AccessStructure.Curve.Order())
 	scalars := make([]*curve.Scalar, len(recoveredShares))
 	for i, s := range recoveredShares {
@@ -260,19 +270,23 @@ type PVEVerifyResponse struct {
 // It returns a PVEVerifyResponse containing the boolean result.
 func PVEVerify(req *PVEVerifyRequest) (*PVEVerifyResponse, error) {
 	if req == nil {
-		return nil, fmt.Errorf("request cannot be nil")
+		return nil, invalid("request", "cannot be nil")
 	}
 	if req.AccessStructure == nil {
-		return nil, fmt.Errorf("access structure cannot be nil")
+		return nil, invalid("access structure", "must be provided")
 	}
 	if len(req.PublicKeys) == 0 {
-		return nil, fmt.Errorf("public keys cannot be empty")
+		return nil, invalid("public keys", "cannot be empty")
 	}
 	if len(req.PublicShares) == 0 {
-		return nil, fmt.Errorf("public shares cannot be empty")
+		return nil, invalid("public shares", "cannot be empty")
 	}
-	if req.Label == "" {
-		return nil, fmt.Errorf("label cannot be empty")
+	if err := checkLabel(req.Label); err != nil {
+		return nil, err
+	}
+
+	if err := req.AccessStructure.Validate(nil); err != nil {
+		return nil, err
 	}
 
 	// Convert the Go-level access-structure to its native representation.
@@ -287,7 +301,7 @@ func PVEVerify(req *PVEVerifyRequest) (*PVEVerifyResponse, error) {
 	for i, name := range leafNames {
 		pk, ok := req.PublicKeys[name]
 		if !ok {
-			return nil, fmt.Errorf("missing public key for leaf %s", name)
+			return nil, invalid("public keys", "missing public key for leaf %s", name)
 		}
 		names[i] = []byte(name)
 		pubBytes[i] = []byte(pk)
@@ -297,7 +311,7 @@ func PVEVerify(req *PVEVerifyRequest) (*PVEVerifyResponse, error) {
 	xsBytes := make([][]byte, len(req.PublicShares))
 	for i, pt := range req.PublicShares {
 		if pt == nil {
-			return nil, fmt.Errorf("public share %d is nil", i)
+			return nil, invalid("public shares", "share %d is nil", i)
 		}
 		xsBytes[i] = pt.Bytes()
 	}
@@ -0,0 +1,158 @@
+package mpc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MinimalQuorums returns every minimal set of leaf names that satisfies the
+// access structure: each returned quorum satisfies the root, and removing any
+// party from it would not.
+//
+// Quorums are sorted internally and the list is ordered by size and then
+// lexicographically, so the output is deterministic.  It is computed in pure Go
+// and does not touch the native library.
+func (as *AccessStructure) MinimalQuorums() ([][]string, error) {
+	if as == nil || as.Root == nil {
+		return nil, fmt.Errorf("access structure has no root")
+	}
+	return as.Root.MinimalQuorums()
+}
+
+// MinimalQuorums returns the minimal satisfying sets of leaf names of the
+// subtree rooted at n.  See AccessStructure.MinimalQuorums.
+func (n *AccessNode) MinimalQuorums() ([][]string, error) {
+	sets, err := n.minimalSets()
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]string, len(sets))
+	for i, s := range sets {
+		out[i] = s.names()
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i]) != len(out[j]) {
+			return len(out[i]) < len(out[j])
+		}
+		return strings.Join(out[i], "\x00") < strings.Join(out[j], "\x00")
+	})
+	return out, nil
+}
+
+// nameSet is a set of leaf names.
+type nameSet map[string]struct{}
+
+func (s nameSet) names() []string {
+	out := make([]string, 0, len(s))
+	for name := range s {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s nameSet) union(t nameSet) nameSet {
+	out := make(nameSet, len(s)+len(t))
+	for name := range s {
+		out[name] = struct{}{}
+	}
+	for name := range t {
+		out[name] = struct{}{}
+	}
+	return out
+}
+
+func (s nameSet) subsetOf(t nameSet) bool {
+	if len(s) > len(t) {
+		return false
+	}
+	for name := range s {
+		if _, ok := t[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *AccessNode) minimalSets() ([]nameSet, error) {
+	if n == nil {
+		return nil, fmt.Errorf("nil node in access structure")
+	}
+	switch n.Kind {
+	case KindLeaf:
+		if len(n.Children) != 0 {
+			return nil, fmt.Errorf("leaf %q has children", n.Name)
+		}
+		return []nameSet{{n.Name: {}}}, nil
+	case KindAnd:
+		return n.combinations(len(n.Children))
+	case KindOr:
+		return n.combinations(1)
+	case KindThreshold:
+		if n.K <= 0 || n.K > len(n.Children) {
+			return nil, fmt.Errorf("threshold %q has invalid K=%d for %d children", n.Name, n.K, len(n.Children))
+		}
+		return n.combinations(n.K)
+	default:
+		return nil, fmt.Errorf("node %q has unknown kind %v", n.Name, n.Kind)
+	}
+}
+
+// combinations returns the minimal sets satisfying any k of n's children.
+func (n *AccessNode) combinations(k int) ([]nameSet, error) {
+	if len(n.Children) == 0 {
+		return nil, fmt.Errorf("%v node %q has no children", n.Kind, n.Name)
+	}
+	children := make([][]nameSet, len(n.Children))
+	for i, child := range n.Children {
+		sets, err := child.minimalSets()
+		if err != nil {
+			return nil, err
+		}
+		children[i] = sets
+	}
+
+	var out []nameSet
+	var choose func(start int, picked []int)
+	choose = func(start int, picked []int) {
+		if len(picked) == k {
+			partial := []nameSet{{}}
+			for _, i := range picked {
+				var next []nameSet
+				for _, p := range partial {
+					for _, s := range children[i] {
+						next = append(next, p.union(s))
+					}
+				}
+				partial = next
+			}
+			out = append(out, partial...)
+			return
+		}
+		for i := start; i <= len(children)-(k-len(picked)); i++ {
+			choose(i+1, append(picked, i))
+		}
+	}
+	choose(0, nil)
+	return minimize(out), nil
+}
+
+// minimize drops duplicates and supersets of other sets.
+func minimize(sets []nameSet) []nameSet {
+	sort.SliceStable(sets, func(i, j int) bool { return len(sets[i]) < len(sets[j]) })
+	var out []nameSet
+	for _, s := range sets {
+		redundant := false
+		for _, kept := range out {
+			if kept.subsetOf(s) {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			out = append(out, s)
+		}
+	}
+	return out
+}
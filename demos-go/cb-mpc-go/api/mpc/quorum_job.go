@@ -0,0 +1,127 @@
+package mpc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// quorumPlan maps a quorum of an access structure's parties onto the network
+// of the whole committee.
+type quorumPlan struct {
+	committee []string // Leaves of the access structure, in tree order
+	quorum    []string // Quorum parties, in committee order
+	indices   []int    // Committee index of every quorum party
+	self      int      // Quorum index of this party
+}
+
+// planQuorum checks that quorum satisfies ac and contains self, and orders it
+// by the committee, the leaves of ac in tree order.
+func planQuorum(ac *AccessStructure, quorum []string, self string) (*quorumPlan, error) {
+	if err := ac.Validate(nil); err != nil {
+		return nil, err
+	}
+	committee, _ := ac.Root.leaves()
+	if len(quorum) == 0 {
+		return nil, invalid("quorum", "party names cannot be empty")
+	}
+	members := make(map[string]bool, len(quorum))
+	for _, name := range quorum {
+		if members[name] {
+			return nil, invalid("quorum", "party %q is listed more than once", name)
+		}
+		if !slices.Contains(committee, name) {
+			return nil, invalid("quorum", "party %q is not in the access structure", name)
+		}
+		members[name] = true
+	}
+	if !ac.Root.satisfiedBy(members) {
+		return nil, invalid("quorum", "parties %v do not satisfy the access structure", quorum)
+	}
+	if !members[self] {
+		return nil, invalid("quorum", "party %q is not in the quorum", self)
+	}
+
+	p := &quorumPlan{committee: committee, self: -1}
+	for i, name := range committee {
+		if !members[name] {
+			continue
+		}
+		if name == self {
+			p.self = len(p.quorum)
+		}
+		p.quorum = append(p.quorum, name)
+		p.indices = append(p.indices, i)
+	}
+	return p, nil
+}
+
+// satisfiedBy reports whether the parties in members satisfy the subtree
+// rooted at n, which must be valid.
+func (n *AccessNode) satisfiedBy(members map[string]bool) bool {
+	if n.Kind == KindLeaf {
+		return members[n.Name]
+	}
+	count := 0
+	for _, c := range n.Children {
+		if c.satisfiedBy(members) {
+			count++
+		}
+	}
+	switch n.Kind {
+	case KindAnd:
+		return count == len(n.Children)
+	case KindOr:
+		return count > 0
+	default:
+		return count >= n.K
+	}
+}
+
+// quorumMessenger runs a quorum job over the messenger of the whole
+// committee: quorum indices are translated to committee indices, and absent
+// parties are never addressed.
+type quorumMessenger struct {
+	inner   transport.Messenger
+	indices []int
+}
+
+// Ensure quorumMessenger implements the Messenger interface
+var _ transport.Messenger = (*quorumMessenger)(nil)
+
+func (m *quorumMessenger) committeeIndex(party int) (int, error) {
+	if party < 0 || party >= len(m.indices) {
+		return 0, fmt.Errorf("party index %d out of range for a quorum of %d", party, len(m.indices))
+	}
+	return m.indices[party], nil
+}
+
+func (m *quorumMessenger) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	i, err := m.committeeIndex(receiver)
+	if err != nil {
+		return err
+	}
+	return m.inner.MessageSend(ctx, i, buffer)
+}
+
+func (m *quorumMessenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	i, err := m.committeeIndex(sender)
+	if err != nil {
+		return nil, err
+	}
+	return m.inner.MessageReceive(ctx, i)
+}
+
+func (m *quorumMessenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	mapped := make([]int, len(senders))
+	for k, sender := range senders {
+		i, err := m.committeeIndex(sender)
+		if err != nil {
+			return nil, err
+		}
+		mapped[k] = i
+	}
+	return m.inner.MessagesReceive(ctx, mapped)
+}
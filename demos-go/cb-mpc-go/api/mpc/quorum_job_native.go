@@ -0,0 +1,61 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// QuorumJob is a JobMP run by a quorum of the parties of an access
+// structure, for example two parties of a 2-of-3 key.  It embeds the JobMP,
+// which the signing and refresh entry points accept, and converts threshold
+// key shares for the quorum.
+type QuorumJob struct {
+	*JobMP
+	ac        *AccessStructure
+	committee []string
+	indices   []int
+}
+
+// NewQuorumJob creates the job of party self in quorum, a set of parties that
+// satisfies ac.  messenger connects the whole committee: it addresses every
+// party by its position among the leaves of ac, in tree order, which is the
+// order of the party names the key was generated with when the structure is
+// built from the same list.  Parties outside the quorum need not be running;
+// they are never addressed.
+//
+// The job's parties are the quorum in committee order, so party indices in
+// requests, such as SignatureReceiver, refer to PartyNames of the job.
+func NewQuorumJob(messenger transport.Messenger, ac *AccessStructure, quorum []string, self string) (*QuorumJob, error) {
+	if messenger == nil {
+		return nil, invalid("messenger", "must be provided")
+	}
+	p, err := planQuorum(ac, quorum, self)
+	if err != nil {
+		return nil, err
+	}
+	job, err := NewJobMP(&quorumMessenger{inner: messenger, indices: p.indices}, len(p.quorum), p.self, p.quorum)
+	if err != nil {
+		return nil, err
+	}
+	return &QuorumJob{JobMP: job, ac: ac, committee: p.committee, indices: p.indices}, nil
+}
+
+// Committee returns the names of all parties of the access structure, indexed
+// as on the committee messenger.
+func (j *QuorumJob) Committee() []string { return append([]string(nil), j.committee...) }
+
+// CommitteeIndex returns the committee index of the job party at index i.
+func (j *QuorumJob) CommitteeIndex(i int) int { return j.indices[i] }
+
+// ECDSAAdditiveShare converts this party's threshold key share into an
+// additive share for the quorum.  The caller is responsible for freeing it.
+func (j *QuorumJob) ECDSAAdditiveShare(key ECDSAMPCKey) (ECDSAMPCKey, error) {
+	return key.ToAdditiveShare(j.ac, j.pnames)
+}
+
+// EDDSAAdditiveShare converts this party's threshold key share into an
+// additive share for the quorum.  The caller is responsible for freeing it.
+func (j *QuorumJob) EDDSAAdditiveShare(key EDDSAMPCKey) (EDDSAMPCKey, error) {
+	return key.ToAdditiveShare(j.ac, j.pnames)
+}
@@ -0,0 +1,73 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// TestQuorumJobEDDSASign signs with two parties of a 2-of-3 key over the
+// network of the whole committee, with the middle party absent.
+func TestQuorumJobEDDSASign(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	pnames := []string{"server", "kms", "pin"}
+	ac := createThresholdAccessStructure(pnames, 2, cv)
+	messengers := mocknet.NewMockNetwork(len(pnames))
+
+	shares := make([]EDDSAMPCKey, len(pnames))
+	var eg errgroup.Group
+	for i := range pnames {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(pnames), i, pnames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			resp, err := EDDSAMPCThresholdDKG(job, &EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: ac})
+			if err != nil {
+				return err
+			}
+			shares[i] = resp.KeyShare
+			return nil
+		})
+	}
+	require.NoError(t, eg.Wait())
+	for i := range shares {
+		defer shares[i].Free()
+	}
+
+	quorum := []string{"pin", "server"}
+	signatures := make([][]byte, len(pnames))
+	for _, i := range []int{0, 2} {
+		eg.Go(func() error {
+			job, err := NewQuorumJob(messengers[i], ac, quorum, pnames[i])
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			additive, err := job.EDDSAAdditiveShare(shares[i])
+			if err != nil {
+				return err
+			}
+			defer additive.Free()
+			resp, err := EDDSAMPCSign(job.JobMP, &EDDSAMPCSignRequest{KeyShare: additive, Message: []byte("quorum"), SignatureReceiver: 0})
+			if err != nil {
+				return err
+			}
+			signatures[i] = resp.Signature
+			return nil
+		})
+	}
+	require.NoError(t, eg.Wait())
+	assert.NotEmpty(t, signatures[0], "server is the signature receiver")
+	assert.Empty(t, signatures[2])
+}
@@ -0,0 +1,75 @@
+package mpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanQuorum(t *testing.T) {
+	ac := &AccessStructure{Root: Threshold("", 2, Leaf("server"), Leaf("kms"), Leaf("pin"))}
+
+	p, err := planQuorum(ac, []string{"pin", "server"}, "pin")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"server", "kms", "pin"}, p.committee)
+	assert.Equal(t, []string{"server", "pin"}, p.quorum, "committee order")
+	assert.Equal(t, []int{0, 2}, p.indices)
+	assert.Equal(t, 1, p.self)
+
+	p, err = planQuorum(ac, []string{"server", "kms", "pin"}, "kms")
+	require.NoError(t, err, "a quorum need not be minimal")
+	assert.Equal(t, []int{0, 1, 2}, p.indices)
+
+	for name, tc := range map[string]struct {
+		quorum []string
+		self   string
+	}{
+		"too small":  {[]string{"server"}, "server"},
+		"empty":      {nil, "server"},
+		"duplicate":  {[]string{"server", "server"}, "server"},
+		"unknown":    {[]string{"server", "hsm"}, "server"},
+		"not member": {[]string{"server", "pin"}, "kms"},
+	} {
+		_, err := planQuorum(ac, tc.quorum, tc.self)
+		assert.ErrorIs(t, err, ErrInvalidInput, name)
+	}
+	_, err = planQuorum(nil, []string{"server"}, "server")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestPlanQuorumNested(t *testing.T) {
+	// The owner and one of two approvers.
+	ac := &AccessStructure{Root: And("", Leaf("owner"), Or("approvers", Leaf("a1"), Leaf("a2")))}
+	p, err := planQuorum(ac, []string{"a2", "owner"}, "owner")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"owner", "a2"}, p.quorum)
+	assert.Equal(t, []int{0, 2}, p.indices)
+
+	_, err = planQuorum(ac, []string{"a1", "a2"}, "a1")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestQuorumMessenger(t *testing.T) {
+	ctx := context.Background()
+	net := mocknet.NewMockNetwork(3)
+	indices := []int{0, 2}
+	server := &quorumMessenger{inner: net[0], indices: indices}
+	pin := &quorumMessenger{inner: net[2], indices: indices}
+
+	require.NoError(t, server.MessageSend(ctx, 1, []byte("to pin")))
+	msg, err := pin.MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("to pin"), msg)
+
+	require.NoError(t, pin.MessageSend(ctx, 0, []byte("to server")))
+	msgs, err := server.MessagesReceive(ctx, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("to server")}, msgs)
+
+	assert.Error(t, server.MessageSend(ctx, 2, nil), "only two quorum parties")
+	_, err = server.MessagesReceive(ctx, []int{-1})
+	assert.Error(t, err)
+}
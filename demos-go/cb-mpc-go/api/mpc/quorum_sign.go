@@ -0,0 +1,57 @@
+package mpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// quorumShares holds the key shares of a quorum that SignWithQuorum signs
+// with, and the plan of the quorum over the committee.
+type quorumShares struct {
+	root   *AccessNode
+	curve  string
+	plan   *quorumPlan
+	shares map[string][]byte // Marshalled share of every quorum party
+}
+
+// collectQuorumShares checks that every party of quorum has a key share in
+// keyShares, that the shares carry the same access structure, curve and
+// party names, and that quorum satisfies the access structure.
+func collectQuorumShares(keyShares map[string][]byte, quorum []string) (*quorumShares, error) {
+	if len(quorum) == 0 {
+		return nil, invalid("quorum", "party names cannot be empty")
+	}
+	q := &quorumShares{shares: make(map[string][]byte, len(quorum))}
+	var key []byte
+	for _, name := range quorum {
+		data, ok := keyShares[name]
+		if !ok {
+			return nil, invalid("key shares", "no share for quorum party %q", name)
+		}
+		_, e, err := openShare(data)
+		if err != nil {
+			return nil, fmt.Errorf("key share of %q: %w", name, err)
+		}
+		if e == nil {
+			return nil, fmt.Errorf("key share of %q: %w", name, ErrNoAccessStructure)
+		}
+		// Everything but the share itself must be the same for every party.
+		k, err := json.Marshal(shareEnvelope{Curve: e.Curve, AccessStructure: e.AccessStructure, PartyNames: e.PartyNames})
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			key, q.root, q.curve = k, e.AccessStructure, e.Curve
+		} else if !bytes.Equal(k, key) {
+			return nil, invalid("key shares", "share of %q belongs to a different key than share of %q", name, quorum[0])
+		}
+		q.shares[name] = data
+	}
+	plan, err := planQuorum(&AccessStructure{Root: q.root}, quorum, quorum[0])
+	if err != nil {
+		return nil, err
+	}
+	q.plan = plan
+	return q, nil
+}
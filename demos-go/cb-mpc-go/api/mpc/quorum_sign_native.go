@@ -0,0 +1,145 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+)
+
+// QuorumSignResponse is the signature produced by SignWithQuorum.
+type QuorumSignResponse struct {
+	Signature []byte // Ed25519 signature, or DER signature for ECDSA keys
+	// RecoverableSignature is the signature as r || s || v for secp256k1
+	// keys; see ECDSAMPCSignResponse.
+	RecoverableSignature []byte
+}
+
+// SignWithQuorum signs message with the threshold key shares of the parties
+// in quorum, running every party in this process.  keyShares maps party
+// names to shares marshalled with MarshalBinaryWithAccessStructure, whose
+// access structure quorum must satisfy; shares of other parties may be
+// present and are not used.  Ed25519 keys sign with EDDSAMPCSign, other
+// curves with ECDSAMPCSign, where message is the digest to sign.
+//
+// SignWithQuorum validates the quorum, converts the shares into additive
+// shares and signs over an in-process network of the committee.  When the
+// parties run in different processes, each one uses NewQuorumJob instead.
+func SignWithQuorum(keyShares map[string][]byte, quorum []string, message []byte) (*QuorumSignResponse, error) {
+	q, err := collectQuorumShares(keyShares, quorum)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMessage(message); err != nil {
+		return nil, err
+	}
+	cv, err := curveByName(q.curve)
+	if err != nil {
+		return nil, err
+	}
+	defer cv.Free()
+	ac := &AccessStructure{Root: q.root, Curve: cv}
+	eddsa := q.curve == "Ed25519"
+
+	// Convert every share before any party starts the protocol, so that a
+	// bad share fails here instead of leaving its peers waiting.
+	ecdsaShares := make([]ECDSAMPCKey, len(q.plan.quorum))
+	eddsaShares := make([]EDDSAMPCKey, len(q.plan.quorum))
+	defer func() {
+		for i := range q.plan.quorum {
+			if !ecdsaShares[i].empty() {
+				ecdsaShares[i].Free()
+			}
+			if !eddsaShares[i].empty() {
+				eddsaShares[i].Free()
+			}
+		}
+	}()
+	for i, name := range q.plan.quorum {
+		if eddsa {
+			eddsaShares[i], err = additiveEDDSAShare(q.shares[name], name, ac, q.plan.quorum)
+		} else {
+			ecdsaShares[i], err = additiveECDSAShare(q.shares[name], name, ac, q.plan.quorum)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	messengers := mocknet.NewMockNetwork(len(q.plan.committee))
+	signatureReceiver := 0
+	var resp QuorumSignResponse
+	var eg errgroup.Group
+	for i, name := range q.plan.quorum {
+		eg.Go(func() error {
+			job, err := NewQuorumJob(messengers[q.plan.indices[i]], ac, quorum, name)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			if eddsa {
+				r, err := EDDSAMPCSign(job.JobMP, &EDDSAMPCSignRequest{KeyShare: eddsaShares[i], Message: message, SignatureReceiver: signatureReceiver})
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				if i == signatureReceiver {
+					resp.Signature = r.Signature
+				}
+				return nil
+			}
+			r, err := ECDSAMPCSign(job.JobMP, &ECDSAMPCSignRequest{KeyShare: ecdsaShares[i], Message: message, SignatureReceiver: signatureReceiver})
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if i == signatureReceiver {
+				resp.Signature, resp.RecoverableSignature = r.Signature, r.RecoverableSignature
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// additiveECDSAShare restores the share of party and converts it for quorum.
+func additiveECDSAShare(data []byte, party string, ac *AccessStructure, quorum []string) (ECDSAMPCKey, error) {
+	var k ECDSAMPCKey
+	if err := k.UnmarshalBinary(data); err != nil {
+		return ECDSAMPCKey{}, fmt.Errorf("key share of %q: %w", party, err)
+	}
+	defer k.Free()
+	if err := checkShareOwner(k.PartyName, party); err != nil {
+		return ECDSAMPCKey{}, err
+	}
+	return k.ToAdditiveShare(ac, quorum)
+}
+
+// additiveEDDSAShare restores the share of party and converts it for quorum.
+func additiveEDDSAShare(data []byte, party string, ac *AccessStructure, quorum []string) (EDDSAMPCKey, error) {
+	var k EDDSAMPCKey
+	if err := k.UnmarshalBinary(data); err != nil {
+		return EDDSAMPCKey{}, fmt.Errorf("key share of %q: %w", party, err)
+	}
+	defer k.Free()
+	if err := checkShareOwner(k.PartyName, party); err != nil {
+		return EDDSAMPCKey{}, err
+	}
+	return k.ToAdditiveShare(ac, quorum)
+}
+
+// checkShareOwner checks that a share listed for party is held by party.
+func checkShareOwner(partyNameFn func() (string, error), party string) error {
+	owner, err := partyNameFn()
+	if err != nil {
+		return fmt.Errorf("reading party name: %v", err)
+	}
+	if owner != party {
+		return invalid("key shares", "share listed for %q is held by %q", party, owner)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// TestSignWithQuorum signs with two parties of a 2-of-3 Ed25519 key from
+// their marshalled threshold shares.
+func TestSignWithQuorum(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	pnames := []string{"server", "kms", "pin"}
+	ac := createThresholdAccessStructure(pnames, 2, cv)
+	messengers := mocknet.NewMockNetwork(len(pnames))
+
+	marshalled := make([][]byte, len(pnames))
+	var pubKey []byte
+	var eg errgroup.Group
+	for i := range pnames {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(pnames), i, pnames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			resp, err := EDDSAMPCThresholdDKG(job, &EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: ac})
+			if err != nil {
+				return err
+			}
+			defer resp.KeyShare.Free()
+			if i == 0 {
+				q, err := resp.KeyShare.Q()
+				if err != nil {
+					return err
+				}
+				defer q.Free()
+				if pubKey, err = q.Encode(cv, curvepkg.FormatEd25519); err != nil {
+					return err
+				}
+			}
+			marshalled[i], err = resp.KeyShare.MarshalBinaryWithAccessStructure(ac, pnames)
+			return err
+		})
+	}
+	require.NoError(t, eg.Wait())
+	shares := map[string][]byte{"server": marshalled[0], "kms": marshalled[1], "pin": marshalled[2]}
+
+	message := []byte("quorum")
+	resp, err := SignWithQuorum(shares, []string{"pin", "server"}, message)
+	require.NoError(t, err)
+	require.NoError(t, verify.Ed25519(pubKey, message, resp.Signature))
+	assert.Nil(t, resp.RecoverableSignature)
+
+	// A share listed under the wrong party is refused before signing.
+	swapped := map[string][]byte{"server": marshalled[2], "pin": marshalled[0]}
+	_, err = SignWithQuorum(swapped, []string{"pin", "server"}, message)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
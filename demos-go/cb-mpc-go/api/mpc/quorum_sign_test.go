@@ -0,0 +1,42 @@
+package mpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectQuorumShares(t *testing.T) {
+	pnames := []string{"server", "kms", "pin"}
+	ac := &AccessStructure{Root: Threshold("", 2, Leaf("server"), Leaf("kms"), Leaf("pin"))}
+	shares := make(map[string][]byte)
+	for _, name := range pnames {
+		data, err := sealShare([]byte("share of "+name), ac, pnames, "Ed25519", name)
+		require.NoError(t, err)
+		shares[name] = data
+	}
+
+	q, err := collectQuorumShares(shares, []string{"pin", "server"})
+	require.NoError(t, err)
+	assert.Equal(t, "Ed25519", q.curve)
+	assert.Equal(t, []string{"server", "pin"}, q.plan.quorum)
+	assert.Equal(t, []int{0, 2}, q.plan.indices)
+	assert.Len(t, q.shares, 2, "shares outside the quorum are not used")
+
+	_, err = collectQuorumShares(shares, []string{"pin"})
+	assert.ErrorIs(t, err, ErrInvalidInput, "does not satisfy the structure")
+	_, err = collectQuorumShares(shares, nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = collectQuorumShares(map[string][]byte{"server": shares["server"]}, []string{"pin", "server"})
+	assert.ErrorIs(t, err, ErrInvalidInput, "missing share")
+
+	_, err = collectQuorumShares(map[string][]byte{"server": shares["server"], "pin": []byte("gob")}, []string{"server", "pin"})
+	assert.ErrorIs(t, err, ErrNoAccessStructure)
+
+	// A share of a key with a different structure is not mixed in.
+	other, err := sealShare([]byte("share of pin"), &AccessStructure{Root: Threshold("", 1, Leaf("server"), Leaf("kms"), Leaf("pin"))}, pnames, "Ed25519", "pin")
+	require.NoError(t, err)
+	_, err = collectQuorumShares(map[string][]byte{"server": shares["server"], "pin": other}, []string{"server", "pin"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
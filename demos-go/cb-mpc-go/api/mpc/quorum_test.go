@@ -0,0 +1,35 @@
+package mpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimalQuorumsThreshold(t *testing.T) {
+	as := &AccessStructure{Root: Threshold("", 2, Leaf("server"), Leaf("kms"), Leaf("pin"))}
+	quorums, err := as.MinimalQuorums()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"kms", "pin"}, {"kms", "server"}, {"pin", "server"}}, quorums)
+}
+
+func TestMinimalQuorumsNested(t *testing.T) {
+	root := And("",
+		Or("role", Leaf("admin"), Leaf("hr")),
+		Threshold("sig", 2, Leaf("a"), Leaf("b"), Leaf("admin")),
+	)
+	quorums, err := root.MinimalQuorums()
+	require.NoError(t, err)
+	// {a, b, admin} is satisfying but not minimal.
+	assert.Equal(t, [][]string{{"a", "admin"}, {"admin", "b"}, {"a", "b", "hr"}}, quorums)
+}
+
+func TestMinimalQuorumsInvalid(t *testing.T) {
+	_, err := (&AccessStructure{}).MinimalQuorums()
+	assert.Error(t, err)
+	_, err = Threshold("", 3, Leaf("a"), Leaf("b")).MinimalQuorums()
+	assert.Error(t, err)
+	_, err = And("").MinimalQuorums()
+	assert.Error(t, err)
+}
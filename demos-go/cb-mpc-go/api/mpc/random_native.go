@@ -0,0 +1,18 @@
+//go:build !nompc
+
+package mpc
+
+import "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
+
+// SeedRandom mixes entropy into the RNG from which the native library draws
+// every secret, such as the shares and nonces of a DKG.  It adds to the
+// system entropy the library already uses and never replaces it, so weak
+// input cannot make the RNG worse.  Key ceremonies use it to feed dice rolls
+// or an HSM RNG into key generation.
+func SeedRandom(entropy []byte) error {
+	if len(entropy) == 0 {
+		return invalid("entropy", "must not be empty")
+	}
+	cgobinding.SeedRandom(entropy)
+	return nil
+}
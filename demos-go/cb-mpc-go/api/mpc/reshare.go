@@ -0,0 +1,132 @@
+package mpc
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// reshareDeal is what a dealer of Reshare sends to every other party.
+// Points are serialized by curve.Point.Bytes and scalars are big-endian.
+type reshareDeal struct {
+	Q []byte `json:"q"` // Public key
+	X []byte `json:"x"` // Public additive share of the dealer
+	// Public holds s·G for the share s the dealer dealt to every party of
+	// the new access structure.  These are Feldman commitments to the
+	// dealer's polynomials, given by their values rather than coefficients:
+	// parties check them against X with reshareCheckSets.
+	Public map[string][]byte `json:"public"`
+	// Share is the share dealt to the receiver, if it is a party of the new
+	// access structure.  It is never part of the transcript.
+	Share []byte `json:"share,omitempty"`
+}
+
+// reshareDealers returns the parties of the job that hold a share of the old
+// key, in job order: every one of them deals.
+func reshareDealers(oldParties, jobParties []string) []string {
+	var dealers []string
+	for _, name := range jobParties {
+		if slices.Contains(oldParties, name) {
+			dealers = append(dealers, name)
+		}
+	}
+	return dealers
+}
+
+// reshareTranscript hashes the public part of the deals, indexed by the
+// dealers' job indices, so that parties can check that every dealer sent
+// them the same public key and public shares.
+func reshareTranscript(deals map[int]*reshareDeal, nParties int) ([]byte, error) {
+	h := sha256.New()
+	for i := 0; i < nParties; i++ {
+		d, ok := deals[i]
+		if !ok {
+			continue
+		}
+		body, err := json.Marshal(struct {
+			Index int `json:"index"`
+			reshareDeal
+		}{i, reshareDeal{Q: d.Q, X: d.X, Public: d.Public}})
+		if err != nil {
+			return nil, err
+		}
+		h.Write(body)
+	}
+	return h.Sum(nil), nil
+}
+
+// reshareCheckSets returns the leaf sets on which Reshare checks a deal: the
+// public shares of the deal are a sharing of X under root – at each
+// threshold node, values of one polynomial of degree K-1 – exactly when each
+// set reconstructs X.  Every set is a minimal quorum of root and there is at
+// most one set per leaf, where the minimal quorums can be exponentially
+// many.
+//
+// The sets vary one child of a node at a time and fix the others to their
+// first set: all children of an AND node, one of an OR node, and for a
+// threshold node the first K-1 children plus each further one, which pins
+// the polynomial through X and the first K-1 values.
+func reshareCheckSets(root *AccessNode) ([][]string, error) {
+	sets, err := root.checkSets()
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]string, len(sets))
+	for i, s := range sets {
+		out[i] = s.names()
+	}
+	return out, nil
+}
+
+func (n *AccessNode) checkSets() ([]nameSet, error) {
+	if n == nil {
+		return nil, fmt.Errorf("nil node in access structure")
+	}
+	var k int
+	switch n.Kind {
+	case KindLeaf:
+		if len(n.Children) != 0 {
+			return nil, fmt.Errorf("leaf %q has children", n.Name)
+		}
+		return []nameSet{{n.Name: {}}}, nil
+	case KindAnd:
+		k = len(n.Children)
+	case KindOr:
+		k = 1
+	case KindThreshold:
+		if n.K <= 0 || n.K > len(n.Children) {
+			return nil, fmt.Errorf("threshold %q has invalid K=%d for %d children", n.Name, n.K, len(n.Children))
+		}
+		k = n.K
+	default:
+		return nil, fmt.Errorf("node %q has unknown kind %v", n.Name, n.Kind)
+	}
+	if len(n.Children) == 0 {
+		return nil, fmt.Errorf("%v node %q has no children", n.Kind, n.Name)
+	}
+	children := make([][]nameSet, len(n.Children))
+	for i, child := range n.Children {
+		sets, err := child.checkSets()
+		if err != nil {
+			return nil, err
+		}
+		children[i] = sets
+	}
+
+	var out []nameSet
+	for i, sets := range children {
+		// The other children among the first k, or the first k-1 for the
+		// children after them.
+		fixed := nameSet{}
+		for j := 0; j < k; j++ {
+			if j != i && (i < k || j < k-1) {
+				fixed = fixed.union(children[j][0])
+			}
+		}
+		for _, s := range sets {
+			out = append(out, fixed.union(s))
+		}
+	}
+	return minimize(out), nil
+}
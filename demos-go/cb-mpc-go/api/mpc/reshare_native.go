@@ -0,0 +1,411 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/internal/curveref"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
+)
+
+// Reshare moves an EdDSA key to a new access structure, such as from 2-of-3
+// to 3-of-5 after adding devices, and keeps its public key.
+//
+// The job runs over the old parties that deal and the parties of
+// newAccessStructure.  Every party of the job that holds a share of the key
+// passes it as oldKeyShare, marshalled with MarshalBinaryWithAccessStructure;
+// together they must satisfy the old access structure.  New parties pass a
+// nil oldKeyShare.  Every party of newAccessStructure gets its new threshold
+// share, to be stored with MarshalBinaryWithAccessStructure(newAccessStructure,
+// job.PartyNames()); old parties that are not in it get the zero
+// EDDSAMPCKey.
+//
+// Each dealer converts its share into an additive share for the dealers and
+// shares that again under newAccessStructure, sending every new party its
+// part together with the public shares of all new parties.  The public
+// shares commit the dealer to its sharing: every party checks that they add
+// up to the public key, that they reconstruct the dealer's public share
+// under newAccessStructure – at each threshold node from a polynomial of
+// degree K-1 – and that its own share matches them.  The parties compare
+// transcripts before any new share is built, and each new share is checked
+// against the public key under newAccessStructure.  The dealt shares travel
+// in the job's messages, so the transport must be encrypted, like mtls.
+//
+// Old and new shares never combine into a signature, but old shares still
+// sign for the same key under the old access structure.  Once every party
+// of the job has confirmed its new share, Reshare zeroes oldKeyShare; copies
+// of the old share stored elsewhere must be deleted by the caller.  If any
+// party fails, oldKeyShare is left intact and no party keeps a new share.
+func Reshare(jobmp *JobMP, oldKeyShare []byte, newAccessStructure *AccessStructure) (EDDSAMPCKey, error) {
+	if jobmp == nil {
+		return EDDSAMPCKey{}, invalid("job", "must be provided")
+	}
+	if jobmp.messenger == nil {
+		return EDDSAMPCKey{}, invalid("job", "has no messenger; create it with NewJobMP")
+	}
+	if newAccessStructure == nil || newAccessStructure.Curve == nil {
+		return EDDSAMPCKey{}, invalid("access structure", "must be provided with its curve")
+	}
+	if newAccessStructure.Curve.String() != "Ed25519" {
+		return EDDSAMPCKey{}, invalid("access structure", "Reshare supports Ed25519 keys, not %s", newAccessStructure.Curve)
+	}
+	if err := newAccessStructure.Validate(jobmp.pnames); err != nil {
+		return EDDSAMPCKey{}, err
+	}
+	cv := newAccessStructure.Curve
+	self := jobmp.GetPartyIndex()
+	name := jobmp.pnames[self]
+	ac := newAccessStructure.toCryptoAC()
+
+	// Deal, if this party holds a share of the key.
+	var (
+		own     *reshareDeal
+		shares  map[string][]byte
+		dealers []string
+		xs      map[string]*curve.Point
+	)
+	if oldKeyShare != nil {
+		_, e, err := openShare(oldKeyShare)
+		if err != nil {
+			return EDDSAMPCKey{}, err
+		}
+		if e == nil {
+			return EDDSAMPCKey{}, ErrNoAccessStructure
+		}
+		if e.Curve != "Ed25519" {
+			return EDDSAMPCKey{}, invalid("key share", "Reshare supports Ed25519 keys, not %s", e.Curve)
+		}
+		dealers = reshareDealers(e.PartyNames, jobmp.pnames)
+		additive, err := EDDSAMPCAdditiveShare(oldKeyShare, dealers)
+		if err != nil {
+			return EDDSAMPCKey{}, fmt.Errorf("converting the share for the dealers %v: %w", dealers, err)
+		}
+		defer additive.Free()
+		if err := checkShareOwner(additive.PartyName, name); err != nil {
+			return EDDSAMPCKey{}, err
+		}
+		if xs, err = additive.Qis(); err != nil {
+			return EDDSAMPCKey{}, fmt.Errorf("reading public shares: %v", err)
+		}
+		defer freePoints(xs)
+		q, err := additive.Q()
+		if err != nil {
+			return EDDSAMPCKey{}, fmt.Errorf("reading public key: %v", err)
+		}
+		defer q.Free()
+		ref := additive.cgobindingRef()
+		if shares, err = ref.ReshareDeal(ac); err != nil {
+			return EDDSAMPCKey{}, err
+		}
+		own = &reshareDeal{Q: q.Bytes(), X: xs[name].Bytes(), Public: make(map[string][]byte, len(shares))}
+		for leaf, s := range shares {
+			p, err := cv.MultiplyGenerator(&curve.Scalar{Bytes: s})
+			if err != nil {
+				return EDDSAMPCKey{}, err
+			}
+			own.Public[leaf] = p.Bytes()
+			p.Free()
+		}
+	}
+
+	// Round 1: send every party the deal, with its own share.
+	ctx := context.Background()
+	peers := make([]int, 0, jobmp.NParties()-1)
+	for i := range jobmp.pnames {
+		if i != self {
+			peers = append(peers, i)
+		}
+	}
+	for _, i := range peers {
+		msg := []byte("{}")
+		if own != nil {
+			d := *own
+			d.Share = shares[jobmp.pnames[i]]
+			var err error
+			if msg, err = json.Marshal(d); err != nil {
+				return EDDSAMPCKey{}, err
+			}
+		}
+		if err := jobmp.messenger.MessageSend(ctx, i, msg); err != nil {
+			return EDDSAMPCKey{}, fmt.Errorf("sending deal to %s: %w", jobmp.pnames[i], err)
+		}
+	}
+	msgs, err := jobmp.messenger.MessagesReceive(ctx, peers)
+	if err != nil {
+		return EDDSAMPCKey{}, fmt.Errorf("receiving deals: %w", err)
+	}
+	deals := make(map[int]*reshareDeal)
+	if own != nil {
+		d := *own
+		d.Share = shares[name]
+		deals[self] = &d
+	}
+	for k, i := range peers {
+		var d reshareDeal
+		if err := json.Unmarshal(msgs[k], &d); err != nil {
+			return EDDSAMPCKey{}, fmt.Errorf("deal of %s: %v", jobmp.pnames[i], err)
+		}
+		if d.Q != nil {
+			deals[i] = &d
+		}
+	}
+	if err := checkReshareDeals(jobmp, newAccessStructure, ac, deals, dealers, xs); err != nil {
+		return EDDSAMPCKey{}, err
+	}
+
+	// Round 2: compare transcripts, so that a dealer cannot tell parties
+	// different public shares.
+	transcript, err := reshareTranscript(deals, jobmp.NParties())
+	if err != nil {
+		return EDDSAMPCKey{}, err
+	}
+	for _, i := range peers {
+		if err := jobmp.messenger.MessageSend(ctx, i, transcript); err != nil {
+			return EDDSAMPCKey{}, fmt.Errorf("sending transcript to %s: %w", jobmp.pnames[i], err)
+		}
+	}
+	if msgs, err = jobmp.messenger.MessagesReceive(ctx, peers); err != nil {
+		return EDDSAMPCKey{}, fmt.Errorf("receiving transcripts: %w", err)
+	}
+	var disagree []string
+	for k, i := range peers {
+		if !bytes.Equal(msgs[k], transcript) {
+			disagree = append(disagree, jobmp.pnames[i])
+		}
+	}
+	if len(disagree) > 0 {
+		return EDDSAMPCKey{}, fmt.Errorf("reshare transcript differs from that of %v", disagree)
+	}
+
+	var leaves []string
+	for _, d := range deals {
+		leaves = slices.Sorted(maps.Keys(d.Public))
+		break
+	}
+	var key EDDSAMPCKey
+	if slices.Contains(leaves, name) {
+		key, err = assembleReshared(cv, ac, name, deals, leaves)
+	}
+
+	// Round 3: confirm, so that the old shares are erased only once every
+	// party holds its new share.
+	status := []byte(reshareOK)
+	if err != nil {
+		status = []byte(err.Error())
+	}
+	for _, i := range peers {
+		if sendErr := jobmp.messenger.MessageSend(ctx, i, status); sendErr != nil && err == nil {
+			err = fmt.Errorf("sending confirmation to %s: %w", jobmp.pnames[i], sendErr)
+		}
+	}
+	if err == nil {
+		if msgs, err = jobmp.messenger.MessagesReceive(ctx, peers); err != nil {
+			err = fmt.Errorf("receiving confirmations: %w", err)
+		}
+	}
+	if err == nil {
+		var failed []string
+		for k, i := range peers {
+			if string(msgs[k]) != reshareOK {
+				failed = append(failed, jobmp.pnames[i])
+			}
+		}
+		if len(failed) > 0 {
+			err = fmt.Errorf("reshare failed at %v", failed)
+		}
+	}
+	if err != nil {
+		key.Free()
+		return EDDSAMPCKey{}, err
+	}
+	clear(oldKeyShare)
+	return key, nil
+}
+
+// reshareOK is the confirmation a party sends once it holds its new share.
+const reshareOK = "ok"
+
+// checkReshareDeals checks that the dealers are the expected ones, that they
+// agree on the public key, that their public additive shares add up to it,
+// that the public shares of each deal are a sharing of the dealer's public
+// share under newAC and that the share dealt to this party matches its
+// public share.  dealers and xs are nil unless this party is a dealer.
+func checkReshareDeals(jobmp *JobMP, newAC *AccessStructure, ac cgobinding.C_AcPtr, deals map[int]*reshareDeal, dealers []string, xs map[string]*curve.Point) error {
+	if len(deals) == 0 {
+		return invalid("key share", "no party of the job holds a share of the key")
+	}
+	cv := newAC.Curve
+	want, err := newAC.Root.leaves()
+	if err != nil {
+		return err
+	}
+	slices.Sort(want)
+	sets, err := reshareCheckSets(newAC.Root)
+	if err != nil {
+		return err
+	}
+	var got []string
+	for i := range jobmp.pnames {
+		if _, ok := deals[i]; ok {
+			got = append(got, jobmp.pnames[i])
+		}
+	}
+	if dealers != nil && !slices.Equal(got, dealers) {
+		return fmt.Errorf("expected deals from %v, got deals from %v", dealers, got)
+	}
+
+	var (
+		first *reshareDeal
+		sum   *curve.Point
+	)
+	defer func() {
+		if sum != nil {
+			sum.Free()
+		}
+	}()
+	name := jobmp.pnames[jobmp.GetPartyIndex()]
+	for i := range jobmp.pnames {
+		d, ok := deals[i]
+		if !ok {
+			continue
+		}
+		dealer := jobmp.pnames[i]
+		if first == nil {
+			first = d
+		}
+		if !bytes.Equal(d.Q, first.Q) {
+			return fmt.Errorf("deal of %s is for another public key", dealer)
+		}
+		if !slices.Equal(slices.Sorted(maps.Keys(d.Public)), want) {
+			return fmt.Errorf("deal of %s is for other parties", dealer)
+		}
+		if err := checkDealSharing(ac, d, sets); err != nil {
+			return fmt.Errorf("deal of %s: %v", dealer, err)
+		}
+		x, err := curve.NewPointFromBytes(d.X)
+		if err != nil {
+			return fmt.Errorf("deal of %s: %v", dealer, err)
+		}
+		if xs != nil && !x.Equals(xs[dealer]) {
+			x.Free()
+			return fmt.Errorf("deal of %s does not match its public share", dealer)
+		}
+		if sum == nil {
+			sum = x
+		} else {
+			next := sum.Add(x)
+			sum.Free()
+			x.Free()
+			sum = next
+		}
+		if slices.Contains(want, name) {
+			if err := checkDealtShare(cv, d.Share, d.Public[name]); err != nil {
+				return fmt.Errorf("deal of %s: %v", dealer, err)
+			}
+		}
+	}
+	q, err := curve.NewPointFromBytes(first.Q)
+	if err != nil {
+		return fmt.Errorf("public key: %v", err)
+	}
+	defer q.Free()
+	if !sum.Equals(q) {
+		return fmt.Errorf("public shares of the dealers do not add up to the public key")
+	}
+	return nil
+}
+
+// checkDealSharing checks that the public shares of d reconstruct its public
+// additive share X on each of the sets of reshareCheckSets, so that they are
+// a sharing of X of the degrees of the access structure.
+func checkDealSharing(ac cgobinding.C_AcPtr, d *reshareDeal, sets [][]string) error {
+	for _, set := range sets {
+		points := make(map[string][]byte, len(set))
+		for _, leaf := range set {
+			points[leaf] = d.Public[leaf]
+		}
+		if err := cgobinding.CheckExponentSharing(ac, d.X, points); err != nil {
+			return fmt.Errorf("public shares of %v do not reconstruct the dealer's public share", set)
+		}
+	}
+	return nil
+}
+
+// checkDealtShare checks that share·G is the public share dealt with it.
+func checkDealtShare(cv curve.Curve, share, public []byte) error {
+	if len(share) == 0 {
+		return fmt.Errorf("no share dealt")
+	}
+	p, err := cv.MultiplyGenerator(&curve.Scalar{Bytes: share})
+	if err != nil {
+		return err
+	}
+	defer p.Free()
+	want, err := curve.NewPointFromBytes(public)
+	if err != nil {
+		return err
+	}
+	defer want.Free()
+	if !p.Equals(want) {
+		return fmt.Errorf("dealt share does not match its public share")
+	}
+	return nil
+}
+
+// assembleReshared adds up the shares dealt to name and the public shares of
+// every party, and builds the new key share.
+func assembleReshared(cv curve.Curve, ac cgobinding.C_AcPtr, name string, deals map[int]*reshareDeal, leaves []string) (EDDSAMPCKey, error) {
+	var (
+		x   *curve.Scalar
+		q   []byte
+		qis = make(map[string]*curve.Point, len(leaves))
+	)
+	defer freePoints(qis)
+	for _, d := range deals {
+		q = d.Q
+		if x == nil {
+			x = &curve.Scalar{Bytes: d.Share}
+		} else {
+			var err error
+			if x, err = cv.Add(x, &curve.Scalar{Bytes: d.Share}); err != nil {
+				return EDDSAMPCKey{}, err
+			}
+		}
+		for _, leaf := range leaves {
+			p, err := curve.NewPointFromBytes(d.Public[leaf])
+			if err != nil {
+				return EDDSAMPCKey{}, err
+			}
+			if prev, ok := qis[leaf]; ok {
+				qis[leaf] = prev.Add(p)
+				prev.Free()
+				p.Free()
+			} else {
+				qis[leaf] = p
+			}
+		}
+	}
+	public := make(map[string][]byte, len(qis))
+	for leaf, p := range qis {
+		public[leaf] = p.Bytes()
+	}
+	key, err := cgobinding.AssembleKeyShare(curveref.Ref(cv), ac, name, x.Bytes, q, public)
+	if err != nil {
+		return EDDSAMPCKey{}, fmt.Errorf("new share does not match the public key: %v", err)
+	}
+	return newEDDSAMPCKey(key), nil
+}
+
+// freePoints frees every point of ps.
+func freePoints(ps map[string]*curve.Point) {
+	for _, p := range ps {
+		p.Free()
+	}
+}
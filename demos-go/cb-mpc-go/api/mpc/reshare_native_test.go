@@ -0,0 +1,257 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"bytes"
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// TestReshare moves a 2-of-3 key to 3-of-5 and checks that the new quorums
+// sign for the same public key, while the old quorums do not and the old
+// shares passed to Reshare are erased.
+func TestReshare(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	oldNames := []string{"server", "kms", "pin"}
+	oldAC := createThresholdAccessStructure(oldNames, 2, cv)
+	messengers := mocknet.NewMockNetwork(len(oldNames))
+	oldData := make([][]byte, len(oldNames))
+	var pubKey []byte
+	var eg errgroup.Group
+	for i := range oldNames {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(oldNames), i, oldNames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			resp, err := EDDSAMPCThresholdDKG(job, &EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: oldAC})
+			if err != nil {
+				return err
+			}
+			defer resp.KeyShare.Free()
+			if i == 0 {
+				if pubKey, err = encodedKey(resp.KeyShare.Q, cv); err != nil {
+					return err
+				}
+			}
+			oldData[i], err = resp.KeyShare.MarshalBinaryWithAccessStructure(oldAC, oldNames)
+			return err
+		})
+	}
+	require.NoError(t, eg.Wait())
+	oldShares := make(map[string][]byte, len(oldNames))
+	kept := make(map[string][]byte, len(oldNames))
+	for i, name := range oldNames {
+		oldShares[name] = oldData[i]
+		kept[name] = bytes.Clone(oldData[i])
+	}
+
+	newNames := []string{"server", "kms", "pin", "phone", "laptop"}
+	newAC := createThresholdAccessStructure(newNames, 3, cv)
+	messengers = mocknet.NewMockNetwork(len(newNames))
+	newData := make([][]byte, len(newNames))
+	results := make([][]byte, len(newNames))
+	for i, name := range newNames {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(newNames), i, newNames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			share, err := Reshare(job, oldShares[name], newAC)
+			if err != nil {
+				return err
+			}
+			defer share.Free()
+			if results[i], err = encodedKey(share.Q, cv); err != nil {
+				return err
+			}
+			newData[i], err = share.MarshalBinaryWithAccessStructure(newAC, newNames)
+			return err
+		})
+	}
+	require.NoError(t, eg.Wait())
+	newShares := make(map[string][]byte, len(newNames))
+	for i, name := range newNames {
+		assert.Equal(t, pubKey, results[i], "public key of %s", name)
+		newShares[name] = newData[i]
+	}
+
+	message := []byte("after resharing")
+	resp, err := SignWithQuorum(newShares, []string{"server", "phone", "laptop"}, message)
+	require.NoError(t, err)
+	assert.NoError(t, verify.Ed25519(pubKey, message, resp.Signature))
+
+	// The old quorum is too small for the new access structure.
+	_, err = SignWithQuorum(newShares, []string{"server", "pin"}, message)
+	assert.Error(t, err)
+	// Old and new shares do not combine.
+	mixed := map[string][]byte{"server": newShares["server"], "kms": newShares["kms"], "pin": kept["pin"]}
+	_, err = SignWithQuorum(mixed, []string{"server", "kms", "pin"}, message)
+	assert.Error(t, err)
+
+	// The old quorum no longer signs with the shares passed to Reshare,
+	// which zeroed them.
+	_, err = SignWithQuorum(oldShares, []string{"server", "pin"}, message)
+	assert.Error(t, err)
+	for name, data := range oldShares {
+		assert.Equal(t, make([]byte, len(data)), data, "old share of %s", name)
+	}
+	// Copies kept elsewhere still sign under the old access structure, which
+	// is why the caller must delete them.
+	resp, err = SignWithQuorum(kept, []string{"server", "pin"}, message)
+	require.NoError(t, err)
+	assert.NoError(t, verify.Ed25519(pubKey, message, resp.Signature))
+}
+
+// TestCheckDealSharing checks that a deal whose public shares are not those
+// of one polynomial of the declared degree is rejected, even when the share
+// sent to the tampered party matches its public share.
+func TestCheckDealSharing(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	names := []string{"server", "kms", "pin"}
+	oldAC := createThresholdAccessStructure(names, 2, cv)
+	messengers := mocknet.NewMockNetwork(len(names))
+	oldShares := make([][]byte, len(names))
+	var eg errgroup.Group
+	for i := range names {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(names), i, names)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			resp, err := EDDSAMPCThresholdDKG(job, &EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: oldAC})
+			if err != nil {
+				return err
+			}
+			defer resp.KeyShare.Free()
+			oldShares[i], err = resp.KeyShare.MarshalBinaryWithAccessStructure(oldAC, names)
+			return err
+		})
+	}
+	require.NoError(t, eg.Wait())
+
+	additive, err := EDDSAMPCAdditiveShare(oldShares[0], []string{"server", "kms"})
+	require.NoError(t, err)
+	defer additive.Free()
+	xs, err := additive.Qis()
+	require.NoError(t, err)
+	defer freePoints(xs)
+
+	newNames := []string{"server", "kms", "pin", "phone", "laptop"}
+	newAC := createThresholdAccessStructure(newNames, 3, cv)
+	ac := newAC.toCryptoAC()
+	sets, err := reshareCheckSets(newAC.Root)
+	require.NoError(t, err)
+	ref := additive.cgobindingRef()
+	shares, err := ref.ReshareDeal(ac)
+	require.NoError(t, err)
+	deal := &reshareDeal{X: xs["server"].Bytes(), Public: make(map[string][]byte, len(shares))}
+	for leaf, s := range shares {
+		p, err := cv.MultiplyGenerator(&curvepkg.Scalar{Bytes: s})
+		require.NoError(t, err)
+		deal.Public[leaf] = p.Bytes()
+		p.Free()
+	}
+	require.NoError(t, checkDealSharing(ac, deal, sets))
+
+	// Deal laptop a share off the polynomial, with its matching public share.
+	r, err := cv.RandomScalar()
+	require.NoError(t, err)
+	p, err := cv.MultiplyGenerator(r)
+	require.NoError(t, err)
+	defer p.Free()
+	deal.Public["laptop"] = p.Bytes()
+	require.NoError(t, checkDealtShare(cv, r.Bytes, deal.Public["laptop"]))
+	assert.Error(t, checkDealSharing(ac, deal, sets))
+}
+
+// TestReshareDropsParties reshares to a committee without one of the old
+// parties, which deals but gets no new share.
+func TestReshareDropsParties(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	names := []string{"server", "kms", "pin"}
+	oldAC := createThresholdAccessStructure(names, 2, cv)
+	messengers := mocknet.NewMockNetwork(len(names))
+	oldShares := make([][]byte, len(names))
+	var eg errgroup.Group
+	for i := range names {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(names), i, names)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			resp, err := EDDSAMPCThresholdDKG(job, &EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: oldAC})
+			if err != nil {
+				return err
+			}
+			defer resp.KeyShare.Free()
+			oldShares[i], err = resp.KeyShare.MarshalBinaryWithAccessStructure(oldAC, names)
+			return err
+		})
+	}
+	require.NoError(t, eg.Wait())
+
+	newAC := createThresholdAccessStructure([]string{"server", "kms"}, 2, cv)
+	messengers = mocknet.NewMockNetwork(len(names))
+	got := make([]EDDSAMPCKey, len(names))
+	for i := range names {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(names), i, names)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			got[i], err = Reshare(job, oldShares[i], newAC)
+			return err
+		})
+	}
+	require.NoError(t, eg.Wait())
+	defer got[0].Free()
+	defer got[1].Free()
+	assert.False(t, got[0].empty())
+	assert.False(t, got[1].empty())
+	assert.True(t, got[2].empty(), "pin leaves the committee")
+}
+
+func TestReshareValidation(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+	names := []string{"server", "kms", "pin"}
+	job, err := NewJobMP(mocknet.NewMockNetwork(3)[0], 3, 0, names)
+	require.NoError(t, err)
+	defer job.Free()
+
+	_, err = Reshare(nil, nil, createThresholdAccessStructure(names, 2, cv))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = Reshare(job, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = Reshare(job, nil, createThresholdAccessStructure([]string{"server", "phone"}, 2, cv))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	secp, err := curvepkg.NewSecp256k1()
+	require.NoError(t, err)
+	defer secp.Free()
+	_, err = Reshare(job, nil, createThresholdAccessStructure(names, 2, secp))
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
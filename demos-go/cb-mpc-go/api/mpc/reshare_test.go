@@ -0,0 +1,68 @@
+package mpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReshareDealers(t *testing.T) {
+	old := []string{"server", "kms", "pin"}
+	job := []string{"phone", "pin", "server", "laptop"}
+	assert.Equal(t, []string{"pin", "server"}, reshareDealers(old, job))
+	assert.Nil(t, reshareDealers(old, []string{"phone", "laptop"}))
+}
+
+func TestReshareTranscript(t *testing.T) {
+	deal := func(share string) *reshareDeal {
+		return &reshareDeal{Q: []byte("Q"), X: []byte("X"), Public: map[string][]byte{"a": []byte("A"), "b": []byte("B")}, Share: []byte(share)}
+	}
+	base, err := reshareTranscript(map[int]*reshareDeal{0: deal("s0"), 2: deal("s2")}, 3)
+	require.NoError(t, err)
+
+	// Each party receives a different share; the transcript ignores it.
+	other, err := reshareTranscript(map[int]*reshareDeal{0: deal("t0"), 2: deal("t2")}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, base, other)
+
+	moved, err := reshareTranscript(map[int]*reshareDeal{0: deal("s0"), 1: deal("s2")}, 3)
+	require.NoError(t, err)
+	assert.NotEqual(t, base, moved, "dealers are part of the transcript")
+
+	tampered := deal("s2")
+	tampered.Public["b"] = []byte("C")
+	changed, err := reshareTranscript(map[int]*reshareDeal{0: deal("s0"), 2: tampered}, 3)
+	require.NoError(t, err)
+	assert.NotEqual(t, base, changed)
+}
+
+func TestReshareCheckSets(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e"}
+	leaves := make([]*AccessNode, len(names))
+	for i, name := range names {
+		leaves[i] = Leaf(name)
+	}
+	sets, err := reshareCheckSets(Threshold("", 3, leaves...))
+	require.NoError(t, err)
+	// The first two parties and each of the others: n-K+1 sets.
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"a", "b", "d"}, {"a", "b", "e"}}, sets)
+
+	root := And("", Threshold("devices", 2, Leaf("phone"), Leaf("laptop"), Leaf("tablet")), Or("backup", Leaf("kms"), Leaf("paper")))
+	sets, err = reshareCheckSets(root)
+	require.NoError(t, err)
+	quorums, err := root.MinimalQuorums()
+	require.NoError(t, err)
+	covered := make(map[string]bool)
+	for _, set := range sets {
+		assert.Contains(t, quorums, set)
+		for _, name := range set {
+			covered[name] = true
+		}
+	}
+	assert.Len(t, covered, 5, "every leaf is checked")
+	assert.LessOrEqual(t, len(sets), 5)
+
+	_, err = reshareCheckSets(Threshold("", 3, Leaf("a"), Leaf("b")))
+	assert.Error(t, err)
+}
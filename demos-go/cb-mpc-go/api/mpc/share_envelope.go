@@ -0,0 +1,181 @@
+package mpc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+)
+
+// shareEnvelopeMagic starts every key share marshalled together with its
+// access structure.  Shares marshalled without one are bare gob streams, which
+// never start with this prefix.
+const shareEnvelopeMagic = "cb-mpc key share v2\x00"
+
+// shareEnvelopeDomain prefixes the envelope checksum so that it cannot be
+// confused with a hash of anything else.
+const shareEnvelopeDomain = "cb-mpc key share envelope v1\x00"
+
+var (
+	// ErrShareIntegrity is returned when a key share does not match its
+	// checksum, e.g. because the blob was truncated or corrupted, or when
+	// the public shares of the key do not reconstruct its public key under
+	// the embedded access structure.
+	ErrShareIntegrity = errors.New("mpc: key share integrity check failed")
+	// ErrNoAccessStructure is returned for key shares marshalled without an
+	// access structure.
+	ErrNoAccessStructure = errors.New("mpc: key share carries no access structure")
+)
+
+// shareEnvelope is the wire form of a key share marshalled together with the
+// access structure it was generated for.
+type shareEnvelope struct {
+	Share           []byte      `json:"share"` // Output of the legacy MarshalBinary
+	Curve           string      `json:"curve"`
+	AccessStructure *AccessNode `json:"access_structure"`
+	// PartyNames lists the parties of the key by their index in the job.
+	PartyNames []string `json:"party_names"`
+	// Checksum detects accidental corruption only.  It is unkeyed: whoever
+	// can edit the blob can recompute it, so it is no protection against
+	// tampering.
+	Checksum []byte `json:"checksum"`
+}
+
+// checksum hashes the share, the curve, the access structure and the party
+// indices together.
+func (e *shareEnvelope) checksum() ([]byte, error) {
+	body, err := json.Marshal(shareEnvelope{
+		Share:           e.Share,
+		Curve:           e.Curve,
+		AccessStructure: e.AccessStructure,
+		PartyNames:      e.PartyNames,
+	})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte(shareEnvelopeDomain))
+	h.Write(body)
+	return h.Sum(nil), nil
+}
+
+// check validates the access structure and the party names of the envelope.
+func (e *shareEnvelope) check() error {
+	if err := validatePartyNames(e.PartyNames); err != nil {
+		return err
+	}
+	if err := (&AccessStructure{Root: e.AccessStructure}).Validate(e.PartyNames); err != nil {
+		return err
+	}
+	cv, err := curveByName(e.Curve)
+	if err != nil {
+		return err
+	}
+	cv.Free()
+	return nil
+}
+
+// sealShare wraps share, the legacy encoding of a key share held by
+// partyName, together with its access structure.  partyNames lists every
+// party of the key by job index.
+func sealShare(share []byte, ac *AccessStructure, partyNames []string, curveName, partyName string) ([]byte, error) {
+	if ac == nil {
+		return nil, invalid("access structure", "must be provided")
+	}
+	if ac.Curve != nil && ac.Curve.String() != curveName {
+		return nil, invalid("access structure", "is on curve %s, the key is on %s", ac.Curve, curveName)
+	}
+	e := &shareEnvelope{Share: share, Curve: curveName, AccessStructure: ac.Root, PartyNames: partyNames}
+	if err := e.check(); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(partyNames, partyName) {
+		return nil, invalid("party names", "do not include %q, the owner of the key share", partyName)
+	}
+	var err error
+	if e.Checksum, err = e.checksum(); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(shareEnvelopeMagic), body...), nil
+}
+
+// openShare returns the legacy encoding of the key share in data and, if data
+// carries one, its envelope after checking its checksum and access structure.
+// Data without an envelope is returned as is with a nil envelope.  Whether
+// the key matches the access structure is checked once the share is
+// restored, see checkKey.
+func openShare(data []byte) ([]byte, *shareEnvelope, error) {
+	body, ok := bytes.CutPrefix(data, []byte(shareEnvelopeMagic))
+	if !ok {
+		return data, nil, nil
+	}
+	var e shareEnvelope
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrShareIntegrity, err)
+	}
+	want, err := e.checksum()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(want, e.Checksum) {
+		return nil, nil, ErrShareIntegrity
+	}
+	if err := e.check(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrShareIntegrity, err)
+	}
+	return e.Share, &e, nil
+}
+
+// ShareAccessStructure returns the access structure and the party names, by
+// job index, embedded in a key share marshalled with
+// MarshalBinaryWithAccessStructure.  The caller is responsible for freeing
+// the curve of the returned structure.  Shares marshalled with MarshalBinary
+// return ErrNoAccessStructure.
+func ShareAccessStructure(data []byte) (*AccessStructure, []string, error) {
+	_, e, err := openShare(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e == nil {
+		return nil, nil, ErrNoAccessStructure
+	}
+	cv, err := curveByName(e.Curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &AccessStructure{Root: e.AccessStructure, Curve: cv}, e.PartyNames, nil
+}
+
+// checkQuorum checks that every party of a quorum is a party of the key.
+func (e *shareEnvelope) checkQuorum(quorumPartyNames []string) error {
+	if len(quorumPartyNames) == 0 {
+		return invalid("quorum", "party names cannot be empty")
+	}
+	for _, name := range quorumPartyNames {
+		if !slices.Contains(e.PartyNames, name) {
+			return invalid("quorum", "party %q is not a party of the key", name)
+		}
+	}
+	return nil
+}
+
+// curveByName returns a new curve given the name its String method reports.
+func curveByName(name string) (curve.Curve, error) {
+	switch name {
+	case "secp256k1":
+		return curve.NewSecp256k1()
+	case "P-256":
+		return curve.NewP256()
+	case "Ed25519":
+		return curve.NewEd25519()
+	}
+	return nil, invalid("curve", "unknown curve %q", name)
+}
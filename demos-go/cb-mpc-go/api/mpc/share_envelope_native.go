@@ -0,0 +1,156 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
+)
+
+// MarshalBinaryWithAccessStructure serializes the key share together with the
+// access structure it was generated for and the names of its parties, by job
+// index, under a checksum.  It fails unless the public shares of the key
+// reconstruct its public key under ac.  UnmarshalBinary accepts the result,
+// and ECDSAMPCAdditiveShare converts it without the caller rebuilding the
+// structure.
+func (k ECDSAMPCKey) MarshalBinaryWithAccessStructure(ac *AccessStructure, partyNames []string) ([]byte, error) {
+	return marshalWithAccessStructure(k.cgobindingRef(), k.MarshalBinary, k.Curve, k.PartyName, ac, partyNames)
+}
+
+// MarshalBinaryWithAccessStructure serializes the key share together with the
+// access structure it was generated for and the names of its parties, by job
+// index, under a checksum.  It fails unless the public shares of the key
+// reconstruct its public key under ac.  UnmarshalBinary accepts the result,
+// and EDDSAMPCAdditiveShare converts it without the caller rebuilding the
+// structure.
+func (k EDDSAMPCKey) MarshalBinaryWithAccessStructure(ac *AccessStructure, partyNames []string) ([]byte, error) {
+	return marshalWithAccessStructure(k.cgobindingRef(), k.MarshalBinary, k.Curve, k.PartyName, ac, partyNames)
+}
+
+// ECDSAMPCAdditiveShare restores a key share marshalled with
+// MarshalBinaryWithAccessStructure and converts it into an additive share
+// for quorumPartyNames under the embedded access structure.  The caller is
+// responsible for freeing the returned share.
+func ECDSAMPCAdditiveShare(data []byte, quorumPartyNames []string) (ECDSAMPCKey, error) {
+	ac, err := sharedAccessStructure(data, quorumPartyNames)
+	if err != nil {
+		return ECDSAMPCKey{}, err
+	}
+	defer ac.Curve.Free()
+	var k ECDSAMPCKey
+	if err := k.UnmarshalBinary(data); err != nil {
+		return ECDSAMPCKey{}, err
+	}
+	defer k.Free()
+	return k.ToAdditiveShare(ac, quorumPartyNames)
+}
+
+// EDDSAMPCAdditiveShare restores a key share marshalled with
+// MarshalBinaryWithAccessStructure and converts it into an additive share
+// for quorumPartyNames under the embedded access structure.  The caller is
+// responsible for freeing the returned share.
+func EDDSAMPCAdditiveShare(data []byte, quorumPartyNames []string) (EDDSAMPCKey, error) {
+	ac, err := sharedAccessStructure(data, quorumPartyNames)
+	if err != nil {
+		return EDDSAMPCKey{}, err
+	}
+	defer ac.Curve.Free()
+	var k EDDSAMPCKey
+	if err := k.UnmarshalBinary(data); err != nil {
+		return EDDSAMPCKey{}, err
+	}
+	defer k.Free()
+	return k.ToAdditiveShare(ac, quorumPartyNames)
+}
+
+func marshalWithAccessStructure(
+	ref cgobinding.Mpc_eckey_mp_ref,
+	marshalFn func() ([]byte, error),
+	curveFn func() (curve.Curve, error),
+	partyNameFn func() (string, error),
+	ac *AccessStructure,
+	partyNames []string,
+) ([]byte, error) {
+	share, err := marshalFn()
+	if err != nil {
+		return nil, err
+	}
+	cv, err := curveFn()
+	if err != nil {
+		return nil, fmt.Errorf("reading curve: %v", err)
+	}
+	defer cv.Free()
+	name, err := partyNameFn()
+	if err != nil {
+		return nil, fmt.Errorf("reading party name: %v", err)
+	}
+	data, err := sealShare(share, ac, partyNames, cv.String(), name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReconstruction(ref, &AccessStructure{Root: ac.Root, Curve: cv}); err != nil {
+		return nil, invalid("access structure", "%v", err)
+	}
+	return data, nil
+}
+
+// checkReconstruction checks that the public shares of the key reconstruct
+// its public key under ac, i.e. that ac is the structure the key was
+// generated for.
+func checkReconstruction(ref cgobinding.Mpc_eckey_mp_ref, ac *AccessStructure) error {
+	if err := ref.CheckAccessStructure(ac.toCryptoAC()); err != nil {
+		return fmt.Errorf("public shares do not reconstruct the public key: %v", err)
+	}
+	return nil
+}
+
+// checkKey checks that a key share restored from the envelope is on its
+// curve, held by one of its parties and matches its access structure.  A nil
+// envelope passes.
+func (e *shareEnvelope) checkKey(ref cgobinding.Mpc_eckey_mp_ref, curveFn func() (curve.Curve, error), partyNameFn func() (string, error)) error {
+	if e == nil {
+		return nil
+	}
+	cv, err := curveFn()
+	if err != nil {
+		return fmt.Errorf("reading curve: %v", err)
+	}
+	defer cv.Free()
+	if cv.String() != e.Curve {
+		return fmt.Errorf("%w: key share is on %s, the access structure on %s", ErrShareIntegrity, cv, e.Curve)
+	}
+	name, err := partyNameFn()
+	if err != nil {
+		return fmt.Errorf("reading party name: %v", err)
+	}
+	if !slices.Contains(e.PartyNames, name) {
+		return fmt.Errorf("%w: key share of %q is not among the parties %v", ErrShareIntegrity, name, e.PartyNames)
+	}
+	if err := checkReconstruction(ref, &AccessStructure{Root: e.AccessStructure, Curve: cv}); err != nil {
+		return fmt.Errorf("%w: %v", ErrShareIntegrity, err)
+	}
+	return nil
+}
+
+// sharedAccessStructure returns the access structure embedded in data after
+// checking quorumPartyNames against the parties of the key.
+func sharedAccessStructure(data []byte, quorumPartyNames []string) (*AccessStructure, error) {
+	_, e, err := openShare(data)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, ErrNoAccessStructure
+	}
+	if err := e.checkQuorum(quorumPartyNames); err != nil {
+		return nil, err
+	}
+	cv, err := curveByName(e.Curve)
+	if err != nil {
+		return nil, err
+	}
+	return &AccessStructure{Root: e.AccessStructure, Curve: cv}, nil
+}
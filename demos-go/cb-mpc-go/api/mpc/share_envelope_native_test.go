@@ -0,0 +1,76 @@
+//go:build !nompc
+
+package mpc
+
+import (
+	"encoding/json"
+	"testing"
+
+	curvepkg "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+)
+
+// TestShareEnvelopeChecksKey checks that a share is sealed and restored only
+// under the access structure its public shares reconstruct the key for,
+// even when the checksum of an edited blob is recomputed.
+func TestShareEnvelopeChecksKey(t *testing.T) {
+	cv, err := curvepkg.NewEd25519()
+	require.NoError(t, err)
+	defer cv.Free()
+
+	pnames := []string{"server", "kms", "pin"}
+	ac := createThresholdAccessStructure(pnames, 2, cv)
+	messengers := mocknet.NewMockNetwork(len(pnames))
+	shares := make([]EDDSAMPCKey, len(pnames))
+	var eg errgroup.Group
+	for i := range pnames {
+		eg.Go(func() error {
+			job, err := NewJobMP(messengers[i], len(pnames), i, pnames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+			resp, err := EDDSAMPCThresholdDKG(job, &EDDSAMPCThresholdDKGRequest{Curve: cv, AccessStructure: ac})
+			if err != nil {
+				return err
+			}
+			shares[i] = resp.KeyShare
+			return nil
+		})
+	}
+	require.NoError(t, eg.Wait())
+	defer func() {
+		for i := range shares {
+			shares[i].Free()
+		}
+	}()
+
+	all := &AccessStructure{Root: And("", Leaf("server"), Leaf("kms"), Leaf("pin")), Curve: cv}
+	_, err = shares[1].MarshalBinaryWithAccessStructure(all, pnames)
+	assert.ErrorIs(t, err, ErrInvalidInput, "the key was not generated for 3-of-3")
+
+	data, err := shares[1].MarshalBinaryWithAccessStructure(ac, pnames)
+	require.NoError(t, err)
+	var restored EDDSAMPCKey
+	require.NoError(t, restored.UnmarshalBinary(data))
+	restored.Free()
+
+	// Lower the threshold and recompute the checksum.
+	_, e, err := openShare(data)
+	require.NoError(t, err)
+	e.AccessStructure = Threshold("", 1, Leaf("server"), Leaf("kms"), Leaf("pin"))
+	e.Checksum, err = e.checksum()
+	require.NoError(t, err)
+	body, err := json.Marshal(e)
+	require.NoError(t, err)
+	edited := append([]byte(shareEnvelopeMagic), body...)
+
+	_, _, err = openShare(edited)
+	require.NoError(t, err, "the checksum alone does not catch the edit")
+	assert.ErrorIs(t, restored.UnmarshalBinary(edited), ErrShareIntegrity)
+	_, err = EDDSAMPCAdditiveShare(edited, []string{"kms"})
+	assert.ErrorIs(t, err, ErrShareIntegrity)
+}
@@ -0,0 +1,80 @@
+package mpc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sealedShare(t *testing.T) []byte {
+	t.Helper()
+	ac := &AccessStructure{Root: Threshold("", 2, Leaf("server"), Leaf("kms"), Leaf("pin"))}
+	data, err := sealShare([]byte("share"), ac, []string{"server", "kms", "pin"}, "Ed25519", "kms")
+	require.NoError(t, err)
+	return data
+}
+
+func TestShareEnvelopeRoundTrip(t *testing.T) {
+	data := sealedShare(t)
+	share, e, err := openShare(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("share"), share)
+	assert.Equal(t, []string{"server", "kms", "pin"}, e.PartyNames)
+
+	ac, names, err := ShareAccessStructure(data)
+	require.NoError(t, err)
+	defer ac.Curve.Free()
+	assert.Equal(t, "Ed25519", ac.Curve.String())
+	assert.Equal(t, []string{"server", "kms", "pin"}, names)
+	quorums, err := ac.MinimalQuorums()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"kms", "pin"}, {"kms", "server"}, {"pin", "server"}}, quorums)
+
+	assert.NoError(t, e.checkQuorum([]string{"server", "pin"}))
+	assert.ErrorIs(t, e.checkQuorum([]string{"server", "hsm"}), ErrInvalidInput)
+}
+
+func TestShareEnvelopeLegacy(t *testing.T) {
+	share, e, err := openShare([]byte("gob"))
+	require.NoError(t, err)
+	assert.Nil(t, e)
+	assert.Equal(t, []byte("gob"), share)
+
+	_, _, err = ShareAccessStructure([]byte("gob"))
+	assert.ErrorIs(t, err, ErrNoAccessStructure)
+}
+
+// TestShareEnvelopeCorrupted checks that the checksum catches edits that do
+// not recompute it.
+func TestShareEnvelopeCorrupted(t *testing.T) {
+	for name, edit := range map[string]func([]byte) []byte{
+		"threshold": func(b []byte) []byte { return bytes.Replace(b, []byte(`"k":2`), []byte(`"k":1`), 1) },
+		"indices": func(b []byte) []byte {
+			return bytes.Replace(b, []byte(`["server","kms"`), []byte(`["kms","server"`), 1)
+		},
+		"curve":     func(b []byte) []byte { return bytes.Replace(b, []byte(`Ed25519`), []byte(`P-256`), 1) },
+		"truncated": func(b []byte) []byte { return b[:len(b)-2] },
+	} {
+		t.Run(name, func(t *testing.T) {
+			data := sealedShare(t)
+			edited := edit(bytes.Clone(data))
+			require.NotEqual(t, data, edited)
+			_, _, err := openShare(edited)
+			assert.ErrorIs(t, err, ErrShareIntegrity)
+		})
+	}
+}
+
+func TestSealShareRejectsMismatches(t *testing.T) {
+	ac := &AccessStructure{Root: Threshold("", 2, Leaf("server"), Leaf("kms"), Leaf("pin"))}
+	_, err := sealShare([]byte("share"), ac, []string{"server", "kms"}, "Ed25519", "kms")
+	assert.ErrorIs(t, err, ErrInvalidInput, "leaf pin is not a party")
+	_, err = sealShare([]byte("share"), ac, []string{"server", "kms", "pin"}, "Ed25519", "hsm")
+	assert.ErrorIs(t, err, ErrInvalidInput, "the owner is not a party")
+	_, err = sealShare([]byte("share"), nil, []string{"server"}, "Ed25519", "server")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = sealShare([]byte("share"), ac, []string{"server", "kms", "pin"}, "Curve448", "kms")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,65 @@
+package mpc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// Formats reported by InspectShare.
+const (
+	ShareFormatEncrypted = "encrypted" // EncryptedKeyShare
+	ShareFormatEnvelope  = "envelope"  // MarshalBinaryWithAccessStructure
+	ShareFormatLegacy    = "legacy"    // MarshalBinary
+)
+
+// ShareInfo is the non-secret metadata of a stored key share.
+type ShareInfo struct {
+	Format string `json:"format"`
+	// KDF, KEKID and Label describe an encrypted share; the share inside is
+	// not decrypted, so nothing else is known about it.
+	KDF   string `json:"kdf,omitempty"`
+	KEKID string `json:"kek_id,omitempty"`
+	Label string `json:"label,omitempty"`
+	// Curve, PartyNames and AccessStructure describe the key of a share
+	// with an envelope, after its checksum has been checked.
+	Curve           string      `json:"curve,omitempty"`
+	PartyNames      []string    `json:"party_names,omitempty"`
+	AccessStructure *AccessNode `json:"access_structure,omitempty"`
+}
+
+// InspectShare reports the metadata of data, a key share marshalled with
+// MarshalBinary or MarshalBinaryWithAccessStructure or a JSON-encoded
+// EncryptedKeyShare, without decrypting or unmarshalling the secret share.
+// It needs no native code.
+//
+// A legacy share carries no metadata that can be read without the native
+// library; InspectShare only checks that it is well-formed.
+func InspectShare(data []byte) (*ShareInfo, error) {
+	if bytes.HasPrefix(data, []byte(shareEnvelopeMagic)) {
+		_, e, err := openShare(data)
+		if err != nil {
+			return nil, err
+		}
+		return &ShareInfo{Format: ShareFormatEnvelope, Curve: e.Curve, PartyNames: e.PartyNames, AccessStructure: e.AccessStructure}, nil
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var e EncryptedKeyShare
+		if err := json.Unmarshal(trimmed, &e); err != nil {
+			return nil, fmt.Errorf("decoding encrypted key share: %v", err)
+		}
+		if e.Version != encryptedShareVersion || e.KDF == "" || len(e.Ciphertext) == 0 {
+			return nil, fmt.Errorf("not an encrypted key share of version %d", encryptedShareVersion)
+		}
+		return &ShareInfo{Format: ShareFormatEncrypted, KDF: e.KDF, KEKID: e.KEKID, Label: e.Label}, nil
+	}
+	var parts [][]byte
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&parts); err != nil {
+		return nil, fmt.Errorf("not a key share: %v", err)
+	}
+	for _, p := range parts {
+		clear(p)
+	}
+	return &ShareInfo{Format: ShareFormatLegacy}, nil
+}
@@ -0,0 +1,42 @@
+package mpc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectShare(t *testing.T) {
+	info, err := InspectShare(sealedShare(t))
+	require.NoError(t, err)
+	assert.Equal(t, ShareFormatEnvelope, info.Format)
+	assert.Equal(t, "Ed25519", info.Curve)
+	assert.Equal(t, []string{"server", "kms", "pin"}, info.PartyNames)
+	assert.Equal(t, "THRESHOLD (2/3)\n  LEAF server\n  LEAF kms\n  LEAF pin\n", info.AccessStructure.String())
+
+	e := EncryptedKeyShare{Label: "kms"}
+	require.NoError(t, e.Seal(sealedShare(t), KEK("backup-2026", make([]byte, 32))))
+	data, err := json.Marshal(&e)
+	require.NoError(t, err)
+	info, err = InspectShare(data)
+	require.NoError(t, err)
+	assert.Equal(t, &ShareInfo{Format: ShareFormatEncrypted, KDF: KDFNone, KEKID: "backup-2026", Label: "kms"}, info)
+
+	var legacy bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&legacy).Encode([][]byte{[]byte("x"), []byte("Q")}))
+	info, err = InspectShare(legacy.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, &ShareInfo{Format: ShareFormatLegacy}, info)
+
+	tampered := bytes.Replace(sealedShare(t), []byte(`"k":2`), []byte(`"k":1`), 1)
+	_, err = InspectShare(tampered)
+	assert.ErrorIs(t, err, ErrShareIntegrity)
+	_, err = InspectShare([]byte(`{"version":1}`))
+	assert.Error(t, err)
+	_, err = InspectShare([]byte("random bytes"))
+	assert.Error(t, err)
+}
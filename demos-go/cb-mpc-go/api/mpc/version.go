@@ -0,0 +1,95 @@
+package mpc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WrapperVersion is the libcbmpc release this package was written against.
+// It must match the project version in the top-level CMakeLists.txt.
+const WrapperVersion = "0.1.0"
+
+// ErrVersionMismatch is wrapped by the error CheckVersion returns when the
+// linked native library is incompatible with this package.
+var ErrVersionMismatch = errors.New("mpc: native library version mismatch")
+
+// Versions are the versions of the Go wrapper and the linked native library.
+type Versions struct {
+	Wrapper string // WrapperVersion
+	Native  string // Reported by libcbmpc; empty in nompc builds
+}
+
+// VersionError describes a native library the wrapper cannot work with.
+type VersionError struct {
+	Versions
+	Reason string
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("native libcbmpc %s is incompatible with the Go wrapper, which expects %s: %s; "+
+		"upgrade the library and the binaries built against it together", e.Native, e.Wrapper, e.Reason)
+}
+
+// Unwrap returns ErrVersionMismatch.
+func (e *VersionError) Unwrap() error { return ErrVersionMismatch }
+
+// versionErr is the outcome of the check, made once at package
+// initialization.
+var versionErr = checkVersions(Version())
+
+// Version returns the versions of the Go wrapper and the linked native
+// library.
+func Version() Versions {
+	return Versions{Wrapper: WrapperVersion, Native: nativeVersion()}
+}
+
+// CheckVersion reports whether the linked native library speaks the same
+// protocols as this package.  It returns a *VersionError, which wraps
+// ErrVersionMismatch, when it does not.  The check is made once when the
+// package is initialized; NewJob2P and NewJobMP return its error, so no
+// protocol runs against an incompatible library.
+//
+// Releases are compatible when their major versions match and, before 1.0,
+// their minor versions too; patch releases never change the protocols.
+func CheckVersion() error {
+	return versionErr
+}
+
+func checkVersions(v Versions) error {
+	if v.Native == "" {
+		return nil
+	}
+	want, err := parseVersion(v.Wrapper)
+	if err != nil {
+		return &VersionError{Versions: v, Reason: err.Error()}
+	}
+	got, err := parseVersion(v.Native)
+	switch {
+	case err != nil:
+		return &VersionError{Versions: v, Reason: err.Error()}
+	case got[0] != want[0]:
+		return &VersionError{Versions: v, Reason: "major versions differ"}
+	case got[0] == 0 && got[1] != want[1]:
+		return &VersionError{Versions: v, Reason: "minor versions of a pre-1.0 release differ"}
+	}
+	return nil
+}
+
+// parseVersion parses "major.minor.patch".
+func parseVersion(s string) ([3]int, error) {
+	var v [3]int
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("malformed version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("malformed version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
@@ -0,0 +1,7 @@
+//go:build !nompc
+
+package mpc
+
+import "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/cgobinding"
+
+func nativeVersion() string { return cgobinding.NativeVersion() }
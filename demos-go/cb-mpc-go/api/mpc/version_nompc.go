@@ -0,0 +1,6 @@
+//go:build nompc
+
+package mpc
+
+// nativeVersion is empty: nompc builds link no native library.
+func nativeVersion() string { return "" }
@@ -0,0 +1,51 @@
+package mpc
+
+import (
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVersions(t *testing.T) {
+	for _, tc := range []struct {
+		wrapper, native string
+		ok              bool
+	}{
+		{"0.1.0", "0.1.0", true},
+		{"0.1.0", "0.1.7", true},
+		{"0.1.0", "0.2.0", false},
+		{"1.2.0", "1.4.1", true},
+		{"1.2.0", "2.0.0", false},
+		{"0.1.0", "0.1", false},
+		{"0.1.0", "v0.1.0", false},
+		{"0.1.0", "", true}, // nompc
+	} {
+		err := checkVersions(Versions{Wrapper: tc.wrapper, Native: tc.native})
+		if tc.ok {
+			assert.NoError(t, err, "%s with %s", tc.wrapper, tc.native)
+			continue
+		}
+		assert.ErrorIs(t, err, ErrVersionMismatch, "%s with %s", tc.wrapper, tc.native)
+		var vErr *VersionError
+		require.ErrorAs(t, err, &vErr)
+		assert.Equal(t, tc.native, vErr.Native)
+		assert.Contains(t, err.Error(), tc.wrapper)
+	}
+}
+
+// The wrapper version must follow the native library's project version.
+func TestWrapperVersionMatchesCMake(t *testing.T) {
+	data, err := os.ReadFile("../../../../CMakeLists.txt")
+	if os.IsNotExist(err) {
+		t.Skip("CMakeLists.txt not found")
+	}
+	require.NoError(t, err)
+	m := regexp.MustCompile(`project\(\s*CBMPC\s+VERSION\s+(\S+)`).FindSubmatch(data)
+	require.NotNil(t, m)
+	assert.Equal(t, string(m[1]), WrapperVersion)
+	assert.NoError(t, CheckVersion())
+	assert.Equal(t, WrapperVersion, Version().Wrapper)
+}
@@ -0,0 +1,30 @@
+// Package adversary implements malicious parties for tests and is intended
+// ONLY for testing.
+//
+// A `Messenger` wraps the Messenger of one party and rewrites what it sends
+// according to a list of `Behavior`s, while receiving normally:
+//
+//   - Corrupt – send a modified message in a round, e.g. a wrong commitment;
+//   - Equivocate – send a modified message to some parties only;
+//   - Stall – stop sending from a round on;
+//   - Replay – send the message of an earlier round again.
+//
+// Rounds are counted per link like in the echo package: the n-th message a
+// party sends to a peer belongs to round n.
+//
+// Where the wrapper sits in the party's stack decides what the honest
+// parties see.  Above the echo and liveness wrappers it misbehaves like a
+// compromised process that still holds the party's keys, so equivocation is
+// proven by the party's own signatures and a stall is a round timeout.
+// Below them it tampers with frames on the wire, so a corrupted or replayed
+// frame fails signature or round checks:
+//
+//	own, _ := echo.NewMessenger(net[0], config0) // party 0's usual stack
+//	evil := adversary.New(own, adversary.Equivocate(1, 2))
+//	// run party 0's side of the protocol over evil, the others normally
+//	_, err := honest[1].MessageReceive(ctx, 0) // *echo.EquivocationError{Sender: 0}
+//
+// A party whose messages do not reach the honest parties at all is found by
+// liveness; one whose messages arrive intact but carry wrong values is found
+// only by the protocol itself.
+package adversary
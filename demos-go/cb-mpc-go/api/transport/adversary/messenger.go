@@ -0,0 +1,129 @@
+package adversary
+
+import (
+	"context"
+	"sync"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// Behavior decides what a malicious party sends.  It is called for every
+// outgoing message with the receiver, the 1-based round, the message and the
+// messages actually sent to the receiver in earlier rounds, and returns the
+// message to send instead, or nil to send nothing.
+type Behavior func(receiver, round int, msg []byte, previous [][]byte) []byte
+
+// Corrupt sends every receiver a modified message in round, e.g. a wrong
+// commitment.  The message is modified by flipping the lowest bit of its
+// last byte.
+func Corrupt(round int) Behavior {
+	return func(_, r int, msg []byte, _ [][]byte) []byte {
+		if r != round {
+			return msg
+		}
+		return flip(msg)
+	}
+}
+
+// Equivocate sends victims a modified message in round and everyone else
+// the original, like Corrupt.
+func Equivocate(round int, victims ...int) Behavior {
+	return func(receiver, r int, msg []byte, _ [][]byte) []byte {
+		if r != round {
+			return msg
+		}
+		for _, v := range victims {
+			if v == receiver {
+				return flip(msg)
+			}
+		}
+		return msg
+	}
+}
+
+// Stall sends nothing from round on.
+func Stall(round int) Behavior {
+	return func(_, r int, msg []byte, _ [][]byte) []byte {
+		if r >= round {
+			return nil
+		}
+		return msg
+	}
+}
+
+// Replay sends the message of round from again in round instead of the
+// real one.
+func Replay(round, from int) Behavior {
+	return func(_, r int, msg []byte, previous [][]byte) []byte {
+		if r != round || from < 1 || from > len(previous) {
+			return msg
+		}
+		return previous[from-1]
+	}
+}
+
+func flip(msg []byte) []byte {
+	out := append([]byte(nil), msg...)
+	if len(out) == 0 {
+		return []byte{1}
+	}
+	out[len(out)-1] ^= 1
+	return out
+}
+
+// Messenger implements transport.Messenger on top of another Messenger,
+// applying behaviors to every message sent.
+type Messenger struct {
+	inner     transport.Messenger
+	behaviors []Behavior
+
+	mu   sync.Mutex
+	sent map[int][][]byte // Messages sent, by receiver
+	seen map[int]int      // Messages passed to MessageSend, by receiver
+}
+
+// Ensure Messenger implements the Messenger interface
+var _ transport.Messenger = (*Messenger)(nil)
+
+// New wraps inner.  Behaviors are applied in order, each to the output of
+// the previous one; once one returns nil the message is dropped.
+func New(inner transport.Messenger, behaviors ...Behavior) *Messenger {
+	return &Messenger{
+		inner:     inner,
+		behaviors: behaviors,
+		sent:      make(map[int][][]byte),
+		seen:      make(map[int]int),
+	}
+}
+
+// MessageSend sends the message the behaviors make of buffer to receiver,
+// if any.  A dropped message is reported as sent.
+func (m *Messenger) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	// Sends are serialised so that rounds reach the receiver in order.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seen[receiver]++
+	round := m.seen[receiver]
+	previous := m.sent[receiver]
+	msg := buffer
+	for _, b := range m.behaviors {
+		if msg = b(receiver, round, msg, previous); msg == nil {
+			break
+		}
+	}
+	if msg == nil {
+		return nil
+	}
+	m.sent[receiver] = append(previous, append([]byte(nil), msg...))
+	return m.inner.MessageSend(ctx, receiver, msg)
+}
+
+// MessageReceive receives from the inner Messenger unchanged.
+func (m *Messenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	return m.inner.MessageReceive(ctx, sender)
+}
+
+// MessagesReceive receives from the inner Messenger unchanged.
+func (m *Messenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	return m.inner.MessagesReceive(ctx, senders)
+}
@@ -0,0 +1,232 @@
+package adversary
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/echo"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/liveness"
+)
+
+// hub is a context-aware in-memory network; hub[i][j] carries i -> j.
+type hub map[int]map[int]chan []byte
+
+type endpoint struct {
+	self int
+	hub  hub
+}
+
+func newHub(n int) hub {
+	h := make(hub)
+	for i := 0; i < n; i++ {
+		h[i] = make(map[int]chan []byte)
+		for j := 0; j < n; j++ {
+			if i != j {
+				h[i][j] = make(chan []byte, 64)
+			}
+		}
+	}
+	return h
+}
+
+func (e *endpoint) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	e.hub[e.self][receiver] <- append([]byte(nil), buffer...)
+	return nil
+}
+
+func (e *endpoint) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	select {
+	case msg := <-e.hub[sender][e.self]:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (e *endpoint) MessagesReceive(context.Context, []int) ([][]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func peers(self, n int) []int {
+	var out []int
+	for j := 0; j < n; j++ {
+		if j != self {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// signedEcho wraps the raw links of n parties with signed echo broadcast.
+// wrap, if set, may replace a party's raw link, e.g. with an adversary.
+func signedEcho(t *testing.T, n int, wrap func(self int, raw transport.Messenger) transport.Messenger) []*echo.Messenger {
+	t.Helper()
+	keys := make([]ed25519.PrivateKey, n)
+	for i := range keys {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+	}
+	verify := func(party int, statement, sig []byte) error {
+		if !ed25519.Verify(keys[party].Public().(ed25519.PublicKey), statement, sig) {
+			return fmt.Errorf("bad signature")
+		}
+		return nil
+	}
+	h := newHub(n)
+	out := make([]*echo.Messenger, n)
+	for i := 0; i < n; i++ {
+		key := keys[i]
+		var raw transport.Messenger = &endpoint{self: i, hub: h}
+		if wrap != nil {
+			raw = wrap(i, raw)
+		}
+		m, err := echo.NewMessenger(raw, echo.Config{
+			Self:    i,
+			Peers:   peers(i, n),
+			Session: []byte("session-1"),
+			Sign:    func(statement []byte) ([]byte, error) { return ed25519.Sign(key, statement), nil },
+			Verify:  verify,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { m.Close() })
+		out[i] = m
+	}
+	return out
+}
+
+func withTimeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// recorder keeps what was sent to each receiver.
+type recorder map[int][]string
+
+func (r recorder) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	r[receiver] = append(r[receiver], string(buffer))
+	return nil
+}
+
+func (r recorder) MessageReceive(context.Context, int) ([]byte, error) { return nil, nil }
+
+func (r recorder) MessagesReceive(context.Context, []int) ([][]byte, error) { return nil, nil }
+
+func TestBehaviors(t *testing.T) {
+	ctx := context.Background()
+	send := func(behaviors ...Behavior) recorder {
+		r := recorder{}
+		m := New(r, behaviors...)
+		for _, msg := range []string{"a", "b", "c"} {
+			for _, to := range []int{1, 2} {
+				require.NoError(t, m.MessageSend(ctx, to, []byte(msg)))
+			}
+		}
+		return r
+	}
+
+	assert.Equal(t, recorder{1: {"a", "b", "c"}, 2: {"a", "b", "c"}}, send())
+	assert.Equal(t, recorder{1: {"a", "c", "c"}, 2: {"a", "c", "c"}}, send(Corrupt(2)))
+	assert.Equal(t, recorder{1: {"a", "b", "c"}, 2: {"a", "c", "c"}}, send(Equivocate(2, 2)))
+	assert.Equal(t, recorder{1: {"a"}, 2: {"a"}}, send(Stall(2)))
+	assert.Equal(t, recorder{1: {"a", "b", "a"}, 2: {"a", "b", "a"}}, send(Replay(3, 1)))
+	// Behaviors compose: the replayed message is the corrupted one.
+	assert.Equal(t, recorder{1: {"`", "b", "`"}, 2: {"`", "b", "`"}}, send(Corrupt(1), Replay(3, 1)))
+}
+
+func TestEquivocationIsProven(t *testing.T) {
+	ms := signedEcho(t, 3, nil)
+	evil := New(ms[0], Equivocate(1, 2))
+	ctx := withTimeout(t)
+	for _, to := range []int{1, 2} {
+		require.NoError(t, evil.MessageSend(ctx, to, []byte("commitment")))
+	}
+
+	for _, honest := range []int{1, 2} {
+		_, err := ms[honest].MessageReceive(ctx, 0)
+		var eq *echo.EquivocationError
+		require.ErrorAs(t, err, &eq, "party %d", honest)
+		assert.Equal(t, 0, eq.Sender)
+		assert.Equal(t, 1, eq.Round)
+		assert.Len(t, eq.Proof, 2, "signed by the equivocating party itself")
+	}
+}
+
+func TestTamperedFramesAreAttributed(t *testing.T) {
+	for name, tc := range map[string]struct {
+		behavior Behavior
+		check    func(t *testing.T, err error)
+	}{
+		"wrong commitment": {Corrupt(1), func(t *testing.T, err error) {
+			var se *echo.SignatureError
+			require.ErrorAs(t, err, &se)
+			assert.Equal(t, 0, se.Party)
+			assert.Equal(t, 1, se.Round)
+		}},
+		"replay": {Replay(2, 1), func(t *testing.T, err error) {
+			var re *echo.ReplayError
+			require.ErrorAs(t, err, &re)
+			assert.Equal(t, echo.ReplayError{Party: 0, Round: 1, Expected: 2}, *re)
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// With two parties there are no echo frames, so rounds on the
+			// wire are protocol rounds.
+			ms := signedEcho(t, 2, func(self int, raw transport.Messenger) transport.Messenger {
+				if self == 0 {
+					return New(raw, tc.behavior)
+				}
+				return raw
+			})
+			ctx := withTimeout(t)
+			require.NoError(t, ms[0].MessageSend(ctx, 1, []byte("round 1")))
+			require.NoError(t, ms[0].MessageSend(ctx, 1, []byte("round 2")))
+
+			msg, err := ms[1].MessageReceive(ctx, 0)
+			if err == nil {
+				assert.Equal(t, []byte("round 1"), msg)
+				_, err = ms[1].MessageReceive(ctx, 0)
+			}
+			tc.check(t, err)
+		})
+	}
+}
+
+func TestStallIsAttributed(t *testing.T) {
+	h := newHub(2)
+	ms := make([]*liveness.Messenger, 2)
+	for i := range ms {
+		m, err := liveness.NewMessenger(&endpoint{self: i, hub: h}, liveness.Config{
+			Peers:             peers(i, 2),
+			RoundTimeout:      100 * time.Millisecond,
+			HeartbeatInterval: 10 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { m.Close() })
+		ms[i] = m
+	}
+	evil := New(ms[0], Stall(2))
+	ctx := withTimeout(t)
+	require.NoError(t, evil.MessageSend(ctx, 1, []byte("round 1")))
+	require.NoError(t, evil.MessageSend(ctx, 1, []byte("round 2")))
+
+	msg, err := ms[1].MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("round 1"), msg)
+	_, err = ms[1].MessageReceive(ctx, 0)
+	var se *liveness.StallError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, 0, se.Party)
+	assert.Equal(t, 2, se.Round)
+	assert.False(t, se.Heartbeat, "the process is alive, only the protocol stalled")
+}
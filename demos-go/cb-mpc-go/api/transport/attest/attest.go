@@ -0,0 +1,217 @@
+package attest
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// StatementType is the in-toto statement type produced by NewStatement.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType identifies the build predicate produced by NewStatement.
+	PredicateType = "https://github.com/coinbase/cb-mpc/attest/build/v1"
+	// PayloadType is the DSSE payload type of signed statements.
+	PayloadType = "application/vnd.in-toto+json"
+)
+
+// ErrUntrusted is wrapped by every verification failure.
+var ErrUntrusted = errors.New("attest: attestation not trusted")
+
+// Subject names an attested artifact.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// BuildPredicate describes how and when an artifact was built.
+type BuildPredicate struct {
+	Builder  string    `json:"builder"`
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
+// Statement is an in-toto statement about one or more artifacts.
+type Statement struct {
+	Type          string         `json:"_type"`
+	Subject       []Subject      `json:"subject"`
+	PredicateType string         `json:"predicateType"`
+	Predicate     BuildPredicate `json:"predicate"`
+}
+
+// NewStatement returns a statement that the artifact name with the given
+// SHA-256 digest was produced by builder.
+func NewStatement(name string, sha256Digest []byte, builder string) *Statement {
+	return &Statement{
+		Type:          StatementType,
+		Subject:       []Subject{{Name: name, Digest: map[string]string{"sha256": hex.EncodeToString(sha256Digest)}}},
+		PredicateType: PredicateType,
+		Predicate:     BuildPredicate{Builder: builder, IssuedAt: time.Now().UTC()},
+	}
+}
+
+// Signature is a single DSSE signature.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   []byte `json:"sig"`
+}
+
+// Envelope is a DSSE envelope.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// pae computes the DSSE pre-authentication encoding.
+func pae(payloadType string, payload []byte) []byte {
+	out := []byte("DSSEv1 " + strconv.Itoa(len(payloadType)) + " " + payloadType + " " + strconv.Itoa(len(payload)) + " ")
+	return append(out, payload...)
+}
+
+// Sign wraps the statement in a DSSE envelope signed with key.
+func Sign(statement *Statement, keyID string, key ed25519.PrivateKey) ([]byte, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 private key")
+	}
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("encoding statement: %v", err)
+	}
+	env := Envelope{
+		PayloadType: PayloadType,
+		Payload:     payload,
+		Signatures:  []Signature{{KeyID: keyID, Sig: ed25519.Sign(key, pae(PayloadType, payload))}},
+	}
+	return json.Marshal(env)
+}
+
+// Verifier checks attestation envelopes.
+type Verifier struct {
+	// Keys maps key IDs to the release keys trusted to sign attestations.
+	Keys map[string]ed25519.PublicKey
+	// Name, if set, is the artifact name the statement must cover.
+	Name string
+	// Digests, if non-empty, is an allow-list of hex SHA-256 digests.
+	Digests []string
+	// MaxAge, if positive, rejects statements issued longer ago.
+	MaxAge time.Duration
+}
+
+// Verify checks the envelope and returns the attested subject.
+func (v *Verifier) Verify(envelope []byte) (*Subject, error) {
+	var env Envelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return nil, fmt.Errorf("%w: malformed envelope: %v", ErrUntrusted, err)
+	}
+	if env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("%w: unexpected payload type %q", ErrUntrusted, env.PayloadType)
+	}
+	if !v.signedByTrustedKey(&env) {
+		return nil, fmt.Errorf("%w: no valid signature by a trusted key", ErrUntrusted)
+	}
+
+	var st Statement
+	if err := json.Unmarshal(env.Payload, &st); err != nil {
+		return nil, fmt.Errorf("%w: malformed statement: %v", ErrUntrusted, err)
+	}
+	if st.Type != StatementType || st.PredicateType != PredicateType {
+		return nil, fmt.Errorf("%w: unsupported statement %s/%s", ErrUntrusted, st.Type, st.PredicateType)
+	}
+	if v.MaxAge > 0 && time.Since(st.Predicate.IssuedAt) > v.MaxAge {
+		return nil, fmt.Errorf("%w: statement issued at %s is older than %v", ErrUntrusted, st.Predicate.IssuedAt, v.MaxAge)
+	}
+	for i := range st.Subject {
+		if s := &st.Subject[i]; v.accepts(s) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no acceptable subject", ErrUntrusted)
+}
+
+func (v *Verifier) signedByTrustedKey(env *Envelope) bool {
+	msg := pae(env.PayloadType, env.Payload)
+	for _, sig := range env.Signatures {
+		if key, ok := v.Keys[sig.KeyID]; ok && len(key) == ed25519.PublicKeySize && ed25519.Verify(key, msg, sig.Sig) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) accepts(s *Subject) bool {
+	if v.Name != "" && s.Name != v.Name {
+		return false
+	}
+	digest, ok := s.Digest["sha256"]
+	if !ok {
+		return false
+	}
+	if len(v.Digests) == 0 {
+		return true
+	}
+	for _, d := range v.Digests {
+		if d == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// Hook returns a handshake hook that verifies the attestation of each of the
+// given peers and ignores all other peers.  It matches the signature of
+// mtls.Config.VerifyAttestation.
+func (v *Verifier) Hook(peers ...int) func(peer int, state tls.ConnectionState, attestation []byte) error {
+	checked := make(map[int]bool, len(peers))
+	for _, p := range peers {
+		checked[p] = true
+	}
+	return func(peer int, _ tls.ConnectionState, attestation []byte) error {
+		if !checked[peer] {
+			return nil
+		}
+		if _, err := v.Verify(attestation); err != nil {
+			return fmt.Errorf("party %d: %w", peer, err)
+		}
+		return nil
+	}
+}
+
+// Present returns a handshake hook that presents the same envelope to every
+// peer.  It matches the signature of mtls.Config.Attest.
+func Present(envelope []byte) func(peer int, state tls.ConnectionState) ([]byte, error) {
+	return func(int, tls.ConnectionState) ([]byte, error) {
+		return envelope, nil
+	}
+}
+
+// FileDigest returns the SHA-256 digest of the file at path.
+func FileDigest(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// ExecutableDigest returns the SHA-256 digest of the running binary, so a
+// coordinator can check at start-up that its attestation covers itself.
+func ExecutableDigest() ([]byte, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return FileDigest(path)
+}
@@ -0,0 +1,86 @@
+package attest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("coordinator binary"))
+
+	env, err := Sign(NewStatement("coordinator", digest[:], "ci"), "release", priv)
+	require.NoError(t, err)
+
+	v := &Verifier{
+		Keys:    map[string]ed25519.PublicKey{"release": pub},
+		Name:    "coordinator",
+		Digests: []string{hex.EncodeToString(digest[:])},
+		MaxAge:  time.Hour,
+	}
+	subject, err := v.Verify(env)
+	require.NoError(t, err)
+	assert.Equal(t, "coordinator", subject.Name)
+
+	// Unknown digest.
+	other := *v
+	other.Digests = []string{"00"}
+	_, err = other.Verify(env)
+	assert.ErrorIs(t, err, ErrUntrusted)
+
+	// Untrusted key.
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	other = *v
+	other.Keys = map[string]ed25519.PublicKey{"release": otherPub}
+	_, err = other.Verify(env)
+	assert.ErrorIs(t, err, ErrUntrusted)
+
+	// Tampered payload.
+	var e Envelope
+	require.NoError(t, json.Unmarshal(env, &e))
+	e.Payload[len(e.Payload)-2] ^= 1
+	tampered, err := json.Marshal(e)
+	require.NoError(t, err)
+	_, err = v.Verify(tampered)
+	assert.ErrorIs(t, err, ErrUntrusted)
+}
+
+func TestVerifyRejectsStaleStatement(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	st := NewStatement("coordinator", make([]byte, 32), "ci")
+	st.Predicate.IssuedAt = time.Now().Add(-48 * time.Hour)
+	env, err := Sign(st, "release", priv)
+	require.NoError(t, err)
+
+	v := &Verifier{Keys: map[string]ed25519.PublicKey{"release": pub}, MaxAge: 24 * time.Hour}
+	_, err = v.Verify(env)
+	assert.ErrorIs(t, err, ErrUntrusted)
+}
+
+func TestHook(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	env, err := Sign(NewStatement("coordinator", make([]byte, 32), "ci"), "release", priv)
+	require.NoError(t, err)
+
+	hook := (&Verifier{Keys: map[string]ed25519.PublicKey{"release": pub}}).Hook(0)
+	assert.NoError(t, hook(0, tls.ConnectionState{}, env))
+	assert.ErrorIs(t, hook(0, tls.ConnectionState{}, nil), ErrUntrusted)
+	assert.NoError(t, hook(1, tls.ConnectionState{}, nil), "other peers are not checked")
+
+	presented, err := Present(env)(2, tls.ConnectionState{})
+	require.NoError(t, err)
+	assert.Equal(t, env, presented)
+}
@@ -0,0 +1,25 @@
+// Package attest lets MPC parties verify which build of the coordinator they
+// are talking to before accepting sessions from it.
+//
+// A release pipeline signs an in-toto statement naming the coordinator binary
+// and its SHA-256 digest, wrapped in a DSSE envelope with an Ed25519 key:
+//
+//	st := attest.NewStatement("coordinator", digest, "https://ci.example.com")
+//	env, _ := attest.Sign(st, "release-2025", releaseKey)
+//
+// The coordinator presents the envelope during the transport handshake and
+// every party verifies it against the release keys it trusts and, optionally,
+// an allow-list of digests:
+//
+//	verifier := &attest.Verifier{Keys: map[string]ed25519.PublicKey{"release-2025": pub}}
+//	cfg := mtls.Config{
+//	    // …
+//	    Attest:            attest.Present(env),          // on the coordinator
+//	    VerifyAttestation: verifier.Hook(coordinatorIdx), // on every party
+//	}
+//
+// A signed hash proves that a trusted pipeline produced the build, not that
+// the remote host is actually running it.  Deployments that need the latter
+// can plug a TEE quote bound to the TLS session (see
+// tls.ConnectionState.ExportKeyingMaterial) into the same handshake hooks.
+package attest
@@ -0,0 +1,17 @@
+// Package compress wraps a `transport.Messenger` with transparent zstd
+// compression of protocol messages.
+//
+// Compression is negotiated per link when the Messenger is created: every
+// party announces a `Mode` for each peer and a link is compressed only if
+// neither side is `ModeOff` and at least one side is `ModePrefer`.  A mobile or
+// air-gapped party therefore sets ModePrefer while well-connected parties keep
+// the default ModeAccept and simply go along with it.
+//
+// Messages below Config.MinSize, and messages that do not shrink, are sent
+// uncompressed even on compressed links.  Decompressed sizes are bounded by
+// Config.MaxMessageSize so a malicious peer cannot exhaust memory with a
+// decompression bomb.
+//
+// Like the liveness wrapper, the compress wrapper adds a one-byte header to
+// every message, so either all parties of a session use it or none does.
+package compress
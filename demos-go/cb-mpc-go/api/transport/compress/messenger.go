@@ -0,0 +1,249 @@
+package compress
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+const (
+	defaultMinSize        = 512
+	defaultMaxMessageSize = 10 * 1024 * 1024 // 10MB, matching the mtls transport
+)
+
+// Frame headers.
+const (
+	frameRaw byte = iota
+	frameZstd
+	frameHello
+)
+
+// helloVersion is the version of the negotiation message.
+const helloVersion = 1
+
+// Mode is a party's compression preference for a link.
+type Mode uint8
+
+const (
+	// ModeAccept compresses the link only if the peer prefers it.
+	ModeAccept Mode = iota
+	// ModePrefer asks for the link to be compressed.
+	ModePrefer
+	// ModeOff never compresses the link.
+	ModeOff
+)
+
+// String returns the symbolic name of the Mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeAccept:
+		return "accept"
+	case ModePrefer:
+		return "prefer"
+	case ModeOff:
+		return "off"
+	default:
+		return "unknown"
+	}
+}
+
+// negotiate returns whether a link between two parties with the given modes is
+// compressed.
+func negotiate(local, remote Mode) bool {
+	if local == ModeOff || remote == ModeOff {
+		return false
+	}
+	return local == ModePrefer || remote == ModePrefer
+}
+
+// Config contains the configuration for a compressing Messenger.
+type Config struct {
+	// Peers lists the indices of all other parties in the session.
+	Peers []int
+	// Mode is the preference announced to every peer.  Defaults to
+	// ModeAccept.
+	Mode Mode
+	// PeerModes overrides Mode for individual peers.
+	PeerModes map[int]Mode
+	// Level is the zstd encoder level.  Defaults to zstd.SpeedDefault.
+	Level zstd.EncoderLevel
+	// MinSize is the smallest message that is compressed.  Defaults to 512
+	// bytes.
+	MinSize int
+	// MaxMessageSize bounds decompressed messages.  Defaults to 10MB.
+	MaxMessageSize int
+}
+
+// Messenger implements transport.Messenger on top of another Messenger,
+// compressing messages on links where compression was negotiated.
+type Messenger struct {
+	inner   transport.Messenger
+	links   map[int]bool // peer index -> compressed
+	minSize int
+	maxSize int
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+
+	closeOnce sync.Once
+}
+
+// Ensure Messenger implements the Messenger interface
+var _ transport.Messenger = (*Messenger)(nil)
+
+// NewMessenger exchanges compression preferences with every peer over inner
+// and returns the wrapped Messenger.  All peers must call NewMessenger at the
+// same point of the session.
+func NewMessenger(ctx context.Context, inner transport.Messenger, config Config) (*Messenger, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("inner messenger cannot be nil")
+	}
+	if len(config.Peers) == 0 {
+		return nil, fmt.Errorf("at least one peer must be provided")
+	}
+	minSize := config.MinSize
+	if minSize <= 0 {
+		minSize = defaultMinSize
+	}
+	maxSize := config.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxMessageSize
+	}
+	level := config.Level
+	if level == 0 {
+		level = zstd.SpeedDefault
+	}
+
+	modes := make(map[int]Mode, len(config.Peers))
+	for _, peer := range config.Peers {
+		if _, dup := modes[peer]; dup {
+			return nil, fmt.Errorf("duplicate peer %d", peer)
+		}
+		mode := config.Mode
+		if m, ok := config.PeerModes[peer]; ok {
+			mode = m
+		}
+		if mode > ModeOff {
+			return nil, fmt.Errorf("invalid compression mode %d for peer %d", mode, peer)
+		}
+		modes[peer] = mode
+		if err := inner.MessageSend(ctx, peer, []byte{frameHello, helloVersion, byte(mode)}); err != nil {
+			return nil, fmt.Errorf("sending compression preferences to %d: %v", peer, err)
+		}
+	}
+	hellos, err := inner.MessagesReceive(ctx, config.Peers)
+	if err != nil {
+		return nil, fmt.Errorf("receiving compression preferences: %v", err)
+	}
+	links := make(map[int]bool, len(config.Peers))
+	for i, peer := range config.Peers {
+		hello := hellos[i]
+		if len(hello) != 3 || hello[0] != frameHello || hello[1] != helloVersion || Mode(hello[2]) > ModeOff {
+			return nil, fmt.Errorf("invalid compression preferences from %d", peer)
+		}
+		links[peer] = negotiate(modes[peer], Mode(hello[2]))
+	}
+
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("creating zstd encoder: %v", err)
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(maxSize)), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		encoder.Close()
+		return nil, fmt.Errorf("creating zstd decoder: %v", err)
+	}
+	return &Messenger{
+		inner:   inner,
+		links:   links,
+		minSize: minSize,
+		maxSize: maxSize,
+		encoder: encoder,
+		decoder: decoder,
+	}, nil
+}
+
+// Compressed reports whether the link to peer was negotiated as compressed.
+func (m *Messenger) Compressed(peer int) bool {
+	return m.links[peer]
+}
+
+// MessageSend sends a message to the specified receiver party
+func (m *Messenger) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	compressed, ok := m.links[receiver]
+	if !ok {
+		return fmt.Errorf("unknown party %d", receiver)
+	}
+	if compressed && len(buffer) >= m.minSize {
+		frame := m.encoder.EncodeAll(buffer, []byte{frameZstd})
+		if len(frame) < len(buffer)+1 {
+			return m.inner.MessageSend(ctx, receiver, frame)
+		}
+	}
+	frame := make([]byte, 1+len(buffer))
+	frame[0] = frameRaw
+	copy(frame[1:], buffer)
+	return m.inner.MessageSend(ctx, receiver, frame)
+}
+
+// MessageReceive receives a message from the specified sender party
+func (m *Messenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	frame, err := m.inner.MessageReceive(ctx, sender)
+	if err != nil {
+		return nil, err
+	}
+	return m.decode(sender, frame)
+}
+
+// MessagesReceive receives messages from multiple sender parties concurrently
+func (m *Messenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	frames, err := m.inner.MessagesReceive(ctx, senders)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([][]byte, len(frames))
+	for i, frame := range frames {
+		if msgs[i], err = m.decode(senders[i], frame); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+func (m *Messenger) decode(sender int, frame []byte) ([]byte, error) {
+	compressed, ok := m.links[sender]
+	if !ok {
+		return nil, fmt.Errorf("unknown party %d", sender)
+	}
+	if len(frame) == 0 {
+		return nil, fmt.Errorf("empty frame from party %d", sender)
+	}
+	switch {
+	case frame[0] == frameRaw:
+		return frame[1:], nil
+	case frame[0] == frameZstd && compressed:
+		msg, err := m.decoder.DecodeAll(frame[1:], nil)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing message from party %d: %v", sender, err)
+		}
+		if len(msg) > m.maxSize {
+			return nil, fmt.Errorf("message too large: %d bytes", len(msg))
+		}
+		return msg, nil
+	default:
+		return nil, fmt.Errorf("unexpected frame type %d from party %d", frame[0], sender)
+	}
+}
+
+// Close releases the zstd encoder and decoder.  It does not close the
+// underlying Messenger.
+func (m *Messenger) Close() error {
+	m.closeOnce.Do(func() {
+		m.encoder.Close()
+		m.decoder.Close()
+	})
+	return nil
+}
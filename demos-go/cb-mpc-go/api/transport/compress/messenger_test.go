@@ -0,0 +1,124 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipe is an in-memory Messenger for two parties that records frame sizes.
+type pipe struct {
+	self  int
+	inbox map[int]chan []byte
+	peers map[int]*pipe
+	sent  []int
+}
+
+func newPipes() (*pipe, *pipe) {
+	a := &pipe{self: 0, inbox: map[int]chan []byte{1: make(chan []byte, 16)}}
+	b := &pipe{self: 1, inbox: map[int]chan []byte{0: make(chan []byte, 16)}}
+	a.peers = map[int]*pipe{1: b}
+	b.peers = map[int]*pipe{0: a}
+	return a, b
+}
+
+func (p *pipe) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	p.sent = append(p.sent, len(buffer))
+	p.peers[receiver].inbox[p.self] <- buffer
+	return nil
+}
+
+func (p *pipe) MessageReceive(_ context.Context, sender int) ([]byte, error) {
+	return <-p.inbox[sender], nil
+}
+
+func (p *pipe) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	msgs := make([][]byte, len(senders))
+	for i, sender := range senders {
+		msgs[i], _ = p.MessageReceive(ctx, sender)
+	}
+	return msgs, nil
+}
+
+// connect negotiates both ends of a link concurrently.
+func connect(t *testing.T, configA, configB Config) (*Messenger, *Messenger, *pipe) {
+	t.Helper()
+	a, b := newPipes()
+	configA.Peers, configB.Peers = []int{1}, []int{0}
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	var mb *Messenger
+	go func() {
+		var err error
+		mb, err = NewMessenger(ctx, b, configB)
+		done <- err
+	}()
+	ma, err := NewMessenger(ctx, a, configA)
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	t.Cleanup(func() { ma.Close(); mb.Close() })
+	return ma, mb, a
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		local, remote Mode
+		want          bool
+	}{
+		{ModeAccept, ModeAccept, false},
+		{ModeAccept, ModePrefer, true},
+		{ModePrefer, ModePrefer, true},
+		{ModePrefer, ModeOff, false},
+		{ModeOff, ModeAccept, false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, negotiate(tc.local, tc.remote), "%s/%s", tc.local, tc.remote)
+		assert.Equal(t, tc.want, negotiate(tc.remote, tc.local), "%s/%s", tc.remote, tc.local)
+	}
+}
+
+func TestCompressedLink(t *testing.T) {
+	ma, mb, a := connect(t, Config{Mode: ModePrefer}, Config{})
+	assert.True(t, ma.Compressed(1))
+	assert.True(t, mb.Compressed(0))
+
+	ctx := context.Background()
+	large := bytes.Repeat([]byte("commitment"), 1000)
+	small := []byte("tiny")
+	require.NoError(t, ma.MessageSend(ctx, 1, large))
+	require.NoError(t, ma.MessageSend(ctx, 1, small))
+	assert.Less(t, a.sent[1], len(large)/10)
+	assert.Equal(t, len(small)+1, a.sent[2])
+
+	msgs, err := mb.MessagesReceive(ctx, []int{0})
+	require.NoError(t, err)
+	assert.Equal(t, large, msgs[0])
+	msg, err := mb.MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, small, msg)
+}
+
+func TestUncompressedLinkRejectsZstdFrames(t *testing.T) {
+	ma, mb, _ := connect(t, Config{Mode: ModePrefer}, Config{Mode: ModeOff})
+	assert.False(t, ma.Compressed(1))
+
+	large := bytes.Repeat([]byte{7}, 4096)
+	require.NoError(t, ma.MessageSend(context.Background(), 1, large))
+	msg, err := mb.MessageReceive(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, large, msg)
+
+	_, err = mb.decode(0, ma.encoder.EncodeAll(large, []byte{frameZstd}))
+	assert.Error(t, err)
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	_, mb, _ := connect(t, Config{Mode: ModePrefer}, Config{MaxMessageSize: 1024})
+	bomb := mb.encoder.EncodeAll(make([]byte, 1<<20), []byte{frameZstd})
+	_, err := mb.decode(0, bomb)
+	assert.Error(t, err)
+}
@@ -0,0 +1,384 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mux"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/preflight"
+)
+
+// Stream IDs of the suite.  A party under test echoes every message on the
+// echo streams and the done stream back to its sender, and runs a preflight
+// check on the preflight streams.
+const (
+	SizesStream             = "conformance/echo/sizes"
+	OrderStream             = "conformance/echo/order"
+	StreamA                 = "conformance/echo/a"
+	StreamB                 = "conformance/echo/b"
+	PreflightStream         = "conformance/preflight"
+	PreflightMismatchStream = "conformance/preflight/mismatch"
+	DoneStream              = "conformance/done"
+)
+
+// KeyVersion is the key version both sides announce in the preflight
+// streams, except the runner in the mismatch case.
+var KeyVersion = keystore.KeyVersion{KeyID: []byte("cbmpc-conformance-v1"), Epoch: 1}
+
+// Intent is the intent hash both sides announce in the preflight streams:
+// SHA-256 of "cbmpc-conformance-intent".
+var Intent = func() []byte {
+	sum := sha256.Sum256([]byte("cbmpc-conformance-intent"))
+	return sum[:]
+}()
+
+// Sizes are the payload sizes of the sizes case.  They cover every length
+// encoding of a WebSocket frame and messages larger than a TLS record.
+var Sizes = []int{0, 1, 125, 126, 127, 65535, 65536, 1 << 20}
+
+// orderCount is the number of messages of the order case.  It stays well
+// below the per-stream queue of a Mux.
+const orderCount = 32
+
+const defaultTimeout = 30 * time.Second
+
+// Config contains the configuration of a conformance run.
+type Config struct {
+	// Self is the index of the local party.
+	Self int
+	// Peer is the index of the other party: the party under test for Run,
+	// the runner for Respond.
+	Peer int
+	// Timeout bounds each case of Run, and the whole of Respond.  It is
+	// also the timeout of each preflight step.  Defaults to 30s.
+	Timeout time.Duration
+}
+
+func (c *Config) check() error {
+	if c.Self == c.Peer {
+		return fmt.Errorf("self and peer must differ")
+	}
+	if c.Self < 0 || c.Peer < 0 {
+		return fmt.Errorf("party indices must not be negative")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	return nil
+}
+
+// Result is the outcome of one case.
+type Result struct {
+	Case     string        `json:"case"`
+	Error    string        `json:"error,omitempty"` // Empty if the case passed
+	Duration time.Duration `json:"duration"`
+}
+
+// Report lists the results of a run in order.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Passed reports whether every case passed.
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// suiteCase is one case of the suite.
+type suiteCase struct {
+	name string
+	run  func(ctx context.Context, m *mux.Mux, config Config) error
+}
+
+// cases are run in this order; done must be last, as it ends the run of
+// the party under test.
+var cases = []suiteCase{
+	{"sizes", runSizes},
+	{"order", runOrder},
+	{"streams", runStreams},
+	{"preflight", runPreflight},
+	{"preflight-mismatch", runPreflightMismatch},
+	{"done", runDone},
+}
+
+// Cases returns the names of the cases of the suite in the order Run runs
+// them.
+func Cases() []string {
+	names := make([]string, len(cases))
+	for i, c := range cases {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Run runs the suite against the party under test, config.Peer, over link,
+// which carries nothing else: Run multiplexes it itself.  A failing case is
+// recorded in the report and the run goes on with the next one; Run only
+// returns an error for an invalid config or when ctx ends.
+func Run(ctx context.Context, link transport.Messenger, config Config) (*Report, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	// Pending messages must survive until their case starts: the party
+	// under test sends its pings as soon as it is connected.
+	m, err := mux.New(link, mux.Config{Peers: []int{config.Peer}, PendingTimeout: config.Timeout * time.Duration(len(cases))})
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	report := &Report{}
+	for _, c := range cases {
+		start := time.Now()
+		cctx, cancel := context.WithTimeout(ctx, config.Timeout)
+		err := c.run(cctx, m, config)
+		cancel()
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		r := Result{Case: c.name, Duration: time.Since(start)}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		report.Results = append(report.Results, r)
+	}
+	return report, nil
+}
+
+// pattern returns n bytes that differ for every size and position, so a
+// truncated, padded or shifted echo does not match.
+func pattern(n, seed int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*31 + n + seed*7)
+	}
+	return b
+}
+
+// echo sends msg on s and checks that it comes back unchanged.
+func echo(ctx context.Context, s *mux.Stream, peer int, msg []byte) error {
+	if err := s.MessageSend(ctx, peer, msg); err != nil {
+		return fmt.Errorf("sending %d bytes: %w", len(msg), err)
+	}
+	return expect(ctx, s, peer, msg)
+}
+
+// expect receives the next message on s and compares it with want.
+func expect(ctx context.Context, s *mux.Stream, peer int, want []byte) error {
+	got, err := s.MessageReceive(ctx, peer)
+	if err != nil {
+		return fmt.Errorf("waiting for the echo of %d bytes on %s: %w", len(want), s.ID(), err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("echo on %s differs: sent %d bytes, got %d bytes back", s.ID(), len(want), len(got))
+	}
+	return nil
+}
+
+// open opens a stream of the suite for the duration of fn.
+func open(m *mux.Mux, id string, fn func(s *mux.Stream) error) error {
+	s, err := m.Open(id)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return fn(s)
+}
+
+func runSizes(ctx context.Context, m *mux.Mux, config Config) error {
+	return open(m, SizesStream, func(s *mux.Stream) error {
+		for _, n := range Sizes {
+			if err := echo(ctx, s, config.Peer, pattern(n, 0)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// runOrder sends all messages before reading any echo, so the party under
+// test must preserve the order of back-to-back messages.
+func runOrder(ctx context.Context, m *mux.Mux, config Config) error {
+	return open(m, OrderStream, func(s *mux.Stream) error {
+		for i := 0; i < orderCount; i++ {
+			if err := s.MessageSend(ctx, config.Peer, pattern(16, i)); err != nil {
+				return fmt.Errorf("sending message %d: %w", i, err)
+			}
+		}
+		for i := 0; i < orderCount; i++ {
+			if err := expect(ctx, s, config.Peer, pattern(16, i)); err != nil {
+				return fmt.Errorf("message %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+}
+
+// runStreams interleaves two streams, so the party under test must echo
+// each message on the stream it arrived on.
+func runStreams(ctx context.Context, m *mux.Mux, config Config) error {
+	return open(m, StreamA, func(a *mux.Stream) error {
+		return open(m, StreamB, func(b *mux.Stream) error {
+			msgA, msgB := []byte("stream a"), []byte("stream b")
+			if err := b.MessageSend(ctx, config.Peer, msgB); err != nil {
+				return err
+			}
+			if err := a.MessageSend(ctx, config.Peer, msgA); err != nil {
+				return err
+			}
+			if err := expect(ctx, a, config.Peer, msgA); err != nil {
+				return err
+			}
+			return expect(ctx, b, config.Peer, msgB)
+		})
+	})
+}
+
+func runPreflight(ctx context.Context, m *mux.Mux, config Config) error {
+	return open(m, PreflightStream, func(s *mux.Stream) error {
+		return preflight.Check(ctx, s, preflight.Config{
+			Self: config.Self, Peers: []int{config.Peer},
+			KeyVersion: KeyVersion, Intent: Intent, Timeout: config.Timeout,
+		})
+	})
+}
+
+// runPreflightMismatch announces another key version, which the party under
+// test must report as mismatched and answer by aborting.
+func runPreflightMismatch(ctx context.Context, m *mux.Mux, config Config) error {
+	return open(m, PreflightMismatchStream, func(s *mux.Stream) error {
+		err := preflight.Check(ctx, s, preflight.Config{
+			Self: config.Self, Peers: []int{config.Peer},
+			KeyVersion: keystore.KeyVersion{KeyID: []byte("cbmpc-conformance-mismatch"), Epoch: 1}, Intent: Intent, Timeout: config.Timeout,
+		})
+		var perr *preflight.Error
+		switch {
+		case err == nil:
+			return fmt.Errorf("preflight passed despite a key version mismatch")
+		case !errors.As(err, &perr):
+			return err
+		case !slices.Equal(perr.Mismatched, []int{config.Peer}):
+			return fmt.Errorf("expected a key version mismatch only: %v", err)
+		case !slices.Equal(perr.Aborted, []int{config.Peer}):
+			return fmt.Errorf("party did not abort on a key version mismatch: %v", err)
+		}
+		return nil
+	})
+}
+
+func runDone(ctx context.Context, m *mux.Mux, config Config) error {
+	return open(m, DoneStream, func(s *mux.Stream) error {
+		return echo(ctx, s, config.Peer, []byte("done"))
+	})
+}
+
+// Respond plays the party under test over link: it is the reference
+// behaviour that the spec describes, and what Run expects from a party
+// written in another language.  It returns once the done message was echoed.
+func Respond(ctx context.Context, link transport.Messenger, config Config) error {
+	if err := config.check(); err != nil {
+		return err
+	}
+	m, err := mux.New(link, mux.Config{Peers: []int{config.Peer}})
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout*time.Duration(len(cases)))
+	defer cancel()
+	done, finish := context.WithCancel(ctx)
+	defer finish()
+	eg, gctx := errgroup.WithContext(done)
+	for _, id := range []string{SizesStream, OrderStream, StreamA, StreamB} {
+		s, err := m.Open(id)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		eg.Go(func() error {
+			for {
+				msg, err := s.MessageReceive(gctx, config.Peer)
+				if err == nil {
+					err = s.MessageSend(gctx, config.Peer, msg)
+				}
+				if gctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+			}
+		})
+	}
+	for _, id := range []string{PreflightStream, PreflightMismatchStream} {
+		s, err := m.Open(id)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		eg.Go(func() error {
+			err := preflight.Check(gctx, s, preflight.Config{
+				Self: config.Self, Peers: []int{config.Peer},
+				KeyVersion: KeyVersion, Intent: Intent, Timeout: config.Timeout,
+			})
+			// A failed check is the runner's to judge.
+			if err != nil && !errors.Is(err, preflight.ErrNotReady) && gctx.Err() == nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+			return nil
+		})
+	}
+	s, err := m.Open(DoneStream)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	eg.Go(func() error {
+		msg, err := s.MessageReceive(gctx, config.Peer)
+		if err == nil {
+			err = s.MessageSend(gctx, config.Peer, msg)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", DoneStream, err)
+		}
+		finish()
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// String formats r as one line per case.
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, res := range r.Results {
+		status := "PASS"
+		if res.Error != "" {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s %-20s %v", status, res.Case, res.Duration.Round(time.Millisecond))
+		if res.Error != "" {
+			fmt.Fprintf(&b, "  %s", res.Error)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
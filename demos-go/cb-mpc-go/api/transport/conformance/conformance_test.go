@@ -0,0 +1,132 @@
+package conformance
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mux"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/websocket"
+)
+
+func mockLinks() []transport.Messenger {
+	links := mocknet.NewMockNetwork(2)
+	return []transport.Messenger{links[0], links[1]}
+}
+
+// runAgainst runs the suite on links[0] against respond on links[1].
+func runAgainst(t *testing.T, links []transport.Messenger, respond func(ctx context.Context, link transport.Messenger) error) *Report {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- respond(ctx, links[1]) }()
+	report, err := Run(ctx, links[0], Config{Self: 0, Peer: 1, Timeout: 2 * time.Second})
+	require.NoError(t, err)
+	require.NoError(t, <-errc)
+	return report
+}
+
+func TestRespondPasses(t *testing.T) {
+	report := runAgainst(t, mockLinks(), func(ctx context.Context, link transport.Messenger) error {
+		return Respond(ctx, link, Config{Self: 1, Peer: 0, Timeout: 2 * time.Second})
+	})
+	assert.True(t, report.Passed(), report.String())
+	assert.Len(t, report.Results, len(Cases()))
+}
+
+func TestRespondPassesOverWebSocket(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// The runner listens; the party under test dials in.
+	links := make([]transport.Messenger, 2)
+	errc := make(chan error, 1)
+	go func() {
+		m, err := websocket.NewMessenger(websocket.Config{SelfIndex: 1, Addresses: map[int]string{0: addr, 1: ""}})
+		links[1] = m
+		errc <- err
+	}()
+	m, err := websocket.NewMessenger(websocket.Config{SelfIndex: 0, Addresses: map[int]string{0: addr, 1: "party under test"}})
+	require.NoError(t, err)
+	defer m.Close()
+	links[0] = m
+	require.NoError(t, <-errc)
+	defer links[1].(*websocket.Messenger).Close()
+
+	report := runAgainst(t, links, func(ctx context.Context, link transport.Messenger) error {
+		return Respond(ctx, link, Config{Self: 1, Peer: 0, Timeout: 2 * time.Second})
+	})
+	assert.True(t, report.Passed(), report.String())
+}
+
+// TestNaiveEchoFailsMismatch checks that the suite catches a party that
+// echoes preflight frames instead of checking them.
+func TestNaiveEchoFailsMismatch(t *testing.T) {
+	report := runAgainst(t, mockLinks(), func(ctx context.Context, link transport.Messenger) error {
+		m, err := mux.New(link, mux.Config{Peers: []int{0}})
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		ids := []string{SizesStream, OrderStream, StreamA, StreamB, PreflightStream, PreflightMismatchStream}
+		for _, id := range ids {
+			s, err := m.Open(id)
+			if err != nil {
+				return err
+			}
+			go func() {
+				for {
+					msg, err := s.MessageReceive(ctx, 0)
+					if err != nil || s.MessageSend(ctx, 0, msg) != nil {
+						return
+					}
+				}
+			}()
+		}
+		s, err := m.Open(DoneStream)
+		if err != nil {
+			return err
+		}
+		msg, err := s.MessageReceive(ctx, 0)
+		if err != nil {
+			return err
+		}
+		return s.MessageSend(ctx, 0, msg)
+	})
+	require.False(t, report.Passed())
+	for _, r := range report.Results {
+		if r.Case == "preflight-mismatch" {
+			assert.Contains(t, r.Error, "despite a key version mismatch")
+		} else {
+			assert.Empty(t, r.Error, r.Case)
+		}
+	}
+}
+
+func TestRunRecordsFailures(t *testing.T) {
+	// Nobody answers: every case times out, and the run still completes.
+	links := mocknet.NewMockNetwork(2)
+	report, err := Run(context.Background(), links[0], Config{Self: 0, Peer: 1, Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	require.Len(t, report.Results, len(Cases()))
+	for _, r := range report.Results {
+		assert.NotEmpty(t, r.Error, r.Case)
+	}
+	assert.Contains(t, report.String(), "FAIL sizes")
+}
+
+func TestConfigCheck(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	_, err := Run(context.Background(), links[0], Config{Self: 1, Peer: 1})
+	assert.Error(t, err)
+	assert.Error(t, Respond(context.Background(), links[1], Config{Self: -1, Peer: 0}))
+}
@@ -0,0 +1,26 @@
+// Package conformance checks that a party written in another language, such
+// as a Rust signer built on frost-ed25519, speaks the wire protocol of the Go
+// parties.
+//
+// The protocol is specified in SPEC.md next to this file: the WebSocket
+// handshake and framing of the websocket transport, the stream framing of the
+// mux package and the ping and commit frames of the preflight package.  The
+// MPC round messages themselves are opaque to all three layers.
+//
+// Run plays the Go side of a fixed suite of cases over any Messenger and
+// reports which of them the party under test passed:
+//
+//	link, _ := websocket.NewMessenger(websocket.Config{
+//		SelfIndex: 0, Addresses: map[int]string{0: ":7000", 1: "party under test"},
+//	})
+//	report, err := conformance.Run(ctx, link, conformance.Config{Self: 0, Peer: 1})
+//	if err == nil && !report.Passed() {
+//		fmt.Print(report)
+//	}
+//
+// The party under test echoes every message of the echo and done streams
+// back on the stream it arrived on, and runs a preflight check with
+// KeyVersion and Intent on the preflight streams.  Respond is that behaviour
+// in Go; it is the reference a port can be read against, and the suite's own
+// tests run it.  The cb-mpc-conformance command wraps both ends for CI.
+package conformance
@@ -13,12 +13,45 @@
 // deliberate design choice lets applications swap transport mechanisms without
 // touching any of the cryptography.
 //
-// Out of the box the repository provides two implementations:
+// Out of the box the repository provides four implementations:
 //
-//   - mocknet – an in-process, fully deterministic transport ideal for tests
-//   - mtls    – a production-ready TCP transport that uses mutual-TLS for
-//     authentication and encryption
+//   - mocknet   – an in-process, fully deterministic transport ideal for tests
+//   - mtls      – a production-ready TCP transport that uses mutual-TLS for
+//     authentication and encryption, with a pinned certificate per party, so
+//     parties can run on separate hosts (a server, a KMS box, a phone); hosts
+//     may start in any order, as dialing is retried with backoff
+//   - websocket – a transport over WebSocket connections for parties behind
+//     HTTP proxies and load balancers
+//   - grpcnet   – a transport over bidirectional gRPC streams, registered on
+//     an existing grpc.Server, with sessions multiplexed over one stream per
+//     pair of parties
+//
+// Messengers can be layered.  The liveness package wraps any Messenger with
+// heartbeats and per-round timeouts so that a stalled party is reported within
+// seconds instead of at the end of a blanket deadline.
+// The compress package adds zstd compression negotiated per link, which helps
+// mobile or air-gapped parties exchanging large DKG messages.
+// The echo package adds echo broadcast, aborting with an identifiable
+// equivocation error when a party sends different values to different parties
+// in a broadcast round.
+// The identity package lets the mtls transport take its TLS key from an HSM
+// or KMS through a crypto.Signer, so party identity keys never touch the host.
+// The adversary package provides malicious parties for tests – corrupting,
+// equivocating, stalling or replaying – to check that honest parties abort
+// with errors naming the culprit.
+// The mux package carries many concurrent sessions over one Messenger, so a
+// single connection per pair of parties serves hundreds of signatures at once.
+// The ephemeral package encrypts each session under keys from a fresh X25519
+// exchange, so a party identity key stolen later does not reveal the round
+// messages of past sessions.
+// The preflight package checks that every required party is online and holds
+// the expected key version before a DKG or signature starts its heavy rounds.
+// The netsim package injects latency and records per-round timings; the
+// cb-mpc-bench command uses it to compare transports.
+// The conformance package specifies the wire protocol of the websocket, mux
+// and preflight layers in SPEC.md and runs a suite against parties written
+// in other languages; the cb-mpc-conformance command wraps it for CI.
 //
 // You are encouraged to implement your own Messenger for custom deployment
-// scenarios (e.g. gRPC, libp2p, message queues, …).
+// scenarios (e.g. libp2p, message queues, …).
 package transport
@@ -0,0 +1,23 @@
+// Package echo wraps any `transport.Messenger` with echo broadcast, so that a
+// party sending different values to different parties in a broadcast round is
+// detected instead of silently splitting the session.
+//
+// Whenever a broadcast message from party j arrives, the Messenger forwards its
+// SHA-256 digest to every other party (the echo) and MessageReceive only
+// returns the message once every other party has echoed the same digest.  A
+// mismatch aborts with an `*EquivocationError` naming the sender, the round and
+// the party whose echo disagreed.
+//
+// Without signatures an honest party learns that the sender and the witness
+// disagree but cannot prove who lied.  With Config.Sign and Config.Verify set,
+// every broadcast message carries the sender's signature over
+// (session, sender, round, digest) and echoes relay it, so an equivocation is
+// proven by two valid signatures of the sender over different digests, which
+// is returned as EquivocationError.Proof.
+//
+// Rounds are counted per link: the n-th message a party sends to a peer
+// belongs to round n.  Every data frame carries its round, so a replayed or
+// reordered frame aborts with a `*ReplayError` naming the sender.  Config.Broadcast selects the rounds that must be
+// consistent; protocols must send exactly one message to every peer in those
+// rounds.  All parties of a session must use the wrapper.
+package echo
@@ -0,0 +1,484 @@
+package echo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// Frame types prepended to every message sent through the underlying
+// Messenger.
+const (
+	frameData byte = iota
+	frameEcho
+)
+
+// statementDomain prefixes every signed statement.
+const statementDomain = "cb-mpc echo broadcast v1\x00"
+
+var (
+	// ErrEquivocation is wrapped by every EquivocationError.
+	ErrEquivocation = errors.New("echo: equivocation detected")
+	// ErrBadSignature is wrapped by every SignatureError.
+	ErrBadSignature = errors.New("echo: invalid signature")
+	// ErrReplay is wrapped by every ReplayError.
+	ErrReplay = errors.New("echo: replayed or reordered message")
+)
+
+// SignedDigest is a digest of a broadcast message together with the sender's
+// signature over the corresponding statement.
+type SignedDigest struct {
+	Digest    []byte
+	Signature []byte
+}
+
+// EquivocationError reports a sender whose broadcast reached this party and
+// Witness with different contents.
+type EquivocationError struct {
+	Sender  int // Party that broadcast the message
+	Round   int // Round of the message on the sender's links
+	Witness int // Party whose echo did not match
+	// Proof holds the two conflicting statements signed by Sender when
+	// signatures are configured, and is nil otherwise.
+	Proof []SignedDigest
+}
+
+func (e *EquivocationError) Error() string {
+	proof := "unproven"
+	if e.Proof != nil {
+		proof = "proven by signatures"
+	}
+	return fmt.Sprintf("party %d sent party %d a different round %d broadcast (%s)", e.Sender, e.Witness, e.Round, proof)
+}
+
+func (e *EquivocationError) Unwrap() error { return ErrEquivocation }
+
+// SignatureError reports a party that sent a broadcast or echo with an invalid
+// signature.
+type SignatureError struct {
+	Party int // Party that sent the invalid frame
+	Round int
+	Err   error // Error returned by Config.Verify
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("party %d sent an invalid signature in round %d: %v", e.Party, e.Round, e.Err)
+}
+
+func (e *SignatureError) Unwrap() error { return ErrBadSignature }
+
+// ReplayError reports a party that sent a data frame out of order, such as a
+// replayed message from an earlier round.
+type ReplayError struct {
+	Party    int // Party that sent the frame
+	Round    int // Round the frame claims
+	Expected int // Round the frame should have had
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("party %d sent round %d, expected %d", e.Party, e.Round, e.Expected)
+}
+
+func (e *ReplayError) Unwrap() error { return ErrReplay }
+
+// Config contains the configuration for an echo Messenger.
+type Config struct {
+	// Self is the index of the local party.
+	Self int
+	// Peers lists the indices of all other parties in the session.
+	Peers []int
+	// Session binds signed statements to one session.  Required with Sign.
+	Session []byte
+	// Broadcast reports whether the given 1-based round is a broadcast
+	// round.  Nil treats every round as a broadcast round.
+	Broadcast func(round int) bool
+	// Sign signs a statement with the local party's key.  Optional; must be
+	// set together with Verify.
+	Sign func(statement []byte) ([]byte, error)
+	// Verify checks a statement signed by party.
+	Verify func(party int, statement, signature []byte) error
+}
+
+// Messenger implements transport.Messenger on top of another Messenger,
+// checking broadcast rounds for consistency.
+type Messenger struct {
+	inner  transport.Messenger
+	config Config
+	peers  map[int]*peer
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	echoes  map[echoKey]map[int]SignedDigest // Echoes received, by witness
+	changed chan struct{}                    // Closed and replaced on every state change
+}
+
+// Ensure Messenger implements the Messenger interface
+var _ transport.Messenger = (*Messenger)(nil)
+
+type echoKey struct{ origin, round int }
+
+// message is a received data frame.
+type message struct {
+	round     int
+	payload   []byte
+	broadcast bool
+	signed    SignedDigest
+}
+
+// peer holds the state of the link to a single party.
+type peer struct {
+	index  int
+	sendMu sync.Mutex
+	sent   int // Data frames sent, guarded by sendMu
+
+	// Guarded by Messenger.mu.
+	received int
+	queue    []message
+	err      error
+}
+
+// NewMessenger wraps inner.  The Messenger takes over all reads from inner;
+// call Close to stop it.
+func NewMessenger(inner transport.Messenger, config Config) (*Messenger, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("inner messenger cannot be nil")
+	}
+	if len(config.Peers) == 0 {
+		return nil, fmt.Errorf("at least one peer must be provided")
+	}
+	if (config.Sign == nil) != (config.Verify == nil) {
+		return nil, fmt.Errorf("sign and verify must be provided together")
+	}
+	if config.Sign != nil && len(config.Session) == 0 {
+		return nil, fmt.Errorf("session must be provided when signing")
+	}
+	peers := make(map[int]*peer, len(config.Peers))
+	for _, index := range config.Peers {
+		if _, dup := peers[index]; dup || index == config.Self {
+			return nil, fmt.Errorf("invalid or duplicate peer %d", index)
+		}
+		peers[index] = &peer{index: index}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Messenger{
+		inner:   inner,
+		config:  config,
+		peers:   peers,
+		cancel:  cancel,
+		echoes:  make(map[echoKey]map[int]SignedDigest),
+		changed: make(chan struct{}),
+	}
+	for _, p := range peers {
+		go m.read(ctx, p)
+	}
+	return m, nil
+}
+
+func (m *Messenger) broadcast(round int) bool {
+	return m.config.Broadcast == nil || m.config.Broadcast(round)
+}
+
+// statement returns the byte string a sender signs for a broadcast.
+func (m *Messenger) statement(sender, round int, digest []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(statementDomain)
+	binary.Write(&b, binary.BigEndian, uint32(len(m.config.Session)))
+	b.Write(m.config.Session)
+	binary.Write(&b, binary.BigEndian, uint32(sender))
+	binary.Write(&b, binary.BigEndian, uint32(round))
+	b.Write(digest)
+	return b.Bytes()
+}
+
+// notifyLocked wakes every waiter.  m.mu must be held.
+func (m *Messenger) notifyLocked() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// read demultiplexes frames from p until the underlying Messenger fails.
+func (m *Messenger) read(ctx context.Context, p *peer) {
+	for {
+		frame, err := m.inner.MessageReceive(ctx, p.index)
+		if err != nil {
+			m.fail(p, fmt.Errorf("receiving from party %d: %w", p.index, err))
+			return
+		}
+		if err := m.handle(ctx, p, frame); err != nil {
+			m.fail(p, err)
+			return
+		}
+	}
+}
+
+func (m *Messenger) fail(p *peer, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+	m.notifyLocked()
+}
+
+// handle processes a single frame from p.
+func (m *Messenger) handle(ctx context.Context, p *peer, frame []byte) error {
+	r := bytes.NewReader(frame)
+	kind, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("empty frame from party %d", p.index)
+	}
+	switch kind {
+	case frameData:
+		var round uint32
+		if err := binary.Read(r, binary.BigEndian, &round); err != nil {
+			return fmt.Errorf("truncated frame from party %d", p.index)
+		}
+		sig, err := readBytes(r)
+		if err != nil {
+			return fmt.Errorf("truncated frame from party %d", p.index)
+		}
+		payload := frame[len(frame)-r.Len():]
+
+		m.mu.Lock()
+		p.received++
+		expected := p.received
+		m.mu.Unlock()
+		if int(round) != expected {
+			return &ReplayError{Party: p.index, Round: int(round), Expected: expected}
+		}
+		msg := message{round: expected, payload: payload, broadcast: m.broadcast(expected)}
+		if msg.broadcast {
+			digest := sha256.Sum256(payload)
+			msg.signed = SignedDigest{Digest: digest[:], Signature: sig}
+			if m.config.Verify != nil {
+				if err := m.config.Verify(p.index, m.statement(p.index, expected, digest[:]), sig); err != nil {
+					return &SignatureError{Party: p.index, Round: expected, Err: err}
+				}
+			}
+			go m.echo(ctx, p.index, expected, msg.signed)
+		}
+
+		m.mu.Lock()
+		p.queue = append(p.queue, msg)
+		m.notifyLocked()
+		m.mu.Unlock()
+		return nil
+
+	case frameEcho:
+		var origin, round uint32
+		if binary.Read(r, binary.BigEndian, &origin) != nil || binary.Read(r, binary.BigEndian, &round) != nil {
+			return fmt.Errorf("truncated echo from party %d", p.index)
+		}
+		if r.Len() < sha256.Size {
+			return fmt.Errorf("truncated echo from party %d", p.index)
+		}
+		digest := make([]byte, sha256.Size)
+		r.Read(digest)
+		sig, err := readBytes(r)
+		if err != nil {
+			return fmt.Errorf("truncated echo from party %d", p.index)
+		}
+		key := echoKey{origin: int(origin), round: int(round)}
+		if _, ok := m.peers[key.origin]; !ok || key.origin == p.index {
+			return fmt.Errorf("party %d echoed a message from invalid party %d", p.index, origin)
+		}
+		if m.config.Verify != nil {
+			if err := m.config.Verify(key.origin, m.statement(key.origin, key.round, digest), sig); err != nil {
+				return &SignatureError{Party: p.index, Round: key.round, Err: err}
+			}
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		witnesses := m.echoes[key]
+		if witnesses == nil {
+			witnesses = make(map[int]SignedDigest)
+			m.echoes[key] = witnesses
+		}
+		if _, dup := witnesses[p.index]; dup {
+			return fmt.Errorf("party %d echoed round %d of party %d twice", p.index, round, origin)
+		}
+		witnesses[p.index] = SignedDigest{Digest: digest, Signature: sig}
+		m.notifyLocked()
+		return nil
+
+	default:
+		return fmt.Errorf("unknown frame type %d from party %d", kind, p.index)
+	}
+}
+
+// echo forwards the digest of a broadcast from origin to every other peer.
+// Send errors are left to the read side, which notices the broken link.
+func (m *Messenger) echo(ctx context.Context, origin, round int, signed SignedDigest) {
+	var b bytes.Buffer
+	b.WriteByte(frameEcho)
+	binary.Write(&b, binary.BigEndian, uint32(origin))
+	binary.Write(&b, binary.BigEndian, uint32(round))
+	b.Write(signed.Digest)
+	writeBytes(&b, signed.Signature)
+	for _, p := range m.peers {
+		if p.index == origin {
+			continue
+		}
+		p.sendMu.Lock()
+		_ = m.inner.MessageSend(ctx, p.index, b.Bytes())
+		p.sendMu.Unlock()
+	}
+}
+
+func (m *Messenger) peer(index int) (*peer, error) {
+	p, ok := m.peers[index]
+	if !ok {
+		return nil, fmt.Errorf("unknown party %d", index)
+	}
+	return p, nil
+}
+
+// MessageSend sends a message to the specified receiver party.  In broadcast
+// rounds the message is signed if Config.Sign is set.
+func (m *Messenger) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	p, err := m.peer(receiver)
+	if err != nil {
+		return err
+	}
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	round := p.sent + 1
+
+	var sig []byte
+	if m.config.Sign != nil && m.broadcast(round) {
+		digest := sha256.Sum256(buffer)
+		if sig, err = m.config.Sign(m.statement(m.config.Self, round, digest[:])); err != nil {
+			return fmt.Errorf("signing round %d: %w", round, err)
+		}
+	}
+	var b bytes.Buffer
+	b.Grow(1 + 4 + 2 + len(sig) + len(buffer))
+	b.WriteByte(frameData)
+	binary.Write(&b, binary.BigEndian, uint32(round))
+	writeBytes(&b, sig)
+	b.Write(buffer)
+	if err := m.inner.MessageSend(ctx, receiver, b.Bytes()); err != nil {
+		return err
+	}
+	p.sent = round
+	return nil
+}
+
+// Broadcast sends buffer to every peer.
+func (m *Messenger) Broadcast(ctx context.Context, buffer []byte) error {
+	for _, index := range m.config.Peers {
+		if err := m.MessageSend(ctx, index, buffer); err != nil {
+			return fmt.Errorf("sending to party %d: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// MessageReceive receives the next message from the specified sender party.
+// For broadcast rounds it waits until every other party has echoed the
+// message and fails with an *EquivocationError if any echo differs.
+func (m *Messenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	p, err := m.peer(sender)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		m.mu.Lock()
+		payload, done, err := m.tryReceiveLocked(p)
+		changed := m.changed
+		m.mu.Unlock()
+		if done || err != nil {
+			return payload, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// tryReceiveLocked returns the next message from p if it is complete.
+func (m *Messenger) tryReceiveLocked(p *peer) ([]byte, bool, error) {
+	if len(p.queue) == 0 {
+		return nil, false, p.err
+	}
+	msg := p.queue[0]
+	if msg.broadcast {
+		key := echoKey{origin: p.index, round: msg.round}
+		witnesses := m.echoes[key]
+		for w, echoed := range witnesses {
+			if !bytes.Equal(echoed.Digest, msg.signed.Digest) {
+				e := &EquivocationError{Sender: p.index, Round: msg.round, Witness: w}
+				if m.config.Verify != nil {
+					e.Proof = []SignedDigest{msg.signed, echoed}
+				}
+				return nil, false, e
+			}
+		}
+		for _, w := range m.peers {
+			if w.index == p.index {
+				continue
+			}
+			if _, ok := witnesses[w.index]; !ok {
+				return nil, false, w.err
+			}
+		}
+		delete(m.echoes, key)
+	}
+	p.queue = p.queue[1:]
+	return msg.payload, true, nil
+}
+
+// MessagesReceive receives messages from multiple sender parties.  Failures
+// are joined; use errors.As to find an *EquivocationError.
+func (m *Messenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	msgs := make([][]byte, len(senders))
+	errs := make([]error, len(senders))
+	var wg sync.WaitGroup
+	for i, sender := range senders {
+		wg.Add(1)
+		go func(i, sender int) {
+			defer wg.Done()
+			msgs[i], errs[i] = m.MessageReceive(ctx, sender)
+		}(i, sender)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Close stops the read goroutines once the underlying Messenger honours
+// context cancellation.  It does not close the underlying Messenger.
+func (m *Messenger) Close() error {
+	m.cancel()
+	return nil
+}
+
+func writeBytes(b *bytes.Buffer, data []byte) {
+	binary.Write(b, binary.BigEndian, uint16(len(data)))
+	b.Write(data)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	if int(n) > r.Len() {
+		return nil, fmt.Errorf("length %d exceeds frame", n)
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return b, nil
+}
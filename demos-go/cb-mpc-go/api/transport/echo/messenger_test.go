@@ -0,0 +1,161 @@
+package echo
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hub is a context-aware in-memory network; hub[i][j] carries i -> j.
+type hub map[int]map[int]chan []byte
+
+type endpoint struct {
+	self int
+	hub  hub
+}
+
+func newHub(n int) hub {
+	h := make(hub)
+	for i := 0; i < n; i++ {
+		h[i] = make(map[int]chan []byte)
+		for j := 0; j < n; j++ {
+			if i != j {
+				h[i][j] = make(chan []byte, 64)
+			}
+		}
+	}
+	return h
+}
+
+func (e *endpoint) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	e.hub[e.self][receiver] <- append([]byte(nil), buffer...)
+	return nil
+}
+
+func (e *endpoint) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	select {
+	case msg := <-e.hub[sender][e.self]:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (e *endpoint) MessagesReceive(context.Context, []int) ([][]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+// network wraps n parties; configure may adjust each party's Config.
+func network(t *testing.T, n int, configure func(*Config)) []*Messenger {
+	t.Helper()
+	h := newHub(n)
+	out := make([]*Messenger, n)
+	for i := 0; i < n; i++ {
+		config := Config{Self: i}
+		for j := 0; j < n; j++ {
+			if j != i {
+				config.Peers = append(config.Peers, j)
+			}
+		}
+		if configure != nil {
+			configure(&config)
+		}
+		m, err := NewMessenger(&endpoint{self: i, hub: h}, config)
+		require.NoError(t, err)
+		t.Cleanup(func() { m.Close() })
+		out[i] = m
+	}
+	return out
+}
+
+func withTimeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestConsistentBroadcast(t *testing.T) {
+	ms := network(t, 3, nil)
+	ctx := withTimeout(t)
+	require.NoError(t, ms[0].Broadcast(ctx, []byte("commitment")))
+	require.NoError(t, ms[1].Broadcast(ctx, []byte("other")))
+
+	msgs, err := ms[2].MessagesReceive(ctx, []int{0, 1})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("commitment"), []byte("other")}, msgs)
+	msg, err := ms[1].MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("commitment"), msg)
+}
+
+func TestEquivocationDetected(t *testing.T) {
+	ms := network(t, 3, nil)
+	ctx := withTimeout(t)
+	require.NoError(t, ms[0].MessageSend(ctx, 1, []byte("pay alice")))
+	require.NoError(t, ms[0].MessageSend(ctx, 2, []byte("pay mallory")))
+
+	_, err := ms[1].MessageReceive(ctx, 0)
+	var eq *EquivocationError
+	require.ErrorAs(t, err, &eq)
+	assert.ErrorIs(t, err, ErrEquivocation)
+	assert.Equal(t, EquivocationError{Sender: 0, Round: 1, Witness: 2}, *eq)
+}
+
+func TestEquivocationProvenBySignatures(t *testing.T) {
+	keys := make([]ed25519.PrivateKey, 3)
+	for i := range keys {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[i] = key
+	}
+	verify := func(party int, statement, sig []byte) error {
+		if !ed25519.Verify(keys[party].Public().(ed25519.PublicKey), statement, sig) {
+			return fmt.Errorf("bad signature")
+		}
+		return nil
+	}
+	ms := network(t, 3, func(c *Config) {
+		key := keys[c.Self]
+		c.Session = []byte("session-1")
+		c.Sign = func(statement []byte) ([]byte, error) { return ed25519.Sign(key, statement), nil }
+		c.Verify = verify
+	})
+	ctx := withTimeout(t)
+	require.NoError(t, ms[0].MessageSend(ctx, 1, []byte("a")))
+	require.NoError(t, ms[0].MessageSend(ctx, 2, []byte("b")))
+
+	_, err := ms[2].MessageReceive(ctx, 0)
+	var eq *EquivocationError
+	require.ErrorAs(t, err, &eq)
+	require.Len(t, eq.Proof, 2)
+	for _, p := range eq.Proof {
+		assert.NoError(t, verify(0, ms[2].statement(0, 1, p.Digest), p.Signature))
+	}
+	assert.NotEqual(t, eq.Proof[0].Digest, eq.Proof[1].Digest)
+}
+
+func TestPointToPointRoundsAreNotEchoed(t *testing.T) {
+	ms := network(t, 3, func(c *Config) {
+		c.Broadcast = func(round int) bool { return round != 1 }
+	})
+	ctx := withTimeout(t)
+	require.NoError(t, ms[0].MessageSend(ctx, 1, []byte("share for 1")))
+	require.NoError(t, ms[0].MessageSend(ctx, 2, []byte("share for 2")))
+	require.NoError(t, ms[0].Broadcast(ctx, []byte("round 2")))
+
+	for _, i := range []int{1, 2} {
+		msg, err := ms[i].MessageReceive(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("share for %d", i)), msg)
+		msg, err = ms[i].MessageReceive(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("round 2"), msg)
+	}
+}
@@ -0,0 +1,40 @@
+// Package ephemeral wraps a `transport.Messenger` so that every session's
+// protocol messages are encrypted under keys that exist for that session
+// only.
+//
+// When the Messenger is created, every pair of parties runs an X25519 key
+// exchange with fresh key pairs over the wrapped Messenger.  Each party signs
+// the ephemeral key it sends a peer, bound to the session ID and both party
+// indices, with its long-term Ed25519 identity key, and checks the peer's
+// signature against Config.PeerKeys, so a proxy that terminates TLS between
+// the parties cannot substitute keys of its own.  The shared secret, bound to
+// the session ID and both public keys, is expanded with HKDF-SHA256 into one
+// AES-256-GCM key per direction, and the private keys are dropped
+// immediately.  Someone who records all traffic today and steals a party's
+// identity key tomorrow can impersonate the party in future sessions but
+// cannot decrypt past ones: the identity key only signed the ephemeral keys,
+// and those are gone.
+//
+// TLS 1.3 already gives each connection forward secrecy, but connections are
+// long-lived – one per pair of parties carries every session through the mux
+// package – and may be terminated by proxies in front of websocket parties.
+// Since the ephemeral keys are signed end to end, such a proxy only sees
+// ciphertext, and per-session keys limit what one compromised connection key
+// reveals to the sessions that were running at the time.
+//
+//	m, err := ephemeral.NewMessenger(ctx, stream, ephemeral.Config{
+//	    Self: 0, Peers: []int{1, 2}, SessionID: []byte(sessionID),
+//	    Identity: identityKey, PeerKeys: peerIdentities,
+//	})
+//	audit.Transport = m.Params() // negotiated suite and per-peer transcripts
+//
+// Params describes what was negotiated: the key exchange, signature, KDF and
+// cipher and, per peer, a hash of the key exchange transcript that both
+// parties of a link compute alike, so their audit records can be matched.
+// It holds no key material.
+//
+// Every message is authenticated with a per-direction counter, so messages
+// that are replayed, reordered, dropped or injected on the wrapped Messenger
+// fail to decrypt and abort the session.  Like the other wrappers, either all
+// parties of a session use it or none does.
+package ephemeral
@@ -0,0 +1,314 @@
+package ephemeral
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// helloVersion is the version of the key exchange message.
+const helloVersion = 2
+
+// label separates this key exchange from any other use of X25519.
+const label = "cb-mpc ephemeral v1"
+
+// Names of the negotiated algorithms, as reported in Params.
+const (
+	KeyExchangeX25519 = "X25519"
+	SignatureEd25519  = "Ed25519"
+	KDFHKDFSHA256     = "HKDF-SHA256"
+	CipherAES256GCM   = "AES-256-GCM"
+)
+
+// ErrClosed is returned after the Messenger was closed.
+var ErrClosed = errors.New("ephemeral: messenger closed")
+
+// Config contains the configuration for an ephemeral-key Messenger.
+type Config struct {
+	// Self is the index of the local party.
+	Self int
+	// Peers lists the indices of all other parties in the session.
+	Peers []int
+	// SessionID is bound into the keys, so that messages of one session can
+	// never be accepted in another.  Every party must pass the same ID.
+	SessionID []byte
+	// Identity is the long-term Ed25519 identity key of the local party.  It
+	// signs the ephemeral key sent to every peer.
+	Identity ed25519.PrivateKey
+	// PeerKeys holds the identity public key of every peer, which must have
+	// signed the ephemeral key it sends.
+	PeerKeys map[int]ed25519.PublicKey
+}
+
+// Params describes the negotiated channel.  It holds no key material.
+type Params struct {
+	KeyExchange string         `json:"key_exchange"`
+	Signature   string         `json:"signature"` // Signature scheme of the ephemeral keys
+	KDF         string         `json:"kdf"`
+	Cipher      string         `json:"cipher"`
+	SessionID   []byte         `json:"session_id,omitempty"`
+	Transcripts map[int][]byte `json:"transcripts"` // Per peer, SHA-256 of the key exchange
+}
+
+// Messenger implements transport.Messenger on top of another Messenger,
+// encrypting every message under per-session keys.
+type Messenger struct {
+	inner  transport.Messenger
+	links  map[int]*link
+	params Params
+}
+
+// link holds the keys of one peer.
+type link struct {
+	sendMu sync.Mutex
+	send   cipher.AEAD // nil once closed
+	sent   uint64
+
+	recvMu   sync.Mutex
+	recv     cipher.AEAD // nil once closed
+	received uint64
+}
+
+// Ensure Messenger implements the Messenger interface
+var _ transport.Messenger = (*Messenger)(nil)
+
+// NewMessenger runs the key exchange with every peer over inner and returns
+// the wrapped Messenger.  All peers must call NewMessenger at the same point
+// of the session.
+func NewMessenger(ctx context.Context, inner transport.Messenger, config Config) (*Messenger, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("inner messenger cannot be nil")
+	}
+	if len(config.Peers) == 0 {
+		return nil, fmt.Errorf("at least one peer must be provided")
+	}
+	seen := make(map[int]bool, len(config.Peers))
+	for _, peer := range config.Peers {
+		if seen[peer] || peer == config.Self {
+			return nil, fmt.Errorf("duplicate peer %d", peer)
+		}
+		seen[peer] = true
+		if len(config.PeerKeys[peer]) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("identity key of peer %d must be provided", peer)
+		}
+	}
+	if len(config.Identity) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("identity key must be provided")
+	}
+
+	// The private key never leaves this function.
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating ephemeral key: %v", err)
+	}
+	for _, peer := range config.Peers {
+		signed := helloMessage(config.SessionID, config.Self, peer, priv.PublicKey().Bytes())
+		hello := append([]byte{helloVersion}, priv.PublicKey().Bytes()...)
+		hello = append(hello, ed25519.Sign(config.Identity, signed)...)
+		if err := inner.MessageSend(ctx, peer, hello); err != nil {
+			return nil, fmt.Errorf("sending ephemeral key to %d: %v", peer, err)
+		}
+	}
+	hellos, err := inner.MessagesReceive(ctx, config.Peers)
+	if err != nil {
+		return nil, fmt.Errorf("receiving ephemeral keys: %v", err)
+	}
+
+	m := &Messenger{
+		inner: inner,
+		links: make(map[int]*link, len(config.Peers)),
+		params: Params{
+			KeyExchange: KeyExchangeX25519,
+			Signature:   SignatureEd25519,
+			KDF:         KDFHKDFSHA256,
+			Cipher:      CipherAES256GCM,
+			SessionID:   append([]byte(nil), config.SessionID...),
+			Transcripts: make(map[int][]byte, len(config.Peers)),
+		},
+	}
+	for i, peer := range config.Peers {
+		if len(hellos[i]) != 1+32+ed25519.SignatureSize || hellos[i][0] != helloVersion {
+			return nil, fmt.Errorf("invalid ephemeral key message from %d", peer)
+		}
+		key, sig := hellos[i][1:33], hellos[i][33:]
+		// A proxy between the parties could swap unsigned ephemeral keys
+		// for its own and read every message.
+		if !ed25519.Verify(config.PeerKeys[peer], helloMessage(config.SessionID, peer, config.Self, key), sig) {
+			return nil, fmt.Errorf("ephemeral key from %d is not signed by its identity key", peer)
+		}
+		pub, err := ecdh.X25519().NewPublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ephemeral key from %d: %v", peer, err)
+		}
+		secret, err := priv.ECDH(pub)
+		if err != nil {
+			// A low-order point yields an all-zero secret.
+			return nil, fmt.Errorf("key exchange with %d: %v", peer, err)
+		}
+		transcript := transcriptHash(config.SessionID, config.Self, priv.PublicKey().Bytes(), peer, pub.Bytes())
+		prk := hkdf.Extract(sha256.New, secret, transcript)
+		clear(secret)
+		l := &link{}
+		if l.send, err = newAEAD(prk, directionInfo(config.Self, peer)); err != nil {
+			return nil, err
+		}
+		if l.recv, err = newAEAD(prk, directionInfo(peer, config.Self)); err != nil {
+			return nil, err
+		}
+		clear(prk)
+		m.links[peer] = l
+		m.params.Transcripts[peer] = transcript
+	}
+	return m, nil
+}
+
+// helloMessage is what sender signs with its identity key for receiver: the
+// ephemeral key bound to the session and both parties.
+func helloMessage(sessionID []byte, sender, receiver int, key []byte) []byte {
+	msg := []byte(label + " hello")
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(sessionID)))
+	msg = append(msg, sessionID...)
+	msg = binary.BigEndian.AppendUint32(msg, uint32(sender))
+	msg = binary.BigEndian.AppendUint32(msg, uint32(receiver))
+	return append(msg, key...)
+}
+
+// transcriptHash hashes the key exchange between parties a and b in an order
+// both sides agree on.
+func transcriptHash(sessionID []byte, a int, pubA []byte, b int, pubB []byte) []byte {
+	if a > b {
+		a, pubA, b, pubB = b, pubB, a, pubA
+	}
+	h := sha256.New()
+	h.Write([]byte(label))
+	binary.Write(h, binary.BigEndian, uint32(len(sessionID)))
+	h.Write(sessionID)
+	binary.Write(h, binary.BigEndian, uint32(a))
+	h.Write(pubA)
+	binary.Write(h, binary.BigEndian, uint32(b))
+	h.Write(pubB)
+	return h.Sum(nil)
+}
+
+// directionInfo is the HKDF info of the key for messages from sender to
+// receiver.
+func directionInfo(sender, receiver int) []byte {
+	info := binary.BigEndian.AppendUint32([]byte(label+" key "), uint32(sender))
+	return binary.BigEndian.AppendUint32(info, uint32(receiver))
+}
+
+// newAEAD expands the AES-256-GCM key for info from prk with HKDF-SHA256.
+func newAEAD(prk, info []byte) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	defer clear(key)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, info), key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the GCM nonce of the n-th message of a direction.
+func nonce(n uint64) []byte {
+	return binary.BigEndian.AppendUint64(make([]byte, 4, 12), n)
+}
+
+// Params returns the negotiated parameters.
+func (m *Messenger) Params() Params {
+	p := m.params
+	p.Transcripts = make(map[int][]byte, len(m.params.Transcripts))
+	for peer, t := range m.params.Transcripts {
+		p.Transcripts[peer] = append([]byte(nil), t...)
+	}
+	return p
+}
+
+// MessageSend sends a message to the specified receiver party
+func (m *Messenger) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	l, ok := m.links[receiver]
+	if !ok {
+		return fmt.Errorf("unknown party %d", receiver)
+	}
+	l.sendMu.Lock()
+	defer l.sendMu.Unlock()
+	if l.send == nil {
+		return ErrClosed
+	}
+	frame := l.send.Seal(nil, nonce(l.sent), buffer, nil)
+	l.sent++
+	return m.inner.MessageSend(ctx, receiver, frame)
+}
+
+// MessageReceive receives a message from the specified sender party
+func (m *Messenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	l, ok := m.links[sender]
+	if !ok {
+		return nil, fmt.Errorf("unknown party %d", sender)
+	}
+	l.recvMu.Lock()
+	defer l.recvMu.Unlock()
+	if l.recv == nil {
+		return nil, ErrClosed
+	}
+	frame, err := m.inner.MessageReceive(ctx, sender)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := l.recv.Open(nil, nonce(l.received), frame, nil)
+	if err != nil {
+		return nil, fmt.Errorf("message %d from party %d failed authentication", l.received, sender)
+	}
+	l.received++
+	return msg, nil
+}
+
+// MessagesReceive receives messages from multiple sender parties concurrently
+func (m *Messenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	msgs := make([][]byte, len(senders))
+	errs := make([]error, len(senders))
+	var wg sync.WaitGroup
+	for i, sender := range senders {
+		wg.Add(1)
+		go func(i, sender int) {
+			defer wg.Done()
+			msgs[i], errs[i] = m.MessageReceive(ctx, sender)
+		}(i, sender)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Close drops the session keys.  It does not close the underlying
+// Messenger.  Calls blocked in MessageReceive keep their key until they
+// return.
+func (m *Messenger) Close() error {
+	for _, l := range m.links {
+		l.sendMu.Lock()
+		l.send = nil
+		l.sendMu.Unlock()
+		if l.recvMu.TryLock() {
+			l.recv = nil
+			l.recvMu.Unlock()
+		}
+	}
+	return nil
+}
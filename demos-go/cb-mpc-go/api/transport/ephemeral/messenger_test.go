@@ -0,0 +1,221 @@
+package ephemeral
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+)
+
+// identities returns an identity key for each of n parties and the map of
+// their public keys.
+func identities(t *testing.T, n int) ([]ed25519.PrivateKey, map[int]ed25519.PublicKey) {
+	t.Helper()
+	keys := make([]ed25519.PrivateKey, n)
+	public := make(map[int]ed25519.PublicKey, n)
+	for i := range keys {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		keys[i], public[i] = priv, pub
+	}
+	return keys, public
+}
+
+// network runs the key exchange for n parties over links, with one session
+// ID per party.
+func network(t *testing.T, links []*mocknet.MockMessenger, sessionIDs ...string) ([]*Messenger, []error) {
+	t.Helper()
+	n := len(links)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	keys, public := identities(t, n)
+	ms := make([]*Messenger, n)
+	errs := make([]error, n)
+	done := make(chan int, n)
+	for i := range links {
+		config := Config{Self: i, SessionID: []byte(sessionIDs[i]), Identity: keys[i], PeerKeys: public}
+		for j := 0; j < n; j++ {
+			if j != i {
+				config.Peers = append(config.Peers, j)
+			}
+		}
+		go func(i int) {
+			ms[i], errs[i] = NewMessenger(ctx, links[i], config)
+			done <- i
+		}(i)
+	}
+	for range links {
+		<-done
+	}
+	return ms, errs
+}
+
+func connect(t *testing.T, sessionID string, n int) ([]*Messenger, []*mocknet.MockMessenger) {
+	t.Helper()
+	links := mocknet.NewMockNetwork(n)
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = sessionID
+	}
+	ms, errs := network(t, links, ids...)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	return ms, links
+}
+
+func TestRoundTrip(t *testing.T) {
+	ms, links := connect(t, "session-1", 3)
+	ctx := context.Background()
+	for round := 0; round < 3; round++ {
+		for i, m := range ms {
+			for j := range ms {
+				if j != i {
+					require.NoError(t, m.MessageSend(ctx, j, []byte(fmt.Sprintf("%d->%d round %d", i, j, round))))
+				}
+			}
+		}
+		msgs, err := ms[0].MessagesReceive(ctx, []int{1, 2})
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("1->0 round %d", round), string(msgs[0]))
+		assert.Equal(t, fmt.Sprintf("2->0 round %d", round), string(msgs[1]))
+		for _, i := range []int{1, 2} {
+			msgs, err := ms[i].MessagesReceive(ctx, []int{0, 3 - i})
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("0->%d round %d", i, round), string(msgs[0]))
+		}
+	}
+
+	// Nothing readable crosses the wrapped Messenger.
+	require.NoError(t, ms[0].MessageSend(ctx, 1, []byte("secret share")))
+	frame, err := links[1].MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.NotContains(t, string(frame), "secret share")
+}
+
+func TestParams(t *testing.T) {
+	ms, _ := connect(t, "session-1", 3)
+	p := ms[0].Params()
+	assert.Equal(t, KeyExchangeX25519, p.KeyExchange)
+	assert.Equal(t, SignatureEd25519, p.Signature)
+	assert.Equal(t, KDFHKDFSHA256, p.KDF)
+	assert.Equal(t, CipherAES256GCM, p.Cipher)
+	assert.Equal(t, []byte("session-1"), p.SessionID)
+	require.Len(t, p.Transcripts, 2)
+
+	// Both ends of a link record the same transcript, and every link has its
+	// own.
+	assert.Equal(t, p.Transcripts[1], ms[1].Params().Transcripts[0])
+	assert.Equal(t, p.Transcripts[2], ms[2].Params().Transcripts[0])
+	assert.Equal(t, ms[1].Params().Transcripts[2], ms[2].Params().Transcripts[1])
+	assert.NotEqual(t, p.Transcripts[1], p.Transcripts[2])
+
+	// Every session uses fresh keys.
+	again, _ := connect(t, "session-1", 3)
+	assert.NotEqual(t, p.Transcripts[1], again[0].Params().Transcripts[1])
+}
+
+func TestSessionIDMismatch(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	_, errs := network(t, links, "session-1", "session-2")
+	assert.ErrorContains(t, errs[0], "not signed by its identity key")
+	assert.ErrorContains(t, errs[1], "not signed by its identity key")
+}
+
+// TestProxySwapsKeys plays a TLS-terminating proxy that replaces both
+// ephemeral keys with its own, which the identity signatures expose.
+func TestProxySwapsKeys(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	keys, public := identities(t, 2)
+	_, proxy, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// The proxy re-signs with a key of its own, the best it can do without
+	// the parties' identity keys.
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forged := append([]byte{helloVersion}, eph.PublicKey().Bytes()...)
+	forged = append(forged, ed25519.Sign(proxy, helloMessage(nil, 1, 0, eph.PublicKey().Bytes()))...)
+	require.NoError(t, links[1].MessageSend(ctx, 0, forged))
+
+	_, err = NewMessenger(ctx, links[0], Config{Self: 0, Peers: []int{1}, Identity: keys[0], PeerKeys: public})
+	assert.ErrorContains(t, err, "ephemeral key from 1 is not signed by its identity key")
+}
+
+func TestTamperingDetected(t *testing.T) {
+	ms, links := connect(t, "session-1", 2)
+	ctx := context.Background()
+
+	// Injected.
+	require.NoError(t, links[0].MessageSend(ctx, 1, []byte("forged")))
+	_, err := ms[1].MessageReceive(ctx, 0)
+	assert.ErrorContains(t, err, "message 0 from party 0 failed authentication")
+
+	// Replayed: a genuine frame is only accepted at its own position.
+	ms, links = connect(t, "session-1", 2)
+	require.NoError(t, ms[0].MessageSend(ctx, 1, []byte("first")))
+	frame, err := links[1].MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	require.NoError(t, links[0].MessageSend(ctx, 1, frame))
+	msg, err := ms[1].MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(msg))
+	require.NoError(t, links[0].MessageSend(ctx, 1, frame))
+	_, err = ms[1].MessageReceive(ctx, 0)
+	assert.ErrorContains(t, err, "message 1 from party 0 failed authentication")
+}
+
+func TestInvalidHello(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keys, public := identities(t, 2)
+	config := Config{Self: 0, Peers: []int{1}, Identity: keys[0], PeerKeys: public}
+
+	// A low-order public key would make the shared secret predictable.
+	low := make([]byte, 32)
+	hello := append([]byte{helloVersion}, low...)
+	hello = append(hello, ed25519.Sign(keys[1], helloMessage(nil, 1, 0, low))...)
+	require.NoError(t, links[1].MessageSend(ctx, 0, hello))
+	_, err := NewMessenger(ctx, links[0], config)
+	assert.ErrorContains(t, err, "key exchange with 1")
+
+	require.NoError(t, links[1].MessageSend(ctx, 0, []byte{9}))
+	_, err = NewMessenger(ctx, links[0], config)
+	assert.ErrorContains(t, err, "invalid ephemeral key message from 1")
+}
+
+func TestConfigValidation(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	ctx := context.Background()
+	_, err := NewMessenger(ctx, nil, Config{Peers: []int{1}})
+	assert.Error(t, err)
+	_, err = NewMessenger(ctx, links[0], Config{})
+	assert.Error(t, err)
+	_, err = NewMessenger(ctx, links[0], Config{Peers: []int{1, 1}})
+	assert.Error(t, err)
+	_, err = NewMessenger(ctx, links[0], Config{Self: 0, Peers: []int{0, 1}})
+	assert.Error(t, err)
+	keys, public := identities(t, 2)
+	_, err = NewMessenger(ctx, links[0], Config{Self: 0, Peers: []int{1}, Identity: keys[0]})
+	assert.ErrorContains(t, err, "identity key of peer 1")
+	_, err = NewMessenger(ctx, links[0], Config{Self: 0, Peers: []int{1}, PeerKeys: public})
+	assert.ErrorContains(t, err, "identity key must be provided")
+}
+
+func TestClose(t *testing.T) {
+	ms, _ := connect(t, "session-1", 2)
+	require.NoError(t, ms[0].Close())
+	assert.ErrorIs(t, ms[0].MessageSend(context.Background(), 1, []byte("x")), ErrClosed)
+}
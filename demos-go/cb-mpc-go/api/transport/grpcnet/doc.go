@@ -0,0 +1,36 @@
+// Package grpcnet carries MPC messages over bidirectional gRPC streams, so
+// that cb-mpc parties can run inside existing gRPC services and share their
+// servers, credentials, interceptors and load balancing.
+//
+// A Node registers the Exchange service on the party's grpc.Server and dials
+// the servers of the other parties through ordinary client connections.  As
+// with mtls, the party with the lower index serves and the party with the
+// higher index dials, so each pair of parties shares one stream:
+//
+//	s := grpc.NewServer(grpc.Creds(creds))
+//	node, _ := grpcnet.New(grpcnet.Config{
+//		SelfIndex: 1, NParties: 3,
+//		Conns:  map[int]grpc.ClientConnInterface{0: conn0},
+//		Server: s,
+//	})
+//	go s.Serve(ln)
+//	_ = node.Connect(ctx)
+//	session, _ := node.Open(sessionID)
+//	defer session.Close()
+//	job, _ := mpc.NewJobMP(session, 3, 1, pnames)
+//
+// Every protocol message travels as one gRPC message, framed with the
+// sender's index and a per-stream sequence number that the receiver checks,
+// so a dropped, replayed or misrouted message fails the link instead of
+// desynchronising a protocol round.  Sessions are multiplexed with the mux
+// package: each frame carries its session ID, and many signing jobs run
+// concurrently over the same streams.
+//
+// The first frame of each stream names the dialing party.  That claim is
+// only as trustworthy as the connection: production deployments should use
+// mutual TLS credentials and check the peer's certificate in VerifyPeer.
+//
+// Frames use their own codec, selected by the "cbmpc-frame" content subtype,
+// so the package needs no generated protobuf code and leaves the codecs of
+// other services untouched.
+package grpcnet
@@ -0,0 +1,362 @@
+package grpcnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mux"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the name of the gRPC service a Node registers.
+const ServiceName = "cbmpc.transport.v1.Transport"
+
+const (
+	exchangeMethod        = "/" + ServiceName + "/Exchange"
+	defaultConnectTimeout = 30 * time.Second
+	defaultMaxMessageSize = 10 * 1024 * 1024
+	// maxParty bounds the party indices a frame may carry.
+	maxParty = 1 << 16
+)
+
+// ErrClosed is returned after the Node was closed.
+var ErrClosed = errors.New("grpcnet: node closed")
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Exchange",
+		Handler:       func(srv any, stream grpc.ServerStream) error { return srv.(*Node).serve(stream) },
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
+
+// Config contains the configuration for a Node.
+type Config struct {
+	// SelfIndex is the index of this party.
+	SelfIndex int
+	// NParties is the number of parties, indexed from 0.
+	NParties int
+	// Conns maps every party with a lower index to a client connection to
+	// the gRPC server it registered its Node on.
+	Conns map[int]grpc.ClientConnInterface
+	// Server receives the Exchange service, which parties with a higher
+	// index dial.  Required unless this party has the highest index.  It
+	// must not be serving yet, and holds at most one Node.
+	Server grpc.ServiceRegistrar
+	// VerifyPeer, if set, is called for every incoming stream with the
+	// index the peer claims and the stream context, from which
+	// peer.FromContext returns its address and TLS state.  Returning an
+	// error rejects the stream without aborting the setup.
+	VerifyPeer func(ctx context.Context, party int) error
+	// ConnectTimeout bounds Connect.  Defaults to 30s.
+	ConnectTimeout time.Duration
+	// MaxMessageSize limits a single message sent or received on the
+	// streams this Node dials.  The server's limit is set with
+	// grpc.MaxRecvMsgSize.  Defaults to 10 MiB.
+	MaxMessageSize int
+	// Mux configures the session multiplexer; Peers is filled in.
+	Mux mux.Config
+}
+
+// Node is one party's end of the streams to every other party.  Sessions
+// opened on it are transport.Messengers.
+type Node struct {
+	config Config
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	links  map[int]*link
+	ready  chan struct{} // Closed once every peer is linked
+	mux    *mux.Mux
+	closed bool
+}
+
+// link is the stream to one peer.
+type link struct {
+	party  int
+	stream grpc.Stream
+	sendMu sync.Mutex
+	sent   uint64
+	recv   chan []byte
+	done   chan struct{} // Closed once reading failed
+	err    error         // Set before done is closed
+}
+
+// conns exposes the links of a Node as the Messenger the multiplexer runs
+// over.
+type conns Node
+
+// Ensure conns implements the Messenger interface
+var _ transport.Messenger = (*conns)(nil)
+
+// New returns a Node and registers its service on config.Server.  Call
+// Connect once the server is serving.
+func New(config Config) (*Node, error) {
+	if config.NParties < 2 || config.NParties > maxParty {
+		return nil, fmt.Errorf("number of parties must be between 2 and %d", maxParty)
+	}
+	if config.SelfIndex < 0 || config.SelfIndex >= config.NParties {
+		return nil, fmt.Errorf("self index %d out of range", config.SelfIndex)
+	}
+	for i := 0; i < config.SelfIndex; i++ {
+		if config.Conns[i] == nil {
+			return nil, fmt.Errorf("no connection to party %d", i)
+		}
+	}
+	if config.SelfIndex < config.NParties-1 && config.Server == nil {
+		return nil, fmt.Errorf("server must be provided")
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaultConnectTimeout
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = defaultMaxMessageSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Node{config: config, ctx: ctx, cancel: cancel, links: make(map[int]*link), ready: make(chan struct{})}
+	if config.Server != nil {
+		config.Server.RegisterService(&serviceDesc, n)
+	}
+	return n, nil
+}
+
+// Connect dials every party with a lower index and waits until every party
+// with a higher index has dialed in.
+func (n *Node) Connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, n.config.ConnectTimeout)
+	defer cancel()
+
+	eg := errgroup.Group{}
+	for i := 0; i < n.config.SelfIndex; i++ {
+		eg.Go(func() error {
+			if err := n.dial(ctx, i); err != nil {
+				return fmt.Errorf("connecting to party %d: %w", i, err)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	select {
+	case <-n.ready:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for parties to connect: %w", ctx.Err())
+	case <-n.ctx.Done():
+		return ErrClosed
+	}
+
+	peers := make([]int, 0, n.config.NParties-1)
+	for i := 0; i < n.config.NParties; i++ {
+		if i != n.config.SelfIndex {
+			peers = append(peers, i)
+		}
+	}
+	muxConfig := n.config.Mux
+	muxConfig.Peers = peers
+	m, err := mux.New((*conns)(n), muxConfig)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		m.Close()
+		return ErrClosed
+	}
+	n.mux = m
+	return nil
+}
+
+// Open starts the session with the given ID on every link.  All parties of a
+// job open the same ID.  Close the returned stream when the job is done.
+func (n *Node) Open(sessionID string) (*mux.Stream, error) {
+	n.mu.Lock()
+	m, closed := n.mux, n.closed
+	n.mu.Unlock()
+	switch {
+	case closed:
+		return nil, ErrClosed
+	case m == nil:
+		return nil, fmt.Errorf("node is not connected")
+	}
+	return m.Open(sessionID)
+}
+
+// Close ends every session and stream.  It does not stop the server.
+func (n *Node) Close() error {
+	n.mu.Lock()
+	n.closed = true
+	m := n.mux
+	n.mu.Unlock()
+	if m != nil {
+		m.Close()
+	}
+	n.cancel()
+	return nil
+}
+
+// dial opens the stream to a party with a lower index and exchanges hellos.
+func (n *Node) dial(ctx context.Context, party int) error {
+	// The stream lives as long as the Node; only the setup is bounded by ctx.
+	streamCtx, cancel := context.WithCancel(n.ctx)
+	stop := context.AfterFunc(ctx, cancel)
+	stream, err := n.config.Conns[party].NewStream(streamCtx, &serviceDesc.Streams[0], exchangeMethod,
+		grpc.CallContentSubtype(codecName),
+		grpc.WaitForReady(true),
+		grpc.MaxCallRecvMsgSize(n.config.MaxMessageSize),
+		grpc.MaxCallSendMsgSize(n.config.MaxMessageSize),
+	)
+	if err == nil {
+		err = stream.SendMsg(&frame{Party: n.config.SelfIndex})
+	}
+	var hello frame
+	if err == nil {
+		err = stream.RecvMsg(&hello)
+	}
+	if err == nil && (hello.Party != party || hello.Seq != 0) {
+		err = fmt.Errorf("peer answered as party %d", hello.Party)
+	}
+	if !stop() || err != nil {
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			err = errors.Join(ctx.Err(), err)
+		}
+		return err
+	}
+	return n.add(party, stream)
+}
+
+// serve handles an Exchange stream dialed by a party with a higher index.
+func (n *Node) serve(stream grpc.ServerStream) error {
+	var hello frame
+	if err := stream.RecvMsg(&hello); err != nil {
+		return err
+	}
+	party := hello.Party
+	if hello.Seq != 0 || party <= n.config.SelfIndex || party >= n.config.NParties {
+		return status.Errorf(codes.PermissionDenied, "unexpected party %d", party)
+	}
+	if n.config.VerifyPeer != nil {
+		if err := n.config.VerifyPeer(stream.Context(), party); err != nil {
+			return status.Errorf(codes.PermissionDenied, "party %d: %v", party, err)
+		}
+	}
+	if err := stream.SendMsg(&frame{Party: n.config.SelfIndex}); err != nil {
+		return err
+	}
+	if err := n.add(party, stream); err != nil {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	// Returning would end the stream.
+	select {
+	case <-n.ctx.Done():
+	case <-stream.Context().Done():
+	}
+	return nil
+}
+
+// add registers the stream to party and starts reading from it.
+func (n *Node) add(party int, stream grpc.Stream) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return ErrClosed
+	}
+	if _, dup := n.links[party]; dup {
+		return fmt.Errorf("party %d connected twice", party)
+	}
+	l := &link{party: party, stream: stream, recv: make(chan []byte), done: make(chan struct{})}
+	n.links[party] = l
+	if len(n.links) == n.config.NParties-1 {
+		close(n.ready)
+	}
+	go n.read(l)
+	return nil
+}
+
+// read delivers the frames of l in order until the stream fails.
+func (n *Node) read(l *link) {
+	defer close(l.done)
+	for next := uint64(1); ; next++ {
+		var f frame
+		if err := l.stream.RecvMsg(&f); err != nil {
+			l.err = err
+			return
+		}
+		if f.Party != l.party || f.Seq != next {
+			l.err = fmt.Errorf("frame %d from party %d out of sequence, want frame %d from party %d",
+				f.Seq, f.Party, next, l.party)
+			return
+		}
+		select {
+		case l.recv <- f.Payload:
+		case <-n.ctx.Done():
+			l.err = ErrClosed
+			return
+		}
+	}
+}
+
+func (c *conns) link(party int) (*link, error) {
+	n := (*Node)(c)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	l, ok := n.links[party]
+	if !ok {
+		return nil, fmt.Errorf("no stream to party %d", party)
+	}
+	return l, nil
+}
+
+// MessageSend sends a message to the specified receiver party.
+func (c *conns) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	l, err := c.link(receiver)
+	if err != nil {
+		return err
+	}
+	l.sendMu.Lock()
+	defer l.sendMu.Unlock()
+	l.sent++
+	return l.stream.SendMsg(&frame{Party: c.config.SelfIndex, Seq: l.sent, Payload: buffer})
+}
+
+// MessageReceive receives the next message from the specified sender party.
+func (c *conns) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	l, err := c.link(sender)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case msg := <-l.recv:
+		return msg, nil
+	case <-l.done:
+		return nil, l.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// MessagesReceive receives messages from multiple sender parties.
+func (c *conns) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	out := make([][]byte, len(senders))
+	for i, sender := range senders {
+		msg, err := c.MessageReceive(ctx, sender)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = msg
+	}
+	return out, nil
+}
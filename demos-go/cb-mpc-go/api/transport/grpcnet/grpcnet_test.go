@@ -0,0 +1,168 @@
+package grpcnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// party is one party's server and its connections to the others.
+type party struct {
+	server *grpc.Server
+	ln     net.Listener
+	conns  map[int]grpc.ClientConnInterface
+}
+
+func newParties(t *testing.T, n int) []*party {
+	t.Helper()
+	ps := make([]*party, n)
+	for i := range ps {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ps[i] = &party{server: grpc.NewServer(), ln: ln, conns: make(map[int]grpc.ClientConnInterface)}
+		t.Cleanup(ps[i].server.Stop)
+	}
+	for i, p := range ps {
+		for j := 0; j < i; j++ {
+			conn, err := grpc.NewClient(ps[j].ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			t.Cleanup(func() { conn.Close() })
+			p.conns[j] = conn
+		}
+	}
+	return ps
+}
+
+// network connects n parties, adjusting each Config with configure.
+func network(t *testing.T, n int, configure func(*Config)) []*Node {
+	t.Helper()
+	ps := newParties(t, n)
+	nodes := make([]*Node, n)
+	for i, p := range ps {
+		config := Config{SelfIndex: i, NParties: n, Conns: p.conns, Server: p.server, ConnectTimeout: 5 * time.Second}
+		if configure != nil {
+			configure(&config)
+		}
+		node, err := New(config)
+		require.NoError(t, err)
+		t.Cleanup(func() { node.Close() })
+		nodes[i] = node
+		go p.server.Serve(p.ln)
+	}
+	eg := errgroup.Group{}
+	for _, node := range nodes {
+		eg.Go(func() error { return node.Connect(context.Background()) })
+	}
+	require.NoError(t, eg.Wait())
+	return nodes
+}
+
+func withTimeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	nodes := network(t, 3, nil)
+	ctx := withTimeout(t)
+
+	eg := errgroup.Group{}
+	for s := 0; s < 8; s++ {
+		for i, node := range nodes {
+			eg.Go(func() error {
+				session, err := node.Open(fmt.Sprintf("session-%d", s))
+				if err != nil {
+					return err
+				}
+				defer session.Close()
+				for round := 0; round < 3; round++ {
+					for j := range nodes {
+						if j != i {
+							if err := session.MessageSend(ctx, j, []byte(fmt.Sprintf("%d:%d:%d", s, round, i))); err != nil {
+								return err
+							}
+						}
+					}
+					var senders []int
+					for j := range nodes {
+						if j != i {
+							senders = append(senders, j)
+						}
+					}
+					msgs, err := session.MessagesReceive(ctx, senders)
+					if err != nil {
+						return err
+					}
+					for k, j := range senders {
+						if want := fmt.Sprintf("%d:%d:%d", s, round, j); string(msgs[k]) != want {
+							return fmt.Errorf("got %q, want %q", msgs[k], want)
+						}
+					}
+				}
+				return nil
+			})
+		}
+	}
+	require.NoError(t, eg.Wait())
+}
+
+func TestVerifyPeerRejects(t *testing.T) {
+	ps := newParties(t, 2)
+	node0, err := New(Config{SelfIndex: 0, NParties: 2, Server: ps[0].server, ConnectTimeout: time.Second,
+		VerifyPeer: func(context.Context, int) error { return errors.New("unknown certificate") }})
+	require.NoError(t, err)
+	defer node0.Close()
+	go ps[0].server.Serve(ps[0].ln)
+
+	node1, err := New(Config{SelfIndex: 1, NParties: 2, Conns: ps[1].conns, ConnectTimeout: time.Second})
+	require.NoError(t, err)
+	defer node1.Close()
+	assert.ErrorContains(t, node1.Connect(context.Background()), "unknown certificate")
+	assert.Error(t, node0.Connect(context.Background()), "party 1 never connected")
+}
+
+func TestConnectTimeout(t *testing.T) {
+	ps := newParties(t, 2)
+	node, err := New(Config{SelfIndex: 1, NParties: 2, Conns: ps[1].conns, ConnectTimeout: 300 * time.Millisecond})
+	require.NoError(t, err)
+	defer node.Close()
+	start := time.Now()
+	err = node.Connect(context.Background()) // Party 0 never serves
+	assert.ErrorContains(t, err, "connecting to party 0")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestOpenBeforeConnect(t *testing.T) {
+	ps := newParties(t, 2)
+	node, err := New(Config{SelfIndex: 1, NParties: 2, Conns: ps[1].conns})
+	require.NoError(t, err)
+	_, err = node.Open("session")
+	assert.Error(t, err)
+	node.Close()
+	_, err = node.Open("session")
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	in := &frame{Party: 3, Seq: 300, Payload: []byte("round 2")}
+	data, err := codec{}.Marshal(in)
+	require.NoError(t, err)
+	var out frame
+	require.NoError(t, codec{}.Unmarshal(data, &out))
+	assert.Equal(t, *in, out)
+
+	assert.Error(t, out.decode(nil))
+	assert.Error(t, out.decode([]byte{2, 3, 1}), "unknown version")
+	assert.Error(t, out.decode([]byte{frameVersion, 0x80}), "truncated")
+}
@@ -0,0 +1,26 @@
+// Package identity supplies the TLS identity of an MPC party from a
+// crypto.Signer, so the private key can stay in an HSM (PKCS#11), a cloud KMS
+// or a TPM instead of a file on the host.
+//
+// A `Provider` pairs a signer with the certificate chain that names the party:
+//
+//	// PKCS#11 libraries such as crypto11 return a crypto.Signer directly.
+//	signer, _ := ctx11.FindKeyPair(nil, []byte("party-1"))
+//	id, _ := identity.New(signer, leafDER)
+//
+//	// KMS SDKs usually expose a sign call; adapt it with RemoteSigner.
+//	id, _ = identity.New(&identity.RemoteSigner{
+//	    PublicKey: pub,
+//	    SignFunc: func(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+//	        return kmsSign(ctx, keyARN, digest, opts)
+//	    },
+//	}, leafDER)
+//
+//	cfg := mtls.Config{Identity: id /* … */}
+//
+// The TLS stack then asks the signer for one signature per handshake and
+// never sees the key, so a compromised host can impersonate the party only
+// while the attacker keeps access to the signer — it does not yield a
+// reusable identity key.  `LoadFiles` keeps file-based keys working for
+// development.
+package identity
@@ -0,0 +1,100 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+)
+
+// Provider supplies a party's TLS identity.
+type Provider interface {
+	// Signer returns the handle of the identity key.  It is used for every
+	// handshake and may be backed by an HSM or KMS.
+	Signer() crypto.Signer
+	// Chain returns the DER-encoded certificate chain, leaf first.
+	Chain() [][]byte
+}
+
+// TLSCertificate builds a tls.Certificate whose private key is p's signer.
+// It checks that the signer's public key matches the leaf certificate.
+func TLSCertificate(p Provider) (tls.Certificate, error) {
+	chain := p.Chain()
+	if len(chain) == 0 {
+		return tls.Certificate{}, fmt.Errorf("identity has no certificate")
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parsing leaf certificate: %v", err)
+	}
+	signer := p.Signer()
+	if signer == nil {
+		return tls.Certificate{}, fmt.Errorf("identity has no signer")
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(leaf.PublicKey) {
+		return tls.Certificate{}, fmt.Errorf("signer does not match the leaf certificate")
+	}
+	return tls.Certificate{Certificate: chain, PrivateKey: signer, Leaf: leaf}, nil
+}
+
+// staticProvider is a Provider with a fixed signer and chain.
+type staticProvider struct {
+	signer crypto.Signer
+	chain  [][]byte
+}
+
+func (p *staticProvider) Signer() crypto.Signer { return p.signer }
+func (p *staticProvider) Chain() [][]byte       { return p.chain }
+
+// New returns a Provider for signer and the DER certificate chain, leaf
+// first.  It fails if the signer does not match the leaf.
+func New(signer crypto.Signer, chain ...[]byte) (Provider, error) {
+	p := &staticProvider{signer: signer, chain: chain}
+	if _, err := TLSCertificate(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadFiles returns a Provider for a PEM certificate and key on disk.  The key
+// is held in process memory; use it for development only.
+func LoadFiles(certFile, keyFile string) (Provider, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading key pair: %v", err)
+	}
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key in %s cannot sign", keyFile)
+	}
+	return New(signer, cert.Certificate...)
+}
+
+// RemoteSigner adapts a remote signing call, such as a KMS Sign API, to
+// crypto.Signer.
+type RemoteSigner struct {
+	// PublicKey is the public half of the remote key.
+	PublicKey crypto.PublicKey
+	// SignFunc signs digest, which has already been hashed with
+	// opts.HashFunc() (or is the full message for Ed25519).  For RSA,
+	// opts may be *rsa.PSSOptions, which TLS 1.3 requires.
+	SignFunc func(digest []byte, opts crypto.SignerOpts) ([]byte, error)
+}
+
+// Ensure RemoteSigner implements the crypto.Signer interface
+var _ crypto.Signer = (*RemoteSigner)(nil)
+
+// Public implements crypto.Signer.
+func (s *RemoteSigner) Public() crypto.PublicKey { return s.PublicKey }
+
+// Sign implements crypto.Signer.  rand is ignored; the remote side supplies
+// its own randomness.
+func (s *RemoteSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	sig, err := s.SignFunc(digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("remote signing: %w", err)
+	}
+	return sig, nil
+}
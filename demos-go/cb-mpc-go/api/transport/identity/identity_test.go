@@ -0,0 +1,125 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func selfSigned(t *testing.T, name string) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return key, der
+}
+
+// hsm stands in for a key that never leaves a device: only signatures come
+// out, and they are counted.
+func hsm(key *ecdsa.PrivateKey, calls *atomic.Int32) *RemoteSigner {
+	return &RemoteSigner{
+		PublicKey: key.Public(),
+		SignFunc: func(digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+			calls.Add(1)
+			return key.Sign(rand.Reader, digest, opts)
+		},
+	}
+}
+
+func TestMutualTLSWithRemoteSigners(t *testing.T) {
+	serverKey, serverDER := selfSigned(t, "party-0")
+	clientKey, clientDER := selfSigned(t, "party-1")
+	var serverCalls, clientCalls atomic.Int32
+
+	serverID, err := New(hsm(serverKey, &serverCalls), serverDER)
+	require.NoError(t, err)
+	clientID, err := New(hsm(clientKey, &clientCalls), clientDER)
+	require.NoError(t, err)
+	serverCert, err := TLSCertificate(serverID)
+	require.NoError(t, err)
+	clientCert, err := TLSCertificate(clientID)
+	require.NoError(t, err)
+	_, isFileKey := serverCert.PrivateKey.(*ecdsa.PrivateKey)
+	assert.False(t, isFileKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(serverCert.Leaf)
+	pool.AddCert(clientCert.Leaf)
+
+	a, b := net.Pipe()
+	server := tls.Server(a, &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	client := tls.Client(b, &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{clientCert},
+		// Like mtls, pin the peer certificate instead of verifying a name.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			assert.Equal(t, serverDER, raw[0])
+			return nil
+		},
+	})
+	done := make(chan error, 1)
+	go func() { done <- server.Handshake() }()
+	require.NoError(t, client.Handshake())
+	require.NoError(t, <-done)
+	a.Close()
+	b.Close()
+
+	assert.Equal(t, int32(1), serverCalls.Load())
+	assert.Equal(t, int32(1), clientCalls.Load())
+}
+
+func TestNewRejectsMismatchedSigner(t *testing.T) {
+	key, _ := selfSigned(t, "a")
+	_, der := selfSigned(t, "b")
+	_, err := New(key, der)
+	assert.ErrorContains(t, err, "does not match")
+	_, err = New(key)
+	assert.Error(t, err)
+}
+
+func TestLoadFiles(t *testing.T) {
+	key, der := selfSigned(t, "party")
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	p, err := LoadFiles(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{der}, p.Chain())
+}
@@ -0,0 +1,20 @@
+// Package liveness wraps any `transport.Messenger` with per-round timeouts and
+// heartbeat keepalives.
+//
+// Without it a party that silently stalls – a hung process, a half-open TCP
+// connection, an operator who walked away from a PIN prompt – is only noticed
+// when the whole operation's deadline expires.  The liveness Messenger instead
+// detects it at the level of individual protocol rounds:
+//
+//   - every party sends a small ping frame to each peer every
+//     HeartbeatInterval; a peer from which nothing (ping or data) has arrived
+//     for HeartbeatTimeout is reported as unresponsive within seconds, and
+//   - every MessageReceive is bounded by the sender's round timeout, which
+//     catches a peer whose process is alive but no longer makes progress.
+//
+// Both conditions surface as a `*StallError` naming the party and the round,
+// so a coordinator can exclude that party and retry with a different quorum.
+//
+// The wrapper adds a one-byte frame header to every message, so either all
+// parties of a session use it or none does.
+package liveness
@@ -0,0 +1,293 @@
+package liveness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+const (
+	defaultHeartbeatInterval = time.Second
+	defaultRoundTimeout      = 30 * time.Second
+	heartbeatTimeoutFactor   = 3
+)
+
+// Frame types prepended to every message sent through the underlying
+// Messenger.
+const (
+	frameData byte = iota
+	framePing
+)
+
+// ErrUnresponsive is wrapped by every StallError.
+var ErrUnresponsive = errors.New("liveness: party unresponsive")
+
+// StallError reports a party that stopped making progress.
+type StallError struct {
+	Party     int           // Index of the unresponsive party
+	Round     int           // 1-based number of the message being awaited from Party
+	Heartbeat bool          // True if heartbeats stopped, false if only the round timed out
+	Elapsed   time.Duration // Time spent waiting before giving up
+}
+
+func (e *StallError) Error() string {
+	reason := "round timeout"
+	if e.Heartbeat {
+		reason = "heartbeat lost"
+	}
+	return fmt.Sprintf("party %d unresponsive in round %d after %v: %s", e.Party, e.Round, e.Elapsed.Round(time.Millisecond), reason)
+}
+
+func (e *StallError) Unwrap() error { return ErrUnresponsive }
+
+// Config contains the configuration for a liveness Messenger.
+type Config struct {
+	// Peers lists the indices of all other parties in the session.
+	Peers []int
+	// RoundTimeout bounds every MessageReceive.  Defaults to 30s.
+	RoundTimeout time.Duration
+	// PeerRoundTimeouts overrides RoundTimeout for individual parties, e.g.
+	// a longer timeout for a party that waits for human input.
+	PeerRoundTimeouts map[int]time.Duration
+	// HeartbeatInterval is how often pings are sent.  Defaults to 1s.
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout is how long a peer may stay completely silent.
+	// Defaults to three heartbeat intervals.
+	HeartbeatTimeout time.Duration
+}
+
+// Messenger implements transport.Messenger on top of another Messenger,
+// adding heartbeats and per-round timeouts.
+type Messenger struct {
+	inner            transport.Messenger
+	peers            map[int]*peer
+	heartbeat        time.Duration
+	heartbeatTimeout time.Duration
+	cancel           context.CancelFunc
+}
+
+// Ensure Messenger implements the Messenger interface
+var _ transport.Messenger = (*Messenger)(nil)
+
+// peer holds the state of the link to a single party.
+type peer struct {
+	index        int
+	roundTimeout time.Duration
+	sendMu       sync.Mutex
+
+	mu       sync.Mutex
+	queue    [][]byte
+	lastSeen time.Time
+	round    int
+	err      error
+	notify   chan struct{}
+}
+
+// NewMessenger wraps inner and starts sending heartbeats to all peers.  The
+// Messenger takes over all reads from inner; call Close to stop it.
+func NewMessenger(inner transport.Messenger, config Config) (*Messenger, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("inner messenger cannot be nil")
+	}
+	if len(config.Peers) == 0 {
+		return nil, fmt.Errorf("at least one peer must be provided")
+	}
+	heartbeat := config.HeartbeatInterval
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeatInterval
+	}
+	heartbeatTimeout := config.HeartbeatTimeout
+	if heartbeatTimeout <= 0 {
+		heartbeatTimeout = heartbeatTimeoutFactor * heartbeat
+	}
+	if heartbeatTimeout <= heartbeat {
+		return nil, fmt.Errorf("heartbeat timeout %v must exceed heartbeat interval %v", heartbeatTimeout, heartbeat)
+	}
+	roundTimeout := config.RoundTimeout
+	if roundTimeout <= 0 {
+		roundTimeout = defaultRoundTimeout
+	}
+
+	now := time.Now()
+	peers := make(map[int]*peer, len(config.Peers))
+	for _, index := range config.Peers {
+		if _, dup := peers[index]; dup {
+			return nil, fmt.Errorf("duplicate peer %d", index)
+		}
+		timeout := roundTimeout
+		if t, ok := config.PeerRoundTimeouts[index]; ok && t > 0 {
+			timeout = t
+		}
+		peers[index] = &peer{index: index, roundTimeout: timeout, lastSeen: now, notify: make(chan struct{}, 1)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Messenger{
+		inner:            inner,
+		peers:            peers,
+		heartbeat:        heartbeat,
+		heartbeatTimeout: heartbeatTimeout,
+		cancel:           cancel,
+	}
+	for _, p := range peers {
+		go m.read(ctx, p)
+	}
+	go m.ping(ctx)
+	return m, nil
+}
+
+// read demultiplexes frames from p until the underlying Messenger fails.
+func (m *Messenger) read(ctx context.Context, p *peer) {
+	for {
+		frame, err := m.inner.MessageReceive(ctx, p.index)
+		if err == nil && len(frame) == 0 {
+			err = fmt.Errorf("empty frame")
+		}
+
+		p.mu.Lock()
+		switch {
+		case err != nil:
+			p.err = fmt.Errorf("receiving from party %d: %w", p.index, err)
+		case frame[0] == frameData:
+			p.queue = append(p.queue, frame[1:])
+			p.lastSeen = time.Now()
+		case frame[0] == framePing:
+			p.lastSeen = time.Now()
+		default:
+			p.err = fmt.Errorf("unknown frame type %d from party %d", frame[0], p.index)
+		}
+		failed := p.err != nil
+		p.mu.Unlock()
+
+		select {
+		case p.notify <- struct{}{}:
+		default:
+		}
+		if failed {
+			return
+		}
+	}
+}
+
+// ping sends a heartbeat to every peer each interval.  Send errors are left
+// to the read side, which notices the broken link.
+func (m *Messenger) ping(ctx context.Context) {
+	ticker := time.NewTicker(m.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, p := range m.peers {
+			_ = m.send(ctx, p, framePing, nil)
+		}
+	}
+}
+
+func (m *Messenger) send(ctx context.Context, p *peer, kind byte, buffer []byte) error {
+	frame := make([]byte, 1+len(buffer))
+	frame[0] = kind
+	copy(frame[1:], buffer)
+
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	return m.inner.MessageSend(ctx, p.index, frame)
+}
+
+func (m *Messenger) peer(index int) (*peer, error) {
+	p, ok := m.peers[index]
+	if !ok {
+		return nil, fmt.Errorf("unknown party %d", index)
+	}
+	return p, nil
+}
+
+// MessageSend sends a message to the specified receiver party
+func (m *Messenger) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	p, err := m.peer(receiver)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, p, frameData, buffer)
+}
+
+// MessageReceive receives the next message from the specified sender party.
+// It fails with a *StallError if the sender's heartbeats stop or its round
+// timeout expires first.
+func (m *Messenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	p, err := m.peer(sender)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	p.mu.Lock()
+	p.round++
+	round := p.round
+	p.mu.Unlock()
+
+	deadline := time.NewTimer(p.roundTimeout)
+	defer deadline.Stop()
+	check := time.NewTicker(m.heartbeat)
+	defer check.Stop()
+
+	for {
+		p.mu.Lock()
+		if len(p.queue) > 0 {
+			msg := p.queue[0]
+			p.queue = p.queue[1:]
+			p.mu.Unlock()
+			return msg, nil
+		}
+		err, silent := p.err, time.Since(p.lastSeen) > m.heartbeatTimeout
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if silent {
+			return nil, &StallError{Party: sender, Round: round, Heartbeat: true, Elapsed: time.Since(start)}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return nil, &StallError{Party: sender, Round: round, Elapsed: time.Since(start)}
+		case <-p.notify:
+		case <-check.C:
+		}
+	}
+}
+
+// MessagesReceive receives messages from multiple sender parties concurrently.
+// Failures are joined; use errors.As to find a *StallError.
+func (m *Messenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	msgs := make([][]byte, len(senders))
+	errs := make([]error, len(senders))
+	var wg sync.WaitGroup
+	for i, sender := range senders {
+		wg.Add(1)
+		go func(i, sender int) {
+			defer wg.Done()
+			msgs[i], errs[i] = m.MessageReceive(ctx, sender)
+		}(i, sender)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Close stops the heartbeats.  It does not close the underlying Messenger;
+// read goroutines exit once the underlying Messenger is closed or honours
+// context cancellation.
+func (m *Messenger) Close() error {
+	m.cancel()
+	return nil
+}
@@ -0,0 +1,127 @@
+package liveness
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipe is a context-aware in-memory Messenger for two parties.
+type pipe struct {
+	self  int
+	inbox map[int]chan []byte
+	peers map[int]*pipe
+}
+
+func newPipes() (*pipe, *pipe) {
+	a := &pipe{self: 0, inbox: map[int]chan []byte{1: make(chan []byte, 64)}}
+	b := &pipe{self: 1, inbox: map[int]chan []byte{0: make(chan []byte, 64)}}
+	a.peers = map[int]*pipe{1: b}
+	b.peers = map[int]*pipe{0: a}
+	return a, b
+}
+
+func (p *pipe) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	p.peers[receiver].inbox[p.self] <- buffer
+	return nil
+}
+
+func (p *pipe) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	select {
+	case msg := <-p.inbox[sender]:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *pipe) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func fastConfig(peer int) Config {
+	return Config{
+		Peers:             []int{peer},
+		RoundTimeout:      time.Second,
+		HeartbeatInterval: 10 * time.Millisecond,
+		HeartbeatTimeout:  50 * time.Millisecond,
+	}
+}
+
+func TestMessengerDeliversDataNotPings(t *testing.T) {
+	a, b := newPipes()
+	ma, err := NewMessenger(a, fastConfig(1))
+	require.NoError(t, err)
+	defer ma.Close()
+	mb, err := NewMessenger(b, fastConfig(0))
+	require.NoError(t, err)
+	defer mb.Close()
+
+	ctx := context.Background()
+	time.Sleep(30 * time.Millisecond) // let some pings through
+	require.NoError(t, ma.MessageSend(ctx, 1, []byte("round 1")))
+	require.NoError(t, ma.MessageSend(ctx, 1, []byte("round 2")))
+
+	msgs, err := mb.MessagesReceive(ctx, []int{0})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("round 1")}, msgs)
+	msg, err := mb.MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("round 2"), msg)
+}
+
+func TestMessengerDetectsLostHeartbeat(t *testing.T) {
+	a, b := newPipes()
+	// Party 1 runs without the wrapper and therefore never pings.
+	ma, err := NewMessenger(a, fastConfig(1))
+	require.NoError(t, err)
+	defer ma.Close()
+	require.NoError(t, b.MessageSend(context.Background(), 0, []byte{frameData, 'x'}))
+
+	_, err = ma.MessageReceive(context.Background(), 1)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = ma.MessageReceive(context.Background(), 1)
+	var stall *StallError
+	require.ErrorAs(t, err, &stall)
+	assert.ErrorIs(t, err, ErrUnresponsive)
+	assert.True(t, stall.Heartbeat)
+	assert.Equal(t, 1, stall.Party)
+	assert.Equal(t, 2, stall.Round)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestMessengerRoundTimeout(t *testing.T) {
+	a, b := newPipes()
+	configA := fastConfig(1)
+	configA.PeerRoundTimeouts = map[int]time.Duration{1: 100 * time.Millisecond}
+	ma, err := NewMessenger(a, configA)
+	require.NoError(t, err)
+	defer ma.Close()
+	// Party 1 is alive and pinging but never sends protocol data.
+	mb, err := NewMessenger(b, fastConfig(0))
+	require.NoError(t, err)
+	defer mb.Close()
+
+	_, err = ma.MessageReceive(context.Background(), 1)
+	var stall *StallError
+	require.ErrorAs(t, err, &stall)
+	assert.False(t, stall.Heartbeat)
+	assert.Equal(t, 1, stall.Round)
+	assert.GreaterOrEqual(t, stall.Elapsed, 100*time.Millisecond)
+}
+
+func TestNewMessengerValidation(t *testing.T) {
+	a, _ := newPipes()
+	_, err := NewMessenger(a, Config{})
+	assert.Error(t, err)
+	_, err = NewMessenger(a, Config{Peers: []int{1, 1}})
+	assert.Error(t, err)
+	_, err = NewMessenger(a, Config{Peers: []int{1}, HeartbeatInterval: time.Second, HeartbeatTimeout: time.Second})
+	assert.Error(t, err)
+}
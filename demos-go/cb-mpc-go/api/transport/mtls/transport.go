@@ -17,9 +17,20 @@ import (
 	"time"
 
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/identity"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	defaultConnectTimeout = 30 * time.Second
+	// initialDialBackoff and maxDialBackoff bound the wait between attempts
+	// to connect to a party that is not listening yet.
+	initialDialBackoff = 100 * time.Millisecond
+	maxDialBackoff     = 5 * time.Second
+	// handshakeTimeout bounds the TLS handshake of each accepted connection.
+	handshakeTimeout = 5 * time.Second
+)
+
 // MTLSMessenger implements the Messenger interface using mutual TLS authentication.
 // It provides secure, authenticated communication between MPC parties.
 type MTLSMessenger struct {
@@ -50,6 +61,27 @@ type Config struct {
 	TLSCert     tls.Certificate
 	NameToIndex map[string]int
 	SelfIndex   int
+
+	// Identity, if set, supplies this party's certificate and signing key
+	// instead of TLSCert, so the key can be kept in an HSM or KMS.
+	Identity identity.Provider
+
+	// Attest, if set, produces the attestation presented to each peer right
+	// after the TLS handshake, e.g. a signed build statement of the
+	// coordinator binary.
+	Attest func(peerIndex int, state tls.ConnectionState) ([]byte, error)
+	// VerifyAttestation, if set, is called with the attestation received from
+	// each peer; returning an error aborts the connection setup.
+	//
+	// Attestations are exchanged whenever either hook is set, so all parties
+	// of a deployment must enable the exchange (an empty attestation is sent
+	// when Attest is nil).
+	VerifyAttestation func(peerIndex int, state tls.ConnectionState, attestation []byte) error
+
+	// ConnectTimeout bounds connection setup: parties that are not listening
+	// yet are retried with exponential backoff until it expires, so hosts
+	// can be started in any order.  Defaults to 30s.
+	ConnectTimeout time.Duration
 }
 
 // PartyNameFromCertificate extracts a unique party name from a certificate by hashing its public key
@@ -66,6 +98,14 @@ func PartyNameFromCertificate(cert *x509.Certificate) (string, error) {
 // NewMTLSMessenger creates a new MTLSMessenger instance with the given configuration.
 // It establishes TLS connections with all other parties according to a deterministic connection pattern.
 func NewMTLSMessenger(config Config) (*MTLSMessenger, error) {
+	if config.Identity != nil {
+		cert, err := identity.TLSCertificate(config.Identity)
+		if err != nil {
+			return nil, fmt.Errorf("loading identity: %v", err)
+		}
+		config.TLSCert = cert
+	}
+
 	tlsConfig := &tls.Config{
 		MinVersion:   tls.VersionTLS13,
 		CipherSuites: nil, // use the safe default cipher suites
@@ -73,6 +113,9 @@ func NewMTLSMessenger(config Config) (*MTLSMessenger, error) {
 		RootCAs:      config.CertPool,
 		ClientCAs:    config.CertPool,
 		ClientAuth:   tls.RequireAndVerifyClientCert,
+		// Every connection runs a full ECDHE handshake; a resumed session
+		// would derive its keys from a ticket that outlives the connection.
+		SessionTicketsDisabled: true,
 		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
 			if len(rawCerts) == 0 {
 				return fmt.Errorf("no server certificate provided")
@@ -99,6 +142,11 @@ func NewMTLSMessenger(config Config) (*MTLSMessenger, error) {
 		},
 	}
 
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaultConnectTimeout
+	}
+	deadline := time.Now().Add(config.ConnectTimeout)
+
 	expectedIncomingConnectionsCount := 0
 	expectedOutgoingConnectionsCount := 0
 	for i := range config.Parties {
@@ -118,9 +166,17 @@ func NewMTLSMessenger(config Config) (*MTLSMessenger, error) {
 		selfIndex:   config.SelfIndex,
 		nameToIndex: config.NameToIndex,
 	}
+	add := func(peerIndex int, conn *tls.Conn) error {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		if _, dup := transport.connections[peerIndex]; dup {
+			return fmt.Errorf("party %d connected twice", peerIndex)
+		}
+		transport.connections[peerIndex] = conn
+		return nil
+	}
 
-	wg := sync.WaitGroup{}
-
+	eg := errgroup.Group{}
 	if expectedIncomingConnectionsCount != 0 {
 		myAddress := config.Parties[config.SelfIndex].Address
 		ln, err := tls.Listen("tcp", myAddress, tlsConfig)
@@ -128,76 +184,197 @@ func NewMTLSMessenger(config Config) (*MTLSMessenger, error) {
 			return nil, fmt.Errorf("starting server on %s: %v", myAddress, err)
 		}
 		transport.listener = ln
+		eg.Go(func() error {
+			return transport.acceptAll(config, expectedIncomingConnectionsCount, deadline, add)
+		})
+	}
+	for i, party := range config.Parties {
+		if i >= config.SelfIndex {
+			continue
+		}
+		eg.Go(func() error {
+			conn, err := dialParty(tlsConfig, transport.nameToIndex, i, party.Address, deadline)
+			if err != nil {
+				return fmt.Errorf("connecting to party %d at %s: %v", i, party.Address, err)
+			}
+			if err := add(i, conn); err != nil {
+				conn.Close()
+				return err
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		transport.Close()
+		return nil, err
+	}
 
-		for i := 0; i < expectedIncomingConnectionsCount; i++ {
-			wg.Add(1)
-			go func(i int) {
-				defer wg.Done()
-				conn, err := ln.Accept()
-				if err != nil {
-					fmt.Printf("error accepting connection on %s: %v\n", myAddress, err)
-					return
-				}
-				c := conn.(*tls.Conn)
+	if config.Attest != nil || config.VerifyAttestation != nil {
+		if err := transport.exchangeAttestations(config); err != nil {
+			transport.Close()
+			return nil, err
+		}
+	}
 
+	return transport, nil
+}
+
+// acceptAll accepts connections until every higher-indexed party is
+// connected or deadline passes.  Each connection completes its handshake in
+// its own goroutine within handshakeTimeout, so a client that connects and
+// stays silent cannot hold up the others.  Connections that fail the
+// handshake, come from a party that should dial us or duplicate an existing
+// one are closed without aborting the setup, so a stray client cannot take a
+// party's place.  The listener is closed once setup ends.
+func (dt *MTLSMessenger) acceptAll(config Config, want int, deadline time.Time, add func(int, *tls.Conn) error) error {
+	ln := dt.listener
+	defer func() {
+		ln.Close()
+		dt.listener = nil
+	}()
+	timer := time.AfterFunc(time.Until(deadline), func() { ln.Close() })
+	defer timer.Stop()
+
+	type handshaken struct {
+		conn      *tls.Conn
+		peerIndex int
+		err       error
+	}
+	results := make(chan handshaken)
+	done := make(chan struct{})
+	defer close(done)
+	acceptErr := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				acceptErr <- err
+				return
+			}
+			go func() {
+				c := conn.(*tls.Conn)
 				// Explicitly complete the TLS handshake. This will let us access the peer certificates.
-				if err := c.Handshake(); err != nil {
-					fmt.Printf("TLS handshake failed: %v\n", err)
-					c.Close()
-					return
-				}
-				peerCerts := c.ConnectionState().PeerCertificates
-				if len(peerCerts) == 0 {
-					fmt.Printf("No peer certificates found\n")
-					return
+				hsDeadline := time.Now().Add(handshakeTimeout)
+				if deadline.Before(hsDeadline) {
+					hsDeadline = deadline
 				}
-				peerName, err := PartyNameFromCertificate(peerCerts[0])
-				if err != nil {
-					fmt.Printf("error extracting peer name from certificate: %v\n", err)
-					return
-				}
-				peerIndex, ok := transport.nameToIndex[peerName]
-				if !ok {
-					fmt.Printf("peer name %s not found in name to index map\n", peerName)
-					return
+				c.SetDeadline(hsDeadline)
+				peerIndex, err := handshake(c, dt.nameToIndex)
+				select {
+				case results <- handshaken{conn: c, peerIndex: peerIndex, err: err}:
+				case <-done:
+					c.Close()
 				}
-				fmt.Printf("Party %d: peer %d connected\n", config.SelfIndex, peerIndex)
-
-				transport.mu.Lock()
-				transport.connections[peerIndex] = c
-				transport.mu.Unlock()
-			}(i)
+			}()
+		}
+	}()
+
+	for got := 0; got < want; {
+		var r handshaken
+		select {
+		case r = <-results:
+		case err := <-acceptErr:
+			return fmt.Errorf("accepting connections (%d of %d peers connected): %v", got, want, err)
+		}
+		err := r.err
+		if err == nil && r.peerIndex <= config.SelfIndex {
+			err = fmt.Errorf("party %d must be dialed, not accepted", r.peerIndex)
+		}
+		if err == nil {
+			err = add(r.peerIndex, r.conn)
 		}
+		if err != nil {
+			fmt.Printf("Party %d: rejected connection from %s: %v\n", config.SelfIndex, r.conn.RemoteAddr(), err)
+			r.conn.Close()
+			continue
+		}
+		r.conn.SetDeadline(time.Time{})
+		fmt.Printf("Party %d: peer %d connected\n", config.SelfIndex, r.peerIndex)
+		got++
 	}
+	return nil
+}
 
-	for i, party := range config.Parties {
-		if i < config.SelfIndex {
-			// TODO: exponential backoff
-			backoff := 1 * time.Second
-			attempts := 0
-			for {
-				counterPartyAddress := party.Address
-				conn, err := tls.Dial("tcp", counterPartyAddress, tlsConfig)
-				if err != nil {
-					attempts++
-					time.Sleep(backoff)
-					if attempts > 10 {
-						return nil, fmt.Errorf("connecting to %s: %v", counterPartyAddress, err)
-					}
-					continue
-				}
-				transport.mu.Lock()
-				transport.connections[i] = conn
-				transport.mu.Unlock()
-				break
+// dialParty connects to a lower-indexed party, retrying with exponential
+// backoff until deadline while the party is not yet listening, e.g. because
+// its host is still starting.
+func dialParty(tlsConfig *tls.Config, nameToIndex map[string]int, peerIndex int, address string, deadline time.Time) (*tls.Conn, error) {
+	backoff := initialDialBackoff
+	for {
+		dialer := &net.Dialer{Deadline: deadline}
+		conn, err := tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+		if err == nil {
+			var got int
+			if got, err = handshake(conn, nameToIndex); err == nil && got != peerIndex {
+				err = fmt.Errorf("certificate of party %d presented", got)
+			}
+			if err == nil {
+				return conn, nil
 			}
+			conn.Close()
+			// The peer is up but is not who we expect; retrying won't help.
+			return nil, err
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, err
 		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxDialBackoff)
 	}
+}
 
-	// Wait for all incoming connections to be established
-	wg.Wait()
+// handshake completes the TLS handshake of conn and returns the index of the
+// party whose certificate the peer presented.
+func handshake(conn *tls.Conn, nameToIndex map[string]int) (int, error) {
+	if err := conn.Handshake(); err != nil {
+		return 0, fmt.Errorf("TLS handshake failed: %v", err)
+	}
+	peerCerts := conn.ConnectionState().PeerCertificates
+	if len(peerCerts) == 0 {
+		return 0, fmt.Errorf("no peer certificates found")
+	}
+	peerName, err := PartyNameFromCertificate(peerCerts[0])
+	if err != nil {
+		return 0, fmt.Errorf("extracting peer name from certificate: %v", err)
+	}
+	peerIndex, ok := nameToIndex[peerName]
+	if !ok {
+		return 0, fmt.Errorf("peer name %s not found in name to index map", peerName)
+	}
+	return peerIndex, nil
+}
 
-	return transport, nil
+// exchangeAttestations sends our attestation to every connected peer and
+// verifies theirs.
+func (dt *MTLSMessenger) exchangeAttestations(config Config) error {
+	ctx := context.Background()
+	eg := errgroup.Group{}
+	for peerIndex, conn := range dt.connections {
+		eg.Go(func() error {
+			state := conn.ConnectionState()
+			var attestation []byte
+			if config.Attest != nil {
+				var err error
+				if attestation, err = config.Attest(peerIndex, state); err != nil {
+					return fmt.Errorf("producing attestation for party %d: %v", peerIndex, err)
+				}
+			}
+			if err := dt.MessageSend(ctx, peerIndex, attestation); err != nil {
+				return fmt.Errorf("sending attestation to party %d: %v", peerIndex, err)
+			}
+			received, err := dt.MessageReceive(ctx, peerIndex)
+			if err != nil {
+				return fmt.Errorf("receiving attestation from party %d: %v", peerIndex, err)
+			}
+			if config.VerifyAttestation != nil {
+				if err := config.VerifyAttestation(peerIndex, state, received); err != nil {
+					return fmt.Errorf("verifying attestation of party %d: %w", peerIndex, err)
+				}
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
 }
 
 // MessageSend sends a message to the specified receiver party
@@ -208,18 +385,16 @@ func (dt *MTLSMessenger) MessageSend(_ context.Context, receiverIndex int, buffe
 		return fmt.Errorf("no connection found for receiver index %d", receiverIndex)
 	}
 
-	// Send message length first (4 bytes, big endian)
-	messageLength := uint32(len(buffer))
-	lengthBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(lengthBytes, messageLength)
-
-	if _, err := conn.Write(lengthBytes); err != nil {
-		return fmt.Errorf("writing message length: %v", err)
-	}
+	// Prefix the message with its length (4 bytes, big endian) and send both
+	// in a single write, so each message costs one TLS record and one syscall.
+	// This matters for large committees, where every round fans out to n-1
+	// peers.
+	frame := make([]byte, 4+len(buffer))
+	binary.BigEndian.PutUint32(frame, uint32(len(buffer)))
+	copy(frame[4:], buffer)
 
-	// Send the actual message
-	if _, err := conn.Write(buffer); err != nil {
-		return fmt.Errorf("writing message data: %v", err)
+	if _, err := conn.Write(frame); err != nil {
+		return fmt.Errorf("writing message: %v", err)
 	}
 
 	return nil
@@ -0,0 +1,229 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// party is one party's self-signed certificate and key.
+type party struct {
+	cert    *x509.Certificate
+	tlsCert tls.Certificate
+}
+
+func newParty(t *testing.T, name string) party {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return party{cert: cert, tlsCert: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}}
+}
+
+// freeAddresses reserves n loopback addresses.
+func freeAddresses(t *testing.T, n int) []string {
+	t.Helper()
+	out := make([]string, n)
+	for i := range out {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		out[i] = ln.Addr().String()
+		ln.Close()
+	}
+	return out
+}
+
+// configs returns the Config of every party of a network of parties.
+func configs(t *testing.T, parties []party) []Config {
+	t.Helper()
+	addrs := freeAddresses(t, len(parties))
+	pool := x509.NewCertPool()
+	all := make(map[int]PartyConfig, len(parties))
+	names := make(map[string]int, len(parties))
+	for i, p := range parties {
+		pool.AddCert(p.cert)
+		all[i] = PartyConfig{Address: addrs[i], Cert: p.cert}
+		name, err := PartyNameFromCertificate(p.cert)
+		require.NoError(t, err)
+		names[name] = i
+	}
+	out := make([]Config, len(parties))
+	for i, p := range parties {
+		out[i] = Config{
+			Parties:        all,
+			CertPool:       pool,
+			TLSCert:        p.tlsCert,
+			NameToIndex:    names,
+			SelfIndex:      i,
+			ConnectTimeout: 5 * time.Second,
+		}
+	}
+	return out
+}
+
+// connect starts every party after the delay given for it.
+func connect(t *testing.T, cs []Config, delays ...time.Duration) ([]*MTLSMessenger, []error) {
+	t.Helper()
+	ms := make([]*MTLSMessenger, len(cs))
+	errs := make([]error, len(cs))
+	done := make(chan int, len(cs))
+	for i, c := range cs {
+		go func() {
+			if i < len(delays) {
+				time.Sleep(delays[i])
+			}
+			ms[i], errs[i] = NewMTLSMessenger(c)
+			done <- i
+		}()
+	}
+	for range cs {
+		<-done
+	}
+	t.Cleanup(func() {
+		for _, m := range ms {
+			if m != nil {
+				m.Close()
+			}
+		}
+	})
+	return ms, errs
+}
+
+func newParties(t *testing.T, n int) []party {
+	ps := make([]party, n)
+	for i := range ps {
+		ps[i] = newParty(t, fmt.Sprintf("party-%d", i))
+	}
+	return ps
+}
+
+func TestMessagesBetweenAllParties(t *testing.T) {
+	ms, errs := connect(t, configs(t, newParties(t, 3)))
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	ctx := context.Background()
+	for i, m := range ms {
+		for j := range ms {
+			if i != j {
+				require.NoError(t, m.MessageSend(ctx, j, []byte(fmt.Sprintf("%d->%d", i, j))))
+			}
+		}
+	}
+	msgs, err := ms[0].MessagesReceive(ctx, []int{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("1->0"), []byte("2->0")}, msgs)
+	msg, err := ms[2].MessageReceive(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1->2"), msg)
+}
+
+func TestLateListenerIsRetried(t *testing.T) {
+	// Party 0 listens for the others; they start first and keep dialing.
+	_, errs := connect(t, configs(t, newParties(t, 3)), 1500*time.Millisecond)
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	cs := configs(t, newParties(t, 2))
+	cs[1].ConnectTimeout = 300 * time.Millisecond
+	start := time.Now()
+	_, err := NewMTLSMessenger(cs[1]) // Party 0 never starts
+	assert.ErrorContains(t, err, "connecting to party 0")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestUnknownCertificateRejected(t *testing.T) {
+	ps := newParties(t, 2)
+	cs := configs(t, ps)
+	cs[0].ConnectTimeout = time.Second
+
+	// An intruder trusted by the CA pool but not a party of the network
+	// dials party 0 before party 1 does.
+	intruder := newParty(t, "intruder")
+	cs[0].CertPool.AddCert(intruder.cert)
+	go func() {
+		for i := 0; i < 20; i++ {
+			conn, err := tls.Dial("tcp", cs[0].Parties[0].Address, &tls.Config{
+				Certificates: []tls.Certificate{intruder.tlsCert},
+				RootCAs:      cs[0].CertPool,
+			})
+			if err == nil {
+				conn.Handshake()
+				conn.Close()
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	ms, errs := connect(t, cs, 0, 300*time.Millisecond)
+	require.NoError(t, errs[0], "the intruder does not take party 1's place")
+	require.NoError(t, errs[1])
+	require.NoError(t, ms[1].MessageSend(context.Background(), 0, []byte("hello")))
+	msg, err := ms[0].MessageReceive(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), msg)
+}
+
+func TestSilentClientDoesNotBlockSetup(t *testing.T) {
+	cs := configs(t, newParties(t, 2))
+	cs[0].ConnectTimeout = 20 * time.Second
+	cs[1].ConnectTimeout = 20 * time.Second
+
+	// A client that connects to party 0 before party 1 and never starts
+	// its handshake.
+	silent := make(chan net.Conn, 1)
+	go func() {
+		defer close(silent)
+		for i := 0; i < 20; i++ {
+			conn, err := net.Dial("tcp", cs[0].Parties[0].Address)
+			if err == nil {
+				silent <- conn
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	ms, errs := connect(t, cs, 0, 500*time.Millisecond)
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	conn := <-silent
+	require.NotNil(t, conn, "the silent client connected")
+	defer conn.Close()
+	assert.Less(t, time.Since(start), handshakeTimeout, "party 1 is admitted while the silent client is pending")
+	require.NoError(t, ms[1].MessageSend(context.Background(), 0, []byte("hello")))
+	msg, err := ms[0].MessageReceive(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), msg)
+}
@@ -0,0 +1,25 @@
+// Package mux carries many concurrent protocol sessions over one
+// `transport.Messenger`, so a single mtls or websocket connection per pair of
+// parties serves hundreds of signatures at once instead of each session
+// opening its own connections.
+//
+// Every message is prefixed with the ID of the stream it belongs to.  A `Mux`
+// takes over all reads from the underlying Messenger and sorts arriving
+// messages into per-stream, per-sender queues; `Mux.Open` returns a `Stream`,
+// itself a transport.Messenger, that sends and receives only the messages of
+// its session:
+//
+//	m, _ := mux.New(link, mux.Config{Peers: []int{1, 2}})
+//	s, _ := m.Open(sessionID)
+//	defer s.Close()
+//	job, _ := mpc.NewJobMP(s, 3, 0, pnames)
+//
+// Parties open the same stream ID independently, so a peer may start sending
+// before the local side has opened the stream.  Such messages are held for
+// Config.PendingTimeout and delivered once the stream is opened.  Queues are
+// bounded by Config.MaxQueued; a session whose peer overruns its queue fails
+// without affecting the other sessions on the link.
+//
+// The header adds 2 bytes plus the length of the stream ID to every message,
+// so either all parties of a deployment use the multiplexer or none does.
+package mux
@@ -0,0 +1,361 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+const (
+	defaultMaxQueued      = 64
+	defaultMaxPending     = 1024
+	defaultPendingTimeout = time.Minute
+	// frameVersion is the first byte of every frame.
+	frameVersion byte = 1
+	// maxIDLength is the longest stream ID the one-byte length can carry.
+	maxIDLength = 255
+)
+
+// ErrClosed is returned by a Stream after it or its Mux was closed.
+var ErrClosed = errors.New("mux: stream closed")
+
+// Config contains the configuration for a Mux.
+type Config struct {
+	// Peers lists the indices of all other parties on the link.
+	Peers []int
+	// MaxQueued bounds the messages held per stream and sender.  A stream
+	// whose queue overflows fails.  Defaults to 64, far more than any
+	// protocol round has in flight.
+	MaxQueued int
+	// MaxPending bounds the streams that peers have sent to but that were
+	// not opened locally yet.  Messages for further streams are dropped.
+	// Defaults to 1024.
+	MaxPending int
+	// PendingTimeout is how long messages for a stream that is not open are
+	// kept.  Defaults to 1m.
+	PendingTimeout time.Duration
+}
+
+// Mux multiplexes Streams over an underlying Messenger.
+type Mux struct {
+	inner  transport.Messenger
+	config Config
+	links  map[int]*link
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	streams map[string]*Stream
+	pending int
+	closed  bool
+}
+
+// link is the connection to one peer.
+type link struct {
+	index  int
+	sendMu sync.Mutex
+	err    error // Guarded by Mux.mu; set once reading from the peer failed
+}
+
+// Stream is one session on a Mux.  It implements transport.Messenger.
+type Stream struct {
+	m       *Mux
+	id      string
+	header  []byte
+	created time.Time
+
+	// Guarded by Mux.mu.
+	queues map[int]*queue
+	opened bool
+	closed bool
+	err    error
+}
+
+// Ensure Stream implements the Messenger interface
+var _ transport.Messenger = (*Stream)(nil)
+
+// queue holds the messages of one sender on one stream.
+type queue struct {
+	msgs   [][]byte
+	notify chan struct{}
+}
+
+// New wraps inner and starts reading from all peers.  The Mux takes over all
+// reads from inner; call Close to stop it.
+func New(inner transport.Messenger, config Config) (*Mux, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("inner messenger cannot be nil")
+	}
+	if len(config.Peers) == 0 {
+		return nil, fmt.Errorf("at least one peer must be provided")
+	}
+	if config.MaxQueued <= 0 {
+		config.MaxQueued = defaultMaxQueued
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = defaultMaxPending
+	}
+	if config.PendingTimeout <= 0 {
+		config.PendingTimeout = defaultPendingTimeout
+	}
+	links := make(map[int]*link, len(config.Peers))
+	for _, index := range config.Peers {
+		if _, dup := links[index]; dup {
+			return nil, fmt.Errorf("duplicate peer %d", index)
+		}
+		links[index] = &link{index: index}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mux{inner: inner, config: config, links: links, cancel: cancel, streams: make(map[string]*Stream)}
+	for _, l := range links {
+		go m.read(ctx, l)
+	}
+	return m, nil
+}
+
+// Open starts the stream with the given ID, typically the session ID, and
+// delivers any messages peers already sent on it.  Every party must open the
+// same ID for a session, and an ID may not be reused while it is open.
+func (m *Mux) Open(id string) (*Stream, error) {
+	if id == "" || len(id) > maxIDLength {
+		return nil, fmt.Errorf("stream ID must be 1 to %d bytes", maxIDLength)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	s, ok := m.streams[id]
+	switch {
+	case !ok:
+		s = m.newStream(id)
+	case s.opened:
+		return nil, fmt.Errorf("stream %q is already open", id)
+	default:
+		m.pending--
+	}
+	s.opened = true
+	return s, nil
+}
+
+// newStream registers an unopened stream.  m.mu must be held.
+func (m *Mux) newStream(id string) *Stream {
+	s := &Stream{m: m, id: id, created: time.Now(), queues: make(map[int]*queue, len(m.links))}
+	s.header = append([]byte{frameVersion, byte(len(id))}, id...)
+	for index := range m.links {
+		s.queues[index] = &queue{notify: make(chan struct{}, 1)}
+	}
+	m.streams[id] = s
+	return s
+}
+
+// read demultiplexes frames from l until the underlying Messenger fails.
+func (m *Mux) read(ctx context.Context, l *link) {
+	for {
+		frame, err := m.inner.MessageReceive(ctx, l.index)
+		var (
+			id      string
+			payload []byte
+		)
+		if err == nil {
+			id, payload, err = decode(frame)
+		}
+
+		m.mu.Lock()
+		if err != nil {
+			l.err = fmt.Errorf("receiving from party %d: %w", l.index, err)
+			for _, s := range m.streams {
+				s.queues[l.index].wake()
+			}
+			m.mu.Unlock()
+			return
+		}
+		m.deliver(l.index, id, payload)
+		m.mu.Unlock()
+	}
+}
+
+// deliver queues a message for its stream.  m.mu must be held.
+func (m *Mux) deliver(sender int, id string, payload []byte) {
+	if m.closed {
+		return
+	}
+	s, ok := m.streams[id]
+	if !ok {
+		m.expire()
+		if m.pending >= m.config.MaxPending {
+			return
+		}
+		s = m.newStream(id)
+		m.pending++
+	}
+	q := s.queues[sender]
+	if s.err != nil {
+		return
+	}
+	if len(q.msgs) >= m.config.MaxQueued {
+		s.err = fmt.Errorf("stream %q: more than %d queued messages from party %d", id, m.config.MaxQueued, sender)
+		for _, q := range s.queues {
+			q.wake()
+		}
+		return
+	}
+	q.msgs = append(q.msgs, payload)
+	q.wake()
+}
+
+// expire drops unopened streams older than PendingTimeout.  m.mu must be
+// held.
+func (m *Mux) expire() {
+	cutoff := time.Now().Add(-m.config.PendingTimeout)
+	for id, s := range m.streams {
+		if !s.opened && s.created.Before(cutoff) {
+			delete(m.streams, id)
+			m.pending--
+		}
+	}
+}
+
+// decode splits a frame into stream ID and payload.
+func decode(frame []byte) (string, []byte, error) {
+	if len(frame) < 2 || frame[0] != frameVersion {
+		return "", nil, fmt.Errorf("malformed frame")
+	}
+	n := int(frame[1])
+	if n == 0 || len(frame) < 2+n {
+		return "", nil, fmt.Errorf("malformed frame")
+	}
+	return string(frame[2 : 2+n]), frame[2+n:], nil
+}
+
+// Close closes every stream and stops reading.  It does not close the
+// underlying Messenger; read goroutines exit once the underlying Messenger is
+// closed or honours context cancellation.
+func (m *Mux) Close() error {
+	m.cancel()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for _, s := range m.streams {
+		s.closed = true
+		for _, q := range s.queues {
+			q.wake()
+		}
+	}
+	clear(m.streams)
+	m.pending = 0
+	return nil
+}
+
+func (q *queue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// ID returns the stream ID.
+func (s *Stream) ID() string { return s.id }
+
+// MessageSend sends a message to the specified receiver party on this stream.
+func (s *Stream) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	l, ok := s.m.links[receiver]
+	if !ok {
+		return fmt.Errorf("unknown party %d", receiver)
+	}
+	s.m.mu.Lock()
+	closed := s.closed
+	s.m.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	frame := make([]byte, len(s.header)+len(buffer))
+	copy(frame, s.header)
+	copy(frame[len(s.header):], buffer)
+
+	l.sendMu.Lock()
+	defer l.sendMu.Unlock()
+	return s.m.inner.MessageSend(ctx, receiver, frame)
+}
+
+// MessageReceive receives the next message from the specified sender party
+// on this stream.
+func (s *Stream) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	l, ok := s.m.links[sender]
+	if !ok {
+		return nil, fmt.Errorf("unknown party %d", sender)
+	}
+	s.m.mu.Lock()
+	q := s.queues[sender]
+	s.m.mu.Unlock()
+	for {
+		s.m.mu.Lock()
+		if len(q.msgs) > 0 {
+			msg := q.msgs[0]
+			q.msgs = q.msgs[1:]
+			s.m.mu.Unlock()
+			return msg, nil
+		}
+		var err error
+		switch {
+		case s.closed:
+			err = ErrClosed
+		case s.err != nil:
+			err = s.err
+		case l.err != nil:
+			err = l.err
+		}
+		s.m.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.notify:
+		}
+	}
+}
+
+// MessagesReceive receives messages from multiple sender parties concurrently.
+func (s *Stream) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	msgs := make([][]byte, len(senders))
+	errs := make([]error, len(senders))
+	var wg sync.WaitGroup
+	for i, sender := range senders {
+		wg.Add(1)
+		go func(i, sender int) {
+			defer wg.Done()
+			msgs[i], errs[i] = s.MessageReceive(ctx, sender)
+		}(i, sender)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Close ends the stream and discards its queued messages.  Messages peers
+// send on it afterwards are dropped once PendingTimeout has passed.
+func (s *Stream) Close() error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.m.streams[s.id] == s {
+		delete(s.m.streams, s.id)
+	}
+	for _, q := range s.queues {
+		q.msgs = nil
+		q.wake()
+	}
+	return nil
+}
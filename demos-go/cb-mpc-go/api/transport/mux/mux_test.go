@@ -0,0 +1,167 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+)
+
+// network returns one Mux per party over a shared mock network.
+func network(t *testing.T, n int, config Config) []*Mux {
+	t.Helper()
+	muxes := make([]*Mux, n)
+	for i, link := range mocknet.NewMockNetwork(n) {
+		c := config
+		for j := 0; j < n; j++ {
+			if j != i {
+				c.Peers = append(c.Peers, j)
+			}
+		}
+		m, err := New(link, c)
+		require.NoError(t, err)
+		t.Cleanup(func() { m.Close() })
+		muxes[i] = m
+	}
+	return muxes
+}
+
+func TestInterleavedStreams(t *testing.T) {
+	const parties, sessions, rounds = 3, 50, 4
+	muxes := network(t, parties, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, parties*sessions)
+	for s := 0; s < sessions; s++ {
+		for p := 0; p < parties; p++ {
+			wg.Add(1)
+			go func(s, p int) {
+				defer wg.Done()
+				errs <- runSession(ctx, muxes[p], fmt.Sprintf("session-%d", s), p, parties, rounds)
+			}(s, p)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+// runSession exchanges rounds of messages naming stream, round and sender
+// with every other party and checks what arrives.
+func runSession(ctx context.Context, m *Mux, id string, self, parties, rounds int) error {
+	s, err := m.Open(id)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	var peers []int
+	for p := 0; p < parties; p++ {
+		if p != self {
+			peers = append(peers, p)
+		}
+	}
+	for r := 0; r < rounds; r++ {
+		for _, p := range peers {
+			if err := s.MessageSend(ctx, p, []byte(fmt.Sprintf("%s/%d/%d", id, r, self))); err != nil {
+				return err
+			}
+		}
+		msgs, err := s.MessagesReceive(ctx, peers)
+		if err != nil {
+			return err
+		}
+		for i, p := range peers {
+			if want := fmt.Sprintf("%s/%d/%d", id, r, p); string(msgs[i]) != want {
+				return fmt.Errorf("got %q, want %q", msgs[i], want)
+			}
+		}
+	}
+	return nil
+}
+
+func TestMessagesBeforeOpen(t *testing.T) {
+	muxes := network(t, 2, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a, err := muxes[0].Open("s1")
+	require.NoError(t, err)
+	require.NoError(t, a.MessageSend(ctx, 1, []byte("early")))
+	require.Eventually(t, func() bool {
+		muxes[1].mu.Lock()
+		defer muxes[1].mu.Unlock()
+		return muxes[1].pending == 1
+	}, time.Second, time.Millisecond)
+
+	b, err := muxes[1].Open("s1")
+	require.NoError(t, err)
+	msg, err := b.MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "early", string(msg))
+
+	_, err = muxes[1].Open("s1")
+	assert.Error(t, err)
+	require.NoError(t, b.Close())
+	_, err = b.MessageReceive(ctx, 0)
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, b.MessageSend(ctx, 0, nil), ErrClosed)
+}
+
+func TestQueueOverflowFailsOnlyThatStream(t *testing.T) {
+	muxes := network(t, 2, Config{MaxQueued: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	noisy, err := muxes[0].Open("noisy")
+	require.NoError(t, err)
+	quiet, err := muxes[0].Open("quiet")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, noisy.MessageSend(ctx, 1, []byte{byte(i)}))
+	}
+	require.NoError(t, quiet.MessageSend(ctx, 1, []byte("ok")))
+
+	n, err := muxes[1].Open("noisy")
+	require.NoError(t, err)
+	q, err := muxes[1].Open("quiet")
+	require.NoError(t, err)
+	msg, err := q.MessageReceive(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(msg))
+
+	// The queued messages are still delivered before the failure.
+	for i := 0; i < 2; i++ {
+		_, err = n.MessageReceive(ctx, 0)
+		require.NoError(t, err)
+	}
+	_, err = n.MessageReceive(ctx, 0)
+	assert.ErrorContains(t, err, "queued messages")
+}
+
+func TestMalformedFrameFailsLink(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	m, err := New(links[1], Config{Peers: []int{0}})
+	require.NoError(t, err)
+	defer m.Close()
+	s, err := m.Open("s1")
+	require.NoError(t, err)
+
+	require.NoError(t, links[0].MessageSend(context.Background(), 1, []byte{0xff}))
+	_, err = s.MessageReceive(context.Background(), 0)
+	assert.ErrorContains(t, err, "receiving from party 0")
+
+	_, err = m.Open(string(make([]byte, maxIDLength+1)))
+	assert.Error(t, err)
+	_, err = New(links[1], Config{Peers: []int{0, 0}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,14 @@
+// Package netsim wraps a `transport.Messenger` to simulate network
+// conditions and to measure how a protocol uses the network.
+//
+// NewLatency delays every message by a fixed one-way latency plus optional
+// jitter, which turns an in-process mocknet or a loopback TCP connection into
+// a stand-in for a WAN link.  Messages on each link keep their order.
+//
+// NewRecorder records one Round per receive call: how long the party waited
+// and how many bytes it sent and received since the previous round.  The
+// cb-mpc-bench command uses it to report per-round latencies.
+//
+// Both wrappers are transparent: they do not change the bytes on the wire, so
+// only some parties of a session may use them.
+package netsim
@@ -0,0 +1,158 @@
+package netsim
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// LatencyConfig contains the configuration for a latency Messenger.
+type LatencyConfig struct {
+	// Latency is added to every message.
+	Latency time.Duration
+	// Jitter, if set, adds a uniformly random extra delay in [0, Jitter).
+	Jitter time.Duration
+}
+
+// Latency implements transport.Messenger on top of another Messenger,
+// delivering every sent message after the configured delay.  MessageSend
+// returns immediately; a failed delayed send is reported by the next
+// MessageSend to the same party.
+type Latency struct {
+	inner  transport.Messenger
+	config LatencyConfig
+
+	mu     sync.Mutex
+	links  map[int]*link
+	closed bool
+}
+
+// Ensure Latency implements the Messenger interface
+var _ transport.Messenger = (*Latency)(nil)
+
+// link is the delayed outgoing queue towards one party.
+type link struct {
+	mu      sync.Mutex
+	queue   []delayed
+	lastDue time.Time
+	err     error
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+type delayed struct {
+	due time.Time
+	msg []byte
+}
+
+// NewLatency wraps inner.  Call Close to stop the delivery goroutines.
+func NewLatency(inner transport.Messenger, config LatencyConfig) (*Latency, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("inner messenger cannot be nil")
+	}
+	if config.Latency < 0 || config.Jitter < 0 {
+		return nil, fmt.Errorf("latency and jitter cannot be negative")
+	}
+	return &Latency{inner: inner, config: config, links: make(map[int]*link)}, nil
+}
+
+// MessageSend queues buffer for delivery to receiver after the configured
+// delay.
+func (l *Latency) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return fmt.Errorf("latency messenger closed")
+	}
+	k, ok := l.links[receiver]
+	if !ok {
+		k = &link{wake: make(chan struct{}, 1), done: make(chan struct{})}
+		l.links[receiver] = k
+		go l.deliver(receiver, k)
+	}
+	l.mu.Unlock()
+
+	due := time.Now().Add(l.config.Latency)
+	if l.config.Jitter > 0 {
+		due = due.Add(rand.N(l.config.Jitter))
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.err != nil {
+		return k.err
+	}
+	// Jitter must not reorder messages on a link.
+	if due.Before(k.lastDue) {
+		due = k.lastDue
+	}
+	k.lastDue = due
+	k.queue = append(k.queue, delayed{due: due, msg: append([]byte(nil), buffer...)})
+	select {
+	case k.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// deliver sends the messages queued on k once they are due.
+func (l *Latency) deliver(receiver int, k *link) {
+	for {
+		k.mu.Lock()
+		if len(k.queue) == 0 {
+			k.mu.Unlock()
+			select {
+			case <-k.wake:
+				continue
+			case <-k.done:
+				return
+			}
+		}
+		next := k.queue[0]
+		k.mu.Unlock()
+
+		if wait := time.Until(next.due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-k.done:
+				timer.Stop()
+				return
+			}
+		}
+		err := l.inner.MessageSend(context.Background(), receiver, next.msg)
+
+		k.mu.Lock()
+		k.queue = k.queue[1:]
+		if err != nil && k.err == nil {
+			k.err = fmt.Errorf("delayed send to party %d: %w", receiver, err)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// MessageReceive receives a message from the specified sender party
+func (l *Latency) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	return l.inner.MessageReceive(ctx, sender)
+}
+
+// MessagesReceive receives messages from multiple sender parties
+func (l *Latency) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	return l.inner.MessagesReceive(ctx, senders)
+}
+
+// Close stops delivery.  Messages still queued are dropped.
+func (l *Latency) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		for _, k := range l.links {
+			close(k.done)
+		}
+	}
+	return nil
+}
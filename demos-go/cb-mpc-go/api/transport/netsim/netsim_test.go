@@ -0,0 +1,132 @@
+package netsim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hub is an in-memory network; hub[i][j] carries i -> j.
+type hub map[int]map[int]chan []byte
+
+type endpoint struct {
+	self int
+	hub  hub
+}
+
+func newHub(n int) hub {
+	h := make(hub)
+	for i := 0; i < n; i++ {
+		h[i] = make(map[int]chan []byte)
+		for j := 0; j < n; j++ {
+			if i != j {
+				h[i][j] = make(chan []byte, 64)
+			}
+		}
+	}
+	return h
+}
+
+func (e *endpoint) MessageSend(_ context.Context, receiver int, buffer []byte) error {
+	if receiver == e.self {
+		return errors.New("cannot send to self")
+	}
+	e.hub[e.self][receiver] <- buffer
+	return nil
+}
+
+func (e *endpoint) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	select {
+	case msg := <-e.hub[sender][e.self]:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (e *endpoint) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	out := make([][]byte, len(senders))
+	for i, s := range senders {
+		msg, err := e.MessageReceive(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = msg
+	}
+	return out, nil
+}
+
+func withTimeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestLatencyDelaysAndKeepsOrder(t *testing.T) {
+	h := newHub(2)
+	l, err := NewLatency(&endpoint{self: 0, hub: h}, LatencyConfig{Latency: 30 * time.Millisecond, Jitter: 20 * time.Millisecond})
+	require.NoError(t, err)
+	defer l.Close()
+	ctx := withTimeout(t)
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, l.MessageSend(ctx, 1, []byte(fmt.Sprint(i))))
+	}
+	assert.Less(t, time.Since(start), 30*time.Millisecond, "MessageSend must not block")
+
+	receiver := &endpoint{self: 1, hub: h}
+	for i := 0; i < 20; i++ {
+		msg, err := receiver.MessageReceive(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(i), string(msg))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestLatencyReportsFailedSend(t *testing.T) {
+	l, err := NewLatency(&endpoint{self: 0, hub: newHub(2)}, LatencyConfig{})
+	require.NoError(t, err)
+	defer l.Close()
+	ctx := withTimeout(t)
+
+	require.NoError(t, l.MessageSend(ctx, 0, []byte("to self")))
+	require.Eventually(t, func() bool {
+		return l.MessageSend(ctx, 0, nil) != nil
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestLatencyRejectsNegativeDelay(t *testing.T) {
+	_, err := NewLatency(&endpoint{}, LatencyConfig{Latency: -time.Second})
+	assert.Error(t, err)
+}
+
+func TestRecorderRounds(t *testing.T) {
+	h := newHub(3)
+	r := NewRecorder(&endpoint{self: 0, hub: h})
+	ctx := withTimeout(t)
+
+	require.NoError(t, r.MessageSend(ctx, 1, []byte("abc")))
+	require.NoError(t, r.MessageSend(ctx, 2, []byte("de")))
+	h[1][0] <- []byte("1234")
+	h[2][0] <- []byte("5")
+	_, err := r.MessagesReceive(ctx, []int{1, 2})
+	require.NoError(t, err)
+
+	h[1][0] <- []byte("xy")
+	_, err = r.MessageReceive(ctx, 1)
+	require.NoError(t, err)
+
+	rounds := r.Rounds()
+	require.Len(t, rounds, 2)
+	assert.Equal(t, Round{Index: 1, Wait: rounds[0].Wait, Messages: 2, BytesSent: 5, BytesReceived: 5}, rounds[0])
+	assert.Equal(t, Round{Index: 2, Wait: rounds[1].Wait, Messages: 1, BytesSent: 0, BytesReceived: 2}, rounds[1])
+
+	r.Reset()
+	assert.Empty(t, r.Rounds())
+}
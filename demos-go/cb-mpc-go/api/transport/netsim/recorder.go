@@ -0,0 +1,94 @@
+package netsim
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// Round describes one receive call of a party and the traffic that led up to
+// it.
+type Round struct {
+	Index         int           // 1-based, counted since the last Reset
+	Wait          time.Duration // Time spent waiting for the round's messages
+	Messages      int           // Messages received in the round
+	BytesSent     int           // Bytes sent since the previous round
+	BytesReceived int           // Bytes received in the round
+}
+
+// Recorder implements transport.Messenger on top of another Messenger,
+// recording a Round for every MessageReceive and MessagesReceive call.
+type Recorder struct {
+	inner transport.Messenger
+
+	mu     sync.Mutex
+	rounds []Round
+	sent   int
+}
+
+// Ensure Recorder implements the Messenger interface
+var _ transport.Messenger = (*Recorder)(nil)
+
+// NewRecorder wraps inner.
+func NewRecorder(inner transport.Messenger) *Recorder {
+	return &Recorder{inner: inner}
+}
+
+// MessageSend sends a message to the specified receiver party
+func (r *Recorder) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	err := r.inner.MessageSend(ctx, receiver, buffer)
+	if err == nil {
+		r.mu.Lock()
+		r.sent += len(buffer)
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// MessageReceive receives a message from the specified sender party
+func (r *Recorder) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	start := time.Now()
+	msg, err := r.inner.MessageReceive(ctx, sender)
+	if err == nil {
+		r.record(time.Since(start), msg)
+	}
+	return msg, err
+}
+
+// MessagesReceive receives messages from multiple sender parties
+func (r *Recorder) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	start := time.Now()
+	msgs, err := r.inner.MessagesReceive(ctx, senders)
+	if err == nil {
+		r.record(time.Since(start), msgs...)
+	}
+	return msgs, err
+}
+
+func (r *Recorder) record(wait time.Duration, msgs ...[]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	round := Round{Index: len(r.rounds) + 1, Wait: wait, Messages: len(msgs), BytesSent: r.sent}
+	for _, m := range msgs {
+		round.BytesReceived += len(m)
+	}
+	r.rounds = append(r.rounds, round)
+	r.sent = 0
+}
+
+// Rounds returns the rounds recorded since the last Reset.
+func (r *Recorder) Rounds() []Round {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Round(nil), r.rounds...)
+}
+
+// Reset clears the recorded rounds, e.g. between two protocol runs.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rounds = nil
+	r.sent = 0
+}
@@ -0,0 +1,40 @@
+// Package preflight checks, before an expensive protocol starts, that every
+// required party is online and holds the expected key.
+//
+// A DKG or signing protocol only notices a missing party when it waits for
+// that party's first message, and a party holding a stale share – one that
+// missed the last refresh – is only noticed when the protocol fails after
+// its heavy rounds.  Check runs a two-message exchange over the session's
+// Messenger first:
+//
+//  1. ping – every party sends each peer its keystore.KeyVersion, the key
+//     ID and refresh epoch of the share it will use (zero for a DKG), and
+//     the hash of the intent it is about to sign (see chain.Intent in the
+//     wallet), and
+//  2. commit – every party tells each peer whether all pings it received
+//     arrived in time and matched its own key version and intent.
+//
+// Key versions are compared with keystore.CompareKeyVersions, as in
+// keystore.CheckPeerVersions, so Error.Versions names stale parties –
+// including this one – and parties holding another key.
+//
+// Comparing intents before the first round means a party that was handed a
+// different transaction than the others, or decoded it differently, stops
+// the session before any share is used.
+//
+// Each step is bounded by Config.Timeout, a few hundred milliseconds by
+// default, so an unreachable party or a mismatched key fails the session
+// almost at once, and the commit step makes every party fail together
+// rather than leaving some of them waiting in the first heavy round.
+//
+//	if err := preflight.Check(ctx, messenger, preflight.Config{
+//	    Self: self, Peers: quorum, KeyVersion: version, Intent: intentHash,
+//	}); err != nil {
+//	    var perr *preflight.Error // names the offline and mismatched parties
+//	    ...
+//	}
+//
+// A failed check may leave frames in flight, so the Messenger (or mux
+// stream) must not be reused for the session; retry with a new one.  Either
+// all parties of a session run the check or none does.
+package preflight
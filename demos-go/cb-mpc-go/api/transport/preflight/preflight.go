@@ -0,0 +1,232 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+)
+
+// Frame kinds.
+const (
+	kindPing   byte = 0xf1
+	kindCommit byte = 0xf2
+)
+
+// ErrNotReady is wrapped by every Error.
+var ErrNotReady = errors.New("preflight: parties not ready")
+
+// Error names the parties that made the check fail.
+type Error struct {
+	Offline    []int // Parties whose ping or commit did not arrive in time
+	Mismatched []int // Parties holding a different key version
+	Disagreed  []int // Parties about to sign a different intent
+	Malformed  []int // Parties that sent something other than a preflight frame
+	Aborted    []int // Parties that reported a failure of their own
+	// Versions is keystore's verdict on the key versions, naming stale
+	// parties, possibly including this one, and parties of another key.
+	Versions *keystore.VersionMismatchError
+}
+
+func (e *Error) Error() string {
+	var parts []string
+	for _, f := range []struct {
+		name    string
+		parties []int
+	}{
+		{"offline", e.Offline},
+		{"key version mismatch", e.Mismatched},
+		{"intent mismatch", e.Disagreed},
+		{"malformed preflight frame", e.Malformed},
+		{"aborted", e.Aborted},
+	} {
+		if len(f.parties) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %v", f.name, f.parties))
+		}
+	}
+	if e.Versions != nil {
+		parts = append(parts, e.Versions.Error())
+	}
+	return "preflight check failed: " + strings.Join(parts, "; ")
+}
+
+func (e *Error) Unwrap() error { return ErrNotReady }
+
+// Config contains the configuration of a preflight check.
+type Config struct {
+	// Self is the index of the local party.
+	Self int
+	// Peers lists the indices of the other parties that must take part.
+	Peers []int
+	// KeyVersion identifies the share every party must hold, compared
+	// like keystore.CheckPeerVersions does.  Zero for a DKG.
+	KeyVersion keystore.KeyVersion
+	// Intent is the hash of what the session is about to sign, such as
+	// chain.Intent.Hash, computed by every party on its own.  Empty for a
+	// DKG.
+	Intent []byte
+	// Timeout bounds each of the two steps.  Defaults to 500ms.
+	Timeout time.Duration
+}
+
+// Check runs the preflight exchange with every peer over m.  It returns an
+// *Error, which wraps ErrNotReady, unless every peer is online, holds
+// config.KeyVersion, computed config.Intent and reports the same of all the
+// others.
+func Check(ctx context.Context, m transport.Messenger, config Config) error {
+	if m == nil {
+		return fmt.Errorf("messenger cannot be nil")
+	}
+	if len(config.Peers) == 0 {
+		return fmt.Errorf("at least one peer must be provided")
+	}
+	seen := make(map[int]bool, len(config.Peers))
+	for _, peer := range config.Peers {
+		if seen[peer] || peer == config.Self {
+			return fmt.Errorf("duplicate peer %d", peer)
+		}
+		seen[peer] = true
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 500 * time.Millisecond
+	}
+
+	result := &Error{}
+	own := config.KeyVersion.Bytes()
+	if len(own) > math.MaxUint16 {
+		return fmt.Errorf("key version too long")
+	}
+	ping := []byte{kindPing}
+	ping = binary.BigEndian.AppendUint16(ping, uint16(len(own)))
+	ping = append(append(ping, own...), config.Intent...)
+	pings := exchange(ctx, m, config.Peers, config.Timeout, ping)
+	responsive := make([]int, 0, len(config.Peers))
+	versions := map[int]keystore.KeyVersion{config.Self: config.KeyVersion}
+	for _, peer := range config.Peers {
+		frame, ok := pings[peer]
+		if !ok {
+			result.Offline = append(result.Offline, peer)
+			continue
+		}
+		responsive = append(responsive, peer)
+		version, intent, ok := parsePing(frame)
+		if !ok {
+			result.Malformed = append(result.Malformed, peer)
+			continue
+		}
+		versions[peer] = version
+		switch {
+		case !version.Equal(config.KeyVersion):
+			result.Mismatched = append(result.Mismatched, peer)
+		case !bytes.Equal(intent, config.Intent):
+			result.Disagreed = append(result.Disagreed, peer)
+		}
+	}
+	if err := keystore.CompareKeyVersions(config.Self, versions); err != nil {
+		if !errors.As(err, &result.Versions) {
+			return err
+		}
+	}
+
+	// Everyone who answered learns whether this party is ready, so that all
+	// parties fail together.
+	status := byte(0)
+	if !result.empty() {
+		status = 1
+	}
+	if len(responsive) > 0 {
+		commits := exchange(ctx, m, responsive, config.Timeout, []byte{kindCommit, status})
+		for _, peer := range responsive {
+			frame, ok := commits[peer]
+			switch {
+			case !ok:
+				result.Offline = append(result.Offline, peer)
+			case len(frame) != 2 || frame[0] != kindCommit:
+				if !contains(result.Malformed, peer) {
+					result.Malformed = append(result.Malformed, peer)
+				}
+			case frame[1] != 0:
+				result.Aborted = append(result.Aborted, peer)
+			}
+		}
+	}
+	if result.empty() {
+		return nil
+	}
+	return result
+}
+
+// parsePing splits a ping into the sender's key version and intent.
+func parsePing(frame []byte) (version keystore.KeyVersion, intent []byte, ok bool) {
+	if len(frame) < 3 || frame[0] != kindPing {
+		return version, nil, false
+	}
+	n := int(binary.BigEndian.Uint16(frame[1:3]))
+	if len(frame) < 3+n {
+		return version, nil, false
+	}
+	version, err := keystore.ParseKeyVersion(frame[3 : 3+n])
+	if err != nil {
+		return version, nil, false
+	}
+	return version, frame[3+n:], true
+}
+
+func (e *Error) empty() bool {
+	return len(e.Offline)+len(e.Mismatched)+len(e.Disagreed)+len(e.Malformed)+len(e.Aborted) == 0 && e.Versions == nil
+}
+
+func contains(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// exchange sends frame to every peer and collects the frames that arrive
+// within timeout.  Messengers need not honour the context in MessageReceive,
+// so receives that outlast it are abandoned.
+func exchange(ctx context.Context, m transport.Messenger, peers []int, timeout time.Duration, frame []byte) map[int][]byte {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type received struct {
+		peer  int
+		frame []byte
+	}
+	ch := make(chan received, len(peers))
+	for _, peer := range peers {
+		go func(peer int) {
+			if err := m.MessageSend(ctx, peer, frame); err != nil {
+				ch <- received{peer: peer}
+				return
+			}
+			msg, err := m.MessageReceive(ctx, peer)
+			if err != nil {
+				msg = nil
+			}
+			ch <- received{peer: peer, frame: msg}
+		}(peer)
+	}
+	out := make(map[int][]byte, len(peers))
+	for range peers {
+		select {
+		case r := <-ch:
+			if r.frame != nil {
+				out[r.peer] = r.frame
+			}
+		case <-ctx.Done():
+			return out
+		}
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package preflight
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+)
+
+var (
+	keyA1 = keystore.KeyVersion{KeyID: []byte("key-a"), Epoch: 1}
+	keyA2 = keystore.KeyVersion{KeyID: []byte("key-a"), Epoch: 2}
+)
+
+// run checks the parties listed in versions, each holding its key version,
+// out of n parties.
+func run(n int, versions map[int]keystore.KeyVersion) map[int]error {
+	links := mocknet.NewMockNetwork(n)
+	type result struct {
+		party int
+		err   error
+	}
+	ch := make(chan result, len(versions))
+	for i, version := range versions {
+		config := Config{Self: i, KeyVersion: version, Timeout: 100 * time.Millisecond}
+		for j := 0; j < n; j++ {
+			if j != i {
+				config.Peers = append(config.Peers, j)
+			}
+		}
+		go func(i int) {
+			ch <- result{i, Check(context.Background(), links[i], config)}
+		}(i)
+	}
+	errs := make(map[int]error, len(versions))
+	for range versions {
+		r := <-ch
+		errs[r.party] = r.err
+	}
+	return errs
+}
+
+func TestAllReady(t *testing.T) {
+	for i, err := range run(3, map[int]keystore.KeyVersion{0: keyA1, 1: keyA1, 2: keyA1}) {
+		assert.NoError(t, err, "party %d", i)
+	}
+}
+
+func TestOfflineParty(t *testing.T) {
+	start := time.Now()
+	errs := run(3, map[int]keystore.KeyVersion{0: keyA1, 1: keyA1})
+	assert.Less(t, time.Since(start), time.Second)
+	for _, i := range []int{0, 1} {
+		var perr *Error
+		require.ErrorAs(t, errs[i], &perr, "party %d", i)
+		assert.ErrorIs(t, errs[i], ErrNotReady)
+		assert.Equal(t, []int{2}, perr.Offline)
+		assert.Equal(t, []int{1 - i}, perr.Aborted, "the other party saw party 2 missing too")
+	}
+}
+
+func TestKeyVersionMismatch(t *testing.T) {
+	errs := run(3, map[int]keystore.KeyVersion{0: keyA2, 1: keyA2, 2: keyA1})
+
+	var perr *Error
+	require.ErrorAs(t, errs[0], &perr)
+	assert.Equal(t, []int{2}, perr.Mismatched)
+	assert.Equal(t, []int{1, 2}, perr.Aborted)
+	require.NotNil(t, perr.Versions)
+	assert.Equal(t, []int{2}, perr.Versions.Stale)
+	assert.ErrorContains(t, errs[0], "key version mismatch: [2]")
+	assert.ErrorContains(t, errs[0], "stale shares (party 2 at epoch 1), latest epoch is 2")
+
+	require.ErrorAs(t, errs[2], &perr)
+	assert.Equal(t, []int{0, 1}, perr.Mismatched)
+	assert.Equal(t, []int{2}, perr.Versions.Stale, "the stale party learns it is behind")
+	assert.ErrorIs(t, perr.Versions, keystore.ErrRollback)
+
+	errs = run(2, map[int]keystore.KeyVersion{0: keyA1, 1: {KeyID: []byte("key-b"), Epoch: 1}})
+	require.ErrorAs(t, errs[0], &perr)
+	assert.Equal(t, []int{1}, perr.Versions.Foreign)
+	assert.ErrorIs(t, perr.Versions, keystore.ErrKeyMismatch)
+}
+
+func TestIntentMismatch(t *testing.T) {
+	links := mocknet.NewMockNetwork(3)
+	intents := map[int]string{0: "pay bob 1", 1: "pay bob 1", 2: "pay mallory 1"}
+	errs := make(chan error, 3)
+	for i, intent := range intents {
+		config := Config{Self: i, KeyVersion: keyA1, Intent: []byte(intent), Timeout: 100 * time.Millisecond}
+		for j := 0; j < 3; j++ {
+			if j != i {
+				config.Peers = append(config.Peers, j)
+			}
+		}
+		go func(i int) {
+			err := Check(context.Background(), links[i], config)
+			if i == 0 {
+				var perr *Error
+				if assert.ErrorAs(t, err, &perr) {
+					assert.Equal(t, []int{2}, perr.Disagreed)
+					assert.Empty(t, perr.Mismatched)
+				}
+			}
+			errs <- err
+		}(i)
+	}
+	for range intents {
+		assert.ErrorIs(t, <-errs, ErrNotReady)
+	}
+}
+
+func TestMalformedFrame(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	ctx := context.Background()
+	require.NoError(t, links[1].MessageSend(ctx, 0, []byte("protocol round 1")))
+	err := Check(ctx, links[0], Config{Self: 0, Peers: []int{1}, Timeout: 50 * time.Millisecond})
+	var perr *Error
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, []int{1}, perr.Malformed)
+}
+
+func TestConfigValidation(t *testing.T) {
+	links := mocknet.NewMockNetwork(2)
+	ctx := context.Background()
+	assert.Error(t, Check(ctx, nil, Config{Peers: []int{1}}))
+	assert.Error(t, Check(ctx, links[0], Config{}))
+	assert.Error(t, Check(ctx, links[0], Config{Peers: []int{1, 1}}))
+	assert.Error(t, Check(ctx, links[0], Config{Self: 1, Peers: []int{1}}))
+}
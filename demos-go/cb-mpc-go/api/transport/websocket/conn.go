@@ -0,0 +1,299 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is the fixed GUID from RFC 6455 section 1.3.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// partyHeader carries the dialing party's index in the upgrade request.
+const partyHeader = "X-Cbmpc-Party"
+
+// Frame opcodes.
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xA
+)
+
+// maxControlPayload is the largest payload a control frame may carry.
+const maxControlPayload = 125
+
+// ErrClosed is returned once the peer has closed the connection.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// conn is one WebSocket connection.  Writes and reads are each serialized;
+// a read and a write may run concurrently.
+type conn struct {
+	nc      net.Conn
+	r       *bufio.Reader
+	client  bool // clients mask outgoing frames and expect unmasked ones
+	maxSize int
+
+	wmu sync.Mutex
+	rmu sync.Mutex
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether a comma-separated header contains token.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dial performs the client side of the opening handshake on nc.
+func dial(nc net.Conn, host, path string, self, maxSize int) (*conn, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(raw)
+	req := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n%s: %d\r\n\r\n", path, host, key, partyHeader, self)
+	if _, err := io.WriteString(nc, req); err != nil {
+		return nil, fmt.Errorf("sending upgrade request: %v", err)
+	}
+	r := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading upgrade response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("upgrade rejected: %s", resp.Status)
+	}
+	if !headerHasToken(resp.Header, "Upgrade", "websocket") || !headerHasToken(resp.Header, "Connection", "upgrade") {
+		return nil, fmt.Errorf("upgrade response is not a websocket upgrade")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, fmt.Errorf("upgrade response has a wrong Sec-WebSocket-Accept")
+	}
+	return &conn{nc: nc, r: r, client: true, maxSize: maxSize}, nil
+}
+
+// accept performs the server side of the opening handshake on nc and
+// returns the index announced by the dialing party.  check vets that index
+// before the upgrade is granted.
+func accept(nc net.Conn, path string, maxSize int, check func(party int) error) (*conn, int, error) {
+	r := bufio.NewReader(nc)
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading upgrade request: %v", err)
+	}
+	party, err := strconv.Atoi(req.Header.Get(partyHeader))
+	switch {
+	case err != nil:
+		err = fmt.Errorf("missing or invalid %s header", partyHeader)
+	case req.Method != http.MethodGet || req.URL.Path != path:
+		err = fmt.Errorf("unexpected upgrade request %s %s", req.Method, req.URL.Path)
+	case !headerHasToken(req.Header, "Upgrade", "websocket") || !headerHasToken(req.Header, "Connection", "upgrade"):
+		err = fmt.Errorf("request is not a websocket upgrade")
+	case req.Header.Get("Sec-WebSocket-Version") != "13":
+		err = fmt.Errorf("unsupported websocket version %q", req.Header.Get("Sec-WebSocket-Version"))
+	case req.Header.Get("Sec-WebSocket-Key") == "":
+		err = fmt.Errorf("missing Sec-WebSocket-Key")
+	default:
+		err = check(party)
+	}
+	if err != nil {
+		io.WriteString(nc, "HTTP/1.1 400 Bad Request\r\nConnection: close\r\n\r\n")
+		return nil, 0, err
+	}
+	resp := fmt.Sprintf("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(req.Header.Get("Sec-WebSocket-Key")))
+	if _, err := io.WriteString(nc, resp); err != nil {
+		return nil, 0, fmt.Errorf("sending upgrade response: %v", err)
+	}
+	return &conn{nc: nc, r: r, maxSize: maxSize}, party, nil
+}
+
+// writeFrame sends a single final frame, masking it if c is a client.
+func (c *conn) writeFrame(ctx context.Context, op byte, payload []byte) error {
+	return c.write(ctx, 0x80|op, payload)
+}
+
+// write sends one frame whose first header byte (FIN flag and opcode) is b0.
+func (c *conn) write(ctx context.Context, b0 byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	deadline, _ := ctx.Deadline()
+	c.nc.SetWriteDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { c.nc.SetWriteDeadline(time.Now()) })
+	defer stop()
+
+	header := make([]byte, 2, 14)
+	header[0] = b0
+	switch n := len(payload); {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	frame := payload
+	if c.client {
+		header[1] |= 0x80
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		header = append(header, mask[:]...)
+		frame = make([]byte, len(payload))
+		for i, b := range payload {
+			frame[i] = b ^ mask[i%4]
+		}
+	}
+	// Header and payload go out in one write so each message costs one
+	// syscall (and one TLS record when wrapped in TLS).
+	if _, err := c.nc.Write(append(header, frame...)); err != nil {
+		return fmt.Errorf("writing frame: %v", err)
+	}
+	return nil
+}
+
+// readMessage returns the payload of the next data message, answering pings
+// and reassembling fragmented messages on the way.
+func (c *conn) readMessage(ctx context.Context) ([]byte, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	deadline, _ := ctx.Deadline()
+	c.nc.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { c.nc.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	var (
+		msg     []byte
+		started bool
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(ctx, opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeFrame(ctx, opClose, nil)
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				return nil, fmt.Errorf("new message inside a fragmented message")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, fmt.Errorf("continuation frame without a message")
+			}
+		default:
+			return nil, fmt.Errorf("unknown opcode %#x", op)
+		}
+		if len(msg)+len(payload) > c.maxSize {
+			return nil, fmt.Errorf("message too large: more than %d bytes", c.maxSize)
+		}
+		msg = append(msg, payload...)
+		if fin {
+			if msg == nil {
+				msg = []byte{}
+			}
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads and unmasks one frame.
+func (c *conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.r, h[:]); err != nil {
+		return false, 0, nil, fmt.Errorf("reading frame header: %v", err)
+	}
+	fin, op = h[0]&0x80 != 0, h[0]&0x0F
+	if h[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("reserved frame bits set")
+	}
+	masked := h[1]&0x80 != 0
+	if masked == c.client {
+		return false, 0, nil, fmt.Errorf("frame masking violates RFC 6455")
+	}
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, fmt.Errorf("reading frame length: %v", err)
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, fmt.Errorf("reading frame length: %v", err)
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= opClose && (n > maxControlPayload || !fin) {
+		return false, 0, nil, fmt.Errorf("invalid control frame")
+	}
+	if n > uint64(c.maxSize) {
+		return false, 0, nil, fmt.Errorf("message too large: %d bytes", n)
+	}
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.r, mask[:]); err != nil {
+			return false, 0, nil, fmt.Errorf("reading frame mask: %v", err)
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, fmt.Errorf("reading frame payload: %v", err)
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// close sends a close frame and closes the underlying connection.
+func (c *conn) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.writeFrame(ctx, opClose, nil)
+	return c.nc.Close()
+}
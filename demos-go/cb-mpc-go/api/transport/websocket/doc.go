@@ -0,0 +1,19 @@
+// Package websocket provides a Messenger that carries MPC messages over
+// WebSocket connections (RFC 6455).
+//
+// WebSocket is useful where parties sit behind HTTP infrastructure – reverse
+// proxies, load balancers or corporate egress gateways – that passes upgraded
+// HTTP connections but not raw TCP.  Each pair of parties shares a single
+// connection and every protocol message travels as one binary message, so the
+// transport adds only a few bytes of framing per message.
+//
+// As with mtls, the party with the lower index listens and the party with the
+// higher index dials.  The dialing party announces its index in the upgrade
+// request.  That announcement is only as trustworthy as the connection it
+// arrives on: production deployments should set TLSConfig with client
+// certificates and check them in VerifyPeer.
+//
+// The package implements the subset of RFC 6455 the transport needs
+// (binary and continuation frames, ping/pong and close) and has no
+// dependencies outside the standard library.
+package websocket
@@ -0,0 +1,257 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultPath           = "/cbmpc"
+	defaultConnectTimeout = 30 * time.Second
+	defaultMaxMessageSize = 10 * 1024 * 1024
+	dialRetryInterval     = 200 * time.Millisecond
+)
+
+// Config contains the configuration for a WebSocket Messenger.
+type Config struct {
+	// SelfIndex is the index of this party.
+	SelfIndex int
+	// Addresses maps every party, including this one, to its host:port.
+	Addresses map[int]string
+	// TLSConfig, if set, wraps every connection in TLS (wss).  It is used as
+	// the server config when accepting and as the client config when dialing.
+	TLSConfig *tls.Config
+	// VerifyPeer, if set, is called for every established connection with
+	// the peer's index and, over TLS, the connection state.  Returning an
+	// error aborts the setup.
+	VerifyPeer func(party int, state *tls.ConnectionState) error
+	// Path is the HTTP path of the upgrade request.  Defaults to "/cbmpc".
+	Path string
+	// ConnectTimeout bounds connection setup.  Defaults to 30s.
+	ConnectTimeout time.Duration
+	// MaxMessageSize limits a single message.  Defaults to 10 MiB.
+	MaxMessageSize int
+}
+
+// Messenger implements transport.Messenger over one WebSocket connection per
+// pair of parties.
+type Messenger struct {
+	conns    map[int]*conn
+	listener net.Listener
+}
+
+// Ensure Messenger implements the Messenger interface
+var _ transport.Messenger = (*Messenger)(nil)
+
+// NewMessenger connects to all other parties and returns once every
+// connection is established.  Parties with a lower index are dialed, parties
+// with a higher index are accepted.
+func NewMessenger(config Config) (*Messenger, error) {
+	if _, ok := config.Addresses[config.SelfIndex]; !ok {
+		return nil, fmt.Errorf("no address for self index %d", config.SelfIndex)
+	}
+	if config.Path == "" {
+		config.Path = defaultPath
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaultConnectTimeout
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = defaultMaxMessageSize
+	}
+	deadline := time.Now().Add(config.ConnectTimeout)
+
+	m := &Messenger{conns: make(map[int]*conn)}
+	var mu sync.Mutex
+	add := func(party int, c *conn) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, dup := m.conns[party]; dup {
+			return fmt.Errorf("party %d connected twice", party)
+		}
+		m.conns[party] = c
+		return nil
+	}
+
+	incoming := 0
+	for i := range config.Addresses {
+		if i > config.SelfIndex {
+			incoming++
+		}
+	}
+
+	eg := errgroup.Group{}
+	if incoming > 0 {
+		ln, err := net.Listen("tcp", config.Addresses[config.SelfIndex])
+		if err != nil {
+			return nil, fmt.Errorf("listening on %s: %v", config.Addresses[config.SelfIndex], err)
+		}
+		m.listener = ln
+		eg.Go(func() error { return m.acceptAll(config, incoming, deadline, add) })
+	}
+	for i, addr := range config.Addresses {
+		if i >= config.SelfIndex {
+			continue
+		}
+		eg.Go(func() error {
+			c, err := dialParty(config, i, addr, deadline)
+			if err != nil {
+				return fmt.Errorf("connecting to party %d at %s: %v", i, addr, err)
+			}
+			return add(i, c)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// acceptAll accepts connections until every higher-indexed party is
+// connected.  Upgrade requests from unknown parties are rejected without
+// aborting the setup.
+func (m *Messenger) acceptAll(config Config, want int, deadline time.Time, add func(int, *conn) error) error {
+	timer := time.AfterFunc(time.Until(deadline), func() { m.listener.Close() })
+	defer timer.Stop()
+	for got := 0; got < want; {
+		nc, err := m.listener.Accept()
+		if err != nil {
+			return fmt.Errorf("accepting connections: %v", err)
+		}
+		if config.TLSConfig != nil {
+			nc = tls.Server(nc, config.TLSConfig)
+		}
+		nc.SetDeadline(deadline)
+		c, party, err := accept(nc, config.Path, config.MaxMessageSize, func(party int) error {
+			if party <= config.SelfIndex || config.Addresses[party] == "" {
+				return fmt.Errorf("unexpected party %d", party)
+			}
+			return verifyPeer(config, party, nc)
+		})
+		if err != nil {
+			nc.Close()
+			continue
+		}
+		nc.SetDeadline(time.Time{})
+		if err := add(party, c); err != nil {
+			c.close()
+			continue
+		}
+		got++
+	}
+	return nil
+}
+
+// dialParty connects to a lower-indexed party, retrying until deadline while
+// the party is not yet listening.
+func dialParty(config Config, party int, addr string, deadline time.Time) (*conn, error) {
+	for {
+		c, err := dialOnce(config, party, addr, deadline)
+		if err == nil {
+			return c, nil
+		}
+		if time.Now().Add(dialRetryInterval).After(deadline) {
+			return nil, err
+		}
+		time.Sleep(dialRetryInterval)
+	}
+}
+
+func dialOnce(config Config, party int, addr string, deadline time.Time) (*conn, error) {
+	dialer := &net.Dialer{Deadline: deadline}
+	nc, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if config.TLSConfig != nil {
+		nc = tls.Client(nc, config.TLSConfig)
+	}
+	nc.SetDeadline(deadline)
+	c, err := dial(nc, addr, config.Path, config.SelfIndex, config.MaxMessageSize)
+	if err == nil {
+		err = verifyPeer(config, party, nc)
+	}
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	nc.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func verifyPeer(config Config, party int, nc net.Conn) error {
+	if config.VerifyPeer == nil {
+		return nil
+	}
+	var state *tls.ConnectionState
+	if tc, ok := nc.(*tls.Conn); ok {
+		if err := tc.Handshake(); err != nil {
+			return err
+		}
+		s := tc.ConnectionState()
+		state = &s
+	}
+	return config.VerifyPeer(party, state)
+}
+
+// MessageSend sends a message to the specified receiver party
+func (m *Messenger) MessageSend(ctx context.Context, receiver int, buffer []byte) error {
+	c, ok := m.conns[receiver]
+	if !ok {
+		return fmt.Errorf("no connection found for receiver index %d", receiver)
+	}
+	return c.writeFrame(ctx, opBinary, buffer)
+}
+
+// MessageReceive receives a message from the specified sender party
+func (m *Messenger) MessageReceive(ctx context.Context, sender int) ([]byte, error) {
+	c, ok := m.conns[sender]
+	if !ok {
+		return nil, fmt.Errorf("no connection found for sender index %d", sender)
+	}
+	msg, err := c.readMessage(ctx)
+	if err != nil && ctx.Err() != nil {
+		return nil, errors.Join(ctx.Err(), err)
+	}
+	return msg, err
+}
+
+// MessagesReceive receives messages from multiple sender parties concurrently
+func (m *Messenger) MessagesReceive(ctx context.Context, senders []int) ([][]byte, error) {
+	out := make([][]byte, len(senders))
+	eg := errgroup.Group{}
+	for i, sender := range senders {
+		eg.Go(func() error {
+			msg, err := m.MessageReceive(ctx, sender)
+			if err != nil {
+				return fmt.Errorf("receiving message from %d: %w", sender, err)
+			}
+			out[i] = msg
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Close sends a close frame on every connection and releases all resources.
+func (m *Messenger) Close() error {
+	for _, c := range m.conns {
+		c.close()
+	}
+	if m.listener != nil {
+		m.listener.Close()
+	}
+	return nil
+}
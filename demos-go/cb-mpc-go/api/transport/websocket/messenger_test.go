@@ -0,0 +1,207 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// freeAddresses reserves n loopback addresses.
+func freeAddresses(t *testing.T, n int) map[int]string {
+	t.Helper()
+	out := make(map[int]string, n)
+	for i := 0; i < n; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		out[i] = ln.Addr().String()
+		ln.Close()
+	}
+	return out
+}
+
+// network connects n parties, adjusting each Config with configure.
+func network(t *testing.T, n int, configure func(*Config)) []*Messenger {
+	t.Helper()
+	addrs := freeAddresses(t, n)
+	out := make([]*Messenger, n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			config := Config{SelfIndex: i, Addresses: addrs, ConnectTimeout: 5 * time.Second}
+			if configure != nil {
+				configure(&config)
+			}
+			m, err := NewMessenger(config)
+			out[i] = m
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+	t.Cleanup(func() {
+		for _, m := range out {
+			m.Close()
+		}
+	})
+	return out
+}
+
+func withTimeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestMessagesBetweenAllParties(t *testing.T) {
+	ms := network(t, 3, nil)
+	ctx := withTimeout(t)
+	for i, m := range ms {
+		for j := range ms {
+			if i != j {
+				require.NoError(t, m.MessageSend(ctx, j, []byte(fmt.Sprintf("%d->%d", i, j))))
+			}
+		}
+	}
+	msgs, err := ms[0].MessagesReceive(ctx, []int{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("1->0"), []byte("2->0")}, msgs)
+	msg, err := ms[2].MessageReceive(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("1->2"), msg)
+}
+
+func TestMessageSizes(t *testing.T) {
+	ms := network(t, 2, nil)
+	ctx := withTimeout(t)
+	// Cover the 7-bit, 16-bit and 64-bit length encodings in both
+	// directions (masked and unmasked frames).
+	for _, n := range []int{0, 125, 126, 0xFFFF, 0x10000, 1 << 20} {
+		payload := bytes.Repeat([]byte{byte(n)}, n)
+		require.NoError(t, ms[1].MessageSend(ctx, 0, payload))
+		got, err := ms[0].MessageReceive(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, payload, got, "size %d", n)
+
+		require.NoError(t, ms[0].MessageSend(ctx, 1, payload))
+		got, err = ms[1].MessageReceive(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, payload, got, "size %d", n)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	ms := network(t, 2, func(c *Config) { c.MaxMessageSize = 1024 })
+	ctx := withTimeout(t)
+	require.NoError(t, ms[1].MessageSend(ctx, 0, make([]byte, 2048)))
+	_, err := ms[0].MessageReceive(ctx, 1)
+	assert.ErrorContains(t, err, "message too large")
+}
+
+func TestReceiveHonoursContext(t *testing.T) {
+	ms := network(t, 2, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := ms[0].MessageReceive(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPingAnsweredAndFragmentsReassembled(t *testing.T) {
+	server, client := net.Pipe()
+	s := &conn{nc: server, r: bufio.NewReader(server), maxSize: 1024}
+	c := &conn{nc: client, r: bufio.NewReader(client), client: true, maxSize: 1024}
+	ctx := withTimeout(t)
+
+	go func() {
+		c.writeFrame(ctx, opPing, []byte("ping"))
+		// A message split over a binary and a continuation frame.
+		c.write(ctx, opBinary, []byte("hel"))
+		c.write(ctx, 0x80|opContinuation, []byte("lo"))
+	}()
+	pong := make(chan []byte, 1)
+	go func() {
+		_, op, payload, err := c.readFrame()
+		if err == nil && op == opPong {
+			pong <- payload
+		}
+	}()
+	msg, err := s.readMessage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), msg)
+	assert.Equal(t, []byte("ping"), <-pong)
+}
+
+func TestUnmaskedClientFrameRejected(t *testing.T) {
+	server, client := net.Pipe()
+	s := &conn{nc: server, r: bufio.NewReader(server), maxSize: 1024}
+	c := &conn{nc: client, r: bufio.NewReader(client), maxSize: 1024} // not marked as client: no mask
+	ctx := withTimeout(t)
+	go c.writeFrame(ctx, opBinary, []byte("x"))
+	_, err := s.readMessage(ctx)
+	assert.ErrorContains(t, err, "masking")
+}
+
+func TestUnknownPartyRejected(t *testing.T) {
+	addrs := freeAddresses(t, 2)
+	done := make(chan error, 1)
+	go func() {
+		m, err := NewMessenger(Config{SelfIndex: 0, Addresses: addrs, ConnectTimeout: 2 * time.Second})
+		if err == nil {
+			m.Close()
+		}
+		done <- err
+	}()
+	// Party 7 is not part of the session; the listener keeps waiting for
+	// party 1, which then connects normally.
+	var nc net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		nc, err = net.Dial("tcp", addrs[0])
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	_, err := dial(nc, addrs[0], defaultPath, 7, 1024)
+	assert.Error(t, err)
+	nc.Close()
+
+	m, err := NewMessenger(Config{SelfIndex: 1, Addresses: addrs, ConnectTimeout: 2 * time.Second})
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, <-done)
+}
+
+func TestVerifyPeerFailureAbortsSetup(t *testing.T) {
+	addrs := freeAddresses(t, 2)
+	reject := errors.New("not allowed")
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			m, err := NewMessenger(Config{
+				SelfIndex:      i,
+				Addresses:      addrs,
+				ConnectTimeout: time.Second,
+				VerifyPeer: func(party int, state *tls.ConnectionState) error {
+					assert.Nil(t, state)
+					if i == 0 {
+						return reject
+					}
+					return nil
+				},
+			})
+			if err == nil {
+				m.Close()
+			}
+			errs <- err
+		}(i)
+	}
+	assert.Error(t, <-errs)
+	assert.Error(t, <-errs)
+}
@@ -0,0 +1,114 @@
+package verify
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+
+	"filippo.io/edwards25519"
+)
+
+// BatchError reports which signatures of a batch do not verify.
+type BatchError struct {
+	Invalid []int // Indices of the failing signatures, in order
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of the signatures do not verify, first at index %d", len(e.Invalid), e.Invalid[0])
+}
+
+// Unwrap returns ErrInvalidSignature.
+func (e *BatchError) Unwrap() error { return ErrInvalidSignature }
+
+// VerifyBatch verifies sigs[i] of msgs[i] under pubs[i] for every i.  It
+// returns nil if all verify and a *BatchError naming the failing indices
+// otherwise.  Keys and signatures of the wrong length are reported as
+// errors, as by Ed25519.
+//
+// All signatures are checked with a single multi-scalar multiplication
+// using random coefficients, which takes little more than half the time of
+// verifying them one by one.  Only when the batch fails are the signatures
+// verified individually to find the culprits.
+//
+// The batch equation is the cofactored one of RFC 8032, section 5.1.7, while
+// Ed25519 uses the cofactorless one.  Both agree on every signature made by
+// an honest signer, including the MPC protocols; a signature crafted with
+// small-order components may pass in a batch yet fail on its own.
+func VerifyBatch(pubs, msgs, sigs [][]byte) error {
+	n := len(pubs)
+	if len(msgs) != n || len(sigs) != n {
+		return fmt.Errorf("batch has %d public keys, %d messages and %d signatures", n, len(msgs), len(sigs))
+	}
+	if n == 0 {
+		return nil
+	}
+
+	// [8]( -(Σ zᵢsᵢ)B + Σ zᵢRᵢ + Σ (zᵢkᵢ)Aᵢ ) must be the identity.
+	scalars := make([]*edwards25519.Scalar, 1, 1+2*n)
+	points := make([]*edwards25519.Point, 1, 1+2*n)
+	sum := edwards25519.NewScalar()
+	for i := range pubs {
+		if len(pubs[i]) != ed25519.PublicKeySize {
+			return fmt.Errorf("public key %d must be %d bytes, got %d", i, ed25519.PublicKeySize, len(pubs[i]))
+		}
+		if len(sigs[i]) != ed25519.SignatureSize {
+			return fmt.Errorf("signature %d must be %d bytes, got %d", i, ed25519.SignatureSize, len(sigs[i]))
+		}
+		A, errA := new(edwards25519.Point).SetBytes(pubs[i])
+		R, errR := new(edwards25519.Point).SetBytes(sigs[i][:32])
+		s, errS := edwards25519.NewScalar().SetCanonicalBytes(sigs[i][32:])
+		if errA != nil || errR != nil || errS != nil {
+			return individually(pubs, msgs, sigs)
+		}
+		z, err := randomCoefficient()
+		if err != nil {
+			return err
+		}
+		h := sha512.New()
+		h.Write(sigs[i][:32])
+		h.Write(pubs[i])
+		h.Write(msgs[i])
+		k, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+		if err != nil {
+			return err
+		}
+		sum.MultiplyAdd(z, s, sum)
+		scalars = append(scalars, z, edwards25519.NewScalar().Multiply(z, k))
+		points = append(points, R, A)
+	}
+	scalars[0] = edwards25519.NewScalar().Negate(sum)
+	points[0] = edwards25519.NewGeneratorPoint()
+
+	check := new(edwards25519.Point).VarTimeMultiScalarMult(scalars, points)
+	if check.MultByCofactor(check).Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil
+	}
+	return individually(pubs, msgs, sigs)
+}
+
+// individually verifies every signature of a failed batch on its own.
+func individually(pubs, msgs, sigs [][]byte) error {
+	var invalid []int
+	for i := range pubs {
+		if !ed25519.Verify(ed25519.PublicKey(pubs[i]), msgs[i], sigs[i]) {
+			invalid = append(invalid, i)
+		}
+	}
+	if len(invalid) == 0 {
+		// Unreachable: the cofactored equation accepts every signature
+		// the cofactorless one does.
+		return nil
+	}
+	return &BatchError{Invalid: invalid}
+}
+
+// randomCoefficient returns a uniformly random 128-bit scalar, enough to make
+// a forged batch pass with probability 2⁻¹²⁸.
+func randomCoefficient() (*edwards25519.Scalar, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:16]); err != nil {
+		return nil, err
+	}
+	return edwards25519.NewScalar().SetCanonicalBytes(b[:])
+}
@@ -0,0 +1,33 @@
+// Package verify checks signatures produced by the MPC protocols in pure Go.
+//
+// Verification needs no secret material and no native code, so services that
+// only verify – explorers, auditors, policy engines, light clients – can use
+// this package with CGO_ENABLED=0 and cross-compile freely, without linking
+// libcbmpc or OpenSSL.
+//
+// Signatures from mpc.EDDSAMPCSign are standard RFC 8032 Ed25519 signatures
+// over the group public key:
+//
+//	if err := verify.Ed25519(groupPubKey, message, signature); err != nil {
+//	    return err // errors.Is(err, verify.ErrInvalidSignature)
+//	}
+//
+// Auditors checking many signatures at once use `VerifyBatch`, which checks
+// the whole batch with one multi-scalar multiplication and only falls back
+// to individual checks to name the signatures that fail.
+//
+// FROST(Ed25519, SHA-512) signatures (RFC 9591) are standard Ed25519
+// signatures too.  Aggregators that hold the signers' round-one commitments
+// and round-two shares check each share with `VerifyFROSTShares`, or
+// aggregate them with `FROSTAggregate`, which names the signer of a bad
+// share in a *ShareError instead of producing a signature that the chain
+// rejects.
+//
+// Signatures from mpc.ECDSAMPCSign on secp256k1 are DER-encoded and checked
+// with `Secp256k1`.  Ethereum and Bitcoin signed messages need them as
+// 65-byte r || s || v instead, with a low S and the recovery id v that
+// `RecoverSecp256k1` uses to find the signer's key again;
+// `RecoverableSecp256k1` converts them:
+//
+//	rsv, err := verify.RecoverableSecp256k1(groupPubKey, digest, der)
+package verify
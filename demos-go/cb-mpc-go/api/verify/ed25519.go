@@ -0,0 +1,28 @@
+package verify
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSignature is returned when a well-formed signature does not
+// verify.
+var ErrInvalidSignature = errors.New("verify: invalid signature")
+
+// Ed25519 verifies an Ed25519 signature of message under the 32-byte public
+// key.  Malformed inputs are reported separately from signatures that do not
+// verify.  Signatures whose S is not reduced modulo the group order are
+// rejected, as RFC 8032 requires.
+func Ed25519(publicKey, message, signature []byte) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("signature must be %d bytes, got %d", ed25519.SignatureSize, len(signature))
+	}
+	if !ed25519.Verify(ed25519.PublicKey(publicKey), message, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
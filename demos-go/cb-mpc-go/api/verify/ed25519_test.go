@@ -0,0 +1,104 @@
+package verify
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestEd25519RFC8032Vector(t *testing.T) {
+	// RFC 8032, section 7.1, test 2.
+	pub := mustHex(t, "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c")
+	msg := mustHex(t, "72")
+	sig := mustHex(t, "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da"+
+		"085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00")
+	require.NoError(t, Ed25519(pub, msg, sig))
+
+	assert.ErrorIs(t, Ed25519(pub, []byte("other"), sig), ErrInvalidSignature)
+	sig[63] ^= 0x10 // Pushes S above the group order.
+	assert.ErrorIs(t, Ed25519(pub, msg, sig), ErrInvalidSignature)
+}
+
+func TestEd25519MalformedInput(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	sig := ed25519.Sign(priv, []byte("m"))
+
+	err = Ed25519(pub[:31], []byte("m"), sig)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+	err = Ed25519(pub, []byte("m"), sig[:63])
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+}
+
+func signBatch(t testing.TB, n int) (pubs, msgs, sigs [][]byte) {
+	for i := 0; i < n; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		msg := []byte(fmt.Sprintf("message %d", i))
+		pubs = append(pubs, pub)
+		msgs = append(msgs, msg)
+		sigs = append(sigs, ed25519.Sign(priv, msg))
+	}
+	return pubs, msgs, sigs
+}
+
+func TestVerifyBatch(t *testing.T) {
+	pubs, msgs, sigs := signBatch(t, 64)
+	require.NoError(t, VerifyBatch(pubs, msgs, sigs))
+	require.NoError(t, VerifyBatch(nil, nil, nil))
+
+	msgs[3] = []byte("tampered")
+	sigs[40] = append([]byte(nil), sigs[41]...)
+	err := VerifyBatch(pubs, msgs, sigs)
+	var be *BatchError
+	require.ErrorAs(t, err, &be)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.Equal(t, []int{3, 40}, be.Invalid)
+
+	// An S above the group order fails the batch, as it fails Ed25519.
+	pubs, msgs, sigs = signBatch(t, 4)
+	sigs[2][63] |= 0xf0
+	require.ErrorAs(t, VerifyBatch(pubs, msgs, sigs), &be)
+	assert.Equal(t, []int{2}, be.Invalid)
+
+	err = VerifyBatch(pubs, msgs[:3], sigs)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+	pubs[1] = pubs[1][:31]
+	err = VerifyBatch(pubs, msgs, sigs)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	pubs, msgs, sigs := signBatch(b, 1024)
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := VerifyBatch(pubs, msgs, sigs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("individual", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := range pubs {
+				if err := Ed25519(pubs[j], msgs[j], sigs[j]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"filippo.io/edwards25519"
+)
+
+// frostContext is the context string of FROST(Ed25519, SHA-512), RFC 9591
+// section 6.1.
+const frostContext = "FROST-ED25519-SHA512-v1"
+
+// FROSTCommitment is a signer's round-one commitment to its nonces.
+type FROSTCommitment struct {
+	Identifier []byte // 32-byte scalar, see FROSTIdentifier
+	Hiding     []byte // 32-byte hiding nonce commitment
+	Binding    []byte // 32-byte binding nonce commitment
+}
+
+// FROSTShare is a signer's round-two signature share.
+type FROSTShare struct {
+	Identifier []byte // 32-byte scalar, see FROSTIdentifier
+	Share      []byte // 32-byte scalar z
+	// VerifyingShare is the signer's public key share, as published by key
+	// generation.
+	VerifyingShare []byte
+}
+
+// ShareError reports which signature shares do not verify.
+type ShareError struct {
+	Invalid [][]byte // Identifiers of the failing signers, in input order
+}
+
+func (e *ShareError) Error() string {
+	return fmt.Sprintf("%d of the signature shares do not verify, first by signer %x", len(e.Invalid), e.Invalid[0])
+}
+
+// Unwrap returns ErrInvalidSignature.
+func (e *ShareError) Unwrap() error { return ErrInvalidSignature }
+
+// FROSTIdentifier returns the identifier of signer i, the scalar i, as used
+// by implementations that number signers from 1.
+func FROSTIdentifier(i uint16) []byte {
+	id := make([]byte, 32)
+	binary.LittleEndian.PutUint16(id, i)
+	return id
+}
+
+// VerifyFROSTShares checks every signature share of a FROST(Ed25519, SHA-512)
+// signing session of message under groupPublicKey, as in RFC 9591 section
+// 5.4.  commitments are the round-one commitments of all signers of the
+// session; shares holds a share of each of them.  It returns a *ShareError
+// naming the signers whose shares do not verify, so that a bad share is
+// pinned on its signer before anything is broadcast.
+func VerifyFROSTShares(groupPublicKey, message []byte, commitments []FROSTCommitment, shares []FROSTShare) error {
+	s, err := newFROSTSession(groupPublicKey, message, commitments, shares)
+	if err != nil {
+		return err
+	}
+	return s.verifyShares()
+}
+
+// FROSTAggregate verifies the signature shares like VerifyFROSTShares and
+// aggregates them into an Ed25519 signature, which it checks with Ed25519
+// before returning it.
+func FROSTAggregate(groupPublicKey, message []byte, commitments []FROSTCommitment, shares []FROSTShare) ([]byte, error) {
+	s, err := newFROSTSession(groupPublicKey, message, commitments, shares)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyShares(); err != nil {
+		return nil, err
+	}
+	z := edwards25519.NewScalar()
+	for _, sh := range s.shares {
+		z.Add(z, sh.z)
+	}
+	sig := append(s.commitment.Bytes(), z.Bytes()...)
+	if err := Ed25519(groupPublicKey, message, sig); err != nil {
+		return nil, fmt.Errorf("aggregate signature: %w", err)
+	}
+	return sig, nil
+}
+
+// frostSession is a decoded signing session.
+type frostSession struct {
+	commitment *edwards25519.Point  // Group commitment R
+	challenge  *edwards25519.Scalar // c = H2(R || PK || msg)
+	shares     []*frostSigner       // In the order of the shares
+}
+
+type frostSigner struct {
+	id       []byte
+	x        *edwards25519.Scalar
+	hiding   *edwards25519.Point
+	binding  *edwards25519.Point
+	rho      *edwards25519.Scalar // Binding factor
+	z        *edwards25519.Scalar
+	verifier *edwards25519.Point
+}
+
+func newFROSTSession(groupPublicKey, message []byte, commitments []FROSTCommitment, shares []FROSTShare) (*frostSession, error) {
+	if _, err := frostElement(groupPublicKey); err != nil {
+		return nil, fmt.Errorf("group public key: %w", err)
+	}
+	if len(commitments) == 0 || len(shares) != len(commitments) {
+		return nil, fmt.Errorf("session has %d commitments and %d shares", len(commitments), len(shares))
+	}
+	signers := make(map[string]*frostSigner, len(commitments))
+	list := make([]*frostSigner, 0, len(commitments))
+	for i, c := range commitments {
+		x, err := frostScalar(c.Identifier)
+		if err != nil || x.Equal(edwards25519.NewScalar()) == 1 {
+			return nil, fmt.Errorf("commitment %d: invalid identifier", i)
+		}
+		key := string(x.Bytes())
+		if signers[key] != nil {
+			return nil, fmt.Errorf("commitment %d: duplicate signer %x", i, c.Identifier)
+		}
+		s := &frostSigner{id: c.Identifier, x: x}
+		if s.hiding, err = frostElement(c.Hiding); err != nil {
+			return nil, fmt.Errorf("commitment %d: hiding commitment: %w", i, err)
+		}
+		if s.binding, err = frostElement(c.Binding); err != nil {
+			return nil, fmt.Errorf("commitment %d: binding commitment: %w", i, err)
+		}
+		signers[key] = s
+		list = append(list, s)
+	}
+	// The commitment list is encoded in ascending order of identifiers.
+	slices.SortFunc(list, func(a, b *frostSigner) int {
+		return bytes.Compare(reversed(a.x.Bytes()), reversed(b.x.Bytes()))
+	})
+
+	var encoded []byte
+	for _, s := range list {
+		encoded = append(encoded, s.x.Bytes()...)
+		encoded = append(encoded, s.hiding.Bytes()...)
+		encoded = append(encoded, s.binding.Bytes()...)
+	}
+	msgHash := frostHash("msg", message)
+	comHash := frostHash("com", encoded)
+	prefix := append(append(slices.Clip(groupPublicKey), msgHash...), comHash...)
+	R := edwards25519.NewIdentityPoint()
+	for _, s := range list {
+		s.rho = frostHashToScalar("rho", append(slices.Clip(prefix), s.x.Bytes()...))
+		R.Add(R, s.hiding)
+		R.Add(R, new(edwards25519.Point).ScalarMult(s.rho, s.binding))
+	}
+
+	h := sha512.New()
+	h.Write(R.Bytes())
+	h.Write(groupPublicKey)
+	h.Write(message)
+	c, err := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	session := &frostSession{commitment: R, challenge: c}
+	for i, sh := range shares {
+		x, err := frostScalar(sh.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: invalid identifier", i)
+		}
+		s := signers[string(x.Bytes())]
+		if s == nil || s.z != nil {
+			return nil, fmt.Errorf("share %d: signer %x has no commitment or more than one share", i, sh.Identifier)
+		}
+		if s.z, err = frostScalar(sh.Share); err != nil {
+			return nil, fmt.Errorf("share %d: %w", i, err)
+		}
+		if s.verifier, err = frostElement(sh.VerifyingShare); err != nil {
+			return nil, fmt.Errorf("share %d: verifying share: %w", i, err)
+		}
+		session.shares = append(session.shares, s)
+	}
+	return session, nil
+}
+
+// verifyShares checks z·B = D + ρ·E + (c·λ)·Y for every signer.
+func (s *frostSession) verifyShares() error {
+	var invalid [][]byte
+	for _, signer := range s.shares {
+		lambda := s.lagrange(signer.x)
+		want := new(edwards25519.Point).ScalarMult(signer.rho, signer.binding)
+		want.Add(want, signer.hiding)
+		want.Add(want, new(edwards25519.Point).ScalarMult(edwards25519.NewScalar().Multiply(s.challenge, lambda), signer.verifier))
+		if new(edwards25519.Point).ScalarBaseMult(signer.z).Equal(want) != 1 {
+			invalid = append(invalid, signer.id)
+		}
+	}
+	if invalid != nil {
+		return &ShareError{Invalid: invalid}
+	}
+	return nil
+}
+
+// lagrange returns the Lagrange coefficient of x at zero over the signers.
+func (s *frostSession) lagrange(x *edwards25519.Scalar) *edwards25519.Scalar {
+	num := edwards25519.NewScalar().Set(scalarOne)
+	den := edwards25519.NewScalar().Set(scalarOne)
+	for _, other := range s.shares {
+		if other.x.Equal(x) == 1 {
+			continue
+		}
+		num.Multiply(num, other.x)
+		den.Multiply(den, edwards25519.NewScalar().Subtract(other.x, x))
+	}
+	return num.Multiply(num, den.Invert(den))
+}
+
+var scalarOne, _ = edwards25519.NewScalar().SetCanonicalBytes(FROSTIdentifier(1))
+
+// frostScalar decodes a canonical 32-byte scalar.
+func frostScalar(b []byte) (*edwards25519.Scalar, error) {
+	s, err := edwards25519.NewScalar().SetCanonicalBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("scalar must be 32 canonical bytes")
+	}
+	return s, nil
+}
+
+// frostElement decodes a group element, which must not be the identity and
+// must lie in the prime-order subgroup (RFC 9591 section 6.5).
+func frostElement(b []byte) (*edwards25519.Point, error) {
+	p, err := new(edwards25519.Point).SetBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid point %s", hex.EncodeToString(b))
+	}
+	// (ℓ-1)·P + P is the identity exactly when P has order dividing ℓ.
+	q := new(edwards25519.Point).ScalarMult(scalarMinusOne, p)
+	if p.Equal(edwards25519.NewIdentityPoint()) == 1 || q.Add(q, p).Equal(edwards25519.NewIdentityPoint()) != 1 {
+		return nil, fmt.Errorf("point %s is not in the prime-order subgroup", hex.EncodeToString(b))
+	}
+	return p, nil
+}
+
+var scalarMinusOne = edwards25519.NewScalar().Negate(scalarOne)
+
+// frostHash is H4 or H5 of RFC 9591 section 6.5.
+func frostHash(tag string, m []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte(frostContext + tag))
+	h.Write(m)
+	return h.Sum(nil)
+}
+
+// frostHashToScalar is H1 or H3 of RFC 9591 section 6.5.
+func frostHashToScalar(tag string, m []byte) *edwards25519.Scalar {
+	s, _ := edwards25519.NewScalar().SetUniformBytes(frostHash(tag, m))
+	return s
+}
+
+// reversed returns the big-endian form of a little-endian scalar encoding.
+func reversed(b []byte) []byte {
+	out := slices.Clone(b)
+	slices.Reverse(out)
+	return out
+}
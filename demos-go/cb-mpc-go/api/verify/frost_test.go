@@ -0,0 +1,140 @@
+package verify
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomScalar(t *testing.T) *edwards25519.Scalar {
+	t.Helper()
+	b := make([]byte, 64)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	s, err := edwards25519.NewScalar().SetUniformBytes(b)
+	require.NoError(t, err)
+	return s
+}
+
+// frostSign runs a 2-of-3 FROST(Ed25519, SHA-512) signing session between
+// signers 1 and 3 and returns the group public key, commitments and shares.
+func frostSign(t *testing.T, message []byte) ([]byte, []FROSTCommitment, []FROSTShare) {
+	t.Helper()
+	secret, coefficient := randomScalar(t), randomScalar(t)
+	group := new(edwards25519.Point).ScalarBaseMult(secret).Bytes()
+	signers := []uint16{1, 3}
+
+	type nonces struct{ d, e *edwards25519.Scalar }
+	ns := make([]nonces, len(signers))
+	var commitments []FROSTCommitment
+	for i, id := range signers {
+		ns[i] = nonces{randomScalar(t), randomScalar(t)}
+		commitments = append(commitments, FROSTCommitment{
+			Identifier: FROSTIdentifier(id),
+			Hiding:     new(edwards25519.Point).ScalarBaseMult(ns[i].d).Bytes(),
+			Binding:    new(edwards25519.Point).ScalarBaseMult(ns[i].e).Bytes(),
+		})
+	}
+	var encoded []byte
+	for _, c := range commitments {
+		encoded = append(append(append(encoded, c.Identifier...), c.Hiding...), c.Binding...)
+	}
+	prefix := append(append(append([]byte{}, group...), frostHash("msg", message)...), frostHash("com", encoded)...)
+	rhos := make([]*edwards25519.Scalar, len(signers))
+	R := edwards25519.NewIdentityPoint()
+	for i, c := range commitments {
+		rhos[i] = frostHashToScalar("rho", append(append([]byte{}, prefix...), c.Identifier...))
+		R.Add(R, new(edwards25519.Point).ScalarBaseMult(edwards25519.NewScalar().MultiplyAdd(rhos[i], ns[i].e, ns[i].d)))
+	}
+	h := sha512.Sum512(append(append(R.Bytes(), group...), message...))
+	c, err := edwards25519.NewScalar().SetUniformBytes(h[:])
+	require.NoError(t, err)
+
+	// λ₁ = 3/(3-1) and λ₃ = 1/(1-3) for the signer set {1, 3}.
+	x := func(id uint16) *edwards25519.Scalar {
+		s, err := edwards25519.NewScalar().SetCanonicalBytes(FROSTIdentifier(id))
+		require.NoError(t, err)
+		return s
+	}
+	two := x(2)
+	lambdas := []*edwards25519.Scalar{
+		edwards25519.NewScalar().Multiply(x(3), edwards25519.NewScalar().Invert(two)),
+		edwards25519.NewScalar().Negate(edwards25519.NewScalar().Invert(two)),
+	}
+	var shares []FROSTShare
+	for i, id := range signers {
+		share := edwards25519.NewScalar().MultiplyAdd(coefficient, x(id), secret)
+		z := edwards25519.NewScalar().MultiplyAdd(rhos[i], ns[i].e, ns[i].d)
+		z.MultiplyAdd(edwards25519.NewScalar().Multiply(c, lambdas[i]), share, z)
+		shares = append(shares, FROSTShare{
+			Identifier:     FROSTIdentifier(id),
+			Share:          z.Bytes(),
+			VerifyingShare: new(edwards25519.Point).ScalarBaseMult(share).Bytes(),
+		})
+	}
+	return group, commitments, shares
+}
+
+func TestFROSTAggregate(t *testing.T) {
+	msg := []byte("transfer 1 SOL")
+	group, commitments, shares := frostSign(t, msg)
+
+	require.NoError(t, VerifyFROSTShares(group, msg, commitments, shares))
+	// Neither commitments nor shares need to be sorted.
+	reordered := []FROSTShare{shares[1], shares[0]}
+	sig, err := FROSTAggregate(group, msg, []FROSTCommitment{commitments[1], commitments[0]}, reordered)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(group, msg, sig))
+
+	err = VerifyFROSTShares(group, []byte("other"), commitments, shares)
+	var shareErr *ShareError
+	require.ErrorAs(t, err, &shareErr)
+	assert.Len(t, shareErr.Invalid, 2)
+}
+
+func TestFROSTBadShare(t *testing.T) {
+	msg := []byte("transfer 1 SOL")
+	group, commitments, shares := frostSign(t, msg)
+	z, err := edwards25519.NewScalar().SetCanonicalBytes(shares[1].Share)
+	require.NoError(t, err)
+	shares[1].Share = z.Add(z, scalarOne).Bytes()
+
+	_, err = FROSTAggregate(group, msg, commitments, shares)
+	var shareErr *ShareError
+	require.ErrorAs(t, err, &shareErr)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.Equal(t, [][]byte{FROSTIdentifier(3)}, shareErr.Invalid)
+}
+
+func TestFROSTMalformedInput(t *testing.T) {
+	msg := []byte("m")
+	group, commitments, shares := frostSign(t, msg)
+
+	_, err := FROSTAggregate(group, msg, commitments, shares[:1])
+	assert.Error(t, err)
+	_, err = FROSTAggregate(group, msg, commitments, []FROSTShare{shares[0], shares[0]})
+	assert.Error(t, err)
+
+	bad := append([]FROSTCommitment{}, commitments...)
+	bad[0].Hiding = edwards25519.NewIdentityPoint().Bytes()
+	err = VerifyFROSTShares(group, msg, bad, shares)
+	assert.ErrorContains(t, err, "prime-order subgroup")
+
+	// A point of order 4 (y = 0) on its own, and added to a valid point.
+	torsion := append(make([]byte, 31), 0x80)
+	_, err = frostElement(torsion)
+	assert.Error(t, err)
+	P, err := new(edwards25519.Point).SetBytes(group)
+	require.NoError(t, err)
+	T, err := new(edwards25519.Point).SetBytes(torsion)
+	require.NoError(t, err)
+	_, err = frostElement(new(edwards25519.Point).Add(P, T).Bytes())
+	assert.Error(t, err)
+	_, err = frostElement(group)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,149 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// The secp256k1 arithmetic is that of github.com/decred/dcrd/dcrec/secp256k1,
+// a pure-Go implementation that dcrd and btcd rely on.
+
+// RecoverableSize is the length of an r || s || v signature.
+const RecoverableSize = 65
+
+// compactMagic is added to the recovery id in the compact signatures of the
+// ecdsa package, which put it first: v + 27 || r || s.
+const compactMagic = 27
+
+// Secp256k1 verifies an ECDSA signature of hash under a 33-byte compressed
+// or 65-byte uncompressed SEC1 public key.  The signature is either DER, as
+// returned by mpc.ECDSAMPCSign, or 64-byte r || s.  Like the MPC engine it
+// accepts S in either half of the group order; chains that insist on low S
+// take the output of RecoverableSecp256k1 instead.
+func Secp256k1(publicKey, hash, signature []byte) error {
+	q, err := parseSecp256k1Key(publicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := parseECDSASignature(signature)
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(hash, q) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// RecoverableSecp256k1 converts a signature of hash under publicKey into the
+// 65-byte r || s || v form used by Ethereum transactions and Bitcoin signed
+// messages.  S is moved to the lower half of the group order (EIP-2, BIP-62)
+// and v is the recovery id, 0 or 1, for which RecoverSecp256k1 returns
+// publicKey.  Ethereum legacy transactions add 27, or 35 + 2·chainID, to v.
+func RecoverableSecp256k1(publicKey, hash, signature []byte) ([]byte, error) {
+	if err := Secp256k1(publicKey, hash, signature); err != nil {
+		return nil, err
+	}
+	q, _ := parseSecp256k1Key(publicKey)
+	sig, _ := parseECDSASignature(signature)
+	r, s := sig.R(), sig.S()
+	if s.IsOverHalfOrder() {
+		s.Negate()
+	}
+	compact := make([]byte, 1+64)
+	r.PutBytesUnchecked(compact[1:33])
+	s.PutBytesUnchecked(compact[33:])
+	for v := byte(0); v < 2; v++ {
+		compact[0] = compactMagic + v
+		p, _, err := ecdsa.RecoverCompact(compact, hash)
+		if err == nil && p.IsEqual(q) {
+			return append(compact[1:], v), nil
+		}
+	}
+	// The nonce point had an x coordinate above the group order, which
+	// happens with probability about 2^-128 and has no Ethereum encoding.
+	return nil, errors.New("signature has no recovery id 0 or 1")
+}
+
+// RecoverSecp256k1 returns the 65-byte uncompressed SEC1 public key for
+// which the r || s || v signature of hash is valid.  v may be given as 0 or
+// 1, or as 27 or 28.
+func RecoverSecp256k1(hash, signature []byte) ([]byte, error) {
+	if len(signature) != RecoverableSize {
+		return nil, fmt.Errorf("signature must be %d bytes, got %d", RecoverableSize, len(signature))
+	}
+	v := signature[64]
+	if v >= compactMagic {
+		v -= compactMagic
+	}
+	if v > 1 {
+		return nil, fmt.Errorf("recovery id must be 0, 1, 27 or 28, got %d", signature[64])
+	}
+	compact := append([]byte{compactMagic + v}, signature[:64]...)
+	q, _, err := ecdsa.RecoverCompact(compact, hash)
+	if err != nil {
+		return nil, err
+	}
+	return q.SerializeUncompressed(), nil
+}
+
+// UncompressSecp256k1 returns the 65-byte uncompressed SEC1 encoding of a
+// 33-byte compressed or 65-byte uncompressed secp256k1 public key, after
+// checking that the key lies on the curve.
+func UncompressSecp256k1(publicKey []byte) ([]byte, error) {
+	q, err := parseSecp256k1Key(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return q.SerializeUncompressed(), nil
+}
+
+// Secp256k1PublicKey returns the 65-byte uncompressed SEC1 public key of the
+// 32-byte big-endian private key d.  It is meant for tests and tools that
+// hold whole keys.
+func Secp256k1PublicKey(privateKey []byte) ([]byte, error) {
+	if len(privateKey) != 32 {
+		return nil, fmt.Errorf("private key must be %d bytes, got %d", 32, len(privateKey))
+	}
+	var d secp256k1.ModNScalar
+	if overflow := d.SetByteSlice(privateKey); overflow || d.IsZero() {
+		return nil, errors.New("private key out of range")
+	}
+	defer d.Zero()
+	return secp256k1.NewPrivateKey(&d).PubKey().SerializeUncompressed(), nil
+}
+
+// parseECDSASignature accepts DER or 64-byte r || s and checks that both
+// values lie in [1, N-1].
+func parseECDSASignature(signature []byte) (*ecdsa.Signature, error) {
+	if len(signature) != 64 {
+		sig, err := ecdsa.ParseDERSignature(signature)
+		if err != nil {
+			return nil, fmt.Errorf("signature is neither DER nor 64-byte r||s (%d bytes): %v", len(signature), err)
+		}
+		return sig, nil
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(signature[:32]) || r.IsZero() || s.SetByteSlice(signature[32:]) || s.IsZero() {
+		return nil, errors.New("signature values out of range")
+	}
+	return ecdsa.NewSignature(&r, &s), nil
+}
+
+// parseSecp256k1Key accepts a compressed or uncompressed SEC1 encoding.
+func parseSecp256k1Key(pub []byte) (*secp256k1.PublicKey, error) {
+	switch {
+	case len(pub) == secp256k1.PubKeyBytesLenCompressed && (pub[0] == 0x02 || pub[0] == 0x03),
+		len(pub) == secp256k1.PubKeyBytesLenUncompressed && pub[0] == 0x04:
+		q, err := secp256k1.ParsePubKey(pub)
+		if err != nil {
+			return nil, fmt.Errorf("public key is not on secp256k1: %v", err)
+		}
+		return q, nil
+	default:
+		return nil, fmt.Errorf("invalid secp256k1 public key encoding (%d bytes)", len(pub))
+	}
+}
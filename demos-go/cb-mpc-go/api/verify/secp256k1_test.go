@@ -0,0 +1,127 @@
+package verify
+
+import (
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+// secpSign signs hash with key and returns the public key and the signature
+// as r || s, with S in the upper half of the group order if high is set.
+func secpSign(key *secp256k1.PrivateKey, hash []byte, high bool) (pub, sig []byte) {
+	signed := ecdsa.Sign(key, hash)
+	r, s := signed.R(), signed.S()
+	if high {
+		s.Negate()
+	}
+	sig = make([]byte, 64)
+	r.PutBytesUnchecked(sig[:32])
+	s.PutBytesUnchecked(sig[32:])
+	return key.PubKey().SerializeUncompressed(), sig
+}
+
+func TestRecoverSecp256k1EIP155Vector(t *testing.T) {
+	// EIP-155 example: key 0x4646…46 signs a transfer on chain 1 with v = 37.
+	hash := mustHex(t, "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53")
+	sig := mustHex(t, "28ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276"+
+		"67cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"+"00")
+	pub, err := RecoverSecp256k1(hash, sig)
+	require.NoError(t, err)
+
+	h := sha3.NewLegacyKeccak256()
+	h.Write(pub[1:])
+	assert.Equal(t, mustHex(t, "9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f"), h.Sum(nil)[12:])
+	require.NoError(t, Secp256k1(pub, hash, sig[:64]))
+
+	rec, err := RecoverableSecp256k1(pub, hash, sig[:64])
+	require.NoError(t, err)
+	assert.Equal(t, sig, rec)
+}
+
+func TestRecoverableSecp256k1(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	hash := mustHex(t, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45")
+
+	for i := 0; i < 8; i++ {
+		pub, sig := secpSign(key, hash, i%2 == 1)
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+		require.NoError(t, err)
+		require.NoError(t, Secp256k1(pub, hash, der))
+
+		rec, err := RecoverableSecp256k1(pub, hash, der)
+		require.NoError(t, err)
+		require.Len(t, rec, RecoverableSize)
+		halfN := new(big.Int).Rsh(secp256k1.Params().N, 1)
+		assert.LessOrEqual(t, new(big.Int).SetBytes(rec[32:64]).Cmp(halfN), 0, "S is normalised")
+		assert.LessOrEqual(t, rec[64], byte(1))
+
+		got, err := RecoverSecp256k1(hash, rec)
+		require.NoError(t, err)
+		assert.Equal(t, pub, got)
+		rec[64] += 27
+		got, err = RecoverSecp256k1(hash, rec)
+		require.NoError(t, err)
+		assert.Equal(t, pub, got)
+	}
+}
+
+func TestSecp256k1Rejects(t *testing.T) {
+	hash := mustHex(t, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45")
+	one, two := new(secp256k1.ModNScalar).SetInt(1), new(secp256k1.ModNScalar).SetInt(2)
+	pub, sig := secpSign(secp256k1.NewPrivateKey(one), hash, false)
+	other, _ := secpSign(secp256k1.NewPrivateKey(two), hash, false)
+
+	assert.ErrorIs(t, Secp256k1(other, hash, sig), ErrInvalidSignature)
+	_, err := RecoverableSecp256k1(other, hash, sig)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.ErrorIs(t, Secp256k1(pub, []byte("other digest"), sig), ErrInvalidSignature)
+
+	for _, bad := range [][]byte{sig[:63], append([]byte{0x30, 0x02}, sig...), make([]byte, 64)} {
+		err := Secp256k1(pub, hash, bad)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidSignature)
+	}
+	err = Secp256k1(pub[:64], hash, sig)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = RecoverSecp256k1(hash, append(sig, 2))
+	assert.Error(t, err)
+}
+
+func TestSecp256k1PublicKeyEncodings(t *testing.T) {
+	one := make([]byte, 32)
+	one[31] = 1
+	pub, err := Secp256k1PublicKey(one)
+	require.NoError(t, err)
+	params := secp256k1.Params()
+	g := append([]byte{0x04}, append(params.Gx.FillBytes(make([]byte, 32)), params.Gy.FillBytes(make([]byte, 32))...)...)
+	assert.Equal(t, g, pub)
+
+	compressed := append([]byte{0x02}, params.Gx.FillBytes(make([]byte, 32))...)
+	got, err := UncompressSecp256k1(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, pub, got)
+	got, err = UncompressSecp256k1(pub)
+	require.NoError(t, err)
+	assert.Equal(t, pub, got)
+
+	_, err = UncompressSecp256k1([]byte{0x02, 0x01})
+	assert.Error(t, err)
+	bad := append([]byte{}, pub...)
+	bad[64] ^= 1
+	_, err = UncompressSecp256k1(bad)
+	assert.Error(t, err, "not on the curve")
+	_, err = Secp256k1PublicKey(make([]byte, 32))
+	assert.Error(t, err)
+	_, err = Secp256k1PublicKey(params.N.FillBytes(make([]byte, 32)))
+	assert.Error(t, err)
+}
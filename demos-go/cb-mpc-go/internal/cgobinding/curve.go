@@ -1,7 +1,11 @@
+//go:build !nompc
+
 package cgobinding
 
 import (
 	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/nativestats"
 )
 
 /*
@@ -18,13 +22,35 @@ type ECurveRef C.ecurve_ref
 type ECCPointRef C.ecc_point_ref
 
 func (c *ECurveRef) Free() {
+	if c.opaque != nil {
+		nativestats.Free(nativestats.Curve)
+	}
 	C.free_ecurve(C.ecurve_ref(*c))
 }
 
 func (p *ECCPointRef) Free() {
+	if p.opaque != nil {
+		nativestats.Free(nativestats.Point)
+	}
 	C.free_ecc_point(C.ecc_point_ref(*p))
 }
 
+// newPointRef accounts for a point returned by the native library.
+func newPointRef(p C.ecc_point_ref) ECCPointRef {
+	if p.opaque != nil {
+		nativestats.Alloc(nativestats.Point)
+	}
+	return ECCPointRef(p)
+}
+
+// newCurveRef accounts for a curve returned by the native library.
+func newCurveRef(c C.ecurve_ref) ECurveRef {
+	if c.opaque != nil {
+		nativestats.Alloc(nativestats.Curve)
+	}
+	return ECurveRef(c)
+}
+
 // =========== Curve Operations =====================
 
 // ECurveFind finds a curve by curve code
@@ -33,13 +59,13 @@ func ECurveFind(curveCode int) (ECurveRef, error) {
 	if cCurve.opaque == nil {
 		return ECurveRef{}, fmt.Errorf("invalid curve code: %d", curveCode)
 	}
-	return ECurveRef(cCurve), nil
+	return newCurveRef(cCurve), nil
 }
 
 // ECurveGenerator returns the generator point of the curve
 func ECurveGenerator(curve ECurveRef) ECCPointRef {
 	cPoint := C.ecurve_generator((*C.ecurve_ref)(&curve))
-	return ECCPointRef(cPoint)
+	return newPointRef(cPoint)
 }
 
 // ECurveOrderToMem returns the order of the curve as bytes
@@ -87,7 +113,7 @@ func ScalarFromInt64(value int64) []byte {
 // new point reference.
 func ECurveMulGenerator(curve ECurveRef, scalar []byte) ECCPointRef {
 	cPoint := C.ecurve_mul_generator((*C.ecurve_ref)(&curve), cmem(scalar))
-	return ECCPointRef(cPoint)
+	return newPointRef(cPoint)
 }
 
 // =========== Point Operations =====================
@@ -98,7 +124,7 @@ func ECCPointFromBytes(pointBytes []byte) (ECCPointRef, error) {
 	if cPoint.opaque == nil {
 		return ECCPointRef{}, fmt.Errorf("failed to create point from bytes")
 	}
-	return ECCPointRef(cPoint), nil
+	return newPointRef(cPoint), nil
 }
 
 // ECCPointMultiply multiplies a point by a scalar
@@ -107,19 +133,19 @@ func ECCPointMultiply(point ECCPointRef, scalar []byte) (ECCPointRef, error) {
 	if cPoint.opaque == nil {
 		return ECCPointRef{}, fmt.Errorf("failed to multiply point")
 	}
-	return ECCPointRef(cPoint), nil
+	return newPointRef(cPoint), nil
 }
 
 // ECCPointAdd adds two points
 func ECCPointAdd(point1, point2 ECCPointRef) ECCPointRef {
 	cPoint := C.ecc_point_add((*C.ecc_point_ref)(&point1), (*C.ecc_point_ref)(&point2))
-	return ECCPointRef(cPoint)
+	return newPointRef(cPoint)
 }
 
 // ECCPointSubtract subtracts two points
 func ECCPointSubtract(point1, point2 ECCPointRef) ECCPointRef {
 	cPoint := C.ecc_point_subtract((*C.ecc_point_ref)(&point1), (*C.ecc_point_ref)(&point2))
-	return ECCPointRef(cPoint)
+	return newPointRef(cPoint)
 }
 
 // ECCPointGetX returns the X coordinate of the point
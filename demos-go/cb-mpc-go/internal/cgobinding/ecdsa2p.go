@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package cgobinding
 
 /*
@@ -7,17 +9,33 @@ import "C"
 
 import (
 	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/nativestats"
 )
 
 // -------------------------- Type Wrappers --------------------------
 
 type Mpc_ecdsa2pc_key_ref C.mpc_ecdsa2pc_key_ref
 
+// Empty reports whether k refers to no native key.
+func (k Mpc_ecdsa2pc_key_ref) Empty() bool { return k.opaque == nil }
+
 // Free releases the underlying native key structure.
 func (k *Mpc_ecdsa2pc_key_ref) Free() {
+	if k.opaque != nil {
+		nativestats.Free(nativestats.Key)
+	}
 	C.free_mpc_ecdsa2p_key(C.mpc_ecdsa2pc_key_ref(*k))
 }
 
+// counted records the creation of key and returns it.
+func (key Mpc_ecdsa2pc_key_ref) counted() Mpc_ecdsa2pc_key_ref {
+	if key.opaque != nil {
+		nativestats.Alloc(nativestats.Key)
+	}
+	return key
+}
+
 // -------------------------- ECDSA 2PC ------------------------------
 
 // DistributedKeyGenCurve performs the two-party ECDSA distributed key
@@ -29,7 +47,7 @@ func DistributedKeyGenCurve(job Job2P, curveRef ECurveRef) (Mpc_ecdsa2pc_key_ref
 	if cErr != 0 {
 		return key, fmt.Errorf("ECDSA-2p keygen failed, %v", cErr)
 	}
-	return key, nil
+	return key.counted(), nil
 }
 
 // Refresh re-shares an existing 2-party ECDSA key.
@@ -39,7 +57,7 @@ func Refresh(job Job2P, key Mpc_ecdsa2pc_key_ref) (Mpc_ecdsa2pc_key_ref, error)
 	if cErr != 0 {
 		return newKey, fmt.Errorf("ECDSA-2p refresh failed, %v", cErr)
 	}
-	return newKey, nil
+	return newKey.counted(), nil
 }
 
 // Sign produces batch signatures using the two-party ECDSA key.
@@ -69,7 +87,7 @@ func KeyQ(key Mpc_ecdsa2pc_key_ref) (ECCPointRef, error) {
 	if cPoint.opaque == nil {
 		return ECCPointRef{}, fmt.Errorf("failed to retrieve Q from key")
 	}
-	return ECCPointRef(cPoint), nil
+	return newPointRef(cPoint), nil
 }
 
 // KeyXShare returns the secret scalar share x_i as raw bytes (big-endian).
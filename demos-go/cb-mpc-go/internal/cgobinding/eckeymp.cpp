@@ -146,6 +146,33 @@ int eckey_dkg_mp_threshold_dkg(job_mp_ref* job_ptr, ecurve_ref* curve_ref, cmem_
   return 0;
 }
 
+int eckey_dkg_mp_threshold_refresh(job_mp_ref* job_ptr, ecurve_ref* curve_ref, cmem_t sid, crypto_ss_ac_ref* ac,
+                                   mpc_party_set_ref* quorum, mpc_eckey_mp_ref* key, mpc_eckey_mp_ref* new_key) {
+  if (key == nullptr || key->opaque == nullptr) {
+    return 1;  // Invalid key reference
+  }
+  job_mp_t* job = static_cast<job_mp_t*>(job_ptr->opaque);
+  ecurve_t* curve_ptr = static_cast<ecurve_t*>(curve_ref->opaque);
+  if (curve_ptr == nullptr) {
+    return 1;  // Invalid curve reference
+  }
+
+  buf_t sid_buf = mem_t(sid);
+  crypto::ss::ac_t* ac_obj = static_cast<crypto::ss::ac_t*>(ac->opaque);
+  party_set_t* quorum_set = static_cast<party_set_t*>(quorum->opaque);
+  eckey::key_share_mp_t* key_share = static_cast<eckey::key_share_mp_t*>(key->opaque);
+
+  std::unique_ptr<eckey::key_share_mp_t> new_key_share(new eckey::key_share_mp_t());
+  eckey::dkg_mp_threshold_t dkg_threshold;
+  error_t err = dkg_threshold.refresh(*job, *curve_ptr, sid_buf, *ac_obj, *quorum_set, *key_share, *new_key_share);
+  if (err) {
+    return err;  // unique_ptr cleans up
+  }
+
+  *new_key = mpc_eckey_mp_ref{new_key_share.release()};
+  return 0;
+}
+
 int eckey_key_share_mp_to_additive_share(mpc_eckey_mp_ref* key, crypto_ss_ac_ref* ac, cmems_t quorum_party_names,
                                          mpc_eckey_mp_ref* additive_key) {
   eckey::key_share_mp_t* key_share = static_cast<eckey::key_share_mp_t*>(key->opaque);
@@ -168,6 +195,134 @@ int eckey_key_share_mp_to_additive_share(mpc_eckey_mp_ref* key, crypto_ss_ac_ref
   return 0;
 }
 
+int eckey_key_share_mp_tweak(mpc_eckey_mp_ref* key, cmem_t tweak, cmem_t adder, mpc_eckey_mp_ref* tweaked_key) {
+  if (key == nullptr || key->opaque == nullptr) {
+    return 1;  // Invalid key reference
+  }
+  eckey::key_share_mp_t* key_share = static_cast<eckey::key_share_mp_t*>(key->opaque);
+  mod_t q = key_share->curve.order();
+  bn_t t = bn_t::from_bin(mem_t(tweak)) % q;
+  crypto::pname_t adder_name = mem_t(adder).to_string();
+  if (key_share->Qis.find(adder_name) == key_share->Qis.end()) {
+    return 1;  // Adder is not a party of the key
+  }
+
+  std::unique_ptr<eckey::key_share_mp_t> tweaked(new eckey::key_share_mp_t(*key_share));
+  ecc_point_t tG = key_share->curve.mul_to_generator(t);
+  tweaked->Q = tweaked->Q + tG;
+  tweaked->Qis[adder_name] = tweaked->Qis[adder_name] + tG;
+  if (tweaked->party_name == adder_name) {
+    tweaked->x_share = (tweaked->x_share + t) % q;
+  }
+
+  *tweaked_key = mpc_eckey_mp_ref{tweaked.release()};
+  return 0;
+}
+
+int eckey_key_share_mp_reshare_deal(mpc_eckey_mp_ref* key, crypto_ss_ac_ref* ac, cmems_t* party_names_mem,
+                                    cmems_t* shares_mem) {
+  if (key == nullptr || key->opaque == nullptr || ac == nullptr || ac->opaque == nullptr) {
+    return 1;  // Invalid reference
+  }
+  eckey::key_share_mp_t* key_share = static_cast<eckey::key_share_mp_t*>(key->opaque);
+  crypto::ss::ac_t* ac_obj = static_cast<crypto::ss::ac_t*>(ac->opaque);
+  const mod_t& q = key_share->curve.order();
+
+  auto dealt = ac_obj->share(q, key_share->x_share);
+  std::vector<coinbase::buf_t> name_bufs;
+  std::vector<coinbase::buf_t> share_bufs;
+  name_bufs.reserve(dealt.size());
+  share_bufs.reserve(dealt.size());
+  for (const auto& kv : dealt) {
+    name_bufs.emplace_back(coinbase::mem_t(kv.first));
+    share_bufs.push_back(kv.second.to_bin(q.get_bin_size()));
+  }
+
+  *party_names_mem = coinbase::mems_t(name_bufs).to_cmems();
+  *shares_mem = coinbase::mems_t(share_bufs).to_cmems();
+  return 0;
+}
+
+int eckey_key_share_mp_assemble(ecurve_ref* curve, crypto_ss_ac_ref* ac, cmem_t party_name, cmem_t x_share, cmem_t Q,
+                                cmems_t party_names, cmems_t points, mpc_eckey_mp_ref* key) {
+  ecurve_t* curve_ptr = static_cast<ecurve_t*>(curve->opaque);
+  if (curve_ptr == nullptr || ac == nullptr || ac->opaque == nullptr) {
+    return 1;  // Invalid reference
+  }
+  crypto::ss::ac_t* ac_obj = static_cast<crypto::ss::ac_t*>(ac->opaque);
+  std::vector<buf_t> name_bufs = coinbase::mems_t(party_names).bufs();
+  std::vector<buf_t> point_bufs = coinbase::mems_t(points).bufs();
+  if (name_bufs.size() != point_bufs.size()) {
+    return 1;  // Inconsistent public shares
+  }
+
+  std::unique_ptr<eckey::key_share_mp_t> assembled(new eckey::key_share_mp_t());
+  assembled->curve = *curve_ptr;
+  assembled->party_name = mem_t(party_name).to_string();
+  mod_t q = curve_ptr->order();
+  assembled->x_share = bn_t::from_bin(mem_t(x_share)) % q;
+  if (coinbase::deser(mem_t(Q), assembled->Q)) return 1;
+  for (size_t i = 0; i < name_bufs.size(); i++) {
+    ecc_point_t point;
+    if (coinbase::deser(point_bufs[i], point)) return 1;
+    assembled->Qis[name_bufs[i].to_string()] = point;
+  }
+
+  auto own = assembled->Qis.find(assembled->party_name);
+  if (own == assembled->Qis.end() || own->second != curve_ptr->mul_to_generator(assembled->x_share)) {
+    return 1;  // Secret share does not match the public share
+  }
+  ecc_point_t reconstructed;
+  if (ac_obj->reconstruct_exponent(assembled->Qis, reconstructed)) return 1;
+  if (reconstructed != assembled->Q) {
+    return 1;  // Public shares do not reconstruct Q
+  }
+
+  *key = mpc_eckey_mp_ref{assembled.release()};
+  return 0;
+}
+
+int eckey_key_share_mp_check_ac(mpc_eckey_mp_ref* key, crypto_ss_ac_ref* ac) {
+  if (key == nullptr || key->opaque == nullptr || ac == nullptr || ac->opaque == nullptr) {
+    return 1;  // Invalid reference
+  }
+  eckey::key_share_mp_t* key_share = static_cast<eckey::key_share_mp_t*>(key->opaque);
+  crypto::ss::ac_t* ac_obj = static_cast<crypto::ss::ac_t*>(ac->opaque);
+  ecc_point_t reconstructed;
+  if (ac_obj->reconstruct_exponent(key_share->Qis, reconstructed)) return 1;
+  if (reconstructed != key_share->Q) {
+    return 1;  // Public shares do not reconstruct Q
+  }
+  return 0;
+}
+
+int eckey_check_exponent_sharing(crypto_ss_ac_ref* ac, cmem_t X, cmems_t party_names, cmems_t points) {
+  if (ac == nullptr || ac->opaque == nullptr) {
+    return 1;  // Invalid reference
+  }
+  crypto::ss::ac_t* ac_obj = static_cast<crypto::ss::ac_t*>(ac->opaque);
+  std::vector<buf_t> name_bufs = coinbase::mems_t(party_names).bufs();
+  std::vector<buf_t> point_bufs = coinbase::mems_t(points).bufs();
+  if (name_bufs.size() != point_bufs.size()) {
+    return 1;  // Inconsistent points
+  }
+
+  ecc_point_t expected;
+  if (coinbase::deser(mem_t(X), expected)) return 1;
+  decltype(eckey::key_share_mp_t::Qis) shares;  // Same map as the public shares of a key
+  for (size_t i = 0; i < name_bufs.size(); i++) {
+    ecc_point_t point;
+    if (coinbase::deser(point_bufs[i], point)) return 1;
+    shares[name_bufs[i].to_string()] = point;
+  }
+  ecc_point_t reconstructed;
+  if (ac_obj->reconstruct_exponent(shares, reconstructed)) return 1;
+  if (reconstructed != expected) {
+    return 1;  // Points do not reconstruct X
+  }
+  return 0;
+}
+
 // --------------------------- Utilities -----------------------------
 int serialize_mpc_eckey_mp(mpc_eckey_mp_ref* k, cmems_t* ser) {
   eckey::key_share_mp_t* key = static_cast<eckey::key_share_mp_t*>(k->opaque);
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package cgobinding
 
 /*
@@ -6,10 +8,22 @@ package cgobinding
 */
 import "C"
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/nativestats"
+)
 
 type Mpc_eckey_mp_ref C.mpc_eckey_mp_ref
 
+// counted records the creation of key and returns it.
+func (key Mpc_eckey_mp_ref) counted() Mpc_eckey_mp_ref {
+	if key.opaque != nil {
+		nativestats.Alloc(nativestats.Key)
+	}
+	return key
+}
+
 // SerializeKeyShare converts an mpc_eckey_mp_ref into a slice of byte buffers
 // that fully represent the secret-share. The data is suitable for short-term
 // transport or caching. It should NOT be relied upon for long-term storage
@@ -31,7 +45,7 @@ func DeserializeKeyShare(ser [][]byte) (Mpc_eckey_mp_ref, error) {
 	if err != 0 {
 		return Mpc_eckey_mp_ref{}, fmt.Errorf("deserialize_mpc_eckey_mp failed: %v", err)
 	}
-	return key, nil
+	return key.counted(), nil
 }
 
 // -----------------------------------------------------------------------------
@@ -57,7 +71,7 @@ func KeyShareDKG(job JobMP, curveRef ECurveRef) (Mpc_eckey_mp_ref, error) {
 	if cErr != 0 {
 		return key, fmt.Errorf("key-share DKG failed, %v", cErr)
 	}
-	return key, nil
+	return key.counted(), nil
 }
 
 // KeyShareRefresh rerandomises the secret shares while keeping the aggregated
@@ -71,7 +85,7 @@ func KeyShareRefresh(job JobMP, sid []byte, key Mpc_eckey_mp_ref) (Mpc_eckey_mp_
 	if cErr != 0 {
 		return newKey, fmt.Errorf("key-share refresh failed, %v", cErr)
 	}
-	return newKey, nil
+	return newKey.counted(), nil
 }
 
 // ThresholdDKG runs a threshold Distributed Key Generation for Schnorr-style
@@ -99,7 +113,35 @@ func ThresholdDKG(job JobMP, curveRef ECurveRef, sid []byte, ac C_AcPtr, roleInd
 	if cErr != 0 {
 		return key, fmt.Errorf("threshold DKG failed, %v", cErr)
 	}
-	return key, nil
+	return key.counted(), nil
+}
+
+// ThresholdRefresh re-randomises the shares of a key generated by
+// ThresholdDKG under the same access structure, keeping the public key.
+func ThresholdRefresh(job JobMP, curveRef ECurveRef, sid []byte, ac C_AcPtr, roleIndices []int, key Mpc_eckey_mp_ref) (Mpc_eckey_mp_ref, error) {
+	if sid == nil {
+		sid = make([]byte, 0)
+	}
+
+	quorum := NewPartySet()
+	defer quorum.Free()
+	for _, idx := range roleIndices {
+		quorum.Add(idx)
+	}
+
+	var newKey Mpc_eckey_mp_ref
+	cErr := C.eckey_dkg_mp_threshold_refresh(
+		job.GetCJob(),
+		(*C.ecurve_ref)(&curveRef),
+		cmem(sid),
+		(*C.crypto_ss_ac_ref)(&ac),
+		(*C.mpc_party_set_ref)(&quorum),
+		(*C.mpc_eckey_mp_ref)(&key),
+		(*C.mpc_eckey_mp_ref)(&newKey))
+	if cErr != 0 {
+		return newKey, fmt.Errorf("threshold refresh failed, %v", cErr)
+	}
+	return newKey.counted(), nil
 }
 
 // Back-compat synonym.
@@ -124,7 +166,100 @@ func (key *Mpc_eckey_mp_ref) ToAdditiveShare(ac C_AcPtr, quorumPartyNames []stri
 	if cErr != 0 {
 		return additiveKey, fmt.Errorf("to_additive_share failed, %v", cErr)
 	}
-	return additiveKey, nil
+	return additiveKey.counted(), nil
+}
+
+// Tweak shifts the key by tweak·G, tweak being a big-endian scalar.
+// Only the party named adder adds tweak to its secret share.
+func (key *Mpc_eckey_mp_ref) Tweak(tweak []byte, adder string) (Mpc_eckey_mp_ref, error) {
+	var tweakedKey Mpc_eckey_mp_ref
+	cErr := C.eckey_key_share_mp_tweak(
+		(*C.mpc_eckey_mp_ref)(key),
+		cmem(tweak),
+		cmem([]byte(adder)),
+		(*C.mpc_eckey_mp_ref)(&tweakedKey))
+	if cErr != 0 {
+		return tweakedKey, fmt.Errorf("tweak failed, %v", cErr)
+	}
+	return tweakedKey.counted(), nil
+}
+
+// ReshareDeal shares the secret share of key under ac.  It returns one
+// big-endian share per leaf of ac, keyed by party name.
+func (key *Mpc_eckey_mp_ref) ReshareDeal(ac C_AcPtr) (map[string][]byte, error) {
+	var nameMems, shareMems CMEMS
+	cErr := C.eckey_key_share_mp_reshare_deal(
+		(*C.mpc_eckey_mp_ref)(key),
+		(*C.crypto_ss_ac_ref)(&ac),
+		&nameMems,
+		&shareMems)
+	if cErr != 0 {
+		return nil, fmt.Errorf("reshare deal failed, %v", cErr)
+	}
+	names, shares := CMEMSGet(nameMems), CMEMSGet(shareMems)
+	if len(names) != len(shares) {
+		return nil, fmt.Errorf("inconsistent deal: %d names vs %d shares", len(names), len(shares))
+	}
+	out := make(map[string][]byte, len(names))
+	for i, name := range names {
+		out[string(name)] = shares[i]
+	}
+	return out, nil
+}
+
+// AssembleKeyShare builds the key share of partyName under ac from its
+// secret share xShare, the serialized public key q and the serialized public
+// shares qis of every party.  It fails unless xShare matches the party's
+// public share and the public shares reconstruct q.
+func AssembleKeyShare(curveRef ECurveRef, ac C_AcPtr, partyName string, xShare, q []byte, qis map[string][]byte) (Mpc_eckey_mp_ref, error) {
+	names := make([][]byte, 0, len(qis))
+	points := make([][]byte, 0, len(qis))
+	for name, point := range qis {
+		names = append(names, []byte(name))
+		points = append(points, point)
+	}
+
+	var key Mpc_eckey_mp_ref
+	cErr := C.eckey_key_share_mp_assemble(
+		(*C.ecurve_ref)(&curveRef),
+		(*C.crypto_ss_ac_ref)(&ac),
+		cmem([]byte(partyName)),
+		cmem(xShare),
+		cmem(q),
+		cmems(names),
+		cmems(points),
+		(*C.mpc_eckey_mp_ref)(&key))
+	if cErr != 0 {
+		return key, fmt.Errorf("assembling key share failed, %v", cErr)
+	}
+	return key.counted(), nil
+}
+
+// CheckAccessStructure checks that the public shares of key reconstruct its
+// public key under ac.
+func (key *Mpc_eckey_mp_ref) CheckAccessStructure(ac C_AcPtr) error {
+	cErr := C.eckey_key_share_mp_check_ac((*C.mpc_eckey_mp_ref)(key), (*C.crypto_ss_ac_ref)(&ac))
+	if cErr != 0 {
+		return fmt.Errorf("checking access structure failed, %v", cErr)
+	}
+	return nil
+}
+
+// CheckExponentSharing checks that the serialized points of the named
+// parties, which must satisfy ac, reconstruct the serialized point x under
+// ac.
+func CheckExponentSharing(ac C_AcPtr, x []byte, points map[string][]byte) error {
+	names := make([][]byte, 0, len(points))
+	values := make([][]byte, 0, len(points))
+	for name, point := range points {
+		names = append(names, []byte(name))
+		values = append(values, point)
+	}
+	cErr := C.eckey_check_exponent_sharing((*C.crypto_ss_ac_ref)(&ac), cmem(x), cmems(names), cmems(values))
+	if cErr != 0 {
+		return fmt.Errorf("checking sharing failed, %v", cErr)
+	}
+	return nil
 }
 
 // -----------------------------------------------------------------------------
@@ -157,7 +292,7 @@ func KeyShareQ(key Mpc_eckey_mp_ref) (ECCPointRef, error) {
 	if cPoint.opaque == nil {
 		return ECCPointRef{}, fmt.Errorf("failed to retrieve Q from key")
 	}
-	return ECCPointRef(cPoint), nil
+	return newPointRef(cPoint), nil
 }
 
 // KeyShareCurve returns the curve associated with the key share.
@@ -166,7 +301,7 @@ func KeyShareCurve(key Mpc_eckey_mp_ref) (ECurveRef, error) {
 	if cRef.opaque == nil {
 		return ECurveRef{}, fmt.Errorf("failed to get curve from key")
 	}
-	return ECurveRef(cRef), nil
+	return newCurveRef(cRef), nil
 }
 
 // KeyShareQis returns per-party public key shares.
@@ -185,8 +320,14 @@ func KeyShareQis(key Mpc_eckey_mp_ref) ([][]byte, [][]byte, error) {
 	return names, points, nil
 }
 
+// Empty reports whether k refers to no native key share.
+func (k Mpc_eckey_mp_ref) Empty() bool { return k.opaque == nil }
+
 // Free releases the underlying native key-share object.
 func (k *Mpc_eckey_mp_ref) Free() {
+	if k.opaque != nil {
+		nativestats.Free(nativestats.Key)
+	}
 	C.free_mpc_eckey_mp(C.mpc_eckey_mp_ref(*k))
 }
 
@@ -39,9 +39,34 @@ int mpc_eckey_mp_refresh(job_mp_ref* j, cmem_t sid_mem, mpc_eckey_mp_ref* k, mpc
 int eckey_dkg_mp_threshold_dkg(job_mp_ref* job, ecurve_ref* curve, cmem_t sid, crypto_ss_ac_ref* ac,
                                mpc_party_set_ref* quorum, mpc_eckey_mp_ref* key);
 
+int eckey_dkg_mp_threshold_refresh(job_mp_ref* job, ecurve_ref* curve, cmem_t sid, crypto_ss_ac_ref* ac,
+                                   mpc_party_set_ref* quorum, mpc_eckey_mp_ref* key, mpc_eckey_mp_ref* new_key);
+
 int eckey_key_share_mp_to_additive_share(mpc_eckey_mp_ref* key, crypto_ss_ac_ref* ac, cmems_t quorum_party_names,
                                          mpc_eckey_mp_ref* additive_key);
 
+// Shifts the key by tweak·G.  Only the party named adder adds tweak to its
+// secret share; every party updates Q and the public share of adder.
+int eckey_key_share_mp_tweak(mpc_eckey_mp_ref* key, cmem_t tweak, cmem_t adder, mpc_eckey_mp_ref* tweaked_key);
+
+// Shares the secret share of key under ac: one share per leaf of ac, each
+// big-endian and as long as the curve order.
+int eckey_key_share_mp_reshare_deal(mpc_eckey_mp_ref* key, crypto_ss_ac_ref* ac, cmems_t* party_names_mem,
+                                    cmems_t* shares_mem);
+
+// Builds the key share of party_name under ac from its secret share, the
+// public key Q and the public shares of every party.  Fails unless x_share
+// matches the party's public share and the public shares reconstruct Q.
+int eckey_key_share_mp_assemble(ecurve_ref* curve, crypto_ss_ac_ref* ac, cmem_t party_name, cmem_t x_share, cmem_t Q,
+                                cmems_t party_names, cmems_t points, mpc_eckey_mp_ref* key);
+
+// Checks that the public shares of key reconstruct its public key Q under ac.
+int eckey_key_share_mp_check_ac(mpc_eckey_mp_ref* key, crypto_ss_ac_ref* ac);
+
+// Checks that the points of the named parties, which must satisfy ac,
+// reconstruct X under ac.
+int eckey_check_exponent_sharing(crypto_ss_ac_ref* ac, cmem_t X, cmems_t party_names, cmems_t points);
+
 // ------------------------- Utilities -----------------------------------------
 int serialize_mpc_eckey_mp(mpc_eckey_mp_ref* k, cmems_t* ser);
 int deserialize_mpc_eckey_mp(cmems_t ser, mpc_eckey_mp_ref* k);
@@ -1,3 +1,5 @@
+//go:build !nompc
+
 package cgobinding
 
 import (
@@ -9,6 +11,7 @@ import (
 
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mtls"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/internal/nativestats"
 )
 
 /*
@@ -308,6 +311,7 @@ func NewJob2P(dt IDataTransport, roleIndex int, pnames []string) (Job2P, error)
 		return Job2P{}, fmt.Errorf("failed to create 2P job")
 	}
 
+	nativestats.Alloc(nativestats.Job)
 	return Job2P{ptr, cJobRef}, nil
 }
 
@@ -315,6 +319,7 @@ func (j *Job2P) Free() {
 	if j.cJob != nil {
 		C.free_job_2p(j.cJob)
 		j.cJob = nil
+		nativestats.Free(nativestats.Job)
 	}
 	if j.dtImplPtr != nil {
 		FreeDTImpl(j.dtImplPtr) // Ignore error on cleanup
@@ -423,6 +428,7 @@ func NewJobMP(dt IDataTransport, partyCount int, roleIndex int, pnames []string)
 		return JobMP{}, fmt.Errorf("failed to create MP job")
 	}
 
+	nativestats.Alloc(nativestats.Job)
 	return JobMP{ptr, cJobRef}, nil
 }
 
@@ -430,6 +436,7 @@ func (j *JobMP) Free() {
 	if j.cJob != nil {
 		C.free_job_mp(j.cJob)
 		j.cJob = nil
+		nativestats.Free(nativestats.Job)
 	}
 	if j.dtImplPtr != nil {
 		FreeDTImpl(j.dtImplPtr) // Ignore error on cleanup
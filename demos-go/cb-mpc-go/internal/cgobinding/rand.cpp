@@ -0,0 +1,8 @@
+#include "rand.h"
+
+#include <openssl/rand.h>
+
+void cbmpc_seed_random(cmem_t entropy) {
+  if (entropy.data == nullptr || entropy.size <= 0) return;
+  RAND_seed(entropy.data, entropy.size);
+}
@@ -0,0 +1,13 @@
+//go:build !nompc
+
+package cgobinding
+
+/*
+#include "rand.h"
+*/
+import "C"
+
+// SeedRandom mixes entropy into the RNG of the native library.
+func SeedRandom(entropy []byte) {
+	C.cbmpc_seed_random(cmem(entropy))
+}
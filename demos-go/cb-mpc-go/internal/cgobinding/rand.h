@@ -0,0 +1,15 @@
+#pragma once
+
+#include <cbmpc/core/cmem.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// Mixes entropy into OpenSSL's RNG, from which the native library draws all
+// its randomness.
+void cbmpc_seed_random(cmem_t entropy);
+
+#ifdef __cplusplus
+}  // extern "C"
+#endif
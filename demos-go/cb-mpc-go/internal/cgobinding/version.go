@@ -0,0 +1,11 @@
+//go:build !nompc
+
+package cgobinding
+
+/*
+#include <cbmpc/core/version.h>
+*/
+import "C"
+
+// NativeVersion returns the version of the linked libcbmpc.
+func NativeVersion() string { return C.GoString(C.cbmpc_version()) }
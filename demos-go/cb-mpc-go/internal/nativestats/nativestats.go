@@ -0,0 +1,34 @@
+// Package nativestats counts the native objects created and freed through
+// cgobinding.  It is pure Go so that the counters exist, at zero, in nompc
+// builds as well.
+package nativestats
+
+import "sync/atomic"
+
+// Kind is a kind of native object.
+type Kind int
+
+// Native object kinds.
+const (
+	Key Kind = iota
+	Job
+	Point
+	Curve
+	NumKinds
+)
+
+var created, freed [NumKinds]atomic.Uint64
+
+// Alloc records that an object of kind k was created.
+func Alloc(k Kind) { created[k].Add(1) }
+
+// Free records that an object of kind k was freed.
+func Free(k Kind) { freed[k].Add(1) }
+
+// Counts returns how many objects of kind k were created and freed so far.
+func Counts(k Kind) (c, f uint64) {
+	// Read freed first so that a concurrent Alloc/Free pair can never make
+	// the live count negative.
+	f = freed[k].Load()
+	return created[k].Load(), f
+}
@@ -12,6 +12,7 @@ import (
     "os/exec"
     "path/filepath"
 
+    "github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
     "github.com/gagliardetto/solana-go"
     "github.com/gagliardetto/solana-go/programs/system"
     "github.com/gagliardetto/solana-go/rpc"
@@ -77,8 +78,9 @@ func main() {
     if err != nil {
         log.Fatalf("signing failed: %v", err)
     }
-    if len(sigBytes) != 64 {
-        log.Fatalf("invalid signature length: %d", len(sigBytes))
+    // Catch a bad signature locally rather than as a rejected transaction.
+    if err := verify.Ed25519(pkPkg.GroupPublicKey, msgBytes, sigBytes); err != nil {
+        log.Fatalf("FROST signature does not verify under the group public key: %v", err)
     }
 
     var sig solana.Signature
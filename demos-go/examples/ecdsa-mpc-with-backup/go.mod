@@ -12,4 +12,9 @@ require (
 	golang.org/x/sync v0.15.0
 )
 
-require github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+require (
+	filippo.io/edwards25519 v1.0.0-rc.1 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+)
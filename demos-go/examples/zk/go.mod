@@ -7,6 +7,7 @@ toolchain go1.24.2
 require github.com/coinbase/cb-mpc/demos-go/cb-mpc-go v0.0.0-20240501131245-1eee31b51009
 
 require (
+	filippo.io/edwards25519 v1.0.0-rc.1 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
 	golang.org/x/sync v0.15.0 // indirect
 )
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"golang.org/x/sync/errgroup"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/chain/evm"
+)
+
+// sepoliaRPC is a public Sepolia endpoint; set ETH_RPC_URL or -rpc to use
+// another node.
+const sepoliaRPC = "https://ethereum-sepolia-rpc.publicnode.com"
+
+func main() {
+	rpcURL := flag.String("rpc", envOr("ETH_RPC_URL", sepoliaRPC), "JSON-RPC endpoint of an Ethereum node")
+	chainID := flag.Int64("chain-id", 11155111, "EIP-155 chain ID (11155111 is Sepolia)")
+	to := flag.String("to", "0x000000000000000000000000000000000000dEaD", "recipient address")
+	amount := flag.Int64("amount", 1_000_000_000, "amount to transfer, in wei")
+	broadcast := flag.Bool("broadcast", false, "broadcast the signed transaction (the MPC address must be funded)")
+	flag.Parse()
+
+	fmt.Println("🚀 Ethereum MPC Transaction Demo (3-party ECDSA on secp256k1)")
+	fmt.Println("=============================================================")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	secp, err := curve.NewSecp256k1()
+	if err != nil {
+		log.Fatal("Failed to create secp256k1 curve:", err)
+	}
+	defer secp.Free()
+
+	// Step 1: Distributed key generation.  No party ever holds the private key.
+	fmt.Println("\n📍 Step 1: Generating a 3-party ECDSA key...")
+	partyNames := []string{"server", "kms", "pin"}
+	shares, err := generateKey(partyNames, secp)
+	if err != nil {
+		log.Fatal("Key generation failed:", err)
+	}
+	defer func() {
+		for i := range shares {
+			shares[i].Free()
+		}
+	}()
+
+	q, err := shares[0].Q()
+	if err != nil {
+		log.Fatal("Failed to read the group public key:", err)
+	}
+	pubKey, err := q.Encode(secp, curve.FormatSEC1Uncompressed)
+	q.Free()
+	if err != nil {
+		log.Fatal("Failed to encode the group public key:", err)
+	}
+
+	eth, err := evm.New(evm.Config{ID: "evm-demo", RPCEndpoint: *rpcURL, ChainID: big.NewInt(*chainID)})
+	if err != nil {
+		log.Fatal("Failed to configure the EVM chain:", err)
+	}
+	from, err := eth.DeriveAddress(pubKey)
+	if err != nil {
+		log.Fatal("Failed to derive the address:", err)
+	}
+	fmt.Printf("✅ Public key: %x\n", pubKey)
+	fmt.Printf("✅ Address:    %s\n", from)
+
+	// Step 2: Build an EIP-1559 transfer; the node supplies nonce and fees.
+	fmt.Println("\n📍 Step 2: Building the transaction...")
+	utx, err := eth.BuildTransfer(ctx, &chain.Transfer{From: from, To: *to, Amount: big.NewInt(*amount)})
+	if err != nil {
+		log.Fatal("Failed to build the transaction:", err)
+	}
+	fmt.Printf("✅ Transfer of %d wei to %s\n", *amount, *to)
+	fmt.Printf("✅ Signing hash (keccak256): %x\n", utx.SigningPayload)
+
+	// Step 3: All three parties sign the digest.
+	fmt.Println("\n📍 Step 3: Performing 3-party MPC signing...")
+	resp, err := sign(partyNames, shares, utx.SigningPayload)
+	if err != nil {
+		log.Fatal("MPC signing failed:", err)
+	}
+	v, ok := resp.RecoveryID()
+	if !ok {
+		log.Fatal("MPC signing did not return a recoverable signature")
+	}
+	rsv := resp.RecoverableSignature
+	fmt.Printf("✅ DER signature: %x\n", resp.Signature)
+	fmt.Printf("  r: 0x%s\n", hex.EncodeToString(rsv[:32]))
+	fmt.Printf("  s: 0x%s\n", hex.EncodeToString(rsv[32:64]))
+	fmt.Printf("  v: %d (yParity)\n", v)
+
+	// Step 4: Anyone can recover the signer from the digest and signature.
+	fmt.Println("\n📍 Step 4: Recovering the signer...")
+	recovered, err := verify.RecoverSecp256k1(utx.SigningPayload, rsv)
+	if err != nil {
+		log.Fatal("Recovery failed:", err)
+	}
+	signer, err := eth.DeriveAddress(recovered)
+	if err != nil {
+		log.Fatal("Failed to derive the signer address:", err)
+	}
+	if signer != from {
+		log.Fatalf("Recovered signer %s, expected %s", signer, from)
+	}
+	fmt.Printf("✅ Recovered signer %s matches the MPC address\n", signer)
+
+	signed := &chain.SignedTx{Unsigned: utx, Signature: rsv}
+	if !*broadcast {
+		fmt.Println("\n🎉 TRANSACTION SIGNED SUCCESSFULLY!")
+		fmt.Printf("Fund %s and run again with -broadcast to send it.\n", from)
+		return
+	}
+
+	// Step 5: Broadcast.
+	fmt.Println("\n📍 Step 5: Broadcasting...")
+	txHash, err := eth.Broadcast(ctx, signed)
+	if err != nil {
+		log.Fatal("Broadcast failed:", err)
+	}
+	fmt.Printf("🎉 Transaction sent: %s\n", txHash)
+}
+
+// generateKey runs ECDSAMPCKeyGen for every party over an in-process network
+// and returns the key shares by party index.
+func generateKey(partyNames []string, c curve.Curve) ([]mpc.ECDSAMPCKey, error) {
+	messengers := mocknet.NewMockNetwork(len(partyNames))
+	shares := make([]mpc.ECDSAMPCKey, len(partyNames))
+
+	var eg errgroup.Group
+	for i := range partyNames {
+		eg.Go(func() error {
+			job, err := mpc.NewJobMP(messengers[i], len(partyNames), i, partyNames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+
+			resp, err := mpc.ECDSAMPCKeyGen(job, &mpc.ECDSAMPCKeyGenRequest{Curve: c})
+			if err != nil {
+				return fmt.Errorf("%s: %w", partyNames[i], err)
+			}
+			shares[i] = resp.KeyShare
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// sign runs ECDSAMPCSign for every party and returns the response of the
+// signature receiver, party 0.
+func sign(partyNames []string, shares []mpc.ECDSAMPCKey, digest []byte) (*mpc.ECDSAMPCSignResponse, error) {
+	messengers := mocknet.NewMockNetwork(len(partyNames))
+	signatureReceiver := 0
+
+	var eg errgroup.Group
+	var result *mpc.ECDSAMPCSignResponse
+	for i := range partyNames {
+		eg.Go(func() error {
+			job, err := mpc.NewJobMP(messengers[i], len(partyNames), i, partyNames)
+			if err != nil {
+				return err
+			}
+			defer job.Free()
+
+			resp, err := mpc.ECDSAMPCSign(job, &mpc.ECDSAMPCSignRequest{
+				KeyShare:          shares[i],
+				Message:           digest,
+				SignatureReceiver: signatureReceiver,
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", partyNames[i], err)
+			}
+			if i == signatureReceiver {
+				result = resp
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
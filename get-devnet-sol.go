@@ -12,6 +12,8 @@ import (
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
 )
 
 const (
@@ -49,8 +51,7 @@ func main() {
 		log.Fatal("Failed to get balance:", err)
 	}
 	
-	balanceSOL := float64(balance.Value) / 1e9
-	fmt.Printf("✅ Current balance: %.9f SOL (%d lamports)\n", balanceSOL, balance.Value)
+	fmt.Printf("✅ Current balance: %s (%d lamports)\n", solchain.Lamports(balance.Value), balance.Value)
 	
 	// Step 3: Request SOL from faucet
 	fmt.Println("\n📍 Step 3: Requesting SOL from Devnet Faucet...")
@@ -79,11 +80,10 @@ func main() {
 		}
 		
 		if newBalance.Value > balance.Value {
-			newBalanceSOL := float64(newBalance.Value) / 1e9
-			fmt.Printf("\n✅ Success! New balance: %.9f SOL (%d lamports)\n", newBalanceSOL, newBalance.Value)
+			fmt.Printf("\n✅ Success! New balance: %s (%d lamports)\n", solchain.Lamports(newBalance.Value), newBalance.Value)
 			
-			received := float64(newBalance.Value - balance.Value) / 1e9
-			fmt.Printf("✅ Received: %.9f SOL\n", received)
+			received := solchain.Lamports(newBalance.Value - balance.Value)
+			fmt.Printf("✅ Received: %s\n", received)
 			
 			fmt.Println("\n🚀 Ready to run the transfer script!")
 			fmt.Println("Run: go run solana-devnet-transfer.go")
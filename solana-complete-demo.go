@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"log"
@@ -14,8 +13,9 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
-	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/sync/errgroup"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
 )
 
 func main() {
@@ -44,14 +44,14 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to get funded wallet balance:", err)
 	}
-	fmt.Printf("💵 Source Balance: %.4f SOL\n", float64(fundedBalance.Value)/1e9)
+	fmt.Printf("💵 Source Balance: %s\n", solchain.Lamports(fundedBalance.Value))
 
 	// Check MPC wallet balance
 	mpcBalance, err := client.GetBalance(context.Background(), mpcWalletAddress, rpc.CommitmentFinalized)
 	if err != nil {
 		log.Fatal("Failed to get MPC wallet balance:", err)
 	}
-	fmt.Printf("🏦 MPC Balance: %.4f SOL\n", float64(mpcBalance.Value)/1e9)
+	fmt.Printf("🏦 MPC Balance: %s\n", solchain.Lamports(mpcBalance.Value))
 
 	// Step 4: Send SOL from funded wallet to MPC wallet
 	fmt.Println("\n📤 Step 1: Funding MPC wallet...")
@@ -74,7 +74,7 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to get new MPC wallet balance:", err)
 	}
-	fmt.Printf("💰 New MPC Balance: %.4f SOL\n", float64(newMpcBalance.Value)/1e9)
+	fmt.Printf("💰 New MPC Balance: %s\n", solchain.Lamports(newMpcBalance.Value))
 
 	// Step 5: Now use MPC to sign a transaction sending SOL back
 	fmt.Println("\n🔐 Step 2: MPC Threshold Signing...")
@@ -209,10 +209,6 @@ func performMPCSigning(message []byte) ([]byte, error) {
 	s1ShareData := "C38CAQL/gAABCgAA/8//gAAFIUAETBk4pw5y7alm+X5UP7hPziJAXIhf8hCftmaQZUyNVCIEP2QwSWMH23FqBDlq1D4TFFqGbsH/XgIk9lLvMszRyNQveQMAA2ttcwQ/GHAvcj73nnFPLMRzCy5Unz/5hi/gOL5uomjzHRVg7PwAA3BpbgQ/NP2vVIR1vMVj2iiaoqN4/11qVHicoUnuINc10l9aS7YABnNlcnZlcgQ/UjqDrXbXxPevCnMV+0n6bLgsOigPjFI/7TOPtmnxsc0CBD8IAAZzZXJ2ZXI="
 	s3ShareData := "C38CAQL/gAABCgAA/8v/gAAFID6kgSsOwCJxO/JIKThC7euhljaiBhroWu6eeWSf3bbQIgQ/ZDBJYwfbcWoEOWrUPhMUWoZuwf9eAiT2Uu8yzNHI1C95AwADa21zBD8YcC9yPveecU8sxHMLLlSfP/mGL+A4vm6iaPMdFWDs/AADcGluBD80/a9UhHW8xWPaKJqio3j/XWpUeJyhSe4g1zXSX1pLtgAGc2VydmVyBD9SOoOtdtfE968KcxX7SfpsuCw6KA+MUj/tM4+2afGxzQIEPwUAA3Bpbg=="
 	
-	// For PIN-based derivation
-	pin := "123456"
-	s3KeyBytes := pbkdf2.Key([]byte(pin), []byte("solana-mpc-salt"), 100000, 32, sha256.New)
-
 	// Step 1: Deserialize key shares
 	s1Bytes, _ := base64.StdEncoding.DecodeString(s1ShareData)
 	var s1Key mpc.EDDSAMPCKey
@@ -227,8 +223,6 @@ func performMPCSigning(message []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to unmarshal S3 key: %v", err)
 	}
 	defer s3Key.Free()
-	
-	_ = s3KeyBytes
 
 	// Step 2: Create access structure
 	ed25519Curve, err := curve.NewEd25519()
@@ -240,53 +234,46 @@ func performMPCSigning(message []byte) ([]byte, error) {
 	partyNames := []string{"server", "kms", "pin"}
 	ac := createThresholdAccessStructure(partyNames, 2, ed25519Curve)
 
-	// Step 3: Convert threshold shares to additive shares for signing
+	// Step 3: Sign with the server and PIN quorum.  The network connects the
+	// whole committee; the KMS party stays offline and is never addressed.
 	quorum := []string{"server", "pin"}
-	s1Add, err := s1Key.ToAdditiveShare(ac, quorum)
-	if err != nil {
-		return nil, fmt.Errorf("could not convert s1 to additive share: %w", err)
-	}
-	defer s1Add.Free()
-
-	s3Add, err := s3Key.ToAdditiveShare(ac, quorum)
-	if err != nil {
-		return nil, fmt.Errorf("could not convert s3 to additive share: %w", err)
-	}
-	defer s3Add.Free()
+	shares := map[string]mpc.EDDSAMPCKey{"server": s1Key, "pin": s3Key}
+	messengers := mocknet.NewMockNetwork(len(partyNames))
+	signatureReceiver := 0 // Index in the quorum job: the server
 
-	// Step 4: Setup MPC network and parties
-	parties := []mpc.EDDSAMPCKey{s1Add, s3Add}
-	nQuorumParties := len(parties)
-	messengers := mocknet.NewMockNetwork(nQuorumParties)
-	signatureReceiver := 0
-
-	// Step 5: Run signing protocol
 	var eg errgroup.Group
 	var finalSignature []byte
-	
-	for i := 0; i < nQuorumParties; i++ {
-		partyIdx := i
+
+	for i, name := range partyNames {
+		share, ok := shares[name]
+		if !ok {
+			continue
+		}
 		eg.Go(func() error {
-			// Note: The JobMP still needs to know about ALL original parties,
-			// even if only a quorum is participating in the signing.
-			job, err := mpc.NewJobMP(messengers[partyIdx], 3, partyIdx, partyNames)
+			job, err := mpc.NewQuorumJob(messengers[i], ac, quorum, name)
 			if err != nil {
 				return err
 			}
 			defer job.Free()
 
+			additive, err := job.EDDSAAdditiveShare(share)
+			if err != nil {
+				return fmt.Errorf("could not convert %s share to additive share: %w", name, err)
+			}
+			defer additive.Free()
+
 			req := &mpc.EDDSAMPCSignRequest{
-				KeyShare:          parties[partyIdx],
+				KeyShare:          additive,
 				Message:           message,
 				SignatureReceiver: signatureReceiver,
 			}
 
-			resp, err := mpc.EDDSAMPCSign(job, req)
+			resp, err := mpc.EDDSAMPCSign(job.JobMP, req)
 			if err != nil {
 				return err
 			}
 
-			if partyIdx == signatureReceiver {
+			if job.GetPartyIndex() == signatureReceiver {
 				finalSignature = resp.Signature
 			}
 			return nil
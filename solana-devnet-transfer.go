@@ -13,13 +13,14 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
 )
 
 const (
 	// Configuration from wallet generator
 	PINSalt = "solana-cb-mpc-salt-2024"
 	PBKDFIterations = 100000
-	TestPIN = "123456"
 	
 	// Latest wallet shares - UPDATE THESE from latest wallet generator run
 	S1Share = "C38CAQL/gAABCgAA/8//gAAFIUAF83fys98VwMWbEzX1WGeETVZocYWJZElXGqdRtSCA1CIEP76k1u2BZw/MmMe1OVo82mKrWW/jWbmyWAFBElHCeoQleQMAA2ttcwQ/RclMbbmtJcmblf7ZdR5QM8gFhWqJn9koRCXyUzx6ct8AA3BpbgQ/lQdhUDG/+AGcIjoGxPER16hutJgvXd5jMmCkZMgOFesABnNlcnZlcgQ/rvy6FBdJYJML+EunNqUxFs//bhk4Tc4OU3N1EeZDnEMCBD8IAAZzZXJ2ZXI="
@@ -27,7 +28,7 @@ const (
 	
 	// Transfer configuration
 	ToAddress = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM" // Random devnet address
-	TransferAmount = 0.01 // SOL (10,000,000 lamports)
+	TransferAmount = "0.01" // SOL (10,000,000 lamports), parsed exactly by solchain.ParseSOL
 	
 	// Solana devnet RPC
 	DevnetRPC = "https://api.devnet.solana.com"
@@ -82,8 +83,7 @@ func main() {
 		log.Fatal("Failed to get balance:", err)
 	}
 	
-	balanceSOL := float64(balance.Value) / 1e9
-	fmt.Printf("✅ Current balance: %.9f SOL (%d lamports)\n", balanceSOL, balance.Value)
+	fmt.Printf("✅ Current balance: %s (%d lamports)\n", solchain.Lamports(balance.Value), balance.Value)
 	
 	if balance.Value == 0 {
 		fmt.Println("⚠️  ZERO BALANCE! Running in DEMO MODE...")
@@ -95,10 +95,14 @@ func main() {
 		fmt.Println("\n🎭 DEMO MODE: Continuing with transaction simulation...")
 	}
 	
-	transferLamports := uint64(TransferAmount * 1e9) // Convert SOL to lamports
+	amount, err := solchain.ParseSOL(TransferAmount)
+	if err != nil {
+		log.Fatal("Invalid transfer amount:", err)
+	}
+	transferLamports := uint64(amount)
 	demoMode := false
 	if balance.Value < transferLamports+5000 { // 5000 lamports for fees
-		fmt.Printf("⚠️  Insufficient balance for transfer (need %.9f SOL + fees)\n", TransferAmount)
+		fmt.Printf("⚠️  Insufficient balance for transfer (need %s + fees)\n", amount)
 		fmt.Println("🎭 DEMO MODE: Will create and sign transaction but not broadcast")
 		demoMode = true
 	}
@@ -116,7 +120,7 @@ func main() {
 		log.Fatal("Failed to create transaction:", err)
 	}
 	
-	fmt.Printf("✅ Transaction created (transferring %.9f SOL)\n", TransferAmount)
+	fmt.Printf("✅ Transaction created (transferring %s)\n", amount)
 	
 	// Step 5: Generate MPC signature
 	fmt.Println("\n📍 Step 5: Generating MPC Signature...")
@@ -161,7 +165,7 @@ func main() {
 		fmt.Println("\n🎭 DEMO COMPLETE - Transaction Created & Signed with MPC!")
 		fmt.Printf("===============================================\n")
 		fmt.Printf("🔐 MPC Signature: %s\n", hex.EncodeToString(transfer.MPCSignature))
-		fmt.Printf("💰 Transfer Amount: %.9f SOL\n", TransferAmount)
+		fmt.Printf("💰 Transfer Amount: %s\n", amount)
 		fmt.Printf("📫 From: %s\n", transfer.FromAddress.String())
 		fmt.Printf("📬 To: %s\n", transfer.ToAddress.String())
 		fmt.Printf("\n💡 To broadcast this transaction:\n")
@@ -198,11 +202,10 @@ func main() {
 		if err != nil {
 			log.Printf("Failed to get new balance: %v", err)
 		} else {
-			newBalanceSOL := float64(newBalance.Value) / 1e9
-			fmt.Printf("✅ New balance: %.9f SOL (%d lamports)\n", newBalanceSOL, newBalance.Value)
+			fmt.Printf("✅ New balance: %s (%d lamports)\n", solchain.Lamports(newBalance.Value), newBalance.Value)
 			
-			transferred := float64(balance.Value - newBalance.Value) / 1e9
-			fmt.Printf("✅ Successfully transferred: %.9f SOL\n", transferred)
+			transferred := solchain.Lamports(balance.Value - newBalance.Value)
+			fmt.Printf("✅ Successfully transferred: %s\n", transferred)
 		}
 	}
 }
@@ -11,6 +11,8 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
 )
 
 const (
@@ -19,7 +21,7 @@ const (
 	
 	// Transfer configuration
 	ToAddress = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
-	TransferAmount = 0.01 // SOL
+	TransferAmount = "0.01" // SOL, parsed exactly by solchain.ParseSOL
 	
 	// Solana devnet RPC
 	DevnetRPC = "https://api.devnet.solana.com"
@@ -66,8 +68,8 @@ func main() {
 		log.Fatal("Failed to get balance:", err)
 	}
 	
-	balanceSOL := float64(balance.Value) / float64(solana.LAMPORTS_PER_SOL)
-	fmt.Printf("✅ Current balance: %.9f SOL (%d lamports)\n", balanceSOL, balance.Value)
+	balanceSOL := solchain.Lamports(balance.Value)
+	fmt.Printf("✅ Current balance: %s (%d lamports)\n", balanceSOL, balance.Value)
 	
 	if balance.Value == 0 {
 		fmt.Printf("❌ No balance! Please fund this address:\n")
@@ -77,10 +79,14 @@ func main() {
 		return
 	}
 	
-	transferLamports := uint64(TransferAmount * float64(solana.LAMPORTS_PER_SOL))
+	amount, err := solchain.ParseSOL(TransferAmount)
+	if err != nil {
+		log.Fatal("Invalid transfer amount:", err)
+	}
+	transferLamports := uint64(amount)
 	if balance.Value < transferLamports+5000 { // 5000 lamports for fees
-		fmt.Printf("❌ Insufficient balance for transfer (need %.9f SOL + fees)\n", TransferAmount)
-		fmt.Printf("   Current: %.9f SOL\n", balanceSOL)
+		fmt.Printf("❌ Insufficient balance for transfer (need %s + fees)\n", amount)
+		fmt.Printf("   Current: %s\n", balanceSOL)
 		return
 	}
 	
@@ -94,7 +100,7 @@ func main() {
 	
 	fmt.Printf("✅ From: %s\n", solanaPublicKey.String())
 	fmt.Printf("✅ To:   %s\n", toPubkey.String())
-	fmt.Printf("✅ Amount: %.9f SOL (%d lamports)\n", TransferAmount, transferLamports)
+	fmt.Printf("✅ Amount: %s (%d lamports)\n", amount, transferLamports)
 	
 	// Step 5: Create transaction
 	fmt.Println("\n📍 Step 5: Creating Transaction...")
@@ -182,17 +188,17 @@ func main() {
 	if err != nil {
 		log.Printf("Failed to get new balance: %v", err)
 	} else {
-		newBalanceSOL := float64(newBalance.Value) / float64(solana.LAMPORTS_PER_SOL)
-		transferred := float64(balance.Value - newBalance.Value) / float64(solana.LAMPORTS_PER_SOL)
+		newBalanceSOL := solchain.Lamports(newBalance.Value)
+		transferred := solchain.Lamports(balance.Value - newBalance.Value)
 		
-		fmt.Printf("✅ Balance before: %.9f SOL\n", balanceSOL)
-		fmt.Printf("✅ Balance after:  %.9f SOL\n", newBalanceSOL)
-		fmt.Printf("✅ Amount sent:    %.9f SOL\n", transferred)
+		fmt.Printf("✅ Balance before: %s\n", balanceSOL)
+		fmt.Printf("✅ Balance after:  %s\n", newBalanceSOL)
+		fmt.Printf("✅ Amount sent:    %s\n", transferred)
 	}
 	
 	fmt.Printf("\n🎯 REAL SOLANA TRANSACTION COMPLETE!\n")
 	fmt.Printf("====================================\n")
-	fmt.Printf("✅ Successfully transferred %.9f SOL on Solana devnet\n", TransferAmount)
+	fmt.Printf("✅ Successfully transferred %s on Solana devnet\n", amount)
 	fmt.Printf("✅ Transaction hash: %s\n", sig.String())
 	fmt.Printf("✅ View at: https://explorer.solana.com/tx/%s?cluster=devnet\n", sig.String())
 }
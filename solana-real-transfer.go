@@ -12,12 +12,14 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
 )
 
 const (
 	// Transfer configuration
 	ToAddress = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM" // Random devnet address
-	TransferAmount = 0.01 // SOL
+	TransferAmount = "0.01" // SOL, parsed exactly by solchain.ParseSOL
 	
 	// Solana devnet RPC
 	DevnetRPC = "https://api.devnet.solana.com"
@@ -84,16 +86,19 @@ func main() {
 		log.Fatal("Failed to get balance:", err)
 	}
 	
-	balanceSOL := float64(balance.Value) / float64(solana.LAMPORTS_PER_SOL)
-	fmt.Printf("✅ Current balance: %.9f SOL (%d lamports)\n", balanceSOL, balance.Value)
+	fmt.Printf("✅ Current balance: %s (%d lamports)\n", solchain.Lamports(balance.Value), balance.Value)
 	
 	if balance.Value == 0 {
 		log.Fatal("❌ No balance! Please get SOL from https://faucet.solana.com for address:", solanaPublicKey.String())
 	}
 	
-	transferLamports := uint64(TransferAmount * float64(solana.LAMPORTS_PER_SOL))
+	amount, err := solchain.ParseSOL(TransferAmount)
+	if err != nil {
+		log.Fatal("Invalid transfer amount:", err)
+	}
+	transferLamports := uint64(amount)
 	if balance.Value < transferLamports+5000 { // 5000 lamports for fees
-		log.Fatalf("❌ Insufficient balance for transfer (need %.9f SOL + fees)", TransferAmount)
+		log.Fatalf("❌ Insufficient balance for transfer (need %s + fees)", amount)
 	}
 	
 	// Step 5: Setup recipient
@@ -106,7 +111,7 @@ func main() {
 	
 	fmt.Printf("✅ From: %s\n", solanaPublicKey.String())
 	fmt.Printf("✅ To:   %s\n", toPubkey.String())
-	fmt.Printf("✅ Amount: %.9f SOL\n", TransferAmount)
+	fmt.Printf("✅ Amount: %s\n", amount)
 	
 	// Step 6: Create transaction
 	fmt.Println("\n📍 Step 6: Creating Transaction...")
@@ -197,11 +202,10 @@ func main() {
 	if err != nil {
 		log.Printf("Failed to get new balance: %v", err)
 	} else {
-		newBalanceSOL := float64(newBalance.Value) / float64(solana.LAMPORTS_PER_SOL)
-		fmt.Printf("✅ New balance: %.9f SOL (%d lamports)\n", newBalanceSOL, newBalance.Value)
+		fmt.Printf("✅ New balance: %s (%d lamports)\n", solchain.Lamports(newBalance.Value), newBalance.Value)
 		
-		transferred := float64(balance.Value - newBalance.Value) / float64(solana.LAMPORTS_PER_SOL)
-		fmt.Printf("✅ Successfully transferred: %.9f SOL\n", transferred)
+		transferred := solchain.Lamports(balance.Value - newBalance.Value)
+		fmt.Printf("✅ Successfully transferred: %s\n", transferred)
 	}
 	
 	fmt.Printf("\n🎯 REAL TRANSACTION COMPLETE!\n")
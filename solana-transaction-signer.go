@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
@@ -11,13 +12,14 @@ import (
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
 	"golang.org/x/crypto/pbkdf2"
+
+	"solana-threshold-wallet/wallet/prompt"
 )
 
 const (
 	// Must match values from generator script
 	PINSalt = "solana-cb-mpc-salt-2024"
 	PBKDFIterations = 100000
-	TestPIN = "123456"
 )
 
 // INSTRUCTIONS: Replace MockS1Share with actual S1 from wallet generator
@@ -67,8 +69,18 @@ func main() {
 	// Step 2: Derive S3 from PIN
 	fmt.Println("\n📍 Step 2: Deriving S3 from PIN...")
 	
-	pinKey := pbkdf2.Key([]byte(TestPIN), []byte(PINSalt), PBKDFIterations, 32, sha256.New)
-	fmt.Printf("✅ PIN '%s' → S3: %s\n", TestPIN, hex.EncodeToString(pinKey))
+	prompter, err := prompt.New(prompt.Config{})
+	if err != nil {
+		log.Fatalf("PIN prompt: %v", err)
+	}
+	pin, err := prompter.Secret(context.Background(), prompt.Request{Prompt: "PIN", Description: "Enter the PIN of share S3."})
+	if err != nil {
+		log.Fatalf("Failed to read PIN: %v", err)
+	}
+	pinKey := pbkdf2.Key(pin, []byte(PINSalt), PBKDFIterations, 32, sha256.New)
+	clear(pin)
+	defer clear(pinKey)
+	fmt.Println("✅ PIN entered → S3 key derived")
 	
 	// Step 3: Prepare message for signing
 	fmt.Println("\n📍 Step 3: Preparing Message...")
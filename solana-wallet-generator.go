@@ -1,34 +1,36 @@
 package main
 
 import (
-	"crypto/ed25519"
-	"encoding/base64"
+	"context"
+	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/curve"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
 	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/transport/mocknet"
 	"github.com/gagliardetto/solana-go"
 	"golang.org/x/sync/errgroup"
+
+	"solana-threshold-wallet/wallet/prompt"
 )
 
 const (
-	// Solana derivation path: m/44'/501'/0'/0'
-	SolanaPath = "m/44'/501'/0'/0'"
-	
-	// PIN-based key derivation parameters
-	PINSalt = "solana-cb-mpc-salt-2024"
-	PBKDFIterations = 100000
-	
-	// Hardcoded PIN for testing
-	TestPIN = "123456"
+	// AccountPath is the derivation path of account %d under cb-mpc's own
+	// non-hardened scheme (see mpc.DeriveEd25519).  It is not the Solana
+	// BIP44 path m/44'/501'/n'/0': other wallets do not derive these
+	// accounts from the same key.
+	AccountPath = mpc.HDPathRoot + "/%d"
+	// derivedAccounts is the number of derived accounts to print.
+	derivedAccounts = 3
 )
 
 type WalletShares struct {
 	SolanaAddress string `json:"solana_address"`
-	S1_Server     string `json:"s1_server"`     // Server share (base64)
+	S1_Server     string `json:"s1_server"`     // Server share (encrypted, JSON)
 	S2_KMS        string `json:"s2_kms"`        // KMS share (for future recovery)
 	S3_PinDerived string `json:"s3_pin_derived"` // PIN-derived share info
 	MasterSeed    string `json:"master_seed"`   // HD master seed (hex)
@@ -98,16 +100,26 @@ func main() {
 	}
 	defer publicKeyPoint.Free()
 
-	// For Ed25519, the public key is the Y-coordinate plus a sign bit.
-	// However, Solana uses the X-coordinate directly as the public key.
-	publicKeyBytes := publicKeyPoint.GetX()
-	if len(publicKeyBytes) != ed25519.PublicKeySize {
-		log.Fatalf("Invalid Ed25519 public key length: got %d, expected %d", len(publicKeyBytes), ed25519.PublicKeySize)
+	// Solana public keys are the RFC 8032 encoding: the Y-coordinate with the
+	// sign of X folded into the top bit.
+	publicKeyBytes, err := publicKeyPoint.Encode(ed25519Curve, curve.FormatEd25519)
+	if err != nil {
+		log.Fatal("Failed to encode public key:", err)
 	}
 	solanaAddress := solana.PublicKey(publicKeyBytes)
 	fmt.Printf("✅ Solana Address: %s\n", solanaAddress.String())
 	fmt.Printf("✅ Public Key: %s\n", hex.EncodeToString(publicKeyBytes))
 
+	// Every account index is a child of the same key: the parties sign for it
+	// with shares from mpc.DeriveChild, no new key generation needed.
+	for i := 0; i < derivedAccounts; i++ {
+		child, err := mpc.DeriveEd25519(publicKeyBytes, nil, fmt.Sprintf(AccountPath, i))
+		if err != nil {
+			log.Fatal("Failed to derive account:", err)
+		}
+		fmt.Printf("✅ Account %d (%s): %s\n", i, child.Path, solana.PublicKey(child.PublicKey))
+	}
+
 	// Step 6: Serialize and encode key shares
 	fmt.Println("\n📍 Step 4: Serializing and Encoding Key Shares...")
 	s1Data, err := keyShares[0].MarshalBinary()
@@ -123,19 +135,24 @@ func main() {
 		log.Fatal("Failed to marshal S3 share:", err)
 	}
 
-	s1Base64 := base64.StdEncoding.EncodeToString(s1Data)
-	s2Base64 := base64.StdEncoding.EncodeToString(s2Data)
-	s3Base64 := base64.StdEncoding.EncodeToString(s3Data)
+	// Seal every share before it leaves memory: S1 and S2 under key-encryption
+	// keys (from SERVER_KEK / KMS_KEK, hex, or freshly generated), S3 under
+	// the PIN the user chooses at the prompt (or PINENTRY_PROGRAM).
+	s1Sealed := sealShare("server", s1Data, kekFromEnv("SERVER_KEK", "server/s1"))
+	s2Sealed := sealShare("kms", s2Data, kekFromEnv("KMS_KEK", "kms/s2"))
+	pin := choosePIN()
+	s3Sealed := sealShare("pin", s3Data, mpc.Passphrase(pin))
+	clear(pin)
 
 	fmt.Println("\n🎉 WALLET GENERATED SUCCESSFULLY!")
 	fmt.Println("=================================")
 	fmt.Printf("Solana Address: %s\n", solanaAddress.String())
 	fmt.Printf("Public Key:     %s\n", hex.EncodeToString(publicKeyBytes))
-	fmt.Println("\n🔐 KEY SHARES (2-of-3 Threshold):")
-	fmt.Println("=================================")
-	fmt.Printf("S1 (Server Share): %s\n", s1Base64)
-	fmt.Printf("S2 (KMS Share):    %s\n", s2Base64)
-	fmt.Printf("S3 (PIN Share):    %s\n", s3Base64)
+	fmt.Println("\n🔐 ENCRYPTED KEY SHARES (2-of-3 Threshold):")
+	fmt.Println("============================================")
+	fmt.Printf("S1 (Server Share, KEK):   %s\n", s1Sealed)
+	fmt.Printf("S2 (KMS Share, KEK):      %s\n", s2Sealed)
+	fmt.Printf("S3 (PIN Share, Argon2id): %s\n", s3Sealed)
 
 	fmt.Printf("\n📊 Share Sizes:\n")
 	fmt.Printf("S1: %d bytes | S2: %d bytes | S3: %d bytes\n",
@@ -143,7 +160,56 @@ func main() {
 
 	fmt.Printf("\n💡 Next Steps:\n")
 	fmt.Printf("1. Store S1 on your server, S2 in a KMS, and use S3 with the user's PIN.\n")
-	fmt.Printf("2. Copy these shares into the 'solana-complete-demo.go' script to test a transaction.\n")
+	fmt.Printf("2. Decrypt a share with mpc.EncryptedKeyShare.Open before UnmarshalBinary, e.g. to test a transaction with 'solana-complete-demo.go'.\n")
+}
+
+// sealShare encrypts a marshalled share under key and returns it as JSON.
+func sealShare(label string, share []byte, key mpc.ShareKey) string {
+	sealed := mpc.EncryptedKeyShare{Label: label}
+	if err := sealed.Seal(share, key); err != nil {
+		log.Fatalf("Failed to encrypt %s share: %v", label, err)
+	}
+	out, err := json.Marshal(&sealed)
+	if err != nil {
+		log.Fatalf("Failed to encode %s share: %v", label, err)
+	}
+	return string(out)
+}
+
+// choosePIN asks the user to choose the PIN that protects S3.
+func choosePIN() []byte {
+	prompter, err := prompt.New(prompt.Config{Pinentry: os.Getenv("PINENTRY_PROGRAM")})
+	if err != nil {
+		log.Fatal("Failed to set up PIN prompt:", err)
+	}
+	pin, err := prompter.Secret(context.Background(), prompt.Request{
+		Prompt:      "PIN",
+		Description: "Choose the PIN that protects S3.",
+		Confirm:     true,
+		Policy:      prompt.PIN(6),
+	})
+	if err != nil {
+		log.Fatal("Failed to read PIN:", err)
+	}
+	return pin
+}
+
+// kekFromEnv reads a 32-byte hex key-encryption key from the environment, or
+// generates one and prints it so that it can be moved into a KMS.
+func kekFromEnv(name, id string) mpc.ShareKey {
+	if v := os.Getenv(name); v != "" {
+		kek, err := hex.DecodeString(v)
+		if err != nil || len(kek) != 32 {
+			log.Fatalf("%s must be 32 bytes of hex", name)
+		}
+		return mpc.KEK(id, kek)
+	}
+	kek := make([]byte, 32)
+	if _, err := rand.Read(kek); err != nil {
+		log.Fatal("Failed to generate KEK:", err)
+	}
+	fmt.Printf("⚠️  %s not set, generated %s=%s – store it in your KMS\n", name, name, hex.EncodeToString(kek))
+	return mpc.KEK(id, kek)
 }
 
 // createThresholdAccessStructure creates a 2-of-3 threshold access structure
@@ -0,0 +1,3 @@
+#include "version.h"
+
+const char* cbmpc_version(void) { return CBMPC_VERSION; }
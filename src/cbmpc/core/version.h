@@ -0,0 +1,13 @@
+#pragma once
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// Returns the version of the linked library as "major.minor.patch", so that
+// language bindings can detect a library from another release at runtime.
+const char* cbmpc_version(void);
+
+#ifdef __cplusplus
+}  // extern "C"
+#endif
@@ -0,0 +1,93 @@
+// Package approval signs and checks the approvals that gate sensitive
+// operations such as retiring a key, promoting a standby or handing a share
+// over: a quorum of named approvers signs the hash of the request with their
+// Ed25519 identity keys, and every host acting on the request checks the
+// signatures itself instead of trusting whoever relayed them.
+//
+//	a, _ := approval.Sign(domain, hash, "alice", aliceKey, time.Now())
+//	// every host:
+//	if err := approval.Quorum{Approvers: keys, Required: 2}.Check(domain, hash, approvals); err != nil {
+//	    return err
+//	}
+//
+// The domain separates each kind of approval from the others and from any
+// other use of the approvers' keys.
+package approval
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotApproved is returned when a request lacks a quorum of valid
+// approvals.
+var ErrNotApproved = errors.New("approval: request not approved")
+
+// Approval records that an approver authorized a request, identified by its
+// hash.
+type Approval struct {
+	Approver    string    `json:"approver"`
+	RequestHash []byte    `json:"request_hash"`
+	At          time.Time `json:"at"`
+	Signature   []byte    `json:"signature"`
+}
+
+// Sign approves the request with the given hash on behalf of approver.
+func Sign(domain string, hash []byte, approver string, key ed25519.PrivateKey, now time.Time) (*Approval, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("approval key of %q must be provided", approver)
+	}
+	return &Approval{
+		Approver:    approver,
+		RequestHash: hash,
+		At:          now.UTC(),
+		Signature:   ed25519.Sign(key, signed(domain, hash)),
+	}, nil
+}
+
+// Quorum names who may approve and how many distinct approvals are needed.
+type Quorum struct {
+	Approvers map[string]ed25519.PublicKey
+	Required  int
+}
+
+// Check checks that approvals contain at least q.Required valid signatures
+// on hash under domain from distinct approvers of q.  Approvals by unknown
+// approvers, for other requests or with invalid signatures are ignored.  The
+// error wraps ErrNotApproved when too few remain.
+func (q Quorum) Check(domain string, hash []byte, approvals []Approval) error {
+	if q.Required < 1 {
+		return fmt.Errorf("policy must require at least one approval")
+	}
+	if n := len(q.Approved(domain, hash, approvals)); n < q.Required {
+		return fmt.Errorf("%w: %d of %d required approvals", ErrNotApproved, n, q.Required)
+	}
+	return nil
+}
+
+// Approved returns the distinct approvers of q whose approvals carry a valid
+// signature on hash under domain.
+func (q Quorum) Approved(domain string, hash []byte, approvals []Approval) []string {
+	var approved []string
+	seen := make(map[string]bool)
+	for _, a := range approvals {
+		key, ok := q.Approvers[a.Approver]
+		if !ok || seen[a.Approver] || len(key) != ed25519.PublicKeySize || !bytes.Equal(a.RequestHash, hash) {
+			continue
+		}
+		if ed25519.Verify(key, signed(domain, hash), a.Signature) {
+			seen[a.Approver] = true
+			approved = append(approved, a.Approver)
+		}
+	}
+	return approved
+}
+
+// signed returns the message approvers sign: the domain followed by the
+// request hash.
+func signed(domain string, hash []byte) []byte {
+	return append([]byte(domain), hash...)
+}
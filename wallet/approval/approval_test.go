@@ -0,0 +1,39 @@
+package approval_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval"
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+)
+
+const domain = "test approval\n"
+
+func TestQuorumCheck(t *testing.T) {
+	signers := approvaltest.New(t, "alice", "bob", "carol")
+	quorum := signers.Quorum(2)
+	hash := []byte("request hash")
+
+	assert.NoError(t, quorum.Check(domain, hash, signers.Approve(t, domain, hash, "alice", "bob")))
+	assert.ErrorIs(t, quorum.Check(domain, hash, signers.Approve(t, domain, hash, "alice")), approval.ErrNotApproved)
+	// The same approver twice counts once.
+	assert.ErrorIs(t, quorum.Check(domain, hash, signers.Approve(t, domain, hash, "alice", "alice")), approval.ErrNotApproved)
+	// Approvals for another request or in another domain do not count.
+	assert.ErrorIs(t, quorum.Check(domain, hash, signers.Approve(t, domain, []byte("other"), "alice", "bob")), approval.ErrNotApproved)
+	assert.ErrorIs(t, quorum.Check(domain, hash, signers.Approve(t, "other domain\n", hash, "alice", "bob")), approval.ErrNotApproved)
+
+	// Unknown approvers and forged signatures do not count.
+	mallory := approvaltest.New(t, "mallory")
+	approvals := append(signers.Approve(t, domain, hash, "alice"), mallory.Approve(t, domain, hash, "mallory")...)
+	forged, err := approval.Sign(domain, hash, "bob", mallory.Private["mallory"], time.Now())
+	require.NoError(t, err)
+	approvals = append(approvals, *forged)
+	assert.ErrorIs(t, quorum.Check(domain, hash, approvals), approval.ErrNotApproved)
+	assert.Equal(t, []string{"alice"}, quorum.Approved(domain, hash, approvals))
+
+	assert.Error(t, approval.Quorum{Approvers: signers.Public}.Check(domain, hash, nil), "no approvals required")
+}
@@ -0,0 +1,51 @@
+// Package approvaltest provides named Ed25519 signers for tests of
+// approval-gated workflows.
+package approvaltest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"solana-threshold-wallet/wallet/approval"
+)
+
+// Signers holds an Ed25519 key pair per name.
+type Signers struct {
+	Public  map[string]ed25519.PublicKey
+	Private map[string]ed25519.PrivateKey
+}
+
+// New generates a key pair for each of names.
+func New(t testing.TB, names ...string) *Signers {
+	t.Helper()
+	s := &Signers{Public: map[string]ed25519.PublicKey{}, Private: map[string]ed25519.PrivateKey{}}
+	for _, name := range names {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generating key of %s: %v", name, err)
+		}
+		s.Public[name], s.Private[name] = pub, priv
+	}
+	return s
+}
+
+// Quorum returns a quorum of all signers requiring required approvals.
+func (s *Signers) Quorum(required int) approval.Quorum {
+	return approval.Quorum{Approvers: s.Public, Required: required}
+}
+
+// Approve has each of names approve the request with the given hash.
+func (s *Signers) Approve(t testing.TB, domain string, hash []byte, names ...string) []approval.Approval {
+	t.Helper()
+	var approvals []approval.Approval
+	for _, name := range names {
+		a, err := approval.Sign(domain, hash, name, s.Private[name], time.Now())
+		if err != nil {
+			t.Fatalf("approving as %s: %v", name, err)
+		}
+		approvals = append(approvals, *a)
+	}
+	return approvals
+}
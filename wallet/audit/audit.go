@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// challengeDomain separates audit session IDs from other uses of the
+	// proof system.
+	challengeDomain = "cb-mpc share audit v1\x00"
+	// nonceSize is the size of the auditor's nonce.
+	nonceSize = 32
+)
+
+// ErrMismatch is returned when a response does not answer the challenge it is
+// checked against.
+var ErrMismatch = errors.New("audit: response does not match challenge")
+
+// Challenge is issued by an auditor for one party's backup blob.
+type Challenge struct {
+	Party      string    `json:"party"`
+	BlobSHA256 []byte    `json:"blob_sha256"`
+	Nonce      []byte    `json:"nonce"`
+	IssuedAt   time.Time `json:"issued_at"`
+}
+
+// NewChallenge creates a challenge for the encrypted backup blob of party.
+func NewChallenge(party string, blob []byte) (*Challenge, error) {
+	if party == "" {
+		return nil, fmt.Errorf("party must be provided")
+	}
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("blob cannot be empty")
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(blob)
+	return &Challenge{Party: party, BlobSHA256: sum[:], Nonce: nonce, IssuedAt: time.Now().UTC()}, nil
+}
+
+// SessionID returns the session identifier the proof must be bound to.  It
+// commits to the party, the blob and the nonce, so a proof cannot be replayed
+// for another blob or another audit.
+func (c *Challenge) SessionID() []byte {
+	var b bytes.Buffer
+	b.WriteString(challengeDomain)
+	for _, field := range [][]byte{[]byte(c.Party), c.BlobSHA256, c.Nonce} {
+		binary.Write(&b, binary.BigEndian, uint32(len(field)))
+		b.Write(field)
+	}
+	sum := sha256.Sum256(b.Bytes())
+	return sum[:]
+}
+
+// equal reports whether c and o are the same challenge.
+func (c *Challenge) equal(o *Challenge) bool {
+	return c.Party == o.Party &&
+		bytes.Equal(c.BlobSHA256, o.BlobSHA256) &&
+		bytes.Equal(c.Nonce, o.Nonce) &&
+		c.IssuedAt.Equal(o.IssuedAt)
+}
+
+// Response is a party's answer to a Challenge.
+type Response struct {
+	Challenge Challenge `json:"challenge"`
+	Proof     []byte    `json:"proof"`
+}
+
+// Respond answers c for the party holding blob.  prove must decrypt blob,
+// check that it holds a share of the party's public share and return a proof
+// of knowledge of that share bound to sessionID.
+func Respond(c *Challenge, blob []byte, prove func(sessionID []byte) ([]byte, error)) (*Response, error) {
+	sum := sha256.Sum256(blob)
+	if !bytes.Equal(sum[:], c.BlobSHA256) {
+		return nil, fmt.Errorf("challenge is for a different blob")
+	}
+	proof, err := prove(c.SessionID())
+	if err != nil {
+		return nil, fmt.Errorf("proving share: %w", err)
+	}
+	return &Response{Challenge: *c, Proof: proof}, nil
+}
+
+// Result is the outcome of an audit.
+type Result struct {
+	Party       string    `json:"party"`
+	BlobSHA256  []byte    `json:"blob_sha256"`
+	PublicShare []byte    `json:"public_share"`
+	Method      string    `json:"method"` // "zk-dl" or "pve"
+	Valid       bool      `json:"valid"`
+	Err         string    `json:"error,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+// Verify checks resp against the challenge c the auditor issued and the
+// party's public share.  verify checks the proof for the given public share
+// and session ID.  An invalid proof is reported in the Result; an error is
+// only returned if resp does not answer c.
+func Verify(c *Challenge, resp *Response, publicShare []byte, verify func(publicShare, proof, sessionID []byte) error) (*Result, error) {
+	if resp == nil || !c.equal(&resp.Challenge) {
+		return nil, ErrMismatch
+	}
+	res := &Result{
+		Party:       c.Party,
+		BlobSHA256:  c.BlobSHA256,
+		PublicShare: publicShare,
+		Method:      "zk-dl",
+		CheckedAt:   time.Now().UTC(),
+	}
+	if err := verify(publicShare, resp.Proof, c.SessionID()); err != nil {
+		res.Err = err.Error()
+		return res, nil
+	}
+	res.Valid = true
+	return res, nil
+}
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProve stands in for the ZK proof system: a "proof" is an HMAC of the
+// session ID keyed by the share, and the public share is the share itself.
+func fakeProve(share []byte) func([]byte) ([]byte, error) {
+	return func(sessionID []byte) ([]byte, error) {
+		mac := hmac.New(sha256.New, share)
+		mac.Write(sessionID)
+		return mac.Sum(nil), nil
+	}
+}
+
+func fakeVerify(publicShare, proof, sessionID []byte) error {
+	want, _ := fakeProve(publicShare)(sessionID)
+	if !hmac.Equal(want, proof) {
+		return errors.New("proof does not verify")
+	}
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	blob := []byte("encrypted backup")
+	share := []byte("share")
+
+	c, err := NewChallenge("kms", blob)
+	require.NoError(t, err)
+
+	// The challenge travels to the party as JSON.
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	var received Challenge
+	require.NoError(t, json.Unmarshal(data, &received))
+
+	resp, err := Respond(&received, blob, fakeProve(share))
+	require.NoError(t, err)
+
+	res, err := Verify(c, resp, share, fakeVerify)
+	require.NoError(t, err)
+	assert.True(t, res.Valid)
+	assert.Equal(t, "kms", res.Party)
+
+	res, err = Verify(c, resp, []byte("other public share"), fakeVerify)
+	require.NoError(t, err)
+	assert.False(t, res.Valid)
+	assert.NotEmpty(t, res.Err)
+}
+
+func TestRespondRejectsOtherBlob(t *testing.T) {
+	c, err := NewChallenge("kms", []byte("blob a"))
+	require.NoError(t, err)
+	_, err = Respond(c, []byte("blob b"), fakeProve([]byte("share")))
+	assert.Error(t, err)
+}
+
+func TestVerifyRejectsReplayedResponse(t *testing.T) {
+	blob := []byte("encrypted backup")
+	old, err := NewChallenge("kms", blob)
+	require.NoError(t, err)
+	resp, err := Respond(old, blob, fakeProve([]byte("share")))
+	require.NoError(t, err)
+
+	fresh, err := NewChallenge("kms", blob)
+	require.NoError(t, err)
+	_, err = Verify(fresh, resp, []byte("share"), fakeVerify)
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	// Session IDs differ, so the old proof is useless for the new challenge.
+	assert.False(t, bytes.Equal(old.SessionID(), fresh.SessionID()))
+}
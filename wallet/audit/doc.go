@@ -0,0 +1,18 @@
+// Package audit lets a third party confirm that an encrypted share backup is a
+// valid share of a published key without ever seeing the share.
+//
+// The exchange has three messages:
+//
+//  1. The auditor hashes the encrypted backup blob and issues a `Challenge`
+//     with a fresh nonce for one party.
+//  2. The party checks that the challenge names the blob it holds, decrypts it
+//     locally and answers with a zero-knowledge proof of knowledge of the
+//     discrete logarithm of its public share, bound to the challenge through
+//     `Challenge.SessionID`.
+//  3. The auditor verifies the proof against the public share taken from the
+//     key's signed public bundle.
+//
+// The proof system is supplied by the caller, so the package itself has no
+// dependency on the native library; cmd/cb-mpc-share-audit wires it to the ZK
+// discrete-log proofs of cb-mpc.
+package audit
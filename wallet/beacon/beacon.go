@@ -0,0 +1,185 @@
+package beacon
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/ceremony"
+)
+
+const defaultMaxAge = time.Minute
+
+// ErrStale is returned when the latest beacon round is older than
+// Config.MaxAge, e.g. because the beacon stalled.
+var ErrStale = errors.New("beacon: latest round is stale")
+
+// Round is one published beacon value.
+type Round struct {
+	Number     uint64
+	Time       time.Time // Publication time of the round
+	Randomness []byte
+	Signature  []byte // Beacon signature over the round, if any
+}
+
+// Beacon is a public source of randomness.
+type Beacon interface {
+	// Name identifies the beacon in audit records, e.g. the drand chain.
+	Name() string
+	// Latest returns the most recently published round.
+	Latest(ctx context.Context) (*Round, error)
+}
+
+// Record is the audit log entry of a session's beacon contribution.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session"`
+	Beacon     string    `json:"beacon"`
+	Round      uint64    `json:"round,omitempty"`
+	RoundTime  time.Time `json:"round_time"`
+	Randomness []byte    `json:"randomness,omitempty"`
+	Signature  []byte    `json:"signature,omitempty"`
+	// Local is the SHA-256 of the session ID, the other mixed input.
+	Local []byte `json:"local"`
+	// Err explains why no round was mixed in, with Config.FailOpen.
+	Err string `json:"err,omitempty"`
+}
+
+// AuditLog receives a record for every session.
+type AuditLog interface {
+	Append(r *Record) error
+}
+
+// AuditLogFunc adapts an ordinary function to the AuditLog interface.
+type AuditLogFunc func(r *Record) error
+
+// Append calls f(r).
+func (f AuditLogFunc) Append(r *Record) error { return f(r) }
+
+// FileLog is an AuditLog that appends records to a file as JSON lines.
+type FileLog struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Ensure FileLog implements the AuditLog interface
+var _ AuditLog = (*FileLog)(nil)
+
+// Append implements AuditLog.
+func (l *FileLog) Append(r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Config contains the configuration for a Source.
+type Config struct {
+	// Beacon supplies the public randomness.  Required.
+	Beacon Beacon
+	// Audit receives the record of every session.  Required.
+	Audit AuditLog
+	// MaxAge is how old the latest round may be.  Defaults to one minute.
+	MaxAge time.Duration
+	// FailOpen lets sessions continue with the system RNG alone when the
+	// beacon fails or is stale.  The failure is recorded.  By default
+	// Session fails.
+	FailOpen bool
+	// Rand is the system RNG.  Defaults to crypto/rand.Reader.
+	Rand io.Reader
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Source hands out beacon-mixed session randomness.
+type Source struct {
+	config Config
+}
+
+// New creates a Source from the given configuration.
+func New(config Config) (*Source, error) {
+	if config.Beacon == nil || config.Audit == nil {
+		return nil, fmt.Errorf("beacon and audit log must be provided")
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = defaultMaxAge
+	}
+	if config.Rand == nil {
+		config.Rand = rand.Reader
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Source{config: config}, nil
+}
+
+// Session returns the RNG of session id, which mixes the system RNG with id
+// and the latest beacon round, and the record appended to the audit log.  No
+// randomness is handed out unless the record was appended.
+func (s *Source) Session(ctx context.Context, id string) (io.Reader, *Record, error) {
+	if id == "" {
+		return nil, nil, fmt.Errorf("session ID must be provided")
+	}
+	local := sha256.Sum256([]byte(id))
+	now := s.config.Now()
+	rec := &Record{Time: now.UTC(), Session: id, Beacon: s.config.Beacon.Name(), Local: local[:]}
+
+	round, err := s.latest(ctx, now)
+	contributions := [][]byte{[]byte(id)}
+	switch {
+	case err == nil:
+		rec.Round, rec.RoundTime = round.Number, round.Time.UTC()
+		rec.Randomness, rec.Signature = round.Randomness, round.Signature
+		contributions = append(contributions, round.Randomness)
+	case s.config.FailOpen && ctx.Err() == nil:
+		rec.Err = err.Error()
+	default:
+		return nil, nil, err
+	}
+
+	rng, err := ceremony.NewMixer(s.config.Rand, contributions...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.config.Audit.Append(rec); err != nil {
+		return nil, nil, fmt.Errorf("recording beacon round: %w", err)
+	}
+	return rng, rec, nil
+}
+
+// latest fetches the latest round and checks that it is recent.
+func (s *Source) latest(ctx context.Context, now time.Time) (*Round, error) {
+	round, err := s.config.Beacon.Latest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching beacon round: %w", err)
+	}
+	if len(round.Randomness) == 0 {
+		return nil, fmt.Errorf("beacon round %d has no randomness", round.Number)
+	}
+	if age := now.Sub(round.Time); age > s.config.MaxAge {
+		return nil, fmt.Errorf("%w: round %d is %s old", ErrStale, round.Number, age.Round(time.Second))
+	}
+	return round, nil
+}
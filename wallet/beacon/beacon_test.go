@@ -0,0 +1,293 @@
+package beacon
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bls "github.com/cloudflare/circl/ecc/bls12381"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+const chainHash = "52db9ba70e0cc0f6eaf7803dd07447a1f5477735fd3f661792ba94600c84e971"
+
+var genesis = time.Unix(1692803367, 0)
+
+// chainKey is the group key of a fake drand chain.
+type chainKey struct {
+	scheme string
+	secret bls.Scalar
+}
+
+func newChainKey(t *testing.T, scheme string) *chainKey {
+	t.Helper()
+	k := &chainKey{scheme: scheme}
+	require.NoError(t, k.secret.Random(rand.Reader))
+	return k
+}
+
+// public returns the hex group public key.
+func (k *chainKey) public() string {
+	if k.scheme == schemeQuicknet {
+		var p bls.G2
+		p.ScalarMult(&k.secret, bls.G2Generator())
+		return hex.EncodeToString(p.BytesCompressed())
+	}
+	var p bls.G1
+	p.ScalarMult(&k.secret, bls.G1Generator())
+	return hex.EncodeToString(p.BytesCompressed())
+}
+
+// sign returns the signature of round, chained to previous.
+func (k *chainKey) sign(round uint64, previous []byte) []byte {
+	var num [8]byte
+	binary.BigEndian.PutUint64(num[:], round)
+	h := sha256.New()
+	if k.scheme == schemeChained {
+		h.Write(previous)
+	}
+	h.Write(num[:])
+	if k.scheme == schemeQuicknet {
+		var p bls.G1
+		p.Hash(h.Sum(nil), dstG1)
+		p.ScalarMult(&k.secret, &p)
+		return p.BytesCompressed()
+	}
+	var p bls.G2
+	p.Hash(h.Sum(nil), dstG2)
+	p.ScalarMult(&k.secret, &p)
+	return p.BytesCompressed()
+}
+
+// fakeDrand serves the drand HTTP API of key's chain for round, with
+// signature sig.
+func fakeDrand(t *testing.T, key *chainKey, round uint64, sig, previous []byte) *httptest.Server {
+	t.Helper()
+	randomness := sha256.Sum256(sig)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /"+chainHash+"/info", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"public_key":   key.public(),
+			"period":       3,
+			"genesis_time": genesis.Unix(),
+			"hash":         chainHash,
+			"schemeID":     key.scheme,
+		})
+	})
+	mux.HandleFunc("GET /"+chainHash+"/public/latest", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"round":              round,
+			"randomness":         hex.EncodeToString(randomness[:]),
+			"signature":          hex.EncodeToString(sig),
+			"previous_signature": hex.EncodeToString(previous),
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fixedBeacon returns the same round, or err.
+type fixedBeacon struct {
+	round *Round
+	err   error
+}
+
+func (b *fixedBeacon) Name() string { return "fixed" }
+
+func (b *fixedBeacon) Latest(context.Context) (*Round, error) { return b.round, b.err }
+
+func TestDrandLatest(t *testing.T) {
+	for _, scheme := range []string{schemeChained, schemeUnchained, schemeQuicknet} {
+		t.Run(scheme, func(t *testing.T) {
+			key := newChainKey(t, scheme)
+			previous := key.sign(100, key.sign(99, nil))
+			sig := key.sign(101, previous)
+			srv := fakeDrand(t, key, 101, sig, previous)
+			d := NewDrand(DrandConfig{URL: srv.URL + "/", ChainHash: chainHash, PublicKey: key.public()})
+
+			round, err := d.Latest(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, uint64(101), round.Number)
+			assert.Equal(t, genesis.Add(300*time.Second), round.Time)
+			assert.Equal(t, sig, round.Signature)
+			assert.Equal(t, "drand:"+chainHash, d.Name())
+		})
+	}
+}
+
+func TestDrandRejectsBadSignatures(t *testing.T) {
+	key := newChainKey(t, schemeQuicknet)
+	other := newChainKey(t, schemeQuicknet)
+	latest := func(srv *httptest.Server, publicKey string) error {
+		_, err := NewDrand(DrandConfig{URL: srv.URL, ChainHash: chainHash, PublicKey: publicKey}).Latest(context.Background())
+		return err
+	}
+
+	err := latest(fakeDrand(t, key, 101, key.sign(100, nil), nil), key.public())
+	assert.ErrorIs(t, err, errBadSignature, "signature of another round")
+	err = latest(fakeDrand(t, key, 101, other.sign(101, nil), nil), key.public())
+	assert.ErrorIs(t, err, errBadSignature, "signature under another key")
+	err = latest(fakeDrand(t, key, 101, bytes.Repeat([]byte{7}, 48), nil), key.public())
+	assert.ErrorIs(t, err, errBadSignature, "not a point")
+	err = latest(fakeDrand(t, other, 101, other.sign(101, nil), nil), key.public())
+	assert.ErrorContains(t, err, "not the configured one")
+	err = latest(fakeDrand(t, key, 101, key.sign(101, nil), nil), "")
+	assert.ErrorContains(t, err, "public key must be provided")
+
+	chained := newChainKey(t, schemeChained)
+	err = latest(fakeDrand(t, chained, 101, chained.sign(101, []byte("prev")), []byte("other")), chained.public())
+	assert.ErrorIs(t, err, errBadSignature, "chained to another round")
+}
+
+func TestDrandRejectsWrongChain(t *testing.T) {
+	key := newChainKey(t, schemeQuicknet)
+	srv := fakeDrand(t, key, 101, key.sign(101, nil), nil)
+	d := NewDrand(DrandConfig{URL: srv.URL, ChainHash: "00" + chainHash[2:], PublicKey: key.public()})
+	_, err := d.Latest(context.Background())
+	assert.Error(t, err)
+}
+
+func TestSessionMixesAndRecordsRound(t *testing.T) {
+	now := time.Now()
+	var records []*Record
+	base := bytes.Repeat([]byte{1}, 1024)
+	newSource := func(randomness []byte) *Source {
+		s, err := New(Config{
+			Beacon: &fixedBeacon{round: &Round{Number: 9, Time: now.Add(-2 * time.Second), Randomness: randomness}},
+			Audit:  AuditLogFunc(func(r *Record) error { records = append(records, r); return nil }),
+			Rand:   bytes.NewReader(base),
+			Now:    func() time.Time { return now },
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	rng, rec, err := newSource([]byte("round 9")).Session(context.Background(), "session-1")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), rec.Round)
+	assert.Equal(t, []byte("round 9"), rec.Randomness)
+	assert.Empty(t, rec.Err)
+	require.Len(t, records, 1)
+	assert.Same(t, rec, records[0])
+	first := make([]byte, 64)
+	_, err = io.ReadFull(rng, first)
+	require.NoError(t, err)
+
+	// With the same system RNG output, a different round gives different
+	// session randomness.
+	rng, _, err = newSource([]byte("round 10")).Session(context.Background(), "session-1")
+	require.NoError(t, err)
+	second := make([]byte, 64)
+	_, err = io.ReadFull(rng, second)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestSessionRejectsStaleRound(t *testing.T) {
+	now := time.Now()
+	stale := &fixedBeacon{round: &Round{Number: 9, Time: now.Add(-time.Hour), Randomness: []byte("old")}}
+	var records []*Record
+	audit := AuditLogFunc(func(r *Record) error { records = append(records, r); return nil })
+
+	s, err := New(Config{Beacon: stale, Audit: audit, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	_, _, err = s.Session(context.Background(), "session-1")
+	assert.ErrorIs(t, err, ErrStale)
+	assert.Empty(t, records)
+
+	s, err = New(Config{Beacon: stale, Audit: audit, FailOpen: true, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	rng, rec, err := s.Session(context.Background(), "session-1")
+	require.NoError(t, err)
+	assert.NotNil(t, rng)
+	assert.Zero(t, rec.Round)
+	assert.Contains(t, rec.Err, "stale")
+	assert.Len(t, records, 1)
+}
+
+func TestSessionFailsWithoutAuditRecord(t *testing.T) {
+	s, err := New(Config{
+		Beacon:   &fixedBeacon{err: fmt.Errorf("unreachable")},
+		Audit:    AuditLogFunc(func(*Record) error { return errors.New("disk full") }),
+		FailOpen: true,
+	})
+	require.NoError(t, err)
+	_, _, err = s.Session(context.Background(), "session-1")
+	assert.ErrorContains(t, err, "disk full")
+}
+
+func TestSignerSeedsEachSession(t *testing.T) {
+	now := time.Now()
+	var records []*Record
+	s, err := New(Config{
+		Beacon: &fixedBeacon{round: &Round{Number: 9, Time: now, Randomness: []byte("round 9")}},
+		Audit:  AuditLogFunc(func(r *Record) error { records = append(records, r); return nil }),
+		Now:    func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	var seeds [][]byte
+	seed := func(b []byte) error { seeds = append(seeds, bytes.Clone(b)); return nil }
+	var order []string
+	signer := s.Signer(seed, coordinatorSigner(func(req *coordinator.SignRequest) {
+		order = append(order, fmt.Sprintf("sign %s after %d seeds", req.Session, len(seeds)))
+	}))
+
+	_, err = signer.Sign(context.Background(), &coordinator.SignRequest{Session: "session-1"})
+	require.NoError(t, err)
+	_, err = signer.Sign(context.Background(), &coordinator.SignRequest{Session: "session-2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sign session-1 after 1 seeds", "sign session-2 after 2 seeds"}, order)
+	require.Len(t, seeds, 2)
+	assert.Len(t, seeds[0], seedSize)
+	assert.NotEqual(t, seeds[0], seeds[1])
+	require.Len(t, records, 2)
+	assert.Equal(t, "session-2", records[1].Session)
+
+	failing := s.Signer(func([]byte) error { return errors.New("no native RNG") }, coordinatorSigner(func(*coordinator.SignRequest) {
+		t.Fatal("signed without seeding")
+	}))
+	_, err = failing.Sign(context.Background(), &coordinator.SignRequest{Session: "session-3"})
+	assert.ErrorContains(t, err, "no native RNG")
+}
+
+// coordinatorSigner is a coordinator.Signer that calls f and returns an
+// empty signature.
+type coordinatorSigner func(req *coordinator.SignRequest)
+
+func (f coordinatorSigner) Sign(_ context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	f(req)
+	return []byte{}, nil
+}
+
+func TestFileLog(t *testing.T) {
+	l := &FileLog{Path: filepath.Join(t.TempDir(), "beacon.jsonl")}
+	require.NoError(t, l.Append(&Record{Session: "session-1", Round: 9}))
+	require.NoError(t, l.Append(&Record{Session: "session-2", Round: 10}))
+
+	data, err := os.ReadFile(l.Path)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 2)
+	var rec Record
+	require.NoError(t, json.Unmarshal(lines[1], &rec))
+	assert.Equal(t, "session-2", rec.Session)
+	assert.Equal(t, uint64(10), rec.Round)
+}
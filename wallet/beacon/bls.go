@@ -0,0 +1,91 @@
+package beacon
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	bls "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+// The drand signature schemes, as named by the schemeID of a chain's info.
+const (
+	schemeChained   = "pedersen-bls-chained"
+	schemeUnchained = "pedersen-bls-unchained"
+	schemeQuicknet  = "bls-unchained-g1-rfc9380"
+)
+
+// Hash-to-curve domains of the schemes: signatures on G2, and on G1.
+var (
+	dstG2 = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_")
+	dstG1 = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_")
+)
+
+// errBadSignature is returned when a round's signature does not verify under
+// the chain's public key.
+var errBadSignature = errors.New("signature does not verify under the chain key")
+
+// verifier checks round signatures of one drand chain.
+type verifier struct {
+	scheme string
+	g1Key  *bls.G1 // Schemes with signatures on G2
+	g2Key  *bls.G2 // Schemes with signatures on G1
+}
+
+// newVerifier parses the group public key of a chain with the given scheme.
+func newVerifier(scheme string, publicKey []byte) (*verifier, error) {
+	v := &verifier{scheme: scheme}
+	switch scheme {
+	case schemeChained, schemeUnchained:
+		v.g1Key = new(bls.G1)
+		if err := v.g1Key.SetBytes(publicKey); err != nil || v.g1Key.IsIdentity() {
+			return nil, fmt.Errorf("invalid %s public key", scheme)
+		}
+	case schemeQuicknet:
+		v.g2Key = new(bls.G2)
+		if err := v.g2Key.SetBytes(publicKey); err != nil || v.g2Key.IsIdentity() {
+			return nil, fmt.Errorf("invalid %s public key", scheme)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported drand scheme %q", scheme)
+	}
+	return v, nil
+}
+
+// verify checks the signature of round.  Chained schemes sign the previous
+// round's signature followed by the round number, the others the round
+// number alone.
+func (v *verifier) verify(round uint64, sig, previous []byte) error {
+	var num [8]byte
+	binary.BigEndian.PutUint64(num[:], round)
+	h := sha256.New()
+	if v.scheme == schemeChained {
+		h.Write(previous)
+	}
+	h.Write(num[:])
+	msg := h.Sum(nil)
+
+	var ok bool
+	if v.g1Key != nil {
+		var s, m bls.G2
+		if err := s.SetBytes(sig); err != nil || s.IsIdentity() {
+			return errBadSignature
+		}
+		m.Hash(msg, dstG2)
+		// e(key, H(m)) = e(g1, sig)
+		ok = bls.ProdPairFrac([]*bls.G1{v.g1Key, bls.G1Generator()}, []*bls.G2{&m, &s}, []int{1, -1}).IsIdentity()
+	} else {
+		var s, m bls.G1
+		if err := s.SetBytes(sig); err != nil || s.IsIdentity() {
+			return errBadSignature
+		}
+		m.Hash(msg, dstG1)
+		// e(H(m), key) = e(sig, g2)
+		ok = bls.ProdPairFrac([]*bls.G1{&m, &s}, []*bls.G2{v.g2Key, bls.G2Generator()}, []int{1, -1}).IsIdentity()
+	}
+	if !ok {
+		return errBadSignature
+	}
+	return nil
+}
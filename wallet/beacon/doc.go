@@ -0,0 +1,35 @@
+// Package beacon mixes a public randomness beacon such as drand into the
+// randomness of a party's signing sessions, as independent evidence that the
+// session randomness was not fixed in advance.
+//
+// A beacon publishes an unpredictable value per round at fixed times.
+// `Source.Session` fetches the latest round, checks that it is recent, and
+// returns an RNG that mixes the system RNG with the session ID and the
+// round's randomness (see ceremony.Mixer), together with a `Record` of the
+// round that is appended to the audit log:
+//
+//	src, _ := beacon.New(beacon.Config{
+//	    Beacon: beacon.NewDrand(beacon.DrandConfig{URL: "https://api.drand.sh", ChainHash: quicknet, PublicKey: quicknetKey}),
+//	    Audit:  auditLog,
+//	})
+//	signer := src.Signer(mpc.SeedRandom, partySigner)
+//
+// `Source.Signer` wraps a party's coordinator.Signer: before each signature
+// it draws from the RNG of the request's session and mixes the output into
+// the RNG of the native library with mpc.SeedRandom, the RNG from which the
+// MPC protocols draw their nonces.  `Source.Seed` does the same for other
+// protocols, and `Source.Session` hands out the RNG itself.
+//
+// Because the round's value did not exist before its publication time, an
+// auditor comparing the recorded round with the session's timestamps can
+// confirm that the randomness mixed into the session was not fixed before
+// then.  The mix never makes the output weaker than the system RNG alone,
+// and mpc.SeedRandom only adds to the native library's own entropy, so a
+// malicious or broken beacon cannot bias either.
+//
+// Drand verifies each round's BLS signature under the chain's group public
+// key, which must be configured from a trusted source, and that the
+// randomness is the SHA-256 of the signature.  Chains of the
+// pedersen-bls-chained, pedersen-bls-unchained and bls-unchained-g1-rfc9380
+// (quicknet) schemes are supported.
+package beacon
@@ -0,0 +1,168 @@
+package beacon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxDrandResponse = 1 << 16
+
+// DrandConfig contains the configuration for a Drand beacon.
+type DrandConfig struct {
+	// URL is the HTTP API endpoint, e.g. https://api.drand.sh.
+	URL string
+	// ChainHash is the hex hash of the drand chain to follow.
+	ChainHash string
+	// PublicKey is the hex group public key of the chain, against which
+	// every round's BLS signature is verified.  It must come from a trusted
+	// source, not from the endpoint.  Required.
+	PublicKey string
+	// Client makes the requests.  Defaults to a client with a 10 second
+	// timeout.
+	Client *http.Client
+}
+
+// Drand reads rounds from the HTTP API of a drand network.
+type Drand struct {
+	config DrandConfig
+
+	mu       sync.Mutex
+	genesis  time.Time
+	period   time.Duration
+	verifier *verifier
+}
+
+// Ensure Drand implements the Beacon interface
+var _ Beacon = (*Drand)(nil)
+
+// NewDrand creates a Drand beacon from the given configuration.
+func NewDrand(config DrandConfig) *Drand {
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	return &Drand{config: config}
+}
+
+// Name implements Beacon.
+func (d *Drand) Name() string { return "drand:" + d.config.ChainHash }
+
+// Latest implements Beacon.  It verifies the round's BLS signature under
+// DrandConfig.PublicKey, checks that the randomness is the SHA-256 of the
+// signature and derives the round time from the chain's genesis time and
+// period.
+func (d *Drand) Latest(ctx context.Context) (*Round, error) {
+	genesis, period, v, err := d.info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Round             uint64 `json:"round"`
+		Randomness        string `json:"randomness"`
+		Signature         string `json:"signature"`
+		PreviousSignature string `json:"previous_signature"`
+	}
+	if err := d.get(ctx, "/public/latest", &resp); err != nil {
+		return nil, err
+	}
+	randomness, err := hex.DecodeString(resp.Randomness)
+	if err != nil {
+		return nil, fmt.Errorf("decoding randomness: %v", err)
+	}
+	sig, err := hex.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("decoding signature: %v", err)
+	}
+	previous, err := hex.DecodeString(resp.PreviousSignature)
+	if err != nil {
+		return nil, fmt.Errorf("decoding previous signature: %v", err)
+	}
+	if resp.Round == 0 {
+		return nil, fmt.Errorf("drand returned round 0")
+	}
+	if err := v.verify(resp.Round, sig, previous); err != nil {
+		return nil, fmt.Errorf("drand round %d: %w", resp.Round, err)
+	}
+	if sum := sha256.Sum256(sig); !bytes.Equal(sum[:], randomness) {
+		return nil, fmt.Errorf("drand round %d: randomness does not match signature", resp.Round)
+	}
+	return &Round{
+		Number:     resp.Round,
+		Time:       genesis.Add(time.Duration(resp.Round-1) * period),
+		Randomness: randomness,
+		Signature:  sig,
+	}, nil
+}
+
+// info returns the chain's genesis time, period and signature verifier,
+// fetching the chain info once.  The chain must have the configured public
+// key.
+func (d *Drand) info(ctx context.Context) (time.Time, time.Duration, *verifier, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.verifier != nil {
+		return d.genesis, d.period, d.verifier, nil
+	}
+	publicKey, err := hex.DecodeString(d.config.PublicKey)
+	if err != nil || len(publicKey) == 0 {
+		return time.Time{}, 0, nil, fmt.Errorf("drand public key must be provided in hex")
+	}
+	var resp struct {
+		PublicKey   string `json:"public_key"`
+		Period      int64  `json:"period"`
+		GenesisTime int64  `json:"genesis_time"`
+		Hash        string `json:"hash"`
+		SchemeID    string `json:"schemeID"`
+	}
+	if err := d.get(ctx, "/info", &resp); err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	if !strings.EqualFold(resp.Hash, d.config.ChainHash) {
+		return time.Time{}, 0, nil, fmt.Errorf("drand endpoint serves chain %s, not %s", resp.Hash, d.config.ChainHash)
+	}
+	if !strings.EqualFold(resp.PublicKey, d.config.PublicKey) {
+		return time.Time{}, 0, nil, fmt.Errorf("drand chain %s has public key %s, not the configured one", resp.Hash, resp.PublicKey)
+	}
+	if resp.Period <= 0 {
+		return time.Time{}, 0, nil, fmt.Errorf("drand chain has invalid period %d", resp.Period)
+	}
+	v, err := newVerifier(resp.SchemeID, publicKey)
+	if err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	d.genesis, d.period = time.Unix(resp.GenesisTime, 0), time.Duration(resp.Period)*time.Second
+	d.verifier = v
+	return d.genesis, d.period, d.verifier, nil
+}
+
+func (d *Drand) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.config.URL+"/"+d.config.ChainHash+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("drand request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDrandResponse))
+	if err != nil {
+		return fmt.Errorf("reading drand response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("drand %s: status %d", path, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decoding drand response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package beacon
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// seedSize is how many bytes Seed draws from a session's RNG.
+const seedSize = 64
+
+// Seed draws from the RNG of session id and passes the output to seed, which
+// mixes it into another RNG: mpc.SeedRandom for the native library, from
+// which the MPC protocols draw their nonces.  The session's record is
+// appended to the audit log first.
+func (s *Source) Seed(ctx context.Context, id string, seed func([]byte) error) (*Record, error) {
+	rng, rec, err := s.Session(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, seedSize)
+	defer clear(buf)
+	if _, err := io.ReadFull(rng, buf); err != nil {
+		return nil, fmt.Errorf("drawing session randomness: %w", err)
+	}
+	if err := seed(buf); err != nil {
+		return nil, fmt.Errorf("seeding with session randomness: %w", err)
+	}
+	return rec, nil
+}
+
+// Signer returns a coordinator.Signer that seeds with the randomness of each
+// request's session, as Seed does, before passing the request to next.  A
+// party host wraps its MPC signer with it, with mpc.SeedRandom as seed, so
+// that the beacon round of every signature is on record.
+func (s *Source) Signer(seed func([]byte) error, next coordinator.Signer) coordinator.Signer {
+	return &seedingSigner{source: s, seed: seed, next: next}
+}
+
+type seedingSigner struct {
+	source *Source
+	seed   func([]byte) error
+	next   coordinator.Signer
+}
+
+func (s *seedingSigner) Sign(ctx context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	if _, err := s.source.Seed(ctx, req.Session, s.seed); err != nil {
+		return nil, err
+	}
+	return s.next.Sign(ctx, req)
+}
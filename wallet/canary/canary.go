@@ -0,0 +1,235 @@
+package canary
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/client"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// messagePrefix starts every canary message.  0xff is not a valid first byte
+// of a Solana transaction message (version 127), so a canary signature can
+// never authorize a transaction.
+const messagePrefix = "\xffcb-mpc canary v1\n"
+
+// ErrFailed is returned by Run when the canary did not produce a valid
+// signature.
+var ErrFailed = errors.New("canary: failed")
+
+// Event is the key event a canary follows.
+type Event string
+
+// Key events.
+const (
+	EventKeygen   Event = "keygen"
+	EventRefresh  Event = "refresh"
+	EventRecovery Event = "recovery"
+)
+
+// Kind is what a canary signs.
+type Kind string
+
+// Canary kinds.
+const (
+	KindMessage  Kind = "message"
+	KindTransfer Kind = "transfer"
+)
+
+// TransferConfig configures a transfer canary.
+type TransferConfig struct {
+	Coordinator *coordinator.Coordinator
+	Chain       string // Identifier of the chain to transfer on
+	Address     string // Address of the key, both sender and recipient
+	// Amount is sent to Address.  Defaults to 1 base unit.
+	Amount *big.Int
+}
+
+// Config contains what Run needs.
+type Config struct {
+	KeyID string
+	Event Event
+	// Verifier checks signatures by the key.  Required.
+	Verifier client.Verifier
+	// Sign signs message with the key's new shares.  Required for a message
+	// canary.
+	Sign func(ctx context.Context, message []byte) ([]byte, error)
+	// Transfer, if set, makes the canary a transfer instead of a message.
+	Transfer *TransferConfig
+	// Log records the result.  Optional.
+	Log Log
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Result is the record of one canary.
+type Result struct {
+	ID         string    `json:"id"`
+	KeyID      string    `json:"key_id"`
+	Event      Event     `json:"event"`
+	Kind       Kind      `json:"kind"`
+	Message    []byte    `json:"message,omitempty"` // KindMessage
+	Session    string    `json:"session,omitempty"` // KindTransfer
+	TxID       string    `json:"tx_id,omitempty"`   // KindTransfer
+	Signature  []byte    `json:"signature,omitempty"`
+	OK         bool      `json:"ok"`
+	Err        string    `json:"err,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Log receives the result of every canary.
+type Log interface {
+	Append(r *Result) error
+}
+
+// LogFunc adapts an ordinary function to the Log interface.
+type LogFunc func(r *Result) error
+
+// Append calls f(r).
+func (f LogFunc) Append(r *Result) error { return f(r) }
+
+// FileLog is a Log that appends results to a file as JSON lines.
+type FileLog struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Ensure FileLog implements the Log interface
+var _ Log = (*FileLog)(nil)
+
+// Append implements Log.
+func (l *FileLog) Append(r *Result) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Message returns the canary message for a key event.  The nonce makes every
+// message unique, so that a recorded signature cannot pass for a later
+// canary.
+func Message(keyID string, event Event, nonce []byte, at time.Time) []byte {
+	return fmt.Appendf([]byte(messagePrefix), "key: %s\nevent: %s\nnonce: %x\ntime: %s\n",
+		keyID, event, nonce, at.UTC().Format(time.RFC3339))
+}
+
+// Run signs a canary with the key and verifies the signature, records the
+// result in config.Log and returns it.  If the canary failed, the result is
+// returned together with an error wrapping ErrFailed; an error recording the
+// result is returned on its own.
+func Run(ctx context.Context, config Config) (*Result, error) {
+	if config.KeyID == "" || config.Event == "" {
+		return nil, fmt.Errorf("key ID and event must be provided")
+	}
+	if config.Verifier == nil {
+		return nil, fmt.Errorf("verifier must be provided")
+	}
+	kind := KindMessage
+	if t := config.Transfer; t != nil {
+		if t.Coordinator == nil || t.Chain == "" || t.Address == "" {
+			return nil, fmt.Errorf("coordinator, chain and address must be provided")
+		}
+		kind = KindTransfer
+	} else if config.Sign == nil {
+		return nil, fmt.Errorf("sign must be provided")
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	r := &Result{
+		ID:        hex.EncodeToString(id),
+		KeyID:     config.KeyID,
+		Event:     config.Event,
+		Kind:      kind,
+		StartedAt: now().UTC(),
+	}
+	var err error
+	if kind == KindTransfer {
+		err = r.transfer(ctx, config.Transfer, config.Verifier)
+	} else {
+		r.Message = Message(config.KeyID, config.Event, id, r.StartedAt)
+		if r.Signature, err = config.Sign(ctx, r.Message); err == nil {
+			err = config.Verifier.Verify(r.Message, r.Signature)
+		}
+	}
+	r.OK = err == nil
+	if err != nil {
+		r.Err = err.Error()
+	}
+	r.FinishedAt = now().UTC()
+
+	if config.Log != nil {
+		if err := config.Log.Append(r); err != nil {
+			return nil, fmt.Errorf("recording canary: %w", err)
+		}
+	}
+	if !r.OK {
+		return r, fmt.Errorf("%w: %s %s canary for %s: %s", ErrFailed, config.Event, kind, config.KeyID, r.Err)
+	}
+	return r, nil
+}
+
+// transfer runs a self-transfer through the coordinator until it is final and
+// checks the signature it recorded.
+func (r *Result) transfer(ctx context.Context, t *TransferConfig, verifier client.Verifier) error {
+	amount := t.Amount
+	if amount == nil {
+		amount = big.NewInt(1)
+	}
+	s, err := t.Coordinator.Submit(ctx, &coordinator.Request{
+		Chain:     t.Chain,
+		Transfer:  chain.Transfer{From: t.Address, To: t.Address, Amount: amount},
+		Priority:  coordinator.PriorityUrgent,
+		Reference: "canary/" + r.ID,
+	})
+	if err != nil {
+		return err
+	}
+	r.Session = s.ID
+	if s, err = t.Coordinator.Run(ctx, s.ID); err != nil {
+		return err
+	}
+	r.TxID, r.Signature = s.TxID, s.Signature
+	switch s.State {
+	case coordinator.StateFinalized:
+	case coordinator.StateFailed:
+		return fmt.Errorf("session failed: %s", s.Err)
+	default:
+		return fmt.Errorf("session is %s, not finalized", s.State)
+	}
+	if s.Unsigned == nil {
+		return fmt.Errorf("session has no transaction")
+	}
+	return verifier.Verify(s.Unsigned.SigningPayload, s.Signature)
+}
@@ -0,0 +1,186 @@
+package canary
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/client"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+var epoch = time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+
+// fakeChain decodes the transfer it built and finalizes everything.
+type fakeChain struct {
+	built *chain.Transfer
+}
+
+func (f *fakeChain) ID() string { return "fake" }
+
+func (f *fakeChain) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (f *fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	f.built = t
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: []byte("tx"), SigningPayload: []byte("tx")}, nil
+}
+
+func (f *fakeChain) Decode([]byte) (*chain.Summary, error) {
+	t := f.built
+	return &chain.Summary{Chain: f.ID(), From: t.From, To: t.To, Amount: t.Amount, Fee: big.NewInt(5000)}, nil
+}
+
+func (f *fakeChain) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (f *fakeChain) Broadcast(context.Context, *chain.SignedTx) (string, error) { return "tx-1", nil }
+
+func (f *fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized, Height: 42}, nil
+}
+
+// signerFunc signs with key, standing in for the MPC parties.
+type signerFunc func(ctx context.Context, req *coordinator.SignRequest) ([]byte, error)
+
+func (f signerFunc) Sign(ctx context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	return f(ctx, req)
+}
+
+func newKey(t *testing.T) (ed25519.PrivateKey, client.Verifier) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return priv, client.Ed25519(pub)
+}
+
+func TestMessageCanary(t *testing.T) {
+	priv, verifier := newKey(t)
+	var logged []*Result
+	r, err := Run(context.Background(), Config{
+		KeyID:    "treasury",
+		Event:    EventRefresh,
+		Verifier: verifier,
+		Sign: func(_ context.Context, message []byte) ([]byte, error) {
+			return ed25519.Sign(priv, message), nil
+		},
+		Log: LogFunc(func(r *Result) error { logged = append(logged, r); return nil }),
+		Now: func() time.Time { return epoch },
+	})
+	require.NoError(t, err)
+	assert.True(t, r.OK)
+	assert.Equal(t, KindMessage, r.Kind)
+	assert.Equal(t, byte(0xff), r.Message[0], "never a transaction")
+	assert.Contains(t, string(r.Message), "key: treasury\nevent: refresh\n")
+	assert.Contains(t, string(r.Message), r.ID, "the message carries a fresh nonce")
+	assert.Equal(t, []*Result{r}, logged)
+}
+
+func TestMessageCanaryFails(t *testing.T) {
+	_, verifier := newKey(t)
+	other, _ := newKey(t)
+	var logged []*Result
+	config := Config{
+		KeyID:    "treasury",
+		Event:    EventRecovery,
+		Verifier: verifier,
+		Sign: func(_ context.Context, message []byte) ([]byte, error) {
+			return ed25519.Sign(other, message), nil // Shares of another key
+		},
+		Log: LogFunc(func(r *Result) error { logged = append(logged, r); return nil }),
+	}
+	r, err := Run(context.Background(), config)
+	assert.ErrorIs(t, err, ErrFailed)
+	require.NotNil(t, r)
+	assert.False(t, r.OK)
+	assert.Equal(t, client.ErrBadSignature.Error(), r.Err)
+
+	config.Sign = func(context.Context, []byte) ([]byte, error) { return nil, errors.New("party kms unreachable") }
+	r, err = Run(context.Background(), config)
+	assert.ErrorIs(t, err, ErrFailed)
+	assert.Equal(t, "party kms unreachable", r.Err)
+	assert.Len(t, logged, 2, "failures are recorded too")
+
+	config.Log = LogFunc(func(*Result) error { return errors.New("disk full") })
+	_, err = Run(context.Background(), config)
+	assert.ErrorContains(t, err, "disk full")
+	assert.NotErrorIs(t, err, ErrFailed)
+}
+
+func TestTransferCanary(t *testing.T) {
+	priv, verifier := newKey(t)
+	ch := &fakeChain{}
+	c, err := coordinator.New(coordinator.Config{
+		Store:  coordinator.NewMemoryStore(),
+		Chains: []chain.Chain{ch},
+		Signer: signerFunc(func(_ context.Context, req *coordinator.SignRequest) ([]byte, error) {
+			return ed25519.Sign(priv, req.Payload), nil
+		}),
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	r, err := Run(context.Background(), Config{
+		KeyID:    "treasury",
+		Event:    EventKeygen,
+		Verifier: verifier,
+		Transfer: &TransferConfig{Coordinator: c, Chain: "fake", Address: "treasury"},
+	})
+	require.NoError(t, err)
+	assert.True(t, r.OK)
+	assert.Equal(t, KindTransfer, r.Kind)
+	assert.Equal(t, "tx-1", r.TxID)
+	assert.NotEmpty(t, r.Session)
+	assert.Equal(t, &chain.Transfer{From: "treasury", To: "treasury", Amount: big.NewInt(1)}, ch.built)
+
+	// The deployed shares are not the key's.
+	other, _ := newKey(t)
+	priv = other
+	r, err = Run(context.Background(), Config{
+		KeyID:    "treasury",
+		Event:    EventKeygen,
+		Verifier: verifier,
+		Transfer: &TransferConfig{Coordinator: c, Chain: "fake", Address: "treasury"},
+	})
+	assert.ErrorIs(t, err, ErrFailed)
+	assert.False(t, r.OK)
+}
+
+func TestFileLog(t *testing.T) {
+	priv, verifier := newKey(t)
+	log := &FileLog{Path: filepath.Join(t.TempDir(), "canary.log")}
+	for _, event := range []Event{EventKeygen, EventRefresh} {
+		_, err := Run(context.Background(), Config{
+			KeyID:    "treasury",
+			Event:    event,
+			Verifier: verifier,
+			Sign: func(_ context.Context, message []byte) ([]byte, error) {
+				return ed25519.Sign(priv, message), nil
+			},
+			Log: log,
+		})
+		require.NoError(t, err)
+	}
+
+	f, err := os.Open(log.Path)
+	require.NoError(t, err)
+	defer f.Close()
+	var events []Event
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var r Result
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &r))
+		assert.True(t, r.OK)
+		events = append(events, r.Event)
+	}
+	assert.Equal(t, []Event{EventKeygen, EventRefresh}, events)
+}
@@ -0,0 +1,28 @@
+// Package canary checks that a key's shares actually sign after a key event
+// – key generation, refresh or recovery – before real funds depend on them.
+//
+// A canary is a tiny signature made with the new share set and verified
+// against the key, end to end:
+//
+//   - A message canary signs a `Message` naming the key, the event and a
+//     fresh nonce through Config.Sign, e.g. by running the MPC signing
+//     protocol with the shares just produced on the ceremony host.  The
+//     message starts with 0xff, which no Solana transaction message does, so
+//     it can never be broadcast.
+//   - A transfer canary sends the smallest amount from the wallet to itself
+//     as an ordinary coordinator session, so it also exercises the deployed
+//     party hosts, the transport and the chain.  Policy must let such
+//     self-transfers through without approval.
+//
+// A message canary after a refresh:
+//
+//	res, err := canary.Run(ctx, canary.Config{
+//	    KeyID: "treasury", Event: canary.EventRefresh,
+//	    Verifier: verifier, Sign: signWithFreshShares,
+//	    Log: &canary.FileLog{Path: "canary.log"},
+//	})
+//
+// Every run, passed or failed, is recorded as a `Result` in the `Log`, so
+// operators have evidence that the share set worked at the time; a failed
+// canary also returns an error wrapping ErrFailed.
+package canary
@@ -0,0 +1,316 @@
+package ceremony
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+)
+
+// ErrNotConfirmed is returned when an operator declines a step.
+var ErrNotConfirmed = errors.New("ceremony: step not confirmed")
+
+// minOperators is the number of distinct operators required for dual control.
+const minOperators = 2
+
+// KeyMaterial is the output of a distributed key generation.
+type KeyMaterial struct {
+	PublicKey []byte            // Group public key in the curve's canonical encoding
+	Shares    map[string][]byte // Serialized key share per party name
+}
+
+// KeyGenerator runs the distributed key generation.  rand is the health-checked
+// ceremony RNG with any external entropy mixed in; generators whose DKG draws
+// from its own RNG must seed that RNG from rand, e.g. with mpc.SeedRandom for
+// the native library, or the contributions never reach the key.
+type KeyGenerator interface {
+	Generate(ctx context.Context, params Params, rand io.Reader) (*KeyMaterial, error)
+}
+
+// KeyGeneratorFunc adapts an ordinary function to the KeyGenerator interface.
+type KeyGeneratorFunc func(ctx context.Context, params Params, rand io.Reader) (*KeyMaterial, error)
+
+// Generate calls f(ctx, params, rand).
+func (f KeyGeneratorFunc) Generate(ctx context.Context, params Params, rand io.Reader) (*KeyMaterial, error) {
+	return f(ctx, params, rand)
+}
+
+// Prompt is what an operator is asked to confirm.
+type Prompt struct {
+	Step     string // Step name
+	Operator string // Operator being asked
+	Summary  string // What is being confirmed
+}
+
+// Confirmer obtains an operator's confirmation, interactively or from a
+// script.  It returns nil to confirm and an error to decline.
+type Confirmer interface {
+	Confirm(ctx context.Context, prompt Prompt) error
+}
+
+// ConfirmerFunc adapts an ordinary function to the Confirmer interface.
+type ConfirmerFunc func(ctx context.Context, prompt Prompt) error
+
+// Confirm calls f(ctx, prompt).
+func (f ConfirmerFunc) Confirm(ctx context.Context, prompt Prompt) error {
+	return f(ctx, prompt)
+}
+
+// Config contains the configuration for a Ceremony.
+type Config struct {
+	// Params describes the key to create.
+	Params Params
+	// Operators are the people who must each confirm every critical step.
+	// At least two distinct operators are required.
+	Operators []string
+	// Confirmer collects operator confirmations.
+	Confirmer Confirmer
+	// Generator runs the DKG.
+	Generator KeyGenerator
+	// Backups receives one backup per party; each backup is read back and
+	// compared against the generated share.
+	Backups keystore.Medium
+	// ReportKey signs the ceremony report.
+	ReportKey ed25519.PrivateKey
+	// Entropy is the RNG checked before key generation.  Defaults to
+	// crypto/rand.Reader.
+	Entropy io.Reader
+	// Contributors supply external entropy that is mixed into the RNG handed
+	// to the Generator.  Each contribution is recorded in the report.
+	Contributors []EntropyContributor
+	// Guide, if set, receives the instructions of each step before it runs.
+	Guide func(step, instructions string)
+}
+
+// Ceremony is a guided, scriptable key ceremony.
+type Ceremony struct {
+	config Config
+	report Report
+	rand   io.Reader // Ceremony RNG, set by the entropy step
+}
+
+// New validates the configuration and prepares a ceremony.
+func New(config Config) (*Ceremony, error) {
+	p := config.Params
+	if p.KeyID == "" || p.Curve == "" {
+		return nil, fmt.Errorf("key ID and curve must be provided")
+	}
+	if p.Threshold < 1 || p.Threshold > len(p.Parties) {
+		return nil, fmt.Errorf("threshold %d out of range for %d parties", p.Threshold, len(p.Parties))
+	}
+	if distinct(p.Parties) != len(p.Parties) {
+		return nil, fmt.Errorf("party names must be distinct")
+	}
+	if distinct(config.Operators) < minOperators || distinct(config.Operators) != len(config.Operators) {
+		return nil, fmt.Errorf("at least %d distinct operators are required", minOperators)
+	}
+	if config.Confirmer == nil || config.Generator == nil || config.Backups == nil {
+		return nil, fmt.Errorf("confirmer, generator and backups must be provided")
+	}
+	if len(config.ReportKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid report signing key")
+	}
+	if config.Entropy == nil {
+		config.Entropy = rand.Reader
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	return &Ceremony{
+		config: config,
+		report: Report{
+			ID:        p.KeyID + "-" + hex.EncodeToString(id[:]),
+			Params:    p,
+			Operators: config.Operators,
+		},
+	}, nil
+}
+
+// Run performs the ceremony and returns the signed report together with the
+// generated key material, which the caller distributes to the parties.  Apart
+// from that, shares are only written to the backup medium.
+func (c *Ceremony) Run(ctx context.Context) (*SignedReport, *KeyMaterial, error) {
+	c.report.StartedAt = time.Now().UTC()
+	p := c.config.Params
+
+	if err := c.step(ctx, "parameters",
+		"Verify the key parameters with all operators present.",
+		fmt.Sprintf("create %s key %q, %d-of-%d over parties %v", p.Curve, p.KeyID, p.Threshold, len(p.Parties), p.Parties),
+		true, nil); err != nil {
+		return nil, nil, err
+	}
+
+	if err := c.step(ctx, "entropy",
+		"Check the random number generator and mix in external entropy.",
+		"RNG health checks", false, func() (string, error) { return c.entropy(ctx) }); err != nil {
+		return nil, nil, err
+	}
+
+	var km *KeyMaterial
+	if err := c.step(ctx, "keygen",
+		"Run the distributed key generation.",
+		"generate key shares", true, func() (string, error) {
+			var err error
+			if km, err = c.config.Generator.Generate(ctx, p, c.rand); err != nil {
+				return "", err
+			}
+			if len(km.PublicKey) == 0 || len(km.Shares) != len(p.Parties) {
+				return "", fmt.Errorf("key generation returned %d shares for %d parties", len(km.Shares), len(p.Parties))
+			}
+			c.report.PublicKey = km.PublicKey
+			return "public key " + hex.EncodeToString(km.PublicKey), nil
+		}); err != nil {
+		return nil, nil, err
+	}
+
+	if err := c.step(ctx, "backup",
+		"Write a backup of every share and read it back.",
+		"back up and verify all shares", true, func() (string, error) {
+			for _, party := range p.Parties {
+				rec, err := c.backup(ctx, party, km.Shares[party])
+				if err != nil {
+					return "", err
+				}
+				c.report.Shares = append(c.report.Shares, *rec)
+			}
+			return fmt.Sprintf("%d backups verified", len(p.Parties)), nil
+		}); err != nil {
+		return nil, nil, err
+	}
+
+	c.report.FinishedAt = time.Now().UTC()
+	signed, err := signReport(&c.report, c.config.ReportKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signed, km, nil
+}
+
+// entropy checks the system RNG, mixes in external contributions and checks
+// the result.
+func (c *Ceremony) entropy(ctx context.Context) (string, error) {
+	if err := c.checkEntropy("system", c.config.Entropy); err != nil {
+		return "", err
+	}
+	c.rand = c.config.Entropy
+	if len(c.config.Contributors) == 0 {
+		return "passed", nil
+	}
+
+	contributions := make([][]byte, 0, len(c.config.Contributors))
+	defer func() {
+		for _, b := range contributions {
+			clear(b)
+		}
+	}()
+	for _, contributor := range c.config.Contributors {
+		b, err := contributor.Contribute(ctx)
+		if err != nil {
+			return "", fmt.Errorf("entropy from %s: %w", contributor.Name(), err)
+		}
+		if len(b) == 0 {
+			return "", fmt.Errorf("entropy from %s is empty", contributor.Name())
+		}
+		contributions = append(contributions, b)
+		sum := sha256.Sum256(b)
+		c.report.Mixing = append(c.report.Mixing, MixRecord{Source: contributor.Name(), Bytes: len(b), SHA256: sum[:]})
+	}
+	mixer, err := NewMixer(c.config.Entropy, contributions...)
+	if err != nil {
+		return "", err
+	}
+	if err := c.checkEntropy("mixed", mixer); err != nil {
+		return "", err
+	}
+	c.rand = mixer
+	return fmt.Sprintf("passed, %d external contributions mixed", len(contributions)), nil
+}
+
+// checkEntropy runs the health checks on r and records the result.
+func (c *Ceremony) checkEntropy(source string, r io.Reader) error {
+	res, err := CheckEntropy(source, r, 0)
+	if err != nil {
+		return err
+	}
+	c.report.Entropy = append(c.report.Entropy, *res)
+	if !res.Passed {
+		return fmt.Errorf("RNG health check failed: %+v", *res)
+	}
+	return nil
+}
+
+// step shows the instructions, runs fn and, if confirm is set, asks every
+// operator to confirm the outcome.
+func (c *Ceremony) step(ctx context.Context, name, instructions, summary string, confirm bool, fn func() (string, error)) error {
+	if c.config.Guide != nil {
+		c.config.Guide(name, instructions)
+	}
+	if fn != nil {
+		outcome, err := fn()
+		if err != nil {
+			return fmt.Errorf("step %s: %w", name, err)
+		}
+		summary += ": " + outcome
+	}
+	rec := StepRecord{Name: name, Summary: summary}
+	if confirm {
+		for _, op := range c.config.Operators {
+			if err := c.config.Confirmer.Confirm(ctx, Prompt{Step: name, Operator: op, Summary: summary}); err != nil {
+				return fmt.Errorf("step %s: %w by %s: %v", name, ErrNotConfirmed, op, err)
+			}
+			rec.Confirmations = append(rec.Confirmations, Confirmation{Operator: op, At: time.Now().UTC()})
+		}
+	}
+	rec.CompletedAt = time.Now().UTC()
+	c.report.Steps = append(c.report.Steps, rec)
+	return nil
+}
+
+// backup stores a share and verifies the stored copy.
+func (c *Ceremony) backup(ctx context.Context, party string, share []byte) (*ShareRecord, error) {
+	if len(share) == 0 {
+		return nil, fmt.Errorf("missing share for party %s", party)
+	}
+	id := c.report.ID + "." + party
+	if err := c.config.Backups.Store(ctx, id, share); err != nil {
+		return nil, fmt.Errorf("backing up share of %s: %w", party, err)
+	}
+	restored, err := c.config.Backups.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("reading back share of %s: %w", party, err)
+	}
+	if !bytes.Equal(restored, share) {
+		return nil, fmt.Errorf("backup of %s does not match the generated share", party)
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &ShareRecord{
+		Party:          party,
+		Salt:           salt,
+		Commitment:     commitShare(salt, share),
+		Backup:         c.config.Backups.Name() + "/" + id,
+		BackupVerified: true,
+	}, nil
+}
+
+func distinct(names []string) int {
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		if n != "" {
+			seen[n] = true
+		}
+	}
+	return len(seen)
+}
@@ -0,0 +1,193 @@
+package ceremony
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fakeDKG = KeyGeneratorFunc(func(_ context.Context, p Params, _ io.Reader) (*KeyMaterial, error) {
+	km := &KeyMaterial{PublicKey: bytes.Repeat([]byte{0xAB}, 32), Shares: map[string][]byte{}}
+	for _, party := range p.Parties {
+		km.Shares[party] = []byte("share of " + party)
+	}
+	return km, nil
+})
+
+func testConfig(t *testing.T, confirmer Confirmer) Config {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	backups, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir(), Key: bytes.Repeat([]byte{7}, 32)})
+	require.NoError(t, err)
+	return Config{
+		Params:    Params{KeyID: "treasury", Curve: "ed25519", Parties: []string{"server", "kms", "pin"}, Threshold: 2},
+		Operators: []string{"alice", "bob"},
+		Confirmer: confirmer,
+		Generator: fakeDKG,
+		Backups:   backups,
+		ReportKey: key,
+	}
+}
+
+func TestShareRecordOfEarlierVersions(t *testing.T) {
+	sum := sha256.Sum256([]byte("share of server"))
+	r := &ShareRecord{Party: "server", SHA256: sum[:]}
+	assert.True(t, r.Matches([]byte("share of server")))
+	assert.False(t, r.Matches([]byte("share of kms")))
+	assert.False(t, (&ShareRecord{Party: "server"}).Matches(nil))
+}
+
+func TestCeremony(t *testing.T) {
+	var prompts []Prompt
+	confirmer := ConfirmerFunc(func(_ context.Context, p Prompt) error {
+		prompts = append(prompts, p)
+		return nil
+	})
+	c, err := New(testConfig(t, confirmer))
+	require.NoError(t, err)
+
+	report, km, err := c.Run(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, report.Verify())
+	assert.Len(t, km.Shares, 3)
+
+	// Three confirmed steps, each confirmed by both operators.
+	assert.Len(t, prompts, 6)
+	require.Len(t, report.Report.Steps, 4)
+	for _, step := range report.Report.Steps {
+		if step.Name != "entropy" {
+			assert.Len(t, step.Confirmations, 2, step.Name)
+		}
+	}
+	require.Len(t, report.Report.Entropy, 1)
+	assert.True(t, report.Report.Entropy[0].Passed)
+	require.Len(t, report.Report.Shares, 3)
+	for _, share := range report.Report.Shares {
+		assert.True(t, share.BackupVerified)
+		assert.Empty(t, share.SHA256, "no unsalted digest is published")
+		assert.True(t, share.Matches(km.Shares[share.Party]), share.Party)
+		assert.False(t, share.Matches([]byte("share of someone")), share.Party)
+	}
+	// Equal shares get unrelated commitments.
+	c, err = New(testConfig(t, confirmer))
+	require.NoError(t, err)
+	again, _, err := c.Run(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, report.Report.Shares[0].Commitment, again.Report.Shares[0].Commitment)
+
+	report.Report.Params.Threshold = 1
+	assert.ErrorIs(t, report.Verify(), ErrBadReportSignature)
+}
+
+func TestCeremonyOperatorDeclines(t *testing.T) {
+	confirmer := ConfirmerFunc(func(_ context.Context, p Prompt) error {
+		if p.Step == "keygen" && p.Operator == "bob" {
+			return errors.New("public key does not match display")
+		}
+		return nil
+	})
+	c, err := New(testConfig(t, confirmer))
+	require.NoError(t, err)
+	_, _, err = c.Run(context.Background())
+	assert.ErrorIs(t, err, ErrNotConfirmed)
+}
+
+func TestCeremonyRejectsBrokenRNG(t *testing.T) {
+	config := testConfig(t, ConfirmerFunc(func(context.Context, Prompt) error { return nil }))
+	config.Entropy = bytes.NewReader(make([]byte, 2*defaultEntropySample))
+	c, err := New(config)
+	require.NoError(t, err)
+	_, _, err = c.Run(context.Background())
+	assert.ErrorContains(t, err, "RNG health check failed")
+}
+
+func TestNewRequiresDualControl(t *testing.T) {
+	config := testConfig(t, ConfirmerFunc(func(context.Context, Prompt) error { return nil }))
+	config.Operators = []string{"alice", "alice"}
+	_, err := New(config)
+	assert.Error(t, err)
+}
+
+func TestCheckEntropy(t *testing.T) {
+	res, err := CheckEntropy("crypto/rand", rand.Reader, 0)
+	require.NoError(t, err)
+	assert.True(t, res.Passed)
+
+	counter := make([]byte, 2*4096)
+	for i := range counter {
+		counter[i] = byte(i)
+	}
+	res, err = CheckEntropy("counter", bytes.NewReader(counter), 4096)
+	require.NoError(t, err)
+	assert.False(t, res.Passed, "identical samples are not fresh")
+}
+
+func TestCeremonyMixesExternalEntropy(t *testing.T) {
+	config := testConfig(t, ConfirmerFunc(func(context.Context, Prompt) error { return nil }))
+	dice := strings.Repeat("31415 26535 ", 10)
+	config.Contributors = []EntropyContributor{
+		DiceContributor("dice", func(context.Context) (string, error) { return dice, nil }),
+		ReaderContributor("hsm", rand.Reader, 32),
+	}
+	var got io.Reader
+	config.Generator = KeyGeneratorFunc(func(ctx context.Context, p Params, r io.Reader) (*KeyMaterial, error) {
+		got = r
+		return fakeDKG(ctx, p, r)
+	})
+	c, err := New(config)
+	require.NoError(t, err)
+	report, _, err := c.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.IsType(t, &Mixer{}, got)
+	require.Len(t, report.Report.Mixing, 2)
+	assert.Equal(t, "dice", report.Report.Mixing[0].Source)
+	assert.Equal(t, 100, report.Report.Mixing[0].Bytes)
+	require.Len(t, report.Report.Entropy, 2)
+	assert.Equal(t, "mixed", report.Report.Entropy[1].Source)
+	assert.True(t, report.Report.Entropy[1].Passed)
+}
+
+func TestDiceContributorValidates(t *testing.T) {
+	for _, rolls := range []string{strings.Repeat("1", 99), strings.Repeat("7", 100)} {
+		_, err := DiceContributor("dice", func(context.Context) (string, error) { return rolls, nil }).Contribute(context.Background())
+		assert.Error(t, err)
+	}
+}
+
+func TestMixer(t *testing.T) {
+	zeros := func() io.Reader { return bytes.NewReader(make([]byte, 1<<20)) }
+
+	// A stuck base RNG is rescued by a good contribution.
+	seed := make([]byte, 32)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+	m, err := NewMixer(zeros(), seed)
+	require.NoError(t, err)
+	res, err := CheckEntropy("mixed", m, 4096)
+	require.NoError(t, err)
+	assert.True(t, res.Passed)
+
+	// The output depends on every contribution.
+	a, err := NewMixer(zeros(), []byte("a"), []byte("b"))
+	require.NoError(t, err)
+	b, err := NewMixer(zeros(), []byte("ab"))
+	require.NoError(t, err)
+	outA, outB := make([]byte, 64), make([]byte, 64)
+	_, err = a.Read(outA)
+	require.NoError(t, err)
+	_, err = b.Read(outB)
+	require.NoError(t, err)
+	assert.NotEqual(t, outA, outB)
+}
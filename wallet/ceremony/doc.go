@@ -0,0 +1,29 @@
+// Package ceremony runs production key creation as a guided, scriptable key
+// ceremony instead of an ad-hoc generator script.
+//
+// A ceremony is a fixed sequence of steps:
+//
+//  1. parameters – every operator confirms key ID, curve, parties and
+//     threshold.
+//  2. entropy    – statistical health checks of the RNG must pass; external
+//     entropy from `EntropyContributor`s (dice rolls, an HSM RNG) is mixed
+//     in and the mixed RNG is checked again.
+//  3. keygen     – the distributed key generation runs through a
+//     `KeyGenerator`; every operator confirms the resulting public key.
+//  4. backup     – each share is written to a backup `keystore.Medium`, read
+//     back and compared; every operator confirms.
+//
+// Dual control is enforced by requiring at least two distinct operators, each
+// of whom must confirm every critical step through a `Confirmer`.  The
+// Confirmer can prompt on a terminal or replay a script, which makes
+// ceremonies repeatable in rehearsals and CI.
+//
+// The mixed RNG is handed to the KeyGenerator.  It XORs the system RNG with a
+// hash-based stream seeded from all contributions, so a weak contribution can
+// never make it worse than the system RNG alone.
+//
+// The outcome is a `Report` – parameters, entropy results, contribution
+// digests, confirmations, public key, salted share commitments and backup
+// locations, but never share material – signed with the ceremony host's
+// Ed25519 key.  `ShareRecord.Matches` checks a share against its commitment.
+package ceremony
@@ -0,0 +1,155 @@
+package ceremony
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// mixSeedBytes is the size of the base RNG sample hashed into the seed.
+	mixSeedBytes = 32
+	// minDiceRolls is the number of six-sided dice rolls needed for 256 bits.
+	minDiceRolls = 100
+	// mixDomain separates the mixer's hash inputs from other SHA-256 uses.
+	mixDomain = "cb-mpc ceremony entropy mix v1"
+)
+
+// EntropyContributor supplies external entropy to be mixed into the ceremony
+// RNG, such as dice rolls entered by an operator or the output of an HSM RNG.
+type EntropyContributor interface {
+	// Name identifies the contributor in the ceremony report.
+	Name() string
+	// Contribute returns the contributed bytes.
+	Contribute(ctx context.Context) ([]byte, error)
+}
+
+// MixRecord records an external entropy contribution without revealing it.
+// The digest lets auditors match it against the retained original, e.g. a
+// signed dice sheet.
+type MixRecord struct {
+	Source string `json:"source"`
+	Bytes  int    `json:"bytes"`
+	SHA256 []byte `json:"sha256"`
+}
+
+// Mixer is an RNG combining a base reader with external contributions.
+//
+// Every output block is a fresh block from the base reader XORed with
+// SHA-256(seed || counter), where the seed hashes a base sample together with
+// all contributions.  The output is therefore unpredictable as long as either
+// the base reader or the contributions are.
+type Mixer struct {
+	base io.Reader
+
+	mu      sync.Mutex
+	seed    [sha256.Size]byte
+	counter uint64
+}
+
+// NewMixer seeds a Mixer from base and the given contributions.
+func NewMixer(base io.Reader, contributions ...[]byte) (*Mixer, error) {
+	sample := make([]byte, mixSeedBytes)
+	if _, err := io.ReadFull(base, sample); err != nil {
+		return nil, fmt.Errorf("reading base entropy: %w", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(mixDomain))
+	writeLengthPrefixed(h, sample)
+	for _, c := range contributions {
+		writeLengthPrefixed(h, c)
+	}
+	m := &Mixer{base: base}
+	h.Sum(m.seed[:0])
+	clear(sample)
+	return m, nil
+}
+
+// Read fills p with mixed random bytes.
+func (m *Mixer) Read(p []byte) (int, error) {
+	if _, err := io.ReadFull(m.base, p); err != nil {
+		return 0, fmt.Errorf("reading base entropy: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var block [sha256.Size]byte
+	var ctr [8]byte
+	for off := 0; off < len(p); off += sha256.Size {
+		binary.BigEndian.PutUint64(ctr[:], m.counter)
+		m.counter++
+		h := sha256.New()
+		h.Write(m.seed[:])
+		h.Write(ctr[:])
+		h.Sum(block[:0])
+		for i := 0; i < sha256.Size && off+i < len(p); i++ {
+			p[off+i] ^= block[i]
+		}
+	}
+	return len(p), nil
+}
+
+func writeLengthPrefixed(w io.Writer, b []byte) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+	w.Write(n[:])
+	w.Write(b)
+}
+
+// ReaderContributor contributes n bytes read from r, e.g. from an HSM RNG.
+func ReaderContributor(name string, r io.Reader, n int) EntropyContributor {
+	return &readerContributor{name: name, r: r, n: n}
+}
+
+type readerContributor struct {
+	name string
+	r    io.Reader
+	n    int
+}
+
+func (c *readerContributor) Name() string { return c.name }
+
+func (c *readerContributor) Contribute(context.Context) ([]byte, error) {
+	if c.n <= 0 {
+		return nil, fmt.Errorf("contribution size must be positive")
+	}
+	b := make([]byte, c.n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// DiceContributor contributes six-sided dice rolls obtained from rolls, which
+// typically prompts an operator.  At least 100 rolls (about 258 bits) are
+// required; whitespace between rolls is ignored.
+func DiceContributor(name string, rolls func(ctx context.Context) (string, error)) EntropyContributor {
+	return &diceContributor{name: name, rolls: rolls}
+}
+
+type diceContributor struct {
+	name  string
+	rolls func(ctx context.Context) (string, error)
+}
+
+func (c *diceContributor) Name() string { return c.name }
+
+func (c *diceContributor) Contribute(ctx context.Context) ([]byte, error) {
+	s, err := c.rolls(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rolls := strings.Join(strings.Fields(s), "")
+	for _, r := range rolls {
+		if r < '1' || r > '6' {
+			return nil, fmt.Errorf("invalid dice roll %q", r)
+		}
+	}
+	if len(rolls) < minDiceRolls {
+		return nil, fmt.Errorf("%d dice rolls provided, at least %d required", len(rolls), minDiceRolls)
+	}
+	return []byte(rolls), nil
+}
@@ -0,0 +1,133 @@
+package ceremony
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBadReportSignature is returned when a ceremony report does not verify.
+var ErrBadReportSignature = errors.New("ceremony: invalid report signature")
+
+// Params describes the key to be created.
+type Params struct {
+	KeyID     string   `json:"key_id"`
+	Curve     string   `json:"curve"`
+	Parties   []string `json:"parties"`
+	Threshold int      `json:"threshold"`
+}
+
+// Confirmation records that an operator confirmed a step.
+type Confirmation struct {
+	Operator string    `json:"operator"`
+	At       time.Time `json:"at"`
+}
+
+// StepRecord records a completed ceremony step.
+type StepRecord struct {
+	Name          string         `json:"name"`
+	Summary       string         `json:"summary"`
+	Confirmations []Confirmation `json:"confirmations,omitempty"`
+	CompletedAt   time.Time      `json:"completed_at"`
+}
+
+// shareCommitmentTag separates share commitments from other hashes.
+const shareCommitmentTag = "cb-mpc ceremony share commitment v1\x00"
+
+// ShareRecord records a generated share without revealing it.  Commitment
+// is the SHA-256 of the share under a random per-share salt, so that a
+// holder of the share can check it against the report while equal shares
+// get unrelated commitments in different reports and stores.
+type ShareRecord struct {
+	Party      string `json:"party"`
+	Salt       []byte `json:"salt,omitempty"`
+	Commitment []byte `json:"commitment,omitempty"`
+	// SHA256 is the unsalted digest recorded by earlier versions, kept so
+	// that their reports still verify.  New reports leave it empty.
+	SHA256         []byte `json:"sha256,omitempty"`
+	Backup         string `json:"backup,omitempty"` // Location of the verified backup
+	BackupVerified bool   `json:"backup_verified"`
+}
+
+// Matches reports whether share is the share the record was made for.
+func (r *ShareRecord) Matches(share []byte) bool {
+	if len(r.Commitment) > 0 {
+		return hmac.Equal(commitShare(r.Salt, share), r.Commitment)
+	}
+	sum := sha256.Sum256(share)
+	return len(r.SHA256) > 0 && hmac.Equal(sum[:], r.SHA256)
+}
+
+// commitShare returns the salted commitment of share.
+func commitShare(salt, share []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(shareCommitmentTag))
+	h.Write(salt)
+	h.Write(share)
+	return h.Sum(nil)
+}
+
+// Report is the record of a key ceremony.
+type Report struct {
+	ID         string          `json:"id"`
+	Params     Params          `json:"params"`
+	Operators  []string        `json:"operators"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Entropy    []EntropyResult `json:"entropy"`
+	Mixing     []MixRecord     `json:"mixing,omitempty"`
+	Steps      []StepRecord    `json:"steps"`
+	PublicKey  []byte          `json:"public_key"`
+	Shares     []ShareRecord   `json:"shares"`
+}
+
+// Hash returns the SHA-256 digest of the report's JSON encoding.  It
+// identifies the ceremony in later artefacts such as public key bundles.
+func (r *Report) Hash() ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// SignedReport is a Report signed with the ceremony host's Ed25519 key.
+type SignedReport struct {
+	Report    Report            `json:"report"`
+	PublicKey ed25519.PublicKey `json:"public_key"`
+	Signature []byte            `json:"signature"`
+}
+
+// signReport signs the hash of r with key.
+func signReport(r *Report, key ed25519.PrivateKey) (*SignedReport, error) {
+	hash, err := r.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing report: %w", err)
+	}
+	return &SignedReport{
+		Report:    *r,
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, hash),
+	}, nil
+}
+
+// Verify checks the report signature against the embedded public key.  Callers
+// must additionally check that PublicKey belongs to a trusted ceremony host.
+func (s *SignedReport) Verify() error {
+	if len(s.PublicKey) != ed25519.PublicKeySize {
+		return ErrBadReportSignature
+	}
+	hash, err := s.Report.Hash()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(s.PublicKey, hash, s.Signature) {
+		return ErrBadReportSignature
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"solana-threshold-wallet/wallet/retry"
+)
+
+var (
+	// ErrNotFound is returned by Confirm when the ledger does not (yet) know
+	// about the requested transaction.
+	ErrNotFound = errors.New("chain: transaction not found")
+	// ErrInsufficientFunds is returned by Quote when the sender cannot pay
+	// for the transfer and its fees.
+	ErrInsufficientFunds = errors.New("chain: insufficient funds")
+	// ErrInvalidRecipient is returned by Quote when the recipient cannot
+	// receive the transfer, e.g. because it is a token account rather than a
+	// wallet or the amount is too small to create it.
+	ErrInvalidRecipient = errors.New("chain: invalid recipient")
+)
+
+// Output is one recipient of a transfer.
+type Output struct {
+	To     string   // Recipient address in the chain's canonical text encoding
+	Amount *big.Int // Amount in the asset's smallest unit
+}
+
+// Transfer describes a value transfer in chain-agnostic terms.
+type Transfer struct {
+	From   string   // Sender address in the chain's canonical text encoding
+	To     string   // Recipient address in the chain's canonical text encoding
+	Amount *big.Int // Amount in the asset's smallest unit (lamports, wei, …)
+	// Token is the mint or contract address of the transferred token, or
+	// empty for the chain's native asset.  Chains without token support
+	// reject transfers that set it.
+	Token string
+	// Outputs, if set, pays every output from From in a single transaction
+	// instead of To and Amount, which must then be empty.  Only chains
+	// implementing Batcher accept it.
+	Outputs []Output
+}
+
+// UnsignedTx is a transaction that is ready to be signed.
+//
+// Payload is the chain-specific serialisation of the unsigned transaction and
+// is what Decode understands.  SigningPayload is the exact byte string that has
+// to be signed by the key controlling Transfer.From.  For some chains the two
+// are identical (Solana signs the message bytes), for others the signing
+// payload is a digest of the payload (EVM signs keccak256 of the envelope).
+type UnsignedTx struct {
+	Chain          string // Identifier of the Chain that built the transaction
+	Payload        []byte // Serialised unsigned transaction
+	SigningPayload []byte // Bytes to be signed by the MPC engine
+}
+
+// SignedTx pairs an UnsignedTx with the signature produced by the MPC engine.
+type SignedTx struct {
+	Unsigned  *UnsignedTx
+	Signature []byte
+}
+
+// Summary is the human-reviewable content of a transaction as understood by
+// Decode.  It is what policies are evaluated against and what approvers see.
+type Summary struct {
+	Chain  string   // Identifier of the Chain that decoded the transaction
+	From   string   // Sender address
+	To     string   // Recipient address
+	Amount *big.Int // Transferred amount in the asset's smallest unit
+	Fee    *big.Int // Maximum network fee in the chain's smallest unit
+	Token  string   // Token mint or contract address; empty for the native asset
+	// Outputs lists the recipients of a transaction paying more than one.
+	// To is then empty and Amount is the total.
+	Outputs []Output
+}
+
+// Recipients returns the outputs of the transaction: Outputs for batched
+// transfers, otherwise a single output for To and Amount.
+func (s *Summary) Recipients() []Output {
+	if len(s.Outputs) > 0 {
+		return s.Outputs
+	}
+	return []Output{{To: s.To, Amount: s.Amount}}
+}
+
+// String renders the summary on a single line for approval prompts and logs.
+func (s *Summary) String() string {
+	if s == nil {
+		return "<nil>"
+	}
+	if len(s.Outputs) > 0 {
+		return fmt.Sprintf("%s: transfer %s from %s to %d recipients (max fee %s)", s.Chain, s.Amount, s.From, len(s.Outputs), s.Fee)
+	}
+	if s.Token != "" {
+		return fmt.Sprintf("%s: transfer %s of token %s from %s to %s (max fee %s)", s.Chain, s.Amount, s.Token, s.From, s.To, s.Fee)
+	}
+	return fmt.Sprintf("%s: transfer %s from %s to %s (max fee %s)", s.Chain, s.Amount, s.From, s.To, s.Fee)
+}
+
+// SimulationResult reports the outcome of executing a signed transaction
+// against the current ledger state without committing it.
+type SimulationResult struct {
+	OK   bool     // True if the transaction would succeed
+	Err  string   // Chain-specific failure description when OK is false
+	Logs []string // Execution logs, if the chain exposes them
+	// Detail is the chain's typed description of the failure, if it has
+	// one, e.g. a *solana.TransactionError with the failing instruction
+	// and program error code.
+	Detail error
+}
+
+// Status is the lifecycle stage of a broadcast transaction.
+type Status uint8
+
+const (
+	// StatusPending means the transaction is known but not yet confirmed.
+	StatusPending Status = iota
+	// StatusConfirmed means the transaction was included but may still be
+	// rolled back.
+	StatusConfirmed
+	// StatusFinalized means the transaction can no longer be rolled back.
+	StatusFinalized
+	// StatusFailed means the transaction was included and reverted.
+	StatusFailed
+)
+
+// String returns the symbolic name of the Status.
+func (s Status) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusConfirmed:
+		return "confirmed"
+	case StatusFinalized:
+		return "finalized"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Receipt describes the on-chain state of a broadcast transaction.
+type Receipt struct {
+	TxID   string // Chain-specific transaction identifier
+	Status Status // Lifecycle stage
+	Height uint64 // Slot or block number in which the transaction landed
+	Err    string // Failure description when Status == StatusFailed
+}
+
+// Chain is the interface every supported ledger implements.
+type Chain interface {
+	// ID returns a short, stable identifier such as "solana-devnet" or
+	// "evm-1".  It is recorded in UnsignedTx and Summary.
+	ID() string
+
+	// DeriveAddress converts the aggregated MPC public key into the chain's
+	// canonical address encoding.
+	DeriveAddress(pubKey []byte) (string, error)
+
+	// BuildTransfer fetches whatever ledger state is required (blockhash,
+	// nonce, …), consults the chain's FeeOracle and returns the unsigned
+	// transaction for the transfer.
+	BuildTransfer(ctx context.Context, transfer *Transfer) (*UnsignedTx, error)
+
+	// Decode parses an UnsignedTx payload back into a Summary.  It must not
+	// contact the network so it can be used by offline approvers; in
+	// particular Summary.Fee is derived from the payload itself rather than
+	// from a fresh estimate.
+	Decode(payload []byte) (*Summary, error)
+
+	// Simulate executes the signed transaction against current ledger state
+	// without committing it.
+	Simulate(ctx context.Context, tx *SignedTx) (*SimulationResult, error)
+
+	// Broadcast submits the signed transaction and returns its identifier.
+	Broadcast(ctx context.Context, tx *SignedTx) (string, error)
+
+	// Confirm returns the current Receipt for a broadcast transaction.  It
+	// returns ErrNotFound when the ledger does not know the transaction.
+	Confirm(ctx context.Context, txID string) (*Receipt, error)
+}
+
+// Expirer is implemented by chains whose transactions are only valid for a
+// limited time, such as Solana where a transaction references a recent
+// blockhash.  Chains with nonce-based replay protection do not implement it.
+type Expirer interface {
+	// Expired reports whether tx can no longer be included in the ledger.
+	// Once it returns true for a transaction that Confirm does not find, the
+	// transaction is guaranteed never to land and may safely be rebuilt.
+	Expired(ctx context.Context, tx *UnsignedTx) (bool, error)
+}
+
+// SigningContexter is implemented by chains whose signing payloads have a
+// structure the parties can check, such as Solana transaction messages.
+// SigningContext returns the signing context declared for them, one of the
+// contexts of package signctx.  Payloads of other chains, such as EVM
+// digests, are declared "raw".
+type SigningContexter interface {
+	SigningContext() string
+}
+
+// Quote is the expected native-asset cost of a transfer.
+type Quote struct {
+	Fee *big.Int // Maximum network fee in the chain's smallest unit
+	// Deposit is the native amount spent on creating accounts for the
+	// recipient, such as the rent of a Solana token account.  It is zero
+	// when nothing has to be created.
+	Deposit *big.Int
+}
+
+// Quoter is implemented by chains that can check a transfer against the
+// current ledger state before it is built: whether the recipient can receive
+// it, which accounts have to be created and whether the sender can pay for
+// it.  Failed checks wrap ErrInsufficientFunds or ErrInvalidRecipient.
+type Quoter interface {
+	Quote(ctx context.Context, transfer *Transfer) (*Quote, error)
+}
+
+// Batcher is implemented by chains that can pay several recipients in one
+// transaction through Transfer.Outputs.
+type Batcher interface {
+	// Fits reports whether transfer can be built as a single transaction
+	// within the chain's size limits, whatever fee it ends up paying.  It
+	// must not contact the network.
+	Fits(transfer *Transfer) (bool, error)
+}
+
+// Holding kinds.
+const (
+	HoldingNative = "native" // The address's own balance
+	HoldingToken  = "token"  // A token account owned by the address
+	HoldingStake  = "stake"  // A stake account the address may withdraw from
+)
+
+// Holding is an asset controlled by an address.
+type Holding struct {
+	Kind  string // HoldingNative, HoldingToken or HoldingStake
+	Token string // Token mint or contract address for HoldingToken
+	// Account is the account holding the asset, e.g. a token or stake
+	// account, or the address itself for the native balance.
+	Account string
+	// Transferable reports whether BuildTransfer can move the holding, e.g.
+	// a token held in the address's associated token account.
+	Transferable bool
+	Amount       *big.Int // Balance in the asset's smallest unit
+}
+
+// Inventory is implemented by chains that can enumerate everything an
+// address controls, e.g. to move it all to a new key.
+type Inventory interface {
+	Holdings(ctx context.Context, address string) ([]Holding, error)
+}
+
+// HistoryTx is a transaction from an address's on-chain history.
+type HistoryTx struct {
+	TxID   string    // Chain-specific transaction identifier
+	Height uint64    // Slot or block number in which the transaction landed
+	Time   time.Time // When it landed; zero if the chain does not say
+	// Payload is the transaction's signing payload as recorded on chain.
+	Payload []byte
+	// Signature is the address's signature over Payload, or nil if the
+	// address did not sign the transaction, e.g. because it only received.
+	Signature []byte
+}
+
+// History is implemented by chains that can list the transactions involving
+// an address, e.g. to reconcile them with the signing sessions of its key.
+type History interface {
+	// Transactions returns up to limit of the most recent transactions
+	// involving address, newest first.
+	Transactions(ctx context.Context, address string, limit int) ([]HistoryTx, error)
+}
+
+// WaitFinalized polls c.Confirm every interval until the transaction reaches
+// StatusFinalized or StatusFailed, or ctx is done.  ErrNotFound is treated as
+// "not yet visible" and retried, as are errors retry.Retryable accepts, such
+// as a rate-limited or lagging RPC node.
+func WaitFinalized(ctx context.Context, c Chain, txID string, interval time.Duration) (*Receipt, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		receipt, err := c.Confirm(ctx, txID)
+		switch {
+		case errors.Is(err, ErrNotFound), retry.Retryable(err):
+		case err != nil:
+			return nil, err
+		case receipt.Status == StatusFinalized || receipt.Status == StatusFailed:
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %s: %w", txID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package chain defines the blockchain abstraction used by the threshold
+// wallet.
+//
+// The core interface is `Chain` which captures everything the wallet,
+// coordinator and policy layers need to know about a ledger:
+//
+//	DeriveAddress(pubKey)
+//	BuildTransfer(ctx, transfer)
+//	Decode(payload)
+//	Simulate(ctx, signedTx)
+//	Broadcast(ctx, signedTx)
+//	Confirm(ctx, txID)
+//
+// A Chain implementation does *not* take part in signing.  BuildTransfer
+// returns an `UnsignedTx` whose SigningPayload is handed verbatim to the MPC
+// engine; the resulting signature is attached via `SignedTx` and passed back
+// to the chain for simulation and broadcast.  This keeps the signing code
+// completely chain-agnostic – adding a new ledger means implementing Chain and
+// nothing else.
+//
+// Network fees are estimated by a pluggable `FeeOracle`.  Builders consult it
+// when assembling transactions and Decode reports the resulting worst-case fee
+// in Summary.Fee, so signers see the expected cost before approving.
+//
+// High-value deployments can wrap a Chain with `WithReceiptVerifier` so that
+// finality is only reported once a second, independent RPC provider agrees.
+//
+// Out of the box the repository provides two implementations:
+//
+//   - solana – Ed25519 keys, system-program transfers, priority fees from
+//     getRecentPrioritizationFees, JSON-RPC via solana-go
+//   - evm    – secp256k1 keys, EIP-1559 (type 2) transfers priced from
+//     eth_feeHistory, plain JSON-RPC
+package chain
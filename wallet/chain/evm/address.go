@@ -0,0 +1,55 @@
+package evm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// keccak256 returns the legacy Keccak-256 digest used throughout Ethereum.
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// addressFromPublicKey returns the 20-byte account address controlled by the
+// 65-byte uncompressed SEC1 public key pub.
+func addressFromPublicKey(pub []byte) []byte {
+	return keccak256(pub[1:])[12:]
+}
+
+// checksumAddress renders a 20-byte address using EIP-55 mixed-case
+// checksum encoding.
+func checksumAddress(addr []byte) string {
+	lower := hex.EncodeToString(addr)
+	hash := hex.EncodeToString(keccak256([]byte(lower)))
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && hash[i] >= '8' {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// parseAddress decodes a 0x-prefixed hex address. Mixed-case input must carry
+// a valid EIP-55 checksum; all-lower or all-upper input is accepted as is.
+func parseAddress(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("address %q must be 0x-prefixed", s)
+	}
+	body := s[2:]
+	addr, err := hex.DecodeString(body)
+	if err != nil || len(addr) != 20 {
+		return nil, fmt.Errorf("address %q is not 20 hex-encoded bytes", s)
+	}
+	if body != strings.ToLower(body) && body != strings.ToUpper(body) && checksumAddress(addr) != "0x"+body {
+		return nil, fmt.Errorf("address %q has an invalid EIP-55 checksum", s)
+	}
+	return addr, nil
+}
@@ -0,0 +1,271 @@
+// Package evm implements chain.Chain for Ethereum and EVM-compatible networks.
+//
+// Addresses are derived from the secp256k1 group public key produced by the
+// ECDSA MPC protocols.  Transfers are EIP-1559 (type 2) transactions; the
+// signing payload is the keccak256 digest of the unsigned envelope, and the
+// DER signature returned by the MPC engine is converted into the r/s/yParity
+// form expected on the wire.
+//
+// The package talks plain JSON-RPC over HTTP and has no dependency on
+// go-ethereum.
+package evm
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// defaultTransferGas is the intrinsic gas cost of a plain value transfer.
+const defaultTransferGas = 21000
+
+// Config contains the configuration for an EVM chain instance.
+type Config struct {
+	// ID is the identifier reported by Chain.ID, e.g. "evm-1".
+	ID string
+	// RPCEndpoint is the JSON-RPC URL of a node.
+	RPCEndpoint string
+	// ChainID is the EIP-155 chain identifier (1 for Ethereum mainnet).
+	ChainID *big.Int
+	// FeeOracle prices transfers. Defaults to an EIP1559Oracle on
+	// RPCEndpoint with default settings.
+	FeeOracle chain.FeeOracle
+}
+
+// Chain implements chain.Chain for EVM networks.
+type Chain struct {
+	id      string
+	chainID *big.Int
+	rpc     *rpcClient
+	fees    chain.FeeOracle
+}
+
+// Ensure Chain implements the chain.Chain interface
+var _ chain.Chain = (*Chain)(nil)
+
+// New creates an EVM chain from the given configuration.
+func New(config Config) (*Chain, error) {
+	if config.ID == "" {
+		return nil, fmt.Errorf("chain ID must be provided")
+	}
+	if config.RPCEndpoint == "" {
+		return nil, fmt.Errorf("RPC endpoint must be provided")
+	}
+	if config.ChainID == nil || config.ChainID.Sign() <= 0 {
+		return nil, fmt.Errorf("EIP-155 chain ID must be positive")
+	}
+	fees := config.FeeOracle
+	if fees == nil {
+		oracle, err := NewEIP1559Oracle(config.RPCEndpoint, EIP1559Config{})
+		if err != nil {
+			return nil, err
+		}
+		fees = oracle
+	}
+	return &Chain{
+		id:      config.ID,
+		chainID: new(big.Int).Set(config.ChainID),
+		rpc:     newRPCClient(config.RPCEndpoint),
+		fees:    fees,
+	}, nil
+}
+
+// ID returns the configured chain identifier.
+func (c *Chain) ID() string { return c.id }
+
+// DeriveAddress returns the EIP-55 checksummed address of a compressed or
+// uncompressed secp256k1 public key.
+func (c *Chain) DeriveAddress(pubKey []byte) (string, error) {
+	pub, err := verify.UncompressSecp256k1(pubKey)
+	if err != nil {
+		return "", err
+	}
+	return checksumAddress(addressFromPublicKey(pub)), nil
+}
+
+// BuildTransfer fetches the sender's pending nonce and a fee estimate and
+// returns an EIP-1559 transfer of the native asset.  Token and batched
+// transfers are not supported.
+func (c *Chain) BuildTransfer(ctx context.Context, transfer *chain.Transfer) (*chain.UnsignedTx, error) {
+	if transfer == nil {
+		return nil, fmt.Errorf("transfer cannot be nil")
+	}
+	if transfer.Token != "" || len(transfer.Outputs) > 0 {
+		return nil, fmt.Errorf("token and batched transfers are not supported")
+	}
+	from, err := parseAddress(transfer.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	fee, err := c.fees.EstimateFee(ctx, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("estimating fee: %w", err)
+	}
+	var nonce hexBig
+	if err := c.rpc.call(ctx, &nonce, "eth_getTransactionCount", checksumAddress(from), "pending"); err != nil {
+		return nil, err
+	}
+	return c.buildTransfer(transfer, nonce.big().Uint64(), fee)
+}
+
+// buildTransfer assembles the transaction once all ledger state is known.
+func (c *Chain) buildTransfer(transfer *chain.Transfer, nonce uint64, fee *chain.FeeEstimate) (*chain.UnsignedTx, error) {
+	from, err := parseAddress(transfer.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := parseAddress(transfer.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	if transfer.Amount == nil || transfer.Amount.Sign() <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if fee == nil || fee.Limit == 0 || fee.Price == nil || fee.Tip == nil || fee.Tip.Cmp(fee.Price) > 0 {
+		return nil, fmt.Errorf("invalid fee estimate")
+	}
+	tx := &dynamicFeeTx{
+		ChainID:   c.chainID,
+		Nonce:     nonce,
+		GasTipCap: new(big.Int).Set(fee.Tip),
+		GasFeeCap: new(big.Int).Set(fee.Price),
+		Gas:       fee.Limit,
+		To:        to,
+		Value:     new(big.Int).Set(transfer.Amount),
+	}
+	payload, err := encodePayload(from, tx)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := tx.sigHash()
+	if err != nil {
+		return nil, err
+	}
+	return &chain.UnsignedTx{Chain: c.id, Payload: payload, SigningPayload: hash}, nil
+}
+
+// Decode parses a payload produced by BuildTransfer.
+func (c *Chain) Decode(payload []byte) (*chain.Summary, error) {
+	from, tx, err := decodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	if tx.ChainID.Cmp(c.chainID) != 0 {
+		return nil, fmt.Errorf("transaction is for chain %s, expected %s", tx.ChainID, c.chainID)
+	}
+	return &chain.Summary{
+		Chain:  c.id,
+		From:   checksumAddress(from),
+		To:     checksumAddress(tx.To),
+		Amount: new(big.Int).Set(tx.Value),
+		Fee:    new(big.Int).Mul(tx.GasFeeCap, new(big.Int).SetUint64(tx.Gas)),
+	}, nil
+}
+
+// Simulate executes the transfer via eth_call against the latest block.
+func (c *Chain) Simulate(ctx context.Context, tx *chain.SignedTx) (*chain.SimulationResult, error) {
+	from, dtx, _, err := c.assemble(tx)
+	if err != nil {
+		return nil, err
+	}
+	call := map[string]string{
+		"from":                 checksumAddress(from),
+		"to":                   checksumAddress(dtx.To),
+		"gas":                  quantity(new(big.Int).SetUint64(dtx.Gas)),
+		"maxFeePerGas":         quantity(dtx.GasFeeCap),
+		"maxPriorityFeePerGas": quantity(dtx.GasTipCap),
+		"value":                quantity(dtx.Value),
+		"data":                 "0x" + hex.EncodeToString(dtx.Data),
+	}
+	err = c.rpc.call(ctx, nil, "eth_call", call, "latest")
+	var rerr *rpcError
+	if errors.As(err, &rerr) {
+		return &chain.SimulationResult{OK: false, Err: rerr.Message}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+// Broadcast submits the signed envelope via eth_sendRawTransaction.
+func (c *Chain) Broadcast(ctx context.Context, tx *chain.SignedTx) (string, error) {
+	_, _, raw, err := c.assemble(tx)
+	if err != nil {
+		return "", err
+	}
+	var hash string
+	if err := c.rpc.call(ctx, &hash, "eth_sendRawTransaction", "0x"+hex.EncodeToString(raw)); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Confirm maps the transaction receipt and the node's finalized head onto a
+// chain.Receipt.
+func (c *Chain) Confirm(ctx context.Context, txID string) (*chain.Receipt, error) {
+	var raw json.RawMessage
+	if err := c.rpc.call(ctx, &raw, "eth_getTransactionReceipt", txID); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, chain.ErrNotFound
+	}
+	var rcpt struct {
+		Status      hexBig `json:"status"`
+		BlockNumber hexBig `json:"blockNumber"`
+	}
+	if err := json.Unmarshal(raw, &rcpt); err != nil {
+		return nil, fmt.Errorf("decoding receipt: %w", err)
+	}
+	receipt := &chain.Receipt{TxID: txID, Height: rcpt.BlockNumber.big().Uint64()}
+	if rcpt.Status.big().Sign() == 0 {
+		receipt.Status = chain.StatusFailed
+		receipt.Err = "execution reverted"
+		return receipt, nil
+	}
+	var final struct {
+		Number hexBig `json:"number"`
+	}
+	if err := c.rpc.call(ctx, &final, "eth_getBlockByNumber", "finalized", false); err != nil {
+		return nil, err
+	}
+	if final.Number.big().Cmp(rcpt.BlockNumber.big()) >= 0 {
+		receipt.Status = chain.StatusFinalized
+	} else {
+		receipt.Status = chain.StatusConfirmed
+	}
+	return receipt, nil
+}
+
+// assemble decodes the payload, resolves the signature against the sender and
+// returns the raw signed envelope.
+func (c *Chain) assemble(tx *chain.SignedTx) ([]byte, *dynamicFeeTx, []byte, error) {
+	if tx == nil || tx.Unsigned == nil {
+		return nil, nil, nil, fmt.Errorf("signed transaction cannot be nil")
+	}
+	from, dtx, err := decodePayload(tx.Unsigned.Payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hash, err := dtx.sigHash()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sig, err := parseSignature(tx.Signature, hash, from)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	raw, err := dtx.encodeSigned(sig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return from, dtx, raw, nil
+}
@@ -0,0 +1,186 @@
+package evm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// Private key 1 has public key G; its address is a well-known test vector.
+const addressOfKeyOne = "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"
+
+func testChain(t *testing.T) *Chain {
+	t.Helper()
+	c, err := New(Config{ID: "evm-test", RPCEndpoint: "http://127.0.0.1:0", ChainID: big.NewInt(11155111)})
+	require.NoError(t, err)
+	return c
+}
+
+// secpN is the order of the secp256k1 group.
+var secpN, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// testSign produces a DER-free r||s signature with the given private key. It
+// is only suitable for tests.
+func testSign(t *testing.T, d *big.Int, hash []byte) []byte {
+	t.Helper()
+	for {
+		k, err := rand.Int(rand.Reader, secpN)
+		require.NoError(t, err)
+		if k.Sign() == 0 {
+			continue
+		}
+		R, err := verify.Secp256k1PublicKey(k.FillBytes(make([]byte, 32)))
+		require.NoError(t, err)
+		r := new(big.Int).SetBytes(R[1:33])
+		r.Mod(r, secpN)
+		s := new(big.Int).Mul(r, d)
+		s.Add(s, new(big.Int).SetBytes(hash))
+		s.Mul(s, new(big.Int).ModInverse(k, secpN))
+		s.Mod(s, secpN)
+		if r.Sign() == 0 || s.Sign() == 0 {
+			continue
+		}
+		out := make([]byte, 64)
+		r.FillBytes(out[:32])
+		s.FillBytes(out[32:])
+		return out
+	}
+}
+
+func TestDeriveAddress(t *testing.T) {
+	c := testChain(t)
+
+	one := make([]byte, 32)
+	one[31] = 1
+	uncompressed, err := verify.Secp256k1PublicKey(one)
+	require.NoError(t, err)
+	addr, err := c.DeriveAddress(uncompressed)
+	require.NoError(t, err)
+	assert.Equal(t, addressOfKeyOne, addr)
+
+	compressed := append([]byte{0x02}, uncompressed[1:33]...)
+	addr, err = c.DeriveAddress(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, addressOfKeyOne, addr)
+
+	_, err = c.DeriveAddress([]byte{0x02, 0x01})
+	assert.Error(t, err)
+}
+
+func TestParseAddressChecksum(t *testing.T) {
+	_, err := parseAddress(addressOfKeyOne)
+	assert.NoError(t, err)
+	_, err = parseAddress("0x7e5f4552091a69125d5dfcb7b8c2659029395bdf")
+	assert.NoError(t, err)
+	_, err = parseAddress("0x7E5f4552091A69125d5DfCb7b8C2659029395Bdf")
+	assert.Error(t, err)
+}
+
+func TestRLPVectors(t *testing.T) {
+	cases := []struct {
+		in   interface{}
+		want string
+	}{
+		{"dog", "83646f67"},
+		{[]interface{}{"cat", "dog"}, "c88363617483646f67"},
+		{"", "80"},
+		{uint64(0), "80"},
+		{uint64(15), "0f"},
+		{uint64(1024), "820400"},
+		{[]interface{}{}, "c0"},
+	}
+	for _, tc := range cases {
+		enc, err := rlpEncode(tc.in)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, hex.EncodeToString(enc))
+
+		_, err = rlpDecode(enc)
+		assert.NoError(t, err)
+	}
+}
+
+func TestBuildDecodeAndAssemble(t *testing.T) {
+	c := testChain(t)
+	d := big.NewInt(1)
+	transfer := &chain.Transfer{
+		From:   addressOfKeyOne,
+		To:     "0x000000000000000000000000000000000000dEaD",
+		Amount: big.NewInt(1_000_000_000),
+	}
+
+	fee := newFeeEstimate(defaultTransferGas, big.NewInt(40), big.NewInt(2))
+	utx, err := c.buildTransfer(transfer, 7, fee)
+	require.NoError(t, err)
+	assert.Len(t, utx.SigningPayload, 32)
+
+	summary, err := c.Decode(utx.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, transfer.From, summary.From)
+	assert.Equal(t, transfer.To, summary.To)
+	assert.Equal(t, 0, transfer.Amount.Cmp(summary.Amount))
+	assert.Equal(t, int64(21000*82), summary.Fee.Int64())
+
+	sig := testSign(t, d, utx.SigningPayload)
+	from, dtx, raw, err := c.assemble(&chain.SignedTx{Unsigned: utx, Signature: sig})
+	require.NoError(t, err)
+	assert.Equal(t, addressOfKeyOne, checksumAddress(from))
+	assert.Equal(t, uint64(7), dtx.Nonce)
+	assert.Equal(t, byte(dynamicFeeTxType), raw[0])
+
+	// A signature by a different key must be rejected.
+	bad := testSign(t, big.NewInt(2), utx.SigningPayload)
+	_, _, _, err = c.assemble(&chain.SignedTx{Unsigned: utx, Signature: bad})
+	assert.Error(t, err)
+}
+
+func TestDecodeRejectsForeignChainID(t *testing.T) {
+	c := testChain(t)
+	other, err := New(Config{ID: "evm-1", RPCEndpoint: "http://127.0.0.1:0", ChainID: big.NewInt(1)})
+	require.NoError(t, err)
+
+	utx, err := other.buildTransfer(&chain.Transfer{
+		From:   addressOfKeyOne,
+		To:     addressOfKeyOne,
+		Amount: big.NewInt(1),
+	}, 0, newFeeEstimate(defaultTransferGas, big.NewInt(1), big.NewInt(1)))
+	require.NoError(t, err)
+
+	_, err = c.Decode(utx.Payload)
+	assert.Error(t, err)
+}
+
+func TestEIP1559OracleEstimate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "eth_feeHistory", req.Method)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{
+			"baseFeePerGas":["0x10","0x12","0x14"],
+			"reward":[["0x1"],["0x3"]]}}`))
+	}))
+	defer srv.Close()
+
+	oracle, err := NewEIP1559Oracle(srv.URL, EIP1559Config{MaxTip: big.NewInt(2)})
+	require.NoError(t, err)
+	fee, err := oracle.EstimateFee(context.Background(), nil)
+	require.NoError(t, err)
+
+	// Tip is the median reward (3) capped at 2; the fee cap doubles the next
+	// block's base fee (0x14 = 20).
+	assert.Equal(t, int64(2), fee.Tip.Int64())
+	assert.Equal(t, int64(42), fee.Price.Int64())
+	assert.Equal(t, uint64(defaultTransferGas), fee.Limit)
+	assert.Equal(t, int64(42*defaultTransferGas), fee.MaxFee.Int64())
+}
@@ -0,0 +1,110 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+const (
+	// feeHistoryBlocks is the number of recent blocks sampled for tips.
+	feeHistoryBlocks = 10
+	// defaultRewardPercentile is the percentile of per-block priority fees
+	// requested from eth_feeHistory.
+	defaultRewardPercentile = 50
+)
+
+// EIP1559Config tunes the EIP1559Oracle. The zero value selects the defaults.
+type EIP1559Config struct {
+	// GasLimit budgeted for transfers. Defaults to 21000.
+	GasLimit uint64
+	// RewardPercentile (1-100) of priority fees paid in recent blocks.
+	RewardPercentile int
+	// MaxTip caps maxPriorityFeePerGas; nil means no cap.
+	MaxTip *big.Int
+}
+
+// EIP1559Oracle estimates EVM fees from eth_feeHistory.
+//
+// The tip is the median of the requested reward percentile over the last
+// blocks and the fee cap tolerates the base fee doubling before inclusion,
+// i.e. maxFeePerGas = 2 * nextBaseFee + tip.
+type EIP1559Oracle struct {
+	rpc    *rpcClient
+	config EIP1559Config
+}
+
+// Ensure EIP1559Oracle implements the chain.FeeOracle interface
+var _ chain.FeeOracle = (*EIP1559Oracle)(nil)
+
+// NewEIP1559Oracle creates an oracle that queries the given RPC endpoint.
+func NewEIP1559Oracle(endpoint string, config EIP1559Config) (*EIP1559Oracle, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("RPC endpoint must be provided")
+	}
+	if config.GasLimit == 0 {
+		config.GasLimit = defaultTransferGas
+	}
+	if config.RewardPercentile == 0 {
+		config.RewardPercentile = defaultRewardPercentile
+	}
+	if config.RewardPercentile < 1 || config.RewardPercentile > 100 {
+		return nil, fmt.Errorf("reward percentile must be between 1 and 100, got %d", config.RewardPercentile)
+	}
+	return &EIP1559Oracle{rpc: newRPCClient(endpoint), config: config}, nil
+}
+
+// EstimateFee implements chain.FeeOracle.
+func (o *EIP1559Oracle) EstimateFee(ctx context.Context, _ *chain.Transfer) (*chain.FeeEstimate, error) {
+	var history struct {
+		BaseFees []*hexBig   `json:"baseFeePerGas"`
+		Rewards  [][]*hexBig `json:"reward"`
+	}
+	err := o.rpc.call(ctx, &history, "eth_feeHistory",
+		quantity(big.NewInt(feeHistoryBlocks)), "latest", []int{o.config.RewardPercentile})
+	if err != nil {
+		return nil, err
+	}
+	if len(history.BaseFees) == 0 || history.BaseFees[len(history.BaseFees)-1] == nil {
+		return nil, fmt.Errorf("node does not report a base fee; EIP-1559 is required")
+	}
+	// The last entry is the base fee of the next, not yet produced, block.
+	baseFee := history.BaseFees[len(history.BaseFees)-1].big()
+
+	var tips []*big.Int
+	for _, block := range history.Rewards {
+		if len(block) > 0 && block[0] != nil {
+			tips = append(tips, block[0].big())
+		}
+	}
+	tip := median(tips)
+	if o.config.MaxTip != nil && tip.Cmp(o.config.MaxTip) > 0 {
+		tip = new(big.Int).Set(o.config.MaxTip)
+	}
+	return newFeeEstimate(o.config.GasLimit, baseFee, tip), nil
+}
+
+// newFeeEstimate prices a transaction with the given gas limit, base fee and
+// tip.
+func newFeeEstimate(gas uint64, baseFee, tip *big.Int) *chain.FeeEstimate {
+	feeCap := new(big.Int).Lsh(baseFee, 1)
+	feeCap.Add(feeCap, tip)
+	return &chain.FeeEstimate{
+		Limit:  gas,
+		Price:  feeCap,
+		Tip:    new(big.Int).Set(tip),
+		MaxFee: new(big.Int).Mul(feeCap, new(big.Int).SetUint64(gas)),
+	}
+}
+
+// median returns the median of values, or zero for an empty slice.
+func median(values []*big.Int) *big.Int {
+	if len(values) == 0 {
+		return new(big.Int)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	return new(big.Int).Set(values[len(values)/2])
+}
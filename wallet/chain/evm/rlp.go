@@ -0,0 +1,163 @@
+package evm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// rlpEncode serialises v using Ethereum's Recursive Length Prefix encoding.
+// Supported item types are []byte, string, uint64, *big.Int (non-negative)
+// and []interface{} for lists.
+func rlpEncode(v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case []byte:
+		if len(x) == 1 && x[0] < 0x80 {
+			return []byte{x[0]}, nil
+		}
+		return append(rlpHeader(0x80, len(x)), x...), nil
+	case string:
+		return rlpEncode([]byte(x))
+	case uint64:
+		return rlpEncode(trimUint(x))
+	case *big.Int:
+		if x == nil {
+			return rlpEncode([]byte{})
+		}
+		if x.Sign() < 0 {
+			return nil, fmt.Errorf("rlp: negative integer %s", x)
+		}
+		return rlpEncode(x.Bytes())
+	case []interface{}:
+		var body []byte
+		for _, item := range x {
+			enc, err := rlpEncode(item)
+			if err != nil {
+				return nil, err
+			}
+			body = append(body, enc...)
+		}
+		return append(rlpHeader(0xc0, len(body)), body...), nil
+	default:
+		return nil, fmt.Errorf("rlp: unsupported type %T", v)
+	}
+}
+
+// rlpHeader returns the prefix for a string (base 0x80) or list (base 0xc0)
+// of the given payload length.
+func rlpHeader(base byte, n int) []byte {
+	if n < 56 {
+		return []byte{base + byte(n)}
+	}
+	l := trimUint(uint64(n))
+	return append([]byte{base + 55 + byte(len(l))}, l...)
+}
+
+// trimUint returns the big-endian encoding of x without leading zeroes.
+func trimUint(x uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], x)
+	i := 0
+	for i < len(buf) && buf[i] == 0 {
+		i++
+	}
+	return buf[i:]
+}
+
+// rlpDecode parses a single RLP item spanning the whole input. Strings are
+// returned as []byte and lists as []interface{}.
+func rlpDecode(data []byte) (interface{}, error) {
+	item, rest, err := rlpSplit(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("rlp: %d trailing bytes", len(rest))
+	}
+	return item, nil
+}
+
+func rlpSplit(data []byte) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("rlp: unexpected end of input")
+	}
+	b := data[0]
+	switch {
+	case b < 0x80:
+		return data[:1], data[1:], nil
+	case b < 0xc0:
+		content, rest, err := rlpContent(data, 0x80)
+		if err != nil {
+			return nil, nil, err
+		}
+		return content, rest, nil
+	default:
+		content, rest, err := rlpContent(data, 0xc0)
+		if err != nil {
+			return nil, nil, err
+		}
+		var list []interface{}
+		for len(content) > 0 {
+			var item interface{}
+			item, content, err = rlpSplit(content)
+			if err != nil {
+				return nil, nil, err
+			}
+			list = append(list, item)
+		}
+		return list, rest, nil
+	}
+}
+
+// rlpContent strips the header of a string or list item and returns its
+// payload together with the remaining input.
+func rlpContent(data []byte, base byte) ([]byte, []byte, error) {
+	b := data[0] - base
+	offset, size := 1, uint64(b)
+	if b >= 56 {
+		lenOfLen := int(b - 55)
+		if len(data) < 1+lenOfLen || lenOfLen > 8 {
+			return nil, nil, fmt.Errorf("rlp: truncated length prefix")
+		}
+		size = 0
+		for _, c := range data[1 : 1+lenOfLen] {
+			size = size<<8 | uint64(c)
+		}
+		offset += lenOfLen
+	}
+	if uint64(len(data)-offset) < size {
+		return nil, nil, fmt.Errorf("rlp: item exceeds input (%d > %d)", size, len(data)-offset)
+	}
+	end := offset + int(size)
+	return data[offset:end], data[end:], nil
+}
+
+// rlpBytes asserts that item is a string.
+func rlpBytes(item interface{}) ([]byte, error) {
+	b, ok := item.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("rlp: expected string, got list")
+	}
+	return b, nil
+}
+
+// rlpBig decodes a string item as a non-negative integer.
+func rlpBig(item interface{}) (*big.Int, error) {
+	b, err := rlpBytes(item)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// rlpUint decodes a string item as a uint64.
+func rlpUint(item interface{}) (uint64, error) {
+	v, err := rlpBig(item)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsUint64() {
+		return 0, fmt.Errorf("rlp: integer overflows uint64")
+	}
+	return v.Uint64(), nil
+}
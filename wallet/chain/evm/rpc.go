@@ -0,0 +1,109 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// rpcClient is a minimal Ethereum JSON-RPC 2.0 client. Pulling in go-ethereum
+// for a handful of calls would dwarf the rest of the wallet.
+type rpcClient struct {
+	endpoint string
+	http     *http.Client
+	nextID   atomic.Uint64
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// rpcError is a JSON-RPC error object returned by the node.
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+func newRPCClient(endpoint string) *rpcClient {
+	return &rpcClient{endpoint: endpoint, http: http.DefaultClient}
+}
+
+// call invokes method with params and unmarshals the result into out (which
+// may be nil to discard it).
+func (c *rpcClient) call(ctx context.Context, out interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: c.nextID.Add(1), Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("encoding %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected HTTP status %s", method, resp.Status)
+	}
+	var rr rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rr); err != nil {
+		return fmt.Errorf("decoding %s response: %w", method, err)
+	}
+	if rr.Error != nil {
+		return fmt.Errorf("%s: %w", method, rr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(rr.Result, out)
+}
+
+// hexBig is a big.Int that (un)marshals as an Ethereum QUANTITY.
+type hexBig big.Int
+
+func (h *hexBig) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		return fmt.Errorf("invalid hex quantity %q", s)
+	}
+	*h = hexBig(*v)
+	return nil
+}
+
+func (h *hexBig) big() *big.Int { return (*big.Int)(h) }
+
+// quantity formats v as an Ethereum QUANTITY string.
+func quantity(v *big.Int) string {
+	if v == nil {
+		return "0x0"
+	}
+	return "0x" + v.Text(16)
+}
@@ -0,0 +1,188 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+)
+
+// dynamicFeeTxType is the EIP-2718 type byte of EIP-1559 transactions.
+const dynamicFeeTxType = 0x02
+
+// dynamicFeeTx holds the fields of an EIP-1559 transaction. Access lists are
+// always empty since the wallet only issues plain value transfers.
+type dynamicFeeTx struct {
+	ChainID   *big.Int
+	Nonce     uint64
+	GasTipCap *big.Int // maxPriorityFeePerGas
+	GasFeeCap *big.Int // maxFeePerGas
+	Gas       uint64
+	To        []byte // 20-byte recipient
+	Value     *big.Int
+	Data      []byte
+}
+
+func (tx *dynamicFeeTx) fields() []interface{} {
+	return []interface{}{
+		tx.ChainID, tx.Nonce, tx.GasTipCap, tx.GasFeeCap, tx.Gas,
+		tx.To, tx.Value, tx.Data, []interface{}{},
+	}
+}
+
+// encodeUnsigned returns the typed envelope 0x02 || rlp(fields).
+func (tx *dynamicFeeTx) encodeUnsigned() ([]byte, error) {
+	body, err := rlpEncode(tx.fields())
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{dynamicFeeTxType}, body...), nil
+}
+
+// encodeSigned returns 0x02 || rlp(fields ++ [yParity, r, s]).
+func (tx *dynamicFeeTx) encodeSigned(sig *signature) ([]byte, error) {
+	body, err := rlpEncode(append(tx.fields(), uint64(sig.V), sig.R, sig.S))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{dynamicFeeTxType}, body...), nil
+}
+
+// sigHash is the digest that has to be signed by the sender.
+func (tx *dynamicFeeTx) sigHash() ([]byte, error) {
+	env, err := tx.encodeUnsigned()
+	if err != nil {
+		return nil, err
+	}
+	return keccak256(env), nil
+}
+
+// decodeDynamicFeeTx parses an unsigned typed envelope.
+func decodeDynamicFeeTx(env []byte) (*dynamicFeeTx, error) {
+	if len(env) == 0 || env[0] != dynamicFeeTxType {
+		return nil, fmt.Errorf("not an EIP-1559 transaction envelope")
+	}
+	item, err := rlpDecode(env[1:])
+	if err != nil {
+		return nil, err
+	}
+	list, ok := item.([]interface{})
+	if !ok || len(list) != 9 {
+		return nil, fmt.Errorf("malformed EIP-1559 transaction body")
+	}
+	tx := &dynamicFeeTx{}
+	if tx.ChainID, err = rlpBig(list[0]); err != nil {
+		return nil, err
+	}
+	if tx.Nonce, err = rlpUint(list[1]); err != nil {
+		return nil, err
+	}
+	if tx.GasTipCap, err = rlpBig(list[2]); err != nil {
+		return nil, err
+	}
+	if tx.GasFeeCap, err = rlpBig(list[3]); err != nil {
+		return nil, err
+	}
+	if tx.Gas, err = rlpUint(list[4]); err != nil {
+		return nil, err
+	}
+	if tx.To, err = rlpBytes(list[5]); err != nil {
+		return nil, err
+	}
+	if len(tx.To) != 20 {
+		return nil, fmt.Errorf("contract creation is not supported")
+	}
+	if tx.Value, err = rlpBig(list[6]); err != nil {
+		return nil, err
+	}
+	if tx.Data, err = rlpBytes(list[7]); err != nil {
+		return nil, err
+	}
+	if al, ok := list[8].([]interface{}); !ok || len(al) != 0 {
+		return nil, fmt.Errorf("access lists are not supported")
+	}
+	return tx, nil
+}
+
+// encodePayload wraps the unsigned envelope together with the sender address.
+// EVM transactions do not carry their sender, so without it an offline
+// approver could not tell which account is being debited.
+func encodePayload(from []byte, tx *dynamicFeeTx) ([]byte, error) {
+	env, err := tx.encodeUnsigned()
+	if err != nil {
+		return nil, err
+	}
+	return rlpEncode([]interface{}{from, env})
+}
+
+// decodePayload reverses encodePayload.
+func decodePayload(payload []byte) ([]byte, *dynamicFeeTx, error) {
+	item, err := rlpDecode(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	list, ok := item.([]interface{})
+	if !ok || len(list) != 2 {
+		return nil, nil, fmt.Errorf("malformed payload")
+	}
+	from, err := rlpBytes(list[0])
+	if err != nil || len(from) != 20 {
+		return nil, nil, fmt.Errorf("malformed sender in payload")
+	}
+	env, err := rlpBytes(list[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := decodeDynamicFeeTx(env)
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, tx, nil
+}
+
+// signature is a normalised secp256k1 signature with recovery id.
+type signature struct {
+	R, S *big.Int
+	V    byte // y-parity, 0 or 1
+}
+
+// parseSignature accepts DER (as produced by the MPC engine), 64-byte r||s or
+// 65-byte r||s||v encodings. The recovery id is resolved against the expected
+// sender and s is normalised to the lower half of the curve order (EIP-2).
+func parseSignature(raw, hash, from []byte) (*signature, error) {
+	var rs []byte
+	switch len(raw) {
+	case 64, 65:
+		rs = raw[:64]
+	default:
+		var der struct{ R, S *big.Int }
+		rest, err := asn1.Unmarshal(raw, &der)
+		if err != nil || len(rest) != 0 {
+			return nil, fmt.Errorf("unrecognised signature encoding (%d bytes)", len(raw))
+		}
+		if der.R.Sign() <= 0 || der.S.Sign() <= 0 || der.R.BitLen() > 256 || der.S.BitLen() > 256 {
+			return nil, fmt.Errorf("signature values out of range")
+		}
+		rs = make([]byte, 64)
+		der.R.FillBytes(rs[:32])
+		der.S.FillBytes(rs[32:])
+	}
+	for v := byte(0); v < 2; v++ {
+		pub, err := verify.RecoverSecp256k1(hash, append(bytes.Clone(rs), v))
+		if err != nil || !bytes.Equal(addressFromPublicKey(pub), from) {
+			continue
+		}
+		rsv, err := verify.RecoverableSecp256k1(pub, hash, rs)
+		if err != nil {
+			return nil, err
+		}
+		return &signature{
+			R: new(big.Int).SetBytes(rsv[:32]),
+			S: new(big.Int).SetBytes(rsv[32:64]),
+			V: rsv[64],
+		}, nil
+	}
+	return nil, fmt.Errorf("signature does not match sender %s", checksumAddress(from))
+}
@@ -0,0 +1,40 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+)
+
+// FeeEstimate describes what a transaction is expected to pay the network.
+//
+// The pricing fields are interpreted per chain:
+//
+//   - Solana: Limit is the compute-unit limit, Price the base fee per
+//     signature in lamports and Tip the priority fee in micro-lamports per
+//     compute unit.
+//   - EVM: Limit is the gas limit, Price the maxFeePerGas and Tip the
+//     maxPriorityFeePerGas, both in wei.
+//
+// MaxFee is the chain-independent upper bound in the chain's smallest unit
+// and is what approvers are shown.
+type FeeEstimate struct {
+	Limit  uint64
+	Price  *big.Int
+	Tip    *big.Int
+	MaxFee *big.Int
+}
+
+// FeeOracle estimates network fees for a transfer.  Every Chain ships a
+// default oracle; callers may plug in their own (e.g. a fixed-price oracle for
+// tests or one backed by a commercial fee API).
+type FeeOracle interface {
+	EstimateFee(ctx context.Context, transfer *Transfer) (*FeeEstimate, error)
+}
+
+// FeeOracleFunc adapts an ordinary function to the FeeOracle interface.
+type FeeOracleFunc func(ctx context.Context, transfer *Transfer) (*FeeEstimate, error)
+
+// EstimateFee calls f(ctx, transfer).
+func (f FeeOracleFunc) EstimateFee(ctx context.Context, transfer *Transfer) (*FeeEstimate, error) {
+	return f(ctx, transfer)
+}
@@ -0,0 +1,66 @@
+package chain
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentHash(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	summary := &Summary{Chain: "solana-devnet", From: "A", To: "B", Amount: big.NewInt(10), Fee: big.NewInt(5000)}
+	base := IntentOf(summary, "A", start, start.Add(time.Hour))
+	want, err := base.Hash()
+	require.NoError(t, err)
+
+	// The fee and the exact encoding are not part of the intent.
+	same := *summary
+	same.Fee = big.NewInt(7000)
+	got, err := IntentOf(&same, "A", start, start.Add(time.Hour)).Hash()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	for name, mutate := range map[string]func(i *Intent){
+		"chain":      func(i *Intent) { i.Chain = "solana-mainnet" },
+		"key":        func(i *Intent) { i.KeyID = "C" },
+		"token":      func(i *Intent) { i.Token = "mint" },
+		"recipient":  func(i *Intent) { i.Outputs = []Output{{To: "C", Amount: big.NewInt(10)}} },
+		"amount":     func(i *Intent) { i.Outputs = []Output{{To: "B", Amount: big.NewInt(11)}} },
+		"not before": func(i *Intent) { i.NotBefore = time.Time{} },
+		"not after":  func(i *Intent) { i.NotAfter = i.NotAfter.Add(time.Second) },
+		// Length prefixes keep fields from running into each other.
+		"boundary": func(i *Intent) { i.Chain, i.KeyID = "solana-devne", "tA" },
+	} {
+		i := *base
+		mutate(&i)
+		got, err := i.Hash()
+		require.NoError(t, err, name)
+		assert.NotEqual(t, want, got, name)
+	}
+}
+
+func TestIntentHashRejectsIncomplete(t *testing.T) {
+	for _, i := range []*Intent{
+		{KeyID: "A", Outputs: []Output{{To: "B", Amount: big.NewInt(1)}}},
+		{Chain: "c", Outputs: []Output{{To: "B", Amount: big.NewInt(1)}}},
+		{Chain: "c", KeyID: "A"},
+		{Chain: "c", KeyID: "A", Outputs: []Output{{To: "B"}}},
+		{Chain: "c", KeyID: "A", Outputs: []Output{{To: "B", Amount: big.NewInt(-1)}}},
+	} {
+		_, err := i.Hash()
+		assert.Error(t, err)
+	}
+}
+
+func TestIntentWithin(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	i := &Intent{NotBefore: start, NotAfter: start.Add(time.Hour)}
+	assert.False(t, i.Within(start.Add(-time.Second)))
+	assert.True(t, i.Within(start))
+	assert.True(t, i.Within(start.Add(time.Hour)))
+	assert.False(t, i.Within(start.Add(time.Hour+time.Second)))
+	assert.True(t, (&Intent{}).Within(start))
+}
@@ -0,0 +1,36 @@
+package solana
+
+import (
+	"github.com/gagliardetto/solana-go"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// maxTransactionSize is the largest serialised transaction the network
+// accepts: the IPv6 minimum MTU minus the packet headers.
+const maxTransactionSize = 1232
+
+// Ensure Chain implements the chain.Batcher interface
+var _ chain.Batcher = (*Chain)(nil)
+
+// Fits reports whether transfer, including the compute-budget instructions of
+// a priority fee, fits into a single transaction signed by the sender.
+// Outputs paying the same recipient share its account key and so take less
+// space than outputs to distinct recipients.
+func (c *Chain) Fits(transfer *chain.Transfer) (bool, error) {
+	fee := newFeeEstimate(1, defaultTransferComputeUnits, 1)
+	var (
+		utx *chain.UnsignedTx
+		err error
+	)
+	if transfer.Token != "" {
+		utx, err = c.buildTokenTransfer(transfer, solana.Hash{}, fee, 0)
+	} else {
+		utx, err = c.buildTransfer(transfer, solana.Hash{}, fee)
+	}
+	if err != nil {
+		return false, err
+	}
+	// A single compact-array length byte and signature precede the message.
+	return 1+solana.SignatureLength+len(utx.Payload) <= maxTransactionSize, nil
+}
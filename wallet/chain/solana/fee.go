@@ -0,0 +1,128 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/retry"
+)
+
+const (
+	// lamportsPerSignature is the base fee charged for every signature.
+	lamportsPerSignature = 5000
+	// defaultInstructionComputeUnits is what the runtime budgets for each
+	// instruction when no SetComputeUnitLimit instruction is present.
+	defaultInstructionComputeUnits = 200_000
+	// defaultTransferComputeUnits comfortably covers a system transfer plus
+	// the two compute-budget instructions (150 CU each).
+	defaultTransferComputeUnits = 1_000
+	// defaultFeePercentile is the percentile of recent prioritization fees
+	// that the oracle bids.
+	defaultFeePercentile = 75
+)
+
+// PriorityFeeConfig tunes the PriorityFeeOracle. The zero value selects the
+// defaults.
+type PriorityFeeConfig struct {
+	// Percentile (1-100) of recently paid prioritization fees to bid.
+	Percentile int
+	// ComputeUnitLimit requested for transfers.
+	ComputeUnitLimit uint32
+	// MaxMicroLamports caps the bid per compute unit; zero means no cap.
+	MaxMicroLamports uint64
+	// Retry governs how failed RPC calls are repeated.
+	Retry retry.Policy
+}
+
+// PriorityFeeOracle estimates Solana fees from getRecentPrioritizationFees.
+type PriorityFeeOracle struct {
+	client *rpc.Client
+	config PriorityFeeConfig
+}
+
+// Ensure PriorityFeeOracle implements the chain.FeeOracle interface
+var _ chain.FeeOracle = (*PriorityFeeOracle)(nil)
+
+// NewPriorityFeeOracle creates an oracle that queries the given RPC endpoint.
+func NewPriorityFeeOracle(endpoint string, config PriorityFeeConfig) (*PriorityFeeOracle, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("RPC endpoint must be provided")
+	}
+	if config.Percentile == 0 {
+		config.Percentile = defaultFeePercentile
+	}
+	if config.Percentile < 1 || config.Percentile > 100 {
+		return nil, fmt.Errorf("percentile must be between 1 and 100, got %d", config.Percentile)
+	}
+	if config.ComputeUnitLimit == 0 {
+		config.ComputeUnitLimit = defaultTransferComputeUnits
+	}
+	return &PriorityFeeOracle{client: rpc.New(endpoint), config: config}, nil
+}
+
+// EstimateFee bids the configured percentile of the prioritization fees
+// recently paid by transactions writing to the sender account.
+func (o *PriorityFeeOracle) EstimateFee(ctx context.Context, transfer *chain.Transfer) (*chain.FeeEstimate, error) {
+	var accounts solana.PublicKeySlice
+	if transfer != nil {
+		if from, err := solana.PublicKeyFromBase58(transfer.From); err == nil {
+			accounts = append(accounts, from)
+		}
+	}
+	var recent []rpc.PriorizationFeeResult
+	err := call(ctx, o.config.Retry, func(ctx context.Context) (err error) {
+		recent, err = o.client.GetRecentPrioritizationFees(ctx, accounts)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching prioritization fees: %w", err)
+	}
+	fees := make([]uint64, len(recent))
+	for i, r := range recent {
+		fees[i] = r.PrioritizationFee
+	}
+	tip := percentile(fees, o.config.Percentile)
+	if o.config.MaxMicroLamports != 0 && tip > o.config.MaxMicroLamports {
+		tip = o.config.MaxMicroLamports
+	}
+	return newFeeEstimate(1, o.config.ComputeUnitLimit, tip), nil
+}
+
+// newFeeEstimate prices a transaction with the given number of signatures,
+// compute-unit limit and priority fee in micro-lamports per unit.
+func newFeeEstimate(signatures int, limit uint32, microLamports uint64) *chain.FeeEstimate {
+	return &chain.FeeEstimate{
+		Limit:  uint64(limit),
+		Price:  big.NewInt(lamportsPerSignature),
+		Tip:    new(big.Int).SetUint64(microLamports),
+		MaxFee: maxFee(signatures, uint64(limit), microLamports),
+	}
+}
+
+// maxFee returns the base fee plus the priority fee rounded up to whole
+// lamports, matching the runtime's fee calculation.
+func maxFee(signatures int, limit, microLamports uint64) *big.Int {
+	priority := new(big.Int).Mul(new(big.Int).SetUint64(limit), new(big.Int).SetUint64(microLamports))
+	priority.Add(priority, big.NewInt(999_999))
+	priority.Div(priority, big.NewInt(1_000_000))
+	return priority.Add(priority, big.NewInt(int64(signatures)*lamportsPerSignature))
+}
+
+// percentile returns the p-th percentile (nearest rank) of values.
+func percentile(values []uint64, p int) uint64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	rank := (p*len(values) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return values[rank-1]
+}
@@ -0,0 +1,109 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// maxSignaturesPerPage is the most signatures getSignaturesForAddress
+// returns per request.
+const maxSignaturesPerPage = 1000
+
+// Ensure Chain implements the chain.History interface
+var _ chain.History = (*Chain)(nil)
+
+// Transactions implements chain.History.  The payload of each transaction is
+// its serialised message, which is what BuildTransfer has signed, and the
+// signature is address's if it is one of the required signers.  Failed
+// transactions are included: they were signed all the same.
+func (c *Chain) Transactions(ctx context.Context, address string, limit int) ([]chain.HistoryTx, error) {
+	owner, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	var sigs []*rpc.TransactionSignature
+	for len(sigs) < limit {
+		n := min(limit-len(sigs), maxSignaturesPerPage)
+		opts := &rpc.GetSignaturesForAddressOpts{Limit: &n, Commitment: c.commitment}
+		if len(sigs) > 0 {
+			opts.Before = sigs[len(sigs)-1].Signature
+		}
+		var page []*rpc.TransactionSignature
+		err = c.call(ctx, func(ctx context.Context) (err error) {
+			page, err = c.client.GetSignaturesForAddressWithOpts(ctx, owner, opts)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetching signatures: %w", err)
+		}
+		sigs = append(sigs, page...)
+		if len(page) < n {
+			break
+		}
+	}
+
+	var version uint64
+	txs := make([]chain.HistoryTx, 0, len(sigs))
+	for _, s := range sigs {
+		var res *rpc.GetTransactionResult
+		err = c.call(ctx, func(ctx context.Context) (err error) {
+			res, err = c.client.GetTransaction(ctx, s.Signature, &rpc.GetTransactionOpts{
+				Encoding:                       solana.EncodingBase64,
+				Commitment:                     c.commitment,
+				MaxSupportedTransactionVersion: &version,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fetching transaction %s: %w", s.Signature, err)
+		}
+		if res.Transaction == nil {
+			return nil, fmt.Errorf("transaction %s has no data", s.Signature)
+		}
+		msg, sig, err := signedBy(res.Transaction.GetBinary(), owner)
+		if err != nil {
+			return nil, fmt.Errorf("decoding transaction %s: %w", s.Signature, err)
+		}
+		tx := chain.HistoryTx{TxID: s.Signature.String(), Height: res.Slot, Payload: msg, Signature: sig}
+		if res.BlockTime != nil {
+			tx.Time = res.BlockTime.Time().UTC()
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// signedBy splits a serialised transaction into its message, exactly as it
+// was signed, and the signature of signer, which is nil if signer is not a
+// required signer of the message.
+func signedBy(raw []byte, signer solana.PublicKey) (msg, sig []byte, err error) {
+	n, size, err := bin.DecodeCompactU16(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	offset := size + n*solana.SignatureLength
+	if len(raw) < offset {
+		return nil, nil, fmt.Errorf("truncated signatures")
+	}
+	msg = raw[offset:]
+	var m solana.Message
+	if err := m.UnmarshalWithDecoder(bin.NewBinDecoder(msg)); err != nil {
+		return nil, nil, err
+	}
+	if int(m.Header.NumRequiredSignatures) != n || len(m.AccountKeys) < n {
+		return nil, nil, fmt.Errorf("%d signatures for %d signers", n, m.Header.NumRequiredSignatures)
+	}
+	for i, key := range m.AccountKeys[:n] {
+		if key.Equals(signer) {
+			at := size + i*solana.SignatureLength
+			return msg, raw[at : at+solana.SignatureLength], nil
+		}
+	}
+	return msg, nil, nil
+}
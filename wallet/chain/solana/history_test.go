@@ -0,0 +1,44 @@
+package solana
+
+import (
+	"crypto/ed25519"
+	"math/big"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+func TestSignedBy(t *testing.T) {
+	c := testChain(t)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	from := solana.PublicKeyFromBytes(pub)
+	utx, err := c.buildTransfer(&chain.Transfer{
+		From:   from.String(),
+		To:     solana.NewWallet().PublicKey().String(),
+		Amount: big.NewInt(1_000_000),
+	}, solana.Hash{1, 2, 3}, newFeeEstimate(1, 1_000, 2_500))
+	require.NoError(t, err)
+	sig := ed25519.Sign(priv, utx.SigningPayload)
+	stx, err := assemble(&chain.SignedTx{Unsigned: utx, Signature: sig})
+	require.NoError(t, err)
+	raw, err := stx.MarshalBinary()
+	require.NoError(t, err)
+
+	msg, got, err := signedBy(raw, from)
+	require.NoError(t, err)
+	assert.Equal(t, utx.SigningPayload, msg, "the message is recovered exactly as signed")
+	assert.Equal(t, sig, got)
+
+	msg, got, err = signedBy(raw, solana.NewWallet().PublicKey())
+	require.NoError(t, err)
+	assert.Equal(t, utx.SigningPayload, msg)
+	assert.Nil(t, got, "not a signer")
+
+	_, _, err = signedBy(raw[:40], from)
+	assert.Error(t, err)
+}
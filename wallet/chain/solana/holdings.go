@@ -0,0 +1,111 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// stakeWithdrawerOffset is the offset of the withdraw authority in a stake
+// account: a 4-byte state tag, the 8-byte rent-exempt reserve and the
+// 32-byte stake authority precede it.
+const stakeWithdrawerOffset = 4 + 8 + 32
+
+// Ensure Chain implements the chain.Inventory interface
+var _ chain.Inventory = (*Chain)(nil)
+
+// Holdings implements chain.Inventory: the SOL balance of address, its SPL
+// token accounts with a non-zero balance and the stake accounts it is the
+// withdraw authority of.  Only tokens in associated token accounts are
+// transferable; stake accounts never are, as BuildTransfer cannot change
+// their authorities.
+func (c *Chain) Holdings(ctx context.Context, address string) ([]chain.Holding, error) {
+	owner, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	var balance *rpc.GetBalanceResult
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		balance, err = c.client.GetBalance(ctx, owner, c.commitment)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching balance: %w", err)
+	}
+	holdings := []chain.Holding{{
+		Kind:         chain.HoldingNative,
+		Account:      address,
+		Transferable: true,
+		Amount:       new(big.Int).SetUint64(balance.Value),
+	}}
+
+	var tokens *rpc.GetTokenAccountsResult
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		tokens, err = c.client.GetTokenAccountsByOwner(ctx, owner,
+			&rpc.GetTokenAccountsConfig{ProgramId: &solana.TokenProgramID},
+			&rpc.GetTokenAccountsOpts{Commitment: c.commitment, Encoding: solana.EncodingBase64})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching token accounts: %w", err)
+	}
+	var found []chain.Holding
+	for _, ka := range tokens.Value {
+		var ta token.Account
+		if err := ta.UnmarshalWithDecoder(bin.NewBinDecoder(ka.Account.Data.GetBinary())); err != nil {
+			return nil, fmt.Errorf("decoding token account %s: %w", ka.Pubkey, err)
+		}
+		if ta.Amount == 0 {
+			continue
+		}
+		ata, _, err := solana.FindAssociatedTokenAddress(owner, ta.Mint)
+		if err != nil {
+			return nil, fmt.Errorf("deriving token account: %w", err)
+		}
+		found = append(found, chain.Holding{
+			Kind:         chain.HoldingToken,
+			Token:        ta.Mint.String(),
+			Account:      ka.Pubkey.String(),
+			Transferable: ka.Pubkey.Equals(ata),
+			Amount:       new(big.Int).SetUint64(ta.Amount),
+		})
+	}
+
+	var stakes rpc.GetProgramAccountsResult
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		stakes, err = c.client.GetProgramAccountsWithOpts(ctx, solana.StakeProgramID, &rpc.GetProgramAccountsOpts{
+			Commitment: c.commitment,
+			Filters: []rpc.RPCFilter{{
+				Memcmp: &rpc.RPCFilterMemcmp{Offset: stakeWithdrawerOffset, Bytes: owner.Bytes()},
+			}},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching stake accounts: %w", err)
+	}
+	for _, ka := range stakes {
+		found = append(found, chain.Holding{
+			Kind:    chain.HoldingStake,
+			Account: ka.Pubkey.String(),
+			Amount:  new(big.Int).SetUint64(ka.Account.Lamports),
+		})
+	}
+
+	// RPC nodes return accounts in no particular order.
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Kind != found[j].Kind {
+			return found[i].Kind > found[j].Kind // token before stake
+		}
+		return found[i].Account < found[j].Account
+	})
+	return append(holdings, found...), nil
+}
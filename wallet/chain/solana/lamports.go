@@ -0,0 +1,98 @@
+package solana
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"solana-threshold-wallet/wallet/display"
+)
+
+// solDecimals is the number of decimal places of one SOL.
+const solDecimals = 9
+
+// LamportsPerSOL is the number of lamports in one SOL.
+const LamportsPerSOL Lamports = 1_000_000_000
+
+// Lamports is an amount of SOL in its smallest unit.  It is written as a
+// decimal number of SOL, such as "0.01", and never converted through
+// floating point.
+type Lamports uint64
+
+// ParseSOL converts a decimal amount of SOL, such as "1.5", into lamports.
+// It rejects amounts with more than nine decimals, signs, exponents and
+// amounts that do not fit in a uint64.
+func ParseSOL(s string) (Lamports, error) {
+	whole, frac, dotted := strings.Cut(s, ".")
+	if whole == "" || (dotted && frac == "") || !digits(whole) || !digits(frac) {
+		return 0, fmt.Errorf("invalid SOL amount %q", s)
+	}
+	if len(frac) > solDecimals {
+		return 0, fmt.Errorf("SOL amount %q has more than %d decimals", s, solDecimals)
+	}
+	v, _ := new(big.Int).SetString(whole+frac+strings.Repeat("0", solDecimals-len(frac)), 10)
+	return LamportsFromBig(v)
+}
+
+// LamportsFromBig converts an amount in lamports, such as chain.Output's,
+// checking that it fits.
+func LamportsFromBig(v *big.Int) (Lamports, error) {
+	if v == nil || v.Sign() < 0 || !v.IsUint64() {
+		return 0, fmt.Errorf("amount %v is out of range for lamports", v)
+	}
+	return Lamports(v.Uint64()), nil
+}
+
+// Big returns l as a *big.Int, the representation of chain amounts.
+func (l Lamports) Big() *big.Int {
+	return new(big.Int).SetUint64(uint64(l))
+}
+
+// SOL returns l as a decimal number of SOL without trailing zeros.
+func (l Lamports) SOL() string {
+	return strings.TrimSuffix(display.FormatAmount(l.Big(), display.Asset{Decimals: solDecimals}), " ")
+}
+
+// String returns l in SOL followed by the ticker, e.g. "0.01 SOL".
+func (l Lamports) String() string {
+	return l.SOL() + " SOL"
+}
+
+// Add returns l + o, or an error if the sum overflows.
+func (l Lamports) Add(o Lamports) (Lamports, error) {
+	if o > math.MaxUint64-l {
+		return 0, fmt.Errorf("%d + %d lamports overflows", l, o)
+	}
+	return l + o, nil
+}
+
+// MarshalJSON encodes l as a decimal string of SOL.
+func (l Lamports) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.SOL())
+}
+
+// UnmarshalJSON decodes a decimal string of SOL.  JSON numbers are refused,
+// as decoders commonly read them as float64.
+func (l *Lamports) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("SOL amount must be a decimal string: %v", err)
+	}
+	v, err := ParseSOL(s)
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
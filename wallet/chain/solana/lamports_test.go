@@ -0,0 +1,73 @@
+package solana
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSOL(t *testing.T) {
+	for s, want := range map[string]Lamports{
+		"0.01":        10_000_000,
+		"1":           LamportsPerSOL,
+		"1.5":         1_500_000_000,
+		"0.000000001": 1,
+		"0":           0,
+		// 0.1 + 0.2 is exact, unlike in float64.
+		"0.3":                   300_000_000,
+		"18446744073.709551615": math.MaxUint64,
+	} {
+		got, err := ParseSOL(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"", ".5", "1.", "-1", "+1", "1e9", "0.0000000001", "1,5", " 1", "18446744073.709551616"} {
+		_, err := ParseSOL(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestLamportsFormat(t *testing.T) {
+	assert.Equal(t, "0.01 SOL", Lamports(10_000_000).String())
+	assert.Equal(t, "1 SOL", LamportsPerSOL.String())
+	assert.Equal(t, "0.000000001", Lamports(1).SOL())
+	assert.Equal(t, "0", Lamports(0).SOL())
+	assert.Equal(t, "18446744073.709551615", Lamports(math.MaxUint64).SOL())
+
+	for _, l := range []Lamports{0, 1, 123_456_789, LamportsPerSOL, math.MaxUint64} {
+		back, err := ParseSOL(l.SOL())
+		require.NoError(t, err)
+		assert.Equal(t, l, back)
+	}
+}
+
+func TestLamportsJSON(t *testing.T) {
+	var v struct {
+		Amount Lamports `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"0.01"}`), &v))
+	assert.Equal(t, Lamports(10_000_000), v.Amount)
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"0.01"}`, string(out))
+
+	// Numbers would have gone through float64.
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":0.01}`), &v))
+}
+
+func TestLamportsArithmetic(t *testing.T) {
+	sum, err := Lamports(1).Add(2)
+	require.NoError(t, err)
+	assert.Equal(t, Lamports(3), sum)
+	_, err = Lamports(math.MaxUint64).Add(1)
+	assert.Error(t, err)
+
+	l, err := LamportsFromBig(LamportsPerSOL.Big())
+	require.NoError(t, err)
+	assert.Equal(t, LamportsPerSOL, l)
+	_, err = LamportsFromBig(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package solana
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// OffchainSigningDomain prefixes every offchain message.  As the first byte
+// of a transaction message, 0xff would announce message version 127, which
+// does not exist, so an offchain message can never be replayed as a
+// transaction.
+const OffchainSigningDomain = "\xffsolana offchain"
+
+const (
+	offchainVersion = 0
+	// offchainLedgerMax bounds the serialized size of messages in the
+	// formats hardware wallets display, the size of a transaction packet.
+	offchainLedgerMax = 1232
+	// offchainFixedLen is the size of the signing domain, version,
+	// application domain, format, signer count and length fields.
+	offchainFixedLen = len(OffchainSigningDomain) + 1 + 32 + 1 + 1 + 2
+)
+
+// OffchainFormat is the message format of an offchain message.
+type OffchainFormat uint8
+
+// Offchain message formats, from most to least restricted.
+const (
+	// OffchainRestrictedASCII allows printable ASCII characters and limits
+	// the serialized message to a transaction packet, so hardware wallets
+	// can display it.
+	OffchainRestrictedASCII OffchainFormat = 0
+	// OffchainLimitedUTF8 allows any UTF-8 with the same size limit.
+	OffchainLimitedUTF8 OffchainFormat = 1
+	// OffchainExtendedUTF8 allows UTF-8 bodies of up to 65535 bytes.
+	OffchainExtendedUTF8 OffchainFormat = 2
+)
+
+// OffchainMessage is a version 0 Solana offchain message:
+//
+//	signing domain     16 bytes  "\xffsolana offchain"
+//	header version      1 byte   0
+//	application domain 32 bytes
+//	message format      1 byte   OffchainFormat
+//	signer count        1 byte
+//	signers            32 bytes each
+//	message length      2 bytes  little endian
+//	message body
+type OffchainMessage struct {
+	// ApplicationDomain identifies the dApp the message is meant for, so
+	// that a signature for one application cannot be presented to another.
+	ApplicationDomain [32]byte
+	Format            OffchainFormat
+	Signers           []solana.PublicKey
+	Body              []byte
+}
+
+// NewOffchainMessage creates a message in the most restricted format that
+// can hold body.
+func NewOffchainMessage(domain [32]byte, signers []solana.PublicKey, body []byte) (*OffchainMessage, error) {
+	m := &OffchainMessage{ApplicationDomain: domain, Signers: signers, Body: body}
+	for _, f := range []OffchainFormat{OffchainRestrictedASCII, OffchainLimitedUTF8, OffchainExtendedUTF8} {
+		m.Format = f
+		if m.validate() == nil {
+			return m, nil
+		}
+	}
+	return nil, m.validate()
+}
+
+// validate checks the signers and that the body is allowed by the format.
+func (m *OffchainMessage) validate() error {
+	if len(m.Signers) == 0 || len(m.Signers) > 255 {
+		return fmt.Errorf("offchain message must have 1 to 255 signers")
+	}
+	seen := make(map[solana.PublicKey]bool, len(m.Signers))
+	for _, s := range m.Signers {
+		if seen[s] {
+			return fmt.Errorf("duplicate offchain message signer %s", s)
+		}
+		seen[s] = true
+	}
+	if len(m.Body) == 0 {
+		return fmt.Errorf("offchain message body must not be empty")
+	}
+	size := offchainFixedLen + 32*len(m.Signers) + len(m.Body)
+	switch m.Format {
+	case OffchainRestrictedASCII:
+		for _, b := range m.Body {
+			if b < 0x20 || b > 0x7e {
+				return fmt.Errorf("offchain message body is not printable ASCII")
+			}
+		}
+		if size > offchainLedgerMax {
+			return fmt.Errorf("offchain message of %d bytes exceeds %d", size, offchainLedgerMax)
+		}
+	case OffchainLimitedUTF8:
+		if !utf8.Valid(m.Body) {
+			return fmt.Errorf("offchain message body is not UTF-8")
+		}
+		if size > offchainLedgerMax {
+			return fmt.Errorf("offchain message of %d bytes exceeds %d", size, offchainLedgerMax)
+		}
+	case OffchainExtendedUTF8:
+		if !utf8.Valid(m.Body) {
+			return fmt.Errorf("offchain message body is not UTF-8")
+		}
+		if len(m.Body) > 0xffff {
+			return fmt.Errorf("offchain message body of %d bytes exceeds %d", len(m.Body), 0xffff)
+		}
+	default:
+		return fmt.Errorf("unknown offchain message format %d", m.Format)
+	}
+	return nil
+}
+
+// Serialize returns the bytes to be signed.
+func (m *OffchainMessage) Serialize() ([]byte, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(OffchainSigningDomain)
+	buf.WriteByte(offchainVersion)
+	buf.Write(m.ApplicationDomain[:])
+	buf.WriteByte(byte(m.Format))
+	buf.WriteByte(byte(len(m.Signers)))
+	for _, s := range m.Signers {
+		buf.Write(s[:])
+	}
+	buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(m.Body))))
+	buf.Write(m.Body)
+	return buf.Bytes(), nil
+}
+
+// HasSigner reports whether key is one of the message's signers.
+func (m *OffchainMessage) HasSigner(key solana.PublicKey) bool {
+	for _, s := range m.Signers {
+		if s.Equals(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOffchainMessage reports whether data starts with the offchain signing
+// domain.
+func IsOffchainMessage(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(OffchainSigningDomain))
+}
+
+// ParseOffchainMessage parses a serialized offchain message.  Unknown
+// versions, bodies not allowed by the declared format and trailing bytes are
+// rejected.
+func ParseOffchainMessage(data []byte) (*OffchainMessage, error) {
+	if !IsOffchainMessage(data) {
+		return nil, fmt.Errorf("missing offchain signing domain")
+	}
+	rest := data[len(OffchainSigningDomain):]
+	if len(rest) < offchainFixedLen-len(OffchainSigningDomain) {
+		return nil, fmt.Errorf("truncated offchain message header")
+	}
+	if rest[0] != offchainVersion {
+		return nil, fmt.Errorf("unsupported offchain message version %d", rest[0])
+	}
+	m := &OffchainMessage{Format: OffchainFormat(rest[33])}
+	copy(m.ApplicationDomain[:], rest[1:33])
+	count := int(rest[34])
+	rest = rest[35:]
+	if len(rest) < 32*count+2 {
+		return nil, fmt.Errorf("truncated offchain message signers")
+	}
+	for i := 0; i < count; i++ {
+		m.Signers = append(m.Signers, solana.PublicKeyFromBytes(rest[32*i:32*i+32]))
+	}
+	rest = rest[32*count:]
+	length := int(binary.LittleEndian.Uint16(rest))
+	if len(rest)-2 != length {
+		return nil, fmt.Errorf("offchain message length %d does not match body of %d bytes", length, len(rest)-2)
+	}
+	m.Body = rest[2:]
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
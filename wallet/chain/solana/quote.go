@@ -0,0 +1,190 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// Ensure Chain implements the chain.Quoter interface
+var _ chain.Quoter = (*Chain)(nil)
+
+// Quote checks transfer against the current ledger state and prices it.
+//
+// Every recipient must be a wallet rather than a token account.  SOL sent to
+// an account that does not exist yet must cover its rent-exempt minimum; tokens
+// sent to a wallet without an associated token account pay for creating it,
+// which is reported as Quote.Deposit.  The sender must hold the amount plus
+// fee and deposit.
+func (c *Chain) Quote(ctx context.Context, transfer *chain.Transfer) (*chain.Quote, error) {
+	if transfer == nil {
+		return nil, fmt.Errorf("transfer cannot be nil")
+	}
+	from, err := solana.PublicKeyFromBase58(transfer.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	outputs := transfer.Outputs
+	if len(outputs) == 0 {
+		outputs = []chain.Output{{To: transfer.To, Amount: transfer.Amount}}
+	} else if transfer.Token != "" {
+		return nil, fmt.Errorf("batched token transfers are not supported")
+	}
+	total := new(big.Int)
+	recipients := make([]*rpc.Account, len(outputs))
+	for i, o := range outputs {
+		to, err := solana.PublicKeyFromBase58(o.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q: %w", o.To, err)
+		}
+		if o.Amount == nil || o.Amount.Sign() <= 0 || !o.Amount.IsUint64() {
+			return nil, fmt.Errorf("amount must be a positive 64-bit value")
+		}
+		if recipients[i], err = c.account(ctx, to); err != nil {
+			return nil, err
+		}
+		if recipients[i] != nil && recipients[i].Owner.Equals(solana.TokenProgramID) {
+			return nil, fmt.Errorf("%w: %s is a token account, not a wallet", chain.ErrInvalidRecipient, to)
+		}
+		total.Add(total, o.Amount)
+	}
+
+	estimate, err := c.fees.EstimateFee(ctx, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("estimating fee: %w", err)
+	}
+	quote := &chain.Quote{Deposit: new(big.Int)}
+	// Pricing the exact message keeps the quote in line with what approvers
+	// will be shown; the blockhash does not affect the fee.
+	var utx *chain.UnsignedTx
+	needed := new(big.Int)
+	if transfer.Token == "" {
+		var minimum uint64
+		for i, o := range outputs {
+			if recipients[i] != nil {
+				continue
+			}
+			if minimum == 0 {
+				err = c.call(ctx, func(ctx context.Context) (err error) {
+					minimum, err = c.client.GetMinimumBalanceForRentExemption(ctx, 0, c.commitment)
+					return err
+				})
+				if err != nil {
+					return nil, fmt.Errorf("fetching rent-exempt minimum: %w", err)
+				}
+			}
+			if amount := Lamports(o.Amount.Uint64()); amount < Lamports(minimum) {
+				return nil, fmt.Errorf("%w: %s does not exist and %s is below its rent-exempt minimum of %s",
+					chain.ErrInvalidRecipient, o.To, amount, Lamports(minimum))
+			}
+		}
+		needed.Set(total)
+		utx, err = c.buildTransfer(transfer, solana.Hash{}, estimate)
+	} else {
+		mint, err := solana.PublicKeyFromBase58(transfer.Token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token mint: %w", err)
+		}
+		decimals, err := c.mintDecimals(ctx, mint)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.checkTokenBalance(ctx, from, mint, transfer.Amount); err != nil {
+			return nil, err
+		}
+		to := solana.MustPublicKeyFromBase58(transfer.To)
+		destination, _, err := solana.FindAssociatedTokenAddress(to, mint)
+		if err != nil {
+			return nil, fmt.Errorf("deriving recipient token account: %w", err)
+		}
+		account, err := c.account(ctx, destination)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			var rent uint64
+			err := c.call(ctx, func(ctx context.Context) (err error) {
+				rent, err = c.client.GetMinimumBalanceForRentExemption(ctx, tokenAccountSize, c.commitment)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("fetching rent-exempt minimum: %w", err)
+			}
+			quote.Deposit.SetUint64(rent)
+		}
+		if utx, err = c.buildTokenTransfer(transfer, solana.Hash{}, estimate, decimals); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary, err := c.Decode(utx.Payload)
+	if err != nil {
+		return nil, err
+	}
+	quote.Fee = summary.Fee
+
+	var balance *rpc.GetBalanceResult
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		balance, err = c.client.GetBalance(ctx, from, c.commitment)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching sender balance: %w", err)
+	}
+	needed.Add(needed, quote.Fee).Add(needed, quote.Deposit)
+	if new(big.Int).SetUint64(balance.Value).Cmp(needed) < 0 {
+		return nil, fmt.Errorf("%w: %s holds %d lamports, %s needed", chain.ErrInsufficientFunds, from, balance.Value, needed)
+	}
+	return quote, nil
+}
+
+// account fetches an account, returning nil if it does not exist.
+func (c *Chain) account(ctx context.Context, key solana.PublicKey) (*rpc.Account, error) {
+	var info *rpc.GetAccountInfoResult
+	err := c.call(ctx, func(ctx context.Context) (err error) {
+		info, err = c.client.GetAccountInfoWithOpts(ctx, key, &rpc.GetAccountInfoOpts{Commitment: c.commitment})
+		return err
+	})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching account %s: %w", key, err)
+	}
+	return info.Value, nil
+}
+
+// checkTokenBalance ensures owner's associated token account holds at least
+// amount tokens.
+func (c *Chain) checkTokenBalance(ctx context.Context, owner, mint solana.PublicKey, amount *big.Int) error {
+	source, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	if err != nil {
+		return fmt.Errorf("deriving sender token account: %w", err)
+	}
+	account, err := c.account(ctx, source)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return fmt.Errorf("%w: %s has no token account for %s", chain.ErrInsufficientFunds, owner, mint)
+	}
+	var ta token.Account
+	if err := ta.UnmarshalWithDecoder(bin.NewBinDecoder(account.Data.GetBinary())); err != nil {
+		return fmt.Errorf("decoding token account: %w", err)
+	}
+	held := new(big.Int).SetUint64(ta.Amount)
+	if held.Cmp(amount) < 0 {
+		return fmt.Errorf("%w: %s holds %s of token %s, %s needed", chain.ErrInsufficientFunds, owner, held, mint, amount)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+
+	"solana-threshold-wallet/wallet/retry"
+)
+
+// JSON-RPC error codes of Solana nodes that lag the cluster.
+const (
+	codeBlockNotAvailable     = -32004
+	codeNodeUnhealthy         = -32005
+	codeSlotSkipped           = -32007
+	codeBlockStatusNotYet     = -32014
+	codeMinContextSlotNotMet  = -32016
+	codeInternalError         = -32603
+	codeTooManyRequestsInBody = 429 // Some providers report rate limits in the body
+)
+
+// Classify sorts the errors of the solana-go RPC client: HTTP 429 and its
+// JSON-RPC equivalent are rate limits, unhealthy nodes and missing slots or
+// blocks mean the node is behind, server and network errors are transient
+// and everything else, including rejected transactions, is permanent.
+func Classify(err error) retry.Class {
+	var re *jsonrpc.RPCError
+	if errors.As(err, &re) {
+		switch re.Code {
+		case codeTooManyRequestsInBody:
+			return retry.RateLimited
+		case codeBlockNotAvailable, codeNodeUnhealthy, codeSlotSkipped, codeBlockStatusNotYet, codeMinContextSlotNotMet:
+			return retry.NodeBehind
+		case codeInternalError:
+			return retry.Transient
+		}
+		return retry.Permanent
+	}
+	var he *jsonrpc.HTTPError
+	if errors.As(err, &he) {
+		switch {
+		case he.Code == http.StatusTooManyRequests:
+			return retry.RateLimited
+		case he.Code >= 500:
+			return retry.Transient
+		}
+		return retry.Permanent
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return retry.Transient
+	}
+	return retry.Classify(err)
+}
+
+// call runs fn under the chain's retry policy.
+func (c *Chain) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	return call(ctx, c.retry, fn)
+}
+
+// call runs the RPC call fn under policy.  Errors that could succeed on a
+// later call are returned as a *retry.Error, so that callers such as
+// chain.WaitFinalized can tell them from permanent failures.
+func call(ctx context.Context, policy retry.Policy, fn func(ctx context.Context) error) error {
+	return policy.Do(ctx, func(ctx context.Context) error {
+		err := fn(ctx)
+		if class := Classify(err); err != nil && class != retry.Permanent {
+			return &retry.Error{Class: class, Err: err}
+		}
+		return err
+	})
+}
@@ -0,0 +1,445 @@
+// Package solana implements chain.Chain for Solana clusters.
+//
+// Addresses are the base58 encoding of the 32-byte Ed25519 group public key
+// produced by the EdDSA MPC protocols.  The signing payload of a transaction
+// is its serialised message, exactly as required by the Solana runtime.
+//
+// `OffchainMessage` prepares and parses Solana offchain messages, the format
+// dApps and hardware wallets use for signing text outside of transactions.
+//
+// Transactions the ledger rejects – in simulation, in sendTransaction's
+// preflight or after landing – are reported as a `*TransactionError` naming
+// the failing instruction, the program and its custom error code, with the
+// program logs attached, rather than as the node's raw error text.
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/retry"
+)
+
+// Config contains the configuration for a Solana chain instance.
+type Config struct {
+	// ID is the identifier reported by Chain.ID, e.g. "solana-devnet".
+	ID string
+	// RPCEndpoint is the JSON-RPC URL of the cluster, e.g. rpc.DevNet_RPC.
+	RPCEndpoint string
+	// Commitment used when fetching blockhashes and simulating.  Defaults to
+	// rpc.CommitmentFinalized.
+	Commitment rpc.CommitmentType
+	// FeeOracle prices transfers.  Defaults to a PriorityFeeOracle on
+	// RPCEndpoint with default settings and Retry.
+	FeeOracle chain.FeeOracle
+	// Retry governs how RPC calls that fail with a retryable error, as
+	// sorted by Classify, are repeated.  Defaults to the zero retry.Policy.
+	Retry retry.Policy
+}
+
+// Chain implements chain.Chain on top of the solana-go RPC client.
+type Chain struct {
+	id         string
+	client     *rpc.Client
+	commitment rpc.CommitmentType
+	fees       chain.FeeOracle
+	retry      retry.Policy
+}
+
+// Ensure Chain implements the chain.Chain, chain.Expirer and
+// chain.SigningContexter interfaces
+var (
+	_ chain.Chain            = (*Chain)(nil)
+	_ chain.Expirer          = (*Chain)(nil)
+	_ chain.SigningContexter = (*Chain)(nil)
+)
+
+// New creates a Solana chain from the given configuration.
+func New(config Config) (*Chain, error) {
+	if config.ID == "" {
+		return nil, fmt.Errorf("chain ID must be provided")
+	}
+	if config.RPCEndpoint == "" {
+		return nil, fmt.Errorf("RPC endpoint must be provided")
+	}
+	commitment := config.Commitment
+	if commitment == "" {
+		commitment = rpc.CommitmentFinalized
+	}
+	fees := config.FeeOracle
+	if fees == nil {
+		oracle, err := NewPriorityFeeOracle(config.RPCEndpoint, PriorityFeeConfig{Retry: config.Retry})
+		if err != nil {
+			return nil, err
+		}
+		fees = oracle
+	}
+	return &Chain{
+		id:         config.ID,
+		client:     rpc.New(config.RPCEndpoint),
+		commitment: commitment,
+		fees:       fees,
+		retry:      config.Retry,
+	}, nil
+}
+
+// ID returns the configured chain identifier.
+func (c *Chain) ID() string { return c.id }
+
+// SigningContext implements chain.SigningContexter: signing payloads are
+// transaction messages.
+func (c *Chain) SigningContext() string { return "solana-tx" }
+
+// DeriveAddress returns the base58 address of a 32-byte Ed25519 public key.
+func (c *Chain) DeriveAddress(pubKey []byte) (string, error) {
+	if len(pubKey) != solana.PublicKeyLength {
+		return "", fmt.Errorf("invalid Ed25519 public key length: %d", len(pubKey))
+	}
+	return solana.PublicKeyFromBytes(pubKey).String(), nil
+}
+
+// BuildTransfer fetches a recent blockhash and a fee estimate and builds a
+// transfer paid for by the sender: a system-program transfer for SOL, or an
+// SPL token transfer when transfer.Token is set.  A non-zero priority fee is
+// expressed through compute-budget instructions preceding the transfer.
+func (c *Chain) BuildTransfer(ctx context.Context, transfer *chain.Transfer) (*chain.UnsignedTx, error) {
+	if transfer == nil {
+		return nil, fmt.Errorf("transfer cannot be nil")
+	}
+	var decimals uint8
+	if transfer.Token != "" && len(transfer.Outputs) > 0 {
+		return nil, fmt.Errorf("batched token transfers are not supported")
+	}
+	if transfer.Token != "" {
+		mint, err := solana.PublicKeyFromBase58(transfer.Token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token mint: %w", err)
+		}
+		if decimals, err = c.mintDecimals(ctx, mint); err != nil {
+			return nil, err
+		}
+	}
+	fee, err := c.fees.EstimateFee(ctx, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("estimating fee: %w", err)
+	}
+	var bh *rpc.GetLatestBlockhashResult
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		bh, err = c.client.GetLatestBlockhash(ctx, c.commitment)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching latest blockhash: %w", err)
+	}
+	if transfer.Token != "" {
+		return c.buildTokenTransfer(transfer, bh.Value.Blockhash, fee, decimals)
+	}
+	return c.buildTransfer(transfer, bh.Value.Blockhash, fee)
+}
+
+// buildTransfer assembles the transfer message for a known blockhash and fee:
+// one system-program transfer per output, or a single one for To and Amount.
+// It is split from BuildTransfer so that it can be exercised without a
+// network.
+func (c *Chain) buildTransfer(transfer *chain.Transfer, blockhash solana.Hash, fee *chain.FeeEstimate) (*chain.UnsignedTx, error) {
+	from, err := solana.PublicKeyFromBase58(transfer.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	outputs := transfer.Outputs
+	switch {
+	case len(outputs) == 0:
+		outputs = []chain.Output{{To: transfer.To, Amount: transfer.Amount}}
+	case transfer.To != "" || transfer.Amount != nil:
+		return nil, fmt.Errorf("transfer sets both a recipient and outputs")
+	}
+	instructions := make([]solana.Instruction, len(outputs))
+	for i, o := range outputs {
+		to, err := solana.PublicKeyFromBase58(o.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q: %w", o.To, err)
+		}
+		lamports, err := LamportsFromBig(o.Amount)
+		if err != nil || lamports == 0 {
+			return nil, fmt.Errorf("amount must be a positive 64-bit lamport value")
+		}
+		instructions[i] = system.NewTransferInstruction(uint64(lamports), from, to).Build()
+	}
+	return c.compile(from, blockhash, fee, uint64(len(outputs))*defaultTransferComputeUnits, instructions...)
+}
+
+// compile prefixes instructions with the compute-budget instructions for fee,
+// raising its compute-unit limit to at least minLimit, and returns the
+// serialised message paid for by payer.
+func (c *Chain) compile(payer solana.PublicKey, blockhash solana.Hash, fee *chain.FeeEstimate, minLimit uint64, instructions ...solana.Instruction) (*chain.UnsignedTx, error) {
+	if fee != nil && fee.Tip != nil && fee.Tip.Sign() > 0 {
+		if fee.Limit == 0 || fee.Limit > 1_400_000 || !fee.Tip.IsUint64() {
+			return nil, fmt.Errorf("invalid priority fee estimate (limit %d, tip %s)", fee.Limit, fee.Tip)
+		}
+		instructions = append([]solana.Instruction{
+			computebudget.NewSetComputeUnitLimitInstruction(uint32(max(fee.Limit, minLimit))).Build(),
+			computebudget.NewSetComputeUnitPriceInstruction(fee.Tip.Uint64()).Build(),
+		}, instructions...)
+	}
+
+	tx, err := solana.NewTransaction(instructions, blockhash, solana.TransactionPayer(payer))
+	if err != nil {
+		return nil, fmt.Errorf("building transaction: %w", err)
+	}
+	msg, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("serialising message: %w", err)
+	}
+	return &chain.UnsignedTx{Chain: c.id, Payload: msg, SigningPayload: msg}, nil
+}
+
+// Decode parses a serialised message and extracts the transfer it must
+// contain: either system-program transfers from a single sender, or an idempotent
+// associated-token-account creation followed by an SPL TransferChecked to
+// that account.  Compute-budget instructions are accepted and folded into
+// Summary.Fee.
+func (c *Chain) Decode(payload []byte) (*chain.Summary, error) {
+	msg, err := decodeMessage(payload)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rest          []*solana.CompiledInstruction
+		limit         uint32
+		limitSet      bool
+		microLamports uint64
+	)
+	for i := range msg.Instructions {
+		ci := &msg.Instructions[i]
+		program, err := msg.Program(ci.ProgramIDIndex)
+		if err != nil {
+			return nil, fmt.Errorf("resolving program: %w", err)
+		}
+		if program.Equals(computebudget.ProgramID) {
+			inst, err := computebudget.DecodeInstruction(nil, ci.Data)
+			if err != nil {
+				return nil, fmt.Errorf("decoding compute-budget instruction: %w", err)
+			}
+			switch impl := inst.Impl.(type) {
+			case *computebudget.SetComputeUnitLimit:
+				limit, limitSet = impl.Units, true
+			case *computebudget.SetComputeUnitPrice:
+				microLamports = impl.MicroLamports
+			default:
+				return nil, fmt.Errorf("unsupported compute-budget instruction %s", computebudget.InstructionIDToName(inst.TypeID.Uint8()))
+			}
+			continue
+		}
+		rest = append(rest, ci)
+	}
+	if !limitSet {
+		limit = uint32(len(rest) * defaultInstructionComputeUnits)
+	}
+	fee := maxFee(int(msg.Header.NumRequiredSignatures), uint64(limit), microLamports)
+
+	if len(rest) == 0 {
+		return nil, fmt.Errorf("message contains no transfer instruction")
+	}
+	program, err := msg.Program(rest[0].ProgramIDIndex)
+	if err != nil {
+		return nil, fmt.Errorf("resolving program: %w", err)
+	}
+	var summary *chain.Summary
+	switch {
+	case program.Equals(system.ProgramID):
+		summary, err = decodeTransfers(msg, rest)
+	case len(rest) == 2:
+		summary, err = decodeTokenTransfer(msg, rest[0], rest[1])
+	default:
+		err = fmt.Errorf("unsupported program %s", program)
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary.Chain, summary.Fee = c.id, fee
+	return summary, nil
+}
+
+// decodeTransfers summarises system-program transfers from a single sender.
+// Chain and Fee are left to the caller.
+func decodeTransfers(msg *solana.Message, instructions []*solana.CompiledInstruction) (*chain.Summary, error) {
+	summary := &chain.Summary{Amount: new(big.Int)}
+	for _, ci := range instructions {
+		transfer, err := decodeTransfer(msg, ci)
+		if err != nil {
+			return nil, err
+		}
+		from := transfer.GetFundingAccount().PublicKey.String()
+		if summary.From != "" && summary.From != from {
+			return nil, fmt.Errorf("transfers from more than one sender")
+		}
+		summary.From = from
+		o := chain.Output{
+			To:     transfer.GetRecipientAccount().PublicKey.String(),
+			Amount: new(big.Int).SetUint64(*transfer.Lamports),
+		}
+		summary.Outputs = append(summary.Outputs, o)
+		summary.Amount.Add(summary.Amount, o.Amount)
+	}
+	if len(summary.Outputs) == 1 {
+		summary.To, summary.Amount, summary.Outputs = summary.Outputs[0].To, summary.Outputs[0].Amount, nil
+	}
+	return summary, nil
+}
+
+// Simulate runs the signed transaction through simulateTransaction.
+func (c *Chain) Simulate(ctx context.Context, tx *chain.SignedTx) (*chain.SimulationResult, error) {
+	stx, err := assemble(tx)
+	if err != nil {
+		return nil, err
+	}
+	var resp *rpc.SimulateTransactionResponse
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		resp, err = c.client.SimulateTransactionWithOpts(ctx, stx, &rpc.SimulateTransactionOpts{
+			SigVerify:  true,
+			Commitment: c.commitment,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("simulating transaction: %w", err)
+	}
+	res := &chain.SimulationResult{OK: resp.Value.Err == nil, Logs: resp.Value.Logs}
+	if e := parseTransactionError(resp.Value.Err, resp.Value.Logs); e != nil {
+		res.Err, res.Detail = e.Error(), e
+	}
+	return res, nil
+}
+
+// Broadcast submits the signed transaction and returns its base58 signature.
+func (c *Chain) Broadcast(ctx context.Context, tx *chain.SignedTx) (string, error) {
+	stx, err := assemble(tx)
+	if err != nil {
+		return "", err
+	}
+	var sig solana.Signature
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		sig, err = c.client.SendTransactionWithOpts(ctx, stx, rpc.TransactionOpts{
+			PreflightCommitment: c.commitment,
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("sending transaction: %w", sendError(err))
+	}
+	return sig.String(), nil
+}
+
+// Expired reports whether the blockhash referenced by tx is no longer valid
+// at the configured commitment.
+func (c *Chain) Expired(ctx context.Context, tx *chain.UnsignedTx) (bool, error) {
+	msg, err := decodeMessage(tx.Payload)
+	if err != nil {
+		return false, err
+	}
+	var out *rpc.IsValidBlockhashResult
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		out, err = c.client.IsBlockhashValid(ctx, msg.RecentBlockhash, c.commitment)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("checking blockhash: %w", err)
+	}
+	return !out.Value, nil
+}
+
+// Confirm maps getSignatureStatuses onto a chain.Receipt.
+func (c *Chain) Confirm(ctx context.Context, txID string) (*chain.Receipt, error) {
+	sig, err := solana.SignatureFromBase58(txID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction signature: %w", err)
+	}
+	var out *rpc.GetSignatureStatusesResult
+	err = c.call(ctx, func(ctx context.Context) (err error) {
+		out, err = c.client.GetSignatureStatuses(ctx, true, sig)
+		return err
+	})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, chain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching signature status: %w", err)
+	}
+	if len(out.Value) == 0 || out.Value[0] == nil {
+		return nil, chain.ErrNotFound
+	}
+	st := out.Value[0]
+	receipt := &chain.Receipt{TxID: txID, Height: st.Slot}
+	switch {
+	case st.Err != nil:
+		receipt.Status = chain.StatusFailed
+		receipt.Err = parseTransactionError(st.Err, nil).Error()
+	case st.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+		receipt.Status = chain.StatusFinalized
+	case st.ConfirmationStatus == rpc.ConfirmationStatusConfirmed:
+		receipt.Status = chain.StatusConfirmed
+	default:
+		receipt.Status = chain.StatusPending
+	}
+	return receipt, nil
+}
+
+// decodeMessage parses a serialised (legacy or v0) transaction message.
+func decodeMessage(payload []byte) (*solana.Message, error) {
+	var msg solana.Message
+	if err := msg.UnmarshalWithDecoder(bin.NewBinDecoder(payload)); err != nil {
+		return nil, fmt.Errorf("decoding message: %w", err)
+	}
+	return &msg, nil
+}
+
+// decodeTransfer resolves a compiled instruction into a system transfer.
+// Instructions for any other program are rejected.
+func decodeTransfer(msg *solana.Message, ci *solana.CompiledInstruction) (*system.Transfer, error) {
+	program, err := msg.Program(ci.ProgramIDIndex)
+	if err != nil {
+		return nil, fmt.Errorf("resolving program: %w", err)
+	}
+	if !program.Equals(system.ProgramID) {
+		return nil, fmt.Errorf("unsupported program %s", program)
+	}
+	accounts, err := ci.ResolveInstructionAccounts(msg)
+	if err != nil {
+		return nil, fmt.Errorf("resolving accounts: %w", err)
+	}
+	inst, err := system.DecodeInstruction(accounts, ci.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding system instruction: %w", err)
+	}
+	transfer, ok := inst.Impl.(*system.Transfer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported system instruction %s", system.InstructionIDToName(inst.TypeID.Uint32()))
+	}
+	return transfer, nil
+}
+
+// assemble rebuilds a wire transaction from the signed payload.
+func assemble(tx *chain.SignedTx) (*solana.Transaction, error) {
+	if tx == nil || tx.Unsigned == nil {
+		return nil, fmt.Errorf("signed transaction cannot be nil")
+	}
+	if len(tx.Signature) != solana.SignatureLength {
+		return nil, fmt.Errorf("invalid Ed25519 signature length: %d", len(tx.Signature))
+	}
+	msg, err := decodeMessage(tx.Unsigned.Payload)
+	if err != nil {
+		return nil, err
+	}
+	var sig solana.Signature
+	copy(sig[:], tx.Signature)
+	return &solana.Transaction{Signatures: []solana.Signature{sig}, Message: *msg}, nil
+}
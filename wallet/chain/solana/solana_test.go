@@ -0,0 +1,331 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/retry"
+)
+
+func testChain(t *testing.T) *Chain {
+	t.Helper()
+	c, err := New(Config{ID: "solana-test", RPCEndpoint: "http://127.0.0.1:0"})
+	require.NoError(t, err)
+	return c
+}
+
+func TestDeriveAddress(t *testing.T) {
+	c := testChain(t)
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	addr, err := c.DeriveAddress(pub)
+	require.NoError(t, err)
+	assert.Equal(t, solana.PublicKeyFromBytes(pub).String(), addr)
+
+	_, err = c.DeriveAddress(pub[:31])
+	assert.Error(t, err)
+}
+
+func TestBuildDecodeAndAssemble(t *testing.T) {
+	c := testChain(t)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	transfer := &chain.Transfer{
+		From:   solana.PublicKeyFromBytes(pub).String(),
+		To:     solana.NewWallet().PublicKey().String(),
+		Amount: big.NewInt(1_000_000),
+	}
+
+	utx, err := c.buildTransfer(transfer, solana.Hash{1, 2, 3}, newFeeEstimate(1, 1_000, 2_500))
+	require.NoError(t, err)
+	assert.Equal(t, utx.Payload, utx.SigningPayload)
+
+	summary, err := c.Decode(utx.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, transfer.From, summary.From)
+	assert.Equal(t, transfer.To, summary.To)
+	assert.Equal(t, 0, transfer.Amount.Cmp(summary.Amount))
+	// 5000 lamports base fee plus ceil(1000 CU * 2500 µlamports / 1e6).
+	assert.Equal(t, int64(5003), summary.Fee.Int64())
+
+	sig := ed25519.Sign(priv, utx.SigningPayload)
+	stx, err := assemble(&chain.SignedTx{Unsigned: utx, Signature: sig})
+	require.NoError(t, err)
+	require.NoError(t, stx.VerifySignatures())
+}
+
+func TestBuildTransferRejectsBadAmount(t *testing.T) {
+	c := testChain(t)
+	transfer := &chain.Transfer{
+		From:   solana.NewWallet().PublicKey().String(),
+		To:     solana.NewWallet().PublicKey().String(),
+		Amount: big.NewInt(0),
+	}
+	_, err := c.buildTransfer(transfer, solana.Hash{}, nil)
+	assert.Error(t, err)
+}
+
+func TestDecodeWithoutPriorityFee(t *testing.T) {
+	c := testChain(t)
+	transfer := &chain.Transfer{
+		From:   solana.NewWallet().PublicKey().String(),
+		To:     solana.NewWallet().PublicKey().String(),
+		Amount: big.NewInt(42),
+	}
+	utx, err := c.buildTransfer(transfer, solana.Hash{}, newFeeEstimate(1, 1_000, 0))
+	require.NoError(t, err)
+
+	summary, err := c.Decode(utx.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, int64(lamportsPerSignature), summary.Fee.Int64())
+}
+
+func TestBuildDecodeBatch(t *testing.T) {
+	c := testChain(t)
+	from := solana.NewWallet().PublicKey().String()
+	repeated := solana.NewWallet().PublicKey().String()
+	transfer := &chain.Transfer{From: from, Outputs: []chain.Output{
+		{To: repeated, Amount: big.NewInt(1)},
+		{To: solana.NewWallet().PublicKey().String(), Amount: big.NewInt(2)},
+		{To: repeated, Amount: big.NewInt(3)},
+	}}
+
+	utx, err := c.buildTransfer(transfer, solana.Hash{}, newFeeEstimate(1, 1_000, 1_000_000))
+	require.NoError(t, err)
+	summary, err := c.Decode(utx.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, from, summary.From)
+	assert.Empty(t, summary.To)
+	assert.Equal(t, int64(6), summary.Amount.Int64())
+	assert.Equal(t, transfer.Outputs, summary.Outputs)
+	assert.Equal(t, repeated, summary.Recipients()[2].To)
+
+	_, err = c.buildTransfer(&chain.Transfer{From: from, To: repeated, Amount: big.NewInt(1), Outputs: transfer.Outputs}, solana.Hash{}, nil)
+	assert.Error(t, err)
+}
+
+func TestFits(t *testing.T) {
+	c := testChain(t)
+	transfer := &chain.Transfer{From: solana.NewWallet().PublicKey().String()}
+	n := 0
+	for {
+		transfer.Outputs = append(transfer.Outputs, chain.Output{To: solana.NewWallet().PublicKey().String(), Amount: big.NewInt(1)})
+		ok, err := c.Fits(transfer)
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		n++
+	}
+	// Each distinct recipient costs a 32-byte key and a 17-byte instruction.
+	assert.Equal(t, 20, n)
+
+	// Repeated recipients only cost the instruction: the 34 bytes left over
+	// hold two more outputs to a known recipient.
+	transfer.Outputs = transfer.Outputs[:n]
+	for i, want := range []bool{true, true, false} {
+		transfer.Outputs = append(transfer.Outputs, chain.Output{To: transfer.Outputs[0].To, Amount: big.NewInt(1)})
+		ok, err := c.Fits(transfer)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, "repeat %d", i+1)
+	}
+}
+
+func TestBuildDecodeTokenTransfer(t *testing.T) {
+	c := testChain(t)
+	transfer := &chain.Transfer{
+		From:   solana.NewWallet().PublicKey().String(),
+		To:     solana.NewWallet().PublicKey().String(),
+		Amount: big.NewInt(2_500_000),
+		Token:  solana.NewWallet().PublicKey().String(),
+	}
+
+	utx, err := c.buildTokenTransfer(transfer, solana.Hash{1}, newFeeEstimate(1, 1_000, 1_000_000), 6)
+	require.NoError(t, err)
+	summary, err := c.Decode(utx.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, transfer.From, summary.From)
+	assert.Equal(t, transfer.To, summary.To, "the recipient wallet, not its token account")
+	assert.Equal(t, transfer.Token, summary.Token)
+	assert.Equal(t, 0, transfer.Amount.Cmp(summary.Amount))
+	// The compute-unit limit is raised to cover the account creation.
+	assert.Equal(t, int64(5000+tokenTransferComputeUnits), summary.Fee.Int64())
+}
+
+func TestDecodeRejectsForeignTokenAccount(t *testing.T) {
+	c := testChain(t)
+	from, to, mint := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	source, _, err := solana.FindAssociatedTokenAddress(from, mint)
+	require.NoError(t, err)
+	ata, _, err := solana.FindAssociatedTokenAddress(to, mint)
+	require.NoError(t, err)
+
+	// The creation instruction names the recipient, but the tokens go to an
+	// account that someone else controls.
+	create := solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, solana.AccountMetaSlice{
+		solana.Meta(from).WRITE().SIGNER(),
+		solana.Meta(ata).WRITE(),
+		solana.Meta(to),
+		solana.Meta(mint),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+	}, createIdempotent)
+	send := token.NewTransferCheckedInstruction(1, 6, source, mint, solana.NewWallet().PublicKey(), from, nil).Build()
+	tx, err := solana.NewTransaction([]solana.Instruction{create, send}, solana.Hash{}, solana.TransactionPayer(from))
+	require.NoError(t, err)
+	payload, err := tx.Message.MarshalBinary()
+	require.NoError(t, err)
+
+	_, err = c.Decode(payload)
+	assert.ErrorContains(t, err, "associated token account")
+}
+
+func TestPercentile(t *testing.T) {
+	values := []uint64{50, 10, 40, 20, 30}
+	assert.Equal(t, uint64(10), percentile(values, 1))
+	assert.Equal(t, uint64(30), percentile(values, 50))
+	assert.Equal(t, uint64(40), percentile(values, 75))
+	assert.Equal(t, uint64(50), percentile(values, 100))
+	assert.Equal(t, uint64(0), percentile(nil, 75))
+}
+
+func TestOffchainMessage(t *testing.T) {
+	signer := solana.NewWallet().PublicKey()
+	var domain [32]byte
+	copy(domain[:], "example.com")
+
+	for _, tc := range []struct {
+		body   string
+		format OffchainFormat
+	}{
+		{"Sign in to example.com", OffchainRestrictedASCII},
+		{"Sign in to example.com\nNonce: 42", OffchainLimitedUTF8},
+		{"Anmelden bei example.com – Schlüssel", OffchainLimitedUTF8},
+		{strings.Repeat("a", 2000), OffchainExtendedUTF8},
+	} {
+		m, err := NewOffchainMessage(domain, []solana.PublicKey{signer}, []byte(tc.body))
+		require.NoError(t, err)
+		assert.Equal(t, tc.format, m.Format, tc.body)
+
+		data, err := m.Serialize()
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(data, []byte("\xffsolana offchain\x00")))
+		parsed, err := ParseOffchainMessage(data)
+		require.NoError(t, err)
+		assert.Equal(t, m, parsed)
+		assert.True(t, parsed.HasSigner(signer))
+
+		// An offchain message never decodes as a transaction message.
+		var tx solana.Message
+		assert.Error(t, tx.UnmarshalWithDecoder(bin.NewBinDecoder(data)))
+	}
+}
+
+func TestOffchainMessageRejectsMalformed(t *testing.T) {
+	signer := solana.NewWallet().PublicKey()
+	_, err := NewOffchainMessage([32]byte{}, nil, []byte("hello"))
+	assert.Error(t, err)
+	_, err = NewOffchainMessage([32]byte{}, []solana.PublicKey{signer, signer}, []byte("hello"))
+	assert.Error(t, err)
+	_, err = NewOffchainMessage([32]byte{}, []solana.PublicKey{signer}, []byte("\xff\xfe"))
+	assert.Error(t, err)
+	_, err = NewOffchainMessage([32]byte{}, []solana.PublicKey{signer}, bytes.Repeat([]byte("a"), 0x10000))
+	assert.Error(t, err)
+
+	m, err := NewOffchainMessage([32]byte{}, []solana.PublicKey{signer}, []byte("hello"))
+	require.NoError(t, err)
+	data, err := m.Serialize()
+	require.NoError(t, err)
+
+	_, err = ParseOffchainMessage(append(bytes.Clone(data), 0))
+	assert.Error(t, err, "trailing bytes")
+	_, err = ParseOffchainMessage(data[:len(data)-1])
+	assert.Error(t, err, "truncated body")
+
+	badVersion := bytes.Clone(data)
+	badVersion[16] = 1
+	_, err = ParseOffchainMessage(badVersion)
+	assert.ErrorContains(t, err, "version")
+
+	// A multi-line body declared as restricted ASCII is rejected.
+	m.Body, m.Format = []byte("a\nb"), OffchainLimitedUTF8
+	data, err = m.Serialize()
+	require.NoError(t, err)
+	data[16+1+32] = byte(OffchainRestrictedASCII)
+	_, err = ParseOffchainMessage(data)
+	assert.ErrorContains(t, err, "ASCII")
+}
+
+func TestClassify(t *testing.T) {
+	for want, errs := range map[retry.Class][]error{
+		retry.RateLimited: {&jsonrpc.HTTPError{Code: http.StatusTooManyRequests}, &jsonrpc.RPCError{Code: 429}},
+		retry.NodeBehind:  {&jsonrpc.RPCError{Code: -32005, Message: "Node is behind by 42 slots"}, &jsonrpc.RPCError{Code: -32016}},
+		retry.Transient:   {&jsonrpc.HTTPError{Code: http.StatusBadGateway}, &jsonrpc.RPCError{Code: -32603}},
+		retry.Permanent:   {&jsonrpc.RPCError{Code: -32002, Message: "Transaction simulation failed"}, &jsonrpc.HTTPError{Code: http.StatusForbidden}, errors.New("bad")},
+	} {
+		for _, err := range errs {
+			assert.Equal(t, want, Classify(err), "%#v", err)
+		}
+	}
+}
+
+func TestConfirmRetriesRPCErrors(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID,
+				"error": map[string]any{"code": -32005, "message": "Node is unhealthy"}})
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": map[string]any{
+				"context": map[string]any{"slot": 9},
+				"value":   []any{map[string]any{"slot": 7, "err": nil, "confirmationStatus": "finalized"}},
+			}})
+		}
+	}))
+	defer srv.Close()
+	var delays []time.Duration
+	c, err := New(Config{ID: "solana-test", RPCEndpoint: srv.URL, Retry: retry.Policy{
+		Sleep: func(_ context.Context, d time.Duration) error { delays = append(delays, d); return nil },
+	}})
+	require.NoError(t, err)
+
+	sig := solana.Signature{1}
+	receipt, err := c.Confirm(context.Background(), sig.String())
+	require.NoError(t, err)
+	assert.Equal(t, chain.StatusFinalized, receipt.Status)
+	assert.Equal(t, uint64(7), receipt.Height)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, delays, 2)
+
+	// Once the attempts are used up the error stays classified.
+	calls = 0
+	c.retry.Attempts = 1
+	_, err = c.Confirm(context.Background(), sig.String())
+	assert.Equal(t, retry.RateLimited, retry.Classify(err))
+}
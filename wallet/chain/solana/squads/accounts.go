@@ -0,0 +1,331 @@
+package squads
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ProgramID is the address of the Squads v4 multisig program.
+var ProgramID = solana.MustPublicKeyFromBase58("SQDS4ep65T869zMMBKyuUq6aD6EgTu8psMjkvj52pCf")
+
+// Member permissions, as stored in Member.Permissions.
+const (
+	PermissionInitiate uint8 = 1 << iota
+	PermissionVote
+	PermissionExecute
+)
+
+var seedPrefix = []byte("multisig")
+
+// MultisigAddress derives the multisig account created with createKey.
+func MultisigAddress(createKey solana.PublicKey) (solana.PublicKey, error) {
+	return pda(seedPrefix, []byte("multisig"), createKey[:])
+}
+
+// VaultAddress derives a vault of a multisig.
+func VaultAddress(multisig solana.PublicKey, index uint8) (solana.PublicKey, error) {
+	return pda(seedPrefix, multisig[:], []byte("vault"), []byte{index})
+}
+
+// TransactionAddress derives the vault transaction with the given index.
+func TransactionAddress(multisig solana.PublicKey, index uint64) (solana.PublicKey, error) {
+	return pda(seedPrefix, multisig[:], []byte("transaction"), le64(index))
+}
+
+// ProposalAddress derives the proposal of the transaction with the given
+// index.
+func ProposalAddress(multisig solana.PublicKey, index uint64) (solana.PublicKey, error) {
+	return pda(seedPrefix, multisig[:], []byte("transaction"), le64(index), []byte("proposal"))
+}
+
+// ephemeralSignerAddress derives an ephemeral signer of a vault transaction.
+func ephemeralSignerAddress(transaction solana.PublicKey, index uint8) (solana.PublicKey, error) {
+	return pda(seedPrefix, transaction[:], []byte("ephemeral_signer"), []byte{index})
+}
+
+func pda(seeds ...[]byte) (solana.PublicKey, error) {
+	key, _, err := solana.FindProgramAddress(seeds, ProgramID)
+	return key, err
+}
+
+func le64(v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, v)
+}
+
+// discriminator returns the 8-byte Anchor discriminator of an account
+// ("account:<Name>") or instruction ("global:<name>").
+func discriminator(name string) []byte {
+	sum := sha256.Sum256([]byte(name))
+	return sum[:8]
+}
+
+// Member is a member of a multisig.
+type Member struct {
+	Key         solana.PublicKey
+	Permissions uint8
+}
+
+// Multisig is the state of a multisig account.
+type Multisig struct {
+	CreateKey             solana.PublicKey
+	ConfigAuthority       solana.PublicKey
+	Threshold             uint16
+	TimeLock              uint32 // Seconds between approval and execution
+	TransactionIndex      uint64 // Index of the last transaction
+	StaleTransactionIndex uint64 // Transactions up to this index can no longer be approved
+	RentCollector         *solana.PublicKey
+	Bump                  uint8
+	Members               []Member
+}
+
+// Member returns the permissions of key, and whether it is a member.
+func (m *Multisig) Member(key solana.PublicKey) (uint8, bool) {
+	for _, member := range m.Members {
+		if member.Key.Equals(key) {
+			return member.Permissions, true
+		}
+	}
+	return 0, false
+}
+
+// DecodeMultisig decodes the data of a multisig account.
+func DecodeMultisig(data []byte) (*Multisig, error) {
+	r, err := accountReader(data, "Multisig")
+	if err != nil {
+		return nil, err
+	}
+	m := &Multisig{
+		CreateKey:             r.key(),
+		ConfigAuthority:       r.key(),
+		Threshold:             r.u16(),
+		TimeLock:              r.u32(),
+		TransactionIndex:      r.u64(),
+		StaleTransactionIndex: r.u64(),
+	}
+	if r.u8() == 1 {
+		k := r.key()
+		m.RentCollector = &k
+	}
+	m.Bump = r.u8()
+	for n := r.len(33); n > 0; n-- {
+		m.Members = append(m.Members, Member{Key: r.key(), Permissions: r.u8()})
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("decoding multisig: %w", r.err)
+	}
+	return m, nil
+}
+
+// ProposalStatus is the stage of a proposal.
+type ProposalStatus uint8
+
+const (
+	StatusDraft ProposalStatus = iota
+	StatusActive
+	StatusRejected
+	StatusApproved
+	StatusExecuting
+	StatusExecuted
+	StatusCancelled
+)
+
+var statusNames = [...]string{"draft", "active", "rejected", "approved", "executing", "executed", "cancelled"}
+
+func (s ProposalStatus) String() string {
+	if int(s) < len(statusNames) {
+		return statusNames[s]
+	}
+	return fmt.Sprintf("status(%d)", uint8(s))
+}
+
+// Proposal is the state of a proposal account, which collects the votes on
+// one transaction.
+type Proposal struct {
+	Multisig         solana.PublicKey
+	TransactionIndex uint64
+	Status           ProposalStatus
+	Timestamp        int64 // Unix time the status was entered, except for StatusExecuting
+	Bump             uint8
+	Approved         []solana.PublicKey
+	Rejected         []solana.PublicKey
+	Cancelled        []solana.PublicKey
+}
+
+// Voted reports whether key approved or rejected the proposal.
+func (p *Proposal) Voted(key solana.PublicKey) bool {
+	for _, list := range [][]solana.PublicKey{p.Approved, p.Rejected} {
+		for _, k := range list {
+			if k.Equals(key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DecodeProposal decodes the data of a proposal account.
+func DecodeProposal(data []byte) (*Proposal, error) {
+	r, err := accountReader(data, "Proposal")
+	if err != nil {
+		return nil, err
+	}
+	p := &Proposal{Multisig: r.key(), TransactionIndex: r.u64(), Status: ProposalStatus(r.u8())}
+	switch {
+	case r.err == nil && p.Status > StatusCancelled:
+		return nil, fmt.Errorf("decoding proposal: unknown status %d", p.Status)
+	case p.Status != StatusExecuting:
+		p.Timestamp = int64(r.u64())
+	}
+	p.Bump = r.u8()
+	p.Approved, p.Rejected, p.Cancelled = r.keys(), r.keys(), r.keys()
+	if r.err != nil {
+		return nil, fmt.Errorf("decoding proposal: %w", r.err)
+	}
+	return p, nil
+}
+
+// CompiledInstruction is an instruction of a vault transaction message.
+type CompiledInstruction struct {
+	ProgramIDIndex uint8
+	AccountIndexes []uint8
+	Data           []byte
+}
+
+// AddressTableLookup loads accounts of a vault transaction message from an
+// address lookup table.
+type AddressTableLookup struct {
+	AccountKey      solana.PublicKey
+	WritableIndexes []uint8
+	ReadonlyIndexes []uint8
+}
+
+// Message is the message a vault transaction executes, signed by the vault.
+type Message struct {
+	NumSigners            uint8
+	NumWritableSigners    uint8
+	NumWritableNonSigners uint8
+	AccountKeys           []solana.PublicKey
+	Instructions          []CompiledInstruction
+	AddressTableLookups   []AddressTableLookup
+}
+
+// signer reports whether the account at index i signs the message.
+func (m *Message) signer(i int) bool {
+	return i < int(m.NumSigners)
+}
+
+// writable reports whether the static account at index i is writable.
+func (m *Message) writable(i int) bool {
+	if i < int(m.NumSigners) {
+		return i < int(m.NumWritableSigners)
+	}
+	return i-int(m.NumSigners) < int(m.NumWritableNonSigners)
+}
+
+// VaultTransaction is the state of a vault transaction account.
+type VaultTransaction struct {
+	Multisig             solana.PublicKey
+	Creator              solana.PublicKey
+	Index                uint64
+	Bump                 uint8
+	VaultIndex           uint8
+	VaultBump            uint8
+	EphemeralSignerBumps []uint8
+	Message              Message
+}
+
+// DecodeVaultTransaction decodes the data of a vault transaction account.
+func DecodeVaultTransaction(data []byte) (*VaultTransaction, error) {
+	r, err := accountReader(data, "VaultTransaction")
+	if err != nil {
+		return nil, err
+	}
+	t := &VaultTransaction{
+		Multisig:             r.key(),
+		Creator:              r.key(),
+		Index:                r.u64(),
+		Bump:                 r.u8(),
+		VaultIndex:           r.u8(),
+		VaultBump:            r.u8(),
+		EphemeralSignerBumps: r.bytes(),
+	}
+	m := &t.Message
+	m.NumSigners, m.NumWritableSigners, m.NumWritableNonSigners = r.u8(), r.u8(), r.u8()
+	m.AccountKeys = r.keys()
+	for n := r.len(9); n > 0; n-- {
+		m.Instructions = append(m.Instructions, CompiledInstruction{ProgramIDIndex: r.u8(), AccountIndexes: r.bytes(), Data: r.bytes()})
+	}
+	for n := r.len(40); n > 0; n-- {
+		m.AddressTableLookups = append(m.AddressTableLookups, AddressTableLookup{AccountKey: r.key(), WritableIndexes: r.bytes(), ReadonlyIndexes: r.bytes()})
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("decoding vault transaction: %w", r.err)
+	}
+	return t, nil
+}
+
+// accountReader checks the Anchor discriminator of an account and returns a
+// reader positioned after it.
+func accountReader(data []byte, name string) (*reader, error) {
+	if len(data) < 8 || !bytes.Equal(data[:8], discriminator("account:"+name)) {
+		return nil, fmt.Errorf("not a %s account", name)
+	}
+	return &reader{data: data[8:]}, nil
+}
+
+// reader reads little-endian Borsh fields.  The first error sticks: later
+// reads return zero values, so a layout can be read field by field and
+// checked once.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if len(r.data) < n {
+		r.err = fmt.Errorf("account data too short")
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) u8() uint8   { return r.next(1)[0] }
+func (r *reader) u16() uint16 { return binary.LittleEndian.Uint16(r.next(2)) }
+func (r *reader) u32() uint32 { return binary.LittleEndian.Uint32(r.next(4)) }
+func (r *reader) u64() uint64 { return binary.LittleEndian.Uint64(r.next(8)) }
+
+func (r *reader) key() solana.PublicKey { return solana.PublicKeyFromBytes(r.next(32)) }
+
+// len reads the length of a vector whose elements take at least size bytes,
+// rejecting lengths the remaining data cannot hold.
+func (r *reader) len(size int) int {
+	n := int(r.u32())
+	if r.err == nil && n*size > len(r.data) {
+		r.err = fmt.Errorf("vector of %d elements exceeds account data", n)
+	}
+	if r.err != nil {
+		return 0
+	}
+	return n
+}
+
+func (r *reader) bytes() []byte {
+	return append([]byte(nil), r.next(r.len(1))...)
+}
+
+func (r *reader) keys() []solana.PublicKey {
+	var out []solana.PublicKey
+	for n := r.len(32); n > 0; n-- {
+		out = append(out, r.key())
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package squads
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// Describe decodes what a vault transaction does, for display to approvers,
+// by handing its message to decoder – normally the Solana chain.  The vault
+// message carries no network fee of its own (the member executing it pays),
+// so Summary.Fee is always zero.  Messages the decoder does not understand,
+// such as arbitrary program calls, are reported as an error; callers should
+// then fall back to showing the raw instructions.
+func Describe(decoder chain.Chain, tx *VaultTransaction) (*chain.Summary, error) {
+	msg, err := legacyMessage(&tx.Message)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := msg.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("encoding vault message: %w", err)
+	}
+	summary, err := decoder.Decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	summary.Fee = new(big.Int)
+	return summary, nil
+}
+
+// legacyMessage rebuilds the vault message as a Solana legacy message, which
+// lays out its account keys in the same order.
+func legacyMessage(m *Message) (*solana.Message, error) {
+	if len(m.AddressTableLookups) > 0 {
+		return nil, fmt.Errorf("vault transactions using address lookup tables are not supported")
+	}
+	n := len(m.AccountKeys)
+	if int(m.NumSigners) > n || m.NumWritableSigners > m.NumSigners || int(m.NumSigners)+int(m.NumWritableNonSigners) > n {
+		return nil, fmt.Errorf("inconsistent vault message header")
+	}
+	msg := &solana.Message{
+		AccountKeys: append(solana.PublicKeySlice(nil), m.AccountKeys...),
+		Header: solana.MessageHeader{
+			NumRequiredSignatures:       m.NumSigners,
+			NumReadonlySignedAccounts:   m.NumSigners - m.NumWritableSigners,
+			NumReadonlyUnsignedAccounts: uint8(n - int(m.NumSigners) - int(m.NumWritableNonSigners)),
+		},
+	}
+	for _, ci := range m.Instructions {
+		if int(ci.ProgramIDIndex) >= n {
+			return nil, fmt.Errorf("program index %d out of range", ci.ProgramIDIndex)
+		}
+		accounts := make([]uint16, len(ci.AccountIndexes))
+		for i, a := range ci.AccountIndexes {
+			if int(a) >= n {
+				return nil, fmt.Errorf("account index %d out of range", a)
+			}
+			accounts[i] = uint16(a)
+		}
+		msg.Instructions = append(msg.Instructions, solana.CompiledInstruction{
+			ProgramIDIndex: uint16(ci.ProgramIDIndex),
+			Accounts:       accounts,
+			Data:           ci.Data,
+		})
+	}
+	return msg, nil
+}
@@ -0,0 +1,20 @@
+// Package squads lets the MPC wallet act as one member of a Squads v4
+// multisig, so treasury funds can sit behind a multisig in which the MPC key
+// is one signer among hardware wallets or other custodians.
+//
+// The package has three layers:
+//
+//   - Account decoders (`DecodeMultisig`, `DecodeProposal`,
+//     `DecodeVaultTransaction`) and address derivations for the program's
+//     PDAs.
+//   - Instruction builders for the member's part of the flow:
+//     `ProposalApprove`, `ProposalReject` and `VaultTransactionExecute`.
+//   - `Participant`, which loads a proposal, decodes the vault transaction with
+//     `Describe` so that reviewers see what they are voting on, asks
+//     Config.Review, and signs and submits the vote with the MPC key through
+//     Config.Signer.
+//
+// Creating multisigs and proposing transactions is left to the Squads tooling.
+// Vault transactions that load accounts from address lookup tables are not
+// supported.
+package squads
@@ -0,0 +1,109 @@
+package squads
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	proposalApproveDiscriminator         = discriminator("global:proposal_approve")
+	proposalRejectDiscriminator          = discriminator("global:proposal_reject")
+	vaultTransactionExecuteDiscriminator = discriminator("global:vault_transaction_execute")
+)
+
+// ProposalApprove returns the instruction by which member approves the
+// proposal of transaction index, with an optional memo.
+func ProposalApprove(multisig, member solana.PublicKey, index uint64, memo string) (solana.Instruction, error) {
+	return vote(proposalApproveDiscriminator, multisig, member, index, memo)
+}
+
+// ProposalReject returns the instruction by which member rejects the proposal
+// of transaction index, with an optional memo.
+func ProposalReject(multisig, member solana.PublicKey, index uint64, memo string) (solana.Instruction, error) {
+	return vote(proposalRejectDiscriminator, multisig, member, index, memo)
+}
+
+func vote(disc []byte, multisig, member solana.PublicKey, index uint64, memo string) (solana.Instruction, error) {
+	proposal, err := ProposalAddress(multisig, index)
+	if err != nil {
+		return nil, err
+	}
+	// ProposalVoteArgs { memo: Option<String> }
+	data := append([]byte(nil), disc...)
+	if memo == "" {
+		data = append(data, 0)
+	} else {
+		data = append(data, 1)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(memo)))
+		data = append(data, memo...)
+	}
+	return solana.NewInstruction(ProgramID, solana.AccountMetaSlice{
+		solana.Meta(multisig),
+		solana.Meta(member).WRITE().SIGNER(),
+		solana.Meta(proposal).WRITE(),
+	}, data), nil
+}
+
+// VaultTransactionExecute returns the instruction by which member executes an
+// approved vault transaction.  The accounts of the transaction's message are
+// passed as remaining accounts; the vault and ephemeral signers are PDAs and
+// are signed for by the program.  Messages that load accounts from address
+// lookup tables are not supported.
+func VaultTransactionExecute(multisig, member solana.PublicKey, tx *VaultTransaction) (solana.Instruction, error) {
+	if !tx.Multisig.Equals(multisig) {
+		return nil, fmt.Errorf("transaction belongs to multisig %s", tx.Multisig)
+	}
+	if len(tx.Message.AddressTableLookups) > 0 {
+		return nil, fmt.Errorf("vault transactions using address lookup tables are not supported")
+	}
+	transaction, err := TransactionAddress(multisig, tx.Index)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := ProposalAddress(multisig, tx.Index)
+	if err != nil {
+		return nil, err
+	}
+	vault, err := VaultAddress(multisig, tx.VaultIndex)
+	if err != nil {
+		return nil, err
+	}
+	pdas := []solana.PublicKey{vault}
+	for i := range tx.EphemeralSignerBumps {
+		signer, err := ephemeralSignerAddress(transaction, uint8(i))
+		if err != nil {
+			return nil, err
+		}
+		pdas = append(pdas, signer)
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(multisig),
+		solana.Meta(proposal).WRITE(),
+		solana.Meta(transaction),
+		solana.Meta(member).SIGNER(),
+	}
+	m := &tx.Message
+	for i, k := range m.AccountKeys {
+		meta := solana.Meta(k)
+		if m.writable(i) {
+			meta.WRITE()
+		}
+		if m.signer(i) && !containsKey(pdas, k) {
+			meta.SIGNER()
+		}
+		accounts = append(accounts, meta)
+	}
+	return solana.NewInstruction(ProgramID, accounts, append([]byte(nil), vaultTransactionExecuteDiscriminator...)), nil
+}
+
+func containsKey(list []solana.PublicKey, k solana.PublicKey) bool {
+	for _, x := range list {
+		if x.Equals(k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package squads
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+// Action is what a member does with a proposal.
+type Action string
+
+const (
+	ActionApprove Action = "approve"
+	ActionReject  Action = "reject"
+	ActionExecute Action = "execute"
+)
+
+// Details is everything known about one proposal of a multisig, as shown to
+// the reviewers of a vote.
+type Details struct {
+	Address     solana.PublicKey // Multisig account
+	Multisig    *Multisig
+	Proposal    *Proposal
+	Transaction *VaultTransaction
+	Vault       solana.PublicKey // Vault the transaction executes as
+	// Summary is the decoded vault transaction, or nil if Config.Decoder is
+	// unset or cannot decode it; DescribeErr then says why.
+	Summary     *chain.Summary
+	DescribeErr error
+}
+
+// Config contains the configuration for a Participant.
+type Config struct {
+	// RPCEndpoint is the JSON-RPC URL of the cluster.
+	RPCEndpoint string
+	// Commitment used when reading accounts and fetching blockhashes.
+	// Defaults to rpc.CommitmentConfirmed.
+	Commitment rpc.CommitmentType
+	// PublicKey is the wallet's 32-byte Ed25519 group public key, which is
+	// the member key in the multisig.  It also pays for the transactions
+	// carrying its votes.
+	PublicKey ed25519.PublicKey
+	// Signer signs with the MPC key, typically by running the EdDSA MPC
+	// signing protocol with a quorum of parties.
+	Signer remotesigner.Signer
+	// Decoder, if set, decodes vault transactions into summaries for Review.
+	Decoder chain.Chain
+	// Review is consulted before every vote or execution and returns an
+	// error to refuse it.  This is where the quorum's approval is obtained.
+	Review func(ctx context.Context, details *Details, action Action) error
+	// Now returns the current time, for the multisig time lock.  Defaults to
+	// time.Now.
+	Now func() time.Time
+}
+
+// Participant acts as one member of Squads multisigs with the MPC key.
+type Participant struct {
+	config Config
+	key    solana.PublicKey
+	client *rpc.Client
+}
+
+// New creates a Participant from the given configuration.
+func New(config Config) (*Participant, error) {
+	if config.RPCEndpoint == "" {
+		return nil, fmt.Errorf("RPC endpoint must be provided")
+	}
+	if len(config.PublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	if config.Signer == nil || config.Review == nil {
+		return nil, fmt.Errorf("signer and review must be provided")
+	}
+	if config.Commitment == "" {
+		config.Commitment = rpc.CommitmentConfirmed
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Participant{
+		config: config,
+		key:    solana.PublicKeyFromBytes(config.PublicKey),
+		client: rpc.New(config.RPCEndpoint),
+	}, nil
+}
+
+// Key returns the member key.
+func (p *Participant) Key() solana.PublicKey { return p.key }
+
+// Load fetches the multisig, proposal and vault transaction with the given
+// transaction index and checks that they belong together.
+func (p *Participant) Load(ctx context.Context, multisig solana.PublicKey, index uint64) (*Details, error) {
+	d := &Details{Address: multisig}
+	data, err := p.account(ctx, multisig)
+	if err != nil {
+		return nil, err
+	}
+	if d.Multisig, err = DecodeMultisig(data); err != nil {
+		return nil, err
+	}
+	if index == 0 || index > d.Multisig.TransactionIndex {
+		return nil, fmt.Errorf("multisig %s has no transaction %d", multisig, index)
+	}
+
+	address, err := ProposalAddress(multisig, index)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = p.account(ctx, address); err != nil {
+		return nil, err
+	}
+	if d.Proposal, err = DecodeProposal(data); err != nil {
+		return nil, err
+	}
+	if address, err = TransactionAddress(multisig, index); err != nil {
+		return nil, err
+	}
+	if data, err = p.account(ctx, address); err != nil {
+		return nil, err
+	}
+	if d.Transaction, err = DecodeVaultTransaction(data); err != nil {
+		return nil, err
+	}
+	if !d.Proposal.Multisig.Equals(multisig) || d.Proposal.TransactionIndex != index ||
+		!d.Transaction.Multisig.Equals(multisig) || d.Transaction.Index != index {
+		return nil, fmt.Errorf("proposal and transaction %d do not belong to multisig %s", index, multisig)
+	}
+	if d.Vault, err = VaultAddress(multisig, d.Transaction.VaultIndex); err != nil {
+		return nil, err
+	}
+	if p.config.Decoder != nil {
+		d.Summary, d.DescribeErr = Describe(p.config.Decoder, d.Transaction)
+	} else {
+		d.DescribeErr = fmt.Errorf("no decoder configured")
+	}
+	return d, nil
+}
+
+// Approve votes to approve the proposal of transaction index after Review
+// agrees, returning the signature of the vote transaction.
+func (p *Participant) Approve(ctx context.Context, multisig solana.PublicKey, index uint64, memo string) (string, error) {
+	return p.vote(ctx, multisig, index, memo, ActionApprove)
+}
+
+// Reject votes to reject the proposal of transaction index after Review
+// agrees, returning the signature of the vote transaction.
+func (p *Participant) Reject(ctx context.Context, multisig solana.PublicKey, index uint64, memo string) (string, error) {
+	return p.vote(ctx, multisig, index, memo, ActionReject)
+}
+
+func (p *Participant) vote(ctx context.Context, multisig solana.PublicKey, index uint64, memo string, action Action) (string, error) {
+	d, err := p.Load(ctx, multisig, index)
+	if err != nil {
+		return "", err
+	}
+	if err := p.permitted(d, PermissionVote); err != nil {
+		return "", err
+	}
+	switch {
+	case d.Proposal.Status != StatusActive:
+		return "", fmt.Errorf("proposal %d is %s, not active", index, d.Proposal.Status)
+	case index <= d.Multisig.StaleTransactionIndex:
+		return "", fmt.Errorf("proposal %d is stale", index)
+	case d.Proposal.Voted(p.key):
+		return "", fmt.Errorf("member %s already voted on proposal %d", p.key, index)
+	}
+	if err := p.config.Review(ctx, d, action); err != nil {
+		return "", fmt.Errorf("%s refused: %w", action, err)
+	}
+	var inst solana.Instruction
+	if action == ActionApprove {
+		inst, err = ProposalApprove(multisig, p.key, index, memo)
+	} else {
+		inst, err = ProposalReject(multisig, p.key, index, memo)
+	}
+	if err != nil {
+		return "", err
+	}
+	return p.send(ctx, inst)
+}
+
+// Execute executes the approved transaction index once its time lock has
+// passed and Review agrees, returning the signature of the transaction.
+func (p *Participant) Execute(ctx context.Context, multisig solana.PublicKey, index uint64) (string, error) {
+	d, err := p.Load(ctx, multisig, index)
+	if err != nil {
+		return "", err
+	}
+	if err := p.permitted(d, PermissionExecute); err != nil {
+		return "", err
+	}
+	if d.Proposal.Status != StatusApproved {
+		return "", fmt.Errorf("proposal %d is %s, not approved", index, d.Proposal.Status)
+	}
+	unlock := time.Unix(d.Proposal.Timestamp, 0).Add(time.Duration(d.Multisig.TimeLock) * time.Second)
+	if p.config.Now().Before(unlock) {
+		return "", fmt.Errorf("proposal %d is time-locked until %s", index, unlock.UTC().Format(time.RFC3339))
+	}
+	if err := p.config.Review(ctx, d, ActionExecute); err != nil {
+		return "", fmt.Errorf("%s refused: %w", ActionExecute, err)
+	}
+	inst, err := VaultTransactionExecute(multisig, p.key, d.Transaction)
+	if err != nil {
+		return "", err
+	}
+	return p.send(ctx, inst)
+}
+
+// permitted checks that the member key holds permission in the multisig.
+func (p *Participant) permitted(d *Details, permission uint8) error {
+	perms, ok := d.Multisig.Member(p.key)
+	if !ok {
+		return fmt.Errorf("%s is not a member of multisig %s", p.key, d.Address)
+	}
+	if perms&permission == 0 {
+		return fmt.Errorf("member %s lacks permission %d in multisig %s", p.key, permission, d.Address)
+	}
+	return nil
+}
+
+// send wraps inst in a transaction paid for by the member, signs it with the
+// MPC key and submits it.
+func (p *Participant) send(ctx context.Context, inst solana.Instruction) (string, error) {
+	bh, err := p.client.GetLatestBlockhash(ctx, p.config.Commitment)
+	if err != nil {
+		return "", fmt.Errorf("fetching latest blockhash: %w", err)
+	}
+	tx, err := solana.NewTransaction([]solana.Instruction{inst}, bh.Value.Blockhash, solana.TransactionPayer(p.key))
+	if err != nil {
+		return "", fmt.Errorf("building transaction: %w", err)
+	}
+	if tx.Message.Header.NumRequiredSignatures != 1 {
+		return "", fmt.Errorf("transaction requires %d signers; only the member can sign", tx.Message.Header.NumRequiredSignatures)
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return "", fmt.Errorf("encoding message: %w", err)
+	}
+	sig, err := p.config.Signer.Sign(ctx, message)
+	if err != nil {
+		return "", fmt.Errorf("signing: %w", err)
+	}
+	if !ed25519.Verify(p.config.PublicKey, message, sig) {
+		return "", fmt.Errorf("signer returned an invalid signature")
+	}
+	tx.Signatures = []solana.Signature{solana.SignatureFromBytes(sig)}
+	out, err := p.client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{PreflightCommitment: p.config.Commitment})
+	if err != nil {
+		return "", fmt.Errorf("sending transaction: %w", err)
+	}
+	return out.String(), nil
+}
+
+// account fetches the data of an existing account owned by the program.
+func (p *Participant) account(ctx context.Context, key solana.PublicKey) ([]byte, error) {
+	info, err := p.client.GetAccountInfoWithOpts(ctx, key, &rpc.GetAccountInfoOpts{Commitment: p.config.Commitment})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, fmt.Errorf("account %s does not exist", key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching account %s: %w", key, err)
+	}
+	if !info.Value.Owner.Equals(ProgramID) {
+		return nil, fmt.Errorf("account %s is not owned by the Squads program", key)
+	}
+	return info.Value.Data.GetBinary(), nil
+}
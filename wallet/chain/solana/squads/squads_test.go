@@ -0,0 +1,388 @@
+package squads
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	solchain "solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+// encoder builds Borsh account data for the tests.
+type encoder []byte
+
+func account(name string) *encoder {
+	e := encoder(discriminator("account:" + name))
+	return &e
+}
+
+func (e *encoder) u8(v uint8) *encoder   { *e = append(*e, v); return e }
+func (e *encoder) u16(v uint16) *encoder { *e = binary.LittleEndian.AppendUint16(*e, v); return e }
+func (e *encoder) u32(v uint32) *encoder { *e = binary.LittleEndian.AppendUint32(*e, v); return e }
+func (e *encoder) u64(v uint64) *encoder { *e = binary.LittleEndian.AppendUint64(*e, v); return e }
+
+func (e *encoder) key(k solana.PublicKey) *encoder { *e = append(*e, k[:]...); return e }
+
+func (e *encoder) bytes(b []byte) *encoder { return e.u32(uint32(len(b))).raw(b) }
+func (e *encoder) raw(b []byte) *encoder   { *e = append(*e, b...); return e }
+
+func (e *encoder) keys(keys ...solana.PublicKey) *encoder {
+	e.u32(uint32(len(keys)))
+	for _, k := range keys {
+		e.key(k)
+	}
+	return e
+}
+
+func encodeMultisig(m *Multisig) []byte {
+	e := account("Multisig").key(m.CreateKey).key(m.ConfigAuthority).u16(m.Threshold).u32(m.TimeLock).
+		u64(m.TransactionIndex).u64(m.StaleTransactionIndex)
+	if m.RentCollector != nil {
+		e.u8(1).key(*m.RentCollector)
+	} else {
+		e.u8(0)
+	}
+	e.u8(m.Bump).u32(uint32(len(m.Members)))
+	for _, member := range m.Members {
+		e.key(member.Key).u8(member.Permissions)
+	}
+	return *e
+}
+
+func encodeProposal(p *Proposal) []byte {
+	e := account("Proposal").key(p.Multisig).u64(p.TransactionIndex).u8(uint8(p.Status))
+	if p.Status != StatusExecuting {
+		e.u64(uint64(p.Timestamp))
+	}
+	return *e.u8(p.Bump).keys(p.Approved...).keys(p.Rejected...).keys(p.Cancelled...)
+}
+
+func encodeVaultTransaction(t *VaultTransaction) []byte {
+	m := &t.Message
+	e := account("VaultTransaction").key(t.Multisig).key(t.Creator).u64(t.Index).u8(t.Bump).u8(t.VaultIndex).
+		u8(t.VaultBump).bytes(t.EphemeralSignerBumps).u8(m.NumSigners).u8(m.NumWritableSigners).
+		u8(m.NumWritableNonSigners).keys(m.AccountKeys...).u32(uint32(len(m.Instructions)))
+	for _, ci := range m.Instructions {
+		e.u8(ci.ProgramIDIndex).bytes(ci.AccountIndexes).bytes(ci.Data)
+	}
+	e.u32(uint32(len(m.AddressTableLookups)))
+	for _, l := range m.AddressTableLookups {
+		e.key(l.AccountKey).bytes(l.WritableIndexes).bytes(l.ReadonlyIndexes)
+	}
+	return *e
+}
+
+// transferFromVault returns vault transaction index of multisig paying
+// lamports from its first vault to recipient.
+func transferFromVault(t *testing.T, multisig, recipient solana.PublicKey, index, lamports uint64) *VaultTransaction {
+	t.Helper()
+	vault, err := VaultAddress(multisig, 0)
+	require.NoError(t, err)
+	data := binary.LittleEndian.AppendUint32(nil, 2) // system Transfer
+	data = binary.LittleEndian.AppendUint64(data, lamports)
+	return &VaultTransaction{
+		Multisig: multisig,
+		Creator:  solana.NewWallet().PublicKey(),
+		Index:    index,
+		Message: Message{
+			NumSigners:            1,
+			NumWritableSigners:    1,
+			NumWritableNonSigners: 1,
+			AccountKeys:           []solana.PublicKey{vault, recipient, system.ProgramID},
+			Instructions:          []CompiledInstruction{{ProgramIDIndex: 2, AccountIndexes: []uint8{0, 1}, Data: data}},
+		},
+	}
+}
+
+func TestDecodeAccounts(t *testing.T) {
+	collector := solana.NewWallet().PublicKey()
+	ms := &Multisig{
+		CreateKey:        solana.NewWallet().PublicKey(),
+		Threshold:        2,
+		TimeLock:         60,
+		TransactionIndex: 7,
+		RentCollector:    &collector,
+		Bump:             254,
+		Members: []Member{
+			{Key: solana.NewWallet().PublicKey(), Permissions: PermissionInitiate | PermissionVote | PermissionExecute},
+			{Key: solana.NewWallet().PublicKey(), Permissions: PermissionVote},
+		},
+	}
+	got, err := DecodeMultisig(encodeMultisig(ms))
+	require.NoError(t, err)
+	assert.Equal(t, ms, got)
+	perms, ok := got.Member(ms.Members[1].Key)
+	assert.True(t, ok)
+	assert.Equal(t, PermissionVote, perms)
+	_, ok = got.Member(collector)
+	assert.False(t, ok)
+
+	for _, p := range []*Proposal{
+		{Multisig: solana.NewWallet().PublicKey(), TransactionIndex: 3, Status: StatusActive, Timestamp: 1_700_000_000,
+			Approved: []solana.PublicKey{collector}},
+		{Multisig: solana.NewWallet().PublicKey(), TransactionIndex: 4, Status: StatusExecuting},
+	} {
+		got, err := DecodeProposal(encodeProposal(p))
+		require.NoError(t, err)
+		assert.Equal(t, p, got)
+	}
+
+	tx := transferFromVault(t, solana.NewWallet().PublicKey(), collector, 3, 1_000)
+	tx.EphemeralSignerBumps = []uint8{251}
+	gotTx, err := DecodeVaultTransaction(encodeVaultTransaction(tx))
+	require.NoError(t, err)
+	assert.Equal(t, tx, gotTx)
+
+	// Wrong account type and truncated data are rejected.
+	_, err = DecodeProposal(encodeMultisig(ms))
+	assert.ErrorContains(t, err, "not a Proposal account")
+	data := encodeMultisig(ms)
+	_, err = DecodeMultisig(data[:len(data)-1])
+	assert.Error(t, err)
+	// A vector length larger than the data must not allocate.
+	bogus := account("Proposal").key(collector).u64(1).u8(uint8(StatusActive)).u64(0).u8(0).u32(1 << 30)
+	_, err = DecodeProposal(*bogus)
+	assert.ErrorContains(t, err, "exceeds account data")
+}
+
+func TestVoteInstructions(t *testing.T) {
+	multisig, member := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	proposal, err := ProposalAddress(multisig, 5)
+	require.NoError(t, err)
+
+	inst, err := ProposalApprove(multisig, member, 5, "")
+	require.NoError(t, err)
+	assert.Equal(t, ProgramID, inst.ProgramID())
+	data, err := inst.Data()
+	require.NoError(t, err)
+	assert.Equal(t, append(discriminator("global:proposal_approve"), 0), data)
+	accounts := inst.Accounts()
+	require.Len(t, accounts, 3)
+	assert.Equal(t, solana.Meta(multisig), accounts[0])
+	assert.Equal(t, solana.Meta(member).WRITE().SIGNER(), accounts[1])
+	assert.Equal(t, solana.Meta(proposal).WRITE(), accounts[2])
+
+	inst, err = ProposalReject(multisig, member, 5, "no")
+	require.NoError(t, err)
+	data, err = inst.Data()
+	require.NoError(t, err)
+	assert.Equal(t, append(discriminator("global:proposal_reject"), 1, 2, 0, 0, 0, 'n', 'o'), data)
+}
+
+func TestVaultTransactionExecuteAccounts(t *testing.T) {
+	multisig, member, recipient := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	tx := transferFromVault(t, multisig, recipient, 9, 1_000)
+	inst, err := VaultTransactionExecute(multisig, member, tx)
+	require.NoError(t, err)
+
+	data, err := inst.Data()
+	require.NoError(t, err)
+	assert.Equal(t, discriminator("global:vault_transaction_execute"), data)
+	proposal, _ := ProposalAddress(multisig, 9)
+	transaction, _ := TransactionAddress(multisig, 9)
+	vault, _ := VaultAddress(multisig, 0)
+	assert.Equal(t, []*solana.AccountMeta{
+		solana.Meta(multisig),
+		solana.Meta(proposal).WRITE(),
+		solana.Meta(transaction),
+		solana.Meta(member).SIGNER(),
+		// The vault signs through the program, not the transaction.
+		solana.Meta(vault).WRITE(),
+		solana.Meta(recipient).WRITE(),
+		solana.Meta(system.ProgramID),
+	}, inst.Accounts())
+
+	_, err = VaultTransactionExecute(solana.NewWallet().PublicKey(), member, tx)
+	assert.Error(t, err)
+	tx.Message.AddressTableLookups = []AddressTableLookup{{AccountKey: recipient}}
+	_, err = VaultTransactionExecute(multisig, member, tx)
+	assert.ErrorContains(t, err, "lookup tables")
+}
+
+func testDecoder(t *testing.T) chain.Chain {
+	t.Helper()
+	c, err := solchain.New(solchain.Config{ID: "solana-test", RPCEndpoint: "http://127.0.0.1:0"})
+	require.NoError(t, err)
+	return c
+}
+
+func TestDescribe(t *testing.T) {
+	multisig, recipient := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	vault, _ := VaultAddress(multisig, 0)
+	summary, err := Describe(testDecoder(t), transferFromVault(t, multisig, recipient, 1, 2_500_000))
+	require.NoError(t, err)
+	assert.Equal(t, vault.String(), summary.From)
+	assert.Equal(t, recipient.String(), summary.To)
+	assert.Equal(t, int64(2_500_000), summary.Amount.Int64())
+	assert.Equal(t, 0, summary.Fee.Sign())
+
+	tx := transferFromVault(t, multisig, recipient, 1, 1)
+	tx.Message.Instructions[0].AccountIndexes = []uint8{0, 9}
+	_, err = Describe(testDecoder(t), tx)
+	assert.ErrorContains(t, err, "out of range")
+}
+
+// fakeCluster serves the JSON-RPC methods used by Participant from a map of
+// accounts, recording submitted transactions.
+type fakeCluster struct {
+	t        *testing.T
+	mu       sync.Mutex
+	accounts map[solana.PublicKey][]byte
+	sent     []*solana.Transaction
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var result any
+	switch req.Method {
+	case "getAccountInfo":
+		var key solana.PublicKey
+		require.NoError(f.t, json.Unmarshal(req.Params[0], &key))
+		data, ok := f.accounts[key]
+		if !ok {
+			result = map[string]any{"context": map[string]any{"slot": 1}, "value": nil}
+			break
+		}
+		result = map[string]any{"context": map[string]any{"slot": 1}, "value": map[string]any{
+			"data": []string{base64.StdEncoding.EncodeToString(data), "base64"}, "executable": false,
+			"lamports": 1_000_000, "owner": ProgramID.String(), "rentEpoch": 0,
+		}}
+	case "getLatestBlockhash":
+		result = map[string]any{"context": map[string]any{"slot": 1}, "value": map[string]any{
+			"blockhash": solana.Hash{7}.String(), "lastValidBlockHeight": 100,
+		}}
+	case "sendTransaction":
+		var encoded string
+		require.NoError(f.t, json.Unmarshal(req.Params[0], &encoded))
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(f.t, err)
+		var tx solana.Transaction
+		require.NoError(f.t, tx.UnmarshalWithDecoder(bin.NewBinDecoder(raw)))
+		require.NoError(f.t, tx.VerifySignatures())
+		f.sent = append(f.sent, &tx)
+		result = tx.Signatures[0].String()
+	default:
+		f.t.Errorf("unexpected method %s", req.Method)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(f.t, json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result}))
+}
+
+func TestParticipant(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	key := solana.PublicKeyFromBytes(pub)
+	other := solana.NewWallet().PublicKey()
+	recipient := solana.NewWallet().PublicKey()
+	createKey := solana.NewWallet().PublicKey()
+	multisig, err := MultisigAddress(createKey)
+	require.NoError(t, err)
+
+	ms := &Multisig{CreateKey: createKey, Threshold: 2, TimeLock: 3600, TransactionIndex: 2, Members: []Member{
+		{Key: key, Permissions: PermissionVote | PermissionExecute},
+		{Key: other, Permissions: PermissionInitiate | PermissionVote},
+	}}
+	cluster := &fakeCluster{t: t, accounts: map[solana.PublicKey][]byte{multisig: encodeMultisig(ms)}}
+	now := time.Unix(1_700_000_000, 0)
+	for index, status := range map[uint64]ProposalStatus{1: StatusApproved, 2: StatusActive} {
+		proposal, _ := ProposalAddress(multisig, index)
+		transaction, _ := TransactionAddress(multisig, index)
+		cluster.accounts[proposal] = encodeProposal(&Proposal{Multisig: multisig, TransactionIndex: index, Status: status,
+			Timestamp: now.Add(-30 * time.Minute).Unix(), Approved: []solana.PublicKey{other}})
+		cluster.accounts[transaction] = encodeVaultTransaction(transferFromVault(t, multisig, recipient, index, 1_000))
+	}
+	srv := httptest.NewServer(cluster)
+	defer srv.Close()
+
+	var reviewed []Action
+	refuse := false
+	p, err := New(Config{
+		RPCEndpoint: srv.URL,
+		PublicKey:   pub,
+		Signer: remotesigner.SignerFunc(func(_ context.Context, message []byte) ([]byte, error) {
+			return ed25519.Sign(priv, message), nil
+		}),
+		Decoder: testDecoder(t),
+		Review: func(_ context.Context, d *Details, action Action) error {
+			require.NoError(t, d.DescribeErr)
+			assert.Equal(t, recipient.String(), d.Summary.To)
+			assert.Equal(t, 0, big.NewInt(1_000).Cmp(d.Summary.Amount))
+			reviewed = append(reviewed, action)
+			if refuse {
+				return errors.New("quorum declined")
+			}
+			return nil
+		},
+		Now: func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	sig, err := p.Approve(ctx, multisig, 2, "ok")
+	require.NoError(t, err)
+	require.Len(t, cluster.sent, 1)
+	sent := cluster.sent[0]
+	assert.Equal(t, sent.Signatures[0].String(), sig)
+	assert.Equal(t, key, sent.Message.AccountKeys[0], "member pays for its vote")
+	data := []byte(sent.Message.Instructions[0].Data)
+	assert.Equal(t, discriminator("global:proposal_approve"), data[:8])
+
+	// Voting is only possible on active proposals, and refusals stop the vote
+	// before anything is signed.
+	_, err = p.Reject(ctx, multisig, 1, "")
+	assert.ErrorContains(t, err, "not active")
+	refuse = true
+	_, err = p.Reject(ctx, multisig, 2, "")
+	assert.ErrorContains(t, err, "quorum declined")
+	refuse = false
+	assert.Len(t, cluster.sent, 1)
+
+	// Executing waits for the time lock.
+	_, err = p.Execute(ctx, multisig, 1)
+	assert.ErrorContains(t, err, "time-locked")
+	now = now.Add(time.Hour)
+	_, err = p.Execute(ctx, multisig, 1)
+	require.NoError(t, err)
+	require.Len(t, cluster.sent, 2)
+	data = []byte(cluster.sent[1].Message.Instructions[0].Data)
+	assert.Equal(t, discriminator("global:vault_transaction_execute"), data)
+	assert.Equal(t, []Action{ActionApprove, ActionReject, ActionExecute}, reviewed)
+
+	_, err = p.Execute(ctx, multisig, 2)
+	assert.ErrorContains(t, err, "not approved")
+	_, err = p.Approve(ctx, multisig, 3, "")
+	assert.ErrorContains(t, err, "no transaction 3")
+
+	// A key that is not a member is refused.
+	outsider, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	q, err := New(Config{RPCEndpoint: srv.URL, PublicKey: outsider, Signer: p.config.Signer, Review: p.config.Review})
+	require.NoError(t, err)
+	_, err = q.Approve(ctx, multisig, 2, "")
+	assert.ErrorContains(t, err, "not a member")
+}
@@ -0,0 +1,165 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+const (
+	// tokenTransferComputeUnits covers creating the recipient's associated
+	// token account plus a TransferChecked.
+	tokenTransferComputeUnits = 40_000
+	// tokenAccountSize is the size of an SPL token account, which determines
+	// its rent-exempt deposit.
+	tokenAccountSize = 165
+)
+
+// createIdempotent is the instruction data of the associated-token-account
+// program's CreateIdempotent instruction, which succeeds whether or not the
+// account already exists.
+var createIdempotent = []byte{1}
+
+// buildTokenTransfer assembles an SPL token transfer from the sender's
+// associated token account to the recipient's, creating the latter if
+// needed.  The creation instruction is always included: it is a no-op for an
+// existing account, and it lets Decode recover the recipient wallet offline.
+func (c *Chain) buildTokenTransfer(transfer *chain.Transfer, blockhash solana.Hash, fee *chain.FeeEstimate, decimals uint8) (*chain.UnsignedTx, error) {
+	if len(transfer.Outputs) > 0 {
+		return nil, fmt.Errorf("batched token transfers are not supported")
+	}
+	from, err := solana.PublicKeyFromBase58(transfer.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := solana.PublicKeyFromBase58(transfer.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+	mint, err := solana.PublicKeyFromBase58(transfer.Token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token mint: %w", err)
+	}
+	if transfer.Amount == nil || transfer.Amount.Sign() <= 0 || !transfer.Amount.IsUint64() {
+		return nil, fmt.Errorf("amount must be a positive 64-bit token value")
+	}
+	source, _, err := solana.FindAssociatedTokenAddress(from, mint)
+	if err != nil {
+		return nil, fmt.Errorf("deriving sender token account: %w", err)
+	}
+	destination, _, err := solana.FindAssociatedTokenAddress(to, mint)
+	if err != nil {
+		return nil, fmt.Errorf("deriving recipient token account: %w", err)
+	}
+
+	create := solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, solana.AccountMetaSlice{
+		solana.Meta(from).WRITE().SIGNER(),
+		solana.Meta(destination).WRITE(),
+		solana.Meta(to),
+		solana.Meta(mint),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+	}, createIdempotent)
+	send := token.NewTransferCheckedInstruction(transfer.Amount.Uint64(), decimals, source, mint, destination, from, nil).Build()
+	return c.compile(from, blockhash, fee, tokenTransferComputeUnits, create, send)
+}
+
+// decodeTokenTransfer checks that create and send form a token transfer as
+// built by buildTokenTransfer and summarises it.  Chain and Fee are left to
+// the caller.
+func decodeTokenTransfer(msg *solana.Message, create, send *solana.CompiledInstruction) (*chain.Summary, error) {
+	program, err := msg.Program(create.ProgramIDIndex)
+	if err != nil {
+		return nil, fmt.Errorf("resolving program: %w", err)
+	}
+	if !program.Equals(solana.SPLAssociatedTokenAccountProgramID) {
+		return nil, fmt.Errorf("unsupported program %s", program)
+	}
+	if !bytes.Equal(create.Data, createIdempotent) {
+		return nil, fmt.Errorf("unsupported associated-token-account instruction")
+	}
+	accounts, err := create.ResolveInstructionAccounts(msg)
+	if err != nil {
+		return nil, fmt.Errorf("resolving accounts: %w", err)
+	}
+	if len(accounts) != 6 || !accounts[4].PublicKey.Equals(solana.SystemProgramID) || !accounts[5].PublicKey.Equals(solana.TokenProgramID) {
+		return nil, fmt.Errorf("malformed associated-token-account instruction")
+	}
+	payer, ata, wallet, mint := accounts[0].PublicKey, accounts[1].PublicKey, accounts[2].PublicKey, accounts[3].PublicKey
+
+	program, err = msg.Program(send.ProgramIDIndex)
+	if err != nil {
+		return nil, fmt.Errorf("resolving program: %w", err)
+	}
+	if !program.Equals(solana.TokenProgramID) {
+		return nil, fmt.Errorf("unsupported program %s", program)
+	}
+	if accounts, err = send.ResolveInstructionAccounts(msg); err != nil {
+		return nil, fmt.Errorf("resolving accounts: %w", err)
+	}
+	inst, err := token.DecodeInstruction(accounts, send.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding token instruction: %w", err)
+	}
+	transfer, ok := inst.Impl.(*token.TransferChecked)
+	if !ok {
+		return nil, fmt.Errorf("unsupported token instruction %s", token.InstructionIDToName(inst.TypeID.Uint8()))
+	}
+	if len(transfer.Signers) != 0 {
+		return nil, fmt.Errorf("multisig token transfers are not supported")
+	}
+	owner := transfer.GetOwnerAccount().PublicKey
+
+	// The recipient wallet is only named by the creation instruction, so it
+	// must provably own the account the tokens are sent to.
+	expected, _, err := solana.FindAssociatedTokenAddress(wallet, mint)
+	if err != nil {
+		return nil, fmt.Errorf("deriving recipient token account: %w", err)
+	}
+	switch {
+	case !expected.Equals(ata) || !ata.Equals(transfer.GetDestinationAccount().PublicKey):
+		return nil, fmt.Errorf("destination is not the recipient's associated token account")
+	case !mint.Equals(transfer.GetMintAccount().PublicKey):
+		return nil, fmt.Errorf("token account is created for a different mint")
+	case !payer.Equals(owner) || !msg.AccountKeys[0].Equals(owner):
+		return nil, fmt.Errorf("token owner must pay for the transaction")
+	}
+	return &chain.Summary{
+		From:   owner.String(),
+		To:     wallet.String(),
+		Amount: new(big.Int).SetUint64(*transfer.Amount),
+		Token:  mint.String(),
+	}, nil
+}
+
+// mintDecimals fetches the number of decimals of an SPL token mint.
+func (c *Chain) mintDecimals(ctx context.Context, mint solana.PublicKey) (uint8, error) {
+	var info *rpc.GetAccountInfoResult
+	err := c.call(ctx, func(ctx context.Context) (err error) {
+		info, err = c.client.GetAccountInfoWithOpts(ctx, mint, &rpc.GetAccountInfoOpts{Commitment: c.commitment})
+		return err
+	})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return 0, fmt.Errorf("token mint %s does not exist", mint)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("fetching token mint: %w", err)
+	}
+	if !info.Value.Owner.Equals(solana.TokenProgramID) {
+		return 0, fmt.Errorf("%s is not an SPL token mint", mint)
+	}
+	var m token.Mint
+	if err := m.UnmarshalWithDecoder(bin.NewBinDecoder(info.Value.Data.GetBinary())); err != nil {
+		return 0, fmt.Errorf("decoding token mint: %w", err)
+	}
+	return m.Decimals, nil
+}
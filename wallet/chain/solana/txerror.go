@@ -0,0 +1,173 @@
+package solana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// codeSimulationFailed is the JSON-RPC error code of a sendTransaction
+// whose preflight simulation failed.
+const codeSimulationFailed = -32002
+
+// TransactionError describes why the ledger rejected a transaction, as
+// reported by simulateTransaction, sendTransaction's preflight or
+// getSignatureStatuses.
+type TransactionError struct {
+	// Kind is the TransactionError variant, e.g. "InstructionError" or
+	// "BlockhashNotFound".
+	Kind string
+	// Instruction is the index of the failing instruction, or -1 if the
+	// error is not an instruction's.
+	Instruction int
+	// Reason is the InstructionError variant, e.g. "Custom" or
+	// "InvalidAccountData".
+	Reason string
+	// Code is the program's own error code when Reason is "Custom".
+	Code uint32
+	// Program is the program that failed, taken from the logs.
+	Program string
+	// Message is the last message the failing program logged.
+	Message string
+	// Logs are the program logs of the simulation, if any.
+	Logs []string
+	// Raw is the error as returned by the node.
+	Raw json.RawMessage
+	// Err is the RPC error it was parsed from, if any.
+	Err error
+}
+
+func (e *TransactionError) Error() string {
+	var b strings.Builder
+	if e.Instruction < 0 {
+		b.WriteString(e.Kind)
+	} else {
+		fmt.Fprintf(&b, "instruction %d", e.Instruction)
+		if e.Program != "" {
+			fmt.Fprintf(&b, " (program %s)", e.Program)
+		}
+		b.WriteString(" failed: ")
+		if e.Reason == "Custom" {
+			fmt.Fprintf(&b, "custom program error 0x%x", e.Code)
+		} else {
+			b.WriteString(e.Reason)
+		}
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	return b.String()
+}
+
+// Unwrap returns the RPC error the TransactionError was parsed from.
+func (e *TransactionError) Unwrap() error { return e.Err }
+
+// parseTransactionError decodes the err value of a transaction status or
+// simulation: a variant name, or an object with a single variant key.
+func parseTransactionError(value any, logs []string) *TransactionError {
+	if value == nil {
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	e := &TransactionError{Instruction: -1, Logs: logs, Raw: raw}
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		e.Kind = name
+		return e
+	}
+	var variant map[string]json.RawMessage
+	if json.Unmarshal(raw, &variant) != nil || len(variant) != 1 {
+		e.Kind = string(raw)
+		return e
+	}
+	for kind, detail := range variant {
+		e.Kind = kind
+		if kind == "InstructionError" {
+			e.parseInstructionError(detail)
+		}
+	}
+	e.Program, e.Message = failingProgram(logs)
+	return e
+}
+
+// parseInstructionError decodes [index, reason], where reason is a variant
+// name or an object such as {"Custom": 6001}.
+func (e *TransactionError) parseInstructionError(detail json.RawMessage) {
+	var pair []json.RawMessage
+	if json.Unmarshal(detail, &pair) != nil || len(pair) != 2 {
+		return
+	}
+	var index int
+	if json.Unmarshal(pair[0], &index) != nil {
+		return
+	}
+	e.Instruction = index
+	if json.Unmarshal(pair[1], &e.Reason) == nil {
+		return
+	}
+	var reason map[string]json.RawMessage
+	if json.Unmarshal(pair[1], &reason) != nil {
+		e.Reason = string(pair[1])
+		return
+	}
+	for name, value := range reason {
+		e.Reason = name
+		if name == "Custom" {
+			json.Unmarshal(value, &e.Code)
+		}
+	}
+}
+
+// failingProgram finds the program whose failure ended the logs and the last
+// message it logged.
+func failingProgram(logs []string) (program, message string) {
+	for i := len(logs) - 1; i >= 0; i-- {
+		line := strings.TrimPrefix(logs[i], "Program ")
+		if line == logs[i] {
+			continue
+		}
+		if id, _, ok := strings.Cut(line, " failed: "); ok && program == "" && !strings.HasPrefix(line, "log: ") && !strings.Contains(id, " ") {
+			program = id
+			continue
+		}
+		if program != "" {
+			if msg, ok := strings.CutPrefix(line, "log: "); ok {
+				return program, msg
+			}
+			if strings.HasPrefix(line, program+" invoke") {
+				return program, ""
+			}
+		}
+	}
+	return program, ""
+}
+
+// sendError turns the RPC error of a sendTransaction whose preflight
+// simulation failed into a *TransactionError, and returns other errors
+// unchanged.
+func sendError(err error) error {
+	var re *jsonrpc.RPCError
+	if !errors.As(err, &re) || re.Code != codeSimulationFailed {
+		return err
+	}
+	raw, merr := json.Marshal(re.Data)
+	if merr != nil {
+		return err
+	}
+	var data struct {
+		Err  any      `json:"err"`
+		Logs []string `json:"logs"`
+	}
+	if json.Unmarshal(raw, &data) != nil || data.Err == nil {
+		return err
+	}
+	e := parseTransactionError(data.Err, data.Logs)
+	e.Err = err
+	return e
+}
@@ -0,0 +1,132 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/retry"
+)
+
+var failedLogs = []string{
+	"Program 11111111111111111111111111111111 invoke [1]",
+	"Program 11111111111111111111111111111111 success",
+	"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA invoke [1]",
+	"Program log: Instruction: Transfer",
+	"Program log: Error: insufficient funds",
+	"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA consumed 4381 of 400000 compute units",
+	"Program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA failed: custom program error: 0x1",
+}
+
+func TestParseTransactionError(t *testing.T) {
+	var custom any
+	require.NoError(t, json.Unmarshal([]byte(`{"InstructionError":[1,{"Custom":1}]}`), &custom))
+	e := parseTransactionError(custom, failedLogs)
+	assert.Equal(t, "InstructionError", e.Kind)
+	assert.Equal(t, 1, e.Instruction)
+	assert.Equal(t, "Custom", e.Reason)
+	assert.Equal(t, uint32(1), e.Code)
+	assert.Equal(t, "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", e.Program)
+	assert.Equal(t, "Error: insufficient funds", e.Message)
+	assert.Equal(t, "instruction 1 (program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA) failed: "+
+		"custom program error 0x1: Error: insufficient funds", e.Error())
+
+	var named any
+	require.NoError(t, json.Unmarshal([]byte(`{"InstructionError":[0,"InvalidAccountData"]}`), &named))
+	e = parseTransactionError(named, nil)
+	assert.Equal(t, "instruction 0 failed: InvalidAccountData", e.Error())
+
+	e = parseTransactionError("BlockhashNotFound", nil)
+	assert.Equal(t, -1, e.Instruction)
+	assert.Equal(t, "BlockhashNotFound", e.Error())
+
+	var rent any
+	require.NoError(t, json.Unmarshal([]byte(`{"InsufficientFundsForRent":{"account_index":2}}`), &rent))
+	assert.Equal(t, "InsufficientFundsForRent", parseTransactionError(rent, nil).Error())
+	assert.Nil(t, parseTransactionError(nil, nil))
+}
+
+// rpcServer answers every call with the given JSON-RPC error or result.
+func rpcServer(t *testing.T, rpcErr, result map[string]any) *Chain {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		if rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	c, err := New(Config{ID: "solana-test", RPCEndpoint: srv.URL})
+	require.NoError(t, err)
+	return c
+}
+
+func signedTestTx(t *testing.T, c *Chain) *chain.SignedTx {
+	t.Helper()
+	transfer := &chain.Transfer{
+		From:   solana.NewWallet().PublicKey().String(),
+		To:     solana.NewWallet().PublicKey().String(),
+		Amount: big.NewInt(1),
+	}
+	unsigned, err := c.buildTransfer(transfer, solana.Hash{1}, newFeeEstimate(1, 1_000, 0))
+	require.NoError(t, err)
+	return &chain.SignedTx{Unsigned: unsigned, Signature: make([]byte, 64)}
+}
+
+func TestBroadcastSurfacesPreflightFailure(t *testing.T) {
+	c := rpcServer(t, map[string]any{
+		"code":    -32002,
+		"message": "Transaction simulation failed: Error processing Instruction 1: custom program error: 0x1",
+		"data": map[string]any{
+			"err":  map[string]any{"InstructionError": []any{1, map[string]any{"Custom": 1}}},
+			"logs": failedLogs,
+		},
+	}, nil)
+	_, err := c.Broadcast(context.Background(), signedTestTx(t, c))
+
+	var te *TransactionError
+	require.ErrorAs(t, err, &te)
+	assert.Equal(t, 1, te.Instruction)
+	assert.Equal(t, uint32(1), te.Code)
+	assert.Equal(t, failedLogs, te.Logs)
+	assert.ErrorContains(t, err, "sending transaction: instruction 1 (program TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA) failed")
+
+	// The RPC error stays reachable and is still permanent.
+	var re *jsonrpc.RPCError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, retry.Permanent, Classify(err))
+}
+
+func TestSimulateSurfacesFailure(t *testing.T) {
+	c := rpcServer(t, nil, map[string]any{
+		"context": map[string]any{"slot": 9},
+		"value": map[string]any{
+			"err":  map[string]any{"InstructionError": []any{1, map[string]any{"Custom": 1}}},
+			"logs": failedLogs,
+		},
+	})
+	res, err := c.Simulate(context.Background(), signedTestTx(t, c))
+	require.NoError(t, err)
+	assert.False(t, res.OK)
+	assert.Contains(t, res.Err, "custom program error 0x1: Error: insufficient funds")
+	var te *TransactionError
+	require.ErrorAs(t, res.Detail, &te)
+	assert.Equal(t, "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", te.Program)
+}
@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrReceiptMismatch is returned when the verifying provider contradicts the
+// primary provider about a transaction's outcome.
+var ErrReceiptMismatch = errors.New("chain: receipt verification failed")
+
+// verifiedChain routes every call to the primary chain but double-checks
+// terminal receipts against an independent verifier.
+type verifiedChain struct {
+	Chain
+	verifier Chain
+}
+
+// WithReceiptVerifier wraps primary so that Confirm only reports a terminal
+// status (finalized or failed) once verifier – a Chain instance backed by an
+// independent RPC provider – agrees on both the status and the height.
+//
+// While the verifier lags behind, Confirm downgrades the primary's finalized
+// receipt to StatusConfirmed so callers keep polling. A contradiction yields
+// ErrReceiptMismatch. This defends high-value transfers against a single
+// malicious or buggy RPC provider reporting false confirmations.
+func WithReceiptVerifier(primary, verifier Chain) (Chain, error) {
+	if primary == nil || verifier == nil {
+		return nil, fmt.Errorf("primary and verifier chains must be provided")
+	}
+	if primary.ID() != verifier.ID() {
+		return nil, fmt.Errorf("verifier chain %q does not match primary chain %q", verifier.ID(), primary.ID())
+	}
+	return &verifiedChain{Chain: primary, verifier: verifier}, nil
+}
+
+// Confirm implements Chain.
+func (c *verifiedChain) Confirm(ctx context.Context, txID string) (*Receipt, error) {
+	primary, err := c.Chain.Confirm(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if !primary.Status.terminal() {
+		return primary, nil
+	}
+
+	second, err := c.verifier.Confirm(ctx, txID)
+	if errors.Is(err, ErrNotFound) {
+		return awaitingVerification(primary), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("verifying receipt for %s: %w", txID, err)
+	}
+	switch {
+	case second.Status == primary.Status && second.Height == primary.Height:
+		return primary, nil
+	case primary.Status == StatusFinalized && !second.Status.terminal():
+		return awaitingVerification(primary), nil
+	default:
+		return nil, fmt.Errorf("%w: %s is %s at height %d according to primary but %s at height %d according to verifier",
+			ErrReceiptMismatch, txID, primary.Status, primary.Height, second.Status, second.Height)
+	}
+}
+
+// awaitingVerification returns a copy of r downgraded to StatusConfirmed.
+func awaitingVerification(r *Receipt) *Receipt {
+	out := *r
+	out.Status = StatusConfirmed
+	return &out
+}
+
+// terminal reports whether the status can no longer change.
+func (s Status) terminal() bool {
+	return s == StatusFinalized || s == StatusFailed
+}
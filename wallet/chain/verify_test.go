@@ -0,0 +1,92 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubChain is a Chain whose Confirm returns a canned receipt.
+type stubChain struct {
+	Chain
+	id      string
+	receipt *Receipt
+	err     error
+}
+
+func (s *stubChain) ID() string { return s.id }
+
+func (s *stubChain) Confirm(context.Context, string) (*Receipt, error) {
+	return s.receipt, s.err
+}
+
+func TestWithReceiptVerifier(t *testing.T) {
+	finalized := &Receipt{TxID: "tx", Status: StatusFinalized, Height: 10}
+	cases := []struct {
+		name     string
+		primary  *stubChain
+		verifier *stubChain
+		want     Status
+		wantErr  error
+	}{
+		{
+			name:     "agreement",
+			primary:  &stubChain{receipt: finalized},
+			verifier: &stubChain{receipt: finalized},
+			want:     StatusFinalized,
+		},
+		{
+			name:     "verifier has not seen the transaction",
+			primary:  &stubChain{receipt: finalized},
+			verifier: &stubChain{err: ErrNotFound},
+			want:     StatusConfirmed,
+		},
+		{
+			name:     "verifier lags",
+			primary:  &stubChain{receipt: finalized},
+			verifier: &stubChain{receipt: &Receipt{TxID: "tx", Status: StatusConfirmed, Height: 10}},
+			want:     StatusConfirmed,
+		},
+		{
+			name:     "primary still pending skips verification",
+			primary:  &stubChain{receipt: &Receipt{TxID: "tx", Status: StatusPending}},
+			verifier: &stubChain{err: errors.New("must not be called")},
+			want:     StatusPending,
+		},
+		{
+			name:     "verifier reports failure",
+			primary:  &stubChain{receipt: finalized},
+			verifier: &stubChain{receipt: &Receipt{TxID: "tx", Status: StatusFailed, Height: 10}},
+			wantErr:  ErrReceiptMismatch,
+		},
+		{
+			name:     "heights differ",
+			primary:  &stubChain{receipt: finalized},
+			verifier: &stubChain{receipt: &Receipt{TxID: "tx", Status: StatusFinalized, Height: 11}},
+			wantErr:  ErrReceiptMismatch,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.primary.id, tc.verifier.id = "test", "test"
+			c, err := WithReceiptVerifier(tc.primary, tc.verifier)
+			require.NoError(t, err)
+
+			receipt, err := c.Confirm(context.Background(), "tx")
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, receipt.Status)
+		})
+	}
+}
+
+func TestWithReceiptVerifierRejectsDifferentChains(t *testing.T) {
+	_, err := WithReceiptVerifier(&stubChain{id: "a"}, &stubChain{id: "b"})
+	assert.Error(t, err)
+}
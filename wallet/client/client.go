@@ -0,0 +1,275 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultPollInterval = time.Second
+	// maxResponseBytes bounds the responses the client reads.
+	maxResponseBytes = 1 << 20
+)
+
+// Session states as reported by the request API.
+const (
+	StateSigned    = "signed"
+	StateBroadcast = "broadcast"
+	StateFinalized = "finalized"
+	StateFailed    = "failed"
+)
+
+// ErrFailed is wrapped by WaitSigned and WaitFinalized when the session
+// failed.
+var ErrFailed = errors.New("client: session failed")
+
+// ErrBadSignature is wrapped by Verifiers when a signature does not verify.
+var ErrBadSignature = errors.New("client: signature does not verify")
+
+// Config contains the configuration for a Client.
+type Config struct {
+	// URL is the base URL of the request API.  Required.
+	URL string
+	// Token is sent as bearer token with every request.
+	Token string
+	// Client sends the requests.  Defaults to an http.Client with a 10s
+	// timeout.
+	Client *http.Client
+	// PollInterval is how often WaitSigned and WaitFinalized poll.  Defaults
+	// to 1s.
+	PollInterval time.Duration
+	// Verifier, if set, checks the signature of every session the client
+	// returns; a session whose signature does not verify is returned with an
+	// error wrapping ErrBadSignature.
+	Verifier Verifier
+}
+
+// Client submits transfers to the request API and follows their sessions.
+type Client struct {
+	base   *url.URL
+	config Config
+}
+
+// TransferRequest is a transfer to be signed.  Amounts are decimal integers
+// in the asset's smallest unit.
+type TransferRequest struct {
+	Chain     string          `json:"chain"`
+	From      string          `json:"from"`
+	To        string          `json:"to,omitempty"`
+	Amount    string          `json:"amount,omitempty"`
+	Token     string          `json:"token,omitempty"`
+	Outputs   []Output        `json:"outputs,omitempty"`  // Instead of To and Amount
+	Priority  string          `json:"priority,omitempty"` // "batch", "normal" or "urgent"
+	MaxFee    string          `json:"max_fee,omitempty"`
+	Reference string          `json:"reference,omitempty"` // Idempotency key, e.g. an order ID
+	Metadata  json.RawMessage `json:"metadata,omitempty"`  // Signed coordinator.Metadata
+	// PreApprovals are coordinator.PreApproval tokens for the transaction.
+	PreApprovals json.RawMessage `json:"pre_approvals,omitempty"`
+}
+
+// Output is one recipient of a multi-output transfer.
+type Output struct {
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+// Session is the state of a signing session.
+type Session struct {
+	ID                string    `json:"id"`
+	State             string    `json:"state"`
+	Chain             string    `json:"chain"`
+	From              string    `json:"from"`
+	Reference         string    `json:"reference,omitempty"`
+	RequiredApprovals int       `json:"required_approvals"`
+	Approvals         []string  `json:"approvals,omitempty"`
+	SigningPayload    []byte    `json:"signing_payload,omitempty"`
+	Signature         []byte    `json:"signature,omitempty"`
+	TxID              string    `json:"tx_id,omitempty"`
+	Error             string    `json:"error,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Signed reports whether the session has a signature.
+func (s *Session) Signed() bool {
+	switch s.State {
+	case StateSigned, StateBroadcast, StateFinalized:
+		return true
+	default:
+		return false
+	}
+}
+
+// Verify checks the session's signature over its signing payload.
+func (s *Session) Verify(v Verifier) error {
+	if len(s.Signature) == 0 || len(s.SigningPayload) == 0 {
+		return fmt.Errorf("session %s is not signed", s.ID)
+	}
+	if err := v.Verify(s.SigningPayload, s.Signature); err != nil {
+		return fmt.Errorf("session %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+// Verifier checks a signature over a message.
+type Verifier interface {
+	Verify(message, signature []byte) error
+}
+
+// Ed25519 verifies Ed25519 signatures by a public key.
+type Ed25519 ed25519.PublicKey
+
+// Ensure Ed25519 implements the Verifier interface
+var _ Verifier = Ed25519(nil)
+
+// Verify implements Verifier.
+func (k Ed25519) Verify(message, signature []byte) error {
+	if len(k) != ed25519.PublicKeySize || !ed25519.Verify(ed25519.PublicKey(k), message, signature) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// SolanaAddress returns the Verifier for signatures by the wallet with the
+// given base58 address.
+func SolanaAddress(address string) (Ed25519, error) {
+	key, err := base58.Decode(address)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Solana address %q", address)
+	}
+	return Ed25519(key), nil
+}
+
+// New creates a Client.
+func New(config Config) (*Client, error) {
+	base, err := url.Parse(config.URL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", config.URL)
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	return &Client{base: base, config: config}, nil
+}
+
+// Submit submits a transfer and returns its session.  If the request carries
+// a Reference that was submitted before, the existing session is returned.
+func (c *Client) Submit(ctx context.Context, req *TransferRequest) (*Session, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPost, body, "v1", "requests")
+}
+
+// Session returns the current state of a session.
+func (c *Client) Session(ctx context.Context, id string) (*Session, error) {
+	return c.do(ctx, http.MethodGet, nil, "v1", "requests", id)
+}
+
+// WaitSigned polls a session until it is signed.  The signature may not be on
+// chain yet.
+func (c *Client) WaitSigned(ctx context.Context, id string) (*Session, error) {
+	return c.wait(ctx, id, (*Session).Signed)
+}
+
+// WaitFinalized polls a session until its transaction is final.
+func (c *Client) WaitFinalized(ctx context.Context, id string) (*Session, error) {
+	return c.wait(ctx, id, func(s *Session) bool { return s.State == StateFinalized })
+}
+
+// wait polls a session until done reports true or the session failed.
+// Polling errors are retried until ctx is done.
+func (c *Client) wait(ctx context.Context, id string, done func(*Session) bool) (*Session, error) {
+	ticker := time.NewTicker(c.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		s, err := c.Session(ctx, id)
+		var apiErr *Error
+		switch {
+		case errors.As(err, &apiErr) && apiErr.Status < http.StatusInternalServerError,
+			errors.Is(err, ErrBadSignature):
+			return s, err
+		case err != nil:
+		case s.State == StateFailed:
+			return s, fmt.Errorf("%w: %s", ErrFailed, s.Error)
+		case done(s):
+			return s, nil
+		}
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+			return s, err
+		case <-ticker.C:
+		}
+	}
+}
+
+// Error is an error response of the request API.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("request API: %d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+func (c *Client) do(ctx context.Context, method string, body []byte, path ...string) (*Session, error) {
+	u := c.base.JoinPath(path...)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	resp, err := c.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(data))
+		}
+		return nil, &Error{Status: resp.StatusCode, Message: e.Error}
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding session: %w", err)
+	}
+	if c.config.Verifier != nil && s.Signed() {
+		if err := s.Verify(c.config.Verifier); err != nil {
+			return &s, err
+		}
+	}
+	return &s, nil
+}
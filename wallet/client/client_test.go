@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mr-tron/base58"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// fakeChain is an in-memory chain.Chain whose transfers finalize at once.
+type fakeChain struct{}
+
+func (fakeChain) ID() string { return "fake" }
+
+func (fakeChain) DeriveAddress(pubKey []byte) (string, error) { return base58.Encode(pubKey), nil }
+
+func (fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	payload := []byte(t.From + "|" + t.To + "|" + t.Amount.String())
+	return &chain.UnsignedTx{Chain: "fake", Payload: payload, SigningPayload: payload}, nil
+}
+
+func (fakeChain) Decode([]byte) (*chain.Summary, error) {
+	return &chain.Summary{Chain: "fake", Amount: big.NewInt(1), Fee: big.NewInt(0)}, nil
+}
+
+func (fakeChain) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (fakeChain) Broadcast(context.Context, *chain.SignedTx) (string, error) { return "tx-1", nil }
+
+func (fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized}, nil
+}
+
+// keySigner signs with a plain Ed25519 key in place of the MPC parties.
+type keySigner ed25519.PrivateKey
+
+func (k keySigner) Sign(_ context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(k), req.Payload), nil
+}
+
+// newServer runs the request API of a coordinator signing with key.
+func newServer(t *testing.T, key ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	c, err := coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{fakeChain{}},
+		Signer:          keySigner(key),
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	pool := coordinator.NewPool(c, coordinator.PoolConfig{})
+	t.Cleanup(pool.Close)
+	h, err := coordinator.NewHandler(c, coordinator.HandlerConfig{
+		Pool: pool,
+		Authenticate: func(r *http.Request) (string, error) {
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				return "", errors.New("unauthorized")
+			}
+			return "svc", nil
+		},
+	})
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSubmitAndVerify(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	srv := newServer(t, key)
+	verifier, err := SolanaAddress(base58.Encode(pub))
+	require.NoError(t, err)
+	c, err := New(Config{URL: srv.URL, Token: "token-1", PollInterval: 5 * time.Millisecond, Verifier: verifier})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &TransferRequest{Chain: "fake", From: "alice", To: "bob", Amount: "5", Reference: "order-1"}
+	s, err := c.Submit(ctx, req)
+	require.NoError(t, err)
+	again, err := c.Submit(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, s.ID, again.ID)
+
+	s, err = c.WaitFinalized(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "tx-1", s.TxID)
+	assert.Equal(t, []byte("alice|bob|5"), s.SigningPayload)
+	require.NoError(t, s.Verify(verifier))
+
+	// A signature by another key is caught.
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Verify(Ed25519(other)), ErrBadSignature)
+	c, err = New(Config{URL: srv.URL, Token: "token-1", Verifier: Ed25519(other)})
+	require.NoError(t, err)
+	_, err = c.WaitSigned(ctx, s.ID)
+	assert.ErrorIs(t, err, ErrBadSignature)
+}
+
+func TestErrors(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	srv := newServer(t, key)
+	ctx := context.Background()
+
+	c, err := New(Config{URL: srv.URL, Token: "token-1"})
+	require.NoError(t, err)
+	_, err = c.Submit(ctx, &TransferRequest{Chain: "fake", From: "alice", To: "bob", Amount: "-5"})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+	_, err = c.WaitSigned(ctx, "missing")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.Status)
+
+	c, err = New(Config{URL: srv.URL, Token: "wrong"})
+	require.NoError(t, err)
+	_, err = c.Session(ctx, "missing")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.Status)
+
+	_, err = New(Config{URL: "localhost"})
+	assert.Error(t, err)
+	_, err = SolanaAddress("not-an-address")
+	assert.Error(t, err)
+}
@@ -0,0 +1,22 @@
+// Package client is a lightweight library for services that request
+// signatures from the wallet.  It speaks the coordinator's request API (see
+// coordinator.NewHandler) and needs neither cgo nor the MPC library, so it
+// can be linked into any Go service.
+//
+// Submit sends a transfer and returns the new session; Session fetches its
+// current state, and WaitSigned and WaitFinalized poll until the session has
+// a signature, reaches finality or fails:
+//
+//	c, err := client.New(client.Config{URL: "https://wallet.internal", Token: token})
+//	s, err := c.Submit(ctx, &client.TransferRequest{Chain: "solana", From: wallet, To: to, Amount: "1000000", Reference: orderID})
+//	s, err = c.WaitFinalized(ctx, s.ID)
+//
+// Submissions carrying a Reference are idempotent: retrying one after a
+// timeout returns the session created by the first attempt.
+//
+// A Session's signature can be checked locally against the signing payload
+// with a Verifier, so a compromised coordinator cannot hand back a signature
+// by another key.  Ed25519 and SolanaAddress cover Solana wallets; other
+// schemes plug in through the Verifier interface.  With Config.Verifier set,
+// every signature the client returns has been checked.
+package client
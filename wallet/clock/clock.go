@@ -0,0 +1,48 @@
+// Package clock provides a deterministic time source for tests.
+//
+// Packages read the time through a Now func() time.Time field in their
+// Config that defaults to time.Now.  Tests pass the Now method of a Fake
+// instead and move time forward explicitly rather than sleeping:
+//
+//	c := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+//	coord, _ := coordinator.New(coordinator.Config{..., Now: c.Now})
+//	c.Advance(25 * time.Hour) // approvals are now overdue
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a clock that only moves when told to.  It is safe for concurrent
+// use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a Fake set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake forward by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set moves the fake to t, which may be in the past.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
@@ -0,0 +1,18 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	assert.Equal(t, start, c.Now())
+	c.Advance(90 * time.Minute)
+	assert.Equal(t, start.Add(90*time.Minute), c.Now())
+	c.Set(start.Add(-time.Hour))
+	assert.Equal(t, start.Add(-time.Hour), c.Now())
+}
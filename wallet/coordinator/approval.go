@@ -0,0 +1,122 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultApprovalTimeout is how long a session waits for approvals.
+const defaultApprovalTimeout = 24 * time.Hour
+
+// ErrApprovalExpired is returned by Approve when the session's approval
+// deadline has passed.  The session is cancelled.
+var ErrApprovalExpired = errors.New("coordinator: approval deadline passed")
+
+// NotificationType identifies what a Notification is about.
+type NotificationType string
+
+const (
+	// NotifyApprovalRequested is sent once when a session starts waiting
+	// for approvals.
+	NotifyApprovalRequested NotificationType = "approval-requested"
+	// NotifyApprovalExpired is sent when a session is cancelled because its
+	// approvals did not arrive in time.
+	NotifyApprovalExpired NotificationType = "approval-expired"
+)
+
+// Notification tells approvers about a session.
+type Notification struct {
+	Type    NotificationType
+	Session *Session
+	// Approvers are the recipients: for NotifyApprovalRequested the
+	// approvers still pending, for NotifyApprovalExpired all eligible
+	// approvers.  It is empty when policy lets anyone approve.
+	Approvers []string
+}
+
+// Notifier delivers notifications to approvers, e.g. by chat or e-mail.
+// Delivery is at least once: a notification whose delivery failed, or was
+// not recorded before a crash, is sent again.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// NotifierFunc adapts an ordinary function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, n *Notification) error
+
+// Notify calls f(ctx, n).
+func (f NotifierFunc) Notify(ctx context.Context, n *Notification) error { return f(ctx, n) }
+
+// approvalDeadline returns when a session evaluated now with decision must
+// have its approvals, or nil if it needs none.
+func (c *Coordinator) approvalDeadline(decision *Decision, now time.Time) *time.Time {
+	if decision.RequiredApprovals <= 0 {
+		return nil
+	}
+	timeout := decision.ApprovalTimeout
+	if timeout <= 0 {
+		timeout = c.approvalTimeout
+	}
+	deadline := now.Add(timeout).UTC()
+	return &deadline
+}
+
+// awaitApprovals cancels a session whose approvals are overdue and otherwise
+// asks its approvers, once, for approval.
+func (c *Coordinator) awaitApprovals(ctx context.Context, s *Session) (*Session, error) {
+	if s.ApprovalOverdue(c.now()) {
+		return c.expireApprovals(ctx, s)
+	}
+	if s.ApprovalAsked {
+		return s, nil
+	}
+	if err := c.notify(ctx, NotifyApprovalRequested, s, s.PendingApprovers()); err != nil {
+		return nil, err
+	}
+	return c.record(ctx, s, &Event{Type: EventApprovalAsked})
+}
+
+// expireApprovals notifies the approvers of an overdue session and cancels
+// it.
+func (c *Coordinator) expireApprovals(ctx context.Context, s *Session) (*Session, error) {
+	if err := c.notify(ctx, NotifyApprovalExpired, s, s.Decision.Approvers); err != nil {
+		return nil, err
+	}
+	reason := fmt.Sprintf("approval expired with %d of %d approvals", len(s.Approvals), s.Decision.RequiredApprovals)
+	return c.record(ctx, s, &Event{Type: EventApprovalExpired, Err: reason})
+}
+
+func (c *Coordinator) notify(ctx context.Context, typ NotificationType, s *Session, approvers []string) error {
+	if c.notifier == nil {
+		return nil
+	}
+	err := c.notifier.Notify(ctx, &Notification{Type: typ, Session: s.clone(), Approvers: append([]string(nil), approvers...)})
+	if err != nil {
+		return fmt.Errorf("notifying approvers: %w", err)
+	}
+	return nil
+}
+
+// ExpireApprovals cancels every session whose approval deadline has passed
+// and returns them.  A Watcher calls it on every scan.
+func (c *Coordinator) ExpireApprovals(ctx context.Context) ([]*Session, error) {
+	waiting, err := c.List(ctx, Filter{States: []State{StatePolicyEvaluated}})
+	if err != nil {
+		return nil, err
+	}
+	var expired []*Session
+	now := c.now()
+	for _, s := range waiting {
+		if !s.ApprovalOverdue(now) {
+			continue
+		}
+		next, err := c.expireApprovals(ctx, s)
+		if err != nil {
+			return expired, fmt.Errorf("session %s: %w", s.ID, err)
+		}
+		expired = append(expired, next)
+	}
+	return expired, nil
+}
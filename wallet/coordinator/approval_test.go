@@ -0,0 +1,337 @@
+package coordinator
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/clock"
+)
+
+// recordingNotifier keeps every notification and fails while err is set.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []Notification
+	err  error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, note *Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, *note)
+	return nil
+}
+
+// epoch is when the fake clocks of the approval tests start.
+var epoch = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+func newApprovalCoordinator(t *testing.T, notifier Notifier, timeout time.Duration) (*Coordinator, *clock.Fake) {
+	t.Helper()
+	policy := PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+		return &Decision{Allow: true, RequiredApprovals: 2, Approvers: []string{"carol", "dave", "erin"}, ApprovalTimeout: timeout}, nil
+	})
+	now := clock.NewFake(epoch)
+	c, err := New(Config{
+		Store:           NewMemoryStore(),
+		Chains:          []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer:          &fakeSigner{},
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+		Notifier:        notifier,
+		Now:             now.Now,
+	})
+	require.NoError(t, err)
+	return c, now
+}
+
+func TestApproversAreNotifiedOnce(t *testing.T) {
+	ctx := context.Background()
+	notifier := &recordingNotifier{err: errors.New("chat unavailable")}
+	c, _ := newApprovalCoordinator(t, notifier, time.Hour)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	_, err = c.Run(ctx, s.ID)
+	require.ErrorContains(t, err, "chat unavailable")
+
+	notifier.err = nil
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatePolicyEvaluated, s.State)
+	assert.True(t, s.ApprovalAsked)
+	assert.Equal(t, epoch.Add(time.Hour), s.ApprovalDeadline)
+	_, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, NotifyApprovalRequested, notifier.sent[0].Type)
+	assert.Equal(t, []string{"carol", "dave", "erin"}, notifier.sent[0].Approvers)
+
+	_, err = c.Approve(ctx, s.ID, "mallory")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	s, err = c.Approve(ctx, s.ID, "carol")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dave", "erin"}, s.PendingApprovers())
+	s, err = c.Approve(ctx, s.ID, "erin")
+	require.NoError(t, err)
+	assert.Equal(t, StateApproved, s.State)
+
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+}
+
+func TestOverdueApprovalsAreCancelled(t *testing.T) {
+	ctx := context.Background()
+	notifier := &recordingNotifier{}
+	c, now := newApprovalCoordinator(t, notifier, time.Hour)
+
+	approving, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	_, err = c.Run(ctx, approving.ID)
+	require.NoError(t, err)
+	_, err = c.Approve(ctx, approving.ID, "carol")
+	require.NoError(t, err)
+	forgotten, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	_, err = c.Run(ctx, forgotten.ID)
+	require.NoError(t, err)
+	now.Advance(time.Hour + time.Second)
+
+	_, err = c.Approve(ctx, approving.ID, "dave")
+	assert.ErrorIs(t, err, ErrApprovalExpired)
+	s, err := c.Session(ctx, approving.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+	assert.Equal(t, "approval expired with 1 of 2 approvals", s.Err)
+
+	w := NewWatcher(c, WatcherConfig{})
+	require.NoError(t, w.Scan(ctx))
+	w.Wait()
+	s, err = c.Session(ctx, forgotten.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+
+	var expired []Notification
+	for _, n := range notifier.sent {
+		if n.Type == NotifyApprovalExpired {
+			expired = append(expired, n)
+		}
+	}
+	require.Len(t, expired, 2)
+	assert.Equal(t, []string{"carol", "dave", "erin"}, expired[0].Approvers)
+}
+
+func TestReplayRejectsLateApproval(t *testing.T) {
+	start := time.Now().UTC()
+	deadline := start.Add(time.Hour)
+	events := []Event{
+		{Session: "s", Seq: 1, Time: start, Type: EventCreated, Request: testRequest},
+		{Session: "s", Seq: 2, Time: start, Type: EventPolicyEvaluated,
+			Unsigned: &chain.UnsignedTx{}, Summary: &chain.Summary{},
+			Decision: &Decision{Allow: true, RequiredApprovals: 1}, Deadline: &deadline},
+	}
+
+	_, err := Replay(append(events, Event{Session: "s", Seq: 3, Time: deadline.Add(time.Second), Type: EventApproved, Approver: "carol"}))
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	_, err = Replay(append(events, Event{Session: "s", Seq: 3, Time: start, Type: EventApprovalExpired, Err: "early"}))
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	s, err := Replay(append(events, Event{Session: "s", Seq: 3, Time: deadline.Add(time.Second), Type: EventApprovalExpired, Err: "late"}))
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+}
+
+func TestSignedApprovalsAreCheckedBeforeSigning(t *testing.T) {
+	ctx := context.Background()
+	keys := map[string]ed25519.PublicKey{}
+	private := map[string]ed25519.PrivateKey{}
+	for _, name := range []string{"carol", "dave", "erin"} {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		keys[name], private[name] = pub, priv
+	}
+	var seen *SignRequest
+	signer := RequireApprovals(signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+		seen = req
+		return (&fakeSigner{}).Sign(ctx, req)
+	}), keys, 2, nil)
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer: signer,
+		Policy: PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+			return &Decision{Allow: true, RequiredApprovals: 2, Approvers: []string{"carol", "dave", "erin"}}, nil
+		}),
+		ConfirmInterval: time.Millisecond,
+		ApproverKeys:    keys,
+	})
+	require.NoError(t, err)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	_, err = c.Approve(ctx, s.ID, "carol")
+	assert.ErrorIs(t, err, ErrUnsignedApproval)
+
+	// Signatures with the wrong key or on another digest are refused.
+	forged, err := SignApproval(s, "carol", private["dave"])
+	require.NoError(t, err)
+	_, err = c.ApproveSigned(ctx, s.ID, forged)
+	assert.Error(t, err)
+	other := *s
+	other.ID = "other-session"
+	misplaced, err := SignApproval(&other, "carol", private["carol"])
+	require.NoError(t, err)
+	_, err = c.ApproveSigned(ctx, s.ID, misplaced)
+	assert.Error(t, err)
+
+	for _, name := range []string{"carol", "erin"} {
+		a, err := SignApproval(s, name, private[name])
+		require.NoError(t, err)
+		s, err = c.ApproveSigned(ctx, s.ID, a)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, StateApproved, s.State)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+
+	// The signatures are replayed with the session, next to its signature.
+	require.Len(t, s.ApprovalSignatures, 2)
+	assert.NotEmpty(t, s.Signature)
+	require.NotNil(t, seen)
+	require.NoError(t, VerifyApprovals(seen, keys, 2, time.Now()))
+
+	// Parties refuse requests the approvals do not cover.
+	tampered := *seen
+	tampered.Payload = []byte("alice|mallory|1000")
+	assert.ErrorIs(t, VerifyApprovals(&tampered, keys, 2, time.Now()), ErrApprovalSignatures)
+	assert.ErrorIs(t, VerifyApprovals(seen, keys, 3, time.Now()), ErrApprovalSignatures)
+	_, err = signer.Sign(ctx, &tampered)
+	assert.ErrorIs(t, err, ErrApprovalSignatures)
+}
+
+func TestMetadataFlowsToEvidence(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	approverPub, approverPriv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	approvers := map[string]ed25519.PublicKey{"carol": approverPub}
+	var seen *SignRequest
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer: signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+			seen = req
+			return (&fakeSigner{}).Sign(ctx, req)
+		}),
+		Policy: PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+			return &Decision{Allow: true, RequiredApprovals: 1}, nil
+		}),
+		ConfirmInterval: time.Millisecond,
+		ApproverKeys:    approvers,
+		MetadataKeys:    map[string]ed25519.PublicKey{"exchange": pub},
+	})
+	require.NoError(t, err)
+
+	fields := map[string]string{"order_id": "42", "user_id": "u-7", "reason": "withdrawal"}
+	req := *testRequest
+	req.Reference = "order-42"
+	SignMetadata(&req, "exchange", fields, priv)
+
+	// Metadata moved to another transfer, or signed by an unknown key, is
+	// refused.
+	moved := req
+	moved.Transfer.To = "mallory"
+	_, err = c.Submit(ctx, &moved)
+	assert.ErrorIs(t, err, ErrMetadataSignature)
+	_, other, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	forged := req
+	SignMetadata(&forged, "exchange", fields, other)
+	_, err = c.Submit(ctx, &forged)
+	assert.ErrorIs(t, err, ErrMetadataSignature)
+
+	s, err := c.Submit(ctx, &req)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	_, err = s.Evidence()
+	assert.Error(t, err, "not signed yet")
+
+	// The approval digest covers the metadata.
+	digest, err := s.ApprovalDigest()
+	require.NoError(t, err)
+	bare := *s
+	bare.Request.Metadata = nil
+	bareDigest, err := bare.ApprovalDigest()
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, bareDigest)
+
+	a, err := SignApproval(s, "carol", approverPriv)
+	require.NoError(t, err)
+	_, err = c.ApproveSigned(ctx, s.ID, a)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	require.Equal(t, StateFinalized, s.State)
+	require.NotNil(t, seen)
+	assert.Equal(t, req.Metadata, seen.Metadata)
+
+	found, err := c.List(ctx, Filter{Metadata: map[string]string{"order_id": "42"}})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, s.ID, found[0].ID)
+	found, err = c.List(ctx, Filter{Metadata: map[string]string{"order_id": "43"}})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	evidence, err := s.Evidence()
+	require.NoError(t, err)
+	assert.Equal(t, fields, evidence.Request.Metadata.Fields)
+	assert.Equal(t, s.Signature, evidence.Signature)
+	assert.Equal(t, "tx-1", evidence.TxID)
+	require.NoError(t, VerifyMetadata(&evidence.Request, map[string]ed25519.PublicKey{"exchange": pub}))
+	require.NoError(t, VerifyApprovals(evidence.SignRequest(), approvers, 1, time.Now()))
+}
+
+func TestApprovalDigestIsCanonical(t *testing.T) {
+	summary := &chain.Summary{
+		Chain:   "solana",
+		From:    "treasury",
+		Amount:  big.NewInt(3000),
+		Fee:     big.NewInt(5000),
+		Outputs: []chain.Output{{To: "alice", Amount: big.NewInt(1000)}, {To: "bob", Amount: big.NewInt(2000)}},
+	}
+	metadata := &Metadata{Signer: "exchange", Fields: map[string]string{"order_id": "42"}}
+	digest, err := approvalDigest("s-1", "solana", []byte("payload"), summary, metadata)
+	require.NoError(t, err)
+	// Approvers outside this package compute the same bytes; the vector pins
+	// the encoding.
+	assert.Equal(t, "96a78bb7d452c042d31217bfea8a821426c12af8132d6234d031956aaccf4825", hex.EncodeToString(digest))
+
+	// A nil amount and a zero amount are different summaries.
+	zero := *summary
+	zero.Fee = new(big.Int)
+	other, err := approvalDigest("s-1", "solana", []byte("payload"), &zero, metadata)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, other)
+	zero.Fee = nil
+	other, err = approvalDigest("s-1", "solana", []byte("payload"), &zero, metadata)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, other)
+}
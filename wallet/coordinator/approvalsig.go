@@ -0,0 +1,189 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// approvalDomain separates approval signatures from any other use of an
+// approver's identity key.
+const approvalDomain = "cb-mpc/coordinator/approval/v2"
+
+// ErrUnsignedApproval is returned by Approve when approvals must be signed.
+var ErrUnsignedApproval = errors.New("coordinator: approval must be signed")
+
+// ErrApprovalSignatures is returned by VerifyApprovals when a sign request
+// does not carry enough valid approval signatures.
+var ErrApprovalSignatures = errors.New("coordinator: insufficient approval signatures")
+
+// ApprovalSignature is an approver's signature, made with its identity key,
+// on the digest of a session's decoded request.  It is non-repudiable
+// evidence that the approver approved exactly that transaction.
+type ApprovalSignature struct {
+	Approver  string `json:"approver"`
+	Digest    []byte `json:"digest"`
+	Signature []byte `json:"signature"`
+}
+
+// approvalDigest binds the session, chain, exact signing payload, its decoded
+// summary and, if present, the request's metadata.  Every field is written
+// length-prefixed in a fixed order, so the digest does not depend on how any
+// encoder lays out the summary; changing the order or the fields requires a
+// new approvalDomain.
+func approvalDigest(session, chainID string, payload []byte, summary *chain.Summary, metadata *Metadata) ([]byte, error) {
+	if summary == nil {
+		return nil, fmt.Errorf("request is not decoded")
+	}
+	h := sha256.New()
+	for _, field := range [][]byte{[]byte(approvalDomain), []byte(session), []byte(chainID), payload} {
+		writeField(h, field)
+	}
+	writeSummary(h, summary)
+	if metadata == nil {
+		h.Write([]byte{0})
+	} else {
+		h.Write([]byte{1})
+		writeField(h, []byte(metadata.Signer))
+		writeFields(h, metadata.Fields)
+	}
+	return h.Sum(nil), nil
+}
+
+// writeSummary writes the fields of s in declaration order.  Amounts are
+// written in decimal, and a nil amount as an empty field.
+func writeSummary(h hash.Hash, s *chain.Summary) {
+	for _, field := range []string{s.Chain, s.From, s.To, amountField(s.Amount), amountField(s.Fee), s.Token} {
+		writeField(h, []byte(field))
+	}
+	binary.Write(h, binary.BigEndian, uint32(len(s.Outputs)))
+	for _, o := range s.Outputs {
+		writeField(h, []byte(o.To))
+		writeField(h, []byte(amountField(o.Amount)))
+	}
+}
+
+func amountField(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// ApprovalDigest returns the digest approvers sign for the session's current
+// transaction.  It changes whenever the transaction is rebuilt.
+func (s *Session) ApprovalDigest() ([]byte, error) {
+	if s.Unsigned == nil {
+		return nil, fmt.Errorf("session %s has no transaction", s.ID)
+	}
+	return approvalDigest(s.ID, s.Request.Chain, s.Unsigned.SigningPayload, s.Summary, s.Request.Metadata)
+}
+
+// ApprovalDigest returns the digest approvers signed for the request.
+func (r *SignRequest) ApprovalDigest() ([]byte, error) {
+	return approvalDigest(r.Session, r.Chain, r.Payload, r.Summary, r.Metadata)
+}
+
+// SignApproval signs the session's approval digest on behalf of approver.
+func SignApproval(s *Session, approver string, key ed25519.PrivateKey) (*ApprovalSignature, error) {
+	digest, err := s.ApprovalDigest()
+	if err != nil {
+		return nil, err
+	}
+	return &ApprovalSignature{
+		Approver:  approver,
+		Digest:    digest,
+		Signature: ed25519.Sign(key, append([]byte(approvalDomain), digest...)),
+	}, nil
+}
+
+// verify checks a against digest and the approver's identity key.
+func (a *ApprovalSignature) verify(digest []byte, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("no identity key for approver %q", a.Approver)
+	}
+	if !bytes.Equal(a.Digest, digest) {
+		return fmt.Errorf("approval of %q is for another request", a.Approver)
+	}
+	if !ed25519.Verify(key, append([]byte(approvalDomain), digest...), a.Signature) {
+		return fmt.Errorf("invalid approval signature of %q", a.Approver)
+	}
+	return nil
+}
+
+// VerifyApprovals checks that req carries valid approval signatures from at
+// least required distinct approvers listed in keys.  Every party runs it
+// before contributing its share, so that a compromised coordinator cannot
+// have a transaction signed that the approvers did not approve:
+//
+//	if err := coordinator.VerifyApprovals(req, approvers, 2, time.Now()); err != nil {
+//	    return nil, err // do not sign
+//	}
+//
+// Pre-approval tokens in req.PreApprovals count as well if they cover
+// req.Summary and now lies in their validity window; parties pass their own
+// clock, and auditors checking evidence the time the transaction was signed.
+// Signatures by unknown approvers, for other requests or invalid ones are
+// ignored.
+func VerifyApprovals(req *SignRequest, keys map[string]ed25519.PublicKey, required int, now time.Time) error {
+	digest, err := req.ApprovalDigest()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrApprovalSignatures, err)
+	}
+	valid := make(map[string]bool)
+	for i := range req.Approvals {
+		a := &req.Approvals[i]
+		if a.verify(digest, keys[a.Approver]) == nil {
+			valid[a.Approver] = true
+		}
+	}
+	for i := range req.PreApprovals {
+		p := &req.PreApprovals[i]
+		if p.Chain == req.Chain && p.covers(req.Summary) && p.within(now) && p.verify(keys[p.Approver]) == nil {
+			valid[p.Approver] = true
+		}
+	}
+	if len(valid) < required {
+		return fmt.Errorf("%w: %d of %d", ErrApprovalSignatures, len(valid), required)
+	}
+	return nil
+}
+
+// RequireApprovals returns a Signer that runs VerifyApprovals on every
+// request, at the time now returns, before passing it to next, for parties
+// whose Signer runs in the same process as the coordinator.  A nil now
+// means time.Now.
+func RequireApprovals(next Signer, keys map[string]ed25519.PublicKey, required int, now func() time.Time) Signer {
+	if now == nil {
+		now = time.Now
+	}
+	return signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+		if err := VerifyApprovals(req, keys, required, now()); err != nil {
+			return nil, err
+		}
+		return next.Sign(ctx, req)
+	})
+}
+
+type signerFunc func(ctx context.Context, req *SignRequest) ([]byte, error)
+
+func (f signerFunc) Sign(ctx context.Context, req *SignRequest) ([]byte, error) { return f(ctx, req) }
+
+// ApproveSigned records a signed approval for a session awaiting approval,
+// like Approve.  The signature must verify against the approver's identity
+// key in Config.ApproverKeys.
+func (c *Coordinator) ApproveSigned(ctx context.Context, id string, a *ApprovalSignature) (*Session, error) {
+	if a == nil {
+		return nil, fmt.Errorf("approval signature cannot be nil")
+	}
+	return c.approve(ctx, id, a.Approver, a)
+}
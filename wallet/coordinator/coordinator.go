@@ -0,0 +1,803 @@
+package coordinator
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/price"
+)
+
+const (
+	// defaultConfirmInterval is how often Run polls for finality.
+	defaultConfirmInterval = 2 * time.Second
+	// defaultMaxRebuilds bounds how often an expired transaction is rebuilt.
+	defaultMaxRebuilds = 3
+)
+
+// PolicyApprover is the approver recorded when policy requires no human
+// approvals.
+const PolicyApprover = "policy"
+
+// Policy decides whether a decoded transaction may be signed.
+type Policy interface {
+	Evaluate(ctx context.Context, req *Request, summary *chain.Summary) (*Decision, error)
+}
+
+// PolicyFunc adapts an ordinary function to the Policy interface.
+type PolicyFunc func(ctx context.Context, req *Request, summary *chain.Summary) (*Decision, error)
+
+// Evaluate calls f(ctx, req, summary).
+func (f PolicyFunc) Evaluate(ctx context.Context, req *Request, summary *chain.Summary) (*Decision, error) {
+	return f(ctx, req, summary)
+}
+
+// AllowAll is a Policy that allows every transaction without approvals.
+var AllowAll Policy = PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+	return &Decision{Allow: true}, nil
+})
+
+// ReapprovePolicy decides whether a transaction rebuilt after expiry may
+// reuse the approvals of the expired one.  previous is the expired
+// transaction, current its replacement.
+type ReapprovePolicy func(previous, current *chain.Summary) bool
+
+// IdenticalIntent is a ReapprovePolicy that accepts a rebuilt transaction
+// moving the same amounts of the same asset between the same accounts for at
+// most the same maximum fee.
+func IdenticalIntent(previous, current *chain.Summary) bool {
+	if previous == nil || current == nil ||
+		previous.Chain != current.Chain || previous.From != current.From || previous.Token != current.Token ||
+		previous.Fee == nil || current.Fee == nil || current.Fee.Cmp(previous.Fee) > 0 {
+		return false
+	}
+	a, b := previous.Recipients(), current.Recipients()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].To != b[i].To || a[i].Amount == nil || b[i].Amount == nil || a[i].Amount.Cmp(b[i].Amount) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SignRequest is handed to the Signer once a session is approved.
+type SignRequest struct {
+	Session string // Session ID, usable as the MPC session identifier
+	Chain   string // Identifier of the chain the transaction is for
+	Payload []byte // chain.UnsignedTx.SigningPayload
+	// Context is the signing context of Payload, as declared by the chain
+	// (chain.SigningContexter) or "raw".  Parties check it with
+	// signctx.Check before signing.
+	Context string
+	// Transaction is chain.UnsignedTx.Payload, and Intent the intent of the
+	// session (see Session.Intent).  Parties decode the transaction
+	// themselves and check it against the intent with VerifyIntent.
+	Transaction []byte
+	Intent      *chain.Intent
+	// Priority of the session, to be honoured by per-party queues.
+	Priority Priority
+
+	// Summary is the decoded transaction the approvers saw, and Approvals
+	// their signatures on it when the coordinator requires signed approvals.
+	// Parties check them with VerifyApprovals before signing.
+	Summary   *chain.Summary
+	Approvals []ApprovalSignature
+	// PreApprovals are the redeemed pre-approval tokens; VerifyApprovals
+	// counts them like Approvals.
+	PreApprovals []PreApproval
+	// Metadata is the request's signed business context, if any.  It is
+	// covered by the approval digest.
+	Metadata *Metadata
+	// Quotes are the prices of Decision.Quotes, for parties that re-evaluate
+	// fiat-denominated limits.
+	Quotes []*price.Quote
+
+	// Quorum lists the parties that should take part, when the coordinator
+	// was configured with candidate quorums.  Otherwise it is nil and the
+	// Signer picks the parties itself.
+	Quorum []string
+
+	// Progress must be called by the Signer at the start of every protocol
+	// round, starting at 1.  It returns an error if the transition cannot be
+	// recorded, in which case signing should be aborted.
+	Progress func(round int) error
+	// Transport, if the Signer calls it, records the parameters of the
+	// channels the parties negotiated for this session in the signed event.
+	Transport func(*Transport)
+}
+
+// Transport describes the per-session channels between the parties of a
+// signature, as negotiated by transport/ephemeral.  It holds no key material.
+type Transport struct {
+	KeyExchange string `json:"key_exchange"`        // e.g. "X25519"
+	Signature   string `json:"signature,omitempty"` // e.g. "Ed25519", signs the ephemeral keys
+	KDF         string `json:"kdf"`                 // e.g. "HKDF-SHA256"
+	Cipher      string `json:"cipher"`              // e.g. "AES-256-GCM"
+	// Transcripts holds the key exchange transcript hash of every pair of
+	// parties, keyed by their names joined with "/".  Both parties of a pair
+	// compute the same hash, so it matches their own logs.
+	Transcripts map[string][]byte `json:"transcripts,omitempty"`
+}
+
+// Signer runs the MPC signing protocol.
+type Signer interface {
+	Sign(ctx context.Context, req *SignRequest) ([]byte, error)
+}
+
+// Observer is a participant that sees every session in real time and can veto
+// it, but holds no key share and takes no part in signing – typically a
+// compliance team sitting inline.
+type Observer interface {
+	// Name identifies the observer in Decision.Reviewers and in reviews.
+	Name() string
+	// Observe is called synchronously after every recorded transition and
+	// must not block.
+	Observe(ctx context.Context, s *Session, e *Event)
+	// Review is called before approval when policy lists the observer in
+	// Decision.Reviewers.  s.Summary holds the decoded transaction.
+	Review(ctx context.Context, s *Session) (*Review, error)
+}
+
+// Config contains the configuration for a Coordinator.
+type Config struct {
+	// Store persists session events.  Required.
+	Store Store
+	// Chains lists the chains sessions may target, keyed by chain.Chain.ID.
+	Chains []chain.Chain
+	// Signer runs the MPC protocol.  Required.
+	Signer Signer
+	// Policy gates every session.  Defaults to AllowAll.
+	Policy Policy
+	// Observers receive every transition and review sessions on request of
+	// the policy.
+	Observers []Observer
+	// Quorums lists the candidate signing quorums, typically from
+	// mpc.AccessStructure.MinimalQuorums.  When set, the coordinator tries
+	// them in the order ranked by Latency and fails over to the next quorum
+	// when the Signer returns a *PartyError.
+	Quorums [][]string
+	// Latency ranks Quorums.  Defaults to a fresh LatencyTracker.
+	Latency *LatencyTracker
+	// ConfirmInterval is how often broadcast transactions are polled for
+	// finality.  Defaults to 2s.
+	ConfirmInterval time.Duration
+	// Reapprove, if set, lets a transaction rebuilt after expiry inherit the
+	// approvals of the expired one, e.g. IdenticalIntent.  Policy and
+	// observer reviews are always re-run.  When nil, rebuilt transactions
+	// wait for human approval again.
+	Reapprove ReapprovePolicy
+	// MaxRebuilds bounds how often an expired transaction is rebuilt before
+	// the session fails.  Defaults to 3.
+	MaxRebuilds int
+	// ApprovalTimeout is how long a session waits for its approvals before
+	// it is cancelled.  Defaults to 24 hours; Decision.ApprovalTimeout
+	// overrides it.
+	ApprovalTimeout time.Duration
+	// Notifier, if set, tells approvers when a session waits for them and
+	// when it was cancelled for lack of approvals.
+	Notifier Notifier
+	// ApproverKeys, if set, maps approvers to their Ed25519 identity keys
+	// and makes approvals non-repudiable: every approval must be signed
+	// (see ApproveSigned) and the signatures are passed to the Signer in
+	// SignRequest.Approvals for the parties to check.  Reapprove is then
+	// ignored, as approvals of an expired transaction do not cover its
+	// replacement.
+	ApproverKeys map[string]ed25519.PublicKey
+	// MetadataKeys maps submitters to the Ed25519 keys that sign
+	// Request.Metadata.  Requests carrying metadata not signed by one of
+	// them are refused.
+	MetadataKeys map[string]ed25519.PublicKey
+	// MaxPreApprovalTTL is the longest validity window Submit accepts for a
+	// pre-approval token.  Defaults to 12 hours.
+	MaxPreApprovalTTL time.Duration
+	// Now returns the current time for approval deadlines and event
+	// timestamps.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Coordinator creates signing sessions and drives them through their state
+// machine.
+type Coordinator struct {
+	store           Store
+	chains          map[string]chain.Chain
+	signer          Signer
+	policy          Policy
+	observers       map[string]Observer
+	quorums         [][]string
+	latency         *LatencyTracker
+	confirmInterval time.Duration
+	reapprove       ReapprovePolicy
+	maxRebuilds     int
+	approvalTimeout time.Duration
+	notifier        Notifier
+	approverKeys    map[string]ed25519.PublicKey
+	metadataKeys    map[string]ed25519.PublicKey
+	maxPreApproval  time.Duration
+	now             func() time.Time
+}
+
+// New creates a Coordinator from the given configuration.
+func New(config Config) (*Coordinator, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("store must be provided")
+	}
+	if config.Signer == nil {
+		return nil, fmt.Errorf("signer must be provided")
+	}
+	chains := make(map[string]chain.Chain, len(config.Chains))
+	for _, c := range config.Chains {
+		if _, dup := chains[c.ID()]; dup {
+			return nil, fmt.Errorf("duplicate chain %q", c.ID())
+		}
+		chains[c.ID()] = c
+	}
+	observers := make(map[string]Observer, len(config.Observers))
+	for _, o := range config.Observers {
+		if _, dup := observers[o.Name()]; dup {
+			return nil, fmt.Errorf("duplicate observer %q", o.Name())
+		}
+		observers[o.Name()] = o
+	}
+	policy := config.Policy
+	if policy == nil {
+		policy = AllowAll
+	}
+	latency := config.Latency
+	if latency == nil {
+		latency = NewLatencyTracker()
+	}
+	interval := config.ConfirmInterval
+	if interval <= 0 {
+		interval = defaultConfirmInterval
+	}
+	maxRebuilds := config.MaxRebuilds
+	if maxRebuilds <= 0 {
+		maxRebuilds = defaultMaxRebuilds
+	}
+	approvalTimeout := config.ApprovalTimeout
+	if approvalTimeout <= 0 {
+		approvalTimeout = defaultApprovalTimeout
+	}
+	maxPreApproval := config.MaxPreApprovalTTL
+	if maxPreApproval <= 0 {
+		maxPreApproval = defaultMaxPreApprovalTTL
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &Coordinator{
+		store:           config.Store,
+		chains:          chains,
+		signer:          config.Signer,
+		policy:          policy,
+		observers:       observers,
+		quorums:         config.Quorums,
+		latency:         latency,
+		confirmInterval: interval,
+		reapprove:       config.Reapprove,
+		maxRebuilds:     maxRebuilds,
+		approvalTimeout: approvalTimeout,
+		notifier:        config.Notifier,
+		approverKeys:    config.ApproverKeys,
+		metadataKeys:    config.MetadataKeys,
+		maxPreApproval:  maxPreApproval,
+		now:             now,
+	}, nil
+}
+
+// Chain returns the chain registered under id.
+func (c *Coordinator) Chain(id string) (chain.Chain, bool) {
+	ch, ok := c.chains[id]
+	return ch, ok
+}
+
+// Submit creates a new session for req.  The session is not run; call Run.
+func (c *Coordinator) Submit(ctx context.Context, req *Request) (*Session, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
+	if _, ok := c.chains[req.Chain]; !ok {
+		return nil, fmt.Errorf("unknown chain %q", req.Chain)
+	}
+	if err := c.VerifyMetadata(req); err != nil {
+		return nil, err
+	}
+	if err := c.VerifyPreApprovals(req); err != nil {
+		return nil, err
+	}
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	return c.record(ctx, &Session{ID: id}, &Event{Type: EventCreated, Request: req})
+}
+
+// VerifyMetadata checks req's metadata against Config.MetadataKeys as Submit
+// does, so that services can refuse a request before accepting it.
+func (c *Coordinator) VerifyMetadata(req *Request) error {
+	return VerifyMetadata(req, c.metadataKeys)
+}
+
+// VerifyPreApprovals checks req's pre-approval tokens against
+// Config.ApproverKeys and Config.MaxPreApprovalTTL as Submit does.
+func (c *Coordinator) VerifyPreApprovals(req *Request) error {
+	return verifyPreApprovals(req, c.approverKeys, c.maxPreApproval)
+}
+
+// Approve records an approval for a session awaiting approval.  Once the
+// required approvals are in, the session is approved and Run starts signing.
+// A session past its approval deadline is cancelled instead and
+// ErrApprovalExpired returned.  With Config.ApproverKeys set, approvals must
+// be signed and given to ApproveSigned instead.
+func (c *Coordinator) Approve(ctx context.Context, id, approver string) (*Session, error) {
+	if c.approverKeys != nil {
+		return nil, fmt.Errorf("%w: session %s", ErrUnsignedApproval, id)
+	}
+	return c.approve(ctx, id, approver, nil)
+}
+
+// approve records an approval, checking sig against the session's approval
+// digest if given.
+func (c *Coordinator) approve(ctx context.Context, id, approver string, sig *ApprovalSignature) (*Session, error) {
+	s, err := c.Session(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.ApprovalOverdue(c.now()) {
+		if _, err := c.expireApprovals(ctx, s); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: session %s", ErrApprovalExpired, id)
+	}
+	if sig != nil {
+		if s.State != StatePolicyEvaluated {
+			return nil, fmt.Errorf("%w: session %s is not awaiting approval", ErrInvalidTransition, id)
+		}
+		digest, err := s.ApprovalDigest()
+		if err != nil {
+			return nil, err
+		}
+		if err := sig.verify(digest, c.approverKeys[approver]); err != nil {
+			return nil, err
+		}
+	}
+	return c.record(ctx, s, &Event{Type: EventApproved, Approver: approver, ApprovalSignature: sig})
+}
+
+// Fail aborts a non-terminal session, e.g. on operator request.
+func (c *Coordinator) Fail(ctx context.Context, id, reason string) (*Session, error) {
+	s, err := c.Session(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return c.record(ctx, s, &Event{Type: EventFailed, Err: reason})
+}
+
+// Run advances a session as far as possible: until it is terminal, waits for
+// human approval, or an error occurs.  It can be called again on the same
+// session to resume after an error or a restart.
+func (c *Coordinator) Run(ctx context.Context, id string) (*Session, error) {
+	s, err := c.Session(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for !s.State.Terminal() {
+		var next *Session
+		switch s.State {
+		case StateCreated:
+			next, err = c.evaluate(ctx, s)
+		case StatePolicyEvaluated:
+			now := c.now()
+			switch pending, pre := s.PendingReviewers(), s.redeemable(now); {
+			case s.Vetoed():
+				next, err = c.record(ctx, s, &Event{Type: EventFailed, Err: "vetoed by observer"})
+			case len(pending) > 0:
+				next, err = c.review(ctx, s, pending[0])
+			case pre != nil && s.Decision.RequiredApprovals > 0 && !s.ApprovalOverdue(now):
+				next, err = c.record(ctx, s, &Event{Type: EventApproved, Approver: pre.Approver, PreApproval: pre})
+			case c.reapprovable(s):
+				next, err = c.record(ctx, s, &Event{Type: EventReapproved})
+			case s.Decision.RequiredApprovals > 0:
+				next, err = c.awaitApprovals(ctx, s)
+				if err == nil && !next.State.Terminal() {
+					return next, nil
+				}
+			default:
+				next, err = c.record(ctx, s, &Event{Type: EventApproved, Approver: PolicyApprover})
+			}
+		case StateApproved, StateRoundsInProgress:
+			next, err = c.sign(ctx, s)
+		case StateSigned:
+			next, err = c.broadcast(ctx, s)
+		case StateBroadcast:
+			next, err = c.confirm(ctx, s)
+		}
+		if err != nil {
+			return s, fmt.Errorf("session %s in state %s: %w", s.ID, s.State, err)
+		}
+		s = next
+	}
+	return s, nil
+}
+
+// reapprovable reports whether a rebuilt session may reuse the approvals of
+// its expired attempt.  Sessions that policy approved need no such shortcut,
+// and signed approvals cannot be reused.
+func (c *Coordinator) reapprovable(s *Session) bool {
+	return c.reapprove != nil && c.approverKeys == nil && s.Attempt > 0 && s.Decision.RequiredApprovals > 0 &&
+		len(s.PreviousApprovals) >= s.Decision.RequiredApprovals &&
+		c.reapprove(s.Previous, s.Summary)
+}
+
+// evaluate builds the transaction and runs it through policy.
+func (c *Coordinator) evaluate(ctx context.Context, s *Session) (*Session, error) {
+	ch, err := c.chain(s)
+	if err != nil {
+		return nil, err
+	}
+	transfer := s.Request.Transfer
+	unsigned, err := ch.BuildTransfer(ctx, &transfer)
+	if err != nil {
+		return nil, fmt.Errorf("building transaction: %w", err)
+	}
+	summary, err := ch.Decode(unsigned.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decoding transaction: %w", err)
+	}
+	if max := s.Request.MaxFee; max != nil && summary.Fee.Cmp(max) > 0 {
+		return c.record(ctx, s, &Event{Type: EventFailed, Err: fmt.Sprintf("fee %s exceeds maximum %s", summary.Fee, max)})
+	}
+	decision, err := c.policy.Evaluate(ctx, &s.Request, summary)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policy: %w", err)
+	}
+	if !decision.Allow {
+		return c.record(ctx, s, &Event{Type: EventFailed, Decision: decision, Err: "denied by policy: " + decision.Reason})
+	}
+	for _, name := range decision.Reviewers {
+		if _, ok := c.observers[name]; !ok {
+			return nil, fmt.Errorf("policy requires review by unknown observer %q", name)
+		}
+	}
+	return c.record(ctx, s, &Event{Type: EventPolicyEvaluated, Unsigned: unsigned, Summary: summary, Decision: decision,
+		Deadline: c.approvalDeadline(decision, c.now())})
+}
+
+// review asks an observer for its verdict and fails the session on a veto.
+func (c *Coordinator) review(ctx context.Context, s *Session, name string) (*Session, error) {
+	o, ok := c.observers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown observer %q", name)
+	}
+	review, err := o.Review(ctx, s.clone())
+	if err != nil {
+		return nil, fmt.Errorf("review by %s: %w", name, err)
+	}
+	review.Reviewer = name
+	next, err := c.record(ctx, s, &Event{Type: EventReviewed, Review: review})
+	if err != nil || !review.Veto {
+		return next, err
+	}
+	return c.record(ctx, next, &Event{Type: EventFailed, Err: fmt.Sprintf("vetoed by %s: %s", name, review.Reason)})
+}
+
+// sign runs the MPC protocol, recording every round it enters.  With
+// candidate quorums configured it tries them in ranked order, skipping those
+// containing a party that already failed in this session.
+func (c *Coordinator) sign(ctx context.Context, s *Session) (*Session, error) {
+	ch, err := c.chain(s)
+	if err != nil {
+		return nil, err
+	}
+	signingContext := "raw"
+	if sc, ok := ch.(chain.SigningContexter); ok {
+		signingContext = sc.SigningContext()
+	}
+	if after := s.Request.NotAfter; !after.IsZero() && c.now().After(after) {
+		return c.record(ctx, s, &Event{Type: EventFailed, Err: "request expired at " + after.UTC().Format(time.RFC3339)})
+	}
+	intent, err := s.Intent()
+	if err != nil {
+		return nil, err
+	}
+	candidates := [][]string{nil}
+	if len(c.quorums) > 0 {
+		candidates = c.latency.Rank(c.quorums)
+	}
+	failed := make(map[string]bool)
+	current := s
+	var lastErr error
+	for _, quorum := range candidates {
+		if !excludes(quorum, failed) {
+			continue
+		}
+		var transport *Transport
+		sig, err := c.signer.Sign(ctx, &SignRequest{
+			Session:      s.ID,
+			Chain:        s.Request.Chain,
+			Payload:      s.Unsigned.SigningPayload,
+			Context:      signingContext,
+			Transaction:  s.Unsigned.Payload,
+			Intent:       intent,
+			Priority:     s.Request.Priority,
+			Summary:      s.Summary,
+			Approvals:    s.ApprovalSignatures,
+			PreApprovals: s.PreApprovals,
+			Metadata:     s.Request.Metadata,
+			Quotes:       s.Decision.Quotes,
+			Quorum:       quorum,
+			Progress: func(round int) error {
+				e := &Event{Type: EventRoundStarted, Round: round}
+				if round == 1 {
+					e.Quorum = quorum
+				}
+				next, err := c.record(ctx, current, e)
+				if err != nil {
+					return err
+				}
+				current = next
+				return nil
+			},
+			Transport: func(t *Transport) { transport = t },
+		})
+		if err == nil {
+			return c.record(ctx, current, &Event{Type: EventSigned, Signature: sig, Transport: transport})
+		}
+		lastErr = fmt.Errorf("signing: %w", err)
+
+		var perr *PartyError
+		if quorum == nil || !errors.As(err, &perr) || ctx.Err() != nil {
+			break
+		}
+		c.latency.ObserveFailure(perr.Party)
+		failed[perr.Party] = true
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no quorum without failed parties %v", failed)
+	}
+	return nil, lastErr
+}
+
+// broadcast simulates and submits the signed transaction.
+func (c *Coordinator) broadcast(ctx context.Context, s *Session) (*Session, error) {
+	ch, err := c.chain(s)
+	if err != nil {
+		return nil, err
+	}
+	if expired, err := c.expired(ctx, ch, s); err != nil || expired {
+		if err != nil {
+			return nil, err
+		}
+		return c.expire(ctx, s)
+	}
+	tx := &chain.SignedTx{Unsigned: s.Unsigned, Signature: s.Signature}
+	sim, err := ch.Simulate(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("simulating transaction: %w", err)
+	}
+	if !sim.OK {
+		return c.record(ctx, s, &Event{Type: EventFailed, Err: "simulation failed: " + sim.Err})
+	}
+	txID, err := ch.Broadcast(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("broadcasting transaction: %w", err)
+	}
+	return c.record(ctx, s, &Event{Type: EventBroadcast, TxID: txID})
+}
+
+// confirm waits for the broadcast transaction to reach a terminal status.
+// On chains whose transactions expire, a transaction the ledger does not know
+// is resubmitted while still valid and rebuilt once it has expired.
+func (c *Coordinator) confirm(ctx context.Context, s *Session) (*Session, error) {
+	ch, err := c.chain(s)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ch.(chain.Expirer); !ok {
+		receipt, err := chain.WaitFinalized(ctx, ch, s.TxID, c.confirmInterval)
+		if err != nil {
+			return nil, err
+		}
+		return c.finish(ctx, s, receipt)
+	}
+
+	ticker := time.NewTicker(c.confirmInterval)
+	defer ticker.Stop()
+	for {
+		// Expiry is checked before the status: a transaction that has expired
+		// can no longer land, so expired and not found means never.
+		expired, err := c.expired(ctx, ch, s)
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := ch.Confirm(ctx, s.TxID)
+		switch {
+		case errors.Is(err, chain.ErrNotFound) && expired:
+			return c.expire(ctx, s)
+		case errors.Is(err, chain.ErrNotFound):
+			// Nodes drop transactions under load; resubmitting the same
+			// signed transaction is idempotent.  A failed resubmission
+			// leaves the session broadcast, to be resumed by Run.
+			if _, err := ch.Broadcast(ctx, &chain.SignedTx{Unsigned: s.Unsigned, Signature: s.Signature}); err != nil {
+				return nil, fmt.Errorf("resubmitting %s: %w", s.TxID, err)
+			}
+		case err != nil:
+			return nil, err
+		case receipt.Status == chain.StatusFinalized || receipt.Status == chain.StatusFailed:
+			return c.finish(ctx, s, receipt)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %s: %w", s.TxID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// expired reports whether the session's transaction has expired.  It is
+// always false on chains without expiry.
+func (c *Coordinator) expired(ctx context.Context, ch chain.Chain, s *Session) (bool, error) {
+	e, ok := ch.(chain.Expirer)
+	if !ok {
+		return false, nil
+	}
+	expired, err := e.Expired(ctx, s.Unsigned)
+	if err != nil {
+		return false, fmt.Errorf("checking expiry: %w", err)
+	}
+	return expired, nil
+}
+
+// expire sends the session back to be rebuilt, or fails it once it has been
+// rebuilt MaxRebuilds times.
+func (c *Coordinator) expire(ctx context.Context, s *Session) (*Session, error) {
+	if s.Attempt >= c.maxRebuilds {
+		return c.record(ctx, s, &Event{Type: EventFailed, Err: fmt.Sprintf("transaction expired %d times", s.Attempt+1)})
+	}
+	return c.record(ctx, s, &Event{Type: EventExpired, TxID: s.TxID})
+}
+
+// finish records the terminal receipt of a broadcast transaction.
+func (c *Coordinator) finish(ctx context.Context, s *Session, receipt *chain.Receipt) (*Session, error) {
+	if receipt.Status == chain.StatusFailed {
+		return c.record(ctx, s, &Event{Type: EventFailed, Receipt: receipt, Err: "transaction failed: " + receipt.Err})
+	}
+	return c.record(ctx, s, &Event{Type: EventFinalized, Receipt: receipt})
+}
+
+func (c *Coordinator) chain(s *Session) (chain.Chain, error) {
+	ch, ok := c.chains[s.Request.Chain]
+	if !ok {
+		return nil, fmt.Errorf("unknown chain %q", s.Request.Chain)
+	}
+	return ch, nil
+}
+
+// record validates e against s, persists it and returns the resulting
+// session.  s is left untouched.
+func (c *Coordinator) record(ctx context.Context, s *Session, e *Event) (*Session, error) {
+	e.Session = s.ID
+	e.Seq = s.Version + 1
+	e.Time = c.now().UTC()
+
+	next := s.clone()
+	if err := next.apply(e); err != nil {
+		return nil, err
+	}
+	if err := c.store.Append(ctx, e); err != nil {
+		return nil, err
+	}
+	for _, o := range c.observers {
+		o.Observe(ctx, next.clone(), e)
+	}
+	return next, nil
+}
+
+// clone returns a copy of s that can be mutated by apply without affecting s.
+func (s *Session) clone() *Session {
+	out := *s
+	out.Reviews = append([]Review(nil), s.Reviews...)
+	out.Approvals = append([]string(nil), s.Approvals...)
+	out.ApprovalSignatures = append([]ApprovalSignature(nil), s.ApprovalSignatures...)
+	out.PreApprovals = append([]PreApproval(nil), s.PreApprovals...)
+	out.PreviousApprovals = append([]string(nil), s.PreviousApprovals...)
+	return &out
+}
+
+// Session returns the current state of a session.
+func (c *Coordinator) Session(ctx context.Context, id string) (*Session, error) {
+	events, err := c.store.Events(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return Replay(events)
+}
+
+// History returns the full transition log of a session.
+func (c *Coordinator) History(ctx context.Context, id string) ([]Event, error) {
+	return c.store.Events(ctx, id)
+}
+
+// Filter selects sessions in List.  Zero fields match everything.
+type Filter struct {
+	States      []State   // Match sessions in any of these states
+	IdleSince   time.Time // Match sessions not updated since this time
+	NonTerminal bool      // Match only sessions that can still progress
+	Reference   string    // Match sessions whose request carries this reference
+	Tenant      string    // Match sessions requested for this tenant
+	// Metadata matches sessions whose request metadata has all of these
+	// fields, e.g. {"order_id": "42"}.
+	Metadata map[string]string
+}
+
+func (f *Filter) match(s *Session) bool {
+	if f.NonTerminal && s.State.Terminal() {
+		return false
+	}
+	if f.Reference != "" && s.Request.Reference != f.Reference {
+		return false
+	}
+	if f.Tenant != "" && s.Request.Tenant != f.Tenant {
+		return false
+	}
+	for k, v := range f.Metadata {
+		if s.Request.Metadata == nil {
+			return false
+		}
+		if got, ok := s.Request.Metadata.Fields[k]; !ok || got != v {
+			return false
+		}
+	}
+	if !f.IdleSince.IsZero() && s.UpdatedAt.After(f.IdleSince) {
+		return false
+	}
+	if len(f.States) == 0 {
+		return true
+	}
+	for _, state := range f.States {
+		if s.State == state {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns all sessions matching filter.  Stuck sessions can be found
+// with Filter{NonTerminal: true, IdleSince: time.Now().Add(-d)}.
+func (c *Coordinator) List(ctx context.Context, filter Filter) ([]*Session, error) {
+	ids, err := c.store.Sessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Session
+	for _, id := range ids {
+		s, err := c.Session(ctx, id)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading session %s: %w", id, err)
+		}
+		if filter.match(s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func newSessionID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating session ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
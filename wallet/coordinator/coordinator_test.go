@@ -0,0 +1,324 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// fakeChain is an in-memory chain.Chain.
+type fakeChain struct {
+	simulation *chain.SimulationResult
+	status     chain.Status
+	broadcasts int
+}
+
+func (f *fakeChain) ID() string { return "fake" }
+
+func (f *fakeChain) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (f *fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	payload := []byte(t.From + "|" + t.To + "|" + t.Amount.String())
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: payload, SigningPayload: payload}, nil
+}
+
+func (f *fakeChain) Decode(payload []byte) (*chain.Summary, error) {
+	return &chain.Summary{Chain: f.ID(), From: "alice", To: "bob", Amount: big.NewInt(1), Fee: big.NewInt(0)}, nil
+}
+
+func (f *fakeChain) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	if f.simulation != nil {
+		return f.simulation, nil
+	}
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (f *fakeChain) Broadcast(context.Context, *chain.SignedTx) (string, error) {
+	f.broadcasts++
+	return "tx-1", nil
+}
+
+func (f *fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: f.status, Height: 7}, nil
+}
+
+// fakeSigner reports rounds rounds and then signs, or fails in failRound.
+type fakeSigner struct {
+	rounds    int
+	failRound int
+	context   string     // Signing context of the last request
+	transport *Transport // Reported through req.Transport, if set
+}
+
+func (f *fakeSigner) Sign(_ context.Context, req *SignRequest) ([]byte, error) {
+	f.context = req.Context
+	if f.transport != nil {
+		req.Transport(f.transport)
+	}
+	for r := 1; r <= f.rounds; r++ {
+		if err := req.Progress(r); err != nil {
+			return nil, err
+		}
+		if r == f.failRound {
+			return nil, errors.New("party stalled")
+		}
+	}
+	return append([]byte("sig:"), req.Payload...), nil
+}
+
+func newTestCoordinator(t *testing.T, ch *fakeChain, signer Signer, policy Policy) *Coordinator {
+	t.Helper()
+	c, err := New(Config{
+		Store:           NewMemoryStore(),
+		Chains:          []chain.Chain{ch},
+		Signer:          signer,
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	return c
+}
+
+var testRequest = &Request{Chain: "fake", Transfer: chain.Transfer{From: "alice", To: "bob", Amount: big.NewInt(1)}}
+
+func eventTypes(events []Event) []EventType {
+	var out []EventType
+	for _, e := range events {
+		out = append(out, e.Type)
+	}
+	return out
+}
+
+func TestRunToFinality(t *testing.T) {
+	ctx := context.Background()
+	ch := &fakeChain{status: chain.StatusFinalized}
+	signer := &fakeSigner{rounds: 3}
+	c := newTestCoordinator(t, ch, signer, nil)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, StateFinalized, s.State)
+	assert.Equal(t, "raw", signer.context, "fakeChain declares no signing context")
+	assert.Equal(t, "tx-1", s.TxID)
+	assert.Equal(t, 3, s.Round)
+	assert.Equal(t, []string{PolicyApprover}, s.Approvals)
+
+	history, err := c.History(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []EventType{
+		EventCreated, EventPolicyEvaluated, EventApproved,
+		EventRoundStarted, EventRoundStarted, EventRoundStarted,
+		EventSigned, EventBroadcast, EventFinalized,
+	}, eventTypes(history))
+}
+
+func TestRunRecordsTransport(t *testing.T) {
+	ctx := context.Background()
+	transport := &Transport{
+		KeyExchange: "X25519", Signature: "Ed25519", KDF: "HKDF-SHA256", Cipher: "AES-256-GCM",
+		Transcripts: map[string][]byte{"p0/p1": {1, 2, 3}},
+	}
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, &fakeSigner{rounds: 1, transport: transport}, nil)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, transport, s.Transport)
+
+	evidence, err := s.Evidence()
+	require.NoError(t, err)
+	assert.Equal(t, transport, evidence.Transport)
+
+	// The parameters survive the event log.
+	history, err := c.History(ctx, s.ID)
+	require.NoError(t, err)
+	replayed, err := Replay(history)
+	require.NoError(t, err)
+	assert.Equal(t, transport, replayed.Transport)
+}
+
+func TestRunWaitsForApprovals(t *testing.T) {
+	ctx := context.Background()
+	policy := PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+		return &Decision{Allow: true, RequiredApprovals: 2}, nil
+	})
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, &fakeSigner{}, policy)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatePolicyEvaluated, s.State)
+
+	_, err = c.Approve(ctx, s.ID, "carol")
+	require.NoError(t, err)
+	_, err = c.Approve(ctx, s.ID, "carol")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	s, err = c.Approve(ctx, s.ID, "dave")
+	require.NoError(t, err)
+	assert.Equal(t, StateApproved, s.State)
+
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+}
+
+func TestRunPolicyDenial(t *testing.T) {
+	ctx := context.Background()
+	policy := PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+		return &Decision{Reason: "recipient not allow-listed"}, nil
+	})
+	c := newTestCoordinator(t, &fakeChain{}, &fakeSigner{}, policy)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+	assert.Contains(t, s.Err, "recipient not allow-listed")
+	assert.True(t, s.Refused())
+}
+
+func TestRunResumesStuckSession(t *testing.T) {
+	ctx := context.Background()
+	ch := &fakeChain{status: chain.StatusFinalized}
+	signer := &fakeSigner{rounds: 3, failRound: 2}
+	c := newTestCoordinator(t, ch, signer, nil)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	_, err = c.Run(ctx, s.ID)
+	require.Error(t, err)
+
+	stuck, err := c.List(ctx, Filter{NonTerminal: true, IdleSince: time.Now()})
+	require.NoError(t, err)
+	require.Len(t, stuck, 1)
+	assert.Equal(t, StateRoundsInProgress, stuck[0].State)
+	assert.Equal(t, 2, stuck[0].Round)
+
+	signer.failRound = 0
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+	assert.Equal(t, 1, ch.broadcasts)
+}
+
+func TestRunSimulationFailure(t *testing.T) {
+	ctx := context.Background()
+	ch := &fakeChain{simulation: &chain.SimulationResult{Err: "insufficient funds"}}
+	c := newTestCoordinator(t, ch, &fakeSigner{}, nil)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+	assert.False(t, s.Refused())
+	assert.Zero(t, ch.broadcasts)
+}
+
+func TestReplayRejectsInvalidTransitions(t *testing.T) {
+	created := Event{Session: "s", Seq: 1, Type: EventCreated, Request: testRequest}
+
+	_, err := Replay([]Event{created, {Session: "s", Seq: 2, Type: EventSigned, Signature: []byte{1}}})
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	_, err = Replay([]Event{created, {Session: "s", Seq: 3, Type: EventFailed, Err: "gap"}})
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	s, err := Replay([]Event{created, {Session: "s", Seq: 2, Type: EventFailed, Err: "aborted"}})
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+}
+
+// complianceObserver records what it sees and vetoes transfers to blocked
+// recipients.
+type complianceObserver struct {
+	blocked string
+	seen    []EventType
+}
+
+func (o *complianceObserver) Name() string { return "compliance" }
+
+func (o *complianceObserver) Observe(_ context.Context, _ *Session, e *Event) {
+	o.seen = append(o.seen, e.Type)
+}
+
+func (o *complianceObserver) Review(_ context.Context, s *Session) (*Review, error) {
+	if s.Summary.To == o.blocked {
+		return &Review{Veto: true, Reason: "sanctioned recipient"}, nil
+	}
+	return &Review{}, nil
+}
+
+func TestObserverReviewAndVeto(t *testing.T) {
+	ctx := context.Background()
+	reviewed := PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+		return &Decision{Allow: true, Reviewers: []string{"compliance"}}, nil
+	})
+
+	for _, tc := range []struct {
+		blocked string
+		want    State
+	}{
+		{blocked: "mallory", want: StateFinalized},
+		{blocked: "bob", want: StateFailed},
+	} {
+		observer := &complianceObserver{blocked: tc.blocked}
+		c, err := New(Config{
+			Store:           NewMemoryStore(),
+			Chains:          []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+			Signer:          &fakeSigner{},
+			Policy:          reviewed,
+			Observers:       []Observer{observer},
+			ConfirmInterval: time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		s, err := c.Submit(ctx, testRequest)
+		require.NoError(t, err)
+		s, err = c.Run(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, s.State)
+		assert.Equal(t, tc.want == StateFailed, s.Refused())
+		require.Len(t, s.Reviews, 1)
+		assert.Equal(t, "compliance", s.Reviews[0].Reviewer)
+
+		history, err := c.History(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, eventTypes(history), observer.seen)
+	}
+}
+
+func TestApprovalsWaitForReview(t *testing.T) {
+	created := Event{Session: "s", Seq: 1, Type: EventCreated, Request: testRequest}
+	evaluated := Event{Session: "s", Seq: 2, Type: EventPolicyEvaluated,
+		Unsigned: &chain.UnsignedTx{}, Summary: &chain.Summary{},
+		Decision: &Decision{Allow: true, RequiredApprovals: 1, Reviewers: []string{"compliance"}}}
+	approved := Event{Session: "s", Seq: 3, Type: EventApproved, Approver: "carol"}
+
+	s, err := Replay([]Event{created, evaluated, approved})
+	require.NoError(t, err)
+	assert.Equal(t, StatePolicyEvaluated, s.State)
+	assert.Equal(t, []string{"compliance"}, s.PendingReviewers())
+
+	reviewed := Event{Session: "s", Seq: 4, Type: EventReviewed, Review: &Review{Reviewer: "compliance"}}
+	s, err = Replay([]Event{created, evaluated, approved, reviewed})
+	require.NoError(t, err)
+	assert.Equal(t, StateApproved, s.State)
+
+	stranger := Event{Session: "s", Seq: 4, Type: EventReviewed, Review: &Review{Reviewer: "marketing"}}
+	_, err = Replay([]Event{created, evaluated, approved, stranger})
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+}
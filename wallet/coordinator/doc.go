@@ -0,0 +1,120 @@
+// Package coordinator drives signing sessions from request to on-chain
+// finality.
+//
+// Every session is an explicit state machine
+//
+//	Created → PolicyEvaluated → Approved → RoundsInProgress(n) → Signed → Broadcast → Finalized
+//	                                                                                 ↘ Failed
+//
+// whose transitions are recorded as an append-only list of `Event`s in a
+// `Store`.  The current `Session` is never stored directly; it is rebuilt by
+// replaying the events through `Replay`.  This has two consequences:
+//
+//   - Diagnosability – `History` returns the full, timestamped transition log
+//     of a session and `List` finds sessions by state or by how long they have
+//     been idle, so a session stuck in round 2 is easy to spot.
+//   - Resumability – `Run` picks up a session from whatever state it was left
+//     in, e.g. after a coordinator restart.  MPC rounds themselves cannot be
+//     resumed half-way, so a session found in RoundsInProgress restarts the
+//     signing protocol from round 1.
+//
+// Observers see every transition as it is recorded.  When the policy lists an
+// observer in Decision.Reviewers, the session additionally waits for that
+// observer's review before it can be approved, and a veto fails it.  Observers
+// hold no share and never take part in signing.
+//
+// Approvals may take hours.  A session waiting for them persists like any
+// other, with the deadline set by Config.ApprovalTimeout or the policy's
+// Decision.ApprovalTimeout.  When it starts waiting, a `Notifier` tells the
+// eligible approvers (Decision.Approvers), and each Approve is recorded, so
+// partial approvals survive restarts and `Session.PendingApprovers` tells who
+// is still missing.  Signing starts only once the required approvals are in.
+// A session still waiting at its deadline is cancelled – by Run, Approve or a
+// `Watcher` scan – and the approvers are notified.
+//
+// With Config.ApproverKeys set, approvals are non-repudiable.  Each approver
+// signs the session's `Session.ApprovalDigest` – covering the session, the
+// exact signing payload and its decoded summary – with its Ed25519 identity
+// key (`SignApproval`) and submits it with `ApproveSigned`.  The signatures
+// are recorded in the approval events, kept in Session.ApprovalSignatures next
+// to the transaction signature, and passed to the Signer in
+// SignRequest.Approvals; every party checks them with `VerifyApprovals`
+// before contributing its share, so not even the coordinator can have a
+// transaction signed that the approvers did not approve.
+//
+// An approver can also approve a transaction ahead of time, e.g. a KMS
+// party during business hours for a transfer that must go out overnight.
+// `SignPreApproval` issues a short-lived token bound to the intent hash of
+// the transfer (see chain.Intent) and the token's validity window, not to a
+// signing payload, which on Solana changes with every build; the submitter
+// attaches it as Request.PreApprovals.  When the built transaction decodes
+// to that intent and the token is within its validity window, Run records it
+// as that approver's approval, without notifying them, again after every
+// rebuild.  A token counts as one approval of an eligible approver, never
+// more, so policy is not weakened; tokens valid for longer than
+// Config.MaxPreApprovalTTL are refused, and the parties count redeemed
+// tokens in VerifyApprovals like signed approvals, checking the window
+// against their own clock.
+//
+// Submitters can attach business context, such as an order ID, user ID or
+// reason code, as `Metadata` signed with `SignMetadata` for the request's
+// chain, reference and recipients.  Submit checks it against
+// Config.MetadataKeys.  The metadata is recorded in the created event, bound
+// into the approval digest, passed to the Signer in SignRequest.Metadata and
+// matched by Filter.Metadata, and `Session.Evidence` bundles it with the
+// approvals and the signature, so every signature can be joined back to the
+// business event that caused it.
+//
+// Requests carry a `Priority`.  A `Pool` runs sessions on a fixed number of
+// workers, highest priority first, and can preempt queued low-priority
+// sessions when its queue is full; the priority is also passed to the Signer
+// so that per-party queues can honour it.  Within a priority the Pool takes
+// turns between signing keys (`SigningKey`, the chain and sending address):
+// the native library takes no global lock, so sessions of different keys run
+// side by side, and a hot wallet with a long backlog cannot starve the keys
+// of other tenants.  PoolConfig.KeyWorkers and KeyQueueSize bound the workers
+// and queue slots one key may hold, and `Pool.KeyStats` reports queued and
+// running sessions, waiting times and rejections per key.
+//
+// Every SignRequest carries the session's `chain.Intent` – chain, sending
+// key, recipients, amounts and validity window, which ends at
+// Request.NotAfter if set – along with the unsigned transaction.  Each party
+// runs `VerifyIntent`, which decodes the transaction with the party's own
+// chain and checks it against that intent, and the parties compare the
+// resulting hashes (preflight.Config.Intent) before the first round, so the
+// bytes signed are bound to the transfer the approvers approved.
+//
+// On chains whose transactions expire (chain.Expirer), a broadcast
+// transaction that the ledger does not know is resubmitted until it lands or
+// expires; a failed resubmission is returned by Run and the session stays
+// broadcast until it is resumed.  An expired transaction is rebuilt: the session records an
+// "expired" event and returns to Created, so the transaction is rebuilt with
+// fresh ledger state, policy and reviews are re-run and the MPC protocol signs
+// again.  With Config.Reapprove set, a rebuilt transaction that matches the
+// expired one (see IdenticalIntent) inherits its approvals.  A `Watcher`
+// resumes signed and broadcast sessions in the background, so this happens
+// even for sessions nobody is running.
+//
+// A Signer whose parties encrypt each session under ephemeral keys
+// (transport/ephemeral) reports the negotiated key exchange, cipher and
+// transcript hashes through SignRequest.Transport; they are recorded in the
+// signed event and included in the evidence, so the audit record shows how
+// the round messages were protected.
+//
+// Infrastructure errors (RPC outages, unreachable parties) are returned from
+// `Run` without changing the session so it can be retried; definitive outcomes
+// (policy rejection, failed simulation, reverted transaction) move the session
+// to Failed.
+//
+// Services submit transfers over HTTP through the request API returned by
+// `NewHandler`, which runs them on a Pool and reports their state, signing
+// payload and signature; a repeated Reference returns the existing session.
+// The client package is a cgo-free library for it that also verifies the
+// returned signatures locally.
+//
+// Finished sessions are kept until a `Purger` deletes them.
+// PurgerConfig.Retention sets how long finalized and failed sessions are
+// retained after their last event; sessions under legal hold
+// (PurgeStore.SetHold) are never deleted, and sessions still in progress are
+// never touched.
+package coordinator
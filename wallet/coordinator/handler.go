@@ -0,0 +1,293 @@
+package coordinator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+const defaultMaxBodyBytes = 1 << 20
+
+// HandlerConfig contains the configuration for the request API.
+type HandlerConfig struct {
+	// Authenticate identifies the caller of every request, e.g. with
+	// remotesigner.BearerTokens.  Required.
+	Authenticate func(*http.Request) (string, error)
+	// Pool runs submitted sessions.  Required.
+	Pool *Pool
+	// MaxBodyBytes limits request bodies.  Defaults to 1 MiB.
+	MaxBodyBytes int64
+}
+
+// apiRequest is the body of POST /v1/requests.
+type apiRequest struct {
+	Chain     string      `json:"chain"`
+	From      string      `json:"from"`
+	To        string      `json:"to,omitempty"`
+	Amount    string      `json:"amount,omitempty"`
+	Token     string      `json:"token,omitempty"`
+	Outputs   []apiOutput `json:"outputs,omitempty"`
+	Priority  string      `json:"priority,omitempty"`
+	MaxFee    string      `json:"max_fee,omitempty"`
+	Reference string      `json:"reference,omitempty"`
+	Metadata  *Metadata   `json:"metadata,omitempty"`
+
+	PreApprovals []PreApproval `json:"pre_approvals,omitempty"`
+}
+
+type apiOutput struct {
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+// apiSession is how the request API reports a session.
+type apiSession struct {
+	ID                string    `json:"id"`
+	State             string    `json:"state"`
+	Chain             string    `json:"chain"`
+	From              string    `json:"from"`
+	Reference         string    `json:"reference,omitempty"`
+	RequiredApprovals int       `json:"required_approvals"`
+	Approvals         []string  `json:"approvals,omitempty"`
+	SigningPayload    []byte    `json:"signing_payload,omitempty"`
+	Signature         []byte    `json:"signature,omitempty"`
+	TxID              string    `json:"tx_id,omitempty"`
+	Error             string    `json:"error,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// NewHandler returns the request API through which services submit transfers
+// and follow their sessions:
+//
+//	POST /v1/requests       submit a transfer; 202 with the new session, or
+//	                        200 with the existing one for a repeated reference
+//	GET  /v1/requests/{id}  the session's state, and once signed its
+//	                        signing payload, signature and transaction ID
+//
+// Submitted sessions are run on config.Pool.  A request whose reference
+// matches an existing session on the same chain returns that session, so
+// callers can retry safely; a session that could not be queued is queued
+// again by the retry.  Reusing a reference for a different transfer is
+// refused with 409.
+func NewHandler(c *Coordinator, config HandlerConfig) (http.Handler, error) {
+	if config.Authenticate == nil {
+		return nil, fmt.Errorf("authenticate must be provided")
+	}
+	if config.Pool == nil {
+		return nil, fmt.Errorf("pool must be provided")
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	h := &handler{c: c, config: config}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/requests", h.submit)
+	mux.HandleFunc("GET /v1/requests/{id}", h.session)
+	return mux, nil
+}
+
+type handler struct {
+	c      *Coordinator
+	config HandlerConfig
+}
+
+func (h *handler) submit(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.config.Authenticate(r); err != nil {
+		writeAPIError(w, http.StatusUnauthorized, err)
+		return
+	}
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(w, r.Body, h.config.MaxBodyBytes)); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("reading body: %w", err))
+		return
+	}
+	var ar apiRequest
+	dec := json.NewDecoder(&body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ar); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("decoding request: %w", err))
+		return
+	}
+	req, err := ar.request()
+	if err == nil {
+		if _, ok := h.c.Chain(req.Chain); !ok {
+			err = fmt.Errorf("unknown chain %q", req.Chain)
+		} else if err = h.c.VerifyMetadata(req); err == nil {
+			err = h.c.VerifyPreApprovals(req)
+		}
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	status := http.StatusAccepted
+	var s *Session
+	if req.Reference != "" {
+		existing, err := h.c.List(r.Context(), Filter{Reference: req.Reference})
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, e := range existing {
+			if e.Request.Chain != req.Chain {
+				continue
+			}
+			if !sameTransfer(&e.Request.Transfer, &req.Transfer) {
+				writeAPIError(w, http.StatusConflict, fmt.Errorf("reference %q was used for another transfer", req.Reference))
+				return
+			}
+			s, status = e, http.StatusOK
+			break
+		}
+	}
+	if s == nil {
+		if s, err = h.c.Submit(r.Context(), req); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	// Only the created event: the session never started running.
+	if s.State == StateCreated && s.Version == 1 {
+		if err := h.config.Pool.Enqueue(s); err != nil {
+			writeAPIError(w, http.StatusServiceUnavailable, fmt.Errorf("session %s was not queued: %w", s.ID, err))
+			return
+		}
+	}
+	writeAPI(w, status, newAPISession(s))
+}
+
+func (h *handler) session(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.config.Authenticate(r); err != nil {
+		writeAPIError(w, http.StatusUnauthorized, err)
+		return
+	}
+	s, err := h.c.Session(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		writeAPIError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeAPIError(w, http.StatusInternalServerError, err)
+	default:
+		writeAPI(w, http.StatusOK, newAPISession(s))
+	}
+}
+
+// request converts and validates the submitted transfer.
+func (ar *apiRequest) request() (*Request, error) {
+	if ar.Chain == "" || ar.From == "" {
+		return nil, fmt.Errorf("chain and from must be provided")
+	}
+	req := &Request{
+		Chain:     ar.Chain,
+		Transfer:  chain.Transfer{From: ar.From, To: ar.To, Token: ar.Token},
+		Reference: ar.Reference,
+		Metadata:  ar.Metadata,
+
+		PreApprovals: ar.PreApprovals,
+	}
+	var err error
+	switch {
+	case len(ar.Outputs) > 0 && (ar.To != "" || ar.Amount != ""):
+		return nil, fmt.Errorf("outputs cannot be combined with to and amount")
+	case len(ar.Outputs) > 0:
+		for i, o := range ar.Outputs {
+			amount, err := apiAmount(o.Amount)
+			if err != nil || o.To == "" {
+				return nil, fmt.Errorf("output %d needs a recipient and a positive amount", i)
+			}
+			req.Transfer.Outputs = append(req.Transfer.Outputs, chain.Output{To: o.To, Amount: amount})
+		}
+	case ar.To == "":
+		return nil, fmt.Errorf("recipient must be provided")
+	default:
+		if req.Transfer.Amount, err = apiAmount(ar.Amount); err != nil {
+			return nil, err
+		}
+	}
+	if ar.MaxFee != "" {
+		if req.MaxFee, err = apiAmount(ar.MaxFee); err != nil {
+			return nil, fmt.Errorf("max fee: %w", err)
+		}
+	}
+	switch ar.Priority {
+	case "", PriorityNormal.String():
+	case PriorityBatch.String():
+		req.Priority = PriorityBatch
+	case PriorityUrgent.String():
+		req.Priority = PriorityUrgent
+	default:
+		return nil, fmt.Errorf("unknown priority %q", ar.Priority)
+	}
+	return req, nil
+}
+
+// sameTransfer reports whether a and b move the same amounts between the
+// same parties.
+func sameTransfer(a, b *chain.Transfer) bool {
+	if a.From != b.From || a.To != b.To || a.Token != b.Token || len(a.Outputs) != len(b.Outputs) || !sameAmount(a.Amount, b.Amount) {
+		return false
+	}
+	for i := range a.Outputs {
+		if a.Outputs[i].To != b.Outputs[i].To || !sameAmount(a.Outputs[i].Amount, b.Outputs[i].Amount) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameAmount(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+// apiAmount parses a positive decimal integer in canonical form.
+func apiAmount(s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() <= 0 || s != v.String() {
+		return nil, fmt.Errorf("amount must be a positive decimal integer")
+	}
+	return v, nil
+}
+
+func newAPISession(s *Session) *apiSession {
+	out := &apiSession{
+		ID:        s.ID,
+		State:     s.State.String(),
+		Chain:     s.Request.Chain,
+		From:      s.Request.Transfer.From,
+		Reference: s.Request.Reference,
+		Approvals: s.Approvals,
+		Signature: s.Signature,
+		TxID:      s.TxID,
+		Error:     s.Err,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+	if s.Decision != nil {
+		out.RequiredApprovals = s.Decision.RequiredApprovals
+	}
+	if s.Unsigned != nil {
+		out.SigningPayload = s.Unsigned.SigningPayload
+	}
+	return out
+}
+
+func writeAPI(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPI(w, status, map[string]string{"error": err.Error()})
+}
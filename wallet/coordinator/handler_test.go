@@ -0,0 +1,81 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+func TestHandler(t *testing.T) {
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, &fakeSigner{rounds: 1}, nil)
+	pool := NewPool(c, PoolConfig{Workers: 1})
+	defer pool.Close()
+	h, err := NewHandler(c, HandlerConfig{
+		Pool: pool,
+		Authenticate: func(r *http.Request) (string, error) {
+			if r.Header.Get("Authorization") != "Bearer token-1" {
+				return "", errors.New("unauthorized")
+			}
+			return "svc", nil
+		},
+	})
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, map[string]any) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer token-1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var out map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out
+	}
+
+	transfer := `{"chain":"fake","from":"alice","to":"bob","amount":"1","reference":"order-1"}`
+	status, s := do("POST", "/v1/requests", transfer)
+	require.Equal(t, http.StatusAccepted, status, s)
+	id := s["id"].(string)
+	status, s = do("POST", "/v1/requests", transfer)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, id, s["id"])
+	status, _ = do("POST", "/v1/requests", `{"chain":"fake","from":"alice","to":"bob","amount":"2","reference":"order-1"}`)
+	assert.Equal(t, http.StatusConflict, status)
+
+	require.Eventually(t, func() bool {
+		_, s = do("GET", "/v1/requests/"+id, "")
+		return s["state"] == StateFinalized.String()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "tx-1", s["tx_id"])
+	assert.NotEmpty(t, s["signature"])
+	assert.NotEmpty(t, s["signing_payload"])
+
+	for _, body := range []string{
+		`{"chain":"other","from":"alice","to":"bob","amount":"1"}`,
+		`{"chain":"fake","from":"alice","to":"bob","amount":"01"}`,
+		`{"chain":"fake","from":"alice","to":"bob","amount":"1","priority":"asap"}`,
+		`{"chain":"fake","from":"alice","to":"bob","amount":"1","unknown":true}`,
+	} {
+		status, _ = do("POST", "/v1/requests", body)
+		assert.Equal(t, http.StatusBadRequest, status, body)
+	}
+	status, _ = do("GET", "/v1/requests/missing", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	resp, err := http.Get(srv.URL + "/v1/requests/" + id)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -0,0 +1,84 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/signctx"
+)
+
+// ErrIntentMismatch is returned by VerifyIntent when a sign request's
+// transaction does not carry the intent it claims.
+var ErrIntentMismatch = errors.New("coordinator: transaction does not match intent")
+
+// Intent returns the intent of the session's current transaction: the
+// decoded transfer from the sender's key, valid from the session's creation
+// until Request.NotAfter.
+func (s *Session) Intent() (*chain.Intent, error) {
+	if s.Summary == nil {
+		return nil, fmt.Errorf("session %s has no transaction", s.ID)
+	}
+	return chain.IntentOf(s.Summary, s.Summary.From, s.CreatedAt, s.Request.NotAfter), nil
+}
+
+// VerifyIntent decodes req.Transaction with the party's own instance of the
+// chain, derives its intent and checks it against req.Intent, the intent the
+// approvers saw, and that now lies in its validity window.  It returns the
+// intent hash, which the parties then compare with each other before
+// signing, e.g. through preflight.Config.Intent:
+//
+//	hash, err := coordinator.VerifyIntent(req, ch, time.Now())
+//	if err != nil {
+//	    return nil, err // do not sign
+//	}
+//
+// For chains whose signing payload is the transaction itself, such as
+// Solana, it also checks that req.Payload is req.Transaction.  Otherwise the
+// party must check that req.Payload is derived from it.
+func VerifyIntent(req *SignRequest, ch chain.Chain, now time.Time) ([]byte, error) {
+	if req.Intent == nil {
+		return nil, fmt.Errorf("%w: request carries no intent", ErrIntentMismatch)
+	}
+	if ch.ID() != req.Chain || req.Intent.Chain != req.Chain {
+		return nil, fmt.Errorf("%w: request for chain %q checked with %q", ErrIntentMismatch, req.Chain, ch.ID())
+	}
+	if _, ok := ch.(chain.SigningContexter); ok && !bytes.Equal(req.Payload, req.Transaction) {
+		return nil, fmt.Errorf("%w: signing payload is not the transaction", ErrIntentMismatch)
+	}
+	summary, err := ch.Decode(req.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding transaction: %v", ErrIntentMismatch, err)
+	}
+	want, err := req.Intent.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntentMismatch, err)
+	}
+	got, err := chain.IntentOf(summary, summary.From, req.Intent.NotBefore, req.Intent.NotAfter).Hash()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntentMismatch, err)
+	}
+	if !bytes.Equal(got, want) {
+		return nil, fmt.Errorf("%w: transaction is %s", ErrIntentMismatch, summary)
+	}
+	if !req.Intent.Within(now) {
+		return nil, fmt.Errorf("%w: not valid at %s", ErrIntentMismatch, now.UTC().Format(time.RFC3339))
+	}
+	return got, nil
+}
+
+// RequireContext returns a Signer that runs signctx.Check on the payload of
+// every request, in the context the request declares, before passing it to
+// next.  Party hosts wrap their Signer with it; envelope.Serve does so for
+// every request it answers.
+func RequireContext(next Signer) Signer {
+	return signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+		if err := signctx.Check(signctx.Context(req.Context), req.Payload); err != nil {
+			return nil, err
+		}
+		return next.Sign(ctx, req)
+	})
+}
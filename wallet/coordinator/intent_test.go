@@ -0,0 +1,152 @@
+package coordinator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/clock"
+	"solana-threshold-wallet/wallet/signctx"
+)
+
+// decodingChain is a fakeChain whose Decode reports summary, as a party's
+// chain would for a transaction other than the one the coordinator decoded.
+type decodingChain struct {
+	fakeChain
+	summary *chain.Summary
+}
+
+func (d *decodingChain) Decode([]byte) (*chain.Summary, error) { return d.summary, nil }
+
+func TestPartiesAgreeOnIntent(t *testing.T) {
+	ctx := context.Background()
+	now := clock.NewFake(epoch)
+	var hashes [][]byte
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer: signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+			// Every party decodes the transaction with its own chain.
+			for i := 0; i < 3; i++ {
+				hash, err := VerifyIntent(req, &fakeChain{}, now.Now())
+				if err != nil {
+					return nil, err
+				}
+				hashes = append(hashes, hash)
+			}
+			return (&fakeSigner{}).Sign(ctx, req)
+		}),
+		ConfirmInterval: time.Millisecond,
+		Now:             now.Now,
+	})
+	require.NoError(t, err)
+
+	req := *testRequest
+	req.NotAfter = epoch.Add(time.Hour)
+	s, err := c.Submit(ctx, &req)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+
+	intent, err := s.Intent()
+	require.NoError(t, err)
+	assert.Equal(t, "alice", intent.KeyID)
+	assert.Equal(t, epoch, intent.NotBefore)
+	assert.Equal(t, req.NotAfter, intent.NotAfter)
+	want, err := intent.Hash()
+	require.NoError(t, err)
+	require.Len(t, hashes, 3)
+	for _, hash := range hashes {
+		assert.Equal(t, want, hash)
+	}
+}
+
+func TestVerifyIntentRejects(t *testing.T) {
+	payload := []byte("alice|bob|1")
+	summary := &chain.Summary{Chain: "fake", From: "alice", To: "bob", Amount: big.NewInt(1), Fee: big.NewInt(0)}
+	intent := chain.IntentOf(summary, "alice", epoch, epoch.Add(time.Hour))
+	request := func() *SignRequest {
+		return &SignRequest{Chain: "fake", Payload: payload, Transaction: payload, Intent: intent}
+	}
+
+	_, err := VerifyIntent(request(), &fakeChain{}, epoch.Add(time.Minute))
+	require.NoError(t, err)
+
+	other := *summary
+	other.To = "mallory"
+	_, err = VerifyIntent(request(), &decodingChain{summary: &other}, epoch.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrIntentMismatch, "transaction pays someone else")
+
+	other = *summary
+	other.Amount = big.NewInt(2)
+	_, err = VerifyIntent(request(), &decodingChain{summary: &other}, epoch.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrIntentMismatch, "transaction pays more")
+
+	_, err = VerifyIntent(request(), &fakeChain{}, epoch.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrIntentMismatch, "window has passed")
+
+	req := request()
+	req.Intent = nil
+	_, err = VerifyIntent(req, &fakeChain{}, epoch)
+	assert.ErrorIs(t, err, ErrIntentMismatch)
+
+	req = request()
+	req.Chain = "other"
+	_, err = VerifyIntent(req, &fakeChain{}, epoch)
+	assert.ErrorIs(t, err, ErrIntentMismatch)
+}
+
+func TestRunFailsLapsedRequest(t *testing.T) {
+	ctx := context.Background()
+	now := clock.NewFake(epoch)
+	signer := &fakeSigner{}
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer: signer,
+		Policy: PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+			return &Decision{Allow: true, RequiredApprovals: 1}, nil
+		}),
+		Now: now.Now,
+	})
+	require.NoError(t, err)
+
+	req := *testRequest
+	req.NotAfter = epoch.Add(time.Hour)
+	s, err := c.Submit(ctx, &req)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatePolicyEvaluated, s.State)
+
+	now.Advance(2 * time.Hour)
+	_, err = c.Approve(ctx, s.ID, "carol")
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+	assert.Contains(t, s.Err, "request expired")
+	assert.Empty(t, signer.context, "the lapsed request was not signed")
+}
+
+func TestRequireContext(t *testing.T) {
+	var signed []string
+	signer := RequireContext(signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+		signed = append(signed, req.Context)
+		return []byte("signature"), nil
+	}))
+
+	_, err := signer.Sign(context.Background(), &SignRequest{Context: string(signctx.Raw), Payload: []byte("hello")})
+	require.NoError(t, err)
+	_, err = signer.Sign(context.Background(), &SignRequest{Context: string(signctx.SolanaTx), Payload: []byte("hello")})
+	assert.ErrorIs(t, err, signctx.ErrMismatch)
+	_, err = signer.Sign(context.Background(), &SignRequest{Context: "unknown", Payload: []byte("hello")})
+	assert.ErrorIs(t, err, signctx.ErrMismatch)
+	assert.Equal(t, []string{string(signctx.Raw)}, signed)
+}
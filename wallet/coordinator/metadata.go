@@ -0,0 +1,159 @@
+package coordinator
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// metadataDomain separates metadata signatures from any other use of a
+// submitter's key.
+const metadataDomain = "cb-mpc/coordinator/metadata/v1"
+
+// ErrMetadataSignature is returned by Submit and VerifyMetadata when a
+// request's metadata is not signed by a known submitter.
+var ErrMetadataSignature = errors.New("coordinator: invalid metadata signature")
+
+// Metadata is business context attached to a request, such as an order ID,
+// user ID or reason code.  The wallet does not interpret Fields; it records
+// them with the session, binds them into approval digests, passes them to
+// the Signer and returns them in the session's Evidence, so that every
+// signature can be joined back to the business event that caused it.
+//
+// Signature is the submitter's Ed25519 signature over Fields and the
+// request's chain, reference, token and recipients; see SignMetadata.
+type Metadata struct {
+	Fields    map[string]string `json:"fields"`
+	Signer    string            `json:"signer"`
+	Signature []byte            `json:"signature"`
+}
+
+// metadataDigest binds fields to the parts of req the submitter decides.
+// The sender is left out, as services such as the webhook server pick it.
+func metadataDigest(signer string, fields map[string]string, req *Request) []byte {
+	h := sha256.New()
+	writeField(h, []byte(metadataDomain))
+	writeField(h, []byte(signer))
+	writeField(h, []byte(req.Chain))
+	writeField(h, []byte(req.Reference))
+	writeField(h, []byte(req.Transfer.Token))
+	outputs := req.Transfer.Outputs
+	if len(outputs) == 0 {
+		outputs = []chain.Output{{To: req.Transfer.To, Amount: req.Transfer.Amount}}
+	}
+	binary.Write(h, binary.BigEndian, uint32(len(outputs)))
+	for _, o := range outputs {
+		writeField(h, []byte(o.To))
+		var amount []byte
+		if o.Amount != nil {
+			amount = []byte(o.Amount.String())
+		}
+		writeField(h, amount)
+	}
+	writeFields(h, fields)
+	return h.Sum(nil)
+}
+
+// writeFields writes fields in key order.
+func writeFields(h hash.Hash, fields map[string]string) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	binary.Write(h, binary.BigEndian, uint32(len(keys)))
+	for _, k := range keys {
+		writeField(h, []byte(k))
+		writeField(h, []byte(fields[k]))
+	}
+}
+
+func writeField(h hash.Hash, field []byte) {
+	binary.Write(h, binary.BigEndian, uint32(len(field)))
+	h.Write(field)
+}
+
+// SignMetadata signs fields for req on behalf of signer and attaches them as
+// req.Metadata.  Set the request's chain, reference and transfer first; the
+// signature does not cover Transfer.From.
+func SignMetadata(req *Request, signer string, fields map[string]string, key ed25519.PrivateKey) {
+	req.Metadata = &Metadata{
+		Fields:    fields,
+		Signer:    signer,
+		Signature: ed25519.Sign(key, metadataDigest(signer, fields, req)),
+	}
+}
+
+// VerifyMetadata checks that req's metadata, if any, is signed by the
+// submitter it names with a key listed in keys.
+func VerifyMetadata(req *Request, keys map[string]ed25519.PublicKey) error {
+	m := req.Metadata
+	if m == nil {
+		return nil
+	}
+	key, ok := keys[m.Signer]
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: unknown submitter %q", ErrMetadataSignature, m.Signer)
+	}
+	if !ed25519.Verify(key, metadataDigest(m.Signer, m.Fields, req), m.Signature) {
+		return fmt.Errorf("%w: signature of %q does not verify", ErrMetadataSignature, m.Signer)
+	}
+	return nil
+}
+
+// Evidence is the self-contained record of a signed session: what was asked
+// for and by whom, what the approvers saw and signed, and what the MPC key
+// signed.  Auditors check it with VerifyMetadata on Request and, for signed
+// approvals, VerifyApprovals on the SignRequest it describes.
+type Evidence struct {
+	Session      string              `json:"session"`
+	Request      Request             `json:"request"`
+	Payload      []byte              `json:"payload"` // Signing payload of the signed transaction
+	Summary      *chain.Summary      `json:"summary"`
+	Approvals    []ApprovalSignature `json:"approvals,omitempty"`
+	PreApprovals []PreApproval       `json:"pre_approvals,omitempty"`
+	Signature    []byte              `json:"signature"`
+	Transport    *Transport          `json:"transport,omitempty"`
+	TxID         string              `json:"tx_id,omitempty"`
+	Receipt      *chain.Receipt      `json:"receipt,omitempty"`
+}
+
+// Evidence returns the session's evidence.  It fails until the session's
+// transaction is signed.
+func (s *Session) Evidence() (*Evidence, error) {
+	if s.Signature == nil || s.Unsigned == nil {
+		return nil, fmt.Errorf("session %s is not signed", s.ID)
+	}
+	return &Evidence{
+		Session:      s.ID,
+		Request:      s.Request,
+		Payload:      s.Unsigned.SigningPayload,
+		Summary:      s.Summary,
+		Approvals:    s.ApprovalSignatures,
+		PreApprovals: s.PreApprovals,
+		Signature:    s.Signature,
+		Transport:    s.Transport,
+		TxID:         s.TxID,
+		Receipt:      s.Receipt,
+	}, nil
+}
+
+// SignRequest returns the sign request the evidence describes, without
+// Progress, for checking its approvals with VerifyApprovals.
+func (e *Evidence) SignRequest() *SignRequest {
+	return &SignRequest{
+		Session:      e.Session,
+		Chain:        e.Request.Chain,
+		Payload:      e.Payload,
+		Summary:      e.Summary,
+		Approvals:    e.Approvals,
+		PreApprovals: e.PreApprovals,
+		Metadata:     e.Request.Metadata,
+	}
+}
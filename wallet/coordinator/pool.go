@@ -0,0 +1,309 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPoolWorkers   = 4
+	defaultPoolQueueSize = 1024
+)
+
+var (
+	// ErrQueueFull is returned by Pool.Enqueue when no queue slot is free
+	// and nothing can be preempted.
+	ErrQueueFull = errors.New("coordinator: session queue full")
+	// ErrKeyQueueFull is returned by Pool.Enqueue when the key of the
+	// session already has PoolConfig.KeyQueueSize sessions queued and none
+	// of them can be preempted.
+	ErrKeyQueueFull = errors.New("coordinator: session queue of key full")
+	// ErrPreempted is reported to PoolConfig.OnDone for a queued session
+	// that was evicted in favour of a higher-priority one.  The session
+	// itself is untouched and can be enqueued again later.
+	ErrPreempted = errors.New("coordinator: session preempted")
+	// ErrPoolClosed is returned by Pool.Enqueue after Close.
+	ErrPoolClosed = errors.New("coordinator: pool closed")
+)
+
+// PoolConfig contains the configuration for a Pool.
+type PoolConfig struct {
+	// Workers is the number of sessions run concurrently.  Defaults to 4.
+	Workers int
+	// QueueSize bounds the number of waiting sessions.  Defaults to 1024.
+	QueueSize int
+	// KeyWorkers bounds the number of sessions of one signing key run
+	// concurrently, so that a busy key cannot occupy every worker.
+	// Defaults to Workers.
+	KeyWorkers int
+	// KeyQueueSize bounds the number of waiting sessions of one signing
+	// key, so that a busy key cannot fill the queue.  Defaults to
+	// QueueSize.
+	KeyQueueSize int
+	// Preempt lets a session evict the lowest-priority queued session when
+	// the queue is full, provided that session has strictly lower priority.
+	Preempt bool
+	// OnDone, if set, is called when a session leaves the pool: after Run
+	// returns, or with ErrPreempted when it was evicted.
+	OnDone func(id string, s *Session, err error)
+}
+
+// SigningKey identifies the key a session signs with.
+type SigningKey struct {
+	Chain   string
+	Address string
+}
+
+// signingKey returns the key that signs the session's transaction.
+func signingKey(s *Session) SigningKey {
+	return SigningKey{Chain: s.Request.Chain, Address: s.Request.Transfer.From}
+}
+
+// KeyStats are the scheduling metrics of one signing key.
+type KeyStats struct {
+	Queued   int           // Sessions waiting
+	Running  int           // Sessions being run
+	Started  uint64        // Sessions started since the pool was created
+	Rejected uint64        // Sessions refused or preempted for the key's queue bound
+	Wait     time.Duration // Total time the started sessions waited
+	// OldestWait is how long the oldest waiting session has waited.
+	OldestWait time.Duration
+}
+
+// Pool runs sessions on a fixed number of workers, highest priority first.
+//
+// Sessions are scheduled per signing key: within a priority, the key whose
+// turn lies furthest back goes next, and sessions of one key run in
+// submission order.  A key with many queued sessions therefore shares the
+// workers with the other keys instead of starving them; KeyWorkers and
+// KeyQueueSize bound what a single key may hold.
+type Pool struct {
+	c      *Coordinator
+	config PoolConfig
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []*queuedSession
+	keys   map[SigningKey]*keyState
+	seq    uint64
+	turn   uint64
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// keyState is the scheduling state of one signing key.
+type keyState struct {
+	stats KeyStats
+	turn  uint64 // Pool turn at which the key last started a session
+}
+
+// NewPool starts a worker pool for c.
+func NewPool(c *Coordinator, config PoolConfig) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = defaultPoolWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultPoolQueueSize
+	}
+	if config.KeyWorkers <= 0 {
+		config.KeyWorkers = config.Workers
+	}
+	if config.KeyQueueSize <= 0 {
+		config.KeyQueueSize = config.QueueSize
+	}
+	p := &Pool{c: c, config: config, keys: make(map[SigningKey]*keyState)}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < config.Workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Enqueue schedules a session to be run with the priority of its request.
+func (p *Pool) Enqueue(s *Session) error {
+	key := signingKey(s)
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	k := p.key(key)
+	var evicted *queuedSession
+	keyFull := k.stats.Queued >= p.config.KeyQueueSize
+	if keyFull || len(p.queue) >= p.config.QueueSize {
+		match, err := func(*queuedSession) bool { return true }, ErrQueueFull
+		if keyFull {
+			// Only a session of the same key frees a slot of its queue.
+			match = func(q *queuedSession) bool { return q.key == key }
+			if len(p.queue) < p.config.QueueSize {
+				err = ErrKeyQueueFull
+			}
+			k.stats.Rejected++
+		}
+		lowest := p.lowest(match)
+		if !p.config.Preempt || lowest < 0 || p.queue[lowest].priority >= s.Request.Priority {
+			p.mu.Unlock()
+			return err
+		}
+		evicted = p.remove(lowest)
+	}
+	p.seq++
+	p.queue = append(p.queue, &queuedSession{id: s.ID, key: key, priority: s.Request.Priority, seq: p.seq, queued: p.c.now()})
+	k.stats.Queued++
+	p.mu.Unlock()
+	p.cond.Broadcast()
+
+	if evicted != nil {
+		p.done(evicted.id, nil, ErrPreempted)
+	}
+	return nil
+}
+
+// Len returns the number of queued sessions.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queue)
+}
+
+// KeyStats returns the scheduling metrics of every key that has had a
+// session in the pool.
+func (p *Pool) KeyStats() map[SigningKey]KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.c.now()
+	out := make(map[SigningKey]KeyStats, len(p.keys))
+	for key, k := range p.keys {
+		out[key] = k.stats
+	}
+	for _, q := range p.queue {
+		stats := out[q.key]
+		if wait := now.Sub(q.queued); wait > stats.OldestWait {
+			stats.OldestWait = wait
+		}
+		out[q.key] = stats
+	}
+	return out
+}
+
+// Close stops accepting sessions, drops the queue and waits for running
+// sessions to return.  Dropped sessions stay in the Store and can be resumed
+// with Coordinator.Run.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	for _, q := range p.queue {
+		p.keys[q.key].stats.Queued--
+	}
+	p.queue = nil
+	p.mu.Unlock()
+	p.cond.Broadcast()
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		i := p.next()
+		for i < 0 && !p.closed {
+			p.cond.Wait()
+			i = p.next()
+		}
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		next := p.remove(i)
+		k := p.keys[next.key]
+		p.turn++
+		k.turn = p.turn
+		k.stats.Running++
+		k.stats.Started++
+		k.stats.Wait += p.c.now().Sub(next.queued)
+		p.mu.Unlock()
+
+		s, err := p.c.Run(context.Background(), next.id)
+
+		p.mu.Lock()
+		k.stats.Running--
+		p.mu.Unlock()
+		p.cond.Broadcast()
+		p.done(next.id, s, err)
+	}
+}
+
+func (p *Pool) done(id string, s *Session, err error) {
+	if p.config.OnDone != nil {
+		p.config.OnDone(id, s, err)
+	}
+}
+
+// key returns the state of key, creating it.  p.mu must be held.
+func (p *Pool) key(key SigningKey) *keyState {
+	k, ok := p.keys[key]
+	if !ok {
+		k = &keyState{}
+		p.keys[key] = k
+	}
+	return k
+}
+
+// next returns the index of the session to run next, or -1 if every queued
+// session waits for its key.  p.mu must be held.
+func (p *Pool) next() int {
+	idx := -1
+	for i, q := range p.queue {
+		if p.keys[q.key].stats.Running >= p.config.KeyWorkers {
+			continue
+		}
+		if idx < 0 || p.before(q, p.queue[idx]) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// before reports whether a runs before b: higher priority first, then the
+// key whose last turn lies further back, then submission order.
+func (p *Pool) before(a, b *queuedSession) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if ta, tb := p.keys[a.key].turn, p.keys[b.key].turn; ta != tb {
+		return ta < tb
+	}
+	return a.seq < b.seq
+}
+
+// lowest returns the index of the matching entry that would run last, or -1.
+// p.mu must be held.
+func (p *Pool) lowest(match func(*queuedSession) bool) int {
+	idx := -1
+	for i, q := range p.queue {
+		if match(q) && (idx < 0 || p.before(p.queue[idx], q)) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// remove removes and returns the i-th queued session.  p.mu must be held.
+func (p *Pool) remove(i int) *queuedSession {
+	q := p.queue[i]
+	p.queue = append(p.queue[:i], p.queue[i+1:]...)
+	p.keys[q.key].stats.Queued--
+	return q
+}
+
+// queuedSession is an entry of the pool's queue.
+type queuedSession struct {
+	id       string
+	key      SigningKey
+	priority Priority
+	seq      uint64
+	queued   time.Time
+}
@@ -0,0 +1,230 @@
+package coordinator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// gatedSigner blocks the first signature until release is closed and records
+// the order in which sessions are signed.
+type gatedSigner struct {
+	release chan struct{}
+	started chan struct{}
+	once    sync.Once
+
+	mu    sync.Mutex
+	order []string
+}
+
+func newGatedSigner() *gatedSigner {
+	return &gatedSigner{release: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (g *gatedSigner) Sign(_ context.Context, req *SignRequest) ([]byte, error) {
+	g.once.Do(func() {
+		close(g.started)
+		<-g.release
+	})
+	g.mu.Lock()
+	g.order = append(g.order, req.Session)
+	g.mu.Unlock()
+	return []byte("sig"), nil
+}
+
+func submitWithPriority(t *testing.T, c *Coordinator, p Priority) *Session {
+	t.Helper()
+	req := *testRequest
+	req.Priority = p
+	s, err := c.Submit(context.Background(), &req)
+	require.NoError(t, err)
+	return s
+}
+
+func TestPoolRunsHighestPriorityFirst(t *testing.T) {
+	signer := newGatedSigner()
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, signer, nil)
+
+	var wg sync.WaitGroup
+	pool := NewPool(c, PoolConfig{Workers: 1, OnDone: func(string, *Session, error) { wg.Done() }})
+	defer pool.Close()
+
+	first := submitWithPriority(t, c, PriorityNormal)
+	wg.Add(1)
+	require.NoError(t, pool.Enqueue(first))
+	<-signer.started
+
+	batch := submitWithPriority(t, c, PriorityBatch)
+	normal := submitWithPriority(t, c, PriorityNormal)
+	urgent := submitWithPriority(t, c, PriorityUrgent)
+	for _, s := range []*Session{batch, normal, urgent} {
+		wg.Add(1)
+		require.NoError(t, pool.Enqueue(s))
+	}
+	assert.Equal(t, 3, pool.Len())
+
+	close(signer.release)
+	wg.Wait()
+	assert.Equal(t, []string{first.ID, urgent.ID, normal.ID, batch.ID}, signer.order)
+}
+
+func TestPoolPreemptsLowerPriority(t *testing.T) {
+	signer := newGatedSigner()
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, signer, nil)
+
+	var mu sync.Mutex
+	results := make(map[string]error)
+	pool := NewPool(c, PoolConfig{Workers: 1, QueueSize: 1, Preempt: true, OnDone: func(id string, _ *Session, err error) {
+		mu.Lock()
+		results[id] = err
+		mu.Unlock()
+	}})
+
+	require.NoError(t, pool.Enqueue(submitWithPriority(t, c, PriorityNormal)))
+	<-signer.started
+
+	batch := submitWithPriority(t, c, PriorityBatch)
+	require.NoError(t, pool.Enqueue(batch))
+	urgent := submitWithPriority(t, c, PriorityUrgent)
+	require.NoError(t, pool.Enqueue(urgent))
+	assert.ErrorIs(t, pool.Enqueue(submitWithPriority(t, c, PriorityUrgent)), ErrQueueFull)
+
+	mu.Lock()
+	assert.ErrorIs(t, results[batch.ID], ErrPreempted)
+	mu.Unlock()
+
+	close(signer.release)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		_, done := results[urgent.ID]
+		return done
+	}, time.Second, time.Millisecond)
+	pool.Close()
+
+	s, err := c.Session(context.Background(), batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateCreated, s.State, "preempted sessions are left untouched")
+	assert.ErrorIs(t, pool.Enqueue(s), ErrPoolClosed)
+}
+
+func submitFrom(t *testing.T, c *Coordinator, from string) *Session {
+	t.Helper()
+	req := *testRequest
+	req.Transfer.From = from
+	s, err := c.Submit(context.Background(), &req)
+	require.NoError(t, err)
+	return s
+}
+
+func TestPoolSharesWorkersBetweenKeys(t *testing.T) {
+	signer := newGatedSigner()
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, signer, nil)
+
+	var wg sync.WaitGroup
+	pool := NewPool(c, PoolConfig{Workers: 1, OnDone: func(string, *Session, error) { wg.Done() }})
+	defer pool.Close()
+
+	first := submitFrom(t, c, "alice")
+	wg.Add(1)
+	require.NoError(t, pool.Enqueue(first))
+	<-signer.started
+
+	var hot []*Session
+	for i := 0; i < 3; i++ {
+		hot = append(hot, submitFrom(t, c, "alice"))
+	}
+	other := submitFrom(t, c, "carol")
+	for _, s := range append(hot, other) {
+		wg.Add(1)
+		require.NoError(t, pool.Enqueue(s))
+	}
+
+	stats := pool.KeyStats()
+	alice, carol := SigningKey{Chain: "fake", Address: "alice"}, SigningKey{Chain: "fake", Address: "carol"}
+	assert.Equal(t, 3, stats[alice].Queued)
+	assert.Equal(t, 1, stats[alice].Running)
+	assert.Equal(t, uint64(1), stats[alice].Started)
+	assert.Equal(t, 1, stats[carol].Queued)
+
+	close(signer.release)
+	wg.Wait()
+	assert.Equal(t, []string{first.ID, other.ID, hot[0].ID, hot[1].ID, hot[2].ID}, signer.order,
+		"the quiet key goes before the backlog of the busy one")
+	stats = pool.KeyStats()
+	assert.Equal(t, KeyStats{Started: 4, Wait: stats[alice].Wait}, stats[alice])
+	assert.Equal(t, uint64(1), stats[carol].Started)
+}
+
+// firstBlockingSigner blocks the first signature until release is closed
+// and lets later ones through meanwhile.
+type firstBlockingSigner struct {
+	release chan struct{}
+	started chan struct{}
+	first   atomic.Bool
+}
+
+func (f *firstBlockingSigner) Sign(context.Context, *SignRequest) ([]byte, error) {
+	if f.first.CompareAndSwap(false, true) {
+		close(f.started)
+		<-f.release
+	}
+	return []byte("sig"), nil
+}
+
+func TestPoolBoundsKeyWorkers(t *testing.T) {
+	signer := &firstBlockingSigner{release: make(chan struct{}), started: make(chan struct{})}
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, signer, nil)
+
+	done := make(chan string, 3)
+	pool := NewPool(c, PoolConfig{Workers: 2, KeyWorkers: 1, OnDone: func(id string, _ *Session, _ error) { done <- id }})
+	defer pool.Close()
+
+	first := submitFrom(t, c, "alice")
+	require.NoError(t, pool.Enqueue(first))
+	<-signer.started
+	second := submitFrom(t, c, "alice")
+	require.NoError(t, pool.Enqueue(second))
+	other := submitFrom(t, c, "carol")
+	require.NoError(t, pool.Enqueue(other))
+
+	select {
+	case id := <-done:
+		assert.Equal(t, other.ID, id, "the free worker runs the other key")
+	case <-time.After(time.Second):
+		t.Fatal("the other key was not run")
+	}
+	assert.Equal(t, 1, pool.Len(), "the busy key waits for its worker")
+
+	close(signer.release)
+	assert.Equal(t, first.ID, <-done)
+	assert.Equal(t, second.ID, <-done)
+}
+
+func TestPoolBoundsKeyQueue(t *testing.T) {
+	signer := newGatedSigner()
+	c := newTestCoordinator(t, &fakeChain{status: chain.StatusFinalized}, signer, nil)
+
+	pool := NewPool(c, PoolConfig{Workers: 1, KeyQueueSize: 1})
+	defer func() {
+		close(signer.release)
+		pool.Close()
+	}()
+
+	require.NoError(t, pool.Enqueue(submitFrom(t, c, "alice")))
+	<-signer.started
+	require.NoError(t, pool.Enqueue(submitFrom(t, c, "alice")))
+	assert.ErrorIs(t, pool.Enqueue(submitFrom(t, c, "alice")), ErrKeyQueueFull)
+	require.NoError(t, pool.Enqueue(submitFrom(t, c, "carol")), "other keys still have room")
+
+	stats := pool.KeyStats()
+	assert.Equal(t, uint64(1), stats[SigningKey{Chain: "fake", Address: "alice"}].Rejected)
+	assert.Equal(t, 2, pool.Len())
+}
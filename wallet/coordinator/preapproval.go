@@ -0,0 +1,146 @@
+package coordinator
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// preApprovalDomain separates pre-approval tokens from approval signatures
+// and any other use of an approver's identity key.
+const preApprovalDomain = "cb-mpc/coordinator/pre-approval/v2"
+
+// defaultMaxPreApprovalTTL is the longest validity window a pre-approval
+// token may have.
+const defaultMaxPreApprovalTTL = 12 * time.Hour
+
+// ErrPreApproval is returned by Submit for a request carrying a pre-approval
+// token that is not valid.
+var ErrPreApproval = errors.New("coordinator: invalid pre-approval token")
+
+// PreApproval is a token, signed with an approver's identity key, approving
+// one transfer ahead of time: the intent (see chain.Intent) whose hash is
+// IntentHash, that is the transfer on Chain from the sender's key if it is
+// signed between NotBefore and NotAfter.  It binds the transfer rather than
+// the transaction, as chains such as Solana build a different transaction,
+// with a fresh blockhash, every time.  A request carrying it is approved by
+// that approver without waiting for them, so that a transfer reviewed during
+// business hours can be executed later.  It stands for one approval only;
+// policy still decides how many are needed and from whom.
+type PreApproval struct {
+	Approver   string    `json:"approver"`
+	Chain      string    `json:"chain"`
+	IntentHash []byte    `json:"intent_hash"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	Signature  []byte    `json:"signature"`
+}
+
+// SignPreApproval issues a pre-approval token on behalf of approver for
+// intent.  The intent's NotBefore and NotAfter are the token's validity
+// window and must both be set.
+func SignPreApproval(approver string, intent *chain.Intent, key ed25519.PrivateKey) (*PreApproval, error) {
+	if intent.NotBefore.IsZero() || intent.NotAfter.IsZero() {
+		return nil, fmt.Errorf("pre-approval must have a validity window")
+	}
+	hash, err := intent.Hash()
+	if err != nil {
+		return nil, err
+	}
+	p := &PreApproval{
+		Approver:   approver,
+		Chain:      intent.Chain,
+		IntentHash: hash,
+		NotBefore:  intent.NotBefore.UTC(),
+		NotAfter:   intent.NotAfter.UTC(),
+	}
+	p.Signature = ed25519.Sign(key, p.digest())
+	return p, nil
+}
+
+// digest binds every field but the signature.
+func (p *PreApproval) digest() []byte {
+	h := sha256.New()
+	for _, field := range [][]byte{[]byte(preApprovalDomain), []byte(p.Approver), []byte(p.Chain), p.IntentHash} {
+		writeField(h, field)
+	}
+	binary.Write(h, binary.BigEndian, p.NotBefore.UnixNano())
+	binary.Write(h, binary.BigEndian, p.NotAfter.UnixNano())
+	return h.Sum(nil)
+}
+
+// verify checks the token's signature against the approver's identity key.
+func (p *PreApproval) verify(key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("no identity key for approver %q", p.Approver)
+	}
+	if !ed25519.Verify(key, p.digest(), p.Signature) {
+		return fmt.Errorf("invalid pre-approval signature of %q", p.Approver)
+	}
+	return nil
+}
+
+// covers reports whether the token approves the decoded transaction, that is
+// whether the transaction's intent within the token's window is the one the
+// approver signed.
+func (p *PreApproval) covers(summary *chain.Summary) bool {
+	if summary == nil || p.Chain != summary.Chain {
+		return false
+	}
+	hash, err := chain.IntentOf(summary, summary.From, p.NotBefore, p.NotAfter).Hash()
+	return err == nil && bytes.Equal(p.IntentHash, hash)
+}
+
+// within reports whether now lies in the token's validity window.
+func (p *PreApproval) within(now time.Time) bool {
+	return !now.Before(p.NotBefore) && !now.After(p.NotAfter)
+}
+
+// verifyPreApprovals checks the tokens of req against keys and maxTTL.
+func verifyPreApprovals(req *Request, keys map[string]ed25519.PublicKey, maxTTL time.Duration) error {
+	if len(req.PreApprovals) > 0 && keys == nil {
+		return fmt.Errorf("%w: approver keys are not configured", ErrPreApproval)
+	}
+	for i := range req.PreApprovals {
+		p := &req.PreApprovals[i]
+		switch {
+		case p.Chain != req.Chain:
+			return fmt.Errorf("%w: token of %q is for chain %q", ErrPreApproval, p.Approver, p.Chain)
+		case !p.NotAfter.After(p.NotBefore):
+			return fmt.Errorf("%w: token of %q has an empty validity window", ErrPreApproval, p.Approver)
+		case p.NotAfter.Sub(p.NotBefore) > maxTTL:
+			return fmt.Errorf("%w: token of %q is valid for longer than %v", ErrPreApproval, p.Approver, maxTTL)
+		}
+		if err := p.verify(keys[p.Approver]); err != nil {
+			return fmt.Errorf("%w: %v", ErrPreApproval, err)
+		}
+	}
+	return nil
+}
+
+// redeemable returns a pre-approval of the session's request that covers its
+// current transaction at now, from an eligible approver who has not
+// approved yet, or nil.
+func (s *Session) redeemable(now time.Time) *PreApproval {
+	if s.Unsigned == nil || s.Decision == nil {
+		return nil
+	}
+	for i := range s.Request.PreApprovals {
+		p := &s.Request.PreApprovals[i]
+		switch {
+		case !p.covers(s.Summary):
+		case !p.within(now):
+		case contains(s.Approvals, p.Approver):
+		case len(s.Decision.Approvers) > 0 && !contains(s.Decision.Approvers, p.Approver):
+		default:
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package coordinator
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/clock"
+)
+
+// preApprove issues a token of approver for a transfer of amount from alice
+// to bob, valid from notBefore until notAfter.
+func preApprove(t *testing.T, approver string, amount int64, notBefore, notAfter time.Time, key ed25519.PrivateKey) *PreApproval {
+	t.Helper()
+	p, err := SignPreApproval(approver, &chain.Intent{
+		Chain:     "fake",
+		KeyID:     "alice",
+		Outputs:   []chain.Output{{To: "bob", Amount: big.NewInt(amount)}},
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+	}, key)
+	require.NoError(t, err)
+	return p
+}
+
+func TestPreApprovalRedeemedOffHours(t *testing.T) {
+	ctx := context.Background()
+	keys := map[string]ed25519.PublicKey{}
+	private := map[string]ed25519.PrivateKey{}
+	for _, name := range []string{"kms", "carol", "dave"} {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		keys[name], private[name] = pub, priv
+	}
+	var seen *SignRequest
+	now := clock.NewFake(epoch)
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer: RequireApprovals(signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+			seen = req
+			return (&fakeSigner{}).Sign(ctx, req)
+		}), keys, 2, now.Now),
+		Policy: PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+			return &Decision{Allow: true, RequiredApprovals: 2, Approvers: []string{"kms", "carol"}}, nil
+		}),
+		ConfirmInterval: time.Millisecond,
+		ApproverKeys:    keys,
+		Now:             now.Now,
+	})
+	require.NoError(t, err)
+
+	// The transaction is reviewed during business hours; the tokens are
+	// valid overnight.
+	night := epoch.Add(10 * time.Hour)
+	req := *testRequest
+	req.PreApprovals = []PreApproval{
+		*preApprove(t, "kms", 1, night, night.Add(8*time.Hour), private["kms"]),
+		*preApprove(t, "carol", 1, night, night.Add(8*time.Hour), private["carol"]),
+	}
+	now.Advance(11 * time.Hour)
+	s, err := c.Submit(ctx, &req)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+	assert.Equal(t, []string{"kms", "carol"}, s.Approvals)
+	require.Len(t, s.PreApprovals, 2)
+
+	// The parties counted the tokens, and they are kept as evidence.
+	require.NotNil(t, seen)
+	assert.NoError(t, VerifyApprovals(seen, keys, 2, now.Now()))
+	evidence, err := s.Evidence()
+	require.NoError(t, err)
+	assert.NoError(t, VerifyApprovals(evidence.SignRequest(), keys, 2, now.Now()))
+
+	// A token does not approve any other transfer, and parties do not count
+	// it outside its window, whatever the coordinator's clock said.
+	tampered := *seen
+	tampered.Summary = &chain.Summary{Chain: "fake", From: "alice", To: "mallory", Amount: big.NewInt(1000)}
+	assert.ErrorIs(t, VerifyApprovals(&tampered, keys, 2, now.Now()), ErrApprovalSignatures)
+	assert.ErrorIs(t, VerifyApprovals(seen, keys, 2, night.Add(9*time.Hour)), ErrApprovalSignatures)
+	assert.ErrorIs(t, VerifyApprovals(seen, keys, 2, epoch), ErrApprovalSignatures)
+}
+
+// blockhashChain builds a different transaction for the same transfer every
+// time, as Solana does with a fresh blockhash.
+type blockhashChain struct {
+	fakeChain
+	builds int
+}
+
+func (b *blockhashChain) BuildTransfer(ctx context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	tx, err := b.fakeChain.BuildTransfer(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	b.builds++
+	tx.Payload = fmt.Appendf(tx.Payload, "|blockhash-%d", b.builds)
+	tx.SigningPayload = tx.Payload
+	return tx, nil
+}
+
+func TestPreApprovalCoversRebuiltTransactions(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	keys := map[string]ed25519.PublicKey{"kms": pub}
+	var payloads [][]byte
+	now := clock.NewFake(epoch)
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&blockhashChain{fakeChain: fakeChain{status: chain.StatusFinalized}}},
+		Signer: RequireApprovals(signerFunc(func(ctx context.Context, req *SignRequest) ([]byte, error) {
+			payloads = append(payloads, req.Payload)
+			return (&fakeSigner{}).Sign(ctx, req)
+		}), keys, 1, now.Now),
+		Policy: PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+			return &Decision{Allow: true, RequiredApprovals: 1, Approvers: []string{"kms"}}, nil
+		}),
+		ConfirmInterval: time.Millisecond,
+		ApproverKeys:    keys,
+		Now:             now.Now,
+	})
+	require.NoError(t, err)
+
+	// The token is issued before any transaction is built, and covers each
+	// of the differently built transactions of the transfer.
+	req := *testRequest
+	req.PreApprovals = []PreApproval{*preApprove(t, "kms", 1, epoch, epoch.Add(time.Hour), priv)}
+	for range 2 {
+		s, err := c.Submit(ctx, &req)
+		require.NoError(t, err)
+		s, err = c.Run(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StateFinalized, s.State)
+		assert.Equal(t, []string{"kms"}, s.Approvals)
+	}
+	require.Len(t, payloads, 2)
+	assert.NotEqual(t, payloads[0], payloads[1])
+}
+
+func TestPreApprovalIsPartial(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	carolPub, carolPriv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	now := clock.NewFake(epoch)
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer: &fakeSigner{},
+		Policy: PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+			return &Decision{Allow: true, RequiredApprovals: 2, Approvers: []string{"kms", "carol"}}, nil
+		}),
+		ConfirmInterval: time.Millisecond,
+		ApproverKeys:    map[string]ed25519.PublicKey{"kms": pub, "carol": carolPub},
+		Now:             now.Now,
+	})
+	require.NoError(t, err)
+
+	req := *testRequest
+	req.PreApprovals = []PreApproval{*preApprove(t, "kms", 1, epoch, epoch.Add(time.Hour), priv)}
+	s, err := c.Submit(ctx, &req)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+
+	// The token stands for one approval; policy still wants another.
+	assert.Equal(t, StatePolicyEvaluated, s.State)
+	assert.Equal(t, []string{"kms"}, s.Approvals)
+	assert.Equal(t, []string{"carol"}, s.PendingApprovers())
+	a, err := SignApproval(s, "carol", carolPriv)
+	require.NoError(t, err)
+	s, err = c.ApproveSigned(ctx, s.ID, a)
+	require.NoError(t, err)
+	assert.Equal(t, StateApproved, s.State)
+}
+
+func TestPreApprovalNotRedeemed(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	now := clock.NewFake(epoch)
+	c, err := New(Config{
+		Store:  NewMemoryStore(),
+		Chains: []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer: &fakeSigner{},
+		Policy: PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+			return &Decision{Allow: true, RequiredApprovals: 1, Approvers: []string{"kms"}}, nil
+		}),
+		ConfirmInterval: time.Millisecond,
+		ApproverKeys:    map[string]ed25519.PublicKey{"kms": pub},
+		Now:             now.Now,
+	})
+	require.NoError(t, err)
+
+	for name, token := range map[string]*PreApproval{
+		"expired":          preApprove(t, "kms", 1, epoch.Add(-2*time.Hour), epoch.Add(-time.Hour), priv),
+		"not yet valid":    preApprove(t, "kms", 1, epoch.Add(time.Hour), epoch.Add(2*time.Hour), priv),
+		"another transfer": preApprove(t, "kms", 2, epoch, epoch.Add(time.Hour), priv),
+	} {
+		req := *testRequest
+		req.PreApprovals = []PreApproval{*token}
+		s, err := c.Submit(ctx, &req)
+		require.NoError(t, err, name)
+		s, err = c.Run(ctx, s.ID)
+		require.NoError(t, err, name)
+		assert.Equal(t, StatePolicyEvaluated, s.State, name)
+		assert.Empty(t, s.Approvals, name)
+	}
+}
+
+func TestSubmitRejectsInvalidPreApprovals(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, other, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	c, err := New(Config{
+		Store:             NewMemoryStore(),
+		Chains:            []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer:            &fakeSigner{},
+		ApproverKeys:      map[string]ed25519.PublicKey{"kms": pub},
+		MaxPreApprovalTTL: 4 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	forged := preApprove(t, "kms", 1, epoch, epoch.Add(time.Hour), priv)
+	forged.NotAfter = forged.NotAfter.Add(time.Hour)
+	otherChain, err := SignPreApproval("kms", &chain.Intent{
+		Chain:     "solana",
+		KeyID:     "alice",
+		Outputs:   []chain.Output{{To: "bob", Amount: big.NewInt(1)}},
+		NotBefore: epoch,
+		NotAfter:  epoch.Add(time.Hour),
+	}, priv)
+	require.NoError(t, err)
+	for name, token := range map[string]*PreApproval{
+		"wrong key":   preApprove(t, "kms", 1, epoch, epoch.Add(time.Hour), other),
+		"unknown":     preApprove(t, "mallory", 1, epoch, epoch.Add(time.Hour), other),
+		"too long":    preApprove(t, "kms", 1, epoch, epoch.Add(5*time.Hour), priv),
+		"other chain": otherChain,
+		"extended":    forged,
+	} {
+		req := *testRequest
+		req.PreApprovals = []PreApproval{*token}
+		_, err := c.Submit(ctx, &req)
+		assert.ErrorIs(t, err, ErrPreApproval, name)
+	}
+
+	// Without approver keys no token can be checked.
+	c, err = New(Config{Store: NewMemoryStore(), Chains: []chain.Chain{&fakeChain{}}, Signer: &fakeSigner{}})
+	require.NoError(t, err)
+	req := *testRequest
+	req.PreApprovals = []PreApproval{*preApprove(t, "kms", 1, epoch, epoch.Add(time.Hour), priv)}
+	_, err = c.Submit(ctx, &req)
+	assert.ErrorIs(t, err, ErrPreApproval)
+}
@@ -0,0 +1,148 @@
+package coordinator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyAlpha is the weight of a new sample in the moving averages.
+	latencyAlpha = 0.2
+	// minAvailability keeps quorum scores finite for parties that keep
+	// failing.
+	minAvailability = 0.01
+)
+
+// PartyError attributes a signing failure to a specific party, e.g. one that
+// stalled in a protocol round.  Signers return it so the coordinator can fail
+// over to a quorum without that party.
+type PartyError struct {
+	Party string
+	Err   error
+}
+
+func (e *PartyError) Error() string { return fmt.Sprintf("party %s: %v", e.Party, e.Err) }
+
+func (e *PartyError) Unwrap() error { return e.Err }
+
+// PartyStats summarises the measured behaviour of a party.
+type PartyStats struct {
+	Latency      time.Duration // Moving average of observed round latencies
+	Availability float64       // Moving average of successes, between 0 and 1
+	Samples      int           // Number of observations
+}
+
+// LatencyTracker keeps exponentially weighted latency and availability
+// statistics per party and ranks candidate quorums by them.  It is fed by
+// whatever measures the parties – typically the Signer, from per-round
+// timings, and the coordinator itself from PartyErrors.
+type LatencyTracker struct {
+	mu    sync.Mutex
+	stats map[string]*PartyStats
+}
+
+// NewLatencyTracker creates an empty LatencyTracker.
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{stats: make(map[string]*PartyStats)}
+}
+
+// Observe records a successful interaction with party that took latency.
+func (t *LatencyTracker) Observe(party string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.stats[party]
+	if !ok {
+		t.stats[party] = &PartyStats{Latency: latency, Availability: 1, Samples: 1}
+		return
+	}
+	st.Latency = time.Duration((1-latencyAlpha)*float64(st.Latency) + latencyAlpha*float64(latency))
+	st.Availability = (1-latencyAlpha)*st.Availability + latencyAlpha
+	st.Samples++
+}
+
+// ObserveFailure records that party failed to respond.
+func (t *LatencyTracker) ObserveFailure(party string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.stats[party]
+	if !ok {
+		st = &PartyStats{Availability: 1}
+		t.stats[party] = st
+	}
+	st.Availability *= 1 - latencyAlpha
+	st.Samples++
+}
+
+// Stats returns the statistics of party, if any were observed.
+func (t *LatencyTracker) Stats(party string) (PartyStats, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.stats[party]
+	if !ok {
+		return PartyStats{}, false
+	}
+	return *st, true
+}
+
+// Rank returns quorums ordered from most to least preferred.  A quorum is as
+// fast as its slowest member and is penalised by the unavailability of each
+// member.  Parties without observations are assumed available and as slow as
+// the slowest measured party, so they are neither favoured nor starved.
+func (t *LatencyTracker) Rank(quorums [][]string) [][]string {
+	t.mu.Lock()
+	var slowest time.Duration
+	for _, st := range t.stats {
+		if st.Latency > slowest {
+			slowest = st.Latency
+		}
+	}
+	scores := make([]float64, len(quorums))
+	for i, q := range quorums {
+		var latency time.Duration
+		availability := 1.0
+		for _, party := range q {
+			l, a := slowest, 1.0
+			if st, ok := t.stats[party]; ok {
+				l, a = st.Latency, st.Availability
+			}
+			if l > latency {
+				latency = l
+			}
+			if a < minAvailability {
+				a = minAvailability
+			}
+			availability *= a
+		}
+		scores[i] = float64(latency+time.Millisecond) / availability
+	}
+	t.mu.Unlock()
+
+	order := make([]int, len(quorums))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if scores[a] != scores[b] {
+			return scores[a] < scores[b]
+		}
+		return len(quorums[a]) < len(quorums[b])
+	})
+	out := make([][]string, len(quorums))
+	for i, idx := range order {
+		out[i] = quorums[idx]
+	}
+	return out
+}
+
+// excludes reports whether quorum contains none of the failed parties.
+func excludes(quorum []string, failed map[string]bool) bool {
+	for _, party := range quorum {
+		if failed[party] {
+			return false
+		}
+	}
+	return true
+}
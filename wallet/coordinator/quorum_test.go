@@ -0,0 +1,98 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+var twoOfThree = [][]string{{"kms", "pin"}, {"kms", "server"}, {"pin", "server"}}
+
+func TestRankPrefersFastAvailableQuorum(t *testing.T) {
+	tracker := NewLatencyTracker()
+	tracker.Observe("server", 5*time.Millisecond)
+	tracker.Observe("kms", 20*time.Millisecond)
+	tracker.Observe("pin", 900*time.Millisecond)
+
+	ranked := tracker.Rank(twoOfThree)
+	assert.Equal(t, []string{"kms", "server"}, ranked[0])
+
+	for i := 0; i < 20; i++ {
+		tracker.ObserveFailure("kms")
+	}
+	ranked = tracker.Rank(twoOfThree)
+	assert.Equal(t, []string{"pin", "server"}, ranked[0])
+
+	st, ok := tracker.Stats("kms")
+	require.True(t, ok)
+	assert.Less(t, st.Availability, 0.05)
+}
+
+// quorumSigner fails with a PartyError whenever the quorum contains down.
+type quorumSigner struct {
+	down     string
+	attempts [][]string
+}
+
+func (q *quorumSigner) Sign(_ context.Context, req *SignRequest) ([]byte, error) {
+	q.attempts = append(q.attempts, req.Quorum)
+	if err := req.Progress(1); err != nil {
+		return nil, err
+	}
+	for _, party := range req.Quorum {
+		if party == q.down {
+			return nil, &PartyError{Party: party, Err: errors.New("stalled in round 1")}
+		}
+	}
+	return []byte("sig"), nil
+}
+
+func TestSignFailsOverToQuorumWithoutFailedParty(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewLatencyTracker()
+	tracker.Observe("server", time.Millisecond)
+	tracker.Observe("kms", time.Millisecond)
+	tracker.Observe("pin", time.Second)
+	signer := &quorumSigner{down: "kms"}
+
+	c, err := New(Config{
+		Store:           NewMemoryStore(),
+		Chains:          []chain.Chain{&fakeChain{status: chain.StatusFinalized}},
+		Signer:          signer,
+		Quorums:         twoOfThree,
+		Latency:         tracker,
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, StateFinalized, s.State)
+	assert.Equal(t, [][]string{{"kms", "server"}, {"pin", "server"}}, signer.attempts)
+	assert.Equal(t, []string{"pin", "server"}, s.Quorum)
+}
+
+func TestSignGivesUpWithoutPartyError(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(Config{
+		Store:   NewMemoryStore(),
+		Chains:  []chain.Chain{&fakeChain{}},
+		Signer:  &fakeSigner{rounds: 2, failRound: 1},
+		Quorums: twoOfThree,
+	})
+	require.NoError(t, err)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	_, err = c.Run(ctx, s.ID)
+	assert.Error(t, err)
+}
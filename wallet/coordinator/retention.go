@@ -0,0 +1,166 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// defaultPurgeInterval is how often a Purger looks for expired sessions.
+const defaultPurgeInterval = time.Hour
+
+// ErrLegalHold is returned when deleting a session that is under legal hold.
+var ErrLegalHold = errors.New("coordinator: session is under legal hold")
+
+// PurgeStore is a Store whose sessions can be deleted and placed under legal
+// hold.
+//
+// A held session must never be deleted: Delete returns ErrLegalHold for it,
+// even if the hold was placed after the caller decided to delete.
+type PurgeStore interface {
+	Store
+	// Delete removes the log of a session.  Deleting a session that does
+	// not exist is not an error.
+	Delete(ctx context.Context, session string) error
+	// SetHold places a stored session under legal hold, or releases it.
+	SetHold(ctx context.Context, session string, hold bool) error
+	// Holds returns the IDs of all sessions under legal hold.
+	Holds(ctx context.Context) ([]string, error)
+}
+
+// Retention is how long finished sessions are kept after their last event,
+// by the state they ended in.  Zero keeps them forever.  Sessions that have
+// not finished are never purged.
+type Retention struct {
+	Finalized time.Duration
+	Failed    time.Duration
+}
+
+// period returns the retention period of sessions in state s.
+func (r Retention) period(s State) time.Duration {
+	switch s {
+	case StateFinalized:
+		return r.Finalized
+	case StateFailed:
+		return r.Failed
+	default:
+		return 0
+	}
+}
+
+// PurgerConfig contains the configuration for a Purger.
+type PurgerConfig struct {
+	// Store holds the sessions.  Required.
+	Store PurgeStore
+	// Retention selects the sessions to delete.
+	Retention Retention
+	// Interval is how often Run purges.  Defaults to 1h.
+	Interval time.Duration
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+	// OnPurge, if set, is called with the outcome of every purge run by
+	// Run.
+	OnPurge func(r *PurgeReport, err error)
+}
+
+// PurgeReport is the outcome of one purge.
+type PurgeReport struct {
+	Time   time.Time
+	Purged []string // Sessions deleted
+	Held   []string // Expired sessions kept because of a legal hold
+}
+
+// Purger deletes finished sessions once their retention period is over,
+// sparing those under legal hold.
+type Purger struct {
+	config PurgerConfig
+}
+
+// NewPurger creates a Purger.  It does nothing until Run or Purge is called.
+func NewPurger(config PurgerConfig) (*Purger, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("store must be provided")
+	}
+	if config.Retention.Finalized < 0 || config.Retention.Failed < 0 {
+		return nil, fmt.Errorf("retention periods cannot be negative")
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultPurgeInterval
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Purger{config: config}, nil
+}
+
+// Run purges immediately and then every Interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+	for {
+		r, err := p.Purge(ctx)
+		if ctx.Err() == nil && p.config.OnPurge != nil {
+			p.config.OnPurge(r, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes every session whose retention period is over.  On error the
+// report lists the sessions deleted so far.
+func (p *Purger) Purge(ctx context.Context) (*PurgeReport, error) {
+	now := p.config.Now()
+	r := &PurgeReport{Time: now}
+	held, err := p.config.Store.Holds(ctx)
+	if err != nil {
+		return r, err
+	}
+	onHold := make(map[string]bool, len(held))
+	for _, id := range held {
+		onHold[id] = true
+	}
+	ids, err := p.config.Store.Sessions(ctx)
+	if err != nil {
+		return r, err
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return r, err
+		}
+		events, err := p.config.Store.Events(ctx, id)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		}
+		if err != nil {
+			return r, err
+		}
+		s, err := Replay(events)
+		if err != nil {
+			return r, fmt.Errorf("session %s: %w", id, err)
+		}
+		keep := p.config.Retention.period(s.State)
+		if keep == 0 || now.Sub(s.UpdatedAt) < keep {
+			continue
+		}
+		if onHold[id] {
+			r.Held = append(r.Held, id)
+			continue
+		}
+		switch err := p.config.Store.Delete(ctx, id); {
+		case errors.Is(err, ErrLegalHold):
+			r.Held = append(r.Held, id)
+		case err != nil:
+			return r, fmt.Errorf("deleting session %s: %w", id, err)
+		default:
+			r.Purged = append(r.Purged, id)
+		}
+	}
+	return r, nil
+}
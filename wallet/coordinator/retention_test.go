@@ -0,0 +1,69 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurger(t *testing.T) {
+	file, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	for name, store := range map[string]PurgeStore{"memory": NewMemoryStore(), "file": file} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			add := func(id string, events ...Event) {
+				for i := range events {
+					events[i].Session, events[i].Seq, events[i].Time = id, uint64(i+1), start
+					require.NoError(t, store.Append(ctx, &events[i]))
+				}
+			}
+			created := Event{Type: EventCreated, Request: testRequest}
+			add("failed", created, Event{Type: EventFailed, Err: "rejected"})
+			add("held", created, Event{Type: EventFailed, Err: "rejected"})
+			add("pending", created)
+
+			assert.ErrorIs(t, store.SetHold(ctx, "missing", true), ErrSessionNotFound)
+			require.NoError(t, store.SetHold(ctx, "held", true))
+			require.NoError(t, store.SetHold(ctx, "held", true))
+			assert.ErrorIs(t, store.Delete(ctx, "held"), ErrLegalHold)
+
+			now := start.Add(24 * time.Hour)
+			p, err := NewPurger(PurgerConfig{Store: store, Retention: Retention{Failed: 30 * 24 * time.Hour}, Now: func() time.Time { return now }})
+			require.NoError(t, err)
+
+			r, err := p.Purge(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, r.Purged)
+			assert.Empty(t, r.Held)
+
+			now = start.Add(31 * 24 * time.Hour)
+			r, err = p.Purge(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"failed"}, r.Purged)
+			assert.Equal(t, []string{"held"}, r.Held)
+			ids, err := store.Sessions(ctx)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"held", "pending"}, ids)
+
+			// Releasing the hold lets the next run delete the session.
+			require.NoError(t, store.SetHold(ctx, "held", false))
+			holds, err := store.Holds(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, holds)
+			r, err = p.Purge(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"held"}, r.Purged)
+			_, err = store.Events(ctx, "held")
+			assert.ErrorIs(t, err, ErrSessionNotFound)
+			require.NoError(t, store.Delete(ctx, "held"))
+		})
+	}
+
+	_, err = NewPurger(PurgerConfig{Store: file, Retention: Retention{Finalized: -time.Hour}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,566 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/ivms101"
+	"solana-threshold-wallet/wallet/price"
+)
+
+// ErrInvalidTransition is returned when an event is not allowed in the
+// session's current state.
+var ErrInvalidTransition = errors.New("coordinator: invalid state transition")
+
+// State is the lifecycle stage of a signing session.
+type State uint8
+
+const (
+	// StateCreated means the request has been accepted but not yet evaluated,
+	// or that its transaction expired and has to be rebuilt.
+	StateCreated State = iota
+	// StatePolicyEvaluated means the transaction was built and allowed by
+	// policy and is waiting for observer reviews and approvals.
+	StatePolicyEvaluated
+	// StateApproved means enough approvals were collected to start signing.
+	StateApproved
+	// StateRoundsInProgress means the MPC signing protocol is running;
+	// Session.Round holds the current round.
+	StateRoundsInProgress
+	// StateSigned means a signature was produced but not yet broadcast.
+	StateSigned
+	// StateBroadcast means the transaction was submitted to the network.
+	StateBroadcast
+	// StateFinalized means the transaction reached finality.
+	StateFinalized
+	// StateFailed means the session was aborted; Session.Err tells why.
+	StateFailed
+)
+
+// String returns the symbolic name of the State.
+func (s State) String() string {
+	switch s {
+	case StateCreated:
+		return "created"
+	case StatePolicyEvaluated:
+		return "policy-evaluated"
+	case StateApproved:
+		return "approved"
+	case StateRoundsInProgress:
+		return "rounds-in-progress"
+	case StateSigned:
+		return "signed"
+	case StateBroadcast:
+		return "broadcast"
+	case StateFinalized:
+		return "finalized"
+	case StateFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Terminal reports whether no further transitions are possible.
+func (s State) Terminal() bool {
+	return s == StateFinalized || s == StateFailed
+}
+
+// EventType identifies a state transition.
+type EventType string
+
+const (
+	EventCreated         EventType = "created"
+	EventPolicyEvaluated EventType = "policy-evaluated"
+	EventReviewed        EventType = "reviewed"
+	EventApprovalAsked   EventType = "approval-requested"
+	EventApproved        EventType = "approved"
+	EventApprovalExpired EventType = "approval-expired"
+	EventRoundStarted    EventType = "round-started"
+	EventSigned          EventType = "signed"
+	EventBroadcast       EventType = "broadcast"
+	EventExpired         EventType = "expired"
+	EventReapproved      EventType = "reapproved"
+	EventFinalized       EventType = "finalized"
+	EventFailed          EventType = "failed"
+)
+
+// Priority orders sessions in the worker pool and in party queues.  Higher
+// values run first; the zero value is PriorityNormal.
+type Priority int8
+
+const (
+	// PriorityBatch is for bulk jobs such as batch payouts.
+	PriorityBatch Priority = -1
+	// PriorityNormal is for regular operations such as user withdrawals.
+	PriorityNormal Priority = 0
+	// PriorityUrgent is for emergency operations such as sweeps.
+	PriorityUrgent Priority = 1
+)
+
+// String returns the symbolic name of the Priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityBatch:
+		return "batch"
+	case PriorityNormal:
+		return "normal"
+	case PriorityUrgent:
+		return "urgent"
+	default:
+		return fmt.Sprintf("priority(%d)", int8(p))
+	}
+}
+
+// Request describes what a session should sign.
+type Request struct {
+	Chain    string         // Identifier of the chain.Chain to use
+	Transfer chain.Transfer // Transfer to build, sign and broadcast
+	Priority Priority       // Scheduling class of the session
+	// MaxFee, if set, fails the session when the built transaction's
+	// maximum fee exceeds it.
+	MaxFee *big.Int
+	// Schedule is the ID of the recurring schedule that submitted the
+	// request, if any.  Policies may treat such requests differently.
+	Schedule string
+	// Reference is the submitter's own identifier for the request, e.g. an
+	// idempotency key, so that it can find the session again.
+	Reference string
+	// Tenant identifies the customer the request is made for, so that
+	// usage can be metered and billed per tenant.
+	Tenant string
+	// TravelRule carries the originator and beneficiary data that regulated
+	// providers exchange for the transfer.  Like the rest of the request it
+	// is recorded in the session's created event.
+	TravelRule *ivms101.Payload
+	// Metadata is signed business context, such as an order ID, that is
+	// carried through approvals, the Signer and the session's Evidence.
+	Metadata *Metadata
+	// PreApprovals are approvals issued ahead of time for the transfer the
+	// request is expected to build (see SignPreApproval).  Each counts as
+	// its approver's approval if it covers the built transaction while it
+	// is valid.
+	PreApprovals []PreApproval
+	// NotAfter, if set, is when the request lapses: it is not signed later,
+	// and parties refuse to sign it later (see VerifyIntent).
+	NotAfter time.Time
+}
+
+// Decision is the outcome of a policy evaluation.
+type Decision struct {
+	Allow             bool     // Whether the transaction may be signed at all
+	Reason            string   // Human-readable explanation, mandatory on denial
+	RequiredApprovals int      // Number of distinct approvals needed before signing
+	Reviewers         []string // Observers whose review is required; any of them may veto
+	// Approvers lists who may approve the session and is notified when it
+	// waits for approval.  When empty, anyone may approve.
+	Approvers []string
+	// ApprovalTimeout overrides Config.ApprovalTimeout for this session.
+	ApprovalTimeout time.Duration
+	// Quotes are the prices the decision was based on, if the policy values
+	// transfers in fiat.  They are passed to the parties in
+	// SignRequest.Quotes so that they evaluate against the same prices.
+	Quotes []*price.Quote
+}
+
+// Review is an observer's verdict on a session.
+type Review struct {
+	Reviewer string // Observer name
+	Veto     bool   // Whether the observer blocks the session
+	Reason   string // Explanation, mandatory on veto
+}
+
+// Event is a single persisted transition.  Only the fields relevant to Type
+// are populated.
+type Event struct {
+	Session string    `json:"session"`
+	Seq     uint64    `json:"seq"` // 1-based position in the session's log
+	Time    time.Time `json:"time"`
+	Type    EventType `json:"type"`
+
+	Request   *Request          `json:"request,omitempty"`   // EventCreated
+	Unsigned  *chain.UnsignedTx `json:"unsigned,omitempty"`  // EventPolicyEvaluated
+	Summary   *chain.Summary    `json:"summary,omitempty"`   // EventPolicyEvaluated
+	Decision  *Decision         `json:"decision,omitempty"`  // EventPolicyEvaluated, EventFailed on denial
+	Deadline  *time.Time        `json:"deadline,omitempty"`  // EventPolicyEvaluated, when approvals are required
+	Review    *Review           `json:"review,omitempty"`    // EventReviewed
+	Approver  string            `json:"approver,omitempty"`  // EventApproved
+	Round     int               `json:"round,omitempty"`     // EventRoundStarted
+	Quorum    []string          `json:"quorum,omitempty"`    // EventRoundStarted, round 1
+	Signature []byte            `json:"signature,omitempty"` // EventSigned
+	Transport *Transport        `json:"transport,omitempty"` // Optionally EventSigned
+	TxID      string            `json:"tx_id,omitempty"`     // EventBroadcast, optionally EventExpired
+	Receipt   *chain.Receipt    `json:"receipt,omitempty"`   // EventFinalized, optionally EventFailed
+	Err       string            `json:"err,omitempty"`       // EventFailed, EventApprovalExpired
+
+	// ApprovalSignature is the approver's signature on the approval digest,
+	// for EventApproved when approvals must be signed.
+	ApprovalSignature *ApprovalSignature `json:"approval_signature,omitempty"`
+	// PreApproval is the redeemed token, for EventApproved recorded from one
+	// of Request.PreApprovals.
+	PreApproval *PreApproval `json:"pre_approval,omitempty"`
+}
+
+// Session is the materialised view of a session's event log.
+type Session struct {
+	ID        string
+	State     State
+	Version   uint64 // Seq of the last applied event
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Request   Request
+	Unsigned  *chain.UnsignedTx
+	Summary   *chain.Summary
+	Decision  *Decision
+	Reviews   []Review
+	Approvals []string
+	Round     int
+	Quorum    []string // Parties of the current signing attempt, if chosen by the coordinator
+	Signature []byte
+	Transport *Transport // Channel parameters of the signing session, if reported
+	TxID      string
+	Receipt   *chain.Receipt
+	Err       string
+
+	// ApprovalDeadline is when the session is cancelled if it is still
+	// waiting for approvals; zero if it needs none.
+	ApprovalDeadline time.Time
+	// ApprovalAsked reports whether the approvers were notified.
+	ApprovalAsked bool
+	// ApprovalSignatures holds the signed approvals of the current
+	// transaction.  Kept with Signature, they prove who approved what.
+	ApprovalSignatures []ApprovalSignature
+	// PreApprovals holds the pre-approval tokens redeemed for the current
+	// transaction.
+	PreApprovals []PreApproval
+
+	// Attempt counts how often the transaction expired and was rebuilt.
+	Attempt int
+	// Previous and PreviousApprovals describe the last expired attempt; they
+	// decide whether the rebuilt transaction may be approved automatically.
+	Previous          *chain.Summary
+	PreviousApprovals []string
+}
+
+// Replay rebuilds a session from its event log.
+func Replay(events []Event) (*Session, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("empty event log")
+	}
+	s := &Session{}
+	for i := range events {
+		if err := s.apply(&events[i]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// apply validates e against the current state and folds it into s.
+func (s *Session) apply(e *Event) error {
+	if e.Seq != s.Version+1 {
+		return fmt.Errorf("%w: event %d follows version %d", ErrInvalidTransition, e.Seq, s.Version)
+	}
+	if s.Version > 0 && e.Session != s.ID {
+		return fmt.Errorf("%w: event for session %q applied to %q", ErrInvalidTransition, e.Session, s.ID)
+	}
+	if err := s.check(e); err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case EventCreated:
+		s.ID = e.Session
+		s.Request = *e.Request
+		s.CreatedAt = e.Time
+		s.State = StateCreated
+	case EventPolicyEvaluated:
+		s.Unsigned, s.Summary, s.Decision = e.Unsigned, e.Summary, e.Decision
+		s.ApprovalDeadline, s.ApprovalAsked = time.Time{}, false
+		if e.Deadline != nil {
+			s.ApprovalDeadline = *e.Deadline
+		}
+		s.State = StatePolicyEvaluated
+	case EventReviewed:
+		s.Reviews = append(s.Reviews, *e.Review)
+		if s.ready() {
+			s.State = StateApproved
+		}
+	case EventApprovalAsked:
+		s.ApprovalAsked = true
+	case EventApproved:
+		s.Approvals = append(s.Approvals, e.Approver)
+		if e.ApprovalSignature != nil {
+			s.ApprovalSignatures = append(s.ApprovalSignatures, *e.ApprovalSignature)
+		}
+		if e.PreApproval != nil {
+			s.PreApprovals = append(s.PreApprovals, *e.PreApproval)
+		}
+		if s.ready() {
+			s.State = StateApproved
+		}
+	case EventApprovalExpired:
+		s.Err = e.Err
+		s.State = StateFailed
+	case EventRoundStarted:
+		s.Round = e.Round
+		if e.Round == 1 {
+			s.Quorum = e.Quorum
+		}
+		s.State = StateRoundsInProgress
+	case EventSigned:
+		s.Signature = e.Signature
+		s.Transport = e.Transport
+		s.State = StateSigned
+	case EventBroadcast:
+		s.TxID = e.TxID
+		s.State = StateBroadcast
+	case EventExpired:
+		s.Attempt++
+		s.Previous, s.PreviousApprovals = s.Summary, s.Approvals
+		s.Unsigned, s.Summary, s.Decision = nil, nil, nil
+		s.Reviews, s.Approvals, s.ApprovalSignatures, s.PreApprovals = nil, nil, nil, nil
+		s.ApprovalDeadline, s.ApprovalAsked = time.Time{}, false
+		s.Round, s.Quorum, s.Signature, s.TxID = 0, nil, nil, ""
+		s.State = StateCreated
+	case EventReapproved:
+		s.Approvals = append([]string(nil), s.PreviousApprovals...)
+		s.State = StateApproved
+	case EventFinalized:
+		s.Receipt = e.Receipt
+		s.State = StateFinalized
+	case EventFailed:
+		s.Receipt, s.Err = e.Receipt, e.Err
+		if e.Decision != nil {
+			s.Decision = e.Decision
+		}
+		s.State = StateFailed
+	}
+	s.Version = e.Seq
+	s.UpdatedAt = e.Time
+	return nil
+}
+
+// check reports whether e is a legal transition from the current state.
+func (s *Session) check(e *Event) error {
+	invalid := func(detail string) error {
+		return fmt.Errorf("%w: %s in state %s: %s", ErrInvalidTransition, e.Type, s.State, detail)
+	}
+	if s.Version == 0 {
+		if e.Type != EventCreated {
+			return invalid("session must start with a created event")
+		}
+		if e.Request == nil {
+			return invalid("missing request")
+		}
+		return nil
+	}
+	if s.State.Terminal() {
+		return invalid("session is terminal")
+	}
+
+	switch e.Type {
+	case EventPolicyEvaluated:
+		if s.State != StateCreated {
+			return invalid("policy already evaluated")
+		}
+		if e.Unsigned == nil || e.Summary == nil || e.Decision == nil {
+			return invalid("missing transaction or decision")
+		}
+		if !e.Decision.Allow {
+			return invalid("denied sessions must fail instead")
+		}
+	case EventReviewed:
+		if s.State != StatePolicyEvaluated {
+			return invalid("session is not awaiting review")
+		}
+		if e.Review == nil || e.Review.Reviewer == "" {
+			return invalid("missing review")
+		}
+		if e.Review.Veto && e.Review.Reason == "" {
+			return invalid("veto without reason")
+		}
+		if !contains(s.PendingReviewers(), e.Review.Reviewer) {
+			return invalid(fmt.Sprintf("%q is not a pending reviewer", e.Review.Reviewer))
+		}
+	case EventApprovalAsked:
+		if s.State != StatePolicyEvaluated {
+			return invalid("session is not awaiting approval")
+		}
+		if s.ApprovalAsked {
+			return invalid("approval already requested")
+		}
+	case EventApproved:
+		if s.State != StatePolicyEvaluated {
+			return invalid("session is not awaiting approval")
+		}
+		if e.Approver == "" {
+			return invalid("missing approver")
+		}
+		if e.ApprovalSignature != nil && e.ApprovalSignature.Approver != e.Approver {
+			return invalid("approval signed by another approver")
+		}
+		if p := e.PreApproval; p != nil {
+			if p.Approver != e.Approver {
+				return invalid("pre-approval issued by another approver")
+			}
+			if !p.covers(s.Summary) {
+				return invalid("pre-approval is for another transaction")
+			}
+			if !p.within(e.Time) {
+				return invalid("pre-approval is not valid at this time")
+			}
+		}
+		if contains(s.Approvals, e.Approver) {
+			return invalid(fmt.Sprintf("%q already approved", e.Approver))
+		}
+		if len(s.Decision.Approvers) > 0 && !contains(s.Decision.Approvers, e.Approver) && e.Approver != PolicyApprover {
+			return invalid(fmt.Sprintf("%q may not approve", e.Approver))
+		}
+		if s.ApprovalOverdue(e.Time) {
+			return invalid("approval deadline passed")
+		}
+	case EventApprovalExpired:
+		if !s.ApprovalOverdue(e.Time) {
+			return invalid("approval deadline not passed")
+		}
+		if e.Err == "" {
+			return invalid("missing failure reason")
+		}
+	case EventRoundStarted:
+		switch {
+		case s.State == StateApproved && e.Round == 1:
+		case s.State == StateRoundsInProgress && (e.Round == s.Round+1 || e.Round == 1):
+		default:
+			return invalid(fmt.Sprintf("unexpected round %d", e.Round))
+		}
+	case EventSigned:
+		if s.State != StateApproved && s.State != StateRoundsInProgress {
+			return invalid("session is not signing")
+		}
+		if len(e.Signature) == 0 {
+			return invalid("missing signature")
+		}
+	case EventBroadcast:
+		if s.State != StateSigned {
+			return invalid("session is not signed")
+		}
+		if e.TxID == "" {
+			return invalid("missing transaction ID")
+		}
+	case EventExpired:
+		if s.State != StateSigned && s.State != StateBroadcast {
+			return invalid("session has no transaction to expire")
+		}
+	case EventReapproved:
+		if s.State != StatePolicyEvaluated {
+			return invalid("session is not awaiting approval")
+		}
+		if s.Attempt == 0 {
+			return invalid("session was never rebuilt")
+		}
+		if len(s.PendingReviewers()) > 0 || s.Vetoed() {
+			return invalid("reviews are incomplete or vetoed")
+		}
+		if len(s.PreviousApprovals) < s.Decision.RequiredApprovals {
+			return invalid("previous attempt has too few approvals")
+		}
+	case EventFinalized:
+		if s.State != StateBroadcast {
+			return invalid("session was not broadcast")
+		}
+		if e.Receipt == nil {
+			return invalid("missing receipt")
+		}
+	case EventFailed:
+		if e.Err == "" {
+			return invalid("missing failure reason")
+		}
+	default:
+		return invalid("unknown event type")
+	}
+	return nil
+}
+
+// PendingReviewers returns the required reviewers that have not reviewed the
+// session yet.
+func (s *Session) PendingReviewers() []string {
+	if s.Decision == nil {
+		return nil
+	}
+	var pending []string
+	for _, r := range s.Decision.Reviewers {
+		reviewed := false
+		for _, review := range s.Reviews {
+			reviewed = reviewed || review.Reviewer == r
+		}
+		if !reviewed {
+			pending = append(pending, r)
+		}
+	}
+	return pending
+}
+
+// PendingApprovers returns the eligible approvers that have not approved the
+// session yet, or nil if anyone may approve.
+func (s *Session) PendingApprovers() []string {
+	if s.Decision == nil {
+		return nil
+	}
+	var pending []string
+	for _, a := range s.Decision.Approvers {
+		if !contains(s.Approvals, a) {
+			pending = append(pending, a)
+		}
+	}
+	return pending
+}
+
+// ApprovalOverdue reports whether the session is still waiting for
+// approvals at now, after its approval deadline.
+func (s *Session) ApprovalOverdue(now time.Time) bool {
+	return s.State == StatePolicyEvaluated && !s.ApprovalDeadline.IsZero() && now.After(s.ApprovalDeadline)
+}
+
+// Vetoed reports whether any reviewer vetoed the session.
+func (s *Session) Vetoed() bool {
+	for _, review := range s.Reviews {
+		if review.Veto {
+			return true
+		}
+	}
+	return false
+}
+
+// Refused reports whether the session failed because policy denied it or an
+// observer vetoed it.  Such a refusal covers the transfer, not just this
+// transaction, so it must not be worked around by submitting the transfer
+// again, in part or whole.
+func (s *Session) Refused() bool {
+	return s.State == StateFailed && (s.Decision != nil && !s.Decision.Allow || s.Vetoed())
+}
+
+// ready reports whether an evaluated session has everything it needs to start
+// signing: all reviews without a veto and enough approvals.  At least one
+// approval is always required; PolicyApprover fills in when policy requires
+// none.
+func (s *Session) ready() bool {
+	required := s.Decision.RequiredApprovals
+	if required < 1 {
+		required = 1
+	}
+	return len(s.PendingReviewers()) == 0 && !s.Vetoed() && len(s.Approvals) >= required
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,345 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrSessionNotFound is returned when a session has no events.
+	ErrSessionNotFound = errors.New("coordinator: session not found")
+	// ErrConflict is returned by Store.Append when another writer appended
+	// to the session first.
+	ErrConflict = errors.New("coordinator: concurrent session update")
+)
+
+// Store persists session event logs.
+//
+// Implementations must be safe for concurrent use and must reject an event
+// whose Seq is not exactly one past the last stored event of its session with
+// ErrConflict.
+type Store interface {
+	// Append durably adds e to the log of e.Session.
+	Append(ctx context.Context, e *Event) error
+	// Events returns the complete log of a session in order, or
+	// ErrSessionNotFound.
+	Events(ctx context.Context, session string) ([]Event, error)
+	// Sessions returns the IDs of all stored sessions.
+	Sessions(ctx context.Context) ([]string, error)
+}
+
+// MemoryStore is a Store that keeps events in memory.  It is intended for
+// tests and single-process demos.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events map[string][]Event
+	holds  map[string]bool
+}
+
+// Ensure MemoryStore implements the PurgeStore interface
+var _ PurgeStore = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{events: make(map[string][]Event), holds: make(map[string]bool)}
+}
+
+// Append implements Store.
+func (m *MemoryStore) Append(_ context.Context, e *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e.Seq != uint64(len(m.events[e.Session]))+1 {
+		return ErrConflict
+	}
+	m.events[e.Session] = append(m.events[e.Session], *e)
+	return nil
+}
+
+// Events implements Store.
+func (m *MemoryStore) Events(_ context.Context, session string) ([]Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	events, ok := m.events[session]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return append([]Event(nil), events...), nil
+}
+
+// Sessions implements Store.
+func (m *MemoryStore) Sessions(context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.events))
+	for id := range m.events {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete implements PurgeStore.
+func (m *MemoryStore) Delete(_ context.Context, session string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holds[session] {
+		return ErrLegalHold
+	}
+	delete(m.events, session)
+	return nil
+}
+
+// SetHold implements PurgeStore.
+func (m *MemoryStore) SetHold(_ context.Context, session string, hold bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.events[session]; !ok {
+		return ErrSessionNotFound
+	}
+	if hold {
+		m.holds[session] = true
+	} else {
+		delete(m.holds, session)
+	}
+	return nil
+}
+
+// Holds implements PurgeStore.
+func (m *MemoryStore) Holds(context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.holds))
+	for id := range m.holds {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// FileStore is a Store that keeps one JSON-lines file per session in a
+// directory.  Every append is fsynced before it is acknowledged, and a torn
+// final line left by a crash mid-append is discarded.  A legal hold is an
+// empty marker file next to the session's log.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Ensure FileStore implements the PurgeStore interface
+var _ PurgeStore = (*FileStore)(nil)
+
+// validSessionID restricts session IDs to names that are safe file names.
+var validSessionID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+const (
+	fileStoreExt = ".jsonl"
+	fileHoldExt  = ".hold"
+)
+
+// NewFileStore creates a FileStore rooted at dir, creating it if necessary.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating session directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(session string) (string, error) {
+	if !validSessionID.MatchString(session) {
+		return "", fmt.Errorf("invalid session ID %q", session)
+	}
+	return filepath.Join(f.dir, session+fileStoreExt), nil
+}
+
+// Append implements Store.
+func (f *FileStore) Append(_ context.Context, e *Event) error {
+	path, err := f.path(e.Session)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events, size, err := f.read(path)
+	if err != nil {
+		return err
+	}
+	if e.Seq != uint64(len(events))+1 {
+		return ErrConflict
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening session log: %w", err)
+	}
+	// Writing at size rather than appending drops a torn line left behind
+	// by a crash during an earlier, unacknowledged append.
+	if _, err := file.WriteAt(append(line, '\n'), size); err != nil {
+		file.Close()
+		return fmt.Errorf("writing session log: %w", err)
+	}
+	if err := file.Truncate(size + int64(len(line)) + 1); err != nil {
+		file.Close()
+		return fmt.Errorf("truncating session log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("syncing session log: %w", err)
+	}
+	return file.Close()
+}
+
+// Events implements Store.
+func (f *FileStore) Events(_ context.Context, session string) ([]Event, error) {
+	path, err := f.path(session)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events, _, err := f.read(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	return events, nil
+}
+
+// read loads a session log and returns its events together with the length of
+// the complete, newline-terminated prefix.  A missing file is an empty log.
+func (f *FileStore) read(path string) ([]Event, int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading session log: %w", err)
+	}
+
+	var (
+		events []Event
+		size   int64
+	)
+	for {
+		i := bytes.IndexByte(data[size:], '\n')
+		if i < 0 {
+			break
+		}
+		var e Event
+		if err := json.Unmarshal(data[size:size+int64(i)], &e); err != nil {
+			return nil, 0, fmt.Errorf("decoding event %d of %s: %w", len(events)+1, path, err)
+		}
+		events = append(events, e)
+		size += int64(i) + 1
+	}
+	return events, size, nil
+}
+
+// Sessions implements Store.
+func (f *FileStore) Sessions(context.Context) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, fileStoreExt) {
+			ids = append(ids, strings.TrimSuffix(name, fileStoreExt))
+		}
+	}
+	return ids, nil
+}
+
+// Delete implements PurgeStore.
+func (f *FileStore) Delete(_ context.Context, session string) error {
+	path, err := f.path(session)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if held, err := f.held(session); err != nil || held {
+		if err == nil {
+			err = ErrLegalHold
+		}
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("deleting session log: %w", err)
+	}
+	return nil
+}
+
+// SetHold implements PurgeStore.
+func (f *FileStore) SetHold(_ context.Context, session string, hold bool) error {
+	path, err := f.path(session)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return ErrSessionNotFound
+	} else if err != nil {
+		return fmt.Errorf("reading session log: %w", err)
+	}
+	marker := filepath.Join(f.dir, session+fileHoldExt)
+	if !hold {
+		if err := os.Remove(marker); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("releasing legal hold: %w", err)
+		}
+		return nil
+	}
+	file, err := os.OpenFile(marker, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("placing legal hold: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("placing legal hold: %w", err)
+	}
+	return file.Close()
+}
+
+// held reports whether session has a legal hold marker.  f.mu must be held.
+func (f *FileStore) held(session string) (bool, error) {
+	_, err := os.Stat(filepath.Join(f.dir, session+fileHoldExt))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, os.ErrNotExist):
+		return false, nil
+	default:
+		return false, fmt.Errorf("reading legal hold: %w", err)
+	}
+}
+
+// Holds implements PurgeStore.
+func (f *FileStore) Holds(context.Context) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing legal holds: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, fileHoldExt) {
+			ids = append(ids, strings.TrimSuffix(name, fileHoldExt))
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,48 @@
+package coordinator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	_, err = store.Events(ctx, "s1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	require.NoError(t, store.Append(ctx, &Event{Session: "s1", Seq: 1, Type: EventCreated, Request: testRequest}))
+	assert.ErrorIs(t, store.Append(ctx, &Event{Session: "s1", Seq: 1, Type: EventFailed}), ErrConflict)
+	assert.Error(t, store.Append(ctx, &Event{Session: "../escape", Seq: 1}))
+
+	// Simulate a crash halfway through writing the second event.
+	f, err := os.OpenFile(filepath.Join(dir, "s1.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"session":"s1","seq":2,"ty`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	events, err := store.Events(ctx, "s1")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, 0, events[0].Request.Transfer.Amount.Cmp(testRequest.Transfer.Amount))
+
+	require.NoError(t, store.Append(ctx, &Event{Session: "s1", Seq: 2, Type: EventFailed, Err: "aborted"}))
+	events, err = store.Events(ctx, "s1")
+	require.NoError(t, err)
+	s, err := Replay(events)
+	require.NoError(t, err)
+	assert.Equal(t, StateFailed, s.State)
+
+	ids, err := store.Sessions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s1"}, ids)
+}
@@ -0,0 +1,101 @@
+package coordinator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultWatchInterval is how often a Watcher looks for unconfirmed sessions.
+const defaultWatchInterval = 30 * time.Second
+
+// WatcherConfig contains the configuration for a Watcher.
+type WatcherConfig struct {
+	// Interval is how often the store is scanned.  Defaults to 30s.
+	Interval time.Duration
+	// OnDone, if set, is called whenever a resumed session's Run returns,
+	// and with an empty id when scanning the store fails.
+	OnDone func(id string, s *Session, err error)
+}
+
+// Watcher keeps signed and broadcast sessions moving without anyone calling
+// Run on them, e.g. after a coordinator restart.  Resumed sessions are
+// confirmed, and when their transaction expires, rebuilt, re-signed and
+// rebroadcast until they are finalized, fail or are cancelled with Fail.
+// Rebuilt sessions that need human approval stop there as usual.  Sessions
+// whose approvals are overdue are cancelled on every scan.
+type Watcher struct {
+	c      *Coordinator
+	config WatcherConfig
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+// NewWatcher creates a Watcher for c.  It does nothing until Run is called.
+func NewWatcher(c *Coordinator, config WatcherConfig) *Watcher {
+	if config.Interval <= 0 {
+		config.Interval = defaultWatchInterval
+	}
+	return &Watcher{c: c, config: config, running: make(map[string]bool)}
+}
+
+// Run scans for unconfirmed sessions immediately and then every Interval
+// until ctx is done.  It waits for the sessions it resumed before returning
+// ctx.Err().
+func (w *Watcher) Run(ctx context.Context) error {
+	defer w.wg.Wait()
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Scan(ctx); err != nil && ctx.Err() == nil && w.config.OnDone != nil {
+			w.config.OnDone("", nil, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan cancels sessions whose approvals are overdue and resumes every signed
+// or broadcast session that this Watcher is not already running.  Sessions
+// run in the background under ctx.
+func (w *Watcher) Scan(ctx context.Context) error {
+	if _, err := w.c.ExpireApprovals(ctx); err != nil {
+		return err
+	}
+	sessions, err := w.c.List(ctx, Filter{States: []State{StateSigned, StateBroadcast}})
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		w.mu.Lock()
+		if w.running[s.ID] {
+			w.mu.Unlock()
+			continue
+		}
+		w.running[s.ID] = true
+		w.mu.Unlock()
+
+		w.wg.Add(1)
+		go func(id string) {
+			defer w.wg.Done()
+			s, err := w.c.Run(ctx, id)
+			w.mu.Lock()
+			delete(w.running, id)
+			w.mu.Unlock()
+			if w.config.OnDone != nil {
+				w.config.OnDone(id, s, err)
+			}
+		}(s.ID)
+	}
+	return nil
+}
+
+// Wait blocks until all sessions resumed by Scan have returned.
+func (w *Watcher) Wait() {
+	w.wg.Wait()
+}
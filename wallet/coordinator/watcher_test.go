@@ -0,0 +1,261 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// expiringChain numbers every built transaction.  Builds up to expire expire
+// without landing as soon as they are broadcast; later builds finalize unless
+// hold is set.  Broadcasts of a transaction already sent fail with resubmit,
+// if set.
+type expiringChain struct {
+	fakeChain
+	mu       sync.Mutex
+	builds   int
+	expire   int
+	hold     bool
+	resubmit error
+	fees     map[int]int64
+	sent     map[int]bool
+}
+
+func (f *expiringChain) build(payload []byte) int {
+	var n int
+	fmt.Sscanf(string(payload[strings.LastIndexByte(string(payload), '#')+1:]), "%d", &n)
+	return n
+}
+
+func (f *expiringChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.builds++
+	payload := []byte(fmt.Sprintf("%s|%s|%s#%d", t.From, t.To, t.Amount, f.builds))
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: payload, SigningPayload: payload}, nil
+}
+
+func (f *expiringChain) Decode(payload []byte) (*chain.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fee, ok := f.fees[f.build(payload)]
+	if !ok {
+		fee = 5000
+	}
+	return &chain.Summary{Chain: f.ID(), From: "alice", To: "bob", Amount: big.NewInt(1), Fee: big.NewInt(fee)}, nil
+}
+
+func (f *expiringChain) Broadcast(_ context.Context, tx *chain.SignedTx) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.broadcasts++
+	if f.sent == nil {
+		f.sent = make(map[int]bool)
+	}
+	if f.sent[f.build(tx.Unsigned.Payload)] && f.resubmit != nil {
+		return "", f.resubmit
+	}
+	f.sent[f.build(tx.Unsigned.Payload)] = true
+	return fmt.Sprintf("tx-%d", f.build(tx.Unsigned.Payload)), nil
+}
+
+func (f *expiringChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int
+	fmt.Sscanf(txID, "tx-%d", &n)
+	if n <= f.expire || f.hold {
+		return nil, chain.ErrNotFound
+	}
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized, Height: 7}, nil
+}
+
+func (f *expiringChain) Expired(_ context.Context, tx *chain.UnsignedTx) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := f.build(tx.Payload)
+	return n <= f.expire && f.sent[n], nil
+}
+
+func newExpiringCoordinator(t *testing.T, ch *expiringChain, policy Policy, reapprove ReapprovePolicy) *Coordinator {
+	t.Helper()
+	c, err := New(Config{
+		Store:           NewMemoryStore(),
+		Chains:          []chain.Chain{ch},
+		Signer:          &fakeSigner{rounds: 1},
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+		Reapprove:       reapprove,
+		MaxRebuilds:     2,
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func requireApprovals(n int) Policy {
+	return PolicyFunc(func(context.Context, *Request, *chain.Summary) (*Decision, error) {
+		return &Decision{Allow: true, RequiredApprovals: n}, nil
+	})
+}
+
+func TestExpiredTransactionIsRebuilt(t *testing.T) {
+	ctx := context.Background()
+	ch := &expiringChain{expire: 1}
+	c := newExpiringCoordinator(t, ch, nil, nil)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, StateFinalized, s.State)
+	assert.Equal(t, "tx-2", s.TxID)
+	assert.Equal(t, 1, s.Attempt)
+
+	history, err := c.History(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []EventType{
+		EventCreated, EventPolicyEvaluated, EventApproved, EventRoundStarted, EventSigned, EventBroadcast,
+		EventExpired, EventPolicyEvaluated, EventApproved, EventRoundStarted, EventSigned, EventBroadcast,
+		EventFinalized,
+	}, eventTypes(history))
+	assert.Equal(t, "tx-1", history[6].TxID)
+}
+
+func TestFailedResubmissionIsReturned(t *testing.T) {
+	ctx := context.Background()
+	ch := &expiringChain{hold: true, resubmit: errors.New("node unavailable")}
+	c := newExpiringCoordinator(t, ch, nil, nil)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	_, err = c.Run(ctx, s.ID)
+	assert.ErrorContains(t, err, "resubmitting tx-1: node unavailable")
+	s, err = c.Session(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateBroadcast, s.State)
+
+	ch.mu.Lock()
+	ch.hold, ch.resubmit = false, nil
+	ch.mu.Unlock()
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+}
+
+func TestRebuiltTransactionReapproval(t *testing.T) {
+	ctx := context.Background()
+
+	run := func(t *testing.T, ch *expiringChain, reapprove ReapprovePolicy) *Session {
+		c := newExpiringCoordinator(t, ch, requireApprovals(2), reapprove)
+		s, err := c.Submit(ctx, testRequest)
+		require.NoError(t, err)
+		_, err = c.Run(ctx, s.ID)
+		require.NoError(t, err)
+		_, err = c.Approve(ctx, s.ID, "carol")
+		require.NoError(t, err)
+		_, err = c.Approve(ctx, s.ID, "dave")
+		require.NoError(t, err)
+		s, err = c.Run(ctx, s.ID)
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("identical intent", func(t *testing.T) {
+		s := run(t, &expiringChain{expire: 1}, IdenticalIntent)
+		assert.Equal(t, StateFinalized, s.State)
+		assert.Equal(t, []string{"carol", "dave"}, s.Approvals)
+	})
+	t.Run("without policy", func(t *testing.T) {
+		s := run(t, &expiringChain{expire: 1}, nil)
+		assert.Equal(t, StatePolicyEvaluated, s.State)
+		assert.Equal(t, 1, s.Attempt)
+		assert.Empty(t, s.Approvals)
+	})
+	t.Run("higher fee", func(t *testing.T) {
+		s := run(t, &expiringChain{expire: 1, fees: map[int]int64{2: 6000}}, IdenticalIntent)
+		assert.Equal(t, StatePolicyEvaluated, s.State)
+	})
+}
+
+func TestExpiryGivesUpAfterMaxRebuilds(t *testing.T) {
+	ctx := context.Background()
+	ch := &expiringChain{expire: 10}
+	c := newExpiringCoordinator(t, ch, nil, nil)
+
+	s, err := c.Submit(ctx, testRequest)
+	require.NoError(t, err)
+	s, err = c.Run(ctx, s.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, StateFailed, s.State)
+	assert.Equal(t, "transaction expired 3 times", s.Err)
+	assert.Equal(t, 3, ch.builds)
+}
+
+func TestWatcherResumesUnconfirmedSessions(t *testing.T) {
+	ch := &expiringChain{hold: true}
+	c := newExpiringCoordinator(t, ch, nil, nil)
+
+	s, err := c.Submit(context.Background(), testRequest)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	_, err = c.Run(ctx, s.ID)
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	s, err = c.Session(context.Background(), s.ID)
+	require.NoError(t, err)
+	require.Equal(t, StateBroadcast, s.State)
+	ch.mu.Lock()
+	assert.Greater(t, ch.broadcasts, 1, "a dropped transaction is resubmitted")
+	ch.expire, ch.hold = 1, false
+	ch.mu.Unlock()
+
+	var done []string
+	w := NewWatcher(c, WatcherConfig{OnDone: func(id string, s *Session, err error) {
+		assert.NoError(t, err)
+		done = append(done, id)
+	}})
+	require.NoError(t, w.Scan(context.Background()))
+	w.Wait()
+	assert.Equal(t, []string{s.ID}, done)
+
+	s, err = c.Session(context.Background(), s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateFinalized, s.State)
+	assert.Equal(t, "tx-2", s.TxID)
+
+	require.NoError(t, w.Scan(context.Background()))
+	w.Wait()
+	assert.Len(t, done, 1, "finalized sessions are left alone")
+}
+
+func TestIdenticalIntent(t *testing.T) {
+	base := &chain.Summary{Chain: "fake", From: "alice", To: "bob", Amount: big.NewInt(1), Fee: big.NewInt(10)}
+	same := *base
+	assert.True(t, IdenticalIntent(base, &same))
+
+	cheaper := *base
+	cheaper.Fee = big.NewInt(5)
+	assert.True(t, IdenticalIntent(base, &cheaper))
+
+	other := *base
+	other.To = "mallory"
+	assert.False(t, IdenticalIntent(base, &other))
+
+	more := *base
+	more.Amount = big.NewInt(2)
+	assert.False(t, IdenticalIntent(base, &more))
+	assert.False(t, IdenticalIntent(nil, base))
+}
@@ -0,0 +1,292 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultAbortGrace = 5 * time.Second
+
+var (
+	// ErrShuttingDown is returned by Run once Shutdown has been called.
+	ErrShuttingDown = errors.New("daemon: shutting down")
+	// ErrDuplicateSession is returned by Run for a session ID that is
+	// already running.
+	ErrDuplicateSession = errors.New("daemon: session already running")
+)
+
+// Flusher is implemented by audit logs that buffer records.
+type Flusher interface {
+	Flush() error
+}
+
+// Zeroizer is implemented by in-memory key material.
+type Zeroizer interface {
+	Zeroize()
+}
+
+// ZeroizerFunc adapts an ordinary function, such as a key's Free method, to
+// the Zeroizer interface.
+type ZeroizerFunc func()
+
+// Zeroize calls f().
+func (f ZeroizerFunc) Zeroize() { f() }
+
+// Bytes returns a Zeroizer that overwrites b with zeros.
+func Bytes(b []byte) Zeroizer {
+	return ZeroizerFunc(func() { clear(b) })
+}
+
+// Reloader is implemented by configuration that can be replaced at runtime,
+// such as a policy.Engine.  Reload must validate the new configuration and
+// keep the old one on error.
+type Reloader interface {
+	Reload() error
+}
+
+// Config contains the configuration for a Daemon.
+type Config struct {
+	// AuditLog, if set, is flushed during Shutdown after the last session
+	// has returned.
+	AuditLog Flusher
+	// Reloaders are reloaded by Reload and on SIGHUP, see ReloadOnSignal.
+	Reloaders []Reloader
+	// AbortGrace is how long Shutdown waits, after cancelling the sessions
+	// still running at its deadline, for them to return.  Defaults to five
+	// seconds.
+	AbortGrace time.Duration
+}
+
+// Daemon tracks in-flight sessions and in-memory secrets.
+type Daemon struct {
+	config Config
+
+	// abort is cancelled when Shutdown runs out of time.
+	abort       context.Context
+	cancelAbort context.CancelFunc
+	draining    chan struct{}
+	zeroized    chan struct{}
+
+	mu       sync.Mutex
+	closing  bool
+	sessions map[string]struct{}
+	idle     *sync.Cond
+	secrets  map[uint64]Zeroizer
+	nextKey  uint64
+	done     chan struct{}
+	result   error
+}
+
+// New creates a Daemon from the given configuration.
+func New(config Config) *Daemon {
+	if config.AbortGrace <= 0 {
+		config.AbortGrace = defaultAbortGrace
+	}
+	abort, cancel := context.WithCancel(context.Background())
+	d := &Daemon{
+		config:      config,
+		abort:       abort,
+		cancelAbort: cancel,
+		draining:    make(chan struct{}),
+		zeroized:    make(chan struct{}),
+		sessions:    make(map[string]struct{}),
+		secrets:     make(map[uint64]Zeroizer),
+	}
+	d.idle = sync.NewCond(&d.mu)
+	return d
+}
+
+// Run runs fn as session id.  The context passed to fn is cancelled when ctx
+// is, or when Shutdown gives up waiting.
+func (d *Daemon) Run(ctx context.Context, id string, fn func(ctx context.Context) error) error {
+	d.mu.Lock()
+	if d.closing {
+		d.mu.Unlock()
+		return ErrShuttingDown
+	}
+	if _, ok := d.sessions[id]; ok {
+		d.mu.Unlock()
+		return ErrDuplicateSession
+	}
+	d.sessions[id] = struct{}{}
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.sessions, id)
+		if len(d.sessions) == 0 {
+			d.idle.Broadcast()
+		}
+		d.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(d.abort, cancel)
+	defer stop()
+	return fn(ctx)
+}
+
+// InFlight returns the IDs of running sessions in sorted order.
+func (d *Daemon) InFlight() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]string, 0, len(d.sessions))
+	for id := range d.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Draining returns a channel that is closed when Shutdown starts.
+func (d *Daemon) Draining() <-chan struct{} {
+	return d.draining
+}
+
+// Zeroized returns a channel that is closed once Shutdown has zeroized the
+// kept secrets.  When Shutdown gave up on a stuck session, that happens only
+// after the session has returned, so a process that must not exit with key
+// material in memory waits for it.
+func (d *Daemon) Zeroized() <-chan struct{} {
+	return d.zeroized
+}
+
+// Keep registers z to be zeroized by Shutdown.  The returned function
+// unregisters it without zeroizing, for material the caller disposes of
+// itself; it must not be called after Shutdown has zeroized z.
+func (d *Daemon) Keep(z Zeroizer) (release func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := d.nextKey
+	d.nextKey++
+	d.secrets[key] = z
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.secrets, key)
+	}
+}
+
+// Reload reloads every configured Reloader.  A failing Reloader does not
+// prevent the others from being reloaded.
+func (d *Daemon) Reload() error {
+	var errs []error
+	for _, r := range d.config.Reloaders {
+		if err := r.Reload(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReloadOnSignal calls Reload whenever the process receives SIGHUP, until ctx
+// is done.  Errors are passed to onError, if set.
+func (d *Daemon) ReloadOnSignal(ctx context.Context, onError func(error)) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-sig:
+				if err := d.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Shutdown stops accepting sessions, waits for in-flight sessions until ctx
+// is done, cancels any still running and waits up to Config.AbortGrace for
+// them to return, flushes the audit log and zeroizes all kept secrets.  It
+// returns an error naming the aborted sessions if the deadline was hit.  A
+// session stuck in a native round may ignore its context; if one is still
+// running after the grace period, Shutdown reports it and returns without
+// zeroizing, which then happens once the session has returned (see
+// Zeroized).  Later calls wait for the first to complete and return its
+// result.
+func (d *Daemon) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closing {
+		done := d.done
+		d.mu.Unlock()
+		<-done
+		return d.result
+	}
+	d.closing = true
+	d.done = make(chan struct{})
+	d.mu.Unlock()
+	close(d.draining)
+
+	idle := make(chan struct{})
+	go func() {
+		d.mu.Lock()
+		for len(d.sessions) > 0 {
+			d.idle.Wait()
+		}
+		d.mu.Unlock()
+		close(idle)
+	}()
+
+	var errs []error
+	stuck := false
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		aborted := d.InFlight()
+		d.cancelAbort()
+		if len(aborted) > 0 {
+			errs = append(errs, fmt.Errorf("aborted sessions %v: %w", aborted, ctx.Err()))
+		}
+		grace := time.NewTimer(d.config.AbortGrace)
+		select {
+		case <-idle:
+		case <-grace.C:
+			stuck = true
+			errs = append(errs, fmt.Errorf("sessions %v still running %s after abort; secrets are zeroized when they return", d.InFlight(), d.config.AbortGrace))
+		}
+		grace.Stop()
+	}
+	d.cancelAbort()
+
+	if d.config.AuditLog != nil {
+		if err := d.config.AuditLog.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("flushing audit log: %w", err))
+		}
+	}
+
+	if stuck {
+		go func() {
+			<-idle
+			d.zeroize()
+		}()
+	} else {
+		d.zeroize()
+	}
+
+	d.result = errors.Join(errs...)
+	close(d.done)
+	return d.result
+}
+
+// zeroize zeroizes and forgets every kept secret, then closes zeroized.
+func (d *Daemon) zeroize() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, z := range d.secrets {
+		z.Zeroize()
+		delete(d.secrets, key)
+	}
+	close(d.zeroized)
+}
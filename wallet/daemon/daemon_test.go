@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flushLog struct {
+	mu      sync.Mutex
+	flushed bool
+	// sessionsDone reports whether every session had returned at flush time.
+	sessionsDone func() bool
+	done         bool
+}
+
+func (l *flushLog) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushed = true
+	l.done = l.sessionsDone()
+	return nil
+}
+
+func TestShutdownFinishesInFlightSessions(t *testing.T) {
+	log := &flushLog{}
+	d := New(Config{AuditLog: log})
+	log.sessionsDone = func() bool { return len(d.InFlight()) == 0 }
+	secret := []byte("share")
+	d.Keep(Bytes(secret))
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- d.Run(context.Background(), "s1", func(ctx context.Context) error {
+			close(started)
+			select {
+			case <-finish:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	<-started
+	assert.Equal(t, []string{"s1"}, d.InFlight())
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- d.Shutdown(context.Background()) }()
+	<-d.Draining()
+	assert.ErrorIs(t, d.Run(context.Background(), "s2", func(context.Context) error { return nil }), ErrShuttingDown)
+
+	close(finish)
+	require.NoError(t, <-result)
+	require.NoError(t, <-shutdown)
+	assert.True(t, log.flushed)
+	assert.True(t, log.done)
+	assert.Equal(t, make([]byte, len(secret)), secret)
+}
+
+func TestShutdownAbortsAfterDeadline(t *testing.T) {
+	d := New(Config{})
+	var freed atomic.Bool
+	d.Keep(ZeroizerFunc(func() { freed.Store(true) }))
+
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- d.Run(context.Background(), "stuck", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := d.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "stuck")
+	assert.NotContains(t, err.Error(), "still running", "the session returned within the grace period")
+	assert.ErrorIs(t, <-result, context.Canceled)
+	assert.True(t, freed.Load(), "zeroized before Shutdown returned")
+	<-d.Zeroized()
+
+	// A second call returns the same outcome.
+	assert.Equal(t, err, d.Shutdown(context.Background()))
+}
+
+func TestRunRejectsDuplicateSessions(t *testing.T) {
+	d := New(Config{})
+	err := d.Run(context.Background(), "s1", func(ctx context.Context) error {
+		return d.Run(ctx, "s1", func(context.Context) error { return nil })
+	})
+	assert.ErrorIs(t, err, ErrDuplicateSession)
+}
+
+func TestReleasedSecretsAreNotZeroized(t *testing.T) {
+	d := New(Config{})
+	secret := []byte{1, 2, 3}
+	release := d.Keep(Bytes(secret))
+	release()
+	require.NoError(t, d.Shutdown(context.Background()))
+	assert.Equal(t, []byte{1, 2, 3}, secret)
+}
+
+type reloader struct{ err error }
+
+func (r *reloader) Reload() error { return r.err }
+
+func TestReloadOnSignal(t *testing.T) {
+	reloaded := make(chan struct{}, 1)
+	failing := &reloader{err: errors.New("bad policy")}
+	d := New(Config{Reloaders: []Reloader{failing}})
+	assert.ErrorContains(t, d.Reload(), "bad policy")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.ReloadOnSignal(ctx, func(err error) { reloaded <- struct{}{} })
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGHUP did not trigger a reload")
+	}
+}
+
+func TestShutdownDoesNotWaitForStuckSessions(t *testing.T) {
+	d := New(Config{AbortGrace: 50 * time.Millisecond})
+	secret := []byte{1, 2, 3}
+	d.Keep(Bytes(secret))
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		// Like a native round, the session ignores its context.
+		result <- d.Run(context.Background(), "native", func(context.Context) error {
+			close(started)
+			<-finish
+			return nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := d.Shutdown(ctx)
+	assert.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond, "waited for the grace period")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "[native] still running")
+	select {
+	case <-d.Zeroized():
+		t.Fatal("zeroized while the session still holds the secret")
+	default:
+	}
+	d.mu.Lock()
+	assert.Equal(t, []byte{1, 2, 3}, secret, "the running session still holds the secret")
+	d.mu.Unlock()
+
+	close(finish)
+	require.NoError(t, <-result)
+	select {
+	case <-d.Zeroized():
+	case <-time.After(time.Second):
+		t.Fatal("not zeroized after the session returned")
+	}
+	assert.Equal(t, make([]byte, 3), secret)
+}
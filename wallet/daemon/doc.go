@@ -0,0 +1,42 @@
+// Package daemon hosts the signing sessions of a long-running MPC party and
+// shuts it down without stranding them.
+//
+// Every protocol run is wrapped in `Daemon.Run`, and key material held in
+// memory is registered with `Daemon.Keep`:
+//
+//	d := daemon.New(daemon.Config{AuditLog: auditLog})
+//	release := d.Keep(daemon.ZeroizerFunc(key.Free))
+//	defer release()
+//	err := d.Run(ctx, sessionID, func(ctx context.Context) error {
+//	    _, err := mpc.EDDSAMPCSign(job, req)
+//	    return err
+//	})
+//
+// On deploy the process calls `Daemon.Shutdown` with a deadline:
+//
+//  1. Run rejects new sessions with ErrShuttingDown and Draining is closed,
+//     so long-lived loops can stop picking up work.
+//  2. In-flight sessions are given until the deadline to finish their
+//     rounds.  Sessions still running then have their contexts cancelled
+//     and are given Config.AbortGrace to return.
+//  3. The audit log is flushed.
+//  4. Everything registered with Keep is zeroized – at once if no session
+//     is left, otherwise as soon as the aborted sessions have returned, so
+//     no protocol goroutine outlives the key material.
+//
+// Shutdown returns by the deadline plus the grace period even if a session
+// is stuck in a native round that does not honour its context.  It then
+// reports the session as still running, and `Daemon.Zeroized` is closed only
+// once it has returned; a process that must not exit with key material in
+// memory waits on that channel:
+//
+//	err := d.Shutdown(ctx)
+//	<-d.Zeroized()
+//
+// Shutdown reports the sessions it had to abort; they can be retried once the
+// new process is up.
+//
+// Configuration that must change without downtime, such as a policy.Engine,
+// is listed in Config.Reloaders.  `Daemon.Reload` reloads all of them and
+// `Daemon.ReloadOnSignal` does so on SIGHUP; sessions keep running throughout.
+package daemon
@@ -0,0 +1,288 @@
+package delegate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"filippo.io/edwards25519"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/clock"
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+// localSigner holds the whole master secret and signs with the tweaked
+// scalar, standing in for the parties' shifted shares.
+type localSigner struct {
+	secret *edwards25519.Scalar
+	prefix []byte
+}
+
+func newLocalSigner(t *testing.T) (ed25519.PublicKey, *localSigner) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	h := sha512.Sum512(priv.Seed())
+	secret, err := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	require.NoError(t, err)
+	return pub, &localSigner{secret: secret, prefix: h[32:]}
+}
+
+func (s *localSigner) SignChild(_ context.Context, child *Key, message []byte) ([]byte, error) {
+	tweak := slices.Clone(child.Tweak)
+	slices.Reverse(tweak)
+	t, err := edwards25519.NewScalar().SetCanonicalBytes(tweak)
+	if err != nil {
+		return nil, err
+	}
+	secret := edwards25519.NewScalar().Add(s.secret, t)
+	public := new(edwards25519.Point).ScalarBaseMult(secret).Bytes()
+
+	nonce, _ := edwards25519.NewScalar().SetUniformBytes(hash(s.prefix, tweak, message))
+	r := new(edwards25519.Point).ScalarBaseMult(nonce).Bytes()
+	k, _ := edwards25519.NewScalar().SetUniformBytes(hash(r, public, message))
+	sig := edwards25519.NewScalar().MultiplyAdd(k, secret, nonce)
+	return append(r, sig.Bytes()...), nil
+}
+
+func hash(parts ...[]byte) []byte {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+func TestDeriveSignsForChild(t *testing.T) {
+	master, signer := newLocalSigner(t)
+	ctx := context.Background()
+
+	key, err := Derive(master, nil, "cbmpc/1/0")
+	require.NoError(t, err)
+	again, err := Derive(master, nil, "cbmpc/1/0")
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "derivation is deterministic")
+	other, err := Derive(master, nil, "cbmpc/1/1")
+	require.NoError(t, err)
+	assert.NotEqual(t, key.PublicKey, other.PublicKey)
+	assert.NotEqual(t, master, key.PublicKey)
+
+	msg := []byte("transaction message")
+	sig, err := key.Signer(signer).Sign(ctx, msg)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(key.PublicKey, msg, sig))
+	assert.False(t, ed25519.Verify(master, msg, sig))
+	assert.False(t, ed25519.Verify(other.PublicKey, msg, sig))
+
+	_, err = Derive(master[:31], nil, "cbmpc/1/0")
+	assert.Error(t, err)
+	_, err = Derive(master, nil, "hot/payments")
+	assert.Error(t, err)
+	_, err = Derive(master, nil, "cbmpc/1'/0")
+	assert.ErrorIs(t, err, mpc.ErrHardenedDerivation)
+
+	// The derivation is mpc.DeriveEd25519, which the parties apply to their
+	// shares.
+	chainCode := bytes.Repeat([]byte{7}, 32)
+	key, err = Derive(master, chainCode, "cbmpc/1/0")
+	require.NoError(t, err)
+	want, err := mpc.DeriveEd25519(master, chainCode, "cbmpc/1/0")
+	require.NoError(t, err)
+	assert.Equal(t, want.PublicKey, []byte(key.PublicKey))
+	assert.Equal(t, want.Tweak, key.Tweak)
+	assert.Equal(t, chainCode, key.ChainCode)
+	sig, err = key.Signer(signer).Sign(ctx, msg)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(key.PublicKey, msg, sig))
+}
+
+// hexChain addresses keys by their hex encoding.
+type hexChain struct{ chain.Chain }
+
+func (hexChain) DeriveAddress(pubKey []byte) (string, error) { return hex.EncodeToString(pubKey), nil }
+
+const usdc = "usdc-mint"
+
+// fixture is a Guard with the keys of its approvers.
+type fixture struct {
+	*Guard
+	master ed25519.PublicKey
+	keys   *approvaltest.Signers
+}
+
+func newTestGuard(t *testing.T, path string, c *clock.Fake) *fixture {
+	t.Helper()
+	master, _ := newLocalSigner(t)
+	keys := approvaltest.New(t, "alice", "bob", "carol")
+	g, err := NewGuard(GuardConfig{
+		Master:    master,
+		Chain:     hexChain{},
+		Approvers: keys.Public,
+		Path:      path,
+		Now:       c.Now,
+	})
+	require.NoError(t, err)
+	return &fixture{Guard: g, master: master, keys: keys}
+}
+
+// approve replaces d's approvals with those of names over its terms.
+func (f *fixture) approve(t *testing.T, d *Delegation, names ...string) *Delegation {
+	t.Helper()
+	d.Approvals = f.keys.Approve(t, grantDomain, d.Hash(), names...)
+	return d
+}
+
+func (f *fixture) payments(t *testing.T) *Delegation {
+	return f.approve(t, &Delegation{
+		ID:      "payments",
+		Path:    "cbmpc/1/0",
+		Service: "checkout",
+		Limits: Limits{
+			Assets: map[string]*big.Int{"": big.NewInt(1_000), usdc: big.NewInt(50)},
+			MaxFee: big.NewInt(10),
+		},
+	}, "alice", "bob")
+}
+
+func transfer(from, token string, amount int64) *remotesigner.Request {
+	return &remotesigner.Request{
+		Method:  remotesigner.MethodSignTransaction,
+		Caller:  "checkout",
+		Summary: &chain.Summary{From: from, To: "merchant", Amount: big.NewInt(amount), Fee: big.NewInt(5), Token: token},
+	}
+}
+
+func TestGrantRequiresQuorum(t *testing.T) {
+	f := newTestGuard(t, "", clock.NewFake(time.Now()))
+
+	d := f.approve(t, f.payments(t), "alice")
+	_, err := f.Grant(d)
+	assert.ErrorIs(t, err, ErrNotApproved)
+	f.approve(t, d, "alice", "alice")
+	_, err = f.Grant(d)
+	assert.ErrorIs(t, err, ErrNotApproved)
+
+	mallory := approvaltest.New(t, "mallory", "bob")
+	d.Approvals = append(f.keys.Approve(t, grantDomain, d.Hash(), "alice"), mallory.Approve(t, grantDomain, d.Hash(), "mallory", "bob")...)
+	_, err = f.Grant(d)
+	assert.ErrorIs(t, err, ErrNotApproved, "unknown approvers and forged keys do not count")
+
+	// Approvals cover the terms they were given for.
+	d = f.payments(t)
+	d.Limits.Assets[usdc] = big.NewInt(1_000_000)
+	_, err = f.Grant(d)
+	assert.ErrorIs(t, err, ErrNotApproved)
+	d = f.payments(t)
+	d.Path = "cbmpc/0/0"
+	_, err = f.Grant(d)
+	assert.ErrorIs(t, err, ErrNotApproved)
+
+	_, err = f.Grant(f.payments(t))
+	require.NoError(t, err)
+	d = f.payments(t)
+	d.ID = "payments-2"
+	_, err = f.Grant(f.approve(t, d, "alice", "carol"))
+	assert.Error(t, err, "a path is delegated once")
+}
+
+func TestGuardEnforcesLimits(t *testing.T) {
+	c := clock.NewFake(time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC))
+	g := newTestGuard(t, "", c)
+	ctx := context.Background()
+	key, err := g.Grant(g.payments(t))
+	require.NoError(t, err)
+	from := hex.EncodeToString(key.PublicKey)
+	auth := g.Authorizer("payments")
+
+	require.NoError(t, auth.Authorize(ctx, transfer(from, "", 600)))
+	require.NoError(t, auth.Authorize(ctx, transfer(from, usdc, 50)))
+	assert.ErrorIs(t, auth.Authorize(ctx, transfer(from, "", 401)), remotesigner.ErrDenied, "daily cap")
+	require.NoError(t, auth.Authorize(ctx, transfer(from, "", 400)))
+	assert.Equal(t, big.NewInt(1_000), g.Spent("payments", ""))
+
+	for name, req := range map[string]*remotesigner.Request{
+		"other asset":  transfer(from, "other-mint", 1),
+		"other sender": transfer("treasury", "", 1),
+		"message":      {Method: remotesigner.MethodSignMessage, Caller: "checkout"},
+	} {
+		assert.ErrorIs(t, auth.Authorize(ctx, req), remotesigner.ErrDenied, name)
+	}
+	req := transfer(from, usdc, 1)
+	req.Caller = "reporting"
+	assert.ErrorIs(t, auth.Authorize(ctx, req), remotesigner.ErrDenied, "other service")
+	req = transfer(from, usdc, 1)
+	req.Summary.Fee = big.NewInt(11)
+	assert.ErrorIs(t, auth.Authorize(ctx, req), remotesigner.ErrDenied, "fee cap")
+
+	// The caps reset at midnight UTC.
+	c.Advance(time.Hour)
+	require.NoError(t, auth.Authorize(ctx, transfer(from, "", 1_000)))
+	assert.Equal(t, big.NewInt(0), g.Spent("payments", usdc))
+
+	require.NoError(t, g.Revoke("payments"))
+	assert.ErrorIs(t, auth.Authorize(ctx, transfer(from, usdc, 1)), remotesigner.ErrDenied)
+	assert.ErrorIs(t, g.Authorizer("unknown").Authorize(ctx, transfer(from, usdc, 1)), remotesigner.ErrDenied)
+}
+
+func TestGuardRecipients(t *testing.T) {
+	g := newTestGuard(t, "", clock.NewFake(time.Now()))
+	d := g.payments(t)
+	d.Limits.Recipients = []string{"merchant"}
+	key, err := g.Grant(g.approve(t, d, "bob", "carol"))
+	require.NoError(t, err)
+	from := hex.EncodeToString(key.PublicKey)
+	auth := g.Authorizer("payments")
+
+	require.NoError(t, auth.Authorize(context.Background(), transfer(from, "", 1)))
+	req := transfer(from, "", 1)
+	req.Summary.To = "stranger"
+	assert.ErrorIs(t, auth.Authorize(context.Background(), req), remotesigner.ErrDenied)
+}
+
+func TestGuardPersistsSpend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delegations.json")
+	c := clock.NewFake(time.Now())
+	f := newTestGuard(t, path, c)
+	key, err := f.Grant(f.payments(t))
+	require.NoError(t, err)
+	from := hex.EncodeToString(key.PublicKey)
+	require.NoError(t, f.Authorizer("payments").Authorize(context.Background(), transfer(from, usdc, 30)))
+
+	config := GuardConfig{Master: f.master, Chain: hexChain{}, Approvers: f.keys.Public, Path: path, Now: c.Now}
+	g, err := NewGuard(config)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(30), g.Spent("payments", usdc))
+	assert.ErrorIs(t, g.Authorizer("payments").Authorize(context.Background(), transfer(from, usdc, 21)), remotesigner.ErrDenied)
+	address, err := g.Address("payments")
+	require.NoError(t, err)
+	assert.Equal(t, from, address)
+}
+
+func TestGuardRefusesEditedDelegations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delegations.json")
+	f := newTestGuard(t, path, clock.NewFake(time.Now()))
+	_, err := f.Grant(f.payments(t))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	edited := bytes.Replace(data, []byte(`"usdc-mint": 50`), []byte(`"usdc-mint": 50000000`), 1)
+	require.NotEqual(t, data, edited)
+	require.NoError(t, os.WriteFile(path, edited, 0o600))
+
+	_, err = NewGuard(GuardConfig{Master: f.master, Chain: hexChain{}, Approvers: f.keys.Public, Path: path})
+	assert.ErrorIs(t, err, ErrNotApproved)
+}
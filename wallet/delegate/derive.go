@@ -0,0 +1,59 @@
+package delegate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+// Scheme identifies the derivation of Derive, mpc.DeriveEd25519, in exported
+// data such as read-only view bundles.
+const Scheme = "cb-mpc/hd/ed25519/v1"
+
+// Key is a child key derived from the master key.
+type Key struct {
+	Path      string            // Derivation path, e.g. "cbmpc/1/0"
+	PublicKey ed25519.PublicKey // Child public key, master + tweak·B
+	// ChainCode is the chain code of the master key the path was derived
+	// with, or nil for mpc.MasterChainCode.  Signers need it to derive
+	// their child shares.
+	ChainCode []byte
+	// Tweak is the 32-byte big-endian scalar added to the master secret to
+	// obtain the child secret.  It is derived from public data only.
+	Tweak []byte
+}
+
+// Derive derives the child key for path from the master public key with
+// mpc.DeriveEd25519, the derivation the parties apply to their shares with
+// mpc.DeriveChild.  Every party, and anyone else who knows the master key
+// and chainCode, obtains the same child without interaction.  A nil
+// chainCode stands for the default chain code of master.
+func Derive(master ed25519.PublicKey, chainCode []byte, path string) (*Key, error) {
+	if len(master) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes", ed25519.PublicKeySize)
+	}
+	child, err := mpc.DeriveEd25519(master, chainCode, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{Path: path, PublicKey: child.PublicKey, ChainCode: chainCode, Tweak: child.Tweak}, nil
+}
+
+// TweakSigner signs for child keys of the master key.  PartySigner is the
+// implementation for MPC parties: each party derives its child share with
+// mpc.DeriveChild, which adds the tweak to exactly one share, and the
+// parties run the EdDSA signing protocol unchanged.
+type TweakSigner interface {
+	SignChild(ctx context.Context, child *Key, message []byte) ([]byte, error)
+}
+
+// Signer returns a remotesigner.Signer that signs with k through s.
+func (k *Key) Signer(s TweakSigner) remotesigner.Signer {
+	return remotesigner.SignerFunc(func(ctx context.Context, message []byte) ([]byte, error) {
+		return s.SignChild(ctx, k, message)
+	})
+}
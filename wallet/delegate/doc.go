@@ -0,0 +1,43 @@
+// Package delegate lets an application service sign from a child key of the
+// MPC wallet within limits that the parties enforce, without a human
+// approving every transaction.  It is the building block for tiering a hot
+// wallet below the cold master key.
+//
+// Child keys are derived publicly from the master key with the HD scheme of
+// mpc.DeriveEd25519:
+//
+//	key, _ := delegate.Derive(master, nil, "cbmpc/1/0")
+//	// key.PublicKey = master + tweak·B
+//
+// Signing with a child key runs the ordinary EdDSA protocol on child shares
+// from mpc.DeriveChild (see PartySigner), so no new key generation is needed
+// and anyone holding the master shares can always sign for every child.  The
+// master key therefore keeps full control: it can sweep a child's funds or
+// continue to operate it after a delegation is revoked.
+//
+// A `Delegation` names the service allowed to use a child key and its
+// `Limits`: the assets it may move with a daily cap each, optionally the
+// recipients it may pay and a per-transaction fee cap.  A quorum of master
+// key holders grants it by signing its terms:
+//
+//	a, _ := delegate.SignGrant(d, "alice", aliceKey, time.Now())
+//	d.Approvals = append(d.Approvals, *a)
+//
+// Each party runs a `Guard` that checks the signatures against its own list
+// of approver keys, holds the delegations and the day's spend, and serves
+// the child key through a remotesigner Server whose Authorizer is the
+// Guard's:
+//
+//	signer := &delegate.PartySigner{Share: share, Job: joinParties}
+//	srv, _ := remotesigner.New(remotesigner.Config{
+//	    PublicKey:    key.PublicKey,
+//	    Signer:       key.Signer(signer),
+//	    Authenticate: remotesigner.BearerTokens(tokens),
+//	    Authorizer:   guard.Authorizer("payments"),
+//	    Chain:        solanaChain,
+//	})
+//
+// The Guard only admits transfers it can decode, and it counts them against
+// the cap before the signature is produced.  A signature that is then not
+// broadcast still counts; the caps err on the side of signing less.
+package delegate
@@ -0,0 +1,418 @@
+package delegate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/approval"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+const (
+	defaultQuorum = 2
+	// grantDomain separates delegation grants from any other use of an
+	// approver's key.
+	grantDomain = "cb-mpc delegation grant\n"
+	// termsVersion identifies the encoding of the terms in Delegation.Hash.
+	termsVersion = "cb-mpc delegation terms v1"
+)
+
+var (
+	// ErrNotFound is returned for unknown delegation IDs.
+	ErrNotFound = errors.New("delegate: delegation not found")
+	// ErrNotApproved is returned when a delegation lacks a quorum of valid
+	// approvals of its terms.
+	ErrNotApproved = approval.ErrNotApproved
+)
+
+// Limits bound what a delegation may sign.
+type Limits struct {
+	// Assets lists the assets the delegation may move, keyed by token mint
+	// or contract address with "" for the native asset, and the most that
+	// may be sent per UTC day in the asset's smallest unit.  Assets not
+	// listed are refused.
+	Assets map[string]*big.Int
+	// Recipients, if set, lists the only addresses that may be paid.
+	Recipients []string
+	// MaxFee, if set, caps the network fee of every transaction.
+	MaxFee *big.Int
+}
+
+// Delegation lets a service sign with a child key within limits.  The
+// fields up to Limits are its terms.
+type Delegation struct {
+	ID      string
+	Path    string // Derivation path of the child key, e.g. "cbmpc/1/0"
+	Service string // Caller identity, as authenticated by the remote signer
+	Limits  Limits
+	// Approvals are the signatures of the master key holders who granted
+	// the delegation, over its Hash.
+	Approvals []Approval
+}
+
+// Hash returns the hash of d's terms that approvals sign.
+func (d *Delegation) Hash() []byte {
+	fields := []string{termsVersion, d.ID, d.Path, d.Service, strconv.Itoa(len(d.Limits.Assets))}
+	assets := make([]string, 0, len(d.Limits.Assets))
+	for asset := range d.Limits.Assets {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	for _, asset := range assets {
+		fields = append(fields, asset, amountField(d.Limits.Assets[asset]))
+	}
+	fields = append(fields, strconv.Itoa(len(d.Limits.Recipients)))
+	fields = append(fields, d.Limits.Recipients...)
+	fields = append(fields, amountField(d.Limits.MaxFee))
+
+	h := sha256.New()
+	for _, field := range fields {
+		binary.Write(h, binary.BigEndian, uint32(len(field)))
+		h.Write([]byte(field))
+	}
+	return h.Sum(nil)
+}
+
+func amountField(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// Approval records that a master key holder granted a delegation.
+type Approval = approval.Approval
+
+// SignGrant approves d's terms on behalf of approver.
+func SignGrant(d *Delegation, approver string, key ed25519.PrivateKey, now time.Time) (*Approval, error) {
+	return approval.Sign(grantDomain, d.Hash(), approver, key, now)
+}
+
+// GuardConfig contains the configuration for a Guard.
+type GuardConfig struct {
+	// Master is the wallet's Ed25519 group public key.  Required.
+	Master ed25519.PublicKey
+	// ChainCode is the chain code child keys are derived with, or nil for
+	// mpc.MasterChainCode.
+	ChainCode []byte
+	// Chain decodes and addresses the delegated transactions.  Required.
+	// It must be the chain the remote signer decodes with.
+	Chain chain.Chain
+	// Approvers maps the master key holders who may grant delegations to
+	// their Ed25519 identity keys.  Grants are checked against them when
+	// they are made and again when they are loaded from Path.  Required.
+	Approvers map[string]ed25519.PublicKey
+	// Quorum is the number of distinct approvals a grant needs.  Defaults
+	// to 2.
+	Quorum int
+	// Path is the file delegations and daily spend are kept in.  If empty,
+	// they are only kept in memory and the daily caps restart with the
+	// process.
+	Path string
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// grant is the persisted state of one delegation.
+type grant struct {
+	Delegation Delegation
+	Key        Key
+	Address    string
+	Revoked    bool
+	Day        string              // UTC day Spent refers to
+	Spent      map[string]*big.Int // Amount signed for per asset on Day
+}
+
+// Guard enforces delegations on behalf of one party.  Every party runs its
+// own Guard in front of its share, so a compromised service or a single
+// compromised party cannot exceed the limits.
+type Guard struct {
+	config GuardConfig
+
+	mu     sync.Mutex
+	grants map[string]*grant
+}
+
+// NewGuard creates a Guard from the given configuration, loading the
+// delegations kept at config.Path.
+func NewGuard(config GuardConfig) (*Guard, error) {
+	if len(config.Master) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes", ed25519.PublicKeySize)
+	}
+	if config.Chain == nil {
+		return nil, fmt.Errorf("chain must be provided")
+	}
+	if config.Quorum <= 0 {
+		config.Quorum = defaultQuorum
+	}
+	if len(config.Approvers) < config.Quorum {
+		return nil, fmt.Errorf("quorum of %d exceeds the %d approvers", config.Quorum, len(config.Approvers))
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	g := &Guard{config: config, grants: map[string]*grant{}}
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Grant registers a delegation approved by a quorum of master key holders
+// and returns its child key.
+func (g *Guard) Grant(d *Delegation) (*Key, error) {
+	key, address, err := g.check(d)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.grants[d.ID]; ok {
+		return nil, fmt.Errorf("delegation %q already exists", d.ID)
+	}
+	for _, other := range g.grants {
+		if other.Delegation.Path == d.Path && !other.Revoked {
+			return nil, fmt.Errorf("path %q is already delegated by %q", d.Path, other.Delegation.ID)
+		}
+	}
+	g.grants[d.ID] = &grant{Delegation: *d, Key: *key, Address: address}
+	if err := g.save(); err != nil {
+		delete(g.grants, d.ID)
+		return nil, err
+	}
+	return key, nil
+}
+
+// check validates d and its approvals and derives its child key and address.
+func (g *Guard) check(d *Delegation) (*Key, string, error) {
+	switch {
+	case d.ID == "":
+		return nil, "", fmt.Errorf("delegation ID must be provided")
+	case d.Service == "":
+		return nil, "", fmt.Errorf("service must be provided")
+	case len(d.Limits.Assets) == 0:
+		return nil, "", fmt.Errorf("at least one asset must be allowed")
+	}
+	for asset, limit := range d.Limits.Assets {
+		if limit == nil || limit.Sign() <= 0 {
+			return nil, "", fmt.Errorf("daily cap of asset %q must be positive", asset)
+		}
+	}
+	quorum := approval.Quorum{Approvers: g.config.Approvers, Required: g.config.Quorum}
+	if err := quorum.Check(grantDomain, d.Hash(), d.Approvals); err != nil {
+		return nil, "", fmt.Errorf("delegation %q: %w", d.ID, err)
+	}
+	key, err := Derive(g.config.Master, g.config.ChainCode, d.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	address, err := g.config.Chain.DeriveAddress(key.PublicKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("deriving address: %w", err)
+	}
+	return key, address, nil
+}
+
+// Revoke stops a delegation.  The child key stays under the control of the
+// master key, which can still sign for it through the normal approval path,
+// e.g. to sweep its funds.
+func (g *Guard) Revoke(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	gr, ok := g.grants[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if gr.Revoked {
+		return nil
+	}
+	gr.Revoked = true
+	if err := g.save(); err != nil {
+		gr.Revoked = false
+		return err
+	}
+	return nil
+}
+
+// Address returns the chain address of a delegation's child key.
+func (g *Guard) Address(id string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	gr, ok := g.grants[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return gr.Address, nil
+}
+
+// Spent returns how much of asset the delegation signed for today.
+func (g *Guard) Spent(id, asset string) *big.Int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	gr, ok := g.grants[id]
+	if !ok || gr.Day != g.today() || gr.Spent[asset] == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(gr.Spent[asset])
+}
+
+// Authorizer returns the remotesigner.Authorizer for a delegation's signer.
+// It admits only transactions the chain can decode, from the child address,
+// by the delegated service and within the limits, and counts each admitted
+// amount against the day's cap before the signature is produced.  Amounts
+// admitted in a signAllTransactions batch that is later refused as a whole
+// stay counted.
+func (g *Guard) Authorizer(id string) remotesigner.Authorizer {
+	return remotesigner.AuthorizerFunc(func(ctx context.Context, req *remotesigner.Request) error {
+		return g.authorize(id, req)
+	})
+}
+
+func (g *Guard) authorize(id string, req *remotesigner.Request) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	gr, ok := g.grants[id]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: unknown delegation %q", remotesigner.ErrDenied, id)
+	case gr.Revoked:
+		return fmt.Errorf("%w: delegation %q is revoked", remotesigner.ErrDenied, id)
+	case req.Caller != gr.Delegation.Service:
+		return fmt.Errorf("%w: %q may not use delegation %q", remotesigner.ErrDenied, req.Caller, id)
+	case req.Summary == nil:
+		return fmt.Errorf("%w: only decodable transfers may be signed", remotesigner.ErrDenied)
+	}
+	s, limits := req.Summary, &gr.Delegation.Limits
+	if s.From != gr.Address {
+		return fmt.Errorf("%w: transfer is not from %s", remotesigner.ErrDenied, gr.Address)
+	}
+	limit, ok := limits.Assets[s.Token]
+	if !ok {
+		return fmt.Errorf("%w: asset %q is not allowed", remotesigner.ErrDenied, s.Token)
+	}
+	if len(limits.Recipients) > 0 {
+		for _, o := range s.Recipients() {
+			if !contains(limits.Recipients, o.To) {
+				return fmt.Errorf("%w: recipient %s is not allowed", remotesigner.ErrDenied, o.To)
+			}
+		}
+	}
+	if limits.MaxFee != nil && s.Fee != nil && s.Fee.Cmp(limits.MaxFee) > 0 {
+		return fmt.Errorf("%w: fee %s exceeds %s", remotesigner.ErrDenied, s.Fee, limits.MaxFee)
+	}
+
+	day := g.today()
+	spent := new(big.Int)
+	if gr.Day == day && gr.Spent[s.Token] != nil {
+		spent.Set(gr.Spent[s.Token])
+	}
+	total := new(big.Int).Add(spent, s.Amount)
+	if total.Cmp(limit) > 0 {
+		return fmt.Errorf("%w: daily cap of %s exceeded, %s already signed today", remotesigner.ErrDenied, limit, spent)
+	}
+
+	prevDay, prevSpent := gr.Day, gr.Spent
+	if gr.Day != day {
+		gr.Day, gr.Spent = day, map[string]*big.Int{}
+	} else {
+		gr.Spent = copySpent(gr.Spent)
+	}
+	gr.Spent[s.Token] = total
+	if err := g.save(); err != nil {
+		gr.Day, gr.Spent = prevDay, prevSpent
+		return err
+	}
+	return nil
+}
+
+func (g *Guard) today() string {
+	return g.config.Now().UTC().Format(time.DateOnly)
+}
+
+func (g *Guard) load() error {
+	if g.config.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(g.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading delegations: %w", err)
+	}
+	var list []*grant
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("decoding delegations: %w", err)
+	}
+	for _, gr := range list {
+		key, address, err := g.check(&gr.Delegation)
+		if err != nil {
+			return fmt.Errorf("loading delegations: %w", err)
+		}
+		gr.Key, gr.Address = *key, address
+		g.grants[gr.Delegation.ID] = gr
+	}
+	return nil
+}
+
+func (g *Guard) save() error {
+	if g.config.Path == "" {
+		return nil
+	}
+	list := make([]*grant, 0, len(g.grants))
+	for _, gr := range g.grants {
+		list = append(list, gr)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Delegation.ID < list[j].Delegation.ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(g.config.Path), ".delegations-*")
+	if err != nil {
+		return fmt.Errorf("writing delegations: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing delegations: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing delegations: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), g.config.Path); err != nil {
+		return fmt.Errorf("writing delegations: %w", err)
+	}
+	return nil
+}
+
+func copySpent(m map[string]*big.Int) map[string]*big.Int {
+	out := make(map[string]*big.Int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
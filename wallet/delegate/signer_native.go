@@ -0,0 +1,70 @@
+//go:build !nompc
+
+package delegate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/mpc"
+)
+
+// ErrNotReceiver is returned by PartySigner on the parties that take part in
+// a signature delivered to another party.
+var ErrNotReceiver = errors.New("delegate: signature delivered to another party")
+
+// PartySigner is the TweakSigner of one party of the MPC key.  Every party
+// of the signing job signs with its own PartySigner, after its own Guard
+// admitted the request; the first party of the job receives the signature
+// and serves it.
+type PartySigner struct {
+	// Share is the party's additive share of the master key: the output of
+	// mpc.EDDSAMPCKeyGen, or a threshold share converted with
+	// ToAdditiveShare for the signing quorum.  Required.
+	Share mpc.EDDSAMPCKey
+	// Job joins the other parties for one signature.  The job is freed
+	// after signing.  Required.
+	Job func(ctx context.Context) (*mpc.JobMP, error)
+}
+
+// SignChild signs message with child's share of p.Share.  The parties other
+// than the first of the job return ErrNotReceiver once they are done.
+func (p *PartySigner) SignChild(ctx context.Context, child *Key, message []byte) ([]byte, error) {
+	job, err := p.Job(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("joining signing job: %w", err)
+	}
+	defer job.Free()
+	sig, err := SignChildShare(job, p.Share, child, message)
+	if err != nil {
+		return nil, err
+	}
+	if sig == nil {
+		return nil, ErrNotReceiver
+	}
+	return sig, nil
+}
+
+// SignChildShare runs the signing protocol of job for child, deriving the
+// child share from share, the party's additive share of the master key.  The
+// first party of the job receives the signature; the others get nil.
+func SignChildShare(job *mpc.JobMP, share mpc.EDDSAMPCKey, child *Key, message []byte) ([]byte, error) {
+	derived, err := mpc.DeriveChild(&mpc.DeriveChildRequest{KeyShare: share, Path: child.Path, ChainCode: child.ChainCode})
+	if err != nil {
+		return nil, fmt.Errorf("deriving child share of %s: %w", child.Path, err)
+	}
+	defer derived.KeyShare.Free()
+	if !bytes.Equal(derived.Child.PublicKey, child.PublicKey) {
+		return nil, fmt.Errorf("share derives %x for %s, not %x", derived.Child.PublicKey, child.Path, []byte(child.PublicKey))
+	}
+	resp, err := mpc.EDDSAMPCSign(job, &mpc.EDDSAMPCSignRequest{KeyShare: derived.KeyShare, Message: message})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Signature) == 0 {
+		return nil, nil
+	}
+	return resp.Signature, nil
+}
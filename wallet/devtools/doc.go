@@ -0,0 +1,25 @@
+// Package devtools contains helpers for development, CI and demos.  Nothing
+// in it is meant for mainnet funds.
+//
+// The Pool keeps a set of pre-funded devnet keypairs in a JSON file so that
+// tests and demos do not depend on the public faucet, which is heavily rate
+// limited.  A test leases a wallet, uses it and returns it:
+//
+//	pool, err := devtools.OpenPool(devtools.PoolConfig{
+//	    Path:   "testdata/devnet-pool.json",
+//	    Funder: devtools.NewRPCFunder(rpc.DevNet_RPC),
+//	})
+//	lease := pool.LeaseT(t) // returned automatically when t finishes
+//
+// Leases expire, so a crashed CI job cannot hold a wallet forever, and the
+// pool file is locked while it is updated so that parallel test processes
+// (go test ./... runs one per package) share it safely.
+//
+// When no idle wallet holds MinBalance the pool tops up: first by moving
+// lamports from wallets with a surplus, then – at most once per
+// AirdropInterval – by asking the faucet.  Top-up failures are not fatal
+// while some wallet is still usable.
+//
+// The pool file holds private keys in the clear.  Use it for devnet and
+// testnet only.
+package devtools
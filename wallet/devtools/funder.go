@@ -0,0 +1,130 @@
+package devtools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/retry"
+)
+
+const (
+	confirmPollInterval = time.Second
+	confirmTimeout      = time.Minute
+)
+
+// errUnconfirmed makes confirm poll again.
+var errUnconfirmed = &retry.Error{Class: retry.Transient, Err: errors.New("transaction not confirmed yet")}
+
+// RPCFunder implements Funder against a Solana JSON-RPC endpoint.  Airdrops
+// use the cluster's requestAirdrop method, which devnet and local test
+// validators support.
+type RPCFunder struct {
+	client *rpc.Client
+	retry  retry.Policy
+}
+
+// Ensure RPCFunder implements the Funder interface
+var _ Funder = (*RPCFunder)(nil)
+
+// NewRPCFunder creates a Funder for the given endpoint, e.g. rpc.DevNet_RPC.
+func NewRPCFunder(endpoint string) *RPCFunder {
+	return &RPCFunder{client: rpc.New(endpoint), retry: retry.Policy{Classify: solchain.Classify}}
+}
+
+// Balance returns the confirmed balance of account in lamports.
+func (f *RPCFunder) Balance(ctx context.Context, account solana.PublicKey) (uint64, error) {
+	var out *rpc.GetBalanceResult
+	err := f.retry.Do(ctx, func(ctx context.Context) (err error) {
+		out, err = f.client.GetBalance(ctx, account, rpc.CommitmentConfirmed)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return out.Value, nil
+}
+
+// Airdrop requests lamports from the faucet and waits for confirmation.  The
+// request itself is not retried: the faucet rate-limits airdrops and the
+// Pool decides when to ask again.
+func (f *RPCFunder) Airdrop(ctx context.Context, account solana.PublicKey, lamports uint64) error {
+	sig, err := f.client.RequestAirdrop(ctx, account, lamports, rpc.CommitmentConfirmed)
+	if err != nil {
+		return err
+	}
+	return f.confirm(ctx, sig)
+}
+
+// Transfer sends lamports from one wallet to another and waits for
+// confirmation.  The sender pays the fee.
+func (f *RPCFunder) Transfer(ctx context.Context, from solana.PrivateKey, to solana.PublicKey, lamports uint64) error {
+	var bh *rpc.GetLatestBlockhashResult
+	err := f.retry.Do(ctx, func(ctx context.Context) (err error) {
+		bh, err = f.client.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fetching latest blockhash: %w", err)
+	}
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{system.NewTransferInstruction(lamports, from.PublicKey(), to).Build()},
+		bh.Value.Blockhash,
+		solana.TransactionPayer(from.PublicKey()),
+	)
+	if err != nil {
+		return fmt.Errorf("building transaction: %w", err)
+	}
+	if _, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(from.PublicKey()) {
+			return &from
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("signing transaction: %w", err)
+	}
+	var sig solana.Signature
+	err = f.retry.Do(ctx, func(ctx context.Context) (err error) {
+		sig, err = f.client.SendTransaction(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("sending transaction: %w", err)
+	}
+	return f.confirm(ctx, sig)
+}
+
+// confirm polls until sig is confirmed, fails or a minute has passed.
+func (f *RPCFunder) confirm(ctx context.Context, sig solana.Signature) error {
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+	poll := f.retry
+	poll.Attempts, poll.Initial, poll.Max = -1, confirmPollInterval/4, confirmPollInterval
+	err := poll.Do(ctx, func(ctx context.Context) error {
+		out, err := f.client.GetSignatureStatuses(ctx, false, sig)
+		if err != nil {
+			return err
+		}
+		if len(out.Value) != 1 || out.Value[0] == nil {
+			return errUnconfirmed
+		}
+		st := out.Value[0]
+		if st.Err != nil {
+			return fmt.Errorf("transaction %s failed: %v", sig, st.Err)
+		}
+		if st.ConfirmationStatus != rpc.ConfirmationStatusConfirmed && st.ConfirmationStatus != rpc.ConfirmationStatusFinalized {
+			return errUnconfirmed
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for %s: %w", sig, err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package devtools
+
+import "os"
+
+// lockFile is a no-op where flock is unavailable; the pool is then only safe
+// within a single process.
+func lockFile(*os.File) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,388 @@
+package devtools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	poolVersion            = 1
+	defaultPoolSize        = 4
+	defaultMinBalance      = solana.LAMPORTS_PER_SOL / 10
+	defaultTargetBalance   = solana.LAMPORTS_PER_SOL
+	defaultLeaseTTL        = 10 * time.Minute
+	defaultAirdropInterval = time.Hour
+	leaseTestTimeout       = 2 * time.Minute
+)
+
+// ErrExhausted is returned by Lease when no idle wallet holds MinBalance,
+// even after a top-up attempt.
+var ErrExhausted = errors.New("devtools: no funded wallet available")
+
+// Funder moves devnet funds.  Airdrop and Transfer return once the transfer
+// is confirmed.
+type Funder interface {
+	Balance(ctx context.Context, account solana.PublicKey) (uint64, error)
+	Airdrop(ctx context.Context, account solana.PublicKey, lamports uint64) error
+	Transfer(ctx context.Context, from solana.PrivateKey, to solana.PublicKey, lamports uint64) error
+}
+
+// PoolConfig contains the configuration for a Pool.
+type PoolConfig struct {
+	// Path of the JSON file holding the pool.  It is created if missing.
+	Path string
+	// Funder queries balances and moves funds.
+	Funder Funder
+	// Size is the number of wallets kept in the pool.  Defaults to 4.
+	Size int
+	// MinBalance is the balance a wallet needs to be leased.  Defaults to
+	// 0.1 SOL.
+	MinBalance uint64
+	// TargetBalance is what top-ups refill a wallet to.  Defaults to 1 SOL.
+	TargetBalance uint64
+	// LeaseTTL bounds how long a lease is held if it is never returned.
+	// Defaults to 10 minutes.
+	LeaseTTL time.Duration
+	// AirdropInterval is the minimum time between two faucet requests,
+	// shared by every process using the pool file.  Defaults to 1 hour.
+	AirdropInterval time.Duration
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Lease is a wallet handed out by the pool.
+type Lease struct {
+	ID      string
+	Key     solana.PrivateKey
+	Expires time.Time
+}
+
+// PublicKey returns the address of the leased wallet.
+func (l *Lease) PublicKey() solana.PublicKey { return l.Key.PublicKey() }
+
+// Pool is a file-backed set of devnet wallets with lease/return semantics.
+type Pool struct {
+	config PoolConfig
+	// mu serializes updates within the process; the file lock does the same
+	// across processes.
+	mu sync.Mutex
+}
+
+// poolState is the content of the pool file.
+type poolState struct {
+	Version     int           `json:"version"`
+	Wallets     []*poolWallet `json:"wallets"`
+	LastAirdrop time.Time     `json:"last_airdrop,omitempty"`
+}
+
+type poolWallet struct {
+	Key         string    `json:"key"` // Base58 private key
+	LeaseID     string    `json:"lease_id,omitempty"`
+	LeasedUntil time.Time `json:"leased_until,omitempty"`
+
+	key solana.PrivateKey
+}
+
+// OpenPool opens the pool file, creating it and generating wallets until
+// the pool has Size of them.  New wallets start empty and are funded by the
+// first top-up.
+func OpenPool(config PoolConfig) (*Pool, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("pool path must be provided")
+	}
+	if config.Funder == nil {
+		return nil, fmt.Errorf("funder must be provided")
+	}
+	if config.Size <= 0 {
+		config.Size = defaultPoolSize
+	}
+	if config.MinBalance == 0 {
+		config.MinBalance = defaultMinBalance
+	}
+	if config.TargetBalance == 0 {
+		config.TargetBalance = defaultTargetBalance
+	}
+	if config.TargetBalance < config.MinBalance {
+		return nil, fmt.Errorf("target balance %d is below min balance %d", config.TargetBalance, config.MinBalance)
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = defaultLeaseTTL
+	}
+	if config.AirdropInterval <= 0 {
+		config.AirdropInterval = defaultAirdropInterval
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	p := &Pool{config: config}
+	err := p.update(func(s *poolState) error {
+		for len(s.Wallets) < config.Size {
+			key, err := solana.NewRandomPrivateKey()
+			if err != nil {
+				return err
+			}
+			s.Wallets = append(s.Wallets, &poolWallet{Key: key.String(), key: key})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Lease hands out an idle wallet holding at least MinBalance, topping up
+// the pool first if none does.
+func (p *Pool) Lease(ctx context.Context) (*Lease, error) {
+	var lease *Lease
+	err := p.update(func(s *poolState) error {
+		now := p.config.Now()
+		idle := s.idle(now)
+		balances, err := p.balances(ctx, idle)
+		if err != nil {
+			return err
+		}
+		if lease = p.pick(s, idle, balances, now); lease != nil {
+			return nil
+		}
+		topUpErr := p.topUp(ctx, s, idle, balances, now)
+		if balances, err = p.balances(ctx, idle); err != nil {
+			return err
+		}
+		if lease = p.pick(s, idle, balances, now); lease != nil {
+			return nil
+		}
+		if topUpErr != nil {
+			return fmt.Errorf("%w: top-up failed: %v", ErrExhausted, topUpErr)
+		}
+		return ErrExhausted
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// LeaseT leases a wallet for the duration of a test and returns it when the
+// test finishes.  The test fails if no wallet can be leased.
+func (p *Pool) LeaseT(t testing.TB) *Lease {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), leaseTestTimeout)
+	defer cancel()
+	lease, err := p.Lease(ctx)
+	if err != nil {
+		t.Fatalf("leasing devnet wallet: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := p.Return(lease); err != nil {
+			t.Errorf("returning devnet wallet: %v", err)
+		}
+	})
+	return lease
+}
+
+// Return gives a leased wallet back to the pool.  It fails if the lease
+// expired and the wallet was leased again.
+func (p *Pool) Return(lease *Lease) error {
+	return p.update(func(s *poolState) error {
+		for _, w := range s.Wallets {
+			if w.LeaseID != "" && w.LeaseID == lease.ID {
+				w.LeaseID, w.LeasedUntil = "", time.Time{}
+				return nil
+			}
+		}
+		return fmt.Errorf("lease %s is no longer held", lease.ID)
+	})
+}
+
+// TopUp refills every idle wallet below MinBalance, e.g. from a scheduled CI
+// job.  It returns the errors of the attempts that failed.
+func (p *Pool) TopUp(ctx context.Context) error {
+	return p.update(func(s *poolState) error {
+		now := p.config.Now()
+		idle := s.idle(now)
+		balances, err := p.balances(ctx, idle)
+		if err != nil {
+			return err
+		}
+		return p.topUp(ctx, s, idle, balances, now)
+	})
+}
+
+// idle returns the wallets that are not leased or whose lease expired.
+func (s *poolState) idle(now time.Time) []*poolWallet {
+	var out []*poolWallet
+	for _, w := range s.Wallets {
+		if w.LeaseID == "" || !now.Before(w.LeasedUntil) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func (p *Pool) balances(ctx context.Context, wallets []*poolWallet) (map[*poolWallet]uint64, error) {
+	out := make(map[*poolWallet]uint64, len(wallets))
+	for _, w := range wallets {
+		b, err := p.config.Funder.Balance(ctx, w.key.PublicKey())
+		if err != nil {
+			return nil, fmt.Errorf("fetching balance of %s: %w", w.key.PublicKey(), err)
+		}
+		out[w] = b
+	}
+	return out, nil
+}
+
+// pick leases the first idle wallet holding MinBalance.
+func (p *Pool) pick(s *poolState, idle []*poolWallet, balances map[*poolWallet]uint64, now time.Time) *Lease {
+	for _, w := range idle {
+		if balances[w] < p.config.MinBalance {
+			continue
+		}
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil
+		}
+		w.LeaseID, w.LeasedUntil = hex.EncodeToString(id), now.Add(p.config.LeaseTTL)
+		return &Lease{ID: w.LeaseID, Key: w.key, Expires: w.LeasedUntil}
+	}
+	return nil
+}
+
+// topUp refills idle wallets below MinBalance to TargetBalance, preferring
+// transfers from idle wallets with a surplus over the faucet.  Balances are
+// updated in place.
+func (p *Pool) topUp(ctx context.Context, s *poolState, idle []*poolWallet, balances map[*poolWallet]uint64, now time.Time) error {
+	var errs []error
+	for _, w := range idle {
+		if balances[w] >= p.config.MinBalance {
+			continue
+		}
+		need := p.config.TargetBalance - balances[w]
+		if donor := richest(idle, balances, w); donor != nil && balances[donor] >= p.config.TargetBalance+need {
+			if err := p.config.Funder.Transfer(ctx, donor.key, w.key.PublicKey(), need); err != nil {
+				errs = append(errs, fmt.Errorf("transfer to %s: %w", w.key.PublicKey(), err))
+			} else {
+				balances[donor] -= need
+				balances[w] += need
+				continue
+			}
+		}
+		if next := s.LastAirdrop.Add(p.config.AirdropInterval); now.Before(next) {
+			errs = append(errs, fmt.Errorf("airdrop to %s: faucet rate limited until %s", w.key.PublicKey(), next.Format(time.RFC3339)))
+			continue
+		}
+		// The attempt counts against the rate limit even if it fails: the
+		// faucet limits requests, not successes.
+		s.LastAirdrop = now
+		if err := p.config.Funder.Airdrop(ctx, w.key.PublicKey(), need); err != nil {
+			errs = append(errs, fmt.Errorf("airdrop to %s: %w", w.key.PublicKey(), err))
+			continue
+		}
+		balances[w] += need
+	}
+	return errors.Join(errs...)
+}
+
+// richest returns the idle wallet other than except with the highest
+// balance.
+func richest(idle []*poolWallet, balances map[*poolWallet]uint64, except *poolWallet) *poolWallet {
+	candidates := make([]*poolWallet, 0, len(idle))
+	for _, w := range idle {
+		if w != except {
+			candidates = append(candidates, w)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return balances[candidates[i]] > balances[candidates[j]] })
+	return candidates[0]
+}
+
+// update runs fn on the pool state under the process and file locks and
+// writes the result back atomically.
+func (p *Pool) update(fn func(*poolState) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lock, err := os.OpenFile(p.config.Path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening pool lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("locking pool: %w", err)
+	}
+	defer unlockFile(lock)
+
+	s, err := p.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		// Leases and airdrop attempts made before the failure still count.
+		if saveErr := p.save(s); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+		return err
+	}
+	return p.save(s)
+}
+
+func (p *Pool) load() (*poolState, error) {
+	data, err := os.ReadFile(p.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &poolState{Version: poolVersion}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading pool: %w", err)
+	}
+	var s poolState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding pool: %w", err)
+	}
+	if s.Version != poolVersion {
+		return nil, fmt.Errorf("unsupported pool version %d", s.Version)
+	}
+	for _, w := range s.Wallets {
+		if w.key, err = solana.PrivateKeyFromBase58(w.Key); err != nil {
+			return nil, fmt.Errorf("decoding pool wallet: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+func (p *Pool) save(s *poolState) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.config.Path), ".devnet-pool-*")
+	if err != nil {
+		return fmt.Errorf("writing pool: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing pool: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing pool: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p.config.Path); err != nil {
+		return fmt.Errorf("writing pool: %w", err)
+	}
+	return nil
+}
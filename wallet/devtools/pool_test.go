@@ -0,0 +1,207 @@
+package devtools
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/clock"
+)
+
+// fakeFunder keeps balances in memory.
+type fakeFunder struct {
+	mu        sync.Mutex
+	balances  map[solana.PublicKey]uint64
+	airdrops  int
+	transfers int
+	faucetErr error
+}
+
+func newFakeFunder() *fakeFunder {
+	return &fakeFunder{balances: map[solana.PublicKey]uint64{}}
+}
+
+func (f *fakeFunder) Balance(_ context.Context, account solana.PublicKey) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.balances[account], nil
+}
+
+func (f *fakeFunder) Airdrop(_ context.Context, account solana.PublicKey, lamports uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.airdrops++
+	if f.faucetErr != nil {
+		return f.faucetErr
+	}
+	f.balances[account] += lamports
+	return nil
+}
+
+func (f *fakeFunder) Transfer(_ context.Context, from solana.PrivateKey, to solana.PublicKey, lamports uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.balances[from.PublicKey()] < lamports {
+		return errors.New("insufficient funds")
+	}
+	f.transfers++
+	f.balances[from.PublicKey()] -= lamports
+	f.balances[to] += lamports
+	return nil
+}
+
+func openPool(t *testing.T, f Funder, c *clock.Fake, size int) *Pool {
+	t.Helper()
+	p, err := OpenPool(PoolConfig{
+		Path:            filepath.Join(t.TempDir(), "pool.json"),
+		Funder:          f,
+		Size:            size,
+		MinBalance:      10,
+		TargetBalance:   100,
+		LeaseTTL:        time.Minute,
+		AirdropInterval: time.Hour,
+		Now:             c.Now,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestLeaseAndReturn(t *testing.T) {
+	f := newFakeFunder()
+	c := clock.NewFake(time.Unix(1_700_000_000, 0))
+	p := openPool(t, f, c, 2)
+	ctx := context.Background()
+
+	// The first lease funds an empty wallet from the faucet.
+	a, err := p.Lease(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), f.balances[a.PublicKey()])
+	assert.Equal(t, 1, f.airdrops)
+
+	// The second wallet is empty and the faucet is rate limited, but the
+	// leased wallet cannot donate: the pool is exhausted.
+	_, err = p.Lease(ctx)
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.ErrorContains(t, err, "rate limited")
+
+	require.NoError(t, p.Return(a))
+	b, err := p.Lease(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, a.PublicKey(), b.PublicKey())
+	assert.Error(t, p.Return(a), "a stale lease cannot be returned")
+}
+
+func TestTopUpPrefersTransfers(t *testing.T) {
+	f := newFakeFunder()
+	c := clock.NewFake(time.Unix(1_700_000_000, 0))
+	p := openPool(t, f, c, 3)
+	ctx := context.Background()
+
+	s, err := p.load()
+	require.NoError(t, err)
+	f.balances[s.Wallets[0].key.PublicKey()] = 1000
+
+	require.NoError(t, p.TopUp(ctx))
+	assert.Equal(t, 0, f.airdrops)
+	assert.Equal(t, 2, f.transfers)
+	for _, w := range s.Wallets[1:] {
+		assert.Equal(t, uint64(100), f.balances[w.key.PublicKey()])
+	}
+	assert.Equal(t, uint64(800), f.balances[s.Wallets[0].key.PublicKey()])
+}
+
+func TestAirdropRateLimitPersisted(t *testing.T) {
+	f := newFakeFunder()
+	f.faucetErr = errors.New("429 Too Many Requests")
+	c := clock.NewFake(time.Unix(1_700_000_000, 0))
+	p := openPool(t, f, c, 1)
+	ctx := context.Background()
+
+	_, err := p.Lease(ctx)
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.ErrorContains(t, err, "429")
+
+	// A second process sharing the file honours the recorded attempt.
+	q, err := OpenPool(p.config)
+	require.NoError(t, err)
+	_, err = q.Lease(ctx)
+	assert.ErrorContains(t, err, "rate limited")
+	assert.Equal(t, 1, f.airdrops)
+
+	c.Advance(time.Hour)
+	f.faucetErr = nil
+	_, err = q.Lease(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.airdrops)
+}
+
+func TestExpiredLeaseReclaimed(t *testing.T) {
+	f := newFakeFunder()
+	c := clock.NewFake(time.Unix(1_700_000_000, 0))
+	p := openPool(t, f, c, 1)
+	ctx := context.Background()
+
+	a, err := p.Lease(ctx)
+	require.NoError(t, err)
+	_, err = p.Lease(ctx)
+	assert.ErrorIs(t, err, ErrExhausted)
+
+	c.Advance(time.Minute)
+	b, err := p.Lease(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, a.PublicKey(), b.PublicKey())
+	assert.NotEqual(t, a.ID, b.ID)
+}
+
+func TestLeaseT(t *testing.T) {
+	f := newFakeFunder()
+	c := clock.NewFake(time.Unix(1_700_000_000, 0))
+	p := openPool(t, f, c, 1)
+
+	t.Run("leased", func(t *testing.T) {
+		lease := p.LeaseT(t)
+		assert.NotEmpty(t, lease.ID)
+	})
+	s, err := p.load()
+	require.NoError(t, err)
+	assert.Empty(t, s.Wallets[0].LeaseID, "the wallet is returned when the subtest ends")
+}
+
+func TestConcurrentLeasesAreDistinct(t *testing.T) {
+	f := newFakeFunder()
+	c := clock.NewFake(time.Unix(1_700_000_000, 0))
+	p := openPool(t, f, c, 4)
+	s, err := p.load()
+	require.NoError(t, err)
+	for _, w := range s.Wallets {
+		f.balances[w.key.PublicKey()] = 100
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = map[solana.PublicKey]bool{}
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease, err := p.Lease(context.Background())
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			seen[lease.PublicKey()] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, seen, 4)
+}
@@ -0,0 +1,255 @@
+package difftest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	mrand "math/rand/v2"
+
+	"solana-threshold-wallet/wallet/delegate"
+)
+
+// Subject is the implementation under test.
+type Subject interface {
+	// Keygen creates a fresh key on the named curve, "ed25519" or
+	// "secp256k1".
+	Keygen(ctx context.Context, curve string) (Key, error)
+}
+
+// Key is a key created by a Subject.  Ed25519 keys that also implement
+// delegate.TweakSigner are signed with on derived child keys too.
+type Key interface {
+	// PublicKey returns the group public key: 32 bytes for Ed25519, a SEC 1
+	// encoding for secp256k1.
+	PublicKey() []byte
+	// Sign signs message.  For secp256k1 the message is a 32-byte digest.
+	Sign(ctx context.Context, message []byte) ([]byte, error)
+	// Close releases the key.
+	Close() error
+}
+
+// Reference is an independent single-key implementation of a curve.
+type Reference interface {
+	// Verify returns an error unless signature is a valid signature of
+	// message under publicKey.
+	Verify(publicKey, message, signature []byte) error
+	// Address encodes publicKey as the chain address.
+	Address(publicKey []byte) (string, error)
+}
+
+// Addresser derives addresses from public keys.  chain.Chain implements it.
+type Addresser interface {
+	DeriveAddress(pubKey []byte) (string, error)
+}
+
+// Curve is a curve to exercise.
+type Curve struct {
+	// Name is passed to Subject.Keygen.
+	Name string
+	// Reference checks the subject's outputs.
+	Reference Reference
+	// Addresses is the wallet's address derivation under test, e.g. a
+	// chain.Chain.  Address checks are skipped when it is nil.
+	Addresses Addresser
+}
+
+// Config configures Run.
+type Config struct {
+	Subject Subject
+	Curves  []Curve
+	// Keys is the number of keys generated per curve.  Defaults to 1.
+	Keys int
+	// Signatures is the number of random messages signed per key and per
+	// derived key.  Defaults to 8.
+	Signatures int
+	// Paths are derivation paths for Ed25519 child keys.
+	Paths []string
+	// Seed seeds the message generator.  Zero picks a random seed; the seed
+	// used is recorded in the report.
+	Seed uint64
+}
+
+// Operations recorded in a Divergence.
+const (
+	OpKeygen  = "keygen"
+	OpAddress = "address"
+	OpSign    = "sign"
+	OpDerive  = "derive"
+)
+
+// Divergence is one disagreement between the subject and the reference.
+type Divergence struct {
+	Curve     string `json:"curve"`
+	Key       int    `json:"key"`
+	Operation string `json:"operation"`
+	Path      string `json:"path,omitempty"`
+	PublicKey []byte `json:"public_key,omitempty"`
+	Message   []byte `json:"message,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+func (d Divergence) String() string {
+	op := d.Operation
+	if d.Path != "" {
+		op += " " + d.Path
+	}
+	return fmt.Sprintf("%s key %d %s: %s", d.Curve, d.Key, op, d.Detail)
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	Seed        uint64       `json:"seed"`
+	Checks      int          `json:"checks"`
+	Skipped     int          `json:"skipped"`
+	Divergences []Divergence `json:"divergences,omitempty"`
+}
+
+// OK reports whether the subject agreed with the references everywhere.
+func (r *Report) OK() bool { return len(r.Divergences) == 0 }
+
+// Run exercises config.Subject on every curve and compares the results with
+// the curve's Reference.  Divergences go into the report; Run only returns
+// an error for an invalid configuration or a cancelled context.
+func Run(ctx context.Context, config Config) (*Report, error) {
+	if config.Subject == nil {
+		return nil, fmt.Errorf("subject must be provided")
+	}
+	if len(config.Curves) == 0 {
+		return nil, fmt.Errorf("at least one curve must be provided")
+	}
+	for _, c := range config.Curves {
+		if c.Name == "" || c.Reference == nil {
+			return nil, fmt.Errorf("curve %q needs a name and a reference", c.Name)
+		}
+	}
+	if config.Keys <= 0 {
+		config.Keys = 1
+	}
+	if config.Signatures <= 0 {
+		config.Signatures = 8
+	}
+	if config.Seed == 0 {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		config.Seed = binary.LittleEndian.Uint64(b[:]) | 1
+	}
+	var seed [32]byte
+	binary.LittleEndian.PutUint64(seed[:], config.Seed)
+	r := &run{config: config, rng: mrand.NewChaCha8(seed), report: &Report{Seed: config.Seed}}
+
+	for _, c := range config.Curves {
+		for i := 0; i < config.Keys; i++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			r.key(ctx, c, i)
+		}
+	}
+	return r.report, nil
+}
+
+type run struct {
+	config Config
+	rng    *mrand.ChaCha8
+	report *Report
+}
+
+func (r *run) diverge(d Divergence) { r.report.Divergences = append(r.report.Divergences, d) }
+
+// key generates key i on c and checks its address, signatures and children.
+func (r *run) key(ctx context.Context, c Curve, i int) {
+	r.report.Checks++
+	k, err := r.config.Subject.Keygen(ctx, c.Name)
+	if err != nil {
+		r.diverge(Divergence{Curve: c.Name, Key: i, Operation: OpKeygen, Detail: err.Error()})
+		return
+	}
+	defer k.Close()
+	pub := k.PublicKey()
+
+	r.address(c, Divergence{Curve: c.Name, Key: i, PublicKey: pub})
+	for s := 0; s < r.config.Signatures; s++ {
+		r.sign(ctx, c, Divergence{Curve: c.Name, Key: i, PublicKey: pub}, k.Sign)
+	}
+	if c.Name != "ed25519" {
+		return
+	}
+	ts, tweaks := k.(delegate.TweakSigner)
+	for _, path := range r.config.Paths {
+		r.report.Checks++
+		child, err := delegate.Derive(pub, nil, path)
+		if err != nil {
+			r.diverge(Divergence{Curve: c.Name, Key: i, Operation: OpDerive, Path: path, PublicKey: pub, Detail: err.Error()})
+			continue
+		}
+		base := Divergence{Curve: c.Name, Key: i, Path: path, PublicKey: child.PublicKey}
+		r.address(c, base)
+		if !tweaks {
+			r.report.Skipped += r.config.Signatures
+			continue
+		}
+		signer := child.Signer(ts)
+		for s := 0; s < r.config.Signatures; s++ {
+			r.sign(ctx, c, base, signer.Sign)
+		}
+	}
+}
+
+// address compares the wallet's address for d.PublicKey with the reference.
+func (r *run) address(c Curve, d Divergence) {
+	if c.Addresses == nil {
+		r.report.Skipped++
+		return
+	}
+	r.report.Checks++
+	d.Operation = OpAddress
+	want, err := c.Reference.Address(d.PublicKey)
+	if err != nil {
+		d.Detail = fmt.Sprintf("reference rejects public key: %v", err)
+		r.diverge(d)
+		return
+	}
+	got, err := c.Addresses.DeriveAddress(d.PublicKey)
+	switch {
+	case err != nil:
+		d.Detail = err.Error()
+		r.diverge(d)
+	case got != want:
+		d.Detail = fmt.Sprintf("wallet derives %s, reference %s", got, want)
+		r.diverge(d)
+	}
+}
+
+// sign signs a random message with sign and verifies it with the reference.
+func (r *run) sign(ctx context.Context, c Curve, d Divergence, sign func(context.Context, []byte) ([]byte, error)) {
+	r.report.Checks++
+	d.Operation = OpSign
+	d.Message = r.message(c)
+	sig, err := sign(ctx, d.Message)
+	if err != nil {
+		d.Detail = err.Error()
+		r.diverge(d)
+		return
+	}
+	if err := c.Reference.Verify(d.PublicKey, d.Message, sig); err != nil {
+		d.Signature = sig
+		d.Detail = fmt.Sprintf("reference rejects signature: %v", err)
+		r.diverge(d)
+	}
+}
+
+// message draws a 32-byte digest for secp256k1 and a message of 0 to 255
+// bytes otherwise.
+func (r *run) message(c Curve) []byte {
+	n := 32
+	if c.Name == "ed25519" {
+		n = int(r.rng.Uint64() % 256)
+	}
+	m := make([]byte, n)
+	_, _ = r.rng.Read(m)
+	return m
+}
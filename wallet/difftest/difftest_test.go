@@ -0,0 +1,229 @@
+package difftest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+
+	"filippo.io/edwards25519"
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain/evm"
+	"solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/delegate"
+)
+
+// softSubject holds whole private keys, standing in for the MPC parties.
+type softSubject struct {
+	corrupt bool // flip a bit in every secp256k1 signature
+}
+
+func (s softSubject) Keygen(_ context.Context, curve string) (Key, error) {
+	if curve == "secp256k1" {
+		d, err := rand.Int(rand.Reader, new(big.Int).Sub(k1N, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		return &k1Key{d: d.Add(d, big.NewInt(1)), corrupt: s.corrupt}, nil
+	}
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	h := sha512.Sum512(priv.Seed())
+	secret, err := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	if err != nil {
+		return nil, err
+	}
+	return &edKey{secret: secret, prefix: h[32:]}, nil
+}
+
+type edKey struct {
+	secret *edwards25519.Scalar
+	prefix []byte
+}
+
+func (k *edKey) PublicKey() []byte {
+	return new(edwards25519.Point).ScalarBaseMult(k.secret).Bytes()
+}
+
+func (k *edKey) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	return k.signTweaked(message, edwards25519.NewScalar().Bytes())
+}
+
+func (k *edKey) SignChild(_ context.Context, child *delegate.Key, message []byte) ([]byte, error) {
+	tweak := slices.Clone(child.Tweak)
+	slices.Reverse(tweak)
+	return k.signTweaked(message, tweak)
+}
+
+// signTweaked signs with the secret plus tweak, a little-endian scalar.
+func (k *edKey) signTweaked(message, tweak []byte) ([]byte, error) {
+	t, err := edwards25519.NewScalar().SetCanonicalBytes(tweak)
+	if err != nil {
+		return nil, err
+	}
+	secret := edwards25519.NewScalar().Add(k.secret, t)
+	public := new(edwards25519.Point).ScalarBaseMult(secret).Bytes()
+	nonce, _ := edwards25519.NewScalar().SetUniformBytes(hash(k.prefix, tweak, message))
+	r := new(edwards25519.Point).ScalarBaseMult(nonce).Bytes()
+	c, _ := edwards25519.NewScalar().SetUniformBytes(hash(r, public, message))
+	return append(r, edwards25519.NewScalar().MultiplyAdd(c, secret, nonce).Bytes()...), nil
+}
+
+func (k *edKey) Close() error { return nil }
+
+func hash(parts ...[]byte) []byte {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// k1N is the order of the secp256k1 group.
+var k1N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+type k1Key struct {
+	d       *big.Int
+	corrupt bool
+}
+
+func (k *k1Key) PublicKey() []byte {
+	q, err := verify.Secp256k1PublicKey(k.d.FillBytes(make([]byte, 32)))
+	if err != nil {
+		panic(err)
+	}
+	out := make([]byte, 33)
+	out[0] = 2 | q[64]&1
+	copy(out[1:], q[1:33])
+	return out
+}
+
+func (k *k1Key) Sign(_ context.Context, digest []byte) ([]byte, error) {
+	for {
+		nonce, err := rand.Int(rand.Reader, k1N)
+		if err != nil {
+			return nil, err
+		}
+		if nonce.Sign() == 0 {
+			continue
+		}
+		R, err := verify.Secp256k1PublicKey(nonce.FillBytes(make([]byte, 32)))
+		if err != nil {
+			return nil, err
+		}
+		r := new(big.Int).SetBytes(R[1:33])
+		r.Mod(r, k1N)
+		s := new(big.Int).Mul(r, k.d)
+		s.Add(s, new(big.Int).SetBytes(digest))
+		s.Mul(s, nonce.ModInverse(nonce, k1N)).Mod(s, k1N)
+		if r.Sign() == 0 || s.Sign() == 0 {
+			continue
+		}
+		out := make([]byte, 64)
+		r.FillBytes(out[:32])
+		s.FillBytes(out[32:])
+		if k.corrupt {
+			out[40] ^= 1
+		}
+		return out, nil
+	}
+}
+
+func (k *k1Key) Close() error { return nil }
+
+// badAddresser drops the EIP-55 checksum.
+type badAddresser struct{}
+
+func (badAddresser) DeriveAddress(pubKey []byte) (string, error) {
+	a, err := Secp256k1Reference{}.Address(pubKey)
+	return strings.ToLower(a), err
+}
+
+func curves(t *testing.T) []Curve {
+	t.Helper()
+	sol, err := solana.New(solana.Config{ID: "solana-test", RPCEndpoint: "http://127.0.0.1:0"})
+	require.NoError(t, err)
+	eth, err := evm.New(evm.Config{ID: "evm-test", RPCEndpoint: "http://127.0.0.1:0", ChainID: big.NewInt(1)})
+	require.NoError(t, err)
+	return []Curve{
+		{Name: "ed25519", Reference: Ed25519Reference{}, Addresses: sol},
+		{Name: "secp256k1", Reference: Secp256k1Reference{}, Addresses: eth},
+	}
+}
+
+func TestRunAgreesWithReferences(t *testing.T) {
+	report, err := Run(context.Background(), Config{
+		Subject:    softSubject{},
+		Curves:     curves(t),
+		Keys:       2,
+		Signatures: 4,
+		Paths:      []string{"cbmpc/1/0", "cbmpc/1/1"},
+	})
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%v", report.Divergences)
+	assert.NotZero(t, report.Seed)
+	assert.Zero(t, report.Skipped)
+	// Per ed25519 key: keygen, address, 4 signatures and, per path,
+	// derive, address and 4 signatures.  Per secp256k1 key: keygen,
+	// address and 4 signatures.
+	assert.Equal(t, 2*(6+2*6)+2*6, report.Checks)
+}
+
+func TestRunFlagsDivergence(t *testing.T) {
+	cs := curves(t)
+	cs[1].Addresses = badAddresser{}
+	report, err := Run(context.Background(), Config{
+		Subject:    softSubject{corrupt: true},
+		Curves:     cs,
+		Signatures: 3,
+		Seed:       42,
+	})
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, uint64(42), report.Seed)
+
+	ops := map[string]int{}
+	for _, d := range report.Divergences {
+		assert.Equal(t, "secp256k1", d.Curve, d.String())
+		ops[d.Operation]++
+	}
+	assert.Equal(t, map[string]int{OpAddress: 1, OpSign: 3}, ops)
+	for _, d := range report.Divergences {
+		if d.Operation == OpSign {
+			assert.Len(t, d.Message, 32)
+			assert.NotEmpty(t, d.Signature)
+		}
+	}
+}
+
+func TestSecp256k1ReferenceVectors(t *testing.T) {
+	ref := Secp256k1Reference{}
+	key := &k1Key{d: big.NewInt(1)}
+	addr, err := ref.Address(key.PublicKey())
+	require.NoError(t, err)
+	assert.Equal(t, "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf", addr)
+
+	digest := make([]byte, 32)
+	digest[31] = 7
+	sig, err := key.Sign(context.Background(), digest)
+	require.NoError(t, err)
+	require.NoError(t, ref.Verify(key.PublicKey(), digest, sig))
+
+	// The mirrored s is also a valid ECDSA signature.
+	high := append([]byte{}, sig...)
+	new(big.Int).Sub(k1N, new(big.Int).SetBytes(sig[32:])).FillBytes(high[32:])
+	assert.NoError(t, ref.Verify(key.PublicKey(), digest, high))
+
+	digest[0] ^= 1
+	assert.ErrorIs(t, ref.Verify(key.PublicKey(), digest, sig), errInvalidSignature)
+	assert.Error(t, ref.Verify([]byte{2, 1}, digest, sig))
+}
@@ -0,0 +1,26 @@
+// Package difftest cross-checks the threshold wallet against single-key
+// reference implementations.
+//
+// Run drives a Subject – normally the MPC protocols, with every party in one
+// process – through keygen, derive and sign over random inputs.  It checks
+// each result against a Reference for the curve:
+//
+//   - the address the wallet derives from the group public key must equal
+//     the reference encoding (base58 for Ed25519, EIP-55 for secp256k1);
+//   - every signature must verify under the group public key with the
+//     reference verifier;
+//   - for Ed25519, child keys from delegate.Derive must have matching
+//     addresses, and keys that implement delegate.TweakSigner must produce
+//     child signatures that verify under the child public key.
+//
+// Mismatches and subject errors are recorded as Divergences in the Report
+// instead of stopping the run.  The report also records the seed that drew
+// the messages, so a failing CI run can be replayed with -seed.
+//
+// Ed25519Reference uses crypto/ed25519 and base58.  Secp256k1Reference uses
+// the verify package, which builds on github.com/decred/dcrd/dcrec/secp256k1,
+// and which the EVM chain code shares; it checks the MPC engine's signatures
+// and the wallet's address encoding, not that code itself.  A build that
+// wants a second opinion, e.g. from go-ethereum, can pass its own Reference
+// in Curve.
+package difftest
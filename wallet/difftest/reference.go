@@ -0,0 +1,88 @@
+package difftest
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/mr-tron/base58"
+	"golang.org/x/crypto/sha3"
+)
+
+// Ensure the references implement Reference
+var (
+	_ Reference = Ed25519Reference{}
+	_ Reference = Secp256k1Reference{}
+)
+
+// errInvalidSignature is returned by the references for well-formed
+// signatures that do not verify.
+var errInvalidSignature = errors.New("invalid signature")
+
+// Ed25519Reference checks Ed25519 keys with crypto/ed25519 and encodes
+// addresses as Solana does, in base58.
+type Ed25519Reference struct{}
+
+func (Ed25519Reference) Verify(publicKey, message, signature []byte) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+	if len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("signature must be %d bytes, got %d", ed25519.SignatureSize, len(signature))
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+func (Ed25519Reference) Address(publicKey []byte) (string, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+	return base58.Encode(publicKey), nil
+}
+
+// Secp256k1Reference checks secp256k1 ECDSA signatures of 32-byte digests
+// with verify.Secp256k1 and encodes addresses as EIP-55 checksummed Ethereum
+// addresses.  Signatures may be DER or 64-byte r||s; high-s signatures are
+// valid ECDSA and are accepted.
+type Secp256k1Reference struct{}
+
+func (Secp256k1Reference) Verify(publicKey, message, signature []byte) error {
+	if len(message) != 32 {
+		return fmt.Errorf("message must be a 32-byte digest, got %d bytes", len(message))
+	}
+	err := verify.Secp256k1(publicKey, message, signature)
+	if errors.Is(err, verify.ErrInvalidSignature) {
+		return errInvalidSignature
+	}
+	return err
+}
+
+func (Secp256k1Reference) Address(publicKey []byte) (string, error) {
+	pub, err := verify.UncompressSecp256k1(publicKey)
+	if err != nil {
+		return "", err
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(pub[1:])
+	addr := hex.EncodeToString(h.Sum(nil)[12:])
+
+	// EIP-55: upper-case every letter whose nibble in keccak(addr) is >= 8.
+	h = sha3.NewLegacyKeccak256()
+	h.Write([]byte(addr))
+	sum := h.Sum(nil)
+	var b strings.Builder
+	b.WriteString("0x")
+	for i, c := range addr {
+		if c >= 'a' && (sum[i/2]>>(4*(1-uint(i%2))))&0xf >= 8 {
+			c -= 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), nil
+}
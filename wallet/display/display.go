@@ -0,0 +1,317 @@
+package display
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+const (
+	// Version is the encoding version written by Payload.Encode.
+	Version = 1
+
+	defaultWidth = 16
+	defaultLines = 2
+	// checksumLen is the number of hex characters of the address checksum.
+	checksumLen = 6
+	// maxLen bounds titles and lines so they fit their one-byte length prefix.
+	maxLen = 255
+)
+
+// magic identifies an encoded Payload.
+var magic = []byte("CBDP")
+
+// Asset describes how a chain's native amounts are displayed.
+type Asset struct {
+	Ticker   string // Unit symbol, e.g. "SOL" or "ETH"
+	Decimals int    // Number of smallest units per whole unit, as a power of ten
+}
+
+// Options controls the shape of the generated screens.
+type Options struct {
+	// Width is the number of characters per line.  Defaults to 16.
+	Width int
+	// Lines is the number of lines below the title.  Defaults to 2.
+	Lines int
+	// FullAddresses adds screens paging through every address in full after
+	// its truncated form.
+	FullAddresses bool
+	// FeeAsset is the asset network fees are paid in when it differs from
+	// the transferred asset, as for token transfers.  Defaults to the
+	// transferred asset; required for summaries of token transfers.
+	FeeAsset Asset
+}
+
+// Screen is a single page on the device.
+type Screen struct {
+	Title string
+	Lines []string
+}
+
+// Payload is the approval content for one transaction.
+type Payload struct {
+	Screens []Screen
+	// Digest is the SummaryDigest of the summary the payload was built from.
+	Digest [32]byte
+}
+
+// Build renders summary for a device with the given options.
+func Build(summary *chain.Summary, asset Asset, opts Options) (*Payload, error) {
+	if summary == nil || summary.Amount == nil || summary.Fee == nil {
+		return nil, fmt.Errorf("summary is incomplete")
+	}
+	if asset.Ticker == "" || asset.Decimals < 0 {
+		return nil, fmt.Errorf("invalid asset")
+	}
+	if summary.Token != "" && opts.FeeAsset.Ticker == "" {
+		return nil, fmt.Errorf("token transfers require a fee asset")
+	}
+	if opts.FeeAsset.Ticker == "" {
+		opts.FeeAsset = asset
+	}
+	if opts.Width == 0 {
+		opts.Width = defaultWidth
+	}
+	if opts.Lines == 0 {
+		opts.Lines = defaultLines
+	}
+	if opts.Width < checksumLen+4 || opts.Width > maxLen || opts.Lines < 1 || opts.Lines > maxLen {
+		return nil, fmt.Errorf("unsupported screen geometry %dx%d", opts.Width, opts.Lines)
+	}
+
+	b := &builder{opts: opts}
+	b.field("Review", "transfer", summary.Chain)
+	b.field("Amount", FormatAmount(summary.Amount, asset))
+	if summary.Token != "" {
+		b.address("Token", summary.Token)
+	}
+	if outputs := summary.Outputs; len(outputs) > 0 {
+		for i, o := range outputs {
+			b.address(fmt.Sprintf("To %d/%d", i+1, len(outputs)), o.To)
+			b.field(fmt.Sprintf("Amount %d/%d", i+1, len(outputs)), FormatAmount(o.Amount, asset))
+		}
+	} else {
+		b.address("To", summary.To)
+	}
+	b.address("From", summary.From)
+	b.field("Max fee", FormatAmount(summary.Fee, opts.FeeAsset))
+	b.field("Approve", "transfer?")
+	return &Payload{Screens: b.screens, Digest: SummaryDigest(summary)}, nil
+}
+
+// builder accumulates screens for a fixed geometry.
+type builder struct {
+	opts    Options
+	screens []Screen
+}
+
+// field lays out values on as many screens as needed.  Each value starts on
+// a new line and is wrapped at the screen width.
+func (b *builder) field(title string, values ...string) {
+	var lines []string
+	for _, v := range values {
+		lines = append(lines, chunk(sanitize(v), b.opts.Width)...)
+	}
+	pages := (len(lines) + b.opts.Lines - 1) / b.opts.Lines
+	for p := 0; p < pages; p++ {
+		end := min((p+1)*b.opts.Lines, len(lines))
+		t := title
+		if pages > 1 {
+			t = fmt.Sprintf("%s (%d/%d)", title, p+1, pages)
+		}
+		b.screens = append(b.screens, Screen{Title: truncate(t, b.opts.Width), Lines: lines[p*b.opts.Lines : end]})
+	}
+}
+
+// address renders the truncated form with its checksum and, if requested,
+// the full address.
+func (b *builder) address(title, addr string) {
+	addr = sanitize(addr)
+	b.field(title, TruncateAddress(addr, b.opts.Width), "chk "+Checksum(addr))
+	if b.opts.FullAddresses {
+		b.field(title+" full", addr)
+	}
+}
+
+// FormatAmount renders an amount in smallest units as a decimal number of
+// whole units followed by the ticker, dropping trailing zeros.
+func FormatAmount(amount *big.Int, asset Asset) string {
+	s := new(big.Int).Abs(amount).String()
+	if asset.Decimals > 0 {
+		if len(s) <= asset.Decimals {
+			s = strings.Repeat("0", asset.Decimals-len(s)+1) + s
+		}
+		whole, frac := s[:len(s)-asset.Decimals], strings.TrimRight(s[len(s)-asset.Decimals:], "0")
+		s = whole
+		if frac != "" {
+			s += "." + frac
+		}
+	}
+	if amount.Sign() < 0 {
+		s = "-" + s
+	}
+	return s + " " + asset.Ticker
+}
+
+// TruncateAddress shortens addr to fit width characters by keeping its head
+// and tail around "..".  Addresses that already fit are returned unchanged.
+func TruncateAddress(addr string, width int) string {
+	if len(addr) <= width {
+		return addr
+	}
+	keep := width - 2
+	head := (keep + 1) / 2
+	return addr[:head] + ".." + addr[len(addr)-(keep-head):]
+}
+
+// Checksum returns a short uppercase hex checksum of addr for comparing
+// truncated addresses across devices.
+func Checksum(addr string) string {
+	sum := sha256.Sum256([]byte(addr))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))[:checksumLen]
+}
+
+// chunk splits s into lines of at most width characters.
+func chunk(s string, width int) []string {
+	if s == "" {
+		return []string{""}
+	}
+	var lines []string
+	for len(s) > width {
+		lines = append(lines, s[:width])
+		s = s[width:]
+	}
+	return append(lines, s)
+}
+
+// truncate cuts s to width characters.
+func truncate(s string, width int) string {
+	if len(s) > width {
+		return s[:width]
+	}
+	return s
+}
+
+// sanitize replaces everything outside printable ASCII, which is all
+// constrained devices can render.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// SummaryDigest hashes the fields of s in a fixed, unambiguous encoding.  It
+// is the digest carried by payloads built from s, so the receiver of a device
+// approval can check it against its own decoding of the transaction.
+func SummaryDigest(s *chain.Summary) [32]byte {
+	h := sha256.New()
+	fields := []string{s.Chain, s.From, s.To, s.Amount.String(), s.Fee.String()}
+	if s.Token != "" {
+		fields = append(fields, s.Token)
+	}
+	for _, o := range s.Outputs {
+		fields = append(fields, o.To, o.Amount.String())
+	}
+	for _, f := range fields {
+		binary.Write(h, binary.BigEndian, uint32(len(f)))
+		h.Write([]byte(f))
+	}
+	var out [32]byte
+	h.Sum(out[:0])
+	return out
+}
+
+// Encode serializes the payload as
+//
+//	"CBDP" | version | digest[32] | screen count | screens
+//
+// where each screen is a length-prefixed title, a line count and
+// length-prefixed lines.  All counts and lengths are single bytes.
+func (p *Payload) Encode() ([]byte, error) {
+	if len(p.Screens) > maxLen {
+		return nil, fmt.Errorf("too many screens: %d", len(p.Screens))
+	}
+	var buf bytes.Buffer
+	buf.Write(magic)
+	buf.WriteByte(Version)
+	buf.Write(p.Digest[:])
+	buf.WriteByte(byte(len(p.Screens)))
+	for i, s := range p.Screens {
+		if len(s.Title) > maxLen || len(s.Lines) > maxLen {
+			return nil, fmt.Errorf("screen %d too large", i)
+		}
+		buf.WriteByte(byte(len(s.Title)))
+		buf.WriteString(s.Title)
+		buf.WriteByte(byte(len(s.Lines)))
+		for _, l := range s.Lines {
+			if len(l) > maxLen {
+				return nil, fmt.Errorf("screen %d line too long", i)
+			}
+			buf.WriteByte(byte(len(l)))
+			buf.WriteString(l)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode parses the output of Encode.
+func Decode(data []byte) (*Payload, error) {
+	r := bytes.NewReader(data)
+	head := make([]byte, len(magic)+1)
+	if _, err := r.Read(head); err != nil || !bytes.Equal(head[:len(magic)], magic) {
+		return nil, fmt.Errorf("not a display payload")
+	}
+	if head[len(magic)] != Version {
+		return nil, fmt.Errorf("unsupported display payload version %d", head[len(magic)])
+	}
+	p := &Payload{}
+	if n, _ := r.Read(p.Digest[:]); n != len(p.Digest) {
+		return nil, fmt.Errorf("truncated display payload")
+	}
+	readString := func() (string, error) {
+		n, err := r.ReadByte()
+		if err != nil {
+			return "", fmt.Errorf("truncated display payload")
+		}
+		s := make([]byte, n)
+		if m, _ := r.Read(s); m != int(n) {
+			return "", fmt.Errorf("truncated display payload")
+		}
+		return string(s), nil
+	}
+	count, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("truncated display payload")
+	}
+	for i := 0; i < int(count); i++ {
+		var s Screen
+		if s.Title, err = readString(); err != nil {
+			return nil, err
+		}
+		lines, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("truncated display payload")
+		}
+		for j := 0; j < int(lines); j++ {
+			l, err := readString()
+			if err != nil {
+				return nil, err
+			}
+			s.Lines = append(s.Lines, l)
+		}
+		p.Screens = append(p.Screens, s)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("trailing data after display payload")
+	}
+	return p, nil
+}
@@ -0,0 +1,126 @@
+package display
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+var sol = Asset{Ticker: "SOL", Decimals: 9}
+
+func testSummary() *chain.Summary {
+	return &chain.Summary{
+		Chain:  "solana-devnet",
+		From:   "7xKXtg2CW87d97TXJSDpbD5jBkheTqA83TZRuJosgAsU",
+		To:     "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM",
+		Amount: big.NewInt(1_500_000_000),
+		Fee:    big.NewInt(5_003),
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	for _, tc := range []struct {
+		amount int64
+		asset  Asset
+		want   string
+	}{
+		{1_500_000_000, sol, "1.5 SOL"},
+		{5_003, sol, "0.000005003 SOL"},
+		{2_000_000_000, sol, "2 SOL"},
+		{0, sol, "0 SOL"},
+		{-42, sol, "-0.000000042 SOL"},
+		{1234, Asset{Ticker: "T", Decimals: 0}, "1234 T"},
+	} {
+		assert.Equal(t, tc.want, FormatAmount(big.NewInt(tc.amount), tc.asset))
+	}
+}
+
+func TestTruncateAddressAndChecksum(t *testing.T) {
+	addr := testSummary().From
+	short := TruncateAddress(addr, 16)
+	assert.Equal(t, "7xKXtg2..JosgAsU", short)
+	assert.Equal(t, "abc", TruncateAddress("abc", 16))
+
+	assert.Len(t, Checksum(addr), checksumLen)
+	assert.NotEqual(t, Checksum(addr), Checksum(testSummary().To))
+}
+
+func TestBuild(t *testing.T) {
+	p, err := Build(testSummary(), sol, Options{})
+	require.NoError(t, err)
+
+	var titles []string
+	for _, s := range p.Screens {
+		titles = append(titles, s.Title)
+		assert.LessOrEqual(t, len(s.Lines), defaultLines)
+		for _, l := range s.Lines {
+			assert.LessOrEqual(t, len(l), defaultWidth)
+		}
+	}
+	assert.Equal(t, []string{"Review", "Amount", "To", "From", "Max fee", "Approve"}, titles)
+	assert.Equal(t, []string{"1.5 SOL"}, p.Screens[1].Lines)
+	assert.Equal(t, "chk "+Checksum(testSummary().To), p.Screens[2].Lines[1])
+	assert.Equal(t, SummaryDigest(testSummary()), p.Digest)
+}
+
+func TestBuildTokenTransfer(t *testing.T) {
+	summary := testSummary()
+	summary.Token = "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"
+	usdc := Asset{Ticker: "USDC", Decimals: 6}
+
+	_, err := Build(summary, usdc, Options{})
+	assert.Error(t, err, "the fee asset is required")
+
+	p, err := Build(summary, usdc, Options{FeeAsset: sol})
+	require.NoError(t, err)
+	assert.Equal(t, "Token", p.Screens[2].Title)
+	assert.Equal(t, []string{"1500 USDC"}, p.Screens[1].Lines)
+	assert.Equal(t, []string{"0.000005003 SOL"}, p.Screens[5].Lines)
+	assert.NotEqual(t, SummaryDigest(testSummary()), p.Digest)
+}
+
+func TestBuildChunksLongFields(t *testing.T) {
+	p, err := Build(testSummary(), sol, Options{FullAddresses: true})
+	require.NoError(t, err)
+
+	var full []string
+	for _, s := range p.Screens {
+		if len(s.Title) >= 7 && s.Title[:7] == "To full" {
+			full = append(full, s.Title)
+		}
+	}
+	// 44 characters at 16 per line over 2 lines per screen.
+	assert.Equal(t, []string{"To full (1/2)", "To full (2/2)"}, full)
+}
+
+func TestBuildRejectsBadInput(t *testing.T) {
+	_, err := Build(&chain.Summary{}, sol, Options{})
+	assert.Error(t, err)
+	_, err = Build(testSummary(), Asset{}, Options{})
+	assert.Error(t, err)
+	_, err = Build(testSummary(), sol, Options{Width: 4})
+	assert.Error(t, err)
+}
+
+func TestEncodeDecode(t *testing.T) {
+	s := testSummary()
+	s.To = "bad\naddré"
+	p, err := Build(s, sol, Options{Width: 20, Lines: 4, FullAddresses: true})
+	require.NoError(t, err)
+	assert.Equal(t, "bad?addr?", p.Screens[2].Lines[0])
+
+	data, err := p.Encode()
+	require.NoError(t, err)
+	got, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	_, err = Decode(data[:len(data)-1])
+	assert.Error(t, err)
+	_, err = Decode(append(data, 0))
+	assert.Error(t, err)
+}
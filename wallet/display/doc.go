@@ -0,0 +1,35 @@
+// Package display turns decoded transactions into compact approval payloads
+// for constrained devices — hardware approvers with a few lines of ASCII and
+// two buttons that take part in signing as ordinary MPC parties.
+//
+// A `Payload` is a sequence of `Screen`s, each a short title and a fixed
+// number of fixed-width lines:
+//
+//	Review          Amount          To              …  Approve
+//	transfer        1.5 SOL         7xKXtg…osgAsU      transfer?
+//	solana-devnet                   chk 3F2A9C
+//
+// Amounts are rendered in whole units with the asset's decimals.  Addresses
+// are truncated to their head and tail followed by a short checksum, which the
+// operator compares against the checksum shown by the wallet that built the
+// transaction; `Options.FullAddresses` additionally pages through the whole
+// address.  Values that do not fit on one screen are chunked over several
+// screens titled "To (1/3)", "To (2/3)", ….
+//
+// `Payload.Encode` produces the length-prefixed binary form sent to devices.
+// `Payload.Digest` is the `SummaryDigest` of the summary the payload was built
+// from, so the device can include it in its approval and the coordinator can
+// check it against its own decoding of the transaction.
+//
+// Approvers with a full screen — the operator shell, mobile apps and approval
+// webhooks — get a `Prompt` from a `Formatter` instead: a localized headline
+// such as
+//
+//	Send 0.010000000 SOL (~$1.83) to 9WzD…AWWM
+//
+// followed by detail lines for the sender, fee and network.  Amounts keep
+// every decimal of the asset, and the `Locale` decides separators and
+// wording.  Fiat values come from a price.Oracle; the quotes used are
+// returned with the prompt, and an amount whose price is unavailable is shown
+// without one.  The prompt carries the same digest as the payload.
+package display
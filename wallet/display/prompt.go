@@ -0,0 +1,278 @@
+package display
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/price"
+)
+
+// Phrases are the fmt templates a prompt is worded with.
+type Phrases struct {
+	Send      string // amount, recipient
+	SendMany  string // total amount, number of recipients
+	Recipient string // amount, recipient; one per output of a batch
+	Token     string // token address
+	From      string // sender
+	Fee       string // maximum fee
+	Network   string // chain
+}
+
+// Locale controls the wording of prompts and how numbers are written.
+type Locale struct {
+	Tag     string // BCP 47 tag, e.g. "en-US"
+	Decimal string // Decimal separator
+	Group   string // Thousands separator
+	// SymbolAfter writes currency symbols after the number, as in "1,83 €".
+	// The symbol is always separated by a no-break space, never wrapped.
+	SymbolAfter bool
+	Phrases     Phrases
+}
+
+var (
+	// LocaleEnUS is the default locale.
+	LocaleEnUS = Locale{Tag: "en-US", Decimal: ".", Group: ",", Phrases: Phrases{
+		Send:      "Send %s to %s",
+		SendMany:  "Send %s to %d recipients",
+		Recipient: "%s to %s",
+		Token:     "Token %s",
+		From:      "From %s",
+		Fee:       "Network fee up to %s",
+		Network:   "Network %s",
+	}}
+	// LocaleDeDE is German as written in Germany.
+	LocaleDeDE = Locale{Tag: "de-DE", Decimal: ",", Group: ".", SymbolAfter: true, Phrases: Phrases{
+		Send:      "Sende %s an %s",
+		SendMany:  "Sende %s an %d Empfänger",
+		Recipient: "%s an %s",
+		Token:     "Token %s",
+		From:      "Von %s",
+		Fee:       "Netzwerkgebühr bis zu %s",
+		Network:   "Netzwerk %s",
+	}}
+	// LocaleFrFR is French as written in France.
+	LocaleFrFR = Locale{Tag: "fr-FR", Decimal: ",", Group: " ", SymbolAfter: true, Phrases: Phrases{
+		Send:      "Envoyer %s à %s",
+		SendMany:  "Envoyer %s à %d destinataires",
+		Recipient: "%s à %s",
+		Token:     "Jeton %s",
+		From:      "De %s",
+		Fee:       "Frais de réseau jusqu'à %s",
+		Network:   "Réseau %s",
+	}}
+)
+
+var locales = []Locale{LocaleEnUS, LocaleDeDE, LocaleFrFR}
+
+// LookupLocale returns the built-in locale for tag, falling back to one of
+// the same language ("de" or "de-AT" finds de-DE).
+func LookupLocale(tag string) (Locale, error) {
+	for _, l := range locales {
+		if strings.EqualFold(l.Tag, tag) {
+			return l, nil
+		}
+	}
+	lang, _, _ := strings.Cut(tag, "-")
+	for _, l := range locales {
+		if l2, _, _ := strings.Cut(l.Tag, "-"); strings.EqualFold(l2, lang) {
+			return l, nil
+		}
+	}
+	return Locale{}, fmt.Errorf("unsupported locale %q", tag)
+}
+
+// currency is how a fiat currency is written.
+type currency struct {
+	symbol string
+	digits int // Minor units
+}
+
+var currencies = map[string]currency{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"CHF": {"CHF", 2},
+}
+
+// FormatAmount writes an amount in smallest units with every decimal of the
+// asset, so that a prompt never hides precision: 10000000 lamports are
+// "0.010000000 SOL".
+func (l Locale) FormatAmount(amount *big.Int, asset Asset) string {
+	s := new(big.Int).Abs(amount).String()
+	whole, frac := s, ""
+	if asset.Decimals > 0 {
+		if len(s) <= asset.Decimals {
+			s = strings.Repeat("0", asset.Decimals-len(s)+1) + s
+		}
+		whole, frac = s[:len(s)-asset.Decimals], s[len(s)-asset.Decimals:]
+	}
+	return l.number(amount.Sign() < 0, whole, frac) + " " + asset.Ticker
+}
+
+// FormatFiat writes v in the given ISO 4217 currency, rounded to its minor
+// units.
+func (l Locale) FormatFiat(v *big.Rat, code string) string {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		c = currency{symbol: strings.ToUpper(code), digits: 2}
+	}
+	s := new(big.Rat).Abs(v).FloatString(c.digits)
+	whole, frac, _ := strings.Cut(s, ".")
+	n := l.number(v.Sign() < 0 && strings.Trim(s, "0.") != "", whole, frac)
+	switch {
+	case l.SymbolAfter:
+		return n + "\u00a0" + c.symbol
+	case c.symbol == strings.ToUpper(code):
+		return c.symbol + "\u00a0" + n
+	default:
+		return c.symbol + n
+	}
+}
+
+// number joins the digits of a decimal with the locale's separators.
+func (l Locale) number(negative bool, whole, frac string) string {
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(d)
+	}
+	if frac != "" {
+		b.WriteString(l.Decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// Formatter renders transaction summaries as localized prompts for human
+// approvers.  The same Prompt serves the operator shell, mobile approvers and
+// approval webhooks.
+type Formatter struct {
+	// Locale defaults to LocaleEnUS.
+	Locale Locale
+	// Currency is the ISO 4217 code fiat values are shown in.  Fiat values
+	// are left out when it or Oracle is unset.
+	Currency string
+	// Oracle supplies prices.  An amount whose price is unavailable is shown
+	// without a fiat value rather than failing the prompt, so a price feed
+	// outage never blocks approvals.
+	Oracle price.Oracle
+	// AddressChars is the number of characters kept at either end of a
+	// truncated address.  Defaults to 4.
+	AddressChars int
+}
+
+// Prompt is the human-readable description of a transaction.
+type Prompt struct {
+	Locale   string   `json:"locale"`
+	Headline string   `json:"headline"` // e.g. "Send 0.010000000 SOL (~$1.83) to 9WzD…AWWM"
+	Details  []string `json:"details"`
+	// Quotes are the prices the fiat values are based on.
+	Quotes []*price.Quote `json:"quotes,omitempty"`
+	// Digest is the hex SummaryDigest of the summary, as in Payload.Digest.
+	Digest string `json:"digest"`
+}
+
+// Prompt renders summary, whose amounts are in asset and whose fee is in
+// feeAsset.  feeAsset defaults to asset and is required for token transfers.
+func (f *Formatter) Prompt(ctx context.Context, summary *chain.Summary, asset, feeAsset Asset) (*Prompt, error) {
+	if summary == nil || summary.Amount == nil || summary.Fee == nil {
+		return nil, fmt.Errorf("summary is incomplete")
+	}
+	if asset.Ticker == "" || asset.Decimals < 0 {
+		return nil, fmt.Errorf("invalid asset")
+	}
+	if summary.Token != "" && feeAsset.Ticker == "" {
+		return nil, fmt.Errorf("token transfers require a fee asset")
+	}
+	if feeAsset.Ticker == "" {
+		feeAsset = asset
+	}
+	r := &render{f: f, ctx: ctx, locale: f.Locale, quotes: make(map[string]*price.Quote)}
+	if r.locale.Tag == "" {
+		r.locale = LocaleEnUS
+	}
+	ph := r.locale.Phrases
+
+	p := &Prompt{Locale: r.locale.Tag}
+	digest := SummaryDigest(summary)
+	p.Digest = hex.EncodeToString(digest[:])
+	if outputs := summary.Outputs; len(outputs) > 0 {
+		p.Headline = fmt.Sprintf(ph.SendMany, r.value(summary.Amount, asset), len(outputs))
+		for _, o := range outputs {
+			p.Details = append(p.Details, fmt.Sprintf(ph.Recipient, r.value(o.Amount, asset), r.address(o.To)))
+		}
+	} else {
+		p.Headline = fmt.Sprintf(ph.Send, r.value(summary.Amount, asset), r.address(summary.To))
+	}
+	if summary.Token != "" {
+		p.Details = append(p.Details, fmt.Sprintf(ph.Token, r.address(summary.Token)))
+	}
+	p.Details = append(p.Details,
+		fmt.Sprintf(ph.From, r.address(summary.From)),
+		fmt.Sprintf(ph.Fee, r.value(summary.Fee, feeAsset)),
+		fmt.Sprintf(ph.Network, sanitize(summary.Chain)),
+	)
+	for _, t := range r.order {
+		p.Quotes = append(p.Quotes, r.quotes[t])
+	}
+	return p, nil
+}
+
+// render holds the state of one Prompt call.
+type render struct {
+	f      *Formatter
+	ctx    context.Context
+	locale Locale
+	quotes map[string]*price.Quote // By ticker; nil when unavailable
+	order  []string
+}
+
+// value writes an amount followed by its approximate fiat value, if known.
+func (r *render) value(amount *big.Int, asset Asset) string {
+	s := r.locale.FormatAmount(amount, asset)
+	if q := r.quote(asset.Ticker); q != nil {
+		s += " (~" + r.locale.FormatFiat(q.Value(amount, asset.Decimals), q.Currency) + ")"
+	}
+	return s
+}
+
+// quote fetches the price of ticker once per prompt.
+func (r *render) quote(ticker string) *price.Quote {
+	if r.f.Oracle == nil || r.f.Currency == "" {
+		return nil
+	}
+	if q, seen := r.quotes[ticker]; seen {
+		return q
+	}
+	q, err := r.f.Oracle.Quote(r.ctx, ticker, r.f.Currency)
+	if err != nil || q == nil || q.Price == nil {
+		q = nil
+	} else {
+		r.order = append(r.order, ticker)
+	}
+	r.quotes[ticker] = q
+	return q
+}
+
+// address shortens addr to its head and tail around an ellipsis.
+func (r *render) address(addr string) string {
+	addr = sanitize(addr)
+	n := r.f.AddressChars
+	if n <= 0 {
+		n = 4
+	}
+	if len(addr) <= 2*n+1 {
+		return addr
+	}
+	return addr[:n] + "…" + addr[len(addr)-n:]
+}
@@ -0,0 +1,94 @@
+package display
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/price"
+)
+
+func TestPrompt(t *testing.T) {
+	prices, err := price.ParseStatic("USD", []string{"SOL=183.20"})
+	require.NoError(t, err)
+	s := testSummary()
+	s.Amount = big.NewInt(10_000_000)
+
+	f := &Formatter{Currency: "USD", Oracle: prices}
+	p, err := f.Prompt(context.Background(), s, sol, Asset{})
+	require.NoError(t, err)
+	assert.Equal(t, "en-US", p.Locale)
+	assert.Equal(t, "Send 0.010000000 SOL (~$1.83) to 9WzD…AWWM", p.Headline)
+	assert.Equal(t, []string{
+		"From 7xKX…gAsU",
+		"Network fee up to 0.000005003 SOL (~$0.00)",
+		"Network solana-devnet",
+	}, p.Details)
+	require.Len(t, p.Quotes, 1)
+	assert.Equal(t, "SOL", p.Quotes[0].Asset)
+	digest := SummaryDigest(s)
+	assert.Equal(t, hex.EncodeToString(digest[:]), p.Digest)
+
+	de := &Formatter{Locale: LocaleDeDE, Currency: "EUR", Oracle: price.OracleFunc(func(context.Context, string, string) (*price.Quote, error) {
+		return &price.Quote{Currency: "EUR", Price: big.NewRat(1681, 10)}, nil
+	})}
+	s.Amount = big.NewInt(12_345_600_000_000)
+	p, err = de.Prompt(context.Background(), s, sol, Asset{})
+	require.NoError(t, err)
+	assert.Equal(t, "Sende 12.345,600000000 SOL (~2.075.295,36\u00a0€) an 9WzD…AWWM", p.Headline)
+}
+
+func TestPromptWithoutPrices(t *testing.T) {
+	down := price.OracleFunc(func(context.Context, string, string) (*price.Quote, error) {
+		return nil, errors.New("feed unavailable")
+	})
+	s := testSummary()
+	s.Chain = "solana-devnet\x1b[2J" // Terminal escapes are neutralized
+	s.Token = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	s.Outputs = []chain.Output{
+		{To: "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM", Amount: big.NewInt(1_000_000)},
+		{To: "3Kz9z4mEoP1Yc3vJcN1xq4t8sWkLkQwRk7kE2Rn6", Amount: big.NewInt(500_000)},
+	}
+	s.Amount = big.NewInt(1_500_000)
+	usdc := Asset{Ticker: "USDC", Decimals: 6}
+
+	f := &Formatter{Locale: LocaleFrFR, Currency: "EUR", Oracle: down}
+	_, err := f.Prompt(context.Background(), s, usdc, Asset{})
+	assert.Error(t, err, "token transfers need a fee asset")
+
+	p, err := f.Prompt(context.Background(), s, usdc, sol)
+	require.NoError(t, err)
+	assert.Equal(t, "Envoyer 1,500000 USDC à 2 destinataires", p.Headline)
+	assert.Equal(t, []string{
+		"1,000000 USDC à 9WzD…AWWM",
+		"0,500000 USDC à 3Kz9…2Rn6",
+		"Jeton EPjF…Dt1v",
+		"De 7xKX…gAsU",
+		"Frais de réseau jusqu'à 0,000005003 SOL",
+		"Réseau solana-devnet?[2J",
+	}, p.Details)
+	assert.Empty(t, p.Quotes)
+}
+
+func TestLocaleFormatting(t *testing.T) {
+	l, err := LookupLocale("de-AT")
+	require.NoError(t, err)
+	assert.Equal(t, "de-DE", l.Tag)
+	_, err = LookupLocale("xx")
+	assert.Error(t, err)
+
+	assert.Equal(t, "1,234,567.000000000 SOL", LocaleEnUS.FormatAmount(big.NewInt(1_234_567_000_000_000), sol))
+	assert.Equal(t, "-0.000000042 SOL", LocaleEnUS.FormatAmount(big.NewInt(-42), sol))
+	assert.Equal(t, "1,234 T", LocaleEnUS.FormatAmount(big.NewInt(1234), Asset{Ticker: "T"}))
+
+	assert.Equal(t, "¥1,235", LocaleEnUS.FormatFiat(big.NewRat(12345, 10), "JPY"))
+	assert.Equal(t, "CHF\u00a00.01", LocaleEnUS.FormatFiat(big.NewRat(5, 1000), "chf"))
+	assert.Equal(t, "$0.00", LocaleEnUS.FormatFiat(big.NewRat(-1, 1000), "USD"))
+	assert.Equal(t, "-1,50\u00a0€", LocaleDeDE.FormatFiat(big.NewRat(-3, 2), "EUR"))
+}
@@ -0,0 +1,25 @@
+// Package wallet is the application-facing API of the threshold wallet: it
+// turns an intent such as "send 1.5 SOL to Y" into a finalized transaction
+// without the caller touching instructions, fees or signing rounds.
+//
+//	w, err := wallet.New(wallet.Config{
+//	    Coordinator: coord,
+//	    Addresses:   map[string]string{"solana-devnet": treasury},
+//	    FeeBudgets:  map[string]*big.Int{"solana-devnet": big.NewInt(50_000)},
+//	})
+//	receipt, err := w.Send(ctx, wallet.SOL("solana-devnet"), "1.5", recipient)
+//
+// `Wallet.Send` parses the amount in whole units of the `Asset`, asks the chain
+// for a `chain.Quote` where supported — which checks that the recipient exists
+// or can be created, prices any account that has to be created for it (such
+// as a Solana associated token account) and checks the sender's balances —
+// enforces the configured fee budget, and then runs a coordinator session
+// through policy, signing, broadcast and confirmation.  The quoted fee is the
+// session's Request.MaxFee, so a transaction built with a higher fee fails
+// instead of exceeding the budget.
+//
+// The result is a `Receipt` describing the session and the transaction.  When
+// policy requires human approval, Send returns the receipt together with
+// ErrAwaitingApproval; once approved, `Wallet.Resume` continues the session.
+// Transfers rejected by policy, observers or the ledger return ErrFailed.
+package wallet
@@ -0,0 +1,40 @@
+// Package envelope defines the compact binary encoding in which coordinators
+// and parties exchange requests and responses – SignRequest, SignResponse,
+// KeyGenRequest and KeyGenResponse – so that parties written in other
+// languages, and future versions of this one, interoperate byte for byte.
+//
+// An envelope is a 4-byte header followed by the message's fields:
+//
+//	0xcb 0x4d | version (1) | type (1) | field*
+//	field = tag (uvarint) | length (uvarint) | value
+//
+// Values are UTF-8 strings, byte strings, uvarints, zigzag varints, unsigned
+// big-endian integers without leading zeros (zero is the empty value) or
+// nested messages of further fields.  Repeated fields are written as
+// consecutive fields with the same tag.
+//
+// The encoding is canonical, so every message has exactly one: fields appear
+// in ascending tag order, empty strings and byte strings and zero integers
+// are omitted, and varints are minimal.  Big integers are written whenever
+// they are non-nil, so nil and zero amounts stay distinct.  Decoders reject
+// anything else with ErrMalformed.  This matters because parties sign and
+// hash what they receive: a SignRequest decodes to exactly the request the
+// coordinator sent, and its approval digest (see
+// coordinator.VerifyApprovals) is unchanged.
+//
+// The version byte changes only for incompatible revisions.  New fields are
+// added with new tags instead: odd tags are optional and skipped by decoders
+// that do not know them, while even tags are required and make such decoders
+// fail with ErrUnsupported rather than act on a message they only partly
+// understand.
+//
+// The field tags of each message are listed in messages.go.  `Signer` sends
+// SignRequests through any `RoundTripper` and `Serve` answers them on the
+// party's side, e.g. over an mtls connection or a message queue:
+//
+//	signer := envelope.NewSigner(envelope.RoundTripperFunc(queue.Call))
+//	coord, err := coordinator.New(coordinator.Config{Signer: signer, ...})
+//
+//	// At each party:
+//	resp, err := envelope.Serve(ctx, "party_1", localSigner, req)
+package envelope
@@ -0,0 +1,276 @@
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+)
+
+// Version is the envelope version written by this package.  Decoders
+// reject any other version.
+const Version = 1
+
+// magic starts every envelope.
+var magic = [2]byte{0xcb, 0x4d}
+
+const headerSize = len(magic) + 2
+
+var (
+	// ErrMalformed is wrapped by decoding errors for envelopes that are
+	// truncated, not in canonical form or missing required fields.
+	ErrMalformed = errors.New("envelope: malformed")
+	// ErrUnsupported is wrapped by decoding errors for envelopes of another
+	// version, another type or with required fields this package does not
+	// know.
+	ErrUnsupported = errors.New("envelope: unsupported")
+)
+
+// Type identifies the message carried in an envelope.
+type Type uint8
+
+// Message types.
+const (
+	TypeSignRequest    Type = 1
+	TypeSignResponse   Type = 2
+	TypeKeyGenRequest  Type = 3
+	TypeKeyGenResponse Type = 4
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeSignRequest:
+		return "sign request"
+	case TypeSignResponse:
+		return "sign response"
+	case TypeKeyGenRequest:
+		return "keygen request"
+	case TypeKeyGenResponse:
+		return "keygen response"
+	default:
+		return fmt.Sprintf("type %d", uint8(t))
+	}
+}
+
+// PeekType checks the envelope header and returns the type of the message,
+// so that a receiver can dispatch it to the right decoder.
+func PeekType(data []byte) (Type, error) {
+	if len(data) < headerSize || data[0] != magic[0] || data[1] != magic[1] {
+		return 0, fmt.Errorf("%w: not an envelope", ErrMalformed)
+	}
+	if data[2] != Version {
+		return 0, fmt.Errorf("%w: version %d", ErrUnsupported, data[2])
+	}
+	return Type(data[3]), nil
+}
+
+// open checks that data is an envelope of type t and returns its body.
+func open(data []byte, t Type) ([]byte, error) {
+	got, err := PeekType(data)
+	if err != nil {
+		return nil, err
+	}
+	if got != t {
+		return nil, fmt.Errorf("%w: got %v, want %v", ErrUnsupported, got, t)
+	}
+	return data[headerSize:], nil
+}
+
+// encoder writes the fields of a message in canonical form: in ascending tag
+// order, with empty strings, byte strings and zero integers omitted.
+type encoder struct {
+	buf []byte
+	err error
+}
+
+func seal(t Type, fn func(e *encoder)) ([]byte, error) {
+	e := &encoder{buf: []byte{magic[0], magic[1], Version, byte(t)}}
+	fn(e)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf, nil
+}
+
+func (e *encoder) field(tag uint64, value []byte) {
+	e.buf = binary.AppendUvarint(e.buf, tag)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(value)))
+	e.buf = append(e.buf, value...)
+}
+
+func (e *encoder) bytes(tag uint64, v []byte) {
+	if len(v) > 0 {
+		e.field(tag, v)
+	}
+}
+
+func (e *encoder) string(tag uint64, v string) {
+	if v != "" && !utf8.ValidString(v) {
+		e.fail(fmt.Errorf("field %d is not valid UTF-8", tag))
+		return
+	}
+	e.bytes(tag, []byte(v))
+}
+
+func (e *encoder) uint(tag, v uint64) {
+	if v != 0 {
+		e.field(tag, binary.AppendUvarint(nil, v))
+	}
+}
+
+func (e *encoder) int(tag uint64, v int64) {
+	if v != 0 {
+		e.field(tag, binary.AppendVarint(nil, v))
+	}
+}
+
+// bigInt writes a non-nil, non-negative integer as its minimal big-endian
+// magnitude; zero is an empty value.  Nil integers are omitted.
+func (e *encoder) bigInt(tag uint64, v *big.Int) {
+	if v == nil {
+		return
+	}
+	if v.Sign() < 0 {
+		e.fail(fmt.Errorf("field %d is negative", tag))
+		return
+	}
+	e.field(tag, v.Bytes())
+}
+
+// message writes a nested message.
+func (e *encoder) message(tag uint64, fn func(e *encoder)) {
+	nested := &encoder{}
+	fn(nested)
+	if nested.err != nil {
+		e.fail(nested.err)
+		return
+	}
+	e.field(tag, nested.buf)
+}
+
+func (e *encoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// errUnknownField is returned by walk callbacks for tags they do not know.
+var errUnknownField = errors.New("unknown field")
+
+// walk calls fn for every field of a message body.  Tags must ascend; only
+// the tags listed in repeated may occur more than once, in a row.  Unknown
+// odd tags are skipped and unknown even tags are rejected.
+func walk(body []byte, repeated []uint64, fn func(tag uint64, value []byte) error) error {
+	var last uint64
+	for len(body) > 0 {
+		tag, n, err := uvarint(body)
+		if err != nil {
+			return err
+		}
+		body = body[n:]
+		length, n, err := uvarint(body)
+		if err != nil {
+			return err
+		}
+		body = body[n:]
+		if length > uint64(len(body)) {
+			return fmt.Errorf("%w: field %d is truncated", ErrMalformed, tag)
+		}
+		value := body[:length]
+		body = body[length:]
+
+		switch {
+		case tag == 0:
+			return fmt.Errorf("%w: field tag 0", ErrMalformed)
+		case tag < last:
+			return fmt.Errorf("%w: field %d follows field %d", ErrMalformed, tag, last)
+		case tag == last && !contains(repeated, tag):
+			return fmt.Errorf("%w: field %d occurs more than once", ErrMalformed, tag)
+		}
+		last = tag
+		switch err := fn(tag, value); {
+		case errors.Is(err, errUnknownField):
+			if tag%2 == 0 {
+				return fmt.Errorf("%w: unknown required field %d", ErrUnsupported, tag)
+			}
+		case err != nil:
+			return fmt.Errorf("field %d: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func contains(tags []uint64, tag uint64) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// uvarint decodes a minimally encoded unsigned varint.
+func uvarint(data []byte) (uint64, int, error) {
+	v, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, 0, fmt.Errorf("%w: invalid varint", ErrMalformed)
+	}
+	if n != len(binary.AppendUvarint(nil, v)) {
+		return 0, 0, fmt.Errorf("%w: overlong varint", ErrMalformed)
+	}
+	return v, n, nil
+}
+
+// The value decoders below reject the values the encoder omits, so that
+// every message has exactly one encoding.
+
+func nonEmpty(value []byte) error {
+	if len(value) == 0 {
+		return fmt.Errorf("%w: empty value", ErrMalformed)
+	}
+	return nil
+}
+
+func decodeBytes(value []byte) ([]byte, error) {
+	if err := nonEmpty(value); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func decodeString(value []byte) (string, error) {
+	if err := nonEmpty(value); err != nil {
+		return "", err
+	}
+	if !utf8.Valid(value) {
+		return "", fmt.Errorf("%w: invalid UTF-8", ErrMalformed)
+	}
+	return string(value), nil
+}
+
+func decodeUint(value []byte) (uint64, error) {
+	v, n, err := uvarint(value)
+	if err != nil {
+		return 0, err
+	}
+	if n != len(value) || v == 0 {
+		return 0, fmt.Errorf("%w: invalid integer", ErrMalformed)
+	}
+	return v, nil
+}
+
+func decodeInt(value []byte) (int64, error) {
+	v, n := binary.Varint(value)
+	if n != len(value) || v == 0 || n != len(binary.AppendVarint(nil, v)) {
+		return 0, fmt.Errorf("%w: invalid integer", ErrMalformed)
+	}
+	return v, nil
+}
+
+func decodeBigInt(value []byte) (*big.Int, error) {
+	if len(value) > 0 && value[0] == 0 {
+		return nil, fmt.Errorf("%w: integer has leading zeros", ErrMalformed)
+	}
+	return new(big.Int).SetBytes(value), nil
+}
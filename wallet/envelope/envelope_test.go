@@ -0,0 +1,188 @@
+package envelope
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/signctx"
+)
+
+func testSignRequest() *coordinator.SignRequest {
+	return &coordinator.SignRequest{
+		Session:  "s-1",
+		Chain:    "solana-devnet",
+		Payload:  []byte{1, 2, 3},
+		Context:  "solana-tx",
+		Priority: coordinator.PriorityBatch,
+		Summary: &chain.Summary{
+			Chain:  "solana-devnet",
+			From:   "alice",
+			Amount: big.NewInt(1_500_000_000),
+			Fee:    new(big.Int),
+			Outputs: []chain.Output{
+				{To: "bob", Amount: big.NewInt(1_000_000_000)},
+				{To: "carol", Amount: big.NewInt(500_000_000)},
+			},
+		},
+		Approvals: []coordinator.ApprovalSignature{
+			{Approver: "ops", Digest: []byte{9}, Signature: []byte{8, 7}},
+			{Approver: "risk", Digest: []byte{9}, Signature: []byte{6}},
+		},
+		Quorum: []string{"party_0", "party_2"},
+	}
+}
+
+func TestSignRequestRoundTrip(t *testing.T) {
+	req := testSignRequest()
+	data, err := MarshalSignRequest(req)
+	require.NoError(t, err)
+	typ, err := PeekType(data)
+	require.NoError(t, err)
+	assert.Equal(t, TypeSignRequest, typ)
+
+	got, err := UnmarshalSignRequest(data)
+	require.NoError(t, err)
+	assert.Equal(t, req, got)
+	// Parties check approvals against the digest of what they received.
+	want, err := req.ApprovalDigest()
+	require.NoError(t, err)
+	digest, err := got.ApprovalDigest()
+	require.NoError(t, err)
+	assert.Equal(t, want, digest)
+
+	again, err := MarshalSignRequest(got)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	// A minimal request, for other implementations to check against.
+	data, err = MarshalSignRequest(&coordinator.SignRequest{Session: "s", Payload: []byte{0xff}, Priority: coordinator.PriorityUrgent})
+	require.NoError(t, err)
+	assert.Equal(t, "cb4d0101"+"010102"+"020173"+"0601ff", hex.EncodeToString(data))
+	got, err = UnmarshalSignRequest(data)
+	require.NoError(t, err)
+	assert.Nil(t, got.Summary)
+	assert.Nil(t, got.Quorum)
+}
+
+func TestDecodersRejectNonCanonicalInput(t *testing.T) {
+	header := "cb4d0101"
+	for name, body := range map[string]string{
+		"valid":              "020173" + "0601ff",
+		"out of order":       "0601ff" + "020173",
+		"duplicate":          "020173" + "020173" + "0601ff",
+		"empty value":        "0200" + "020173" + "0601ff",
+		"overlong tag":       "820001" + "73" + "0601ff",
+		"truncated":          "020173" + "0605ff",
+		"zero priority":      "010100" + "020173" + "0601ff",
+		"unknown even field": "020173" + "0601ff" + "100101",
+		"missing payload":    "020173",
+	} {
+		_, err := UnmarshalSignRequest(mustHex(t, header+body))
+		if name == "valid" {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.Error(t, err, name)
+	}
+
+	// Unknown odd fields are optional and skipped.
+	_, err := UnmarshalSignRequest(mustHex(t, header+"020173"+"0601ff"+"110101"))
+	assert.NoError(t, err)
+	_, err = UnmarshalSignRequest(mustHex(t, header+"020173"+"0601ff"+"100101"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = UnmarshalSignRequest(mustHex(t, "cb4d0201020173"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = UnmarshalSignRequest(mustHex(t, "cb4d0102020173"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = UnmarshalSignRequest([]byte("{}"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestKeyGenRoundTrip(t *testing.T) {
+	req := &KeyGenRequest{KeyID: "treasury", Curve: "ed25519", Parties: []string{"a", "b", "c"}, Threshold: 2, SessionID: []byte{1}}
+	data, err := req.MarshalBinary()
+	require.NoError(t, err)
+	var got KeyGenRequest
+	require.NoError(t, got.UnmarshalBinary(data))
+	assert.Equal(t, *req, got)
+
+	_, err = (&KeyGenRequest{KeyID: "k", Curve: "ed25519", Parties: []string{"a"}, Threshold: 2}).MarshalBinary()
+	assert.Error(t, err)
+	// A sign request is not a keygen request.
+	data, err = MarshalSignRequest(testSignRequest())
+	require.NoError(t, err)
+	assert.ErrorIs(t, got.UnmarshalBinary(data), ErrUnsupported)
+
+	resp := &KeyGenResponse{KeyID: "treasury", Party: "a", PublicKey: []byte{4, 5}}
+	data, err = resp.MarshalBinary()
+	require.NoError(t, err)
+	var gotResp KeyGenResponse
+	require.NoError(t, gotResp.UnmarshalBinary(data))
+	assert.Equal(t, *resp, gotResp)
+
+	_, err = (&KeyGenResponse{KeyID: "treasury", PublicKey: []byte{4}, Err: "both"}).MarshalBinary()
+	assert.Error(t, err)
+}
+
+func TestSignerOverRoundTripper(t *testing.T) {
+	var seen *coordinator.SignRequest
+	party := coordinator.Signer(signerFunc(func(_ context.Context, req *coordinator.SignRequest) ([]byte, error) {
+		seen = req
+		if req.Summary == nil {
+			return nil, errors.New("request is not decoded")
+		}
+		return []byte("signature"), nil
+	}))
+	signer := NewSigner(RoundTripperFunc(func(ctx context.Context, request []byte) ([]byte, error) {
+		return Serve(ctx, "party_1", party, request)
+	}))
+
+	req := testSignRequest()
+	req.Context = "raw"
+	var rounds []int
+	req.Progress = func(round int) error {
+		rounds = append(rounds, round)
+		return nil
+	}
+	sig, err := signer.Sign(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("signature"), sig)
+	assert.Equal(t, []int{1}, rounds)
+	assert.Equal(t, req.Payload, seen.Payload)
+
+	req.Summary = nil
+	_, err = signer.Sign(context.Background(), req)
+	var perr *coordinator.PartyError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "party_1", perr.Party)
+	assert.EqualError(t, perr.Err, "request is not decoded")
+
+	// Serve checks the payload against its declared context before the
+	// party's signer sees the request.
+	seen = nil
+	_, err = signer.Sign(context.Background(), testSignRequest())
+	require.ErrorAs(t, err, &perr)
+	assert.Contains(t, perr.Err.Error(), signctx.ErrMismatch.Error())
+	assert.Nil(t, seen)
+}
+
+type signerFunc func(ctx context.Context, req *coordinator.SignRequest) ([]byte, error)
+
+func (f signerFunc) Sign(ctx context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	return f(ctx, req)
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
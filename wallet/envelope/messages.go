@@ -0,0 +1,349 @@
+package envelope
+
+import (
+	"fmt"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// Field tags.  Even tags are required: a decoder that does not know one
+// rejects the message.  Odd tags may be ignored.
+const (
+	// SignRequest
+	tagSignPriority = 1
+	tagSignSession  = 2
+	tagSignChain    = 4
+	tagSignPayload  = 6
+	tagSignContext  = 8
+	tagSignSummary  = 10
+	tagSignApproval = 12 // repeated
+	tagSignQuorum   = 14 // repeated
+
+	// chain.Summary, chain.Output and coordinator.ApprovalSignature within
+	// a SignRequest
+	tagSummaryChain   = 2
+	tagSummaryFrom    = 4
+	tagSummaryTo      = 6
+	tagSummaryAmount  = 8
+	tagSummaryFee     = 10
+	tagSummaryToken   = 12
+	tagSummaryOutput  = 14 // repeated
+	tagOutputTo       = 2
+	tagOutputAmount   = 4
+	tagApprover       = 2
+	tagApprovalDigest = 4
+	tagApprovalSig    = 6
+
+	// SignResponse and KeyGenResponse
+	tagRespID     = 2 // Session or key ID
+	tagRespParty  = 4
+	tagRespResult = 6 // Signature or public key
+	tagRespError  = 8
+
+	// KeyGenRequest
+	tagKeyGenID        = 2
+	tagKeyGenCurve     = 4
+	tagKeyGenParty     = 6 // repeated
+	tagKeyGenThreshold = 8
+	tagKeyGenSession   = 10
+)
+
+// MarshalSignRequest encodes req.  Progress is not encoded: the receiving
+// party reports progress through its own channel, if at all.
+func MarshalSignRequest(req *coordinator.SignRequest) ([]byte, error) {
+	if req == nil || req.Session == "" || len(req.Payload) == 0 {
+		return nil, fmt.Errorf("sign request needs a session and a payload")
+	}
+	return seal(TypeSignRequest, func(e *encoder) {
+		e.int(tagSignPriority, int64(req.Priority))
+		e.string(tagSignSession, req.Session)
+		e.string(tagSignChain, req.Chain)
+		e.bytes(tagSignPayload, req.Payload)
+		e.string(tagSignContext, req.Context)
+		if s := req.Summary; s != nil {
+			e.message(tagSignSummary, func(e *encoder) {
+				e.string(tagSummaryChain, s.Chain)
+				e.string(tagSummaryFrom, s.From)
+				e.string(tagSummaryTo, s.To)
+				e.bigInt(tagSummaryAmount, s.Amount)
+				e.bigInt(tagSummaryFee, s.Fee)
+				e.string(tagSummaryToken, s.Token)
+				for _, o := range s.Outputs {
+					e.message(tagSummaryOutput, func(e *encoder) {
+						e.string(tagOutputTo, o.To)
+						e.bigInt(tagOutputAmount, o.Amount)
+					})
+				}
+			})
+		}
+		for _, a := range req.Approvals {
+			e.message(tagSignApproval, func(e *encoder) {
+				e.string(tagApprover, a.Approver)
+				e.bytes(tagApprovalDigest, a.Digest)
+				e.bytes(tagApprovalSig, a.Signature)
+			})
+		}
+		for _, party := range req.Quorum {
+			if party == "" {
+				e.fail(fmt.Errorf("quorum party names cannot be empty"))
+			}
+			e.string(tagSignQuorum, party)
+		}
+	})
+}
+
+// UnmarshalSignRequest decodes a request encoded by MarshalSignRequest.  Its
+// Progress is nil.
+func UnmarshalSignRequest(data []byte) (*coordinator.SignRequest, error) {
+	body, err := open(data, TypeSignRequest)
+	if err != nil {
+		return nil, err
+	}
+	req := &coordinator.SignRequest{}
+	err = walk(body, []uint64{tagSignApproval, tagSignQuorum}, func(tag uint64, v []byte) (err error) {
+		switch tag {
+		case tagSignPriority:
+			var p int64
+			if p, err = decodeInt(v); err == nil && (p < -128 || p > 127) {
+				err = fmt.Errorf("%w: priority %d", ErrMalformed, p)
+			}
+			req.Priority = coordinator.Priority(p)
+		case tagSignSession:
+			req.Session, err = decodeString(v)
+		case tagSignChain:
+			req.Chain, err = decodeString(v)
+		case tagSignPayload:
+			req.Payload, err = decodeBytes(v)
+		case tagSignContext:
+			req.Context, err = decodeString(v)
+		case tagSignSummary:
+			req.Summary, err = decodeSummary(v)
+		case tagSignApproval:
+			var a coordinator.ApprovalSignature
+			err = walk(v, nil, func(tag uint64, v []byte) (err error) {
+				switch tag {
+				case tagApprover:
+					a.Approver, err = decodeString(v)
+				case tagApprovalDigest:
+					a.Digest, err = decodeBytes(v)
+				case tagApprovalSig:
+					a.Signature, err = decodeBytes(v)
+				default:
+					err = errUnknownField
+				}
+				return err
+			})
+			req.Approvals = append(req.Approvals, a)
+		case tagSignQuorum:
+			var party string
+			party, err = decodeString(v)
+			req.Quorum = append(req.Quorum, party)
+		default:
+			err = errUnknownField
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req.Session == "" || len(req.Payload) == 0 {
+		return nil, fmt.Errorf("%w: sign request without session or payload", ErrMalformed)
+	}
+	return req, nil
+}
+
+func decodeSummary(data []byte) (*chain.Summary, error) {
+	s := &chain.Summary{}
+	err := walk(data, []uint64{tagSummaryOutput}, func(tag uint64, v []byte) (err error) {
+		switch tag {
+		case tagSummaryChain:
+			s.Chain, err = decodeString(v)
+		case tagSummaryFrom:
+			s.From, err = decodeString(v)
+		case tagSummaryTo:
+			s.To, err = decodeString(v)
+		case tagSummaryAmount:
+			s.Amount, err = decodeBigInt(v)
+		case tagSummaryFee:
+			s.Fee, err = decodeBigInt(v)
+		case tagSummaryToken:
+			s.Token, err = decodeString(v)
+		case tagSummaryOutput:
+			var o chain.Output
+			err = walk(v, nil, func(tag uint64, v []byte) (err error) {
+				switch tag {
+				case tagOutputTo:
+					o.To, err = decodeString(v)
+				case tagOutputAmount:
+					o.Amount, err = decodeBigInt(v)
+				default:
+					err = errUnknownField
+				}
+				return err
+			})
+			s.Outputs = append(s.Outputs, o)
+		default:
+			err = errUnknownField
+		}
+		return err
+	})
+	return s, err
+}
+
+// SignResponse is a party's answer to a SignRequest.
+type SignResponse struct {
+	Session   string // Session of the request
+	Party     string // Name of the responding party
+	Signature []byte // Signature, unless Err is set
+	Err       string // Reason the party did not sign
+}
+
+// MarshalBinary encodes the response.
+func (r *SignResponse) MarshalBinary() ([]byte, error) {
+	return marshalResponse(TypeSignResponse, r.Session, r.Party, r.Signature, r.Err)
+}
+
+// UnmarshalBinary decodes a response encoded by MarshalBinary.
+func (r *SignResponse) UnmarshalBinary(data []byte) error {
+	var err error
+	r.Session, r.Party, r.Signature, r.Err, err = unmarshalResponse(TypeSignResponse, data)
+	return err
+}
+
+// KeyGenRequest asks a party to take part in generating a key.
+type KeyGenRequest struct {
+	KeyID     string
+	Curve     string   // ed25519 or secp256k1
+	Parties   []string // Names of all parties, in protocol order
+	Threshold int      // Parties needed to sign; 0 for all of them
+	SessionID []byte   // Optional protocol session identifier
+}
+
+// MarshalBinary encodes the request.
+func (r *KeyGenRequest) MarshalBinary() ([]byte, error) {
+	if r.KeyID == "" || r.Curve == "" || len(r.Parties) == 0 {
+		return nil, fmt.Errorf("keygen request needs a key ID, a curve and parties")
+	}
+	if r.Threshold < 0 || r.Threshold > len(r.Parties) {
+		return nil, fmt.Errorf("threshold %d is out of range for %d parties", r.Threshold, len(r.Parties))
+	}
+	return seal(TypeKeyGenRequest, func(e *encoder) {
+		e.string(tagKeyGenID, r.KeyID)
+		e.string(tagKeyGenCurve, r.Curve)
+		for _, p := range r.Parties {
+			if p == "" {
+				e.fail(fmt.Errorf("party names cannot be empty"))
+			}
+			e.string(tagKeyGenParty, p)
+		}
+		e.uint(tagKeyGenThreshold, uint64(r.Threshold))
+		e.bytes(tagKeyGenSession, r.SessionID)
+	})
+}
+
+// UnmarshalBinary decodes a request encoded by MarshalBinary.
+func (r *KeyGenRequest) UnmarshalBinary(data []byte) error {
+	body, err := open(data, TypeKeyGenRequest)
+	if err != nil {
+		return err
+	}
+	var out KeyGenRequest
+	err = walk(body, []uint64{tagKeyGenParty}, func(tag uint64, v []byte) (err error) {
+		switch tag {
+		case tagKeyGenID:
+			out.KeyID, err = decodeString(v)
+		case tagKeyGenCurve:
+			out.Curve, err = decodeString(v)
+		case tagKeyGenParty:
+			var p string
+			p, err = decodeString(v)
+			out.Parties = append(out.Parties, p)
+		case tagKeyGenThreshold:
+			var t uint64
+			if t, err = decodeUint(v); err == nil && t > uint64(len(out.Parties)) {
+				err = fmt.Errorf("%w: threshold %d of %d parties", ErrMalformed, t, len(out.Parties))
+			}
+			out.Threshold = int(t)
+		case tagKeyGenSession:
+			out.SessionID, err = decodeBytes(v)
+		default:
+			err = errUnknownField
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if out.KeyID == "" || out.Curve == "" || len(out.Parties) == 0 {
+		return fmt.Errorf("%w: keygen request without key ID, curve or parties", ErrMalformed)
+	}
+	*r = out
+	return nil
+}
+
+// KeyGenResponse is a party's answer to a KeyGenRequest.
+type KeyGenResponse struct {
+	KeyID     string // Key ID of the request
+	Party     string // Name of the responding party
+	PublicKey []byte // Group public key, unless Err is set
+	Err       string // Reason the key was not generated
+}
+
+// MarshalBinary encodes the response.
+func (r *KeyGenResponse) MarshalBinary() ([]byte, error) {
+	return marshalResponse(TypeKeyGenResponse, r.KeyID, r.Party, r.PublicKey, r.Err)
+}
+
+// UnmarshalBinary decodes a response encoded by MarshalBinary.
+func (r *KeyGenResponse) UnmarshalBinary(data []byte) error {
+	var err error
+	r.KeyID, r.Party, r.PublicKey, r.Err, err = unmarshalResponse(TypeKeyGenResponse, data)
+	return err
+}
+
+// Both responses carry an ID, the party, a result and an error, exactly one
+// of which is set.
+func marshalResponse(t Type, id, party string, result []byte, errText string) ([]byte, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%v needs an ID", t)
+	}
+	if (len(result) == 0) == (errText == "") {
+		return nil, fmt.Errorf("%v needs either a result or an error", t)
+	}
+	return seal(t, func(e *encoder) {
+		e.string(tagRespID, id)
+		e.string(tagRespParty, party)
+		e.bytes(tagRespResult, result)
+		e.string(tagRespError, errText)
+	})
+}
+
+func unmarshalResponse(t Type, data []byte) (id, party string, result []byte, errText string, err error) {
+	body, err := open(data, t)
+	if err != nil {
+		return "", "", nil, "", err
+	}
+	err = walk(body, nil, func(tag uint64, v []byte) (err error) {
+		switch tag {
+		case tagRespID:
+			id, err = decodeString(v)
+		case tagRespParty:
+			party, err = decodeString(v)
+		case tagRespResult:
+			result, err = decodeBytes(v)
+		case tagRespError:
+			errText, err = decodeString(v)
+		default:
+			err = errUnknownField
+		}
+		return err
+	})
+	if err != nil {
+		return "", "", nil, "", err
+	}
+	if id == "" || (len(result) == 0) == (errText == "") {
+		return "", "", nil, "", fmt.Errorf("%w: %v needs an ID and either a result or an error", ErrMalformed, t)
+	}
+	return id, party, result, errText, nil
+}
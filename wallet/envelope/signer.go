@@ -0,0 +1,92 @@
+package envelope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// RoundTripper delivers an encoded request to a party and returns its
+// encoded response, over whatever carries envelopes between them.
+type RoundTripper interface {
+	RoundTrip(ctx context.Context, request []byte) ([]byte, error)
+}
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as
+// RoundTrippers.
+type RoundTripperFunc func(ctx context.Context, request []byte) ([]byte, error)
+
+// RoundTrip calls f(ctx, request).
+func (f RoundTripperFunc) RoundTrip(ctx context.Context, request []byte) ([]byte, error) {
+	return f(ctx, request)
+}
+
+// Signer is a coordinator.Signer for a party in another process: it sends
+// every SignRequest as an envelope and decodes the SignResponse.
+type Signer struct {
+	rt RoundTripper
+}
+
+// Ensure Signer implements the coordinator.Signer interface
+var _ coordinator.Signer = (*Signer)(nil)
+
+// NewSigner creates a Signer that sends its requests through rt.
+func NewSigner(rt RoundTripper) *Signer {
+	return &Signer{rt: rt}
+}
+
+// Sign implements coordinator.Signer.  Protocol rounds run at the parties,
+// so progress is reported as round 1 when the request is sent.  A party's
+// refusal is returned as a *coordinator.PartyError.
+func (s *Signer) Sign(ctx context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	data, err := MarshalSignRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Progress != nil {
+		if err := req.Progress(1); err != nil {
+			return nil, err
+		}
+	}
+	out, err := s.rt.RoundTrip(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	var resp SignResponse
+	if err := resp.UnmarshalBinary(out); err != nil {
+		return nil, fmt.Errorf("decoding sign response: %w", err)
+	}
+	if resp.Session != req.Session {
+		return nil, fmt.Errorf("sign response is for session %s, not %s", resp.Session, req.Session)
+	}
+	if resp.Err != "" {
+		if resp.Party != "" {
+			return nil, &coordinator.PartyError{Party: resp.Party, Err: errors.New(resp.Err)}
+		}
+		return nil, errors.New(resp.Err)
+	}
+	return resp.Signature, nil
+}
+
+// Serve is the party's side of Signer: it decodes a SignRequest, checks its
+// payload against the declared signing context with
+// coordinator.RequireContext, passes it to signer and encodes the outcome as
+// a SignResponse from party, so that signing failures reach the coordinator.
+// A request that cannot be decoded has no session to answer for and is
+// returned as an error instead.
+func Serve(ctx context.Context, party string, signer coordinator.Signer, request []byte) ([]byte, error) {
+	req, err := UnmarshalSignRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	req.Progress = func(int) error { return nil }
+	resp := &SignResponse{Session: req.Session, Party: party}
+	if resp.Signature, err = coordinator.RequireContext(signer).Sign(ctx, req); err != nil {
+		resp.Signature, resp.Err = nil, err.Error()
+	} else if len(resp.Signature) == 0 {
+		resp.Err = "signer returned no signature"
+	}
+	return resp.MarshalBinary()
+}
@@ -0,0 +1,27 @@
+// Package escrow exports a key's material to a regulated escrow agent, and
+// lets the agent check what it received.
+//
+// Qualified custodians are commonly required to deposit their keys with an
+// independent escrow agent, so that customers' assets can be recovered if
+// the custodian fails.  A deposit must be complete, readable by the agent
+// alone, verifiable by the agent without decrypting it, and held under
+// written terms.  `Export` produces such a deposit, a `Package` signed by the
+// custodian that carries
+//
+//   - every party's secret share, verifiably encrypted to the agent's key
+//     and bound to the package and party through its label,
+//   - the `Policy` document: custodian, release conditions, retention
+//     period, contacts and the terms of the agreement, and
+//   - the key's signed public bundle, listing the group key and the public
+//     share of every party.
+//
+// On receipt the agent runs `Verify` (cmd/cb-mpc-escrow verify), which checks
+// the custodian's signature, that every share is encrypted to the agent's own
+// key and encrypts the secret of the public share listed in the bundle, that
+// no party of the bundle is missing and that the policy is complete and in
+// force, and returns a `Report` for the agent's records.
+//
+// The encryption is supplied by the caller, so the package itself has no
+// dependency on the native library; cmd/cb-mpc-escrow wires it to the
+// publicly verifiable encryption (PVE) of cb-mpc.
+package escrow
@@ -0,0 +1,316 @@
+package escrow
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"solana-threshold-wallet/wallet/fingerprint"
+)
+
+const (
+	// packageVersion is the format version written into every package.
+	packageVersion = 1
+	// packageDomain prefixes the signed digest so that a package signature
+	// cannot be replayed as a signature over anything else.
+	packageDomain = "cb-mpc escrow package v1\x00"
+	// labelPrefix starts the label every share is encrypted under.
+	labelPrefix = "cb-mpc escrow v1"
+)
+
+// ErrInvalid is returned by Verify for a package that cannot be checked at
+// all: malformed, of an unknown version or not signed by the custodian.
+var ErrInvalid = errors.New("escrow: invalid package")
+
+// Agent is the escrow agent the shares are deposited with.
+type Agent struct {
+	Name string `json:"name"`
+	// EncryptionKey is the agent's public encryption key, e.g. an
+	// mpc.BaseEncPublicKey.  Only the agent can decrypt the shares.
+	EncryptionKey []byte `json:"encryption_key"`
+}
+
+// Policy is the escrow policy document agreed between the custodian and the
+// agent.  It travels inside the signed package, so the agent holds the
+// custodian's signature on the terms together with the shares.
+type Policy struct {
+	Custodian    string `json:"custodian"`
+	Jurisdiction string `json:"jurisdiction,omitempty"`
+	// ReleaseConditions lists the events on which the agent may release the
+	// shares, e.g. "custodian insolvency" or "court order".
+	ReleaseConditions []string `json:"release_conditions"`
+	// RetainUntil is how long the agent keeps the package.
+	RetainUntil time.Time `json:"retain_until"`
+	// Contacts are who the agent notifies before a release.
+	Contacts []string `json:"contacts,omitempty"`
+	// Terms is the text of, or a reference to, the escrow agreement.
+	Terms string `json:"terms,omitempty"`
+}
+
+// validate reports what the policy is missing, relative to when the package
+// was created and now.
+func (p *Policy) validate(created, now time.Time) []string {
+	var problems []string
+	if p.Custodian == "" {
+		problems = append(problems, "policy names no custodian")
+	}
+	if len(p.ReleaseConditions) == 0 {
+		problems = append(problems, "policy has no release conditions")
+	}
+	switch {
+	case !p.RetainUntil.After(created):
+		problems = append(problems, "policy has no retention period")
+	case now.After(p.RetainUntil):
+		problems = append(problems, "retention period has ended")
+	}
+	return problems
+}
+
+// Share is one party's secret share, verifiably encrypted to the agent.
+type Share struct {
+	Party       string `json:"party"`
+	PublicShare []byte `json:"public_share"` // As listed in the public bundle
+	Label       string `json:"label"`        // Label the ciphertext is bound to
+	Ciphertext  []byte `json:"ciphertext"`
+}
+
+// Package is an escrow deposit: every share of a key encrypted to the agent,
+// the policy it is held under and the key's public bundle.
+type Package struct {
+	Version   int       `json:"version"`
+	ID        string    `json:"id"`
+	KeyID     string    `json:"key_id"` // Fingerprint of PublicKey
+	PublicKey []byte    `json:"public_key"`
+	Agent     Agent     `json:"agent"`
+	Policy    Policy    `json:"policy"`
+	Bundle    []byte    `json:"bundle"` // Signed public bundle, verbatim
+	Shares    []Share   `json:"shares"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignedPackage is a Package signed with the custodian's Ed25519 key.  The
+// package is kept as the exact JSON that was signed.
+type SignedPackage struct {
+	Package   json.RawMessage   `json:"package"`
+	PublicKey ed25519.PublicKey `json:"public_key"`
+	Signature []byte            `json:"signature"`
+}
+
+// ExportConfig contains what Export needs to build a package.
+type ExportConfig struct {
+	Agent  Agent
+	Policy Policy
+	// Bundle is the key's signed public bundle, and PublicKey and
+	// PublicShares the group key and per-party public shares it lists.
+	Bundle       []byte
+	PublicKey    []byte
+	PublicShares map[string][]byte
+	// Encrypt verifiably encrypts the secret share of party, whose public
+	// share is publicShare, to agentKey under label, e.g. with
+	// mpc.PVEEncrypt.  Secret shares never pass through this package.
+	Encrypt func(party string, publicShare, agentKey []byte, label string) ([]byte, error)
+	// Signer is the custodian's signing key.
+	Signer ed25519.PrivateKey
+	// Now returns the creation time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Export encrypts every share listed in config.PublicShares to the agent and
+// returns the signed package as JSON.
+func Export(config ExportConfig) ([]byte, error) {
+	if config.Agent.Name == "" || len(config.Agent.EncryptionKey) == 0 {
+		return nil, fmt.Errorf("agent name and encryption key must be provided")
+	}
+	if len(config.Bundle) == 0 || len(config.PublicKey) == 0 || len(config.PublicShares) == 0 {
+		return nil, fmt.Errorf("public bundle, key and shares must be provided")
+	}
+	if config.Encrypt == nil {
+		return nil, fmt.Errorf("encrypt must be provided")
+	}
+	if len(config.Signer) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid signing key")
+	}
+	if !json.Valid(config.Bundle) {
+		return nil, fmt.Errorf("public bundle is not JSON")
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	created := now().UTC()
+	if problems := config.Policy.validate(created, created); len(problems) > 0 {
+		return nil, fmt.Errorf("incomplete policy: %s", problems[0])
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	p := &Package{
+		Version:   packageVersion,
+		ID:        hex.EncodeToString(id),
+		KeyID:     fingerprint.Key(config.PublicKey).String(),
+		PublicKey: config.PublicKey,
+		Agent:     config.Agent,
+		Policy:    config.Policy,
+		Bundle:    config.Bundle,
+		CreatedAt: created,
+	}
+	for _, party := range sortedParties(config.PublicShares) {
+		qi := config.PublicShares[party]
+		label := shareLabel(p.ID, party)
+		ct, err := config.Encrypt(party, qi, config.Agent.EncryptionKey, label)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting share of %s: %w", party, err)
+		}
+		p.Shares = append(p.Shares, Share{Party: party, PublicShare: qi, Label: label, Ciphertext: ct})
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	// Not indented: that would reformat the signed package.
+	return json.Marshal(&SignedPackage{
+		Package:   data,
+		PublicKey: config.Signer.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(config.Signer, packageDigest(data)),
+	})
+}
+
+// shareLabel binds a ciphertext to its package and party, so that it cannot
+// be passed off as another party's share or moved to another package.
+func shareLabel(packageID, party string) string {
+	return labelPrefix + "/" + packageID + "/" + party
+}
+
+func packageDigest(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(packageDomain))
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func sortedParties(shares map[string][]byte) []string {
+	parties := make([]string, 0, len(shares))
+	for party := range shares {
+		parties = append(parties, party)
+	}
+	sort.Strings(parties)
+	return parties
+}
+
+// VerifyConfig contains what the agent checks a package against.
+type VerifyConfig struct {
+	// Custodian, if set, is the key that must have signed the package.
+	Custodian ed25519.PublicKey
+	// AgentKey is the agent's own encryption key.  Required.
+	AgentKey []byte
+	// Bundle verifies the package's public bundle and returns the group key
+	// and public shares it lists, e.g. with mpc.ParsePublicBundle.
+	Bundle func(bundle []byte) (publicKey []byte, publicShares map[string][]byte, err error)
+	// VerifyShare checks, without decrypting it, that s.Ciphertext encrypts
+	// the secret of s.PublicShare to agentKey under s.Label, e.g. with
+	// mpc.PVEVerify.
+	VerifyShare func(s *Share, agentKey []byte) error
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Report is the outcome of the agent's verification of a package.
+type Report struct {
+	PackageID string    `json:"package_id"`
+	KeyID     string    `json:"key_id"`
+	Custodian []byte    `json:"custodian"` // Key that signed the package
+	Parties   []string  `json:"parties"`   // Parties whose share verified
+	Valid     bool      `json:"valid"`
+	Problems  []string  `json:"problems,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+
+	Package *Package `json:"-"`
+}
+
+// Verify checks a signed package as the escrow agent: the custodian's
+// signature, that every share is encrypted to config.AgentKey and encrypts
+// the secret of the public share listed in the key's bundle, that no party
+// of the bundle is missing and that the policy is complete and in force.
+// Problems with the content are reported in the Report; an error wrapping
+// ErrInvalid is only returned for a package that cannot be checked.
+func Verify(data []byte, config VerifyConfig) (*Report, error) {
+	if len(config.AgentKey) == 0 || config.Bundle == nil || config.VerifyShare == nil {
+		return nil, fmt.Errorf("agent key, bundle and share verifiers must be provided")
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	var signed SignedPackage
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(signed.PublicKey) != ed25519.PublicKeySize || !ed25519.Verify(signed.PublicKey, packageDigest(signed.Package), signed.Signature) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalid)
+	}
+	if config.Custodian != nil && !bytes.Equal(config.Custodian, signed.PublicKey) {
+		return nil, fmt.Errorf("%w: signed by %x, not the custodian", ErrInvalid, []byte(signed.PublicKey))
+	}
+	var p Package
+	if err := json.Unmarshal(signed.Package, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if p.Version != packageVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalid, p.Version)
+	}
+
+	r := &Report{PackageID: p.ID, KeyID: p.KeyID, Custodian: signed.PublicKey, Package: &p}
+	problem := func(format string, args ...any) { r.Problems = append(r.Problems, fmt.Sprintf(format, args...)) }
+	if !bytes.Equal(p.Agent.EncryptionKey, config.AgentKey) {
+		problem("shares are encrypted to another agent key")
+	}
+	r.Problems = append(r.Problems, p.Policy.validate(p.CreatedAt, now())...)
+	if p.KeyID != fingerprint.Key(p.PublicKey).String() {
+		problem("key ID does not match the public key")
+	}
+
+	publicKey, publicShares, err := config.Bundle(p.Bundle)
+	if err != nil {
+		problem("public bundle: %v", err)
+	} else if !bytes.Equal(publicKey, p.PublicKey) {
+		problem("public bundle is for another key")
+	}
+	seen := make(map[string]bool, len(p.Shares))
+	for i := range p.Shares {
+		s := &p.Shares[i]
+		qi, listed := publicShares[s.Party]
+		switch {
+		case seen[s.Party]:
+			problem("duplicate share of %s", s.Party)
+		case publicShares != nil && !listed:
+			problem("share of %s, who is not in the public bundle", s.Party)
+		case publicShares != nil && !bytes.Equal(qi, s.PublicShare):
+			problem("public share of %s does not match the bundle", s.Party)
+		case s.Label != shareLabel(p.ID, s.Party):
+			problem("share of %s has label %q", s.Party, s.Label)
+		default:
+			if err := config.VerifyShare(s, config.AgentKey); err != nil {
+				problem("share of %s: %v", s.Party, err)
+				break
+			}
+			r.Parties = append(r.Parties, s.Party)
+		}
+		seen[s.Party] = true
+	}
+	for _, party := range sortedParties(publicShares) {
+		if !seen[party] {
+			problem("share of %s is missing", party)
+		}
+	}
+	r.Valid = len(r.Problems) == 0
+	r.CheckedAt = now().UTC()
+	return r, nil
+}
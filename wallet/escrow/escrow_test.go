@@ -0,0 +1,217 @@
+package escrow
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+)
+
+var epoch = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+// fakeEncrypt stands in for PVE: the "ciphertext" commits to the agent key,
+// label and public share, so it can be checked without a secret.
+func fakeEncrypt(_ string, publicShare, agentKey []byte, label string) ([]byte, error) {
+	sum := sha256.Sum256(bytes.Join([][]byte{agentKey, []byte(label), publicShare}, []byte{0}))
+	return sum[:], nil
+}
+
+func fakeVerifyShare(s *Share, agentKey []byte) error {
+	want, _ := fakeEncrypt(s.Party, s.PublicShare, agentKey, s.Label)
+	if !bytes.Equal(want, s.Ciphertext) {
+		return errors.New("ciphertext rejected")
+	}
+	return nil
+}
+
+type fixture struct {
+	custodian ed25519.PrivateKey
+	agentKey  []byte
+	publicKey []byte
+	shares    map[string][]byte
+	bundle    []byte
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{
+		custodian: approvaltest.New(t, "custodian").Private["custodian"],
+		agentKey:  []byte("agent encryption key"),
+		publicKey: []byte("group key"),
+		shares:    map[string][]byte{"kms": []byte("Q1"), "hsm": []byte("Q2"), "cold": []byte("Q3")},
+	}
+	var err error
+	f.bundle, err = json.Marshal(map[string]any{"public_key": f.publicKey, "public_shares": f.shares})
+	require.NoError(t, err)
+	return f
+}
+
+func (f *fixture) export(t *testing.T) []byte {
+	data, err := Export(ExportConfig{
+		Agent: Agent{Name: "Escrow Trust", EncryptionKey: f.agentKey},
+		Policy: Policy{
+			Custodian:         "Example Custody",
+			ReleaseConditions: []string{"custodian insolvency", "court order"},
+			RetainUntil:       epoch.AddDate(7, 0, 0),
+		},
+		Bundle:       f.bundle,
+		PublicKey:    f.publicKey,
+		PublicShares: f.shares,
+		Encrypt:      fakeEncrypt,
+		Signer:       f.custodian,
+		Now:          func() time.Time { return epoch },
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func (f *fixture) config() VerifyConfig {
+	return VerifyConfig{
+		Custodian: f.custodian.Public().(ed25519.PublicKey),
+		AgentKey:  f.agentKey,
+		Bundle: func(data []byte) ([]byte, map[string][]byte, error) {
+			var b struct {
+				PublicKey    []byte            `json:"public_key"`
+				PublicShares map[string][]byte `json:"public_shares"`
+			}
+			err := json.Unmarshal(data, &b)
+			return b.PublicKey, b.PublicShares, err
+		},
+		VerifyShare: fakeVerifyShare,
+		Now:         func() time.Time { return epoch.Add(time.Hour) },
+	}
+}
+
+// tamper re-signs the package after mutate changed it, as a custodian
+// producing a bad deposit would.
+func (f *fixture) tamper(t *testing.T, data []byte, mutate func(p *Package)) []byte {
+	var signed SignedPackage
+	require.NoError(t, json.Unmarshal(data, &signed))
+	var p Package
+	require.NoError(t, json.Unmarshal(signed.Package, &p))
+	mutate(&p)
+	raw, err := json.Marshal(&p)
+	require.NoError(t, err)
+	signed.Package = raw
+	signed.Signature = ed25519.Sign(f.custodian, packageDigest(raw))
+	out, err := json.Marshal(&signed)
+	require.NoError(t, err)
+	return out
+}
+
+func TestExportAndVerify(t *testing.T) {
+	f := newFixture(t)
+	data := f.export(t)
+
+	r, err := Verify(data, f.config())
+	require.NoError(t, err)
+	assert.True(t, r.Valid, r.Problems)
+	assert.Equal(t, []string{"cold", "hsm", "kms"}, r.Parties)
+	assert.NotEmpty(t, r.KeyID)
+	require.NotNil(t, r.Package)
+	assert.Equal(t, "Example Custody", r.Package.Policy.Custodian)
+	for _, s := range r.Package.Shares {
+		assert.Contains(t, s.Label, r.PackageID)
+	}
+}
+
+func TestVerifyReportsProblems(t *testing.T) {
+	f := newFixture(t)
+	data := f.export(t)
+	for name, tc := range map[string]struct {
+		mutate func(p *Package)
+		config func(c *VerifyConfig)
+		want   string
+	}{
+		"missing share": {
+			mutate: func(p *Package) { p.Shares = p.Shares[1:] },
+			want:   "share of cold is missing",
+		},
+		"swapped share": {
+			mutate: func(p *Package) {
+				p.Shares[0].Ciphertext, p.Shares[1].Ciphertext = p.Shares[1].Ciphertext, p.Shares[0].Ciphertext
+			},
+			want: "ciphertext rejected",
+		},
+		"relabelled share": {
+			mutate: func(p *Package) { p.Shares[0].Label = shareLabel("other", "cold") },
+			want:   "has label",
+		},
+		"other agent": {
+			config: func(c *VerifyConfig) { c.AgentKey = []byte("someone else") },
+			want:   "another agent key",
+		},
+		"no release conditions": {
+			mutate: func(p *Package) { p.Policy.ReleaseConditions = nil },
+			want:   "no release conditions",
+		},
+		"retention ended": {
+			config: func(c *VerifyConfig) { c.Now = func() time.Time { return epoch.AddDate(8, 0, 0) } },
+			want:   "retention period has ended",
+		},
+		"other key": {
+			mutate: func(p *Package) { p.PublicKey = []byte("other key") },
+			want:   "public bundle is for another key",
+		},
+	} {
+		in := data
+		if tc.mutate != nil {
+			in = f.tamper(t, data, tc.mutate)
+		}
+		config := f.config()
+		if tc.config != nil {
+			tc.config(&config)
+		}
+		r, err := Verify(in, config)
+		require.NoError(t, err, name)
+		assert.False(t, r.Valid, name)
+		assert.Contains(t, joined(r.Problems), tc.want, name)
+	}
+}
+
+func TestVerifyRejectsForgery(t *testing.T) {
+	f := newFixture(t)
+	data := f.export(t)
+
+	var signed SignedPackage
+	require.NoError(t, json.Unmarshal(data, &signed))
+	signed.Package = bytes.Replace(signed.Package, []byte("court order"), []byte("on request"), 1)
+	forged, err := json.Marshal(&signed)
+	require.NoError(t, err)
+	_, err = Verify(forged, f.config())
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// A package signed by anyone but the pinned custodian.
+	other := newFixture(t)
+	_, err = Verify(other.export(t), f.config())
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestExportRequiresPolicy(t *testing.T) {
+	f := newFixture(t)
+	_, err := Export(ExportConfig{
+		Agent:        Agent{Name: "Escrow Trust", EncryptionKey: f.agentKey},
+		Policy:       Policy{Custodian: "Example Custody"},
+		Bundle:       f.bundle,
+		PublicKey:    f.publicKey,
+		PublicShares: f.shares,
+		Encrypt:      fakeEncrypt,
+		Signer:       f.custodian,
+	})
+	assert.ErrorContains(t, err, "incomplete policy")
+}
+
+func joined(problems []string) string {
+	var b bytes.Buffer
+	for _, p := range problems {
+		b.WriteString(p + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,21 @@
+// Package fingerprint gives keys and shares short names that people can
+// compare by reading them aloud.
+//
+// A fingerprint is 88 bits of a domain-separated SHA-256 hash, written as
+// eight words of the BIP-39 English word list:
+//
+//	fmt.Println(fingerprint.Key(publicKey)) // eight words
+//	fmt.Println(fingerprint.Share(blob))    // of an encrypted or stored share
+//
+// Operators on a call compare fingerprints instead of reading hex or base64:
+// every tool that prints a key or a share prints its fingerprint too.  Key
+// and share fingerprints use different hashes, so a share can never be
+// mistaken for a key.  ShareDigest takes the SHA-256 digest of a share that
+// audits already record, so a share can be named without access to it, and
+// Parse reads back a fingerprint that was typed in.
+//
+// Eighty-eight bits are plenty to tell keys apart and to catch a wrong or
+// altered share, but not to resist a brute-force search for a collision;
+// fingerprints confirm that people are talking about the same object, they
+// do not authenticate it.
+package fingerprint
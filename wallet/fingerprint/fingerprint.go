@@ -0,0 +1,106 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// Size is the length of a fingerprint in bytes.
+const Size = 11
+
+// Words is the number of words a fingerprint is written as, each carrying
+// 11 bits.
+const Words = Size * 8 / 11
+
+// Domain separation tags of the fingerprinted objects.
+const (
+	keyTag   = "cb-mpc key fingerprint v1\x00"
+	shareTag = "cb-mpc share fingerprint v1\x00"
+)
+
+// ErrInvalid is returned by Parse for text that is not a fingerprint.
+var ErrInvalid = errors.New("fingerprint: invalid fingerprint")
+
+// Fingerprint identifies a key or a share.
+type Fingerprint [Size]byte
+
+// Key returns the fingerprint of a public key.
+func Key(publicKey []byte) Fingerprint {
+	return of(keyTag, publicKey)
+}
+
+// Share returns the fingerprint of a share blob.
+func Share(blob []byte) Fingerprint {
+	sum := sha256.Sum256(blob)
+	return ShareDigest(sum[:])
+}
+
+// ShareDigest returns the fingerprint of the share whose SHA-256 digest is
+// digest.
+func ShareDigest(digest []byte) Fingerprint {
+	return of(shareTag, digest)
+}
+
+func of(tag string, data []byte) Fingerprint {
+	h := sha256.New()
+	h.Write([]byte(tag))
+	h.Write(data)
+	var f Fingerprint
+	copy(f[:], h.Sum(nil))
+	return f
+}
+
+// Words returns the fingerprint as BIP-39 words.
+func (f Fingerprint) Words() []string {
+	list := bip39.GetWordList()
+	words := make([]string, Words)
+	var acc uint32
+	bits := 0
+	n := 0
+	for _, b := range f {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		if bits >= 11 {
+			bits -= 11
+			words[n] = list[acc>>bits&0x7ff]
+			n++
+		}
+	}
+	return words
+}
+
+// String returns the words of the fingerprint separated by spaces.
+func (f Fingerprint) String() string {
+	return strings.Join(f.Words(), " ")
+}
+
+// Parse reads a fingerprint written as words.  Case and any separators
+// other than letters are ignored.
+func Parse(s string) (Fingerprint, error) {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r < 'a' || r > 'z' })
+	if len(words) != Words {
+		return Fingerprint{}, fmt.Errorf("%w: need %d words, got %d", ErrInvalid, Words, len(words))
+	}
+	var f Fingerprint
+	var acc uint32
+	bits := 0
+	n := 0
+	for i, w := range words {
+		index, ok := bip39.GetWordIndex(w)
+		if !ok {
+			return Fingerprint{}, fmt.Errorf("%w: word %d (%q) is not in the BIP-39 word list", ErrInvalid, i+1, w)
+		}
+		acc = acc<<11 | uint32(index)
+		bits += 11
+		for bits >= 8 {
+			bits -= 8
+			f[n] = byte(acc >> bits)
+			n++
+		}
+	}
+	return f, nil
+}
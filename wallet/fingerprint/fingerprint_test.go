@@ -0,0 +1,47 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWords(t *testing.T) {
+	var zero Fingerprint
+	assert.Equal(t, "abandon abandon abandon abandon abandon abandon abandon abandon", zero.String())
+	var ones Fingerprint
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	assert.Equal(t, strings.Repeat("zoo ", Words-1)+"zoo", ones.String())
+	// 0x00 0x20 starts with the 11-bit index 1.
+	assert.Equal(t, "ability", Fingerprint{0x00, 0x20}.Words()[0])
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	f := Key([]byte("public key"))
+	got, err := Parse(f.String())
+	require.NoError(t, err)
+	assert.Equal(t, f, got)
+
+	// Transcribed with other separators and case.
+	got, err = Parse(strings.ToUpper(strings.Join(f.Words(), "-")))
+	require.NoError(t, err)
+	assert.Equal(t, f, got)
+
+	_, err = Parse("abandon abandon")
+	assert.ErrorIs(t, err, ErrInvalid)
+	_, err = Parse("abandon abandon abandon abandon abandon abandon abandon bitcoin")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestDomainSeparation(t *testing.T) {
+	data := []byte("same bytes")
+	sum := sha256.Sum256(data)
+	assert.Equal(t, Share(data), ShareDigest(sum[:]))
+	assert.NotEqual(t, Key(sum[:]), ShareDigest(sum[:]))
+	assert.NotEqual(t, Key(data), Key([]byte("other bytes")))
+}
@@ -0,0 +1,27 @@
+// Package handover moves a party's share from one host to another, e.g.
+// when hardware is replaced, with signed evidence of every step instead of
+// operators copying blobs around.
+//
+// A handover is a five-step ceremony between the old host, the new host and
+// the coordinator:
+//
+//  1. approval – a `Request` names the key, the party and both hosts.
+//     Approvers sign it with `Approve` until the `Policy` quorum is reached.
+//  2. offer – the new host checks the approvals and answers with an `Offer`
+//     from `NewOffer`: a one-time X25519 key and the `StoragePolicy` it will
+//     keep the share under, which must meet the policy's
+//     `StorageRequirement`.
+//  3. transfer – the old host checks the approvals and the offer and `Send`s
+//     its share sealed to the offered key in a `Package`.
+//  4. receipt – the new host `Receive`s the package, stores the share, reads
+//     it back and signs a `Receipt` for the share digest.
+//  5. deletion – once the old host sees a valid receipt for the exact share
+//     it sent, `Release` wipes its copies through retire Wipers and signs a
+//     `Deletion` statement.
+//
+// `Complete` then checks the whole chain of evidence and appends a `Record`
+// to the AuditLog.  Each host checks the approvals and the other host's
+// signatures itself, so a compromised coordinator cannot redirect a share.
+// As with key retirement, a deletion statement attests that the old host
+// wiped its copies; it cannot prove that no other copy exists.
+package handover
@@ -0,0 +1,570 @@
+package handover
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+
+	"solana-threshold-wallet/wallet/approval"
+	"solana-threshold-wallet/wallet/retire"
+)
+
+// Signature domains separate the signatures of each step from one another
+// and from any other use of the same keys.
+const (
+	approvalDomain = "cb-mpc share handover approval\n"
+	offerDomain    = "cb-mpc share handover offer\n"
+	packageDomain  = "cb-mpc share handover package\n"
+	receiptDomain  = "cb-mpc share handover receipt\n"
+	deletionDomain = "cb-mpc share handover deletion\n"
+	sealDomain     = "cb-mpc share handover seal\n"
+)
+
+var (
+	// ErrNotApproved is returned when a request lacks a quorum of valid
+	// approvals.
+	ErrNotApproved = approval.ErrNotApproved
+	// ErrBadEvidence is returned when an offer, package, receipt or deletion
+	// statement does not verify or does not match the request.
+	ErrBadEvidence = errors.New("handover: invalid evidence")
+	// ErrStorage is returned when an offered storage policy does not meet
+	// the policy's requirement.
+	ErrStorage = errors.New("handover: storage policy not acceptable")
+)
+
+// StorageRequirement is what a new host must promise about how it keeps the
+// share.
+type StorageRequirement struct {
+	Encrypted      bool `json:"encrypted"`       // Share must be encrypted at rest
+	HardwareBacked bool `json:"hardware_backed"` // Encryption key must be held in hardware
+}
+
+// StoragePolicy is how a new host keeps the share.
+type StoragePolicy struct {
+	Medium         string `json:"medium"` // Name of the keystore.Medium
+	Encrypted      bool   `json:"encrypted"`
+	HardwareBacked bool   `json:"hardware_backed"`
+}
+
+// check reports whether p meets r.
+func (r StorageRequirement) check(p StoragePolicy) error {
+	if r.Encrypted && !p.Encrypted {
+		return fmt.Errorf("%w: share must be encrypted at rest", ErrStorage)
+	}
+	if r.HardwareBacked && !p.HardwareBacked {
+		return fmt.Errorf("%w: share must be protected by hardware", ErrStorage)
+	}
+	return nil
+}
+
+// Policy names who may approve a handover, the identity keys of the hosts
+// and the storage new hosts must provide.
+type Policy struct {
+	Approvers map[string]ed25519.PublicKey `json:"approvers"`
+	Required  int                          `json:"required"` // Distinct approvals needed
+	Hosts     map[string]ed25519.PublicKey `json:"hosts"`
+	Storage   StorageRequirement           `json:"storage"`
+}
+
+// Request asks for a party's share to move from one host to another.
+type Request struct {
+	ID          string    `json:"id"`
+	KeyID       string    `json:"key_id"`
+	Party       string    `json:"party"`
+	From        string    `json:"from"` // Host holding the share
+	To          string    `json:"to"`   // Host receiving it
+	Reason      string    `json:"reason"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// NewRequest creates a request with a fresh random ID.
+func NewRequest(keyID, party, from, to, reason string, now time.Time) (*Request, error) {
+	if keyID == "" || party == "" {
+		return nil, fmt.Errorf("key ID and party must be provided")
+	}
+	if from == "" || to == "" || from == to {
+		return nil, fmt.Errorf("two distinct hosts must be provided")
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("reason must be provided")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generating request ID: %w", err)
+	}
+	return &Request{
+		ID:          hex.EncodeToString(id),
+		KeyID:       keyID,
+		Party:       party,
+		From:        from,
+		To:          to,
+		Reason:      reason,
+		RequestedAt: now.UTC(),
+	}, nil
+}
+
+// Hash returns the SHA-256 digest of the request's JSON encoding.
+func (r *Request) Hash() ([]byte, error) {
+	return hashJSON(r)
+}
+
+// Approval records that an approver authorized a request.
+type Approval = approval.Approval
+
+// Approve signs req on behalf of approver.
+func Approve(req *Request, approver string, key ed25519.PrivateKey, now time.Time) (*Approval, error) {
+	hash, err := req.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing request: %w", err)
+	}
+	return approval.Sign(approvalDomain, hash, approver, key, now)
+}
+
+// checkApprovals checks that approvals contain at least policy.Required
+// valid signatures on req from distinct approvers, and returns the request
+// hash.
+func checkApprovals(req *Request, approvals []Approval, policy Policy) ([]byte, error) {
+	hash, err := req.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing request: %w", err)
+	}
+	quorum := approval.Quorum{Approvers: policy.Approvers, Required: policy.Required}
+	if err := quorum.Check(approvalDomain, hash, approvals); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// HostConfig identifies a host taking part in a handover.
+type HostConfig struct {
+	Name   string
+	Key    ed25519.PrivateKey // Signs the host's evidence
+	Policy Policy
+	Now    func() time.Time
+}
+
+func (h *HostConfig) now() time.Time {
+	if h.Now == nil {
+		return time.Now().UTC()
+	}
+	return h.Now().UTC()
+}
+
+// check verifies that the host is the one named by role in req, that it
+// has a key and that req is approved, and returns the request hash.
+func (h *HostConfig) check(req *Request, role string, approvals []Approval) ([]byte, error) {
+	if len(h.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("host key must be provided")
+	}
+	if role != h.Name {
+		return nil, fmt.Errorf("host %q is not part of the handover", h.Name)
+	}
+	return checkApprovals(req, approvals, h.Policy)
+}
+
+// verify checks a host's signature over digest.
+func (p *Policy) verify(host, domain string, digest, sig []byte) error {
+	key, ok := p.Hosts[host]
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: unknown host %q", ErrBadEvidence, host)
+	}
+	if !ed25519.Verify(key, append([]byte(domain), digest...), sig) {
+		return fmt.Errorf("%w: signature of host %s does not verify", ErrBadEvidence, host)
+	}
+	return nil
+}
+
+// Offer is the new host's one-time encryption key and storage promise.
+type Offer struct {
+	RequestHash   []byte        `json:"request_hash"`
+	Host          string        `json:"host"`
+	EncryptionKey []byte        `json:"encryption_key"` // X25519 public key
+	Storage       StoragePolicy `json:"storage"`
+	Signature     []byte        `json:"signature"`
+}
+
+func (o *Offer) digest() ([]byte, error) {
+	unsigned := *o
+	unsigned.Signature = nil
+	return hashJSON(&unsigned)
+}
+
+// verify checks that o is the new host's offer for the request with hash
+// requestHash and that it meets the storage requirement.
+func (o *Offer) verify(req *Request, requestHash []byte, policy Policy) error {
+	if o.Host != req.To || !bytes.Equal(o.RequestHash, requestHash) {
+		return fmt.Errorf("%w: offer is not from %s for this request", ErrBadEvidence, req.To)
+	}
+	digest, err := o.digest()
+	if err != nil {
+		return err
+	}
+	if err := policy.verify(o.Host, offerDomain, digest, o.Signature); err != nil {
+		return err
+	}
+	return policy.Storage.check(o.Storage)
+}
+
+// NewOffer checks that req is approved and names host as the new host and
+// returns its signed offer and the private key that will open the package.
+func NewOffer(host HostConfig, req *Request, approvals []Approval, storage StoragePolicy) (*Offer, *ecdh.PrivateKey, error) {
+	hash, err := host.check(req, req.To, approvals)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := host.Policy.Storage.check(storage); err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	o := &Offer{RequestHash: hash, Host: host.Name, EncryptionKey: key.PublicKey().Bytes(), Storage: storage}
+	digest, err := o.digest()
+	if err != nil {
+		return nil, nil, err
+	}
+	o.Signature = ed25519.Sign(host.Key, append([]byte(offerDomain), digest...))
+	return o, key, nil
+}
+
+// Package is the share sealed to the offer's key by the old host.
+type Package struct {
+	RequestHash []byte `json:"request_hash"`
+	Host        string `json:"host"`
+	Ephemeral   []byte `json:"ephemeral"`  // X25519 public key of the sender
+	Ciphertext  []byte `json:"ciphertext"` // AES-256-GCM, nonce first
+	ShareDigest []byte `json:"share_digest"`
+	Signature   []byte `json:"signature"`
+}
+
+func (p *Package) digest() ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	return hashJSON(&unsigned)
+}
+
+// Send checks that req is approved and names host as the old host, verifies
+// the new host's offer and returns the share stored under id in source,
+// sealed to the offer.
+func Send(ctx context.Context, host HostConfig, source keystore.Medium, id string, req *Request, approvals []Approval, offer *Offer) (*Package, error) {
+	hash, err := host.check(req, req.From, approvals)
+	if err != nil {
+		return nil, err
+	}
+	if err := offer.verify(req, hash, host.Policy); err != nil {
+		return nil, err
+	}
+	recipient, err := ecdh.X25519().NewPublicKey(offer.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadEvidence, err)
+	}
+	share, err := source.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading share: %w", err)
+	}
+	defer clear(share)
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := sealKey(ephemeral, recipient, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(share)
+	p := &Package{
+		RequestHash: hash,
+		Host:        host.Name,
+		Ephemeral:   ephemeral.PublicKey().Bytes(),
+		Ciphertext:  aead.Seal(nonce, nonce, share, hash),
+		ShareDigest: sum[:],
+	}
+	digest, err := p.digest()
+	if err != nil {
+		return nil, err
+	}
+	p.Signature = ed25519.Sign(host.Key, append([]byte(packageDomain), digest...))
+	return p, nil
+}
+
+// sealKey derives the AES-256-GCM key shared by priv and peer, bound to the
+// sender's ephemeral key and the recipient's key.
+func sealKey(priv *ecdh.PrivateKey, peer, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	shared, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(shared)
+	h := sha256.New()
+	h.Write([]byte(sealDomain))
+	h.Write(shared)
+	h.Write(ephemeral.Bytes())
+	h.Write(recipient.Bytes())
+	key := h.Sum(nil)
+	defer clear(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Receipt is the new host's statement that it stored the share.
+type Receipt struct {
+	RequestHash []byte        `json:"request_hash"`
+	Host        string        `json:"host"`
+	ShareDigest []byte        `json:"share_digest"`
+	Storage     StoragePolicy `json:"storage"`
+	StoredAt    time.Time     `json:"stored_at"`
+	Signature   []byte        `json:"signature"`
+}
+
+func (r *Receipt) digest() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = nil
+	return hashJSON(&unsigned)
+}
+
+// verify checks that r is the new host's receipt for the share in p.
+func (r *Receipt) verify(req *Request, requestHash []byte, p *Package, policy Policy) error {
+	if r.Host != req.To || !bytes.Equal(r.RequestHash, requestHash) {
+		return fmt.Errorf("%w: receipt is not from %s for this request", ErrBadEvidence, req.To)
+	}
+	if !bytes.Equal(r.ShareDigest, p.ShareDigest) {
+		return fmt.Errorf("%w: receipt is for a different share", ErrBadEvidence)
+	}
+	digest, err := r.digest()
+	if err != nil {
+		return err
+	}
+	if err := policy.verify(r.Host, receiptDomain, digest, r.Signature); err != nil {
+		return err
+	}
+	return policy.Storage.check(r.Storage)
+}
+
+// ReceiveConfig contains the new host's configuration for Receive.
+type ReceiveConfig struct {
+	Host HostConfig
+	// Key is the private key returned by NewOffer.
+	Key *ecdh.PrivateKey
+	// Target is where the share is stored, under ID.  Its Name is recorded
+	// as the medium of the storage policy.
+	Target keystore.Medium
+	ID     string
+}
+
+// Receive checks req, the approvals and the old host's package against the
+// host's own offer, stores the share in the target and returns the signed
+// receipt.
+func Receive(ctx context.Context, config ReceiveConfig, req *Request, approvals []Approval, offer *Offer, p *Package) (*Receipt, error) {
+	host := &config.Host
+	hash, err := host.check(req, req.To, approvals)
+	if err != nil {
+		return nil, err
+	}
+	if config.Key == nil || !bytes.Equal(config.Key.PublicKey().Bytes(), offer.EncryptionKey) {
+		return nil, fmt.Errorf("key does not belong to the offer")
+	}
+	if config.Target == nil || offer.Storage.Medium != config.Target.Name() {
+		return nil, fmt.Errorf("target is not the medium promised in the offer")
+	}
+	if p.Host != req.From || !bytes.Equal(p.RequestHash, hash) {
+		return nil, fmt.Errorf("%w: package is not from %s for this request", ErrBadEvidence, req.From)
+	}
+	digest, err := p.digest()
+	if err != nil {
+		return nil, err
+	}
+	if err := host.Policy.verify(p.Host, packageDomain, digest, p.Signature); err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(p.Ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadEvidence, err)
+	}
+	aead, err := sealKey(config.Key, ephemeral, ephemeral, config.Key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrBadEvidence)
+	}
+	nonce, ciphertext := p.Ciphertext[:aead.NonceSize()], p.Ciphertext[aead.NonceSize():]
+	share, err := aead.Open(nil, nonce, ciphertext, hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: share does not decrypt", ErrBadEvidence)
+	}
+	defer clear(share)
+	if sum := sha256.Sum256(share); !bytes.Equal(sum[:], p.ShareDigest) {
+		return nil, fmt.Errorf("%w: share does not match its digest", ErrBadEvidence)
+	}
+
+	if err := config.Target.Store(ctx, config.ID, share); err != nil {
+		return nil, fmt.Errorf("storing share: %w", err)
+	}
+	restored, err := config.Target.Load(ctx, config.ID)
+	if err != nil {
+		return nil, fmt.Errorf("reading back share: %w", err)
+	}
+	defer clear(restored)
+	if !bytes.Equal(restored, share) {
+		return nil, fmt.Errorf("stored share does not match the received one")
+	}
+
+	r := &Receipt{RequestHash: hash, Host: host.Name, ShareDigest: p.ShareDigest, Storage: offer.Storage, StoredAt: host.now()}
+	if digest, err = r.digest(); err != nil {
+		return nil, err
+	}
+	r.Signature = ed25519.Sign(host.Key, append([]byte(receiptDomain), digest...))
+	return r, nil
+}
+
+// Deletion is the old host's statement that it wiped its share.
+type Deletion struct {
+	RequestHash []byte              `json:"request_hash"`
+	Host        string              `json:"host"`
+	ShareDigest []byte              `json:"share_digest"`
+	Wiped       []retire.WipeRecord `json:"wiped"`
+	Signature   []byte              `json:"signature"`
+}
+
+func (d *Deletion) digest() ([]byte, error) {
+	unsigned := *d
+	unsigned.Signature = nil
+	return hashJSON(&unsigned)
+}
+
+// Release checks that the new host's receipt is for the share in the
+// package the old host sent, wipes the share from every location and
+// returns the signed deletion statement.
+func Release(ctx context.Context, host HostConfig, shares []retire.Share, req *Request, approvals []Approval, p *Package, receipt *Receipt) (*Deletion, error) {
+	hash, err := host.check(req, req.From, approvals)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("at least one share location must be provided")
+	}
+	if p.Host != host.Name || !bytes.Equal(p.RequestHash, hash) {
+		return nil, fmt.Errorf("%w: package was not sent by this host for this request", ErrBadEvidence)
+	}
+	if err := receipt.verify(req, hash, p, host.Policy); err != nil {
+		return nil, err
+	}
+
+	d := &Deletion{RequestHash: hash, Host: host.Name, ShareDigest: p.ShareDigest}
+	for _, share := range shares {
+		if err := share.Wiper.Wipe(ctx, share.ID); err != nil {
+			return nil, fmt.Errorf("wiping %s on %s: %w", share.ID, share.Wiper.Name(), err)
+		}
+		d.Wiped = append(d.Wiped, retire.WipeRecord{Location: share.Wiper.Name(), ID: share.ID, WipedAt: host.now()})
+	}
+	digest, err := d.digest()
+	if err != nil {
+		return nil, err
+	}
+	d.Signature = ed25519.Sign(host.Key, append([]byte(deletionDomain), digest...))
+	return d, nil
+}
+
+// Record is the audit log entry of a completed handover.
+type Record struct {
+	Request     Request    `json:"request"`
+	Approvals   []Approval `json:"approvals"`
+	Offer       Offer      `json:"offer"`
+	Package     Package    `json:"package"` // Sealed share; only the offer's key opens it
+	Receipt     Receipt    `json:"receipt"`
+	Deletion    Deletion   `json:"deletion"`
+	CompletedAt time.Time  `json:"completed_at"`
+}
+
+// AuditLog receives the record of every completed handover.
+type AuditLog interface {
+	Append(r *Record) error
+}
+
+// AuditLogFunc adapts an ordinary function to the AuditLog interface.
+type AuditLogFunc func(r *Record) error
+
+// Append calls f(r).
+func (f AuditLogFunc) Append(r *Record) error { return f(r) }
+
+// Complete checks the whole handover against policy and appends its record
+// to log.
+func Complete(log AuditLog, policy Policy, req *Request, approvals []Approval, offer *Offer, p *Package, receipt *Receipt, deletion *Deletion, now time.Time) (*Record, error) {
+	hash, err := checkApprovals(req, approvals, policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := offer.verify(req, hash, policy); err != nil {
+		return nil, err
+	}
+	if p.Host != req.From || !bytes.Equal(p.RequestHash, hash) {
+		return nil, fmt.Errorf("%w: package is not from %s for this request", ErrBadEvidence, req.From)
+	}
+	digest, err := p.digest()
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.verify(p.Host, packageDomain, digest, p.Signature); err != nil {
+		return nil, err
+	}
+	if err := receipt.verify(req, hash, p, policy); err != nil {
+		return nil, err
+	}
+	if deletion.Host != req.From || !bytes.Equal(deletion.RequestHash, hash) || !bytes.Equal(deletion.ShareDigest, p.ShareDigest) {
+		return nil, fmt.Errorf("%w: deletion statement is not from %s for this share", ErrBadEvidence, req.From)
+	}
+	if len(deletion.Wiped) == 0 {
+		return nil, fmt.Errorf("%w: host %s wiped nothing", ErrBadEvidence, req.From)
+	}
+	if digest, err = deletion.digest(); err != nil {
+		return nil, err
+	}
+	if err := policy.verify(deletion.Host, deletionDomain, digest, deletion.Signature); err != nil {
+		return nil, err
+	}
+
+	rec := &Record{
+		Request:     *req,
+		Approvals:   approvals,
+		Offer:       *offer,
+		Package:     *p,
+		Receipt:     *receipt,
+		Deletion:    *deletion,
+		CompletedAt: now.UTC(),
+	}
+	if err := log.Append(rec); err != nil {
+		return nil, fmt.Errorf("recording handover: %w", err)
+	}
+	return rec, nil
+}
+
+func hashJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
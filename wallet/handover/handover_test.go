@@ -0,0 +1,127 @@
+package handover
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+	"solana-threshold-wallet/wallet/retire"
+)
+
+type fixture struct {
+	policy    Policy
+	approvers *approvaltest.Signers
+	old, new  HostConfig
+	oldDir    string
+	source    *keystore.FileMedium
+	target    *keystore.FileMedium
+	req       *Request
+	approvals []Approval
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	hosts := approvaltest.New(t, "kms-1", "kms-2")
+	f := &fixture{approvers: approvaltest.New(t, "alice", "bob")}
+	f.policy = Policy{
+		Approvers: f.approvers.Public,
+		Required:  2,
+		Hosts:     hosts.Public,
+		Storage:   StorageRequirement{Encrypted: true},
+	}
+	f.old = HostConfig{Name: "kms-1", Key: hosts.Private["kms-1"], Policy: f.policy}
+	f.new = HostConfig{Name: "kms-2", Key: hosts.Private["kms-2"], Policy: f.policy}
+
+	var err error
+	f.oldDir = t.TempDir()
+	f.source, err = keystore.NewFileMedium(keystore.FileMediumConfig{Dir: f.oldDir})
+	require.NoError(t, err)
+	require.NoError(t, f.source.Store(context.Background(), "treasury.kms", []byte("kms share")))
+	encKey := make([]byte, 32)
+	_, err = rand.Read(encKey)
+	require.NoError(t, err)
+	f.target, err = keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir(), Key: encKey})
+	require.NoError(t, err)
+
+	f.req, err = NewRequest("treasury", "kms", "kms-1", "kms-2", "hardware refresh", time.Now())
+	require.NoError(t, err)
+	hash, err := f.req.Hash()
+	require.NoError(t, err)
+	f.approvals = f.approvers.Approve(t, approvalDomain, hash, "alice", "bob")
+	return f
+}
+
+func TestHandover(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	offer, key, err := NewOffer(f.new, f.req, f.approvals, StoragePolicy{Medium: f.target.Name(), Encrypted: true})
+	require.NoError(t, err)
+	p, err := Send(ctx, f.old, f.source, "treasury.kms", f.req, f.approvals, offer)
+	require.NoError(t, err)
+	receipt, err := Receive(ctx, ReceiveConfig{Host: f.new, Key: key, Target: f.target, ID: "treasury.kms"}, f.req, f.approvals, offer, p)
+	require.NoError(t, err)
+
+	stored, err := f.target.Load(ctx, "treasury.kms")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("kms share"), stored)
+
+	deletion, err := Release(ctx, f.old, []retire.Share{{Wiper: retire.NewFileWiper(f.oldDir), ID: "treasury.kms"}}, f.req, f.approvals, p, receipt)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(f.oldDir, "treasury.kms"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	var records []*Record
+	log := AuditLogFunc(func(r *Record) error { records = append(records, r); return nil })
+	rec, err := Complete(log, f.policy, f.req, f.approvals, offer, p, receipt, deletion, time.Now())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Same(t, rec, records[0])
+	assert.Equal(t, p.ShareDigest, rec.Deletion.ShareDigest)
+
+	forged := *deletion
+	forged.Host = "kms-2"
+	_, err = Complete(log, f.policy, f.req, f.approvals, offer, p, receipt, &forged, time.Now())
+	assert.ErrorIs(t, err, ErrBadEvidence)
+}
+
+func TestHandoverChecksEvidence(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	_, _, err := NewOffer(f.new, f.req, f.approvals[:1], StoragePolicy{Medium: f.target.Name(), Encrypted: true})
+	assert.ErrorIs(t, err, ErrNotApproved)
+	_, _, err = NewOffer(f.new, f.req, f.approvals, StoragePolicy{Medium: f.target.Name()})
+	assert.ErrorIs(t, err, ErrStorage)
+	_, _, err = NewOffer(f.old, f.req, f.approvals, StoragePolicy{Medium: f.target.Name(), Encrypted: true})
+	assert.Error(t, err, "only the new host may offer")
+
+	offer, key, err := NewOffer(f.new, f.req, f.approvals, StoragePolicy{Medium: f.target.Name(), Encrypted: true})
+	require.NoError(t, err)
+	weakened := *offer
+	weakened.Storage.Encrypted = false
+	_, err = Send(ctx, f.old, f.source, "treasury.kms", f.req, f.approvals, &weakened)
+	assert.ErrorIs(t, err, ErrBadEvidence)
+
+	p, err := Send(ctx, f.old, f.source, "treasury.kms", f.req, f.approvals, offer)
+	require.NoError(t, err)
+	receipt, err := Receive(ctx, ReceiveConfig{Host: f.new, Key: key, Target: f.target, ID: "treasury.kms"}, f.req, f.approvals, offer, p)
+	require.NoError(t, err)
+
+	// A receipt for a different share does not release the old host's copy.
+	other := *receipt
+	other.ShareDigest = make([]byte, len(receipt.ShareDigest))
+	_, err = Release(ctx, f.old, []retire.Share{{Wiper: retire.NewFileWiper(f.oldDir), ID: "treasury.kms"}}, f.req, f.approvals, p, &other)
+	assert.ErrorIs(t, err, ErrBadEvidence)
+	_, err = os.Stat(filepath.Join(f.oldDir, "treasury.kms"))
+	assert.NoError(t, err)
+}
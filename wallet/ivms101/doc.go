@@ -0,0 +1,11 @@
+// Package ivms101 holds the identity data that the travel rule requires
+// virtual asset service providers to exchange with a transfer, in the JSON
+// form of the interVASP Messaging Standard (IVMS101) used by protocols such
+// as TRP.
+//
+// Only the parts of the standard needed for originator and beneficiary
+// records are modelled.  `Payload.Validate` checks the structure, the code
+// lists and the data constraints that do not depend on outside data, such as
+// that every natural person has a legal name and that an originating
+// individual is identified by more than a name.
+package ivms101
@@ -0,0 +1,346 @@
+package ivms101
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Name identifier types of natural persons.
+const (
+	NaturalNameAlias  = "ALIA"
+	NaturalNameBirth  = "BIRT"
+	NaturalNameMaiden = "MAID"
+	NaturalNameLegal  = "LEGL"
+	NaturalNameMisc   = "MISC"
+)
+
+// Name identifier types of legal persons.
+const (
+	LegalNameLegal   = "LEGL"
+	LegalNameShort   = "SHRT"
+	LegalNameTrading = "TRAD"
+)
+
+// Address types.
+const (
+	AddressHome       = "HOME"
+	AddressBusiness   = "BIZZ"
+	AddressGeographic = "GEOG"
+)
+
+// National identifier types.
+const (
+	IdentifierAlienRegistration = "ARNU"
+	IdentifierPassport          = "CCPT"
+	IdentifierRegistration      = "RAID"
+	IdentifierDriverLicense     = "DRLC"
+	IdentifierForeignInvestment = "FIIN"
+	IdentifierTax               = "TXID"
+	IdentifierSocialSecurity    = "SOCS"
+	IdentifierIdentityCard      = "IDCD"
+	IdentifierLEI               = "LEIX"
+	IdentifierMisc              = "MISC"
+)
+
+var (
+	naturalNameTypes = set(NaturalNameAlias, NaturalNameBirth, NaturalNameMaiden, NaturalNameLegal, NaturalNameMisc)
+	legalNameTypes   = set(LegalNameLegal, LegalNameShort, LegalNameTrading)
+	addressTypes     = set(AddressHome, AddressBusiness, AddressGeographic)
+	identifierTypes  = set(IdentifierAlienRegistration, IdentifierPassport, IdentifierRegistration, IdentifierDriverLicense,
+		IdentifierForeignInvestment, IdentifierTax, IdentifierSocialSecurity, IdentifierIdentityCard, IdentifierLEI, IdentifierMisc)
+	// legalIdentifierTypes are the national identifiers a legal person may
+	// carry (IVMS101 constraint C7).
+	legalIdentifierTypes = set(IdentifierRegistration, IdentifierTax, IdentifierLEI, IdentifierMisc)
+
+	countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+	lei         = regexp.MustCompile(`^[A-Z0-9]{18}[0-9]{2}$`)
+)
+
+func set(values ...string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
+
+// Payload is the IVMS101 identity data exchanged for one transfer.
+type Payload struct {
+	Originator      Originator       `json:"originator"`
+	Beneficiary     Beneficiary      `json:"beneficiary"`
+	OriginatingVASP *OriginatingVASP `json:"originatingVASP,omitempty"`
+	BeneficiaryVASP *BeneficiaryVASP `json:"beneficiaryVASP,omitempty"`
+}
+
+// Originator is the account holder who orders the transfer.
+type Originator struct {
+	OriginatorPersons []Person `json:"originatorPersons"`
+	AccountNumber     []string `json:"accountNumber,omitempty"`
+}
+
+// Beneficiary is the intended recipient of the transfer.
+type Beneficiary struct {
+	BeneficiaryPersons []Person `json:"beneficiaryPersons"`
+	AccountNumber      []string `json:"accountNumber,omitempty"`
+}
+
+// OriginatingVASP is the service provider initiating the transfer.
+type OriginatingVASP struct {
+	OriginatingVASP Person `json:"originatingVASP"`
+}
+
+// BeneficiaryVASP is the service provider receiving the transfer.
+type BeneficiaryVASP struct {
+	BeneficiaryVASP Person `json:"beneficiaryVASP"`
+}
+
+// Person is either a natural or a legal person.
+type Person struct {
+	NaturalPerson *NaturalPerson `json:"naturalPerson,omitempty"`
+	LegalPerson   *LegalPerson   `json:"legalPerson,omitempty"`
+}
+
+// NaturalPerson is an individual.
+type NaturalPerson struct {
+	Name                   NaturalPersonName       `json:"name"`
+	GeographicAddress      []Address               `json:"geographicAddress,omitempty"`
+	NationalIdentification *NationalIdentification `json:"nationalIdentification,omitempty"`
+	CustomerIdentification string                  `json:"customerIdentification,omitempty"`
+	DateAndPlaceOfBirth    *DateAndPlaceOfBirth    `json:"dateAndPlaceOfBirth,omitempty"`
+	CountryOfResidence     string                  `json:"countryOfResidence,omitempty"`
+}
+
+// NaturalPersonName lists the names of an individual.
+type NaturalPersonName struct {
+	NameIdentifier []NaturalPersonNameIdentifier `json:"nameIdentifier"`
+}
+
+// NaturalPersonNameIdentifier is one name of an individual.
+type NaturalPersonNameIdentifier struct {
+	PrimaryIdentifier   string `json:"primaryIdentifier"` // Family name, or the full name if it cannot be split
+	SecondaryIdentifier string `json:"secondaryIdentifier,omitempty"`
+	NameIdentifierType  string `json:"nameIdentifierType"` // One of the NaturalName constants
+}
+
+// LegalPerson is a company or other organisation.
+type LegalPerson struct {
+	Name                   LegalPersonName         `json:"name"`
+	GeographicAddress      []Address               `json:"geographicAddress,omitempty"`
+	CustomerNumber         string                  `json:"customerNumber,omitempty"`
+	NationalIdentification *NationalIdentification `json:"nationalIdentification,omitempty"`
+	CountryOfRegistration  string                  `json:"countryOfRegistration,omitempty"`
+}
+
+// LegalPersonName lists the names of an organisation.
+type LegalPersonName struct {
+	NameIdentifier []LegalPersonNameIdentifier `json:"nameIdentifier"`
+}
+
+// LegalPersonNameIdentifier is one name of an organisation.
+type LegalPersonNameIdentifier struct {
+	LegalPersonName               string `json:"legalPersonName"`
+	LegalPersonNameIdentifierType string `json:"legalPersonNameIdentifierType"` // One of the LegalName constants
+}
+
+// Address is a postal address.
+type Address struct {
+	AddressType        string   `json:"addressType"` // One of the Address constants
+	StreetName         string   `json:"streetName,omitempty"`
+	BuildingNumber     string   `json:"buildingNumber,omitempty"`
+	BuildingName       string   `json:"buildingName,omitempty"`
+	PostCode           string   `json:"postCode,omitempty"`
+	TownName           string   `json:"townName,omitempty"`
+	CountrySubDivision string   `json:"countrySubDivision,omitempty"`
+	AddressLine        []string `json:"addressLine,omitempty"`
+	Country            string   `json:"country"` // ISO 3166-1 alpha-2
+}
+
+// NationalIdentification is an identity document or registration number.
+type NationalIdentification struct {
+	NationalIdentifier     string `json:"nationalIdentifier"`
+	NationalIdentifierType string `json:"nationalIdentifierType"` // One of the Identifier constants
+	CountryOfIssue         string `json:"countryOfIssue,omitempty"`
+	RegistrationAuthority  string `json:"registrationAuthority,omitempty"`
+}
+
+// DateAndPlaceOfBirth of an individual.
+type DateAndPlaceOfBirth struct {
+	DateOfBirth  string `json:"dateOfBirth"` // YYYY-MM-DD
+	PlaceOfBirth string `json:"placeOfBirth"`
+}
+
+// Validate checks the payload against the structure and the constraints of
+// IVMS101 that can be checked without outside data.  The error names the
+// offending field.
+func (p *Payload) Validate() error {
+	if len(p.Originator.OriginatorPersons) == 0 {
+		return fmt.Errorf("originator.originatorPersons: at least one person is required")
+	}
+	for i := range p.Originator.OriginatorPersons {
+		person := &p.Originator.OriginatorPersons[i]
+		if err := person.validate(); err != nil {
+			return fmt.Errorf("originator.originatorPersons[%d].%w", i, err)
+		}
+		// C1: an originating individual must be identifiable beyond the
+		// name.
+		if np := person.NaturalPerson; np != nil && len(np.GeographicAddress) == 0 && np.NationalIdentification == nil &&
+			np.CustomerIdentification == "" && np.DateAndPlaceOfBirth == nil {
+			return fmt.Errorf("originator.originatorPersons[%d].naturalPerson: an address, national identification, customer identification or date and place of birth is required", i)
+		}
+	}
+	if len(p.Beneficiary.BeneficiaryPersons) == 0 {
+		return fmt.Errorf("beneficiary.beneficiaryPersons: at least one person is required")
+	}
+	for i := range p.Beneficiary.BeneficiaryPersons {
+		if err := p.Beneficiary.BeneficiaryPersons[i].validate(); err != nil {
+			return fmt.Errorf("beneficiary.beneficiaryPersons[%d].%w", i, err)
+		}
+	}
+	if err := validateAccounts("originator", p.Originator.AccountNumber); err != nil {
+		return err
+	}
+	if err := validateAccounts("beneficiary", p.Beneficiary.AccountNumber); err != nil {
+		return err
+	}
+	if v := p.OriginatingVASP; v != nil {
+		if err := v.OriginatingVASP.validate(); err != nil {
+			return fmt.Errorf("originatingVASP.originatingVASP.%w", err)
+		}
+	}
+	if v := p.BeneficiaryVASP; v != nil {
+		if err := v.BeneficiaryVASP.validate(); err != nil {
+			return fmt.Errorf("beneficiaryVASP.beneficiaryVASP.%w", err)
+		}
+	}
+	return nil
+}
+
+func (p *Person) validate() error {
+	switch {
+	case (p.NaturalPerson == nil) == (p.LegalPerson == nil):
+		return fmt.Errorf("person: exactly one of naturalPerson and legalPerson is required")
+	case p.NaturalPerson != nil:
+		if err := p.NaturalPerson.validate(); err != nil {
+			return fmt.Errorf("naturalPerson.%w", err)
+		}
+	default:
+		if err := p.LegalPerson.validate(); err != nil {
+			return fmt.Errorf("legalPerson.%w", err)
+		}
+	}
+	return nil
+}
+
+func (n *NaturalPerson) validate() error {
+	if len(n.Name.NameIdentifier) == 0 {
+		return fmt.Errorf("name: at least one name identifier is required")
+	}
+	legal := false
+	for i, id := range n.Name.NameIdentifier {
+		if id.PrimaryIdentifier == "" {
+			return fmt.Errorf("name.nameIdentifier[%d].primaryIdentifier: must not be empty", i)
+		}
+		if !naturalNameTypes[id.NameIdentifierType] {
+			return fmt.Errorf("name.nameIdentifier[%d].nameIdentifierType: unknown type %q", i, id.NameIdentifierType)
+		}
+		legal = legal || id.NameIdentifierType == NaturalNameLegal
+	}
+	// C6: every individual has a legal name.
+	if !legal {
+		return fmt.Errorf("name: a name of type %s is required", NaturalNameLegal)
+	}
+	if err := validateAddresses(n.GeographicAddress); err != nil {
+		return err
+	}
+	if n.NationalIdentification != nil {
+		if err := n.NationalIdentification.validate(identifierTypes); err != nil {
+			return err
+		}
+	}
+	if b := n.DateAndPlaceOfBirth; b != nil {
+		if _, err := time.Parse("2006-01-02", b.DateOfBirth); err != nil {
+			return fmt.Errorf("dateAndPlaceOfBirth.dateOfBirth: must be YYYY-MM-DD")
+		}
+		if b.PlaceOfBirth == "" {
+			return fmt.Errorf("dateAndPlaceOfBirth.placeOfBirth: must not be empty")
+		}
+	}
+	if n.CountryOfResidence != "" && !countryCode.MatchString(n.CountryOfResidence) {
+		return fmt.Errorf("countryOfResidence: %q is not an ISO 3166-1 alpha-2 code", n.CountryOfResidence)
+	}
+	return nil
+}
+
+func (l *LegalPerson) validate() error {
+	if len(l.Name.NameIdentifier) == 0 {
+		return fmt.Errorf("name: at least one name identifier is required")
+	}
+	legal := false
+	for i, id := range l.Name.NameIdentifier {
+		if id.LegalPersonName == "" {
+			return fmt.Errorf("name.nameIdentifier[%d].legalPersonName: must not be empty", i)
+		}
+		if !legalNameTypes[id.LegalPersonNameIdentifierType] {
+			return fmt.Errorf("name.nameIdentifier[%d].legalPersonNameIdentifierType: unknown type %q", i, id.LegalPersonNameIdentifierType)
+		}
+		legal = legal || id.LegalPersonNameIdentifierType == LegalNameLegal
+	}
+	// C5: every organisation has a legal name.
+	if !legal {
+		return fmt.Errorf("name: a name of type %s is required", LegalNameLegal)
+	}
+	if err := validateAddresses(l.GeographicAddress); err != nil {
+		return err
+	}
+	if l.NationalIdentification != nil {
+		if err := l.NationalIdentification.validate(legalIdentifierTypes); err != nil {
+			return err
+		}
+	}
+	if l.CountryOfRegistration != "" && !countryCode.MatchString(l.CountryOfRegistration) {
+		return fmt.Errorf("countryOfRegistration: %q is not an ISO 3166-1 alpha-2 code", l.CountryOfRegistration)
+	}
+	return nil
+}
+
+func validateAccounts(party string, accounts []string) error {
+	for i, a := range accounts {
+		if a == "" {
+			return fmt.Errorf("%s.accountNumber[%d]: must not be empty", party, i)
+		}
+	}
+	return nil
+}
+
+func validateAddresses(addresses []Address) error {
+	for i, a := range addresses {
+		if !addressTypes[a.AddressType] {
+			return fmt.Errorf("geographicAddress[%d].addressType: unknown type %q", i, a.AddressType)
+		}
+		if !countryCode.MatchString(a.Country) {
+			return fmt.Errorf("geographicAddress[%d].country: %q is not an ISO 3166-1 alpha-2 code", i, a.Country)
+		}
+		// C8: an address is either free-form lines or a street and town.
+		if len(a.AddressLine) == 0 && (a.StreetName == "" || a.TownName == "") {
+			return fmt.Errorf("geographicAddress[%d]: addressLine or streetName and townName are required", i)
+		}
+	}
+	return nil
+}
+
+func (n *NationalIdentification) validate(allowed map[string]bool) error {
+	if n.NationalIdentifier == "" {
+		return fmt.Errorf("nationalIdentification.nationalIdentifier: must not be empty")
+	}
+	if !allowed[n.NationalIdentifierType] {
+		return fmt.Errorf("nationalIdentification.nationalIdentifierType: type %q not allowed", n.NationalIdentifierType)
+	}
+	if n.NationalIdentifierType == IdentifierLEI && !lei.MatchString(n.NationalIdentifier) {
+		return fmt.Errorf("nationalIdentification.nationalIdentifier: %q is not a valid LEI", n.NationalIdentifier)
+	}
+	if n.CountryOfIssue != "" && !countryCode.MatchString(n.CountryOfIssue) {
+		return fmt.Errorf("nationalIdentification.countryOfIssue: %q is not an ISO 3166-1 alpha-2 code", n.CountryOfIssue)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package ivms101
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const example = `{
+  "originator": {
+    "originatorPersons": [{"naturalPerson": {
+      "name": {"nameIdentifier": [{"primaryIdentifier": "Doe", "secondaryIdentifier": "Jane", "nameIdentifierType": "LEGL"}]},
+      "geographicAddress": [{"addressType": "HOME", "streetName": "Main St", "buildingNumber": "1", "townName": "Berlin", "country": "DE"}],
+      "customerIdentification": "C-1001"
+    }}],
+    "accountNumber": ["7xKXtg2CW87d97TXJSDpbD5jBkheTqA83TZRuJosgAsU"]
+  },
+  "beneficiary": {
+    "beneficiaryPersons": [{"naturalPerson": {
+      "name": {"nameIdentifier": [{"primaryIdentifier": "Roe", "nameIdentifierType": "LEGL"}]}
+    }}],
+    "accountNumber": ["9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"]
+  },
+  "originatingVASP": {"originatingVASP": {"legalPerson": {
+    "name": {"nameIdentifier": [{"legalPersonName": "Example VASP GmbH", "legalPersonNameIdentifierType": "LEGL"}]},
+    "nationalIdentification": {"nationalIdentifier": "529900T8BM49AURSDO55", "nationalIdentifierType": "LEIX"},
+    "countryOfRegistration": "DE"
+  }}}
+}`
+
+func parse(t *testing.T) *Payload {
+	t.Helper()
+	var p Payload
+	require.NoError(t, json.Unmarshal([]byte(example), &p))
+	return &p
+}
+
+func TestValidate(t *testing.T) {
+	p := parse(t)
+	require.NoError(t, p.Validate())
+	assert.Equal(t, "Doe", p.Originator.OriginatorPersons[0].NaturalPerson.Name.NameIdentifier[0].PrimaryIdentifier)
+
+	// The payload survives a round trip unchanged.
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	var again Payload
+	require.NoError(t, json.Unmarshal(data, &again))
+	assert.Equal(t, p, &again)
+}
+
+func TestValidateRejects(t *testing.T) {
+	cases := map[string]struct {
+		mutate func(p *Payload)
+		field  string
+	}{
+		"no originator":  {func(p *Payload) { p.Originator.OriginatorPersons = nil }, "originator.originatorPersons"},
+		"no beneficiary": {func(p *Payload) { p.Beneficiary.BeneficiaryPersons = nil }, "beneficiary.beneficiaryPersons"},
+		"both person kinds": {func(p *Payload) {
+			p.Beneficiary.BeneficiaryPersons[0].LegalPerson = p.OriginatingVASP.OriginatingVASP.LegalPerson
+		}, "beneficiary.beneficiaryPersons[0]"},
+		"originator identified by name only": {func(p *Payload) {
+			np := p.Originator.OriginatorPersons[0].NaturalPerson
+			np.GeographicAddress, np.CustomerIdentification = nil, ""
+		}, "originator.originatorPersons[0].naturalPerson"},
+		"no legal name": {func(p *Payload) {
+			p.Beneficiary.BeneficiaryPersons[0].NaturalPerson.Name.NameIdentifier[0].NameIdentifierType = NaturalNameAlias
+		}, "beneficiary.beneficiaryPersons[0].naturalPerson.name"},
+		"unknown name type": {func(p *Payload) {
+			p.Beneficiary.BeneficiaryPersons[0].NaturalPerson.Name.NameIdentifier[0].NameIdentifierType = "NICK"
+		}, "nameIdentifierType"},
+		"bad country": {func(p *Payload) {
+			p.Originator.OriginatorPersons[0].NaturalPerson.GeographicAddress[0].Country = "Germany"
+		}, "geographicAddress[0].country"},
+		"incomplete address": {func(p *Payload) {
+			p.Originator.OriginatorPersons[0].NaturalPerson.GeographicAddress[0].TownName = ""
+		}, "geographicAddress[0]"},
+		"bad LEI": {func(p *Payload) {
+			p.OriginatingVASP.OriginatingVASP.LegalPerson.NationalIdentification.NationalIdentifier = "12345"
+		}, "originatingVASP.originatingVASP.legalPerson.nationalIdentification"},
+		"passport for a company": {func(p *Payload) {
+			p.OriginatingVASP.OriginatingVASP.LegalPerson.NationalIdentification.NationalIdentifierType = IdentifierPassport
+		}, "nationalIdentifierType"},
+		"bad date of birth": {func(p *Payload) {
+			p.Originator.OriginatorPersons[0].NaturalPerson.DateAndPlaceOfBirth = &DateAndPlaceOfBirth{DateOfBirth: "01/02/1990", PlaceOfBirth: "Paris"}
+		}, "dateOfBirth"},
+		"empty account": {func(p *Payload) { p.Beneficiary.AccountNumber = []string{""} }, "beneficiary.accountNumber[0]"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := parse(t)
+			tc.mutate(p)
+			assert.ErrorContains(t, p.Validate(), tc.field)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Package metering counts signing usage per tenant and exports it in the
+// OpenMetrics text format, so that operators running MPC as a service can
+// meter and bill their customers.
+//
+// A `Meter` is a coordinator.Observer.  It attributes every session to the
+// tenant named in its request (coordinator.Request.Tenant, or Config.Tenant
+// for other schemes, e.g. looking up the tenant of the sending key in the
+// provisioning API) and counts, per tenant:
+//
+//	cbmpc_tenant_sessions_total         sessions requested
+//	cbmpc_tenant_signatures_total       transactions signed
+//	cbmpc_tenant_failures_total         sessions failed, before or after signing
+//	cbmpc_tenant_finalized_total        transactions finalized on chain
+//	cbmpc_tenant_signing_seconds        histogram of MPC signing latency, from
+//	                                    the first protocol round to the signature
+//
+// `Meter.Handler` serves the metrics for a Prometheus-compatible scraper and
+// `Meter.Usage` returns the same numbers for billing jobs.  Like all
+// counters they start at zero when the process starts; usage over a period is
+// the increase between two scrapes.
+package metering
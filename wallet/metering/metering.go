@@ -0,0 +1,210 @@
+package metering
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// ContentType is the media type of the OpenMetrics text format.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// DefaultBuckets are the upper bounds, in seconds, of the signing latency
+// histogram.
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Config contains the configuration of a Meter.
+type Config struct {
+	// Name identifies the observer to the coordinator.  Defaults to
+	// "metering".
+	Name string
+	// Tenant returns the tenant a session is billed to.  Defaults to the
+	// session's Request.Tenant.
+	Tenant func(s *coordinator.Session) string
+	// Buckets are the upper bounds of the signing latency histogram in
+	// seconds, in increasing order.  Defaults to DefaultBuckets.
+	Buckets []float64
+}
+
+// Usage is the usage of one tenant.
+type Usage struct {
+	Tenant     string `json:"tenant"`
+	Sessions   uint64 `json:"sessions"`
+	Signatures uint64 `json:"signatures"`
+	Failures   uint64 `json:"failures"`
+	Finalized  uint64 `json:"finalized"`
+	// Timed counts the signatures whose latency was measured.  Signatures
+	// whose first round was not seen, e.g. because the process restarted
+	// while signing, are not timed.
+	Timed uint64 `json:"timed"`
+	// SigningSeconds is the total MPC signing time of the timed signatures.
+	SigningSeconds float64 `json:"signing_seconds"`
+	// Buckets counts timed signatures by latency: Buckets[i] counts those
+	// that took at most Config.Buckets[i] seconds.
+	Buckets []uint64 `json:"buckets"`
+}
+
+// Meter counts usage per tenant.  It is safe for concurrent use.
+type Meter struct {
+	name    string
+	tenant  func(s *coordinator.Session) string
+	buckets []float64
+
+	mu      sync.Mutex
+	tenants map[string]*Usage
+	started map[string]time.Time // Session ID → start of its first round
+}
+
+// Ensure Meter implements the coordinator.Observer interface
+var _ coordinator.Observer = (*Meter)(nil)
+
+// New creates a Meter.
+func New(config Config) (*Meter, error) {
+	if config.Name == "" {
+		config.Name = "metering"
+	}
+	if config.Tenant == nil {
+		config.Tenant = func(s *coordinator.Session) string { return s.Request.Tenant }
+	}
+	if len(config.Buckets) == 0 {
+		config.Buckets = DefaultBuckets
+	}
+	for i := 1; i < len(config.Buckets); i++ {
+		if config.Buckets[i] <= config.Buckets[i-1] {
+			return nil, fmt.Errorf("buckets must be in increasing order")
+		}
+	}
+	return &Meter{
+		name:    config.Name,
+		tenant:  config.Tenant,
+		buckets: append([]float64(nil), config.Buckets...),
+		tenants: map[string]*Usage{},
+		started: map[string]time.Time{},
+	}, nil
+}
+
+// Name implements coordinator.Observer.
+func (m *Meter) Name() string { return m.name }
+
+// Review implements coordinator.Observer.  A Meter never vetoes.
+func (m *Meter) Review(context.Context, *coordinator.Session) (*coordinator.Review, error) {
+	return &coordinator.Review{Reviewer: m.name}, nil
+}
+
+// Observe implements coordinator.Observer.
+func (m *Meter) Observe(_ context.Context, s *coordinator.Session, e *coordinator.Event) {
+	switch e.Type {
+	case coordinator.EventCreated, coordinator.EventRoundStarted, coordinator.EventSigned,
+		coordinator.EventFailed, coordinator.EventFinalized:
+	default:
+		return
+	}
+	tenant := m.tenant(s)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.tenants[tenant]
+	if u == nil {
+		u = &Usage{Tenant: tenant, Buckets: make([]uint64, len(m.buckets))}
+		m.tenants[tenant] = u
+	}
+	switch e.Type {
+	case coordinator.EventCreated:
+		u.Sessions++
+	case coordinator.EventRoundStarted:
+		if e.Round == 1 {
+			m.started[s.ID] = e.Time
+		}
+	case coordinator.EventSigned:
+		u.Signatures++
+		if start, ok := m.started[s.ID]; ok {
+			delete(m.started, s.ID)
+			d := e.Time.Sub(start).Seconds()
+			u.Timed++
+			u.SigningSeconds += d
+			for i, le := range m.buckets {
+				if d <= le {
+					u.Buckets[i]++
+				}
+			}
+		}
+	case coordinator.EventFailed:
+		u.Failures++
+		delete(m.started, s.ID)
+	case coordinator.EventFinalized:
+		u.Finalized++
+	}
+}
+
+// Usage returns the usage of every tenant seen, ordered by tenant.
+func (m *Meter) Usage() []Usage {
+	m.mu.Lock()
+	out := make([]Usage, 0, len(m.tenants))
+	for _, u := range m.tenants {
+		c := *u
+		c.Buckets = append([]uint64(nil), u.Buckets...)
+		out = append(out, c)
+	}
+	m.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Tenant < out[j].Tenant })
+	return out
+}
+
+// WriteOpenMetrics writes the usage of every tenant to w in the OpenMetrics
+// text format.
+func (m *Meter) WriteOpenMetrics(w io.Writer) error {
+	usage := m.Usage()
+	bw := bufio.NewWriter(w)
+	counters := []struct {
+		name, help string
+		value      func(u *Usage) uint64
+	}{
+		{"cbmpc_tenant_sessions", "Signing sessions requested.", func(u *Usage) uint64 { return u.Sessions }},
+		{"cbmpc_tenant_signatures", "Transactions signed.", func(u *Usage) uint64 { return u.Signatures }},
+		{"cbmpc_tenant_failures", "Signing sessions failed.", func(u *Usage) uint64 { return u.Failures }},
+		{"cbmpc_tenant_finalized", "Transactions finalized on chain.", func(u *Usage) uint64 { return u.Finalized }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(bw, "# TYPE %s counter\n# HELP %s %s\n", c.name, c.name, c.help)
+		for i := range usage {
+			fmt.Fprintf(bw, "%s_total{tenant=\"%s\"} %d\n", c.name, escape(usage[i].Tenant), c.value(&usage[i]))
+		}
+	}
+
+	const h = "cbmpc_tenant_signing_seconds"
+	fmt.Fprintf(bw, "# TYPE %s histogram\n# UNIT %s seconds\n# HELP %s MPC signing latency from the first round to the signature.\n", h, h, h)
+	for i := range usage {
+		u := &usage[i]
+		tenant := escape(u.Tenant)
+		for j, le := range m.buckets {
+			fmt.Fprintf(bw, "%s_bucket{tenant=\"%s\",le=\"%s\"} %d\n", h, tenant, strconv.FormatFloat(le, 'g', -1, 64), u.Buckets[j])
+		}
+		fmt.Fprintf(bw, "%s_bucket{tenant=\"%s\",le=\"+Inf\"} %d\n", h, tenant, u.Timed)
+		fmt.Fprintf(bw, "%s_count{tenant=\"%s\"} %d\n", h, tenant, u.Timed)
+		fmt.Fprintf(bw, "%s_sum{tenant=\"%s\"} %s\n", h, tenant, strconv.FormatFloat(u.SigningSeconds, 'g', -1, 64))
+	}
+	fmt.Fprintf(bw, "# EOF\n")
+	return bw.Flush()
+}
+
+// Handler serves the usage in the OpenMetrics text format.
+func (m *Meter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		m.WriteOpenMetrics(w)
+	})
+}
+
+// escaper escapes label values as OpenMetrics requires.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string { return escaper.Replace(s) }
@@ -0,0 +1,73 @@
+package metering
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+func TestMeterCountsPerTenant(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(Config{Buckets: []float64{1, 5}})
+	require.NoError(t, err)
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	run := func(id, tenant string, latency time.Duration, events ...coordinator.EventType) {
+		s := &coordinator.Session{ID: id, Request: coordinator.Request{Tenant: tenant}}
+		for _, typ := range events {
+			e := &coordinator.Event{Session: id, Type: typ, Time: start}
+			switch typ {
+			case coordinator.EventRoundStarted:
+				e.Round = 1
+			case coordinator.EventSigned:
+				e.Time = start.Add(latency)
+			}
+			m.Observe(ctx, s, e)
+		}
+	}
+	full := []coordinator.EventType{coordinator.EventCreated, coordinator.EventPolicyEvaluated, coordinator.EventApproved,
+		coordinator.EventRoundStarted, coordinator.EventSigned, coordinator.EventBroadcast, coordinator.EventFinalized}
+	run("s1", "acme", 500*time.Millisecond, full...)
+	run("s2", "acme", 3*time.Second, full...)
+	run("s3", "acme", 0, coordinator.EventCreated, coordinator.EventFailed)
+	run("s4", `we"ird`, 10*time.Second, full...)
+
+	usage := m.Usage()
+	require.Len(t, usage, 2)
+	acme := usage[0]
+	assert.Equal(t, "acme", acme.Tenant)
+	assert.Equal(t, uint64(3), acme.Sessions)
+	assert.Equal(t, uint64(2), acme.Signatures)
+	assert.Equal(t, uint64(1), acme.Failures)
+	assert.Equal(t, uint64(2), acme.Finalized)
+	assert.Equal(t, uint64(2), acme.Timed)
+	assert.InDelta(t, 3.5, acme.SigningSeconds, 1e-9)
+	assert.Equal(t, []uint64{1, 2}, acme.Buckets)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE cbmpc_tenant_signatures counter",
+		`cbmpc_tenant_signatures_total{tenant="acme"} 2`,
+		`cbmpc_tenant_failures_total{tenant="acme"} 1`,
+		`cbmpc_tenant_sessions_total{tenant="we\"ird"} 1`,
+		`cbmpc_tenant_signing_seconds_bucket{tenant="acme",le="1"} 1`,
+		`cbmpc_tenant_signing_seconds_bucket{tenant="acme",le="+Inf"} 2`,
+		`cbmpc_tenant_signing_seconds_sum{tenant="acme"} 3.5`,
+		`cbmpc_tenant_signing_seconds_bucket{tenant="we\"ird",le="5"} 0`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+
+	_, err = New(Config{Buckets: []float64{5, 1}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+// Package onetime keeps crash-consistent books on one-time signing material:
+// presignatures, tweak counters and derivation indices that must never be
+// used twice, even if a party crashes half-way through a signature.
+//
+// A `Ledger` follows write-ahead semantics.  Material is recorded as used
+// *before* the protocol touches it and the record is durable before the call
+// returns:
+//
+//	idx, _ := ledger.Reserve(ctx, "derive/treasury") // next unused index
+//	err := onetime.Use(ctx, ledger, "presig/key-1", presigID, func() error {
+//	    return signWithPresignature(presigID, msg)
+//	})
+//
+// If the process dies after the record but before or during signing, the
+// material is lost rather than reused: after a restart Reserve hands out the
+// next index and Consume reports `ErrReused` for the presignature.  Losing an
+// occasional presignature is cheap; reusing one can leak the key.
+//
+// Three implementations are provided:
+//
+//   - MemoryLedger – for tests; nothing survives a restart.
+//   - FileLedger – an fsynced JSON-lines journal that is replayed on open and
+//     tolerates a torn final record.
+//   - SQLLedger – any database/sql driver.  Counters advance by
+//     compare-and-swap and consumed items are guarded by a primary key, so
+//     several signer processes can share one database.
+//
+// # Failover
+//
+// Coordinators in a high-availability pair keep their books on one-time
+// material warm on a standby.  A Replicated ledger writes every record
+// locally and then to each Replica, and acknowledges it only once all of them
+// have it; MemoryLedger and FileLedger are replicas, and ReplicaFunc carries
+// records to another process.  Scope presignatures by key and party, e.g.
+// "presig/<key>/<party>", so that the books say which party consumed what.
+//
+//	standby := onetime.NewStandby(standbyJournal)
+//	primary, _ := onetime.NewReplicated(onetime.ReplicatedConfig{
+//	    Origin: "coordinator-a", Local: journal, Replicas: []onetime.Replica{standby},
+//	})
+//
+// On failover, Promote the standby and use its books as the Local ledger of
+// the new primary.  Every presignature the old primary acknowledged is
+// reported as reused, Reserve continues past every index it handed out, and
+// records the old primary sends after the promotion fail with ErrFenced.  A
+// crash between the local write and the replicas loses material, like a
+// crash during signing, but never reuses it.
+package onetime
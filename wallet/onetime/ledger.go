@@ -0,0 +1,276 @@
+package onetime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrReused is returned by Consume when the item was consumed before.
+var ErrReused = errors.New("onetime: material already used")
+
+// Ledger records the use of one-time material.  Implementations must be safe
+// for concurrent use, and a successful return must mean the record survives a
+// crash.
+type Ledger interface {
+	// Reserve returns the next unused index of a counter scope, starting at
+	// zero.  An index is never returned twice.
+	Reserve(ctx context.Context, scope string) (uint64, error)
+	// Consume marks item in scope as used.  It returns ErrReused if the item
+	// was consumed before.
+	Consume(ctx context.Context, scope, item string) error
+}
+
+// Use consumes item and then runs fn, which may use the material.  fn is not
+// run if the item was consumed before.
+func Use(ctx context.Context, l Ledger, scope, item string, fn func() error) error {
+	if err := l.Consume(ctx, scope, item); err != nil {
+		return err
+	}
+	return fn()
+}
+
+func checkScope(scope string) error {
+	if scope == "" {
+		return fmt.Errorf("scope must be provided")
+	}
+	return nil
+}
+
+// books is the in-memory state shared by MemoryLedger and FileLedger.
+type books struct {
+	next     map[string]uint64
+	consumed map[string]map[string]string // Origin of every consumed item
+}
+
+func newBooks() books {
+	return books{next: make(map[string]uint64), consumed: make(map[string]map[string]string)}
+}
+
+// apply records r.  It returns ErrReused for a consume record that was
+// applied before.
+func (b *books) apply(r *Record) error {
+	if r.Item == "" {
+		if r.Index >= b.next[r.Scope] {
+			b.next[r.Scope] = r.Index + 1
+		}
+		return nil
+	}
+	if _, ok := b.consumed[r.Scope][r.Item]; ok {
+		return ErrReused
+	}
+	if b.consumed[r.Scope] == nil {
+		b.consumed[r.Scope] = make(map[string]string)
+	}
+	b.consumed[r.Scope][r.Item] = r.Origin
+	return nil
+}
+
+// replayed reports whether r is a replicated record that was applied before:
+// an index at or below the counter, or an item consumed by the same origin.
+func (b *books) replayed(r *Record) bool {
+	if r.Item == "" {
+		return r.Index < b.next[r.Scope]
+	}
+	origin, ok := b.consumed[r.Scope][r.Item]
+	return ok && origin == r.Origin
+}
+
+// Record is one entry in the books of a ledger: an index reserved in Scope
+// when Item is empty, the consumption of Item otherwise.  Records are what a
+// Replicated ledger sends to its replicas.
+type Record struct {
+	Scope string `json:"scope"`
+	Index uint64 `json:"index,omitempty"`
+	Item  string `json:"item,omitempty"`
+	// Origin names the ledger that made the record, for records received
+	// from a primary; it is empty for records made locally.
+	Origin string `json:"origin,omitempty"`
+}
+
+// check validates a record received from a primary.
+func (r *Record) check() error {
+	if err := checkScope(r.Scope); err != nil {
+		return err
+	}
+	if r.Origin == "" {
+		return fmt.Errorf("origin must be provided")
+	}
+	return nil
+}
+
+// MemoryLedger is a Ledger that keeps its books in memory.  It is intended
+// for tests.
+type MemoryLedger struct {
+	mu    sync.Mutex
+	books books
+}
+
+// Ensure MemoryLedger implements the Ledger and Replica interfaces
+var (
+	_ Ledger  = (*MemoryLedger)(nil)
+	_ Replica = (*MemoryLedger)(nil)
+)
+
+// NewMemoryLedger creates an empty MemoryLedger.
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{books: newBooks()}
+}
+
+// Reserve implements Ledger.
+func (m *MemoryLedger) Reserve(_ context.Context, scope string) (uint64, error) {
+	if err := checkScope(scope); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := m.books.next[scope]
+	m.books.apply(&Record{Scope: scope, Index: idx})
+	return idx, nil
+}
+
+// Consume implements Ledger.
+func (m *MemoryLedger) Consume(_ context.Context, scope, item string) error {
+	if err := checkScope(scope); err != nil {
+		return err
+	}
+	if item == "" {
+		return fmt.Errorf("item must be provided")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.books.apply(&Record{Scope: scope, Item: item})
+}
+
+// Apply implements Replica.
+func (m *MemoryLedger) Apply(_ context.Context, r Record) error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.books.replayed(&r) {
+		return nil
+	}
+	return m.books.apply(&r)
+}
+
+// FileLedger is a Ledger backed by an append-only journal file.  Every record
+// is fsynced before it is acknowledged and the journal is replayed when the
+// ledger is opened; a torn final line left by a crash is discarded.
+type FileLedger struct {
+	mu    sync.Mutex
+	file  *os.File
+	size  int64
+	books books
+}
+
+// Ensure FileLedger implements the Ledger and Replica interfaces
+var (
+	_ Ledger  = (*FileLedger)(nil)
+	_ Replica = (*FileLedger)(nil)
+)
+
+// OpenFileLedger opens or creates the journal at path.
+func OpenFileLedger(path string) (*FileLedger, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+	l := &FileLedger{books: newBooks()}
+	for {
+		i := bytes.IndexByte(data[l.size:], '\n')
+		if i < 0 {
+			break
+		}
+		var r Record
+		if err := json.Unmarshal(data[l.size:l.size+int64(i)], &r); err != nil {
+			return nil, fmt.Errorf("decoding journal record at offset %d: %w", l.size, err)
+		}
+		if err := l.books.apply(&r); err != nil {
+			return nil, fmt.Errorf("journal record at offset %d: %w", l.size, err)
+		}
+		l.size += int64(i) + 1
+	}
+	if l.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o600); err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	return l, nil
+}
+
+// Close closes the journal.
+func (l *FileLedger) Close() error {
+	return l.file.Close()
+}
+
+// Reserve implements Ledger.
+func (l *FileLedger) Reserve(_ context.Context, scope string) (uint64, error) {
+	if err := checkScope(scope); err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := &Record{Scope: scope, Index: l.books.next[scope]}
+	if err := l.append(r); err != nil {
+		return 0, err
+	}
+	return r.Index, nil
+}
+
+// Consume implements Ledger.
+func (l *FileLedger) Consume(_ context.Context, scope, item string) error {
+	if err := checkScope(scope); err != nil {
+		return err
+	}
+	if item == "" {
+		return fmt.Errorf("item must be provided")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.books.consumed[scope][item]; ok {
+		return ErrReused
+	}
+	return l.append(&Record{Scope: scope, Item: item})
+}
+
+// Apply implements Replica.  The record is durable before Apply returns.
+func (l *FileLedger) Apply(_ context.Context, r Record) error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.books.replayed(&r) {
+		return nil
+	}
+	if _, ok := l.books.consumed[r.Scope][r.Item]; ok {
+		return ErrReused
+	}
+	return l.append(&r)
+}
+
+// append writes r durably and then applies it to the books.
+func (l *FileLedger) append(r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encoding record: %w", err)
+	}
+	line = append(line, '\n')
+	// Writing at size rather than appending drops a torn line left behind
+	// by a crash during an earlier, unacknowledged append.
+	if _, err := l.file.WriteAt(line, l.size); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	if err := l.file.Truncate(l.size + int64(len(line))); err != nil {
+		return fmt.Errorf("truncating journal: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("syncing journal: %w", err)
+	}
+	l.size += int64(len(line))
+	return l.books.apply(r)
+}
@@ -0,0 +1,109 @@
+package onetime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLedger exercises the Ledger contract.
+func testLedger(t *testing.T, l Ledger) {
+	ctx := context.Background()
+
+	for want := uint64(0); want < 3; want++ {
+		idx, err := l.Reserve(ctx, "derive/a")
+		require.NoError(t, err)
+		assert.Equal(t, want, idx)
+	}
+	idx, err := l.Reserve(ctx, "derive/b")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), idx)
+
+	require.NoError(t, l.Consume(ctx, "presig", "p1"))
+	assert.ErrorIs(t, l.Consume(ctx, "presig", "p1"), ErrReused)
+	require.NoError(t, l.Consume(ctx, "presig/other", "p1"))
+	assert.Error(t, l.Consume(ctx, "", "p1"))
+	assert.Error(t, l.Consume(ctx, "presig", ""))
+
+	ran := false
+	err = Use(ctx, l, "presig", "p1", func() error { ran = true; return nil })
+	assert.ErrorIs(t, err, ErrReused)
+	assert.False(t, ran)
+}
+
+func TestMemoryLedger(t *testing.T) {
+	testLedger(t, NewMemoryLedger())
+}
+
+func TestMemoryLedgerConcurrentReserve(t *testing.T) {
+	l := NewMemoryLedger()
+	seen := make(chan uint64, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx, err := l.Reserve(context.Background(), "s")
+			assert.NoError(t, err)
+			seen <- idx
+		}()
+	}
+	wg.Wait()
+	close(seen)
+	unique := make(map[uint64]bool)
+	for idx := range seen {
+		unique[idx] = true
+	}
+	assert.Len(t, unique, 100)
+}
+
+func TestFileLedger(t *testing.T) {
+	l, err := OpenFileLedger(filepath.Join(t.TempDir(), "journal"))
+	require.NoError(t, err)
+	defer l.Close()
+	testLedger(t, l)
+}
+
+func TestFileLedgerSurvivesCrash(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "journal")
+	l, err := OpenFileLedger(path)
+	require.NoError(t, err)
+
+	_, err = l.Reserve(ctx, "derive")
+	require.NoError(t, err)
+	// The presignature is recorded before signing starts; the process then
+	// dies mid-sign and leaves a torn record behind.
+	crashed := errors.New("crash")
+	err = Use(ctx, l, "presig", "p1", func() error { return crashed })
+	assert.ErrorIs(t, err, crashed)
+	require.NoError(t, l.Close())
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"scope":"der`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l, err = OpenFileLedger(path)
+	require.NoError(t, err)
+	defer l.Close()
+	assert.ErrorIs(t, l.Consume(ctx, "presig", "p1"), ErrReused)
+	idx, err := l.Reserve(ctx, "derive")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), idx)
+
+	// The torn record was overwritten, so the journal replays cleanly.
+	require.NoError(t, l.Close())
+	l, err = OpenFileLedger(path)
+	require.NoError(t, err)
+	defer l.Close()
+	idx, err = l.Reserve(ctx, "derive")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), idx)
+}
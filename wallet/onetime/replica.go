@@ -0,0 +1,151 @@
+package onetime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrFenced is returned by a promoted Standby to records of its former
+// primary.
+var ErrFenced = errors.New("onetime: standby was promoted")
+
+// Replica receives the records of a primary ledger.  Apply must be durable
+// before it returns and idempotent: a record applied again by the same origin
+// succeeds, an index at or below the replica's counter is a no-op, and an
+// item consumed locally or by another origin fails with ErrReused.
+type Replica interface {
+	Apply(ctx context.Context, r Record) error
+}
+
+// ReplicaFunc adapts a function to the Replica interface, e.g. to send
+// records to a standby in another process.
+type ReplicaFunc func(ctx context.Context, r Record) error
+
+// Apply implements Replica.
+func (f ReplicaFunc) Apply(ctx context.Context, r Record) error {
+	return f(ctx, r)
+}
+
+// ReplicatedConfig configures a Replicated ledger.
+type ReplicatedConfig struct {
+	// Origin names this primary in the records it replicates.  Every
+	// primary of a set of replicas must use a different name.
+	Origin string
+	// Local keeps the books of this primary.
+	Local Ledger
+	// Replicas receive every record before it is acknowledged.
+	Replicas []Replica
+}
+
+// Replicated is a Ledger that copies every record to warm standbys, so that
+// a standby promoted after a failover never hands out material the primary
+// handed out before.
+//
+// A record is written to Local first and then applied to every replica in
+// turn; the call succeeds only once all of them have acknowledged it.  A
+// crash or a failed replica in between loses the material – it is marked
+// used locally and perhaps on some replicas – but never lets it be reused.
+type Replicated struct {
+	origin   string
+	local    Ledger
+	replicas []Replica
+}
+
+// Ensure Replicated implements the Ledger interface
+var _ Ledger = (*Replicated)(nil)
+
+// NewReplicated creates a Replicated ledger.
+func NewReplicated(config ReplicatedConfig) (*Replicated, error) {
+	if config.Origin == "" {
+		return nil, fmt.Errorf("origin must be provided")
+	}
+	if config.Local == nil {
+		return nil, fmt.Errorf("local ledger must be provided")
+	}
+	for i, r := range config.Replicas {
+		if r == nil {
+			return nil, fmt.Errorf("replica %d is nil", i)
+		}
+	}
+	return &Replicated{
+		origin:   config.Origin,
+		local:    config.Local,
+		replicas: append([]Replica(nil), config.Replicas...),
+	}, nil
+}
+
+// Reserve implements Ledger.
+func (l *Replicated) Reserve(ctx context.Context, scope string) (uint64, error) {
+	idx, err := l.local.Reserve(ctx, scope)
+	if err != nil {
+		return 0, err
+	}
+	if err := l.replicate(ctx, Record{Scope: scope, Index: idx, Origin: l.origin}); err != nil {
+		return 0, err
+	}
+	return idx, nil
+}
+
+// Consume implements Ledger.
+func (l *Replicated) Consume(ctx context.Context, scope, item string) error {
+	if err := l.local.Consume(ctx, scope, item); err != nil {
+		return err
+	}
+	return l.replicate(ctx, Record{Scope: scope, Item: item, Origin: l.origin})
+}
+
+func (l *Replicated) replicate(ctx context.Context, r Record) error {
+	for i, replica := range l.replicas {
+		if err := replica.Apply(ctx, r); err != nil {
+			return fmt.Errorf("replica %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Standby is a Replica that can take over from its primary.  Promote fences
+// the former primary – every later record of it fails with ErrFenced – and
+// returns the standby's books for use as the Local ledger of the new primary.
+type Standby struct {
+	mu       sync.Mutex
+	ledger   replicaLedger
+	promoted bool
+}
+
+type replicaLedger interface {
+	Ledger
+	Replica
+}
+
+// Ensure Standby implements the Replica interface
+var _ Replica = (*Standby)(nil)
+
+// NewStandby creates a Standby keeping its books in ledger, typically a
+// FileLedger or a MemoryLedger.
+func NewStandby(ledger interface {
+	Ledger
+	Replica
+}) *Standby {
+	return &Standby{ledger: ledger}
+}
+
+// Apply implements Replica.
+func (s *Standby) Apply(ctx context.Context, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.promoted {
+		return ErrFenced
+	}
+	return s.ledger.Apply(ctx, r)
+}
+
+// Promote fences the former primary and returns the books of the standby.
+// Records being applied when Promote is called complete first.
+func (s *Standby) Promote() Ledger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.promoted = true
+	return s.ledger
+}
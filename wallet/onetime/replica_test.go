@@ -0,0 +1,213 @@
+package onetime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errCrash = errors.New("crashed")
+
+// crash counts the ledger operations of a primary.  The operation at step at
+// takes effect but is not acknowledged, and every later one fails: the
+// primary process died.
+type crash struct {
+	at, n int
+}
+
+func (c *crash) step(op func() error) error {
+	c.n++
+	switch {
+	case c.at > 0 && c.n > c.at:
+		return errCrash
+	case c.n == c.at:
+		op()
+		return errCrash
+	}
+	return op()
+}
+
+// crashingLedger and crashingReplica route the operations of a primary
+// through a crash.
+type crashingLedger struct {
+	c *crash
+	l Ledger
+}
+
+func (cl *crashingLedger) Reserve(ctx context.Context, scope string) (idx uint64, err error) {
+	err = cl.c.step(func() (err error) {
+		idx, err = cl.l.Reserve(ctx, scope)
+		return err
+	})
+	return idx, err
+}
+
+func (cl *crashingLedger) Consume(ctx context.Context, scope, item string) error {
+	return cl.c.step(func() error { return cl.l.Consume(ctx, scope, item) })
+}
+
+func crashingReplica(c *crash, r Replica) Replica {
+	return ReplicaFunc(func(ctx context.Context, rec Record) error {
+		return c.step(func() error { return r.Apply(ctx, rec) })
+	})
+}
+
+// acked is what a primary acknowledged before it crashed.
+type acked struct {
+	items   []string
+	indices []uint64
+}
+
+const presigScope = "presig/key-1/alice"
+
+// runPrimary reserves and consumes presignatures until the primary crashes.
+func runPrimary(ctx context.Context, l Ledger) (acked, error) {
+	var a acked
+	for i := 0; i < 4; i++ {
+		idx, err := l.Reserve(ctx, presigScope)
+		if err != nil {
+			return a, err
+		}
+		a.indices = append(a.indices, idx)
+		item := fmt.Sprintf("presig-%d", idx)
+		if err := l.Consume(ctx, presigScope, item); err != nil {
+			return a, err
+		}
+		a.items = append(a.items, item)
+	}
+	return a, nil
+}
+
+func TestReplicatedFailoverAtEveryStep(t *testing.T) {
+	ctx := context.Background()
+
+	// Count the steps of a run without a crash.
+	c := &crash{}
+	primary, err := NewReplicated(ReplicatedConfig{
+		Origin:   "a",
+		Local:    &crashingLedger{c, NewMemoryLedger()},
+		Replicas: []Replica{crashingReplica(c, NewStandby(NewMemoryLedger())), crashingReplica(c, NewStandby(NewMemoryLedger()))},
+	})
+	require.NoError(t, err)
+	_, err = runPrimary(ctx, primary)
+	require.NoError(t, err)
+	steps := c.n
+	require.Equal(t, 4*2*3, steps)
+
+	for at := 1; at <= steps; at++ {
+		t.Run(fmt.Sprintf("crash at %d", at), func(t *testing.T) {
+			c := &crash{at: at}
+			b, d := NewStandby(NewMemoryLedger()), NewStandby(NewMemoryLedger())
+			oldLocal := NewMemoryLedger()
+			old, err := NewReplicated(ReplicatedConfig{
+				Origin:   "a",
+				Local:    &crashingLedger{c, oldLocal},
+				Replicas: []Replica{crashingReplica(c, b), crashingReplica(c, d)},
+			})
+			require.NoError(t, err)
+			done, err := runPrimary(ctx, old)
+			require.ErrorIs(t, err, errCrash)
+
+			// Fail over to b, which keeps d as its standby.
+			promoted, err := NewReplicated(ReplicatedConfig{Origin: "b", Local: b.Promote(), Replicas: []Replica{d}})
+			require.NoError(t, err)
+
+			for _, item := range done.items {
+				assert.ErrorIs(t, promoted.Consume(ctx, presigScope, item), ErrReused, item)
+			}
+			idx, err := promoted.Reserve(ctx, presigScope)
+			require.NoError(t, err)
+			for _, old := range done.indices {
+				assert.Greater(t, idx, old)
+			}
+
+			// The old primary comes back with its books intact but is
+			// fenced; the material it marks is lost, not handed out.
+			c.at = 0
+			zombie, err := NewReplicated(ReplicatedConfig{Origin: "a", Local: oldLocal, Replicas: []Replica{b, d}})
+			require.NoError(t, err)
+			assert.ErrorIs(t, zombie.Consume(ctx, presigScope, "presig-fresh"), ErrFenced)
+			_, err = zombie.Reserve(ctx, presigScope)
+			assert.ErrorIs(t, err, ErrFenced)
+		})
+	}
+}
+
+func TestReplicatedReplicaReuse(t *testing.T) {
+	ctx := context.Background()
+	replica := NewMemoryLedger()
+	require.NoError(t, replica.Consume(ctx, presigScope, "p1"))
+
+	l, err := NewReplicated(ReplicatedConfig{Origin: "a", Local: NewMemoryLedger(), Replicas: []Replica{replica}})
+	require.NoError(t, err)
+	assert.ErrorIs(t, l.Consume(ctx, presigScope, "p1"), ErrReused)
+	// The item is marked used locally all the same.
+	assert.ErrorIs(t, l.Consume(ctx, presigScope, "p1"), ErrReused)
+
+	_, err = NewReplicated(ReplicatedConfig{Local: NewMemoryLedger()})
+	assert.Error(t, err)
+	_, err = NewReplicated(ReplicatedConfig{Origin: "a"})
+	assert.Error(t, err)
+}
+
+// testReplica exercises the Replica contract.
+func testReplica(t *testing.T, r interface {
+	Ledger
+	Replica
+}) {
+	ctx := context.Background()
+
+	consume := Record{Scope: presigScope, Item: "p1", Origin: "a"}
+	require.NoError(t, r.Apply(ctx, consume))
+	require.NoError(t, r.Apply(ctx, consume), "replayed by the same origin")
+	assert.ErrorIs(t, r.Apply(ctx, Record{Scope: presigScope, Item: "p1", Origin: "b"}), ErrReused)
+	assert.ErrorIs(t, r.Consume(ctx, presigScope, "p1"), ErrReused)
+	require.NoError(t, r.Consume(ctx, presigScope, "p2"))
+	assert.ErrorIs(t, r.Apply(ctx, Record{Scope: presigScope, Item: "p2", Origin: "a"}), ErrReused)
+
+	require.NoError(t, r.Apply(ctx, Record{Scope: "derive/a", Index: 5, Origin: "a"}))
+	require.NoError(t, r.Apply(ctx, Record{Scope: "derive/a", Index: 2, Origin: "a"}))
+	idx, err := r.Reserve(ctx, "derive/a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), idx)
+
+	assert.Error(t, r.Apply(ctx, Record{Scope: presigScope, Item: "p3"}))
+	assert.Error(t, r.Apply(ctx, Record{Item: "p3", Origin: "a"}))
+}
+
+func TestMemoryLedgerReplica(t *testing.T) {
+	testReplica(t, NewMemoryLedger())
+}
+
+func TestFileLedgerReplica(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	l, err := OpenFileLedger(path)
+	require.NoError(t, err)
+	testReplica(t, l)
+	require.NoError(t, l.Close())
+
+	// Replicated records survive a restart, with their origin.
+	l, err = OpenFileLedger(path)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Apply(context.Background(), Record{Scope: presigScope, Item: "p1", Origin: "a"}))
+	assert.ErrorIs(t, l.Apply(context.Background(), Record{Scope: presigScope, Item: "p2", Origin: "a"}), ErrReused)
+	idx, err := l.Reserve(context.Background(), "derive/a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), idx)
+}
+
+func TestStandbyPromote(t *testing.T) {
+	ctx := context.Background()
+	s := NewStandby(NewMemoryLedger())
+	require.NoError(t, s.Apply(ctx, Record{Scope: presigScope, Item: "p1", Origin: "a"}))
+	l := s.Promote()
+	assert.ErrorIs(t, s.Apply(ctx, Record{Scope: presigScope, Item: "p2", Origin: "a"}), ErrFenced)
+	assert.ErrorIs(t, l.Consume(ctx, presigScope, "p1"), ErrReused)
+	require.NoError(t, l.Consume(ctx, presigScope, "p2"))
+}
@@ -0,0 +1,147 @@
+package onetime
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Schema creates the tables used by SQLLedger.  It is portable across
+// PostgreSQL, MySQL and SQLite.
+const Schema = `
+CREATE TABLE IF NOT EXISTS onetime_counters (
+	scope VARCHAR(255) NOT NULL PRIMARY KEY,
+	next  BIGINT       NOT NULL
+);
+CREATE TABLE IF NOT EXISTS onetime_consumed (
+	scope VARCHAR(255) NOT NULL,
+	item  VARCHAR(255) NOT NULL,
+	PRIMARY KEY (scope, item)
+);`
+
+// errContention is returned by tryReserve when another process changed the
+// counter concurrently.
+var errContention = errors.New("concurrent update")
+
+// maxRetries bounds how often Reserve retries after losing a race with
+// another process.
+const maxRetries = 10
+
+// SQLLedgerConfig contains the configuration for an SQLLedger.
+type SQLLedgerConfig struct {
+	// DB is the database holding the tables created by Schema.
+	DB *sql.DB
+	// DollarPlaceholders selects PostgreSQL-style $1, $2 placeholders
+	// instead of ?.
+	DollarPlaceholders bool
+}
+
+// SQLLedger is a Ledger stored in an SQL database.  Reserve uses an
+// optimistic compare-and-swap on the counter row and Consume relies on the
+// primary key, so concurrent processes never hand out the same material.
+type SQLLedger struct {
+	db     *sql.DB
+	dollar bool
+}
+
+// Ensure SQLLedger implements the Ledger interface
+var _ Ledger = (*SQLLedger)(nil)
+
+// NewSQLLedger creates an SQLLedger from the given configuration.  The tables
+// must already exist; see Schema.
+func NewSQLLedger(config SQLLedgerConfig) (*SQLLedger, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("database must be provided")
+	}
+	return &SQLLedger{db: config.DB, dollar: config.DollarPlaceholders}, nil
+}
+
+// query rewrites ? placeholders for the configured dialect.
+func (l *SQLLedger) query(q string) string {
+	if !l.dollar {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Reserve implements Ledger.
+func (l *SQLLedger) Reserve(ctx context.Context, scope string) (uint64, error) {
+	if err := checkScope(scope); err != nil {
+		return 0, err
+	}
+	var err error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		var idx uint64
+		if idx, err = l.tryReserve(ctx, scope); !errors.Is(err, errContention) {
+			return idx, err
+		}
+	}
+	return 0, fmt.Errorf("reserving index in %s: %w", scope, err)
+}
+
+// tryReserve advances the counter of scope by one if nobody else did so
+// concurrently.
+func (l *SQLLedger) tryReserve(ctx context.Context, scope string) (uint64, error) {
+	var next int64
+	err := l.db.QueryRowContext(ctx, l.query("SELECT next FROM onetime_counters WHERE scope = ?"), scope).Scan(&next)
+	if errors.Is(err, sql.ErrNoRows) {
+		// A concurrent insert of the same scope fails on the primary key;
+		// the retry then sees the row.
+		if _, err := l.db.ExecContext(ctx, l.query("INSERT INTO onetime_counters (scope, next) VALUES (?, ?)"), scope, 1); err != nil {
+			return 0, fmt.Errorf("%w: %v", errContention, err)
+		}
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading counter %s: %w", scope, err)
+	}
+	res, err := l.db.ExecContext(ctx, l.query("UPDATE onetime_counters SET next = ? WHERE scope = ? AND next = ?"), next+1, scope, next)
+	if err != nil {
+		return 0, fmt.Errorf("advancing counter %s: %w", scope, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("advancing counter %s: %w", scope, err)
+	}
+	if n != 1 {
+		return 0, errContention
+	}
+	return uint64(next), nil
+}
+
+// Consume implements Ledger.
+func (l *SQLLedger) Consume(ctx context.Context, scope, item string) error {
+	if err := checkScope(scope); err != nil {
+		return err
+	}
+	if item == "" {
+		return fmt.Errorf("item must be provided")
+	}
+	var n int
+	err := l.db.QueryRowContext(ctx, l.query("SELECT COUNT(*) FROM onetime_consumed WHERE scope = ? AND item = ?"), scope, item).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("checking %s/%s: %w", scope, item, err)
+	}
+	if n > 0 {
+		return ErrReused
+	}
+	// Losing a race to another process fails here on the primary key,
+	// which is the safe outcome: the material is not used by this caller.
+	if _, err := l.db.ExecContext(ctx, l.query("INSERT INTO onetime_consumed (scope, item) VALUES (?, ?)"), scope, item); err != nil {
+		return fmt.Errorf("consuming %s/%s: %w", scope, item, err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package onetime
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB is a minimal database/sql driver that understands exactly the
+// statements issued by SQLLedger, with primary-key semantics.
+type fakeDB struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	consumed map[[2]string]bool
+	// beforeUpdate, if set, runs before every counter update to simulate a
+	// concurrent writer.
+	beforeUpdate func()
+}
+
+func (d *fakeDB) Open(string) (driver.Conn, error) { return &fakeConn{db: d}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("transactions not supported") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return strings.Count(s.query, "?") }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.db
+	if strings.HasPrefix(s.query, "UPDATE") && d.beforeUpdate != nil {
+		d.beforeUpdate()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO onetime_counters"):
+		scope := args[0].(string)
+		if _, ok := d.counters[scope]; ok {
+			return nil, fmt.Errorf("duplicate key")
+		}
+		d.counters[scope] = args[1].(int64)
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "UPDATE onetime_counters"):
+		scope := args[1].(string)
+		if d.counters[scope] != args[2].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		d.counters[scope] = args[0].(int64)
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "INSERT INTO onetime_consumed"):
+		key := [2]string{args[0].(string), args[1].(string)}
+		if d.consumed[key] {
+			return nil, fmt.Errorf("duplicate key")
+		}
+		d.consumed[key] = true
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("unexpected statement %q", s.query)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.db
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "SELECT next"):
+		next, ok := d.counters[args[0].(string)]
+		if !ok {
+			return &fakeRows{}, nil
+		}
+		return &fakeRows{values: []driver.Value{next}}, nil
+	case strings.HasPrefix(s.query, "SELECT COUNT"):
+		n := int64(0)
+		if d.consumed[[2]string{args[0].(string), args[1].(string)}] {
+			n = 1
+		}
+		return &fakeRows{values: []driver.Value{n}}, nil
+	}
+	return nil, fmt.Errorf("unexpected query %q", s.query)
+}
+
+// fakeRows holds at most one single-column row.
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return []string{"v"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func newSQLLedger(t *testing.T) (*SQLLedger, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{counters: make(map[string]int64), consumed: make(map[[2]string]bool)}
+	db := sql.OpenDB(connector{fake})
+	t.Cleanup(func() { db.Close() })
+	l, err := NewSQLLedger(SQLLedgerConfig{DB: db})
+	require.NoError(t, err)
+	return l, fake
+}
+
+// connector opens connections to a specific fakeDB.
+type connector struct{ db *fakeDB }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.db.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.db }
+
+func TestSQLLedger(t *testing.T) {
+	l, _ := newSQLLedger(t)
+	testLedger(t, l)
+}
+
+func TestSQLLedgerRetriesOnContention(t *testing.T) {
+	ctx := context.Background()
+	l, fake := newSQLLedger(t)
+	_, err := l.Reserve(ctx, "s")
+	require.NoError(t, err)
+
+	// Another process reserves index 1 between our read and our update.
+	raced := false
+	fake.beforeUpdate = func() {
+		if !raced {
+			raced = true
+			fake.mu.Lock()
+			fake.counters["s"]++
+			fake.mu.Unlock()
+		}
+	}
+	idx, err := l.Reserve(ctx, "s")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), idx)
+
+	fake.beforeUpdate = func() {
+		fake.mu.Lock()
+		fake.counters["s"]++
+		fake.mu.Unlock()
+	}
+	_, err = l.Reserve(ctx, "s")
+	assert.ErrorIs(t, err, errContention)
+}
+
+func TestSQLLedgerDollarPlaceholders(t *testing.T) {
+	l := &SQLLedger{dollar: true}
+	assert.Equal(t, "UPDATE t SET a = $1 WHERE b = $2", l.query("UPDATE t SET a = ? WHERE b = ?"))
+}
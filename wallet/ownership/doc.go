@@ -0,0 +1,21 @@
+// Package ownership proves control of the wallet's address to a counterparty
+// without a test transfer, e.g. to an exchange whitelisting it as a
+// withdrawal address.
+//
+// The counterparty issues a `Challenge` naming itself, the address, the date
+// and a random nonce, and sends its `Message`:
+//
+//	c, _ := ownership.NewChallenge("Example Exchange", address, time.Now())
+//	// send c.Message() to the wallet operator, remember c
+//
+// The operator answers with `Sign`, which only signs well-formed, current
+// challenges for the wallet's own address, so the signing path cannot be
+// used to sign arbitrary messages or transactions.  The signature is produced
+// by the MPC key through a remotesigner.Signer, i.e. by a quorum of parties.
+//
+// The counterparty checks the returned `Proof` against the challenge it
+// issued with `Verify`, and accepts each challenge only once.  Auditors
+// confirming control of many addresses check all proofs as one batch with
+// `VerifyAll`.  Addresses are
+// Solana addresses, the base58 encoding of the Ed25519 group public key.
+package ownership
@@ -0,0 +1,220 @@
+package ownership
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	"github.com/gagliardetto/solana-go"
+
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+const (
+	// title is the first line of every challenge.  It makes the message
+	// recognisable to people and keeps it from parsing as a transaction.
+	title = "Address ownership challenge"
+
+	dateLayout         = "2006-01-02"
+	nonceBytes         = 16
+	maxCounterpartyLen = 100
+	// dateTolerance bounds the clock difference between the parties: a
+	// challenge may be signed on the day before, on or after its date.
+	dateTolerance = 24 * time.Hour
+)
+
+// ErrInvalidProof is returned by Verify when a proof does not answer the
+// challenge.
+var ErrInvalidProof = errors.New("ownership: invalid proof")
+
+// Challenge asks the holder of Address to prove control of it to
+// Counterparty.
+type Challenge struct {
+	Counterparty string // Name of the party asking, e.g. the exchange
+	Address      string // Base58 address whose ownership is to be proven
+	Date         string // UTC issue date, YYYY-MM-DD
+	Nonce        string // 32 hex digits chosen by the counterparty
+}
+
+// NewChallenge issues a challenge with a fresh random nonce, dated now.
+func NewChallenge(counterparty, address string, now time.Time) (*Challenge, error) {
+	nonce := make([]byte, nonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	c := &Challenge{
+		Counterparty: counterparty,
+		Address:      address,
+		Date:         now.UTC().Format(dateLayout),
+		Nonce:        hex.EncodeToString(nonce),
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Message returns the text to be signed:
+//
+//	Address ownership challenge
+//	Counterparty: <counterparty>
+//	Address: <address>
+//	Date: <YYYY-MM-DD>
+//	Nonce: <nonce>
+func (c *Challenge) Message() []byte {
+	return []byte(fmt.Sprintf("%s\nCounterparty: %s\nAddress: %s\nDate: %s\nNonce: %s",
+		title, c.Counterparty, c.Address, c.Date, c.Nonce))
+}
+
+// ParseChallenge parses a message produced by Challenge.Message.  Anything
+// else, including a challenge with extra or reordered lines, is rejected, so
+// that signing a parsed challenge never signs unexpected content.
+func ParseChallenge(message []byte) (*Challenge, error) {
+	lines := strings.Split(string(message), "\n")
+	if len(lines) != 5 || lines[0] != title {
+		return nil, fmt.Errorf("not an address ownership challenge")
+	}
+	var fields [4]string
+	for i, name := range []string{"Counterparty", "Address", "Date", "Nonce"} {
+		value, ok := strings.CutPrefix(lines[i+1], name+": ")
+		if !ok {
+			return nil, fmt.Errorf("challenge line %d must be %s", i+2, name)
+		}
+		fields[i] = value
+	}
+	c := &Challenge{Counterparty: fields[0], Address: fields[1], Date: fields[2], Nonce: fields[3]}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if string(c.Message()) != string(message) {
+		return nil, fmt.Errorf("challenge is not in canonical form")
+	}
+	return c, nil
+}
+
+// validate checks the fields, so that every valid challenge round-trips
+// through Message and ParseChallenge.
+func (c *Challenge) validate() error {
+	if c.Counterparty == "" || len(c.Counterparty) > maxCounterpartyLen {
+		return fmt.Errorf("counterparty must be 1 to %d bytes", maxCounterpartyLen)
+	}
+	for _, r := range c.Counterparty {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("counterparty contains unprintable character %q", r)
+		}
+	}
+	if strings.TrimSpace(c.Counterparty) != c.Counterparty {
+		return fmt.Errorf("counterparty has surrounding whitespace")
+	}
+	if _, err := solana.PublicKeyFromBase58(c.Address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	if _, err := time.Parse(dateLayout, c.Date); err != nil {
+		return fmt.Errorf("invalid date %q", c.Date)
+	}
+	if nonce, err := hex.DecodeString(c.Nonce); err != nil || len(nonce) != nonceBytes || hex.EncodeToString(nonce) != c.Nonce {
+		return fmt.Errorf("nonce must be %d lowercase hex digits", 2*nonceBytes)
+	}
+	return nil
+}
+
+// Proof answers a challenge.
+type Proof struct {
+	Message   string `json:"message"`   // Challenge message as signed
+	Address   string `json:"address"`   // Address that signed it
+	Signature string `json:"signature"` // Base58 Ed25519 signature
+}
+
+// Sign answers a challenge received as message with the MPC key, after
+// checking that it is well formed, names the wallet's address and is dated
+// within a day of now.  Callers should show the returned challenge's
+// Counterparty to whoever authorizes the signature.
+func Sign(ctx context.Context, signer remotesigner.Signer, publicKey ed25519.PublicKey, message []byte, now time.Time) (*Challenge, *Proof, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	c, err := ParseChallenge(message)
+	if err != nil {
+		return nil, nil, err
+	}
+	address := solana.PublicKeyFromBytes(publicKey).String()
+	if c.Address != address {
+		return nil, nil, fmt.Errorf("challenge is for address %s, not %s", c.Address, address)
+	}
+	date, _ := time.Parse(dateLayout, c.Date)
+	if d := now.Sub(date); d < -dateTolerance || d > 2*dateTolerance {
+		return nil, nil, fmt.Errorf("challenge dated %s is not current", c.Date)
+	}
+	sig, err := signer.Sign(ctx, message)
+	if err != nil {
+		return nil, nil, fmt.Errorf("signing challenge: %w", err)
+	}
+	if !ed25519.Verify(publicKey, message, sig) {
+		return nil, nil, fmt.Errorf("signer returned an invalid signature")
+	}
+	return c, &Proof{Message: string(message), Address: address, Signature: solana.SignatureFromBytes(sig).String()}, nil
+}
+
+// Verify checks that proof answers the challenge the counterparty issued.
+// The counterparty must only accept each issued challenge once.
+func Verify(issued *Challenge, proof *Proof) error {
+	key, sig, err := parseProof(issued, proof)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, issued.Message(), sig) {
+		return fmt.Errorf("%w: signature does not verify", ErrInvalidProof)
+	}
+	return nil
+}
+
+// VerifyAll checks proofs[i] against issued[i] for every i, as an auditor
+// confirming control of many addresses at once does.  The signatures are
+// verified as one batch; an error names the first proof that fails.
+func VerifyAll(issued []*Challenge, proofs []*Proof) error {
+	if len(issued) != len(proofs) {
+		return fmt.Errorf("%d challenges but %d proofs", len(issued), len(proofs))
+	}
+	keys, msgs, sigs := make([][]byte, len(proofs)), make([][]byte, len(proofs)), make([][]byte, len(proofs))
+	for i := range proofs {
+		key, sig, err := parseProof(issued[i], proofs[i])
+		if err != nil {
+			return fmt.Errorf("proof %d: %w", i, err)
+		}
+		keys[i], msgs[i], sigs[i] = key, issued[i].Message(), sig
+	}
+	var be *verify.BatchError
+	if err := verify.VerifyBatch(keys, msgs, sigs); errors.As(err, &be) {
+		return fmt.Errorf("proof %d: %w: signature does not verify", be.Invalid[0], ErrInvalidProof)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseProof checks that proof answers issued and returns the key and
+// signature to verify.
+func parseProof(issued *Challenge, proof *Proof) (ed25519.PublicKey, []byte, error) {
+	if proof == nil || proof.Message != string(issued.Message()) {
+		return nil, nil, fmt.Errorf("%w: proof does not answer the issued challenge", ErrInvalidProof)
+	}
+	if proof.Address != issued.Address {
+		return nil, nil, fmt.Errorf("%w: proof is for address %s", ErrInvalidProof, proof.Address)
+	}
+	key, err := solana.PublicKeyFromBase58(issued.Address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid address: %v", ErrInvalidProof, err)
+	}
+	sig, err := solana.SignatureFromBase58(proof.Signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed signature", ErrInvalidProof)
+	}
+	return key[:], sig[:], nil
+}
@@ -0,0 +1,154 @@
+package ownership
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+func testKey(t *testing.T) (ed25519.PublicKey, remotesigner.Signer) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return pub, remotesigner.SignerFunc(func(_ context.Context, message []byte) ([]byte, error) {
+		return ed25519.Sign(priv, message), nil
+	})
+}
+
+func TestChallengeRoundTrip(t *testing.T) {
+	address := solana.NewWallet().PublicKey().String()
+	now := time.Date(2026, 3, 14, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600))
+	c, err := NewChallenge("Example Exchange", address, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-15", c.Date)
+	assert.Len(t, c.Nonce, 32)
+
+	parsed, err := ParseChallenge(c.Message())
+	require.NoError(t, err)
+	assert.Equal(t, c, parsed)
+
+	other, err := NewChallenge("Example Exchange", address, now)
+	require.NoError(t, err)
+	assert.NotEqual(t, c.Nonce, other.Nonce)
+
+	for name, msg := range map[string]string{
+		"other text":      "hello",
+		"extra line":      string(c.Message()) + "\nAmount: 1",
+		"reordered":       strings.Replace(string(c.Message()), "Counterparty: Example Exchange\nAddress: "+address, "Address: "+address+"\nCounterparty: Example Exchange", 1),
+		"bad address":     strings.Replace(string(c.Message()), address, "not-an-address", 1),
+		"bad date":        strings.Replace(string(c.Message()), c.Date, "15/03/2026", 1),
+		"uppercase hex":   strings.Replace(string(c.Message()), c.Nonce, strings.ToUpper(c.Nonce), 1),
+		"trailing space":  strings.Replace(string(c.Message()), "Exchange", "Exchange ", 1),
+		"carriage return": strings.Replace(string(c.Message()), "Exchange", "Exchange\r", 1),
+	} {
+		_, err := ParseChallenge([]byte(msg))
+		assert.Error(t, err, name)
+	}
+
+	_, err = NewChallenge("", address, now)
+	assert.Error(t, err)
+	_, err = NewChallenge(strings.Repeat("x", 101), address, now)
+	assert.Error(t, err)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	pub, signer := testKey(t)
+	address := solana.PublicKeyFromBytes(pub).String()
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	issued, err := NewChallenge("Example Exchange", address, now)
+	require.NoError(t, err)
+
+	c, proof, err := Sign(context.Background(), signer, pub, issued.Message(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "Example Exchange", c.Counterparty)
+	assert.Equal(t, address, proof.Address)
+	require.NoError(t, Verify(issued, proof))
+
+	// A proof for another challenge, or a tampered one, is rejected.
+	other, err := NewChallenge("Example Exchange", address, now)
+	require.NoError(t, err)
+	assert.ErrorIs(t, Verify(other, proof), ErrInvalidProof)
+	forged := *proof
+	forged.Signature = solana.Signature{1}.String()
+	assert.ErrorIs(t, Verify(issued, &forged), ErrInvalidProof)
+	assert.ErrorIs(t, Verify(issued, nil), ErrInvalidProof)
+
+	// A proof signed by another key is rejected.
+	otherPub, otherSigner := testKey(t)
+	_, otherProof, err := Sign(context.Background(), otherSigner, otherPub, mustChallenge(t, solana.PublicKeyFromBytes(otherPub).String(), now).Message(), now)
+	require.NoError(t, err)
+	otherProof.Message, otherProof.Address = proof.Message, proof.Address
+	assert.ErrorIs(t, Verify(issued, otherProof), ErrInvalidProof)
+}
+
+func TestVerifyAll(t *testing.T) {
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	var issued []*Challenge
+	var proofs []*Proof
+	for i := 0; i < 5; i++ {
+		pub, signer := testKey(t)
+		c := mustChallenge(t, solana.PublicKeyFromBytes(pub).String(), now)
+		_, proof, err := Sign(context.Background(), signer, pub, c.Message(), now)
+		require.NoError(t, err)
+		issued, proofs = append(issued, c), append(proofs, proof)
+	}
+	require.NoError(t, VerifyAll(issued, proofs))
+
+	// Swapping two signatures keeps every proof well formed but breaks both.
+	proofs[1].Signature, proofs[3].Signature = proofs[3].Signature, proofs[1].Signature
+	err := VerifyAll(issued, proofs)
+	assert.ErrorIs(t, err, ErrInvalidProof)
+	assert.ErrorContains(t, err, "proof 1")
+
+	proofs[2] = nil
+	assert.ErrorContains(t, VerifyAll(issued, proofs), "proof 2")
+	assert.Error(t, VerifyAll(issued, proofs[:4]))
+}
+
+func mustChallenge(t *testing.T, address string, now time.Time) *Challenge {
+	t.Helper()
+	c, err := NewChallenge("Example Exchange", address, now)
+	require.NoError(t, err)
+	return c
+}
+
+func TestSignRefuses(t *testing.T) {
+	pub, signer := testKey(t)
+	address := solana.PublicKeyFromBytes(pub).String()
+	now := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// Arbitrary messages are never signed.
+	_, _, err := Sign(ctx, signer, pub, []byte("transfer everything"), now)
+	assert.Error(t, err)
+
+	// Challenges for other addresses are refused.
+	_, _, err = Sign(ctx, signer, pub, mustChallenge(t, solana.NewWallet().PublicKey().String(), now).Message(), now)
+	assert.ErrorContains(t, err, "is for address")
+
+	// Challenges dated more than a day away are refused.
+	msg := mustChallenge(t, address, now).Message()
+	_, _, err = Sign(ctx, signer, pub, msg, now.Add(37*time.Hour)) // late on the following day
+	assert.NoError(t, err)
+	_, _, err = Sign(ctx, signer, pub, msg, now.Add(72*time.Hour))
+	assert.ErrorContains(t, err, "not current")
+	_, _, err = Sign(ctx, signer, pub, msg, now.Add(-48*time.Hour))
+	assert.ErrorContains(t, err, "not current")
+
+	// Signer failures and bad signatures are reported.
+	failing := remotesigner.SignerFunc(func(context.Context, []byte) ([]byte, error) { return nil, errors.New("quorum unavailable") })
+	_, _, err = Sign(ctx, failing, pub, msg, now)
+	assert.ErrorContains(t, err, "quorum unavailable")
+	_, wrong := testKey(t)
+	_, _, err = Sign(ctx, wrong, pub, msg, now)
+	assert.ErrorContains(t, err, "invalid signature")
+}
@@ -0,0 +1,24 @@
+// Package payout pays many recipients from one wallet with as few
+// transactions as possible.
+//
+//	p, err := payout.New(payout.Config{Coordinator: coord, Chain: "solana-devnet", From: treasury})
+//	results, err := p.Run(ctx, []chain.Output{{To: alice, Amount: big.NewInt(1e9)}, …})
+//
+// `Payout.Plan` packs the outputs into transactions with the chain's
+// `chain.Batcher`: outputs are grouped by recipient, so that repeated
+// recipients share an account key, and each transaction is filled until the
+// next output would exceed the size limit.  Every transaction is an ordinary
+// coordinator session at PriorityBatch, so policy sees every recipient, and
+// at most Config.Concurrency sessions are signed and broadcast at once.
+//
+// `Payout.Run` reports a `Result` per output.  Transactions that failed
+// definitively — failed simulation or reverted — are split in half and
+// retried, so that a single bad recipient is isolated within a few attempts
+// instead of sinking everyone else in its transaction.  A session is only
+// retried when its transaction provably cannot land: it was never broadcast,
+// or it was included and failed.  Transactions denied by policy or vetoed by
+// an observer are never retried, since smaller parts could slip under a
+// per-transaction limit or past the veto; their outputs report `ErrRefused`.
+// Sessions that are awaiting approval or hit an infrastructure error are left
+// as they are and reported, to be resumed through the coordinator.
+package payout
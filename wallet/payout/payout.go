@@ -0,0 +1,227 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"solana-threshold-wallet/wallet"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// ErrRefused is wrapped, together with wallet.ErrFailed, by the Result.Err
+// of outputs whose transaction policy denied or an observer vetoed.  Such
+// outputs are never retried.
+var ErrRefused = errors.New("payout: transfer refused")
+
+const (
+	defaultConcurrency = 4
+	defaultMaxAttempts = 3
+)
+
+// Config contains the configuration for a Payout.
+type Config struct {
+	// Coordinator runs the signing sessions.  Required.
+	Coordinator *coordinator.Coordinator
+	// Chain is the identifier of a chain registered with the coordinator
+	// that implements chain.Batcher.  Required.
+	Chain string
+	// From is the paying wallet's address.  Required.
+	From string
+	// Concurrency bounds the sessions run at once.  Defaults to 4.
+	Concurrency int
+	// MaxAttempts bounds how often an output is tried.  Defaults to 3.
+	MaxAttempts int
+	// MaxOutputs, if set, caps the outputs per transaction below what the
+	// size limit allows, e.g. to keep approval screens short.
+	MaxOutputs int
+}
+
+// Result is the outcome for one output.
+type Result struct {
+	Output   chain.Output
+	Session  string            // Session of the last attempt
+	State    coordinator.State // State that session was left in
+	TxID     string            // Transaction of the last attempt, once broadcast
+	Attempts int               // Number of sessions the output was part of
+	// Err is nil once the output is paid.  It wraps wallet.ErrFailed, also
+	// with ErrRefused, or wallet.ErrAwaitingApproval, or is the error that
+	// interrupted the session.
+	Err error
+}
+
+// Payout packs and pays outputs on one chain.
+type Payout struct {
+	c       *coordinator.Coordinator
+	batcher chain.Batcher
+	config  Config
+}
+
+// New creates a Payout from the given configuration.
+func New(config Config) (*Payout, error) {
+	if config.Coordinator == nil {
+		return nil, fmt.Errorf("coordinator must be provided")
+	}
+	if config.From == "" {
+		return nil, fmt.Errorf("sender address must be provided")
+	}
+	ch, ok := config.Coordinator.Chain(config.Chain)
+	if !ok {
+		return nil, fmt.Errorf("unknown chain %q", config.Chain)
+	}
+	batcher, ok := ch.(chain.Batcher)
+	if !ok {
+		return nil, fmt.Errorf("chain %q does not support batched transfers", config.Chain)
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	return &Payout{c: config.Coordinator, batcher: batcher, config: config}, nil
+}
+
+// Plan packs outputs into transactions and returns, per transaction, the
+// indices of its outputs.
+func (p *Payout) Plan(outputs []chain.Output) ([][]int, error) {
+	order := make([]int, len(outputs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return outputs[order[a]].To < outputs[order[b]].To })
+
+	var (
+		batches [][]int
+		current []int
+	)
+	for _, i := range order {
+		candidate := append(append([]int(nil), current...), i)
+		fits := p.config.MaxOutputs <= 0 || len(candidate) <= p.config.MaxOutputs
+		if fits {
+			var err error
+			if fits, err = p.batcher.Fits(p.transfer(outputs, candidate)); err != nil {
+				return nil, fmt.Errorf("output %d: %w", i, err)
+			}
+		}
+		switch {
+		case fits:
+			current = candidate
+		case len(current) == 0:
+			return nil, fmt.Errorf("output %d does not fit into a transaction", i)
+		default:
+			batches = append(batches, current)
+			current = []int{i}
+		}
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches, nil
+}
+
+func (p *Payout) transfer(outputs []chain.Output, batch []int) *chain.Transfer {
+	t := &chain.Transfer{From: p.config.From, Outputs: make([]chain.Output, len(batch))}
+	for j, i := range batch {
+		t.Outputs[j] = outputs[i]
+	}
+	return t
+}
+
+// Run pays every output and returns a result per output, in the order of
+// outputs.  The returned error is only set when outputs cannot be planned or
+// ctx is done; individual failures are reported in the results.
+func (p *Payout) Run(ctx context.Context, outputs []chain.Output) ([]Result, error) {
+	batches, err := p.Plan(outputs)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(outputs))
+	for i := range results {
+		results[i].Output = outputs[i]
+	}
+	for attempt := 1; len(batches) > 0 && attempt <= p.config.MaxAttempts; attempt++ {
+		retry := p.round(ctx, outputs, batches, results)
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		batches = batches[:0]
+		for _, b := range retry {
+			if len(b) > 1 {
+				batches = append(batches, b[:len(b)/2], b[len(b)/2:])
+			} else {
+				batches = append(batches, b)
+			}
+		}
+	}
+	return results, nil
+}
+
+// round runs one session per batch and returns the batches that may be
+// retried.
+func (p *Payout) round(ctx context.Context, outputs []chain.Output, batches [][]int, results []Result) [][]int {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		retry [][]int
+		sem   = make(chan struct{}, p.config.Concurrency)
+	)
+	for _, batch := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		}
+		wg.Add(1)
+		go func(batch []int) {
+			defer func() { <-sem; wg.Done() }()
+			s, retriable, err := p.pay(ctx, p.transfer(outputs, batch))
+			mu.Lock()
+			defer mu.Unlock()
+			for _, i := range batch {
+				r := &results[i]
+				r.Attempts++
+				r.Err = err
+				if s != nil {
+					r.Session, r.State, r.TxID = s.ID, s.State, s.TxID
+				}
+			}
+			if retriable {
+				retry = append(retry, batch)
+			}
+		}(batch)
+	}
+	wg.Wait()
+	return retry
+}
+
+// pay runs a session for t.  It reports whether the outputs may be paid again
+// by another session, which is the case only when this session's transaction
+// can never land and neither policy nor an observer refused it: a smaller
+// transaction must not slip under a limit or past a veto.
+func (p *Payout) pay(ctx context.Context, t *chain.Transfer) (*coordinator.Session, bool, error) {
+	s, err := p.c.Submit(ctx, &coordinator.Request{Chain: p.config.Chain, Transfer: *t, Priority: coordinator.PriorityBatch})
+	if err != nil {
+		return nil, true, err
+	}
+	next, err := p.c.Run(ctx, s.ID)
+	if next != nil {
+		s = next
+	}
+	switch {
+	case err != nil:
+		return s, false, err
+	case s.State == coordinator.StateFinalized:
+		return s, false, nil
+	case s.Refused():
+		return s, false, fmt.Errorf("%w: %w: %s", ErrRefused, wallet.ErrFailed, s.Err)
+	case s.State == coordinator.StateFailed:
+		return s, s.TxID == "" || s.Receipt != nil, fmt.Errorf("%w: %s", wallet.ErrFailed, s.Err)
+	default:
+		return s, false, wallet.ErrAwaitingApproval
+	}
+}
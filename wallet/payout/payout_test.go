@@ -0,0 +1,260 @@
+package payout
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// fakeChain fits capacity outputs per transaction, counting repeated
+// recipients once, and fails the simulation of transactions paying "mallory".
+type fakeChain struct {
+	capacity int
+
+	mu     sync.Mutex
+	built  map[string]*chain.Transfer
+	paid   map[string]int64
+	active int
+	peak   int
+}
+
+func newFakeChain(capacity int) *fakeChain {
+	return &fakeChain{capacity: capacity, built: map[string]*chain.Transfer{}, paid: map[string]int64{}}
+}
+
+func (f *fakeChain) ID() string { return "fake" }
+
+func (f *fakeChain) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (f *fakeChain) Fits(t *chain.Transfer) (bool, error) {
+	seen := map[string]bool{}
+	for _, o := range t.Outputs {
+		seen[o.To] = true
+	}
+	return len(seen) <= f.capacity, nil
+}
+
+func (f *fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payload := []byte(fmt.Sprintf("tx-%d", len(f.built)+1))
+	f.built[string(payload)] = t
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: payload, SigningPayload: payload}, nil
+}
+
+func (f *fakeChain) Decode(payload []byte) (*chain.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.built[string(payload)]
+	total := new(big.Int)
+	for _, o := range t.Outputs {
+		total.Add(total, o.Amount)
+	}
+	return &chain.Summary{Chain: f.ID(), From: t.From, Amount: total, Fee: big.NewInt(5000), Outputs: t.Outputs}, nil
+}
+
+func (f *fakeChain) Simulate(_ context.Context, tx *chain.SignedTx) (*chain.SimulationResult, error) {
+	f.mu.Lock()
+	f.active++
+	f.peak = max(f.peak, f.active)
+	t := f.built[string(tx.Unsigned.Payload)]
+	f.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.active--
+	for _, o := range t.Outputs {
+		if o.To == "mallory" {
+			return &chain.SimulationResult{Err: "invalid account"}, nil
+		}
+	}
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (f *fakeChain) Broadcast(_ context.Context, tx *chain.SignedTx) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.built[string(tx.Unsigned.Payload)].Outputs {
+		f.paid[o.To] += o.Amount.Int64()
+	}
+	return string(tx.Unsigned.Payload), nil
+}
+
+func (f *fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized}, nil
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(context.Context, *coordinator.SignRequest) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+func newTestPayout(t *testing.T, ch *fakeChain, policy coordinator.Policy, config Config) *Payout {
+	t.Helper()
+	c, err := coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{ch},
+		Signer:          fakeSigner{},
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	config.Coordinator, config.Chain, config.From = c, "fake", "treasury"
+	p, err := New(config)
+	require.NoError(t, err)
+	return p
+}
+
+func outputs(recipients ...string) []chain.Output {
+	out := make([]chain.Output, len(recipients))
+	for i, r := range recipients {
+		out[i] = chain.Output{To: r, Amount: big.NewInt(int64(i + 1))}
+	}
+	return out
+}
+
+func TestPlanPacksRepeatedRecipients(t *testing.T) {
+	p := newTestPayout(t, newFakeChain(2), nil, Config{})
+
+	// Grouped by recipient, the two payments to "a" share a slot.
+	batches, err := p.Plan(outputs("a", "b", "c", "a", "d"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 3, 1}, {2, 4}}, batches)
+
+	p.config.MaxOutputs = 2
+	batches, err = p.Plan(outputs("a", "b", "c", "a", "d"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 3}, {1, 2}, {4}}, batches)
+}
+
+func TestRunPaysEveryOutput(t *testing.T) {
+	ch := newFakeChain(3)
+	p := newTestPayout(t, ch, nil, Config{Concurrency: 2})
+	var recipients []string
+	for i := 0; i < 20; i++ {
+		recipients = append(recipients, fmt.Sprintf("r%02d", i))
+	}
+
+	results, err := p.Run(context.Background(), outputs(recipients...))
+	require.NoError(t, err)
+	require.Len(t, results, 20)
+	for i, r := range results {
+		assert.NoError(t, r.Err)
+		assert.Equal(t, coordinator.StateFinalized, r.State)
+		assert.Equal(t, 1, r.Attempts)
+		assert.Equal(t, int64(i+1), ch.paid[r.Output.To])
+	}
+	assert.Len(t, ch.built, 7)
+	assert.LessOrEqual(t, ch.peak, 2)
+}
+
+func TestRunIsolatesBadRecipient(t *testing.T) {
+	ch := newFakeChain(4)
+	p := newTestPayout(t, ch, nil, Config{MaxAttempts: 3})
+
+	results, err := p.Run(context.Background(), outputs("a", "b", "mallory", "d"))
+	require.NoError(t, err)
+	for _, r := range results {
+		if r.Output.To == "mallory" {
+			assert.ErrorIs(t, r.Err, wallet.ErrFailed)
+			assert.Equal(t, 3, r.Attempts)
+			continue
+		}
+		assert.NoError(t, r.Err, r.Output.To)
+		assert.Equal(t, r.Output.Amount.Int64(), ch.paid[r.Output.To], "paid exactly once")
+	}
+	assert.Zero(t, ch.paid["mallory"])
+	// The first transaction is split into {a,b} and {d,mallory}; the latter
+	// is split again.
+	assert.Equal(t, 2, results[0].Attempts)
+	assert.Equal(t, 3, results[3].Attempts)
+}
+
+func TestRunLeavesPendingApprovals(t *testing.T) {
+	ch := newFakeChain(4)
+	policy := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		return &coordinator.Decision{Allow: true, RequiredApprovals: 1}, nil
+	})
+	p := newTestPayout(t, ch, policy, Config{})
+
+	results, err := p.Run(context.Background(), outputs("a", "b"))
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.ErrorIs(t, r.Err, wallet.ErrAwaitingApproval)
+		assert.Equal(t, coordinator.StatePolicyEvaluated, r.State)
+		assert.Equal(t, 1, r.Attempts)
+	}
+	assert.Equal(t, results[0].Session, results[1].Session)
+}
+
+func TestRunDoesNotSplitRefusedTransactions(t *testing.T) {
+	// A per-transaction cap that the halves of the batch would fit under.
+	policy := coordinator.PolicyFunc(func(_ context.Context, _ *coordinator.Request, s *chain.Summary) (*coordinator.Decision, error) {
+		if s.Amount.Int64() > 5 {
+			return &coordinator.Decision{Allow: false, Reason: "amount above 5"}, nil
+		}
+		return &coordinator.Decision{Allow: true}, nil
+	})
+	ch := newFakeChain(4)
+	p := newTestPayout(t, ch, policy, Config{MaxAttempts: 3})
+
+	results, err := p.Run(context.Background(), outputs("a", "b", "c", "d"))
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.ErrorIs(t, r.Err, ErrRefused)
+		assert.ErrorIs(t, r.Err, wallet.ErrFailed)
+		assert.Equal(t, coordinator.StateFailed, r.State)
+		assert.Equal(t, 1, r.Attempts)
+	}
+	assert.Len(t, ch.built, 1)
+	assert.Empty(t, ch.paid)
+}
+
+// vetoAll is an observer vetoing every session it reviews.
+type vetoAll struct{}
+
+func (vetoAll) Name() string { return "compliance" }
+
+func (vetoAll) Observe(context.Context, *coordinator.Session, *coordinator.Event) {}
+
+func (vetoAll) Review(context.Context, *coordinator.Session) (*coordinator.Review, error) {
+	return &coordinator.Review{Veto: true, Reason: "sanctioned recipient"}, nil
+}
+
+func TestRunDoesNotRetryVetoedTransactions(t *testing.T) {
+	ch := newFakeChain(4)
+	c, err := coordinator.New(coordinator.Config{
+		Store:     coordinator.NewMemoryStore(),
+		Chains:    []chain.Chain{ch},
+		Signer:    fakeSigner{},
+		Observers: []coordinator.Observer{vetoAll{}},
+		Policy: coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+			return &coordinator.Decision{Allow: true, Reviewers: []string{"compliance"}}, nil
+		}),
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	p, err := New(Config{Coordinator: c, Chain: "fake", From: "treasury", MaxAttempts: 3})
+	require.NoError(t, err)
+
+	results, err := p.Run(context.Background(), outputs("a", "b"))
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.ErrorIs(t, r.Err, ErrRefused)
+		assert.Equal(t, 1, r.Attempts)
+	}
+	assert.Len(t, ch.built, 1)
+	assert.Empty(t, ch.paid)
+}
@@ -0,0 +1,58 @@
+// Package policy implements a rule-based coordinator.Policy whose rules,
+// address books and rate limits can be replaced while the process keeps
+// signing.
+//
+// Rules are a JSON document:
+//
+//	{
+//	  "version": "2025-06-01.1",
+//	  "address_books": {"exchanges": ["7xKX…", "9WzD…"]},
+//	  "rules": [
+//	    {"name": "small-to-exchange", "to_book": "exchanges", "max_amount": "1000000000"},
+//	    {"name": "anything-else", "approvals": 2, "reviewers": ["compliance"]}
+//	  ],
+//	  "rate_limits": [
+//	    {"name": "hourly", "chain": "solana-mainnet", "window": "1h", "max_count": 100, "max_amount": "50000000000"}
+//	  ]
+//	}
+//
+// The first rule whose chain, address book and amount cap match decides the
+// approvals and reviewers required; a transfer no rule matches is denied.  A
+// transfer that would exceed any applicable rate limit is denied as well.
+//
+// Amounts are in the smallest unit of the transferred asset, so
+// "max_amount" is only compared with transfers of one asset: the token
+// (mint or contract address) named by the rule's or rate limit's "token", or
+// the chain's native asset when it has none.  A rule with a max_amount and
+// no token therefore never matches token transfers, and a rate limit without
+// a token counts them towards max_count and max_value only.  Cap tokens with
+// a token-scoped rule or limit, which keeps its own total for that token:
+//
+//	{"name": "small-usdc", "token": "EPjF…", "max_amount": "1000000000"}
+//
+// Rules and rate limits may also cap the fiat value of transfers with
+// "max_value", in the document's "currency".  Transfers are priced through
+// Config.Prices using the ticker and decimals listed under "assets", keyed by
+// chain ID or token address:
+//
+//	"currency": "USD",
+//	"assets": {"solana-mainnet": {"ticker": "SOL", "decimals": 9}},
+//	"max_price_age": "2m",
+//	"rules": [{"name": "small", "max_value": "10000.00"}, …]
+//
+// A transfer of an asset that is not listed never matches a max_value rule
+// and is denied by max_value rate limits.  A quote older than max_price_age
+// (default 5m) or an unreachable oracle makes Evaluate fail instead of
+// denying, so the request can be retried.  The quotes used are returned in
+// Decision.Quotes and reach the parties in SignRequest.Quotes;
+// `Engine.EvaluateQuoted` re-evaluates a transfer against exactly those
+// prices.
+//
+// An `Engine` holds the active rules.  `Engine.Reload` reads the source
+// again, validates the whole document and only then swaps it in atomically,
+// so a broken edit leaves the previous rules in force and an evaluation never
+// sees half of an update.  Rate-limit usage is kept across reloads for limits
+// that keep their name.  Reloads are triggered through the admin API served
+// by `Engine.Handler` or, in a party daemon, on SIGHUP via
+// daemon.Config.Reloaders.
+package policy
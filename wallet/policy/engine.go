@@ -0,0 +1,285 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/price"
+)
+
+// FileSource returns a source reading the policy document at path.
+func FileSource(path string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading policy: %v", err)
+		}
+		return data, nil
+	}
+}
+
+// Config contains the configuration for an Engine.
+type Config struct {
+	// Source returns the current policy document, e.g. FileSource.
+	// Required.
+	Source func() ([]byte, error)
+	// Now returns the current time for rate limiting.  Defaults to
+	// time.Now.
+	Now func() time.Time
+	// Prices quotes the assets of policies with max_value limits.
+	// Evaluating such a policy without it fails.
+	Prices price.Oracle
+}
+
+// Engine evaluates transfers against the active Rules.
+type Engine struct {
+	source func() ([]byte, error)
+	now    func() time.Time
+	prices price.Oracle
+
+	rules atomic.Pointer[Rules]
+	// reloadMu serializes reloads; evaluations never take it.
+	reloadMu sync.Mutex
+
+	mu    sync.Mutex
+	usage map[string][]usage // Rate-limit usage by limit name
+}
+
+// usage is one transfer counted against a rate limit.
+type usage struct {
+	at     time.Time
+	token  string // Token of the transfer; empty for the native asset
+	amount *big.Int
+	value  *big.Rat // Nil when the transfer was not priced
+}
+
+// Ensure Engine implements the coordinator.Policy interface
+var _ coordinator.Policy = (*Engine)(nil)
+
+// NewEngine creates an Engine and loads the initial rules, which must be
+// valid.
+func NewEngine(config Config) (*Engine, error) {
+	if config.Source == nil {
+		return nil, fmt.Errorf("policy source must be provided")
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	e := &Engine{source: config.Source, now: config.Now, prices: config.Prices, usage: make(map[string][]usage)}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Rules returns the active rules.  They must not be modified.
+func (e *Engine) Rules() *Rules {
+	return e.rules.Load()
+}
+
+// Reload reads and validates the policy source and atomically replaces the
+// active rules.  On error the previous rules stay in force.
+func (e *Engine) Reload() error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	data, err := e.source()
+	if err != nil {
+		return err
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	e.rules.Store(rules)
+
+	// Drop usage of limits that no longer exist.
+	keep := make(map[string]bool, len(rules.RateLimits))
+	for _, l := range rules.RateLimits {
+		keep[l.Name] = true
+	}
+	e.mu.Lock()
+	for name := range e.usage {
+		if !keep[name] {
+			delete(e.usage, name)
+		}
+	}
+	e.mu.Unlock()
+	return nil
+}
+
+// Evaluate implements coordinator.Policy.  An allowed transfer is counted
+// against every applicable rate limit.  Quotes taken for max_value limits
+// must be fresh and are returned in Decision.Quotes.
+func (e *Engine) Evaluate(ctx context.Context, _ *coordinator.Request, summary *chain.Summary) (*coordinator.Decision, error) {
+	return e.evaluate(ctx, summary, e.prices, true)
+}
+
+// EvaluateQuoted evaluates a transfer like Evaluate but prices it only with
+// the given quotes, as recorded in the coordinator's Decision.Quotes and
+// passed in coordinator.SignRequest.Quotes.  Parties use it to reach the same
+// decision as the coordinator however long the session waited for
+// approvals, so the quotes' age is not checked again.
+func (e *Engine) EvaluateQuoted(ctx context.Context, summary *chain.Summary, quotes []*price.Quote) (*coordinator.Decision, error) {
+	return e.evaluate(ctx, summary, price.Recorded(quotes), false)
+}
+
+func (e *Engine) evaluate(ctx context.Context, summary *chain.Summary, prices price.Oracle, fresh bool) (*coordinator.Decision, error) {
+	if summary == nil || summary.Amount == nil {
+		return nil, fmt.Errorf("summary is incomplete")
+	}
+	rules := e.rules.Load()
+	v := &valuation{ctx: ctx, rules: rules, summary: summary, prices: prices, fresh: fresh, now: e.now}
+	rule, err := rules.match(summary, v)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return &coordinator.Decision{Reason: fmt.Sprintf("no rule of policy %s matches", rules.Version)}, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	var applicable []string
+	for i := range rules.RateLimits {
+		l := &rules.RateLimits[i]
+		if !l.applies(summary) {
+			continue
+		}
+		count, total, totalValue := e.window(l, now)
+		if l.MaxCount > 0 && count+1 > l.MaxCount {
+			return &coordinator.Decision{Reason: fmt.Sprintf("rate limit %s: more than %d transfers per %s", l.Name, l.MaxCount, l.Window)}, nil
+		}
+		if l.MaxAmount != nil && l.Token == summary.Token && total.Add(total, summary.Amount).Cmp(&l.MaxAmount.Int) > 0 {
+			return &coordinator.Decision{Reason: fmt.Sprintf("rate limit %s: more than %s per %s", l.Name, l.MaxAmount, l.Window)}, nil
+		}
+		if l.MaxValue != nil {
+			value, err := v.value()
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				return &coordinator.Decision{Reason: fmt.Sprintf("rate limit %s: no price for the transferred asset", l.Name)}, nil
+			}
+			if totalValue.Add(totalValue, value).Cmp(&l.MaxValue.Rat) > 0 {
+				return &coordinator.Decision{Reason: fmt.Sprintf("rate limit %s: more than %s %s per %s", l.Name, l.MaxValue.FloatString(2), rules.Currency, l.Window)}, nil
+			}
+		}
+		applicable = append(applicable, l.Name)
+	}
+	value, _ := v.value() // Already fetched if any limit needed it
+	for _, name := range applicable {
+		e.usage[name] = append(e.usage[name], usage{at: now, token: summary.Token, amount: new(big.Int).Set(summary.Amount), value: value})
+	}
+	d := &coordinator.Decision{
+		Allow:             true,
+		Reason:            fmt.Sprintf("rule %s of policy %s", rule.Name, rules.Version),
+		RequiredApprovals: rule.Approvals,
+		Reviewers:         rule.Reviewers,
+	}
+	if v.quote != nil {
+		d.Quotes = []*price.Quote{v.quote}
+	}
+	return d, nil
+}
+
+// valuation prices one transfer, fetching the quote at most once and only
+// when a max_value limit needs it.
+type valuation struct {
+	ctx     context.Context
+	rules   *Rules
+	summary *chain.Summary
+	prices  price.Oracle
+	fresh   bool
+	now     func() time.Time
+
+	done  bool
+	quote *price.Quote
+	val   *big.Rat
+	err   error
+}
+
+// value returns the transfer's value in the policy currency, nil if the
+// policy lists no asset for it, or an error if it cannot be priced.
+func (v *valuation) value() (*big.Rat, error) {
+	if v.done {
+		return v.val, v.err
+	}
+	v.done = true
+	asset, ok := v.rules.asset(v.summary)
+	if !ok {
+		return nil, nil
+	}
+	if v.prices == nil {
+		v.err = fmt.Errorf("policy %s prices transfers but no price oracle is configured", v.rules.Version)
+		return nil, v.err
+	}
+	q, err := v.prices.Quote(v.ctx, asset.Ticker, v.rules.Currency)
+	if err != nil {
+		v.err = fmt.Errorf("pricing %s: %w", asset.Ticker, err)
+		return nil, v.err
+	}
+	if v.fresh {
+		if err := q.Fresh(v.now(), v.rules.MaxPriceAge.Duration); err != nil {
+			v.err = err
+			return nil, err
+		}
+	}
+	v.quote, v.val = q, q.Value(v.summary.Amount, asset.Decimals)
+	return v.val, nil
+}
+
+// window prunes expired usage of l and returns the count, total amount and
+// total value of what remains.  The total amount only sums transfers of the
+// asset l.MaxAmount is denominated in.  e.mu must be held.
+func (e *Engine) window(l *RateLimit, now time.Time) (int, *big.Int, *big.Rat) {
+	entries := e.usage[l.Name]
+	start := 0
+	for start < len(entries) && !entries[start].at.After(now.Add(-l.Window.Duration)) {
+		start++
+	}
+	entries = entries[start:]
+	e.usage[l.Name] = entries
+	total, value := new(big.Int), new(big.Rat)
+	for _, u := range entries {
+		if u.token == l.Token {
+			total.Add(total, u.amount)
+		}
+		if u.value != nil {
+			value.Add(value, u.value)
+		}
+	}
+	return len(entries), total, value
+}
+
+// Handler returns the admin API: GET reports the active policy version and
+// POST reloads it.  Both respond with {"version": …}, or {"error": …} with
+// status 400 when the new policy is rejected.
+func (e *Engine) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if err := e.Reload(); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "version": e.Rules().Version})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"version": e.Rules().Version})
+	})
+}
@@ -0,0 +1,342 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/daemon"
+	"solana-threshold-wallet/wallet/price"
+)
+
+const testPolicy = `{
+  "version": "v1",
+  "address_books": {"exchanges": ["exchange-1"]},
+  "rules": [
+    {"name": "small-to-exchange", "to_book": "exchanges", "max_amount": "1000"},
+    {"name": "other", "chain": "solana-test", "approvals": 2, "reviewers": ["compliance"]}
+  ],
+  "rate_limits": [
+    {"name": "hourly", "window": "1h", "max_count": 3, "max_amount": "2500"}
+  ]
+}`
+
+func transfer(to string, amount int64) *chain.Summary {
+	return &chain.Summary{Chain: "solana-test", From: "wallet", To: to, Amount: big.NewInt(amount), Fee: big.NewInt(5000)}
+}
+
+func newTestEngine(t *testing.T, policy string) (*Engine, string, *time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(policy), 0o600))
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	e, err := NewEngine(Config{Source: FileSource(path), Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	return e, path, &now
+}
+
+func TestEvaluateRules(t *testing.T) {
+	e, _, _ := newTestEngine(t, testPolicy)
+	ctx := context.Background()
+
+	d, err := e.Evaluate(ctx, nil, transfer("exchange-1", 1000))
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+	assert.Equal(t, 0, d.RequiredApprovals)
+	assert.Contains(t, d.Reason, "small-to-exchange")
+
+	d, err = e.Evaluate(ctx, nil, transfer("exchange-1", 1001))
+	require.NoError(t, err)
+	assert.Equal(t, 2, d.RequiredApprovals)
+	assert.Equal(t, []string{"compliance"}, d.Reviewers)
+
+	other := transfer("somewhere", 1)
+	other.Chain = "evm-1"
+	d, err = e.Evaluate(ctx, nil, other)
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "no rule")
+}
+
+func TestEvaluateBatchRecipients(t *testing.T) {
+	e, _, _ := newTestEngine(t, testPolicy)
+	ctx := context.Background()
+
+	batch := transfer("", 600)
+	batch.Outputs = []chain.Output{{To: "exchange-1", Amount: big.NewInt(100)}, {To: "somewhere", Amount: big.NewInt(500)}}
+	d, err := e.Evaluate(ctx, nil, batch)
+	require.NoError(t, err)
+	assert.Equal(t, 2, d.RequiredApprovals, "one recipient outside the book is enough to miss the rule")
+
+	batch.Outputs[1].To = "exchange-1"
+	d, err = e.Evaluate(ctx, nil, batch)
+	require.NoError(t, err)
+	assert.Contains(t, d.Reason, "small-to-exchange")
+}
+
+func TestTokenAmountsAreScoped(t *testing.T) {
+	e, _, _ := newTestEngine(t, `{
+  "version": "v1",
+  "address_books": {"exchanges": ["exchange-1"]},
+  "rules": [
+    {"name": "small-to-exchange", "to_book": "exchanges", "max_amount": "1000"},
+    {"name": "small-usdc", "token": "usdc-mint", "max_amount": "5000000"},
+    {"name": "other", "chain": "solana-test", "approvals": 2}
+  ],
+  "rate_limits": [
+    {"name": "hourly", "window": "1h", "max_count": 10, "max_amount": "2500"},
+    {"name": "hourly-usdc", "token": "usdc-mint", "window": "1h", "max_amount": "8000000"}
+  ]
+}`)
+	ctx := context.Background()
+	usdc := func(amount int64) *chain.Summary {
+		s := transfer("exchange-1", amount)
+		s.Token = "usdc-mint"
+		return s
+	}
+	bonk := transfer("exchange-1", 500)
+	bonk.Token = "bonk-mint"
+
+	// A native cap in lamports does not match a token transfer of a smaller
+	// number of base units.
+	d, err := e.Evaluate(ctx, nil, bonk)
+	require.NoError(t, err)
+	assert.Contains(t, d.Reason, "rule other")
+
+	d, err = e.Evaluate(ctx, nil, usdc(6_000_000))
+	require.NoError(t, err)
+	assert.Contains(t, d.Reason, "rule other")
+	d, err = e.Evaluate(ctx, nil, usdc(1_000_000))
+	require.NoError(t, err)
+	assert.Contains(t, d.Reason, "small-usdc")
+
+	// Token amounts are totalled per token, and not against the native
+	// limit, which 7 million USDC base units would have exceeded.
+	d, err = e.Evaluate(ctx, nil, transfer("exchange-1", 1000))
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+	d, err = e.Evaluate(ctx, nil, usdc(2_000_000))
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "rate limit hourly-usdc")
+}
+
+func TestRateLimits(t *testing.T) {
+	e, _, now := newTestEngine(t, testPolicy)
+	ctx := context.Background()
+
+	for _, amount := range []int64{1000, 1000} {
+		d, err := e.Evaluate(ctx, nil, transfer("exchange-1", amount))
+		require.NoError(t, err)
+		require.True(t, d.Allow)
+	}
+	d, err := e.Evaluate(ctx, nil, transfer("exchange-1", 1000))
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "more than 2500")
+
+	d, err = e.Evaluate(ctx, nil, transfer("exchange-1", 500))
+	require.NoError(t, err)
+	require.True(t, d.Allow)
+	d, err = e.Evaluate(ctx, nil, transfer("exchange-1", 1))
+	require.NoError(t, err)
+	assert.Contains(t, d.Reason, "more than 3 transfers")
+
+	*now = now.Add(time.Hour)
+	d, err = e.Evaluate(ctx, nil, transfer("exchange-1", 1))
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+}
+
+func TestReloadIsValidatedAndAtomic(t *testing.T) {
+	e, path, _ := newTestEngine(t, testPolicy)
+	ctx := context.Background()
+
+	d, err := e.Evaluate(ctx, nil, transfer("exchange-1", 1000))
+	require.NoError(t, err)
+	require.True(t, d.Allow)
+
+	for _, bad := range []string{
+		`{"version": "v2", "rules": []}`,
+		`{"version": "v2", "rules": [{"name": "r", "to_book": "missing"}]}`,
+		`{"version": "v2", "rules": [{"name": "r", "max_amount": "-1"}]}`,
+		`{"version": "v2", "rules": [{"name": "r"}], "rate_limits": [{"name": "l", "window": "1h"}]}`,
+		`{"version": "v2", "rules": [{"name": "r", "typo": true}]}`,
+		`not json`,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(bad), 0o600))
+		assert.Error(t, e.Reload(), bad)
+		assert.Equal(t, "v1", e.Rules().Version)
+	}
+
+	// The rate limit keeps its usage across a reload that keeps its name.
+	next := `{"version": "v2", "rules": [{"name": "all"}], "rate_limits": [{"name": "hourly", "window": "1h", "max_count": 1}]}`
+	require.NoError(t, os.WriteFile(path, []byte(next), 0o600))
+	require.NoError(t, e.Reload())
+	assert.Equal(t, "v2", e.Rules().Version)
+	d, err = e.Evaluate(ctx, nil, transfer("anyone", 1))
+	require.NoError(t, err)
+	assert.Contains(t, d.Reason, "more than 1 transfers")
+}
+
+func TestConcurrentEvaluateAndReload(t *testing.T) {
+	e, _, _ := newTestEngine(t, `{"version": "v1", "rules": [{"name": "all"}]}`)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				d, err := e.Evaluate(context.Background(), nil, transfer("x", 1))
+				assert.NoError(t, err)
+				assert.True(t, d.Allow)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				assert.NoError(t, e.Reload())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAdminHandler(t *testing.T) {
+	e, path, _ := newTestEngine(t, testPolicy)
+	srv := httptest.NewServer(e.Handler())
+	defer srv.Close()
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": "v2", "rules": [{"name": "all"}]}`), 0o600))
+	resp, err := http.Post(srv.URL, "application/json", nil)
+	require.NoError(t, err)
+	var out map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "v2", out["version"])
+
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o600))
+	resp, err = http.Post(srv.URL, "application/json", nil)
+	require.NoError(t, err)
+	out = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "v2", out["version"])
+	assert.NotEmpty(t, out["error"])
+}
+
+func TestEngineIsDaemonReloader(t *testing.T) {
+	e, path, _ := newTestEngine(t, testPolicy)
+	d := daemon.New(daemon.Config{Reloaders: []daemon.Reloader{e}})
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": "v3", "rules": [{"name": "all"}]}`), 0o600))
+	require.NoError(t, d.Reload())
+	assert.Equal(t, "v3", e.Rules().Version)
+}
+
+const valuedPolicy = `{
+  "version": "v2",
+  "currency": "USD",
+  "assets": {"solana-test": {"ticker": "SOL", "decimals": 9}},
+  "max_price_age": "1m",
+  "rules": [
+    {"name": "small", "max_value": "100.00"},
+    {"name": "large", "approvals": 2}
+  ],
+  "rate_limits": [
+    {"name": "daily", "window": "24h", "max_value": "250"}
+  ]
+}`
+
+func TestEvaluateFiatLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(valuedPolicy), 0o600))
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	prices := &price.Static{Currency: "USD", Prices: map[string]*big.Rat{"SOL": big.NewRat(200, 1)}, Now: func() time.Time { return now }}
+	var oracle price.Oracle = prices
+	e, err := NewEngine(Config{
+		Source: FileSource(path),
+		Now:    func() time.Time { return now },
+		Prices: price.OracleFunc(func(ctx context.Context, asset, currency string) (*price.Quote, error) {
+			return oracle.Quote(ctx, asset, currency)
+		}),
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// 0.5 SOL at $200 is exactly the $100 cap.
+	d, err := e.Evaluate(ctx, nil, transfer("anyone", 500_000_000))
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+	assert.Contains(t, d.Reason, "small")
+	require.Len(t, d.Quotes, 1)
+	assert.Equal(t, "SOL", d.Quotes[0].Asset)
+
+	d, err = e.Evaluate(ctx, nil, transfer("anyone", 500_000_001))
+	require.NoError(t, err)
+	assert.Contains(t, d.Reason, "large")
+	assert.Equal(t, 2, d.RequiredApprovals)
+
+	// $200.0000002 has been counted; another $50 exceeds $250 per day.
+	d, err = e.Evaluate(ctx, nil, transfer("anyone", 250_000_000))
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "rate limit daily")
+
+	// Parties reach the coordinator's decision from the recorded quote even
+	// after the price moved and the quote aged.
+	now = now.Add(time.Hour)
+	prices.Prices["SOL"] = big.NewRat(1, 1)
+	q, err := e.EvaluateQuoted(ctx, transfer("anyone", 1), []*price.Quote{{Asset: "SOL", Currency: "USD", Price: big.NewRat(200, 1), At: now.Add(-time.Hour)}})
+	require.NoError(t, err)
+	assert.True(t, q.Allow)
+
+	_, err = e.EvaluateQuoted(ctx, transfer("anyone", 1), nil)
+	assert.ErrorIs(t, err, price.ErrNoPrice)
+
+	// A stale quote is an error rather than a denial, so that the request
+	// can be retried once the feed recovers.
+	oracle = price.OracleFunc(func(context.Context, string, string) (*price.Quote, error) {
+		return &price.Quote{Asset: "SOL", Currency: "USD", Price: big.NewRat(1, 1), At: now.Add(-2 * time.Minute)}, nil
+	})
+	_, err = e.Evaluate(ctx, nil, transfer("anyone", 1))
+	assert.ErrorIs(t, err, price.ErrStale)
+
+	// Transfers of unlisted assets never match a max_value rule and are
+	// denied by value-based rate limits.
+	d, err = e.Evaluate(ctx, nil, &chain.Summary{Chain: "other", From: "wallet", To: "anyone", Amount: big.NewInt(1), Fee: big.NewInt(1)})
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "no price")
+}
+
+func TestValuedPolicyValidation(t *testing.T) {
+	for name, policy := range map[string]string{
+		"no currency": `{"version": "v1", "rules": [{"name": "a", "max_value": "1"}]}`,
+		"exponent":    `{"version": "v1", "currency": "USD", "rules": [{"name": "a", "max_value": "1e3"}]}`,
+		"fraction":    `{"version": "v1", "currency": "USD", "rules": [{"name": "a", "max_value": "1/3"}]}`,
+		"negative":    `{"version": "v1", "currency": "USD", "rules": [{"name": "a", "max_value": "-1"}]}`,
+		"empty limit": `{"version": "v1", "rules": [{"name": "a"}], "rate_limits": [{"name": "l", "window": "1h"}]}`,
+	} {
+		_, err := Parse([]byte(policy))
+		assert.Error(t, err, name)
+	}
+
+	var v Value
+	require.NoError(t, json.Unmarshal([]byte(`"100.50"`), &v))
+	out, err := json.Marshal(&v)
+	require.NoError(t, err)
+	assert.Equal(t, `"100.5"`, string(out))
+}
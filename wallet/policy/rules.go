@@ -0,0 +1,299 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+const (
+	defaultMaxPriceAge = 5 * time.Minute
+	// maxValueDigits bounds the decimals written for a Value.
+	maxValueDigits = 40
+)
+
+// Amount is a non-negative integer in the chain's smallest unit, encoded as a
+// decimal string.
+type Amount struct {
+	big.Int
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("amount must be a decimal string: %v", err)
+	}
+	if _, ok := a.SetString(s, 10); !ok || a.Sign() < 0 {
+		return fmt.Errorf("invalid amount %q", s)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// Duration is a time.Duration encoded as a string such as "1h30m".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %v", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Value is a non-negative amount of fiat currency, encoded as a decimal
+// string such as "10000" or "2500.50".
+type Value struct {
+	big.Rat
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("value must be a decimal string: %v", err)
+	}
+	if strings.ContainsAny(s, "/eE") {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	if _, ok := v.SetString(s); !ok || v.Sign() < 0 {
+		return fmt.Errorf("invalid value %q", s)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v *Value) MarshalJSON() ([]byte, error) {
+	// A parsed decimal has a finite expansion; write all of it.
+	for prec := 0; prec < maxValueDigits; prec++ {
+		s := v.FloatString(prec)
+		if r, _ := new(big.Rat).SetString(s); r.Cmp(&v.Rat) == 0 {
+			return json.Marshal(s)
+		}
+	}
+	return json.Marshal(v.FloatString(maxValueDigits))
+}
+
+// Asset tells how to price a chain's native amounts or a token's.
+type Asset struct {
+	Ticker   string `json:"ticker"`   // Asset the price oracle is asked for, e.g. "SOL"
+	Decimals int    `json:"decimals"` // Smallest units per whole unit, as a power of ten
+}
+
+// Rule grants a decision to matching transfers.  MaxAmount is in the
+// smallest unit of Token, or of the chain's native asset if Token is empty;
+// a rule with a MaxAmount and no Token does not match token transfers.
+type Rule struct {
+	Name      string   `json:"name"`
+	Chain     string   `json:"chain,omitempty"`      // Chain ID to match; empty matches any
+	Token     string   `json:"token,omitempty"`      // Token mint or contract address to match; empty matches any
+	ToBook    string   `json:"to_book,omitempty"`    // Address book the recipient must be in
+	MaxAmount *Amount  `json:"max_amount,omitempty"` // Inclusive cap on the transferred amount
+	MaxValue  *Value   `json:"max_value,omitempty"`  // Inclusive cap on the transferred value in Rules.Currency
+	Approvals int      `json:"approvals,omitempty"`  // Human approvals required
+	Reviewers []string `json:"reviewers,omitempty"`  // Observers whose review is required
+}
+
+// RateLimit caps transfers over a sliding window.  MaxAmount is in the
+// smallest unit of Token, or of the chain's native asset if Token is empty;
+// a limit without a Token counts token transfers towards MaxCount and
+// MaxValue but not towards MaxAmount.
+type RateLimit struct {
+	Name      string   `json:"name"`
+	Chain     string   `json:"chain,omitempty"`      // Chain ID to match; empty matches any
+	Token     string   `json:"token,omitempty"`      // Token mint or contract address to match; empty matches any
+	Window    Duration `json:"window"`               // Length of the sliding window
+	MaxCount  int      `json:"max_count,omitempty"`  // Transfers per window; zero means unlimited
+	MaxAmount *Amount  `json:"max_amount,omitempty"` // Total amount per window
+	MaxValue  *Value   `json:"max_value,omitempty"`  // Total value per window in Rules.Currency
+}
+
+// applies reports whether l counts transfers of summary.
+func (l *RateLimit) applies(summary *chain.Summary) bool {
+	return (l.Chain == "" || l.Chain == summary.Chain) && (l.Token == "" || l.Token == summary.Token)
+}
+
+// Rules is a complete policy document.
+type Rules struct {
+	Version      string              `json:"version"`
+	AddressBooks map[string][]string `json:"address_books,omitempty"`
+	Rules        []Rule              `json:"rules"`
+	RateLimits   []RateLimit         `json:"rate_limits,omitempty"`
+
+	// Currency is the ISO 4217 code of every max_value.
+	Currency string `json:"currency,omitempty"`
+	// Assets prices transfers for max_value limits, keyed by chain ID for
+	// native transfers and by token address for token transfers.
+	Assets map[string]Asset `json:"assets,omitempty"`
+	// MaxPriceAge is the oldest quote accepted for max_value limits.
+	// Defaults to 5 minutes.
+	MaxPriceAge Duration `json:"max_price_age,omitempty"`
+
+	books map[string]map[string]bool
+}
+
+// Parse decodes and validates a policy document.  Unknown fields are
+// rejected so that typos do not silently weaken a policy.
+func Parse(data []byte) (*Rules, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var r Rules
+	if err := dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("decoding policy: %v", err)
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *Rules) validate() error {
+	if r.Version == "" {
+		return fmt.Errorf("policy version must be provided")
+	}
+	if len(r.Rules) == 0 {
+		return fmt.Errorf("policy must contain at least one rule")
+	}
+	r.books = make(map[string]map[string]bool, len(r.AddressBooks))
+	for name, addrs := range r.AddressBooks {
+		book := make(map[string]bool, len(addrs))
+		for _, a := range addrs {
+			if a == "" {
+				return fmt.Errorf("address book %q contains an empty address", name)
+			}
+			book[a] = true
+		}
+		r.books[name] = book
+	}
+	names := make(map[string]bool)
+	for i, rule := range r.Rules {
+		if rule.Name == "" || names[rule.Name] {
+			return fmt.Errorf("rule %d: name must be unique and non-empty", i)
+		}
+		names[rule.Name] = true
+		if rule.ToBook != "" && r.books[rule.ToBook] == nil {
+			return fmt.Errorf("rule %q: unknown address book %q", rule.Name, rule.ToBook)
+		}
+		if rule.Approvals < 0 {
+			return fmt.Errorf("rule %q: approvals cannot be negative", rule.Name)
+		}
+	}
+	limits := make(map[string]bool)
+	for i, l := range r.RateLimits {
+		if l.Name == "" || limits[l.Name] {
+			return fmt.Errorf("rate limit %d: name must be unique and non-empty", i)
+		}
+		limits[l.Name] = true
+		if l.Window.Duration <= 0 {
+			return fmt.Errorf("rate limit %q: window must be positive", l.Name)
+		}
+		if l.MaxCount < 0 || (l.MaxCount == 0 && l.MaxAmount == nil && l.MaxValue == nil) {
+			return fmt.Errorf("rate limit %q: max_count, max_amount or max_value must be set", l.Name)
+		}
+	}
+	if r.valued() && r.Currency == "" {
+		return fmt.Errorf("policy uses max_value but sets no currency")
+	}
+	for key, a := range r.Assets {
+		if a.Ticker == "" || a.Decimals < 0 {
+			return fmt.Errorf("asset %q: ticker and non-negative decimals must be set", key)
+		}
+	}
+	if r.MaxPriceAge.Duration < 0 {
+		return fmt.Errorf("max_price_age cannot be negative")
+	}
+	if r.MaxPriceAge.Duration == 0 {
+		r.MaxPriceAge.Duration = defaultMaxPriceAge
+	}
+	return nil
+}
+
+// valued reports whether any rule or rate limit caps the fiat value.
+func (r *Rules) valued() bool {
+	for _, rule := range r.Rules {
+		if rule.MaxValue != nil {
+			return true
+		}
+	}
+	for _, l := range r.RateLimits {
+		if l.MaxValue != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// asset returns how the transferred asset of summary is priced.
+func (r *Rules) asset(summary *chain.Summary) (Asset, bool) {
+	key := summary.Chain
+	if summary.Token != "" {
+		key = summary.Token
+	}
+	a, ok := r.Assets[key]
+	return a, ok
+}
+
+// inBook reports whether every recipient of summary is in the address book.
+func (r *Rules) inBook(book string, summary *chain.Summary) bool {
+	for _, o := range summary.Recipients() {
+		if !r.books[book][o.To] {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the first rule matching summary, or nil.  A rule with a
+// max_value does not match a transfer of an asset without a price.
+func (r *Rules) match(summary *chain.Summary, v *valuation) (*Rule, error) {
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.Chain != "" && rule.Chain != summary.Chain {
+			continue
+		}
+		if rule.Token != "" && rule.Token != summary.Token {
+			continue
+		}
+		if rule.ToBook != "" && !r.inBook(rule.ToBook, summary) {
+			continue
+		}
+		// The cap is in the units of rule.Token, which must be what is sent.
+		if rule.MaxAmount != nil && (rule.Token != summary.Token || summary.Amount.Cmp(&rule.MaxAmount.Int) > 0) {
+			continue
+		}
+		if rule.MaxValue != nil {
+			value, err := v.value()
+			if err != nil {
+				return nil, err
+			}
+			if value == nil || value.Cmp(&rule.MaxValue.Rat) > 0 {
+				continue
+			}
+		}
+		return rule, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,18 @@
+// Package price supplies fiat prices of the wallet's assets.
+//
+// An `Oracle` returns a `Quote`: the price of one whole unit of an asset,
+// identified by its ticker, in a fiat currency, with the time it was
+// observed and the source that reported it:
+//
+//	q, err := oracle.Quote(ctx, "SOL", "USD")
+//	usd := q.Value(amountInLamports, 9) // *big.Rat
+//
+// `Coingecko` and `Pyth` read live prices from those services and stamp each
+// quote with the time the service last updated it, so `Quote.Fresh` can
+// reject prices older than a limit even when the service itself is up.
+// `Static` serves fixed prices, e.g. configured by hand on an air-gapped
+// host, `Recorded` replays the quotes recorded with a session and
+// `OracleFunc` adapts a function.  Prices are exact rationals so
+// that converting an amount never loses precision before it is rounded for
+// display.
+package price
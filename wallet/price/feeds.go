@@ -0,0 +1,214 @@
+package price
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const maxFeedResponse = 1 << 16
+
+// CoingeckoConfig contains the configuration for a Coingecko oracle.
+type CoingeckoConfig struct {
+	// URL is the API endpoint.  Defaults to https://api.coingecko.com.
+	URL string
+	// IDs maps tickers to Coingecko coin IDs, e.g. "SOL" to "solana".
+	IDs map[string]string
+	// APIKey is sent in the x-cg-pro-api-key header when set.
+	APIKey string
+	// Client makes the requests.  Defaults to a client with a 10 second
+	// timeout.
+	Client *http.Client
+}
+
+// Coingecko reads prices from the Coingecko simple price API.
+type Coingecko struct {
+	config CoingeckoConfig
+}
+
+// NewCoingecko creates a Coingecko oracle from the given configuration.
+func NewCoingecko(config CoingeckoConfig) *Coingecko {
+	if config.URL == "" {
+		config.URL = "https://api.coingecko.com"
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	return &Coingecko{config: config}
+}
+
+// Quote implements Oracle.  The quote is stamped with Coingecko's
+// last_updated_at, not the time of the request.
+func (c *Coingecko) Quote(ctx context.Context, asset, currency string) (*Quote, error) {
+	id, ok := c.config.IDs[asset]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s: no Coingecko ID configured", ErrNoPrice, asset)
+	}
+	vs := strings.ToLower(currency)
+	q := url.Values{"ids": {id}, "vs_currencies": {vs}, "include_last_updated_at": {"true"}, "precision": {"full"}}
+	var header http.Header
+	if c.config.APIKey != "" {
+		header = http.Header{"X-Cg-Pro-Api-Key": {c.config.APIKey}}
+	}
+	var resp map[string]map[string]json.Number
+	if err := get(ctx, c.config.Client, "coingecko", c.config.URL+"/api/v3/simple/price?"+q.Encode(), header, &resp); err != nil {
+		return nil, err
+	}
+	fields, ok := resp[id]
+	if !ok || fields[vs] == "" {
+		return nil, fmt.Errorf("%w for %s in %s from coingecko", ErrNoPrice, asset, currency)
+	}
+	p, ok := new(big.Rat).SetString(fields[vs].String())
+	if !ok || p.Sign() <= 0 {
+		return nil, fmt.Errorf("coingecko: invalid price %q for %s", fields[vs], asset)
+	}
+	updated, err := fields["last_updated_at"].Int64()
+	if err != nil {
+		return nil, fmt.Errorf("coingecko: missing update time for %s", asset)
+	}
+	return &Quote{Asset: asset, Currency: strings.ToUpper(currency), Price: p, At: time.Unix(updated, 0).UTC(), Source: "coingecko"}, nil
+}
+
+// PythConfig contains the configuration for a Pyth oracle.
+type PythConfig struct {
+	// URL is the Hermes endpoint.  Defaults to https://hermes.pyth.network.
+	URL string
+	// Currency is the quote currency of every configured feed.  Defaults
+	// to USD.
+	Currency string
+	// Feeds maps tickers to hex price feed IDs, e.g. "SOL" to the ID of
+	// Crypto.SOL/USD.
+	Feeds map[string]string
+	// MaxConfidence rejects prices whose confidence interval is wider than
+	// this fraction of the price, e.g. 0.01 for 1%.  Zero disables the
+	// check.
+	MaxConfidence float64
+	// Client makes the requests.  Defaults to a client with a 10 second
+	// timeout.
+	Client *http.Client
+}
+
+// Pyth reads prices from a Pyth Hermes endpoint.
+type Pyth struct {
+	config PythConfig
+}
+
+// NewPyth creates a Pyth oracle from the given configuration.
+func NewPyth(config PythConfig) *Pyth {
+	if config.URL == "" {
+		config.URL = "https://hermes.pyth.network"
+	}
+	if config.Currency == "" {
+		config.Currency = "USD"
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	return &Pyth{config: config}
+}
+
+// Quote implements Oracle.  The quote is stamped with the feed's publish
+// time.
+func (p *Pyth) Quote(ctx context.Context, asset, currency string) (*Quote, error) {
+	feed, ok := p.config.Feeds[asset]
+	if !ok || !strings.EqualFold(currency, p.config.Currency) {
+		return nil, fmt.Errorf("%w for %s in %s: no Pyth feed configured", ErrNoPrice, asset, currency)
+	}
+	feed = strings.TrimPrefix(strings.ToLower(feed), "0x")
+	var resp struct {
+		Parsed []struct {
+			ID    string `json:"id"`
+			Price struct {
+				Price       string `json:"price"`
+				Conf        string `json:"conf"`
+				Expo        int    `json:"expo"`
+				PublishTime int64  `json:"publish_time"`
+			} `json:"price"`
+		} `json:"parsed"`
+	}
+	q := url.Values{"ids[]": {feed}, "parsed": {"true"}}
+	if err := get(ctx, p.config.Client, "pyth", p.config.URL+"/v2/updates/price/latest?"+q.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Parsed) != 1 || strings.TrimPrefix(strings.ToLower(resp.Parsed[0].ID), "0x") != feed {
+		return nil, fmt.Errorf("%w for %s from pyth", ErrNoPrice, asset)
+	}
+	pp := resp.Parsed[0].Price
+	mantissa, ok1 := new(big.Int).SetString(pp.Price, 10)
+	conf, ok2 := new(big.Int).SetString(pp.Conf, 10)
+	if !ok1 || !ok2 || mantissa.Sign() <= 0 || pp.Expo < -30 || pp.Expo > 30 {
+		return nil, fmt.Errorf("pyth: invalid price for %s", asset)
+	}
+	if max := p.config.MaxConfidence; max > 0 {
+		ratio, _ := new(big.Rat).SetFrac(conf, mantissa).Float64()
+		if ratio > max {
+			return nil, fmt.Errorf("pyth: confidence of %s price is %.4f%%, wider than %.4f%%", asset, ratio*100, max*100)
+		}
+	}
+	return &Quote{
+		Asset:    asset,
+		Currency: strings.ToUpper(p.config.Currency),
+		Price:    scale(mantissa, pp.Expo),
+		At:       time.Unix(pp.PublishTime, 0).UTC(),
+		Source:   "pyth",
+	}, nil
+}
+
+// scale returns mantissa·10^expo.
+func scale(mantissa *big.Int, expo int) *big.Rat {
+	f := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(expo))), nil)
+	if expo < 0 {
+		return new(big.Rat).SetFrac(mantissa, f)
+	}
+	return new(big.Rat).SetInt(new(big.Int).Mul(mantissa, f))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// get fetches a JSON document.
+func get(ctx context.Context, client *http.Client, source, u string, header http.Header, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request: %w", source, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedResponse))
+	if err != nil {
+		return fmt.Errorf("reading %s response: %w", source, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", source, resp.StatusCode)
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("decoding %s response: %w", source, err)
+	}
+	return nil
+}
+
+var (
+	_ Oracle = (*Coingecko)(nil)
+	_ Oracle = (*Pyth)(nil)
+)
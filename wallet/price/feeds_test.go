@@ -0,0 +1,74 @@
+package price
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoingecko(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/simple/price", r.URL.Path)
+		assert.Equal(t, "solana", r.URL.Query().Get("ids"))
+		assert.Equal(t, "eur", r.URL.Query().Get("vs_currencies"))
+		assert.Equal(t, "key", r.Header.Get("X-Cg-Pro-Api-Key"))
+		w.Write([]byte(`{"solana": {"eur": 168.4215, "last_updated_at": 1767225600}}`))
+	}))
+	defer srv.Close()
+	c := NewCoingecko(CoingeckoConfig{URL: srv.URL + "/", IDs: map[string]string{"SOL": "solana"}, APIKey: "key"})
+
+	q, err := c.Quote(context.Background(), "SOL", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "168.4215", q.Price.FloatString(4))
+	assert.Equal(t, "EUR", q.Currency)
+	assert.Equal(t, "coingecko", q.Source)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), q.At)
+
+	_, err = c.Quote(context.Background(), "BTC", "EUR")
+	assert.ErrorIs(t, err, ErrNoPrice)
+}
+
+func TestPyth(t *testing.T) {
+	const feed = "ef0d8b6fda2ceba41da15d4095d1da392a0d2f8ed0c6c7bc0f4cfac8c280b56d"
+	conf := "18320000"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/updates/price/latest", r.URL.Path)
+		assert.Equal(t, []string{feed}, r.URL.Query()["ids[]"])
+		w.Write([]byte(`{"parsed": [{"id": "` + feed + `", "price": {"price": "18320000000", "conf": "` + conf + `", "expo": -8, "publish_time": 1767225600}}]}`))
+	}))
+	defer srv.Close()
+	p := NewPyth(PythConfig{URL: srv.URL, Feeds: map[string]string{"SOL": "0x" + feed}, MaxConfidence: 0.01})
+
+	q, err := p.Quote(context.Background(), "SOL", "usd")
+	require.NoError(t, err)
+	assert.Equal(t, "183.20", q.Price.FloatString(2))
+	assert.Equal(t, "USD", q.Currency)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), q.At)
+
+	_, err = p.Quote(context.Background(), "SOL", "EUR")
+	assert.ErrorIs(t, err, ErrNoPrice)
+
+	// A 2% confidence interval is too wide.
+	conf = "366400000"
+	_, err = p.Quote(context.Background(), "SOL", "USD")
+	assert.ErrorContains(t, err, "confidence")
+}
+
+func TestFreshAndRecorded(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := &Quote{Asset: "SOL", Currency: "USD", At: at, Source: "pyth"}
+	assert.NoError(t, q.Fresh(at.Add(time.Minute), time.Minute))
+	assert.ErrorIs(t, q.Fresh(at.Add(time.Minute+time.Second), time.Minute), ErrStale)
+
+	r := Recorded{q}
+	got, err := r.Quote(context.Background(), "SOL", "usd")
+	require.NoError(t, err)
+	assert.Same(t, q, got)
+	_, err = r.Quote(context.Background(), "SOL", "EUR")
+	assert.ErrorIs(t, err, ErrNoPrice)
+}
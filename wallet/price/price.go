@@ -0,0 +1,114 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoPrice is returned by an Oracle that has no price for an asset.
+	ErrNoPrice = errors.New("no price")
+	// ErrStale is returned for a quote older than allowed.
+	ErrStale = errors.New("stale price")
+)
+
+// Quote is the price of one whole unit of an asset in a fiat currency.
+type Quote struct {
+	Asset    string    `json:"asset"`    // Ticker, e.g. "SOL"
+	Currency string    `json:"currency"` // ISO 4217 code, e.g. "USD"
+	Price    *big.Rat  `json:"price"`
+	At       time.Time `json:"at"` // When the price was observed
+	Source   string    `json:"source,omitempty"`
+}
+
+// Value converts amount, in smallest units of an asset with the given
+// decimals, to the quote's currency.
+func (q *Quote) Value(amount *big.Int, decimals int) *big.Rat {
+	v := new(big.Rat).SetInt(amount)
+	v.Mul(v, q.Price)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return v.Quo(v, new(big.Rat).SetInt(scale))
+}
+
+// Fresh checks that q was observed no more than maxAge before now.
+func (q *Quote) Fresh(now time.Time, maxAge time.Duration) error {
+	if age := now.Sub(q.At); age > maxAge {
+		return fmt.Errorf("%w: %s/%s from %s is %s old, limit %s", ErrStale, q.Asset, q.Currency, q.Source, age.Round(time.Second), maxAge)
+	}
+	return nil
+}
+
+// Oracle reports prices.
+type Oracle interface {
+	// Quote returns the current price of asset in currency, or an error
+	// wrapping ErrNoPrice if it has none.
+	Quote(ctx context.Context, asset, currency string) (*Quote, error)
+}
+
+// OracleFunc adapts a function to Oracle.
+type OracleFunc func(ctx context.Context, asset, currency string) (*Quote, error)
+
+// Quote calls f(ctx, asset, currency).
+func (f OracleFunc) Quote(ctx context.Context, asset, currency string) (*Quote, error) {
+	return f(ctx, asset, currency)
+}
+
+// Static serves fixed prices in one currency.
+type Static struct {
+	Currency string
+	// Prices maps tickers to the price of one whole unit.
+	Prices map[string]*big.Rat
+	// Now stamps the quotes.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// ParseStatic builds a Static from "TICKER=PRICE" entries such as
+// "SOL=183.20", as given on a command line.
+func ParseStatic(currency string, entries []string) (*Static, error) {
+	s := &Static{Currency: strings.ToUpper(currency), Prices: make(map[string]*big.Rat, len(entries))}
+	for _, e := range entries {
+		ticker, value, ok := strings.Cut(e, "=")
+		p, valid := new(big.Rat).SetString(value)
+		if !ok || ticker == "" || !valid || p.Sign() < 0 {
+			return nil, fmt.Errorf("malformed price %q", e)
+		}
+		s.Prices[ticker] = p
+	}
+	return s, nil
+}
+
+// Quote returns the configured price of asset.
+func (s *Static) Quote(_ context.Context, asset, currency string) (*Quote, error) {
+	p, ok := s.Prices[asset]
+	if !ok || !strings.EqualFold(currency, s.Currency) {
+		return nil, fmt.Errorf("%w for %s in %s", ErrNoPrice, asset, currency)
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	return &Quote{Asset: asset, Currency: s.Currency, Price: new(big.Rat).Set(p), At: now(), Source: "static"}, nil
+}
+
+var _ Oracle = (*Static)(nil)
+
+// Recorded serves exactly the quotes recorded with a session, so that every
+// party evaluates the session against the price the coordinator used rather
+// than one fetched later.
+type Recorded []*Quote
+
+// Quote returns the recorded quote of asset in currency.
+func (r Recorded) Quote(_ context.Context, asset, currency string) (*Quote, error) {
+	for _, q := range r {
+		if q != nil && q.Asset == asset && strings.EqualFold(q.Currency, currency) {
+			return q, nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %s in %s recorded", ErrNoPrice, asset, currency)
+}
+
+var _ Oracle = Recorded(nil)
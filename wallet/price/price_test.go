@@ -0,0 +1,39 @@
+package price
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/clock"
+)
+
+func TestStatic(t *testing.T) {
+	c := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	s, err := ParseStatic("usd", []string{"SOL=183.20", "ETH=3000"})
+	require.NoError(t, err)
+	s.Now = c.Now
+
+	q, err := s.Quote(context.Background(), "SOL", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", q.Currency)
+	assert.Equal(t, c.Now(), q.At)
+	assert.Equal(t, "916/5", q.Price.String())
+
+	// 0.01 SOL in lamports.
+	assert.Equal(t, "1.83200", q.Value(big.NewInt(10_000_000), 9).FloatString(5))
+
+	_, err = s.Quote(context.Background(), "BTC", "USD")
+	assert.ErrorIs(t, err, ErrNoPrice)
+	_, err = s.Quote(context.Background(), "SOL", "EUR")
+	assert.ErrorIs(t, err, ErrNoPrice)
+
+	for _, bad := range []string{"SOL", "=1", "SOL=abc", "SOL=-1"} {
+		_, err = ParseStatic("USD", []string{bad})
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,26 @@
+// Package prompt asks operators and users for PINs and passphrases, so that
+// no tool needs a hardcoded PIN or a secret on its command line.
+//
+// On a terminal, on Unix and Windows alike, input is read in raw mode and
+// echoed as one * per character; backspace, Ctrl-U (clear) and Ctrl-C
+// (cancel) work as usual.  Input piped from another program is read a line
+// at a time, which keeps scripted test runs possible.  With Pinentry set,
+// the secret is asked for by a pinentry program instead, e.g. the one
+// gpg-agent is configured with, which also works from GUI sessions:
+//
+//	p, _ := prompt.New(prompt.Config{Pinentry: "pinentry-mac"})
+//	pin, err := p.Secret(ctx, prompt.Request{
+//		Prompt:      "PIN",
+//		Description: "Choose the PIN that protects share S3.",
+//		Confirm:     true,
+//		Policy:      prompt.PIN(6),
+//	})
+//	defer clear(pin)
+//
+// A Policy rejects weak secrets before they are used: PIN refuses short
+// PINs, repeated patterns (111111, 121212), runs (123456, 987654) and the
+// most common PINs; Passphrase refuses short passphrases, few distinct
+// characters and common passwords.  Secret asks again, explaining the
+// problem, up to Attempts times.  Policies apply when a secret is chosen;
+// when it is entered to unlock, leave Policy unset.
+package prompt
@@ -0,0 +1,140 @@
+package prompt
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// gpgErrCanceled is the libgpg-error code pinentry returns when the user
+// cancels the dialog.
+const gpgErrCanceled = 99
+
+// maxAssuanLine bounds a line read from pinentry.
+const maxAssuanLine = 64 << 10
+
+// pinentryRequest is what one pinentry dialog shows.
+type pinentryRequest struct {
+	description, prompt, errorText string
+}
+
+// runPinentry starts program and asks it for one secret.
+func runPinentry(ctx context.Context, program string, req pinentryRequest) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, program)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting pinentry: %v", err)
+	}
+	secret, err := getPIN(stdout, stdin, req)
+	stdin.Close()
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		clear(secret)
+		return nil, fmt.Errorf("pinentry: %v", waitErr)
+	}
+	return secret, err
+}
+
+// getPIN runs one Assuan conversation with pinentry: it configures the
+// dialog, sends GETPIN and decodes the data lines of the answer.
+func getPIN(r io.Reader, w io.Writer, req pinentryRequest) ([]byte, error) {
+	in := bufio.NewReaderSize(r, 4096)
+	if _, err := readAssuan(in); err != nil {
+		return nil, fmt.Errorf("pinentry greeting: %w", err)
+	}
+	var commands []string
+	if tty := os.Getenv("GPG_TTY"); tty != "" {
+		commands = append(commands, "OPTION ttyname="+tty)
+	}
+	commands = append(commands, "SETTITLE cb-mpc", "SETDESC "+escapeAssuan(req.description), "SETPROMPT "+escapeAssuan(req.prompt))
+	if req.errorText != "" {
+		commands = append(commands, "SETERROR "+escapeAssuan(req.errorText))
+	}
+	for _, c := range commands {
+		if _, err := fmt.Fprintf(w, "%s\n", c); err != nil {
+			return nil, err
+		}
+		if _, err := readAssuan(in); err != nil {
+			return nil, fmt.Errorf("pinentry %s: %w", strings.Fields(c)[0], err)
+		}
+	}
+	if _, err := io.WriteString(w, "GETPIN\n"); err != nil {
+		return nil, err
+	}
+	secret, err := readAssuan(in)
+	if err != nil {
+		clear(secret)
+		return nil, err
+	}
+	io.WriteString(w, "BYE\n")
+	return secret, nil
+}
+
+// readAssuan reads the response to one command up to its OK or ERR line and
+// returns the decoded data lines.
+func readAssuan(in *bufio.Reader) ([]byte, error) {
+	var data []byte
+	for {
+		line, err := in.ReadSlice('\n')
+		if err == bufio.ErrBufferFull || len(data) > maxAssuanLine {
+			clear(data)
+			return nil, fmt.Errorf("response too long")
+		}
+		if err != nil {
+			clear(data)
+			return nil, fmt.Errorf("reading response: %v", err)
+		}
+		line = line[:len(line)-1]
+		switch {
+		case string(line) == "OK" || strings.HasPrefix(string(line), "OK "):
+			return data, nil
+		case strings.HasPrefix(string(line), "ERR "):
+			clear(data)
+			return nil, assuanError(string(line[4:]))
+		case strings.HasPrefix(string(line), "D "):
+			data = unescapeAssuan(data, line[2:])
+		}
+		// Status (S) and comment (#) lines carry nothing we need.
+		clear(line)
+	}
+}
+
+// assuanError converts "code description" from an ERR line.
+func assuanError(s string) error {
+	code, desc, _ := strings.Cut(s, " ")
+	if n, err := strconv.ParseUint(code, 10, 32); err == nil && n&0xffff == gpgErrCanceled {
+		return ErrCancelled
+	}
+	return fmt.Errorf("pinentry error %s: %s", code, desc)
+}
+
+// escapeAssuan percent-encodes the characters Assuan lines cannot carry.
+func escapeAssuan(s string) string {
+	return strings.NewReplacer("%", "%25", "\n", "%0A", "\r", "%0D").Replace(s)
+}
+
+// unescapeAssuan appends the percent-decoded data to dst.
+func unescapeAssuan(dst, data []byte) []byte {
+	for i := 0; i < len(data); i++ {
+		if data[i] == '%' && i+2 < len(data) {
+			if b, err := strconv.ParseUint(string(data[i+1:i+3]), 16, 8); err == nil {
+				dst = append(dst, byte(b))
+				i += 2
+				continue
+			}
+		}
+		dst = append(dst, data[i])
+	}
+	return dst
+}
@@ -0,0 +1,96 @@
+package prompt
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePinentry answers Assuan commands on the other end of a pipe, records
+// them and replies to GETPIN with reply.  It stops at BYE, which getPIN does
+// not wait to be answered.
+func fakePinentry(t *testing.T, reply string) (io.Reader, io.Writer, <-chan []string) {
+	t.Helper()
+	cmdR, cmdW := io.Pipe()
+	respR, respW := io.Pipe()
+	done := make(chan []string, 1)
+	go func() {
+		defer respW.Close()
+		var commands []string
+		io.WriteString(respW, "OK Pleased to meet you\n")
+		lines := bufio.NewScanner(cmdR)
+		for lines.Scan() {
+			commands = append(commands, lines.Text())
+			switch strings.Fields(lines.Text())[0] {
+			case "GETPIN":
+				io.WriteString(respW, "S PINENTRY_LAUNCHED 1\n"+reply+"\n")
+			case "BYE":
+				done <- commands
+				return
+			default:
+				io.WriteString(respW, "OK\n")
+			}
+		}
+		done <- commands
+	}()
+	t.Cleanup(func() { cmdW.Close() })
+	return respR, cmdW, done
+}
+
+func TestGetPIN(t *testing.T) {
+	r, w, done := fakePinentry(t, "D 48%2529%0A13\nOK")
+	pin, err := getPIN(r, w, pinentryRequest{description: "Unlock share S3\n100% offline", prompt: "PIN:", errorText: "wrong PIN"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("48%29\n13"), pin)
+
+	commands := <-done
+	assert.Contains(t, commands, "SETDESC Unlock share S3%0A100%25 offline")
+	assert.Contains(t, commands, "SETPROMPT PIN:")
+	assert.Contains(t, commands, "SETERROR wrong PIN")
+	assert.Equal(t, "GETPIN", commands[len(commands)-2])
+}
+
+func TestGetPINErrors(t *testing.T) {
+	r, w, _ := fakePinentry(t, "ERR 83886179 Operation cancelled <Pinentry>")
+	_, err := getPIN(r, w, pinentryRequest{prompt: "PIN:"})
+	assert.ErrorIs(t, err, ErrCancelled)
+
+	r, w, _ = fakePinentry(t, "ERR 83886254 No pinentry <Pinentry>")
+	_, err = getPIN(r, w, pinentryRequest{prompt: "PIN:"})
+	assert.ErrorContains(t, err, "No pinentry")
+	assert.NotErrorIs(t, err, ErrCancelled)
+}
+
+func TestPinentryProgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	program := filepath.Join(t.TempDir(), "pinentry")
+	require.NoError(t, os.WriteFile(program, []byte(`#!/bin/sh
+echo "OK Pleased to meet you"
+while read cmd rest; do
+	case "$cmd" in
+	GETPIN) echo "D correct horse battery staple"; echo OK ;;
+	BYE) echo OK; exit 0 ;;
+	*) echo OK ;;
+	esac
+done
+`), 0o700))
+
+	p, err := New(Config{Pinentry: program})
+	require.NoError(t, err)
+	secret, err := p.Secret(context.Background(), Request{Prompt: "Passphrase", Confirm: true, Policy: Passphrase(12)})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("correct horse battery staple"), secret)
+
+	_, err = New(Config{Pinentry: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// defaultAttempts is how often Secret asks before giving up.
+const defaultAttempts = 3
+
+// maxSecret bounds an entered secret, in bytes.
+const maxSecret = 1024
+
+var (
+	// ErrCancelled is returned when the user cancels the entry, with
+	// Ctrl-C or Ctrl-D on the terminal or the Cancel button of pinentry.
+	ErrCancelled = errors.New("prompt: cancelled")
+	// ErrAttempts is returned when every attempt was too weak or not
+	// confirmed.
+	ErrAttempts = errors.New("prompt: too many attempts")
+)
+
+// Config contains the configuration for a Prompter.
+type Config struct {
+	// In is read for secrets.  If it is a terminal, input is masked.
+	// Defaults to os.Stdin.
+	In io.Reader
+	// Out receives prompts and messages.  Defaults to os.Stderr.
+	Out io.Writer
+	// Pinentry, if set, is the path of a pinentry program (as in gpg-agent's
+	// pinentry-program) that asks for secrets instead of the terminal.
+	Pinentry string
+	// Attempts is how often Secret asks before it fails with ErrAttempts.
+	// Defaults to 3.
+	Attempts int
+}
+
+// Request describes one secret to ask for.
+type Request struct {
+	// Prompt labels the input, e.g. "PIN".
+	Prompt string
+	// Description explains what the secret is for.
+	Description string
+	// Confirm asks for the secret twice, e.g. when it is set.
+	Confirm bool
+	// Policy, if set, rejects weak secrets.  It should be set whenever
+	// the secret is chosen, not only when it is entered to unlock.
+	Policy Policy
+}
+
+// Prompter asks the user for PINs and passphrases.
+type Prompter struct {
+	in       io.Reader
+	lines    *bufio.Reader
+	out      io.Writer
+	pinentry string
+	attempts int
+}
+
+// New creates a Prompter from the given configuration.
+func New(config Config) (*Prompter, error) {
+	p := &Prompter{in: config.In, out: config.Out, attempts: config.Attempts}
+	if config.Pinentry != "" {
+		path, err := exec.LookPath(config.Pinentry)
+		if err != nil {
+			return nil, fmt.Errorf("pinentry program: %v", err)
+		}
+		p.pinentry = path
+	}
+	if p.in == nil {
+		p.in = os.Stdin
+	}
+	if p.out == nil {
+		p.out = os.Stderr
+	}
+	if p.attempts <= 0 {
+		p.attempts = defaultAttempts
+	}
+	p.lines = bufio.NewReader(p.in)
+	return p, nil
+}
+
+// Secret asks for a secret until one passes the policy and, with Confirm, is
+// entered identically twice.  The caller should clear the returned secret
+// once it is used.
+func (p *Prompter) Secret(ctx context.Context, req Request) ([]byte, error) {
+	if req.Prompt == "" {
+		req.Prompt = "Passphrase"
+	}
+	var problem error
+	for attempt := 0; attempt < p.attempts; attempt++ {
+		secret, err := p.read(ctx, req.Description, req.Prompt+": ", problem)
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) == 0 {
+			problem = errors.New("nothing entered")
+			continue
+		}
+		if req.Policy != nil {
+			if problem = req.Policy(secret); problem != nil {
+				clear(secret)
+				continue
+			}
+		}
+		if req.Confirm {
+			again, err := p.read(ctx, req.Description, "Repeat "+req.Prompt+": ", nil)
+			if err != nil {
+				clear(secret)
+				return nil, err
+			}
+			same := subtle.ConstantTimeCompare(secret, again) == 1
+			clear(again)
+			if !same {
+				clear(secret)
+				problem = errors.New("entries do not match")
+				continue
+			}
+		}
+		return secret, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrAttempts, problem)
+}
+
+// read asks for one entry, reporting problem with the previous one.
+func (p *Prompter) read(ctx context.Context, description, prompt string, problem error) ([]byte, error) {
+	if p.pinentry != "" {
+		req := pinentryRequest{description: description, prompt: prompt}
+		if problem != nil {
+			req.errorText = problem.Error()
+		}
+		return runPinentry(ctx, p.pinentry, req)
+	}
+	if problem != nil {
+		fmt.Fprintf(p.out, "%v, please try again.\n", problem)
+	}
+	if description != "" {
+		fmt.Fprintln(p.out, description)
+	}
+	fmt.Fprint(p.out, prompt)
+	if f, ok := p.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return p.readMasked(int(f.Fd()))
+	}
+	return p.readLine()
+}
+
+// readLine reads an unmasked line, for input piped from another program.
+func (p *Prompter) readLine() ([]byte, error) {
+	line, err := p.lines.ReadSlice('\n')
+	defer clear(line)
+	switch {
+	case err == bufio.ErrBufferFull || len(line) > maxSecret:
+		return nil, fmt.Errorf("input longer than %d bytes", maxSecret)
+	case err == io.EOF && len(line) == 0:
+		return nil, ErrCancelled
+	case err != nil && err != io.EOF:
+		return nil, err
+	}
+	fmt.Fprintln(p.out)
+	return bytes.Clone(bytes.TrimRight(line, "\r\n")), nil
+}
+
+// readMasked reads a line from the terminal in raw mode, echoing one * per
+// character.
+func (p *Prompter) readMasked(fd int) ([]byte, error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("setting terminal to raw mode: %v", err)
+	}
+	defer term.Restore(fd, state)
+	defer fmt.Fprint(p.out, "\r\n")
+
+	secret := make([]byte, 0, 64)
+	var b [1]byte
+	for {
+		if _, err := p.in.Read(b[:]); err != nil {
+			clear(secret)
+			return nil, err
+		}
+		switch c := b[0]; c {
+		case '\r', '\n':
+			return secret, nil
+		case 3: // Ctrl-C
+			clear(secret)
+			return nil, ErrCancelled
+		case 4: // Ctrl-D
+			if len(secret) == 0 {
+				return nil, ErrCancelled
+			}
+		case 8, 127: // Backspace
+			if len(secret) > 0 {
+				_, size := utf8.DecodeLastRune(secret)
+				clear(secret[len(secret)-size:])
+				secret = secret[:len(secret)-size]
+				fmt.Fprint(p.out, "\b \b")
+			}
+		case 21: // Ctrl-U
+			for range utf8.RuneCount(secret) {
+				fmt.Fprint(p.out, "\b \b")
+			}
+			clear(secret)
+			secret = secret[:0]
+		default:
+			if c < ' ' || len(secret) >= maxSecret {
+				continue
+			}
+			if cap(secret) == len(secret) {
+				grown := make([]byte, len(secret), 2*cap(secret))
+				copy(grown, secret)
+				clear(secret)
+				secret = grown
+			}
+			secret = append(secret, c)
+			if utf8.RuneStart(c) {
+				fmt.Fprint(p.out, "*")
+			}
+		}
+	}
+}
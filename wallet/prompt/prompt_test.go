@@ -0,0 +1,82 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrompter(t *testing.T, input string) (*Prompter, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	p, err := New(Config{In: strings.NewReader(input), Out: &out})
+	require.NoError(t, err)
+	return p, &out
+}
+
+func TestSecretConfirm(t *testing.T) {
+	ctx := context.Background()
+	p, out := newPrompter(t, "482913\n482931\n482913\n482913\n")
+	pin, err := p.Secret(ctx, Request{Prompt: "PIN", Description: "Choose the PIN of share S3.", Confirm: true, Policy: PIN(6)})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("482913"), pin)
+	assert.Contains(t, out.String(), "Repeat PIN: ")
+	assert.Contains(t, out.String(), "entries do not match, please try again.")
+	assert.NotContains(t, out.String(), "482913", "secrets are never echoed")
+}
+
+func TestSecretPolicy(t *testing.T) {
+	ctx := context.Background()
+	p, out := newPrompter(t, "123456\n111111\n\r\n")
+	_, err := p.Secret(ctx, Request{Prompt: "PIN", Policy: PIN(6)})
+	assert.ErrorIs(t, err, ErrAttempts)
+	assert.Contains(t, out.String(), "too weak: is a run of consecutive digits")
+	assert.Contains(t, out.String(), "too weak: repeats a pattern")
+
+	p, _ = newPrompter(t, "1234\n12345\n1234")
+	_, err = p.Secret(ctx, Request{Prompt: "PIN", Policy: PIN(6)})
+	assert.ErrorIs(t, err, ErrAttempts)
+
+	p, _ = newPrompter(t, "")
+	_, err = p.Secret(ctx, Request{})
+	assert.ErrorIs(t, err, ErrCancelled)
+}
+
+func TestPolicies(t *testing.T) {
+	for pin, ok := range map[string]bool{
+		"482913":   true,
+		"90817263": true,
+		"48291":    false, // Too short
+		"48291a":   false,
+		"000000":   false,
+		"121212":   false,
+		"345345":   false,
+		"901234":   false,
+		"654321":   false,
+		"112233":   false,
+	} {
+		err := PIN(6)([]byte(pin))
+		if ok {
+			assert.NoError(t, err, pin)
+		} else {
+			var weak *WeakError
+			assert.ErrorAs(t, err, &weak, pin)
+		}
+	}
+	for phrase, ok := range map[string]bool{
+		"correct horse battery staple": true,
+		"vingt-trois éléphants":        true,
+		"short":                        false,
+		"Password!!!!":                 false,
+		"abababababab":                 false,
+		"aaaabbbbcccc":                 false,
+		"\xff\xfe invalid utf-8 text":  false,
+	} {
+		err := Passphrase(12)([]byte(phrase))
+		assert.Equal(t, ok, err == nil, "%q: %v", phrase, err)
+	}
+}
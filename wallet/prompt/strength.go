@@ -0,0 +1,122 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Policy checks the strength of an entered secret and returns a *WeakError
+// if it is too weak.
+type Policy func(secret []byte) error
+
+// WeakError reports a secret rejected by a Policy.
+type WeakError struct {
+	Reason string
+}
+
+func (e *WeakError) Error() string { return "too weak: " + e.Reason }
+
+func weak(format string, args ...any) error {
+	return &WeakError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// commonPINs are frequently chosen PINs that the pattern checks miss.
+var commonPINs = map[string]bool{
+	"1212": true, "6969": true, "1004": true, "2580": true, "0852": true,
+	"112233": true, "159753": true, "147258": true, "789456": true,
+	"102030": true, "696969": true, "520520": true, "131313": true,
+}
+
+// commonPassphrases are frequently chosen passphrases, compared after
+// dropping everything but letters and lowering the case.
+var commonPassphrases = map[string]bool{
+	"password": true, "passphrase": true, "letmein": true, "qwerty": true,
+	"qwertyuiop": true, "iloveyou": true, "welcome": true, "admin": true,
+	"changeme": true, "secret": true, "trustno": true, "abc": true,
+}
+
+// PIN accepts numeric PINs of at least minDigits digits that are not a
+// repeated pattern such as 111111 or 121212, a run such as 123456 or
+// 987654, or one of the most common PINs.
+func PIN(minDigits int) Policy {
+	return func(secret []byte) error {
+		s := string(secret)
+		for _, r := range s {
+			if r < '0' || r > '9' {
+				return weak("a PIN must consist of digits")
+			}
+		}
+		switch {
+		case len(s) < minDigits:
+			return weak("a PIN needs at least %d digits", minDigits)
+		case repeated(s):
+			return weak("repeats a pattern")
+		case run(s):
+			return weak("is a run of consecutive digits")
+		case commonPINs[s]:
+			return weak("is one of the most common PINs")
+		}
+		return nil
+	}
+}
+
+// Passphrase accepts passphrases of at least minLength characters with at
+// least five different characters that are not a repeated pattern or a
+// common password.
+func Passphrase(minLength int) Policy {
+	return func(secret []byte) error {
+		if !utf8.Valid(secret) {
+			return weak("is not valid UTF-8")
+		}
+		s := string(secret)
+		distinct := make(map[rune]bool)
+		for _, r := range s {
+			distinct[r] = true
+		}
+		letters := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, s)
+		switch {
+		case utf8.RuneCountInString(s) < minLength:
+			return weak("a passphrase needs at least %d characters", minLength)
+		case len(distinct) < 5:
+			return weak("uses fewer than 5 different characters")
+		case repeated(s):
+			return weak("repeats a pattern")
+		case commonPassphrases[letters]:
+			return weak("is a common password")
+		}
+		return nil
+	}
+}
+
+// repeated reports whether s consists of a shorter string repeated, e.g.
+// "1111" or "abcabc".
+func repeated(s string) bool {
+	for p := 1; p <= len(s)/2; p++ {
+		if len(s)%p == 0 && strings.Repeat(s[:p], len(s)/p) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// run reports whether the digits of s ascend or descend by one, wrapping
+// from 9 to 0.
+func run(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	up, down := true, true
+	for i := 1; i < len(s); i++ {
+		d := (int(s[i]) - int(s[i-1]) + 10) % 10
+		up = up && d == 1
+		down = down && d == 9
+	}
+	return up || down
+}
@@ -0,0 +1,151 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ClientConfig contains the configuration for a Client.
+type ClientConfig struct {
+	// URL is the base URL of the provisioning API.  Required.
+	URL string
+	// Token is sent as a bearer token, if set.
+	Token string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Client calls the provisioning API.  Unsuccessful responses are returned
+// as a *StatusError, which matches ErrNotFound and ErrConflict.
+type Client struct {
+	config ClientConfig
+}
+
+// NewClient creates a Client from the given configuration.
+func NewClient(config ClientConfig) (*Client, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("URL must be provided")
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &Client{config: config}, nil
+}
+
+// ListKeys returns all keys.
+func (c *Client) ListKeys(ctx context.Context) ([]*Key, error) {
+	var resp ListKeysResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/keys", "", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// TenantKeys returns the keys of tenant.
+func (c *Client) TenantKeys(ctx context.Context, tenant string) ([]*Key, error) {
+	var resp ListKeysResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/keys?tenant="+url.QueryEscape(tenant), "", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// Key returns key id.
+func (c *Client) Key(ctx context.Context, id string) (*Key, error) {
+	k := new(Key)
+	return k, c.do(ctx, http.MethodGet, keyPath(id), "", nil, k)
+}
+
+// CreateKey ensures that key id exists as described by req.
+func (c *Client) CreateKey(ctx context.Context, id string, req *CreateKeyRequest) (*Key, error) {
+	k := new(Key)
+	return k, c.do(ctx, http.MethodPut, keyPath(id), "", req, k)
+}
+
+// SetPolicy ensures that key id has the policy document doc.
+func (c *Client) SetPolicy(ctx context.Context, id string, doc []byte) (*Key, error) {
+	k := new(Key)
+	return k, c.do(ctx, http.MethodPut, keyPath(id)+"/policy", "", json.RawMessage(doc), k)
+}
+
+// SetFrozen freezes or unfreezes key id.
+func (c *Client) SetFrozen(ctx context.Context, id string, req *FreezeRequest) (*Key, error) {
+	k := new(Key)
+	return k, c.do(ctx, http.MethodPut, keyPath(id)+"/frozen", "", req, k)
+}
+
+// RotateKey refreshes the shares of key id.  Retries with the same
+// idempotencyKey rotate at most once.
+func (c *Client) RotateKey(ctx context.Context, id, idempotencyKey string) (*Key, error) {
+	if idempotencyKey == "" {
+		return nil, fmt.Errorf("idempotency key must be provided")
+	}
+	k := new(Key)
+	return k, c.do(ctx, http.MethodPost, keyPath(id)+"/rotate", idempotencyKey, nil, k)
+}
+
+func keyPath(id string) string {
+	return "/v1/keys/" + url.PathEscape(id)
+}
+
+// do sends a JSON request and decodes the JSON response into out.
+func (c *Client) do(ctx context.Context, method, path, idempotencyKey string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.config.URL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return &StatusError{Status: resp.StatusCode, Message: e.Error}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StatusError is returned by a Client for unsuccessful responses.
+type StatusError struct {
+	Status  int    // HTTP status code
+	Message string // Error reported by the server
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("provisioning API returned %d: %s", e.Status, e.Message)
+}
+
+// Is makes errors.Is match ErrNotFound and ErrConflict by status code.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotFound && e.Status == http.StatusNotFound ||
+		target == ErrConflict && e.Status == http.StatusConflict
+}
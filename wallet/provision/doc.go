@@ -0,0 +1,36 @@
+// Package provision exposes an admin API for managing MPC wallet keys and
+// their policies declaratively, e.g. from infrastructure-as-code tooling.
+//
+// Endpoints (JSON bodies, see the request and response types):
+//
+//	GET  /v1/keys[?tenant=…]     → ListKeysResponse
+//	GET  /v1/keys/{id}           → Key
+//	PUT  /v1/keys/{id}           CreateKeyRequest → Key
+//	PUT  /v1/keys/{id}/policy    policy document  → Key
+//	PUT  /v1/keys/{id}/frozen    FreezeRequest    → Key
+//	POST /v1/keys/{id}/rotate    (Idempotency-Key required) → Key
+//
+// The PUT endpoints describe the desired state and may be repeated freely:
+// creating a key that already exists with the same curve and access
+// structure, or setting the policy it already has, changes nothing.  A key's
+// curve and access structure cannot change once it is created; a PUT asking
+// for different ones fails with 409 Conflict.  The same holds for the tenant,
+// the customer a key is metered and billed to; GET /v1/keys?tenant=… lists
+// the keys of one tenant.  With Config.Tenant set, callers that belong to a
+// tenant only ever see, create and manage that tenant's keys; other keys do
+// not exist for them.  Policy documents are the policy package's rules
+// and are validated before they are stored.
+//
+// Rotation refreshes the shares of a key without changing it, so unlike the
+// other operations it is not naturally idempotent.  It requires an
+// Idempotency-Key header, and so may any other mutating request: a request
+// repeated with the same key and body gets the recorded response instead of
+// being executed again, and reusing a key for a different request fails with
+// 409 Conflict.
+//
+// The Server keeps its state in a keystore.Medium and delegates key
+// generation and rotation to a Backend, such as the party hosts' ceremony
+// tooling.  Signing services consult `Server.Check` so that frozen keys stop
+// signing, and feed a key's policy to a policy.Engine with
+// `Server.PolicySource`.  `Client` is a typed Go client for the API.
+package provision
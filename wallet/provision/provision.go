@@ -0,0 +1,587 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+
+	"solana-threshold-wallet/wallet/policy"
+	"solana-threshold-wallet/wallet/tenant"
+)
+
+const defaultMaxBodyBytes = 1 << 20
+
+var (
+	// ErrNotFound is returned for keys that do not exist.
+	ErrNotFound = errors.New("provision: key not found")
+	// ErrConflict is returned when a request contradicts the existing state
+	// or reuses an idempotency key for a different request.
+	ErrConflict = errors.New("provision: conflict")
+	// ErrFrozen is returned by Check for frozen keys.
+	ErrFrozen = errors.New("provision: key is frozen")
+)
+
+// validID restricts key IDs to names that are safe in URLs and state names.
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// AccessStructure is the quorum policy of a key: any Threshold of Parties
+// can sign.
+type AccessStructure struct {
+	Parties   []string `json:"parties"`
+	Threshold int      `json:"threshold"`
+}
+
+// KeySpec is the immutable description of a key.
+type KeySpec struct {
+	ID     string          `json:"id"`
+	Curve  string          `json:"curve"`
+	Access AccessStructure `json:"access"`
+	// Tenant is the customer the key belongs to, for metering and billing.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// validate checks the spec for consistency.
+func (s *KeySpec) validate() error {
+	if !validID.MatchString(s.ID) {
+		return fmt.Errorf("invalid key ID %q", s.ID)
+	}
+	if s.Curve != "ed25519" && s.Curve != "secp256k1" {
+		return fmt.Errorf("unsupported curve %q", s.Curve)
+	}
+	if s.Tenant != "" && !tenant.Valid(s.Tenant) {
+		return fmt.Errorf("invalid tenant %q", s.Tenant)
+	}
+	seen := make(map[string]bool, len(s.Access.Parties))
+	for _, p := range s.Access.Parties {
+		if p == "" || seen[p] {
+			return fmt.Errorf("party names must be unique and non-empty")
+		}
+		seen[p] = true
+	}
+	if n := len(s.Access.Parties); n < 2 || s.Access.Threshold < 1 || s.Access.Threshold > n {
+		return fmt.Errorf("threshold %d of %d parties is not a valid access structure", s.Access.Threshold, n)
+	}
+	return nil
+}
+
+// KeyState is the lifecycle stage of a key.
+type KeyState string
+
+const (
+	// KeyCreating means the Backend has not yet confirmed the key.
+	KeyCreating KeyState = "creating"
+	// KeyActive means the key may sign.
+	KeyActive KeyState = "active"
+	// KeyFrozen means the key must not sign until it is unfrozen.
+	KeyFrozen KeyState = "frozen"
+)
+
+// Key is the provisioned state of a key.
+type Key struct {
+	KeySpec
+	State     KeyState `json:"state"`
+	PublicKey []byte   `json:"public_key,omitempty"`
+	// Generation counts the rotations of the key's shares.
+	Generation   int    `json:"generation"`
+	FrozenReason string `json:"frozen_reason,omitempty"`
+	// PolicyVersion is the version of the key's policy document, if any.
+	PolicyVersion string    `json:"policy_version,omitempty"`
+	PolicyDigest  []byte    `json:"policy_digest,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UpdatedBy     string    `json:"updated_by"`
+}
+
+// CreateKeyRequest is the body of PUT /v1/keys/{id}.
+type CreateKeyRequest struct {
+	Curve  string          `json:"curve"`
+	Access AccessStructure `json:"access"`
+	Tenant string          `json:"tenant,omitempty"`
+}
+
+// FreezeRequest is the body of PUT /v1/keys/{id}/frozen.
+type FreezeRequest struct {
+	Frozen bool   `json:"frozen"`
+	Reason string `json:"reason,omitempty"` // Mandatory when freezing
+}
+
+// ListKeysResponse is the body returned by GET /v1/keys.
+type ListKeysResponse struct {
+	Keys []*Key `json:"keys"`
+}
+
+// Backend generates and rotates keys, e.g. by running the DKG and refresh
+// protocols on the party hosts.
+type Backend interface {
+	// CreateKey generates the key described by spec and returns its public
+	// key.  It must be idempotent in spec.ID: after a crash the Server calls
+	// it again for a key it has not recorded as created.
+	CreateKey(ctx context.Context, spec KeySpec) ([]byte, error)
+	// RotateKey refreshes the shares of key without changing its public key.
+	RotateKey(ctx context.Context, key *Key) error
+}
+
+// Config contains the configuration for a Server.
+type Config struct {
+	// State holds keys, policies and idempotency records.  Required.
+	State keystore.Medium
+	// Backend creates and rotates keys.  Required.
+	Backend Backend
+	// Authenticate identifies the caller of an HTTP request, e.g.
+	// remotesigner.BearerTokens.  Required.
+	Authenticate func(*http.Request) (string, error)
+	// Tenant, if set, returns the tenant a caller is confined to, e.g.
+	// tenant.Registry.TenantOf.  Such a caller only sees and manages keys of
+	// its tenant, and keys it creates belong to it.  Callers without a
+	// tenant manage all keys.
+	Tenant func(caller string) string
+	// MaxBodyBytes limits request bodies.  Defaults to 1 MiB.
+	MaxBodyBytes int64
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Server is an http.Handler serving the provisioning API.
+type Server struct {
+	config Config
+	mux    *http.ServeMux
+
+	// mu serializes all mutations, so that a key's state, its entry in the
+	// index and idempotency records are updated together.
+	mu sync.Mutex
+}
+
+// Ensure Server implements the http.Handler interface
+var _ http.Handler = (*Server)(nil)
+
+// New creates a Server from the given configuration.
+func New(config Config) (*Server, error) {
+	if config.State == nil || config.Backend == nil || config.Authenticate == nil {
+		return nil, fmt.Errorf("state, backend and authenticate must be provided")
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	s := &Server{config: config, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /v1/keys", s.handle(s.listKeys))
+	s.mux.HandleFunc("GET /v1/keys/{id}", s.handle(s.getKey))
+	s.mux.HandleFunc("PUT /v1/keys/{id}", s.handle(s.createKey))
+	s.mux.HandleFunc("PUT /v1/keys/{id}/policy", s.handle(s.setPolicy))
+	s.mux.HandleFunc("PUT /v1/keys/{id}/frozen", s.handle(s.setFrozen))
+	s.mux.HandleFunc("POST /v1/keys/{id}/rotate", s.handle(s.rotateKey))
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Check returns nil if key id exists and may sign, ErrFrozen if it is frozen
+// and ErrNotFound if it was never created.
+func (s *Server) Check(ctx context.Context, id string) error {
+	k, err := s.key(ctx, id)
+	if err != nil {
+		return err
+	}
+	switch k.State {
+	case KeyActive:
+		return nil
+	case KeyFrozen:
+		return fmt.Errorf("%w: %s", ErrFrozen, k.FrozenReason)
+	default:
+		return fmt.Errorf("key %s is still being created", id)
+	}
+}
+
+// PolicySource returns a policy.Config.Source reading the policy document of
+// key id, so that a policy.Engine picks up policy changes on Reload.
+func (s *Server) PolicySource(id string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		data, err := s.config.State.Load(context.Background(), policyName(id))
+		if errors.Is(err, keystore.ErrNotFound) {
+			return nil, fmt.Errorf("key %s has no policy", id)
+		}
+		return data, err
+	}
+}
+
+// request is a decoded API call.
+type request struct {
+	caller string
+	id     string // Key ID from the path, if any
+	tenant string // Tenant query parameter, if any
+	scope  string // Tenant the caller is confined to, if any
+	body   []byte
+}
+
+// response is a handler's result.
+type response struct {
+	status int
+	body   any
+}
+
+// idempotencyRecord is the stored outcome of a request with an
+// Idempotency-Key.
+type idempotencyRecord struct {
+	Request []byte          `json:"request"` // SHA-256 of method, path and body
+	Status  int             `json:"status"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// handle authenticates the caller, applies idempotency keys and encodes the
+// handler's response or error.
+func (s *Server) handle(fn func(ctx context.Context, req *request) (*response, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, body := s.serve(w, r, fn)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, req *request) (*response, error)) (int, []byte) {
+	ctx := r.Context()
+	caller, err := s.config.Authenticate(r)
+	if err != nil {
+		return errorBody(http.StatusUnauthorized, err)
+	}
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes)); err != nil {
+		return errorBody(http.StatusBadRequest, fmt.Errorf("reading body: %v", err))
+	}
+	req := &request{caller: caller, id: r.PathValue("id"), tenant: r.URL.Query().Get("tenant"), body: body.Bytes()}
+	if req.id != "" && !validID.MatchString(req.id) {
+		return errorBody(http.StatusBadRequest, fmt.Errorf("invalid key ID %q", req.id))
+	}
+	if s.config.Tenant != nil {
+		req.scope = s.config.Tenant(caller)
+	}
+
+	idemKey := r.Header.Get("Idempotency-Key")
+	if r.Method == http.MethodGet || idemKey == "" {
+		if r.Method == http.MethodPost && idemKey == "" {
+			return errorBody(http.StatusBadRequest, fmt.Errorf("header Idempotency-Key must be provided"))
+		}
+		return s.run(ctx, req, fn)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.Path)
+	h.Write(req.body)
+	digest := h.Sum(nil)
+	name := idempotencyName(caller, idemKey)
+	if data, err := s.config.State.Load(ctx, name); err == nil {
+		var rec idempotencyRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return errorBody(http.StatusInternalServerError, fmt.Errorf("decoding idempotency record: %v", err))
+		}
+		if !bytes.Equal(rec.Request, digest) {
+			return errorBody(http.StatusConflict, fmt.Errorf("%w: idempotency key reused for a different request", ErrConflict))
+		}
+		return rec.Status, rec.Body
+	} else if !errors.Is(err, keystore.ErrNotFound) {
+		return errorBody(http.StatusInternalServerError, err)
+	}
+
+	status, out := s.runLocked(ctx, req, fn)
+	if status >= http.StatusInternalServerError {
+		// Server errors may be transient; let the client retry.
+		return status, out
+	}
+	data, err := json.Marshal(&idempotencyRecord{Request: digest, Status: status, Body: out})
+	if err == nil {
+		err = s.config.State.Store(ctx, name, data)
+	}
+	if err != nil {
+		return errorBody(http.StatusInternalServerError, fmt.Errorf("recording idempotency key: %v", err))
+	}
+	return status, out
+}
+
+// run executes fn, holding the lock for mutations.
+func (s *Server) run(ctx context.Context, req *request, fn func(ctx context.Context, req *request) (*response, error)) (int, []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runLocked(ctx, req, fn)
+}
+
+func (s *Server) runLocked(ctx context.Context, req *request, fn func(ctx context.Context, req *request) (*response, error)) (int, []byte) {
+	resp, err := fn(ctx, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var herr *httpError
+		switch {
+		case errors.As(err, &herr):
+			status = herr.status
+		case errors.Is(err, ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrConflict):
+			status = http.StatusConflict
+		}
+		return errorBody(status, err)
+	}
+	data, err := json.Marshal(resp.body)
+	if err != nil {
+		return errorBody(http.StatusInternalServerError, err)
+	}
+	return resp.status, append(data, '\n')
+}
+
+func errorBody(status int, err error) (int, []byte) {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return status, append(data, '\n')
+}
+
+// httpError carries the status code of a failed request.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func badRequest(format string, args ...any) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func keyName(id string) string    { return "key." + id }
+func policyName(id string) string { return "policy." + id }
+
+const indexName = "keys"
+
+// idempotencyName is where the record of an idempotency key is kept.  Keys
+// are scoped to the caller.
+func idempotencyName(caller, key string) string {
+	sum := sha256.Sum256([]byte(caller + "\x00" + key))
+	return "idem." + hex.EncodeToString(sum[:])
+}
+
+// key loads the state of key id.
+func (s *Server) key(ctx context.Context, id string) (*Key, error) {
+	data, err := s.config.State.Load(ctx, keyName(id))
+	if errors.Is(err, keystore.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	k := new(Key)
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, fmt.Errorf("decoding key %s: %v", id, err)
+	}
+	return k, nil
+}
+
+// keyFor returns the key of req, hiding keys of other tenants from callers
+// confined to a tenant.
+func (s *Server) keyFor(ctx context.Context, req *request) (*Key, error) {
+	k, err := s.key(ctx, req.id)
+	if err == nil && req.scope != "" && k.Tenant != req.scope {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, req.id)
+	}
+	return k, err
+}
+
+// save stores k, stamped with the caller and time of the change.
+func (s *Server) save(ctx context.Context, k *Key, caller string) error {
+	k.UpdatedAt, k.UpdatedBy = s.config.Now().UTC(), caller
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return s.config.State.Store(ctx, keyName(k.ID), data)
+}
+
+// index returns the IDs of all keys.
+func (s *Server) index(ctx context.Context) ([]string, error) {
+	data, err := s.config.State.Load(ctx, indexName)
+	if errors.Is(err, keystore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("decoding key index: %v", err)
+	}
+	return ids, nil
+}
+
+func (s *Server) listKeys(ctx context.Context, req *request) (*response, error) {
+	ids, err := s.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(ids))
+	for _, id := range ids {
+		k, err := s.key(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if (req.tenant != "" && k.Tenant != req.tenant) || (req.scope != "" && k.Tenant != req.scope) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return &response{status: http.StatusOK, body: &ListKeysResponse{Keys: keys}}, nil
+}
+
+func (s *Server) getKey(ctx context.Context, req *request) (*response, error) {
+	k, err := s.keyFor(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: k}, nil
+}
+
+func (s *Server) createKey(ctx context.Context, req *request) (*response, error) {
+	var body CreateKeyRequest
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		return nil, badRequest("decoding request: %v", err)
+	}
+	spec := KeySpec{ID: req.id, Curve: body.Curve, Access: body.Access, Tenant: body.Tenant}
+	if req.scope != "" {
+		if spec.Tenant != "" && spec.Tenant != req.scope {
+			return nil, &httpError{status: http.StatusForbidden, err: fmt.Errorf("caller may only create keys of tenant %s", req.scope)}
+		}
+		spec.Tenant = req.scope
+	}
+	if err := spec.validate(); err != nil {
+		return nil, badRequest("%v", err)
+	}
+
+	k, err := s.key(ctx, req.id)
+	switch {
+	case err == nil && req.scope != "" && k.Tenant != req.scope:
+		// Key IDs are global; say that the ID is taken, not by whom.
+		return nil, fmt.Errorf("%w: key ID %s is taken", ErrConflict, spec.ID)
+	case errors.Is(err, ErrNotFound):
+		// Record the key before generating it, so that it is listed and a
+		// retry after a crash finishes the creation.
+		now := s.config.Now().UTC()
+		k = &Key{KeySpec: spec, State: KeyCreating, CreatedAt: now}
+		if err := s.save(ctx, k, req.caller); err != nil {
+			return nil, err
+		}
+		ids, err := s.index(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(append(ids, spec.ID))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.config.State.Store(ctx, indexName, data); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case !sameSpec(&k.KeySpec, &spec):
+		return nil, fmt.Errorf("%w: key %s exists with a different curve, access structure or tenant", ErrConflict, spec.ID)
+	case k.State != KeyCreating:
+		return &response{status: http.StatusOK, body: k}, nil
+	}
+
+	pub, err := s.config.Backend.CreateKey(ctx, spec)
+	if err != nil {
+		return nil, fmt.Errorf("creating key %s: %w", spec.ID, err)
+	}
+	k.PublicKey, k.State = pub, KeyActive
+	if err := s.save(ctx, k, req.caller); err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusCreated, body: k}, nil
+}
+
+func sameSpec(a, b *KeySpec) bool {
+	return a.ID == b.ID && a.Curve == b.Curve && a.Tenant == b.Tenant && a.Access.Threshold == b.Access.Threshold &&
+		slices.Equal(a.Access.Parties, b.Access.Parties)
+}
+
+func (s *Server) setPolicy(ctx context.Context, req *request) (*response, error) {
+	rules, err := policy.Parse(req.body)
+	if err != nil {
+		return nil, badRequest("invalid policy: %v", err)
+	}
+	k, err := s.keyFor(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(req.body)
+	if bytes.Equal(k.PolicyDigest, digest[:]) {
+		return &response{status: http.StatusOK, body: k}, nil
+	}
+	if err := s.config.State.Store(ctx, policyName(req.id), req.body); err != nil {
+		return nil, err
+	}
+	k.PolicyVersion, k.PolicyDigest = rules.Version, digest[:]
+	if err := s.save(ctx, k, req.caller); err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: k}, nil
+}
+
+func (s *Server) setFrozen(ctx context.Context, req *request) (*response, error) {
+	var body FreezeRequest
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		return nil, badRequest("decoding request: %v", err)
+	}
+	if body.Frozen && body.Reason == "" {
+		return nil, badRequest("a reason must be given for freezing a key")
+	}
+	k, err := s.keyFor(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if k.State == KeyCreating {
+		return nil, fmt.Errorf("%w: key %s is still being created", ErrConflict, req.id)
+	}
+	want, reason := KeyActive, ""
+	if body.Frozen {
+		want, reason = KeyFrozen, body.Reason
+	}
+	if k.State == want && k.FrozenReason == reason {
+		return &response{status: http.StatusOK, body: k}, nil
+	}
+	k.State, k.FrozenReason = want, reason
+	if err := s.save(ctx, k, req.caller); err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: k}, nil
+}
+
+func (s *Server) rotateKey(ctx context.Context, req *request) (*response, error) {
+	k, err := s.keyFor(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if k.State == KeyCreating {
+		return nil, fmt.Errorf("%w: key %s is still being created", ErrConflict, req.id)
+	}
+	if err := s.config.Backend.RotateKey(ctx, k); err != nil {
+		return nil, fmt.Errorf("rotating key %s: %w", req.id, err)
+	}
+	k.Generation++
+	if err := s.save(ctx, k, req.caller); err != nil {
+		return nil, err
+	}
+	return &response{status: http.StatusOK, body: k}, nil
+}
@@ -0,0 +1,228 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+// fakeBackend counts the keys it creates and rotates.
+type fakeBackend struct {
+	mu      sync.Mutex
+	created map[string]int
+	rotated map[string]int
+}
+
+func (b *fakeBackend) CreateKey(_ context.Context, spec KeySpec) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.created[spec.ID]++
+	return []byte("pub:" + spec.ID), nil
+}
+
+func (b *fakeBackend) RotateKey(_ context.Context, key *Key) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rotated[key.ID]++
+	return nil
+}
+
+func newTestServer(t *testing.T) (*Server, *Client, *fakeBackend, string) {
+	t.Helper()
+	state, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	backend := &fakeBackend{created: map[string]int{}, rotated: map[string]int{}}
+	s, err := New(Config{
+		State:        state,
+		Backend:      backend,
+		Authenticate: remotesigner.BearerTokens(map[string]string{"secret": "terraform"}),
+	})
+	require.NoError(t, err)
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(ClientConfig{URL: srv.URL, Token: "secret"})
+	require.NoError(t, err)
+	return s, c, backend, srv.URL
+}
+
+var treasury = &CreateKeyRequest{Curve: "ed25519", Access: AccessStructure{Parties: []string{"server", "kms", "pin"}, Threshold: 2}}
+
+func TestCreateKeyIsDeclarative(t *testing.T) {
+	ctx := context.Background()
+	_, c, backend, _ := newTestServer(t)
+
+	k, err := c.CreateKey(ctx, "treasury", treasury)
+	require.NoError(t, err)
+	assert.Equal(t, KeyActive, k.State)
+	assert.Equal(t, []byte("pub:treasury"), k.PublicKey)
+	assert.Equal(t, "terraform", k.UpdatedBy)
+
+	again, err := c.CreateKey(ctx, "treasury", treasury)
+	require.NoError(t, err)
+	assert.Equal(t, k, again)
+	assert.Equal(t, 1, backend.created["treasury"])
+
+	changed := *treasury
+	changed.Access.Threshold = 3
+	_, err = c.CreateKey(ctx, "treasury", &changed)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	_, err = c.CreateKey(ctx, "bad", &CreateKeyRequest{Curve: "ed25519", Access: AccessStructure{Parties: []string{"a", "a"}, Threshold: 1}})
+	var serr *StatusError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, http.StatusBadRequest, serr.Status)
+
+	retenanted := *treasury
+	retenanted.Tenant = "acme"
+	_, err = c.CreateKey(ctx, "treasury", &retenanted)
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = c.CreateKey(ctx, "acme-hot", &retenanted)
+	require.NoError(t, err)
+
+	keys, err := c.ListKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "treasury", keys[0].ID)
+	keys, err = c.TenantKeys(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "acme-hot", keys[0].ID)
+	_, err = c.Key(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPolicyAndFreeze(t *testing.T) {
+	ctx := context.Background()
+	s, c, _, _ := newTestServer(t)
+	_, err := c.CreateKey(ctx, "treasury", treasury)
+	require.NoError(t, err)
+	require.NoError(t, s.Check(ctx, "treasury"))
+
+	_, err = c.SetPolicy(ctx, "treasury", []byte(`{"version": "v1", "rules": []}`))
+	assert.Error(t, err)
+	doc := []byte(`{"version": "v1", "rules": [{"name": "all", "approvals": 2}]}`)
+	k, err := c.SetPolicy(ctx, "treasury", doc)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", k.PolicyVersion)
+	got, err := s.PolicySource("treasury")()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(doc), string(got))
+
+	_, err = c.SetFrozen(ctx, "treasury", &FreezeRequest{Frozen: true})
+	assert.Error(t, err, "freezing requires a reason")
+	k, err = c.SetFrozen(ctx, "treasury", &FreezeRequest{Frozen: true, Reason: "incident 42"})
+	require.NoError(t, err)
+	assert.Equal(t, KeyFrozen, k.State)
+	assert.ErrorIs(t, s.Check(ctx, "treasury"), ErrFrozen)
+
+	_, err = c.SetFrozen(ctx, "treasury", &FreezeRequest{Frozen: false})
+	require.NoError(t, err)
+	assert.NoError(t, s.Check(ctx, "treasury"))
+	assert.ErrorIs(t, s.Check(ctx, "other"), ErrNotFound)
+}
+
+func TestRotateIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	_, c, backend, url := newTestServer(t)
+	for _, id := range []string{"treasury", "payouts"} {
+		_, err := c.CreateKey(ctx, id, treasury)
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		k, err := c.RotateKey(ctx, "treasury", "rotate-1")
+		require.NoError(t, err)
+		assert.Equal(t, 1, k.Generation)
+	}
+	assert.Equal(t, 1, backend.rotated["treasury"])
+
+	k, err := c.RotateKey(ctx, "treasury", "rotate-2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, k.Generation)
+
+	_, err = c.RotateKey(ctx, "payouts", "rotate-1")
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Zero(t, backend.rotated["payouts"])
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v1/keys/treasury/rotate", url), nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestTenantCallersAreConfined(t *testing.T) {
+	ctx := context.Background()
+	state, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	s, err := New(Config{
+		State:   state,
+		Backend: &fakeBackend{created: map[string]int{}, rotated: map[string]int{}},
+		Authenticate: remotesigner.BearerTokens(map[string]string{
+			"secret": "operator", "acme-token": "acme/terraform", "globex-token": "globex/terraform",
+		}),
+		Tenant: func(caller string) string {
+			tenant, _, _ := strings.Cut(caller, "/")
+			if tenant == caller {
+				return ""
+			}
+			return tenant
+		},
+	})
+	require.NoError(t, err)
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	client := func(token string) *Client {
+		c, err := NewClient(ClientConfig{URL: srv.URL, Token: token})
+		require.NoError(t, err)
+		return c
+	}
+	operator, acme, globex := client("secret"), client("acme-token"), client("globex-token")
+
+	k, err := acme.CreateKey(ctx, "acme-treasury", treasury)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", k.Tenant)
+	_, err = globex.CreateKey(ctx, "globex-treasury", treasury)
+	require.NoError(t, err)
+
+	// Keys of other tenants can neither be created, seen nor managed.
+	foreign := *treasury
+	foreign.Tenant = "globex"
+	_, err = acme.CreateKey(ctx, "sneaky", &foreign)
+	var serr *StatusError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, http.StatusForbidden, serr.Status)
+	_, err = acme.CreateKey(ctx, "globex-treasury", treasury)
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = acme.Key(ctx, "globex-treasury")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = acme.SetFrozen(ctx, "globex-treasury", &FreezeRequest{Frozen: true, Reason: "sabotage"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = acme.RotateKey(ctx, "globex-treasury", "rotate-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	keys, err := acme.ListKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "acme-treasury", keys[0].ID)
+	keys, err = acme.TenantKeys(ctx, "globex")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// Callers without a tenant see everything.
+	keys, err = operator.ListKeys(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
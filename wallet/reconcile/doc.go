@@ -0,0 +1,15 @@
+// Package reconcile replays a key's signing history against the chain.
+//
+// The coordinator's audit store records every signature the MPC parties
+// produced, while the chain records every transaction the key actually
+// signed.  `Run` walks both: it re-verifies each recorded signature over the
+// payload that was signed, matches every on-chain transaction signed by the
+// key to the session that produced it, and reports transactions without one.
+// Such a transaction was signed with the key's shares outside the
+// coordinator, e.g. by parties colluding out-of-band or with leaked shares,
+// and is reason to rotate the key.
+//
+// Every signed attempt of a session counts, including those whose
+// transaction expired and was rebuilt, so a late landing of an earlier
+// attempt is not mistaken for unauthorized use.
+package reconcile
@@ -0,0 +1,186 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/client"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// defaultLimit is how many on-chain transactions Run fetches by default.
+const defaultLimit = 1000
+
+// Kind classifies a Finding.
+type Kind string
+
+const (
+	// KindUnauthorized is an on-chain transaction signed by the key that no
+	// session produced.
+	KindUnauthorized Kind = "unauthorized"
+	// KindBadSignature is a recorded or on-chain signature that does not
+	// verify against the key.
+	KindBadSignature Kind = "bad-signature"
+	// KindPayloadMismatch is an on-chain transaction carrying a recorded
+	// signature over something other than the session's payload.
+	KindPayloadMismatch Kind = "payload-mismatch"
+)
+
+// Config contains what Run reconciles.
+type Config struct {
+	Store   coordinator.Store // Audit store of the coordinator
+	History chain.History     // Chain the key transacts on
+	Chain   string            // Identifier of that chain in the sessions
+	Address string            // Address of the key
+	// Verifier checks signatures by the key, e.g. client.SolanaAddress.
+	Verifier client.Verifier
+	// Limit is how many of the most recent on-chain transactions are
+	// checked.  Defaults to 1000.
+	Limit int
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Finding is a discrepancy between the audit store and the chain.
+type Finding struct {
+	Kind    Kind      `json:"kind"`
+	Session string    `json:"session,omitempty"` // Empty for KindUnauthorized
+	TxID    string    `json:"tx_id,omitempty"`   // Empty for recorded signatures that never landed
+	Height  uint64    `json:"height,omitempty"`
+	Time    time.Time `json:"time,omitempty"`
+	Detail  string    `json:"detail"`
+}
+
+// Match pairs an on-chain transaction with the session that signed it.
+type Match struct {
+	Session string `json:"session"`
+	TxID    string `json:"tx_id"`
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	Address   string    `json:"address"`
+	Recorded  int       `json:"recorded"` // Signatures recorded in the store
+	OnChain   int       `json:"on_chain"` // Transactions signed by the key
+	Matched   []Match   `json:"matched,omitempty"`
+	Findings  []Finding `json:"findings,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Clean reports whether nothing was found.
+func (r *Report) Clean() bool { return len(r.Findings) == 0 }
+
+// record is a signature recorded in a session's log.
+type record struct {
+	session   string
+	payload   []byte
+	signature []byte
+}
+
+// Run replays every session of config.Address in the store, re-verifies the
+// recorded signatures and reconciles them with the key's most recent
+// on-chain transactions.
+func Run(ctx context.Context, config Config) (*Report, error) {
+	if config.Store == nil || config.History == nil || config.Verifier == nil {
+		return nil, fmt.Errorf("store, history and verifier must be provided")
+	}
+	if config.Chain == "" || config.Address == "" {
+		return nil, fmt.Errorf("chain and address must be provided")
+	}
+	limit := config.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	records, err := recorded(ctx, config.Store, config.Chain, config.Address)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Address: config.Address, Recorded: len(records)}
+	bySignature := make(map[string]*record, len(records))
+	for _, rec := range records {
+		if err := config.Verifier.Verify(rec.payload, rec.signature); err != nil {
+			r.Findings = append(r.Findings, Finding{Kind: KindBadSignature, Session: rec.session,
+				Detail: fmt.Sprintf("recorded signature does not verify: %v", err)})
+			continue
+		}
+		bySignature[hex.EncodeToString(rec.signature)] = rec
+	}
+
+	txs, err := config.History.Transactions(ctx, config.Address, limit)
+	if err != nil {
+		return nil, fmt.Errorf("fetching history: %w", err)
+	}
+	for _, tx := range txs {
+		if tx.Signature == nil {
+			continue // Not signed by the key, e.g. an incoming transfer
+		}
+		r.OnChain++
+		finding := Finding{TxID: tx.TxID, Height: tx.Height, Time: tx.Time}
+		rec, ok := bySignature[hex.EncodeToString(tx.Signature)]
+		switch {
+		case ok && bytes.Equal(rec.payload, tx.Payload):
+			r.Matched = append(r.Matched, Match{Session: rec.session, TxID: tx.TxID})
+			continue
+		case ok:
+			finding.Kind, finding.Session = KindPayloadMismatch, rec.session
+			finding.Detail = "transaction carries the session's signature over another payload"
+		default:
+			if err := config.Verifier.Verify(tx.Payload, tx.Signature); err != nil {
+				finding.Kind = KindBadSignature
+				finding.Detail = fmt.Sprintf("on-chain signature does not verify: %v", err)
+			} else {
+				finding.Kind = KindUnauthorized
+				finding.Detail = "signed by the key without a signing session"
+			}
+		}
+		r.Findings = append(r.Findings, finding)
+	}
+	r.CheckedAt = now().UTC()
+	return r, nil
+}
+
+// recorded returns every signature recorded for transfers from address on
+// chainID, in every attempt of every session.
+func recorded(ctx context.Context, store coordinator.Store, chainID, address string) ([]*record, error) {
+	ids, err := store.Sessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	var records []*record
+	for _, id := range ids {
+		events, err := store.Events(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", id, err)
+		}
+		if len(events) == 0 || events[0].Request == nil ||
+			events[0].Request.Chain != chainID || events[0].Request.Transfer.From != address {
+			continue
+		}
+		// A session signs once per attempt; each attempt is preceded by the
+		// policy evaluation that recorded the transaction it signs.
+		var payload []byte
+		for _, e := range events {
+			switch e.Type {
+			case coordinator.EventPolicyEvaluated:
+				if e.Unsigned != nil {
+					payload = e.Unsigned.SigningPayload
+				}
+			case coordinator.EventSigned:
+				if payload == nil {
+					return nil, fmt.Errorf("session %s: signature %d without a transaction", id, e.Seq)
+				}
+				records = append(records, &record{session: id, payload: payload, signature: e.Signature})
+			}
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,130 @@
+package reconcile
+
+import (
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/client"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+var epoch = time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+type fakeHistory []chain.HistoryTx
+
+func (h fakeHistory) Transactions(_ context.Context, _ string, limit int) ([]chain.HistoryTx, error) {
+	return h[:min(limit, len(h))], nil
+}
+
+type fixture struct {
+	priv  ed25519.PrivateKey
+	store *coordinator.MemoryStore
+}
+
+func newFixture(t *testing.T) *fixture {
+	return &fixture{
+		priv:  approvaltest.New(t, "wallet").Private["wallet"],
+		store: coordinator.NewMemoryStore(),
+	}
+}
+
+// session records a session from address from that signed each of payloads in
+// turn, as if every attempt but the last had expired, and returns the
+// signatures.
+func (f *fixture) session(t *testing.T, id, from string, payloads ...string) [][]byte {
+	ctx := context.Background()
+	var seq uint64
+	add := func(e coordinator.Event) {
+		seq++
+		e.Session, e.Seq, e.Time = id, seq, epoch
+		require.NoError(t, f.store.Append(ctx, &e))
+	}
+	add(coordinator.Event{Type: coordinator.EventCreated, Request: &coordinator.Request{
+		Chain: "fake", Transfer: chain.Transfer{From: from},
+	}})
+	var sigs [][]byte
+	for i, p := range payloads {
+		if i > 0 {
+			add(coordinator.Event{Type: coordinator.EventExpired})
+		}
+		add(coordinator.Event{Type: coordinator.EventPolicyEvaluated,
+			Unsigned: &chain.UnsignedTx{Chain: "fake", SigningPayload: []byte(p)}})
+		sig := ed25519.Sign(f.priv, []byte(p))
+		add(coordinator.Event{Type: coordinator.EventSigned, Signature: sig})
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
+
+func (f *fixture) config(history fakeHistory) Config {
+	return Config{
+		Store:    f.store,
+		History:  history,
+		Chain:    "fake",
+		Address:  "wallet",
+		Verifier: client.Ed25519(f.priv.Public().(ed25519.PublicKey)),
+		Now:      func() time.Time { return epoch },
+	}
+}
+
+func TestRunMatchesSessions(t *testing.T) {
+	f := newFixture(t)
+	a := f.session(t, "a", "wallet", "pay bob")
+	b := f.session(t, "b", "wallet", "pay carol", "pay carol again")
+	f.session(t, "c", "other", "pay dave")
+
+	r, err := Run(context.Background(), f.config(fakeHistory{
+		{TxID: "tx3", Payload: []byte("pay carol"), Signature: b[0]}, // The expired attempt landed late
+		{TxID: "tx2", Payload: []byte("incoming")},
+		{TxID: "tx1", Payload: []byte("pay bob"), Signature: a[0]},
+	}))
+	require.NoError(t, err)
+	assert.True(t, r.Clean(), r.Findings)
+	assert.Equal(t, 3, r.Recorded)
+	assert.Equal(t, 2, r.OnChain)
+	assert.Equal(t, []Match{{Session: "b", TxID: "tx3"}, {Session: "a", TxID: "tx1"}}, r.Matched)
+	assert.Equal(t, epoch, r.CheckedAt)
+}
+
+func TestRunReportsFindings(t *testing.T) {
+	f := newFixture(t)
+	a := f.session(t, "a", "wallet", "pay bob")
+	rogue := ed25519.Sign(f.priv, []byte("pay mallory"))
+
+	r, err := Run(context.Background(), f.config(fakeHistory{
+		{TxID: "tx1", Height: 7, Payload: []byte("pay mallory"), Signature: rogue},
+		{TxID: "tx2", Payload: []byte("pay bob twice"), Signature: a[0]},
+		{TxID: "tx3", Payload: []byte("garbage"), Signature: make([]byte, ed25519.SignatureSize)},
+	}))
+	require.NoError(t, err)
+	assert.False(t, r.Clean())
+	require.Len(t, r.Findings, 3)
+	assert.Equal(t, Finding{Kind: KindUnauthorized, TxID: "tx1", Height: 7,
+		Detail: "signed by the key without a signing session"}, r.Findings[0])
+	assert.Equal(t, KindPayloadMismatch, r.Findings[1].Kind)
+	assert.Equal(t, "a", r.Findings[1].Session)
+	assert.Equal(t, KindBadSignature, r.Findings[2].Kind)
+	assert.Empty(t, r.Matched)
+}
+
+func TestRunReverifiesRecordedSignatures(t *testing.T) {
+	f := newFixture(t)
+	f.session(t, "a", "wallet", "pay bob")
+	config := f.config(nil)
+	other, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	config.Verifier = client.Ed25519(other)
+
+	r, err := Run(context.Background(), config)
+	require.NoError(t, err)
+	require.Len(t, r.Findings, 1)
+	assert.Equal(t, KindBadSignature, r.Findings[0].Kind)
+	assert.Equal(t, "a", r.Findings[0].Session)
+}
@@ -0,0 +1,16 @@
+// Package recoveryphrase writes the backup encryption key as a BIP-39
+// mnemonic so that it can be kept the way people already keep seed phrases:
+// on paper, in a steel plate or in a safe.
+//
+// Only the 32-byte key that encrypts the share backups (see
+// keystore.NewFileMedium) is encoded, never a share.  A phrase alone is
+// useless without the encrypted backup directory, and the backups are
+// useless without the phrase.
+//
+//	phrase, _ := recoveryphrase.Encode(backupKey) // 24 words
+//	key, _ := recoveryphrase.Decode(phrase)
+//
+// Decode checks the BIP-39 checksum, so a misspelt or swapped word is caught
+// before the key is used.  `ReadKeyFile` accepts either a hex key or a
+// phrase, which lets the -backup-key flags take both.
+package recoveryphrase
@@ -0,0 +1,76 @@
+package recoveryphrase
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// KeySize is the length of the backup encryption key.
+const KeySize = 32
+
+// Words is the number of words in a phrase for a KeySize key.
+const Words = 24
+
+// ErrInvalidPhrase is returned for phrases with unknown words, the wrong
+// number of words or a failing checksum.
+var ErrInvalidPhrase = errors.New("recoveryphrase: invalid phrase")
+
+// Encode returns the BIP-39 phrase of a KeySize-byte key.
+func Encode(key []byte) (string, error) {
+	if len(key) != KeySize {
+		return "", fmt.Errorf("recoveryphrase: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return bip39.NewMnemonic(key)
+}
+
+// Decode returns the key encoded by phrase.  Case and runs of whitespace,
+// including line breaks from a transcribed phrase, are ignored.
+func Decode(phrase string) ([]byte, error) {
+	words := strings.Fields(strings.ToLower(phrase))
+	if len(words) != Words {
+		return nil, fmt.Errorf("%w: need %d words, got %d", ErrInvalidPhrase, Words, len(words))
+	}
+	for i, w := range words {
+		if _, ok := bip39.GetWordIndex(w); !ok {
+			return nil, fmt.Errorf("%w: word %d (%q) is not in the BIP-39 word list", ErrInvalidPhrase, i+1, w)
+		}
+	}
+	key, err := bip39.EntropyFromMnemonic(strings.Join(words, " "))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPhrase, err)
+	}
+	return key, nil
+}
+
+// ParseKey reads a backup key given either as hex or as a phrase.
+func ParseKey(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if len(strings.Fields(text)) > 1 {
+		return Decode(text)
+	}
+	key, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// ReadKeyFile reads a backup key file holding hex or a phrase.
+func ReadKeyFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file must be provided")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(string(data))
+}
@@ -0,0 +1,83 @@
+package recoveryphrase
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x7f}, KeySize)
+	phrase, err := Encode(key)
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(phrase), Words)
+
+	got, err := Decode(phrase)
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	// Transcriptions come back with odd casing and line breaks.
+	got, err = Decode("  " + strings.ToUpper(strings.ReplaceAll(phrase, " ", "\n")) + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = Encode(key[:16])
+	assert.Error(t, err)
+}
+
+func TestVector(t *testing.T) {
+	// BIP-39 reference vector for 32 bytes of 0x7f.
+	phrase, err := Encode(bytes.Repeat([]byte{0x7f}, KeySize))
+	require.NoError(t, err)
+	assert.Equal(t, "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title", phrase)
+}
+
+func TestDecodeRejects(t *testing.T) {
+	phrase, err := Encode(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+	words := strings.Fields(phrase)
+
+	_, err = Decode(strings.Join(words[:12], " "))
+	assert.ErrorIs(t, err, ErrInvalidPhrase)
+
+	swapped := append([]string(nil), words...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	_, err = Decode(strings.Join(swapped, " "))
+	assert.ErrorIs(t, err, ErrInvalidPhrase)
+
+	misspelt := append([]string(nil), words...)
+	misspelt[5] = "notaword"
+	_, err = Decode(strings.Join(misspelt, " "))
+	assert.ErrorIs(t, err, ErrInvalidPhrase)
+	assert.Contains(t, err.Error(), "word 6")
+}
+
+func TestReadKeyFile(t *testing.T) {
+	key := bytes.Repeat([]byte{9}, KeySize)
+	phrase, err := Encode(key)
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	hexFile := filepath.Join(dir, "backup.key")
+	require.NoError(t, os.WriteFile(hexFile, []byte(hex.EncodeToString(key)+"\n"), 0o600))
+	got, err := ReadKeyFile(hexFile)
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	phraseFile := filepath.Join(dir, "backup.phrase")
+	require.NoError(t, os.WriteFile(phraseFile, []byte(phrase+"\n"), 0o600))
+	got, err = ReadKeyFile(phraseFile)
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = ParseKey(hex.EncodeToString(key[:16]))
+	assert.Error(t, err)
+	_, err = ReadKeyFile("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+// Package remotesigner exposes the MPC wallet over HTTP with the semantics of
+// a Solana wallet-adapter remote signer, so dApp backends and tooling that
+// already talk to a remote signer can use the MPC service with minimal changes.
+//
+// Endpoints (JSON bodies, byte strings base64-encoded, keys base58-encoded):
+//
+//	GET  /v1/publicKey            → {"publicKey": "<base58>"}
+//	POST /v1/signTransaction      {"transaction": "<base64>"}     → {"transaction": "<base64>"}
+//	POST /v1/signAllTransactions  {"transactions": ["<base64>"]}  → {"transactions": ["<base64>"]}
+//	POST /v1/signMessage          {"message": "<base64>"}         → {"signature": "<base64>"}
+//	POST /v1/signOffchainMessage  {"message": "<base64>", "applicationDomain": "<base58>", "signers": ["<base58>"]}
+//	                              or {"offchainMessage": "<base64>"}
+//	                              → {"signature": "<base64>", "offchainMessage": "<base64>"}
+//
+// Transactions are serialized wire transactions (legacy or v0).  As with a
+// browser wallet, only the wallet's own signature slot is filled in; the
+// transaction is otherwise returned byte for byte, and partially signed
+// transactions keep their other signatures.  signAllTransactions is
+// all-or-nothing: if any transaction is rejected, none is signed.
+//
+// Every request is authenticated through Config.Authenticate and every
+// signature is authorized by Config.Authorizer, which sees the exact bytes to
+// be signed and, when Config.Chain can decode them, a transfer summary.
+// signMessage refuses messages that signctx.Check does not accept as raw, such
+// as transaction messages and offchain messages, so it can never be abused to
+// sign a transaction blind.  Every message is checked again against its
+// signing context by RequireContext, which wraps Config.Signer, and Signers
+// implementing ContextSigner are told the context, so that parties in other
+// processes can check it too.
+//
+// signOffchainMessage signs Solana offchain messages, which dApps verify when
+// a wallet signs in.  It either prepares the message from a body, an
+// optional application domain (all zeros if omitted) and optional signers
+// (the wallet alone if omitted), choosing the most restricted format, or
+// accepts a fully serialized message, which must parse strictly.  Either way
+// the wallet must be one of the signers, and the response carries the exact
+// bytes that were signed.  Offchain messages start with a byte that no
+// transaction message can start with, so they cannot be replayed as
+// transactions.
+package remotesigner
@@ -0,0 +1,500 @@
+package remotesigner
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/verify"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+
+	"solana-threshold-wallet/wallet/chain"
+	solchain "solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/signctx"
+)
+
+const (
+	defaultMaxBodyBytes    = 1 << 20
+	defaultMaxTransactions = 100
+)
+
+// Methods reported in Request.Method.
+const (
+	MethodSignTransaction     = "signTransaction"
+	MethodSignAllTransactions = "signAllTransactions"
+	MethodSignMessage         = "signMessage"
+	MethodSignOffchainMessage = "signOffchainMessage"
+)
+
+var (
+	// ErrUnauthenticated is returned by Authenticate functions to reject a
+	// caller.
+	ErrUnauthenticated = errors.New("remotesigner: unauthenticated")
+	// ErrDenied is wrapped by Authorizers to reject a request.
+	ErrDenied = errors.New("remotesigner: request denied")
+)
+
+// Signer produces Ed25519 signatures with the MPC key, typically by running
+// the EdDSA MPC signing protocol.
+type Signer interface {
+	Sign(ctx context.Context, message []byte) ([]byte, error)
+}
+
+// SignerFunc adapts an ordinary function to the Signer interface.
+type SignerFunc func(ctx context.Context, message []byte) ([]byte, error)
+
+// Sign calls f(ctx, message).
+func (f SignerFunc) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	return f(ctx, message)
+}
+
+// ContextSigner is implemented by Signers that declare the signing context of
+// every message to the parties, which check it with signctx.Check.  The
+// Server signs through SignContext.
+type ContextSigner interface {
+	Signer
+	SignContext(ctx context.Context, signingContext signctx.Context, message []byte) ([]byte, error)
+}
+
+// RequireContext returns a ContextSigner that runs signctx.Check on every
+// message, in the context it is signed in, before passing it to next.  Sign
+// checks the message as raw.  If next is a ContextSigner it is told the
+// context too, so that parties in other processes check it as well.  New
+// wraps Config.Signer with it.
+func RequireContext(next Signer) ContextSigner {
+	return &checkingSigner{next: next}
+}
+
+type checkingSigner struct{ next Signer }
+
+func (s *checkingSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	return s.SignContext(ctx, signctx.Raw, message)
+}
+
+func (s *checkingSigner) SignContext(ctx context.Context, signingContext signctx.Context, message []byte) ([]byte, error) {
+	if err := signctx.Check(signingContext, message); err != nil {
+		return nil, err
+	}
+	if cs, ok := s.next.(ContextSigner); ok {
+		return cs.SignContext(ctx, signingContext, message)
+	}
+	return s.next.Sign(ctx, message)
+}
+
+// Request describes a single signature about to be produced.
+type Request struct {
+	Method  string         // One of the Method constants
+	Caller  string         // Identity returned by Config.Authenticate
+	Message []byte         // Exact bytes to be signed
+	Summary *chain.Summary // Transfer summary, nil for messages and for transactions Config.Chain cannot decode
+	// Offchain is the parsed offchain message, nil for other methods.
+	Offchain *solchain.OffchainMessage
+}
+
+// Authorizer decides whether a signature may be produced.
+type Authorizer interface {
+	Authorize(ctx context.Context, req *Request) error
+}
+
+// AuthorizerFunc adapts an ordinary function to the Authorizer interface.
+type AuthorizerFunc func(ctx context.Context, req *Request) error
+
+// Authorize calls f(ctx, req).
+func (f AuthorizerFunc) Authorize(ctx context.Context, req *Request) error {
+	return f(ctx, req)
+}
+
+// AllowAll authorizes every request.  Only use it behind another policy layer.
+var AllowAll = AuthorizerFunc(func(context.Context, *Request) error { return nil })
+
+// BearerTokens authenticates callers by bearer token.  tokens maps each token
+// to the caller identity it grants.
+func BearerTokens(tokens map[string]string) func(*http.Request) (string, error) {
+	return func(r *http.Request) (string, error) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return "", ErrUnauthenticated
+		}
+		for t, caller := range tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				return caller, nil
+			}
+		}
+		return "", ErrUnauthenticated
+	}
+}
+
+// Config contains the configuration for a Server.
+type Config struct {
+	// PublicKey is the wallet's 32-byte Ed25519 group public key.
+	PublicKey ed25519.PublicKey
+	// Signer signs with the MPC key.
+	Signer Signer
+	// Authenticate identifies the caller of an HTTP request.
+	Authenticate func(*http.Request) (string, error)
+	// Authorizer approves every signature.
+	Authorizer Authorizer
+	// Chain, if set, decodes transactions into summaries for the Authorizer.
+	Chain chain.Chain
+	// MaxBodyBytes limits request bodies.  Defaults to 1 MiB.
+	MaxBodyBytes int64
+	// MaxTransactions limits signAllTransactions batches.  Defaults to 100.
+	MaxTransactions int
+}
+
+// Server is an http.Handler serving the remote signer API.
+type Server struct {
+	config Config
+	key    solana.PublicKey
+	mux    *http.ServeMux
+}
+
+// Ensure Server implements the http.Handler interface
+var _ http.Handler = (*Server)(nil)
+
+// New creates a Server from the given configuration.
+func New(config Config) (*Server, error) {
+	if len(config.PublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	if config.Signer == nil || config.Authenticate == nil || config.Authorizer == nil {
+		return nil, fmt.Errorf("signer, authenticate and authorizer must be provided")
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if config.MaxTransactions <= 0 {
+		config.MaxTransactions = defaultMaxTransactions
+	}
+	config.Signer = RequireContext(config.Signer)
+	s := &Server{config: config, key: solana.PublicKeyFromBytes(config.PublicKey), mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /v1/publicKey", s.handle(s.publicKey))
+	s.mux.HandleFunc("POST /v1/signTransaction", s.handle(s.signTransaction))
+	s.mux.HandleFunc("POST /v1/signAllTransactions", s.handle(s.signAllTransactions))
+	s.mux.HandleFunc("POST /v1/signMessage", s.handle(s.signMessage))
+	s.mux.HandleFunc("POST /v1/signOffchainMessage", s.handle(s.signOffchainMessage))
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// httpError carries the status code of a failed request.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func badRequest(format string, args ...any) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+// handle authenticates the caller, decodes the body into the handler's
+// request and encodes its response or error.
+func (s *Server) handle(fn func(ctx context.Context, caller string, body []byte) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			resp any
+			err  error
+		)
+		caller, authErr := s.config.Authenticate(r)
+		if authErr != nil {
+			err = &httpError{status: http.StatusUnauthorized, err: authErr}
+		} else {
+			var body bytes.Buffer
+			if _, rerr := body.ReadFrom(http.MaxBytesReader(w, r.Body, s.config.MaxBodyBytes)); rerr != nil {
+				err = badRequest("reading body: %v", rerr)
+			} else {
+				resp, err = fn(r.Context(), caller, body.Bytes())
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			status := http.StatusInternalServerError
+			var herr *httpError
+			switch {
+			case errors.As(err, &herr):
+				status = herr.status
+			case errors.Is(err, ErrDenied):
+				status = http.StatusForbidden
+			}
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func (s *Server) publicKey(context.Context, string, []byte) (any, error) {
+	return map[string]string{"publicKey": s.key.String()}, nil
+}
+
+func (s *Server) signTransaction(ctx context.Context, caller string, body []byte) (any, error) {
+	var req struct {
+		Transaction string `json:"transaction"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, badRequest("decoding request: %v", err)
+	}
+	signed, err := s.signTransactions(ctx, caller, MethodSignTransaction, []string{req.Transaction})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"transaction": signed[0]}, nil
+}
+
+func (s *Server) signAllTransactions(ctx context.Context, caller string, body []byte) (any, error) {
+	var req struct {
+		Transactions []string `json:"transactions"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, badRequest("decoding request: %v", err)
+	}
+	if len(req.Transactions) == 0 || len(req.Transactions) > s.config.MaxTransactions {
+		return nil, badRequest("between 1 and %d transactions must be provided", s.config.MaxTransactions)
+	}
+	signed, err := s.signTransactions(ctx, caller, MethodSignAllTransactions, req.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]string{"transactions": signed}, nil
+}
+
+func (s *Server) signMessage(ctx context.Context, caller string, body []byte) (any, error) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, badRequest("decoding request: %v", err)
+	}
+	msg, err := base64.StdEncoding.DecodeString(req.Message)
+	if err != nil || len(msg) == 0 {
+		return nil, badRequest("message must be non-empty base64")
+	}
+	if err := signctx.Check(signctx.Raw, msg); err != nil {
+		return nil, badRequest("%v; use the endpoint for it", err)
+	}
+	if err := s.config.Authorizer.Authorize(ctx, &Request{Method: MethodSignMessage, Caller: caller, Message: msg}); err != nil {
+		return nil, err
+	}
+	sig, err := s.sign(ctx, signctx.Raw, msg)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"signature": base64.StdEncoding.EncodeToString(sig)}, nil
+}
+
+func (s *Server) signOffchainMessage(ctx context.Context, caller string, body []byte) (any, error) {
+	var req struct {
+		Message           string   `json:"message"`
+		ApplicationDomain string   `json:"applicationDomain"`
+		Signers           []string `json:"signers"`
+		OffchainMessage   string   `json:"offchainMessage"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, badRequest("decoding request: %v", err)
+	}
+	var (
+		m   *solchain.OffchainMessage
+		err error
+	)
+	if req.OffchainMessage != "" {
+		if req.Message != "" || req.ApplicationDomain != "" || req.Signers != nil {
+			return nil, badRequest("offchainMessage excludes message, applicationDomain and signers")
+		}
+		raw, derr := base64.StdEncoding.DecodeString(req.OffchainMessage)
+		if derr != nil {
+			return nil, badRequest("offchainMessage must be base64")
+		}
+		m, err = solchain.ParseOffchainMessage(raw)
+	} else {
+		m, err = s.offchainMessage(req.Message, req.ApplicationDomain, req.Signers)
+	}
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	if !m.HasSigner(s.key) {
+		return nil, badRequest("offchain message does not list %s as a signer", s.key)
+	}
+	msg, err := m.Serialize()
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	if err := s.config.Authorizer.Authorize(ctx, &Request{Method: MethodSignOffchainMessage, Caller: caller, Message: msg, Offchain: m}); err != nil {
+		return nil, err
+	}
+	sig, err := s.sign(ctx, signctx.OffchainMsg, msg)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"signature":       base64.StdEncoding.EncodeToString(sig),
+		"offchainMessage": base64.StdEncoding.EncodeToString(msg),
+	}, nil
+}
+
+// offchainMessage prepares an offchain message from its parts.  The signers
+// default to the wallet alone.
+func (s *Server) offchainMessage(body, domain string, signers []string) (*solchain.OffchainMessage, error) {
+	text, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("message must be base64")
+	}
+	var appDomain [32]byte
+	if domain != "" {
+		key, err := solana.PublicKeyFromBase58(domain)
+		if err != nil {
+			return nil, fmt.Errorf("applicationDomain must be 32 bytes base58: %v", err)
+		}
+		appDomain = key
+	}
+	keys := []solana.PublicKey{s.key}
+	if signers != nil {
+		keys = nil
+		for _, signer := range signers {
+			key, err := solana.PublicKeyFromBase58(signer)
+			if err != nil {
+				return nil, fmt.Errorf("signer %q: %v", signer, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return solchain.NewOffchainMessage(appDomain, keys, text)
+}
+
+// pendingTx is a parsed transaction awaiting the wallet's signature.
+type pendingTx struct {
+	raw     []byte
+	slot    int // Byte offset of the wallet's signature in raw
+	message []byte
+}
+
+// signTransactions parses and authorizes every transaction before signing
+// any of them.
+func (s *Server) signTransactions(ctx context.Context, caller, method string, encoded []string) ([]string, error) {
+	pending := make([]*pendingTx, len(encoded))
+	for i, e := range encoded {
+		raw, err := base64.StdEncoding.DecodeString(e)
+		if err != nil {
+			return nil, badRequest("transaction %d: invalid base64", i)
+		}
+		p, err := s.parse(raw)
+		if err != nil {
+			return nil, badRequest("transaction %d: %v", i, err)
+		}
+		req := &Request{Method: method, Caller: caller, Message: p.message}
+		if s.config.Chain != nil {
+			if summary, err := s.config.Chain.Decode(p.message); err == nil {
+				req.Summary = summary
+			}
+		}
+		if err := s.config.Authorizer.Authorize(ctx, req); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		pending[i] = p
+	}
+
+	pubs, msgs, sigs := make([][]byte, len(pending)), make([][]byte, len(pending)), make([][]byte, len(pending))
+	for i, p := range pending {
+		sig, err := s.signUnchecked(ctx, signctx.SolanaTx, p.message)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		pubs[i], msgs[i], sigs[i] = s.config.PublicKey, p.message, sig
+	}
+	// Checking the whole batch at once keeps large batches cheap.
+	if err := verify.VerifyBatch(pubs, msgs, sigs); err != nil {
+		var be *verify.BatchError
+		if errors.As(err, &be) {
+			return nil, fmt.Errorf("transaction %d: signer returned an invalid signature", be.Invalid[0])
+		}
+		return nil, fmt.Errorf("signer returned a malformed signature: %v", err)
+	}
+	out := make([]string, len(pending))
+	for i, p := range pending {
+		copy(p.raw[p.slot:], sigs[i])
+		out[i] = base64.StdEncoding.EncodeToString(p.raw)
+	}
+	return out, nil
+}
+
+// parse locates the message and the wallet's signature slot in a wire
+// transaction: a compact-u16 signature count, the signatures, then the
+// message.
+func (s *Server) parse(raw []byte) (*pendingTx, error) {
+	count, n, err := compactU16(raw)
+	if err != nil {
+		return nil, err
+	}
+	start := n + count*solana.SignatureLength
+	if start >= len(raw) {
+		return nil, fmt.Errorf("truncated transaction")
+	}
+	var msg solana.Message
+	if err := msg.UnmarshalWithDecoder(bin.NewBinDecoder(raw[start:])); err != nil {
+		return nil, fmt.Errorf("decoding message: %v", err)
+	}
+	required := int(msg.Header.NumRequiredSignatures)
+	if required != count {
+		return nil, fmt.Errorf("transaction has %d signature slots, message requires %d", count, required)
+	}
+	for i := 0; i < required && i < len(msg.AccountKeys); i++ {
+		if msg.AccountKeys[i].Equals(s.key) {
+			return &pendingTx{raw: raw, slot: n + i*solana.SignatureLength, message: raw[start:]}, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction does not require a signature from %s", s.key)
+}
+
+// sign signs message in the given context and checks the result against the
+// public key, so that a misbehaving signing backend cannot hand out invalid
+// signatures.
+func (s *Server) sign(ctx context.Context, signingContext signctx.Context, message []byte) ([]byte, error) {
+	sig, err := s.signUnchecked(ctx, signingContext, message)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(s.config.PublicKey, message, sig) {
+		return nil, fmt.Errorf("signer returned an invalid signature")
+	}
+	return sig, nil
+}
+
+// signUnchecked signs message in the given context; the caller checks the
+// signature.
+func (s *Server) signUnchecked(ctx context.Context, signingContext signctx.Context, message []byte) ([]byte, error) {
+	sig, err := s.config.Signer.(ContextSigner).SignContext(ctx, signingContext, message)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+	return sig, nil
+}
+
+// compactU16 decodes Solana's compact-u16 length prefix.
+func compactU16(b []byte) (int, int, error) {
+	v := 0
+	for i := 0; i < 3; i++ {
+		if i >= len(b) {
+			return 0, 0, fmt.Errorf("truncated length prefix")
+		}
+		v |= int(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid length prefix")
+}
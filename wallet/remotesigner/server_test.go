@@ -0,0 +1,377 @@
+package remotesigner
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/signctx"
+)
+
+type testEnv struct {
+	server *httptest.Server
+	key    solana.PublicKey
+	seen   []*Request
+}
+
+func newTestEnv(t *testing.T, authorize func(*Request) error) *testEnv {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	env := &testEnv{key: solana.PublicKeyFromBytes(pub)}
+	s, err := New(Config{
+		PublicKey: pub,
+		Signer: SignerFunc(func(_ context.Context, msg []byte) ([]byte, error) {
+			return ed25519.Sign(priv, msg), nil
+		}),
+		Authenticate: BearerTokens(map[string]string{"token-1": "alice"}),
+		Authorizer: AuthorizerFunc(func(_ context.Context, req *Request) error {
+			env.seen = append(env.seen, req)
+			if authorize != nil {
+				return authorize(req)
+			}
+			return nil
+		}),
+	})
+	require.NoError(t, err)
+	env.server = httptest.NewServer(s)
+	t.Cleanup(env.server.Close)
+	return env
+}
+
+func (e *testEnv) post(t *testing.T, path, token string, body any) (int, map[string]json.RawMessage) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, e.server.URL+path, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var out map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return resp.StatusCode, out
+}
+
+// transferTx builds a transfer from the wallet, optionally co-signed by a
+// separate fee payer whose signature is already present.
+func transferTx(t *testing.T, from solana.PublicKey, feePayer *solana.PrivateKey) []byte {
+	t.Helper()
+	payer := from
+	if feePayer != nil {
+		payer = feePayer.PublicKey()
+	}
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{system.NewTransferInstruction(1_000, from, solana.NewWallet().PublicKey()).Build()},
+		solana.Hash{7},
+		solana.TransactionPayer(payer),
+	)
+	require.NoError(t, err)
+	if feePayer != nil {
+		_, err = tx.PartialSign(func(k solana.PublicKey) *solana.PrivateKey {
+			if k.Equals(payer) {
+				return feePayer
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	} else {
+		tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	}
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return raw
+}
+
+func decodeTx(t *testing.T, field json.RawMessage) *solana.Transaction {
+	t.Helper()
+	var s string
+	require.NoError(t, json.Unmarshal(field, &s))
+	raw, err := base64.StdEncoding.DecodeString(s)
+	require.NoError(t, err)
+	tx, err := solana.TransactionFromBytes(raw)
+	require.NoError(t, err)
+	return tx
+}
+
+func TestPublicKey(t *testing.T) {
+	env := newTestEnv(t, nil)
+	req, err := http.NewRequest(http.MethodGet, env.server.URL+"/v1/publicKey", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token-1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var out struct{ PublicKey string }
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	assert.Equal(t, env.key.String(), out.PublicKey)
+}
+
+func TestSignTransaction(t *testing.T) {
+	env := newTestEnv(t, nil)
+	raw := transferTx(t, env.key, nil)
+
+	status, out := env.post(t, "/v1/signTransaction", "token-1", map[string]string{
+		"transaction": base64.StdEncoding.EncodeToString(raw),
+	})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	tx := decodeTx(t, out["transaction"])
+	require.NoError(t, tx.VerifySignatures())
+	require.Len(t, env.seen, 1)
+	assert.Equal(t, "alice", env.seen[0].Caller)
+	assert.Equal(t, MethodSignTransaction, env.seen[0].Method)
+}
+
+func TestSignTransactionKeepsOtherSignatures(t *testing.T) {
+	env := newTestEnv(t, nil)
+	payer := solana.NewWallet().PrivateKey
+	raw := transferTx(t, env.key, &payer)
+
+	status, out := env.post(t, "/v1/signTransaction", "token-1", map[string]string{
+		"transaction": base64.StdEncoding.EncodeToString(raw),
+	})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	tx := decodeTx(t, out["transaction"])
+	require.Len(t, tx.Signatures, 2)
+	require.NoError(t, tx.VerifySignatures())
+}
+
+func TestSignAllTransactionsIsAllOrNothing(t *testing.T) {
+	env := newTestEnv(t, nil)
+	good := base64.StdEncoding.EncodeToString(transferTx(t, env.key, nil))
+
+	status, out := env.post(t, "/v1/signAllTransactions", "token-1", map[string][]string{
+		"transactions": {good, good},
+	})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	var signed []string
+	require.NoError(t, json.Unmarshal(out["transactions"], &signed))
+	assert.Len(t, signed, 2)
+
+	foreign := base64.StdEncoding.EncodeToString(transferTx(t, solana.NewWallet().PublicKey(), nil))
+	status, out = env.post(t, "/v1/signAllTransactions", "token-1", map[string][]string{
+		"transactions": {good, foreign},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, string(out["error"]), "transaction 1")
+}
+
+func TestSignMessage(t *testing.T) {
+	env := newTestEnv(t, nil)
+	msg := []byte("Sign in to example.com\nNonce: 42")
+
+	status, out := env.post(t, "/v1/signMessage", "token-1", map[string]string{
+		"message": base64.StdEncoding.EncodeToString(msg),
+	})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	var sigB64 string
+	require.NoError(t, json.Unmarshal(out["signature"], &sigB64))
+	sig, err := base64.StdEncoding.DecodeString(sigB64)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(ed25519.PublicKey(env.key[:]), msg, sig))
+}
+
+func TestSignMessageRejectsTransactionMessages(t *testing.T) {
+	env := newTestEnv(t, nil)
+	tx, err := solana.TransactionFromBytes(transferTx(t, env.key, nil))
+	require.NoError(t, err)
+	msg, err := tx.Message.MarshalBinary()
+	require.NoError(t, err)
+
+	status, _ := env.post(t, "/v1/signMessage", "token-1", map[string]string{
+		"message": base64.StdEncoding.EncodeToString(msg),
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Empty(t, env.seen)
+}
+
+func TestSignOffchainMessage(t *testing.T) {
+	env := newTestEnv(t, nil)
+	domain := solana.NewWallet().PublicKey()
+
+	status, out := env.post(t, "/v1/signOffchainMessage", "token-1", map[string]string{
+		"message":           base64.StdEncoding.EncodeToString([]byte("Sign in to example.com")),
+		"applicationDomain": domain.String(),
+	})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	var sigB64, msgB64 string
+	require.NoError(t, json.Unmarshal(out["signature"], &sigB64))
+	require.NoError(t, json.Unmarshal(out["offchainMessage"], &msgB64))
+	sig, err := base64.StdEncoding.DecodeString(sigB64)
+	require.NoError(t, err)
+	msg, err := base64.StdEncoding.DecodeString(msgB64)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(ed25519.PublicKey(env.key[:]), msg, sig))
+
+	require.Len(t, env.seen, 1)
+	seen := env.seen[0]
+	assert.Equal(t, MethodSignOffchainMessage, seen.Method)
+	assert.Equal(t, msg, seen.Message)
+	assert.Equal(t, [32]byte(domain), seen.Offchain.ApplicationDomain)
+	assert.Equal(t, []solana.PublicKey{env.key}, seen.Offchain.Signers)
+
+	// A prepared message is accepted as is.
+	status, out = env.post(t, "/v1/signOffchainMessage", "token-1", map[string]string{"offchainMessage": msgB64})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	var again string
+	require.NoError(t, json.Unmarshal(out["signature"], &again))
+	assert.Equal(t, sigB64, again)
+}
+
+func TestSignOffchainMessageRequiresWalletSigner(t *testing.T) {
+	env := newTestEnv(t, nil)
+	status, out := env.post(t, "/v1/signOffchainMessage", "token-1", map[string]any{
+		"message": base64.StdEncoding.EncodeToString([]byte("hello")),
+		"signers": []string{solana.NewWallet().PublicKey().String()},
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, string(out["error"]), "signer")
+	assert.Empty(t, env.seen)
+}
+
+func TestSignMessageRejectsOffchainMessages(t *testing.T) {
+	env := newTestEnv(t, nil)
+	status, out := env.post(t, "/v1/signOffchainMessage", "token-1", map[string]string{
+		"message": base64.StdEncoding.EncodeToString([]byte("hello")),
+	})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+
+	status, _ = env.post(t, "/v1/signMessage", "token-1", map[string]json.RawMessage{
+		"message": out["offchainMessage"],
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Len(t, env.seen, 1)
+}
+
+// contextSigner records the signing context of every message.
+type contextSigner struct {
+	SignerFunc
+	contexts []signctx.Context
+}
+
+func (s *contextSigner) SignContext(ctx context.Context, c signctx.Context, message []byte) ([]byte, error) {
+	s.contexts = append(s.contexts, c)
+	return s.SignerFunc(ctx, message)
+}
+
+func TestContextSignerIsToldTheContext(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	signer := &contextSigner{SignerFunc: func(_ context.Context, msg []byte) ([]byte, error) {
+		return ed25519.Sign(priv, msg), nil
+	}}
+	s, err := New(Config{
+		PublicKey:    pub,
+		Signer:       signer,
+		Authenticate: BearerTokens(map[string]string{"token-1": "alice"}),
+		Authorizer:   AllowAll,
+	})
+	require.NoError(t, err)
+	env := &testEnv{server: httptest.NewServer(s), key: solana.PublicKeyFromBytes(pub)}
+	t.Cleanup(env.server.Close)
+
+	tx := base64.StdEncoding.EncodeToString(transferTx(t, env.key, nil))
+	status, out := env.post(t, "/v1/signTransaction", "token-1", map[string]string{"transaction": tx})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	msg := base64.StdEncoding.EncodeToString([]byte("hello"))
+	status, out = env.post(t, "/v1/signMessage", "token-1", map[string]string{"message": msg})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+	status, out = env.post(t, "/v1/signOffchainMessage", "token-1", map[string]string{"message": msg})
+	require.Equal(t, http.StatusOK, status, string(out["error"]))
+
+	assert.Equal(t, []signctx.Context{signctx.SolanaTx, signctx.Raw, signctx.OffchainMsg}, signer.contexts)
+}
+
+func TestRequireContext(t *testing.T) {
+	ctx := context.Background()
+	var signed [][]byte
+	next := &contextSigner{SignerFunc: func(_ context.Context, msg []byte) ([]byte, error) {
+		signed = append(signed, msg)
+		return []byte("signature"), nil
+	}}
+	signer := RequireContext(next)
+
+	_, err := signer.Sign(ctx, []byte("hello"))
+	require.NoError(t, err)
+	_, err = signer.SignContext(ctx, signctx.SolanaTx, []byte("hello"))
+	assert.ErrorIs(t, err, signctx.ErrMismatch)
+	msg := transferTx(t, solana.NewWallet().PublicKey(), nil)[1+solana.SignatureLength:]
+	_, err = signer.Sign(ctx, msg)
+	assert.ErrorIs(t, err, signctx.ErrMismatch, "a transaction is not a raw message")
+	_, err = signer.SignContext(ctx, signctx.SolanaTx, msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]byte{[]byte("hello"), msg}, signed)
+	assert.Equal(t, []signctx.Context{signctx.Raw, signctx.SolanaTx}, next.contexts)
+}
+
+func TestAuthenticationAndAuthorization(t *testing.T) {
+	env := newTestEnv(t, func(*Request) error { return fmt.Errorf("%w: policy", ErrDenied) })
+	body := map[string]string{"message": base64.StdEncoding.EncodeToString([]byte("hello"))}
+
+	status, _ := env.post(t, "/v1/signMessage", "wrong", body)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, out := env.post(t, "/v1/signMessage", "token-1", body)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, string(out["error"]), "policy")
+}
+
+func TestSignerMustProduceValidSignatures(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	s, err := New(Config{
+		PublicKey: pub,
+		Signer: SignerFunc(func(context.Context, []byte) ([]byte, error) {
+			return make([]byte, ed25519.SignatureSize), nil
+		}),
+		Authenticate: func(*http.Request) (string, error) { return "anyone", nil },
+		Authorizer:   AllowAll,
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	body := `{"message":"` + base64.StdEncoding.EncodeToString([]byte("hello")) + `"}`
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/signMessage", bytes.NewBufferString(body)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid signature")
+}
+
+func TestSignAllTransactionsChecksEverySignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	calls := 0
+	s, err := New(Config{
+		PublicKey: pub,
+		Signer: SignerFunc(func(_ context.Context, msg []byte) ([]byte, error) {
+			calls++
+			if calls == 2 {
+				msg = []byte("something else")
+			}
+			return ed25519.Sign(priv, msg), nil
+		}),
+		Authenticate: func(*http.Request) (string, error) { return "anyone", nil },
+		Authorizer:   AllowAll,
+	})
+	require.NoError(t, err)
+
+	tx := base64.StdEncoding.EncodeToString(transferTx(t, solana.PublicKeyFromBytes(pub), nil))
+	data, err := json.Marshal(map[string][]string{"transactions": {tx, tx, tx}})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/signAllTransactions", bytes.NewReader(data)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "transaction 1: signer returned an invalid signature")
+}
@@ -0,0 +1,33 @@
+// Package retire deletes a threshold key for good and leaves a destruction
+// certificate behind for compliance archives.
+//
+// Retirement is a three-step workflow:
+//
+//  1. approval – a `Request` names the key, its public key, the parties
+//     holding shares and the reason.  Approvers sign it with `Approve` until
+//     the `Policy` quorum is reached.
+//  2. destruction – every party runs `Destroy`, which checks the approvals
+//     itself, wipes its share from each storage location through a `Wiper`
+//     and returns a `Statement` signed with the party's Ed25519 key.
+//  3. certification – `Certify` checks the approvals and one statement from
+//     every party and aggregates them into a `Certificate` signed with the
+//     coordinator's Ed25519 key.
+//
+// In code:
+//
+//	req, _ := retire.NewRequest("treasury", publicKey, []string{"server", "kms", "pin"}, "rotated", time.Now())
+//	a, _ := retire.Approve(req, "alice", aliceKey, time.Now())
+//	// each party:
+//	st, _ := retire.Destroy(ctx, retire.DestroyConfig{Party: "kms", Key: kmsKey, Policy: policy,
+//	    Shares: []retire.Share{{Wiper: retire.NewFileWiper(dir), ID: "kms"}}}, req, approvals)
+//	// the coordinator:
+//	cert, _ := retire.Certify(req, approvals, statements, policy, hostKey, time.Now())
+//
+// Parties never take the coordinator's word for the approvals, so a
+// compromised coordinator cannot make them destroy a key on its own.  A
+// statement attests that the party overwrote and removed its share; it cannot
+// prove that no copy exists elsewhere.  `FileWiper` overwrites the file in
+// place before removing it, which does not reliably erase data on SSDs or
+// copy-on-write file systems, so shares encrypted at rest should also have
+// their encryption key destroyed.
+package retire
@@ -0,0 +1,313 @@
+package retire
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"solana-threshold-wallet/wallet/approval"
+)
+
+// approvalDomain separates approval signatures from any other use of an
+// approver's key.
+const approvalDomain = "cb-mpc key retirement approval\n"
+
+var (
+	// ErrNotApproved is returned when a request lacks a quorum of valid
+	// approvals.
+	ErrNotApproved = approval.ErrNotApproved
+	// ErrBadStatement is returned when a destruction statement does not
+	// verify or does not match the request.
+	ErrBadStatement = errors.New("retire: invalid destruction statement")
+	// ErrBadCertificate is returned when a destruction certificate does not
+	// verify.
+	ErrBadCertificate = errors.New("retire: invalid destruction certificate")
+)
+
+// Policy names who may approve a retirement and which keys the parties sign
+// their statements with.
+type Policy struct {
+	Approvers map[string]ed25519.PublicKey `json:"approvers"`
+	Required  int                          `json:"required"` // Distinct approvals needed
+	Parties   map[string]ed25519.PublicKey `json:"parties"`
+}
+
+// Request asks for the destruction of every share of a key.
+type Request struct {
+	ID          string    `json:"id"`
+	KeyID       string    `json:"key_id"`
+	PublicKey   []byte    `json:"public_key"`
+	Parties     []string  `json:"parties"`
+	Reason      string    `json:"reason"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// NewRequest creates a request with a fresh random ID.
+func NewRequest(keyID string, publicKey []byte, parties []string, reason string, now time.Time) (*Request, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("key ID must be provided")
+	}
+	if len(publicKey) == 0 {
+		return nil, fmt.Errorf("public key must be provided")
+	}
+	if len(parties) == 0 {
+		return nil, fmt.Errorf("at least one party must be provided")
+	}
+	seen := make(map[string]bool)
+	for _, p := range parties {
+		if p == "" || seen[p] {
+			return nil, fmt.Errorf("parties must be distinct and non-empty")
+		}
+		seen[p] = true
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("reason must be provided")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generating request ID: %w", err)
+	}
+	return &Request{
+		ID:          hex.EncodeToString(id),
+		KeyID:       keyID,
+		PublicKey:   publicKey,
+		Parties:     parties,
+		Reason:      reason,
+		RequestedAt: now.UTC(),
+	}, nil
+}
+
+// Hash returns the SHA-256 digest of the request's JSON encoding.
+func (r *Request) Hash() ([]byte, error) {
+	return hashJSON(r)
+}
+
+// Approval records that an approver authorized a request.
+type Approval = approval.Approval
+
+// Approve signs req on behalf of approver.
+func Approve(req *Request, approver string, key ed25519.PrivateKey, now time.Time) (*Approval, error) {
+	hash, err := req.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing request: %w", err)
+	}
+	return approval.Sign(approvalDomain, hash, approver, key, now)
+}
+
+// checkApprovals checks that approvals contain at least policy.Required
+// valid signatures on req from distinct approvers.
+func checkApprovals(req *Request, approvals []Approval, policy Policy) error {
+	hash, err := req.Hash()
+	if err != nil {
+		return fmt.Errorf("hashing request: %w", err)
+	}
+	return approval.Quorum{Approvers: policy.Approvers, Required: policy.Required}.Check(approvalDomain, hash, approvals)
+}
+
+// Share is one storage location of a party's share.
+type Share struct {
+	Wiper Wiper
+	ID    string
+}
+
+// WipeRecord records that a share was wiped from a storage location.
+type WipeRecord struct {
+	Location string    `json:"location"`
+	ID       string    `json:"id"`
+	WipedAt  time.Time `json:"wiped_at"`
+}
+
+// Statement is a party's signed declaration that it destroyed its share.
+type Statement struct {
+	Party       string       `json:"party"`
+	RequestHash []byte       `json:"request_hash"`
+	KeyID       string       `json:"key_id"`
+	Wiped       []WipeRecord `json:"wiped"`
+	Signature   []byte       `json:"signature"`
+}
+
+// digest returns the hash signed by the party: that of the statement
+// without its signature.
+func (s *Statement) digest() ([]byte, error) {
+	unsigned := *s
+	unsigned.Signature = nil
+	return hashJSON(&unsigned)
+}
+
+// verify checks that s is party's statement for the request with hash
+// requestHash.
+func (s *Statement) verify(requestHash []byte, policy Policy) error {
+	key, ok := policy.Parties[s.Party]
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: unknown party %q", ErrBadStatement, s.Party)
+	}
+	if !bytes.Equal(s.RequestHash, requestHash) {
+		return fmt.Errorf("%w: party %s answered a different request", ErrBadStatement, s.Party)
+	}
+	if len(s.Wiped) == 0 {
+		return fmt.Errorf("%w: party %s wiped nothing", ErrBadStatement, s.Party)
+	}
+	digest, err := s.digest()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, digest, s.Signature) {
+		return fmt.Errorf("%w: signature of party %s does not verify", ErrBadStatement, s.Party)
+	}
+	return nil
+}
+
+// DestroyConfig contains a party's configuration for Destroy.
+type DestroyConfig struct {
+	Party  string
+	Key    ed25519.PrivateKey // Signs the statement
+	Policy Policy
+	Shares []Share // Every location holding the party's share
+	Now    func() time.Time
+}
+
+// Destroy checks that req names the party and is approved, wipes the party's
+// share from every location and returns the signed statement.  It stops at
+// the first location that cannot be wiped; running it again after fixing the
+// cause fails on the locations already wiped, so those should then be
+// removed from Shares.
+func Destroy(ctx context.Context, config DestroyConfig, req *Request, approvals []Approval) (*Statement, error) {
+	if len(config.Key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("statement key must be provided")
+	}
+	if len(config.Shares) == 0 {
+		return nil, fmt.Errorf("at least one share location must be provided")
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	named := false
+	for _, p := range req.Parties {
+		named = named || p == config.Party
+	}
+	if !named {
+		return nil, fmt.Errorf("party %q is not named in the request", config.Party)
+	}
+	if err := checkApprovals(req, approvals, config.Policy); err != nil {
+		return nil, err
+	}
+	hash, err := req.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing request: %w", err)
+	}
+
+	s := &Statement{Party: config.Party, RequestHash: hash, KeyID: req.KeyID}
+	for _, share := range config.Shares {
+		if err := share.Wiper.Wipe(ctx, share.ID); err != nil {
+			return nil, fmt.Errorf("wiping %s on %s: %w", share.ID, share.Wiper.Name(), err)
+		}
+		s.Wiped = append(s.Wiped, WipeRecord{Location: share.Wiper.Name(), ID: share.ID, WipedAt: now().UTC()})
+	}
+	digest, err := s.digest()
+	if err != nil {
+		return nil, fmt.Errorf("hashing statement: %w", err)
+	}
+	s.Signature = ed25519.Sign(config.Key, digest)
+	return s, nil
+}
+
+// Certificate records the approved destruction of every share of a key.
+type Certificate struct {
+	Request    Request     `json:"request"`
+	Approvals  []Approval  `json:"approvals"`
+	Statements []Statement `json:"statements"`
+	IssuedAt   time.Time   `json:"issued_at"`
+}
+
+// SignedCertificate is a Certificate signed with the coordinator's Ed25519
+// key.
+type SignedCertificate struct {
+	Certificate Certificate       `json:"certificate"`
+	PublicKey   ed25519.PublicKey `json:"public_key"`
+	Signature   []byte            `json:"signature"`
+}
+
+// Certify checks the approvals and the statements, which must include exactly
+// one valid statement from every party named in req, and issues the signed
+// certificate.
+func Certify(req *Request, approvals []Approval, statements []Statement, policy Policy, key ed25519.PrivateKey, now time.Time) (*SignedCertificate, error) {
+	c := Certificate{Request: *req, Approvals: approvals, Statements: statements, IssuedAt: now.UTC()}
+	if err := c.check(policy); err != nil {
+		return nil, err
+	}
+	hash, err := hashJSON(&c)
+	if err != nil {
+		return nil, fmt.Errorf("hashing certificate: %w", err)
+	}
+	return &SignedCertificate{
+		Certificate: c,
+		PublicKey:   key.Public().(ed25519.PublicKey),
+		Signature:   ed25519.Sign(key, hash),
+	}, nil
+}
+
+// check verifies the approvals and that every party has exactly one valid
+// statement.
+func (c *Certificate) check(policy Policy) error {
+	if err := checkApprovals(&c.Request, c.Approvals, policy); err != nil {
+		return err
+	}
+	hash, err := c.Request.Hash()
+	if err != nil {
+		return fmt.Errorf("hashing request: %w", err)
+	}
+	stated := make(map[string]bool)
+	for i := range c.Statements {
+		s := &c.Statements[i]
+		if stated[s.Party] {
+			return fmt.Errorf("%w: duplicate statement from party %s", ErrBadStatement, s.Party)
+		}
+		if err := s.verify(hash, policy); err != nil {
+			return err
+		}
+		stated[s.Party] = true
+	}
+	for _, p := range c.Request.Parties {
+		if !stated[p] {
+			return fmt.Errorf("%w: no statement from party %s", ErrBadStatement, p)
+		}
+	}
+	if len(stated) != len(c.Request.Parties) {
+		return fmt.Errorf("%w: statement from a party not named in the request", ErrBadStatement)
+	}
+	return nil
+}
+
+// Verify checks the certificate signature against the embedded public key
+// and re-checks the approvals and statements against policy.  Callers must
+// additionally check that PublicKey belongs to a trusted coordinator.
+func (s *SignedCertificate) Verify(policy Policy) error {
+	if len(s.PublicKey) != ed25519.PublicKeySize {
+		return ErrBadCertificate
+	}
+	hash, err := hashJSON(&s.Certificate)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(s.PublicKey, hash, s.Signature) {
+		return ErrBadCertificate
+	}
+	return s.Certificate.check(policy)
+}
+
+func hashJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
@@ -0,0 +1,160 @@
+package retire
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+)
+
+var parties = []string{"server", "kms", "pin"}
+
+type fixture struct {
+	policy    Policy
+	approvers *approvaltest.Signers
+	parties   *approvaltest.Signers
+	dirs      map[string]string
+	req       *Request
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{
+		approvers: approvaltest.New(t, "alice", "bob", "carol"),
+		parties:   approvaltest.New(t, parties...),
+		dirs:      map[string]string{},
+	}
+	f.policy = Policy{Approvers: f.approvers.Public, Required: 2, Parties: f.parties.Public}
+	for _, party := range parties {
+		f.dirs[party] = t.TempDir()
+		medium, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: f.dirs[party], Key: bytes.Repeat([]byte{7}, 32)})
+		require.NoError(t, err)
+		require.NoError(t, medium.Store(context.Background(), party, []byte("share of "+party)))
+	}
+	var err error
+	f.req, err = NewRequest("treasury", bytes.Repeat([]byte{0xAB}, 32), parties, "key rotated", time.Now())
+	require.NoError(t, err)
+	return f
+}
+
+func (f *fixture) approve(t *testing.T, names ...string) []Approval {
+	t.Helper()
+	hash, err := f.req.Hash()
+	require.NoError(t, err)
+	return f.approvers.Approve(t, approvalDomain, hash, names...)
+}
+
+func (f *fixture) destroy(party string, approvals []Approval) (*Statement, error) {
+	return Destroy(context.Background(), DestroyConfig{
+		Party:  party,
+		Key:    f.parties.Private[party],
+		Policy: f.policy,
+		Shares: []Share{{Wiper: NewFileWiper(f.dirs[party]), ID: party}},
+	}, f.req, approvals)
+}
+
+func TestRetire(t *testing.T) {
+	f := newFixture(t)
+	approvals := f.approve(t, "alice", "bob")
+
+	var statements []Statement
+	for _, party := range parties {
+		s, err := f.destroy(party, approvals)
+		require.NoError(t, err)
+		require.Len(t, s.Wiped, 1)
+		assert.Equal(t, "file:"+f.dirs[party], s.Wiped[0].Location)
+		_, err = os.Stat(filepath.Join(f.dirs[party], party))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		statements = append(statements, *s)
+	}
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	cert, err := Certify(f.req, approvals, statements, f.policy, hostKey, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, cert.Verify(f.policy))
+
+	// The certificate survives archiving as JSON.
+	data, err := json.Marshal(cert)
+	require.NoError(t, err)
+	var archived SignedCertificate
+	require.NoError(t, json.Unmarshal(data, &archived))
+	require.NoError(t, archived.Verify(f.policy))
+
+	archived.Certificate.Request.Reason = "something else"
+	assert.ErrorIs(t, archived.Verify(f.policy), ErrBadCertificate)
+}
+
+func TestDestroyRequiresQuorum(t *testing.T) {
+	f := newFixture(t)
+	approvals := f.approve(t, "alice")
+	// A repeated approval by the same approver does not count twice.
+	approvals = append(approvals, approvals[0])
+	// Neither does an approval by someone outside the policy.
+	_, mallory, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	a, err := Approve(f.req, "mallory", mallory, time.Now())
+	require.NoError(t, err)
+	approvals = append(approvals, *a)
+
+	_, err = f.destroy("kms", approvals)
+	assert.ErrorIs(t, err, ErrNotApproved)
+	_, err = os.Stat(filepath.Join(f.dirs["kms"], "kms"))
+	assert.NoError(t, err, "share must survive an unapproved request")
+}
+
+func TestDestroyRejectsApprovalOfOtherRequest(t *testing.T) {
+	f := newFixture(t)
+	approvals := f.approve(t, "alice", "bob")
+	f.req.KeyID = "other"
+	_, err := f.destroy("kms", approvals)
+	assert.ErrorIs(t, err, ErrNotApproved)
+}
+
+func TestDestroyRejectsUnnamedParty(t *testing.T) {
+	f := newFixture(t)
+	f.req.Parties = []string{"server", "pin"}
+	_, err := f.destroy("kms", f.approve(t, "alice", "bob"))
+	assert.Error(t, err)
+}
+
+func TestCertifyRequiresEveryParty(t *testing.T) {
+	f := newFixture(t)
+	approvals := f.approve(t, "bob", "carol")
+	var statements []Statement
+	for _, party := range parties[:2] {
+		s, err := f.destroy(party, approvals)
+		require.NoError(t, err)
+		statements = append(statements, *s)
+	}
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = Certify(f.req, approvals, statements, f.policy, hostKey, time.Now())
+	assert.ErrorIs(t, err, ErrBadStatement)
+
+	_, err = Certify(f.req, approvals, append(statements, statements[0]), f.policy, hostKey, time.Now())
+	assert.ErrorIs(t, err, ErrBadStatement)
+
+	forged := statements[1]
+	forged.Party = "pin"
+	_, err = Certify(f.req, approvals, append(statements, forged), f.policy, hostKey, time.Now())
+	assert.ErrorIs(t, err, ErrBadStatement)
+}
+
+func TestFileWiperFailsWithoutShare(t *testing.T) {
+	w := NewFileWiper(t.TempDir())
+	assert.Error(t, w.Wipe(context.Background(), "missing"))
+	assert.Error(t, w.Wipe(context.Background(), "../escape"))
+}
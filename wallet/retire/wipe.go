@@ -0,0 +1,87 @@
+package retire
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Wiper securely deletes stored share data.
+type Wiper interface {
+	// Name identifies the storage location in statements.
+	Name() string
+	// Wipe destroys the data stored under id.  It fails if there is none.
+	Wipe(ctx context.Context, id string) error
+}
+
+// validID matches the share IDs accepted by keystore.FileMedium.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// FileWiper wipes files in a directory, such as the files of a
+// keystore.FileMedium.
+type FileWiper struct {
+	dir string
+}
+
+// Ensure FileWiper implements the Wiper interface
+var _ Wiper = (*FileWiper)(nil)
+
+// NewFileWiper returns a FileWiper for dir.
+func NewFileWiper(dir string) *FileWiper {
+	return &FileWiper{dir: dir}
+}
+
+// Name implements Wiper.
+func (w *FileWiper) Name() string { return "file:" + w.dir }
+
+// Wipe implements Wiper.  It overwrites the file with random data, syncs it,
+// removes it and syncs the directory.
+func (w *FileWiper) Wipe(_ context.Context, id string) error {
+	if !validID.MatchString(id) || id == "." || id == ".." {
+		return fmt.Errorf("invalid share ID %q", id)
+	}
+	path := filepath.Join(w.dir, id)
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no share %s in %s", id, w.dir)
+	}
+	if err != nil {
+		return fmt.Errorf("opening share: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("reading share size: %v", err)
+	}
+	if _, err := io.CopyN(f, rand.Reader, info.Size()); err != nil {
+		f.Close()
+		return fmt.Errorf("overwriting share: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing share: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("removing share: %v", err)
+	}
+	d, err := os.Open(w.dir)
+	if err != nil {
+		return fmt.Errorf("opening directory: %v", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing directory: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("share %s still present after removal", id)
+	}
+	return nil
+}
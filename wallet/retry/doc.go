@@ -0,0 +1,20 @@
+// Package retry repeats failed RPC calls with exponential backoff and jitter.
+//
+// Whether a call is repeated depends on the Class of its error.  Chains
+// classify the errors of their RPC clients and attach the Class with an
+// *Error, e.g. solana.Classify distinguishes rate limiting (HTTP 429), nodes
+// that lag the cluster and permanent failures such as rejected transactions.
+// Unclassified errors are permanent unless they are network errors, so
+// nothing is retried by accident.
+//
+//	policy := retry.Policy{Classify: solana.Classify, Budget: retry.NewBudget(0.1, 20)}
+//	err := policy.Do(ctx, func(ctx context.Context) error {
+//	    balance, err = client.GetBalance(ctx, key, rpc.CommitmentFinalized)
+//	    return err
+//	})
+//
+// A Budget shared by all calls to an endpoint caps the extra load retries
+// add during an outage.  Polling loops, such as waiting for a transaction to
+// be confirmed, use a Policy with negative Attempts and return a Transient
+// *Error while the awaited state is not reached yet.
+package retry
@@ -0,0 +1,238 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAttempts   = 5
+	defaultInitial    = 200 * time.Millisecond
+	defaultMax        = 10 * time.Second
+	defaultMultiplier = 2
+	defaultJitter     = 0.2
+)
+
+// ErrBudgetExhausted is returned by Do when a failed call could have been
+// retried but the Policy's Budget has no retries left.
+var ErrBudgetExhausted = errors.New("retry: budget exhausted")
+
+// Class tells Do whether and how a failed call is retried.
+type Class uint8
+
+const (
+	// Permanent errors are returned at once: invalid requests, rejected
+	// transactions, missing accounts.
+	Permanent Class = iota
+	// Transient errors, such as dropped connections and server errors, are
+	// retried with exponential backoff.
+	Transient
+	// RateLimited errors are retried after Error.RetryAfter if the server
+	// sent one, and otherwise after twice the usual backoff.
+	RateLimited
+	// NodeBehind errors come from a node that lags the cluster and does not
+	// have the requested state yet.  They are retried like Transient ones.
+	NodeBehind
+)
+
+// String returns the symbolic name of the Class.
+func (c Class) String() string {
+	switch c {
+	case Permanent:
+		return "permanent"
+	case Transient:
+		return "transient"
+	case RateLimited:
+		return "rate-limited"
+	case NodeBehind:
+		return "node-behind"
+	default:
+		return "unknown"
+	}
+}
+
+// Error attaches a Class to an error, e.g. by a chain's RPC classifier.
+type Error struct {
+	Class      Class
+	RetryAfter time.Duration // Delay requested by the server, if any
+	Err        error
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%v (%v)", e.Err, e.Class) }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Classify returns the Class of err: the Class of an *Error in its chain,
+// Transient for network errors and Permanent for everything else, including
+// cancelled contexts.
+func Classify(err error) Class {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Class
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Permanent
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return Transient
+	}
+	return Permanent
+}
+
+// Retryable reports whether err may succeed when the call is repeated.
+func Retryable(err error) bool {
+	return err != nil && Classify(err) != Permanent
+}
+
+// Policy describes how often and how patiently a call is retried.  The
+// zero Policy retries five times, starting at 200ms and doubling up to 10s,
+// with 20% jitter.
+type Policy struct {
+	// Attempts bounds the calls made, including the first.  Zero means 5;
+	// a negative value retries until ctx is done.
+	Attempts int
+	// Initial is the delay before the first retry.  Defaults to 200ms.
+	Initial time.Duration
+	// Max caps the delay between attempts.  Defaults to 10s.
+	Max time.Duration
+	// Multiplier grows the delay after every retry.  Defaults to 2.
+	Multiplier float64
+	// Jitter randomises every delay by up to this fraction in either
+	// direction, so that clients failing together do not retry together.
+	// Defaults to 0.2; a negative value disables it.
+	Jitter float64
+	// Classify decides which errors are retried.  Defaults to Classify.
+	Classify func(error) Class
+	// Budget, if set, limits retries across all calls sharing it.
+	Budget *Budget
+	// Sleep waits between attempts.  Defaults to a timer; tests may
+	// replace it to run without waiting.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Do calls fn until it succeeds, fails with an error that is not
+// retryable, or the attempts, the budget or ctx run out.  It returns fn's
+// last error, wrapped with ErrBudgetExhausted when the budget stopped it.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	p = p.withDefaults()
+	p.Budget.deposit()
+	delay := p.Initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		class := p.Classify(err)
+		if class == Permanent || ctx.Err() != nil {
+			return err
+		}
+		if p.Attempts > 0 && attempt >= p.Attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		if !p.Budget.withdraw() {
+			return fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+		}
+
+		wait := p.jitter(delay)
+		if class == RateLimited {
+			var e *Error
+			if errors.As(err, &e) && e.RetryAfter > 0 {
+				wait = e.RetryAfter
+			} else {
+				wait *= 2
+			}
+		}
+		if serr := p.Sleep(ctx, wait); serr != nil {
+			return fmt.Errorf("%w: %w", serr, err)
+		}
+		delay = min(time.Duration(float64(delay)*p.Multiplier), p.Max)
+	}
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.Attempts == 0 {
+		p.Attempts = defaultAttempts
+	}
+	if p.Initial <= 0 {
+		p.Initial = defaultInitial
+	}
+	if p.Max <= 0 {
+		p.Max = defaultMax
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = defaultMultiplier
+	}
+	if p.Jitter == 0 {
+		p.Jitter = defaultJitter
+	}
+	if p.Classify == nil {
+		p.Classify = Classify
+	}
+	if p.Sleep == nil {
+		p.Sleep = sleep
+	}
+	return p
+}
+
+func (p Policy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Budget limits retries across calls, so that an outage does not multiply
+// the load on an endpoint that is already struggling.  Every call earns
+// Ratio retries, up to Max saved; every retry spends one.  With Ratio 0.1
+// at most one call in ten is retried once the savings are spent.
+type Budget struct {
+	mu     sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+// NewBudget returns a Budget earning ratio retries per call, holding at most
+// max and starting full.
+func NewBudget(ratio float64, max int) *Budget {
+	return &Budget{ratio: ratio, max: float64(max), tokens: float64(max)}
+}
+
+func (b *Budget) deposit() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+b.ratio, b.max)
+}
+
+func (b *Budget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordSleeps returns a Policy that does not wait but records its delays.
+func recordSleeps(p Policy, delays *[]time.Duration) Policy {
+	p.Jitter = -1
+	p.Sleep = func(_ context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+	return p
+}
+
+// failing returns errors from errs in turn, then succeeds.
+func failing(calls *int, errs ...error) func(context.Context) error {
+	return func(context.Context) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+var (
+	errFlaky   = &Error{Class: Transient, Err: errors.New("connection reset")}
+	errBehind  = &Error{Class: NodeBehind, Err: errors.New("node is behind")}
+	errLimited = &Error{Class: RateLimited, Err: errors.New("429")}
+)
+
+func TestBackoff(t *testing.T) {
+	var delays []time.Duration
+	p := recordSleeps(Policy{Initial: time.Second, Max: 3 * time.Second}, &delays)
+	calls := 0
+	require.NoError(t, p.Do(context.Background(), failing(&calls, errFlaky, errBehind, errFlaky, errLimited)))
+	assert.Equal(t, 5, calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 6 * time.Second}, delays,
+		"doubling up to Max, and twice that when rate limited")
+
+	delays = nil
+	calls = 0
+	limited := &Error{Class: RateLimited, RetryAfter: 7 * time.Second, Err: errors.New("429")}
+	require.NoError(t, p.Do(context.Background(), failing(&calls, limited)))
+	assert.Equal(t, []time.Duration{7 * time.Second}, delays, "the server's Retry-After wins")
+}
+
+func TestDoStops(t *testing.T) {
+	var delays []time.Duration
+	p := recordSleeps(Policy{Attempts: 3}, &delays)
+	ctx := context.Background()
+
+	calls := 0
+	rejected := errors.New("transaction rejected")
+	assert.Equal(t, rejected, p.Do(ctx, failing(&calls, rejected)))
+	assert.Equal(t, 1, calls, "permanent errors are not retried")
+
+	calls = 0
+	err := p.Do(ctx, failing(&calls, errFlaky, errFlaky, errFlaky, errFlaky))
+	assert.ErrorIs(t, err, errFlaky)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, Transient, Classify(err), "the class survives for callers further up")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	p.Sleep = nil
+	calls = 0
+	err = p.Do(cancelled, failing(&calls, errFlaky, errFlaky))
+	assert.ErrorIs(t, err, errFlaky)
+	assert.Equal(t, 1, calls)
+}
+
+func TestBudget(t *testing.T) {
+	var delays []time.Duration
+	b := NewBudget(0.5, 2)
+	p := recordSleeps(Policy{Attempts: -1, Budget: b}, &delays)
+	ctx := context.Background()
+
+	calls := 0
+	err := p.Do(ctx, failing(&calls, errFlaky, errFlaky, errFlaky, errFlaky))
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+	assert.ErrorIs(t, err, errFlaky)
+	assert.Equal(t, 3, calls, "two saved retries")
+
+	// Every call earns half a retry.
+	calls = 0
+	require.NoError(t, p.Do(ctx, failing(&calls)))
+	calls = 0
+	err = p.Do(ctx, failing(&calls, errFlaky))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	calls = 0
+	assert.ErrorIs(t, p.Do(ctx, failing(&calls, errFlaky)), ErrBudgetExhausted)
+}
+
+func TestClassify(t *testing.T) {
+	assert.Equal(t, Permanent, Classify(errors.New("invalid params")))
+	assert.Equal(t, Permanent, Classify(context.DeadlineExceeded))
+	assert.Equal(t, NodeBehind, Classify(errBehind))
+	assert.True(t, Retryable(errLimited))
+	assert.False(t, Retryable(nil))
+	assert.Equal(t, "rate-limited", RateLimited.String())
+}
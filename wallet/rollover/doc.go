@@ -0,0 +1,31 @@
+// Package rollover moves everything a wallet holds to a wallet controlled by
+// a brand-new key, for when a key is replaced rather than refreshed.
+//
+// `NewPlan` enumerates the old wallet's holdings through chain.Inventory and
+// orders the moves:
+//
+//  1. token balances in associated token accounts, each a transfer signed
+//     by the old key through the coordinator;
+//  2. manual steps for holdings a transfer cannot move, such as stake
+//     accounts whose authorities have to be reassigned, or tokens in other
+//     token accounts;
+//  3. a sweep of the native balance less the sweep's own fee.
+//
+// The native balance goes last because it pays the fees and token account
+// rent of every other step, and a `Migrator` does not sweep it while manual
+// steps are outstanding.  Each step runs as an ordinary coordinator session,
+// so policies, approvals and the audit trail apply as for any other
+// transfer.
+//
+//	plan, _ := rollover.NewPlan(ctx, solanaChain, oldAddress, newAddress)
+//	m, _ := rollover.New(rollover.Config{Coordinator: coord, Path: "rollover.json"})
+//	err := m.Run(ctx, plan)
+//	// ErrAwaitingApproval: approve the session, then Run again.
+//	// ErrManualSteps: do them, plan.MarkDone(i, "…"), then Run again.
+//
+// With Config.Path set the plan and its progress are saved after every
+// change; after a crash `LoadPlan` reads it back and Run continues where it
+// stopped.  Sessions carry a reference derived from the plan ID and step, so
+// one submitted just before a crash is picked up rather than submitted
+// twice.
+package rollover
@@ -0,0 +1,178 @@
+package rollover
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+)
+
+// Step kinds.
+const (
+	StepToken  = "token"  // Move a token balance through a signing session
+	StepManual = "manual" // A holding the planner cannot move; an operator has to
+	StepNative = "native" // Sweep whatever native balance is left; always last
+)
+
+// StepState is the progress of a step.
+type StepState string
+
+const (
+	StatePending   StepState = "pending"   // Not started
+	StateSubmitted StepState = "submitted" // A signing session is under way
+	StateDone      StepState = "done"      // Moved, or marked done by an operator
+	StateFailed    StepState = "failed"    // The session failed; the next run retries
+)
+
+// Step is one move of a Plan.
+type Step struct {
+	Kind    string   `json:"kind"`
+	Token   string   `json:"token,omitempty"` // Token mint or contract address
+	Account string   `json:"account"`         // Account holding the asset
+	Amount  *big.Int `json:"amount"`          // Balance when planned
+	// Note tells the operator what to do for a manual step, and records
+	// what they reported when marking it done.
+	Note string `json:"note,omitempty"`
+
+	State   StepState `json:"state"`
+	Session string    `json:"session,omitempty"` // Current signing session
+	TxID    string    `json:"tx_id,omitempty"`
+	Err     string    `json:"error,omitempty"` // Why the last session failed
+}
+
+// Plan is the ordered list of moves that empties From into To.
+type Plan struct {
+	ID      string    `json:"id"`
+	Chain   string    `json:"chain"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Created time.Time `json:"created"`
+	Steps   []Step    `json:"steps"`
+}
+
+// NewPlan enumerates the holdings of from on ch, which has to implement
+// chain.Inventory, and plans moving them to to.  Transferable tokens come
+// first, then a manual step for every holding BuildTransfer cannot move,
+// such as stake accounts, and finally the native sweep, which pays for all
+// the others.  Holdings arriving after planning are not part of the plan;
+// plan again once it is done to pick them up.
+func NewPlan(ctx context.Context, ch chain.Chain, from, to string) (*Plan, error) {
+	inv, ok := ch.(chain.Inventory)
+	if !ok {
+		return nil, fmt.Errorf("chain %q cannot enumerate holdings", ch.ID())
+	}
+	if from == "" || to == "" || from == to {
+		return nil, fmt.Errorf("rollover needs two distinct addresses")
+	}
+	holdings, err := inv.Holdings(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("listing holdings: %w", err)
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	p := &Plan{ID: hex.EncodeToString(id[:]), Chain: ch.ID(), From: from, To: to, Created: time.Now().UTC()}
+
+	var native *chain.Holding
+	var manual []Step
+	for i, h := range holdings {
+		switch {
+		case h.Kind == chain.HoldingNative:
+			native = &holdings[i]
+		case h.Kind == chain.HoldingToken && h.Transferable:
+			p.Steps = append(p.Steps, Step{Kind: StepToken, Token: h.Token, Account: h.Account, Amount: h.Amount, State: StatePending})
+		case h.Kind == chain.HoldingStake:
+			manual = append(manual, Step{Kind: StepManual, Account: h.Account, Amount: h.Amount, State: StatePending,
+				Note: fmt.Sprintf("set the stake and withdraw authorities of %s to %s", h.Account, to)})
+		default:
+			manual = append(manual, Step{Kind: StepManual, Token: h.Token, Account: h.Account, Amount: h.Amount, State: StatePending,
+				Note: fmt.Sprintf("move %s %s from %s to %s", h.Amount, h.Kind, h.Account, to)})
+		}
+	}
+	p.Steps = append(p.Steps, manual...)
+	if native != nil {
+		p.Steps = append(p.Steps, Step{Kind: StepNative, Account: native.Account, Amount: native.Amount, State: StatePending})
+	}
+	return p, nil
+}
+
+// Done reports whether every step is done.
+func (p *Plan) Done() bool {
+	for _, s := range p.Steps {
+		if s.State != StateDone {
+			return false
+		}
+	}
+	return true
+}
+
+// MarkDone records that an operator completed manual step index.
+func (p *Plan) MarkDone(index int, note string) error {
+	if index < 0 || index >= len(p.Steps) {
+		return fmt.Errorf("no step %d", index)
+	}
+	s := &p.Steps[index]
+	if s.Kind != StepManual {
+		return fmt.Errorf("step %d is not a manual step", index)
+	}
+	s.State = StateDone
+	if note != "" {
+		s.Note = note
+	}
+	return nil
+}
+
+// reference identifies the session of a step so that a session submitted
+// before a crash is found again.
+func (p *Plan) reference(index int) string {
+	return fmt.Sprintf("rollover:%s:%d", p.ID, index)
+}
+
+// LoadPlan reads a plan saved by Save.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("decoding plan: %w", err)
+	}
+	if p.ID == "" || p.From == "" || p.To == "" {
+		return nil, errors.New("decoding plan: missing ID or addresses")
+	}
+	return &p, nil
+}
+
+// Save writes the plan to path, replacing it atomically.
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rollover-*")
+	if err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	return nil
+}
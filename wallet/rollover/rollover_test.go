@@ -0,0 +1,225 @@
+package rollover
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+const fee = 5000
+
+// ledger is a chain whose transactions are "from|to|amount|token" strings
+// applied to an in-memory balance sheet when broadcast.
+type ledger struct {
+	native map[string]int64
+	tokens map[string]int64 // "owner/mint"
+	stakes map[string]int64 // stake account of old
+	sent   int
+}
+
+func (l *ledger) ID() string { return "fake" }
+
+func (l *ledger) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (l *ledger) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	payload := []byte(fmt.Sprintf("%s|%s|%s|%s", t.From, t.To, t.Amount, t.Token))
+	return &chain.UnsignedTx{Chain: l.ID(), Payload: payload, SigningPayload: payload}, nil
+}
+
+func (l *ledger) Decode(payload []byte) (*chain.Summary, error) {
+	f := strings.Split(string(payload), "|")
+	amount, _ := new(big.Int).SetString(f[2], 10)
+	return &chain.Summary{Chain: l.ID(), From: f[0], To: f[1], Amount: amount, Fee: big.NewInt(fee), Token: f[3]}, nil
+}
+
+func (l *ledger) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (l *ledger) Broadcast(_ context.Context, tx *chain.SignedTx) (string, error) {
+	s, _ := l.Decode(tx.Unsigned.Payload)
+	if s.Token == "" {
+		l.native[s.From] -= s.Amount.Int64()
+		l.native[s.To] += s.Amount.Int64()
+	} else {
+		l.tokens[s.From+"/"+s.Token] -= s.Amount.Int64()
+		l.tokens[s.To+"/"+s.Token] += s.Amount.Int64()
+	}
+	l.native[s.From] -= fee
+	l.sent++
+	return fmt.Sprintf("tx-%d", l.sent), nil
+}
+
+func (l *ledger) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized}, nil
+}
+
+func (l *ledger) Quote(context.Context, *chain.Transfer) (*chain.Quote, error) {
+	return &chain.Quote{Fee: big.NewInt(fee), Deposit: new(big.Int)}, nil
+}
+
+func (l *ledger) Holdings(_ context.Context, address string) ([]chain.Holding, error) {
+	hs := []chain.Holding{{Kind: chain.HoldingNative, Account: address, Transferable: true, Amount: big.NewInt(l.native[address])}}
+	for _, mint := range []string{"usdc", "wif"} {
+		if v := l.tokens[address+"/"+mint]; v > 0 {
+			hs = append(hs, chain.Holding{Kind: chain.HoldingToken, Token: mint, Account: address + "-" + mint, Transferable: true, Amount: big.NewInt(v)})
+		}
+	}
+	if v := l.tokens[address+"/aux"]; v > 0 {
+		hs = append(hs, chain.Holding{Kind: chain.HoldingToken, Token: "aux", Account: "aux-account", Amount: big.NewInt(v)})
+	}
+	for account, v := range l.stakes {
+		hs = append(hs, chain.Holding{Kind: chain.HoldingStake, Account: account, Amount: big.NewInt(v)})
+	}
+	return hs, nil
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(context.Context, *coordinator.SignRequest) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+func newTest(t *testing.T, l *ledger, policy coordinator.Policy) (*coordinator.Coordinator, *Migrator, string) {
+	t.Helper()
+	c, err := coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{l},
+		Signer:          fakeSigner{},
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "rollover.json")
+	m, err := New(Config{Coordinator: c, Path: path})
+	require.NoError(t, err)
+	return c, m, path
+}
+
+func TestRollover(t *testing.T) {
+	l := &ledger{
+		native: map[string]int64{"old": 1_000_000},
+		tokens: map[string]int64{"old/usdc": 50, "old/wif": 7, "old/aux": 3},
+		stakes: map[string]int64{"stake-1": 2_000_000},
+	}
+	_, m, path := newTest(t, l, nil)
+	ctx := context.Background()
+
+	plan, err := NewPlan(ctx, l, "old", "new")
+	require.NoError(t, err)
+	var kinds []string
+	for _, s := range plan.Steps {
+		kinds = append(kinds, s.Kind)
+	}
+	assert.Equal(t, []string{StepToken, StepToken, StepManual, StepManual, StepNative}, kinds)
+
+	// The tokens move, but the native balance stays to pay for the manual
+	// steps.
+	err = m.Run(ctx, plan)
+	assert.ErrorIs(t, err, ErrManualSteps)
+	assert.Equal(t, int64(50), l.tokens["new/usdc"])
+	assert.Equal(t, int64(7), l.tokens["new/wif"])
+	assert.Equal(t, int64(1_000_000-2*fee), l.native["old"])
+	assert.False(t, plan.Done())
+
+	// Progress survives a restart.
+	plan, err = LoadPlan(path)
+	require.NoError(t, err)
+	assert.Equal(t, StateDone, plan.Steps[0].State)
+	assert.Equal(t, "tx-1", plan.Steps[0].TxID)
+	require.Error(t, plan.MarkDone(0, ""))
+	require.NoError(t, plan.MarkDone(2, "authorities reassigned"))
+	require.NoError(t, plan.MarkDone(3, ""))
+
+	require.NoError(t, m.Run(ctx, plan))
+	assert.True(t, plan.Done())
+	assert.Equal(t, int64(0), l.native["old"])
+	assert.Equal(t, int64(1_000_000-3*fee), l.native["new"])
+	assert.Equal(t, 3, l.sent)
+
+	// Running a finished plan does nothing.
+	require.NoError(t, m.Run(ctx, plan))
+	assert.Equal(t, 3, l.sent)
+}
+
+func TestRolloverResumesSessions(t *testing.T) {
+	l := &ledger{native: map[string]int64{"old": 1_000_000}, tokens: map[string]int64{"old/usdc": 50}}
+	policy := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		return &coordinator.Decision{Allow: true, RequiredApprovals: 1}, nil
+	})
+	c, m, _ := newTest(t, l, policy)
+	ctx := context.Background()
+
+	plan, err := NewPlan(ctx, l, "old", "new")
+	require.NoError(t, err)
+	err = m.Run(ctx, plan)
+	assert.ErrorIs(t, err, ErrAwaitingApproval)
+	session := plan.Steps[0].Session
+	require.NotEmpty(t, session)
+
+	// A crash lost the session ID; it is found again by its reference.
+	plan.Steps[0].Session, plan.Steps[0].State = "", StatePending
+	_, err = c.Approve(ctx, session, "carol")
+	require.NoError(t, err)
+	err = m.Run(ctx, plan)
+	assert.ErrorIs(t, err, ErrAwaitingApproval, "the sweep needs its own approval")
+	assert.Equal(t, session, plan.Steps[0].Session)
+	assert.Equal(t, StateDone, plan.Steps[0].State)
+
+	_, err = c.Approve(ctx, plan.Steps[1].Session, "carol")
+	require.NoError(t, err)
+	require.NoError(t, m.Run(ctx, plan))
+	assert.Equal(t, int64(50), l.tokens["new/usdc"])
+	assert.Equal(t, int64(0), l.native["old"])
+}
+
+func TestRolloverRetriesFailedSteps(t *testing.T) {
+	l := &ledger{native: map[string]int64{"old": 1_000_000}}
+	deny := true
+	policy := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		if deny {
+			return &coordinator.Decision{Reason: "frozen"}, nil
+		}
+		return &coordinator.Decision{Allow: true}, nil
+	})
+	_, m, _ := newTest(t, l, policy)
+	ctx := context.Background()
+
+	plan, err := NewPlan(ctx, l, "old", "new")
+	require.NoError(t, err)
+	err = m.Run(ctx, plan)
+	assert.ErrorIs(t, err, ErrStepFailed)
+	assert.Equal(t, StateFailed, plan.Steps[0].State)
+	assert.Contains(t, plan.Steps[0].Err, "frozen")
+
+	deny = false
+	require.NoError(t, m.Run(ctx, plan))
+	assert.Equal(t, int64(1_000_000-fee), l.native["new"])
+
+	// An empty wallet has nothing to sweep.
+	plan, err = NewPlan(ctx, l, "old", "new")
+	require.NoError(t, err)
+	require.NoError(t, m.Run(ctx, plan))
+	assert.Equal(t, "nothing left to sweep", plan.Steps[0].Note)
+	assert.Equal(t, 1, l.sent, "the denied session was never broadcast")
+}
+
+func TestNewPlanRequiresInventory(t *testing.T) {
+	_, err := NewPlan(context.Background(), plainChain{&ledger{}}, "old", "new")
+	assert.Error(t, err)
+	_, err = NewPlan(context.Background(), &ledger{}, "old", "old")
+	assert.Error(t, err)
+}
+
+// plainChain hides the optional interfaces of the ledger.
+type plainChain struct{ chain.Chain }
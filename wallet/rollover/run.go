@@ -0,0 +1,200 @@
+package rollover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+var (
+	// ErrAwaitingApproval is returned by Run when a session waits for
+	// approvals.  Run again once they are given.
+	ErrAwaitingApproval = errors.New("rollover: transfer awaiting approval")
+	// ErrManualSteps is returned by Run when every automatic step is done
+	// but manual ones are not, so the native balance is kept to pay for
+	// them.
+	ErrManualSteps = errors.New("rollover: manual steps pending")
+	// ErrStepFailed is returned by Run when a session failed.  Running
+	// again retries the step with a new session.
+	ErrStepFailed = errors.New("rollover: step failed")
+)
+
+// Config configures a Migrator.
+type Config struct {
+	// Coordinator runs the signing sessions.  Required.
+	Coordinator *coordinator.Coordinator
+	// Path, if set, is where the plan is saved after every change so that
+	// an interrupted run can be resumed with LoadPlan.
+	Path string
+	// Priority is the scheduling class of the sessions.
+	Priority coordinator.Priority
+}
+
+// Migrator drives a Plan through the coordinator.
+type Migrator struct {
+	c      *coordinator.Coordinator
+	config Config
+}
+
+// New creates a Migrator from the given configuration.
+func New(config Config) (*Migrator, error) {
+	if config.Coordinator == nil {
+		return nil, fmt.Errorf("coordinator must be provided")
+	}
+	return &Migrator{c: config.Coordinator, config: config}, nil
+}
+
+// Run executes the pending steps of p in order, one session at a time, and
+// returns nil once the plan is done.  It stops at the first step that
+// cannot complete yet and can be called again to resume: steps that are
+// done are skipped and submitted sessions are continued rather than
+// submitted again.
+func (m *Migrator) Run(ctx context.Context, p *Plan) error {
+	ch, ok := m.c.Chain(p.Chain)
+	if !ok {
+		return fmt.Errorf("unknown chain %q", p.Chain)
+	}
+	manual := 0
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		switch {
+		case s.State == StateDone:
+			continue
+		case s.Kind == StepManual:
+			manual++
+			continue
+		case s.Kind == StepNative && manual > 0:
+			return fmt.Errorf("%w: %d before the native sweep", ErrManualSteps, manual)
+		}
+		if err := m.step(ctx, ch, p, i); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i, s.Kind, err)
+		}
+	}
+	if manual > 0 {
+		return fmt.Errorf("%w: %d", ErrManualSteps, manual)
+	}
+	return nil
+}
+
+func (m *Migrator) step(ctx context.Context, ch chain.Chain, p *Plan, index int) error {
+	s := &p.Steps[index]
+	if s.State == StateFailed {
+		s.State, s.Session, s.Err = StatePending, "", ""
+	}
+	if s.Session == "" {
+		id, err := m.submit(ctx, ch, p, index)
+		if err != nil || id == "" {
+			return err
+		}
+		s.State, s.Session = StateSubmitted, id
+		if err := m.save(p); err != nil {
+			return err
+		}
+	}
+
+	session, err := m.c.Run(ctx, s.Session)
+	if err != nil {
+		return err
+	}
+	switch session.State {
+	case coordinator.StateFinalized:
+		s.State, s.TxID = StateDone, session.TxID
+	case coordinator.StateFailed:
+		s.State, s.Err = StateFailed, session.Err
+	default:
+		return ErrAwaitingApproval
+	}
+	if err := m.save(p); err != nil {
+		return err
+	}
+	if s.State == StateFailed {
+		return fmt.Errorf("%w: %s", ErrStepFailed, s.Err)
+	}
+	return nil
+}
+
+// submit returns the session of the step, submitting it unless a session
+// with its reference exists already.  It returns an empty ID and marks the
+// step done when there is nothing left to sweep.
+func (m *Migrator) submit(ctx context.Context, ch chain.Chain, p *Plan, index int) (string, error) {
+	// A session that failed was retried above; only one that is still
+	// running or finished is picked up again.
+	existing, err := m.c.List(ctx, coordinator.Filter{Reference: p.reference(index)})
+	if err != nil {
+		return "", err
+	}
+	for _, session := range existing {
+		if session.State != coordinator.StateFailed {
+			return session.ID, nil
+		}
+	}
+
+	s := &p.Steps[index]
+	transfer := chain.Transfer{From: p.From, To: p.To, Amount: s.Amount, Token: s.Token}
+	if s.Kind == StepNative {
+		amount, err := sweepAmount(ctx, ch, p)
+		if err != nil {
+			return "", err
+		}
+		if amount.Sign() <= 0 {
+			s.State, s.Note = StateDone, "nothing left to sweep"
+			return "", m.save(p)
+		}
+		transfer.Amount = amount
+	}
+	session, err := m.c.Submit(ctx, &coordinator.Request{
+		Chain:     p.Chain,
+		Transfer:  transfer,
+		Priority:  m.config.Priority,
+		Reference: p.reference(index),
+	})
+	if err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+// sweepAmount is the current native balance of p.From less the cost of
+// moving it.
+func sweepAmount(ctx context.Context, ch chain.Chain, p *Plan) (*big.Int, error) {
+	inv, ok := ch.(chain.Inventory)
+	if !ok {
+		return nil, fmt.Errorf("chain %q cannot enumerate holdings", p.Chain)
+	}
+	q, ok := ch.(chain.Quoter)
+	if !ok {
+		return nil, fmt.Errorf("chain %q cannot quote the sweep", p.Chain)
+	}
+	holdings, err := inv.Holdings(ctx, p.From)
+	if err != nil {
+		return nil, fmt.Errorf("listing holdings: %w", err)
+	}
+	balance := new(big.Int)
+	for _, h := range holdings {
+		if h.Kind == chain.HoldingNative {
+			balance = h.Amount
+		}
+	}
+	if balance.Sign() <= 0 {
+		return balance, nil
+	}
+	// The fee does not depend on the amount, so quote any amount the
+	// wallet can pay for.
+	quote, err := q.Quote(ctx, &chain.Transfer{From: p.From, To: p.To, Amount: new(big.Int).Rsh(balance, 1)})
+	if err != nil {
+		return nil, fmt.Errorf("quoting sweep: %w", err)
+	}
+	amount := new(big.Int).Sub(balance, quote.Fee)
+	return amount.Sub(amount, quote.Deposit), nil
+}
+
+func (m *Migrator) save(p *Plan) error {
+	if m.config.Path == "" {
+		return nil
+	}
+	return p.Save(m.config.Path)
+}
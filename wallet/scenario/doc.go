@@ -0,0 +1,39 @@
+// Package scenario runs YAML-scripted scenarios end to end against the whole
+// stack – MPC parties on a transport, coordinator, wallet and ledger – and
+// asserts the resulting balances.  The scenarios double as documentation of
+// how the pieces are used together and as regression tests for all of them.
+//
+// A scenario names its parties, transport and asset and lists its steps:
+//
+//	name: payout
+//	parties: 3
+//	transport: tcp
+//	steps:
+//	  - op: keygen
+//	  - op: fund
+//	    amount: "1"
+//	  - op: transfer
+//	    to: alice
+//	    amount: "0.1"
+//	    count: 5
+//	  - op: refresh
+//	  - op: recover
+//	    party: party_2
+//	  - op: sweep
+//	    to: cold
+//	  - op: expect
+//	    balances:
+//	      alice: "0.5"
+//	      wallet: "0"
+//	      cold: ">= 0.49"
+//
+// The operations are keygen, fund, transfer, refresh, recover, sweep and
+// expect; see Step.  Accounts other than the wallet itself are created empty
+// when first named, and expect checks their balances against an
+// `Expectation`.  testdata/ holds the scenarios CI runs.
+//
+// `Run` drives an `Env`, which owns the stack.  The cb-mpc-scenario command
+// provides one on a local validator (solana-test-validator) with the parties
+// inside its process; the package tests use an in-memory ledger, so every
+// scenario is also checked for consistency without a validator.
+package scenario
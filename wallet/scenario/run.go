@@ -0,0 +1,227 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// ErrUnexpectedBalance is returned by Run when an expect step fails.
+var ErrUnexpectedBalance = errors.New("scenario: unexpected balance")
+
+// Env is the stack a scenario runs against: normally MPC parties on a
+// transport, a coordinator and wallet, and a local validator.  Amounts are in
+// the asset's smallest unit.
+type Env interface {
+	// Keygen generates the wallet key, split among parties shares on the
+	// named curve, and returns the wallet's address.
+	Keygen(ctx context.Context, parties int, curve string) (string, error)
+	// NewAccount returns the address of a fresh, empty account.
+	NewAccount(ctx context.Context) (string, error)
+	// Fund pays amount into address from a faucet.
+	Fund(ctx context.Context, address string, amount *big.Int) error
+	// Transfer sends amount from the wallet to address through the wallet
+	// and waits until the transfer is final.
+	Transfer(ctx context.Context, to string, amount *big.Int) error
+	// Refresh re-shares the wallet key among its parties.
+	Refresh(ctx context.Context) error
+	// Recover discards the share of party and restores it from its backup.
+	Recover(ctx context.Context, party string) error
+	// Sweep sends the wallet's whole balance, less fees, to address and
+	// returns the amount sent.
+	Sweep(ctx context.Context, to string) (*big.Int, error)
+	// Balance returns the final balance of address.
+	Balance(ctx context.Context, address string) (*big.Int, error)
+}
+
+// Config configures Run.
+type Config struct {
+	Scenario *Scenario
+	Env      Env
+	// OnStep, if set, is called after every successful step, e.g. to print
+	// progress.
+	OnStep func(StepResult)
+}
+
+// StepResult records one executed step.
+type StepResult struct {
+	Index    int           `json:"index"`
+	Op       string        `json:"op"`
+	Detail   string        `json:"detail"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+func (r StepResult) String() string {
+	return fmt.Sprintf("step %d (%s): %s [%s]", r.Index, r.Op, r.Detail, r.Duration.Round(time.Millisecond))
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	Scenario string `json:"scenario"`
+	// Accounts maps the account names used in the scenario to their
+	// addresses.
+	Accounts map[string]string `json:"accounts"`
+	// Steps lists the steps that succeeded, in order.
+	Steps []StepResult `json:"steps"`
+}
+
+// Run executes the steps of config.Scenario against config.Env in order and
+// stops at the first step that fails, returning the report so far together
+// with an error naming the step.  Failed expectations wrap
+// ErrUnexpectedBalance.
+func Run(ctx context.Context, config Config) (*Report, error) {
+	if config.Scenario == nil {
+		return nil, fmt.Errorf("scenario must be provided")
+	}
+	if config.Env == nil {
+		return nil, fmt.Errorf("environment must be provided")
+	}
+	r := &run{
+		Config: config,
+		report: &Report{Scenario: config.Scenario.Name, Accounts: map[string]string{}},
+	}
+	for i, st := range config.Scenario.Steps {
+		if err := ctx.Err(); err != nil {
+			return r.report, err
+		}
+		start := time.Now()
+		detail, err := r.step(ctx, &st)
+		if err != nil {
+			return r.report, fmt.Errorf("step %d (%s): %w", i+1, st.Op, err)
+		}
+		res := StepResult{Index: i + 1, Op: st.Op, Detail: detail, Duration: time.Since(start)}
+		r.report.Steps = append(r.report.Steps, res)
+		if config.OnStep != nil {
+			config.OnStep(res)
+		}
+	}
+	return r.report, nil
+}
+
+type run struct {
+	Config
+	report *Report
+}
+
+func (r *run) step(ctx context.Context, st *Step) (string, error) {
+	s, asset := r.Scenario, r.Scenario.asset()
+	switch st.Op {
+	case OpKeygen:
+		if _, ok := r.report.Accounts[WalletAccount]; ok {
+			return "", fmt.Errorf("the wallet already has a key")
+		}
+		addr, err := r.Env.Keygen(ctx, s.Parties, st.Curve)
+		if err != nil {
+			return "", err
+		}
+		r.report.Accounts[WalletAccount] = addr
+		return fmt.Sprintf("%d-party %s key, wallet address %s", s.Parties, st.Curve, addr), nil
+
+	case OpFund:
+		addr, err := r.account(ctx, st.Account)
+		if err != nil {
+			return "", err
+		}
+		amount, _ := asset.ParseAmount(st.Amount)
+		if err := r.Env.Fund(ctx, addr, amount); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("funded %s with %s", st.Account, asset.Format(amount)), nil
+
+	case OpTransfer:
+		if _, err := r.wallet(); err != nil {
+			return "", err
+		}
+		to, err := r.account(ctx, st.To)
+		if err != nil {
+			return "", err
+		}
+		amount, _ := asset.ParseAmount(st.Amount)
+		for i := 0; i < st.Count; i++ {
+			if err := r.Env.Transfer(ctx, to, amount); err != nil {
+				return "", fmt.Errorf("transfer %d of %d: %w", i+1, st.Count, err)
+			}
+		}
+		return fmt.Sprintf("sent %d × %s to %s", st.Count, asset.Format(amount), st.To), nil
+
+	case OpRefresh:
+		if _, err := r.wallet(); err != nil {
+			return "", err
+		}
+		return "shares refreshed", r.Env.Refresh(ctx)
+
+	case OpRecover:
+		if _, err := r.wallet(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("share of %s restored from backup", st.Party), r.Env.Recover(ctx, st.Party)
+
+	case OpSweep:
+		if _, err := r.wallet(); err != nil {
+			return "", err
+		}
+		to, err := r.account(ctx, st.To)
+		if err != nil {
+			return "", err
+		}
+		amount, err := r.Env.Sweep(ctx, to)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("swept %s to %s", asset.Format(amount), st.To), nil
+
+	case OpExpect:
+		names := make([]string, 0, len(st.Balances))
+		for name := range st.Balances {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			addr, err := r.account(ctx, name)
+			if err != nil {
+				return "", err
+			}
+			balance, err := r.Env.Balance(ctx, addr)
+			if err != nil {
+				return "", fmt.Errorf("balance of %s: %w", name, err)
+			}
+			want, _ := s.expectation(st.Balances[name])
+			if !want.Holds(balance) {
+				return "", fmt.Errorf("%w: %s holds %s, want %s", ErrUnexpectedBalance, name, asset.Format(balance), st.Balances[name])
+			}
+		}
+		return fmt.Sprintf("%d balances as expected", len(names)), nil
+
+	default:
+		return "", fmt.Errorf("unknown operation %q", st.Op)
+	}
+}
+
+// wallet returns the wallet's address, once a key was generated.
+func (r *run) wallet() (string, error) {
+	addr, ok := r.report.Accounts[WalletAccount]
+	if !ok {
+		return "", fmt.Errorf("the wallet has no key yet; add a keygen step first")
+	}
+	return addr, nil
+}
+
+// account returns the address of the named account, creating it when it is
+// first used.
+func (r *run) account(ctx context.Context, name string) (string, error) {
+	if name == WalletAccount {
+		return r.wallet()
+	}
+	if addr, ok := r.report.Accounts[name]; ok {
+		return addr, nil
+	}
+	addr, err := r.Env.NewAccount(ctx)
+	if err != nil {
+		return "", fmt.Errorf("creating account %s: %w", name, err)
+	}
+	r.report.Accounts[name] = addr
+	return addr, nil
+}
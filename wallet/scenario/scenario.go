@@ -0,0 +1,239 @@
+package scenario
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"solana-threshold-wallet/wallet"
+)
+
+// Operations of a Step.
+const (
+	OpKeygen   = "keygen"
+	OpFund     = "fund"
+	OpTransfer = "transfer"
+	OpRefresh  = "refresh"
+	OpRecover  = "recover"
+	OpSweep    = "sweep"
+	OpExpect   = "expect"
+)
+
+// WalletAccount names the threshold wallet's own address in steps.
+const WalletAccount = "wallet"
+
+// Scenario is a scripted run of the whole stack.
+type Scenario struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Parties is the number of key shares.  Defaults to 3.
+	Parties int `yaml:"parties"`
+	// Transport connects the parties: mocknet, tcp or ws.  Defaults to
+	// mocknet.
+	Transport string `yaml:"transport"`
+	// Asset is what amounts are written in.  Defaults to SOL.
+	Asset *Asset `yaml:"asset"`
+	Steps []Step `yaml:"steps"`
+}
+
+// Asset describes the unit of the amounts in a scenario.
+type Asset struct {
+	Symbol   string `yaml:"symbol"`
+	Decimals int    `yaml:"decimals"`
+}
+
+// Step is one operation.  Which fields apply depends on Op:
+//
+//   - keygen: Curve, defaulting to ed25519.
+//   - fund: Amount, paid into Account (default the wallet) by the faucet.
+//   - transfer: Amount, sent from the wallet to To, Count times (default 1).
+//   - refresh: no fields.
+//   - recover: Party, whose share is lost and restored from its backup.
+//   - sweep: To, which receives the wallet's whole balance less fees.
+//   - expect: Balances, see Expectation.
+//
+// Accounts other than "wallet" are fresh addresses created when first named.
+type Step struct {
+	Op       string            `yaml:"op"`
+	Curve    string            `yaml:"curve,omitempty"`
+	Account  string            `yaml:"account,omitempty"`
+	To       string            `yaml:"to,omitempty"`
+	Amount   string            `yaml:"amount,omitempty"`
+	Count    int               `yaml:"count,omitempty"`
+	Party    string            `yaml:"party,omitempty"`
+	Balances map[string]string `yaml:"balances,omitempty"`
+}
+
+// Load reads and parses the scenario file at path.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse parses and validates a YAML scenario.  Unknown fields are errors, so
+// that a misspelt expectation cannot silently pass.
+func Parse(data []byte) (*Scenario, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var s Scenario
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("parsing scenario: %w", err)
+	}
+	if s.Parties == 0 {
+		s.Parties = 3
+	}
+	if s.Transport == "" {
+		s.Transport = "mocknet"
+	}
+	if s.Asset == nil {
+		s.Asset = &Asset{Symbol: "SOL", Decimals: 9}
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Scenario) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("scenario name must be provided")
+	}
+	if s.Parties < 2 {
+		return fmt.Errorf("at least 2 parties are required")
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario %q has no steps", s.Name)
+	}
+	for i := range s.Steps {
+		if err := s.validateStep(&s.Steps[i]); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, s.Steps[i].Op, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scenario) validateStep(st *Step) error {
+	amount := func() error {
+		_, err := s.asset().ParseAmount(st.Amount)
+		return err
+	}
+	switch st.Op {
+	case OpKeygen:
+		if st.Curve == "" {
+			st.Curve = "ed25519"
+		}
+		return nil
+	case OpFund:
+		if st.Account == "" {
+			st.Account = WalletAccount
+		}
+		return amount()
+	case OpTransfer:
+		if st.Count == 0 {
+			st.Count = 1
+		}
+		if st.Count < 0 {
+			return fmt.Errorf("count cannot be negative")
+		}
+		if err := checkRecipient(st.To); err != nil {
+			return err
+		}
+		return amount()
+	case OpRefresh:
+		return nil
+	case OpRecover:
+		if st.Party == "" {
+			return fmt.Errorf("party must be provided")
+		}
+		return nil
+	case OpSweep:
+		return checkRecipient(st.To)
+	case OpExpect:
+		if len(st.Balances) == 0 {
+			return fmt.Errorf("balances must be provided")
+		}
+		for account, want := range st.Balances {
+			if _, err := s.expectation(want); err != nil {
+				return fmt.Errorf("balance of %s: %w", account, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation %q", st.Op)
+	}
+}
+
+func checkRecipient(to string) error {
+	switch to {
+	case "":
+		return fmt.Errorf("recipient must be provided")
+	case WalletAccount:
+		return fmt.Errorf("the wallet cannot send to itself")
+	}
+	return nil
+}
+
+func (s *Scenario) asset() wallet.Asset {
+	return wallet.Asset{Symbol: s.Asset.Symbol, Decimals: s.Asset.Decimals}
+}
+
+// Expectation is a condition on a balance, written as an amount in whole
+// units optionally preceded by one of the operators =, >=, <=, > or <, e.g.
+// "0.35" or ">= 1.2".  Fees make the exact balance of a paying account hard
+// to predict, so those are usually checked with a bound.
+type Expectation struct {
+	Op     string
+	Amount *big.Int
+}
+
+// Holds reports whether balance satisfies the expectation.
+func (e Expectation) Holds(balance *big.Int) bool {
+	c := balance.Cmp(e.Amount)
+	switch e.Op {
+	case ">=":
+		return c >= 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case "<":
+		return c < 0
+	default:
+		return c == 0
+	}
+}
+
+// expectation parses an Expectation in the scenario's asset.
+func (s *Scenario) expectation(text string) (Expectation, error) {
+	text = strings.TrimSpace(text)
+	var e Expectation
+	for _, op := range []string{">=", "<=", "=", ">", "<"} {
+		if rest, ok := strings.CutPrefix(text, op); ok {
+			e.Op, text = op, strings.TrimSpace(rest)
+			break
+		}
+	}
+	// ParseAmount only accepts positive amounts, but a swept or untouched
+	// account is expected to be empty.
+	if whole, frac, _ := strings.Cut(text, "."); whole != "" && strings.Trim(whole+frac, "0") == "" {
+		e.Amount = new(big.Int)
+		return e, nil
+	}
+	amount, err := s.asset().ParseAmount(text)
+	if err != nil {
+		return e, err
+	}
+	e.Amount = amount
+	return e, nil
+}
@@ -0,0 +1,170 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ledgerEnv is an in-memory Env charging a flat fee per transfer.
+type ledgerEnv struct {
+	fee      *big.Int
+	wallet   string
+	parties  int
+	accounts int
+	balances map[string]*big.Int
+	ops      []string
+}
+
+func newLedgerEnv() *ledgerEnv {
+	return &ledgerEnv{fee: big.NewInt(5000), balances: map[string]*big.Int{}}
+}
+
+func (e *ledgerEnv) balance(addr string) *big.Int {
+	if e.balances[addr] == nil {
+		e.balances[addr] = new(big.Int)
+	}
+	return e.balances[addr]
+}
+
+func (e *ledgerEnv) Keygen(_ context.Context, parties int, curve string) (string, error) {
+	e.wallet, e.parties = "mpc-"+curve, parties
+	return e.wallet, nil
+}
+
+func (e *ledgerEnv) NewAccount(context.Context) (string, error) {
+	e.accounts++
+	return fmt.Sprintf("account-%d", e.accounts), nil
+}
+
+func (e *ledgerEnv) Fund(_ context.Context, addr string, amount *big.Int) error {
+	e.balance(addr).Add(e.balance(addr), amount)
+	return nil
+}
+
+func (e *ledgerEnv) Transfer(_ context.Context, to string, amount *big.Int) error {
+	from := e.balance(e.wallet)
+	cost := new(big.Int).Add(amount, e.fee)
+	if from.Cmp(cost) < 0 {
+		return fmt.Errorf("insufficient funds")
+	}
+	from.Sub(from, cost)
+	e.balance(to).Add(e.balance(to), amount)
+	return nil
+}
+
+func (e *ledgerEnv) Refresh(context.Context) error {
+	e.ops = append(e.ops, "refresh")
+	return nil
+}
+
+func (e *ledgerEnv) Recover(_ context.Context, party string) error {
+	e.ops = append(e.ops, "recover "+party)
+	return nil
+}
+
+func (e *ledgerEnv) Sweep(ctx context.Context, to string) (*big.Int, error) {
+	amount := new(big.Int).Sub(e.balance(e.wallet), e.fee)
+	return amount, e.Transfer(ctx, to, amount)
+}
+
+func (e *ledgerEnv) Balance(_ context.Context, addr string) (*big.Int, error) {
+	return new(big.Int).Set(e.balance(addr)), nil
+}
+
+func TestScenariosInTestdata(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			s, err := Load(path)
+			require.NoError(t, err)
+			env := newLedgerEnv()
+			report, err := Run(context.Background(), Config{Scenario: s, Env: env})
+			require.NoError(t, err)
+			assert.Len(t, report.Steps, len(s.Steps))
+			assert.Equal(t, env.wallet, report.Accounts[WalletAccount])
+		})
+	}
+}
+
+func TestRunStopsAtFailedExpectation(t *testing.T) {
+	s, err := Parse([]byte(`
+name: short
+steps:
+  - op: keygen
+  - op: fund
+    amount: "1"
+  - op: transfer
+    to: alice
+    amount: "0.5"
+    count: 2
+  - op: recover
+    party: party_0
+  - op: expect
+    balances:
+      alice: "1"
+      wallet: "> 0"
+  - op: refresh
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.Parties)
+	assert.Equal(t, "mocknet", s.Transport)
+	assert.Equal(t, "ed25519", s.Steps[0].Curve)
+
+	env := newLedgerEnv()
+	var seen []string
+	report, err := Run(context.Background(), Config{Scenario: s, Env: env, OnStep: func(r StepResult) { seen = append(seen, r.Op) }})
+	// The second transfer cannot pay its fee.
+	assert.ErrorContains(t, err, "step 3 (transfer): transfer 2 of 2: insufficient funds")
+	assert.Equal(t, []string{OpKeygen, OpFund}, seen)
+	assert.Len(t, report.Steps, 2)
+
+	s.Steps[2].Count = 1
+	report, err = Run(context.Background(), Config{Scenario: s, Env: newLedgerEnv()})
+	assert.ErrorIs(t, err, ErrUnexpectedBalance)
+	assert.ErrorContains(t, err, "alice holds 0.5 SOL, want 1")
+	assert.Len(t, report.Steps, 4)
+}
+
+func TestParseRejectsInvalidScenarios(t *testing.T) {
+	for name, doc := range map[string]string{
+		"unknown field":     "name: x\nsteps:\n  - op: refresh\n    amout: \"1\"\n",
+		"unknown op":        "name: x\nsteps:\n  - op: mint\n",
+		"no steps":          "name: x\n",
+		"no name":           "steps:\n  - op: refresh\n",
+		"bad amount":        "name: x\nsteps:\n  - op: fund\n    amount: \"1.0000000001\"\n",
+		"self transfer":     "name: x\nsteps:\n  - op: transfer\n    to: wallet\n    amount: \"1\"\n",
+		"bad expectation":   "name: x\nsteps:\n  - op: expect\n    balances:\n      a: \"~1\"\n",
+		"missing party":     "name: x\nsteps:\n  - op: recover\n",
+		"too few parties":   "name: x\nparties: 1\nsteps:\n  - op: keygen\n",
+		"negative transfer": "name: x\nsteps:\n  - op: transfer\n    to: a\n    amount: \"1\"\n    count: -1\n",
+	} {
+		_, err := Parse([]byte(doc))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestExpectation(t *testing.T) {
+	s := &Scenario{Asset: &Asset{Symbol: "SOL", Decimals: 9}}
+	for text, want := range map[string]Expectation{
+		"1.5":    {Amount: big.NewInt(1_500_000_000)},
+		">= 0.1": {Op: ">=", Amount: big.NewInt(100_000_000)},
+		"<2":     {Op: "<", Amount: big.NewInt(2_000_000_000)},
+		"0":      {Amount: new(big.Int)},
+		"= 0.00": {Op: "=", Amount: new(big.Int)},
+	} {
+		got, err := s.expectation(text)
+		require.NoError(t, err, text)
+		assert.Equal(t, want, got, text)
+	}
+	assert.True(t, Expectation{Op: ">=", Amount: big.NewInt(2)}.Holds(big.NewInt(2)))
+	assert.False(t, Expectation{Op: ">", Amount: big.NewInt(2)}.Holds(big.NewInt(2)))
+	assert.False(t, Expectation{Amount: big.NewInt(2)}.Holds(big.NewInt(3)))
+}
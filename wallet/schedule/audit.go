@@ -0,0 +1,172 @@
+package schedule
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/daemon"
+)
+
+// RecordType identifies what an audit record describes.
+type RecordType string
+
+const (
+	RecordCreated    RecordType = "created"
+	RecordAuthorized RecordType = "authorized"
+	RecordRevoked    RecordType = "revoked"
+	RecordCompleted  RecordType = "completed"
+	RecordRunStarted RecordType = "run-started"
+	RecordRunEnded   RecordType = "run-ended"
+	RecordRefused    RecordType = "refused" // Authorizations did not verify; run not started
+)
+
+// Record is one entry of the audit log.
+type Record struct {
+	Time     time.Time         `json:"time"`
+	Type     RecordType        `json:"type"`
+	Schedule string            `json:"schedule"`
+	Actor    string            `json:"actor,omitempty"`   // Authorizer, or who revoked
+	Run      int               `json:"run,omitempty"`     // Run number, from 1
+	Skipped  int               `json:"skipped,omitempty"` // Runs missed before this one
+	Session  string            `json:"session,omitempty"`
+	State    coordinator.State `json:"state,omitempty"` // State the session was left in
+	TxID     string            `json:"tx_id,omitempty"`
+	Err      string            `json:"err,omitempty"`
+}
+
+// AuditLog receives a record for every change to a schedule and every run.
+type AuditLog interface {
+	Append(r *Record) error
+}
+
+// FileAuditLog appends records to a file as JSON lines.
+type FileAuditLog struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// Ensure FileAuditLog implements the daemon.Flusher interface
+var _ daemon.Flusher = (*FileAuditLog)(nil)
+
+// OpenFileAuditLog opens or creates the audit log at path.
+func OpenFileAuditLog(path string) (*FileAuditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return &FileAuditLog{path: path, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Append buffers r.  Records are written when Flush or Close is called.
+func (l *FileAuditLog) Append(r *Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
+
+// Flush writes buffered records and syncs the file.
+func (l *FileAuditLog) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.w.Flush(); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return l.f.Sync()
+}
+
+// Close flushes and closes the log.
+func (l *FileAuditLog) Close() error {
+	if err := l.Flush(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// AuditRetention selects the audit records Purge deletes.
+type AuditRetention struct {
+	// Periods maps record types to how long records of that type are kept.
+	// Types that are not listed are kept forever.
+	Periods map[RecordType]time.Duration
+	// Held, if set, reports whether a record is under legal hold, e.g.
+	// because its session is.  Held records are kept.
+	Held func(r *Record) bool
+}
+
+// Purge rewrites the log without the records whose retention period is over
+// and returns how many were deleted.  The new log replaces the old one
+// atomically, so a crash leaves either of them intact.
+func (l *FileAuditLog) Purge(retention AuditRetention, now time.Time) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.w.Flush(); err != nil {
+		return 0, fmt.Errorf("writing audit log: %w", err)
+	}
+	in, err := os.Open(l.path)
+	if err != nil {
+		return 0, fmt.Errorf("reading audit log: %w", err)
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return 0, fmt.Errorf("purging audit log: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	purged := 0
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<20)
+	out := bufio.NewWriter(tmp)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return 0, fmt.Errorf("decoding audit record: %w", err)
+		}
+		keep, ok := retention.Periods[r.Type]
+		if ok && now.Sub(r.Time) >= keep && (retention.Held == nil || !retention.Held(&r)) {
+			purged++
+			continue
+		}
+		out.Write(scanner.Bytes())
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("reading audit log: %w", err)
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+	if err := out.Flush(); err != nil {
+		return 0, fmt.Errorf("purging audit log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return 0, fmt.Errorf("purging audit log: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return 0, fmt.Errorf("purging audit log: %w", err)
+	}
+	// Appends must go to the new file.
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return purged, fmt.Errorf("reopening audit log: %w", err)
+	}
+	l.f.Close()
+	l.f, l.w = f, bufio.NewWriter(f)
+	return purged, nil
+}
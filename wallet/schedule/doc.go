@@ -0,0 +1,35 @@
+// Package schedule runs recurring transfers that a quorum authorized once in
+// advance, such as a weekly treasury top-up.
+//
+// A `Schedule` fixes the terms of every run: chain, sender, recipient, token,
+// amount, an optional fee ceiling, the interval and how long the schedule
+// lasts.  The terms cannot be changed after creation.  A schedule becomes
+// active once `Config.Quorum` distinct authorizers have signed its terms with
+// `SignAuthorization` and the signatures were recorded with `Authorize`, and
+// stays active until it is revoked, its last run has been started or its end
+// time has passed.  The signatures are checked against the authorizers' keys
+// in `Config.Authorizers` when they are recorded and again before every run,
+// so a schedule whose terms were edited in the schedules file is refused.
+//
+// At every due time `Tick` submits a coordinator session for the terms and
+// runs it.  The coordinator's policy still evaluates each run; the request
+// carries the schedule ID so that policies can tell scheduled runs apart.
+// When the policy asks for approvals, the scheduler approves on behalf of the
+// authorizers, as "schedule/<authorizer>", provided the built transaction
+// matches the terms exactly and the policy does not ask for more approvals
+// than the schedule holds.  Anything else is left to interactive approval.
+// When the coordinator requires signed approvals, the scheduler signs them
+// with `Config.Key`, whose public key the coordinator's `ApproverKeys` lists
+// under each of those names.  Reviews requested by the policy are never skipped.
+//
+// Runs are at most once: the scheduler records a run as started before it
+// submits the session, so a crash between the two skips that run instead of
+// repeating it.  When the scheduler was down for several intervals, only one
+// catch-up run is made and the missed runs are recorded as skipped.
+//
+// Every authorization, revocation and run is appended to an `AuditLog`.
+// `FileAuditLog` writes JSON lines and can be passed to the daemon as its
+// audit log so that it is flushed on shutdown.  `FileAuditLog.Purge` deletes
+// records older than their type's retention period, except those under legal
+// hold.
+package schedule
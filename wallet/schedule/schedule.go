@@ -0,0 +1,603 @@
+package schedule
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/approval"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/daemon"
+)
+
+const (
+	defaultQuorum   = 2
+	defaultInterval = time.Minute
+	// authorizationDomain separates schedule authorizations from any other
+	// use of an authorizer's key.
+	authorizationDomain = "cb-mpc schedule authorization\n"
+	// termsVersion identifies the encoding of the terms in Schedule.Hash.
+	termsVersion = "cb-mpc schedule terms v1"
+)
+
+var (
+	// ErrNotFound is returned for unknown schedule IDs.
+	ErrNotFound = errors.New("schedule: not found")
+	// ErrNotAuthorized is returned when a schedule lacks a quorum of valid
+	// authorizations of its current terms.
+	ErrNotAuthorized = approval.ErrNotApproved
+)
+
+// Status is the lifecycle state of a schedule.
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // Waiting for authorizations
+	StatusActive    Status = "active"    // Runs at its due times
+	StatusCompleted Status = "completed" // Out of runs or past its end time
+	StatusRevoked   Status = "revoked"
+)
+
+// Schedule is a recurring transfer.  The fields up to MaxRuns are its terms
+// and are set by the creator; the rest is maintained by the Scheduler.
+type Schedule struct {
+	ID       string
+	Chain    string         // Identifier of a chain registered with the coordinator
+	Transfer chain.Transfer // Transfer made by every run; Outputs are not supported
+	MaxFee   *big.Int       // Maximum network fee per run, if set
+	Priority coordinator.Priority
+	Start    time.Time     // Time of the first run; defaults to creation
+	Every    time.Duration // Interval between runs
+	Until    time.Time     // No runs after this time, if set
+	MaxRuns  int           // Maximum number of runs, if set
+
+	Quorum         int             // Authorizations required, fixed at creation
+	Authorizations []Authorization // Signed authorizations of the terms, in order
+	CreatedAt      time.Time
+	Next           time.Time // When the next run is due
+	Runs           int       // Runs started so far
+	LastSession    string    // Session of the last run
+	Revoked        string    // Reason the schedule was revoked, if it was
+	Completed      time.Time // When the schedule was found completed, if it was
+}
+
+// Status returns the schedule's state at time now.  It counts the
+// authorizations without checking their signatures; runs check them.
+func (s *Schedule) Status(now time.Time) Status {
+	switch {
+	case s.Revoked != "":
+		return StatusRevoked
+	case !s.Completed.IsZero(),
+		s.MaxRuns > 0 && s.Runs >= s.MaxRuns,
+		!s.Until.IsZero() && (s.Next.After(s.Until) || now.After(s.Until)):
+		return StatusCompleted
+	case len(s.Authorizations) < s.Quorum:
+		return StatusPending
+	default:
+		return StatusActive
+	}
+}
+
+// matches reports whether a decoded transaction is exactly what the schedule
+// authorizes.
+func (s *Schedule) matches(summary *chain.Summary) bool {
+	t := &s.Transfer
+	return summary != nil && summary.Chain == s.Chain && len(summary.Outputs) == 0 &&
+		summary.From == t.From && summary.To == t.To && summary.Token == t.Token &&
+		summary.Amount != nil && summary.Amount.Cmp(t.Amount) == 0 &&
+		(s.MaxFee == nil || summary.Fee.Cmp(s.MaxFee) <= 0)
+}
+
+func (s *Schedule) clone() *Schedule {
+	c := *s
+	c.Authorizations = append([]Authorization(nil), s.Authorizations...)
+	return &c
+}
+
+// Hash returns the digest authorizers sign: the terms and the quorum, each
+// written length-prefixed in a fixed order.  Amounts are written in decimal,
+// a nil fee ceiling and unset times as empty fields.
+func (s *Schedule) Hash() []byte {
+	h := sha256.New()
+	t := &s.Transfer
+	for _, field := range []string{
+		termsVersion, s.ID, s.Chain, t.From, t.To, amountField(t.Amount), t.Token, amountField(s.MaxFee),
+		strconv.Itoa(int(s.Priority)), timeField(s.Start), strconv.FormatInt(int64(s.Every), 10),
+		timeField(s.Until), strconv.Itoa(s.MaxRuns), strconv.Itoa(s.Quorum),
+	} {
+		binary.Write(h, binary.BigEndian, uint32(len(field)))
+		h.Write([]byte(field))
+	}
+	return h.Sum(nil)
+}
+
+func amountField(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+func timeField(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// Authorization records that an authorizer consented to a schedule's terms.
+type Authorization = approval.Approval
+
+// SignAuthorization signs sched's terms on behalf of authorizer.  Sign the
+// schedule returned by Scheduler.Create, whose quorum and start are set.
+func SignAuthorization(sched *Schedule, authorizer string, key ed25519.PrivateKey, now time.Time) (*Authorization, error) {
+	return approval.Sign(authorizationDomain, sched.Hash(), authorizer, key, now)
+}
+
+// Config contains the configuration for a Scheduler.
+type Config struct {
+	// Coordinator runs the signing sessions.  Required.
+	Coordinator *coordinator.Coordinator
+	// Audit receives a record for every change and run.  Required.  It is
+	// flushed after every record if it implements daemon.Flusher.
+	Audit AuditLog
+	// Path is the file schedules are kept in.  If empty, schedules are only
+	// kept in memory.
+	Path string
+	// Authorizers maps who may authorize schedules to their Ed25519 identity
+	// keys.  Authorizations are checked against them when they are recorded
+	// and again before every run, so schedules edited at Path do not run.
+	// Required.
+	Authorizers map[string]ed25519.PublicKey
+	// Key, if set, signs the approvals the scheduler makes on behalf of
+	// authorizers, which a coordinator with ApproverKeys requires.  Its
+	// public key must be listed there as "schedule/<authorizer>" for every
+	// authorizer.
+	Key ed25519.PrivateKey
+	// Quorum is the number of distinct authorizations a new schedule
+	// requires.  Defaults to 2.
+	Quorum int
+	// Interval is how often Run checks for due schedules.  Defaults to one
+	// minute.
+	Interval time.Duration
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Scheduler keeps schedules and starts their runs.
+type Scheduler struct {
+	c      *coordinator.Coordinator
+	config Config
+
+	mu        sync.Mutex
+	schedules map[string]*Schedule
+}
+
+// New creates a Scheduler from the given configuration, loading the
+// schedules kept at config.Path.
+func New(config Config) (*Scheduler, error) {
+	if config.Coordinator == nil {
+		return nil, fmt.Errorf("coordinator must be provided")
+	}
+	if config.Audit == nil {
+		return nil, fmt.Errorf("audit log must be provided")
+	}
+	if config.Quorum <= 0 {
+		config.Quorum = defaultQuorum
+	}
+	if len(config.Authorizers) < config.Quorum {
+		return nil, fmt.Errorf("quorum of %d exceeds the %d authorizers", config.Quorum, len(config.Authorizers))
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	s := &Scheduler{c: config.Coordinator, config: config, schedules: map[string]*Schedule{}}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create adds a schedule from the terms in sched.  The schedule is pending
+// until it is authorized by a quorum.
+func (s *Scheduler) Create(sched *Schedule) (*Schedule, error) {
+	now := s.config.Now()
+	t := &sched.Transfer
+	switch {
+	case sched.ID == "":
+		return nil, fmt.Errorf("schedule ID must be provided")
+	case t.From == "" || t.To == "":
+		return nil, fmt.Errorf("sender and recipient must be provided")
+	case t.Amount == nil || t.Amount.Sign() <= 0:
+		return nil, fmt.Errorf("amount must be positive")
+	case len(t.Outputs) > 0:
+		return nil, fmt.Errorf("batched transfers cannot be scheduled")
+	case sched.Every <= 0:
+		return nil, fmt.Errorf("interval must be positive")
+	case sched.MaxRuns < 0:
+		return nil, fmt.Errorf("maximum runs must not be negative")
+	}
+	if _, ok := s.c.Chain(sched.Chain); !ok {
+		return nil, fmt.Errorf("unknown chain %q", sched.Chain)
+	}
+
+	sched = &Schedule{
+		ID:        sched.ID,
+		Chain:     sched.Chain,
+		Transfer:  *t,
+		MaxFee:    sched.MaxFee,
+		Priority:  sched.Priority,
+		Start:     sched.Start,
+		Every:     sched.Every,
+		Until:     sched.Until,
+		MaxRuns:   sched.MaxRuns,
+		Quorum:    s.config.Quorum,
+		CreatedAt: now,
+	}
+	if sched.Start.IsZero() {
+		sched.Start = now
+	}
+	if !sched.Until.IsZero() && sched.Until.Before(sched.Start) {
+		return nil, fmt.Errorf("schedule ends before it starts")
+	}
+	sched.Next = sched.Start
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[sched.ID]; ok {
+		return nil, fmt.Errorf("schedule %q already exists", sched.ID)
+	}
+	if err := s.audit(&Record{Type: RecordCreated, Schedule: sched.ID}); err != nil {
+		return nil, err
+	}
+	s.schedules[sched.ID] = sched
+	if err := s.save(); err != nil {
+		delete(s.schedules, sched.ID)
+		return nil, err
+	}
+	return sched.clone(), nil
+}
+
+// Authorize records an authorizer's signed consent to the schedule's terms.
+func (s *Scheduler) Authorize(id string, a *Authorization) (*Schedule, error) {
+	if a == nil {
+		return nil, fmt.Errorf("authorization must be provided")
+	}
+	if _, ok := s.config.Authorizers[a.Approver]; !ok {
+		return nil, fmt.Errorf("%q may not authorize schedules", a.Approver)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, ok := s.schedules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if st := sched.Status(s.config.Now()); st == StatusRevoked || st == StatusCompleted {
+		return nil, fmt.Errorf("schedule %q is %s", id, st)
+	}
+	quorum := approval.Quorum{Approvers: s.config.Authorizers, Required: 1}
+	if err := quorum.Check(authorizationDomain, sched.Hash(), []Authorization{*a}); err != nil {
+		return nil, fmt.Errorf("authorization of %q for schedule %q: %w", a.Approver, id, err)
+	}
+	for _, prior := range sched.Authorizations {
+		if prior.Approver == a.Approver {
+			return nil, fmt.Errorf("%q already authorized schedule %q", a.Approver, id)
+		}
+	}
+	return s.update(sched, &Record{Type: RecordAuthorized, Schedule: id, Actor: a.Approver}, func(sched *Schedule) {
+		sched.Authorizations = append(sched.Authorizations, *a)
+	})
+}
+
+// Revoke stops a schedule.  Runs already started are not affected.
+func (s *Scheduler) Revoke(id, actor, reason string) (*Schedule, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("reason must be provided")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, ok := s.schedules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if sched.Revoked != "" {
+		return sched.clone(), nil
+	}
+	return s.update(sched, &Record{Type: RecordRevoked, Schedule: id, Actor: actor, Err: reason}, func(sched *Schedule) {
+		sched.Revoked = reason
+	})
+}
+
+// Schedule returns the schedule with the given ID.
+func (s *Scheduler) Schedule(id string) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, ok := s.schedules[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return sched.clone(), nil
+}
+
+// Schedules returns all schedules ordered by ID.
+func (s *Scheduler) Schedules() []*Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Schedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		out = append(out, sched.clone())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Run calls Tick every Config.Interval until ctx is done.  Errors are
+// recorded in the audit log and do not stop the loop.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		_ = s.Tick(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick starts the runs that are due, one after the other, and waits for each
+// to finish or to wait for approval.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	var errs []error
+	for _, sched := range s.Schedules() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		started, run, skipped, err := s.start(sched.ID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %q: %w", sched.ID, err))
+			continue
+		}
+		if started == nil {
+			continue
+		}
+		if err := s.run(ctx, started, run, skipped); err != nil {
+			errs = append(errs, fmt.Errorf("schedule %q run %d: %w", sched.ID, run, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// start claims the next run of a due schedule.  It returns a nil schedule
+// when nothing is due.
+func (s *Scheduler) start(id string) (*Schedule, int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched := s.schedules[id]
+	now := s.config.Now()
+	switch sched.Status(now) {
+	case StatusCompleted:
+		if !sched.Completed.IsZero() {
+			return nil, 0, 0, nil
+		}
+		_, err := s.update(sched, &Record{Type: RecordCompleted, Schedule: id, Run: sched.Runs}, func(sched *Schedule) {
+			sched.Completed = now
+		})
+		return nil, 0, 0, err
+	case StatusActive:
+		if sched.Next.After(now) {
+			return nil, 0, 0, nil
+		}
+	default:
+		return nil, 0, 0, nil
+	}
+	if _, err := s.authorizers(sched); err != nil {
+		return nil, 0, 0, errors.Join(err, s.audit(&Record{Type: RecordRefused, Schedule: id, Err: err.Error()}))
+	}
+
+	run, skipped := sched.Runs+1, 0
+	next := sched.Next.Add(sched.Every)
+	for !next.After(now) {
+		next = next.Add(sched.Every)
+		skipped++
+	}
+	started, err := s.update(sched, &Record{Type: RecordRunStarted, Schedule: id, Run: run, Skipped: skipped}, func(sched *Schedule) {
+		sched.Runs, sched.Next = run, next
+	})
+	return started, run, skipped, err
+}
+
+// run submits and runs the session of one run and records its outcome.
+func (s *Scheduler) run(ctx context.Context, sched *Schedule, run, skipped int) error {
+	r := &Record{Type: RecordRunEnded, Schedule: sched.ID, Run: run}
+	session, err := s.c.Submit(ctx, &coordinator.Request{
+		Chain:    sched.Chain,
+		Transfer: sched.Transfer,
+		Priority: sched.Priority,
+		MaxFee:   sched.MaxFee,
+		Schedule: sched.ID,
+	})
+	if err != nil {
+		r.Err = err.Error()
+		return errors.Join(err, s.audit(r))
+	}
+	s.mu.Lock()
+	if current := s.schedules[sched.ID]; current.Runs == run {
+		current.LastSession = session.ID
+		err = s.save()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	r.Session = session.ID
+	out, err := s.c.Run(ctx, session.ID)
+	if err == nil && out.State == coordinator.StatePolicyEvaluated {
+		out, err = s.approve(ctx, sched, out)
+	}
+	if out != nil {
+		r.State, r.TxID, r.Err = out.State, out.TxID, out.Err
+	}
+	if err != nil {
+		r.Err = err.Error()
+	}
+	return errors.Join(err, s.audit(r))
+}
+
+// approve substitutes the schedule's authorizations for the approvals the
+// policy requires, if the transaction matches the schedule's terms, and
+// resumes the session.  Otherwise the session is left awaiting approval.
+func (s *Scheduler) approve(ctx context.Context, sched *Schedule, session *coordinator.Session) (*coordinator.Session, error) {
+	authorizers, err := s.authorizers(sched)
+	if err != nil {
+		return session, err
+	}
+	required := max(session.Decision.RequiredApprovals, 1)
+	if !sched.matches(session.Summary) || required > len(authorizers) {
+		return session, nil
+	}
+	for _, a := range authorizers[:required] {
+		approver := "schedule/" + a
+		if contains(session.Approvals, approver) {
+			continue
+		}
+		next, err := s.approveAs(ctx, session, approver)
+		if err != nil {
+			return session, err
+		}
+		session = next
+	}
+	return s.c.Run(ctx, session.ID)
+}
+
+// authorizers returns the authorizers whose signatures on sched's current
+// terms verify against Config.Authorizers, in order.  It fails unless they
+// make up the quorum of the schedule and of the configuration.
+func (s *Scheduler) authorizers(sched *Schedule) ([]string, error) {
+	quorum := approval.Quorum{Approvers: s.config.Authorizers, Required: max(sched.Quorum, s.config.Quorum)}
+	hash := sched.Hash()
+	if err := quorum.Check(authorizationDomain, hash, sched.Authorizations); err != nil {
+		return nil, err
+	}
+	return quorum.Approved(authorizationDomain, hash, sched.Authorizations), nil
+}
+
+// approveAs approves session on behalf of approver, signing the approval
+// with Config.Key if it is set.
+func (s *Scheduler) approveAs(ctx context.Context, session *coordinator.Session, approver string) (*coordinator.Session, error) {
+	if s.config.Key == nil {
+		return s.c.Approve(ctx, session.ID, approver)
+	}
+	sig, err := coordinator.SignApproval(session, approver, s.config.Key)
+	if err != nil {
+		return nil, err
+	}
+	return s.c.ApproveSigned(ctx, session.ID, sig)
+}
+
+// update applies fn to a copy of sched, records r and persists the result.
+// It must be called with s.mu held.
+func (s *Scheduler) update(sched *Schedule, r *Record, fn func(*Schedule)) (*Schedule, error) {
+	next := sched.clone()
+	fn(next)
+	if err := s.audit(r); err != nil {
+		return nil, err
+	}
+	s.schedules[sched.ID] = next
+	if err := s.save(); err != nil {
+		s.schedules[sched.ID] = sched
+		return nil, err
+	}
+	return next.clone(), nil
+}
+
+func (s *Scheduler) audit(r *Record) error {
+	r.Time = s.config.Now()
+	if err := s.config.Audit.Append(r); err != nil {
+		return fmt.Errorf("recording %s: %w", r.Type, err)
+	}
+	if f, ok := s.config.Audit.(daemon.Flusher); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("recording %s: %w", r.Type, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) load() error {
+	if s.config.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading schedules: %w", err)
+	}
+	var list []*Schedule
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("decoding schedules: %w", err)
+	}
+	for _, sched := range list {
+		s.schedules[sched.ID] = sched
+	}
+	return nil
+}
+
+func (s *Scheduler) save() error {
+	if s.config.Path == "" {
+		return nil
+	}
+	list := make([]*Schedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		list = append(list, sched)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.config.Path), ".schedules-*")
+	if err != nil {
+		return fmt.Errorf("writing schedules: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing schedules: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing schedules: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.config.Path); err != nil {
+		return fmt.Errorf("writing schedules: %w", err)
+	}
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
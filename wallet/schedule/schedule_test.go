@@ -0,0 +1,360 @@
+package schedule
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/clock"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// fakeChain decodes the transfer it built with a fixed fee and finalizes
+// everything it broadcasts.
+type fakeChain struct {
+	fee   int64
+	built []chain.Transfer
+}
+
+func (f *fakeChain) ID() string { return "fake" }
+
+func (f *fakeChain) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (f *fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	f.built = append(f.built, *t)
+	payload := []byte{byte(len(f.built) - 1)}
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: payload, SigningPayload: payload}, nil
+}
+
+func (f *fakeChain) Decode(payload []byte) (*chain.Summary, error) {
+	t := f.built[payload[0]]
+	return &chain.Summary{Chain: f.ID(), From: t.From, To: t.To, Amount: t.Amount, Fee: big.NewInt(f.fee)}, nil
+}
+
+func (f *fakeChain) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (f *fakeChain) Broadcast(_ context.Context, tx *chain.SignedTx) (string, error) {
+	return string(rune('a' + tx.Unsigned.Payload[0])), nil
+}
+
+func (f *fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized}, nil
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(context.Context, *coordinator.SignRequest) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+type memoryAudit struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func (m *memoryAudit) Append(r *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, *r)
+	return nil
+}
+
+func (m *memoryAudit) types() []RecordType {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []RecordType
+	for _, r := range m.records {
+		out = append(out, r.Type)
+	}
+	return out
+}
+
+var start = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+type fixture struct {
+	ch          *fakeChain
+	c           *coordinator.Coordinator
+	s           *Scheduler
+	audit       *memoryAudit
+	clock       *clock.Fake
+	authorizers *approvaltest.Signers
+}
+
+// newFixture creates a scheduler whose policy requires required approvals
+// for scheduled requests and three for all others.
+func newFixture(t *testing.T, required int, config Config) *fixture {
+	t.Helper()
+	f := &fixture{
+		ch:          &fakeChain{fee: 5000},
+		audit:       &memoryAudit{},
+		clock:       clock.NewFake(start),
+		authorizers: approvaltest.New(t, "alice", "bob", "carol"),
+	}
+	policy := coordinator.PolicyFunc(func(_ context.Context, req *coordinator.Request, _ *chain.Summary) (*coordinator.Decision, error) {
+		if req.Schedule == "" {
+			return &coordinator.Decision{Allow: true, RequiredApprovals: 3}, nil
+		}
+		return &coordinator.Decision{Allow: true, RequiredApprovals: required}, nil
+	})
+	// The scheduler signs its approvals with its own key, which stands for
+	// every authorizer.
+	scheduler := approvaltest.New(t, "scheduler")
+	keys := map[string]ed25519.PublicKey{}
+	for _, a := range []string{"alice", "bob", "carol"} {
+		keys["schedule/"+a] = scheduler.Public["scheduler"]
+	}
+	var err error
+	f.c, err = coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{f.ch},
+		Signer:          fakeSigner{},
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+		ApproverKeys:    keys,
+	})
+	require.NoError(t, err)
+	config.Coordinator, config.Audit, config.Now = f.c, f.audit, f.clock.Now
+	config.Key, config.Authorizers = scheduler.Private["scheduler"], f.authorizers.Public
+	f.s, err = New(config)
+	require.NoError(t, err)
+	return f
+}
+
+func weekly() *Schedule {
+	return &Schedule{
+		ID:       "weekly",
+		Chain:    "fake",
+		Transfer: chain.Transfer{From: "treasury", To: "ops", Amount: big.NewInt(1_000)},
+		Start:    start,
+		Every:    7 * 24 * time.Hour,
+		MaxRuns:  3,
+	}
+}
+
+func (f *fixture) authorize(t *testing.T, id string, authorizers ...string) {
+	t.Helper()
+	for _, a := range authorizers {
+		_, err := f.s.Authorize(id, f.sign(t, id, a, f.authorizers.Private[a]))
+		require.NoError(t, err)
+	}
+}
+
+// sign signs the terms of schedule id on behalf of authorizer with key.
+func (f *fixture) sign(t *testing.T, id, authorizer string, key ed25519.PrivateKey) *Authorization {
+	t.Helper()
+	sched, err := f.s.Schedule(id)
+	require.NoError(t, err)
+	a, err := SignAuthorization(sched, authorizer, key, f.clock.Now())
+	require.NoError(t, err)
+	return a
+}
+
+func TestScheduledRuns(t *testing.T) {
+	f := newFixture(t, 2, Config{})
+	ctx := context.Background()
+
+	_, err := f.s.Create(weekly())
+	require.NoError(t, err)
+	require.NoError(t, f.s.Tick(ctx))
+	assert.Empty(t, f.ch.built, "pending schedules do not run")
+
+	f.authorize(t, "weekly", "alice", "bob")
+	require.NoError(t, f.s.Tick(ctx))
+	sched, err := f.s.Schedule("weekly")
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, sched.Status(f.clock.Now()))
+	assert.Equal(t, 1, sched.Runs)
+	assert.Equal(t, start.Add(sched.Every), sched.Next)
+
+	session, err := f.c.Session(ctx, sched.LastSession)
+	require.NoError(t, err)
+	assert.Equal(t, coordinator.StateFinalized, session.State)
+	assert.Equal(t, []string{"schedule/alice", "schedule/bob"}, session.Approvals)
+	assert.Len(t, session.ApprovalSignatures, 2)
+	assert.Equal(t, "weekly", session.Request.Schedule)
+
+	// Not due again until next week.
+	f.clock.Set(start.Add(24 * time.Hour))
+	require.NoError(t, f.s.Tick(ctx))
+	assert.Len(t, f.ch.built, 1)
+
+	// Three weeks later only one catch-up run is made.
+	f.clock.Set(start.Add(3*sched.Every + time.Hour))
+	require.NoError(t, f.s.Tick(ctx))
+	sched, _ = f.s.Schedule("weekly")
+	assert.Equal(t, 2, sched.Runs)
+	assert.Equal(t, start.Add(4*sched.Every), sched.Next)
+	assert.Equal(t, 2, f.audit.records[len(f.audit.records)-2].Skipped)
+
+	f.clock.Set(sched.Next)
+	require.NoError(t, f.s.Tick(ctx))
+	require.NoError(t, f.s.Tick(ctx))
+	sched, _ = f.s.Schedule("weekly")
+	assert.Equal(t, StatusCompleted, sched.Status(f.clock.Now()))
+	assert.Len(t, f.ch.built, 3)
+
+	assert.Equal(t, []RecordType{
+		RecordCreated, RecordAuthorized, RecordAuthorized,
+		RecordRunStarted, RecordRunEnded,
+		RecordRunStarted, RecordRunEnded,
+		RecordRunStarted, RecordRunEnded,
+		RecordCompleted,
+	}, f.audit.types())
+	last := f.audit.records[len(f.audit.records)-2]
+	assert.Equal(t, 3, last.Run)
+	assert.Equal(t, coordinator.StateFinalized, last.State)
+	assert.Equal(t, "c", last.TxID)
+}
+
+func TestRunBeyondAuthorizationAwaitsApproval(t *testing.T) {
+	f := newFixture(t, 3, Config{})
+	ctx := context.Background()
+	_, err := f.s.Create(weekly())
+	require.NoError(t, err)
+	f.authorize(t, "weekly", "alice", "bob")
+
+	require.NoError(t, f.s.Tick(ctx))
+	sched, _ := f.s.Schedule("weekly")
+	session, err := f.c.Session(ctx, sched.LastSession)
+	require.NoError(t, err)
+	assert.Equal(t, coordinator.StatePolicyEvaluated, session.State)
+	assert.Empty(t, session.Approvals)
+}
+
+func TestRunOverFeeFails(t *testing.T) {
+	f := newFixture(t, 2, Config{})
+	ctx := context.Background()
+	sched := weekly()
+	sched.MaxFee = big.NewInt(4000)
+	_, err := f.s.Create(sched)
+	require.NoError(t, err)
+	f.authorize(t, "weekly", "alice", "bob")
+
+	require.NoError(t, f.s.Tick(ctx))
+	r := f.audit.records[len(f.audit.records)-1]
+	assert.Equal(t, RecordRunEnded, r.Type)
+	assert.Equal(t, coordinator.StateFailed, r.State)
+	assert.Contains(t, r.Err, "exceeds maximum")
+}
+
+func TestAuthorizeAndRevoke(t *testing.T) {
+	f := newFixture(t, 2, Config{})
+	ctx := context.Background()
+	_, err := f.s.Create(weekly())
+	require.NoError(t, err)
+	_, err = f.s.Create(weekly())
+	assert.Error(t, err, "duplicate ID")
+
+	mallory := approvaltest.New(t, "mallory").Private["mallory"]
+	_, err = f.s.Authorize("weekly", f.sign(t, "weekly", "mallory", mallory))
+	assert.Error(t, err, "unlisted authorizer")
+	_, err = f.s.Authorize("weekly", f.sign(t, "weekly", "bob", mallory))
+	assert.ErrorIs(t, err, ErrNotAuthorized, "authorization signed with another key")
+	f.authorize(t, "weekly", "alice")
+	_, err = f.s.Authorize("weekly", f.sign(t, "weekly", "alice", f.authorizers.Private["alice"]))
+	assert.Error(t, err, "authorizations must be distinct")
+	_, err = f.s.Authorize("missing", f.sign(t, "weekly", "bob", f.authorizers.Private["bob"]))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	sched, err := f.s.Revoke("weekly", "carol", "ops account rotated")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRevoked, sched.Status(f.clock.Now()))
+	_, err = f.s.Authorize("weekly", f.sign(t, "weekly", "bob", f.authorizers.Private["bob"]))
+	assert.Error(t, err)
+	require.NoError(t, f.s.Tick(ctx))
+	assert.Empty(t, f.ch.built)
+}
+
+func TestSchedulesPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	f := newFixture(t, 2, Config{Path: path})
+	_, err := f.s.Create(weekly())
+	require.NoError(t, err)
+	f.authorize(t, "weekly", "alice", "bob")
+	require.NoError(t, f.s.Tick(context.Background()))
+
+	s, err := New(Config{Coordinator: f.c, Audit: f.audit, Path: path, Authorizers: f.authorizers.Public, Now: f.clock.Now})
+	require.NoError(t, err)
+	sched, err := s.Schedule("weekly")
+	require.NoError(t, err)
+	require.Len(t, sched.Authorizations, 2)
+	assert.Equal(t, "alice", sched.Authorizations[0].Approver)
+	assert.Equal(t, "bob", sched.Authorizations[1].Approver)
+	assert.Equal(t, 1, sched.Runs)
+	assert.Equal(t, big.NewInt(1_000), sched.Transfer.Amount)
+
+	// The run is not repeated after a restart.
+	require.NoError(t, s.Tick(context.Background()))
+	assert.Len(t, f.ch.built, 1)
+}
+
+func TestEditedScheduleIsRefused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	f := newFixture(t, 2, Config{Path: path})
+	_, err := f.s.Create(weekly())
+	require.NoError(t, err)
+	f.authorize(t, "weekly", "alice", "bob")
+
+	// Raise the amount in the schedules file behind the scheduler's back.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	edited := strings.Replace(string(data), `"Amount": 1000`, `"Amount": 1000000`, 1)
+	require.NotEqual(t, string(data), edited)
+	require.NoError(t, os.WriteFile(path, []byte(edited), 0o600))
+
+	s, err := New(Config{Coordinator: f.c, Audit: f.audit, Path: path, Authorizers: f.authorizers.Public, Now: f.clock.Now})
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Tick(context.Background()), ErrNotAuthorized)
+	assert.Empty(t, f.ch.built)
+	assert.Equal(t, RecordRefused, f.audit.records[len(f.audit.records)-1].Type)
+}
+
+func TestFileAuditLogPurge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := OpenFileAuditLog(path)
+	require.NoError(t, err)
+	defer log.Close()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, r := range []*Record{
+		{Time: start, Type: RecordCreated, Schedule: "payroll"},
+		{Time: start, Type: RecordRunEnded, Schedule: "payroll", Session: "s1"},
+		{Time: start, Type: RecordRunEnded, Schedule: "payroll", Session: "s2"},
+		{Time: start.Add(48 * time.Hour), Type: RecordRunEnded, Schedule: "payroll", Session: "s3"},
+	} {
+		require.NoError(t, log.Append(r))
+	}
+
+	n, err := log.Purge(AuditRetention{
+		Periods: map[RecordType]time.Duration{RecordRunEnded: 24 * time.Hour},
+		Held:    func(r *Record) bool { return r.Session == "s2" },
+	}, start.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Appends after a purge go to the rewritten log.
+	require.NoError(t, log.Append(&Record{Time: start.Add(49 * time.Hour), Type: RecordRevoked, Schedule: "payroll"}))
+	require.NoError(t, log.Flush())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var sessions []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r Record
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		sessions = append(sessions, string(r.Type)+":"+r.Session)
+	}
+	assert.Equal(t, []string{"created:", "run-ended:s2", "run-ended:s3", "revoked:"}, sessions)
+}
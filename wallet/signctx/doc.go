@@ -0,0 +1,30 @@
+// Package signctx keeps a signature requested for one purpose from being
+// usable for another.
+//
+// The MPC key is a single Ed25519 key, and an Ed25519 signature does not say
+// what the signed bytes mean: a "raw message" can be a Solana transaction, and
+// an SSH certificate or a JWT signed with the key is as binding as a
+// transfer.  Every sign request therefore declares a `Context`:
+//
+//	solana-tx     a serialized Solana transaction message (legacy or v0)
+//	offchain-msg  a Solana offchain message (see solana.OffchainMessage)
+//	ssh-cert      an OpenSSH certificate without its signature
+//	jwt           a JWS signing input, header.payload, with alg EdDSA
+//	raw           anything else
+//
+// and every party runs `Check` on the bytes before contributing its share:
+//
+//	if err := signctx.Check(signctx.Context(req.Context), req.Payload); err != nil {
+//	    return err // do not sign
+//	}
+//
+// Check requires the bytes to carry the structural markers of the declared
+// context and of no other one.  In particular a raw message that parses as a
+// Solana transaction, or carries the offchain signing domain, is refused, so
+// a caller allowed to sign messages cannot obtain a transaction signature by
+// calling it a message.  Checking is structural only; whether a transaction
+// or certificate should be signed at all is up to policy.
+//
+// coordinator.SignRequest and remotesigner (through ContextSigner) declare
+// the context of every request.
+package signctx
@@ -0,0 +1,183 @@
+package signctx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
+)
+
+// Context is the declared purpose of a signature.
+type Context string
+
+// Signing contexts.
+const (
+	SolanaTx    Context = "solana-tx"
+	OffchainMsg Context = "offchain-msg"
+	SSHCert     Context = "ssh-cert"
+	JWT         Context = "jwt"
+	Raw         Context = "raw"
+)
+
+// sshCertSuffix ends the key type name of every OpenSSH certificate, e.g.
+// ssh-ed25519-cert-v01@openssh.com.
+const sshCertSuffix = "-cert-v01@openssh.com"
+
+// ErrMismatch is returned when a message does not fit its declared context.
+var ErrMismatch = errors.New("signctx: message does not match declared context")
+
+// structured lists the contexts with structural markers, in the order Detect
+// reports them.
+var structured = []struct {
+	context Context
+	check   func([]byte) error
+}{
+	{SolanaTx, checkSolanaTx},
+	{OffchainMsg, checkOffchainMsg},
+	{SSHCert, checkSSHCert},
+	{JWT, checkJWT},
+}
+
+// Valid reports whether c is a known context.
+func (c Context) Valid() bool {
+	if c == Raw {
+		return true
+	}
+	for _, s := range structured {
+		if s.context == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the structured contexts whose markers message carries.  For
+// well-formed messages it returns at most one.
+func Detect(message []byte) []Context {
+	var found []Context
+	for _, s := range structured {
+		if s.check(message) == nil {
+			found = append(found, s.context)
+		}
+	}
+	return found
+}
+
+// Check returns an error wrapping ErrMismatch unless message carries the
+// structural markers of context c and of no other context.  A raw message
+// must carry none.
+func Check(c Context, message []byte) error {
+	if !c.Valid() {
+		return fmt.Errorf("%w: unknown context %q", ErrMismatch, c)
+	}
+	found := Detect(message)
+	if c == Raw {
+		if len(found) > 0 {
+			return fmt.Errorf("%w: raw message has the structure of %s", ErrMismatch, found[0])
+		}
+		return nil
+	}
+	for _, f := range found {
+		if f != c {
+			return fmt.Errorf("%w: %s message also has the structure of %s", ErrMismatch, c, f)
+		}
+	}
+	if len(found) == 0 {
+		for _, s := range structured {
+			if s.context == c {
+				return fmt.Errorf("%w: not a %s message: %v", ErrMismatch, c, s.check(message))
+			}
+		}
+	}
+	return nil
+}
+
+// checkSolanaTx accepts exactly one legacy or v0 transaction message that
+// requires at least one signature.
+func checkSolanaTx(message []byte) error {
+	var msg solana.Message
+	dec := bin.NewBinDecoder(message)
+	if err := msg.UnmarshalWithDecoder(dec); err != nil {
+		return err
+	}
+	if dec.HasRemaining() {
+		return fmt.Errorf("%d trailing bytes", dec.Remaining())
+	}
+	if n := int(msg.Header.NumRequiredSignatures); n == 0 || n > len(msg.AccountKeys) {
+		return fmt.Errorf("invalid number of required signatures %d", n)
+	}
+	return nil
+}
+
+func checkOffchainMsg(message []byte) error {
+	_, err := solchain.ParseOffchainMessage(message)
+	return err
+}
+
+// checkSSHCert accepts the signed part of an OpenSSH certificate, which
+// starts with the certificate key type and a non-empty nonce, both as
+// length-prefixed strings.
+func checkSSHCert(message []byte) error {
+	keyType, rest, ok := sshString(message)
+	if !ok || !strings.HasSuffix(string(keyType), sshCertSuffix) {
+		return fmt.Errorf("missing certificate key type")
+	}
+	if nonce, _, ok := sshString(rest); !ok || len(nonce) == 0 {
+		return fmt.Errorf("missing certificate nonce")
+	}
+	return nil
+}
+
+func sshString(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(n) > uint64(len(b)-4) {
+		return nil, nil, false
+	}
+	return b[4 : 4+n], b[4+n:], true
+}
+
+// checkJWT accepts a JWS signing input of a JWT: the base64url-encoded JSON
+// header, which must name the EdDSA algorithm, and the base64url-encoded JSON
+// claims, joined by a dot.
+func checkJWT(message []byte) error {
+	parts := bytes.Split(message, []byte("."))
+	if len(parts) != 2 {
+		return fmt.Errorf("signing input must have two parts")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return fmt.Errorf("header: %v", err)
+	}
+	if header.Alg != "EdDSA" {
+		return fmt.Errorf("header alg is %q, not EdDSA", header.Alg)
+	}
+	var claims map[string]json.RawMessage
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return fmt.Errorf("claims: %v", err)
+	}
+	if claims == nil {
+		return fmt.Errorf("claims must be a JSON object")
+	}
+	return nil
+}
+
+func decodeSegment(segment []byte, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(string(segment))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
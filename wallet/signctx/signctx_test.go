@@ -0,0 +1,89 @@
+package signctx
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
+)
+
+func transferMessage(t *testing.T) []byte {
+	t.Helper()
+	from := solana.NewWallet().PublicKey()
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{system.NewTransferInstruction(1, from, solana.NewWallet().PublicKey()).Build()},
+		solana.Hash{1},
+		solana.TransactionPayer(from),
+	)
+	require.NoError(t, err)
+	msg, err := tx.Message.MarshalBinary()
+	require.NoError(t, err)
+	return msg
+}
+
+func offchainMessage(t *testing.T) []byte {
+	t.Helper()
+	m, err := solchain.NewOffchainMessage([32]byte{}, []solana.PublicKey{solana.NewWallet().PublicKey()}, []byte("hello"))
+	require.NoError(t, err)
+	data, err := m.Serialize()
+	require.NoError(t, err)
+	return data
+}
+
+func sshCert() []byte {
+	var b []byte
+	for _, field := range []string{"ssh-ed25519-cert-v01@openssh.com", "0123456789abcdef0123456789abcdef", "<public key and fields>"} {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(field)))
+		b = append(b, field...)
+	}
+	return b
+}
+
+func jwt(alg string) []byte {
+	enc := base64.RawURLEncoding.EncodeToString
+	return []byte(enc([]byte(`{"alg":"`+alg+`","typ":"JWT"}`)) + "." + enc([]byte(`{"sub":"treasury","exp":1700000000}`)))
+}
+
+func TestCheck(t *testing.T) {
+	messages := map[Context][]byte{
+		SolanaTx:    transferMessage(t),
+		OffchainMsg: offchainMessage(t),
+		SSHCert:     sshCert(),
+		JWT:         jwt("EdDSA"),
+		Raw:         []byte("Sign in to example.com"),
+	}
+	for declared := range messages {
+		for actual, message := range messages {
+			err := Check(declared, message)
+			if declared == actual {
+				assert.NoError(t, err, "%s as %s", actual, declared)
+			} else {
+				assert.ErrorIs(t, err, ErrMismatch, "%s as %s", actual, declared)
+			}
+		}
+	}
+}
+
+func TestRawRefusesTransactions(t *testing.T) {
+	err := Check(Raw, transferMessage(t))
+	assert.ErrorIs(t, err, ErrMismatch)
+	assert.ErrorContains(t, err, "solana-tx")
+	assert.Equal(t, []Context{SolanaTx}, Detect(transferMessage(t)))
+}
+
+func TestCheckRejectsMalformed(t *testing.T) {
+	msg := transferMessage(t)
+	assert.Error(t, Check(SolanaTx, append(msg, 0)), "trailing bytes")
+	assert.Error(t, Check(JWT, jwt("RS256")))
+	assert.Error(t, Check(JWT, append(jwt("EdDSA"), ".sig"...)), "a signed JWT is not a signing input")
+	assert.Error(t, Check(SSHCert, sshCert()[:10]))
+	assert.Error(t, Check("x509", []byte("hello")))
+	assert.False(t, Context("x509").Valid())
+	assert.True(t, Raw.Valid())
+}
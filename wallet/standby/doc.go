@@ -0,0 +1,27 @@
+// Package standby keeps a warm standby for a party host: after every refresh
+// the primary sends the standby its new share, encrypted, so that a lost
+// primary can be replaced without restoring backups.  The standby holds the
+// share but cannot sign with it until a quorum of approvers promotes it.
+//
+// The primary seals each refreshed share to the standby's X25519 key and
+// signs the resulting `Update` with its own Ed25519 key:
+//
+//	u, _ := standby.Seal(standby.SealConfig{Party: "kms", Key: primaryKey, Recipient: standbyPub},
+//	    "treasury", generation, share, time.Now())
+//	// deliver u to the standby host
+//
+// The standby host runs a `Standby`, which accepts updates only from the
+// party's primary, only for newer generations and only while it is not
+// promoted.  It checks that every update decrypts, but stores it encrypted
+// and never hands the share out on its own:
+//
+//	err := sb.Receive(ctx, u)
+//
+// Promotion is a ceremony like key retirement: a `Promotion` names the key,
+// the party, the standby and the generation it holds, approvers sign it with
+// `Approve` until the `Policy` quorum is reached, and `Standby.Promote` checks
+// the approvals itself before decrypting and returning the share for the
+// party daemon to load.  A promoted standby refuses further updates; the
+// failed primary must not be brought back, or retired through a refresh that
+// leaves it out.
+package standby
@@ -0,0 +1,408 @@
+package standby
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+
+	"solana-threshold-wallet/wallet/approval"
+)
+
+const (
+	// updateDomain separates update signatures from any other use of a
+	// primary's key.
+	updateDomain = "cb-mpc standby share update\n"
+	// promotionDomain separates promotion approvals from any other use of
+	// an approver's key.
+	promotionDomain = "cb-mpc standby promotion approval\n"
+	// sealDomain binds share encryption keys to their use.
+	sealDomain = "cb-mpc standby share seal\n"
+)
+
+var (
+	// ErrBadUpdate is returned for updates that do not verify or decrypt.
+	ErrBadUpdate = errors.New("standby: invalid update")
+	// ErrStale is returned for updates that are not newer than the share
+	// the standby holds.
+	ErrStale = errors.New("standby: stale update")
+	// ErrPromoted is returned for updates to a standby that was promoted.
+	ErrPromoted = errors.New("standby: already promoted")
+	// ErrNotApproved is returned when a promotion lacks a quorum of valid
+	// approvals.
+	ErrNotApproved = approval.ErrNotApproved
+)
+
+// Policy names the primaries a standby follows and who may promote it.
+type Policy struct {
+	// Primaries maps each party to the Ed25519 key its primary host signs
+	// updates with.
+	Primaries map[string]ed25519.PublicKey `json:"primaries"`
+	Approvers map[string]ed25519.PublicKey `json:"approvers"`
+	Required  int                          `json:"required"` // Distinct approvals needed
+}
+
+// Update carries a party's refreshed share, encrypted to a standby.
+type Update struct {
+	KeyID      string    `json:"key_id"`
+	Party      string    `json:"party"`
+	Generation uint64    `json:"generation"` // Increases with every refresh
+	Ephemeral  []byte    `json:"ephemeral"`  // X25519 public key of the sender
+	Ciphertext []byte    `json:"ciphertext"` // AES-256-GCM, nonce first
+	CreatedAt  time.Time `json:"created_at"`
+	Signature  []byte    `json:"signature"` // Primary's signature over the rest
+}
+
+// digest returns the hash signed by the primary: that of the update without
+// its signature.
+func (u *Update) digest() ([]byte, error) {
+	unsigned := *u
+	unsigned.Signature = nil
+	return hashJSON(&unsigned)
+}
+
+// SealConfig contains a primary's configuration for Seal.
+type SealConfig struct {
+	Party     string
+	Key       ed25519.PrivateKey // Signs the update
+	Recipient *ecdh.PublicKey    // The standby's X25519 key
+}
+
+// Seal encrypts share, the party's share of key keyID after refresh number
+// generation, to the standby and signs the update.
+func Seal(config SealConfig, keyID string, generation uint64, share []byte, now time.Time) (*Update, error) {
+	if len(config.Key) != ed25519.PrivateKeySize || config.Recipient == nil {
+		return nil, fmt.Errorf("signing key and recipient must be provided")
+	}
+	if config.Recipient.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("recipient must be an X25519 key")
+	}
+	if keyID == "" || config.Party == "" || len(share) == 0 {
+		return nil, fmt.Errorf("key ID, party and share must be provided")
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	u := &Update{
+		KeyID:      keyID,
+		Party:      config.Party,
+		Generation: generation,
+		Ephemeral:  ephemeral.PublicKey().Bytes(),
+		CreatedAt:  now.UTC(),
+	}
+	aead, err := sealKey(ephemeral, config.Recipient, ephemeral.PublicKey(), config.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	u.Ciphertext = aead.Seal(nonce, nonce, share, u.associatedData())
+	digest, err := u.digest()
+	if err != nil {
+		return nil, err
+	}
+	u.Signature = ed25519.Sign(config.Key, append([]byte(updateDomain), digest...))
+	return u, nil
+}
+
+// associatedData binds the ciphertext to the key, party and generation.
+func (u *Update) associatedData() []byte {
+	return []byte(fmt.Sprintf("%s\x00%s\x00%d", u.KeyID, u.Party, u.Generation))
+}
+
+// sealKey derives the AES-256-GCM key shared by priv and peer, bound to the
+// sender's ephemeral key and the standby's key.
+func sealKey(priv *ecdh.PrivateKey, peer, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	shared, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(shared)
+	h := sha256.New()
+	h.Write([]byte(sealDomain))
+	h.Write(shared)
+	h.Write(ephemeral.Bytes())
+	h.Write(recipient.Bytes())
+	key := h.Sum(nil)
+	defer clear(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Promotion asks for a standby to take over a party's share.
+type Promotion struct {
+	ID          string    `json:"id"`
+	KeyID       string    `json:"key_id"`
+	Party       string    `json:"party"`
+	Standby     string    `json:"standby"`    // Name of the standby host
+	Generation  uint64    `json:"generation"` // Generation of the share it holds
+	Reason      string    `json:"reason"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// NewPromotion creates a promotion with a fresh random ID.  generation is
+// the one the standby reports in its Status.
+func NewPromotion(keyID, party, standby string, generation uint64, reason string, now time.Time) (*Promotion, error) {
+	if keyID == "" || party == "" || standby == "" {
+		return nil, fmt.Errorf("key ID, party and standby must be provided")
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("reason must be provided")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generating promotion ID: %w", err)
+	}
+	return &Promotion{
+		ID:          hex.EncodeToString(id),
+		KeyID:       keyID,
+		Party:       party,
+		Standby:     standby,
+		Generation:  generation,
+		Reason:      reason,
+		RequestedAt: now.UTC(),
+	}, nil
+}
+
+// Hash returns the SHA-256 digest of the promotion's JSON encoding.
+func (p *Promotion) Hash() ([]byte, error) {
+	return hashJSON(p)
+}
+
+// Approval records that an approver authorized a promotion.
+type Approval = approval.Approval
+
+// Approve signs p on behalf of approver.
+func Approve(p *Promotion, approver string, key ed25519.PrivateKey, now time.Time) (*Approval, error) {
+	hash, err := p.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hashing promotion: %w", err)
+	}
+	return approval.Sign(promotionDomain, hash, approver, key, now)
+}
+
+// checkApprovals checks that approvals contain at least policy.Required
+// valid signatures on p from distinct approvers.
+func checkApprovals(p *Promotion, approvals []Approval, policy Policy) error {
+	hash, err := p.Hash()
+	if err != nil {
+		return fmt.Errorf("hashing promotion: %w", err)
+	}
+	return approval.Quorum{Approvers: policy.Approvers, Required: policy.Required}.Check(promotionDomain, hash, approvals)
+}
+
+// Config contains the configuration for a Standby.
+type Config struct {
+	// Name identifies the standby host in promotions.  Required.
+	Name string
+	// Party is the party this host stands by for.  Required.
+	Party string
+	// Key is the X25519 key updates are sealed to.  Required.
+	Key *ecdh.PrivateKey
+	// Policy names the party's primary and the approvers.
+	Policy Policy
+	// Store keeps the latest update and the promotion record of each key.
+	// Required.
+	Store keystore.Medium
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Status describes what a standby holds for a key.
+type Status struct {
+	KeyID      string     `json:"key_id"`
+	Generation uint64     `json:"generation"`
+	ReceivedAt time.Time  `json:"received_at"`
+	Promoted   *Promotion `json:"promoted,omitempty"`
+}
+
+// record is the stored state of a key.
+type record struct {
+	Status
+	Update *Update `json:"update"`
+}
+
+// Standby receives a party's share updates and releases the share once
+// promoted.
+type Standby struct {
+	config Config
+	mu     sync.Mutex
+}
+
+// New creates a Standby from the given configuration.
+func New(config Config) (*Standby, error) {
+	if config.Name == "" || config.Party == "" || config.Key == nil || config.Store == nil {
+		return nil, fmt.Errorf("name, party, key and store must be provided")
+	}
+	if config.Key.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("key must be an X25519 key")
+	}
+	if len(config.Policy.Primaries[config.Party]) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("policy has no primary for party %q", config.Party)
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Standby{config: config}, nil
+}
+
+// Receive verifies u and stores it if it is newer than the share held.
+func (s *Standby) Receive(ctx context.Context, u *Update) error {
+	if u.Party != s.config.Party {
+		return fmt.Errorf("%w: update for party %q", ErrBadUpdate, u.Party)
+	}
+	digest, err := u.digest()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(s.config.Policy.Primaries[u.Party], append([]byte(updateDomain), digest...), u.Signature) {
+		return fmt.Errorf("%w: signature does not verify", ErrBadUpdate)
+	}
+	share, err := s.open(u)
+	if err != nil {
+		return err
+	}
+	clear(share)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, err := s.load(ctx, u.KeyID)
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		if rec.Promoted != nil {
+			return ErrPromoted
+		}
+		if u.Generation <= rec.Generation {
+			return fmt.Errorf("%w: generation %d, holding %d", ErrStale, u.Generation, rec.Generation)
+		}
+	}
+	return s.save(ctx, &record{
+		Status: Status{KeyID: u.KeyID, Generation: u.Generation, ReceivedAt: s.config.Now().UTC()},
+		Update: u,
+	})
+}
+
+// Status reports what the standby holds for key keyID, or keystore.ErrNotFound.
+func (s *Standby) Status(ctx context.Context, keyID string) (*Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, err := s.load(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, keystore.ErrNotFound
+	}
+	return &rec.Status, nil
+}
+
+// Promote checks that p is addressed to this standby, names the generation
+// it holds and is approved, records the promotion and returns the decrypted
+// share.  Promoting again with the same approved promotion returns the share
+// again; any later update is refused.
+func (s *Standby) Promote(ctx context.Context, p *Promotion, approvals []Approval) ([]byte, error) {
+	if p.Standby != s.config.Name || p.Party != s.config.Party {
+		return nil, fmt.Errorf("promotion is for %s as %s, not %s as %s", p.Standby, p.Party, s.config.Name, s.config.Party)
+	}
+	if err := checkApprovals(p, approvals, s.config.Policy); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, err := s.load(ctx, p.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, fmt.Errorf("no share held for key %s", p.KeyID)
+	}
+	if rec.Promoted != nil && rec.Promoted.ID != p.ID {
+		return nil, ErrPromoted
+	}
+	if p.Generation != rec.Generation {
+		return nil, fmt.Errorf("promotion is for generation %d, holding %d", p.Generation, rec.Generation)
+	}
+	if rec.Promoted == nil {
+		rec.Promoted = p
+		if err := s.save(ctx, rec); err != nil {
+			return nil, fmt.Errorf("recording promotion: %w", err)
+		}
+	}
+	return s.open(rec.Update)
+}
+
+// open decrypts the share in u.
+func (s *Standby) open(u *Update) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().NewPublicKey(u.Ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadUpdate, err)
+	}
+	aead, err := sealKey(s.config.Key, ephemeral, ephemeral, s.config.Key.PublicKey())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadUpdate, err)
+	}
+	if len(u.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrBadUpdate)
+	}
+	nonce, ciphertext := u.Ciphertext[:aead.NonceSize()], u.Ciphertext[aead.NonceSize():]
+	share, err := aead.Open(nil, nonce, ciphertext, u.associatedData())
+	if err != nil {
+		return nil, fmt.Errorf("%w: share does not decrypt", ErrBadUpdate)
+	}
+	return share, nil
+}
+
+func recordID(keyID string) string { return "standby." + keyID }
+
+// load returns the record of keyID, or nil if there is none.
+func (s *Standby) load(ctx context.Context, keyID string) (*record, error) {
+	data, err := s.config.Store.Load(ctx, recordID(keyID))
+	if errors.Is(err, keystore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec := new(record)
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("decoding standby record: %v", err)
+	}
+	return rec, nil
+}
+
+func (s *Standby) save(ctx context.Context, rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.config.Store.Store(ctx, recordID(rec.KeyID), data)
+}
+
+func hashJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
@@ -0,0 +1,120 @@
+package standby
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+)
+
+type fixture struct {
+	primary   ed25519.PrivateKey
+	approvers *approvaltest.Signers
+	seal      SealConfig
+	standby   *Standby
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	primaries := approvaltest.New(t, "kms")
+	f := &fixture{primary: primaries.Private["kms"], approvers: approvaltest.New(t, "alice", "bob", "carol")}
+	policy := Policy{Primaries: primaries.Public, Approvers: f.approvers.Public, Required: 2}
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	store, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	f.standby, err = New(Config{Name: "kms-standby", Party: "kms", Key: key, Policy: policy, Store: store})
+	require.NoError(t, err)
+	f.seal = SealConfig{Party: "kms", Key: f.primary, Recipient: key.PublicKey()}
+	return f
+}
+
+func (f *fixture) approve(t *testing.T, p *Promotion, names ...string) []Approval {
+	t.Helper()
+	hash, err := p.Hash()
+	require.NoError(t, err)
+	return f.approvers.Approve(t, promotionDomain, hash, names...)
+}
+
+func TestReceiveKeepsNewestVerifiedUpdate(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	u1, err := Seal(f.seal, "treasury", 1, []byte("share 1"), time.Now())
+	require.NoError(t, err)
+	require.NoError(t, f.standby.Receive(ctx, u1))
+	u2, err := Seal(f.seal, "treasury", 2, []byte("share 2"), time.Now())
+	require.NoError(t, err)
+	require.NoError(t, f.standby.Receive(ctx, u2))
+	assert.ErrorIs(t, f.standby.Receive(ctx, u1), ErrStale)
+
+	st, err := f.standby.Status(ctx, "treasury")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), st.Generation)
+	assert.Nil(t, st.Promoted)
+
+	tampered := *u2
+	tampered.Generation = 3
+	assert.ErrorIs(t, f.standby.Receive(ctx, &tampered), ErrBadUpdate)
+
+	_, forger, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	forged := f.seal
+	forged.Key = forger
+	u3, err := Seal(forged, "treasury", 3, []byte("evil"), time.Now())
+	require.NoError(t, err)
+	assert.ErrorIs(t, f.standby.Receive(ctx, u3), ErrBadUpdate)
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	misaddressed := f.seal
+	misaddressed.Recipient = other.PublicKey()
+	u4, err := Seal(misaddressed, "treasury", 4, []byte("share 4"), time.Now())
+	require.NoError(t, err)
+	assert.ErrorIs(t, f.standby.Receive(ctx, u4), ErrBadUpdate)
+}
+
+func TestPromoteRequiresQuorum(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	u, err := Seal(f.seal, "treasury", 5, []byte("share 5"), time.Now())
+	require.NoError(t, err)
+	require.NoError(t, f.standby.Receive(ctx, u))
+
+	p, err := NewPromotion("treasury", "kms", "kms-standby", 5, "primary host lost", time.Now())
+	require.NoError(t, err)
+	_, err = f.standby.Promote(ctx, p, f.approve(t, p, "alice", "alice"))
+	assert.ErrorIs(t, err, ErrNotApproved)
+
+	stale, err := NewPromotion("treasury", "kms", "kms-standby", 4, "primary host lost", time.Now())
+	require.NoError(t, err)
+	_, err = f.standby.Promote(ctx, stale, f.approve(t, stale, "alice", "bob"))
+	assert.Error(t, err)
+
+	share, err := f.standby.Promote(ctx, p, f.approve(t, p, "alice", "bob"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("share 5"), share)
+
+	st, err := f.standby.Status(ctx, "treasury")
+	require.NoError(t, err)
+	require.NotNil(t, st.Promoted)
+	assert.Equal(t, p.ID, st.Promoted.ID)
+
+	next, err := Seal(f.seal, "treasury", 6, []byte("share 6"), time.Now())
+	require.NoError(t, err)
+	assert.ErrorIs(t, f.standby.Receive(ctx, next), ErrPromoted)
+
+	again, err := NewPromotion("treasury", "kms", "kms-standby", 5, "again", time.Now())
+	require.NoError(t, err)
+	_, err = f.standby.Promote(ctx, again, f.approve(t, again, "bob", "carol"))
+	assert.ErrorIs(t, err, ErrPromoted)
+}
@@ -0,0 +1,34 @@
+// Package tenant lets one party daemon host the shares of many customers
+// while keeping them strictly apart.
+//
+// A `Registry` is configured with every `Tenant` the daemon serves and gives
+// each of them its own namespace:
+//
+//   - Shares – `Registry.Store` returns a keystore.Medium that prefixes IDs
+//     with the tenant and encrypts data with the tenant's own AES-256-GCM
+//     key, bound to tenant and share ID, before it reaches the shared
+//     medium.  A share copied into another tenant's namespace does not
+//     decrypt.
+//
+//   - Policy – every tenant has its own policy.Engine, so rules, address
+//     books and rate limits never mix.  The Registry is itself a
+//     coordinator.Policy that evaluates each request with the engine of
+//     coordinator.Request.Tenant and denies requests for unknown tenants.
+//     It is a daemon.Reloader that reloads every engine.
+//
+//   - API callers – `Registry.Authenticate` accepts the tenants' bearer
+//     tokens and names callers "tenant/caller"; `Registry.TenantOf` maps
+//     them back.  Plugged into provision.Config.Tenant, it confines every
+//     caller to its own tenant's keys.
+//
+// Wiring a daemon:
+//
+//	reg, err := tenant.New(tenant.Config{Store: shared, Tenants: tenants})
+//	d := daemon.New(daemon.Config{Reloaders: []daemon.Reloader{reg}})
+//	prov, err := provision.New(provision.Config{
+//	    State:        state,
+//	    Backend:      backend,
+//	    Authenticate: reg.Authenticate,
+//	    Tenant:       reg.TenantOf,
+//	})
+package tenant
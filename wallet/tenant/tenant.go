@@ -0,0 +1,226 @@
+package tenant
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/policy"
+	"solana-threshold-wallet/wallet/price"
+	"solana-threshold-wallet/wallet/remotesigner"
+)
+
+// ErrUnknownTenant is returned for tenants the Registry does not host.
+var ErrUnknownTenant = errors.New("tenant: unknown tenant")
+
+// validID restricts tenant IDs so that they cannot contain the separator of
+// namespaced share IDs or caller names.
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,62}$`)
+
+// Valid reports whether id is a valid tenant ID: up to 63 letters, digits
+// and dashes, starting with a letter or digit.
+func Valid(id string) bool { return validID.MatchString(id) }
+
+// Tenant is one customer hosted by the daemon.
+type Tenant struct {
+	ID string
+	// Key is the tenant's 32-byte share encryption key.
+	Key []byte
+	// Tokens maps the API bearer tokens of the tenant's callers to caller
+	// names.
+	Tokens map[string]string
+	// Policy returns the tenant's policy document, e.g. policy.FileSource.
+	Policy func() ([]byte, error)
+}
+
+// Config contains the configuration for a Registry.
+type Config struct {
+	// Store is the medium holding the shares of all tenants.  Required.
+	Store keystore.Medium
+	// Tenants lists the tenants hosted.
+	Tenants []Tenant
+	// Now is passed to the policy engines.  Defaults to time.Now.
+	Now func() time.Time
+	// Prices is passed to the policy engines for max_value limits.
+	Prices price.Oracle
+}
+
+// Registry holds the namespaces of all tenants.
+type Registry struct {
+	tenants      map[string]*entry
+	authenticate func(*http.Request) (string, error)
+}
+
+type entry struct {
+	store  *medium
+	engine *policy.Engine
+}
+
+// Ensure Registry implements the coordinator.Policy interface
+var _ coordinator.Policy = (*Registry)(nil)
+
+// New creates a Registry and loads every tenant's policy, which must be
+// valid.
+func New(config Config) (*Registry, error) {
+	if config.Store == nil {
+		return nil, fmt.Errorf("store must be provided")
+	}
+	r := &Registry{tenants: make(map[string]*entry, len(config.Tenants))}
+	tokens := map[string]string{}
+	for _, t := range config.Tenants {
+		if !Valid(t.ID) {
+			return nil, fmt.Errorf("invalid tenant ID %q", t.ID)
+		}
+		if r.tenants[t.ID] != nil {
+			return nil, fmt.Errorf("tenant %s is listed twice", t.ID)
+		}
+		if len(t.Key) != 32 {
+			return nil, fmt.Errorf("tenant %s: encryption key must be 32 bytes, got %d", t.ID, len(t.Key))
+		}
+		if t.Policy == nil {
+			return nil, fmt.Errorf("tenant %s: policy source must be provided", t.ID)
+		}
+		block, err := aes.NewCipher(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		engine, err := policy.NewEngine(policy.Config{Source: t.Policy, Now: config.Now, Prices: config.Prices})
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+		for token, caller := range t.Tokens {
+			if token == "" || caller == "" {
+				return nil, fmt.Errorf("tenant %s: tokens and caller names must be non-empty", t.ID)
+			}
+			if _, ok := tokens[token]; ok {
+				return nil, fmt.Errorf("tenant %s: token is already in use", t.ID)
+			}
+			tokens[token] = t.ID + "/" + caller
+		}
+		r.tenants[t.ID] = &entry{store: &medium{base: config.Store, tenant: t.ID, aead: aead}, engine: engine}
+	}
+	r.authenticate = remotesigner.BearerTokens(tokens)
+	return r, nil
+}
+
+// Tenants returns the IDs of the hosted tenants in order.
+func (r *Registry) Tenants() []string {
+	ids := make([]string, 0, len(r.tenants))
+	for id := range r.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Store returns the share namespace of tenant id.
+func (r *Registry) Store(id string) (keystore.Medium, error) {
+	e := r.tenants[id]
+	if e == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, id)
+	}
+	return e.store, nil
+}
+
+// Policy returns the policy engine of tenant id.
+func (r *Registry) Policy(id string) (*policy.Engine, error) {
+	e := r.tenants[id]
+	if e == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTenant, id)
+	}
+	return e.engine, nil
+}
+
+// Evaluate implements coordinator.Policy with the engine of req.Tenant.
+// Requests without a hosted tenant are denied.
+func (r *Registry) Evaluate(ctx context.Context, req *coordinator.Request, summary *chain.Summary) (*coordinator.Decision, error) {
+	e := r.tenants[req.Tenant]
+	if e == nil {
+		return &coordinator.Decision{Reason: fmt.Sprintf("unknown tenant %q", req.Tenant)}, nil
+	}
+	return e.engine.Evaluate(ctx, req, summary)
+}
+
+// Reload reloads the policy of every tenant.  Each engine keeps its previous
+// rules on error; the errors of all tenants are returned together.
+func (r *Registry) Reload() error {
+	var errs []error
+	for _, id := range r.Tenants() {
+		if err := r.tenants[id].engine.Reload(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Authenticate identifies the caller of an HTTP request by its bearer token
+// and names it "tenant/caller".
+func (r *Registry) Authenticate(req *http.Request) (string, error) {
+	return r.authenticate(req)
+}
+
+// TenantOf returns the tenant of a caller named by Authenticate, or "" for
+// other names.
+func (r *Registry) TenantOf(caller string) string {
+	id, _, ok := strings.Cut(caller, "/")
+	if !ok || r.tenants[id] == nil {
+		return ""
+	}
+	return id
+}
+
+// medium is a tenant's namespace in a shared medium.
+type medium struct {
+	base   keystore.Medium
+	tenant string
+	aead   cipher.AEAD
+}
+
+// Ensure medium implements the keystore.Medium interface
+var _ keystore.Medium = (*medium)(nil)
+
+func (m *medium) Name() string { return m.base.Name() + "#" + m.tenant }
+
+// id returns the name of share id in the shared medium.  Tenant IDs cannot
+// contain underscores, so names of different tenants never collide.
+func (m *medium) id(id string) string { return m.tenant + "_" + id }
+
+func (m *medium) Store(ctx context.Context, id string, data []byte) error {
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generating nonce: %v", err)
+	}
+	return m.base.Store(ctx, m.id(id), m.aead.Seal(nonce, nonce, data, []byte(m.id(id))))
+}
+
+func (m *medium) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := m.base.Load(ctx, m.id(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < m.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:m.aead.NonceSize()], data[m.aead.NonceSize():]
+	plain, err := m.aead.Open(nil, nonce, ciphertext, []byte(m.id(id)))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s of tenant %s: %v", id, m.tenant, err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,152 @@
+package tenant
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coinbase/cb-mpc/demos-go/cb-mpc-go/api/keystore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/policy"
+)
+
+func static(doc string) func() ([]byte, error) {
+	return func() ([]byte, error) { return []byte(doc), nil }
+}
+
+func newTestRegistry(t *testing.T) (*Registry, string) {
+	t.Helper()
+	dir := t.TempDir()
+	shared, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "globex.json"), []byte(`{"version": "v1", "rules": [{"name": "small", "max_amount": "10"}]}`), 0o600))
+	r, err := New(Config{
+		Store: shared,
+		Tenants: []Tenant{
+			{
+				ID:     "acme",
+				Key:    bytes.Repeat([]byte{1}, 32),
+				Tokens: map[string]string{"acme-token": "terraform"},
+				Policy: static(`{"version": "v1", "rules": [{"name": "all"}]}`),
+			},
+			{
+				ID:     "globex",
+				Key:    bytes.Repeat([]byte{2}, 32),
+				Tokens: map[string]string{"globex-token": "terraform"},
+				Policy: policy.FileSource(filepath.Join(dir, "globex.json")),
+			},
+		},
+	})
+	require.NoError(t, err)
+	return r, dir
+}
+
+func TestStoresAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	r, dir := newTestRegistry(t)
+	assert.Equal(t, []string{"acme", "globex"}, r.Tenants())
+
+	acme, err := r.Store("acme")
+	require.NoError(t, err)
+	globex, err := r.Store("globex")
+	require.NoError(t, err)
+	require.NoError(t, acme.Store(ctx, "treasury", []byte("acme share")))
+
+	got, err := acme.Load(ctx, "treasury")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("acme share"), got)
+	_, err = globex.Load(ctx, "treasury")
+	assert.ErrorIs(t, err, keystore.ErrNotFound)
+
+	// The shared medium only ever sees ciphertext.
+	raw, err := os.ReadFile(filepath.Join(dir, "acme_treasury"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "acme share")
+
+	// A share copied into another namespace, or renamed within one, does not
+	// decrypt.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "globex_treasury"), raw, 0o600))
+	_, err = globex.Load(ctx, "treasury")
+	assert.Error(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "acme_hot"), raw, 0o600))
+	_, err = acme.Load(ctx, "hot")
+	assert.Error(t, err)
+
+	_, err = r.Store("initech")
+	assert.ErrorIs(t, err, ErrUnknownTenant)
+}
+
+func TestEvaluateUsesTheTenantsPolicy(t *testing.T) {
+	ctx := context.Background()
+	r, dir := newTestRegistry(t)
+	large := &chain.Summary{Chain: "solana-test", From: "treasury", To: "alice", Amount: big.NewInt(100)}
+
+	d, err := r.Evaluate(ctx, &coordinator.Request{Tenant: "acme"}, large)
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+	d, err = r.Evaluate(ctx, &coordinator.Request{Tenant: "globex"}, large)
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	d, err = r.Evaluate(ctx, &coordinator.Request{}, large)
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "unknown tenant")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "globex.json"), []byte(`{"version": "v2", "rules": [{"name": "all"}]}`), 0o600))
+	require.NoError(t, r.Reload())
+	d, err = r.Evaluate(ctx, &coordinator.Request{Tenant: "globex"}, large)
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "globex.json"), []byte(`not json`), 0o600))
+	err = r.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tenant globex")
+	e, err := r.Policy("globex")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", e.Rules().Version)
+}
+
+func TestAuthenticateNamesTenantCallers(t *testing.T) {
+	r, _ := newTestRegistry(t)
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer globex-token")
+	caller, err := r.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "globex/terraform", caller)
+	assert.Equal(t, "globex", r.TenantOf(caller))
+	assert.Equal(t, "", r.TenantOf("terraform"))
+	assert.Equal(t, "", r.TenantOf("initech/terraform"))
+
+	req.Header.Set("Authorization", "Bearer wrong")
+	_, err = r.Authenticate(req)
+	assert.Error(t, err)
+}
+
+func TestNewRejectsInvalidTenants(t *testing.T) {
+	shared, err := keystore.NewFileMedium(keystore.FileMediumConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+	key := bytes.Repeat([]byte{1}, 32)
+	doc := static(`{"version": "v1", "rules": [{"name": "all"}]}`)
+	for name, tenants := range map[string][]Tenant{
+		"bad ID":       {{ID: "acme_corp", Key: key, Policy: doc}},
+		"duplicate":    {{ID: "acme", Key: key, Policy: doc}, {ID: "acme", Key: key, Policy: doc}},
+		"short key":    {{ID: "acme", Key: key[:16], Policy: doc}},
+		"no policy":    {{ID: "acme", Key: key}},
+		"shared token": {{ID: "acme", Key: key, Policy: doc, Tokens: map[string]string{"t": "a"}}, {ID: "globex", Key: key, Policy: doc, Tokens: map[string]string{"t": "b"}}},
+	} {
+		_, err := New(Config{Store: shared, Tenants: tenants})
+		assert.Error(t, err, name)
+	}
+	_, err = New(Config{})
+	assert.Error(t, err)
+}
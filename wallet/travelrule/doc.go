@@ -0,0 +1,30 @@
+// Package travelrule enforces and transmits the travel-rule data that
+// regulated virtual asset service providers attach to transfers.
+//
+// The submitter attaches the originator and beneficiary as IVMS101 data to
+// the signing request:
+//
+//	c.Submit(ctx, &coordinator.Request{
+//	    Chain:      "solana-mainnet",
+//	    Transfer:   transfer,
+//	    TravelRule: &ivms101.Payload{Originator: …, Beneficiary: …},
+//	})
+//
+// The data is part of the request and is therefore recorded in the session's
+// created event, i.e. in the coordinator's audit trail, alongside everything
+// else the session did.
+//
+// `Policy` wraps the coordinator's policy.  It denies transfers at or above
+// the configured threshold of their asset that carry no data, data that is
+// not valid IVMS101, and data whose account numbers do not include the
+// transfer's sender and recipients; everything else is passed on to the
+// wrapped policy.
+//
+// A `Transmitter`, if configured, posts the data of every broadcast session
+// to a TRP endpoint as a `Message` with the transaction hash, using the TRP
+// api-version and request-identifier headers.  Failed posts are retried with
+// exponential backoff up to Config.MaxAttempts; `Failed` lists the
+// transmissions given up and `Retry` queues one again.  The transmissions are
+// kept at TransmitterConfig.Path, which, together with the created events,
+// records what was sent where and when.
+package travelrule
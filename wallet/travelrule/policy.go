@@ -0,0 +1,78 @@
+package travelrule
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// PolicyConfig contains the configuration of Policy.
+type PolicyConfig struct {
+	// Next decides the requests that pass the travel-rule checks, e.g. a
+	// policy.Engine.  Required.
+	Next coordinator.Policy
+	// Thresholds maps assets – a token mint or contract address, or "" for
+	// the native asset – to the amount in smallest units from which
+	// travel-rule data is required.  Transfers of assets missing from the map
+	// always require it.
+	Thresholds map[string]*big.Int
+}
+
+// Policy returns a coordinator.Policy that checks the travel-rule data of a
+// request before config.Next evaluates it.  Requests must carry data when the
+// transfer reaches the threshold of its asset, and any data carried must be
+// valid IVMS101 naming the transfer's sender and recipients among the
+// originator's and beneficiary's account numbers, where those are given.
+// Requests failing a check are denied.
+func Policy(config PolicyConfig) (coordinator.Policy, error) {
+	if config.Next == nil {
+		return nil, fmt.Errorf("next policy must be provided")
+	}
+	return coordinator.PolicyFunc(func(ctx context.Context, req *coordinator.Request, summary *chain.Summary) (*coordinator.Decision, error) {
+		if summary == nil || summary.Amount == nil {
+			return nil, fmt.Errorf("summary is incomplete")
+		}
+		if reason := check(&config, req, summary); reason != "" {
+			return &coordinator.Decision{Reason: "travel rule: " + reason}, nil
+		}
+		return config.Next.Evaluate(ctx, req, summary)
+	}), nil
+}
+
+// check returns why req violates the travel rule, or "" if it does not.
+func check(config *PolicyConfig, req *coordinator.Request, summary *chain.Summary) string {
+	data := req.TravelRule
+	if data == nil {
+		threshold, ok := config.Thresholds[summary.Token]
+		if !ok || summary.Amount.Cmp(threshold) >= 0 {
+			return "originator and beneficiary data is required"
+		}
+		return ""
+	}
+	if err := data.Validate(); err != nil {
+		return err.Error()
+	}
+	if accounts := data.Originator.AccountNumber; len(accounts) > 0 && !contains(accounts, summary.From) {
+		return fmt.Sprintf("sender %s is not an originator account", summary.From)
+	}
+	if accounts := data.Beneficiary.AccountNumber; len(accounts) > 0 {
+		for _, o := range summary.Recipients() {
+			if !contains(accounts, o.To) {
+				return fmt.Sprintf("recipient %s is not a beneficiary account", o.To)
+			}
+		}
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
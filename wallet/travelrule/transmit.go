@@ -0,0 +1,381 @@
+package travelrule
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/ivms101"
+)
+
+const (
+	defaultInterval    = 30 * time.Second
+	defaultMaxAttempts = 8
+	defaultBackoff     = 10 * time.Second
+	defaultTimeout     = 10 * time.Second
+
+	// apiVersion is the TRP version announced in the api-version header.
+	apiVersion = "3.1.0"
+)
+
+// ErrNotFound is returned for sessions without a transmission.
+var ErrNotFound = errors.New("travelrule: transmission not found")
+
+// Asset identifies the transferred asset in a Message.
+type Asset struct {
+	Chain string `json:"chain"`           // Coordinator chain ID
+	Token string `json:"token,omitempty"` // Token mint or contract; empty for the native asset
+}
+
+// Message is the body posted to the TRP endpoint once a transfer carrying
+// travel-rule data was broadcast.
+type Message struct {
+	Asset   Asset            `json:"asset"`
+	Amount  string           `json:"amount"` // Decimal integer in the asset's smallest unit
+	IVMS101 *ivms101.Payload `json:"IVMS101"`
+	TxID    string           `json:"txid"`
+}
+
+// Transmission is the stored state of the data of one session.
+type Transmission struct {
+	Session   string
+	TxID      string // Transaction the data was or is to be sent for
+	RequestID string // TRP request-identifier, stable across retries
+	CreatedAt time.Time
+
+	Attempts    int       // Failed attempts
+	NextAttempt time.Time // Earliest time of the next attempt
+	Delivered   time.Time // When the endpoint acknowledged the data; zero if pending
+	Failed      bool      // Attempts were given up; see Retry
+	LastError   string    // Error of the last failed attempt
+}
+
+// TransmitterConfig contains the configuration for a Transmitter.
+type TransmitterConfig struct {
+	// Coordinator runs the sessions whose data is transmitted.  Required.
+	Coordinator *coordinator.Coordinator
+	// Endpoint is the TRP URL the data is posted to.  Required.
+	Endpoint string
+	// Client posts the data.  Defaults to a client with a 10s timeout; set
+	// one with client certificates for mutually authenticated TLS.
+	Client *http.Client
+	// Path is the file transmissions are kept in.  If empty, they are only
+	// kept in memory and every broadcast session is transmitted again after
+	// a restart.
+	Path string
+	// Interval is how often Run looks for broadcast sessions.  Defaults to
+	// 30s.
+	Interval time.Duration
+	// MaxAttempts bounds the attempts per transmission.  Defaults to 8.
+	MaxAttempts int
+	// Backoff is the delay after the first failed attempt; it doubles with
+	// every further failure.  Defaults to 10s.
+	Backoff time.Duration
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Transmitter posts the travel-rule data of broadcast sessions to a TRP
+// endpoint.
+type Transmitter struct {
+	c      *coordinator.Coordinator
+	config TransmitterConfig
+
+	mu            sync.Mutex
+	transmissions map[string]*Transmission
+}
+
+// NewTransmitter creates a Transmitter from the given configuration, loading
+// the transmissions kept at config.Path.
+func NewTransmitter(config TransmitterConfig) (*Transmitter, error) {
+	if config.Coordinator == nil {
+		return nil, fmt.Errorf("coordinator must be provided")
+	}
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint must be provided")
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultBackoff
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	t := &Transmitter{c: config.Coordinator, config: config, transmissions: map[string]*Transmission{}}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Run calls Process every Config.Interval until ctx is done.
+func (t *Transmitter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+	for {
+		_ = t.Process(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process transmits the data of every broadcast or finalized session that
+// carries travel-rule data and has not been transmitted for its current
+// transaction, retrying failed attempts once their backoff has passed.  A
+// session whose transaction expired and was rebuilt is transmitted again for
+// the new transaction.
+func (t *Transmitter) Process(ctx context.Context) error {
+	sessions, err := t.c.List(ctx, coordinator.Filter{States: []coordinator.State{coordinator.StateBroadcast, coordinator.StateFinalized}})
+	if err != nil {
+		return err
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].UpdatedAt.Before(sessions[j].UpdatedAt) })
+	var errs []error
+	for _, s := range sessions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.Request.TravelRule == nil || s.TxID == "" {
+			continue
+		}
+		if err := t.transmit(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", s.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Transmitter) transmit(ctx context.Context, s *coordinator.Session) error {
+	tr, err := t.Transmission(s.ID)
+	if errors.Is(err, ErrNotFound) || (err == nil && tr.TxID != s.TxID) {
+		id, err := requestID()
+		if err != nil {
+			return err
+		}
+		tr = &Transmission{Session: s.ID, TxID: s.TxID, RequestID: id, CreatedAt: t.config.Now()}
+		if err := t.put(tr); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if !tr.Delivered.IsZero() || tr.Failed || t.config.Now().Before(tr.NextAttempt) {
+		return nil
+	}
+
+	msg := &Message{
+		Asset:   Asset{Chain: s.Request.Chain, Token: s.Request.Transfer.Token},
+		IVMS101: s.Request.TravelRule,
+		TxID:    s.TxID,
+	}
+	if s.Summary != nil && s.Summary.Amount != nil {
+		msg.Amount = s.Summary.Amount.String()
+	} else if s.Request.Transfer.Amount != nil {
+		msg.Amount = s.Request.Transfer.Amount.String()
+	}
+	err = t.post(ctx, tr.RequestID, msg)
+	if err == nil {
+		return t.update(s.ID, func(tr *Transmission) { tr.Delivered, tr.LastError = t.config.Now(), "" })
+	}
+	return errors.Join(err, t.update(s.ID, func(tr *Transmission) {
+		tr.Attempts++
+		tr.LastError = err.Error()
+		if tr.Attempts >= t.config.MaxAttempts {
+			tr.Failed = true
+			return
+		}
+		tr.NextAttempt = t.config.Now().Add(t.config.Backoff << (tr.Attempts - 1))
+	}))
+}
+
+func (t *Transmitter) post(ctx context.Context, id string, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("api-version", apiVersion)
+	req.Header.Set("request-identifier", id)
+	resp, err := t.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting travel-rule data: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("posting travel-rule data: %s", resp.Status)
+	}
+	return nil
+}
+
+// requestID returns a random UUID (version 4), as TRP uses for
+// request-identifier.
+func requestID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating request identifier: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Transmission returns the stored state of a session's transmission.
+func (t *Transmitter) Transmission(session string) (*Transmission, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr, ok := t.transmissions[session]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, session)
+	}
+	c := *tr
+	return &c, nil
+}
+
+// Failed returns the transmissions that were given up, oldest first.
+func (t *Transmitter) Failed() []*Transmission {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []*Transmission
+	for _, tr := range t.transmissions {
+		if tr.Failed {
+			c := *tr
+			out = append(out, &c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Session < out[j].Session
+	})
+	return out
+}
+
+// Retry makes a failed transmission pending again, e.g. after the endpoint
+// was fixed.
+func (t *Transmitter) Retry(session string) error {
+	tr, err := t.Transmission(session)
+	if err != nil {
+		return err
+	}
+	if !tr.Failed {
+		return fmt.Errorf("transmission for session %s has not failed", session)
+	}
+	return t.update(session, func(tr *Transmission) {
+		tr.Failed, tr.Attempts, tr.NextAttempt = false, 0, time.Time{}
+	})
+}
+
+// put stores a new transmission, replacing any earlier one of the session.
+func (t *Transmitter) put(tr *Transmission) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev := t.transmissions[tr.Session]
+	t.transmissions[tr.Session] = tr
+	if err := t.save(); err != nil {
+		if prev == nil {
+			delete(t.transmissions, tr.Session)
+		} else {
+			t.transmissions[tr.Session] = prev
+		}
+		return err
+	}
+	return nil
+}
+
+// update applies fn to the stored transmission and persists the result.
+func (t *Transmitter) update(session string, fn func(*Transmission)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr, ok := t.transmissions[session]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, session)
+	}
+	next := *tr
+	fn(&next)
+	t.transmissions[session] = &next
+	if err := t.save(); err != nil {
+		t.transmissions[session] = tr
+		return err
+	}
+	return nil
+}
+
+func (t *Transmitter) load() error {
+	if t.config.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading transmissions: %w", err)
+	}
+	var list []*Transmission
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("decoding transmissions: %w", err)
+	}
+	for _, tr := range list {
+		t.transmissions[tr.Session] = tr
+	}
+	return nil
+}
+
+// save writes all transmissions to Config.Path atomically.  t.mu must be
+// held.
+func (t *Transmitter) save() error {
+	if t.config.Path == "" {
+		return nil
+	}
+	list := make([]*Transmission, 0, len(t.transmissions))
+	for _, tr := range t.transmissions {
+		list = append(list, tr)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Session < list[j].Session })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(t.config.Path), ".transmissions-*")
+	if err != nil {
+		return fmt.Errorf("writing transmissions: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing transmissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing transmissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.config.Path); err != nil {
+		return fmt.Errorf("writing transmissions: %w", err)
+	}
+	return nil
+}
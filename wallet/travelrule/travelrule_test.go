@@ -0,0 +1,251 @@
+package travelrule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/ivms101"
+)
+
+// fakeChain finalizes everything it broadcasts.
+type fakeChain struct {
+	mu    sync.Mutex
+	built []chain.Transfer
+}
+
+func (f *fakeChain) ID() string { return "fake" }
+
+func (f *fakeChain) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (f *fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.built = append(f.built, *t)
+	payload := []byte{byte(len(f.built))}
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: payload, SigningPayload: payload}, nil
+}
+
+func (f *fakeChain) Decode(payload []byte) (*chain.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.built[payload[0]-1]
+	return &chain.Summary{Chain: f.ID(), From: t.From, To: t.To, Amount: t.Amount, Token: t.Token, Fee: big.NewInt(5000)}, nil
+}
+
+func (f *fakeChain) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (f *fakeChain) Broadcast(_ context.Context, tx *chain.SignedTx) (string, error) {
+	return fmt.Sprintf("tx-%d", tx.Unsigned.Payload[0]), nil
+}
+
+func (f *fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized}, nil
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(context.Context, *coordinator.SignRequest) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+func payload(from, to string) *ivms101.Payload {
+	return &ivms101.Payload{
+		Originator: ivms101.Originator{
+			OriginatorPersons: []ivms101.Person{{NaturalPerson: &ivms101.NaturalPerson{
+				Name: ivms101.NaturalPersonName{NameIdentifier: []ivms101.NaturalPersonNameIdentifier{
+					{PrimaryIdentifier: "Doe", SecondaryIdentifier: "Jane", NameIdentifierType: ivms101.NaturalNameLegal},
+				}},
+				CustomerIdentification: "C-1001",
+			}}},
+			AccountNumber: []string{from},
+		},
+		Beneficiary: ivms101.Beneficiary{
+			BeneficiaryPersons: []ivms101.Person{{LegalPerson: &ivms101.LegalPerson{
+				Name: ivms101.LegalPersonName{NameIdentifier: []ivms101.LegalPersonNameIdentifier{
+					{LegalPersonName: "Example Ltd", LegalPersonNameIdentifierType: ivms101.LegalNameLegal},
+				}},
+			}}},
+			AccountNumber: []string{to},
+		},
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	next := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		return &coordinator.Decision{Allow: true, RequiredApprovals: 1}, nil
+	})
+	p, err := Policy(PolicyConfig{Next: next, Thresholds: map[string]*big.Int{"": big.NewInt(1000)}})
+	require.NoError(t, err)
+	ctx := context.Background()
+	summary := func(amount int64, token string) *chain.Summary {
+		return &chain.Summary{Chain: "fake", From: "hot", To: "alice", Amount: big.NewInt(amount), Token: token}
+	}
+
+	// Below the threshold no data is needed; at it, it is.
+	d, err := p.Evaluate(ctx, &coordinator.Request{}, summary(999, ""))
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+	assert.Equal(t, 1, d.RequiredApprovals)
+	d, err = p.Evaluate(ctx, &coordinator.Request{}, summary(1000, ""))
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "travel rule")
+	// Assets without a threshold always need data.
+	d, err = p.Evaluate(ctx, &coordinator.Request{}, summary(1, "mint"))
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+
+	d, err = p.Evaluate(ctx, &coordinator.Request{TravelRule: payload("hot", "alice")}, summary(5000, "mint"))
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+
+	// Data is checked even below the threshold.
+	invalid := payload("hot", "alice")
+	invalid.Beneficiary.BeneficiaryPersons = nil
+	d, err = p.Evaluate(ctx, &coordinator.Request{TravelRule: invalid}, summary(1, ""))
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "beneficiary.beneficiaryPersons")
+
+	d, err = p.Evaluate(ctx, &coordinator.Request{TravelRule: payload("cold", "alice")}, summary(5000, ""))
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "originator account")
+
+	batch := summary(5000, "")
+	batch.To, batch.Outputs = "", []chain.Output{{To: "alice", Amount: big.NewInt(1)}, {To: "bob", Amount: big.NewInt(1)}}
+	d, err = p.Evaluate(ctx, &coordinator.Request{TravelRule: payload("hot", "alice")}, batch)
+	require.NoError(t, err)
+	assert.False(t, d.Allow)
+	assert.Contains(t, d.Reason, "recipient bob")
+
+	_, err = Policy(PolicyConfig{})
+	assert.Error(t, err)
+}
+
+// trpEndpoint records the messages posted to it.
+type trpEndpoint struct {
+	t        *testing.T
+	mu       sync.Mutex
+	status   int
+	messages []Message
+	ids      []string
+}
+
+func (e *trpEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	assert.Equal(e.t, apiVersion, r.Header.Get("api-version"))
+	e.ids = append(e.ids, r.Header.Get("request-identifier"))
+	if e.status != http.StatusOK {
+		w.WriteHeader(e.status)
+		return
+	}
+	var msg Message
+	require.NoError(e.t, json.NewDecoder(r.Body).Decode(&msg))
+	e.messages = append(e.messages, msg)
+}
+
+func TestTransmitter(t *testing.T) {
+	c, err := coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{&fakeChain{}},
+		Signer:          fakeSigner{},
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	endpoint := &trpEndpoint{t: t, status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(endpoint)
+	defer srv.Close()
+	now := time.Now()
+	config := TransmitterConfig{
+		Coordinator: c,
+		Endpoint:    srv.URL,
+		Path:        filepath.Join(t.TempDir(), "transmissions.json"),
+		MaxAttempts: 2,
+		Backoff:     time.Minute,
+		Now:         func() time.Time { return now },
+	}
+	tr, err := NewTransmitter(config)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	data := payload("hot", "alice")
+	with, err := c.Submit(ctx, &coordinator.Request{
+		Chain:      "fake",
+		Transfer:   chain.Transfer{From: "hot", To: "alice", Amount: big.NewInt(5000)},
+		TravelRule: data,
+	})
+	require.NoError(t, err)
+	without, err := c.Submit(ctx, &coordinator.Request{
+		Chain:    "fake",
+		Transfer: chain.Transfer{From: "hot", To: "bob", Amount: big.NewInt(10)},
+	})
+	require.NoError(t, err)
+
+	// Nothing is sent before broadcast.
+	require.NoError(t, tr.Process(ctx))
+	assert.Empty(t, endpoint.ids)
+
+	for _, id := range []string{with.ID, without.ID} {
+		s, err := c.Run(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, coordinator.StateFinalized, s.State)
+	}
+	// The data is part of the session's audit trail.
+	history, err := c.History(ctx, with.ID)
+	require.NoError(t, err)
+	assert.Equal(t, data, history[0].Request.TravelRule)
+
+	// A failed post is retried after the backoff, with the same request
+	// identifier, and given up after MaxAttempts.
+	assert.Error(t, tr.Process(ctx))
+	require.NoError(t, tr.Process(ctx))
+	require.Len(t, endpoint.ids, 1)
+	now = now.Add(time.Minute)
+	assert.Error(t, tr.Process(ctx))
+	require.Len(t, endpoint.ids, 2)
+	assert.Equal(t, endpoint.ids[0], endpoint.ids[1])
+	failed := tr.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, with.ID, failed[0].Session)
+	assert.Contains(t, failed[0].LastError, "503")
+
+	endpoint.status = http.StatusOK
+	require.NoError(t, tr.Retry(with.ID))
+	require.NoError(t, tr.Process(ctx))
+	require.Len(t, endpoint.messages, 1)
+	msg := endpoint.messages[0]
+	assert.Equal(t, Asset{Chain: "fake"}, msg.Asset)
+	assert.Equal(t, "5000", msg.Amount)
+	assert.Equal(t, "tx-1", msg.TxID)
+	assert.Equal(t, data, msg.IVMS101)
+
+	got, err := tr.Transmission(with.ID)
+	require.NoError(t, err)
+	assert.Equal(t, now, got.Delivered)
+	_, err = tr.Transmission(without.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Delivered data is not sent again, also after a restart.
+	tr, err = NewTransmitter(config)
+	require.NoError(t, err)
+	require.NoError(t, tr.Process(ctx))
+	assert.Len(t, endpoint.ids, 3)
+	assert.Error(t, tr.Retry(with.ID))
+}
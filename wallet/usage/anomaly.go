@@ -0,0 +1,228 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// Detector decides whether a transfer is unusual for its key.
+type Detector interface {
+	// Detect returns why u is anomalous given the key's profile p, or ""
+	// if it is not.  p is a copy and may be modified.
+	Detect(ctx context.Context, u *Use, p *Profile) (string, error)
+}
+
+// DetectorFunc adapts an ordinary function to the Detector interface.
+type DetectorFunc func(ctx context.Context, u *Use, p *Profile) (string, error)
+
+// Detect calls f(ctx, u, p).
+func (f DetectorFunc) Detect(ctx context.Context, u *Use, p *Profile) (string, error) {
+	return f(ctx, u, p)
+}
+
+// FirstDestination reports transfers to an address the key never paid.
+func FirstDestination() Detector {
+	return DetectorFunc(func(_ context.Context, u *Use, p *Profile) (string, error) {
+		for _, o := range u.Summary.Recipients() {
+			if p.Destinations[o.To] == nil {
+				return fmt.Sprintf("first transfer to %s", o.To), nil
+			}
+		}
+		return "", nil
+	})
+}
+
+// UnusualAmount reports transfers of more than multiple times the key's mean
+// amount of the asset.  Keys with fewer than minCount transfers of the asset
+// have no usual amount and are never reported.
+func UnusualAmount(multiple int64, minCount int) Detector {
+	if minCount < 1 {
+		minCount = 1
+	}
+	return DetectorFunc(func(_ context.Context, u *Use, p *Profile) (string, error) {
+		a := p.Assets[u.Summary.Token]
+		if a == nil || a.Count < minCount {
+			return "", nil
+		}
+		mean := a.Mean()
+		if u.Summary.Amount.Cmp(new(big.Int).Mul(mean, big.NewInt(multiple))) <= 0 {
+			return "", nil
+		}
+		return fmt.Sprintf("amount %s is more than %d times the usual %s", u.Summary.Amount, multiple, mean), nil
+	})
+}
+
+// UnusualHour reports transfers in a UTC hour of day that accounts for less
+// than share of the key's signings.  Keys with fewer than minCount signings
+// are never reported.
+func UnusualHour(share float64, minCount int) Detector {
+	if minCount < 1 {
+		minCount = 1
+	}
+	return DetectorFunc(func(_ context.Context, u *Use, p *Profile) (string, error) {
+		if p.Count < minCount {
+			return "", nil
+		}
+		hour := u.Time.UTC().Hour()
+		if float64(p.Hours[hour])/float64(p.Count) >= share {
+			return "", nil
+		}
+		return fmt.Sprintf("key rarely signs at %02d:00 UTC (%d of %d signings)", hour, p.Hours[hour], p.Count), nil
+	})
+}
+
+// All reports an anomaly only when every detector does, joining their
+// reasons.
+func All(detectors ...Detector) Detector {
+	return DetectorFunc(func(ctx context.Context, u *Use, p *Profile) (string, error) {
+		var reasons []string
+		for _, d := range detectors {
+			reason, err := d.Detect(ctx, u, p)
+			if err != nil || reason == "" {
+				return "", err
+			}
+			reasons = append(reasons, reason)
+		}
+		return strings.Join(reasons, " and "), nil
+	})
+}
+
+// Hook escalates the decision for transfers its Detector reports.
+type Hook struct {
+	// Name identifies the hook in decision reasons and findings.
+	Name     string
+	Detector Detector
+	// RequiredApprovals is the minimum number of approvals required once the
+	// hook fires.
+	RequiredApprovals int
+	// Approvers, if set, replaces the approvers eligible under the wrapped
+	// policy.  When several hooks fire, their approvers are combined.
+	Approvers []string
+	// Reviewers are observers whose review is required in addition.
+	Reviewers []string
+}
+
+// Finding is an anomaly reported by a hook.
+type Finding struct {
+	Hook    string
+	Reason  string
+	Time    time.Time
+	Request *coordinator.Request
+	Summary *chain.Summary
+}
+
+// PolicyConfig contains the configuration of Policy.
+type PolicyConfig struct {
+	// Next decides the transfer before anomaly detection, e.g. a
+	// policy.Engine.  Required.
+	Next coordinator.Policy
+	// Stats supplies the key profiles.  Required.
+	Stats *Stats
+	// Hooks run in order on every transfer Next allows.
+	Hooks []Hook
+	// OnFinding, if set, is called for every anomaly, e.g. to alert a
+	// security team.  It must not block.
+	OnFinding func(ctx context.Context, f *Finding)
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Policy returns a coordinator.Policy that escalates the decisions of
+// config.Next for transfers that a hook's detector reports as anomalous.
+// Detector errors are returned, so the session is evaluated again later
+// rather than signed unchecked.
+func Policy(config PolicyConfig) (coordinator.Policy, error) {
+	if config.Next == nil {
+		return nil, fmt.Errorf("next policy must be provided")
+	}
+	if config.Stats == nil {
+		return nil, fmt.Errorf("stats must be provided")
+	}
+	for _, h := range config.Hooks {
+		if h.Name == "" || h.Detector == nil {
+			return nil, fmt.Errorf("hook %q needs a name and a detector", h.Name)
+		}
+		if h.RequiredApprovals < 1 {
+			return nil, fmt.Errorf("hook %s must require at least one approval", h.Name)
+		}
+		if len(h.Approvers) > 0 && len(h.Approvers) < h.RequiredApprovals {
+			return nil, fmt.Errorf("hook %s requires %d approvals from %d approvers", h.Name, h.RequiredApprovals, len(h.Approvers))
+		}
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return coordinator.PolicyFunc(func(ctx context.Context, req *coordinator.Request, summary *chain.Summary) (*coordinator.Decision, error) {
+		decision, err := config.Next.Evaluate(ctx, req, summary)
+		if err != nil || decision == nil || !decision.Allow || len(config.Hooks) == 0 {
+			return decision, err
+		}
+		if summary == nil || summary.Amount == nil {
+			return nil, fmt.Errorf("summary is incomplete")
+		}
+		u := &Use{Time: config.Now(), Request: req, Summary: summary}
+		var fired []*Hook
+		var reasons []string
+		for i := range config.Hooks {
+			h := &config.Hooks[i]
+			reason, err := h.Detector.Detect(ctx, u, config.Stats.Profile(summary.Chain, summary.From))
+			if err != nil {
+				return nil, fmt.Errorf("anomaly hook %s: %w", h.Name, err)
+			}
+			if reason == "" {
+				continue
+			}
+			fired = append(fired, h)
+			reasons = append(reasons, fmt.Sprintf("%s (%s)", h.Name, reason))
+			if config.OnFinding != nil {
+				config.OnFinding(ctx, &Finding{Hook: h.Name, Reason: reason, Time: u.Time, Request: req, Summary: summary})
+			}
+		}
+		if len(fired) == 0 {
+			return decision, nil
+		}
+		escalated := escalate(decision, fired)
+		escalated.Reason = fmt.Sprintf("%s; escalated by %s", decision.Reason, strings.Join(reasons, ", "))
+		return escalated, nil
+	}), nil
+}
+
+// escalate returns a copy of d raised to what every fired hook requires.
+func escalate(d *coordinator.Decision, fired []*Hook) *coordinator.Decision {
+	out := *d
+	var approvers []string
+	out.Reviewers = append([]string(nil), d.Reviewers...)
+	for _, h := range fired {
+		if h.RequiredApprovals > out.RequiredApprovals {
+			out.RequiredApprovals = h.RequiredApprovals
+		}
+		approvers = appendNew(approvers, h.Approvers...)
+		out.Reviewers = appendNew(out.Reviewers, h.Reviewers...)
+	}
+	if len(approvers) > 0 {
+		out.Approvers = approvers
+	}
+	return &out
+}
+
+func appendNew(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, have := range list {
+			if have == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
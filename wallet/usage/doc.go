@@ -0,0 +1,32 @@
+// Package usage keeps per-key signing statistics and escalates unusual
+// requests to a stricter quorum.
+//
+// `Stats` is a coordinator.Observer.  Every transaction the coordinator
+// signs updates the `Profile` of its sending key – chain and address – with
+// the number of signings, the amounts per asset, the destinations paid and
+// the UTC hour of day of each request.  Re-signing a rebuilt transaction
+// after expiry is not counted again.  Statistics are kept in memory; after a
+// restart, `Stats.Load` rebuilds them from the coordinator's sessions:
+//
+//	stats := usage.New(usage.Config{})
+//	sessions, err := coord.List(ctx, coordinator.Filter{})
+//	stats.Load(sessions)
+//
+// `Policy` wraps another coordinator.Policy.  When the wrapped policy allows
+// a transfer, each `Hook`'s `Detector` inspects it together with the key's
+// profile, and every hook that reports an anomaly escalates the decision:
+// more approvals, a narrower set of approvers and additional reviewers.  The
+// escalation is noted in Decision.Reason, so approvers see why they were
+// asked.  Detection never loosens a decision or denies a transfer outright.
+//
+// Detectors are the extension point for integrators.  The package provides
+// FirstDestination, UnusualAmount and UnusualHour, and All to combine them,
+// e.g. "first-ever destination and ten times the usual amount":
+//
+//	usage.Hook{
+//	    Name:              "new-destination-large-amount",
+//	    Detector:          usage.All(usage.FirstDestination(), usage.UnusualAmount(10, 5)),
+//	    RequiredApprovals: 3,
+//	    Approvers:         []string{"cfo", "ciso", "treasury-lead"},
+//	}
+package usage
@@ -0,0 +1,241 @@
+package usage
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// defaultRecent is the default number of recent signing times kept per key.
+const defaultRecent = 1024
+
+// Use is one signing with a key: recorded into its Profile after the fact,
+// and presented to Detectors before signing.
+type Use struct {
+	Time    time.Time
+	Request *coordinator.Request
+	Summary *chain.Summary
+}
+
+// Profile is the signing history of one key.
+type Profile struct {
+	Chain   string
+	Address string
+	Count   int       // Number of signings
+	First   time.Time // Time of the first signing
+	Last    time.Time // Time of the latest signing
+	// Recent holds the times of the latest signings, oldest first, up to
+	// Config.Recent of them.
+	Recent []time.Time
+	// Hours counts signings by UTC hour of day.
+	Hours [24]int
+	// Assets holds amount statistics by asset: a token mint or contract
+	// address, or "" for the native asset.
+	Assets map[string]*Asset
+	// Destinations holds the addresses paid, with how often they were.
+	Destinations map[string]*Destination
+}
+
+// Asset holds the amounts a key transferred of one asset.
+type Asset struct {
+	Count int
+	Total *big.Int
+	Max   *big.Int
+}
+
+// Mean returns the average transferred amount, rounded down.
+func (a *Asset) Mean() *big.Int {
+	if a == nil || a.Count == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Quo(a.Total, big.NewInt(int64(a.Count)))
+}
+
+// Destination records payments to one address.
+type Destination struct {
+	Count int
+	First time.Time
+	Last  time.Time
+}
+
+// CountSince returns the number of signings after t, as far as Recent
+// reaches back.
+func (p *Profile) CountSince(t time.Time) int {
+	i := sort.Search(len(p.Recent), func(i int) bool { return p.Recent[i].After(t) })
+	return len(p.Recent) - i
+}
+
+// PerDay returns the average number of signings per day between the first
+// signing and now, counting at least one day.
+func (p *Profile) PerDay(now time.Time) float64 {
+	days := now.Sub(p.First).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	return float64(p.Count) / days
+}
+
+func (p *Profile) clone() *Profile {
+	c := *p
+	c.Recent = append([]time.Time(nil), p.Recent...)
+	c.Assets = make(map[string]*Asset, len(p.Assets))
+	for k, a := range p.Assets {
+		c.Assets[k] = &Asset{Count: a.Count, Total: new(big.Int).Set(a.Total), Max: new(big.Int).Set(a.Max)}
+	}
+	c.Destinations = make(map[string]*Destination, len(p.Destinations))
+	for k, d := range p.Destinations {
+		dc := *d
+		c.Destinations[k] = &dc
+	}
+	return &c
+}
+
+// Config contains the configuration of Stats.
+type Config struct {
+	// Name identifies the observer to the coordinator.  Defaults to "usage".
+	Name string
+	// Recent is the number of recent signing times kept per key.  Defaults
+	// to 1024.
+	Recent int
+}
+
+// Stats tracks the usage of every key.  It is safe for concurrent use.
+type Stats struct {
+	name   string
+	recent int
+
+	mu       sync.Mutex
+	profiles map[profileKey]*Profile
+}
+
+type profileKey struct{ chain, address string }
+
+// Ensure Stats implements the coordinator.Observer interface
+var _ coordinator.Observer = (*Stats)(nil)
+
+// New creates an empty Stats.
+func New(config Config) *Stats {
+	if config.Name == "" {
+		config.Name = "usage"
+	}
+	if config.Recent <= 0 {
+		config.Recent = defaultRecent
+	}
+	return &Stats{name: config.Name, recent: config.Recent, profiles: map[profileKey]*Profile{}}
+}
+
+// Record adds u to the profile of its sending key.
+func (st *Stats) Record(u *Use) {
+	s := u.Summary
+	if s == nil || s.Amount == nil {
+		return
+	}
+	t := u.Time.UTC()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	k := profileKey{s.Chain, s.From}
+	p := st.profiles[k]
+	if p == nil {
+		p = &Profile{Chain: s.Chain, Address: s.From, First: t, Assets: map[string]*Asset{}, Destinations: map[string]*Destination{}}
+		st.profiles[k] = p
+	}
+	p.Count++
+	if t.After(p.Last) {
+		p.Last = t
+	}
+	if t.Before(p.First) {
+		p.First = t
+	}
+	i := sort.Search(len(p.Recent), func(i int) bool { return p.Recent[i].After(t) })
+	p.Recent = append(p.Recent, time.Time{})
+	copy(p.Recent[i+1:], p.Recent[i:])
+	p.Recent[i] = t
+	if len(p.Recent) > st.recent {
+		p.Recent = p.Recent[len(p.Recent)-st.recent:]
+	}
+	p.Hours[t.Hour()]++
+
+	a := p.Assets[s.Token]
+	if a == nil {
+		a = &Asset{Total: new(big.Int), Max: new(big.Int)}
+		p.Assets[s.Token] = a
+	}
+	a.Count++
+	a.Total.Add(a.Total, s.Amount)
+	if s.Amount.Cmp(a.Max) > 0 {
+		a.Max.Set(s.Amount)
+	}
+	for _, o := range s.Recipients() {
+		d := p.Destinations[o.To]
+		if d == nil {
+			d = &Destination{First: t}
+			p.Destinations[o.To] = d
+		}
+		d.Count++
+		if t.After(d.Last) {
+			d.Last = t
+		}
+	}
+}
+
+// Profile returns a copy of the profile of the key with address on chain.
+// A key that has not signed yet has an empty profile.
+func (st *Stats) Profile(chain, address string) *Profile {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if p := st.profiles[profileKey{chain, address}]; p != nil {
+		return p.clone()
+	}
+	return &Profile{Chain: chain, Address: address, Assets: map[string]*Asset{}, Destinations: map[string]*Destination{}}
+}
+
+// Profiles returns copies of all profiles, ordered by chain and address.
+func (st *Stats) Profiles() []*Profile {
+	st.mu.Lock()
+	out := make([]*Profile, 0, len(st.profiles))
+	for _, p := range st.profiles {
+		out = append(out, p.clone())
+	}
+	st.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Chain != out[j].Chain {
+			return out[i].Chain < out[j].Chain
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out
+}
+
+// Load records every session that was signed, e.g. all sessions returned
+// by Coordinator.List after a restart.  Sessions are timed by when they were
+// requested, as in Observe.
+func (st *Stats) Load(sessions []*coordinator.Session) {
+	for _, s := range sessions {
+		if s.Signature != nil {
+			st.Record(&Use{Time: s.CreatedAt, Request: &s.Request, Summary: s.Summary})
+		}
+	}
+}
+
+// Name implements coordinator.Observer.
+func (st *Stats) Name() string { return st.name }
+
+// Observe implements coordinator.Observer and records the first signing of
+// each session.
+func (st *Stats) Observe(_ context.Context, s *coordinator.Session, e *coordinator.Event) {
+	if e.Type != coordinator.EventSigned || s.Attempt > 0 {
+		return
+	}
+	st.Record(&Use{Time: s.CreatedAt, Request: &s.Request, Summary: s.Summary})
+}
+
+// Review implements coordinator.Observer.  Stats never vetoes.
+func (st *Stats) Review(context.Context, *coordinator.Session) (*coordinator.Review, error) {
+	return &coordinator.Review{Reviewer: st.name}, nil
+}
@@ -0,0 +1,135 @@
+package usage
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+func transfer(to string, amount int64) *chain.Summary {
+	return &chain.Summary{Chain: "solana-test", From: "treasury", To: to, Amount: big.NewInt(amount), Fee: big.NewInt(5000)}
+}
+
+func TestStatsRecordsSignedSessions(t *testing.T) {
+	st := New(Config{Recent: 2})
+	at := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	signed := &coordinator.Event{Type: coordinator.EventSigned}
+	for i, to := range []string{"alice", "bob", "alice"} {
+		s := &coordinator.Session{CreatedAt: at.Add(time.Duration(i) * time.Hour), Summary: transfer(to, int64(100*(i+1)))}
+		st.Observe(context.Background(), s, signed)
+	}
+	// Neither other transitions nor re-signing after expiry count.
+	st.Observe(context.Background(), &coordinator.Session{CreatedAt: at, Summary: transfer("carol", 1)}, &coordinator.Event{Type: coordinator.EventFinalized})
+	st.Observe(context.Background(), &coordinator.Session{CreatedAt: at, Summary: transfer("carol", 1), Attempt: 1}, signed)
+
+	p := st.Profile("solana-test", "treasury")
+	assert.Equal(t, 3, p.Count)
+	assert.Equal(t, at, p.First)
+	assert.Equal(t, at.Add(2*time.Hour), p.Last)
+	assert.Len(t, p.Recent, 2)
+	assert.Equal(t, 1, p.CountSince(at.Add(90*time.Minute)))
+	assert.Equal(t, 1, p.Hours[9])
+	assert.Equal(t, 1, p.Hours[11])
+	assert.Equal(t, "600", p.Assets[""].Total.String())
+	assert.Equal(t, "300", p.Assets[""].Max.String())
+	assert.Equal(t, "200", p.Assets[""].Mean().String())
+	assert.Equal(t, 2, p.Destinations["alice"].Count)
+	assert.Nil(t, p.Destinations["carol"])
+
+	// Profiles are copies.
+	p.Assets[""].Total.SetInt64(0)
+	assert.Equal(t, "600", st.Profile("solana-test", "treasury").Assets[""].Total.String())
+	assert.Zero(t, st.Profile("solana-test", "hot").Count)
+
+	restored := New(Config{})
+	restored.Load([]*coordinator.Session{
+		{CreatedAt: at, Summary: transfer("alice", 100), Signature: []byte{1}},
+		{CreatedAt: at, Summary: transfer("bob", 100)},
+	})
+	require.Len(t, restored.Profiles(), 1)
+	assert.Equal(t, 1, restored.Profiles()[0].Count)
+}
+
+func TestPolicyEscalatesAnomalies(t *testing.T) {
+	ctx := context.Background()
+	st := New(Config{})
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		st.Record(&Use{Time: at.Add(time.Duration(i) * 24 * time.Hour), Summary: transfer("exchange", 1000)})
+	}
+	next := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		return &coordinator.Decision{Allow: true, Reason: "rule default", RequiredApprovals: 1, Approvers: []string{"ops-1", "ops-2"}}, nil
+	})
+	var findings []*Finding
+	p, err := Policy(PolicyConfig{
+		Next:  next,
+		Stats: st,
+		Hooks: []Hook{
+			{
+				Name:              "new-destination-large-amount",
+				Detector:          All(FirstDestination(), UnusualAmount(10, 5)),
+				RequiredApprovals: 2,
+				Approvers:         []string{"cfo", "ciso"},
+			},
+			{Name: "odd-hour", Detector: UnusualHour(0.05, 5), RequiredApprovals: 1, Reviewers: []string{"compliance"}},
+		},
+		OnFinding: func(_ context.Context, f *Finding) { findings = append(findings, f) },
+		Now:       func() time.Time { return at.Add(30 * 24 * time.Hour) },
+	})
+	require.NoError(t, err)
+
+	// Usual: known destination, usual amount, usual hour.
+	d, err := p.Evaluate(ctx, &coordinator.Request{}, transfer("exchange", 5000))
+	require.NoError(t, err)
+	assert.Equal(t, 1, d.RequiredApprovals)
+	assert.Equal(t, "rule default", d.Reason)
+
+	// A new destination alone, or a large amount alone, is not escalated.
+	d, err = p.Evaluate(ctx, &coordinator.Request{}, transfer("stranger", 5000))
+	require.NoError(t, err)
+	assert.Equal(t, 1, d.RequiredApprovals)
+	d, err = p.Evaluate(ctx, &coordinator.Request{}, transfer("exchange", 50000))
+	require.NoError(t, err)
+	assert.Equal(t, 1, d.RequiredApprovals)
+	assert.Empty(t, findings)
+
+	d, err = p.Evaluate(ctx, &coordinator.Request{}, transfer("stranger", 50000))
+	require.NoError(t, err)
+	assert.True(t, d.Allow)
+	assert.Equal(t, 2, d.RequiredApprovals)
+	assert.Equal(t, []string{"cfo", "ciso"}, d.Approvers)
+	assert.Empty(t, d.Reviewers)
+	assert.Contains(t, d.Reason, "escalated by new-destination-large-amount (first transfer to stranger and amount 50000")
+	require.Len(t, findings, 1)
+	assert.Equal(t, "new-destination-large-amount", findings[0].Hook)
+
+	// Both hooks fire at an unusual hour.
+	findings = nil
+	p, err = Policy(PolicyConfig{Next: next, Stats: st, Hooks: []Hook{
+		{Name: "large", Detector: UnusualAmount(10, 5), RequiredApprovals: 2, Approvers: []string{"cfo", "ciso"}},
+		{Name: "odd-hour", Detector: UnusualHour(0.05, 5), RequiredApprovals: 1, Reviewers: []string{"compliance"}},
+	}, Now: func() time.Time { return at.Add(3 * time.Hour) }})
+	require.NoError(t, err)
+	d, err = p.Evaluate(ctx, &coordinator.Request{}, transfer("exchange", 50000))
+	require.NoError(t, err)
+	assert.Equal(t, 2, d.RequiredApprovals)
+	assert.Equal(t, []string{"cfo", "ciso"}, d.Approvers)
+	assert.Equal(t, []string{"compliance"}, d.Reviewers)
+}
+
+func TestPolicyRejectsWeakHooks(t *testing.T) {
+	st := New(Config{})
+	_, err := Policy(PolicyConfig{Next: coordinator.AllowAll, Stats: st, Hooks: []Hook{{Name: "h", Detector: FirstDestination()}}})
+	assert.Error(t, err)
+	_, err = Policy(PolicyConfig{Next: coordinator.AllowAll, Stats: st, Hooks: []Hook{
+		{Name: "h", Detector: FirstDestination(), RequiredApprovals: 2, Approvers: []string{"cfo"}},
+	}})
+	assert.Error(t, err)
+}
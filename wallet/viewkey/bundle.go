@@ -0,0 +1,134 @@
+package viewkey
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/delegate"
+)
+
+// bundleVersion is the format version of exported bundles.  Version 2
+// derives child keys with mpc.DeriveEd25519 and records the chain code.
+const bundleVersion = 2
+
+// Address is one address of the wallet.
+type Address struct {
+	// Path is the delegate derivation path of the key, or empty for the
+	// master key.
+	Path      string            `json:"path,omitempty"`
+	PublicKey ed25519.PublicKey `json:"public_key"`
+	Address   string            `json:"address"`
+}
+
+// Bundle is everything a read-only integration needs to follow the wallet:
+// its public key, how child keys are derived and the resulting addresses.  It
+// holds public data only.
+type Bundle struct {
+	Version int    `json:"version"`
+	Chain   string `json:"chain"`
+	// MasterKey is the wallet's Ed25519 group public key.
+	MasterKey ed25519.PublicKey `json:"master_key"`
+	// Derivation names the scheme child keys are derived with, so that an
+	// integration can derive the keys of new paths itself.
+	Derivation string `json:"derivation"`
+	// ChainCode is the chain code of MasterKey, or empty for the default
+	// chain code of the scheme.
+	ChainCode []byte    `json:"chain_code,omitempty"`
+	Addresses []Address `json:"addresses"`
+	Created   time.Time `json:"created"`
+}
+
+// ExportConfig contains the configuration for Export.
+type ExportConfig struct {
+	// Chain derives the addresses.  Required.
+	Chain chain.Chain
+	// MasterKey is the wallet's group public key.  Required.
+	MasterKey ed25519.PublicKey
+	// ChainCode is the chain code child keys are derived with, or nil for
+	// the default chain code of the master key.
+	ChainCode []byte
+	// Paths lists the delegate derivation paths of the child keys to
+	// include next to the master key.
+	Paths []string
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Export builds the bundle of the master key and its child keys.
+func Export(config ExportConfig) (*Bundle, error) {
+	if config.Chain == nil {
+		return nil, fmt.Errorf("chain must be provided")
+	}
+	if len(config.MasterKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes", ed25519.PublicKeySize)
+	}
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+	master, err := config.Chain.DeriveAddress(config.MasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("deriving master address: %w", err)
+	}
+	b := &Bundle{
+		Version:    bundleVersion,
+		Chain:      config.Chain.ID(),
+		MasterKey:  config.MasterKey,
+		Derivation: delegate.Scheme,
+		ChainCode:  config.ChainCode,
+		Addresses:  []Address{{PublicKey: config.MasterKey, Address: master}},
+		Created:    now().UTC(),
+	}
+	seen := map[string]bool{}
+	for _, path := range config.Paths {
+		if seen[path] {
+			return nil, fmt.Errorf("duplicate path %q", path)
+		}
+		seen[path] = true
+		key, err := delegate.Derive(config.MasterKey, config.ChainCode, path)
+		if err != nil {
+			return nil, fmt.Errorf("deriving %q: %w", path, err)
+		}
+		address, err := config.Chain.DeriveAddress(key.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("deriving address of %q: %w", path, err)
+		}
+		b.Addresses = append(b.Addresses, Address{Path: path, PublicKey: key.PublicKey, Address: address})
+	}
+	return b, nil
+}
+
+// Verify checks that every address in b follows from its master key, so
+// that an integration can trust a bundle received over any channel once it
+// has confirmed MasterKey.
+func (b *Bundle) Verify(ch chain.Chain) error {
+	if b.Version != bundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	if b.Derivation != delegate.Scheme {
+		return fmt.Errorf("unsupported derivation %q", b.Derivation)
+	}
+	if b.Chain != ch.ID() {
+		return fmt.Errorf("bundle is for chain %q, not %q", b.Chain, ch.ID())
+	}
+	for _, a := range b.Addresses {
+		key := b.MasterKey
+		if a.Path != "" {
+			child, err := delegate.Derive(b.MasterKey, b.ChainCode, a.Path)
+			if err != nil {
+				return fmt.Errorf("deriving %q: %w", a.Path, err)
+			}
+			key = child.PublicKey
+		}
+		address, err := ch.DeriveAddress(key)
+		if err != nil {
+			return err
+		}
+		if !key.Equal(a.PublicKey) || address != a.Address {
+			return fmt.Errorf("address %s does not follow from the master key", a.Address)
+		}
+	}
+	return nil
+}
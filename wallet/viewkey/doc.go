@@ -0,0 +1,24 @@
+// Package viewkey exports read-only views of the wallet for analytics and
+// accounting systems.
+//
+// A `Bundle` holds the wallet's group public key, the derivation scheme of
+// its child keys (see delegate.Derive) and the addresses of the master key
+// and of every derivation path in use:
+//
+//	bundle, _ := viewkey.Export(viewkey.ExportConfig{Chain: solanaChain, MasterKey: pub, Paths: []string{"cbmpc/1/0"}})
+//
+// The bundle contains public data only; nothing in it helps to sign.  An
+// integration that confirmed the master key out of band can check the rest
+// with `Bundle.Verify` and derive further child keys itself.
+//
+// Integrations that should follow the wallet as it grows receive a view
+// token instead.  A `Registry` issues tokens per holder, optionally with an
+// expiry, stores only their hashes and revokes them individually:
+//
+//	token, grant, _ := registry.Issue("accounting", 90*24*time.Hour)
+//	_ = registry.Revoke(grant.ID)
+//
+// A `Handler` serves the current bundle to valid tokens at GET /v1/bundle.
+// View tokens carry a "view_" prefix and are checked by their own Registry,
+// so they are never accepted by a remotesigner or coordinator endpoint.
+package viewkey
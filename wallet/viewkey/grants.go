@@ -0,0 +1,218 @@
+package viewkey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenPrefix marks view tokens, so that they are not mistaken for, or
+// accepted as, credentials of a signing endpoint.
+const tokenPrefix = "view_"
+
+var (
+	// ErrUnknownToken is returned for tokens the Registry never issued.
+	ErrUnknownToken = errors.New("viewkey: unknown token")
+	// ErrRevoked is returned for tokens of a revoked grant.
+	ErrRevoked = errors.New("viewkey: token revoked")
+	// ErrExpired is returned for tokens of an expired grant.
+	ErrExpired = errors.New("viewkey: token expired")
+)
+
+// Grant records a view token issued to an integration.  The token itself is
+// only returned by Issue; the Registry keeps its hash.
+type Grant struct {
+	ID      string    `json:"id"`
+	Holder  string    `json:"holder"` // Integration the token was issued to, e.g. "accounting"
+	Hash    []byte    `json:"hash"`   // SHA-256 of the token
+	Issued  time.Time `json:"issued"`
+	Expires time.Time `json:"expires,omitempty"` // Zero if the grant does not expire
+	Revoked time.Time `json:"revoked,omitempty"` // Zero unless revoked
+}
+
+// RegistryConfig contains the configuration for a Registry.
+type RegistryConfig struct {
+	// Path is the JSON file the grants are kept in.  When empty, grants
+	// are kept in memory only.
+	Path string
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Registry issues, checks and revokes view tokens.
+type Registry struct {
+	path   string
+	now    func() time.Time
+	mu     sync.Mutex
+	grants map[string]*Grant
+}
+
+// NewRegistry creates a Registry from the given configuration, loading the
+// grants stored at config.Path.
+func NewRegistry(config RegistryConfig) (*Registry, error) {
+	r := &Registry{path: config.Path, now: config.Now, grants: make(map[string]*Grant)}
+	if r.now == nil {
+		r.now = time.Now
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Issue creates a grant for holder and returns its token.  A ttl of zero
+// issues a token that is valid until revoked.
+func (r *Registry) Issue(holder string, ttl time.Duration) (string, *Grant, error) {
+	if holder == "" {
+		return "", nil, fmt.Errorf("holder must be provided")
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+	hash := sha256.Sum256([]byte(token))
+	g := &Grant{ID: hex.EncodeToString(id), Holder: holder, Hash: hash[:], Issued: r.now().UTC()}
+	if ttl > 0 {
+		g.Expires = g.Issued.Add(ttl)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.grants[g.ID] = g
+	if err := r.save(); err != nil {
+		delete(r.grants, g.ID)
+		return "", nil, err
+	}
+	copied := *g
+	return token, &copied, nil
+}
+
+// Check returns the grant of token, or ErrUnknownToken, ErrRevoked or
+// ErrExpired.
+func (r *Registry) Check(token string) (*Grant, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrUnknownToken
+	}
+	hash := sha256.Sum256([]byte(token))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range r.grants {
+		if subtle.ConstantTimeCompare(g.Hash, hash[:]) != 1 {
+			continue
+		}
+		switch {
+		case !g.Revoked.IsZero():
+			return nil, fmt.Errorf("%w: grant %s of %s", ErrRevoked, g.ID, g.Holder)
+		case !g.Expires.IsZero() && !r.now().Before(g.Expires):
+			return nil, fmt.Errorf("%w: grant %s of %s", ErrExpired, g.ID, g.Holder)
+		}
+		copied := *g
+		return &copied, nil
+	}
+	return nil, ErrUnknownToken
+}
+
+// Revoke revokes the grant with the given ID.  Revoking a revoked grant is
+// a no-op.
+func (r *Registry) Revoke(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.grants[id]
+	if !ok {
+		return fmt.Errorf("unknown grant %q", id)
+	}
+	if !g.Revoked.IsZero() {
+		return nil
+	}
+	g.Revoked = r.now().UTC()
+	if err := r.save(); err != nil {
+		g.Revoked = time.Time{}
+		return err
+	}
+	return nil
+}
+
+// Grants returns all grants, including revoked and expired ones, in the
+// order they were issued.
+func (r *Registry) Grants() []Grant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Grant, 0, len(r.grants))
+	for _, g := range r.grants {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Issued.Equal(out[j].Issued) {
+			return out[i].Issued.Before(out[j].Issued)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (r *Registry) load() error {
+	if r.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading grants: %w", err)
+	}
+	var list []*Grant
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("decoding grants: %w", err)
+	}
+	for _, g := range list {
+		r.grants[g.ID] = g
+	}
+	return nil
+}
+
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	list := make([]*Grant, 0, len(r.grants))
+	for _, g := range r.grants {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".grants-*")
+	if err != nil {
+		return fmt.Errorf("writing grants: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing grants: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing grants: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("writing grants: %w", err)
+	}
+	return nil
+}
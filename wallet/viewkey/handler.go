@@ -0,0 +1,80 @@
+package viewkey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HandlerConfig contains the configuration for a Handler.
+type HandlerConfig struct {
+	// Registry checks the bearer tokens.  Required.
+	Registry *Registry
+	// Bundle returns the current bundle, e.g. by calling Export with the
+	// paths in use, so that integrations see new addresses.  Required.
+	Bundle func() (*Bundle, error)
+}
+
+// Handler serves the bundle to holders of a valid view token:
+//
+//	GET /v1/bundle   Authorization: Bearer view_…
+//
+// It offers nothing else, and in particular no way to reach shares or
+// signing endpoints.
+type Handler struct {
+	registry *Registry
+	bundle   func() (*Bundle, error)
+}
+
+// Ensure Handler implements the http.Handler interface
+var _ http.Handler = (*Handler)(nil)
+
+// NewHandler creates a Handler from the given configuration.
+func NewHandler(config HandlerConfig) (*Handler, error) {
+	if config.Registry == nil || config.Bundle == nil {
+		return nil, fmt.Errorf("registry and bundle must be provided")
+	}
+	return &Handler{registry: config.Registry, bundle: config.Bundle}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/bundle" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		writeError(w, http.StatusUnauthorized, ErrUnknownToken)
+		return
+	}
+	if _, err := h.registry.Check(token); err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, ErrRevoked) || errors.Is(err, ErrExpired) {
+			status = http.StatusForbidden
+		}
+		writeError(w, status, err)
+		return
+	}
+	b, err := h.bundle()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(b)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,166 @@
+package viewkey
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	solchain "solana-threshold-wallet/wallet/chain/solana"
+	"solana-threshold-wallet/wallet/clock"
+	"solana-threshold-wallet/wallet/delegate"
+)
+
+func newChain(t *testing.T) *solchain.Chain {
+	t.Helper()
+	ch, err := solchain.New(solchain.Config{ID: "solana-devnet", RPCEndpoint: "http://127.0.0.1:0"})
+	require.NoError(t, err)
+	return ch
+}
+
+func TestExport(t *testing.T) {
+	ch := newChain(t)
+	master, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	b, err := Export(ExportConfig{Chain: ch, MasterKey: master, Paths: []string{"cbmpc/1/0", "cbmpc/1/2"}})
+	require.NoError(t, err)
+	require.Len(t, b.Addresses, 3)
+	assert.Equal(t, "", b.Addresses[0].Path)
+	addr, err := ch.DeriveAddress(master)
+	require.NoError(t, err)
+	assert.Equal(t, addr, b.Addresses[0].Address)
+	child, err := delegate.Derive(master, nil, "cbmpc/1/2")
+	require.NoError(t, err)
+	assert.Equal(t, child.PublicKey, b.Addresses[2].PublicKey)
+	require.NoError(t, b.Verify(ch))
+
+	// The bundle survives JSON and still verifies.
+	data, err := json.Marshal(b)
+	require.NoError(t, err)
+	var decoded Bundle
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, decoded.Verify(ch))
+
+	// An address slipped into the bundle is caught.
+	other, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherAddr, err := ch.DeriveAddress(other)
+	require.NoError(t, err)
+	decoded.Addresses[1].Address = otherAddr
+	assert.Error(t, decoded.Verify(ch))
+
+	// A bundle with its own chain code verifies only with that chain code.
+	chainCode := bytes.Repeat([]byte{9}, 32)
+	b, err = Export(ExportConfig{Chain: ch, MasterKey: master, ChainCode: chainCode, Paths: []string{"cbmpc/1/2"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, child.PublicKey, b.Addresses[1].PublicKey)
+	require.NoError(t, b.Verify(ch))
+	b.ChainCode = nil
+	assert.Error(t, b.Verify(ch))
+
+	_, err = Export(ExportConfig{Chain: ch, MasterKey: master, Paths: []string{"a", "a"}})
+	assert.Error(t, err)
+	_, err = Export(ExportConfig{Chain: ch, MasterKey: master, Paths: []string{"hot/payments"}})
+	assert.Error(t, err)
+}
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grants.json")
+	fake := clock.NewFake(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC))
+	r, err := NewRegistry(RegistryConfig{Path: path, Now: fake.Now})
+	require.NoError(t, err)
+
+	token, grant, err := r.Issue("accounting", time.Hour)
+	require.NoError(t, err)
+	assert.Contains(t, token, "view_")
+	g, err := r.Check(token)
+	require.NoError(t, err)
+	assert.Equal(t, "accounting", g.Holder)
+	_, err = r.Check("view_" + strings.Repeat("0", 64))
+	assert.ErrorIs(t, err, ErrUnknownToken)
+
+	fake.Advance(time.Minute)
+	permanent, forever, err := r.Issue("analytics", 0)
+	require.NoError(t, err)
+	fake.Advance(time.Hour)
+	_, err = r.Check(token)
+	assert.ErrorIs(t, err, ErrExpired)
+
+	// Grants survive a restart; revocation is persisted.
+	r, err = NewRegistry(RegistryConfig{Path: path, Now: fake.Now})
+	require.NoError(t, err)
+	_, err = r.Check(permanent)
+	require.NoError(t, err)
+	require.NoError(t, r.Revoke(forever.ID))
+	require.NoError(t, r.Revoke(forever.ID))
+	r, err = NewRegistry(RegistryConfig{Path: path, Now: fake.Now})
+	require.NoError(t, err)
+	_, err = r.Check(permanent)
+	assert.ErrorIs(t, err, ErrRevoked)
+
+	grants := r.Grants()
+	require.Len(t, grants, 2)
+	assert.Equal(t, grant.ID, grants[0].ID)
+	assert.False(t, grants[1].Revoked.IsZero())
+	assert.Error(t, r.Revoke("missing"))
+}
+
+func TestHandler(t *testing.T) {
+	ch := newChain(t)
+	master, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	r, err := NewRegistry(RegistryConfig{})
+	require.NoError(t, err)
+	paths := []string{"cbmpc/1/0"}
+	h, err := NewHandler(HandlerConfig{Registry: r, Bundle: func() (*Bundle, error) {
+		return Export(ExportConfig{Chain: ch, MasterKey: master, Paths: paths})
+	}})
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	get := func(method, path, token string) (*http.Response, *Bundle) {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var b Bundle
+		json.NewDecoder(resp.Body).Decode(&b)
+		return resp, &b
+	}
+
+	token, grant, err := r.Issue("accounting", 0)
+	require.NoError(t, err)
+	resp, b := get(http.MethodGet, "/v1/bundle", token)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, b.Addresses, 2)
+
+	// New paths show up without a new token.
+	paths = append(paths, "cbmpc/1/2")
+	_, b = get(http.MethodGet, "/v1/bundle", token)
+	assert.Len(t, b.Addresses, 3)
+
+	resp, _ = get(http.MethodGet, "/v1/bundle", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = get(http.MethodPost, "/v1/bundle", token)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	resp, _ = get(http.MethodGet, "/v1/sign", token)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	require.NoError(t, r.Revoke(grant.ID))
+	resp, _ = get(http.MethodGet, "/v1/bundle", token)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
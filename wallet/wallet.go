@@ -0,0 +1,210 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+	"solana-threshold-wallet/wallet/display"
+)
+
+var (
+	// ErrAwaitingApproval is returned by Send and Resume when the session
+	// waits for human approval.
+	ErrAwaitingApproval = errors.New("wallet: transfer awaiting approval")
+	// ErrFailed is returned when the transfer was denied, vetoed or failed
+	// on-chain.  The receipt carries the reason.
+	ErrFailed = errors.New("wallet: transfer failed")
+	// ErrFeeBudget is returned by Send when the quoted cost exceeds the
+	// chain's fee budget.
+	ErrFeeBudget = errors.New("wallet: fee budget exceeded")
+)
+
+// Asset identifies what is sent and how its amounts are written.
+type Asset struct {
+	Chain    string // Identifier of the chain.Chain
+	Token    string // Token mint or contract address; empty for the native asset
+	Symbol   string // Display symbol, e.g. "SOL" or "USDC"
+	Decimals int    // Number of decimal places of one whole unit
+}
+
+// SOL returns the native asset of a Solana chain.
+func SOL(chainID string) Asset {
+	return Asset{Chain: chainID, Symbol: "SOL", Decimals: 9}
+}
+
+// ParseAmount converts a decimal amount in whole units, such as "1.5", into
+// the asset's smallest unit.  The amount must be positive and may not have
+// more fractional digits than the asset has decimals.
+func (a Asset) ParseAmount(amount string) (*big.Int, error) {
+	whole, frac, dotted := strings.Cut(amount, ".")
+	if whole == "" || (dotted && frac == "") || !digits(whole) || !digits(frac) {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	if len(frac) > a.Decimals {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", amount, a.Decimals)
+	}
+	v, _ := new(big.Int).SetString(whole+frac+strings.Repeat("0", a.Decimals-len(frac)), 10)
+	if v.Sign() <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	return v, nil
+}
+
+// Format renders an amount in smallest units as whole units with the symbol.
+func (a Asset) Format(amount *big.Int) string {
+	return display.FormatAmount(amount, display.Asset{Ticker: a.Symbol, Decimals: a.Decimals})
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Receipt describes the outcome of a transfer.
+type Receipt struct {
+	Session string            // Coordinator session ID, for Resume and History
+	State   coordinator.State // State the session was left in
+	Chain   string            // Identifier of the chain
+	Token   string            // Token mint or contract address; empty for the native asset
+	From    string            // Sender address
+	To      string            // Recipient address
+	Amount  *big.Int          // Amount in the asset's smallest unit
+	Fee     *big.Int          // Maximum network fee of the built transaction, if built
+	TxID    string            // Transaction identifier, once broadcast
+	Height  uint64            // Slot or block number, once finalized
+	Err     string            // Failure reason when State is StateFailed
+}
+
+// Config contains the configuration for a Wallet.
+type Config struct {
+	// Coordinator runs the signing sessions.  Required.  The assets sent
+	// must be on chains registered with it.
+	Coordinator *coordinator.Coordinator
+	// Addresses maps chain IDs to the wallet's sender address on that chain.
+	Addresses map[string]string
+	// FeeBudgets caps the quoted fee plus account-creation deposit of a
+	// single transfer per chain ID, in the chain's smallest unit.  Chains
+	// without a budget are not capped.
+	FeeBudgets map[string]*big.Int
+	// Priority is the scheduling class of the sessions.  Defaults to
+	// coordinator.PriorityNormal.
+	Priority coordinator.Priority
+}
+
+// Wallet sends assets on behalf of an application.
+type Wallet struct {
+	c          *coordinator.Coordinator
+	addresses  map[string]string
+	feeBudgets map[string]*big.Int
+	priority   coordinator.Priority
+}
+
+// New creates a Wallet from the given configuration.
+func New(config Config) (*Wallet, error) {
+	if config.Coordinator == nil {
+		return nil, fmt.Errorf("coordinator must be provided")
+	}
+	for id := range config.Addresses {
+		if _, ok := config.Coordinator.Chain(id); !ok {
+			return nil, fmt.Errorf("address for unknown chain %q", id)
+		}
+	}
+	return &Wallet{
+		c:          config.Coordinator,
+		addresses:  config.Addresses,
+		feeBudgets: config.FeeBudgets,
+		priority:   config.Priority,
+	}, nil
+}
+
+// Send transfers amount, in whole units of asset, to recipient and waits
+// until the transaction is finalized.  See the package documentation for the
+// checks performed on the way.
+func (w *Wallet) Send(ctx context.Context, asset Asset, amount, recipient string) (*Receipt, error) {
+	ch, ok := w.c.Chain(asset.Chain)
+	if !ok {
+		return nil, fmt.Errorf("unknown chain %q", asset.Chain)
+	}
+	from, ok := w.addresses[asset.Chain]
+	if !ok {
+		return nil, fmt.Errorf("no address configured for chain %q", asset.Chain)
+	}
+	value, err := asset.ParseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	transfer := chain.Transfer{From: from, To: recipient, Amount: value, Token: asset.Token}
+	req := &coordinator.Request{Chain: asset.Chain, Transfer: transfer, Priority: w.priority}
+
+	if q, ok := ch.(chain.Quoter); ok {
+		quote, err := q.Quote(ctx, &transfer)
+		if err != nil {
+			return nil, fmt.Errorf("checking transfer: %w", err)
+		}
+		cost := new(big.Int).Add(quote.Fee, quote.Deposit)
+		if budget := w.feeBudgets[asset.Chain]; budget != nil && cost.Cmp(budget) > 0 {
+			return nil, fmt.Errorf("%w: fee %s plus deposit %s exceeds %s", ErrFeeBudget, quote.Fee, quote.Deposit, budget)
+		}
+		// The built transaction may not pay more than the fee checked here.
+		req.MaxFee = quote.Fee
+	}
+
+	s, err := w.c.Submit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return w.Resume(ctx, s.ID)
+}
+
+// Resume continues a transfer, e.g. after it was approved or after Send
+// returned an infrastructure error.
+func (w *Wallet) Resume(ctx context.Context, session string) (*Receipt, error) {
+	s, err := w.c.Run(ctx, session)
+	if s == nil {
+		return nil, err
+	}
+	r := receipt(s)
+	switch {
+	case err != nil:
+		return r, err
+	case s.State == coordinator.StateFinalized:
+		return r, nil
+	case s.State == coordinator.StateFailed:
+		return r, fmt.Errorf("%w: %s", ErrFailed, s.Err)
+	default:
+		return r, ErrAwaitingApproval
+	}
+}
+
+// receipt summarises a session, preferring the decoded transaction over the
+// request where available.
+func receipt(s *coordinator.Session) *Receipt {
+	t := s.Request.Transfer
+	r := &Receipt{
+		Session: s.ID,
+		State:   s.State,
+		Chain:   s.Request.Chain,
+		Token:   t.Token,
+		From:    t.From,
+		To:      t.To,
+		Amount:  t.Amount,
+		TxID:    s.TxID,
+		Err:     s.Err,
+	}
+	if s.Summary != nil {
+		r.From, r.To, r.Amount, r.Fee = s.Summary.From, s.Summary.To, s.Summary.Amount, s.Summary.Fee
+	}
+	if s.Receipt != nil {
+		r.Height = s.Receipt.Height
+	}
+	return r
+}
@@ -0,0 +1,173 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// fakeChain decodes the transfer it built and quotes a fixed fee.
+type fakeChain struct {
+	quote    *chain.Quote
+	quoteErr error
+	built    *chain.Transfer
+}
+
+func (f *fakeChain) ID() string { return "fake" }
+
+func (f *fakeChain) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (f *fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	f.built = t
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: []byte("tx"), SigningPayload: []byte("tx")}, nil
+}
+
+func (f *fakeChain) Decode([]byte) (*chain.Summary, error) {
+	t := f.built
+	return &chain.Summary{Chain: f.ID(), From: t.From, To: t.To, Amount: t.Amount, Fee: big.NewInt(5000), Token: t.Token}, nil
+}
+
+func (f *fakeChain) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (f *fakeChain) Broadcast(context.Context, *chain.SignedTx) (string, error) { return "tx-1", nil }
+
+func (f *fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized, Height: 42}, nil
+}
+
+func (f *fakeChain) Quote(context.Context, *chain.Transfer) (*chain.Quote, error) {
+	if f.quoteErr != nil {
+		return nil, f.quoteErr
+	}
+	return f.quote, nil
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(_ context.Context, req *coordinator.SignRequest) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+var asset = Asset{Chain: "fake", Symbol: "FAKE", Decimals: 9}
+
+func newTestWallet(t *testing.T, ch *fakeChain, policy coordinator.Policy, budget int64) (*Wallet, *coordinator.Coordinator) {
+	t.Helper()
+	c, err := coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{ch},
+		Signer:          fakeSigner{},
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	w, err := New(Config{
+		Coordinator: c,
+		Addresses:   map[string]string{"fake": "treasury"},
+		FeeBudgets:  map[string]*big.Int{"fake": big.NewInt(budget)},
+	})
+	require.NoError(t, err)
+	return w, c
+}
+
+func TestSend(t *testing.T) {
+	ch := &fakeChain{quote: &chain.Quote{Fee: big.NewInt(5000), Deposit: big.NewInt(0)}}
+	w, _ := newTestWallet(t, ch, nil, 10_000)
+
+	r, err := w.Send(context.Background(), asset, "1.5", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, coordinator.StateFinalized, r.State)
+	assert.Equal(t, "treasury", r.From)
+	assert.Equal(t, "bob", r.To)
+	assert.Equal(t, big.NewInt(1_500_000_000), r.Amount)
+	assert.Equal(t, "tx-1", r.TxID)
+	assert.Equal(t, uint64(42), r.Height)
+	assert.Equal(t, "1.5 FAKE", asset.Format(r.Amount))
+}
+
+func TestSendChecksQuote(t *testing.T) {
+	ch := &fakeChain{quote: &chain.Quote{Fee: big.NewInt(5000), Deposit: big.NewInt(2_039_280)}}
+	w, _ := newTestWallet(t, ch, nil, 10_000)
+
+	_, err := w.Send(context.Background(), asset, "1", "bob")
+	assert.ErrorIs(t, err, ErrFeeBudget)
+
+	ch.quoteErr = fmt.Errorf("%w: treasury holds 0 lamports", chain.ErrInsufficientFunds)
+	_, err = w.Send(context.Background(), asset, "1", "bob")
+	assert.ErrorIs(t, err, chain.ErrInsufficientFunds)
+	assert.Nil(t, ch.built, "nothing is built when the checks fail")
+}
+
+func TestSendCapsFeeAtQuote(t *testing.T) {
+	// The transaction is built with a fee of 5000, above the quote.
+	ch := &fakeChain{quote: &chain.Quote{Fee: big.NewInt(4000), Deposit: big.NewInt(0)}}
+	w, _ := newTestWallet(t, ch, nil, 10_000)
+
+	r, err := w.Send(context.Background(), asset, "1", "bob")
+	assert.ErrorIs(t, err, ErrFailed)
+	assert.Equal(t, coordinator.StateFailed, r.State)
+	assert.Contains(t, r.Err, "exceeds maximum")
+}
+
+func TestSendAwaitingApproval(t *testing.T) {
+	ch := &fakeChain{quote: &chain.Quote{Fee: big.NewInt(5000), Deposit: big.NewInt(0)}}
+	policy := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		return &coordinator.Decision{Allow: true, RequiredApprovals: 1}, nil
+	})
+	w, c := newTestWallet(t, ch, policy, 10_000)
+	ctx := context.Background()
+
+	r, err := w.Send(ctx, asset, "2", "bob")
+	assert.ErrorIs(t, err, ErrAwaitingApproval)
+	assert.Equal(t, coordinator.StatePolicyEvaluated, r.State)
+	assert.Equal(t, big.NewInt(5000), r.Fee)
+
+	_, err = c.Approve(ctx, r.Session, "carol")
+	require.NoError(t, err)
+	r, err = w.Resume(ctx, r.Session)
+	require.NoError(t, err)
+	assert.Equal(t, coordinator.StateFinalized, r.State)
+}
+
+func TestSendDenied(t *testing.T) {
+	ch := &fakeChain{quote: &chain.Quote{Fee: big.NewInt(5000), Deposit: big.NewInt(0)}}
+	policy := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		return &coordinator.Decision{Reason: "limit exceeded"}, nil
+	})
+	w, _ := newTestWallet(t, ch, policy, 10_000)
+
+	r, err := w.Send(context.Background(), asset, "2", "bob")
+	assert.ErrorIs(t, err, ErrFailed)
+	assert.Equal(t, coordinator.StateFailed, r.State)
+	assert.Contains(t, r.Err, "limit exceeded")
+}
+
+func TestParseAmount(t *testing.T) {
+	usdc := Asset{Symbol: "USDC", Decimals: 6}
+	for _, tc := range []struct {
+		in   string
+		want int64
+	}{
+		{"1", 1_000_000},
+		{"1.5", 1_500_000},
+		{"0.000001", 1},
+		{"12.340000", 12_340_000},
+	} {
+		v, err := usdc.ParseAmount(tc.in)
+		require.NoError(t, err, tc.in)
+		assert.Equal(t, tc.want, v.Int64(), tc.in)
+	}
+	for _, in := range []string{"", "0", "0.0", "-1", "1.", ".5", "1.0000001", "1e6", "1,5"} {
+		_, err := usdc.ParseAmount(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -0,0 +1,29 @@
+// Package webhook lets an exchange's withdrawal system trigger transfers by
+// webhook and reports their outcome back.
+//
+// The exchange posts a `Withdrawal` to
+//
+//	POST /v1/withdrawals  {"idempotency_key": "...", "chain": "...", "to": "...", "amount": "1000000"}
+//
+// authenticated by a `Verifier`: either `HMAC`, a signature over timestamp and
+// body in the X-Webhook-Timestamp and X-Webhook-Signature headers, or `JWT`, an
+// HS256 bearer token whose "body_sha256" claim binds it to the body.  Both
+// reject requests outside a replay window.
+//
+// Each accepted withdrawal becomes a coordinator session paid from the
+// configured address on that chain, so it passes through the policy and any
+// approvals like every other transfer.  The idempotency key is stored before
+// the session is submitted and is carried as the session's Request.Reference:
+// repeated webhooks are answered with the existing withdrawal, a key reused
+// for a different withdrawal is refused with 409, and a crash between the two
+// steps never yields a second session.  A withdrawal may carry the exchange's
+// signed business metadata (see coordinator.Metadata), which is checked on
+// arrival, follows the session and is repeated in every Result.
+//
+// `Run` advances pending withdrawals and, once a session is finalized or has
+// failed, posts a `Result` with the transaction hash to Config.CallbackURL,
+// signed with Config.CallbackSecret in the same header format as inbound HMAC
+// webhooks and carrying an Idempotency-Key header.  Failed callbacks are
+// retried with exponential backoff; after Config.MaxAttempts the withdrawal is
+// dead-lettered.  `DeadLetters` lists those and `Redeliver` queues one again.
+package webhook
@@ -0,0 +1,570 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+const (
+	defaultInterval      = 5 * time.Second
+	defaultMaxAttempts   = 8
+	defaultBackoff       = 10 * time.Second
+	defaultTimeout       = 10 * time.Second
+	maxBodyBytes         = 64 << 10
+	headerIdempotencyKey = "Idempotency-Key"
+)
+
+// ErrNotFound is returned for unknown idempotency keys.
+var ErrNotFound = errors.New("webhook: withdrawal not found")
+
+// Withdrawal is the body of an inbound webhook.
+type Withdrawal struct {
+	// IdempotencyKey identifies the withdrawal in the exchange's system.
+	// Webhooks repeating a key are answered with the existing withdrawal
+	// and never create another session.
+	IdempotencyKey string `json:"idempotency_key"`
+	Chain          string `json:"chain"`
+	To             string `json:"to"`
+	Amount         string `json:"amount"` // Decimal integer in the asset's smallest unit
+	Token          string `json:"token,omitempty"`
+	// Metadata is the exchange's signed business context, e.g. the user
+	// and order behind the withdrawal.  It is signed for the session's
+	// request with coordinator.SignMetadata, using the idempotency key as
+	// reference, and returned in the Result.
+	Metadata *coordinator.Metadata `json:"metadata,omitempty"`
+}
+
+// Result is returned by the webhook endpoint and, once the session is
+// terminal, posted to Config.CallbackURL.
+type Result struct {
+	IdempotencyKey string            `json:"idempotency_key"`
+	Session        string            `json:"session,omitempty"`
+	State          coordinator.State `json:"state,omitempty"`
+	TxID           string            `json:"tx_id,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	// Metadata repeats the withdrawal's metadata fields, so that the result
+	// can be joined to the business event without a lookup.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Delivery is the stored state of an accepted withdrawal.
+type Delivery struct {
+	Withdrawal Withdrawal
+	CreatedAt  time.Time
+	Session    string  // Coordinator session, once submitted
+	Result     *Result // Final result, once the session is terminal
+
+	Attempts    int       // Failed callback attempts
+	NextAttempt time.Time // Earliest time of the next callback attempt
+	Delivered   bool      // The exchange acknowledged the result
+	DeadLetter  bool      // Callbacks were given up; see Redeliver
+	LastError   string    // Error of the last failed callback
+}
+
+// Config contains the configuration for a Server.
+type Config struct {
+	// Coordinator runs the signing sessions.  Required.
+	Coordinator *coordinator.Coordinator
+	// Verifier authenticates inbound webhooks.  Required.
+	Verifier Verifier
+	// Addresses maps chain IDs to the sender address withdrawals are paid
+	// from.  Webhooks for other chains are rejected.
+	Addresses map[string]string
+	// CallbackURL receives the result of every withdrawal.  Required.
+	CallbackURL string
+	// CallbackSecret signs callbacks as described at Sign.  Required.
+	CallbackSecret []byte
+	// Client posts callbacks.  Defaults to a client with a 10s timeout.
+	Client *http.Client
+	// Path is the file withdrawals are kept in.  If empty, they are only
+	// kept in memory and idempotency does not survive a restart.
+	Path string
+	// Priority is the scheduling class of the sessions.
+	Priority coordinator.Priority
+	// Interval is how often Run processes pending withdrawals.  Defaults to
+	// 5s.
+	Interval time.Duration
+	// MaxAttempts bounds the callback attempts before a withdrawal is moved
+	// to the dead letters.  Defaults to 8.
+	MaxAttempts int
+	// Backoff is the delay after the first failed callback; it doubles with
+	// every further failure.  Defaults to 10s.
+	Backoff time.Duration
+	// Now returns the current time.  Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Server accepts withdrawal webhooks and reports their outcome.
+type Server struct {
+	c      *coordinator.Coordinator
+	config Config
+	mux    *http.ServeMux
+	wake   chan struct{}
+
+	// submitMu serializes submissions so that a key never gets two sessions.
+	submitMu   sync.Mutex
+	mu         sync.Mutex
+	deliveries map[string]*Delivery
+}
+
+// Ensure Server implements the http.Handler interface
+var _ http.Handler = (*Server)(nil)
+
+// New creates a Server from the given configuration, loading the withdrawals
+// kept at config.Path.
+func New(config Config) (*Server, error) {
+	if config.Coordinator == nil {
+		return nil, fmt.Errorf("coordinator must be provided")
+	}
+	if config.Verifier == nil {
+		return nil, fmt.Errorf("verifier must be provided")
+	}
+	if config.CallbackURL == "" || len(config.CallbackSecret) == 0 {
+		return nil, fmt.Errorf("callback URL and secret must be provided")
+	}
+	for id := range config.Addresses {
+		if _, ok := config.Coordinator.Chain(id); !ok {
+			return nil, fmt.Errorf("address for unknown chain %q", id)
+		}
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultBackoff
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	s := &Server{
+		c:          config.Coordinator,
+		config:     config,
+		mux:        http.NewServeMux(),
+		wake:       make(chan struct{}, 1),
+		deliveries: map[string]*Delivery{},
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.mux.HandleFunc("POST /v1/withdrawals", s.withdraw)
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// withdraw accepts a withdrawal.  New withdrawals are answered with 202,
+// repeated ones with 200 and their current result, and a repeated key with a
+// different body with 409.
+func (s *Server) withdraw(w http.ResponseWriter, r *http.Request) {
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(w, r.Body, maxBodyBytes)); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("reading body: %w", err))
+		return
+	}
+	if err := s.config.Verifier.Verify(r, body.Bytes()); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	var wd Withdrawal
+	dec := json.NewDecoder(&body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&wd); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding withdrawal: %w", err))
+		return
+	}
+	if err := s.validate(&wd); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.mu.Lock()
+	d, exists := s.deliveries[wd.IdempotencyKey]
+	if exists && !reflect.DeepEqual(d.Withdrawal, wd) {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("idempotency key %q was used for another withdrawal", wd.IdempotencyKey))
+		return
+	}
+	if !exists {
+		d = &Delivery{Withdrawal: wd, CreatedAt: s.config.Now()}
+		s.deliveries[wd.IdempotencyKey] = d
+		if err := s.save(); err != nil {
+			delete(s.deliveries, wd.IdempotencyKey)
+			s.mu.Unlock()
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	s.mu.Unlock()
+
+	// A failed submission is retried by Process; the withdrawal is accepted
+	// either way.
+	_ = s.submit(r.Context(), wd.IdempotencyKey)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	status := http.StatusAccepted
+	if exists {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(s.result(r.Context(), wd.IdempotencyKey))
+}
+
+func (s *Server) validate(wd *Withdrawal) error {
+	switch {
+	case wd.IdempotencyKey == "":
+		return fmt.Errorf("idempotency key must be provided")
+	case wd.To == "":
+		return fmt.Errorf("recipient must be provided")
+	case s.config.Addresses[wd.Chain] == "":
+		return fmt.Errorf("withdrawals on chain %q are not supported", wd.Chain)
+	}
+	if _, err := amount(wd.Amount); err != nil {
+		return err
+	}
+	return s.c.VerifyMetadata(s.request(wd))
+}
+
+func amount(s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() <= 0 || s != v.String() {
+		return nil, fmt.Errorf("amount must be a positive decimal integer")
+	}
+	return v, nil
+}
+
+// result describes a withdrawal as it currently stands.
+func (s *Server) result(ctx context.Context, key string) *Result {
+	d, err := s.Delivery(key)
+	if err != nil {
+		return &Result{IdempotencyKey: key}
+	}
+	if d.Result != nil {
+		return d.Result
+	}
+	r := &Result{IdempotencyKey: key, Session: d.Session}
+	if m := d.Withdrawal.Metadata; m != nil {
+		r.Metadata = m.Fields
+	}
+	if d.Session != "" {
+		if session, err := s.c.Session(ctx, d.Session); err == nil {
+			r.State = session.State
+		}
+	}
+	return r
+}
+
+// submit creates the session of a withdrawal unless it has one.  A session
+// submitted before a crash is found again by its reference.
+func (s *Server) submit(ctx context.Context, key string) error {
+	s.submitMu.Lock()
+	defer s.submitMu.Unlock()
+	d, err := s.Delivery(key)
+	if err != nil || d.Session != "" {
+		return err
+	}
+
+	existing, err := s.c.List(ctx, coordinator.Filter{Reference: key})
+	if err != nil {
+		return err
+	}
+	var id string
+	if len(existing) > 0 {
+		id = existing[0].ID
+	} else {
+		session, err := s.c.Submit(ctx, s.request(&d.Withdrawal))
+		if err != nil {
+			return err
+		}
+		id = session.ID
+	}
+	return s.update(key, func(d *Delivery) { d.Session = id })
+}
+
+// request returns the session request of a validated withdrawal.
+func (s *Server) request(wd *Withdrawal) *coordinator.Request {
+	value, _ := amount(wd.Amount)
+	return &coordinator.Request{
+		Chain: wd.Chain,
+		Transfer: chain.Transfer{
+			From:   s.config.Addresses[wd.Chain],
+			To:     wd.To,
+			Amount: value,
+			Token:  wd.Token,
+		},
+		Priority:  s.config.Priority,
+		Reference: wd.IdempotencyKey,
+		Metadata:  wd.Metadata,
+	}
+}
+
+// Run processes pending withdrawals every Config.Interval, and as soon as a
+// webhook arrives, until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		_ = s.Process(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Process advances every pending withdrawal once: it submits missing
+// sessions, runs sessions until they are terminal or wait for approval, and
+// posts the results that are due.  Withdrawals are processed one after the
+// other.
+func (s *Server) Process(ctx context.Context) error {
+	var errs []error
+	for _, d := range s.pending() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.process(ctx, d.Withdrawal.IdempotencyKey); err != nil {
+			errs = append(errs, fmt.Errorf("withdrawal %q: %w", d.Withdrawal.IdempotencyKey, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) process(ctx context.Context, key string) error {
+	if err := s.submit(ctx, key); err != nil {
+		return err
+	}
+	d, err := s.Delivery(key)
+	if err != nil {
+		return err
+	}
+	if d.Result == nil {
+		session, err := s.c.Run(ctx, d.Session)
+		if err != nil {
+			return err
+		}
+		if !session.State.Terminal() {
+			return nil
+		}
+		result := &Result{IdempotencyKey: key, Session: session.ID, State: session.State, TxID: session.TxID, Error: session.Err}
+		if m := session.Request.Metadata; m != nil {
+			result.Metadata = m.Fields
+		}
+		if err := s.update(key, func(d *Delivery) { d.Result = result }); err != nil {
+			return err
+		}
+		d.Result = result
+	}
+	if s.config.Now().Before(d.NextAttempt) {
+		return nil
+	}
+	return s.deliver(ctx, d)
+}
+
+// deliver posts a result to the callback URL.
+func (s *Server) deliver(ctx context.Context, d *Delivery) error {
+	key := d.Withdrawal.IdempotencyKey
+	err := s.post(ctx, d.Result)
+	if err == nil {
+		return s.update(key, func(d *Delivery) { d.Delivered, d.LastError = true, "" })
+	}
+	return errors.Join(err, s.update(key, func(d *Delivery) {
+		d.Attempts++
+		d.LastError = err.Error()
+		if d.Attempts >= s.config.MaxAttempts {
+			d.DeadLetter = true
+			return
+		}
+		d.NextAttempt = s.config.Now().Add(s.config.Backoff << (d.Attempts - 1))
+	}))
+}
+
+func (s *Server) post(ctx context.Context, result *Result) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := s.config.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerIdempotencyKey, result.IdempotencyKey)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(s.config.CallbackSecret, timestamp, body))
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting result: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("posting result: %s", resp.Status)
+	}
+	return nil
+}
+
+// Delivery returns the stored state of a withdrawal.
+func (s *Server) Delivery(key string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.deliveries[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	c := *d
+	return &c, nil
+}
+
+// DeadLetters returns the withdrawals whose results could not be delivered,
+// oldest first.
+func (s *Server) DeadLetters() []*Delivery {
+	return s.list(func(d *Delivery) bool { return d.DeadLetter })
+}
+
+// Redeliver moves a dead-lettered withdrawal back to the pending ones, e.g.
+// after the exchange's endpoint was fixed.
+func (s *Server) Redeliver(key string) error {
+	d, err := s.Delivery(key)
+	if err != nil {
+		return err
+	}
+	if !d.DeadLetter {
+		return fmt.Errorf("withdrawal %q is not dead-lettered", key)
+	}
+	err = s.update(key, func(d *Delivery) {
+		d.DeadLetter, d.Attempts, d.NextAttempt = false, 0, time.Time{}
+	})
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+func (s *Server) pending() []*Delivery {
+	return s.list(func(d *Delivery) bool { return !d.Delivered && !d.DeadLetter })
+}
+
+func (s *Server) list(match func(*Delivery) bool) []*Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*Delivery
+	for _, d := range s.deliveries {
+		if match(d) {
+			c := *d
+			out = append(out, &c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Withdrawal.IdempotencyKey < out[j].Withdrawal.IdempotencyKey
+	})
+	return out
+}
+
+// update applies fn to the stored withdrawal and persists the result.
+func (s *Server) update(key string, fn func(*Delivery)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.deliveries[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	next := *d
+	fn(&next)
+	s.deliveries[key] = &next
+	if err := s.save(); err != nil {
+		s.deliveries[key] = d
+		return err
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func (s *Server) load() error {
+	if s.config.Path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading withdrawals: %w", err)
+	}
+	var list []*Delivery
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("decoding withdrawals: %w", err)
+	}
+	for _, d := range list {
+		s.deliveries[d.Withdrawal.IdempotencyKey] = d
+	}
+	return nil
+}
+
+func (s *Server) save() error {
+	if s.config.Path == "" {
+		return nil
+	}
+	list := make([]*Delivery, 0, len(s.deliveries))
+	for _, d := range s.deliveries {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Withdrawal.IdempotencyKey < list[j].Withdrawal.IdempotencyKey })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.config.Path), ".withdrawals-*")
+	if err != nil {
+		return fmt.Errorf("writing withdrawals: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing withdrawals: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing withdrawals: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.config.Path); err != nil {
+		return fmt.Errorf("writing withdrawals: %w", err)
+	}
+	return nil
+}
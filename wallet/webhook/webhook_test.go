@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"solana-threshold-wallet/wallet/approval/approvaltest"
+	"solana-threshold-wallet/wallet/chain"
+	"solana-threshold-wallet/wallet/clock"
+	"solana-threshold-wallet/wallet/coordinator"
+)
+
+// fakeChain finalizes everything it broadcasts.
+type fakeChain struct {
+	mu    sync.Mutex
+	built []chain.Transfer
+}
+
+func (f *fakeChain) ID() string { return "fake" }
+
+func (f *fakeChain) DeriveAddress(pubKey []byte) (string, error) { return string(pubKey), nil }
+
+func (f *fakeChain) BuildTransfer(_ context.Context, t *chain.Transfer) (*chain.UnsignedTx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.built = append(f.built, *t)
+	payload := []byte{byte(len(f.built))}
+	return &chain.UnsignedTx{Chain: f.ID(), Payload: payload, SigningPayload: payload}, nil
+}
+
+func (f *fakeChain) Decode(payload []byte) (*chain.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.built[payload[0]-1]
+	return &chain.Summary{Chain: f.ID(), From: t.From, To: t.To, Amount: t.Amount, Fee: big.NewInt(5000)}, nil
+}
+
+func (f *fakeChain) Simulate(context.Context, *chain.SignedTx) (*chain.SimulationResult, error) {
+	return &chain.SimulationResult{OK: true}, nil
+}
+
+func (f *fakeChain) Broadcast(_ context.Context, tx *chain.SignedTx) (string, error) {
+	return fmt.Sprintf("tx-%d", tx.Unsigned.Payload[0]), nil
+}
+
+func (f *fakeChain) Confirm(_ context.Context, txID string) (*chain.Receipt, error) {
+	return &chain.Receipt{TxID: txID, Status: chain.StatusFinalized}, nil
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(context.Context, *coordinator.SignRequest) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+var (
+	inboundSecret  = []byte("inbound-secret")
+	callbackSecret = []byte("callback-secret")
+)
+
+// exchange receives callbacks and verifies their signatures.
+type exchange struct {
+	t       *testing.T
+	mu      sync.Mutex
+	status  int
+	results []Result
+}
+
+func (e *exchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var body bytes.Buffer
+	body.ReadFrom(r.Body)
+	timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	assert.Equal(e.t, Sign(callbackSecret, timestamp, body.Bytes()), r.Header.Get(HeaderSignature))
+	if e.status != http.StatusOK {
+		w.WriteHeader(e.status)
+		return
+	}
+	var result Result
+	require.NoError(e.t, json.Unmarshal(body.Bytes(), &result))
+	assert.Equal(e.t, result.IdempotencyKey, r.Header.Get("Idempotency-Key"))
+	e.results = append(e.results, result)
+}
+
+type fixture struct {
+	ch       *fakeChain
+	c        *coordinator.Coordinator
+	s        *Server
+	server   *httptest.Server
+	exchange *exchange
+	clock    *clock.Fake
+	// keys holds the exchange's key for signing withdrawal metadata.
+	keys *approvaltest.Signers
+}
+
+func newFixture(t *testing.T, policy coordinator.Policy, config Config) *fixture {
+	t.Helper()
+	f := &fixture{
+		ch:       &fakeChain{},
+		exchange: &exchange{t: t, status: http.StatusOK},
+		clock:    clock.NewFake(time.Now()),
+		keys:     approvaltest.New(t, "exchange"),
+	}
+	callbacks := httptest.NewServer(f.exchange)
+	t.Cleanup(callbacks.Close)
+
+	var err error
+	f.c, err = coordinator.New(coordinator.Config{
+		Store:           coordinator.NewMemoryStore(),
+		Chains:          []chain.Chain{f.ch},
+		Signer:          fakeSigner{},
+		Policy:          policy,
+		ConfirmInterval: time.Millisecond,
+		MetadataKeys:    f.keys.Public,
+	})
+	require.NoError(t, err)
+	config.Coordinator = f.c
+	config.Verifier = &HMAC{Secret: inboundSecret, Now: f.clock.Now}
+	config.Addresses = map[string]string{"fake": "hot-wallet"}
+	config.CallbackURL = callbacks.URL
+	config.CallbackSecret = callbackSecret
+	config.Now = f.clock.Now
+	f.s, err = New(config)
+	require.NoError(t, err)
+	f.server = httptest.NewServer(f.s)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func withdrawal(key string, amount string) Withdrawal {
+	return Withdrawal{IdempotencyKey: key, Chain: "fake", To: "customer", Amount: amount}
+}
+
+func (f *fixture) post(t *testing.T, body any, secret []byte) (int, *Result) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, f.server.URL+"/v1/withdrawals", bytes.NewReader(data))
+	require.NoError(t, err)
+	timestamp := f.clock.Now().Unix()
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, data))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var result Result
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, &result
+}
+
+func TestWithdrawal(t *testing.T) {
+	f := newFixture(t, nil, Config{})
+	ctx := context.Background()
+
+	status, first := f.post(t, withdrawal("w-1", "1500"), inboundSecret)
+	require.Equal(t, http.StatusAccepted, status)
+	assert.NotEmpty(t, first.Session)
+	assert.Equal(t, coordinator.StateCreated, first.State)
+
+	status, again := f.post(t, withdrawal("w-1", "1500"), inboundSecret)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, first.Session, again.Session)
+	status, _ = f.post(t, withdrawal("w-1", "9999"), inboundSecret)
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = f.post(t, withdrawal("w-2", "1500"), []byte("wrong"))
+	assert.Equal(t, http.StatusUnauthorized, status)
+	for _, amount := range []string{"0", "-5", "1.5", "01", ""} {
+		status, _ = f.post(t, withdrawal("w-3", amount), inboundSecret)
+		assert.Equal(t, http.StatusBadRequest, status, amount)
+	}
+
+	require.NoError(t, f.s.Process(ctx))
+	require.Len(t, f.exchange.results, 1)
+	assert.Equal(t, Result{IdempotencyKey: "w-1", Session: first.Session, State: coordinator.StateFinalized, TxID: "tx-1"}, f.exchange.results[0])
+	assert.Equal(t, []chain.Transfer{{From: "hot-wallet", To: "customer", Amount: big.NewInt(1500)}}, f.ch.built)
+
+	d, err := f.s.Delivery("w-1")
+	require.NoError(t, err)
+	assert.True(t, d.Delivered)
+	status, final := f.post(t, withdrawal("w-1", "1500"), inboundSecret)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "tx-1", final.TxID)
+
+	require.NoError(t, f.s.Process(ctx))
+	assert.Len(t, f.exchange.results, 1, "results are delivered once")
+}
+
+func TestWithdrawalMetadata(t *testing.T) {
+	f := newFixture(t, nil, Config{})
+	ctx := context.Background()
+	fields := map[string]string{"order_id": "42", "user_id": "u-7"}
+	signed := func(wd Withdrawal) Withdrawal {
+		value, _ := new(big.Int).SetString(wd.Amount, 10)
+		req := &coordinator.Request{
+			Chain:     wd.Chain,
+			Transfer:  chain.Transfer{To: wd.To, Amount: value, Token: wd.Token},
+			Reference: wd.IdempotencyKey,
+		}
+		coordinator.SignMetadata(req, "exchange", fields, f.keys.Private["exchange"])
+		wd.Metadata = req.Metadata
+		return wd
+	}
+
+	// Metadata signed for another withdrawal is refused up front.
+	misplaced := signed(withdrawal("w-1", "1500"))
+	misplaced.Amount = "2500"
+	status, _ := f.post(t, misplaced, inboundSecret)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, first := f.post(t, signed(withdrawal("w-1", "1500")), inboundSecret)
+	require.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, fields, first.Metadata)
+	status, _ = f.post(t, signed(withdrawal("w-1", "1500")), inboundSecret)
+	assert.Equal(t, http.StatusOK, status, "the repeated withdrawal is the same")
+
+	require.NoError(t, f.s.Process(ctx))
+	require.Len(t, f.exchange.results, 1)
+	assert.Equal(t, fields, f.exchange.results[0].Metadata)
+	sessions, err := f.c.List(ctx, coordinator.Filter{Metadata: map[string]string{"order_id": "42"}})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, first.Session, sessions[0].ID)
+}
+
+func TestWithdrawalAwaitsApproval(t *testing.T) {
+	policy := coordinator.PolicyFunc(func(context.Context, *coordinator.Request, *chain.Summary) (*coordinator.Decision, error) {
+		return &coordinator.Decision{Allow: true, RequiredApprovals: 1}, nil
+	})
+	f := newFixture(t, policy, Config{})
+	ctx := context.Background()
+
+	_, result := f.post(t, withdrawal("w-1", "1500"), inboundSecret)
+	require.NoError(t, f.s.Process(ctx))
+	assert.Empty(t, f.exchange.results)
+
+	_, err := f.c.Approve(ctx, result.Session, "carol")
+	require.NoError(t, err)
+	require.NoError(t, f.s.Process(ctx))
+	require.Len(t, f.exchange.results, 1)
+	assert.Equal(t, coordinator.StateFinalized, f.exchange.results[0].State)
+}
+
+func TestDeadLetter(t *testing.T) {
+	f := newFixture(t, nil, Config{MaxAttempts: 2, Backoff: time.Minute})
+	ctx := context.Background()
+	f.exchange.status = http.StatusServiceUnavailable
+
+	f.post(t, withdrawal("w-1", "1500"), inboundSecret)
+	assert.Error(t, f.s.Process(ctx))
+	require.NoError(t, f.s.Process(ctx), "not due before the backoff")
+	f.clock.Advance(time.Minute)
+	assert.Error(t, f.s.Process(ctx))
+
+	dead := f.s.DeadLetters()
+	require.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, "503")
+	f.clock.Advance(time.Hour)
+	require.NoError(t, f.s.Process(ctx), "dead letters are not retried")
+
+	f.exchange.status = http.StatusOK
+	require.NoError(t, f.s.Redeliver("w-1"))
+	require.NoError(t, f.s.Process(ctx))
+	assert.Len(t, f.exchange.results, 1)
+	assert.Empty(t, f.s.DeadLetters())
+	assert.Len(t, f.ch.built, 1)
+}
+
+func TestRecoversSubmittedSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "withdrawals.json")
+	f := newFixture(t, nil, Config{})
+	ctx := context.Background()
+
+	// The process stored the key and submitted the session, then crashed
+	// before it could store the session.
+	session, err := f.c.Submit(ctx, &coordinator.Request{
+		Chain:     "fake",
+		Transfer:  chain.Transfer{From: "hot-wallet", To: "customer", Amount: big.NewInt(1500)},
+		Reference: "w-1",
+	})
+	require.NoError(t, err)
+	data, err := json.Marshal([]*Delivery{{Withdrawal: withdrawal("w-1", "1500"), CreatedAt: f.clock.Now()}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	s, err := New(Config{
+		Coordinator:    f.c,
+		Verifier:       &HMAC{Secret: inboundSecret},
+		Addresses:      map[string]string{"fake": "hot-wallet"},
+		CallbackURL:    f.s.config.CallbackURL,
+		CallbackSecret: callbackSecret,
+		Path:           path,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Process(ctx))
+	d, err := s.Delivery("w-1")
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, d.Session)
+	assert.True(t, d.Delivered)
+	assert.Len(t, f.ch.built, 1)
+}
+
+func jwtToken(t *testing.T, secret []byte, alg string, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signing))
+	return signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWT(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"idempotency_key":"w-1"}`)
+	digest := sha256.Sum256(body)
+	claims := func() map[string]any {
+		return map[string]any{"iss": "exchange", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix(), "body_sha256": hex.EncodeToString(digest[:])}
+	}
+	v := &JWT{Secret: inboundSecret, Issuer: "exchange", Now: func() time.Time { return now }}
+	verify := func(token string, body []byte) error {
+		r := httptest.NewRequest(http.MethodPost, "/v1/withdrawals", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return v.Verify(r, body)
+	}
+
+	require.NoError(t, verify(jwtToken(t, inboundSecret, "HS256", claims()), body))
+	assert.ErrorIs(t, verify(jwtToken(t, inboundSecret, "HS256", claims()), []byte(`{}`)), ErrUnauthenticated)
+	assert.ErrorIs(t, verify(jwtToken(t, []byte("wrong"), "HS256", claims()), body), ErrUnauthenticated)
+	assert.ErrorIs(t, verify(jwtToken(t, inboundSecret, "none", claims()), body), ErrUnauthenticated)
+
+	expired := claims()
+	expired["exp"] = now.Add(-time.Second).Unix()
+	assert.ErrorIs(t, verify(jwtToken(t, inboundSecret, "HS256", expired), body), ErrUnauthenticated)
+	stale := claims()
+	stale["iat"] = now.Add(-time.Hour).Unix()
+	assert.ErrorIs(t, verify(jwtToken(t, inboundSecret, "HS256", stale), body), ErrUnauthenticated)
+	other := claims()
+	other["iss"] = "someone"
+	assert.ErrorIs(t, verify(jwtToken(t, inboundSecret, "HS256", other), body), ErrUnauthenticated)
+}